@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	tritonGrpc "github.com/Trendyol/go-triton-client/client/grpc"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/nsqio/go-nsq"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"lumina/internal/device/config"
+	"lumina/internal/device/metadata"
+)
+
+const (
+	doctorTimeout     = 5 * time.Second
+	minFreeDiskBytes  = 1 << 30
+	minFreeDiskRatio  = 0.05
+	maxClockSkew      = 30 * time.Second
+	serverHealthzPath = "/healthz"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the device environment",
+	Long:  `Check ffmpeg/ffprobe, lumina server, Triton, NSQ, MinIO, disk space and clock, and print actionable results`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !runDoctor() {
+			os.Exit(1)
+		}
+	},
+}
+
+type checkResult struct {
+	name   string
+	ok     bool
+	detail string
+	hint   string
+}
+
+func pass(name, format string, args ...any) checkResult {
+	return checkResult{name: name, ok: true, detail: fmt.Sprintf(format, args...)}
+}
+
+func fail(name, hint, format string, args ...any) checkResult {
+	return checkResult{name: name, ok: false, detail: fmt.Sprintf(format, args...), hint: hint}
+}
+
+func runDoctor() bool {
+	conf, err := config.LoadConfig(configFile, profileName)
+	if err != nil {
+		logrus.Fatal("initConfig error, ", err.Error())
+	}
+
+	checks := []func(*config.Config) checkResult{
+		checkBinary("ffmpeg"),
+		checkBinary("ffprobe"),
+		checkServer,
+		checkTriton,
+		checkNSQ,
+		checkMinio,
+		checkDisk,
+	}
+
+	allOk := true
+	for _, check := range checks {
+		r := check(conf)
+		printResult(r)
+		allOk = allOk && r.ok
+	}
+	return allOk
+}
+
+func printResult(r checkResult) {
+	state := "PASS"
+	if !r.ok {
+		state = "FAIL"
+	}
+	fmt.Printf("[%s] %-8s %s\n", state, r.name, r.detail)
+	if !r.ok && r.hint != "" {
+		fmt.Printf("       %-8s hint: %s\n", "", r.hint)
+	}
+}
+
+func checkBinary(name string) func(*config.Config) checkResult {
+	return func(*config.Config) checkResult {
+		p, err := exec.LookPath(name)
+		if err != nil {
+			return fail(name, fmt.Sprintf("install %s and make sure it is in PATH", name), "not found")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, p, "-version").Output()
+		if err != nil {
+			return fail(name, fmt.Sprintf("reinstall %s", name), "%s -version failed: %v", p, err)
+		}
+		version, _, _ := strings.Cut(string(out), "\n")
+		return pass(name, "%s", version)
+	}
+}
+
+// checkServer checks the lumina server is reachable and the local clock agrees
+// with the server clock, a skewed clock breaks message timestamps and tokens.
+func checkServer(conf *config.Config) checkResult {
+	const name = "server"
+	cli := &http.Client{Timeout: doctorTimeout}
+	resp, err := cli.Get(conf.LuminaServerAddr + serverHealthzPath)
+	if err != nil {
+		return fail(name, "check luminaServerAddr in config and the network route to the server", "%s unreachable: %v", conf.LuminaServerAddr, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fail(name, "check the lumina server logs", "%s returned status %d", conf.LuminaServerAddr, resp.StatusCode)
+	}
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return pass(name, "%s reachable, server clock unknown", conf.LuminaServerAddr)
+	}
+	skew := time.Since(serverTime)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxClockSkew {
+		return fail(name, "enable NTP (e.g. timedatectl set-ntp true)", "%s reachable, clock skew %s", conf.LuminaServerAddr, skew.Truncate(time.Second))
+	}
+	return pass(name, "%s reachable, clock skew %s", conf.LuminaServerAddr, skew.Truncate(time.Second))
+}
+
+func checkTriton(conf *config.Config) checkResult {
+	const name = "triton"
+	hint := "check triton.serverAddr in config and that tritonserver is running"
+	cli, err := tritonGrpc.NewClient(conf.Triton.ServerAddr, false, doctorTimeout.Seconds(), doctorTimeout.Seconds(),
+		false, true, nil, nil)
+	if err != nil {
+		return fail(name, hint, "create client for %s failed: %v", conf.Triton.ServerAddr, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	if live, err := cli.IsServerLive(ctx, nil); err != nil {
+		return fail(name, hint, "%s unreachable: %v", conf.Triton.ServerAddr, err)
+	} else if !live {
+		return fail(name, "check the tritonserver logs", "%s is not live", conf.Triton.ServerAddr)
+	}
+	if ready, err := cli.IsServerReady(ctx, nil); err != nil || !ready {
+		return fail(name, "check the tritonserver logs and model repository", "%s is live but not ready", conf.Triton.ServerAddr)
+	}
+	return pass(name, "%s live and ready", conf.Triton.ServerAddr)
+}
+
+func checkNSQ(conf *config.Config) checkResult {
+	const name = "nsq"
+	producer, err := nsq.NewProducer(conf.NSQ.NSQDAddr, nsq.NewConfig())
+	if err != nil {
+		return fail(name, "check nsq.nsqdAddr in config", "create producer failed: %v", err)
+	}
+	defer producer.Stop()
+	producer.SetLogger(nil, nsq.LogLevelError)
+
+	if err := producer.Ping(); err != nil {
+		return fail(name, "check nsq.nsqdAddr in config and that nsqd is running", "%s unreachable: %v", conf.NSQ.NSQDAddr, err)
+	}
+	return pass(name, "%s reachable, topic %s", conf.NSQ.NSQDAddr, conf.NSQ.Topic)
+}
+
+func checkMinio(conf *config.Config) checkResult {
+	const name = "minio"
+	hint := "check s3.endpoint and s3.useSSL in config"
+
+	// the metadata DB is locked while the device is running, fall back to
+	// the anonymous health check when the credentials can not be read
+	info, err := getDeviceInfo()
+	if err != nil || info == nil || strValue(info.S3AccessKeyID) == "" {
+		cli := &http.Client{Timeout: doctorTimeout}
+		scheme := "http"
+		if conf.S3.UseSSL {
+			scheme = "https"
+		}
+		resp, err := cli.Get(fmt.Sprintf("%s://%s/minio/health/live", scheme, conf.S3.Endpoint))
+		if err != nil {
+			return fail(name, hint, "%s unreachable: %v", conf.S3.Endpoint, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fail(name, hint, "%s health check returned status %d", conf.S3.Endpoint, resp.StatusCode)
+		}
+		return pass(name, "%s live, credentials not checked", conf.S3.Endpoint)
+	}
+
+	cli, err := newMinioClient(conf, info)
+	if err != nil {
+		return fail(name, hint, "create client failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	exists, err := cli.BucketExists(ctx, conf.S3.Bucket)
+	if err != nil {
+		return fail(name, "check the endpoint and re-register the device if the credentials were revoked", "%s: %v", conf.S3.Endpoint, err)
+	} else if !exists {
+		return fail(name, "create the bucket or fix s3.bucket in config", "bucket %s does not exist", conf.S3.Bucket)
+	}
+	return pass(name, "%s reachable, bucket %s exists", conf.S3.Endpoint, conf.S3.Bucket)
+}
+
+func newMinioClient(conf *config.Config, info *metadata.DeviceInfo) (*minio.Client, error) {
+	region := conf.S3.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return minio.New(conf.S3.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(*info.S3AccessKeyID, strValue(info.S3SecretAccessKey), ""),
+		Secure: conf.S3.UseSSL,
+		Region: region,
+	})
+}
+
+func checkDisk(conf *config.Config) checkResult {
+	const name = "disk"
+	if err := os.MkdirAll(conf.WorkDir, 0755); err != nil {
+		return fail(name, "check workDir in config and its permissions", "create %s failed: %v", conf.WorkDir, err)
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(conf.WorkDir, &stat); err != nil {
+		return fail(name, "check workDir in config", "statfs %s failed: %v", conf.WorkDir, err)
+	}
+	free := int64(stat.Bavail) * int64(stat.Bsize)
+	total := int64(stat.Blocks) * int64(stat.Bsize)
+	ratio := float64(free) / float64(total)
+	if free < minFreeDiskBytes || ratio < minFreeDiskRatio {
+		return fail(name, "free up space or move workDir to a larger disk",
+			"%s free of %s (%.1f%%) in %s", formatBytes(free), formatBytes(total), ratio*100, conf.WorkDir)
+	}
+	return pass(name, "%s free of %s (%.1f%%) in %s", formatBytes(free), formatBytes(total), ratio*100, conf.WorkDir)
+}
@@ -58,7 +58,7 @@ func init() {
 
 func addJob(jobFilePath string) {
 	logrus.Infof("Adding job from file: %s", jobFilePath)
-	conf, err := config.LoadConfig(configFile)
+	conf, err := config.LoadConfig(configFile, profileName)
 	if err != nil {
 		logrus.Fatal("initConfig error, ", err.Error())
 	}
@@ -93,7 +93,7 @@ func addJob(jobFilePath string) {
 
 func listJobs() {
 	logrus.Info("Listing all jobs")
-	conf, err := config.LoadConfig(configFile)
+	conf, err := config.LoadConfig(configFile, profileName)
 	if err != nil {
 		logrus.Fatal("initConfig error, ", err.Error())
 	}
@@ -128,7 +128,7 @@ func listJobs() {
 
 func deleteJob(jobUuid string) {
 	logrus.Infof("Deleting job: %s", jobUuid)
-	conf, err := config.LoadConfig(configFile)
+	conf, err := config.LoadConfig(configFile, profileName)
 	if err != nil {
 		logrus.Fatal("initConfig error, ", err.Error())
 	}
@@ -7,11 +7,13 @@ import (
 
 	"lumina/internal/version"
 	"lumina/pkg/log"
+	"lumina/pkg/profile"
 )
 
 var (
-	logLevel   string
-	configFile string
+	logLevel    string
+	configFile  string
+	profileName string
 )
 
 var rootCmd = &cobra.Command{
@@ -37,10 +39,14 @@ func Execute() {
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "l", "info", "Log level (debug, info, warn, error, fatal)")
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "etc/device.yaml", "Path to config file")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", profile.Default(), "Config profile overlay merged over the config file, e.g. staging or prod")
 
 	rootCmd.AddCommand(serveCommand)
 	rootCmd.AddCommand(jobCmd)
 	rootCmd.AddCommand(registerCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(simulateCmd)
 }
 
 func main() {
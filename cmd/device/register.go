@@ -19,6 +19,7 @@ import (
 const (
 	deviceRegisterPath   = "/api/v1/device/register"
 	deviceUnregisterPath = "/api/v1/device/unregister"
+	rotateTokenPath      = "/api/v1/device/rotate-token"
 )
 
 var (
@@ -62,6 +63,15 @@ var unregisterCmd = &cobra.Command{
 	},
 }
 
+var rotateTokenCmd = &cobra.Command{
+	Use:   "rotate-token",
+	Short: "Rotate the device token",
+	Long:  `Replace the device token with a new one issued by the server, the old token stops working once the new one is used`,
+	Run: func(cmd *cobra.Command, args []string) {
+		rotateToken()
+	},
+}
+
 var requestRegisterCmd = &cobra.Command{
 	Use:   "request <access-token>",
 	Short: "Request register from server",
@@ -82,10 +92,13 @@ func init() {
 	requestRegisterCmd.Flags().StringVar(&deviceName, "name", deviceName, "device name")
 	registerCmd.AddCommand(requestRegisterCmd)
 	registerCmd.AddCommand(unregisterCmd)
+
+	rotateTokenCmd.Flags().StringVar(&serverAddr, "server", serverAddr, "server address")
+	registerCmd.AddCommand(rotateTokenCmd)
 }
 
 func getDeviceInfo() (*metadata.DeviceInfo, error) {
-	conf, err := config.LoadConfig(configFile)
+	conf, err := config.LoadConfig(configFile, profileName)
 	if err != nil {
 		return nil, err
 	}
@@ -128,7 +141,7 @@ func showRegisterInfo() {
 }
 
 func setDeviceInfo(info *metadata.DeviceInfo) error {
-	conf, err := config.LoadConfig(configFile)
+	conf, err := config.LoadConfig(configFile, profileName)
 	if err != nil {
 		logrus.Fatal("initConfig error, ", err.Error())
 	}
@@ -252,3 +265,43 @@ func unregisterDevice() {
 		return
 	}
 }
+
+func rotateToken() {
+	info, err := getDeviceInfo()
+	if err != nil {
+		logrus.WithError(err).Fatalf("get device info")
+		return
+	}
+	if info == nil || info.Token == nil || *info.Token == "" {
+		logrus.Fatalf("device is not registered")
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, serverAddr+rotateTokenPath, nil)
+	if err != nil {
+		logrus.WithError(err).Fatalf("new request")
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+*info.Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logrus.WithError(err).Fatalf("rotate token from server")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logrus.Fatalf("rotate token from server, status code: %d", resp.StatusCode)
+		return
+	}
+	var respBody dao.RotateDeviceTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		logrus.WithError(err).Fatalf("decode rotate token response")
+		return
+	}
+
+	if err := setDeviceInfo(&metadata.DeviceInfo{Token: &respBody.Token}); err != nil {
+		logrus.WithError(err).Fatalf("set device info")
+		return
+	}
+	logrus.Infof("rotate token of device %s success", *info.Uuid)
+}
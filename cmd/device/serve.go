@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,6 +11,7 @@ import (
 
 	"lumina/internal/device"
 	"lumina/internal/device/config"
+	"lumina/pkg/tracing"
 )
 
 var serveCommand = &cobra.Command{
@@ -24,13 +26,24 @@ func init() {
 }
 
 func runServe() {
-	conf, err := config.LoadConfig(configFile)
+	conf, err := config.LoadConfig(configFile, profileName)
 	if err != nil {
 		logrus.Fatal("initConfig error, ", err.Error())
 	}
 
 	logrus.Infof("config: %+v", conf)
 
+	shutdownTracing, err := tracing.Init(context.Background(), "lumina-device", conf.Tracing)
+	if err != nil {
+		logrus.WithError(err).Fatalf("init tracing")
+	}
+	// flushes the spans left, restart does not return
+	flushTracing := func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logrus.WithError(err).Warn("shutdown tracing")
+		}
+	}
+
 	device, err := device.NewDevice(conf)
 	if err != nil {
 		logrus.WithError(err).Fatalf("new device")
@@ -41,7 +54,27 @@ func runServe() {
 	termChan := make(chan os.Signal, 1)
 	signal.Notify(termChan, syscall.SIGINT, syscall.SIGTERM)
 
-	<-termChan
-	logrus.Infof("device is shutting down...")
-	device.Stop()
+	select {
+	case <-termChan:
+		logrus.Infof("device is shutting down...")
+		device.Stop()
+		flushTracing()
+	case <-device.RestartRequested():
+		logrus.Infof("device is restarting after upgrade...")
+		device.Stop()
+		flushTracing()
+		restart()
+	}
+}
+
+// restart replaces the process by the upgraded binary with the same
+// arguments and environment.
+func restart() {
+	exe, err := os.Executable()
+	if err != nil {
+		logrus.WithError(err).Fatal("restart device")
+	}
+	if err := syscall.Exec(exe, os.Args, os.Environ()); err != nil {
+		logrus.WithError(err).Fatal("restart device")
+	}
 }
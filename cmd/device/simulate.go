@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"lumina/internal/device/simulator"
+)
+
+var (
+	simulateCameras   int
+	simulateListen    string
+	simulateHost      string
+	simulateTranscode bool
+)
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate <video file or directory>...",
+	Short: "Serve video files as virtual RTSP cameras",
+	Long: `Loop local video files in real time as virtual RTSP cameras, the cameras take the files in turn,
+so that a single machine can exercise the job scheduling, the uploads and the consumer without physical cameras.
+Add the printed urls as cameras and run jobs on them.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runSimulate(args)
+	},
+}
+
+func init() {
+	simulateCmd.Flags().IntVarP(&simulateCameras, "cameras", "n", 4, "Number of virtual cameras")
+	simulateCmd.Flags().StringVar(&simulateListen, "listen", ":8554", "Address of the RTSP server")
+	simulateCmd.Flags().StringVar(&simulateHost, "host", "127.0.0.1", "Host of the printed camera urls")
+	simulateCmd.Flags().BoolVar(&simulateTranscode, "transcode", false, "Re-encode the files to H.264 even if they already are")
+}
+
+func runSimulate(paths []string) {
+	sources, err := simulator.ExpandSources(paths)
+	if err != nil {
+		logrus.WithError(err).Fatal("invalid source")
+	}
+
+	sim, err := simulator.New(logrus.NewEntry(logrus.StandardLogger()), simulator.Options{
+		Sources:   sources,
+		Cameras:   simulateCameras,
+		Listen:    simulateListen,
+		Transcode: simulateTranscode,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("new simulator")
+	}
+
+	srcs := sim.Sources()
+	for i, u := range sim.Urls(simulateHost) {
+		fmt.Fprintf(os.Stdout, "%s\t%s\n", u, srcs[i])
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := sim.Run(ctx); err != nil {
+		logrus.WithError(err).Fatal("run simulator")
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"lumina/internal/device"
+	"lumina/internal/device/config"
+)
+
+// a running device refreshes its status file every few seconds
+const statusStaleAfter = 30 * time.Second
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show device status",
+	Long:  `Show registration state, executor states, last sync/report times and spool size`,
+	Run: func(cmd *cobra.Command, args []string) {
+		showStatus()
+	},
+}
+
+func showStatus() {
+	conf, err := config.LoadConfig(configFile, profileName)
+	if err != nil {
+		logrus.Fatal("initConfig error, ", err.Error())
+	}
+
+	status, err := device.ReadRuntimeStatus(conf)
+	if err != nil {
+		logrus.WithError(err).Fatalf("read runtime status")
+		return
+	}
+	running := status != nil && time.Since(status.UpdateTime) < statusStaleAfter
+
+	fmt.Println("Registration:")
+	if running {
+		printRegistration(status.DeviceUuid, status.RegisterTime)
+	} else {
+		// the metadata DB is locked by a running device, only read it when stopped
+		info, err := getDeviceInfo()
+		if err != nil {
+			fmt.Printf("  unknown (%v)\n", err)
+		} else if info == nil {
+			printRegistration("", "")
+		} else {
+			printRegistration(strValue(info.Uuid), strValue(info.RegisterTime))
+		}
+	}
+
+	fmt.Println("Daemon:")
+	switch {
+	case status == nil:
+		fmt.Println("  not started")
+	case !running:
+		fmt.Printf("  not running (last seen %s)\n", formatTime(status.UpdateTime))
+	default:
+		fmt.Printf("  running, pid %d, up since %s\n", status.Pid, formatTime(status.StartTime))
+	}
+
+	if status != nil {
+		fmt.Printf("  last sync:   %s%s\n", formatTime(status.LastSyncTime), formatError(status.LastSyncError))
+		fmt.Printf("  last report: %s%s\n", formatTime(status.LastReportTime), formatError(status.LastReportError))
+
+		fmt.Println("Executors:")
+		if len(status.Executors) == 0 {
+			fmt.Println("  none")
+		}
+		for _, e := range status.Executors {
+			fmt.Printf("  %s  %-14s %-9s restarts: %d%s\n", e.JobUuid, e.Kind, e.Status, e.Restarts, formatHealth(e.Health))
+		}
+	}
+
+	files, size, err := spoolSize(conf)
+	fmt.Println("Spool:")
+	if err != nil {
+		fmt.Printf("  unknown (%v)\n", err)
+	} else {
+		fmt.Printf("  %d pending results, %s in %s\n", files, formatBytes(size), conf.JobDir())
+	}
+	if running && status.DiskUsage != nil {
+		fmt.Printf("  %s free of %s\n", formatBytes(status.DiskUsage.FreeBytes), formatBytes(status.DiskUsage.TotalBytes))
+	}
+}
+
+func printRegistration(uuid, registerTime string) {
+	if uuid == "" || registerTime == "" {
+		fmt.Println("  not registered")
+		return
+	}
+	fmt.Printf("  registered as %s at %s\n", uuid, registerTime)
+}
+
+// spoolSize counts the results waiting to be uploaded and the bytes they take.
+func spoolSize(conf *config.Config) (int, int64, error) {
+	var files int
+	var size int64
+	err := filepath.WalkDir(conf.JobDir(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), ".json") {
+			files++
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return files, size, err
+}
+
+func strValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return fmt.Sprintf("%s (%s ago)", t.Format(time.RFC3339), time.Since(t).Truncate(time.Second))
+}
+
+func formatError(err string) string {
+	if err == "" {
+		return ""
+	}
+	return ", error: " + err
+}
+
+func formatHealth(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return ", unhealthy: " + reason
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"lumina/internal/consumer"
+	"lumina/internal/model"
+	"lumina/internal/server"
+)
+
+var (
+	backfillFrom      string
+	backfillTo        string
+	backfillBatchSize int
+)
+
+var backfillInfluxCmd = &cobra.Command{
+	Use:   "backfill-influx",
+	Short: "Replay historical messages into InfluxDB",
+	Long: `Replay the messages of the database taken in [--from, --to) into the InfluxDB
+message, alert and detection measurements, so that the trend charts cover the history
+from before InfluxDB was enabled. Replaying a range twice is harmless. The times
+are RFC3339 or 2006-01-02 dates in local time.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runBackfillInflux()
+	},
+}
+
+func init() {
+	backfillInfluxCmd.Flags().StringVar(&backfillFrom, "from", "", "Replay the messages taken from this time (required)")
+	backfillInfluxCmd.Flags().StringVar(&backfillTo, "to", "", "Replay the messages taken before this time, now if empty")
+	backfillInfluxCmd.Flags().IntVar(&backfillBatchSize, "batch-size", 500, "Number of messages written at once")
+	backfillInfluxCmd.MarkFlagRequired("from")
+
+	toolsCmd.AddCommand(backfillInfluxCmd)
+}
+
+func parseBackfillTime(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func runBackfillInflux() {
+	conf, err := server.LoadConfig(configFile, profileName)
+	if err != nil {
+		logrus.Fatal("initConfig error, ", err.Error())
+	}
+	if !conf.InfluxDB.Enabled {
+		logrus.Fatal("influxdb is not enabled in the config")
+	}
+
+	opts := consumer.BackfillOptions{
+		To:        time.Now(),
+		BatchSize: backfillBatchSize,
+	}
+	if opts.From, err = parseBackfillTime(backfillFrom); err != nil {
+		logrus.WithError(err).Fatal("invalid --from")
+	}
+	if backfillTo != "" {
+		if opts.To, err = parseBackfillTime(backfillTo); err != nil {
+			logrus.WithError(err).Fatal("invalid --to")
+		}
+	}
+	if !opts.From.Before(opts.To) {
+		logrus.Fatal("--from must be before --to")
+	}
+
+	db, err := model.InitDB(conf.DB)
+	if err != nil {
+		logrus.Fatal("failed to init database", err)
+	}
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	start := time.Now()
+	last := start
+	opts.Progress = func(done, total int64) {
+		if time.Since(last) < 5*time.Second && done < total {
+			return
+		}
+		last = time.Now()
+		percent := 100.0
+		if total > 0 {
+			percent = float64(done) * 100 / float64(total)
+		}
+		logrus.Infof("replayed %d/%d messages (%.1f%%) in %s", done, total, percent, time.Since(start).Round(time.Second))
+	}
+
+	logrus.Infof("replaying the messages taken from %s to %s", opts.From.Format(time.RFC3339), opts.To.Format(time.RFC3339))
+	done, err := consumer.BackfillInflux(ctx, consumer.InfluxDBConfig(conf.InfluxDB), opts)
+	if err != nil {
+		logrus.WithError(err).Fatalf("backfill stopped after %d messages", done)
+	}
+	logrus.Infof("replayed %d messages in %s", done, time.Since(start).Round(time.Second))
+}
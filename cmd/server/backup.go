@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"lumina/internal/backup"
+	"lumina/internal/model"
+	"lumina/internal/server"
+)
+
+const passphraseEnv = "LUMINA_BACKUP_PASSPHRASE"
+
+var (
+	backupDir      string
+	passphraseFile string
+	minioPrefixes  []string
+	backupKeep     int
+	backupMaxAge   time.Duration
+	restoreForce   bool
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Backup the database",
+	Long: `Dump all tables from a consistent snapshot into a compressed archive, optionally
+encrypted and with a manifest of selected MinIO prefixes, then prune old backups.
+The passphrase is read from --passphrase-file or the ` + passphraseEnv + ` environment variable.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runBackup()
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <backup file>",
+	Short: "Restore the database from a backup",
+	Long:  `Restore all tables from an archive created by the backup command`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runRestore(args[0])
+	},
+}
+
+func init() {
+	backupCmd.Flags().StringVarP(&backupDir, "dir", "d", "backups", "Directory to write backups to")
+	backupCmd.Flags().StringVar(&passphraseFile, "passphrase-file", "", "File containing the encryption passphrase")
+	backupCmd.Flags().StringSliceVar(&minioPrefixes, "minio-prefix", nil, "MinIO prefix to list in the backup manifest, repeatable")
+	backupCmd.Flags().IntVar(&backupKeep, "keep", 7, "Number of backups to keep, 0 keeps all")
+	backupCmd.Flags().DurationVar(&backupMaxAge, "max-age", 0, "Remove backups older than this, 0 disables")
+
+	restoreCmd.Flags().StringVar(&passphraseFile, "passphrase-file", "", "File containing the encryption passphrase")
+	restoreCmd.Flags().BoolVar(&restoreForce, "force", false, "Overwrite existing data")
+}
+
+func readPassphrase() string {
+	if passphraseFile != "" {
+		data, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			logrus.WithError(err).Fatalf("read passphrase file")
+		}
+		return strings.TrimSpace(string(data))
+	}
+	return os.Getenv(passphraseEnv)
+}
+
+func runBackup() {
+	conf, err := server.LoadConfig(configFile, profileName)
+	if err != nil {
+		logrus.Fatal("initConfig error, ", err.Error())
+	}
+
+	db, err := model.InitDB(conf.DB)
+	if err != nil {
+		logrus.Fatal("failed to init database", err)
+	}
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	opts := backup.Options{
+		Passphrase: readPassphrase(),
+		Bucket:     conf.S3.Bucket,
+		Prefixes:   minioPrefixes,
+	}
+	if len(minioPrefixes) > 0 {
+		opts.MinioCli, err = minio.New(conf.S3.Endpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(conf.S3.AccessKeyID, conf.S3.SecretAccessKey, ""),
+			Secure: conf.S3.UseSSL,
+			Region: conf.S3.Region,
+		})
+		if err != nil {
+			logrus.WithError(err).Fatalf("create minio client")
+		}
+	}
+
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		logrus.WithError(err).Fatalf("create backup dir")
+	}
+	path := filepath.Join(backupDir, backup.FileName(time.Now(), opts.Passphrase != ""))
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		logrus.WithError(err).Fatalf("create backup file")
+	}
+
+	manifest, err := backup.Dump(context.Background(), db, f, opts)
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		logrus.WithError(err).Fatalf("backup database")
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		logrus.WithError(err).Fatalf("rename backup file")
+	}
+
+	for _, t := range manifest.Tables {
+		logrus.Infof("table %s: %d rows", t.Name, t.Rows)
+	}
+	for _, p := range manifest.Prefixes {
+		logrus.Infof("prefix %s: %d objects, %d bytes", p.Prefix, p.Objects, p.Bytes)
+	}
+	if opts.Passphrase == "" {
+		logrus.Warnf("backup is not encrypted, set %s or --passphrase-file to encrypt it", passphraseEnv)
+	}
+	logrus.Infof("backup written to %s", path)
+
+	removed, err := backup.Prune(backupDir, backupKeep, backupMaxAge)
+	for _, p := range removed {
+		logrus.Infof("removed old backup %s", p)
+	}
+	if err != nil {
+		logrus.WithError(err).Fatalf("prune old backups")
+	}
+}
+
+func runRestore(path string) {
+	conf, err := server.LoadConfig(configFile, profileName)
+	if err != nil {
+		logrus.Fatal("initConfig error, ", err.Error())
+	}
+
+	db, err := model.InitDB(conf.DB)
+	if err != nil {
+		logrus.Fatal("failed to init database", err)
+	}
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	f, err := os.Open(path)
+	if err != nil {
+		logrus.WithError(err).Fatalf("open backup file")
+	}
+	defer f.Close()
+
+	manifest, err := backup.Restore(context.Background(), db, f, readPassphrase(), restoreForce)
+	if err != nil {
+		logrus.WithError(err).Fatalf("restore database")
+	}
+	for _, t := range manifest.Tables {
+		logrus.Infof("table %s: %d rows", t.Name, t.Rows)
+	}
+	if len(manifest.Prefixes) > 0 {
+		fmt.Printf("the backup lists objects of bucket %s, they are not restored:\n", manifest.Bucket)
+		for _, p := range manifest.Prefixes {
+			fmt.Printf("  %s: %d objects, %d bytes\n", p.Prefix, p.Objects, p.Bytes)
+		}
+	}
+	logrus.Infof("restore from %s created at %s success", path, manifest.CreateTime.Format(time.RFC3339))
+}
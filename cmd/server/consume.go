@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,6 +11,7 @@ import (
 
 	"lumina/internal/consumer"
 	"lumina/internal/model"
+	"lumina/pkg/tracing"
 )
 
 var consumeCmd = &cobra.Command{
@@ -17,11 +19,21 @@ var consumeCmd = &cobra.Command{
 	Short: "Consume messages from NSQ",
 	Long:  `Consume messages from NSQ`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := consumer.LoadConfig(configFile)
+		conf, err := consumer.LoadConfig(configFile, profileName)
 		if err != nil {
 			logrus.Fatal("initConfig error, ", err.Error())
 		}
 
+		shutdownTracing, err := tracing.Init(context.Background(), "lumina-consumer", conf.Tracing)
+		if err != nil {
+			logrus.Fatal("failed to init tracing", err)
+		}
+		defer func() {
+			if err := shutdownTracing(context.Background()); err != nil {
+				logrus.WithError(err).Warn("shutdown tracing")
+			}
+		}()
+
 		db, err := model.InitDB(conf.DB)
 		if err != nil {
 			logrus.Fatal("failed to init database", err)
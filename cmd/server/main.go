@@ -7,11 +7,13 @@ import (
 
 	"lumina/internal/version"
 	"lumina/pkg/log"
+	"lumina/pkg/profile"
 )
 
 var (
-	logLevel   string
-	configFile string
+	logLevel    string
+	configFile  string
+	profileName string
 )
 
 var rootCmd = &cobra.Command{
@@ -37,12 +39,16 @@ func Execute() {
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "l", "info", "Log level (debug, info, warn, error, fatal)")
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "etc/server.yaml", "Path to config file")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", profile.Default(), "Config profile overlay merged over the config file, e.g. staging or prod")
 
 	rootCmd.AddCommand(serveCommand)
 	rootCmd.AddCommand(updateDBCommand)
+	rootCmd.AddCommand(migrateCmd)
 	rootCmd.AddCommand(toolsCmd)
 	rootCmd.AddCommand(consumeCmd)
 	rootCmd.AddCommand(agentCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
 }
 
 func main() {
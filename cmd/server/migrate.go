@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+
+	"lumina/internal/model"
+	"lumina/internal/server"
+)
+
+var (
+	migrateTo    string
+	migrateSteps int
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate the database schema",
+	Long: `Apply or revert the versioned migrations of the database schema. The server
+refuses to start while some migrations are pending.`,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply the pending migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		withMigrationDB(func(db *gorm.DB) {
+			if err := model.Migrate(db, migrateTo); err != nil {
+				logrus.Fatal("failed to migrate database, ", err)
+			}
+			logrus.Info("database migrated")
+		})
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the last applied migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		withMigrationDB(func(db *gorm.DB) {
+			if err := model.Rollback(db, migrateSteps); err != nil {
+				logrus.Fatal("failed to revert migrations, ", err)
+			}
+			logrus.Info("migrations reverted")
+		})
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List the migrations and whether they are applied",
+	Run: func(cmd *cobra.Command, args []string) {
+		withMigrationDB(func(db *gorm.DB) {
+			statuses, err := model.ListMigrationStatus(db)
+			if err != nil {
+				logrus.Fatal("failed to list migrations, ", err)
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tAPPLIED\tREVERSIBLE\tDESCRIPTION")
+			for _, s := range statuses {
+				applied := "pending"
+				if s.AppliedTime != nil {
+					applied = s.AppliedTime.Format("2006-01-02 15:04:05")
+				}
+				description := s.Description
+				if s.Unknown {
+					description = "(unknown, applied by a newer version)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", s.Id, applied, s.Reversible, description)
+			}
+			w.Flush()
+		})
+	},
+}
+
+func init() {
+	migrateUpCmd.Flags().StringVar(&migrateTo, "to", "", "Apply the migrations up to this id, all if empty")
+	migrateDownCmd.Flags().IntVar(&migrateSteps, "steps", 1, "Number of migrations to revert")
+
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+}
+
+func withMigrationDB(fn func(db *gorm.DB)) {
+	conf, err := server.LoadConfig(configFile, profileName)
+	if err != nil {
+		logrus.Fatal("initConfig error, ", err.Error())
+	}
+
+	db, err := model.InitDB(conf.DB)
+	if err != nil {
+		logrus.Fatal("failed to init database", err)
+	}
+	defer func() {
+		sqlDb, _ := db.DB()
+		sqlDb.Close()
+	}()
+
+	fn(db)
+}
@@ -11,6 +11,7 @@ import (
 
 	"lumina/internal/model"
 	"lumina/internal/server"
+	"lumina/pkg/tracing"
 )
 
 var serveCommand = &cobra.Command{
@@ -25,12 +26,22 @@ func init() {
 }
 
 func runServe() {
-	conf, err := server.LoadConfig(configFile)
+	conf, err := server.LoadConfig(configFile, profileName)
 	if err != nil {
 		logrus.Fatal("initConfig error, ", err.Error())
 	}
 
-	logrus.Infof("config: %+v", conf)
+	logrus.Infof("config: %s", conf)
+
+	shutdownTracing, err := tracing.Init(context.Background(), "lumina-server", conf.Tracing)
+	if err != nil {
+		logrus.Fatal("failed to init tracing", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logrus.WithError(err).Warn("shutdown tracing")
+		}
+	}()
 
 	db, err := model.InitDB(conf.DB)
 	if err != nil {
@@ -40,6 +51,13 @@ func runServe() {
 		sqlDB, _ := db.DB()
 		sqlDB.Close()
 	}()
+	pending, err := model.PendingMigrations(db)
+	if err != nil {
+		logrus.Fatal("failed to check database migrations", err)
+	}
+	if len(pending) > 0 {
+		logrus.Fatalf("database migrations %v are pending, run lumina-server migrate up", pending)
+	}
 
 	rds, err := model.InitRedis(conf.Redis)
 	if err != nil {
@@ -64,6 +82,13 @@ func runServe() {
 
 	<-termChan
 	logrus.Infof("server is shutting down...")
+	go func() {
+		// a second signal doesn't wait for the in-flight requests
+		<-termChan
+		logrus.Fatal("server forced to shut down")
+	}()
 	srv.Shutdown()
+	// stops the background loops before the database and redis are closed
 	cancelFunc()
+	logrus.Info("server stopped")
 }
@@ -3,44 +3,33 @@ package main
 import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"gorm.io/gorm"
 
 	"lumina/internal/model"
-	"lumina/internal/server"
 )
 
 var insertTestData bool
 
 var updateDBCommand = &cobra.Command{
-	Use:   "updatedb",
-	Short: "Update database tables",
+	Use:        "updatedb",
+	Short:      "Update database tables",
+	Deprecated: `use "migrate up" instead`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := server.LoadConfig(configFile)
-		if err != nil {
-			logrus.Fatal("initConfig error, ", err.Error())
-		}
-
-		db, err := model.InitDB(conf.DB)
-		if err != nil {
-			logrus.Fatal("failed to init database", err)
-		}
-		defer func() {
-			sqlDb, _ := db.DB()
-			sqlDb.Close()
-		}()
-
-		err = model.AutoMigrate(db)
-		if err != nil {
-			logrus.Fatal("failed to auto migrate database", err)
-		} else {
-			logrus.Infof("Database tables update successfully")
-		}
-
-		if insertTestData {
-			err = model.InsertTestData(db)
+		withMigrationDB(func(db *gorm.DB) {
+			err := model.Migrate(db, "")
 			if err != nil {
-				logrus.Fatal("failed to insert test data", err)
+				logrus.Fatal("failed to migrate database", err)
+			} else {
+				logrus.Infof("Database tables update successfully")
+			}
+
+			if insertTestData {
+				err = model.InsertTestData(db)
+				if err != nil {
+					logrus.Fatal("failed to insert test data", err)
+				}
 			}
-		}
+		})
 	},
 }
 
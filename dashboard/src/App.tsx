@@ -5,13 +5,14 @@ import zhCN from 'antd/locale/zh_CN';
 import MainLayout from './components/layout/MainLayout';
 import { JobList, JobDetail } from './pages/job';
 import { MessageList, MessageDetail } from './pages/message';
-import { DeviceList, DeviceDetail } from './pages/device';
+import { DeviceList, DeviceDetail, DeviceGroupList } from './pages/device';
 import { WorkflowList, WorkflowDetail } from './pages/workflow';
 import { AccessTokenList, AccessTokenDetail } from './pages/access-token';
 import { UserList } from './pages/user';
 import { CameraList, CameraDetail, CameraForm } from './pages/camera';
 import JobFormPage from './pages/job/JobFormPage';
 import AgentPage from './pages/agent/AgentPage';
+import { HealthEventList } from './pages/health';
 
 function App() {
   return (
@@ -34,6 +35,7 @@ function App() {
             {/* 设备路由 */}
             <Route path="devices" element={<DeviceList />} />
             <Route path="devices/:id" element={<DeviceDetail />} />
+            <Route path="device-groups" element={<DeviceGroupList />} />
 
             {/* 工作流路由 */}
             <Route path="workflows" element={<WorkflowList />} />
@@ -52,6 +54,9 @@ function App() {
             {/* 用户路由 */}
             <Route path="users" element={<UserList />} />
 
+            {/* 系统健康 */}
+            <Route path="health-events" element={<HealthEventList />} />
+
             {/* 智能助手 */}
             <Route path="agent" element={<AgentPage />} />
           </Route>
@@ -0,0 +1,91 @@
+import React, { useEffect, useState } from 'react';
+import { Button, Select, Space, message } from 'antd';
+import {
+  ArrowUpOutlined,
+  ArrowDownOutlined,
+  ArrowLeftOutlined,
+  ArrowRightOutlined,
+  ZoomInOutlined,
+  ZoomOutOutlined,
+} from '@ant-design/icons';
+import { cameraApi } from '../services/api';
+import type { PtzPreset } from '../types';
+
+interface PtzControlProps {
+  cameraId: number;
+}
+
+const PTZ_SPEED = 0.5;
+
+// 云台控制，按住方向键持续移动，松开停止
+const PtzControl: React.FC<PtzControlProps> = ({ cameraId }) => {
+  const [presets, setPresets] = useState<PtzPreset[]>([]);
+
+  useEffect(() => {
+    cameraApi
+      .ptzPresets(cameraId)
+      .then((resp) => setPresets(resp.presets || []))
+      .catch(() => setPresets([]));
+  }, [cameraId]);
+
+  const move = async (pan: number, tilt: number, zoom: number) => {
+    try {
+      // 超时作为松开事件丢失时的兜底
+      await cameraApi.ptzMove(cameraId, { pan, tilt, zoom, timeout: 5000 });
+    } catch (error) {
+      message.error('云台控制失败');
+      // eslint-disable-next-line no-console
+      console.error('Error ptz move:', error);
+    }
+  };
+
+  const stop = async () => {
+    try {
+      await cameraApi.ptzStop(cameraId);
+    } catch (error) {
+      // eslint-disable-next-line no-console
+      console.warn('Ptz stop failed:', error);
+    }
+  };
+
+  const gotoPreset = async (token: string) => {
+    try {
+      await cameraApi.ptzGotoPreset(cameraId, token);
+    } catch (error) {
+      message.error('转到预置位失败');
+      // eslint-disable-next-line no-console
+      console.error('Error ptz goto preset:', error);
+    }
+  };
+
+  const holdProps = (pan: number, tilt: number, zoom: number) => ({
+    onMouseDown: () => move(pan, tilt, zoom),
+    onMouseUp: stop,
+    onMouseLeave: (e: React.MouseEvent) => {
+      if (e.buttons === 1) stop();
+    },
+    onTouchStart: () => move(pan, tilt, zoom),
+    onTouchEnd: stop,
+  });
+
+  return (
+    <Space wrap>
+      <Button icon={<ArrowLeftOutlined />} {...holdProps(-PTZ_SPEED, 0, 0)} />
+      <Button icon={<ArrowUpOutlined />} {...holdProps(0, PTZ_SPEED, 0)} />
+      <Button icon={<ArrowDownOutlined />} {...holdProps(0, -PTZ_SPEED, 0)} />
+      <Button icon={<ArrowRightOutlined />} {...holdProps(PTZ_SPEED, 0, 0)} />
+      <Button icon={<ZoomInOutlined />} {...holdProps(0, 0, PTZ_SPEED)} />
+      <Button icon={<ZoomOutOutlined />} {...holdProps(0, 0, -PTZ_SPEED)} />
+      {presets.length > 0 && (
+        <Select
+          placeholder="预置位"
+          style={{ width: 140 }}
+          onChange={gotoPreset}
+          options={presets.map((p) => ({ value: p.token, label: p.name || p.token }))}
+        />
+      )}
+    </Space>
+  );
+};
+
+export default PtzControl;
@@ -0,0 +1,95 @@
+import React from 'react';
+import { Form, Input, Button, Space, Select, Card } from 'antd';
+import { DeleteOutlined } from '@ant-design/icons';
+
+interface ResultFilterFieldsProps {
+  // 表单中过滤条件的字段名
+  name?: string;
+  // 组合逻辑下方的说明
+  extra?: string;
+}
+
+// 结果过滤条件编辑，用于工作流和任务表单
+const ResultFilterFields: React.FC<ResultFilterFieldsProps> = ({ name = 'resultFilter', extra }) => (
+  <>
+    <Form.Item name={[name, "combineOp"]} label="组合逻辑" extra={extra}>
+      <Select placeholder="请选择逻辑">
+        <Select.Option value="and">并且 (AND)</Select.Option>
+        <Select.Option value="or">或者 (OR)</Select.Option>
+      </Select>
+    </Form.Item>
+
+    <Form.List name={[name, "conditions"]}>
+      {(fields, { add, remove }) => (
+        <div>
+          {fields.map((field) => (
+            <Card key={field.key} size="small" style={{ marginBottom: 8 }}>
+              <Space align="center" size="small">
+                <Form.Item
+                  {...field}
+                  name={[field.name, 'field']}
+                  fieldKey={[field.fieldKey!, 'field']}
+                  style={{ marginBottom: 0 }}
+                  tooltip="留空匹配查询结果，可用 match、confidence、reason、labels、hour、time、sensors.<名称>、metadata.<字段>"
+                >
+                  <Input placeholder="字段（可选）" style={{ width: 160 }} />
+                </Form.Item>
+
+                <Form.Item
+                  {...field}
+                  name={[field.name, 'op']}
+                  fieldKey={[field.fieldKey!, 'op']}
+                  rules={[{ required: true, message: '请选择操作符' }]}
+                  style={{ marginBottom: 0 }}
+                >
+                  <Select placeholder="选择操作符" style={{ width: 120 }}>
+                    <Select.Option value="eq">等于</Select.Option>
+                    <Select.Option value="ne">不等于</Select.Option>
+                    <Select.Option value="in">包含于</Select.Option>
+                    <Select.Option value="not_in">不包含于</Select.Option>
+                    <Select.Option value="contains">包含</Select.Option>
+                    <Select.Option value="not_contains">不包含</Select.Option>
+                    <Select.Option value="starts_with">开头为</Select.Option>
+                    <Select.Option value="ends_with">结尾为</Select.Option>
+                    <Select.Option value="empty">为空</Select.Option>
+                    <Select.Option value="not_empty">不为空</Select.Option>
+                    <Select.Option value="gt">大于</Select.Option>
+                    <Select.Option value="ge">大于等于</Select.Option>
+                    <Select.Option value="lt">小于</Select.Option>
+                    <Select.Option value="le">小于等于</Select.Option>
+                    <Select.Option value="between">介于</Select.Option>
+                    <Select.Option value="not_between">不介于</Select.Option>
+                  </Select>
+                </Form.Item>
+
+                <Form.Item
+                  {...field}
+                  name={[field.name, 'value']}
+                  fieldKey={[field.fieldKey!, 'value']}
+                  style={{ marginBottom: 0 }}
+                >
+                  <Input placeholder="匹配值（empty/not_empty 可留空，between 填 最小,最大）" style={{ width: 360 }} />
+                </Form.Item>
+
+                <Button
+                  type="text"
+                  size="small"
+                  danger
+                  icon={<DeleteOutlined />}
+                  onClick={() => remove(field.name)}
+                  title="删除条件"
+                >
+                </Button>
+              </Space>
+            </Card>
+          ))}
+          <Button type="dashed" onClick={() => add()} block>
+            新增条件
+          </Button>
+        </div>
+      )}
+    </Form.List>
+  </>
+);
+
+export default ResultFilterFields;
@@ -0,0 +1,120 @@
+import React, { useEffect, useRef } from 'react';
+
+// 使用 WHEP 协议拉取 WebRTC 流，延迟低于 http-flv
+
+interface WhepPlayerProps {
+  url: string;
+  autoPlay?: boolean;
+  controls?: boolean;
+  muted?: boolean;
+  // 是否同时拉取音频
+  audio?: boolean;
+  style?: React.CSSProperties;
+  onError?: (error?: any) => void;
+  onPlaying?: () => void;
+}
+
+// 等待 ICE 候选收集完成，WHEP 服务端不一定支持 trickle ICE
+const waitIceGathering = (pc: RTCPeerConnection, timeout = 2000): Promise<void> =>
+  new Promise((resolve) => {
+    if (pc.iceGatheringState === 'complete') {
+      resolve();
+      return;
+    }
+    const timer = window.setTimeout(resolve, timeout);
+    pc.addEventListener('icegatheringstatechange', () => {
+      if (pc.iceGatheringState === 'complete') {
+        window.clearTimeout(timer);
+        resolve();
+      }
+    });
+  });
+
+const WhepPlayer: React.FC<WhepPlayerProps> = ({
+  url,
+  autoPlay = true,
+  controls = true,
+  muted = true,
+  audio = false,
+  style,
+  onError,
+  onPlaying,
+}) => {
+  const videoRef = useRef<HTMLVideoElement>(null);
+
+  useEffect(() => {
+    const video = videoRef.current;
+    if (!video) return;
+
+    let closed = false;
+    const pc = new RTCPeerConnection();
+    pc.addTransceiver('video', { direction: 'recvonly' });
+    if (audio) {
+      pc.addTransceiver('audio', { direction: 'recvonly' });
+    }
+    pc.ontrack = (event) => {
+      video.srcObject = event.streams[0] || new MediaStream([event.track]);
+    };
+    pc.onconnectionstatechange = () => {
+      if (!closed && (pc.connectionState === 'failed' || pc.connectionState === 'disconnected')) {
+        onError && onError(pc.connectionState);
+      }
+    };
+
+    const handlePlaying = () => {
+      onPlaying && onPlaying();
+    };
+    video.addEventListener('playing', handlePlaying);
+
+    const connect = async () => {
+      try {
+        const offer = await pc.createOffer();
+        await pc.setLocalDescription(offer);
+        await waitIceGathering(pc);
+        const resp = await fetch(url, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/sdp' },
+          body: pc.localDescription?.sdp,
+        });
+        if (!resp.ok) {
+          throw new Error(`WHEP 请求失败: ${resp.status}`);
+        }
+        const answer = await resp.text();
+        if (closed) return;
+        await pc.setRemoteDescription({ type: 'answer', sdp: answer });
+        if (autoPlay) {
+          video.play().catch(() => {
+            // eslint-disable-next-line no-console
+            console.warn('自动播放失败，用户交互后再播放', url);
+          });
+        }
+      } catch (e) {
+        // eslint-disable-next-line no-console
+        console.error('WHEP 播放失败:', e);
+        if (!closed) {
+          onError && onError(e);
+        }
+      }
+    };
+    connect();
+
+    return () => {
+      closed = true;
+      video.removeEventListener('playing', handlePlaying);
+      video.srcObject = null;
+      pc.close();
+    };
+  }, [url, autoPlay, audio]);
+
+  return (
+    <video
+      ref={videoRef}
+      style={{ width: '100%', height: 'auto', ...style }}
+      controls={controls}
+      muted={muted}
+      playsInline
+    />
+  );
+};
+
+export default WhepPlayer;
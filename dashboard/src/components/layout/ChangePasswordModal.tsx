@@ -0,0 +1,92 @@
+import React, { useState } from 'react';
+import { Modal, Form, Input, message } from 'antd';
+import { userApi } from '../../services/api';
+import { handleApiError } from '../../utils/helpers';
+
+interface ChangePasswordModalProps {
+  open: boolean;
+  onClose: () => void;
+}
+
+interface ChangePasswordValues {
+  oldPassword: string;
+  newPassword: string;
+  confirmPassword: string;
+}
+
+const ChangePasswordModal: React.FC<ChangePasswordModalProps> = ({ open, onClose }) => {
+  const [form] = Form.useForm<ChangePasswordValues>();
+  const [loading, setLoading] = useState(false);
+
+  // 提交修改，服务端校验当前密码
+  const handleOk = async () => {
+    const values = await form.validateFields();
+    setLoading(true);
+    try {
+      await userApi.changePassword({
+        oldPassword: values.oldPassword,
+        newPassword: values.newPassword,
+      });
+      message.success('密码已修改');
+      onClose();
+    } catch (error) {
+      handleApiError(error, '修改密码失败', form);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <Modal
+      title="修改密码"
+      open={open}
+      onOk={handleOk}
+      onCancel={onClose}
+      confirmLoading={loading}
+      okText="保存"
+      cancelText="取消"
+      destroyOnClose
+    >
+      <Form form={form} layout="vertical" preserve={false}>
+        <Form.Item
+          name="oldPassword"
+          label="当前密码"
+          rules={[{ required: true, message: '请输入当前密码' }]}
+        >
+          <Input.Password autoComplete="current-password" />
+        </Form.Item>
+        <Form.Item
+          name="newPassword"
+          label="新密码"
+          rules={[
+            { required: true, message: '请输入新密码' },
+            { min: 8, message: '密码至少 8 位' },
+            { max: 72, message: '密码最多 72 位' },
+          ]}
+        >
+          <Input.Password autoComplete="new-password" />
+        </Form.Item>
+        <Form.Item
+          name="confirmPassword"
+          label="确认新密码"
+          dependencies={['newPassword']}
+          rules={[
+            { required: true, message: '请再次输入新密码' },
+            ({ getFieldValue }) => ({
+              validator(_, value) {
+                if (!value || getFieldValue('newPassword') === value) {
+                  return Promise.resolve();
+                }
+                return Promise.reject(new Error('两次输入的密码不一致'));
+              },
+            }),
+          ]}
+        >
+          <Input.Password autoComplete="new-password" />
+        </Form.Item>
+      </Form>
+    </Modal>
+  );
+};
+
+export default ChangePasswordModal;
@@ -1,5 +1,5 @@
-import React, { useState } from 'react';
-import { Layout, Menu, theme } from 'antd';
+import React, { useEffect, useState } from 'react';
+import { Layout, Menu, Button, theme } from 'antd';
 import {
   UserOutlined,
   DesktopOutlined,
@@ -11,20 +11,47 @@ import {
   MenuUnfoldOutlined,
   RobotOutlined,
   CameraOutlined,
+  ClusterOutlined,
+  AlertOutlined,
+  LockOutlined,
+  SafetyOutlined,
 } from '@ant-design/icons';
 import { useNavigate, useLocation, Outlet } from 'react-router-dom';
+import { userApi } from '../../services/api';
+import { setDisplayTimezone } from '../../utils/helpers';
+import ChangePasswordModal from './ChangePasswordModal';
+import TwoFactorModal from './TwoFactorModal';
 
 const { Header, Sider, Content } = Layout;
 
 const MainLayout: React.FC = () => {
   const [collapsed, setCollapsed] = useState(false);
   const [openKeysState, setOpenKeysState] = useState<string[]>([]);
+  const [displayTz, setDisplayTz] = useState('');
+  const [passwordOpen, setPasswordOpen] = useState(false);
+  const [twoFactorOpen, setTwoFactorOpen] = useState(false);
+  const [totpEnabled, setTotpEnabled] = useState(false);
   const navigate = useNavigate();
   const location = useLocation();
   const {
     token: { colorBgContainer, borderRadiusLG },
   } = theme.useToken();
 
+  // 加载用户的显示时区，页面在时区变化后重新渲染
+  const loadProfile = () => {
+    userApi.getProfile()
+      .then((profile) => {
+        setDisplayTimezone(profile.timezone);
+        setDisplayTz(profile.timezone || '');
+        setTotpEnabled(!!profile.totpEnabled);
+      })
+      .catch(() => {});
+  };
+
+  useEffect(() => {
+    loadProfile();
+  }, []);
+
   // 菜单项配置
   const menuItems = [
     {
@@ -52,6 +79,11 @@ const MainLayout: React.FC = () => {
           icon: <DesktopOutlined />,
           label: '主机管理',
         },
+        {
+          key: '/device-groups',
+          icon: <ClusterOutlined />,
+          label: '设备组',
+        },
         {
           key: '/access-tokens',
           icon: <KeyOutlined />,
@@ -64,6 +96,11 @@ const MainLayout: React.FC = () => {
         },
       ],
     },
+    {
+      key: '/health-events',
+      icon: <AlertOutlined />,
+      label: '系统健康',
+    },
     {
       key: '/users',
       icon: <UserOutlined />,
@@ -167,7 +204,22 @@ const MainLayout: React.FC = () => {
           >
             {collapsed ? <MenuUnfoldOutlined /> : <MenuFoldOutlined />}
           </div>
+          <div style={{ marginLeft: 'auto', padding: '0 24px' }}>
+            <Button type="text" icon={<SafetyOutlined />} onClick={() => setTwoFactorOpen(true)}>
+              两步验证
+            </Button>
+            <Button type="text" icon={<LockOutlined />} onClick={() => setPasswordOpen(true)}>
+              修改密码
+            </Button>
+          </div>
         </Header>
+        <ChangePasswordModal open={passwordOpen} onClose={() => setPasswordOpen(false)} />
+        <TwoFactorModal
+          open={twoFactorOpen}
+          enabled={totpEnabled}
+          onClose={() => setTwoFactorOpen(false)}
+          onChange={loadProfile}
+        />
         <Content
           style={{
             margin: '24px 16px',
@@ -178,7 +230,7 @@ const MainLayout: React.FC = () => {
             overflow: 'auto',
           }}
         >
-          <Outlet />
+          <Outlet key={displayTz} />
         </Content>
       </Layout>
     </Layout>
@@ -0,0 +1,165 @@
+import React, { useEffect, useState } from 'react';
+import { Modal, Form, Input, Button, QRCode, Space, Typography, Alert, message } from 'antd';
+import { userApi } from '../../services/api';
+import { handleApiError } from '../../utils/helpers';
+import type { TotpSetupResponse } from '../../types';
+
+const { Text, Paragraph } = Typography;
+
+interface TwoFactorModalProps {
+  open: boolean;
+  enabled: boolean;
+  onClose: () => void;
+  // 启用或停用后刷新用户信息
+  onChange: () => void;
+}
+
+const TwoFactorModal: React.FC<TwoFactorModalProps> = ({ open, enabled, onClose, onChange }) => {
+  const [form] = Form.useForm();
+  const [loading, setLoading] = useState(false);
+  const [setup, setSetup] = useState<TotpSetupResponse | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
+
+  useEffect(() => {
+    if (!open) {
+      setSetup(null);
+      setRecoveryCodes([]);
+      form.resetFields();
+    }
+  }, [open, form]);
+
+  // 生成密钥，展示二维码
+  const handleSetup = async () => {
+    setLoading(true);
+    try {
+      setSetup(await userApi.setupTotp());
+    } catch (error) {
+      handleApiError(error, '生成密钥失败');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // 用验证码确认启用
+  const handleEnable = async () => {
+    const { code } = await form.validateFields(['code']);
+    setLoading(true);
+    try {
+      const resp = await userApi.enableTotp(code);
+      setRecoveryCodes(resp.recoveryCodes);
+      message.success('两步验证已启用');
+      onChange();
+    } catch (error) {
+      handleApiError(error, '启用失败', form);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // 重新生成恢复码
+  const handleRegenerate = async () => {
+    const { code } = await form.validateFields(['code']);
+    setLoading(true);
+    try {
+      const resp = await userApi.regenerateRecoveryCodes(code);
+      setRecoveryCodes(resp.recoveryCodes);
+      form.resetFields();
+    } catch (error) {
+      handleApiError(error, '生成恢复码失败', form);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // 停用两步验证
+  const handleDisable = async () => {
+    const values = await form.validateFields(['password', 'disableCode']);
+    setLoading(true);
+    try {
+      await userApi.disableTotp({ password: values.password, code: values.disableCode });
+      message.success('两步验证已停用');
+      onChange();
+      onClose();
+    } catch (error) {
+      handleApiError(error, '停用失败', form);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const codeRule = [
+    { required: true, message: '请输入验证码' },
+    { pattern: /^\d{6}$/, message: '验证码为 6 位数字' },
+  ];
+
+  const renderRecoveryCodes = () => (
+    <>
+      <Alert
+        type="warning"
+        showIcon
+        message="请妥善保存以下恢复码，每个只能使用一次，关闭后将无法再次查看"
+        style={{ marginBottom: 16 }}
+      />
+      <Paragraph copyable={{ text: recoveryCodes.join('\n') }}>
+        {recoveryCodes.map((code) => (
+          <div key={code}><Text code>{code}</Text></div>
+        ))}
+      </Paragraph>
+    </>
+  );
+
+  const renderEnroll = () => {
+    if (!setup) {
+      return (
+        <Space direction="vertical">
+          <Text>启用后登录时需要输入验证器应用（如 Google Authenticator）生成的验证码。</Text>
+          <Button type="primary" loading={loading} onClick={handleSetup}>开始设置</Button>
+        </Space>
+      );
+    }
+    return (
+      <Form form={form} layout="vertical">
+        <Space direction="vertical" align="center" style={{ width: '100%', marginBottom: 16 }}>
+          <QRCode value={setup.uri} />
+          <Text type="secondary">无法扫码时手动输入密钥：</Text>
+          <Text code copyable>{setup.secret}</Text>
+        </Space>
+        <Form.Item name="code" label="验证码" rules={codeRule}>
+          <Input maxLength={6} autoComplete="one-time-code" />
+        </Form.Item>
+        <Button type="primary" loading={loading} onClick={handleEnable}>启用</Button>
+      </Form>
+    );
+  };
+
+  const renderManage = () => (
+    <Form form={form} layout="vertical">
+      <Paragraph>两步验证已启用。</Paragraph>
+      <Form.Item name="code" label="验证码" rules={codeRule}>
+        <Input maxLength={6} autoComplete="one-time-code" />
+      </Form.Item>
+      <Button loading={loading} onClick={handleRegenerate} style={{ marginBottom: 24 }}>
+        重新生成恢复码
+      </Button>
+      <Form.Item name="password" label="当前密码" rules={[{ required: true, message: '请输入当前密码' }]}>
+        <Input.Password autoComplete="current-password" />
+      </Form.Item>
+      <Form.Item
+        name="disableCode"
+        label="验证码或恢复码"
+        rules={[{ required: true, message: '请输入验证码或恢复码' }]}
+      >
+        <Input autoComplete="one-time-code" />
+      </Form.Item>
+      <Button danger loading={loading} onClick={handleDisable}>停用两步验证</Button>
+    </Form>
+  );
+
+  return (
+    <Modal title="两步验证" open={open} onCancel={onClose} footer={null} destroyOnClose>
+      {recoveryCodes.length > 0 ? renderRecoveryCodes() : enabled ? renderManage() : renderEnroll()}
+    </Modal>
+  );
+};
+
+export default TwoFactorModal;
@@ -146,6 +146,12 @@ const AccessTokenDetail: React.FC = () => {
               />
             </Space>
           </Descriptions.Item>
+          <Descriptions.Item label="已注册设备">
+            {accessToken.bindCount} / {accessToken.maxBinds}
+          </Descriptions.Item>
+          <Descriptions.Item label="最近注册时间">
+            {formatDate(accessToken.lastBindTime || '') || '-'}
+          </Descriptions.Item>
           <Descriptions.Item label="设备UUID" span={2}>
             <Tag color={accessToken.deviceUuid ? "blue" : "default"}>
               {accessToken.deviceUuid || '未绑定'}
@@ -1,5 +1,5 @@
 import React from 'react';
-import { Form, Input, Button, Space, message, DatePicker } from 'antd';
+import { Form, Input, Button, Space, message, DatePicker, InputNumber } from 'antd';
 import dayjs from 'dayjs';
 import { accessTokenApi } from '../../services/api';
 import type { CreateAccessTokenRequest } from '../../types';
@@ -20,13 +20,14 @@ const AccessTokenForm: React.FC<AccessTokenFormProps> = ({ onSubmit, onCancel })
     try {
       const data: CreateAccessTokenRequest = {
         expireTime: values.expireTime ? values.expireTime.toISOString() : '',
+        maxBinds: values.maxBinds,
       };
 
       await accessTokenApi.create(data);
       message.success('创建接入凭证成功');
       onSubmit();
     } catch (error) {
-      handleApiError(error, '创建接入凭证失败');
+      handleApiError(error, '创建接入凭证失败', form);
     } finally {
       setLoading(false);
     }
@@ -53,6 +54,18 @@ const AccessTokenForm: React.FC<AccessTokenFormProps> = ({ onSubmit, onCancel })
         />
       </Form.Item>
 
+      <Form.Item
+        name="maxBinds"
+        label="可注册设备数"
+        initialValue={1}
+        extra="批量部署时可用一个凭证注册多台设备"
+        rules={[
+          { required: true, message: '请输入可注册设备数' },
+        ]}
+      >
+        <InputNumber min={1} max={10000} precision={0} style={{ width: '100%' }} />
+      </Form.Item>
+
       <Form.Item>
         <Space>
           <Button type="primary" htmlType="submit" loading={loading}>
@@ -127,6 +127,12 @@ const AccessTokenList: React.FC = () => {
       ellipsis: true,
       render: (deviceUuid: string) => deviceUuid || '未绑定',
     },
+    {
+      title: '已注册设备',
+      key: 'binds',
+      width: 120,
+      render: (_, record) => `${record.bindCount} / ${record.maxBinds}`,
+    },
     {
       title: '创建时间',
       dataIndex: 'createTime',
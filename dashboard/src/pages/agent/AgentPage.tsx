@@ -26,6 +26,8 @@ type AgentThought = {
 // 移除自定义 ChatFragment/ChatMessageEx，直接使用 ChatMessageSpec
 
 const DEFAULT_PAGE_SIZE = 50;
+// 回复流中断后最多续传的次数
+const MAX_STREAM_RESUMES = 3;
 
 const AgentPage: React.FC = () => {
   const [conversations, setConversations] = useState<ConversationSpec[]>([]);
@@ -124,8 +126,12 @@ const AgentPage: React.FC = () => {
         throw new Error('网络错误: ' + resp.status);
       }
 
-      const reader = resp.body?.getReader();
-      const decoder = new TextDecoder('utf-8');
+      // 连接中断时用流ID续传，offset 为已处理的完整事件数
+      const streamId = resp.headers.get('X-Stream-Id');
+      let reader = resp.body?.getReader();
+      let decoder = new TextDecoder('utf-8');
+      let offset = 0;
+      let resumes = 0;
       let assistantMsgIndex = -1;
 
       // 解析 SSE：逐行处理 "data: {...}\n"；事件不会有多行
@@ -198,7 +204,21 @@ const AgentPage: React.FC = () => {
       let stopStreaming = false;
       let newlineIndex: number;
       while (reader && !stopStreaming) {
-        const { done, value } = await reader.read();
+        let chunk: ReadableStreamReadResult<Uint8Array>;
+        try {
+          chunk = await reader.read();
+        } catch (e) {
+          if (!streamId || resumes >= MAX_STREAM_RESUMES) throw e;
+          resumes++;
+          const resumed = await conversationApi.resumeStream(selected.uuid, streamId, offset);
+          if (!resumed.ok) throw e;
+          // 丢弃未完成的行，续传从下一个完整事件开始
+          buffer = '';
+          decoder = new TextDecoder('utf-8');
+          reader = resumed.body?.getReader();
+          continue;
+        }
+        const { done, value } = chunk;
         if (done) {
           // 流自然结束（未显式发送 [DONE]），也视为完成
           stopStreaming = true;
@@ -210,6 +230,7 @@ const AgentPage: React.FC = () => {
           const line = buffer.slice(0, newlineIndex).trim();
           buffer = buffer.slice(newlineIndex + 1);
           if (!line.startsWith('data:')) continue;
+          offset++;
           const payload = line.slice(5).trim();
           if (!payload) continue;
           if (payload === '[DONE]') {
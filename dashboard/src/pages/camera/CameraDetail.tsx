@@ -1,12 +1,15 @@
 import React, { useState, useEffect, useRef } from 'react';
-import { Card, Descriptions, Button, Space, message, Modal, Tag, Drawer, Tabs, Spin } from 'antd';
-import { EditOutlined, DeleteOutlined, ArrowLeftOutlined, ReloadOutlined } from '@ant-design/icons';
+import { Card, Descriptions, Button, Space, message, Modal, Tag, Drawer, Tabs, Spin, Select, Image, Table, Empty, Input } from 'antd';
+import { Line } from '@ant-design/plots';
+import { EditOutlined, DeleteOutlined, ArrowLeftOutlined, ReloadOutlined, CameraOutlined, ApiOutlined } from '@ant-design/icons';
 import { useParams, useNavigate, useSearchParams } from 'react-router-dom';
 import { cameraApi } from '../../services/api';
 import { formatDate } from '../../utils/helpers';
-import type { CameraSpec, PreviewTask } from '../../types';
+import type { CameraOccupancyResponse, CameraProbeResult, CameraSnapshotResponse, CameraSpec, PreviewMode, PreviewTask } from '../../types';
 import CameraForm from './CameraForm';
 import FlvPlayer from '../../components/FlvPlayer';
+import WhepPlayer from '../../components/WhepPlayer';
+import PtzControl from '../../components/PtzControl';
 
 const CameraDetail: React.FC = () => {
   const [camera, setCamera] = useState<CameraSpec | null>(null);
@@ -15,6 +18,22 @@ const CameraDetail: React.FC = () => {
   const [activeTab, setActiveTab] = useState<string>('detail');
   const [previewTask, setPreviewTask] = useState<PreviewTask | null>(null);
   const [previewLoading, setPreviewLoading] = useState(false);
+  const [previewMode, setPreviewMode] = useState<PreviewMode>('flv');
+  const [snapshot, setSnapshot] = useState<CameraSnapshotResponse | null>(null);
+  const [snapshotLoading, setSnapshotLoading] = useState(false);
+  const [probeResult, setProbeResult] = useState<CameraProbeResult | null>(null);
+  const [probeLoading, setProbeLoading] = useState(false);
+  const [occupancy, setOccupancy] = useState<CameraOccupancyResponse | null>(null);
+  const [occupancyLoading, setOccupancyLoading] = useState(false);
+  const [occupancyWindow, setOccupancyWindow] = useState('5m');
+  const [heatmapUrl, setHeatmapUrl] = useState<string | null>(null);
+  const [heatmapLoading, setHeatmapLoading] = useState(false);
+  const [heatmapRange, setHeatmapRange] = useState('7d');
+  const [heatmapLabel, setHeatmapLabel] = useState('');
+  const [calibrationOpen, setCalibrationOpen] = useState(false);
+  const [calibrationPoints, setCalibrationPoints] = useState('');
+  const [calibrationUnit, setCalibrationUnit] = useState('m');
+  const [calibrationSaving, setCalibrationSaving] = useState(false);
   const retryTimerRef = useRef<number | null>(null);
   const touchTimerRef = useRef<number | null>(null);
   const { id } = useParams<{ id: string }>();
@@ -45,11 +64,11 @@ const CameraDetail: React.FC = () => {
     fetchCamera();
   };
 
-  const startPreview = async () => {
+  const startPreview = async (mode: PreviewMode = previewMode) => {
     if (!id) return;
     setPreviewLoading(true);
     try {
-      const task = await cameraApi.startPreview(parseInt(id));
+      const task = await cameraApi.startPreview(parseInt(id), mode);
       setPreviewTask(task);
     } catch (error) {
       message.error('开启预览失败');
@@ -76,6 +95,16 @@ const CameraDetail: React.FC = () => {
     retryTimerRef.current = timer;
   };
 
+  const handlePreviewPlaying = () => {
+    // 播放成功，确保没有残留的重试定时器
+    if (retryTimerRef.current) {
+      window.clearTimeout(retryTimerRef.current);
+      retryTimerRef.current = null;
+    }
+    // 播放成功后再开始 touch 定时器
+    startTouchTimer();
+  };
+
   // 播放成功后才开始触摸心跳定时器
   const startTouchTimer = () => {
     if (touchTimerRef.current) return; // 已有定时器不重复设置
@@ -96,6 +125,100 @@ const CameraDetail: React.FC = () => {
     }
   };
 
+  // 获取当前画面快照，无需开启预览
+  const takeSnapshot = async () => {
+    if (!id) return;
+    setSnapshotLoading(true);
+    try {
+      const resp = await cameraApi.snapshot(parseInt(id));
+      setSnapshot(resp);
+    } catch (error) {
+      message.error('获取快照失败');
+      // eslint-disable-next-line no-console
+      console.error('Error taking snapshot:', error);
+    } finally {
+      setSnapshotLoading(false);
+    }
+  };
+
+  // 探测摄像头连通性，用于创建任务前校验配置
+  const probeCamera = async () => {
+    if (!id) return;
+    setProbeLoading(true);
+    try {
+      const resp = await cameraApi.probe(parseInt(id));
+      setProbeResult(resp);
+    } catch (error) {
+      message.error('探测失败');
+      // eslint-disable-next-line no-console
+      console.error('Error probing camera:', error);
+    } finally {
+      setProbeLoading(false);
+    }
+  };
+
+  // 获取区域人数统计
+  const fetchOccupancy = async () => {
+    if (!id) return;
+    setOccupancyLoading(true);
+    try {
+      const resp = await cameraApi.occupancy(parseInt(id), { window: occupancyWindow });
+      setOccupancy(resp);
+    } catch (error) {
+      message.error('获取人数统计失败');
+      // eslint-disable-next-line no-console
+      console.error('Error fetching occupancy:', error);
+    } finally {
+      setOccupancyLoading(false);
+    }
+  };
+
+  // 获取检测热力图
+  const fetchHeatmap = async () => {
+    if (!id) return;
+    setHeatmapLoading(true);
+    try {
+      const blob = await cameraApi.heatmap(parseInt(id), {
+        range: heatmapRange,
+        label: heatmapLabel || undefined,
+      });
+      setHeatmapUrl(URL.createObjectURL(blob));
+    } catch (error: any) {
+      setHeatmapUrl(null);
+      if (error?.response?.status !== 404) {
+        message.error('获取热力图失败');
+        // eslint-disable-next-line no-console
+        console.error('Error fetching heatmap:', error);
+      }
+    } finally {
+      setHeatmapLoading(false);
+    }
+  };
+
+  const clearPreviewTimers = () => {
+    if (touchTimerRef.current) {
+      window.clearInterval(touchTimerRef.current);
+      touchTimerRef.current = null;
+    }
+    if (retryTimerRef.current) {
+      window.clearTimeout(retryTimerRef.current);
+      retryTimerRef.current = null;
+    }
+  };
+
+  // 停止预览，设备在下次同步时停止推流
+  const stopPreview = async (cameraId: string | undefined = id) => {
+    clearPreviewTimers();
+    setPreviewTask(null);
+    if (!cameraId) return;
+    try {
+      await cameraApi.stopPreview(parseInt(cameraId));
+    } catch (error) {
+      // eslint-disable-next-line no-console
+      console.warn('Stop preview failed:', error);
+    }
+  };
+
   useEffect(() => {
     // Tab 切换到预览时启动预览
     if (activeTab === 'preview') {
@@ -103,13 +226,11 @@ const CameraDetail: React.FC = () => {
       // 不在此处启动 touch 定时器，待播放成功后再启动
     }
     return () => {
-      if (touchTimerRef.current) {
-        window.clearInterval(touchTimerRef.current);
-        touchTimerRef.current = null;
-      }
-      if (retryTimerRef.current) {
-        window.clearTimeout(retryTimerRef.current);
-        retryTimerRef.current = null;
+      // 离开预览页时立即停止预览，不再等待任务过期
+      if (activeTab === 'preview') {
+        stopPreview(id);
+      } else {
+        clearPreviewTimers();
       }
     };
     // eslint-disable-next-line react-hooks/exhaustive-deps
@@ -119,6 +240,29 @@ const CameraDetail: React.FC = () => {
     fetchCamera();
   }, [id]);
 
+  useEffect(() => {
+    if (activeTab === 'occupancy') {
+      fetchOccupancy();
+    }
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [activeTab, id, occupancyWindow]);
+
+  useEffect(() => {
+    if (activeTab === 'heatmap') {
+      fetchHeatmap();
+    }
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [activeTab, id, heatmapRange]);
+
+  // 释放上一张热力图
+  useEffect(() => {
+    return () => {
+      if (heatmapUrl) {
+        URL.revokeObjectURL(heatmapUrl);
+      }
+    };
+  }, [heatmapUrl]);
+
   // 根据查询参数激活指定标签页
   useEffect(() => {
     const tab = searchParams.get('tab');
@@ -147,6 +291,61 @@ const CameraDetail: React.FC = () => {
     });
   };
 
+  // 打开地面标定编辑
+  const openCalibration = () => {
+    const points = camera?.calibration?.points ?? [
+      { pixel: [0, 0], world: [0, 0] },
+      { pixel: [0, 0], world: [0, 0] },
+      { pixel: [0, 0], world: [0, 0] },
+      { pixel: [0, 0], world: [0, 0] },
+    ];
+    setCalibrationPoints(JSON.stringify(points, null, 2));
+    setCalibrationUnit(camera?.calibration?.unit || 'm');
+    setCalibrationOpen(true);
+  };
+
+  // 保存地面标定
+  const handleSaveCalibration = async () => {
+    if (!camera) return;
+    let points;
+    try {
+      points = JSON.parse(calibrationPoints);
+    } catch (error) {
+      message.error('标定点不是合法的 JSON');
+      return;
+    }
+    setCalibrationSaving(true);
+    try {
+      const calibration = await cameraApi.setCalibration(camera.id, { points, unit: calibrationUnit });
+      setCamera({ ...camera, calibration });
+      setCalibrationOpen(false);
+      message.success(`标定成功，平均误差 ${calibration.error.toFixed(3)} ${calibration.unit}`);
+    } catch (error: any) {
+      message.error(`标定失败: ${error?.response?.data?.error || error?.message || ''}`);
+    } finally {
+      setCalibrationSaving(false);
+    }
+  };
+
+  // 清除地面标定
+  const handleDeleteCalibration = () => {
+    if (!camera) return;
+    Modal.confirm({
+      title: '确认清除',
+      content: '清除后检测任务将不再输出世界坐标和速度，确定清除地面标定吗？',
+      onOk: async () => {
+        try {
+          await cameraApi.deleteCalibration(camera.id);
+          setCamera({ ...camera, calibration: undefined });
+          message.success('已清除');
+        } catch (error) {
+          message.error('清除失败');
+          console.error('Error deleting calibration:', error);
+        }
+      },
+    });
+  };
+
   const getProtocolColor = (protocol: string) => {
     switch (protocol.toLowerCase()) {
       case 'rtsp':
@@ -213,7 +412,16 @@ const CameraDetail: React.FC = () => {
             key: 'detail',
             label: '详情',
             children: (
-              <Card title="摄像头详情" loading={loading}>
+              <Card title="摄像头详情" loading={loading} extra={
+                <Space>
+                  <Button icon={<ApiOutlined />} onClick={probeCamera} loading={probeLoading}>
+                    连通性探测
+                  </Button>
+                  <Button icon={<CameraOutlined />} onClick={takeSnapshot} loading={snapshotLoading}>
+                    快照
+                  </Button>
+                </Space>
+              }>
                 {camera && (
                   <Descriptions column={2} bordered>
                     <Descriptions.Item label="UUID">{camera.uuid}</Descriptions.Item>
@@ -237,6 +445,26 @@ const CameraDetail: React.FC = () => {
                     <Descriptions.Item label="密码">
                       {camera.password ? '••••••••' : '-'}
                     </Descriptions.Item>
+                    <Descriptions.Item label="标签" span={2}>
+                      {camera.tags && camera.tags.length > 0
+                        ? camera.tags.map((tag) => <Tag key={tag}>{tag}</Tag>)
+                        : '-'}
+                    </Descriptions.Item>
+                    <Descriptions.Item label="地面标定" span={2}>
+                      <Space>
+                        {camera.calibration
+                          ? `${camera.calibration.points.length} 个标定点，平均误差 ${camera.calibration.error.toFixed(3)} ${camera.calibration.unit}`
+                          : '未标定'}
+                        <Button size="small" onClick={openCalibration}>
+                          {camera.calibration ? '修改' : '设置'}
+                        </Button>
+                        {camera.calibration && (
+                          <Button size="small" danger onClick={handleDeleteCalibration}>
+                            清除
+                          </Button>
+                        )}
+                      </Space>
+                    </Descriptions.Item>
                     <Descriptions.Item label="创建时间">
                       {formatDate(camera.createTime)}
                     </Descriptions.Item>
@@ -245,6 +473,46 @@ const CameraDetail: React.FC = () => {
                     </Descriptions.Item>
                   </Descriptions>
                 )}
+                {probeResult && (
+                  <Descriptions column={2} bordered size="small" title="探测结果" style={{ marginTop: 16 }}>
+                    <Descriptions.Item label="连通性">
+                      <Tag color={probeResult.reachable ? 'green' : 'red'}>
+                        {probeResult.reachable ? '可连通' : '不可连通'}
+                      </Tag>
+                    </Descriptions.Item>
+                    <Descriptions.Item label="耗时">{probeResult.duration} ms</Descriptions.Item>
+                    {probeResult.error && (
+                      <Descriptions.Item label="错误" span={2}>{probeResult.error}</Descriptions.Item>
+                    )}
+                    {probeResult.video && (
+                      <>
+                        <Descriptions.Item label="视频编码">
+                          {probeResult.video.codec}{probeResult.video.profile ? ` (${probeResult.video.profile})` : ''}
+                        </Descriptions.Item>
+                        <Descriptions.Item label="分辨率">
+                          {probeResult.video.width}x{probeResult.video.height}
+                        </Descriptions.Item>
+                        <Descriptions.Item label="帧率">{probeResult.video.fps}</Descriptions.Item>
+                        <Descriptions.Item label="码率">
+                          {probeResult.bitrate ? `${Math.round(probeResult.bitrate / 1000)} kbps` : '-'}
+                        </Descriptions.Item>
+                      </>
+                    )}
+                    {probeResult.audio && (
+                      <Descriptions.Item label="音频" span={2}>
+                        {probeResult.audio.codec} {probeResult.audio.sampleRate} Hz, {probeResult.audio.channels} 声道
+                      </Descriptions.Item>
+                    )}
+                  </Descriptions>
+                )}
+                {snapshot && (
+                  <div style={{ marginTop: 16 }}>
+                    <Image src={snapshot.url} style={{ maxWidth: '100%' }} />
+                    <div style={{ color: '#999', marginTop: 8 }}>
+                      拍摄于 {formatDate(snapshot.createTime)}
+                    </div>
+                  </div>
+                )}
               </Card>
             ),
           },
@@ -253,9 +521,26 @@ const CameraDetail: React.FC = () => {
             label: '预览',
             children: (
               <Card title="摄像头预览" extra={
-                <Button onClick={startPreview} loading={previewLoading}>
-                  重新获取播放地址
-                </Button>
+                <Space>
+                  <Select
+                    value={previewMode}
+                    style={{ width: 140 }}
+                    onChange={(mode: PreviewMode) => {
+                      setPreviewMode(mode);
+                      startPreview(mode);
+                    }}
+                    options={[
+                      { value: 'flv', label: 'HTTP-FLV' },
+                      { value: 'webrtc', label: 'WebRTC (低延迟)' },
+                    ]}
+                  />
+                  <Button onClick={() => startPreview()} loading={previewLoading}>
+                    重新获取播放地址
+                  </Button>
+                  <Button onClick={() => stopPreview()} disabled={!previewTask}>
+                    停止预览
+                  </Button>
+                </Space>
               }>
                 {previewLoading && (
                   <div style={{ textAlign: 'center', padding: '24px' }}>
@@ -263,33 +548,172 @@ const CameraDetail: React.FC = () => {
                   </div>
                 )}
                 {!previewLoading && previewTask?.previewAddr ? (
-                  <FlvPlayer
-                    url={previewTask.previewAddr}
-                    onPlaying={() => {
-                      // 播放成功，确保没有残留的重试定时器
-                      if (retryTimerRef.current) {
-                        window.clearTimeout(retryTimerRef.current);
-                        retryTimerRef.current = null;
-                      }
-                      // 播放成功后再开始 touch 定时器
-                      startTouchTimer();
-                    }}
-                    onError={() => {
-                      // 播放错误，计划5秒后重试
-                      scheduleRetry();
-                    }}
-                  />
+                  previewTask.mode === 'webrtc' ? (
+                    <WhepPlayer
+                      url={previewTask.previewAddr}
+                      audio={previewTask.audio}
+                      onPlaying={handlePreviewPlaying}
+                      onError={() => {
+                        // 播放错误，计划5秒后重试
+                        scheduleRetry();
+                      }}
+                    />
+                  ) : (
+                    <FlvPlayer
+                      url={previewTask.previewAddr}
+                      onPlaying={handlePreviewPlaying}
+                      onError={() => {
+                        // 播放错误，计划5秒后重试
+                        scheduleRetry();
+                      }}
+                    />
+                  )
                 ) : (
                   <div style={{ textAlign: 'center', padding: '24px', color: '#999' }}>
                     点击上方按钮开始预览
                   </div>
                 )}
+                {camera?.onvifPort ? (
+                  <div style={{ marginTop: 16 }}>
+                    <PtzControl cameraId={camera.id} />
+                  </div>
+                ) : null}
+              </Card>
+            ),
+          },
+          {
+            key: 'occupancy',
+            label: '人数统计',
+            children: (
+              <Card title="区域人数统计" extra={
+                <Space>
+                  <Select
+                    value={occupancyWindow}
+                    onChange={setOccupancyWindow}
+                    style={{ width: 120 }}
+                    options={[
+                      { value: '1m', label: '1分钟' },
+                      { value: '5m', label: '5分钟' },
+                      { value: '15m', label: '15分钟' },
+                      { value: '1h', label: '1小时' },
+                    ]}
+                  />
+                  <Button icon={<ReloadOutlined />} onClick={fetchOccupancy} loading={occupancyLoading}>
+                    刷新
+                  </Button>
+                </Space>
+              }>
+                {occupancyLoading ? (
+                  <Spin />
+                ) : occupancy && occupancy.zones.length > 0 ? (
+                  <>
+                    <Table
+                      size="small"
+                      rowKey="zone"
+                      pagination={false}
+                      style={{ marginBottom: 16 }}
+                      dataSource={occupancy.zones}
+                      columns={[
+                        { title: '区域', dataIndex: 'zone' },
+                        { title: '当前人数', dataIndex: 'current' },
+                        {
+                          title: '进入人次',
+                          render: (_: any, z: any) => z.points.reduce((sum: number, p: any) => sum + p.in, 0),
+                        },
+                        {
+                          title: '离开人次',
+                          render: (_: any, z: any) => z.points.reduce((sum: number, p: any) => sum + p.out, 0),
+                        },
+                        {
+                          title: '更新时间',
+                          dataIndex: 'updateTime',
+                          render: (t?: string) => (t ? formatDate(t) : '-'),
+                        },
+                      ]}
+                    />
+                    <Line
+                      data={occupancy.zones.flatMap((z) => z.points.map((p) => ({ time: p.time, max: p.max, zone: z.zone })))}
+                      xField="time"
+                      yField="max"
+                      seriesField="zone"
+                      xAxis={{ type: 'time' }}
+                      smooth
+                    />
+                  </>
+                ) : (
+                  <Empty description="暂无数据，请在检测任务中开启区域人数统计" />
+                )}
+              </Card>
+            ),
+          },
+          {
+            key: 'heatmap',
+            label: '热力图',
+            children: (
+              <Card title="检测热力图" extra={
+                <Space>
+                  <Input
+                    placeholder="标签，如 person"
+                    allowClear
+                    style={{ width: 160 }}
+                    value={heatmapLabel}
+                    onChange={(e) => setHeatmapLabel(e.target.value)}
+                    onPressEnter={fetchHeatmap}
+                  />
+                  <Select
+                    value={heatmapRange}
+                    onChange={setHeatmapRange}
+                    style={{ width: 120 }}
+                    options={[
+                      { value: '24h', label: '最近24小时' },
+                      { value: '7d', label: '最近7天' },
+                      { value: '30d', label: '最近30天' },
+                    ]}
+                  />
+                  <Button icon={<ReloadOutlined />} onClick={fetchHeatmap} loading={heatmapLoading}>
+                    刷新
+                  </Button>
+                </Space>
+              }>
+                {heatmapLoading ? (
+                  <Spin />
+                ) : heatmapUrl ? (
+                  <Image src={heatmapUrl} alt="检测热力图" style={{ maxWidth: '100%' }} />
+                ) : (
+                  <Empty description="所选时间范围内暂无检测结果" />
+                )}
               </Card>
             ),
           },
         ]}
       />
 
+      <Modal
+        title="地面标定"
+        open={calibrationOpen}
+        onOk={handleSaveCalibration}
+        onCancel={() => setCalibrationOpen(false)}
+        confirmLoading={calibrationSaving}
+        width={600}
+      >
+        <p style={{ color: '#999' }}>
+          至少填写 4 个不共线的点：pixel 为图像中的像素坐标，world 为该点在地面上的实际坐标。
+          标定后检测任务会输出检测框的世界坐标（metadata.world）和估算速度（metadata.max_speed），可在结果过滤中使用。
+        </p>
+        <Input
+          addonBefore="单位"
+          value={calibrationUnit}
+          onChange={(e) => setCalibrationUnit(e.target.value)}
+          style={{ marginBottom: 12 }}
+        />
+        <Input.TextArea
+          rows={14}
+          value={calibrationPoints}
+          onChange={(e) => setCalibrationPoints(e.target.value)}
+          style={{ fontFamily: 'monospace' }}
+        />
+      </Modal>
+
       <Drawer
         title="编辑摄像头"
         width={600}
@@ -1,9 +1,11 @@
 import React, { useState, useEffect } from 'react';
-import { Form, Input, Select, InputNumber, Button, Card, message, Space } from 'antd';
-import { ArrowLeftOutlined, SaveOutlined } from '@ant-design/icons';
+import { Form, Input, Select, InputNumber, Button, Card, message, Space, Switch } from 'antd';
+import { ArrowLeftOutlined, SaveOutlined, SearchOutlined } from '@ant-design/icons';
 import { useParams, useNavigate } from 'react-router-dom';
 import { cameraApi, deviceApi } from '../../services/api';
 import type { CameraSpec, CreateCameraRequest, UpdateCameraRequest, DeviceSpec } from '../../types';
+import { applyFieldErrors } from '../../utils/helpers';
+import OnvifDiscover from './OnvifDiscover';
 
 const { Option } = Select;
 
@@ -21,6 +23,8 @@ const CameraForm: React.FC<CameraFormProps> = ({ mode = 'page', cameraId, onSubm
   const [loading, setLoading] = useState(false);
   const [camera, setCamera] = useState<CameraSpec | null>(null);
   const [devices, setDevices] = useState<DeviceSpec[]>([]);
+  const [discoverOpen, setDiscoverOpen] = useState(false);
+  const [tagOptions, setTagOptions] = useState<string[]>([]);
   const navigate = useNavigate();
   const params = useParams<{ id: string }>();
 
@@ -43,6 +47,9 @@ const CameraForm: React.FC<CameraFormProps> = ({ mode = 'page', cameraId, onSubm
         username: response.username,
         password: response.password,
         bindDeviceId: response.bindDevice?.id,
+        previewAudio: response.previewAudio,
+        onvifPort: response.onvifPort,
+        tags: response.tags,
       });
     } catch (error) {
       message.error('获取摄像头信息失败');
@@ -69,8 +76,20 @@ const CameraForm: React.FC<CameraFormProps> = ({ mode = 'page', cameraId, onSubm
     }
   };
 
+  // 获取已有标签
+  const fetchTags = async () => {
+    try {
+      const resp = await cameraApi.tags();
+      setTagOptions(resp.tags || []);
+    } catch (error) {
+      // eslint-disable-next-line no-console
+      console.error('获取标签列表失败:', error);
+    }
+  };
+
   useEffect(() => {
     fetchDevices();
+    fetchTags();
   }, []);
 
   const handleFinish = async (values: any) => {
@@ -86,6 +105,9 @@ const CameraForm: React.FC<CameraFormProps> = ({ mode = 'page', cameraId, onSubm
           username: values.username,
           password: values.password,
           bindDeviceId: values.bindDeviceId,
+          previewAudio: values.previewAudio,
+          onvifPort: values.onvifPort || 0,
+          tags: values.tags || [],
         };
         await cameraApi.update(camera.id, updateData);
         message.success('更新成功');
@@ -99,6 +121,9 @@ const CameraForm: React.FC<CameraFormProps> = ({ mode = 'page', cameraId, onSubm
           username: values.username,
           password: values.password,
           bindDeviceId: values.bindDeviceId,
+          previewAudio: values.previewAudio,
+          onvifPort: values.onvifPort || 0,
+          tags: values.tags || [],
         };
         await cameraApi.create(createData);
         message.success('创建成功');
@@ -110,7 +135,11 @@ const CameraForm: React.FC<CameraFormProps> = ({ mode = 'page', cameraId, onSubm
         navigate('/camera');
       }
     } catch (error) {
-      message.error(isEdit ? '更新失败' : '创建失败');
+      if (applyFieldErrors(form, error)) {
+        message.error(`${isEdit ? '更新失败' : '创建失败'}: 请检查标出的字段`);
+      } else {
+        message.error(isEdit ? '更新失败' : '创建失败');
+      }
       // eslint-disable-next-line no-console
       console.error('Error saving camera:', error);
     } finally {
@@ -118,88 +147,125 @@ const CameraForm: React.FC<CameraFormProps> = ({ mode = 'page', cameraId, onSubm
     }
   };
 
+  // 使用扫描结果预填表单
+  const handleDiscoverSelect = (data: CreateCameraRequest) => {
+    form.setFieldsValue(data);
+    setDiscoverOpen(false);
+  };
+
   const formNode = (
-    <Form
-      form={form}
-      layout="vertical"
-      onFinish={handleFinish}
-      initialValues={{
-        protocol: 'rtsp',
-        port: 554,
-      }}
-    >
-      <Form.Item label="名称" name="name" rules={[{ required: true, message: '请输入摄像头名称' }]}>
-        <Input placeholder="请输入摄像头名称" />
-      </Form.Item>
-
-      <Form.Item label="协议" name="protocol" rules={[{ required: true, message: '请选择协议' }]}>
-        <Select placeholder="请选择协议">
-          <Option value="rtsp">RTSP</Option>
-          <Option value="rtmp">RTMP</Option>
-        </Select>
-      </Form.Item>
-
-      <Form.Item
-        label="IP地址"
-        name="ip"
-        rules={[
-          { required: true, message: '请输入IP地址' },
-          {
-            pattern:
-              /^(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)$/,
-            message: '请输入有效的IP地址',
-          },
-        ]}
-      >
-        <Input placeholder="请输入IP地址，如：192.168.1.100" />
-      </Form.Item>
-
-      <Form.Item
-        label="端口"
-        name="port"
-        rules={[
-          { required: true, message: '请输入端口号' },
-          { type: 'number', min: 1, max: 65535, message: '端口号必须在1-65535之间' },
-        ]}
-      >
-        <InputNumber placeholder="请输入端口号" style={{ width: '100%' }} min={1} max={65535} />
-      </Form.Item>
-
-      <Form.Item label="路径" name="path">
-        <Input placeholder="请输入路径，如：/stream1" />
-      </Form.Item>
-
-      <Form.Item label="用户名" name="username">
-        <Input placeholder="请输入用户名（可选）" />
-      </Form.Item>
-
-      <Form.Item label="密码" name="password">
-        <Input.Password placeholder="请输入密码（可选）" />
-      </Form.Item>
-
-      <Form.Item label="绑定设备" name="bindDeviceId">
-        <Select placeholder="请选择设备（可选）" allowClear>
-          {devices.map((device) => (
-            <Option key={device.id} value={device.id}>
-              {device.name} ({device.uuid})
-            </Option>
-          ))}
-        </Select>
-      </Form.Item>
-
-      <Form.Item>
-        <Space>
-          <Button type="primary" htmlType="submit" loading={loading} icon={<SaveOutlined />}>
-            {isEdit ? '更新' : '创建'}
+    <>
+      {!isEdit && (
+        <div style={{ marginBottom: 16 }}>
+          <Button icon={<SearchOutlined />} onClick={() => setDiscoverOpen(true)}>
+            扫描 ONVIF 摄像头
           </Button>
-          {isDrawer ? (
-            <Button onClick={() => onCancel && onCancel()}>取消</Button>
-          ) : (
-            <Button onClick={() => navigate('/cameras')}>取消</Button>
-          )}
-        </Space>
-      </Form.Item>
-    </Form>
+        </div>
+      )}
+      <OnvifDiscover
+        open={discoverOpen}
+        devices={devices}
+        onSelect={handleDiscoverSelect}
+        onCancel={() => setDiscoverOpen(false)}
+      />
+      <Form
+        form={form}
+        layout="vertical"
+        onFinish={handleFinish}
+        initialValues={{
+          protocol: 'rtsp',
+          port: 554,
+        }}
+      >
+        <Form.Item label="名称" name="name" rules={[{ required: true, message: '请输入摄像头名称' }]}>
+          <Input placeholder="请输入摄像头名称" />
+        </Form.Item>
+
+        <Form.Item label="协议" name="protocol" rules={[{ required: true, message: '请选择协议' }]}>
+          <Select placeholder="请选择协议">
+            <Option value="rtsp">RTSP</Option>
+            <Option value="rtmp">RTMP</Option>
+          </Select>
+        </Form.Item>
+
+        <Form.Item
+          label="IP地址"
+          name="ip"
+          rules={[
+            { required: true, message: '请输入IP地址' },
+            {
+              pattern:
+                /^(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)$/,
+              message: '请输入有效的IP地址',
+            },
+          ]}
+        >
+          <Input placeholder="请输入IP地址，如：192.168.1.100" />
+        </Form.Item>
+
+        <Form.Item
+          label="端口"
+          name="port"
+          rules={[
+            { required: true, message: '请输入端口号' },
+            { type: 'number', min: 1, max: 65535, message: '端口号必须在1-65535之间' },
+          ]}
+        >
+          <InputNumber placeholder="请输入端口号" style={{ width: '100%' }} min={1} max={65535} />
+        </Form.Item>
+
+        <Form.Item label="路径" name="path">
+          <Input placeholder="请输入路径，如：/stream1" />
+        </Form.Item>
+
+        <Form.Item label="用户名" name="username">
+          <Input placeholder="请输入用户名（可选）" />
+        </Form.Item>
+
+        <Form.Item label="密码" name="password">
+          <Input.Password placeholder="请输入密码（可选）" />
+        </Form.Item>
+
+        <Form.Item label="绑定设备" name="bindDeviceId">
+          <Select placeholder="请选择设备（可选）" allowClear>
+            {devices.map((device) => (
+              <Option key={device.id} value={device.id}>
+                {device.name} ({device.uuid})
+              </Option>
+            ))}
+          </Select>
+        </Form.Item>
+
+        <Form.Item label="预览音频" name="previewAudio" valuePropName="checked">
+          <Switch />
+        </Form.Item>
+
+        <Form.Item label="ONVIF 端口" name="onvifPort" tooltip="用于云台控制，留空表示不支持 ONVIF">
+          <InputNumber placeholder="如：80" style={{ width: '100%' }} min={0} max={65535} />
+        </Form.Item>
+
+        <Form.Item label="标签" name="tags" tooltip="如所在楼栋、楼层，用于在列表中按标签过滤">
+          <Select mode="tags" placeholder="输入后回车添加标签（可选）" tokenSeparators={[',']}>
+            {tagOptions.map((tag) => (
+              <Option key={tag} value={tag}>{tag}</Option>
+            ))}
+          </Select>
+        </Form.Item>
+
+        <Form.Item>
+          <Space>
+            <Button type="primary" htmlType="submit" loading={loading} icon={<SaveOutlined />}>
+              {isEdit ? '更新' : '创建'}
+            </Button>
+            {isDrawer ? (
+              <Button onClick={() => onCancel && onCancel()}>取消</Button>
+            ) : (
+              <Button onClick={() => navigate('/cameras')}>取消</Button>
+            )}
+          </Space>
+        </Form.Item>
+      </Form>
+    </>
   );
 
   if (isDrawer) {
@@ -1,11 +1,18 @@
 import React, { useState, useEffect } from 'react';
-import { Table, Button, Space, message, Modal, Card, Tag, Drawer } from 'antd';
+import { Table, Button, Space, message, Modal, Card, Tag, Drawer, Input, Select } from 'antd';
 import { PlusOutlined, EditOutlined, DeleteOutlined, EyeOutlined, ReloadOutlined, VideoCameraOutlined } from '@ant-design/icons';
 import { useNavigate } from 'react-router-dom';
-import { cameraApi } from '../../services/api';
+import { cameraApi, deviceApi } from '../../services/api';
 import { formatDate } from '../../utils/helpers';
 import CameraForm from './CameraForm';
-import type { CameraSpec, ListParams } from '../../types';
+import type { CameraProtocol, CameraSpec, DeviceSpec, ListCamerasParams, ListParams } from '../../types';
+
+interface CameraFilters {
+  tag?: string;
+  name?: string;
+  protocol?: CameraProtocol;
+  bindDeviceId?: number;
+}
 
 const CameraList: React.FC = () => {
   const [cameras, setCameras] = useState<CameraSpec[]>([]);
@@ -14,12 +21,16 @@ const CameraList: React.FC = () => {
   const [pagination, setPagination] = useState({ start: 0, limit: 10 });
   const [drawerVisible, setDrawerVisible] = useState(false);
   const [editingCameraId, setEditingCameraId] = useState<number | null>(null);
+  const [filters, setFilters] = useState<CameraFilters>({});
+  const [tags, setTags] = useState<string[]>([]);
+  const [devices, setDevices] = useState<DeviceSpec[]>([]);
   const navigate = useNavigate();
 
-  const fetchCameras = async (params: ListParams = pagination) => {
+  const fetchCameras = async (params: ListParams = pagination, filterParams: CameraFilters = filters) => {
     setLoading(true);
     try {
-      const response = await cameraApi.list(params);
+      const query: ListCamerasParams = { ...params, ...filterParams };
+      const response = await cameraApi.list(query);
       setCameras(response.items);
       setTotal(response.total);
     } catch (error) {
@@ -30,10 +41,34 @@ const CameraList: React.FC = () => {
     }
   };
 
+  // 获取过滤选项
+  const fetchFilterOptions = async () => {
+    try {
+      const [tagResp, deviceResp] = await Promise.all([
+        cameraApi.tags(),
+        deviceApi.list({ start: 0, limit: 100 }),
+      ]);
+      setTags(tagResp.tags || []);
+      setDevices(deviceResp.devices || []);
+    } catch (error) {
+      console.error('Error fetching filter options:', error);
+    }
+  };
+
   useEffect(() => {
     fetchCameras();
+    fetchFilterOptions();
   }, []);
 
+  // 过滤条件变化时回到第一页
+  const handleFilterChange = (changed: CameraFilters) => {
+    const newFilters = { ...filters, ...changed };
+    const newPagination = { start: 0, limit: pagination.limit };
+    setFilters(newFilters);
+    setPagination(newPagination);
+    fetchCameras(newPagination, newFilters);
+  };
+
   const handleDelete = (camera: CameraSpec) => {
     Modal.confirm({
       title: '确认删除',
@@ -85,6 +120,23 @@ const CameraList: React.FC = () => {
         <Tag color={getProtocolColor(protocol)}>{protocol.toUpperCase()}</Tag>
       ),
     },
+    {
+      title: '标签',
+      dataIndex: 'tags',
+      key: 'tags',
+      render: (cameraTags: string[]) =>
+        cameraTags && cameraTags.length > 0
+          ? cameraTags.map((tag) => (
+              <Tag
+                key={tag}
+                style={{ cursor: 'pointer' }}
+                onClick={() => handleFilterChange({ tag })}
+              >
+                {tag}
+              </Tag>
+            ))
+          : '-',
+    },
     {
       title: 'IP地址',
       dataIndex: 'ip',
@@ -159,11 +211,49 @@ const CameraList: React.FC = () => {
           </Button>
           <Button
             icon={<ReloadOutlined />}
-            onClick={() => fetchCameras()}
+            onClick={() => { fetchCameras(); fetchFilterOptions(); }}
           >
             刷新
           </Button>
         </Space>
+        <Space wrap>
+          <Input.Search
+            placeholder="按名称搜索"
+            allowClear
+            style={{ width: 200 }}
+            onSearch={(value) => handleFilterChange({ name: value || undefined })}
+          />
+          <Select
+            placeholder="标签"
+            allowClear
+            showSearch
+            style={{ width: 160 }}
+            value={filters.tag}
+            onChange={(value) => handleFilterChange({ tag: value })}
+            options={tags.map((tag) => ({ label: tag, value: tag }))}
+          />
+          <Select
+            placeholder="协议"
+            allowClear
+            style={{ width: 120 }}
+            value={filters.protocol}
+            onChange={(value) => handleFilterChange({ protocol: value })}
+            options={[
+              { label: 'RTSP', value: 'rtsp' },
+              { label: 'RTMP', value: 'rtmp' },
+            ]}
+          />
+          <Select
+            placeholder="绑定设备"
+            allowClear
+            showSearch
+            optionFilterProp="label"
+            style={{ width: 200 }}
+            value={filters.bindDeviceId}
+            onChange={(value) => handleFilterChange({ bindDeviceId: value })}
+            options={devices.map((device) => ({ label: device.name, value: device.id }))}
+          />
+        </Space>
       </div>
       <Table
         columns={columns}
@@ -203,6 +293,7 @@ const CameraList: React.FC = () => {
             setDrawerVisible(false);
             setEditingCameraId(null);
             fetchCameras();
+            fetchFilterOptions();
           }}
           onCancel={() => { setDrawerVisible(false); setEditingCameraId(null); }}
         />
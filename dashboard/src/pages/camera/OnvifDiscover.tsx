@@ -0,0 +1,120 @@
+import React, { useState } from 'react';
+import { Modal, Form, Select, Input, InputNumber, Button, Table, Tag, message } from 'antd';
+import { cameraApi } from '../../services/api';
+import type { CreateCameraRequest, DeviceSpec, DiscoveredCamera } from '../../types';
+
+interface OnvifDiscoverProps {
+  open: boolean;
+  devices: DeviceSpec[];
+  onSelect: (camera: CreateCameraRequest) => void;
+  onCancel: () => void;
+}
+
+// 由选定设备扫描 ONVIF 摄像头，选中结果后预填摄像头表单
+const OnvifDiscover: React.FC<OnvifDiscoverProps> = ({ open, devices, onSelect, onCancel }) => {
+  const [form] = Form.useForm();
+  const [loading, setLoading] = useState(false);
+  const [cameras, setCameras] = useState<DiscoveredCamera[]>([]);
+
+  const handleDiscover = async (values: any) => {
+    setLoading(true);
+    try {
+      const resp = await cameraApi.discover({
+        deviceId: values.deviceId,
+        subnet: values.subnet || undefined,
+        username: values.username || undefined,
+        password: values.password || undefined,
+        timeout: values.timeout,
+      });
+      setCameras(resp.cameras || []);
+      if (!resp.cameras?.length) {
+        message.info('未发现 ONVIF 摄像头');
+      }
+    } catch (error) {
+      message.error('扫描失败');
+      // eslint-disable-next-line no-console
+      console.error('Error discovering cameras:', error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const columns = [
+    { title: 'IP', dataIndex: 'ip', key: 'ip' },
+    {
+      title: '型号',
+      key: 'model',
+      render: (_: any, record: DiscoveredCamera) =>
+        [record.manufacturer, record.model].filter(Boolean).join(' ') || record.name || '-',
+    },
+    {
+      title: '云台',
+      dataIndex: 'ptz',
+      key: 'ptz',
+      render: (ptz: boolean) => (ptz ? <Tag color="blue">支持</Tag> : '-'),
+    },
+    {
+      title: '状态',
+      key: 'error',
+      render: (_: any, record: DiscoveredCamera) =>
+        record.error ? <Tag color="red" title={record.error}>读取失败</Tag> : <Tag color="green">正常</Tag>,
+    },
+    {
+      title: '操作',
+      key: 'action',
+      render: (_: any, record: DiscoveredCamera) => (
+        <Button
+          type="link"
+          disabled={!record.camera}
+          onClick={() => record.camera && onSelect(record.camera)}
+        >
+          使用
+        </Button>
+      ),
+    },
+  ];
+
+  return (
+    <Modal title="扫描 ONVIF 摄像头" open={open} onCancel={onCancel} footer={null} width={720} destroyOnClose>
+      <Form form={form} layout="inline" onFinish={handleDiscover} initialValues={{ timeout: 5 }}>
+        <Form.Item name="deviceId" rules={[{ required: true, message: '请选择设备' }]}>
+          <Select placeholder="执行扫描的设备" style={{ width: 180 }}>
+            {devices.map((device) => (
+              <Select.Option key={device.id} value={device.id}>
+                {device.name}
+              </Select.Option>
+            ))}
+          </Select>
+        </Form.Item>
+        <Form.Item name="subnet">
+          <Input placeholder="网段（可选），如 192.168.1.0/24" style={{ width: 220 }} />
+        </Form.Item>
+        <Form.Item name="username">
+          <Input placeholder="用户名" style={{ width: 110 }} />
+        </Form.Item>
+        <Form.Item name="password">
+          <Input.Password placeholder="密码" style={{ width: 110 }} />
+        </Form.Item>
+        <Form.Item name="timeout">
+          <InputNumber min={1} max={20} addonAfter="秒" style={{ width: 100 }} />
+        </Form.Item>
+        <Form.Item>
+          <Button type="primary" htmlType="submit" loading={loading}>
+            扫描
+          </Button>
+        </Form.Item>
+      </Form>
+      <Table
+        style={{ marginTop: 16 }}
+        rowKey="xaddr"
+        size="small"
+        columns={columns}
+        dataSource={cameras}
+        loading={loading}
+        pagination={false}
+      />
+    </Modal>
+  );
+};
+
+export default OnvifDiscover;
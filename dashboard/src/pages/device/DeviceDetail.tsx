@@ -24,6 +24,7 @@ import {
 import { deviceApi } from '../../services/api';
 import type { DeviceSpec } from '../../types';
 import { formatDate, handleApiError, getDeleteConfirmConfig, copyToClipboard } from '../../utils/helpers';
+import { DEVICE_STATUS_MAP } from '../../utils/constants';
 import DeviceForm from './DeviceForm';
 
 const { Text } = Typography;
@@ -213,9 +214,32 @@ const DeviceDetail: React.FC = () => {
           <Descriptions.Item label="最后心跳时间">
             {formatDate(device.lastPingTime)}
           </Descriptions.Item>
+          <Descriptions.Item label="状态">
+            {device.state ? (
+              <Tag color={DEVICE_STATUS_MAP[device.state].color}>{DEVICE_STATUS_MAP[device.state].text}</Tag>
+            ) : '-'}
+          </Descriptions.Item>
         </Descriptions>
       </Card>
 
+      {device.inventory && (
+        <Card title="软件版本" style={{ marginTop: 16 }}>
+          <Descriptions column={2} bordered size="small">
+            <Descriptions.Item label="Lumina">
+              {device.inventory.luminaVersion || '-'}
+              {device.inventory.luminaCommit && <Text type="secondary"> ({device.inventory.luminaCommit})</Text>}
+            </Descriptions.Item>
+            <Descriptions.Item label="操作系统">
+              {device.inventory.os || '-'} {device.inventory.arch}
+            </Descriptions.Item>
+            <Descriptions.Item label="内核">{device.inventory.kernel || '-'}</Descriptions.Item>
+            <Descriptions.Item label="FFmpeg">{device.inventory.ffmpegVersion || '-'}</Descriptions.Item>
+            <Descriptions.Item label="OpenCV">{device.inventory.opencvVersion || '-'}</Descriptions.Item>
+            <Descriptions.Item label="Triton">{device.inventory.tritonVersion || '-'}</Descriptions.Item>
+          </Descriptions>
+        </Card>
+      )}
+
       <Drawer
         title="编辑主机"
         width={600}
@@ -40,7 +40,7 @@ const DeviceForm: React.FC<DeviceFormProps> = ({ device, onSubmit, onCancel }) =
         onSubmit();
       }
     } catch (error) {
-      handleApiError(error, isEditing ? '更新主机失败' : '创建主机失败');
+      handleApiError(error, isEditing ? '更新主机失败' : '创建主机失败', form);
     } finally {
       setLoading(false);
     }
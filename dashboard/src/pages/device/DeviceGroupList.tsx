@@ -0,0 +1,280 @@
+import React, { useState, useEffect } from 'react';
+import {
+  Table,
+  Button,
+  Space,
+  Modal,
+  message,
+  Card,
+  Form,
+  Input,
+  Select,
+  Typography,
+} from 'antd';
+import {
+  DeleteOutlined,
+  EditOutlined,
+  PlusOutlined,
+  ReloadOutlined,
+} from '@ant-design/icons';
+import type { ColumnsType } from 'antd/es/table';
+import { deviceApi, deviceGroupApi } from '../../services/api';
+import type { Device, DeviceGroupSpec, ListParams } from '../../types';
+import { formatDate, handleApiError, getDeleteConfirmConfig } from '../../utils/helpers';
+import { DEFAULT_PAGE_SIZE } from '../../utils/constants';
+
+const { Text } = Typography;
+const { Option } = Select;
+
+const DeviceGroupList: React.FC = () => {
+  const [form] = Form.useForm();
+  const [groups, setGroups] = useState<DeviceGroupSpec[]>([]);
+  const [devices, setDevices] = useState<Device[]>([]);
+  const [loading, setLoading] = useState(false);
+  const [total, setTotal] = useState(0);
+  const [current, setCurrent] = useState(1);
+  const [pageSize, setPageSize] = useState(DEFAULT_PAGE_SIZE);
+  const [modalVisible, setModalVisible] = useState(false);
+  const [editing, setEditing] = useState<DeviceGroupSpec | null>(null);
+  const [saving, setSaving] = useState(false);
+
+  // 获取设备组列表
+  const fetchGroups = async () => {
+    setLoading(true);
+    try {
+      const params: ListParams = {
+        start: (current - 1) * pageSize,
+        limit: pageSize,
+      };
+      const response = await deviceGroupApi.list(params);
+      setGroups(response.items || []);
+      setTotal(response.total || 0);
+    } catch (error) {
+      handleApiError(error, '获取设备组列表失败');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // 获取可选主机
+  const fetchDevices = async () => {
+    try {
+      const response = await deviceApi.list({ start: 0, limit: 100 });
+      setDevices(response.devices || []);
+    } catch (error) {
+      console.error('获取主机列表失败:', error);
+    }
+  };
+
+  useEffect(() => {
+    fetchGroups();
+  }, [current, pageSize]);
+
+  useEffect(() => {
+    fetchDevices();
+  }, []);
+
+  // 打开创建或编辑弹窗，编辑时获取成员设备
+  const openModal = async (group?: DeviceGroupSpec) => {
+    form.resetFields();
+    setEditing(group || null);
+    if (group) {
+      try {
+        const detail = await deviceGroupApi.get(group.id);
+        form.setFieldsValue({
+          name: detail.name,
+          description: detail.description,
+          channel: detail.channel || 'stable',
+          deviceIds: (detail.devices || []).map((d) => d.id),
+        });
+      } catch (error) {
+        handleApiError(error, '获取设备组详情失败');
+        return;
+      }
+    }
+    setModalVisible(true);
+  };
+
+  const handleSave = async () => {
+    const values = await form.validateFields();
+    setSaving(true);
+    try {
+      const data = {
+        name: values.name,
+        description: values.description || '',
+        deviceIds: values.deviceIds || [],
+        channel: values.channel || 'stable',
+      };
+      if (editing) {
+        await deviceGroupApi.update(editing.id, data);
+        message.success('更新成功');
+      } else {
+        await deviceGroupApi.create(data);
+        message.success('创建成功');
+      }
+      setModalVisible(false);
+      fetchGroups();
+    } catch (error) {
+      handleApiError(error, editing ? '更新失败' : '创建失败');
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  // 处理删除设备组
+  const handleDelete = (group: DeviceGroupSpec) => {
+    Modal.confirm({
+      ...getDeleteConfirmConfig(`删除设备组 "${group.name}"`),
+      onOk: async () => {
+        try {
+          await deviceGroupApi.delete(group.id);
+          message.success('删除成功');
+          fetchGroups();
+        } catch (error) {
+          handleApiError(error, '删除失败，请先移除使用该设备组的任务');
+        }
+      },
+    });
+  };
+
+  // 表格列配置
+  const columns: ColumnsType<DeviceGroupSpec> = [
+    {
+      title: '名称',
+      dataIndex: 'name',
+      key: 'name',
+      width: 200,
+      ellipsis: true,
+      render: (name: string) => <Text strong>{name}</Text>,
+    },
+    {
+      title: '描述',
+      dataIndex: 'description',
+      key: 'description',
+      ellipsis: true,
+      render: (desc: string) => desc || '-',
+    },
+    {
+      title: '发布通道',
+      dataIndex: 'channel',
+      key: 'channel',
+      width: 100,
+      render: (channel: string) => (channel === 'beta' ? 'Beta' : '稳定版'),
+    },
+    {
+      title: '创建时间',
+      dataIndex: 'createTime',
+      key: 'createTime',
+      width: 180,
+      render: (date: string) => formatDate(date),
+    },
+    {
+      title: '操作',
+      key: 'action',
+      width: 120,
+      render: (_, record) => (
+        <Space size="small">
+          <Button
+            type="text"
+            size="small"
+            icon={<EditOutlined />}
+            onClick={() => openModal(record)}
+            title="编辑"
+          />
+          <Button
+            type="text"
+            size="small"
+            danger
+            icon={<DeleteOutlined />}
+            onClick={() => handleDelete(record)}
+            title="删除"
+          />
+        </Space>
+      ),
+    },
+  ];
+
+  return (
+    <Card>
+      <div style={{ marginBottom: 16 }}>
+        <Space>
+          <Button type="primary" icon={<PlusOutlined />} onClick={() => openModal()}>
+            新建设备组
+          </Button>
+          <Button icon={<ReloadOutlined />} onClick={fetchGroups}>
+            刷新
+          </Button>
+        </Space>
+      </div>
+
+      <Table
+        columns={columns}
+        dataSource={groups}
+        rowKey="id"
+        loading={loading}
+        pagination={{
+          current,
+          pageSize,
+          total,
+          showSizeChanger: true,
+          showQuickJumper: true,
+          showTotal: (total, range) =>
+            `第 ${range[0]}-${range[1]} 条，共 ${total} 条`,
+          onChange: (page, size) => {
+            setCurrent(page);
+            setPageSize(size || DEFAULT_PAGE_SIZE);
+          },
+        }}
+      />
+
+      <Modal
+        title={editing ? '编辑设备组' : '新建设备组'}
+        open={modalVisible}
+        onOk={handleSave}
+        onCancel={() => setModalVisible(false)}
+        confirmLoading={saving}
+        destroyOnClose
+      >
+        <Form form={form} layout="vertical">
+          <Form.Item
+            name="name"
+            label="名称"
+            rules={[{ required: true, message: '请输入设备组名称' }, { max: 96, message: '名称不能超过96个字符' }]}
+          >
+            <Input placeholder="请输入设备组名称" />
+          </Form.Item>
+          <Form.Item name="description" label="描述" rules={[{ max: 255, message: '描述不能超过255个字符' }]}>
+            <Input.TextArea rows={2} placeholder="请输入描述" />
+          </Form.Item>
+          <Form.Item
+            name="channel"
+            label="发布通道"
+            initialValue="stable"
+            tooltip="Beta 通道的设备组可以升级到 Beta 版本和稳定版本"
+          >
+            <Select>
+              <Option value="stable">稳定版</Option>
+              <Option value="beta">Beta</Option>
+            </Select>
+          </Form.Item>
+          <Form.Item name="deviceIds" label="成员主机" tooltip="设备组任务会在所有成员主机上运行">
+            <Select
+              mode="multiple"
+              placeholder="请选择主机"
+              allowClear
+              optionFilterProp="children"
+            >
+              {devices.map((device) => (
+                <Option key={device.id} value={device.id}>
+                  {device.name || device.uuid}
+                </Option>
+              ))}
+            </Select>
+          </Form.Item>
+        </Form>
+      </Modal>
+    </Card>
+  );
+};
+
+export default DeviceGroupList;
@@ -9,6 +9,8 @@ import {
   Input,
   Typography,
   Tooltip,
+  Tag,
+  Select,
 } from 'antd';
 import {
   DeleteOutlined,
@@ -17,12 +19,13 @@ import {
 } from '@ant-design/icons';
 import type { ColumnsType } from 'antd/es/table';
 import { useNavigate } from 'react-router-dom';
-import { deviceApi } from '../../services/api';
-import type { Device, ListParams } from '../../types';
+import { deviceApi, eventsApi } from '../../services/api';
+import type { Device, DeviceState, ListParams } from '../../types';
 import { formatDate, handleApiError, getDeleteConfirmConfig } from '../../utils/helpers';
-import { DEFAULT_PAGE_SIZE } from '../../utils/constants';
+import { DEFAULT_PAGE_SIZE, DEVICE_STATUS_MAP } from '../../utils/constants';
 
 const { Search } = Input;
+const { Option } = Select;
 const { Text, Link } = Typography;
 
 const DeviceList: React.FC = () => {
@@ -33,6 +36,7 @@ const DeviceList: React.FC = () => {
   const [current, setCurrent] = useState(1);
   const [pageSize, setPageSize] = useState(DEFAULT_PAGE_SIZE);
   const [searchText, setSearchText] = useState('');
+  const [stateFilter, setStateFilter] = useState<DeviceState | undefined>();
   // 已移除令牌显示逻辑
 
   // 相对时间格式化：秒/分钟/小时/天前
@@ -56,9 +60,10 @@ const DeviceList: React.FC = () => {
   const fetchDevices = async () => {
     setLoading(true);
     try {
-      const params: ListParams = {
+      const params: ListParams & { state?: DeviceState } = {
         start: (current - 1) * pageSize,
         limit: pageSize,
+        state: stateFilter,
       };
       const response = await deviceApi.list(params);
       setDevices(response.devices || []);
@@ -72,7 +77,20 @@ const DeviceList: React.FC = () => {
 
   useEffect(() => {
     fetchDevices();
-  }, [current, pageSize]);
+  }, [current, pageSize, stateFilter]);
+
+  // 设备在线状态变化时更新列表，按状态过滤时重新获取
+  useEffect(() => {
+    return eventsApi.subscribe(['device_state'], (event) => {
+      const changed = event.device;
+      if (!changed) return;
+      if (stateFilter) {
+        fetchDevices();
+        return;
+      }
+      setDevices((prev) => prev.map((d) => (d.id === changed.deviceId ? { ...d, state: changed.state } : d)));
+    });
+  }, [current, pageSize, stateFilter]);
 
   // 处理删除主机
   const handleDelete = (device: Device) => {
@@ -121,6 +139,16 @@ const DeviceList: React.FC = () => {
         </Text>
       ),
     },
+    {
+      title: '状态',
+      dataIndex: 'state',
+      key: 'state',
+      width: 100,
+      render: (state?: DeviceState) => {
+        const info = state ? DEVICE_STATUS_MAP[state] : undefined;
+        return info ? <Tag color={info.color}>{info.text}</Tag> : '-';
+      },
+    },
     {
       title: '注册时间',
       dataIndex: 'registerTime',
@@ -177,6 +205,19 @@ const DeviceList: React.FC = () => {
           </Button>
         </Space>
         <Space style={{ float: 'right' }}>
+          <Select
+            placeholder="筛选状态"
+            allowClear
+            style={{ width: 120 }}
+            value={stateFilter}
+            onChange={(value) => { setStateFilter(value); setCurrent(1); }}
+          >
+            {(['online', 'degraded', 'offline'] as DeviceState[]).map((state) => (
+              <Option key={state} value={state}>
+                {DEVICE_STATUS_MAP[state].text}
+              </Option>
+            ))}
+          </Select>
           <Search
             placeholder="搜索UUID"
             allowClear
@@ -1,3 +1,4 @@
 export { default as DeviceList } from './DeviceList';
 export { default as DeviceDetail } from './DeviceDetail';
-export { default as DeviceForm } from './DeviceForm';
\ No newline at end of file
+export { default as DeviceForm } from './DeviceForm';
+export { default as DeviceGroupList } from './DeviceGroupList';
\ No newline at end of file
@@ -0,0 +1,244 @@
+import React, { useState, useEffect } from 'react';
+import {
+  Table,
+  Button,
+  Space,
+  message,
+  Card,
+  Select,
+  Tag,
+  Typography,
+} from 'antd';
+import {
+  CheckOutlined,
+  ReloadOutlined,
+} from '@ant-design/icons';
+import type { ColumnsType } from 'antd/es/table';
+import { Link } from 'react-router-dom';
+import { healthApi } from '../../services/api';
+import type { CanaryStatusResponse, HealthEventKind, HealthEventSpec } from '../../types';
+import { formatDate, handleApiError } from '../../utils/helpers';
+import { DEFAULT_PAGE_SIZE } from '../../utils/constants';
+
+const { Option } = Select;
+const { Text } = Typography;
+
+const kindLabels: Record<HealthEventKind, string> = {
+  message_volume: '消息量异常',
+  alert_volume: '告警量异常',
+  canary: '消息链路自检失败',
+  device_offline: '设备离线',
+};
+
+// 链路自检的实际值与通常值是成功率，设备离线的是未上报的秒数与离线阈值
+const formatValue = (kind: HealthEventKind, value: number) => {
+  if (kind === 'canary') return `${Math.round(value * 100)}%`;
+  if (kind === 'device_offline') return `${Math.round(value)}秒`;
+  return `${Math.round(value)}`;
+};
+
+const HealthEventList: React.FC = () => {
+  const [events, setEvents] = useState<HealthEventSpec[]>([]);
+  const [loading, setLoading] = useState(false);
+  const [total, setTotal] = useState(0);
+  const [current, setCurrent] = useState(1);
+  const [pageSize, setPageSize] = useState(DEFAULT_PAGE_SIZE);
+  const [kindFilter, setKindFilter] = useState<HealthEventKind | undefined>();
+  const [resolvedFilter, setResolvedFilter] = useState<boolean | undefined>(false);
+  const [canary, setCanary] = useState<CanaryStatusResponse | null>(null);
+
+  // 获取事件列表
+  const fetchEvents = async () => {
+    setLoading(true);
+    try {
+      const response = await healthApi.list({
+        start: (current - 1) * pageSize,
+        limit: pageSize,
+        kind: kindFilter,
+        resolved: resolvedFilter,
+      });
+      setEvents(response.items || []);
+      setTotal(response.total || 0);
+    } catch (error) {
+      handleApiError(error, '获取系统健康事件失败');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // 获取链路自检状态
+  const fetchCanary = async () => {
+    try {
+      setCanary(await healthApi.canary());
+    } catch (error) {
+      handleApiError(error, '获取链路自检状态失败');
+    }
+  };
+
+  useEffect(() => {
+    fetchEvents();
+  }, [current, pageSize, kindFilter, resolvedFilter]);
+
+  useEffect(() => {
+    fetchCanary();
+  }, []);
+
+  // 处理解决事件
+  const handleResolve = async (event: HealthEventSpec) => {
+    try {
+      await healthApi.resolve(event.id);
+      message.success('已标记为解决');
+      fetchEvents();
+    } catch (error) {
+      handleApiError(error, '操作失败');
+    }
+  };
+
+  // 表格列配置
+  const columns: ColumnsType<HealthEventSpec> = [
+    {
+      title: '类型',
+      dataIndex: 'kind',
+      key: 'kind',
+      width: 120,
+      render: (kind: HealthEventKind) => <Tag color="orange">{kindLabels[kind] || kind}</Tag>,
+    },
+    {
+      title: '关联对象',
+      key: 'target',
+      width: 100,
+      render: (_, record) => {
+        if (record.jobId) return <Link to={`/jobs/${record.jobId}`}>任务 {record.jobId}</Link>;
+        if (record.deviceId) return <Link to={`/devices/${record.deviceId}`}>主机 {record.deviceId}</Link>;
+        return '-';
+      },
+    },
+    {
+      title: '描述',
+      dataIndex: 'summary',
+      key: 'summary',
+      render: (summary: string) => <Text>{summary}</Text>,
+    },
+    {
+      title: '实际值 / 通常值',
+      key: 'volume',
+      width: 140,
+      render: (_, record) => `${formatValue(record.kind, record.observed)} / ${formatValue(record.kind, record.expected)}`,
+    },
+    {
+      title: '偏离程度',
+      dataIndex: 'score',
+      key: 'score',
+      width: 100,
+      render: (score: number) => score.toFixed(1),
+    },
+    {
+      title: '开始时间',
+      dataIndex: 'startTime',
+      key: 'startTime',
+      width: 180,
+      render: (time: string) => formatDate(time),
+    },
+    {
+      title: '最近异常时段结束',
+      dataIndex: 'endTime',
+      key: 'endTime',
+      width: 180,
+      render: (time: string) => formatDate(time),
+    },
+    {
+      title: '状态',
+      key: 'resolved',
+      width: 180,
+      render: (_, record) => (record.resolved
+        ? <Tag color="green">已解决 {record.resolveTime ? formatDate(record.resolveTime) : ''}</Tag>
+        : <Tag color="red">未解决</Tag>),
+    },
+    {
+      title: '操作',
+      key: 'action',
+      width: 80,
+      render: (_, record) => (
+        <Space size="small">
+          {!record.resolved && (
+            <Button
+              type="text"
+              size="small"
+              icon={<CheckOutlined />}
+              onClick={() => handleResolve(record)}
+              title="标记为已解决"
+            />
+          )}
+        </Space>
+      ),
+    },
+  ];
+
+  return (
+    <Card>
+      <div style={{ marginBottom: 16 }}>
+        <Space style={{ marginBottom: 16 }}>
+          <Button
+            icon={<ReloadOutlined />}
+            onClick={() => { fetchEvents(); fetchCanary(); }}
+          >
+            刷新
+          </Button>
+          {canary?.enabled && (
+            <Text type="secondary">
+              链路自检：最近 {canary.runs.length} 次成功率 {Math.round(canary.successRate * 100)}%
+              {canary.runs[0]?.success && `，最近一次耗时 ${canary.runs[0].latency} ms`}
+            </Text>
+          )}
+        </Space>
+        <Space style={{ float: 'right' }}>
+          <Select
+            placeholder="筛选类型"
+            allowClear
+            style={{ width: 150 }}
+            value={kindFilter}
+            onChange={(value) => { setKindFilter(value); setCurrent(1); }}
+          >
+            {Object.entries(kindLabels).map(([kind, label]) => (
+              <Option key={kind} value={kind}>
+                {label}
+              </Option>
+            ))}
+          </Select>
+          <Select
+            placeholder="筛选状态"
+            allowClear
+            style={{ width: 120 }}
+            value={resolvedFilter}
+            onChange={(value) => { setResolvedFilter(value); setCurrent(1); }}
+          >
+            <Option value={false}>未解决</Option>
+            <Option value={true}>已解决</Option>
+          </Select>
+        </Space>
+      </div>
+
+      <Table
+        columns={columns}
+        dataSource={events}
+        rowKey="id"
+        loading={loading}
+        pagination={{
+          current,
+          pageSize,
+          total,
+          showSizeChanger: true,
+          showQuickJumper: true,
+          showTotal: (total, range) =>
+            `第 ${range[0]}-${range[1]} 条，共 ${total} 条`,
+          onChange: (page, size) => {
+            setCurrent(page);
+            setPageSize(size || DEFAULT_PAGE_SIZE);
+          },
+        }}
+      />
+    </Card>
+  );
+};
+
+export default HealthEventList;
@@ -0,0 +1 @@
+export { default as HealthEventList } from './HealthEventList';
@@ -20,6 +20,7 @@ import {
   DatePicker,
   Select,
   Checkbox,
+  Timeline,
 } from 'antd';
 import {
   ArrowLeftOutlined,
@@ -35,9 +36,9 @@ import {
 import { useParams, useNavigate } from 'react-router-dom';
 import type { ColumnsType } from 'antd/es/table';
 import { jobApi, messageApi, deviceApi, workflowApi } from '../../services/api';
-import type { Job, Message, Device, Workflow, ListParams, JobStatsResponse, JobStatsRequest, CameraSpec } from '../../types';
+import type { Job, Message, Device, Workflow, ListParams, JobStatsResponse, JobStatsRequest, CameraSpec, JobDeviceStatus, JobStatusHistory, JobArtifact } from '../../types';
 import { formatDate, handleApiError, getDeleteConfirmConfig, isOlderThanMinutes } from '../../utils/helpers';
-import { JOB_STATUS_MAP, JOB_KIND_MAP, MESSAGE_TYPE_MAP, DEFAULT_PAGE_SIZE } from '../../utils/constants';
+import { JOB_STATUS_MAP, JOB_KIND_MAP, MESSAGE_TYPE_MAP, DEFAULT_PAGE_SIZE, WEEKDAY_OPTIONS } from '../../utils/constants';
 import JobForm from './JobForm';
 import VideoThumbnail from '../../components/VideoThumbnail';
 import { Line } from '@ant-design/plots';
@@ -46,6 +47,15 @@ const { Title, Text } = Typography;
 const { Search } = Input;
 const { Panel } = Collapse;
 
+// 实际帧率，被主机限流时附上限
+const formatFrameRate = (frameRate?: number, limit?: number) => {
+  if (!frameRate) {
+    return '-';
+  }
+  const text = `${frameRate.toFixed(1)} 帧/秒`;
+  return limit ? `${text}（限流至 ${limit.toFixed(1)}）` : text;
+};
+
 
 
 const JobDetail: React.FC = () => {
@@ -99,6 +109,10 @@ const JobDetail: React.FC = () => {
   const [stats, setStats] = useState<JobStatsResponse | null>(null);
   const [statsLoading, setStatsLoading] = useState(false);
   const [statsParams, setStatsParams] = useState<JobStatsRequest>({ window: '5m' });
+  const [deviceStatus, setDeviceStatus] = useState<JobDeviceStatus[]>([]);
+  const [history, setHistory] = useState<JobStatusHistory[]>([]);
+  const [artifacts, setArtifacts] = useState<JobArtifact[]>([]);
+  const [historyLoading, setHistoryLoading] = useState(false);
 
   // 统计区间选择（非必需，用户选择时才更新）
   const handleRangeChange = (values: any) => {
@@ -141,6 +155,26 @@ const JobDetail: React.FC = () => {
       if ((jobData as any).workflow) {
         setWorkflow((jobData as any).workflow);
       }
+
+      // 设备组任务获取各设备上的运行状态
+      if (jobData.deviceGroup) {
+        try {
+          const res = await jobApi.deviceStatus(jobId);
+          setDeviceStatus(res.items || []);
+        } catch (error) {
+          console.warn('Failed to fetch job device status:', error);
+        }
+      } else {
+        setDeviceStatus([]);
+      }
+
+      // 对象存储不可用时设备上报的快照
+      try {
+        const res = await jobApi.artifacts(jobId);
+        setArtifacts(res.items || []);
+      } catch (error) {
+        console.warn('Failed to fetch job artifacts:', error);
+      }
     } catch (error) {
       handleApiError(error, '获取任务详情失败');
     } finally {
@@ -200,6 +234,60 @@ const JobDetail: React.FC = () => {
     }
   }, [activeTab, id, job, statsParams]);
 
+  // 获取状态变更记录
+  const fetchJobHistory = async () => {
+    if (!id || !job) return;
+    setHistoryLoading(true);
+    try {
+      const res = await jobApi.history(job.id, { start: 0, limit: 100 });
+      setHistory(res.items || []);
+    } catch (error) {
+      handleApiError(error, '获取状态变更记录失败');
+    } finally {
+      setHistoryLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    if (activeTab === 'history') {
+      fetchJobHistory();
+    }
+  }, [activeTab, id, job]);
+
+  const ARTIFACT_KIND_MAP: Record<string, string> = {
+    trigger_thumbnail: '最新触发图片',
+    error_screenshot: '最近错误画面',
+  };
+
+  // 状态变更记录的显示内容
+  const JOB_ACTION_MAP: Record<string, string> = {
+    create: '创建任务',
+    start: '启动任务',
+    stop: '停止任务',
+    pause: '暂停任务',
+    resume: '恢复任务',
+  };
+  const renderHistoryItem = (h: JobStatusHistory) => {
+    const statusText = (s?: string) =>
+      (s && JOB_STATUS_MAP[s as keyof typeof JOB_STATUS_MAP]?.text) || s || '-';
+    const title = h.source === 'server'
+      ? `${JOB_ACTION_MAP[h.status] || h.status}${h.username ? `（${h.username}）` : ''}`
+      : `${statusText(h.prevStatus)} → ${statusText(h.status)}${h.deviceId ? `（主机 ${h.deviceId}）` : ''}`;
+    return (
+      <div>
+        <Text strong>{title}</Text>
+        <Text type="secondary" style={{ marginLeft: 8 }}>{formatDate(h.createTime)}</Text>
+        {h.reason && <div><Text type={h.status === 'failed' ? 'danger' : 'secondary'}>{h.reason}</Text></div>}
+      </div>
+    );
+  };
+  const historyColor = (h: JobStatusHistory) => {
+    if (h.source === 'server') return 'blue';
+    if (h.status === 'failed') return 'red';
+    if (h.status === 'running') return 'green';
+    return 'gray';
+  };
+
   // 处理任务操作
   const handleJobAction = async (action: string) => {
     if (!job) return;
@@ -211,7 +299,13 @@ const JobDetail: React.FC = () => {
         if (action === 'start') {
           await jobApi.start(identifier);
           message.success('任务启动成功');
-        } else if (action === 'pause' || action === 'stop') {
+        } else if (action === 'pause') {
+          await jobApi.pause(identifier);
+          message.success('任务暂停成功');
+        } else if (action === 'resume') {
+          await jobApi.resume(identifier);
+          message.success('任务恢复成功');
+        } else if (action === 'stop') {
           await jobApi.stop(identifier);
           message.success('任务停止成功');
         }
@@ -401,10 +495,39 @@ const JobDetail: React.FC = () => {
                 >
                   {job.device.name || job.device.uuid || '-'}
                 </Button>
+              ) : job?.deviceGroup ? (
+                <Space size={4}>
+                  <Tag color="purple">设备组</Tag>
+                  <Button
+                    type="link"
+                    size="small"
+                    onClick={() => navigate('/device-groups')}
+                    style={{ padding: 0 }}
+                  >
+                    {job.deviceGroup.name}
+                  </Button>
+                </Space>
               ) : (
                 '-'
               )}
             </Descriptions.Item>
+            <Descriptions.Item label="布防计划">
+              {job.schedule?.windows?.length ? (
+                <Space direction="vertical" size={0}>
+                  {job.schedule.windows.map((w, i) => (
+                    <Text key={i}>
+                      {w.days?.length
+                        ? WEEKDAY_OPTIONS.filter((d) => w.days!.includes(d.value)).map((d) => d.label).join('、')
+                        : '每天'}{' '}
+                      {w.start}-{w.end}
+                    </Text>
+                  ))}
+                  {job.schedule.timezone && <Text type="secondary">时区：{job.schedule.timezone}</Text>}
+                </Space>
+              ) : (
+                '始终布防'
+              )}
+            </Descriptions.Item>
             <Descriptions.Item label="工作流">
               {job?.workflow ? (
                 <Button
@@ -434,6 +557,78 @@ const JobDetail: React.FC = () => {
             </Descriptions.Item>
           </Descriptions>
 
+          {job.deviceGroup && (
+            <Card title="各设备运行状态" style={{ marginTop: 16 }} size="small">
+              <Table
+                size="small"
+                rowKey={(record) => record.device?.id ?? record.updateTime}
+                dataSource={deviceStatus}
+                pagination={false}
+                locale={{ emptyText: '暂无设备上报状态' }}
+                columns={[
+                  {
+                    title: '主机',
+                    dataIndex: 'device',
+                    key: 'device',
+                    render: (d: JobDeviceStatus['device']) => d ? (
+                      <Button type="link" size="small" onClick={() => handleViewDevice(d)} style={{ padding: 0 }}>
+                        {d.name || d.uuid}
+                      </Button>
+                    ) : '-',
+                  },
+                  {
+                    title: '状态',
+                    dataIndex: 'status',
+                    key: 'status',
+                    render: (status: string) => {
+                      const info = JOB_STATUS_MAP[status as keyof typeof JOB_STATUS_MAP] || { text: status, color: 'default' };
+                      return <Tag color={info.color}>{info.text}</Tag>;
+                    },
+                  },
+                  {
+                    title: '帧率',
+                    key: 'frameRate',
+                    render: (_, record: JobDeviceStatus) => formatFrameRate(record.frameRate, record.frameRateLimit),
+                  },
+                  {
+                    title: '重启次数',
+                    dataIndex: 'restartCount',
+                    key: 'restartCount',
+                    render: (v?: number) => v ?? 0,
+                  },
+                  {
+                    title: '错误信息',
+                    dataIndex: 'lastError',
+                    key: 'lastError',
+                    ellipsis: true,
+                    render: (v?: string) => v || '-',
+                  },
+                  {
+                    title: '上报时间',
+                    dataIndex: 'updateTime',
+                    key: 'updateTime',
+                    render: (v: string) => formatDate(v),
+                  },
+                ]}
+              />
+            </Card>
+          )}
+
+          {artifacts.length > 0 && (
+            <Card title="存储不可用时的设备快照" style={{ marginTop: 16 }} size="small">
+              <Space size="large" wrap>
+                {artifacts.map((a) => (
+                  <Space key={a.kind} direction="vertical" size={4}>
+                    <Image width={240} src={`${a.url}?t=${encodeURIComponent(a.time)}`} />
+                    <Text type="secondary">
+                      {ARTIFACT_KIND_MAP[a.kind] || a.kind} · {formatDate(a.time)}
+                    </Text>
+                  </Space>
+                ))}
+              </Space>
+            </Card>
+          )}
+
           {/* Display detect options */}
           {job.detect && (
             <Card title="检测参数" style={{ marginTop: 16 }} size="small">
@@ -453,6 +648,17 @@ const JobDetail: React.FC = () => {
                 <Descriptions.Item label="检测间隔">
                   {job.detect.interval !== undefined ? `${job.detect.interval} ms` : '-'}
                 </Descriptions.Item>
+                <Descriptions.Item label="优先级">
+                  {job.priority ?? 0}
+                </Descriptions.Item>
+                <Descriptions.Item label="最大帧率">
+                  {job.maxFps ? `${job.maxFps} 帧/秒` : '不限'}
+                </Descriptions.Item>
+                {!job.deviceGroup && (
+                  <Descriptions.Item label="实际帧率">
+                    {formatFrameRate(job.frameRate, job.frameRateLimit)}
+                  </Descriptions.Item>
+                )}
                 <Descriptions.Item label="触发次数">
                   {job.detect.triggerCount !== undefined ? job.detect.triggerCount : '-'}
                 </Descriptions.Item>
@@ -531,6 +737,30 @@ const JobDetail: React.FC = () => {
         </div>
       ),
     },
+    {
+      key: 'history',
+      label: '状态变更',
+      children: (
+        <div>
+          <Space style={{ marginBottom: 16 }}>
+            <Button icon={<ReloadOutlined />} onClick={fetchJobHistory}>刷新</Button>
+          </Space>
+          {historyLoading ? (
+            <Spin />
+          ) : history.length === 0 ? (
+            <Text type="secondary">暂无状态变更记录</Text>
+          ) : (
+            <Timeline
+              items={history.map((h) => ({
+                key: h.id,
+                color: historyColor(h),
+                children: renderHistoryItem(h),
+              }))}
+            />
+          )}
+        </div>
+      ),
+    },
     {
       key: 'stats',
       label: '统计',
@@ -621,6 +851,22 @@ const JobDetail: React.FC = () => {
                 启动
               </Button>
             )}
+            {job.enabled && !job.paused && (
+              <Button
+                icon={<PauseCircleOutlined />}
+                onClick={() => handleJobAction('pause')}
+              >
+                暂停
+              </Button>
+            )}
+            {job.enabled && job.paused && (
+              <Button
+                icon={<PlayCircleOutlined />}
+                onClick={() => handleJobAction('resume')}
+              >
+                恢复
+              </Button>
+            )}
             {job.enabled && (
               <Button
                 type="default"
@@ -1,8 +1,11 @@
 import React from 'react';
-import { Form, Input, Select, Button, message, InputNumber, Divider } from 'antd';
-import { jobApi, deviceApi, workflowApi, cameraApi } from '../../services/api';
-import type { Job, CreateJobRequest, JobKind, DetectOptions, VideoSegmentOptions, Workflow, Camera } from '../../types';
+import { Form, Input, Select, Button, message, InputNumber, Divider, Switch, Radio, Space, Modal, List, Tag } from 'antd';
+import { MinusCircleOutlined, PlusOutlined } from '@ant-design/icons';
+import { jobApi, deviceApi, deviceGroupApi, workflowApi, cameraApi } from '../../services/api';
+import type { Job, CreateJobRequest, JobKind, DetectOptions, VideoSegmentOptions, Workflow, Camera, DeviceGroupSpec, JobCheck } from '../../types';
 import { handleApiError } from '../../utils/helpers';
+import { WEEKDAY_OPTIONS } from '../../utils/constants';
+import ResultFilterFields from '../../components/ResultFilterFields';
 
 const { Option } = Select;
 
@@ -15,7 +18,9 @@ interface JobFormProps {
 const JobForm: React.FC<JobFormProps> = ({ job, onSubmit, onCancel }) => {
   const [form] = Form.useForm();
   const [loading, setLoading] = React.useState(false);
+  const [validating, setValidating] = React.useState(false);
   const [devices, setDevices] = React.useState<any[]>([]);
+  const [deviceGroups, setDeviceGroups] = React.useState<DeviceGroupSpec[]>([]);
   const [cameras, setCameras] = React.useState<Camera[]>([]);
   const [workflows, setWorkflows] = React.useState<Workflow[]>([]);
   const [selectedKind, setSelectedKind] = React.useState<JobKind>('detect');
@@ -42,6 +47,7 @@ const JobForm: React.FC<JobFormProps> = ({ job, onSubmit, onCancel }) => {
 
   React.useEffect(() => {
     fetchDevices();
+    deviceGroupApi.list({ start: 0, limit: 100 }).then((res) => setDeviceGroups(res.items || [])).catch(() => {});
     fetchWorkflows();
     cameraApi.list({ start: 0, limit: 100 }).then((res) => setCameras(res.items || [])).catch(() => {});
   }, []);
@@ -52,7 +58,11 @@ const JobForm: React.FC<JobFormProps> = ({ job, onSubmit, onCancel }) => {
       const initialValues: any = {
         kind: job.kind,
         cameraId: (job as any)?.camera?.id,
-        deviceId: job.device.id,
+        target: job.deviceGroup ? 'group' : 'device',
+        deviceId: job.device?.id,
+        deviceGroupId: job.deviceGroup?.id,
+        priority: job.priority,
+        maxFps: job.maxFps,
         // 后端返回的字段由 workflowId 改为 workflow 对象，这里取其 id 作为初始值
         workflowId: (job as any)?.workflow?.id,
       };
@@ -60,6 +70,12 @@ const JobForm: React.FC<JobFormProps> = ({ job, onSubmit, onCancel }) => {
       // Initialize detect options if present
       if (job.detect) {
         initialValues.detect = job.detect;
+        if (job.detect.occupancy) {
+          initialValues.occupancyEnabled = true;
+          if (job.detect.occupancy.zones?.length) {
+            initialValues.occupancyZones = JSON.stringify(job.detect.occupancy.zones, null, 2);
+          }
+        }
       }
 
       // Initialize video segment options if present
@@ -67,6 +83,19 @@ const JobForm: React.FC<JobFormProps> = ({ job, onSubmit, onCancel }) => {
         initialValues.videoSegment = job.videoSegment;
       }
 
+      if (job.talkDown) {
+        initialValues.talkDown = job.talkDown;
+      }
+
+      if (job.resultFilter) {
+        initialValues.resultFilter = job.resultFilter;
+      }
+
+      if (job.schedule) {
+        initialValues.scheduleWindows = job.schedule.windows;
+        initialValues.scheduleTimezone = job.schedule.timezone;
+      }
+
       form.setFieldsValue(initialValues);
       setSelectedKind(job.kind);
     }
@@ -83,24 +112,62 @@ const JobForm: React.FC<JobFormProps> = ({ job, onSubmit, onCancel }) => {
     }
   };
 
+  // 由表单值生成任务请求
+  const buildRequest = (values: any): CreateJobRequest => {
+    const data: CreateJobRequest = {
+      kind: values.kind,
+      cameraId: values.cameraId,
+      workflowId: values.workflowId,
+      priority: values.priority || 0,
+      maxFps: values.maxFps || 0,
+    };
+    // 运行主机与设备组二选一
+    if (values.target === 'group') {
+      data.deviceGroupId = values.deviceGroupId;
+      data.deviceId = 0;
+    } else {
+      data.deviceId = values.deviceId;
+      data.deviceGroupId = 0;
+    }
+
+    // Add specific options based on kind
+    if (values.kind === 'detect' && values.detect) {
+      data.detect = { ...values.detect };
+      if (values.occupancyEnabled) {
+        data.detect!.occupancy = {
+          ...values.detect.occupancy,
+          zones: values.occupancyZones ? JSON.parse(values.occupancyZones) : undefined,
+        };
+      } else {
+        delete data.detect!.occupancy;
+      }
+    } else if (values.kind === 'video_segment' && values.videoSegment) {
+      data.videoSegment = values.videoSegment;
+    }
+
+    if (values.talkDown) {
+      data.talkDown = values.talkDown;
+    }
+
+    // 没有过滤条件时使用工作流的过滤条件
+    data.resultFilter = {
+      combineOp: values.resultFilter?.combineOp || 'and',
+      conditions: values.resultFilter?.conditions || [],
+    };
+
+    // 没有布防时段时取消计划，任务始终布防
+    data.schedule = {
+      windows: values.scheduleWindows || [],
+      timezone: values.scheduleTimezone || undefined,
+    };
+    return data;
+  };
+
   // 处理表单提交
   const handleSubmit = async (values: any) => {
     setLoading(true);
     try {
-      const data: CreateJobRequest = {
-        kind: values.kind,
-        cameraId: values.cameraId,
-        workflowId: values.workflowId,
-        deviceId: values.deviceId,
-      };
-
-      // Add specific options based on kind
-      if (values.kind === 'detect' && values.detect) {
-        data.detect = values.detect;
-      } else if (values.kind === 'video_segment' && values.videoSegment) {
-        data.videoSegment = values.videoSegment;
-      }
-
+      const data = buildRequest(values);
       if (job) {
         await jobApi.update(job.id, data);
         message.success('更新任务成功');
@@ -111,16 +178,82 @@ const JobForm: React.FC<JobFormProps> = ({ job, onSubmit, onCancel }) => {
 
       onSubmit();
     } catch (error) {
-      handleApiError(error, job ? '更新任务失败' : '创建任务失败');
+      handleApiError(error, job ? '更新任务失败' : '创建任务失败', form);
     } finally {
       setLoading(false);
     }
   };
 
+  // 检查任务是否就绪
+  const handleValidate = async () => {
+    let values: any;
+    try {
+      values = await form.validateFields();
+    } catch {
+      return;
+    }
+    setValidating(true);
+    try {
+      const report = await jobApi.validate(buildRequest(values));
+      const checkLabels: Record<string, string> = {
+        target: '运行设备',
+        camera: '摄像头连通性',
+        model: '模型加载',
+        workflow: '工作流接口',
+      };
+      const statusTags: Record<JobCheck['status'], React.ReactNode> = {
+        ok: <Tag color="green">通过</Tag>,
+        failed: <Tag color="red">未通过</Tag>,
+        skipped: <Tag>未检查</Tag>,
+      };
+      Modal[report.ready ? 'success' : 'warning']({
+        title: report.ready ? '任务已就绪' : '任务未就绪',
+        width: 520,
+        content: (
+          <List
+            size="small"
+            dataSource={report.checks}
+            renderItem={(check) => (
+              <List.Item>
+                <Space direction="vertical" size={0}>
+                  <Space>
+                    {statusTags[check.status]}
+                    {checkLabels[check.name] || check.name}
+                  </Space>
+                  {check.message && <span style={{ color: '#888' }}>{check.message}</span>}
+                </Space>
+              </List.Item>
+            )}
+          />
+        ),
+      });
+    } catch (error) {
+      handleApiError(error, '检查任务失败');
+    } finally {
+      setValidating(false);
+    }
+  };
+
   // Render detect options form
   const renderDetectOptions = () => (
     <>
       <Divider>检测参数</Divider>
+      <Form.Item
+        name="priority"
+        label="优先级"
+        tooltip="主机并发已满时优先级高的任务先启动，推理算力不足时优先级低的任务先降帧"
+      >
+        <InputNumber placeholder="默认 0" style={{ width: '100%' }} />
+      </Form.Item>
+
+      <Form.Item
+        name="maxFps"
+        label="最大帧率"
+        tooltip="每秒最多推理的帧数，不填则只受检测间隔限制"
+      >
+        <InputNumber min={0} step={0.5} placeholder="帧/秒" style={{ width: '100%' }} />
+      </Form.Item>
+
       <Form.Item
         name={['detect', 'modelName']}
         label="模型名称"
@@ -201,6 +334,50 @@ const JobForm: React.FC<JobFormProps> = ({ job, onSubmit, onCancel }) => {
           style={{ width: '100%' }}
         />
       </Form.Item>
+
+      <Form.Item
+        name="occupancyEnabled"
+        label="区域人数统计"
+        valuePropName="checked"
+        tooltip="跟踪检测目标，统计各区域的人数和进出人次"
+      >
+        <Switch />
+      </Form.Item>
+
+      <Form.Item noStyle shouldUpdate={(prev, cur) => prev.occupancyEnabled !== cur.occupancyEnabled}>
+        {({ getFieldValue }) => getFieldValue('occupancyEnabled') && (
+          <>
+            <Form.Item name={['detect', 'occupancy', 'labels']} label="统计类别">
+              <Input placeholder="逗号分隔，默认 person" />
+            </Form.Item>
+            <Form.Item name={['detect', 'occupancy', 'interval']} label="上报间隔">
+              <InputNumber min={1} placeholder="上报间隔（秒），默认 60" style={{ width: '100%' }} />
+            </Form.Item>
+            <Form.Item
+              name="occupancyZones"
+              label="统计区域"
+              tooltip="JSON 数组，坐标按画面宽高归一化到 0~1，为空时统计整个画面"
+              rules={[{
+                validator: (_, value) => {
+                  if (!value) return Promise.resolve();
+                  try {
+                    const zones = JSON.parse(value);
+                    if (Array.isArray(zones)) return Promise.resolve();
+                  } catch (e) {
+                    // fall through
+                  }
+                  return Promise.reject(new Error('请输入合法的 JSON 数组'));
+                },
+              }]}
+            >
+              <Input.TextArea
+                rows={4}
+                placeholder='[{"name": "入口", "polygon": [[0, 0.5], [0.5, 0.5], [0.5, 1], [0, 1]]}]'
+              />
+            </Form.Item>
+          </>
+        )}
+      </Form.Item>
     </>
   );
 
@@ -258,18 +435,46 @@ const JobForm: React.FC<JobFormProps> = ({ job, onSubmit, onCancel }) => {
         </Select>
       </Form.Item>
 
-      <Form.Item
-        name="deviceId"
-        label="运行主机"
-        rules={[{ required: true, message: '请选择运行主机' }]}
-      >
-        <Select placeholder="请选择设备" allowClear>
-          {devices.map((device) => (
-            <Option key={device.id} value={device.id}>
-              {device.name} ({device.uuid})
-            </Option>
-          ))}
-        </Select>
+      <Form.Item name="target" label="运行目标" initialValue="device">
+        <Radio.Group>
+          <Radio value="device">单台主机</Radio>
+          <Radio value="group">设备组</Radio>
+        </Radio.Group>
+      </Form.Item>
+
+      <Form.Item noStyle shouldUpdate={(prev, cur) => prev.target !== cur.target}>
+        {({ getFieldValue }) =>
+          getFieldValue('target') === 'group' ? (
+            <Form.Item
+              name="deviceGroupId"
+              label="设备组"
+              tooltip="任务会在组内所有主机上运行"
+              rules={[{ required: true, message: '请选择设备组' }]}
+            >
+              <Select placeholder="请选择设备组" allowClear>
+                {deviceGroups.map((group) => (
+                  <Option key={group.id} value={group.id}>
+                    {group.name}
+                  </Option>
+                ))}
+              </Select>
+            </Form.Item>
+          ) : (
+            <Form.Item
+              name="deviceId"
+              label="运行主机"
+              rules={[{ required: true, message: '请选择运行主机' }]}
+            >
+              <Select placeholder="请选择设备" allowClear>
+                {devices.map((device) => (
+                  <Option key={device.id} value={device.id}>
+                    {device.name} ({device.uuid})
+                  </Option>
+                ))}
+              </Select>
+            </Form.Item>
+          )
+        }
       </Form.Item>
 
       <Form.Item
@@ -290,12 +495,109 @@ const JobForm: React.FC<JobFormProps> = ({ job, onSubmit, onCancel }) => {
       {selectedKind === 'detect' && renderDetectOptions()}
       {selectedKind === 'video_segment' && renderVideoSegmentOptions()}
 
+      <Divider>告警喊话</Divider>
+      <Form.Item
+        name={['talkDown', 'enabled']}
+        label="告警时现场喊话"
+        valuePropName="checked"
+      >
+        <Switch />
+      </Form.Item>
+
+      <Form.Item
+        name={['talkDown', 'text']}
+        label="喊话文本"
+        tooltip="由设备合成语音播放，填写音频地址时忽略"
+      >
+        <Input.TextArea rows={2} placeholder="例如：您已进入监控区域，请立即离开" />
+      </Form.Item>
+
+      <Form.Item
+        name={['talkDown', 'audioUrl']}
+        label="音频地址"
+      >
+        <Input placeholder="设备可访问的音频文件地址（可选）" />
+      </Form.Item>
+
+      <Form.Item
+        name={['talkDown', 'output']}
+        label="播放方式"
+        initialValue="onvif"
+      >
+        <Select>
+          <Option value="onvif">摄像头音频回传（ONVIF）</Option>
+          <Option value="local">设备本地音频输出</Option>
+        </Select>
+      </Form.Item>
+
+      <Form.Item
+        name={['talkDown', 'cooldown']}
+        label="冷却时间"
+      >
+        <InputNumber
+          min={0}
+          placeholder="两次告警喊话的最小间隔（秒）"
+          style={{ width: '100%' }}
+        />
+      </Form.Item>
+
+      <Divider>告警过滤</Divider>
+      <ResultFilterFields extra="不设置条件时使用工作流的过滤条件，均未设置时按工作流的匹配结果告警" />
+
+      <Divider>布防计划</Divider>
+      <Form.List name="scheduleWindows">
+        {(fields, { add, remove }) => (
+          <>
+            {fields.map(({ key, name, ...restField }) => (
+              <Space key={key} align="baseline" style={{ display: 'flex', marginBottom: 8 }}>
+                <Form.Item {...restField} name={[name, 'days']}>
+                  <Select mode="multiple" placeholder="每天" options={WEEKDAY_OPTIONS} style={{ width: 240 }} />
+                </Form.Item>
+                <Form.Item
+                  {...restField}
+                  name={[name, 'start']}
+                  rules={[{ required: true, pattern: /^([01]\d|2[0-3]):[0-5]\d$/, message: '格式为 HH:MM' }]}
+                >
+                  <Input placeholder="开始 22:00" style={{ width: 110 }} />
+                </Form.Item>
+                <Form.Item
+                  {...restField}
+                  name={[name, 'end']}
+                  rules={[{ required: true, pattern: /^([01]\d|2[0-3]):[0-5]\d$/, message: '格式为 HH:MM' }]}
+                >
+                  <Input placeholder="结束 06:00" style={{ width: 110 }} />
+                </Form.Item>
+                <MinusCircleOutlined onClick={() => remove(name)} />
+              </Space>
+            ))}
+            <Form.Item
+              extra="不设置时段时任务始终布防；结束时间不晚于开始时间时跨越午夜，如 22:00-06:00，00:00-00:00 为全天"
+            >
+              <Button type="dashed" onClick={() => add({ start: '22:00', end: '06:00' })} icon={<PlusOutlined />}>
+                添加布防时段
+              </Button>
+            </Form.Item>
+          </>
+        )}
+      </Form.List>
+
+      <Form.Item
+        name="scheduleTimezone"
+        label="时区"
+        tooltip="为空时使用设备本地时区"
+      >
+        <Input placeholder="例如：Asia/Shanghai" />
+      </Form.Item>
+
       <Divider />
 
       <Form.Item>
         <Button type="primary" htmlType="submit" loading={loading} style={{ marginRight: 8}}>
           {job ? '更新' : '创建'}
         </Button>
+        <Button onClick={handleValidate} loading={validating} style={{ marginRight: 8}}>
+          检查就绪
+        </Button>
         <Button onClick={onCancel}>
           取消
         </Button>
@@ -1,4 +1,4 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useRef } from 'react';
 import {
   Table,
   Button,
@@ -10,6 +10,7 @@ import {
   Input,
   Select,
   Drawer,
+  Form,
 } from 'antd';
 import {
   PlusOutlined,
@@ -19,12 +20,13 @@ import {
   PlayCircleOutlined,
   PauseCircleOutlined,
   ReloadOutlined,
+  CopyOutlined,
 } from '@ant-design/icons';
 import type { ColumnsType } from 'antd/es/table';
 import { useNavigate } from 'react-router-dom';
-import { jobApi } from '../../services/api';
-import type { Job, ListParams, CameraSpec, DeviceSpec, WorkflowSpec } from '../../types';
-import { formatDate, handleApiError, getDeleteConfirmConfig, isOlderThanMinutes } from '../../utils/helpers';
+import { jobApi, cameraApi, deviceApi, graphqlApi, eventsApi } from '../../services/api';
+import type { Job, ListParams, Camera, CameraSpec, Device, DeviceSpec, WorkflowSpec } from '../../types';
+import { formatDate, handleApiError, getDeleteConfirmConfig, isOlderThanMinutes, debounce } from '../../utils/helpers';
 import { JOB_STATUS_MAP, JOB_KIND_MAP, DEFAULT_PAGE_SIZE } from '../../utils/constants';
 import JobForm from './JobForm';
 
@@ -42,6 +44,11 @@ const JobList: React.FC = () => {
   const [statusFilter, setStatusFilter] = useState<string>('');
   const [drawerVisible, setDrawerVisible] = useState(false);
   const [editingJob, setEditingJob] = useState<Job | null>(null);
+  const [cloneForm] = Form.useForm();
+  const [cloningJob, setCloningJob] = useState<Job | null>(null);
+  const [cloning, setCloning] = useState(false);
+  const [cameras, setCameras] = useState<Camera[]>([]);
+  const [devices, setDevices] = useState<Device[]>([]);
 
   // 获取任务列表
   const fetchJobs = async () => {
@@ -66,6 +73,20 @@ const JobList: React.FC = () => {
     fetchJobs();
   }, [current, pageSize]);
 
+  // 当前页的任务状态变更或有新任务时重新获取，合并短时间内的多次变更
+  const jobsRef = useRef<Job[]>([]);
+  jobsRef.current = jobs;
+  useEffect(() => {
+    const refresh = debounce(fetchJobs, 1000);
+    return eventsApi.subscribe(['job_status'], (event) => {
+      const changed = event.job;
+      if (!changed) return;
+      if (changed.transition.status === 'create' || jobsRef.current.some((j) => j.id === changed.jobId)) {
+        refresh();
+      }
+    });
+  }, [current, pageSize]);
+
   // 处理创建任务
   const handleCreate = () => {
     setEditingJob(null);
@@ -156,6 +177,47 @@ const JobList: React.FC = () => {
     }
   };
 
+  // 打开复制任务弹窗
+  const handleOpenClone = (job: Job) => {
+    cloneForm.resetFields();
+    setCloningJob(job);
+    fetchCloneOptions();
+  };
+
+  // 获取复制任务可选的摄像头和主机，服务端未开启 GraphQL 时分别请求
+  const fetchCloneOptions = async () => {
+    try {
+      const data = await graphqlApi.query<{ cameras: { items: Camera[] }; devices: { items: Device[] } }>(
+        '{ cameras(limit: 100) { items { id uuid name } } devices(limit: 100) { items { id uuid name } } }',
+      );
+      setCameras(data.cameras.items);
+      setDevices(data.devices.items);
+    } catch {
+      cameraApi.list({ start: 0, limit: 100 }).then((res) => setCameras(res.items || [])).catch(() => {});
+      deviceApi.list({ start: 0, limit: 100 }).then((res) => setDevices(res.devices || [])).catch(() => {});
+    }
+  };
+
+  // 处理复制任务，未选择的摄像头和主机沿用原任务
+  const handleClone = async () => {
+    if (!cloningJob?.id) return;
+    const values = cloneForm.getFieldsValue();
+    setCloning(true);
+    try {
+      const res = await jobApi.clone(cloningJob.id, {
+        cameraId: values.cameraId,
+        deviceId: values.deviceId,
+      });
+      message.success(`复制成功，新任务 ${res.uuid}`);
+      setCloningJob(null);
+      fetchJobs();
+    } catch (error) {
+      handleApiError(error, '复制任务失败');
+    } finally {
+      setCloning(false);
+    }
+  };
+
   // 处理表单提交
   const handleFormSubmit = () => {
     setDrawerVisible(false);
@@ -224,7 +286,9 @@ const JobList: React.FC = () => {
       width: 100,
       ellipsis: true,
       render: (device: any, record: Job) => {
-        if (!device) return '-';
+        if (!device) {
+          return record.deviceGroup ? <Tag color="purple">{record.deviceGroup.name}</Tag> : '-';
+        }
         return (
           <Button
             type="link"
@@ -286,7 +350,7 @@ const JobList: React.FC = () => {
     {
       title: '操作',
       key: 'action',
-      width: 120,
+      width: 150,
       render: (_, record) => (
         <Space size="small">
           <Button
@@ -303,6 +367,13 @@ const JobList: React.FC = () => {
             onClick={() => handleEdit(record)}
             title="编辑"
           />
+          <Button
+            type="text"
+            size="small"
+            icon={<CopyOutlined />}
+            onClick={() => handleOpenClone(record)}
+            title="复制"
+          />
           {!record.enabled && (
             <Button
               type="text"
@@ -412,6 +483,36 @@ const JobList: React.FC = () => {
           onCancel={() => setDrawerVisible(false)}
         />
       </Drawer>
+
+      <Modal
+        title={`复制任务 ${cloningJob?.uuid || ''}`}
+        open={!!cloningJob}
+        onOk={handleClone}
+        onCancel={() => setCloningJob(null)}
+        confirmLoading={cloning}
+        destroyOnClose
+      >
+        <Form form={cloneForm} layout="vertical">
+          <Form.Item name="cameraId" label="摄像头" tooltip="不选择则沿用原任务的摄像头">
+            <Select placeholder={cloningJob?.camera?.name || '沿用原任务'} allowClear showSearch optionFilterProp="children">
+              {cameras.map((camera) => (
+                <Option key={camera.id} value={camera.id}>
+                  {camera.name || camera.uuid}
+                </Option>
+              ))}
+            </Select>
+          </Form.Item>
+          <Form.Item name="deviceId" label="运行主机" tooltip="不选择则沿用原任务的主机或设备组">
+            <Select placeholder={cloningJob?.device?.name || cloningJob?.deviceGroup?.name || '沿用原任务'} allowClear showSearch optionFilterProp="children">
+              {devices.map((device) => (
+                <Option key={device.id} value={device.id}>
+                  {device.name || device.uuid}
+                </Option>
+              ))}
+            </Select>
+          </Form.Item>
+        </Form>
+      </Modal>
     </Card>
   );
 };
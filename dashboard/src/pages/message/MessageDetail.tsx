@@ -9,6 +9,7 @@ import {
   Typography,
   Spin,
   Image,
+  Tag,
 } from 'antd';
 import {
   ArrowLeftOutlined,
@@ -16,6 +17,8 @@ import {
   DeleteOutlined,
   PlayCircleOutlined,
   FileImageOutlined,
+  SoundOutlined,
+  SafetyCertificateOutlined,
 } from '@ant-design/icons';
 import { useParams, useNavigate } from 'react-router-dom';
 import { messageApi, jobApi } from '../../services/api';
@@ -31,6 +34,8 @@ const MessageDetail: React.FC = () => {
   const [messageDetail, setMessageDetail] = useState<MessageSpec | null>(null);
   const [job, setJob] = useState<JobSpec | null>(null);
   const [loading, setLoading] = useState(false);
+  const [talkingDown, setTalkingDown] = useState(false);
+  const [verifying, setVerifying] = useState(false);
 
   // 获取消息详情
   const fetchMessageDetail = async () => {
@@ -78,6 +83,65 @@ const MessageDetail: React.FC = () => {
     });
   };
 
+  // 现场喊话
+  const handleTalkDown = async () => {
+    if (!messageDetail) return;
+    setTalkingDown(true);
+    try {
+      const result = await messageApi.talkDown(messageDetail.id);
+      if (result.error) {
+        message.error(`喊话失败: ${result.error}`);
+      } else {
+        message.success('喊话完成');
+      }
+    } catch (error) {
+      handleApiError(error, '喊话失败');
+    } finally {
+      setTalkingDown(false);
+    }
+  };
+
+  // 校验设备签名
+  const handleVerify = async () => {
+    if (!messageDetail) return;
+    setVerifying(true);
+    try {
+      const report = await messageApi.verify(messageDetail.id);
+      Modal.info({
+        title: report.verified ? '签名校验通过' : '签名校验未通过',
+        width: 640,
+        content: (
+          <Descriptions column={1} size="small">
+            <Descriptions.Item label="设备">{report.deviceUuid}</Descriptions.Item>
+            <Descriptions.Item label="公钥指纹">
+              <Text code copyable>{report.keyFingerprint}</Text>
+            </Descriptions.Item>
+            <Descriptions.Item label="签名">
+              {report.signatureValid ? <Tag color="green">有效</Tag> : <Tag color="red">无效</Tag>}
+            </Descriptions.Item>
+            <Descriptions.Item label="内容">
+              {report.contentMatches
+                ? <Tag color="green">一致</Tag>
+                : <Tag color="red">不一致 {report.mismatches?.join(', ')}</Tag>}
+            </Descriptions.Item>
+            {report.media?.map((m) => (
+              <Descriptions.Item key={m.path} label="文件">
+                {m.matches ? <Tag color="green">哈希一致</Tag> : <Tag color="red">{m.error || '哈希不一致'}</Tag>}
+                <Text type="secondary">{m.path}</Text>
+              </Descriptions.Item>
+            ))}
+            {report.error && <Descriptions.Item label="错误">{report.error}</Descriptions.Item>}
+            <Descriptions.Item label="校验时间">{formatDate(report.verifyTime)}</Descriptions.Item>
+          </Descriptions>
+        ),
+      });
+    } catch (error) {
+      handleApiError(error, '签名校验失败');
+    } finally {
+      setVerifying(false);
+    }
+  };
+
   // 渲染媒体内容
   const renderMedia = () => {
     if (!messageDetail) return null;
@@ -153,6 +217,24 @@ const MessageDetail: React.FC = () => {
             </Title>
           </div>
           <Space>
+            {messageDetail.signed && (
+              <Button
+                icon={<SafetyCertificateOutlined />}
+                loading={verifying}
+                onClick={handleVerify}
+              >
+                校验签名
+              </Button>
+            )}
+            {job?.talkDown && (job.talkDown.text || job.talkDown.audioUrl) && (
+              <Button
+                icon={<SoundOutlined />}
+                loading={talkingDown}
+                onClick={handleTalkDown}
+              >
+                现场喊话
+              </Button>
+            )}
             <Button
               danger
               icon={<DeleteOutlined />}
@@ -208,14 +290,28 @@ const MessageDetail: React.FC = () => {
               }}>
                 {messageDetail.detectBoxes.map((box, index) => (
                   <div key={index} style={{ marginBottom: 8 }}>
-                    <Text strong>{box.class}</Text> - 
+                    <Text strong>{box.label || box.classId}</Text> - 
                     置信度: {(box.confidence * 100).toFixed(1)}%, 
-                    位置: ({box.x}, {box.y}, {box.width}, {box.height})
+                    位置: ({box.x1}, {box.y1}, {box.x2}, {box.y2})
                   </div>
                 ))}
               </div>
             </Descriptions.Item>
           )}
+          {messageDetail.frameWidth && messageDetail.frameHeight ? (
+            <Descriptions.Item label="画面尺寸">
+              {messageDetail.frameWidth} × {messageDetail.frameHeight}
+            </Descriptions.Item>
+          ) : null}
+          {messageDetail.sensors && Object.keys(messageDetail.sensors).length > 0 && (
+            <Descriptions.Item label="传感器" span={2}>
+              <Space wrap>
+                {Object.entries(messageDetail.sensors).map(([name, value]) => (
+                  <Tag key={name}>{name}: {typeof value === 'object' ? JSON.stringify(value) : String(value)}</Tag>
+                ))}
+              </Space>
+            </Descriptions.Item>
+          )}
           {(messageDetail.imagePath || messageDetail.videoPath) && (
             <Descriptions.Item label="媒体内容" span={2}>
               {renderMedia()}
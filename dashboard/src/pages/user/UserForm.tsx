@@ -27,7 +27,7 @@ const UserForm: React.FC<UserFormProps> = ({ onSubmit, onCancel }) => {
       message.success('创建用户成功');
       onSubmit();
     } catch (error) {
-      handleApiError(error, '创建用户失败');
+      handleApiError(error, '创建用户失败', form);
     } finally {
       setLoading(false);
     }
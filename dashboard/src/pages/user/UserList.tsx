@@ -8,11 +8,15 @@ import {
   Card,
   Input,
   Drawer,
+  Typography,
 } from 'antd';
 import {
   PlusOutlined,
   DeleteOutlined,
   ReloadOutlined,
+  KeyOutlined,
+  SafetyOutlined,
+  LogoutOutlined,
 } from '@ant-design/icons';
 import type { ColumnsType } from 'antd/es/table';
 import { userApi } from '../../services/api';
@@ -75,6 +79,68 @@ const UserList: React.FC = () => {
     });
   };
 
+  // 处理重置密码，临时密码只显示这一次
+  const handleResetPassword = (user: User) => {
+    Modal.confirm({
+      title: `重置用户 "${user.username}" 的密码`,
+      content: '重置后原密码立即失效，请将临时密码告知用户并提醒其登录后修改。',
+      okText: '重置',
+      cancelText: '取消',
+      onOk: async () => {
+        try {
+          const { password } = await userApi.resetPassword(user.id);
+          Modal.success({
+            title: '密码已重置',
+            content: (
+              <Typography.Paragraph copyable={{ text: password }}>
+                临时密码：<Typography.Text code>{password}</Typography.Text>
+              </Typography.Paragraph>
+            ),
+          });
+        } catch (error) {
+          handleApiError(error, '重置密码失败');
+        }
+      },
+    });
+  };
+
+  // 处理重置两步验证，用户丢失验证器时使用
+  const handleResetTotp = (user: User) => {
+    Modal.confirm({
+      title: `重置用户 "${user.username}" 的两步验证`,
+      content: '重置后用户仅凭密码即可登录，需重新启用两步验证。',
+      okText: '重置',
+      cancelText: '取消',
+      onOk: async () => {
+        try {
+          await userApi.resetTotp(user.id);
+          message.success('两步验证已重置');
+          fetchUsers();
+        } catch (error) {
+          handleApiError(error, '重置两步验证失败');
+        }
+      },
+    });
+  };
+
+  // 处理强制登出，用于账号疑似被盗用时
+  const handleForceLogout = (user: User) => {
+    Modal.confirm({
+      title: `强制用户 "${user.username}" 登出`,
+      content: '用户的全部登录会话立即失效，需重新登录，API 令牌不受影响。',
+      okText: '登出',
+      cancelText: '取消',
+      onOk: async () => {
+        try {
+          await userApi.forceLogout(user.id);
+          message.success('用户已登出');
+        } catch (error) {
+          handleApiError(error, '强制登出失败');
+        }
+      },
+    });
+  };
+
   // 处理表单提交
   const handleFormSubmit = () => {
     setDrawerVisible(false);
@@ -119,9 +185,32 @@ const UserList: React.FC = () => {
     {
       title: '操作',
       key: 'action',
-      width: 100,
+      width: 150,
       render: (_, record) => (
         <Space size="small">
+          <Button
+            type="text"
+            size="small"
+            icon={<KeyOutlined />}
+            onClick={() => handleResetPassword(record)}
+            title="重置密码"
+          />
+          <Button
+            type="text"
+            size="small"
+            icon={<LogoutOutlined />}
+            onClick={() => handleForceLogout(record)}
+            title="强制登出"
+          />
+          {record.totpEnabled && (
+            <Button
+              type="text"
+              size="small"
+              icon={<SafetyOutlined />}
+              onClick={() => handleResetTotp(record)}
+              title="重置两步验证"
+            />
+          )}
           <Button
             type="text"
             size="small"
@@ -34,6 +34,12 @@ const OPERATOR_LABELS: Record<string, string> = {
   ends_with: '结尾为',
   empty: '为空',
   not_empty: '不为空',
+  gt: '大于',
+  ge: '大于等于',
+  lt: '小于',
+  le: '小于等于',
+  between: '介于',
+  not_between: '不介于',
 };
 
 // 结果过滤内联文本生成函数
@@ -1,9 +1,9 @@
 import React from 'react';
-import { Form, Input, Button, Space, message, InputNumber, Select, Divider, Card } from 'antd';
+import { Form, Input, Button, Space, message, InputNumber, Divider } from 'antd';
 import { workflowApi } from '../../services/api';
 import type { Workflow, CreateWorkflowRequest, UpdateWorkflowRequest } from '../../types';
 import { handleApiError } from '../../utils/helpers';
-import { DeleteOutlined } from '@ant-design/icons';
+import ResultFilterFields from '../../components/ResultFilterFields';
 
 interface WorkflowFormProps {
   workflow?: Workflow | null;
@@ -62,7 +62,7 @@ const WorkflowForm: React.FC<WorkflowFormProps> = ({ workflow, onSubmit, onCance
       }
       onSubmit();
     } catch (error) {
-      handleApiError(error, isEditing ? '更新工作流失败' : '创建工作流失败');
+      handleApiError(error, isEditing ? '更新工作流失败' : '创建工作流失败', form);
     } finally {
       setLoading(false);
     }
@@ -151,67 +151,7 @@ const WorkflowForm: React.FC<WorkflowFormProps> = ({ workflow, onSubmit, onCance
       </Form.Item>
 
       <Divider>结果过滤</Divider>
-      <Form.Item name={["resultFilter", "combineOp"]} label="组合逻辑">
-        <Select placeholder="请选择逻辑">
-          <Select.Option value="and">并且 (AND)</Select.Option>
-          <Select.Option value="or">或者 (OR)</Select.Option>
-        </Select>
-      </Form.Item>
-
-      <Form.List name={["resultFilter", "conditions"]}>
-        {(fields, { add, remove }) => (
-          <div>
-            {fields.map((field) => (
-              <Card key={field.key} size="small" style={{ marginBottom: 8 }}>
-                <Space align="center" size="small">
-                  <Form.Item
-                    {...field}
-                    name={[field.name, 'op']}
-                    fieldKey={[field.fieldKey!, 'op']}
-                    rules={[{ required: true, message: '请选择操作符' }]}
-                    style={{ marginBottom: 0 }}
-                  >
-                    <Select placeholder="选择操作符" style={{ width: 120 }}>
-                      <Select.Option value="eq">等于</Select.Option>
-                      <Select.Option value="ne">不等于</Select.Option>
-                      <Select.Option value="in">包含于</Select.Option>
-                      <Select.Option value="not_in">不包含于</Select.Option>
-                      <Select.Option value="contains">包含</Select.Option>
-                      <Select.Option value="not_contains">不包含</Select.Option>
-                      <Select.Option value="starts_with">开头为</Select.Option>
-                      <Select.Option value="ends_with">结尾为</Select.Option>
-                      <Select.Option value="empty">为空</Select.Option>
-                      <Select.Option value="not_empty">不为空</Select.Option>
-                    </Select>
-                  </Form.Item>
-
-                  <Form.Item
-                    {...field}
-                    name={[field.name, 'value']}
-                    fieldKey={[field.fieldKey!, 'value']}
-                    style={{ marginBottom: 0 }}
-                  >
-                    <Input placeholder="匹配值（empty/not_empty 可留空）" style={{ width: 360 }} />
-                  </Form.Item>
-
-                  <Button
-                    type="text"
-                    size="small"
-                    danger
-                    icon={<DeleteOutlined />}
-                    onClick={() => remove(field.name)}
-                    title="删除条件"
-                  >
-                  </Button>
-                </Space>
-              </Card>
-            ))}
-            <Button type="dashed" onClick={() => add()} block>
-              新增条件
-            </Button>
-          </div>
-        )}
-      </Form.List>
+      <ResultFilterFields />
 
       <Divider />
 
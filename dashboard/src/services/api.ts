@@ -5,6 +5,19 @@ import type {
   CreateUserRequest,
   CreateUserResponse,
   User,
+  UpdateProfileRequest,
+  ChangePasswordRequest,
+  ResetPasswordResponse,
+  TotpSetupResponse,
+  TotpRecoveryCodesResponse,
+  ListApiTokensResponse,
+  CreateApiTokenRequest,
+  CreateApiTokenResponse,
+  RefreshResponse,
+  ListSessionsResponse,
+  DisableTotpRequest,
+  Organization,
+  ListOrganizationsResponse,
 
   // Device types
   ListDeviceResponse,
@@ -50,10 +63,15 @@ import type {
   ListParams,
 } from '../types';
 
+// 仪表盘与服务器分开部署时，REACT_APP_API_URL 为服务器地址，如 https://lumina.example.com，
+// 服务器需要在 cors 配置中允许仪表盘的地址
+export const apiBaseUrl = `${process.env.REACT_APP_API_URL || ''}/api/v1`;
+
 // 创建 axios 实例
 const api = axios.create({
-  baseURL: '/api/v1',
+  baseURL: apiBaseUrl,
   timeout: 10000,
+  withCredentials: true,
 });
 
 // 请求拦截器
@@ -71,12 +89,42 @@ api.interceptors.request.use(
   }
 );
 
+// 登录凭证过期后用 refresh_token cookie 换取新的，并发的请求共用同一次刷新，
+// 刷新令牌每次刷新后作废，重复使用会导致会话被注销
+let refreshing: Promise<void> | null = null;
+
+const refreshSession = (): Promise<void> => {
+  if (!refreshing) {
+    refreshing = axios
+      .post<RefreshResponse>(`${apiBaseUrl}/refresh`, {}, { withCredentials: true })
+      .then((resp) => {
+        if (localStorage.getItem('token')) {
+          localStorage.setItem('token', resp.data.token);
+        }
+      })
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+  return refreshing;
+};
+
 // 响应拦截器
 api.interceptors.response.use(
   (response) => {
     return response.data;
   },
-  (error) => {
+  async (error) => {
+    const config = error.config;
+    if (error.response?.status === 401 && config && !config._retried && !config.url?.startsWith('/login')) {
+      config._retried = true;
+      try {
+        await refreshSession();
+      } catch {
+        return Promise.reject(error);
+      }
+      return api(config);
+    }
     console.error('API Error:', error);
     return Promise.reject(error);
   }
@@ -95,12 +143,87 @@ export const userApi = {
   // 删除用户
   delete: (userId: number): Promise<void> =>
     api.delete(`/admin/user/${userId}`),
+
+  // 获取当前用户信息
+  getProfile: (): Promise<User> =>
+    api.get('/settings/profile'),
+
+  // 更新当前用户偏好，如显示时区
+  updateProfile: (data: UpdateProfileRequest): Promise<User> =>
+    api.put('/settings/profile', data),
+
+  // 修改当前用户密码
+  changePassword: (data: ChangePasswordRequest): Promise<void> =>
+    api.put('/settings/password', data),
+
+  // 管理员重置用户密码，返回临时密码
+  resetPassword: (userId: number): Promise<ResetPasswordResponse> =>
+    api.post(`/admin/user/${userId}/password/reset`),
+
+  // 开始启用两步验证，返回密钥和二维码地址
+  setupTotp: (): Promise<TotpSetupResponse> =>
+    api.post('/settings/totp'),
+
+  // 用验证码确认并启用两步验证，返回恢复码
+  enableTotp: (code: string): Promise<TotpRecoveryCodesResponse> =>
+    api.post('/settings/totp/enable', { code }),
+
+  // 停用两步验证
+  disableTotp: (data: DisableTotpRequest): Promise<void> =>
+    api.post('/settings/totp/disable', data),
+
+  // 重新生成恢复码
+  regenerateRecoveryCodes: (code: string): Promise<TotpRecoveryCodesResponse> =>
+    api.post('/settings/totp/recovery-codes', { code }),
+
+  // 管理员强制用户登出，已签发的登录凭证立即失效
+  forceLogout: (userId: number): Promise<void> =>
+    api.post(`/admin/user/${userId}/logout`),
+
+  // 管理员重置用户的两步验证
+  resetTotp: (userId: number): Promise<void> =>
+    api.delete(`/admin/user/${userId}/totp`),
+
+  // 列出当前用户的 API 令牌
+  listApiTokens: (): Promise<ListApiTokensResponse> =>
+    api.get('/settings/tokens'),
+
+  // 创建 API 令牌，令牌只在返回中出现一次
+  createApiToken: (data: CreateApiTokenRequest): Promise<CreateApiTokenResponse> =>
+    api.post('/settings/tokens', data),
+
+  // 吊销 API 令牌
+  revokeApiToken: (tokenId: number): Promise<void> =>
+    api.delete(`/settings/token/${tokenId}`),
+
+  // 列出当前用户的登录会话
+  listSessions: (): Promise<ListSessionsResponse> =>
+    api.get('/settings/sessions'),
+
+  // 注销登录会话
+  revokeSession: (sessionId: string): Promise<void> =>
+    api.delete(`/settings/session/${sessionId}`),
+};
+
+// 组织 API，仅默认组织的管理员可用
+export const organizationApi = {
+  // 获取组织列表
+  list: (): Promise<ListOrganizationsResponse> =>
+    api.get('/admin/organizations'),
+
+  // 创建组织
+  create: (name: string): Promise<Organization> =>
+    api.post('/admin/organizations', { name }),
+
+  // 删除组织
+  delete: (orgId: number): Promise<void> =>
+    api.delete(`/admin/organization/${orgId}`),
 };
 
 // 设备 API
 export const deviceApi = {
   // 获取设备列表
-  list: (params: ListParams): Promise<ListDeviceResponse> =>
+  list: (params: ListParams & { state?: import('../types').DeviceState }): Promise<ListDeviceResponse> =>
     api.get('/device', { params }),
 
   // 获取设备详情
@@ -118,6 +241,18 @@ export const deviceApi = {
   // 删除设备
   delete: (deviceId: number): Promise<void> =>
     api.delete(`/device/${deviceId}`),
+
+  // 要求设备在下次上报时轮换令牌
+  rotateToken: (deviceId: number): Promise<void> =>
+    api.put(`/device/${deviceId}/rotate-token`),
+
+  // 按软件版本筛选设备
+  inventory: (params: import('../types').ListDeviceInventoryRequest): Promise<import('../types').ListDeviceInventoryResponse> =>
+    api.get('/device/inventory', { params }),
+
+  // 获取设备上所有任务的消息、告警和帧率趋势
+  stats: (deviceId: number, params?: JobStatsRequest): Promise<import('../types').DeviceStatsResponse> =>
+    api.get(`/device/${deviceId}/stats`, { params }),
 };
 
 // 接入凭证 API
@@ -153,6 +288,10 @@ export const jobApi = {
   create: (data: CreateJobRequest): Promise<CreateJobResponse> =>
     api.post('/job', data),
 
+  // 检查任务是否就绪，不保存任务
+  validate: (data: CreateJobRequest): Promise<import('../types').ValidateJobResponse> =>
+    api.post('/job/validate', data),
+
   // 更新任务
   update: (jobId: number, data: Partial<UpdateJobRequest>): Promise<JobSpec> =>
     api.put(`/job/${jobId}`, data),
@@ -169,24 +308,265 @@ export const jobApi = {
   stop: (jobId: string | number): Promise<void> =>
     api.put(`/job/${jobId}/stop`),
 
+  // 暂停任务
+  pause: (jobId: string | number): Promise<void> =>
+    api.put(`/job/${jobId}/pause`),
+
+  // 恢复任务
+  resume: (jobId: string | number): Promise<void> =>
+    api.put(`/job/${jobId}/resume`),
+
   // 获取任务统计
   stats: (jobId: number, params?: JobStatsRequest): Promise<JobStatsResponse> =>
     api.get(`/job/${jobId}/stats`, { params }),
+
+  // 获取任务检测准确率趋势，基于消息的人工标注
+  precision: (jobId: number, params?: import('../types').JobPrecisionRequest): Promise<import('../types').JobPrecisionResponse> =>
+    api.get(`/job/${jobId}/precision`, { params }),
+
+  // 获取设备组任务在各设备上的状态
+  deviceStatus: (jobId: number): Promise<import('../types').ListJobDeviceStatusResponse> =>
+    api.get(`/job/${jobId}/device-status`),
+
+  // 获取任务状态变更记录
+  history: (jobId: number, params?: ListParams): Promise<import('../types').ListJobStatusHistoryResponse> =>
+    api.get(`/job/${jobId}/history`, { params }),
+
+  // 获取对象存储不可用时设备随状态上报的小文件
+  artifacts: (jobId: number): Promise<import('../types').ListJobArtifactsResponse> =>
+    api.get(`/job/${jobId}/artifacts`),
+
+  // 复制任务，可指定新的摄像头或设备
+  clone: (jobId: number, data: import('../types').CloneJobRequest): Promise<CreateJobResponse> =>
+    api.post(`/job/${jobId}/clone`, data),
+};
+
+// 设备组 API
+export const deviceGroupApi = {
+  // 获取设备组列表
+  list: (params: ListParams): Promise<import('../types').ListDeviceGroupsResponse> =>
+    api.get('/device-group', { params }),
+
+  // 获取设备组详情，包含成员设备
+  get: (groupId: number): Promise<import('../types').DeviceGroupSpec> =>
+    api.get(`/device-group/${groupId}`),
+
+  // 创建设备组
+  create: (data: import('../types').CreateDeviceGroupRequest): Promise<{ id: number }> =>
+    api.post('/device-group', data),
+
+  // 更新设备组
+  update: (groupId: number, data: import('../types').UpdateDeviceGroupRequest): Promise<import('../types').DeviceGroupSpec> =>
+    api.put(`/device-group/${groupId}`, data),
+
+  // 删除设备组
+  delete: (groupId: number): Promise<void> =>
+    api.delete(`/device-group/${groupId}`),
+
+  // 列出设备组的升级
+  listRollouts: (groupId: number, params: ListParams): Promise<import('../types').ListRolloutsResponse> =>
+    api.get(`/device-group/${groupId}/rollout`, { params }),
+
+  // 升级设备组
+  createRollout: (groupId: number, data: import('../types').CreateRolloutRequest): Promise<{ id: number }> =>
+    api.post(`/device-group/${groupId}/rollout`, data),
+};
+
+// 版本升级 API
+export const releaseApi = {
+  // 列出版本
+  list: (params: ListParams & { channel?: import('../types').ReleaseChannel }): Promise<import('../types').ListReleasesResponse> =>
+    api.get('/release', { params }),
+
+  // 创建版本
+  create: (data: import('../types').CreateReleaseRequest): Promise<{ id: number }> =>
+    api.post('/release', data),
+
+  // 删除版本
+  delete: (releaseId: number): Promise<void> =>
+    api.delete(`/release/${releaseId}`),
+
+  // 获取升级
+  getRollout: (rolloutId: number): Promise<import('../types').RolloutSpec> =>
+    api.get(`/rollout/${rolloutId}`),
+
+  // 列出升级中各设备的状态
+  listDeviceUpgrades: (rolloutId: number): Promise<{ items: import('../types').DeviceUpgradeSpec[] }> =>
+    api.get(`/rollout/${rolloutId}/devices`),
+
+  // 暂停升级
+  haltRollout: (rolloutId: number): Promise<import('../types').RolloutSpec> =>
+    api.put(`/rollout/${rolloutId}/halt`),
+
+  // 恢复升级
+  resumeRollout: (rolloutId: number): Promise<import('../types').RolloutSpec> =>
+    api.put(`/rollout/${rolloutId}/resume`),
+
+  // 取消升级
+  cancelRollout: (rolloutId: number): Promise<import('../types').RolloutSpec> =>
+    api.put(`/rollout/${rolloutId}/cancel`),
+};
+
+// 系统健康 API
+export const healthApi = {
+  // 列出系统健康事件
+  list: (params: ListParams & { kind?: import('../types').HealthEventKind; jobId?: number; deviceId?: number; resolved?: boolean }): Promise<import('../types').ListHealthEventsResponse> =>
+    api.get('/health-event', { params }),
+
+  // 解决系统健康事件
+  resolve: (eventId: number): Promise<import('../types').HealthEventSpec> =>
+    api.put(`/health-event/${eventId}/resolve`),
+
+  // 获取消息处理链路自检状态
+  canary: (): Promise<import('../types').CanaryStatusResponse> =>
+    api.get('/canary'),
+};
+
+// 定时报表 API
+export const reportApi = {
+  // 列出定时报表
+  listSchedules: (): Promise<{ items: import('../types').ReportScheduleSpec[] }> =>
+    api.get('/report-schedule'),
+
+  // 创建定时报表
+  createSchedule: (data: import('../types').CreateReportScheduleRequest): Promise<{ id: number }> =>
+    api.post('/report-schedule', data),
+
+  // 获取定时报表
+  getSchedule: (scheduleId: number): Promise<import('../types').ReportScheduleSpec> =>
+    api.get(`/report-schedule/${scheduleId}`),
+
+  // 更新定时报表
+  updateSchedule: (scheduleId: number, data: import('../types').UpdateReportScheduleRequest): Promise<import('../types').ReportScheduleSpec> =>
+    api.put(`/report-schedule/${scheduleId}`, data),
+
+  // 删除定时报表
+  deleteSchedule: (scheduleId: number): Promise<void> =>
+    api.delete(`/report-schedule/${scheduleId}`),
+
+  // 立即生成最近一个周期的报表并投递
+  runSchedule: (scheduleId: number): Promise<import('../types').RunReportResponse> =>
+    api.post(`/report-schedule/${scheduleId}/run`, undefined, { timeout: 0 }),
+
+  // 列出已生成的报表
+  listReports: (scheduleId: number, params?: ListParams): Promise<import('../types').ListReportsResponse> =>
+    api.get(`/report-schedule/${scheduleId}/reports`, { params }),
+
+  // 下载报表文件
+  download: (reportId: number): Promise<Blob> =>
+    api.get(`/report/${reportId}/download`, { responseType: 'blob', timeout: 0 }),
+};
+
+// GraphQL API，服务端未开启时返回 404
+export const graphqlApi = {
+  // 执行查询，有字段错误时抛出
+  query: async <T>(query: string, variables?: Record<string, unknown>): Promise<T> => {
+    const res: import('../types').GraphQLResponse<T> = await api.post('/graphql', { query, variables });
+    if (res.errors?.length || !res.data) {
+      throw new Error(res.errors?.map((e) => e.message).join('; ') || 'empty graphql response');
+    }
+    return res.data;
+  },
+};
+
+// 实时事件 API
+export const eventsApi = {
+  // 订阅事件，返回取消订阅的函数，断线后由浏览器自动重连
+  subscribe: (kinds: import('../types').EventKind[], onEvent: (event: import('../types').LuminaEvent) => void): (() => void) => {
+    const source = new EventSource(`${apiBaseUrl}/events?kinds=${kinds.join(',')}`, { withCredentials: true });
+    const listener = (e: MessageEvent) => {
+      try {
+        onEvent(JSON.parse(e.data));
+      } catch (error) {
+        console.error('Failed to parse event:', error);
+      }
+    };
+    kinds.forEach((kind) => source.addEventListener(kind, listener as EventListener));
+    return () => source.close();
+  },
 };
 
 // 消息 API
 export const messageApi = {
   // 获取消息列表
-  list: (params: ListParams & { jobId?: number }): Promise<ListMessageResponse> =>
+  list: (params: ListParams & import('../types').MessageFilter): Promise<ListMessageResponse> =>
     api.get('/message', { params }),
 
+  // 全文搜索工作流回答
+  search: (params: ListParams & import('../types').MessageFilter & { q: string }): Promise<ListMessageResponse> =>
+    api.get('/message/search', { params }),
+
+  // 按语义搜索消息，如“夜间有人翻越围栏”，按相似度从高到低返回
+  semanticSearch: (params: import('../types').SemanticSearchParams): Promise<import('../types').SemanticSearchResponse> =>
+    api.get('/message/semantic-search', { params }),
+
+  // 导出消息，返回 CSV 或 JSONL 文件
+  export: (params: import('../types').MessageFilter & { format?: 'csv' | 'jsonl'; q?: string; limit?: number }): Promise<Blob> =>
+    api.get('/message/export', { params, responseType: 'blob', timeout: 0 }),
+
   // 获取消息详情
   get: (messageId: number): Promise<MessageSpec> =>
     api.get(`/message/${messageId}`),
 
+  // 获取消息图片和视频的预签名链接，用于私有存储桶
+  media: (messageId: number, expire?: number): Promise<import('../types').MessageMediaResponse> =>
+    api.get(`/message/${messageId}/media`, { params: { expire } }),
+
+  // 校验消息的设备签名，checkMedia 为 false 时不下载文件校验哈希
+  verify: (messageId: number, checkMedia?: boolean): Promise<import('../types').MessageVerification> =>
+    api.get(`/message/${messageId}/verify`, { params: { checkMedia } }),
+
   // 删除消息
   delete: (messageId: number): Promise<void> =>
     api.delete(`/message/${messageId}`),
+
+  // 获取消息的人工标注
+  getFeedback: (messageId: number): Promise<import('../types').MessageFeedbackSpec> =>
+    api.get(`/message/${messageId}/feedback`),
+
+  // 标注消息检测正确或误报，重复标注时覆盖
+  putFeedback: (messageId: number, data: import('../types').MessageFeedbackRequest): Promise<import('../types').MessageFeedbackSpec> =>
+    api.put(`/message/${messageId}/feedback`, data),
+
+  // 删除消息的人工标注
+  deleteFeedback: (messageId: number): Promise<void> =>
+    api.delete(`/message/${messageId}/feedback`),
+
+  // 现场喊话
+  talkDown: (messageId: number, data?: import('../types').TalkDownRequest): Promise<import('../types').TalkDownResult> =>
+    api.post(`/message/${messageId}/talk-down`, data, { timeout: 70000 }),
+
+  // 获取告警处理状态
+  getAlert: (messageId: number): Promise<import('../types').AlertSpec> =>
+    api.get(`/message/${messageId}/alert`),
+
+  // 确认告警
+  ack: (messageId: number): Promise<import('../types').AlertSpec> =>
+    api.put(`/message/${messageId}/ack`),
+
+  // 指派告警，assigneeId 为 0 时取消指派
+  assign: (messageId: number, assigneeId: number): Promise<import('../types').AlertSpec> =>
+    api.put(`/message/${messageId}/assign`, { assigneeId }),
+
+  // 评论告警
+  comment: (messageId: number, content: string): Promise<import('../types').AlertSpec> =>
+    api.post(`/message/${messageId}/comment`, { content }),
+
+  // 解决告警
+  resolve: (messageId: number, comment?: string): Promise<import('../types').AlertSpec> =>
+    api.put(`/message/${messageId}/resolve`, { comment }),
+
+  // 告警处理统计
+  alertStats: (params?: import('../types').AlertStatsRequest): Promise<import('../types').AlertStatsResponse> =>
+    api.get('/stats/alerts', { params }),
+
+  // 各任务各级别的告警数量趋势
+  alertTrend: (params?: import('../types').AlertTrendRequest): Promise<import('../types').AlertTrendResponse> =>
+    api.get('/stats/alerts/trend', { params }),
+
+  // 告警图库，按时间桶和摄像头分组
+  alertGallery: (params?: import('../types').AlertGalleryRequest): Promise<import('../types').AlertGalleryResponse> =>
+    api.get('/alerts/gallery', { params }),
 };
 
 // 工作流 API
@@ -215,21 +595,53 @@ export const workflowApi = {
 // 摄像头 API
 export const cameraApi = {
   // 获取摄像头列表
-  list: (params: ListParams): Promise<ListCamerasResponse> =>
+  list: (params: import('../types').ListCamerasParams): Promise<ListCamerasResponse> =>
     api.get('/camera', { params }),
 
+  // 获取摄像头标签
+  tags: (): Promise<import('../types').ListCameraTagsResponse> =>
+    api.get('/camera/tags'),
+
   // 获取摄像头详情
   get: (cameraId: number): Promise<CameraSpec> =>
     api.get(`/camera/${cameraId}`),
 
   // 开始摄像头预览
-  startPreview: (cameraId: number): Promise<import('../types').PreviewTask> =>
-    api.post(`/camera/${cameraId}/preview`),
+  startPreview: (cameraId: number, mode?: import('../types').PreviewMode): Promise<import('../types').PreviewTask> =>
+    api.post(`/camera/${cameraId}/preview`, undefined, { params: mode ? { mode } : undefined }),
 
   // 刷新（触摸）摄像头预览任务过期时间
   touchPreview: (cameraId: number): Promise<void> =>
     api.put(`/camera/${cameraId}/preview`),
 
+  // 停止摄像头预览
+  stopPreview: (cameraId: number): Promise<void> =>
+    api.delete(`/camera/${cameraId}/preview`),
+
+  // 由绑定设备截取一帧快照
+  snapshot: (cameraId: number): Promise<import('../types').CameraSnapshotResponse> =>
+    api.post(`/camera/${cameraId}/snapshot`, undefined, { timeout: 30000 }),
+
+  // 由绑定设备探测摄像头连通性和码流信息
+  probe: (cameraId: number): Promise<import('../types').CameraProbeResult> =>
+    api.post(`/camera/${cameraId}/probe`, undefined, { timeout: 40000 }),
+
+  // 区域人数统计
+  occupancy: (cameraId: number, params?: import('../types').OccupancyStatsRequest): Promise<import('../types').CameraOccupancyResponse> =>
+    api.get(`/camera/${cameraId}/occupancy`, { params }),
+
+  // 检测热力图，返回 JPEG 图片
+  heatmap: (cameraId: number, params?: import('../types').HeatmapRequest): Promise<Blob> =>
+    api.get(`/camera/${cameraId}/heatmap`, { params, responseType: 'blob', timeout: 60000 }),
+
+  // 设置地面标定
+  setCalibration: (cameraId: number, data: import('../types').SetCameraCalibrationRequest): Promise<import('../types').CameraCalibration> =>
+    api.put(`/camera/${cameraId}/calibration`, data),
+
+  // 删除地面标定
+  deleteCalibration: (cameraId: number): Promise<void> =>
+    api.delete(`/camera/${cameraId}/calibration`),
+
   // 创建摄像头
   create: (data: CreateCameraRequest): Promise<CreateCameraResponse> =>
     api.post('/camera', data),
@@ -241,6 +653,26 @@ export const cameraApi = {
   // 删除摄像头
   delete: (cameraId: number): Promise<void> =>
     api.delete(`/camera/${cameraId}`),
+
+  // 由设备扫描 ONVIF 摄像头
+  discover: (data: import('../types').DiscoverCamerasRequest): Promise<import('../types').DiscoverCamerasResponse> =>
+    api.post('/camera/discover', data, { timeout: 60000 }),
+
+  // 云台移动
+  ptzMove: (cameraId: number, data: import('../types').PtzMoveRequest): Promise<void> =>
+    api.post(`/camera/${cameraId}/ptz/move`, data),
+
+  // 云台停止
+  ptzStop: (cameraId: number): Promise<void> =>
+    api.post(`/camera/${cameraId}/ptz/stop`),
+
+  // 获取云台预置位
+  ptzPresets: (cameraId: number): Promise<import('../types').ListPtzPresetsResponse> =>
+    api.get(`/camera/${cameraId}/ptz/presets`),
+
+  // 转到云台预置位
+  ptzGotoPreset: (cameraId: number, presetToken: string): Promise<void> =>
+    api.post(`/camera/${cameraId}/ptz/goto-preset`, { presetToken }),
 };
 
 export default api;
@@ -316,15 +748,52 @@ export const conversationApi = {
 
   // Chat stream
   chatStream: (uuid: string, data: ChatRequest): Promise<Response> =>
-    fetch(`/api/v1/conversation/${uuid}/chat`,
+    fetch(`${apiBaseUrl}/conversation/${uuid}/chat`,
       {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify(data),
+        credentials: 'include',
       }
     ),
 
+  // 连接中断后从第 offset 个事件起续传回复，streamId 来自 chatStream 的 X-Stream-Id 响应头
+  resumeStream: (uuid: string, streamId: string, offset: number): Promise<Response> =>
+    fetch(`${apiBaseUrl}/conversation/${uuid}/chat/${streamId}/resume?offset=${offset}`, { credentials: 'include' }),
+
   // Generate title for conversation
   genTitle: (uuid: string): Promise<{ title: string }> =>
     api.post(`/conversation/${uuid}/title`),
-};
\ No newline at end of file
+};
+// 助手提议的任务或工作流变更，批准后才会执行
+export type AgentProposalKind = 'create_job' | 'update_job' | 'create_workflow' | 'update_workflow';
+
+export type AgentProposalSpec = {
+  uuid: string;
+  kind: AgentProposalKind;
+  targetId?: number;
+  summary: string;
+  request: Record<string, unknown>;
+  createTime: string;
+  expireTime: string;
+};
+
+export type ApproveAgentProposalResponse = {
+  kind: AgentProposalKind;
+  id: number;
+  uuid?: string;
+};
+
+export const agentApi = {
+  // 获取提议
+  getProposal: (uuid: string): Promise<AgentProposalSpec> =>
+    api.get(`/agent/proposal/${uuid}`),
+
+  // 批准并执行提议
+  approveProposal: (uuid: string): Promise<ApproveAgentProposalResponse> =>
+    api.post(`/agent/proposal/${uuid}/approve`),
+
+  // 拒绝提议
+  rejectProposal: (uuid: string): Promise<void> =>
+    api.delete(`/agent/proposal/${uuid}`),
+};
@@ -13,9 +13,21 @@ export interface PaginationResponse<T> {
   limit: number;
 }
 
+// 校验失败的字段
+export interface FieldError {
+  // 字段路径，与请求 JSON 的名称一致，如 channelIds[0]、detect.modelName
+  field: string;
+  // 失败的校验规则，如 required、max，类型错误为 type
+  code: string;
+  message: string;
+}
+
 // 错误响应类型
 export interface ErrorResponse {
   error: string;
+  // 错误码，如 validation_failed、not_found
+  code?: string;
+  fields?: FieldError[];
   message?: string;
 }
 
@@ -26,12 +38,116 @@ export interface User {
   email?: string;
   created_at?: string;
   updated_at?: string;
+  // IANA 时区名，为空时使用浏览器时区
+  timezone?: string;
+  // 是否已启用两步验证
+  totpEnabled?: boolean;
+  // 所属组织ID
+  orgId?: number;
+}
+
+export interface UpdateProfileRequest {
+  timezone: string;
 }
 
 export interface CreateUserRequest {
   username: string;
   email?: string;
   password: string;
+  // 所属组织ID，默认为当前管理员的组织
+  orgId?: number;
+}
+
+// 组织类型，多租户模式下用户只能看到本组织的数据
+export interface Organization {
+  id: number;
+  name: string;
+  createdTime: string;
+}
+
+export interface ListOrganizationsResponse {
+  items: Organization[];
+}
+
+export interface ChangePasswordRequest {
+  oldPassword: string;
+  newPassword: string;
+}
+
+export interface ResetPasswordResponse {
+  password: string;
+}
+
+export interface TotpSetupResponse {
+  secret: string;
+  // otpauth 地址，以二维码展示
+  uri: string;
+}
+
+export interface TotpRecoveryCodesResponse {
+  recoveryCodes: string[];
+}
+
+export interface DisableTotpRequest {
+  password: string;
+  // 两步验证码或恢复码
+  code: string;
+}
+
+// API 令牌授权范围，all 为全部权限，read 为只读，其余为对应资源的全部权限
+export type ApiTokenScope = 'all' | 'read' | 'jobs' | 'cameras' | 'devices' | 'workflows' | 'messages';
+
+export interface ApiToken {
+  id: number;
+  name: string;
+  // 令牌开头几位，用于区分令牌
+  hint: string;
+  scopes: ApiTokenScope[];
+  // 为空时永不过期
+  expireTime?: string;
+  lastUsedTime?: string;
+  lastUsedIp?: string;
+  createdTime: string;
+}
+
+export interface CreateApiTokenRequest {
+  name: string;
+  scopes: ApiTokenScope[];
+  // RFC3339 格式，为空时永不过期
+  expireTime?: string;
+}
+
+export interface CreateApiTokenResponse extends ApiToken {
+  // 令牌，只返回这一次
+  token: string;
+}
+
+export interface ListApiTokensResponse {
+  items: ApiToken[];
+}
+
+export interface RefreshResponse {
+  token: string;
+  // 新的刷新令牌，原刷新令牌已作废
+  refreshToken: string;
+  // 登录凭证的有效秒数
+  expiresIn: number;
+}
+
+// 登录会话
+export interface Session {
+  id: string;
+  loginTime: string;
+  refreshTime: string;
+  expireTime: string;
+  userAgent: string;
+  ip: string;
+  // 是否为当前会话
+  current: boolean;
+}
+
+export interface ListSessionsResponse {
+  items: Session[];
 }
 
 export interface CreateUserResponse {
@@ -45,6 +161,9 @@ export interface ListUsersResponse {
   total: number;
 }
 
+// 设备状态，根据最近上报时间得出
+export type DeviceState = 'online' | 'degraded' | 'offline';
+
 // 设备类型
 export interface Device {
   id: number;
@@ -53,6 +172,7 @@ export interface Device {
   name: string;
   registerTime: string;
   lastPingTime: string;
+  state?: DeviceState;
 }
 
 export interface DeviceSpec {
@@ -62,6 +182,546 @@ export interface DeviceSpec {
   name: string;
   registerTime: string;
   lastPingTime: string;
+  inventory?: DeviceInventory;
+  state?: DeviceState;
+  // 已要求设备轮换令牌且尚未完成
+  rotateToken?: boolean;
+  // 最近一次完成令牌轮换的时间
+  tokenRotateTime?: string;
+}
+
+// 设备软件版本
+export interface DeviceInventory {
+  os: string;
+  kernel: string;
+  arch: string;
+  luminaVersion: string;
+  luminaCommit: string;
+  ffmpegVersion: string;
+  opencvVersion: string;
+  tritonVersion: string;
+}
+
+export interface ListDeviceInventoryRequest extends ListParams {
+  os?: string;
+  kernel?: string;
+  luminaVersion?: string;
+  ffmpegVersion?: string;
+  opencvVersion?: string;
+  tritonVersion?: string;
+}
+
+export interface DeviceInventorySpec {
+  id: number;
+  uuid: string;
+  name: string;
+  lastPingTime: string;
+  inventory?: DeviceInventory;
+}
+
+export interface ListDeviceInventoryResponse {
+  items: DeviceInventorySpec[];
+  total: number;
+  luminaVersions: Record<string, number>;
+}
+
+// 设备组
+export interface DeviceGroupSpec {
+  id: number;
+  name: string;
+  description: string;
+  createTime: string;
+  updateTime: string;
+  channel: ReleaseChannel;
+  upgradeWindow?: JobSchedule;
+  devices?: DeviceSpec[];
+}
+
+export interface CreateDeviceGroupRequest {
+  name: string;
+  description?: string;
+  deviceIds?: number[];
+  channel?: ReleaseChannel;
+  upgradeWindow?: JobSchedule;
+}
+
+export interface UpdateDeviceGroupRequest {
+  name?: string;
+  description?: string;
+  deviceIds?: number[];
+  channel?: ReleaseChannel;
+  upgradeWindow?: JobSchedule;
+}
+
+export interface ListDeviceGroupsResponse {
+  items: DeviceGroupSpec[];
+  total: number;
+}
+
+// 设备程序版本与设备组升级
+export type ReleaseChannel = 'stable' | 'beta';
+
+export interface ReleaseSpec {
+  id: number;
+  version: string;
+  channel: ReleaseChannel;
+  url: string;
+  sha256: string;
+  notes?: string;
+  createTime: string;
+}
+
+export interface CreateReleaseRequest {
+  version: string;
+  channel: ReleaseChannel;
+  url: string;
+  sha256: string;
+  notes?: string;
+}
+
+export interface ListReleasesResponse {
+  items: ReleaseSpec[];
+  total: number;
+}
+
+export type RolloutStatus = 'running' | 'halted' | 'completed' | 'cancelled';
+
+export interface RolloutSpec {
+  id: number;
+  groupId: number;
+  release?: ReleaseSpec;
+  status: RolloutStatus;
+  failureThreshold: number;
+  haltReason?: string;
+  upgrading: number;
+  succeeded: number;
+  failed: number;
+  createTime: string;
+  updateTime: string;
+}
+
+export interface CreateRolloutRequest {
+  releaseId?: number;
+  failureThreshold?: number;
+}
+
+export interface ListRolloutsResponse {
+  items: RolloutSpec[];
+  total: number;
+}
+
+export interface DeviceUpgradeSpec {
+  device: DeviceSpec;
+  status: 'upgrading' | 'succeeded' | 'failed';
+  error?: string;
+  updateTime: string;
+}
+
+// 任务状态变更记录
+export interface JobStatusHistory {
+  id: number;
+  source: 'device' | 'server';
+  status: string;
+  prevStatus?: string;
+  deviceId?: number;
+  username?: string;
+  reason?: string;
+  createTime: string;
+}
+
+export interface ListJobStatusHistoryResponse {
+  items: JobStatusHistory[];
+  total: number;
+}
+
+// 对象存储不可用时设备随状态上报的小文件
+export type ArtifactKind = 'trigger_thumbnail' | 'error_screenshot';
+
+export interface JobArtifact {
+  kind: ArtifactKind;
+  contentType: string;
+  size: number;
+  deviceId: number;
+  time: string;
+  url: string;
+}
+
+export interface ListJobArtifactsResponse {
+  items: JobArtifact[];
+}
+
+// 任务就绪检查
+export interface JobCheck {
+  name: 'target' | 'camera' | 'model' | 'workflow';
+  status: 'ok' | 'failed' | 'skipped';
+  message?: string;
+  duration: number;
+}
+
+export interface ValidateJobResponse {
+  ready: boolean;
+  checks: JobCheck[];
+}
+
+// 系统健康事件
+export type HealthEventKind = 'message_volume' | 'alert_volume' | 'canary' | 'device_offline';
+
+// 定时报表，站点即摄像头标签
+export type ReportPeriod = 'daily' | 'weekly';
+
+export type ReportFormat = 'html' | 'pdf';
+
+export interface ReportScheduleSpec {
+  id: number;
+  name: string;
+  enabled: boolean;
+  site: string;
+  period: ReportPeriod;
+  format: ReportFormat;
+  hour: number;
+  weekday: number;
+  timezone: string;
+  channelIds: number[];
+  lastPeriodEnd?: string;
+  lastRunTime?: string;
+  lastError?: string;
+  createTime: string;
+  updateTime: string;
+}
+
+export interface CreateReportScheduleRequest {
+  name: string;
+  enabled?: boolean;
+  site?: string;
+  period: ReportPeriod;
+  format?: ReportFormat;
+  hour: number;
+  weekday?: number;
+  timezone?: string;
+  channelIds: number[];
+}
+
+export type UpdateReportScheduleRequest = Partial<CreateReportScheduleRequest>;
+
+export interface ReportSummary {
+  messages: number;
+  alerts: number;
+  cameras: number;
+  devices: number;
+  offlineDevices: number;
+}
+
+export interface ReportSpec {
+  id: number;
+  scheduleId: number;
+  site: string;
+  period: ReportPeriod;
+  format: ReportFormat;
+  periodStart: string;
+  periodEnd: string;
+  summary: ReportSummary;
+  createTime: string;
+}
+
+export interface ListReportsResponse {
+  items: ReportSpec[];
+  total: number;
+}
+
+export interface RunReportResponse {
+  report: ReportSpec;
+  error?: string;
+}
+
+export interface HealthEventSpec {
+  id: number;
+  kind: HealthEventKind;
+  jobId?: number;
+  deviceId?: number;
+  summary: string;
+  observed: number;
+  expected: number;
+  score: number;
+  startTime: string;
+  endTime: string;
+  resolved: boolean;
+  resolveTime?: string;
+  createTime: string;
+}
+
+export interface ListHealthEventsResponse {
+  items: HealthEventSpec[];
+  total: number;
+}
+
+// GraphQL 查询结果，字段错误与其余字段一并返回
+export interface GraphQLResponse<T> {
+  data: T | null;
+  errors?: { message: string; path?: (string | number)[] }[];
+}
+
+// 一次消息处理链路自检
+export interface CanaryRun {
+  time: string;
+  success: boolean;
+  latency: number;
+  error?: string;
+}
+
+export interface CanaryStatusResponse {
+  enabled: boolean;
+  successRate: number;
+  runs: CanaryRun[];
+}
+
+// 实时事件
+export type EventKind = 'device_state' | 'job_status' | 'alert';
+
+export interface DeviceStateEvent {
+  deviceId: number;
+  deviceUuid: string;
+  deviceName: string;
+  state: DeviceState;
+  prevState: DeviceState;
+}
+
+export interface JobStatusEvent {
+  jobId: number;
+  jobUuid: string;
+  transition: JobStatusHistory;
+}
+
+// 告警级别
+export type AlertSeverity = 'low' | 'medium' | 'high' | 'critical';
+
+export interface SeverityRule {
+  severity: AlertSeverity;
+  filter: FilterCondition;
+}
+
+// 告警处理状态
+export type AlertStatus = 'open' | 'acknowledged' | 'resolved';
+
+export interface AlertActivitySpec {
+  id: number;
+  action: 'ack' | 'assign' | 'comment' | 'resolve';
+  userId: number;
+  username: string;
+  assigneeId?: number;
+  content?: string;
+  createTime: string;
+}
+
+export interface AlertSpec {
+  alertId: number;
+  severity: AlertSeverity;
+  status: AlertStatus;
+  ackTime?: string;
+  ackUserId?: number;
+  assigneeId?: number;
+  resolveTime?: string;
+  resolveUserId?: number;
+  createTime: string;
+  occurrences: number;
+  lastOccurTime?: string;
+  lastMessageId?: number;
+  activities?: AlertActivitySpec[];
+}
+
+export interface AlertGalleryRequest {
+  start?: string;
+  end?: string;
+  // 如 10m、1h
+  bucket?: string;
+  jobId?: number;
+  cameraId?: number;
+  status?: AlertStatus;
+  thumbnails?: number;
+}
+
+export interface AlertThumbnail {
+  messageId: number;
+  imageUrl: string;
+}
+
+export interface AlertGalleryCamera {
+  cameraId: number;
+  cameraName: string;
+  count: number;
+  occurrences: number;
+  thumbnails: AlertThumbnail[];
+}
+
+export interface AlertGalleryBucket {
+  start: string;
+  count: number;
+  cameras: AlertGalleryCamera[];
+}
+
+// 没有告警的时间桶不返回
+export interface AlertGalleryResponse {
+  start: string;
+  end: string;
+  bucket: string;
+  buckets: AlertGalleryBucket[];
+}
+
+// 消息媒体的预签名链接
+// 语义搜索
+export interface SemanticSearchParams {
+  q: string;
+  limit?: number;
+  minScore?: number;
+  jobId?: number;
+  cameraId?: number;
+  alerted?: boolean;
+  since?: string;
+  until?: string;
+}
+
+export interface SemanticSearchResponse {
+  items: (MessageSpec & { score: number })[];
+}
+
+// 消息签名校验报告
+export interface MediaVerification {
+  path: string;
+  expectedSha256: string;
+  actualSha256?: string;
+  matches: boolean;
+  error?: string;
+}
+
+export interface MessageVerification {
+  messageId: number;
+  signed: boolean;
+  verified: boolean;
+  deviceUuid?: string;
+  keyFingerprint?: string;
+  keyRegisterTime?: string;
+  signatureValid: boolean;
+  contentMatches: boolean;
+  mismatches?: string[];
+  media?: MediaVerification[];
+  error?: string;
+  verifyTime: string;
+}
+
+// 消息的人工标注
+export type FeedbackVerdict = 'true_positive' | 'false_positive';
+
+export interface MessageFeedbackRequest {
+  verdict: FeedbackVerdict;
+  correctedLabels?: string[];
+  comment?: string;
+}
+
+export interface MessageFeedbackSpec {
+  messageId: number;
+  jobId: number;
+  verdict: FeedbackVerdict;
+  correctedLabels?: string[];
+  comment?: string;
+  userId: number;
+  username: string;
+  createTime: string;
+  updateTime: string;
+}
+
+// 任务检测准确率趋势
+export interface JobPrecisionRequest {
+  start?: string;
+  end?: string;
+  bucket?: string;
+}
+
+export interface PrecisionPoint {
+  time: string;
+  truePositive: number;
+  falsePositive: number;
+  precision: number;
+}
+
+export interface JobPrecisionResponse {
+  start: string;
+  end: string;
+  bucket: string;
+  total: PrecisionPoint;
+  points: PrecisionPoint[];
+}
+
+export interface MessageMediaResponse {
+  imageUrl?: string;
+  videoUrl?: string;
+  expireTime: string;
+}
+
+// 消息列表过滤条件
+export interface MessageFilter {
+  jobId?: number;
+  cameraId?: number;
+  deviceId?: number;
+  // RFC3339，拍摄时间 [since, until)
+  since?: string;
+  until?: string;
+  label?: string;
+  minConfidence?: number;
+  alerted?: boolean;
+  alertStatus?: AlertStatus;
+}
+
+export interface AlertStatsRequest {
+  start?: string;
+  end?: string;
+  jobId?: number;
+  slaSeconds?: number;
+}
+
+export interface AlertStatsResponse {
+  start: string;
+  end: string;
+  open: number;
+  acknowledged: number;
+  resolved: number;
+  avgAckSeconds: number;
+  avgResolveSeconds: number;
+  slaSeconds: number;
+  ackedWithinSla: number;
+}
+
+export interface AlertEvent {
+  alertId: number;
+  message: MessageSpec;
+  jobUuid: string;
+  jobKind: string;
+  cameraId: number;
+  cameraName: string;
+  severity: AlertSeverity;
+  createTime: string;
+}
+
+export interface LuminaEvent {
+  kind: EventKind;
+  time: string;
+  device?: DeviceStateEvent;
+  job?: JobStatusEvent;
+  alert?: AlertEvent;
+}
+
+// 设备组任务在单个设备上的状态
+export interface JobDeviceStatus {
+  device?: DeviceSpec;
+  status: JobStatus;
+  restartCount?: number;
+  lastError?: string;
+  healthReason?: string;
+  frameRate?: number;
+  frameRateLimit?: number;
+  updateTime: string;
+}
+
+export interface ListJobDeviceStatusResponse {
+  items: JobDeviceStatus[];
 }
 
 export interface RegisterRequest {
@@ -87,7 +747,13 @@ export interface AccessToken {
   accessToken: string;
   createTime: string;
   expireTime: string;
+  // 最近一次使用该凭证注册的设备
   deviceUuid: string;
+  // 可注册的设备数量上限
+  maxBinds: number;
+  // 已注册的设备数量
+  bindCount: number;
+  lastBindTime?: string;
 }
 
 export interface AccessTokenSpec {
@@ -96,10 +762,15 @@ export interface AccessTokenSpec {
   createTime: string;
   expireTime: string;
   deviceUuid: string;
+  maxBinds: number;
+  bindCount: number;
+  lastBindTime?: string;
 }
 
 export interface CreateAccessTokenRequest {
   expireTime: string;
+  // 可注册的设备数量上限，默认 1
+  maxBinds?: number;
 }
 
 export interface CreateAccessTokenResponse {
@@ -117,7 +788,7 @@ export interface ListAccessTokenResponse {
 
 // Job related enums and types
 export type JobKind = 'detect' | 'video_segment';
-export type JobStatus = 'stopped' | 'running';
+export type JobStatus = 'stopped' | 'running' | 'pending' | 'paused' | 'disarmed';
 
 export interface DetectOptions {
   modelName: string;
@@ -127,6 +798,19 @@ export interface DetectOptions {
   interval?: number;
   triggerCount?: number;
   triggerInterval?: number;
+  occupancy?: OccupancyOptions;
+}
+
+// 区域人数统计，多边形坐标按画面宽高归一化到 0~1
+export interface OccupancyZone {
+  name: string;
+  polygon: [number, number][];
+}
+
+export interface OccupancyOptions {
+  labels?: string;
+  zones?: OccupancyZone[];
+  interval?: number;
 }
 
 export interface VideoSegmentOptions {
@@ -144,7 +828,13 @@ export type Operator =
   | 'starts_with'
   | 'ends_with'
   | 'empty'
-  | 'not_empty';
+  | 'not_empty'
+  | 'gt'
+  | 'ge'
+  | 'lt'
+  | 'le'
+  | 'between'
+  | 'not_between';
 
 export type CombineOperator = 'and' | 'or';
 
@@ -159,6 +849,50 @@ export interface FilterCondition {
   conditions: Condition[];
 }
 
+// 设备端消息后处理钩子
+export interface JobHook {
+  name: string;
+  params?: Record<string, any>;
+}
+
+// 告警现场喊话
+export type TalkDownOutput = 'onvif' | 'local';
+
+export interface TalkDownOptions {
+  enabled: boolean;
+  text?: string;
+  audioUrl?: string;
+  output?: TalkDownOutput;
+  cooldown?: number;
+}
+
+// 每日布防时段，结束时间不晚于开始时间时跨越午夜
+export interface ScheduleWindow {
+  // 0 为周日，为空表示每天
+  days?: number[];
+  start: string;
+  end: string;
+}
+
+// 任务布防计划，设备在时段外撤防
+export interface JobSchedule {
+  windows: ScheduleWindow[];
+  timezone?: string;
+}
+
+export interface TalkDownRequest {
+  text?: string;
+  audioUrl?: string;
+  output?: TalkDownOutput;
+}
+
+export interface TalkDownResult {
+  taskUuid: string;
+  error?: string;
+  duration: number;
+  createTime?: string;
+}
+
 // 任务类型
 export interface Job {
   id: number;
@@ -166,15 +900,27 @@ export interface Job {
   kind: JobKind;
   status: JobStatus;
   enabled: boolean;
+  paused?: boolean;
+  priority?: number;
+  maxFps?: number;
+  frameRate?: number;
+  frameRateLimit?: number;
   camera: CameraSpec;
   createTime: string;
   updateTime: string;
   detect?: DetectOptions;
   videoSegment?: VideoSegmentOptions;
-  device: DeviceSpec;
+  device?: DeviceSpec;
+  deviceGroup?: DeviceGroupSpec;
   workflow?: WorkflowSpec;
   query?: string;
   resultFilter?: FilterCondition;
+  hooks?: JobHook[];
+  talkDown?: TalkDownOptions;
+  schedule?: JobSchedule;
+  severityRules?: SeverityRule[];
+  // 告警去重窗口（秒），0 表示不去重
+  alertDedupWindow?: number;
 }
 
 export interface JobSpec {
@@ -183,15 +929,27 @@ export interface JobSpec {
   kind: JobKind;
   status: JobStatus;
   enabled: boolean;
+  paused?: boolean;
+  priority?: number;
+  maxFps?: number;
+  frameRate?: number;
+  frameRateLimit?: number;
   camera: CameraSpec;
   createTime: string;
   updateTime: string;
   detect?: DetectOptions;
   videoSegment?: VideoSegmentOptions;
-  device: DeviceSpec;
+  device?: DeviceSpec;
+  deviceGroup?: DeviceGroupSpec;
   workflow?: WorkflowSpec;
   query?: string;
   resultFilter?: FilterCondition;
+  hooks?: JobHook[];
+  talkDown?: TalkDownOptions;
+  schedule?: JobSchedule;
+  severityRules?: SeverityRule[];
+  // 告警去重窗口（秒），0 表示不去重
+  alertDedupWindow?: number;
 }
 
 export interface CreateJobRequest {
@@ -200,8 +958,18 @@ export interface CreateJobRequest {
   cameraId: number;
   detect?: DetectOptions;
   videoSegment?: VideoSegmentOptions;
-  deviceId: number;
+  deviceId?: number;
+  priority?: number;
+  maxFps?: number;
+  deviceGroupId?: number;
   workflowId?: number;
+  resultFilter?: FilterCondition;
+  hooks?: JobHook[];
+  talkDown?: TalkDownOptions;
+  schedule?: JobSchedule;
+  severityRules?: SeverityRule[];
+  // 告警去重窗口（秒），0 表示不去重
+  alertDedupWindow?: number;
 }
 
 export interface UpdateJobRequest {
@@ -211,7 +979,24 @@ export interface UpdateJobRequest {
   detect?: DetectOptions;
   videoSegment?: VideoSegmentOptions;
   device?: DeviceSpec;
+  deviceId?: number;
+  deviceGroupId?: number;
   workflowId?: number;
+  priority?: number;
+  maxFps?: number;
+  resultFilter?: FilterCondition;
+  hooks?: JobHook[];
+  talkDown?: TalkDownOptions;
+  schedule?: JobSchedule;
+  severityRules?: SeverityRule[];
+  // 告警去重窗口（秒），0 表示不去重
+  alertDedupWindow?: number;
+}
+
+export interface CloneJobRequest {
+  cameraId?: number;
+  deviceId?: number;
+  deviceGroupId?: number;
 }
 
 export interface CreateJobResponse {
@@ -226,18 +1011,33 @@ export interface ListJobResponse {
 }
 
 // Detection box type for message
+// 检测框，坐标为像素，除 label 外的字段总是返回
 export interface DetectionBox {
-  x: number;
-  y: number;
-  width: number;
-  height: number;
+  x1: number;
+  y1: number;
+  x2: number;
+  y2: number;
   confidence: number;
-  class: string;
+  classId: number;
+  label?: string;
+}
+
+// 按画面宽高归一化的检测框，坐标取值 0～1
+export interface NormalizedBox {
+  x1: number;
+  y1: number;
+  x2: number;
+  y2: number;
+  confidence: number;
+  classId: number;
+  label?: string;
 }
 
 // Workflow response type
 export interface WorkflowResp {
   answer: string;
+  confidence: number;
+  match: boolean;
   [key: string]: any;
 }
 
@@ -252,6 +1052,11 @@ export interface Message {
   createTime: string;
   workflowResp?: WorkflowResp;
   alerted: boolean;
+  frameWidth?: number;
+  frameHeight?: number;
+  normalizedBoxes?: NormalizedBox[];
+  metadata?: Record<string, any>;
+  sensors?: Record<string, any>;
 }
 
 export interface MessageSpec {
@@ -264,6 +1069,13 @@ export interface MessageSpec {
   createTime: string;
   workflowResp?: WorkflowResp;
   alerted: boolean;
+  frameWidth?: number;
+  frameHeight?: number;
+  normalizedBoxes?: NormalizedBox[];
+  metadata?: Record<string, any>;
+  sensors?: Record<string, any>;
+  // 是否带有设备签名
+  signed?: boolean;
 }
 
 export interface ListMessageResponse {
@@ -345,6 +1157,10 @@ export interface Camera {
   createTime: string;
   updateTime: string;
   bindDevice?: DeviceSpec;
+  previewAudio: boolean;
+  onvifPort?: number;
+  tags: string[];
+  calibration?: CameraCalibration;
 }
 
 export interface CameraSpec {
@@ -360,6 +1176,10 @@ export interface CameraSpec {
   createTime: string;
   updateTime: string;
   bindDevice?: DeviceSpec;
+  previewAudio: boolean;
+  onvifPort?: number;
+  tags: string[];
+  calibration?: CameraCalibration;
 }
 
 export interface CreateCameraRequest {
@@ -371,6 +1191,27 @@ export interface CreateCameraRequest {
   username?: string;
   password?: string;
   bindDeviceId?: number;
+  previewAudio?: boolean;
+  onvifPort?: number;
+  tags?: string[];
+}
+
+// 地面标定点，像素坐标与世界坐标
+export interface CalibrationPoint {
+  pixel: [number, number];
+  world: [number, number];
+}
+
+export interface CameraCalibration {
+  points: CalibrationPoint[];
+  matrix: number[];
+  unit: string;
+  error: number;
+}
+
+export interface SetCameraCalibrationRequest {
+  points: CalibrationPoint[];
+  unit?: string;
 }
 
 export interface CreateCameraResponse {
@@ -386,6 +1227,92 @@ export interface UpdateCameraRequest {
   username?: string;
   password?: string;
   bindDeviceId?: number;
+  previewAudio?: boolean;
+  onvifPort?: number;
+  tags?: string[];
+}
+
+// ONVIF 扫描
+export interface DiscoverCamerasRequest {
+  deviceId: number;
+  subnet?: string;
+  username?: string;
+  password?: string;
+  timeout?: number;
+}
+
+export interface DiscoveredCamera {
+  xaddr: string;
+  ip: string;
+  onvifPort: number;
+  name?: string;
+  manufacturer?: string;
+  model?: string;
+  streamUri?: string;
+  ptz: boolean;
+  error?: string;
+  camera?: CreateCameraRequest;
+}
+
+export interface DiscoverCamerasResponse {
+  cameras: DiscoveredCamera[];
+}
+
+// 云台控制
+export interface PtzMoveRequest {
+  pan: number;
+  tilt: number;
+  zoom: number;
+  timeout?: number;
+}
+
+export interface PtzPreset {
+  token: string;
+  name: string;
+}
+
+export interface ListPtzPresetsResponse {
+  presets: PtzPreset[];
+}
+
+export interface CameraSnapshotResponse {
+  url: string;
+  expireTime: string;
+  createTime: string;
+}
+
+export interface CameraProbeResult {
+  taskUuid: string;
+  reachable: boolean;
+  error?: string;
+  format?: string;
+  bitrate?: number;
+  video?: {
+    codec: string;
+    profile?: string;
+    width: number;
+    height: number;
+    fps: number;
+    pixFmt?: string;
+  };
+  audio?: {
+    codec: string;
+    sampleRate: number;
+    channels: number;
+  };
+  duration: number;
+  createTime?: string;
+}
+
+export interface ListCamerasParams extends ListParams {
+  tag?: string;
+  name?: string;
+  protocol?: CameraProtocol;
+  bindDeviceId?: number;
+}
+
+export interface ListCameraTagsResponse {
+  tags: string[];
 }
 
 export interface ListCamerasResponse {
@@ -421,15 +1348,91 @@ export interface LabelTimeCount {
   count: number;
 }
 
+// 统计产生告警的消息，去重合并的告警按每次出现计数
+export interface AlertTimeCount {
+  jobId?: number;
+  jobUuid?: string;
+  severity: AlertSeverity;
+  time: string;
+  count: number;
+}
+
 export interface JobStatsResponse {
   messages: TimeCount[];
   labels?: LabelTimeCount[];
+  alerts: AlertTimeCount[];
+}
+
+export interface AlertTrendRequest extends JobStatsRequest {
+  jobId?: number;
+  severity?: AlertSeverity;
+}
+
+export interface AlertTrendResponse {
+  totals: AlertTimeCount[];
+  items: AlertTimeCount[];
+}
+
+export interface FrameRatePoint {
+  time: string;
+  frameRate: number;
+  frameRateLimit: number;
+}
+
+// 设备组任务的消息和告警包含组内所有设备，帧率只包含本设备
+export interface DeviceJobStats {
+  jobId: number;
+  jobUuid: string;
+  kind: JobKind;
+  deviceGroupId?: number;
+  messages: TimeCount[];
+  alerts: TimeCount[];
+  frameRates: FrameRatePoint[];
+}
+
+export interface DeviceStatsResponse {
+  messages: TimeCount[];
+  alerts: TimeCount[];
+  jobs: DeviceJobStats[];
+}
+
+export interface OccupancyStatsRequest extends JobStatsRequest {
+  zone?: string;
+}
+
+export interface OccupancyPoint {
+  time: string;
+  max: number;
+  in: number;
+  out: number;
+  net: number;
+}
+
+export interface ZoneOccupancyTrend {
+  zone: string;
+  current: number;
+  updateTime?: string;
+  points: OccupancyPoint[];
+}
+
+export interface HeatmapRequest {
+  range?: string;
+  label?: string;
+}
+
+export interface CameraOccupancyResponse {
+  zones: ZoneOccupancyTrend[];
 }
 
 // 预览任务类型
+export type PreviewMode = 'flv' | 'webrtc' | 'hls';
+
 export interface PreviewTask {
   taskUuid: string;
+  mode?: PreviewMode;
+  audio?: boolean;
   previewAddr: string;
+  hlsAddr?: string;
   pullAddr: string;
   pushAddr: string;
   expireTime: string;
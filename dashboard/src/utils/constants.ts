@@ -6,8 +6,22 @@ export const DEFAULT_PAGE_SIZE_OPTIONS = ['10', '20', '50', '100'];
 export const JOB_STATUS_MAP = {
   stopped: { text: '已停止', color: 'default' },
   running: { text: '运行中', color: 'processing' },
+  pending: { text: '排队中', color: 'warning' },
+  paused: { text: '已暂停', color: 'warning' },
+  disarmed: { text: '已撤防', color: 'default' },
 };
 
+// 布防计划的星期选项，0 为周日
+export const WEEKDAY_OPTIONS = [
+  { value: 1, label: '周一' },
+  { value: 2, label: '周二' },
+  { value: 3, label: '周三' },
+  { value: 4, label: '周四' },
+  { value: 5, label: '周五' },
+  { value: 6, label: '周六' },
+  { value: 0, label: '周日' },
+];
+
 // 任务类型映射
 export const JOB_KIND_MAP = {
   detect: { text: '检测任务', color: 'blue' },
@@ -16,6 +30,7 @@ export const JOB_KIND_MAP = {
 
 export const DEVICE_STATUS_MAP = {
   online: { text: '在线', color: 'success' },
+  degraded: { text: '上报延迟', color: 'warning' },
   offline: { text: '离线', color: 'default' },
   error: { text: '错误', color: 'error' },
 };
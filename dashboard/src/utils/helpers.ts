@@ -1,11 +1,26 @@
 import { message } from 'antd';
+import type { FormInstance } from 'antd';
 import dayjs from 'dayjs';
+import utc from 'dayjs/plugin/utc';
+import timezone from 'dayjs/plugin/timezone';
 import { DATE_FORMAT } from './constants';
+import type { ErrorResponse } from '../types';
+
+dayjs.extend(utc);
+dayjs.extend(timezone);
+
+// 用户偏好的显示时区，为空时使用浏览器时区；接口返回的时间均为 UTC
+let displayTimezone = '';
+
+export const setDisplayTimezone = (tz: string | undefined) => {
+  displayTimezone = tz || '';
+};
 
 // 格式化日期
 export const formatDate = (date: string | undefined, format: string = DATE_FORMAT): string => {
   if (!date) return '-';
-  return dayjs(date).format(format);
+  const d = dayjs(date);
+  return (displayTimezone ? d.tz(displayTimezone) : d).format(format);
 };
 
 // 判断某时间是否超过指定分钟数
@@ -18,10 +33,28 @@ export const isOlderThanMinutes = (date: string | undefined, minutes: number): b
   return diff >= minutes;
 };
 
-// 处理 API 错误
-export const handleApiError = (error: any, defaultMessage: string = '操作失败') => {
+// 字段路径转为表单字段名，如 channelIds[0] 转为 ['channelIds', 0]
+const fieldNamePath = (field: string): (string | number)[] =>
+  field.split(/[.[\]]/).filter((s) => s !== '').map((s) => (/^\d+$/.test(s) ? Number(s) : s));
+
+// 在表单上标出校验失败的字段，返回是否标出了字段
+export const applyFieldErrors = (form: FormInstance, error: any): boolean => {
+  const data: ErrorResponse | undefined = error?.response?.data;
+  if (data?.code !== 'validation_failed' || !data.fields?.length) {
+    return false;
+  }
+  form.setFields(data.fields.map((f) => ({ name: fieldNamePath(f.field), errors: [f.message] })));
+  return true;
+};
+
+// 处理 API 错误，传入表单时同时标出校验失败的字段
+export const handleApiError = (error: any, defaultMessage: string = '操作失败', form?: FormInstance) => {
   console.error('API Error:', error);
-  const errorMessage = error?.response?.data?.message || error?.message || defaultMessage;
+  if (form && applyFieldErrors(form, error)) {
+    message.error(`${defaultMessage}: 请检查标出的字段`);
+    return;
+  }
+  const errorMessage = error?.response?.data?.error || error?.response?.data?.message || error?.message || defaultMessage;
   message.error(errorMessage);
 };
 
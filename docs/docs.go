@@ -208,52 +208,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "内部服务器错误",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/v1/admin/user/{user_id}": {
-            "delete": {
-                "description": "删除指定的用户",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "用户管理"
-                ],
-                "summary": "删除用户",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "用户ID",
-                        "name": "user_id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK"
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
-                        }
-                    },
                     "404": {
-                        "description": "Not Found",
+                        "description": "访问令牌不存在",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "内部服务器错误",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -261,117 +223,77 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/admin/users": {
+        "/api/v1/admin/config/reload": {
             "get": {
-                "description": "获取用户列表",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "获取可热加载的配置项和最近一次重新加载的结果，配置文件变化时自动重新加载",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "用户管理"
                 ],
-                "summary": "获取用户列表",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "分页开始位置",
-                        "name": "start",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "分页大小",
-                        "name": "limit",
-                        "in": "query",
-                        "required": true
-                    }
-                ],
+                "summary": "获取配置加载状态",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dao.ListUsersResponse"
+                            "$ref": "#/definitions/dao.ConfigStatusResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     }
                 }
             },
             "post": {
-                "description": "创建用户",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "重新读取配置文件，立即应用 LLM、邮件、消息保留和限流配置，返回已生效和需重启才生效的配置项",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "用户管理"
                 ],
-                "summary": "创建用户",
-                "parameters": [
-                    {
-                        "description": "请求参数",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dao.CreateUserRequest"
-                        }
-                    }
-                ],
+                "summary": "重新加载配置",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dao.CreateUserResponse"
+                            "$ref": "#/definitions/dao.ConfigReloadSpec"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "配置文件加载失败，保留原配置",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/v1/camera": {
+        "/api/v1/admin/db-stats": {
             "get": {
-                "description": "列出摄像头",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "获取数据库连接池的连接数、等待次数和饱和度",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "摄像头"
-                ],
-                "summary": "列出摄像头",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "分页起始位置",
-                        "name": "start",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "分页每页数量",
-                        "name": "limit",
-                        "in": "query",
-                        "required": true
-                    }
+                    "用户管理"
                 ],
+                "summary": "获取数据库连接池状态",
                 "responses": {
                     "200": {
-                        "description": "列出成功",
-                        "schema": {
-                            "$ref": "#/definitions/dao.ListCamerasResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "请求参数错误",
+                        "description": "获取成功",
                         "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
+                            "$ref": "#/definitions/dao.DBPoolStats"
                         }
                     },
                     "401": {
@@ -387,36 +309,30 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "创建摄像头",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/api/v1/admin/organization/{org_id}": {
+            "delete": {
+                "description": "删除组织及其设备、摄像头、任务和工作流，组织下仍有用户时不能删除，默认组织不能删除",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "摄像头"
+                    "组织"
                 ],
-                "summary": "创建摄像头",
+                "summary": "删除组织",
                 "parameters": [
                     {
-                        "description": "创建摄像头请求",
-                        "name": "req",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dao.CreateCameraRequest"
-                        }
+                        "type": "integer",
+                        "description": "组织ID",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "创建成功",
-                        "schema": {
-                            "$ref": "#/definitions/dao.CreateCameraResponse"
-                        }
+                        "description": "OK"
                     },
                     "400": {
                         "description": "请求参数错误",
@@ -430,6 +346,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "无权限",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "组织不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "组织下仍有用户",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -439,39 +373,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/camera/{camera_id}": {
+        "/api/v1/admin/organizations": {
             "get": {
-                "description": "获取摄像头",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "获取所有组织，仅默认组织的管理员可用",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "摄像头"
-                ],
-                "summary": "获取摄像头",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "摄像头ID",
-                        "name": "camera_id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "组织"
                 ],
+                "summary": "获取组织列表",
                 "responses": {
                     "200": {
-                        "description": "获取成功",
-                        "schema": {
-                            "$ref": "#/definitions/dao.CameraSpec"
-                        }
-                    },
-                    "400": {
-                        "description": "请求参数错误",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
+                            "$ref": "#/definitions/dao.ListOrganizationsResponse"
                         }
                     },
                     "401": {
@@ -480,8 +396,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "摄像头不存在",
+                    "403": {
+                        "description": "无权限",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -494,8 +410,8 @@ const docTemplate = `{
                     }
                 }
             },
-            "put": {
-                "description": "更新摄像头",
+            "post": {
+                "description": "创建组织，再在组织下创建其管理员，仅默认组织的管理员可用",
                 "consumes": [
                     "application/json"
                 ],
@@ -503,30 +419,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "摄像头"
+                    "组织"
                 ],
-                "summary": "更新摄像头",
+                "summary": "创建组织",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "摄像头ID",
-                        "name": "camera_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "更新摄像头请求",
-                        "name": "req",
+                        "description": "组织信息",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dao.UpdateCameraRequest"
+                            "$ref": "#/definitions/dao.CreateOrganizationRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "更新成功"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.OrganizationSpec"
+                        }
                     },
                     "400": {
                         "description": "请求参数错误",
@@ -540,8 +452,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "摄像头不存在",
+                    "403": {
+                        "description": "无权限",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -553,31 +465,44 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "删除摄像头",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/api/v1/admin/usage": {
+            "get": {
+                "description": "按调用次数列出调用接口最多的用户、设备和匿名调用方，及其错误率和耗时，统计按小时汇总并周期写入，需要管理员权限",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "摄像头"
+                    "用户管理"
                 ],
-                "summary": "删除摄像头",
+                "summary": "接口调用方排行",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "开始时间，RFC3339，默认 24 小时前",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，RFC3339，默认当前时间",
+                        "name": "end",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "摄像头ID",
-                        "name": "camera_id",
-                        "in": "path",
-                        "required": true
+                        "description": "返回条数，默认 20",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "删除成功"
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListApiUsageResponse"
+                        }
                     },
                     "400": {
                         "description": "请求参数错误",
@@ -600,31 +525,59 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/camera/{camera_id}/preview": {
-            "put": {
-                "description": "刷新摄像头预览任务过期时间",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/admin/usage/endpoint": {
+            "get": {
+                "description": "按调用次数列出接口的调用次数、错误率和耗时，可只统计一个调用方，需要管理员权限",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "摄像头"
+                    "用户管理"
                 ],
-                "summary": "刷新摄像头预览任务过期时间",
+                "summary": "接口调用统计",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "开始时间，RFC3339，默认 24 小时前",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，RFC3339，默认当前时间",
+                        "name": "end",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "摄像头ID",
-                        "name": "camera_id",
-                        "in": "path",
-                        "required": true
+                        "description": "返回条数，默认 20",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "user",
+                            "device",
+                            "anonymous"
+                        ],
+                        "type": "string",
+                        "description": "调用方类型",
+                        "name": "consumerKind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "调用方：用户名、设备 uuid 或 IP",
+                        "name": "consumer",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "刷新成功"
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListApiUsageResponse"
+                        }
                     },
                     "400": {
                         "description": "请求参数错误",
@@ -638,18 +591,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "摄像头不存在",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
-                        }
-                    },
-                    "409": {
-                        "description": "预览任务不存在",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
-                        }
-                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -657,61 +598,45 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "开始摄像头预览",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/api/v1/admin/user/{user_id}": {
+            "delete": {
+                "description": "删除指定的用户",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "摄像头"
+                    "用户管理"
                 ],
-                "summary": "开始摄像头预览",
+                "summary": "删除用户",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "摄像头ID",
-                        "name": "camera_id",
+                        "description": "用户ID",
+                        "name": "user_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "预览任务",
-                        "schema": {
-                            "$ref": "#/definitions/dao.PreviewTask"
-                        }
+                        "description": "OK"
                     },
                     "400": {
-                        "description": "请求参数错误",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "未授权",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "摄像头不存在",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
-                        }
-                    },
-                    "409": {
-                        "description": "预览任务已存在",
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "内部服务器错误",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -719,41 +644,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/device": {
-            "get": {
-                "description": "列出设备",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/admin/user/{user_id}/logout": {
+            "post": {
+                "description": "注销用户的全部登录会话，已签发的登录凭证立即失效，API 令牌不受影响",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "设备"
+                    "用户管理"
                 ],
-                "summary": "列出设备",
+                "summary": "强制用户登出",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "分页起始位置",
-                        "name": "start",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "分页每页数量",
-                        "name": "limit",
-                        "in": "query",
+                        "description": "用户ID",
+                        "name": "user_id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "列出成功",
-                        "schema": {
-                            "$ref": "#/definitions/dao.ListDeviceResponse"
-                        }
+                        "description": "OK"
                     },
                     "400": {
                         "description": "请求参数错误",
@@ -767,6 +679,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -776,49 +694,52 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/device/jobs": {
-            "get": {
-                "description": "获取设备的作业列表",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/admin/user/{user_id}/password/reset": {
+            "post": {
+                "description": "为指定用户生成临时密码，临时密码只在响应中返回一次",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "设备"
+                    "用户管理"
                 ],
-                "summary": "获取设备的作业列表",
+                "summary": "重置用户密码",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "设备ID",
-                        "name": "device_id",
+                        "description": "用户ID",
+                        "name": "user_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "获取成功",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dao.ListJobsResponse"
+                            "$ref": "#/definitions/dao.ResetPasswordResponse"
                         }
                     },
                     "400": {
-                        "description": "请求参数错误",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "未授权",
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "内部服务器错误",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -826,49 +747,49 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/device/preview-tasks": {
-            "get": {
-                "description": "获取设备的预览任务列表",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/admin/user/{user_id}/totp": {
+            "delete": {
+                "description": "停用指定用户的两步验证，用于用户丢失验证器和恢复码的情况",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "设备"
+                    "用户管理"
                 ],
-                "summary": "获取设备的预览任务列表",
+                "summary": "重置用户两步验证",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "设备ID",
-                        "name": "device_id",
+                        "description": "用户ID",
+                        "name": "user_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "获取成功",
-                        "schema": {
-                            "$ref": "#/definitions/dao.ListPreviewTasksResponse"
-                        }
+                        "description": "OK"
                     },
                     "400": {
-                        "description": "请求参数错误",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "未授权",
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "内部服务器错误",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -876,9 +797,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/device/register": {
-            "post": {
-                "description": "注册设备",
+        "/api/v1/admin/users": {
+            "get": {
+                "description": "获取用户列表",
                 "consumes": [
                     "application/json"
                 ],
@@ -886,57 +807,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "设备"
+                    "用户管理"
                 ],
-                "summary": "注册设备",
+                "summary": "获取用户列表",
                 "parameters": [
                     {
-                        "description": "注册请求",
-                        "name": "req",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dao.RegisterRequest"
-                        }
+                        "type": "integer",
+                        "description": "分页开始位置",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "分页大小",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "注册成功",
-                        "schema": {
-                            "$ref": "#/definitions/dao.RegisterResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "请求参数错误",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "未授权",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
-                        }
-                    },
-                    "409": {
-                        "description": "冲突",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "内部服务器错误",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
+                            "$ref": "#/definitions/dao.ListUsersResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/device/report-status": {
+            },
             "post": {
-                "description": "上报设备状态",
+                "description": "创建用户",
                 "consumes": [
                     "application/json"
                 ],
@@ -944,35 +844,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "设备"
+                    "用户管理"
                 ],
-                "summary": "上报设备状态",
+                "summary": "创建用户",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "设备ID",
-                        "name": "device_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "设备状态",
-                        "name": "req",
+                        "description": "请求参数",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dao.DeviceStatus"
+                            "$ref": "#/definitions/dao.CreateUserRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "上报成功"
-                    },
-                    "400": {
-                        "description": "请求参数错误",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
+                            "$ref": "#/definitions/dao.CreateUserResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/agent/proposal/{proposal_uuid}": {
+            "get": {
+                "description": "获取助手提议的任务或工作流变更，提议在批准前不会生效，30分钟后过期",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "对话"
+                ],
+                "summary": "获取助手提议",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "提议uuid",
+                        "name": "proposal_uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.AgentProposalSpec"
                         }
                     },
                     "401": {
@@ -981,6 +900,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "提议不存在或已过期",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -988,24 +913,28 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/device/unregister": {
-            "post": {
-                "description": "注销设备",
-                "consumes": [
-                    "application/json"
-                ],
+            },
+            "delete": {
+                "description": "拒绝并删除助手提议，不做任何变更",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "设备"
+                    "对话"
+                ],
+                "summary": "拒绝助手提议",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "提议uuid",
+                        "name": "proposal_uuid",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "注销设备",
                 "responses": {
                     "200": {
-                        "description": "注销成功"
+                        "description": "拒绝成功"
                     },
                     "401": {
                         "description": "未授权",
@@ -1013,6 +942,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "提议不存在或已过期",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -1022,37 +957,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/device/{device_id}": {
-            "get": {
-                "description": "获取设备",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/agent/proposal/{proposal_uuid}/approve": {
+            "post": {
+                "description": "批准并执行助手提议的任务或工作流变更，与直接调用对应的创建、更新接口效果相同",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "设备"
+                    "对话"
                 ],
-                "summary": "获取设备",
+                "summary": "批准助手提议",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "设备ID",
-                        "name": "device_id",
+                        "type": "string",
+                        "description": "提议uuid",
+                        "name": "proposal_uuid",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "获取成功",
+                        "description": "执行成功",
                         "schema": {
-                            "$ref": "#/definitions/dao.DeviceSpec"
+                            "$ref": "#/definitions/dao.ApproveAgentProposalResponse"
                         }
                     },
                     "400": {
-                        "description": "请求参数错误",
+                        "description": "提议的变更不合法",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -1063,6 +995,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "提议不存在或已过期",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -1070,31 +1008,70 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "删除设备",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/api/v1/alerts/gallery": {
+            "get": {
+                "description": "将时间范围内产生的告警按时间桶和摄像头分组，返回每组的告警数量和最新告警图片，用于快速浏览排查",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "设备"
+                    "消息"
                 ],
-                "summary": "删除设备",
+                "summary": "获取告警图库",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "开始时间(RFC3339 或 Unix 时间戳)，默认 24 小时前",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间(RFC3339 或 Unix 时间戳)，默认当前时间",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "1h",
+                        "description": "时间桶大小，如 10m、1h，最小 1m，最大 24h",
+                        "name": "bucket",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "设备ID",
-                        "name": "device_id",
-                        "in": "path",
-                        "required": true
+                        "description": "任务ID",
+                        "name": "jobId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "cameraId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "告警处理状态：open、acknowledged、resolved",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 4,
+                        "description": "每组返回的最新图片数量，最大 10",
+                        "name": "thumbnails",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "删除成功"
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.AlertGalleryResponse"
+                        }
                     },
                     "400": {
                         "description": "请求参数错误",
@@ -1102,14 +1079,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "未授权",
+                    "500": {
+                        "description": "内部服务器错误",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "内部服务器错误",
+                    "501": {
+                        "description": "数据库不支持，如 SQLite",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -1117,9 +1094,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/job": {
+        "/api/v1/camera": {
             "get": {
-                "description": "分页获取任务列表",
+                "description": "列出摄像头，支持按标签、名称、协议和绑定设备过滤",
                 "consumes": [
                     "application/json"
                 ],
@@ -1127,30 +1104,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务"
+                    "摄像头"
                 ],
-                "summary": "获取任务列表",
+                "summary": "列出摄像头",
                 "parameters": [
                     {
                         "type": "integer",
-                        "default": 0,
-                        "description": "起始位置",
+                        "description": "分页起始位置",
                         "name": "start",
-                        "in": "query"
+                        "in": "query",
+                        "required": true
                     },
                     {
                         "type": "integer",
-                        "default": 10,
-                        "description": "每页数量",
+                        "description": "分页每页数量",
                         "name": "limit",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "标签",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "名称，模糊匹配",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "协议，rtsp 或 rtmp",
+                        "name": "protocol",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "绑定设备ID",
+                        "name": "bindDeviceId",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "获取成功",
+                        "description": "列出成功",
                         "schema": {
-                            "$ref": "#/definitions/dao.ListJobsResponse"
+                            "$ref": "#/definitions/dao.ListCamerasResponse"
                         }
                     },
                     "400": {
@@ -1159,6 +1160,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -1168,7 +1175,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "创建任务",
+                "description": "创建摄像头",
                 "consumes": [
                     "application/json"
                 ],
@@ -1176,17 +1183,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务"
+                    "摄像头"
                 ],
-                "summary": "创建任务",
+                "summary": "创建摄像头",
                 "parameters": [
                     {
-                        "description": "创建任务请求",
+                        "description": "创建摄像头请求",
                         "name": "req",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dao.CreateJobRequest"
+                            "$ref": "#/definitions/dao.CreateCameraRequest"
                         }
                     }
                 ],
@@ -1194,7 +1201,7 @@ const docTemplate = `{
                     "200": {
                         "description": "创建成功",
                         "schema": {
-                            "$ref": "#/definitions/dao.CreateJobResponse"
+                            "$ref": "#/definitions/dao.CreateCameraResponse"
                         }
                     },
                     "400": {
@@ -1218,9 +1225,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/job/{job_id}": {
-            "get": {
-                "description": "根据job_id获取任务详情",
+        "/api/v1/camera/discover": {
+            "post": {
+                "description": "由指定设备扫描所在网络的 ONVIF 摄像头，返回可用于创建摄像头的预填信息",
                 "consumes": [
                     "application/json"
                 ],
@@ -1228,23 +1235,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务"
+                    "摄像头"
                 ],
-                "summary": "获取任务",
+                "summary": "扫描 ONVIF 摄像头",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "任务job_id",
-                        "name": "job_id",
-                        "in": "path",
-                        "required": true
+                        "description": "扫描请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.DiscoverCamerasRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "获取成功",
+                        "description": "扫描结果",
                         "schema": {
-                            "$ref": "#/definitions/dao.JobSpec"
+                            "$ref": "#/definitions/dao.DiscoverCamerasResponse"
                         }
                     },
                     "400": {
@@ -1253,8 +1262,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "404": {
-                        "description": "任务不存在",
+                        "description": "设备不存在",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -1264,11 +1279,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
+                    },
+                    "504": {
+                        "description": "设备响应超时",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "put": {
-                "description": "根据job_id更新任务信息",
+            }
+        },
+        "/api/v1/camera/tags": {
+            "get": {
+                "description": "列出所有摄像头使用的标签，用于按标签过滤摄像头",
                 "consumes": [
                     "application/json"
                 ],
@@ -1276,39 +1299,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务"
-                ],
-                "summary": "更新任务",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "任务job_id",
-                        "name": "job_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "更新任务请求",
-                        "name": "req",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dao.UpdateJobRequest"
-                        }
-                    }
+                    "摄像头"
                 ],
+                "summary": "列出摄像头标签",
                 "responses": {
                     "200": {
-                        "description": "更新成功"
-                    },
-                    "400": {
-                        "description": "请求参数错误",
+                        "description": "列出成功",
                         "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
+                            "$ref": "#/definitions/dao.ListCameraTagsResponse"
                         }
                     },
-                    "404": {
-                        "description": "任务不存在",
+                    "401": {
+                        "description": "未授权",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -1320,9 +1322,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "根据job_id删除任务",
+            }
+        },
+        "/api/v1/camera/{camera_id}": {
+            "get": {
+                "description": "获取摄像头",
                 "consumes": [
                     "application/json"
                 ],
@@ -1330,21 +1334,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务"
+                    "摄像头"
                 ],
-                "summary": "删除任务",
+                "summary": "获取摄像头",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "任务job_id",
-                        "name": "job_id",
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "删除成功"
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CameraSpec"
+                        }
                     },
                     "400": {
                         "description": "请求参数错误",
@@ -1352,8 +1359,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "404": {
-                        "description": "任务不存在",
+                        "description": "摄像头不存在",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -1365,11 +1378,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/job/{job_id}/start": {
+            },
             "put": {
-                "description": "根据job_id启动任务",
+                "description": "更新摄像头",
                 "consumes": [
                     "application/json"
                 ],
@@ -1377,21 +1388,30 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务"
+                    "摄像头"
                 ],
-                "summary": "启动任务",
+                "summary": "更新摄像头",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "任务job_id",
-                        "name": "job_id",
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "更新摄像头请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.UpdateCameraRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "启动成功"
+                        "description": "更新成功"
                     },
                     "400": {
                         "description": "请求参数错误",
@@ -1399,8 +1419,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "404": {
-                        "description": "任务不存在",
+                        "description": "摄像头不存在",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -1412,11 +1438,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/job/{job_id}/stats": {
-            "get": {
-                "description": "根据job_id从InfluxDB查询消息数量趋势；检测任务还返回各Label数量趋势",
+            },
+            "delete": {
+                "description": "删除摄像头",
                 "consumes": [
                     "application/json"
                 ],
@@ -1424,43 +1448,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务"
+                    "摄像头"
                 ],
-                "summary": "获取任务统计",
+                "summary": "删除摄像头",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "任务job_id",
-                        "name": "job_id",
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "开始时间(RFC3339)",
-                        "name": "start",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "结束时间(RFC3339)",
-                        "name": "end",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "default": "5m",
-                        "description": "聚合窗口，如1m、5m、15m",
-                        "name": "window",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "获取成功",
-                        "schema": {
-                            "$ref": "#/definitions/dao.JobStatsResponse"
-                        }
+                        "description": "删除成功"
                     },
                     "400": {
                         "description": "请求参数错误",
@@ -1468,8 +1470,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "任务不存在",
+                    "401": {
+                        "description": "未授权",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -1483,9 +1485,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/job/{job_id}/stop": {
+        "/api/v1/camera/{camera_id}/calibration": {
             "put": {
-                "description": "根据job_id停止任务",
+                "description": "根据至少4组图像像素与地面世界坐标的对应点求解单应矩阵并保存，摄像头上的检测任务会据此把检测框换算为世界坐标并估算速度",
                 "consumes": [
                     "application/json"
                 ],
@@ -1493,30 +1495,48 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务"
+                    "摄像头"
                 ],
-                "summary": "停止任务",
+                "summary": "设置摄像头地面标定",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "任务job_id",
-                        "name": "job_id",
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "标定点",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.SetCameraCalibrationRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "停止成功"
+                        "description": "设置成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CameraCalibration"
+                        }
                     },
                     "400": {
-                        "description": "请求参数错误",
+                        "description": "请求参数错误或标定点退化",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "任务不存在",
+                        "description": "摄像头不存在",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -1528,11 +1548,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/login": {
-            "post": {
-                "description": "用户登录",
+            },
+            "delete": {
+                "description": "删除摄像头地面标定",
                 "consumes": [
                     "application/json"
                 ],
@@ -1540,91 +1558,80 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "用户"
+                    "摄像头"
                 ],
-                "summary": "用户登录",
+                "summary": "删除摄像头地面标定",
                 "parameters": [
                     {
-                        "description": "请求参数",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dao.LoginRequest"
-                        }
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "删除成功"
+                    },
+                    "401": {
+                        "description": "未授权",
                         "schema": {
-                            "$ref": "#/definitions/dao.LoginResponse"
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "摄像头不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/v1/logout": {
-            "post": {
-                "description": "用户登出",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "用户"
-                ],
-                "summary": "用户登出",
-                "responses": {
-                    "200": {
-                        "description": "OK"
-                    }
-                }
-            }
-        },
-        "/api/v1/message": {
+        "/api/v1/camera/{camera_id}/heatmap": {
             "get": {
-                "description": "根据jobId分页获取消息列表",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "统计摄像头上检测任务最近一段时间内检测框中心点的分布，以最近一张检测图片为底图渲染热力图，返回JPEG图片，响应头X-Heatmap-Samples为统计的检测框数量",
                 "produces": [
-                    "application/json"
+                    "image/jpeg"
                 ],
                 "tags": [
-                    "消息"
+                    "摄像头"
                 ],
-                "summary": "获取消息列表",
+                "summary": "获取摄像头检测热力图",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "任务ID",
-                        "name": "jobId",
-                        "in": "query",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 0,
-                        "description": "起始位置",
-                        "name": "start",
+                        "type": "string",
+                        "default": "7d",
+                        "description": "统计范围，如24h、7d，最长90d",
+                        "name": "range",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "每页数量",
-                        "name": "limit",
+                        "type": "string",
+                        "description": "只统计指定标签",
+                        "name": "label",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "获取成功",
+                        "description": "热力图",
                         "schema": {
-                            "$ref": "#/definitions/dao.ListMessagesResponse"
+                            "type": "file"
                         }
                     },
                     "400": {
@@ -1633,6 +1640,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "摄像头不存在或范围内没有检测结果",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -1640,9 +1653,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "创建消息",
+            }
+        },
+        "/api/v1/camera/{camera_id}/occupancy": {
+            "get": {
+                "description": "从InfluxDB查询摄像头上开启区域人数统计的检测任务上报的各区域人数趋势，包括窗口内最大人数、进出人次和净流入，以及最近一次上报的人数",
                 "consumes": [
                     "application/json"
                 ],
@@ -1650,75 +1665,48 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "消息"
+                    "摄像头"
                 ],
-                "summary": "创建消息",
+                "summary": "获取摄像头区域人数统计",
                 "parameters": [
                     {
-                        "description": "创建消息请求",
-                        "name": "req",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dao.CreateMessageRequest"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "创建成功",
-                        "schema": {
-                            "$ref": "#/definitions/dao.CreateMessageResponse"
-                        }
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
+                        "in": "path",
+                        "required": true
                     },
-                    "400": {
-                        "description": "请求参数错误",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
-                        }
+                    {
+                        "type": "string",
+                        "description": "开始时间(RFC3339 或 Unix 时间戳)",
+                        "name": "start",
+                        "in": "query"
                     },
-                    "401": {
-                        "description": "未授权",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
-                        }
+                    {
+                        "type": "string",
+                        "description": "结束时间(RFC3339 或 Unix 时间戳)",
+                        "name": "end",
+                        "in": "query"
                     },
-                    "500": {
-                        "description": "内部服务器错误",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/v1/message/{message_id}": {
-            "get": {
-                "description": "根据message_id获取消息详情",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "消息"
-                ],
-                "summary": "获取消息",
-                "parameters": [
                     {
                         "type": "string",
-                        "description": "消息message_id",
-                        "name": "message_id",
-                        "in": "path",
-                        "required": true
+                        "default": "5m",
+                        "description": "聚合窗口，如1m、5m、15m",
+                        "name": "window",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "区域名称",
+                        "name": "zone",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "获取成功",
                         "schema": {
-                            "$ref": "#/definitions/dao.MessageSpec"
+                            "$ref": "#/definitions/dao.CameraOccupancyResponse"
                         }
                     },
                     "400": {
@@ -1728,7 +1716,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "消息不存在",
+                        "description": "摄像头不存在",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -1740,9 +1728,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "根据message_id删除消息",
+            }
+        },
+        "/api/v1/camera/{camera_id}/preview": {
+            "put": {
+                "description": "刷新摄像头预览任务过期时间",
                 "consumes": [
                     "application/json"
                 ],
@@ -1750,21 +1740,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "消息"
+                    "摄像头"
                 ],
-                "summary": "删除消息",
+                "summary": "刷新摄像头预览任务过期时间",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "消息message_id",
-                        "name": "message_id",
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "删除成功"
+                        "description": "刷新成功"
                     },
                     "400": {
                         "description": "请求参数错误",
@@ -1772,47 +1762,34 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "404": {
-                        "description": "消息不存在",
+                        "description": "摄像头不存在",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "内部服务器错误",
+                    "409": {
+                        "description": "预览任务不存在",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/api/v1/settings/profile": {
-            "get": {
-                "description": "获取用户信息",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "用户管理"
-                ],
-                "summary": "获取用户信息",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
                         "schema": {
-                            "$ref": "#/definitions/dao.UserSpec"
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/workflow": {
-            "get": {
-                "description": "列出工作流",
+            },
+            "post": {
+                "description": "开始摄像头预览",
                 "consumes": [
                     "application/json"
                 ],
@@ -1820,30 +1797,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "工作流"
+                    "摄像头"
                 ],
-                "summary": "列出工作流",
+                "summary": "开始摄像头预览",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "分页起始位置",
-                        "name": "start",
-                        "in": "query",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "分页每页数量",
-                        "name": "limit",
-                        "in": "query",
-                        "required": true
+                        "type": "string",
+                        "description": "预览方式，flv、webrtc 或 hls，默认 flv",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "是否包含音频，默认使用摄像头设置",
+                        "name": "audio",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "列出成功",
+                        "description": "预览任务",
                         "schema": {
-                            "$ref": "#/definitions/dao.ListWorkflowResponse"
+                            "$ref": "#/definitions/dao.PreviewTask"
                         }
                     },
                     "400": {
@@ -1858,6 +1840,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "摄像头不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "预览任务已存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -1866,8 +1860,8 @@ const docTemplate = `{
                     }
                 }
             },
-            "post": {
-                "description": "创建工作流",
+            "delete": {
+                "description": "立即删除摄像头预览任务，设备在下次同步时停止推流",
                 "consumes": [
                     "application/json"
                 ],
@@ -1875,26 +1869,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "工作流"
+                    "摄像头"
                 ],
-                "summary": "创建工作流",
+                "summary": "停止摄像头预览",
                 "parameters": [
                     {
-                        "description": "创建工作流请求",
-                        "name": "req",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dao.CreateWorkflowRequest"
-                        }
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "创建成功",
-                        "schema": {
-                            "$ref": "#/definitions/dao.CreateWorkflowResponse"
-                        }
+                        "description": "停止成功"
                     },
                     "400": {
                         "description": "请求参数错误",
@@ -1908,6 +1897,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "摄像头不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -1917,9 +1912,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/workflow/{workflow_id}": {
-            "get": {
-                "description": "获取工作流",
+        "/api/v1/camera/{camera_id}/probe": {
+            "post": {
+                "description": "在摄像头绑定的设备上使用 ffprobe 探测摄像头地址，返回编码、分辨率、帧率等信息，无法连通时 reachable 为 false 并返回错误信息，用于创建任务前校验摄像头配置",
                 "consumes": [
                     "application/json"
                 ],
@@ -1927,23 +1922,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "工作流"
+                    "摄像头"
                 ],
-                "summary": "获取工作流",
+                "summary": "探测摄像头连通性",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "工作流ID",
-                        "name": "workflow_id",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "获取成功",
+                        "description": "探测结果",
                         "schema": {
-                            "$ref": "#/definitions/dao.WorkflowSpec"
+                            "$ref": "#/definitions/dao.CameraProbeResult"
                         }
                     },
                     "400": {
@@ -1959,7 +1954,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "工作流不存在",
+                        "description": "摄像头不存在",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -1969,11 +1964,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
+                    },
+                    "504": {
+                        "description": "设备响应超时",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "put": {
-                "description": "更新工作流",
+            }
+        },
+        "/api/v1/camera/{camera_id}/ptz/goto-preset": {
+            "post": {
+                "description": "摄像头云台转到预置位",
                 "consumes": [
                     "application/json"
                 ],
@@ -1981,33 +1984,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "工作流"
+                    "摄像头"
                 ],
-                "summary": "更新工作流",
+                "summary": "摄像头云台转到预置位",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "工作流ID",
-                        "name": "workflow_id",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "更新工作流请求",
+                        "description": "预置位",
                         "name": "req",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dao.UpdateWorkflowRequest"
+                            "$ref": "#/definitions/dao.PtzGotoPresetRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "更新成功"
+                        "description": "操作成功"
                     },
                     "400": {
-                        "description": "请求参数错误",
+                        "description": "请求参数错误或摄像头不支持云台",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -2019,21 +2022,23 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "工作流不存在",
+                        "description": "摄像头不存在",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "内部服务器错误",
+                    "502": {
+                        "description": "摄像头请求失败",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "删除工作流",
+            }
+        },
+        "/api/v1/camera/{camera_id}/ptz/move": {
+            "post": {
+                "description": "以指定速度持续移动摄像头云台，直到停止或超时",
                 "consumes": [
                     "application/json"
                 ],
@@ -2041,24 +2046,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "工作流"
+                    "摄像头"
                 ],
-                "summary": "删除工作流",
+                "summary": "摄像头云台移动",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "工作流ID",
-                        "name": "workflow_id",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "移动请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.PtzMoveRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "删除成功"
+                        "description": "操作成功"
                     },
                     "400": {
-                        "description": "请求参数错误",
+                        "description": "请求参数错误或摄像头不支持云台",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -2069,8 +2083,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "内部服务器错误",
+                    "404": {
+                        "description": "摄像头不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "摄像头请求失败",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -2078,9 +2098,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/conversation": {
+        "/api/v1/camera/{camera_id}/ptz/presets": {
             "get": {
-                "description": "获取所有对话，支持分页",
+                "description": "获取摄像头云台预置位",
                 "consumes": [
                     "application/json"
                 ],
@@ -2088,104 +2108,55 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "对话"
+                    "摄像头"
                 ],
-                "summary": "获取对话列表",
+                "summary": "获取摄像头云台预置位",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "分页起始索引",
-                        "name": "start",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "分页每页数量",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "摄像头ID",
+                        "name": "camera_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "预置位列表",
                         "schema": {
-                            "$ref": "#/definitions/dao.ListConversationsResponse"
+                            "$ref": "#/definitions/dao.ListPtzPresetsResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "摄像头不支持云台",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    }
-                }
-            },
-            "post": {
-                "description": "创建一个新的对话，标题为请求体中的title字段",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "对话"
-                ],
-                "summary": "创建对话",
-                "parameters": [
-                    {
-                        "description": "创建对话请求体",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dao.CreateConversationRequest"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    "401": {
+                        "description": "未授权",
                         "schema": {
-                            "$ref": "#/definitions/dao.CreateConversationResponse"
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "摄像头不存在",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "502": {
+                        "description": "摄像头请求失败",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/v1/conversation/{uuid}": {
-            "get": {
-                "description": "获取指定UUID的对话",
+        "/api/v1/camera/{camera_id}/ptz/stop": {
+            "post": {
+                "description": "停止摄像头云台移动",
                 "consumes": [
                     "application/json"
                 ],
@@ -2193,56 +2164,52 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "对话"
+                    "摄像头"
                 ],
-                "summary": "获取对话",
+                "summary": "停止摄像头云台移动",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "对话UUID",
-                        "name": "uuid",
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "操作成功"
+                    },
+                    "400": {
+                        "description": "摄像头不支持云台",
                         "schema": {
-                            "$ref": "#/definitions/dao.ConversationSpec"
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "未授权",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "摄像头不存在",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "502": {
+                        "description": "摄像头请求失败",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "删除指定UUID的对话",
+            }
+        },
+        "/api/v1/camera/{camera_id}/snapshot": {
+            "post": {
+                "description": "在摄像头绑定的设备上截取一帧画面并上传到对象存储，返回预签名下载地址，无需开启预览",
                 "consumes": [
                     "application/json"
                 ],
@@ -2250,61 +2217,61 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "对话"
+                    "摄像头"
                 ],
-                "summary": "删除对话",
+                "summary": "获取摄像头快照",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "对话UUID",
-                        "name": "uuid",
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: conversation deleted",
+                        "description": "快照地址",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/dao.CameraSnapshotResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "请求参数错误",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "摄像头不存在",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "内部服务器错误",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "设备响应超时",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/v1/conversation/{uuid}/chat": {
+        "/api/v1/camera/{camera_id}/test-detect": {
             "post": {
-                "description": "发送聊天消息并获取回复",
+                "description": "在摄像头绑定的设备上同步执行一次单帧检测，返回检测框和标注图片，相同参数的结果会短暂缓存",
                 "consumes": [
                     "application/json"
                 ],
@@ -2312,30 +2279,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "对话"
+                    "摄像头"
                 ],
-                "summary": "聊天",
+                "summary": "测试摄像头检测效果",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "对话ID",
-                        "name": "conversationId",
-                        "in": "query",
+                        "description": "摄像头ID",
+                        "name": "camera_id",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "description": "聊天请求",
-                        "name": "request",
+                        "description": "测试检测请求",
+                        "name": "req",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dao.ChatRequest"
+                            "$ref": "#/definitions/dao.TestDetectRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "聊天回复"
+                        "description": "检测结果",
+                        "schema": {
+                            "$ref": "#/definitions/dao.TestDetectResponse"
+                        }
                     },
                     "400": {
                         "description": "请求参数错误",
@@ -2343,18 +2313,36 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "摄像头不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
+                    },
+                    "504": {
+                        "description": "设备响应超时",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/v1/conversation/{uuid}/message": {
+        "/api/v1/canary": {
             "get": {
-                "description": "根据conversationId分页获取聊天消息列表",
+                "description": "服务端定期向设备使用的 NSQ 主题发布测试消息，经消费者和内置的模拟工作流处理后检查端到端耗时，成功率低于阈值时产生系统健康事件",
                 "consumes": [
                     "application/json"
                 ],
@@ -2362,47 +2350,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "对话"
-                ],
-                "summary": "获取聊天消息列表",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "对话ID",
-                        "name": "conversationId",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "default": 0,
-                        "description": "起始位置",
-                        "name": "start",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "每页数量",
-                        "name": "limit",
-                        "in": "query"
-                    }
+                    "系统健康"
                 ],
+                "summary": "获取消息处理链路自检状态",
                 "responses": {
                     "200": {
                         "description": "获取成功",
                         "schema": {
-                            "$ref": "#/definitions/dao.ListChatMessagesResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "请求参数错误",
-                        "schema": {
-                            "$ref": "#/definitions/server.ErrorResponse"
+                            "$ref": "#/definitions/dao.CanaryStatusResponse"
                         }
                     },
-                    "500": {
-                        "description": "内部服务器错误",
+                    "401": {
+                        "description": "未授权",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
@@ -2410,9 +2369,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/conversation/{uuid}/title": {
-            "post": {
-                "description": "根据聊天历史生成聊天标题",
+        "/api/v1/device": {
+            "get": {
+                "description": "列出设备",
                 "consumes": [
                     "application/json"
                 ],
@@ -2420,23 +2379,248 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "对话"
+                    "设备"
                 ],
-                "summary": "生成聊天标题",
+                "summary": "列出设备",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "对话ID",
-                        "name": "conversationId",
+                        "description": "分页起始位置",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "分页每页数量",
+                        "name": "limit",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "设备状态，online、degraded 或 offline",
+                        "name": "state",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "聊天标题",
+                        "description": "列出成功",
                         "schema": {
-                            "$ref": "#/definitions/dao.GenChatTitleResponse"
+                            "$ref": "#/definitions/dao.ListDeviceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device-group": {
+            "get": {
+                "description": "列出设备组，不包含成员设备",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备组"
+                ],
+                "summary": "列出设备组",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "分页起始位置",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "分页每页数量",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "列出成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListDeviceGroupsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "创建设备组，任务可以以设备组为目标，在组内所有设备上运行",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备组"
+                ],
+                "summary": "创建设备组",
+                "parameters": [
+                    {
+                        "description": "创建设备组请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateDeviceGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "创建成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateDeviceGroupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device-group/{group_id}": {
+            "get": {
+                "description": "获取设备组及其成员设备",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备组"
+                ],
+                "summary": "获取设备组",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备组ID",
+                        "name": "group_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.DeviceGroupSpec"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "设备组不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "更新设备组名称、描述或成员，成员变化后以该组为目标的任务会在新成员上启动、在移出的设备上停止",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备组"
+                ],
+                "summary": "更新设备组",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备组ID",
+                        "name": "group_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "更新设备组请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.UpdateDeviceGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.DeviceGroupSpec"
                         }
                     },
                     "400": {
@@ -2445,432 +2629,11691 @@ const docTemplate = `{
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "设备组不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
                             "$ref": "#/definitions/server.ErrorResponse"
                         }
-                    }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除设备组，仍有任务以该组为目标时不能删除",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备组"
+                ],
+                "summary": "删除设备组",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备组ID",
+                        "name": "group_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除成功"
+                    },
+                    "400": {
+                        "description": "设备组仍被任务使用",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "设备组不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device-group/{group_id}/rollout": {
+            "get": {
+                "description": "按创建时间倒序列出设备组的升级及其进度",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "版本升级"
+                ],
+                "summary": "列出设备组的升级",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备组ID",
+                        "name": "group_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "分页起始位置",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "分页每页数量",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "列出成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListRolloutsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "设备组不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "将设备组升级到指定版本，版本必须属于设备组的发布通道，未指定时使用通道的最新版本。\n设备在设备组的升级时段内逐个升级，升级过的设备中失败的比例超过阈值时自动暂停",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "版本升级"
+                ],
+                "summary": "升级设备组",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备组ID",
+                        "name": "group_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "升级请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateRolloutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "创建成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateRolloutResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或设备组已有进行中的升级",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "设备组或版本不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/camera-probe-result": {
+            "post": {
+                "description": "上报摄像头探测结果",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "上报摄像头探测结果",
+                "parameters": [
+                    {
+                        "description": "探测结果",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CameraProbeResult"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "上报成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/camera-probe-tasks": {
+            "get": {
+                "description": "获取设备的摄像头探测任务列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "获取设备的摄像头探测任务列表",
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListCameraProbeTasksResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/inventory": {
+            "get": {
+                "description": "按操作系统和各组件版本筛选设备，并按 lumina 版本统计设备数，用于规划升级",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "列出设备软件版本",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "分页起始位置",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "分页每页数量",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "操作系统，模糊匹配",
+                        "name": "os",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "内核版本前缀",
+                        "name": "kernel",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "lumina 版本前缀，如 v0.3",
+                        "name": "luminaVersion",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ffmpeg 版本前缀",
+                        "name": "ffmpegVersion",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OpenCV 版本前缀",
+                        "name": "opencvVersion",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Triton 版本前缀",
+                        "name": "tritonVersion",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "列出成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListDeviceInventoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/jobs": {
+            "get": {
+                "description": "获取设备的作业列表，响应带有 ETag，仅在作业增删或更新时变化。\n请求带 If-None-Match 且作业未变化时返回 304，watch=true 时等待作业变化或超时后再返回，用于设备及时获取作业变更",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "获取设备的作业列表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "是否等待作业变化",
+                        "name": "watch",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "等待的最长秒数，默认 30，最大 60",
+                        "name": "timeout",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "上次响应的 ETag",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListJobsResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "作业未变化"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/onvif-discover-result": {
+            "post": {
+                "description": "上报 ONVIF 扫描结果",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "上报 ONVIF 扫描结果",
+                "parameters": [
+                    {
+                        "description": "扫描结果",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.OnvifDiscoverResult"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "上报成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/onvif-discover-tasks": {
+            "get": {
+                "description": "获取设备的 ONVIF 扫描任务列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "获取设备的 ONVIF 扫描任务列表",
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListOnvifDiscoverTasksResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/preview-tasks": {
+            "get": {
+                "description": "获取设备的预览任务列表，响应带有 ETag，请求带 If-None-Match 且预览任务未变化时返回 304，\nwatch=true 时等待预览任务变化或超时后再返回",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "获取设备的预览任务列表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "是否等待预览任务变化",
+                        "name": "watch",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "等待的最长秒数，默认 30，最大 60",
+                        "name": "timeout",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "上次响应的 ETag",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListPreviewTasksResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "预览任务未变化"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/register": {
+            "post": {
+                "description": "注册设备",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "注册设备",
+                "parameters": [
+                    {
+                        "description": "注册请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "注册成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.RegisterResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "冲突",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "请求过于频繁",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/report-status": {
+            "post": {
+                "description": "上报设备状态，对象存储不可用时可附带最多 4 个不超过 64KB 的小文件，服务端临时保存 24 小时",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "上报设备状态",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "设备状态",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.DeviceStatus"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "上报成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.DeviceStatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/rotate-token": {
+            "post": {
+                "description": "为当前设备签发新令牌，设备首次使用新令牌后旧令牌失效，此前旧令牌仍然有效，未使用的新令牌在再次轮换时作废",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "轮换设备令牌",
+                "responses": {
+                    "200": {
+                        "description": "轮换成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.RotateDeviceTokenResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/snapshot-result": {
+            "post": {
+                "description": "上报快照结果",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "上报快照结果",
+                "parameters": [
+                    {
+                        "description": "快照结果",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.SnapshotResult"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "上报成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/snapshot-tasks": {
+            "get": {
+                "description": "获取设备的快照任务列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "获取设备的快照任务列表",
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListSnapshotTasksResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/talk-down-result": {
+            "post": {
+                "description": "上报喊话结果",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "上报喊话结果",
+                "parameters": [
+                    {
+                        "description": "喊话结果",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.TalkDownResult"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "上报成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/talk-down-tasks": {
+            "get": {
+                "description": "获取设备的喊话任务列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "获取设备的喊话任务列表",
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListTalkDownTasksResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/test-detect-result": {
+            "post": {
+                "description": "上报测试检测结果",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "上报测试检测结果",
+                "parameters": [
+                    {
+                        "description": "测试检测结果",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.TestDetectResult"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "上报成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "测试检测任务不存在或未分配给该设备",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/test-detect-tasks": {
+            "get": {
+                "description": "获取设备的测试检测任务列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "获取设备的测试检测任务列表",
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListTestDetectTasksResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/unregister": {
+            "post": {
+                "description": "注销设备",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "注销设备",
+                "responses": {
+                    "200": {
+                        "description": "注销成功"
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/upgrade-result": {
+            "post": {
+                "description": "设备下载或安装新版本失败时上报，升级成功以设备重启后获取升级任务时上报的版本为准",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "上报升级失败",
+                "parameters": [
+                    {
+                        "description": "升级结果",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.UpgradeResult"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "上报成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/upgrade-task": {
+            "get": {
+                "description": "设备所在设备组有进行中的升级且处于升级时段时返回升级任务。设备已是目标版本时记为升级成功",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "获取设备的升级任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "设备当前版本",
+                        "name": "version",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.GetUpgradeTaskResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/{device_id}": {
+            "get": {
+                "description": "获取设备",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "获取设备",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.DeviceSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "更新设备名称和最大并发执行任务数",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "更新设备",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "更新设备请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.UpdateDeviceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.DeviceSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "设备不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除设备",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "删除设备",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "设备不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/{device_id}/rotate-token": {
+            "put": {
+                "description": "要求设备在下次上报状态时轮换令牌，用于令牌泄露后无需重新注册设备即可使旧令牌失效",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "强制轮换设备令牌",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "设置成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "设备不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/device/{device_id}/stats": {
+            "get": {
+                "description": "从InfluxDB查询设备上所有任务（包括所在设备组的任务）的消息数量、告警数量和执行器帧率趋势，用于按设备排查算力不足。\n设备组任务的消息和告警无法区分设备，包含组内所有设备；帧率只包含本设备",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "设备"
+                ],
+                "summary": "获取设备统计",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "设备ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间(RFC3339 或 Unix 时间戳)",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间(RFC3339 或 Unix 时间戳)",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "5m",
+                        "description": "聚合窗口，如1m、5m、15m",
+                        "name": "window",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.DeviceStatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "设备不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/events": {
+            "get": {
+                "description": "通过 Server-Sent Events 推送设备在线状态变化、任务状态变更和新告警，事件名为事件类型，数据为 JSON，用于仪表盘替代定时刷新列表",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "事件"
+                ],
+                "summary": "推送实时事件",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "订阅的事件类型，逗号分隔：device_state、job_status、alert，为空时订阅全部",
+                        "name": "kinds",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "事件",
+                        "schema": {
+                            "$ref": "#/definitions/dao.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/graphql": {
+            "post": {
+                "description": "在一次查询中组合设备、摄像头、任务、最近消息和任务统计，只返回选择的字段，用于减少仪表盘复杂页面的请求数，仅支持查询。\n根查询字段：devices(state)、device(id)、cameras、camera(id)、jobs、job(id)、messages，列表字段返回 items 和 total，参数 start、limit 同 REST 接口，limit 最大 100。\n设备可查询 cameras、jobs，摄像头可查询 jobs，任务可查询 latestMessages(limit, alerted) 和 stats(start, end, window)，其余字段同 REST 接口返回的 JSON 字段",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GraphQL"
+                ],
+                "summary": "执行 GraphQL 查询",
+                "parameters": [
+                    {
+                        "description": "GraphQL 请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/graphql.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询结果，字段错误在 errors 中返回",
+                        "schema": {
+                            "$ref": "#/definitions/graphql.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "查询语法错误",
+                        "schema": {
+                            "$ref": "#/definitions/graphql.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/health-event": {
+            "get": {
+                "description": "按创建时间倒序列出系统健康事件，如任务消息量或告警量与同一时段的通常值相差过大、设备离线",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "系统健康"
+                ],
+                "summary": "列出系统健康事件",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "分页起始位置",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "分页每页数量",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "事件类型，message_volume、alert_volume、canary 或 device_offline",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "任务ID",
+                        "name": "jobId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "设备ID",
+                        "name": "deviceId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "是否已解决",
+                        "name": "resolved",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "列出成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListHealthEventsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/health-event/{event_id}/resolve": {
+            "put": {
+                "description": "手动将事件标记为已解决，异常再次出现时会产生新的事件",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "系统健康"
+                ],
+                "summary": "解决系统健康事件",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "事件ID",
+                        "name": "event_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "解决成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.HealthEventSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "事件不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job": {
+            "get": {
+                "description": "分页获取任务列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "获取任务列表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "起始位置",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "每页数量",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListJobsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "创建任务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "创建任务",
+                "parameters": [
+                    {
+                        "description": "创建任务请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateJobRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "创建成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateJobResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/validate": {
+            "post": {
+                "description": "不保存任务，检查运行设备、摄像头连通性、设备 Triton 是否已加载模型以及工作流接口是否可用，返回各检查项的结果。\n摄像头与模型在运行任务的设备上检查，设备未响应时两项均为未通过",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "检查任务是否就绪",
+                "parameters": [
+                    {
+                        "description": "创建任务请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateJobRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "就绪报告",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ValidateJobResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/{job_id}": {
+            "get": {
+                "description": "根据job_id获取任务详情",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "获取任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.JobSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "根据job_id更新任务信息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "更新任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "更新任务请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.UpdateJobRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "根据job_id删除任务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "删除任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/{job_id}/artifacts": {
+            "get": {
+                "description": "设备上传对象存储失败时会随状态上报最新触发图片的缩略图和最近一次错误时的画面，服务端保存 24 小时，每种类型只保留最新一个",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "获取设备随状态上报的小文件",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListJobArtifactsResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/{job_id}/artifacts/{kind}": {
+            "get": {
+                "description": "返回文件内容，Content-Type 为设备上报的类型",
+                "produces": [
+                    "image/jpeg",
+                    "image/png"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "获取设备随状态上报的小文件内容",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "类型：trigger_thumbnail、error_screenshot",
+                        "name": "kind",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "文件内容",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "任务或文件不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/{job_id}/clone": {
+            "post": {
+                "description": "以相同的配置复制任务，可指定新的摄像头、设备或设备组，便于将同一检测配置批量应用到多个摄像头",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "复制任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "复制任务请求",
+                        "name": "req",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CloneJobRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "复制成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateJobResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/{job_id}/device-status": {
+            "get": {
+                "description": "获取以设备组为目标的任务在各成员设备上的运行状态",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "获取任务在各设备上的状态",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListJobDeviceStatusResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/{job_id}/history": {
+            "get": {
+                "description": "按时间倒序列出任务的状态变更，包括设备上报的执行器状态变化和通过接口进行的创建、启动、停止、暂停、恢复操作，设备组任务按设备分别记录",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "获取任务状态变更记录",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "分页起始位置",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "分页每页数量",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListJobStatusHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/{job_id}/pause": {
+            "put": {
+                "description": "根据job_id暂停任务，设备保留执行器和连接，但不再处理和上报数据",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "暂停任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "暂停成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/{job_id}/precision": {
+            "get": {
+                "description": "根据消息的人工标注统计任务检测准确率随时间的变化，用于发现模型效果下降。\n只统计有标注的消息，按消息的采集时间分桶，标注保留到消息被清理之后",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "获取任务检测准确率趋势",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间(RFC3339 或 Unix 时间戳)，默认 30 天前",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间(RFC3339 或 Unix 时间戳)，默认当前时间",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "24h",
+                        "description": "时间桶大小，如 6h、24h，最小 1h，最大 720h",
+                        "name": "bucket",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.JobPrecisionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/{job_id}/resume": {
+            "put": {
+                "description": "根据job_id恢复已暂停的任务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "恢复任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "恢复成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/{job_id}/start": {
+            "put": {
+                "description": "根据job_id启动任务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "启动任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "启动成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/{job_id}/stats": {
+            "get": {
+                "description": "根据job_id从InfluxDB查询消息数量和各级别告警数量趋势；检测任务还返回各Label数量趋势",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "获取任务统计",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间(RFC3339 或 Unix 时间戳)",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间(RFC3339 或 Unix 时间戳)",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "5m",
+                        "description": "聚合窗口，如1m、5m、15m",
+                        "name": "window",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.JobStatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/job/{job_id}/stop": {
+            "put": {
+                "description": "根据job_id停止任务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务"
+                ],
+                "summary": "停止任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务job_id",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "停止成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/login": {
+            "post": {
+                "description": "用户登录",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户"
+                ],
+                "summary": "用户登录",
+                "parameters": [
+                    {
+                        "description": "请求参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.LoginResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "用户名、密码或两步验证码错误，需要两步验证码时错误码为 totp_required",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "请求过于频繁",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/logout": {
+            "post": {
+                "description": "用户登出，注销刷新令牌所属的会话，请求携带的登录凭证立即失效",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户"
+                ],
+                "summary": "用户登出",
+                "parameters": [
+                    {
+                        "description": "刷新令牌，为空时使用 refresh_token cookie",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dao.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/v1/message": {
+            "get": {
+                "description": "按任务、摄像头、设备、拍摄时间、检测标签和置信度、告警状态过滤并分页获取消息列表，最新的在前",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "获取消息列表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "任务ID",
+                        "name": "jobId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "起始位置",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "每页数量",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "只返回产生告警的消息",
+                        "name": "alerted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "告警处理状态：open、acknowledged、resolved，仅 alerted 为 true 时生效",
+                        "name": "alertStatus",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "cameraId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "设备ID，匹配直接或通过设备组分配给该设备的任务",
+                        "name": "deviceId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "拍摄时间下限（含），RFC3339 格式或 Unix 时间戳",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "拍摄时间上限（不含），RFC3339 格式或 Unix 时间戳",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "检测标签",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "检测框最低置信度，0-1",
+                        "name": "minConfidence",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListMessagesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "创建消息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "创建消息",
+                "parameters": [
+                    {
+                        "description": "创建消息请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateMessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "创建成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/export": {
+            "get": {
+                "description": "按与消息列表相同的过滤条件流式导出消息，包括检测框和工作流回答，用于离线分析和合规报告，最新的在前",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "导出消息",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "csv",
+                        "description": "导出格式：csv、jsonl",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100000,
+                        "description": "最多导出的消息数量，最大 1000000",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "全文搜索工作流回答",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "任务ID",
+                        "name": "jobId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "cameraId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "设备ID，匹配直接或通过设备组分配给该设备的任务",
+                        "name": "deviceId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "拍摄时间下限（含），RFC3339 格式或 Unix 时间戳",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "拍摄时间上限（不含），RFC3339 格式或 Unix 时间戳",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "检测标签",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "检测框最低置信度，0-1",
+                        "name": "minConfidence",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "只导出产生告警的消息",
+                        "name": "alerted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "告警处理状态：open、acknowledged、resolved，仅 alerted 为 true 时生效",
+                        "name": "alertStatus",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "导出的文件",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/search": {
+            "get": {
+                "description": "在所有任务的工作流回答中全文搜索，如“把手伸进配电箱”，可同时按任务、摄像头、设备、时间等过滤，最新的在前",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "搜索消息",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "搜索的文本，以空格分隔的多个词需全部匹配",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "任务ID",
+                        "name": "jobId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "cameraId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "设备ID，匹配直接或通过设备组分配给该设备的任务",
+                        "name": "deviceId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "拍摄时间下限（含），RFC3339 格式或 Unix 时间戳",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "拍摄时间上限（不含），RFC3339 格式或 Unix 时间戳",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "只返回产生告警的消息",
+                        "name": "alerted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "起始位置",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "每页数量",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "搜索成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListMessagesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/semantic-search": {
+            "get": {
+                "description": "按描述的含义搜索消息，如“夜间有人翻越围栏”，按相似度从高到低返回。消息由 consumer 的 embedding sink 向量化，\n未启用时返回 400；已删除的消息不会返回",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "按语义搜索消息",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "搜索的描述",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "返回的数量，最大 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "最低相似度，0-1",
+                        "name": "minScore",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "任务ID",
+                        "name": "jobId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "cameraId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "只返回产生告警的消息",
+                        "name": "alerted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "拍摄时间下限（含），RFC3339 格式或 Unix 时间戳",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "拍摄时间上限（不含），RFC3339 格式或 Unix 时间戳",
+                        "name": "until",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "搜索成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.SemanticSearchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/{message_id}": {
+            "get": {
+                "description": "根据message_id获取消息详情",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "获取消息",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.MessageSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "根据message_id删除消息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "删除消息",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/{message_id}/ack": {
+            "put": {
+                "description": "确认消息产生的告警，告警升级策略不再通知后续的通道，重复确认时保留第一次确认",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "确认告警",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "确认成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.AlertSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在或未产生告警",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/{message_id}/alert": {
+            "get": {
+                "description": "获取消息产生的告警的级别、处理状态和处理记录",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "获取告警处理状态",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.AlertSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在或未产生告警",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/{message_id}/assign": {
+            "put": {
+                "description": "指派负责处理告警的用户，assigneeId 为 0 时取消指派",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "指派告警",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "指派的用户",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.AssignAlertRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "指派成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.AlertSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在或未产生告警",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/{message_id}/comment": {
+            "post": {
+                "description": "在告警的处理记录中添加评论",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "评论告警",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "评论内容",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CommentAlertRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "评论成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.AlertSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在或未产生告警",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/{message_id}/feedback": {
+            "get": {
+                "description": "获取消息检测结果是否正确的人工标注",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "获取消息的人工标注",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.MessageFeedbackSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在或未标注",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "标注消息的检测结果正确（true_positive）或误报（false_positive），可附带修正后的标签，\n重复标注时覆盖之前的标注。标注用于统计任务的检测准确率趋势",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "标注消息",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "标注内容",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.MessageFeedbackRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "标注成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.MessageFeedbackSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除消息的人工标注，该消息不再计入准确率统计",
+                "tags": [
+                    "消息"
+                ],
+                "summary": "删除消息的人工标注",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "删除成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/{message_id}/media": {
+            "get": {
+                "description": "返回消息图片和视频的预签名链接，用于访问私有存储桶中的对象，链接在过期时间前有效",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "获取消息媒体的临时链接",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "有效期，单位秒，最大 7 天，默认为配置的 s3.presignExpire",
+                        "name": "expire",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.MessageMediaResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/{message_id}/resolve": {
+            "put": {
+                "description": "将告警标记为已解决，未确认的告警同时被确认，重复解决时保留第一次解决",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "解决告警",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "解决说明",
+                        "name": "req",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ResolveAlertRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "解决成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.AlertSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在或未产生告警",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/{message_id}/talk-down": {
+            "post": {
+                "description": "通过消息所属任务的设备在现场扬声器播放音频或合成语音，请求中未填写的字段使用任务的喊话配置，等待设备播放完成后返回",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "对消息现场手动喊话",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "喊话内容",
+                        "name": "req",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dao.TalkDownRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "喊话结果",
+                        "schema": {
+                            "$ref": "#/definitions/dao.TalkDownResult"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "设备响应超时",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/{message_id}/verify": {
+            "get": {
+                "description": "校验消息的设备签名，证明图片、视频和检测结果在设备采集后未被篡改：签名由设备登记的公钥签出，\n消息内容与签名内容一致，存储桶中的文件哈希与签名时一致。只有启用证据签名的设备上报的消息带有签名",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "校验消息的设备签名",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息message_id",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": true,
+                        "description": "是否下载图片和视频校验其哈希",
+                        "name": "checkMedia",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "校验报告",
+                        "schema": {
+                            "$ref": "#/definitions/dao.MessageVerification"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notification/channel": {
+            "get": {
+                "description": "列出所有通知通道及其最近一次投递结果，需要管理员权限",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "列出通知通道",
+                "responses": {
+                    "200": {
+                        "description": "列出成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListNotificationChannelsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "创建通知通道，告警产生后 webhook 通道按模板渲染请求体并 POST 到配置的地址，email 通道通过 SMTP 将告警图片、分析结果发送到通道和任务的收件人，钉钉、企业微信和 slack 通道向群机器人发送带图片预签名地址的 markdown 消息，失败时按指数退避重试，需要管理员权限",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "创建通知通道",
+                "parameters": [
+                    {
+                        "description": "通知通道",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateNotificationChannelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "创建成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateNotificationChannelResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notification/channel/{channel_id}": {
+            "get": {
+                "description": "获取通知通道，需要管理员权限",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "获取通知通道",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "通知通道ID",
+                        "name": "channel_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.NotificationChannelSpec"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "通知通道不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "更新通知通道，只修改传入的字段，需要管理员权限",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "更新通知通道",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "通知通道ID",
+                        "name": "channel_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "通知通道",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.UpdateNotificationChannelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.NotificationChannelSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "通知通道不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除通知通道，需要管理员权限",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "删除通知通道",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "通知通道ID",
+                        "name": "channel_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除成功"
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "通知通道不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notification/channel/{channel_id}/test": {
+            "post": {
+                "description": "向通知通道发送一条示例告警，不重试，返回对端的状态码和发送的请求体或邮件正文，需要管理员权限",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "测试通知通道",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "通知通道ID",
+                        "name": "channel_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "测试结果",
+                        "schema": {
+                            "$ref": "#/definitions/dao.TestNotificationChannelResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "通知通道不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notification/escalation": {
+            "get": {
+                "description": "列出所有告警升级策略，需要管理员权限",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "列出告警升级策略",
+                "responses": {
+                    "200": {
+                        "description": "列出成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListEscalationPoliciesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "创建告警升级策略，策略接受的告警按步骤通知通道，延迟为 0 的步骤立即通知，其余步骤在告警产生后仍未确认达到延迟分钟数时通知，需要管理员权限",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "创建告警升级策略",
+                "parameters": [
+                    {
+                        "description": "告警升级策略",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateEscalationPolicyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "创建成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateEscalationPolicyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notification/escalation/{policy_id}": {
+            "get": {
+                "description": "获取告警升级策略，需要管理员权限",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "获取告警升级策略",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "告警升级策略ID",
+                        "name": "policy_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.EscalationPolicySpec"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "告警升级策略不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "更新告警升级策略，只修改传入的字段，需要管理员权限",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "更新告警升级策略",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "告警升级策略ID",
+                        "name": "policy_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "告警升级策略",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.UpdateEscalationPolicyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.EscalationPolicySpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "告警升级策略不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除告警升级策略，需要管理员权限",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "删除告警升级策略",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "告警升级策略ID",
+                        "name": "policy_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除成功"
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "告警升级策略不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notification/preference": {
+            "get": {
+                "description": "获取当前用户的告警推送偏好",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "获取通知偏好",
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.NotificationPreferenceSpec"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "更新当前用户的告警推送偏好",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "更新通知偏好",
+                "parameters": [
+                    {
+                        "description": "通知偏好",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.UpdateNotificationPreferenceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.NotificationPreferenceSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notification/subscription": {
+            "post": {
+                "description": "保存当前用户浏览器的推送订阅",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "订阅推送",
+                "parameters": [
+                    {
+                        "description": "订阅信息",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.PushSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "订阅成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除当前用户浏览器的推送订阅",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "取消订阅推送",
+                "parameters": [
+                    {
+                        "description": "订阅信息",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.DeletePushSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "取消成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notification/vapid-public-key": {
+            "get": {
+                "description": "获取浏览器订阅推送所需的VAPID公钥",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "通知"
+                ],
+                "summary": "获取推送公钥",
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.VapidPublicKeyResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/oidc/callback": {
+            "get": {
+                "description": "身份提供方登录后的回调，校验 ID Token，按需创建用户并同步角色，设置登录凭证后跳回登录前的页面",
+                "tags": [
+                    "用户"
+                ],
+                "summary": "OIDC 登录回调",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "授权码",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "登录请求的 state",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "400": {
+                        "description": "state 无效或已过期",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "登录失败",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "用户无权登录",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "用户名已被本地用户占用",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/oidc/login": {
+            "get": {
+                "description": "跳转到 OIDC 身份提供方登录，登录成功后回调接口设置登录凭证并跳回 redirect",
+                "tags": [
+                    "用户"
+                ],
+                "summary": "OIDC 登录",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "登录后跳转的页面路径，默认为 /",
+                        "name": "redirect",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "未启用 OIDC 登录",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "身份提供方不可用",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/refresh": {
+            "post": {
+                "description": "用刷新令牌换取新的登录凭证和刷新令牌，原刷新令牌随即作废，已作废的刷新令牌再次使用时视为被盗用，其会话随之失效",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户"
+                ],
+                "summary": "刷新登录凭证",
+                "parameters": [
+                    {
+                        "description": "刷新令牌，为空时使用 refresh_token cookie",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dao.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.RefreshResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "刷新令牌无效、已过期或已作废",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/release": {
+            "get": {
+                "description": "按创建时间倒序列出设备程序版本",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "版本升级"
+                ],
+                "summary": "列出版本",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "分页起始位置",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "分页每页数量",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "发布通道，stable 或 beta",
+                        "name": "channel",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "列出成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListReleasesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "登记一个设备程序版本，可通过设备组升级下发到设备",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "版本升级"
+                ],
+                "summary": "创建版本",
+                "parameters": [
+                    {
+                        "description": "创建版本请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateReleaseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "创建成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateReleaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/release/{release_id}": {
+            "delete": {
+                "description": "删除设备程序版本，已被升级使用的版本不能删除",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "版本升级"
+                ],
+                "summary": "删除版本",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "版本ID",
+                        "name": "release_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除成功"
+                    },
+                    "400": {
+                        "description": "版本已被升级使用",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "版本不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/report-schedule": {
+            "get": {
+                "description": "列出所有定时报表，需要管理员权限",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "报表"
+                ],
+                "summary": "列出定时报表",
+                "responses": {
+                    "200": {
+                        "description": "列出成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListReportSchedulesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "创建定时报表，每日或每周在指定时刻生成上一周期站点（摄像头标签）的消息数、检测最多的标签、告警和设备在线率汇总，\n保存为 HTML 或 PDF 并通过通知通道投递：邮件通道发送完整报表，即时通讯通道发送摘要和报表链接，webhook 通道发送 JSON 摘要。需要管理员权限",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "报表"
+                ],
+                "summary": "创建定时报表",
+                "parameters": [
+                    {
+                        "description": "定时报表",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateReportScheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "创建成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateReportScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/report-schedule/{schedule_id}": {
+            "get": {
+                "description": "获取定时报表及最近一次生成的结果，需要管理员权限",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "报表"
+                ],
+                "summary": "获取定时报表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "定时报表ID",
+                        "name": "schedule_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ReportScheduleSpec"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "定时报表不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "更新定时报表，只修改传入的字段，需要管理员权限",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "报表"
+                ],
+                "summary": "更新定时报表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "定时报表ID",
+                        "name": "schedule_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "定时报表",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.UpdateReportScheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ReportScheduleSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "定时报表不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除定时报表，已生成的报表保留，需要管理员权限",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "报表"
+                ],
+                "summary": "删除定时报表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "定时报表ID",
+                        "name": "schedule_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除成功"
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "定时报表不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/report-schedule/{schedule_id}/reports": {
+            "get": {
+                "description": "列出定时报表已生成的报表，最新的在前，需要管理员权限",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "报表"
+                ],
+                "summary": "列出已生成的报表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "定时报表ID",
+                        "name": "schedule_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "起始位置",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "返回数量，最大 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "列出成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListReportsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "定时报表不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/report-schedule/{schedule_id}/run": {
+            "post": {
+                "description": "立即生成最近一个已结束周期的报表并投递，用于检查报表和通道配置，不影响定时生成。需要管理员权限",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "报表"
+                ],
+                "summary": "立即生成报表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "定时报表ID",
+                        "name": "schedule_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "生成结果",
+                        "schema": {
+                            "$ref": "#/definitions/dao.RunReportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "报表未启用",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "定时报表不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "报表生成失败",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/report/{report_id}/download": {
+            "get": {
+                "description": "下载已生成报表的 HTML 或 PDF 文件，需要管理员权限",
+                "produces": [
+                    "text/html",
+                    "application/pdf"
+                ],
+                "tags": [
+                    "报表"
+                ],
+                "summary": "下载报表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "报表ID",
+                        "name": "report_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "报表文件",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "报表不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/rollout/{rollout_id}": {
+            "get": {
+                "description": "获取升级及其进度",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "版本升级"
+                ],
+                "summary": "获取升级",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "升级ID",
+                        "name": "rollout_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.RolloutSpec"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "升级不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/rollout/{rollout_id}/cancel": {
+            "put": {
+                "description": "取消进行中或已暂停的升级",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "版本升级"
+                ],
+                "summary": "取消升级",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "升级ID",
+                        "name": "rollout_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "取消成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.RolloutSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "升级已结束",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "升级不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/rollout/{rollout_id}/devices": {
+            "get": {
+                "description": "列出已开始升级的设备及其状态和失败原因",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "版本升级"
+                ],
+                "summary": "列出升级中各设备的状态",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "升级ID",
+                        "name": "rollout_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "列出成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListDeviceUpgradesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "升级不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/rollout/{rollout_id}/halt": {
+            "put": {
+                "description": "暂停进行中的升级，已在升级的设备不受影响",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "版本升级"
+                ],
+                "summary": "暂停升级",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "升级ID",
+                        "name": "rollout_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "暂停成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.RolloutSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "升级不在进行中",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "升级不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/rollout/{rollout_id}/resume": {
+            "put": {
+                "description": "恢复已暂停的升级，已失败的设备不会重试",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "版本升级"
+                ],
+                "summary": "恢复升级",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "升级ID",
+                        "name": "rollout_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "恢复成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.RolloutSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "升级未暂停或设备组已有进行中的升级",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "升级不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/password": {
+            "put": {
+                "description": "修改当前用户的密码，需要验证当前密码",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户管理"
+                ],
+                "summary": "修改密码",
+                "parameters": [
+                    {
+                        "description": "新旧密码",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "请求参数错误或当前密码错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/profile": {
+            "get": {
+                "description": "获取用户信息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户管理"
+                ],
+                "summary": "获取用户信息",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.UserSpec"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "更新当前用户的偏好设置，接口返回的时间均为 UTC，显示时区只影响前端展示",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户管理"
+                ],
+                "summary": "更新用户信息",
+                "parameters": [
+                    {
+                        "description": "用户偏好",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.UserSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/session/{session_id}": {
+            "delete": {
+                "description": "注销当前用户的登录会话，其刷新令牌立即失效，已签发的登录凭证在过期前仍然有效",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户管理"
+                ],
+                "summary": "注销登录会话",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "会话ID",
+                        "name": "session_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "会话不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/sessions": {
+            "get": {
+                "description": "列出当前用户未过期的登录会话",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户管理"
+                ],
+                "summary": "列出登录会话",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListSessionsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/token/{token_id}": {
+            "delete": {
+                "description": "吊销当前用户的 API 令牌，吊销后立即失效",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户管理"
+                ],
+                "summary": "吊销 API 令牌",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "令牌ID",
+                        "name": "token_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "令牌不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/tokens": {
+            "get": {
+                "description": "列出当前用户创建的 API 令牌，不含令牌本身",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户管理"
+                ],
+                "summary": "列出 API 令牌",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListApiTokensResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "为当前用户创建限定授权范围和过期时间的 API 令牌，令牌只在创建时返回一次",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户管理"
+                ],
+                "summary": "创建 API 令牌",
+                "parameters": [
+                    {
+                        "description": "令牌名称、授权范围和过期时间",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateApiTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateApiTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/totp": {
+            "post": {
+                "description": "为当前用户生成新的两步验证密钥，用验证器应用扫描二维码后调用启用接口确认",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户管理"
+                ],
+                "summary": "开始启用两步验证",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.TotpSetupResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "已启用两步验证",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/totp/disable": {
+            "post": {
+                "description": "验证当前密码和两步验证码（或恢复码）后停用两步验证",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户管理"
+                ],
+                "summary": "停用两步验证",
+                "parameters": [
+                    {
+                        "description": "密码和验证码",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.DisableTotpRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "密码或验证码错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "管理员必须启用两步验证",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/totp/enable": {
+            "post": {
+                "description": "用验证器应用生成的验证码确认密钥并启用两步验证，返回一次性的恢复码",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户管理"
+                ],
+                "summary": "启用两步验证",
+                "parameters": [
+                    {
+                        "description": "验证码",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.TotpCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.TotpRecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "验证码错误或未开始启用",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "已启用两步验证",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/settings/totp/recovery-codes": {
+            "post": {
+                "description": "生成新的恢复码，原有的恢复码全部失效",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "用户管理"
+                ],
+                "summary": "重新生成恢复码",
+                "parameters": [
+                    {
+                        "description": "验证码",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.TotpCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.TotpRecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "验证码错误或未启用两步验证",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/stats/alerts": {
+            "get": {
+                "description": "统计时间范围内产生的告警的处理状态、平均确认和解决耗时，以及在 SLA 内确认的告警数",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "获取告警处理统计",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "开始时间(RFC3339 或 Unix 时间戳)，默认 24 小时前",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间(RFC3339 或 Unix 时间戳)，默认当前时间",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "任务ID",
+                        "name": "jobId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 300,
+                        "description": "确认告警的 SLA，单位秒",
+                        "name": "slaSeconds",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.AlertStatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/stats/alerts/trend": {
+            "get": {
+                "description": "从InfluxDB查询各任务各级别的告警数量趋势及所有任务的合计，统计产生告警的消息，去重合并的告警按每次出现计数",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "消息"
+                ],
+                "summary": "获取告警趋势",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "开始时间(RFC3339 或 Unix 时间戳)",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间(RFC3339 或 Unix 时间戳)",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "5m",
+                        "description": "聚合窗口，如1m、5m、15m",
+                        "name": "window",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "任务ID",
+                        "name": "jobId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "告警级别：low、medium、high、critical",
+                        "name": "severity",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.AlertTrendResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/workflow": {
+            "get": {
+                "description": "列出工作流",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "工作流"
+                ],
+                "summary": "列出工作流",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "分页起始位置",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "分页每页数量",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "列出成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListWorkflowResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "创建工作流",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "工作流"
+                ],
+                "summary": "创建工作流",
+                "parameters": [
+                    {
+                        "description": "创建工作流请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateWorkflowRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "创建成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateWorkflowResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/workflow/export": {
+            "get": {
+                "description": "导出工作流配置，不包含API Key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "工作流"
+                ],
+                "summary": "导出工作流",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "工作流ID，逗号分隔，为空时导出全部",
+                        "name": "ids",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "导出成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.WorkflowBundle"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/workflow/import": {
+            "post": {
+                "description": "导入导出的工作流配置，新建的工作流需要重新填写API Key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "工作流"
+                ],
+                "summary": "导入工作流",
+                "parameters": [
+                    {
+                        "description": "导入工作流请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.ImportWorkflowRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "导入成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ImportWorkflowResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "工作流属于其他组织",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/workflow/{workflow_id}": {
+            "get": {
+                "description": "获取工作流",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "工作流"
+                ],
+                "summary": "获取工作流",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "工作流ID",
+                        "name": "workflow_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.WorkflowSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "工作流不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "更新工作流",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "工作流"
+                ],
+                "summary": "更新工作流",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "工作流ID",
+                        "name": "workflow_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "更新工作流请求",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.UpdateWorkflowRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "工作流不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除工作流",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "工作流"
+                ],
+                "summary": "删除工作流",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "工作流ID",
+                        "name": "workflow_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "工作流不存在",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/ws/alerts": {
+            "get": {
+                "description": "通过WebSocket推送新产生的告警消息，可按任务、摄像头或最低告警级别过滤",
+                "tags": [
+                    "消息"
+                ],
+                "summary": "推送新告警",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "任务ID",
+                        "name": "jobId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "摄像头ID",
+                        "name": "cameraId",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "low",
+                            "medium",
+                            "high",
+                            "critical"
+                        ],
+                        "type": "string",
+                        "description": "最低告警级别",
+                        "name": "minSeverity",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "告警事件",
+                        "schema": {
+                            "$ref": "#/definitions/dao.AlertEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/conversation": {
+            "get": {
+                "description": "获取所有对话，支持分页",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "对话"
+                ],
+                "summary": "获取对话列表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "分页起始索引",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "分页每页数量",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListConversationsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "创建一个新的对话，标题为请求体中的title字段",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "对话"
+                ],
+                "summary": "创建对话",
+                "parameters": [
+                    {
+                        "description": "创建对话请求体",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateConversationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.CreateConversationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/conversation/{uuid}": {
+            "get": {
+                "description": "获取指定UUID的对话",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "对话"
+                ],
+                "summary": "获取对话",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "对话UUID",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ConversationSpec"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除指定UUID的对话",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "对话"
+                ],
+                "summary": "删除对话",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "对话UUID",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: conversation deleted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/conversation/{uuid}/chat": {
+            "post": {
+                "description": "发送聊天消息并获取回复",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "对话"
+                ],
+                "summary": "聊天",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "对话ID",
+                        "name": "conversationId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "聊天请求",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dao.ChatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "聊天回复",
+                        "headers": {
+                            "X-Stream-Id": {
+                                "type": "string",
+                                "description": "流ID，连接中断后用于续传回复"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/conversation/{uuid}/chat/{stream_id}/resume": {
+            "get": {
+                "description": "连接中断后从第 offset 个事件起继续读取聊天回复，流ID由聊天接口的 X-Stream-Id 响应头返回，回复完成后 10 分钟内可续传",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "对话"
+                ],
+                "summary": "续传聊天回复",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "对话uuid",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "流ID",
+                        "name": "stream_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "已收到的事件数",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "聊天回复"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "流不存在或已过期",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/conversation/{uuid}/message": {
+            "get": {
+                "description": "根据conversationId分页获取聊天消息列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "对话"
+                ],
+                "summary": "获取聊天消息列表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "对话ID",
+                        "name": "conversationId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "起始位置",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "每页数量",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/dao.ListChatMessagesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/conversation/{uuid}/title": {
+            "post": {
+                "description": "根据聊天历史生成聊天标题",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "对话"
+                ],
+                "summary": "生成聊天标题",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "对话ID",
+                        "name": "conversationId",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "聊天标题",
+                        "schema": {
+                            "$ref": "#/definitions/dao.GenChatTitleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/server.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dao.AccessTokenSpec": {
+            "type": "object",
+            "required": [
+                "accessToken"
+            ],
+            "properties": {
+                "accessToken": {
+                    "type": "string"
+                },
+                "bindCount": {
+                    "description": "已注册的设备数量",
+                    "type": "integer"
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "deviceUuid": {
+                    "description": "最近一次使用该令牌注册的设备",
+                    "type": "string"
+                },
+                "expireTime": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastBindTime": {
+                    "description": "最近一次注册设备的时间，未使用过时为空",
+                    "type": "string"
+                },
+                "maxBinds": {
+                    "description": "可注册的设备数量上限",
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.AgentProposalSpec": {
+            "type": "object",
+            "properties": {
+                "createTime": {
+                    "type": "string"
+                },
+                "expireTime": {
+                    "type": "string"
+                },
+                "kind": {
+                    "$ref": "#/definitions/model.AgentProposalKind"
+                },
+                "request": {
+                    "description": "批准后执行的请求，工作流的key已隐藏",
+                    "type": "object"
+                },
+                "summary": {
+                    "type": "string"
+                },
+                "targetId": {
+                    "description": "更新的任务或工作流ID，创建时为0",
+                    "type": "integer"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.AgentThoughtSpec": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "observation": {
+                    "type": "string"
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "thought": {
+                    "type": "string"
+                },
+                "toolCall": {
+                    "$ref": "#/definitions/dao.ToolCallSpec"
+                }
+            }
+        },
+        "dao.AlertActivitySpec": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "操作：ack、assign、comment、resolve",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.AlertAction"
+                        }
+                    ]
+                },
+                "assigneeId": {
+                    "description": "指派的用户ID，仅 assign 操作，0 表示取消指派",
+                    "type": "integer"
+                },
+                "content": {
+                    "description": "评论内容或解决说明",
+                    "type": "string"
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "userId": {
+                    "type": "integer"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.AlertEvent": {
+            "type": "object",
+            "properties": {
+                "alertId": {
+                    "type": "integer"
+                },
+                "cameraId": {
+                    "type": "integer"
+                },
+                "cameraName": {
+                    "type": "string"
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "jobKind": {
+                    "$ref": "#/definitions/model.JobKind"
+                },
+                "jobUuid": {
+                    "type": "string"
+                },
+                "message": {
+                    "$ref": "#/definitions/dao.MessageSpec"
+                },
+                "severity": {
+                    "description": "告警级别：low、medium、high、critical",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.AlertSeverity"
+                        }
+                    ]
+                }
+            }
+        },
+        "dao.AlertGalleryBucket": {
+            "type": "object",
+            "properties": {
+                "cameras": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.AlertGalleryCamera"
+                    }
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.AlertGalleryCamera": {
+            "type": "object",
+            "properties": {
+                "cameraId": {
+                    "type": "integer"
+                },
+                "cameraName": {
+                    "type": "string"
+                },
+                "count": {
+                    "description": "告警数量",
+                    "type": "integer"
+                },
+                "occurrences": {
+                    "description": "告警的发生次数，包含被去重合并的重复告警",
+                    "type": "integer"
+                },
+                "thumbnails": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.AlertThumbnailSpec"
+                    }
+                }
+            }
+        },
+        "dao.AlertGalleryResponse": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string"
+                },
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.AlertGalleryBucket"
+                    }
+                },
+                "end": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.AlertSpec": {
+            "type": "object",
+            "properties": {
+                "ackTime": {
+                    "description": "确认时间，为空表示未确认",
+                    "type": "string"
+                },
+                "ackUserId": {
+                    "description": "确认告警的用户ID",
+                    "type": "integer"
+                },
+                "activities": {
+                    "description": "处理记录，按时间先后排列",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.AlertActivitySpec"
+                    }
+                },
+                "alertId": {
+                    "type": "integer"
+                },
+                "assigneeId": {
+                    "description": "负责处理告警的用户ID，0 表示未指派",
+                    "type": "integer"
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "lastMessageId": {
+                    "type": "integer"
+                },
+                "lastOccurTime": {
+                    "description": "最近一次告警的时间和消息ID",
+                    "type": "string"
+                },
+                "occurrences": {
+                    "description": "去重窗口内合并的告警次数，含第一次",
+                    "type": "integer"
+                },
+                "resolveTime": {
+                    "description": "解决时间，为空表示未解决",
+                    "type": "string"
+                },
+                "resolveUserId": {
+                    "description": "解决告警的用户ID",
+                    "type": "integer"
+                },
+                "severity": {
+                    "$ref": "#/definitions/model.AlertSeverity"
+                },
+                "status": {
+                    "description": "处理状态：open、acknowledged、resolved",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.AlertStatus"
+                        }
+                    ]
+                }
+            }
+        },
+        "dao.AlertStatsResponse": {
+            "type": "object",
+            "properties": {
+                "ackedWithinSla": {
+                    "description": "在 SLA 内确认的告警数",
+                    "type": "integer"
+                },
+                "acknowledged": {
+                    "description": "已确认未解决的告警数",
+                    "type": "integer"
+                },
+                "avgAckSeconds": {
+                    "description": "平均确认耗时，单位秒",
+                    "type": "number"
+                },
+                "avgResolveSeconds": {
+                    "description": "平均解决耗时，单位秒",
+                    "type": "number"
+                },
+                "end": {
+                    "type": "string"
+                },
+                "open": {
+                    "description": "未处理（未确认且未解决）的告警数",
+                    "type": "integer"
+                },
+                "resolved": {
+                    "description": "已解决的告警数",
+                    "type": "integer"
+                },
+                "slaSeconds": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.AlertThumbnailSpec": {
+            "type": "object",
+            "properties": {
+                "imageUrl": {
+                    "type": "string"
+                },
+                "messageId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.AlertTimeCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "jobId": {
+                    "description": "任务ID，任务已删除时为 0；合计趋势中为空",
+                    "type": "integer"
+                },
+                "jobUuid": {
+                    "type": "string"
+                },
+                "severity": {
+                    "$ref": "#/definitions/model.AlertSeverity"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.AlertTrendResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "各任务各级别的告警数量趋势",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.AlertTimeCount"
+                    }
+                },
+                "totals": {
+                    "description": "所有任务合计的各级别告警数量趋势",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.AlertTimeCount"
+                    }
+                }
+            }
+        },
+        "dao.ApiTokenSpec": {
+            "type": "object",
+            "properties": {
+                "createdTime": {
+                    "type": "string"
+                },
+                "expireTime": {
+                    "description": "过期时间，为空时永不过期",
+                    "type": "string"
+                },
+                "hint": {
+                    "description": "令牌开头几位，用于区分令牌",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "令牌ID",
+                    "type": "integer"
+                },
+                "lastUsedIp": {
+                    "description": "最后使用的客户端IP",
+                    "type": "string"
+                },
+                "lastUsedTime": {
+                    "description": "最后使用时间，为空时从未使用",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "令牌名称",
+                    "type": "string"
+                },
+                "scopes": {
+                    "description": "授权范围，all 为全部权限，read 为只读，jobs、cameras、devices、workflows、messages 为对应资源的全部权限",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dao.ApiUsageSpec": {
+            "type": "object",
+            "properties": {
+                "avgLatency": {
+                    "description": "平均和最大耗时，单位毫秒",
+                    "type": "number"
+                },
+                "clientErrors": {
+                    "description": "4xx 和 5xx 响应的数量",
+                    "type": "integer"
+                },
+                "consumer": {
+                    "type": "string"
+                },
+                "consumerKind": {
+                    "$ref": "#/definitions/model.ApiConsumerKind"
+                },
+                "endpoint": {
+                    "description": "接口路由，如 /api/v1/job/:job_id",
+                    "type": "string"
+                },
+                "errorRate": {
+                    "description": "4xx 和 5xx 响应的比例",
+                    "type": "number"
+                },
+                "maxLatency": {
+                    "type": "number"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "serverErrors": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ApproveAgentProposalResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "创建或更新的任务或工作流ID",
+                    "type": "integer"
+                },
+                "kind": {
+                    "$ref": "#/definitions/model.AgentProposalKind"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.AssignAlertRequest": {
+            "type": "object",
+            "properties": {
+                "assigneeId": {
+                    "description": "负责处理告警的用户ID，0 表示取消指派",
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "dao.CalibrationPoint": {
+            "type": "object",
+            "properties": {
+                "pixel": {
+                    "description": "像素坐标 [x, y]",
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "world": {
+                    "description": "世界坐标 [x, y]",
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                }
+            }
+        },
+        "dao.CameraCalibration": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "标定点的平均重投影误差，单位同 Unit",
+                    "type": "number"
+                },
+                "matrix": {
+                    "description": "像素到世界坐标的单应矩阵，3x3 行优先",
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.CalibrationPoint"
+                    }
+                },
+                "unit": {
+                    "description": "世界坐标单位，如 m",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CameraOccupancyResponse": {
+            "type": "object",
+            "properties": {
+                "zones": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.ZoneOccupancyTrend"
+                    }
+                }
+            }
+        },
+        "dao.CameraProbeAudio": {
+            "type": "object",
+            "properties": {
+                "channels": {
+                    "type": "integer"
+                },
+                "codec": {
+                    "type": "string"
+                },
+                "sampleRate": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CameraProbeResult": {
+            "type": "object",
+            "required": [
+                "taskUuid"
+            ],
+            "properties": {
+                "audio": {
+                    "$ref": "#/definitions/dao.CameraProbeAudio"
+                },
+                "bitrate": {
+                    "description": "码率，单位 bit/s",
+                    "type": "integer"
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "duration": {
+                    "description": "探测耗时，单位毫秒",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "无法连通时的错误信息",
+                    "type": "string"
+                },
+                "format": {
+                    "description": "封装格式，如 rtsp",
+                    "type": "string"
+                },
+                "modelError": {
+                    "description": "模型未就绪的原因",
+                    "type": "string"
+                },
+                "modelReady": {
+                    "description": "任务指定了模型时，模型是否已就绪",
+                    "type": "boolean"
+                },
+                "reachable": {
+                    "description": "摄像头是否可以连通并读取到码流",
+                    "type": "boolean"
+                },
+                "taskUuid": {
+                    "type": "string"
+                },
+                "video": {
+                    "$ref": "#/definitions/dao.CameraProbeVideo"
+                }
+            }
+        },
+        "dao.CameraProbeTask": {
+            "type": "object",
+            "properties": {
+                "expireTime": {
+                    "type": "string"
+                },
+                "modelName": {
+                    "description": "不为空时同时检查设备的 Triton 是否已加载该模型",
+                    "type": "string"
+                },
+                "pullAddr": {
+                    "type": "string"
+                },
+                "taskUuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CameraProbeVideo": {
+            "type": "object",
+            "properties": {
+                "codec": {
+                    "description": "视频编码，如 h264、hevc",
+                    "type": "string"
+                },
+                "fps": {
+                    "description": "帧率",
+                    "type": "number"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "pixFmt": {
+                    "type": "string"
+                },
+                "profile": {
+                    "type": "string"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CameraSnapshotResponse": {
+            "type": "object",
+            "properties": {
+                "createTime": {
+                    "type": "string"
+                },
+                "expireTime": {
+                    "description": "预签名地址的过期时间",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "快照的预签名下载地址",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CameraSpec": {
+            "type": "object",
+            "required": [
+                "createTime",
+                "ip",
+                "name",
+                "protocol",
+                "updateTime",
+                "uuid"
+            ],
+            "properties": {
+                "bindDevice": {
+                    "$ref": "#/definitions/dao.DeviceSpec"
+                },
+                "calibration": {
+                    "description": "地面标定，未标定时为空",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.CameraCalibration"
+                        }
+                    ]
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "onvifPort": {
+                    "description": "ONVIF 设备服务端口，0 表示不支持 ONVIF",
+                    "type": "integer"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "previewAudio": {
+                    "description": "预览时是否包含音频",
+                    "type": "boolean"
+                },
+                "protocol": {
+                    "$ref": "#/definitions/model.CameraProtocol"
+                },
+                "tags": {
+                    "description": "标签，如所在楼栋、楼层",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updateTime": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CanaryRun": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "latency": {
+                    "description": "端到端耗时，单位毫秒，未完成时为 0",
+                    "type": "integer"
+                },
+                "success": {
+                    "description": "测试消息是否在时限内经过消费者和工作流处理完成",
+                    "type": "boolean"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CanaryStatusResponse": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "runs": {
+                    "description": "最近各次自检，按时间倒序",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.CanaryRun"
+                    }
+                },
+                "successRate": {
+                    "description": "最近各次自检的成功率",
+                    "type": "number"
+                }
+            }
+        },
+        "dao.ChangePasswordRequest": {
+            "type": "object",
+            "required": [
+                "newPassword",
+                "oldPassword"
+            ],
+            "properties": {
+                "newPassword": {
+                    "description": "新密码，至少 8 位",
+                    "type": "string",
+                    "maxLength": 72,
+                    "minLength": 8
+                },
+                "oldPassword": {
+                    "description": "当前密码",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.ChatMessageSpec": {
+            "type": "object",
+            "properties": {
+                "agentThoughts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.AgentThoughtSpec"
+                    }
+                },
+                "answer": {
+                    "type": "string"
+                },
+                "conversationId": {
+                    "type": "integer"
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "query": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.ChatRequest": {
+            "type": "object",
+            "required": [
+                "query"
+            ],
+            "properties": {
+                "query": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CloneJobRequest": {
+            "type": "object",
+            "properties": {
+                "cameraId": {
+                    "description": "目标摄像头",
+                    "type": "integer"
+                },
+                "deviceGroupId": {
+                    "description": "目标设备组",
+                    "type": "integer"
+                },
+                "deviceId": {
+                    "description": "目标设备，设置非零的设备或设备组会清除另一个",
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CommentAlertRequest": {
+            "type": "object",
+            "required": [
+                "content"
+            ],
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "maxLength": 4096
+                }
+            }
+        },
+        "dao.Condition": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.ConfigReloadSpec": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "description": "已生效的配置项，如 llm、smtp、messageRetention、rateLimit",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "description": "加载失败的原因，失败时保留原配置",
+                    "type": "string"
+                },
+                "restartRequired": {
+                    "description": "有变化但重启后才生效的配置项",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "time": {
+                    "description": "重新加载的时间",
+                    "type": "string"
+                },
+                "trigger": {
+                    "description": "触发方式：watch 为配置文件变化，manual 为管理员触发",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.ConfigStatusResponse": {
+            "type": "object",
+            "properties": {
+                "lastReload": {
+                    "description": "最近一次重新加载的结果，未重新加载过时为空",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.ConfigReloadSpec"
+                        }
+                    ]
+                },
+                "reloadable": {
+                    "description": "无需重启即可生效的配置项",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dao.ConversationSpec": {
+            "type": "object",
+            "required": [
+                "createTime",
+                "uuid"
+            ],
+            "properties": {
+                "createTime": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CreateAccessTokenRequest": {
+            "type": "object",
+            "properties": {
+                "expireTime": {
+                    "description": "过期时间，RFC3339 格式或 Unix 时间戳（秒或毫秒），默认 24 小时后",
+                    "type": "string"
+                },
+                "maxBinds": {
+                    "description": "可注册的设备数量上限，默认 1，用于批量部署时一个令牌注册多台设备",
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 1
+                }
+            }
+        },
+        "dao.CreateAccessTokenResponse": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CreateApiTokenRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expireTime": {
+                    "description": "过期时间，RFC3339 格式或 Unix 时间戳（秒或毫秒），为空时永不过期",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "令牌名称",
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "scopes": {
+                    "description": "授权范围，可选 all、read、jobs、cameras、devices、workflows、messages",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dao.CreateApiTokenResponse": {
+            "type": "object",
+            "properties": {
+                "createdTime": {
+                    "type": "string"
+                },
+                "expireTime": {
+                    "description": "过期时间，为空时永不过期",
+                    "type": "string"
+                },
+                "hint": {
+                    "description": "令牌开头几位，用于区分令牌",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "令牌ID",
+                    "type": "integer"
+                },
+                "lastUsedIp": {
+                    "description": "最后使用的客户端IP",
+                    "type": "string"
+                },
+                "lastUsedTime": {
+                    "description": "最后使用时间，为空时从未使用",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "令牌名称",
+                    "type": "string"
+                },
+                "scopes": {
+                    "description": "授权范围，all 为全部权限，read 为只读，jobs、cameras、devices、workflows、messages 为对应资源的全部权限",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token": {
+                    "description": "令牌，只返回这一次",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CreateCameraRequest": {
+            "type": "object",
+            "required": [
+                "ip",
+                "name",
+                "protocol"
+            ],
+            "properties": {
+                "bindDeviceId": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "onvifPort": {
+                    "description": "ONVIF 设备服务端口，0 表示不支持 ONVIF",
+                    "type": "integer",
+                    "maximum": 65535,
+                    "minimum": 0
+                },
+                "password": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "previewAudio": {
+                    "description": "预览时是否包含音频",
+                    "type": "boolean"
+                },
+                "protocol": {
+                    "$ref": "#/definitions/model.CameraProtocol"
+                },
+                "tags": {
+                    "description": "标签",
+                    "type": "array",
+                    "maxItems": 32,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CreateCameraResponse": {
+            "type": "object",
+            "properties": {
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CreateConversationRequest": {
+            "type": "object",
+            "properties": {
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CreateConversationResponse": {
+            "type": "object",
+            "required": [
+                "uuid"
+            ],
+            "properties": {
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CreateDeviceGroupRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "channel": {
+                    "description": "发布通道，stable 或 beta，默认 stable",
+                    "enum": [
+                        "stable",
+                        "beta"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReleaseChannel"
+                        }
+                    ]
+                },
+                "description": {
+                    "description": "描述",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "deviceIds": {
+                    "description": "成员设备ID",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "name": {
+                    "description": "设备组名称",
+                    "type": "string",
+                    "maxLength": 96
+                },
+                "upgradeWindow": {
+                    "description": "升级时段，为空表示任意时间",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.JobSchedule"
+                        }
+                    ]
+                }
+            }
+        },
+        "dao.CreateDeviceGroupResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CreateEscalationPolicyRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "steps"
+            ],
+            "properties": {
+                "cameraIds": {
+                    "description": "只升级这些摄像头的告警，为空表示全部",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "jobIds": {
+                    "description": "只升级这些任务的告警，为空表示全部",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "minSeverity": {
+                    "description": "只升级不低于该级别的告警，为空表示全部",
+                    "enum": [
+                        "low",
+                        "medium",
+                        "high",
+                        "critical"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.AlertSeverity"
+                        }
+                    ]
+                },
+                "name": {
+                    "description": "策略名称",
+                    "type": "string",
+                    "maxLength": 96
+                },
+                "steps": {
+                    "description": "升级步骤",
+                    "type": "array",
+                    "maxItems": 10,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/dao.EscalationStep"
+                    }
+                }
+            }
+        },
+        "dao.CreateEscalationPolicyResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CreateJobRequest": {
+            "type": "object",
+            "required": [
+                "cameraId",
+                "kind"
+            ],
+            "properties": {
+                "alertDedupWindow": {
+                    "description": "告警去重窗口，单位秒，窗口内相同标签的重复告警合并为一条并累计次数，0 表示不去重",
+                    "type": "integer",
+                    "maximum": 86400,
+                    "minimum": 0
+                },
+                "cameraId": {
+                    "type": "integer"
+                },
+                "detect": {
+                    "$ref": "#/definitions/dao.DetectOptions"
+                },
+                "deviceGroupId": {
+                    "description": "目标设备组，与 DeviceId 二选一",
+                    "type": "integer"
+                },
+                "deviceId": {
+                    "type": "integer"
+                },
+                "hooks": {
+                    "description": "设备发布消息前依次执行的后处理钩子",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.JobHook"
+                    }
+                },
+                "kind": {
+                    "$ref": "#/definitions/model.JobKind"
+                },
+                "maxFps": {
+                    "description": "每秒推理帧数上限，0 表示只受检测间隔限制",
+                    "type": "number",
+                    "minimum": 0
+                },
+                "plugin": {
+                    "description": "自定义任务类型的参数，原样传给设备上注册的执行器",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "priority": {
+                    "description": "调度优先级，设备达到并发上限时优先级高的任务先启动，推理算力不足时优先级低的任务先降帧",
+                    "type": "integer"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "resultFilter": {
+                    "$ref": "#/definitions/dao.FilterCondition"
+                },
+                "schedule": {
+                    "description": "布防计划，为空表示始终布防",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.JobSchedule"
+                        }
+                    ]
+                },
+                "severityRules": {
+                    "description": "告警级别规则，按顺序匹配，都不匹配时由分析结果的置信度决定",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.SeverityRule"
+                    }
+                },
+                "talkDown": {
+                    "description": "告警时的现场喊话",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.TalkDownOptions"
+                        }
+                    ]
+                },
+                "videoSegment": {
+                    "$ref": "#/definitions/dao.VideoSegmentOptions"
+                },
+                "workflowId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CreateJobResponse": {
+            "type": "object",
+            "properties": {
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CreateMessageRequest": {
+            "type": "object",
+            "required": [
+                "jobId",
+                "timestamp"
+            ],
+            "properties": {
+                "detectBoxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.DetectionBox"
+                    }
+                },
+                "imagePath": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "videoPath": {
+                    "type": "string"
+                },
+                "workflowResp": {
+                    "$ref": "#/definitions/dao.WorkflowResp"
+                }
+            }
+        },
+        "dao.CreateMessageResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CreateNotificationChannelRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "cameraIds": {
+                    "description": "只投递这些摄像头的告警，为空表示全部",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "escalationOnly": {
+                    "description": "只接收告警升级策略发送的告警",
+                    "type": "boolean"
+                },
+                "headers": {
+                    "description": "请求头，webhook 通道使用",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "jobIds": {
+                    "description": "只投递这些任务的告警，为空表示全部",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "jobRecipients": {
+                    "description": "按任务ID额外接收该任务告警的邮箱，email 通道使用",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "kind": {
+                    "description": "通道类型：webhook、email、dingtalk、wecom 或 slack，默认 webhook",
+                    "enum": [
+                        "webhook",
+                        "email",
+                        "dingtalk",
+                        "wecom",
+                        "slack"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.NotificationChannelKind"
+                        }
+                    ]
+                },
+                "maxRetries": {
+                    "description": "投递失败后的重试次数，默认 3",
+                    "type": "integer",
+                    "maximum": 10,
+                    "minimum": 0
+                },
+                "minSeverity": {
+                    "description": "只投递不低于该级别的告警，为空表示全部",
+                    "enum": [
+                        "low",
+                        "medium",
+                        "high",
+                        "critical"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.AlertSeverity"
+                        }
+                    ]
+                },
+                "name": {
+                    "description": "通道名称",
+                    "type": "string",
+                    "maxLength": 96
+                },
+                "recipients": {
+                    "description": "接收所有告警的邮箱，email 通道使用",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "钉钉机器人加签密钥",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "template": {
+                    "description": "模板，Go text/template 语法，数据为告警事件，.ImageUrl 为告警图片的预签名地址；webhook 通道为请求体，为空时发送告警事件的 JSON；email 通道为邮件正文，钉钉、企业微信和 slack 通道为 markdown 消息内容，为空时使用默认内容",
+                    "type": "string",
+                    "maxLength": 65535
+                },
+                "url": {
+                    "description": "告警投递地址，email 以外的通道必填",
+                    "type": "string",
+                    "maxLength": 1024
+                }
+            }
+        },
+        "dao.CreateNotificationChannelResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CreateOrganizationRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "description": "组织名称",
+                    "type": "string",
+                    "maxLength": 96
+                }
+            }
+        },
+        "dao.CreateReleaseRequest": {
+            "type": "object",
+            "required": [
+                "channel",
+                "sha256",
+                "url",
+                "version"
+            ],
+            "properties": {
+                "channel": {
+                    "description": "发布通道，stable 或 beta",
+                    "enum": [
+                        "stable",
+                        "beta"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReleaseChannel"
+                        }
+                    ]
+                },
+                "notes": {
+                    "description": "版本说明",
+                    "type": "string",
+                    "maxLength": 1024
+                },
+                "sha256": {
+                    "description": "程序的 sha256 校验值",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "程序下载地址",
+                    "type": "string",
+                    "maxLength": 1024
+                },
+                "version": {
+                    "description": "版本号，与设备上报的 lumina 版本一致，如 v0.4.0",
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "dao.CreateReleaseResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CreateReportScheduleRequest": {
+            "type": "object",
+            "required": [
+                "channelIds",
+                "name",
+                "period"
+            ],
+            "properties": {
+                "channelIds": {
+                    "description": "投递报表的通知通道ID",
+                    "type": "array",
+                    "maxItems": 20,
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "format": {
+                    "description": "报表格式：html 或 pdf，默认 html",
+                    "enum": [
+                        "html",
+                        "pdf"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReportFormat"
+                        }
+                    ]
+                },
+                "hour": {
+                    "description": "生成上一周期报表的时刻（0-23 点）",
+                    "type": "integer",
+                    "maximum": 23,
+                    "minimum": 0
+                },
+                "name": {
+                    "description": "报表名称",
+                    "type": "string",
+                    "maxLength": 96
+                },
+                "period": {
+                    "description": "报表周期：daily 每日，weekly 每周",
+                    "enum": [
+                        "daily",
+                        "weekly"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReportPeriod"
+                        }
+                    ]
+                },
+                "site": {
+                    "description": "报表覆盖的站点，即摄像头标签，为空表示全部摄像头",
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "timezone": {
+                    "description": "报表使用的时区，如 Asia/Shanghai，为空表示服务器本地时区",
+                    "type": "string"
+                },
+                "weekday": {
+                    "description": "周报在星期几生成，0 表示星期日",
+                    "type": "integer",
+                    "maximum": 6,
+                    "minimum": 0
+                }
+            }
+        },
+        "dao.CreateReportScheduleResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CreateRolloutRequest": {
+            "type": "object",
+            "properties": {
+                "failureThreshold": {
+                    "description": "失败率阈值，0 到 1，默认 0.2",
+                    "type": "number",
+                    "maximum": 1,
+                    "minimum": 0
+                },
+                "releaseId": {
+                    "description": "目标版本，为空时使用设备组通道的最新版本",
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CreateRolloutResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CreateUserRequest": {
+            "type": "object",
+            "required": [
+                "departmentId",
+                "nickname",
+                "password",
+                "username"
+            ],
+            "properties": {
+                "departmentId": {
+                    "description": "部门id",
+                    "type": "integer"
+                },
+                "isAdmin": {
+                    "description": "是否管理员",
+                    "type": "boolean"
+                },
+                "nickname": {
+                    "description": "昵称",
+                    "type": "string"
+                },
+                "orgId": {
+                    "description": "所属组织ID，默认为当前管理员的组织，仅默认组织的管理员可以指定其他组织",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "password": {
+                    "description": "密码",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "用户名",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.CreateUserResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "用户ID",
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.CreateWorkflowRequest": {
+            "type": "object",
+            "required": [
+                "endpoint",
+                "key",
+                "modelName",
+                "name"
+            ],
+            "properties": {
+                "endpoint": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "modelName": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "resultFilter": {
+                    "$ref": "#/definitions/dao.FilterCondition"
+                },
+                "timeout": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "dao.CreateWorkflowResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.DBPoolStats": {
+            "type": "object",
+            "properties": {
+                "idle": {
+                    "description": "空闲连接数",
+                    "type": "integer"
+                },
+                "inUse": {
+                    "description": "使用中的连接数",
+                    "type": "integer"
+                },
+                "maxIdleClosed": {
+                    "description": "因超出最大空闲连接数而关闭的连接数",
+                    "type": "integer"
+                },
+                "maxIdleTimeClosed": {
+                    "description": "因超出最大空闲时间而关闭的连接数",
+                    "type": "integer"
+                },
+                "maxLifetimeClosed": {
+                    "description": "因超出最大存活时间而关闭的连接数",
+                    "type": "integer"
+                },
+                "maxOpenConns": {
+                    "description": "连接池允许的最大连接数，0 表示不限制",
+                    "type": "integer"
+                },
+                "openConns": {
+                    "description": "当前打开的连接数",
+                    "type": "integer"
+                },
+                "saturation": {
+                    "description": "连接池饱和度，使用中的连接数占最大连接数的比例，不限制时为 0",
+                    "type": "number"
+                },
+                "waitCount": {
+                    "description": "累计等待连接的次数",
+                    "type": "integer"
+                },
+                "waitDuration": {
+                    "description": "累计等待连接的时长，毫秒",
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.DeletePushSubscriptionRequest": {
+            "type": "object",
+            "required": [
+                "endpoint"
+            ],
+            "properties": {
+                "endpoint": {
+                    "description": "浏览器推送服务地址",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.DetectOptions": {
+            "type": "object",
+            "required": [
+                "modelName"
+            ],
+            "properties": {
+                "confThreshold": {
+                    "type": "number"
+                },
+                "interval": {
+                    "type": "integer"
+                },
+                "iouThreshold": {
+                    "type": "number"
+                },
+                "labels": {
+                    "type": "string"
+                },
+                "modelName": {
+                    "type": "string"
+                },
+                "occupancy": {
+                    "description": "区域人数统计，基于跟踪的检测结果统计各区域的人数和进出人次",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.OccupancyOptions"
+                        }
+                    ]
+                },
+                "privacyLabels": {
+                    "description": "需要打码的类别，逗号分隔，如 face,license_plate",
+                    "type": "string"
+                },
+                "privacyMode": {
+                    "description": "打码方式，blur 或 pixelate，默认 blur",
+                    "enum": [
+                        "blur",
+                        "pixelate"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.PrivacyMode"
+                        }
+                    ]
+                },
+                "triggerCount": {
+                    "type": "integer"
+                },
+                "triggerInterval": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.DetectionBox": {
+            "type": "object",
+            "properties": {
+                "classId": {
+                    "type": "integer"
+                },
+                "confidence": {
+                    "type": "number"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "x1": {
+                    "type": "integer"
+                },
+                "x2": {
+                    "type": "integer"
+                },
+                "y1": {
+                    "type": "integer"
+                },
+                "y2": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.DeviceArtifact": {
+            "type": "object",
+            "required": [
+                "data",
+                "jobUuid"
+            ],
+            "properties": {
+                "contentType": {
+                    "type": "string",
+                    "enum": [
+                        "image/jpeg",
+                        "image/png"
+                    ]
+                },
+                "data": {
+                    "description": "文件内容，base64 编码，最大 64KB",
+                    "type": "array",
+                    "maxItems": 65536,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "jobUuid": {
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "类型：trigger_thumbnail 最新触发图片的缩略图，error_screenshot 最近一次错误时的画面",
+                    "enum": [
+                        "trigger_thumbnail",
+                        "error_screenshot"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ArtifactKind"
+                        }
+                    ]
+                },
+                "timestamp": {
+                    "description": "采集时间，Unix 纳秒时间戳",
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.DeviceGroupSpec": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "description": "发布通道，stable 或 beta",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReleaseChannel"
+                        }
+                    ]
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "devices": {
+                    "description": "成员设备，列表接口不返回",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.DeviceSpec"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updateTime": {
+                    "type": "string"
+                },
+                "upgradeWindow": {
+                    "description": "升级时段，为空表示任意时间",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.JobSchedule"
+                        }
+                    ]
+                }
+            }
+        },
+        "dao.DeviceInventorySpec": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "inventory": {
+                    "$ref": "#/definitions/model.DeviceInventory"
+                },
+                "lastPingTime": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.DeviceJobStats": {
+            "type": "object",
+            "properties": {
+                "alerts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.TimeCount"
+                    }
+                },
+                "deviceGroupId": {
+                    "description": "设备组任务的消息和告警包含组内所有设备",
+                    "type": "integer"
+                },
+                "frameRates": {
+                    "description": "该任务在本设备上的执行器帧率",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.FrameRatePoint"
+                    }
+                },
+                "jobId": {
+                    "type": "integer"
+                },
+                "jobUuid": {
+                    "type": "string"
+                },
+                "kind": {
+                    "$ref": "#/definitions/model.JobKind"
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.TimeCount"
+                    }
+                }
+            }
+        },
+        "dao.DeviceJobStatus": {
+            "type": "object",
+            "properties": {
+                "exectorStatus": {
+                    "$ref": "#/definitions/model.ExectorStatus"
+                },
+                "frameRate": {
+                    "description": "实际每秒推理帧数",
+                    "type": "number"
+                },
+                "frameRateLimit": {
+                    "description": "设备限流后的每秒推理帧数上限，0 表示未限流",
+                    "type": "number"
+                },
+                "healthReason": {
+                    "description": "执行器健康检查失败的原因，为空表示健康",
+                    "type": "string"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "restartCount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.DeviceSpec": {
+            "type": "object",
+            "properties": {
+                "diskUsage": {
+                    "$ref": "#/definitions/model.DiskUsage"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "inventory": {
+                    "description": "设备软件版本，设备未上报时为空",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DeviceInventory"
+                        }
+                    ]
+                },
+                "lastPingTime": {
+                    "type": "string"
+                },
+                "maxExecutors": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "registerTime": {
+                    "type": "string"
+                },
+                "rotateToken": {
+                    "description": "是否已要求设备轮换令牌且尚未完成",
+                    "type": "boolean"
+                },
+                "state": {
+                    "description": "根据最近上报时间得出的状态：online 在线，degraded 上报延迟，offline 离线或未注册",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DeviceState"
+                        }
+                    ]
+                },
+                "token": {
+                    "type": "string"
+                },
+                "tokenRotateTime": {
+                    "description": "最近一次完成令牌轮换的时间，未轮换过时为空",
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.DeviceStateEvent": {
+            "type": "object",
+            "properties": {
+                "deviceId": {
+                    "type": "integer"
+                },
+                "deviceName": {
+                    "type": "string"
+                },
+                "deviceUuid": {
+                    "type": "string"
+                },
+                "prevState": {
+                    "$ref": "#/definitions/model.DeviceState"
+                },
+                "state": {
+                    "$ref": "#/definitions/model.DeviceState"
+                }
+            }
+        },
+        "dao.DeviceStatsResponse": {
+            "type": "object",
+            "properties": {
+                "alerts": {
+                    "description": "所有任务合计的告警数量趋势",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.TimeCount"
+                    }
+                },
+                "jobs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.DeviceJobStats"
+                    }
+                },
+                "messages": {
+                    "description": "所有任务合计的消息数量趋势",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.TimeCount"
+                    }
+                }
+            }
+        },
+        "dao.DeviceStatus": {
+            "type": "object",
+            "properties": {
+                "artifacts": {
+                    "description": "对象存储不可用时随状态上报的小文件，服务端临时保存",
+                    "type": "array",
+                    "maxItems": 4,
+                    "items": {
+                        "$ref": "#/definitions/dao.DeviceArtifact"
+                    }
+                },
+                "diskUsage": {
+                    "$ref": "#/definitions/model.DiskUsage"
+                },
+                "inventory": {
+                    "description": "设备软件版本，设备启动后定期采集",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DeviceInventory"
+                        }
+                    ]
+                },
+                "jobStatus": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/dao.DeviceJobStatus"
+                    }
+                },
+                "signingKey": {
+                    "description": "设备签名公钥（ed25519），启用证据签名时上报，首次上报后固定，设备注销后才可更换",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "dao.DeviceStatusResponse": {
+            "type": "object",
+            "properties": {
+                "maxExecutors": {
+                    "description": "服务端为该设备设置的最大并发执行任务数，0 表示未设置",
+                    "type": "integer"
+                },
+                "rotateToken": {
+                    "description": "为 true 时设备应调用轮换令牌接口更换令牌",
+                    "type": "boolean"
+                }
+            }
+        },
+        "dao.DeviceUpgradeSpec": {
+            "type": "object",
+            "properties": {
+                "device": {
+                    "$ref": "#/definitions/dao.DeviceSpec"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "状态：upgrading、succeeded、failed",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DeviceUpgradeStatus"
+                        }
+                    ]
+                },
+                "updateTime": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.DisableTotpRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "password"
+            ],
+            "properties": {
+                "code": {
+                    "description": "两步验证码或恢复码",
+                    "type": "string"
+                },
+                "password": {
+                    "description": "当前密码",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.DiscoverCamerasRequest": {
+            "type": "object",
+            "required": [
+                "deviceId"
+            ],
+            "properties": {
+                "deviceId": {
+                    "description": "执行扫描的设备ID",
+                    "type": "integer"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "subnet": {
+                    "description": "扫描的网段，如 192.168.1.0/24，为空时只在设备所在局域网组播探测",
+                    "type": "string"
+                },
+                "timeout": {
+                    "description": "扫描时长，单位秒，默认 5",
+                    "type": "integer",
+                    "maximum": 20,
+                    "minimum": 1
+                },
+                "username": {
+                    "description": "摄像头用户名和密码，用于读取设备信息和码流地址",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.DiscoverCamerasResponse": {
+            "type": "object",
+            "properties": {
+                "cameras": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.DiscoveredCamera"
+                    }
+                }
+            }
+        },
+        "dao.DiscoveredCamera": {
+            "type": "object",
+            "properties": {
+                "camera": {
+                    "description": "用于创建摄像头的预填请求，无法获取码流地址时为空",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.CreateCameraRequest"
+                        }
+                    ]
+                },
+                "error": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "manufacturer": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "onvifPort": {
+                    "type": "integer"
+                },
+                "ptz": {
+                    "type": "boolean"
+                },
+                "streamUri": {
+                    "type": "string"
+                },
+                "xaddr": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.EscalationPolicySpec": {
+            "type": "object",
+            "properties": {
+                "cameraIds": {
+                    "description": "只升级这些摄像头的告警，为空表示全部",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "jobIds": {
+                    "description": "只升级这些任务的告警，为空表示全部",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "minSeverity": {
+                    "description": "只升级不低于该级别的告警，为空表示全部",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.AlertSeverity"
+                        }
+                    ]
+                },
+                "name": {
+                    "type": "string"
+                },
+                "steps": {
+                    "description": "升级步骤，告警确认后不再通知后续步骤",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.EscalationStep"
+                    }
+                },
+                "updateTime": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.EscalationStep": {
+            "type": "object",
+            "required": [
+                "channelId"
+            ],
+            "properties": {
+                "channelId": {
+                    "description": "通知的通道ID",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "delay": {
+                    "description": "告警产生后仍未确认多少分钟时通知，0 表示立即通知",
+                    "type": "integer",
+                    "maximum": 1440,
+                    "minimum": 0
+                }
+            }
+        },
+        "dao.Event": {
+            "type": "object",
+            "properties": {
+                "alert": {
+                    "$ref": "#/definitions/dao.AlertEvent"
+                },
+                "device": {
+                    "$ref": "#/definitions/dao.DeviceStateEvent"
+                },
+                "job": {
+                    "$ref": "#/definitions/dao.JobStatusEvent"
+                },
+                "kind": {
+                    "description": "类型：device_state 设备在线状态变化，job_status 任务状态变更，alert 新告警",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.EventKind"
+                        }
+                    ]
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.EventKind": {
+            "type": "string",
+            "enum": [
+                "device_state",
+                "job_status",
+                "alert"
+            ],
+            "x-enum-varnames": [
+                "EventKindDeviceState",
+                "EventKindJobStatus",
+                "EventKindAlert"
+            ]
+        },
+        "dao.FilterCondition": {
+            "type": "object",
+            "properties": {
+                "combineOp": {
+                    "type": "string"
+                },
+                "conditions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.Condition"
+                    }
+                }
+            }
+        },
+        "dao.FrameRatePoint": {
+            "type": "object",
+            "properties": {
+                "frameRate": {
+                    "description": "实际每秒推理帧数",
+                    "type": "number"
+                },
+                "frameRateLimit": {
+                    "description": "设备限流后的每秒推理帧数上限，0 表示未限流",
+                    "type": "number"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.GenChatTitleResponse": {
+            "type": "object",
+            "required": [
+                "title"
+            ],
+            "properties": {
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.GetUpgradeTaskResponse": {
+            "type": "object",
+            "properties": {
+                "task": {
+                    "description": "无需升级时为空",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.UpgradeTask"
+                        }
+                    ]
+                }
+            }
+        },
+        "dao.HealthEventSpec": {
+            "type": "object",
+            "properties": {
+                "createTime": {
+                    "type": "string"
+                },
+                "deviceId": {
+                    "description": "设备相关事件的设备ID",
+                    "type": "integer"
+                },
+                "endTime": {
+                    "description": "最近一个异常时段的结束时间",
+                    "type": "string"
+                },
+                "expected": {
+                    "description": "最近一个异常时段的通常值",
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "jobId": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "description": "类型：message_volume 消息量异常，alert_volume 告警量异常，canary 消息处理链路自检失败，device_offline 设备离线",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.HealthEventKind"
+                        }
+                    ]
+                },
+                "observed": {
+                    "description": "最近一个异常时段的实际值",
+                    "type": "number"
+                },
+                "resolveTime": {
+                    "type": "string"
+                },
+                "resolved": {
+                    "type": "boolean"
+                },
+                "score": {
+                    "description": "偏离程度，实际值与通常值相差的标准差倍数",
+                    "type": "number"
+                },
+                "startTime": {
+                    "description": "异常开始时间",
+                    "type": "string"
+                },
+                "summary": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.ImportWorkflowRequest": {
+            "type": "object",
+            "required": [
+                "bundle"
+            ],
+            "properties": {
+                "bundle": {
+                    "description": "导出的工作流数据",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.WorkflowBundle"
+                        }
+                    ]
+                },
+                "keys": {
+                    "description": "工作流UUID到API Key的映射，新建的工作流必须提供",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "overwrite": {
+                    "description": "是否覆盖已存在的同UUID工作流，未提供新Key时保留原Key",
+                    "type": "boolean"
+                }
+            }
+        },
+        "dao.ImportWorkflowResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "skipped": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dao.JobArtifactSpec": {
+            "type": "object",
+            "properties": {
+                "contentType": {
+                    "type": "string"
+                },
+                "deviceId": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "description": "类型：trigger_thumbnail 最新触发图片的缩略图，error_screenshot 最近一次错误时的画面",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ArtifactKind"
+                        }
+                    ]
+                },
+                "size": {
+                    "description": "文件大小，字节",
+                    "type": "integer"
+                },
+                "time": {
+                    "description": "设备采集的时间",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "文件内容的地址",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.JobCheck": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "description": "检查耗时，单位毫秒",
+                    "type": "integer"
+                },
+                "message": {
+                    "description": "未通过或未检查的原因",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "检查项：target 运行设备，camera 摄像头连通性，model 模型加载，workflow 工作流接口",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "结果：ok 通过，failed 未通过，skipped 不适用或无法检查",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.JobCheckStatus"
+                        }
+                    ]
+                }
+            }
+        },
+        "dao.JobCheckStatus": {
+            "type": "string",
+            "enum": [
+                "ok",
+                "failed",
+                "skipped"
+            ],
+            "x-enum-varnames": [
+                "JobCheckOk",
+                "JobCheckFailed",
+                "JobCheckSkipped"
+            ]
+        },
+        "dao.JobDeviceStatus": {
+            "type": "object",
+            "properties": {
+                "device": {
+                    "$ref": "#/definitions/dao.DeviceSpec"
+                },
+                "frameRate": {
+                    "description": "实际每秒推理帧数",
+                    "type": "number"
+                },
+                "frameRateLimit": {
+                    "description": "设备限流后的每秒推理帧数上限，0 表示未限流",
+                    "type": "number"
+                },
+                "healthReason": {
+                    "type": "string"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "restartCount": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updateTime": {
+                    "description": "最近一次上报时间",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.JobHook": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "description": "钩子名称，如 static_metadata、file_metadata、homography",
+                    "type": "string"
+                },
+                "params": {
+                    "description": "钩子参数",
+                    "type": "object",
+                    "additionalProperties": {}
+                }
+            }
+        },
+        "dao.JobPrecisionResponse": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string"
+                },
+                "end": {
+                    "type": "string"
+                },
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.PrecisionPoint"
+                    }
+                },
+                "start": {
+                    "type": "string"
+                },
+                "total": {
+                    "description": "整个时间范围的准确率",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.PrecisionPoint"
+                        }
+                    ]
+                }
+            }
+        },
+        "dao.JobSchedule": {
+            "type": "object",
+            "properties": {
+                "timezone": {
+                    "description": "时区，如 Asia/Shanghai，为空时使用设备本地时区",
+                    "type": "string"
+                },
+                "windows": {
+                    "description": "布防时段，为空表示始终布防",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.ScheduleWindow"
+                    }
+                }
+            }
+        },
+        "dao.JobSpec": {
+            "type": "object",
+            "required": [
+                "camera",
+                "createTime",
+                "enabled",
+                "kind",
+                "status",
+                "updateTime",
+                "uuid"
+            ],
+            "properties": {
+                "alertDedupWindow": {
+                    "description": "告警去重窗口，单位秒，窗口内相同标签的重复告警合并为一条并累计次数，0 表示不去重",
+                    "type": "integer"
+                },
+                "camera": {
+                    "$ref": "#/definitions/dao.CameraSpec"
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "detect": {
+                    "$ref": "#/definitions/dao.DetectOptions"
+                },
+                "device": {
+                    "$ref": "#/definitions/dao.DeviceSpec"
+                },
+                "deviceGroup": {
+                    "description": "目标设备组，任务在组内所有设备上运行",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.DeviceGroupSpec"
+                        }
+                    ]
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "frameRate": {
+                    "description": "实际每秒推理帧数，设备组任务见各设备的状态",
+                    "type": "number"
+                },
+                "frameRateLimit": {
+                    "description": "设备限流后的每秒推理帧数上限，0 表示未限流",
+                    "type": "number"
+                },
+                "healthReason": {
+                    "type": "string"
+                },
+                "hooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.JobHook"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "$ref": "#/definitions/model.JobKind"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "maxFps": {
+                    "description": "每秒推理帧数上限，0 表示只受检测间隔限制",
+                    "type": "number"
+                },
+                "paused": {
+                    "type": "boolean"
+                },
+                "plugin": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "restartCount": {
+                    "type": "integer"
+                },
+                "resultFilter": {
+                    "$ref": "#/definitions/dao.FilterCondition"
+                },
+                "schedule": {
+                    "description": "布防计划，为空表示始终布防",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.JobSchedule"
+                        }
+                    ]
+                },
+                "severityRules": {
+                    "description": "告警级别规则，按顺序匹配，都不匹配时由分析结果的置信度决定",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.SeverityRule"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "talkDown": {
+                    "$ref": "#/definitions/dao.TalkDownOptions"
+                },
+                "updateTime": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                },
+                "videoSegment": {
+                    "$ref": "#/definitions/dao.VideoSegmentOptions"
+                },
+                "workflow": {
+                    "$ref": "#/definitions/dao.WorkflowSpec"
+                }
+            }
+        },
+        "dao.JobStatsResponse": {
+            "type": "object",
+            "properties": {
+                "alerts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.AlertTimeCount"
+                    }
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.LabelTimeCount"
+                    }
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.TimeCount"
+                    }
+                }
+            }
+        },
+        "dao.JobStatusEvent": {
+            "type": "object",
+            "properties": {
+                "jobId": {
+                    "type": "integer"
+                },
+                "jobUuid": {
+                    "type": "string"
+                },
+                "transition": {
+                    "$ref": "#/definitions/dao.JobStatusHistorySpec"
+                }
+            }
+        },
+        "dao.JobStatusHistorySpec": {
+            "type": "object",
+            "properties": {
+                "createTime": {
+                    "type": "string"
+                },
+                "deviceId": {
+                    "description": "上报的设备",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "prevStatus": {
+                    "description": "设备上报时变更前的执行器状态",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "变更时上报的错误或健康原因",
+                    "type": "string"
+                },
+                "source": {
+                    "description": "来源：device 设备上报的执行器状态变化，server 通过接口进行的操作",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.JobStatusSource"
+                        }
+                    ]
+                },
+                "status": {
+                    "description": "设备上报时为变更后的执行器状态，如 running、failed；接口操作时为操作，如 create、start、stop、pause、resume",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "进行操作的用户",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.LabelTimeCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.ListAccessTokenResponse": {
+            "type": "object",
+            "properties": {
+                "accessTokens": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.AccessTokenSpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListApiTokensResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "令牌列表",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.ApiTokenSpec"
+                    }
+                }
+            }
+        },
+        "dao.ListApiUsageResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.ApiUsageSpec"
+                    }
+                }
+            }
+        },
+        "dao.ListCameraProbeTasksResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.CameraProbeTask"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListCameraTagsResponse": {
+            "type": "object",
+            "properties": {
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dao.ListCamerasResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.CameraSpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListChatMessagesResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.ChatMessageSpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListConversationsResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.ConversationSpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListDeviceGroupsResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.DeviceGroupSpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListDeviceInventoryResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.DeviceInventorySpec"
+                    }
+                },
+                "luminaVersions": {
+                    "description": "符合条件的设备按 lumina 版本计数，未上报版本的设备计入空版本",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer",
+                        "format": "int64"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListDeviceResponse": {
+            "type": "object",
+            "properties": {
+                "devices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.DeviceSpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListDeviceUpgradesResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.DeviceUpgradeSpec"
+                    }
+                }
+            }
+        },
+        "dao.ListEscalationPoliciesResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.EscalationPolicySpec"
+                    }
+                }
+            }
+        },
+        "dao.ListHealthEventsResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.HealthEventSpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListJobArtifactsResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.JobArtifactSpec"
+                    }
+                }
+            }
+        },
+        "dao.ListJobDeviceStatusResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.JobDeviceStatus"
+                    }
+                }
+            }
+        },
+        "dao.ListJobStatusHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.JobStatusHistorySpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListJobsResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.JobSpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListMessagesResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.MessageSpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListNotificationChannelsResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.NotificationChannelSpec"
+                    }
+                }
+            }
+        },
+        "dao.ListOnvifDiscoverTasksResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.OnvifDiscoverTask"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListOrganizationsResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "组织列表",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.OrganizationSpec"
+                    }
+                }
+            }
+        },
+        "dao.ListPreviewTasksResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.PreviewTask"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListPtzPresetsResponse": {
+            "type": "object",
+            "properties": {
+                "presets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.PtzPreset"
+                    }
+                }
+            }
+        },
+        "dao.ListReleasesResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.ReleaseSpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListReportSchedulesResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.ReportScheduleSpec"
+                    }
+                }
+            }
+        },
+        "dao.ListReportsResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.ReportSpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListRolloutsResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.RolloutSpec"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListSessionsResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "会话列表",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.SessionSpec"
+                    }
+                }
+            }
+        },
+        "dao.ListSnapshotTasksResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.SnapshotTask"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListTalkDownTasksResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.TalkDownTask"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListTestDetectTasksResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.TestDetectTask"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListUsersResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "用户列表",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.UserSpec"
+                    }
+                },
+                "total": {
+                    "description": "用户总数",
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.ListWorkflowResponse": {
+            "type": "object",
+            "properties": {
+                "total": {
+                    "type": "integer"
+                },
+                "workflows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.WorkflowSpec"
+                    }
+                }
+            }
+        },
+        "dao.LoginRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "description": "密码",
+                    "type": "string"
+                },
+                "totpCode": {
+                    "description": "两步验证码或恢复码，启用了两步验证的用户必填",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "用户名",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "expiresIn": {
+                    "description": "登录凭证的有效秒数",
+                    "type": "integer"
+                },
+                "refreshToken": {
+                    "description": "刷新令牌，每次刷新后作废并返回新的",
+                    "type": "string"
+                },
+                "token": {
+                    "description": "登录凭证，短期有效，过期后用刷新令牌换取新的",
+                    "type": "string"
+                },
+                "totpEnrollmentRequired": {
+                    "description": "管理员须先启用两步验证才能访问管理接口",
+                    "type": "boolean"
+                },
+                "user": {
+                    "$ref": "#/definitions/dao.UserSpec"
+                }
+            }
+        },
+        "dao.MediaVerification": {
+            "type": "object",
+            "properties": {
+                "actualSha256": {
+                    "description": "当前文件的 SHA-256，文件无法读取时为空",
+                    "type": "string"
+                },
+                "error": {
+                    "description": "文件无法读取的原因",
+                    "type": "string"
+                },
+                "expectedSha256": {
+                    "description": "签名时的 SHA-256",
+                    "type": "string"
+                },
+                "matches": {
+                    "type": "boolean"
+                },
+                "path": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.MessageFeedbackRequest": {
+            "type": "object",
+            "required": [
+                "correctedLabels",
+                "verdict"
+            ],
+            "properties": {
+                "comment": {
+                    "description": "备注",
+                    "type": "string",
+                    "maxLength": 4096
+                },
+                "correctedLabels": {
+                    "description": "修正后的标签，即消息实际应有的标签，检测正确时可为空",
+                    "type": "array",
+                    "maxItems": 64,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "verdict": {
+                    "description": "标注结果：true_positive 检测正确，false_positive 误报",
+                    "enum": [
+                        "true_positive",
+                        "false_positive"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.FeedbackVerdict"
+                        }
+                    ]
+                }
+            }
+        },
+        "dao.MessageFeedbackSpec": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "correctedLabels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "integer"
+                },
+                "messageId": {
+                    "type": "integer"
+                },
+                "updateTime": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                },
+                "username": {
+                    "type": "string"
+                },
+                "verdict": {
+                    "$ref": "#/definitions/model.FeedbackVerdict"
+                }
+            }
+        },
+        "dao.MessageMediaResponse": {
+            "type": "object",
+            "properties": {
+                "expireTime": {
+                    "type": "string"
+                },
+                "imageUrl": {
+                    "type": "string"
+                },
+                "videoUrl": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.MessageSpec": {
+            "type": "object",
+            "properties": {
+                "alerted": {
+                    "type": "boolean"
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "detectBoxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.DetectionBox"
+                    }
+                },
+                "frameHeight": {
+                    "type": "integer"
+                },
+                "frameWidth": {
+                    "description": "检测框所在画面的宽高（像素），为 0 表示未知",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "imagePath": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "integer"
+                },
+                "metadata": {
+                    "description": "设备后处理钩子添加的字段",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "normalizedBoxes": {
+                    "description": "按画面宽高归一化的检测框，画面宽高未知时为空",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.NormalizedBox"
+                    }
+                },
+                "sensors": {
+                    "description": "设备传感器读数，按传感器名称索引",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "signed": {
+                    "description": "是否带有设备签名，可通过校验接口证明未被篡改",
+                    "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "videoPath": {
+                    "type": "string"
+                },
+                "workflowResp": {
+                    "$ref": "#/definitions/dao.WorkflowResp"
+                }
+            }
+        },
+        "dao.MessageVerification": {
+            "type": "object",
+            "properties": {
+                "contentMatches": {
+                    "description": "消息内容是否与签名的内容一致",
+                    "type": "boolean"
+                },
+                "deviceUuid": {
+                    "description": "签名设备的uuid",
+                    "type": "string"
+                },
+                "error": {
+                    "description": "无法校验的原因，如公钥未登记",
+                    "type": "string"
+                },
+                "keyFingerprint": {
+                    "description": "签名公钥的指纹",
+                    "type": "string"
+                },
+                "keyRegisterTime": {
+                    "description": "签名公钥登记的时间",
+                    "type": "string"
+                },
+                "media": {
+                    "description": "图片和视频文件的校验结果，未校验文件时为空",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.MediaVerification"
+                    }
+                },
+                "messageId": {
+                    "type": "integer"
+                },
+                "mismatches": {
+                    "description": "与签名内容不一致的字段",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "signatureValid": {
+                    "description": "签名是否由登记的设备公钥签出",
+                    "type": "boolean"
+                },
+                "signed": {
+                    "description": "消息是否带有设备签名",
+                    "type": "boolean"
+                },
+                "verified": {
+                    "description": "签名、内容和文件均校验通过",
+                    "type": "boolean"
+                },
+                "verifyTime": {
+                    "description": "校验时间",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.NormalizedBox": {
+            "type": "object",
+            "properties": {
+                "classId": {
+                    "type": "integer"
+                },
+                "confidence": {
+                    "type": "number"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "x1": {
+                    "type": "number"
+                },
+                "x2": {
+                    "type": "number"
+                },
+                "y1": {
+                    "type": "number"
+                },
+                "y2": {
+                    "type": "number"
+                }
+            }
+        },
+        "dao.NotificationChannelSpec": {
+            "type": "object",
+            "properties": {
+                "cameraIds": {
+                    "description": "只投递这些摄像头的告警，为空表示全部",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "escalationOnly": {
+                    "description": "只接收告警升级策略发送的告警",
+                    "type": "boolean"
+                },
+                "headers": {
+                    "description": "请求头，webhook 通道使用",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "jobIds": {
+                    "description": "只投递这些任务的告警，为空表示全部",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "jobRecipients": {
+                    "description": "按任务ID额外接收该任务告警的邮箱，email 通道使用",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "kind": {
+                    "description": "通道类型：webhook、email、dingtalk（钉钉机器人）、wecom（企业微信机器人）或 slack",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.NotificationChannelKind"
+                        }
+                    ]
+                },
+                "lastDeliveryTime": {
+                    "description": "最近一次投递时间",
+                    "type": "string"
+                },
+                "lastError": {
+                    "description": "最近一次投递的错误，为空表示成功",
+                    "type": "string"
+                },
+                "maxRetries": {
+                    "description": "投递失败后的重试次数",
+                    "type": "integer"
+                },
+                "minSeverity": {
+                    "description": "只投递不低于该级别的告警，为空表示全部",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.AlertSeverity"
+                        }
+                    ]
+                },
+                "name": {
+                    "type": "string"
+                },
+                "recipients": {
+                    "description": "接收所有告警的邮箱，email 通道使用",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "钉钉机器人加签密钥",
+                    "type": "string"
+                },
+                "template": {
+                    "description": "模板，Go text/template 语法，数据为告警事件，.ImageUrl 为告警图片的预签名地址；webhook 通道为请求体，为空时发送告警事件的 JSON；email 通道为邮件正文，钉钉、企业微信和 slack 通道为 markdown 消息内容，为空时使用默认内容",
+                    "type": "string"
+                },
+                "updateTime": {
+                    "type": "string"
+                },
+                "url": {
+                    "description": "告警投递地址，webhook 通道为接收地址，钉钉、企业微信和 slack 通道为机器人 webhook 地址",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.NotificationPreferenceSpec": {
+            "type": "object",
+            "properties": {
+                "cameraIds": {
+                    "description": "只推送这些摄像头的告警，为空表示全部",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "enabled": {
+                    "description": "是否启用推送",
+                    "type": "boolean"
+                },
+                "jobIds": {
+                    "description": "只推送这些任务的告警，为空表示全部",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "minSeverity": {
+                    "description": "只推送不低于该级别的告警，为空表示全部",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.AlertSeverity"
+                        }
+                    ]
+                },
+                "updateTime": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.OccupancyOptions": {
+            "type": "object",
+            "properties": {
+                "interval": {
+                    "description": "上报间隔，单位秒，默认 60",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "labels": {
+                    "description": "统计的类别，逗号分隔，默认 person",
+                    "type": "string"
+                },
+                "zones": {
+                    "description": "统计区域，为空时统计整个画面",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.OccupancyZone"
+                    }
+                }
+            }
+        },
+        "dao.OccupancyPoint": {
+            "type": "object",
+            "properties": {
+                "in": {
+                    "description": "窗口内进入人次",
+                    "type": "integer"
+                },
+                "max": {
+                    "description": "窗口内的最大人数",
+                    "type": "integer"
+                },
+                "net": {
+                    "description": "净流入人次，即人数的变化量",
+                    "type": "integer"
+                },
+                "out": {
+                    "description": "窗口内离开人次",
+                    "type": "integer"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.OccupancyZone": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "description": "区域名称",
+                    "type": "string"
+                },
+                "polygon": {
+                    "description": "区域多边形顶点，坐标按画面宽高归一化到 0~1",
+                    "type": "array",
+                    "minItems": 3,
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "number",
+                            "format": "float64"
+                        }
+                    }
+                }
+            }
+        },
+        "dao.OnvifCamera": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "manufacturer": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "onvifPort": {
+                    "type": "integer"
+                },
+                "ptz": {
+                    "type": "boolean"
+                },
+                "streamUri": {
+                    "type": "string"
+                },
+                "xaddr": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.OnvifDiscoverResult": {
+            "type": "object",
+            "required": [
+                "taskUuid"
+            ],
+            "properties": {
+                "cameras": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.OnvifCamera"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "taskUuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.OnvifDiscoverTask": {
+            "type": "object",
+            "properties": {
+                "expireTime": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "subnet": {
+                    "type": "string"
+                },
+                "taskUuid": {
+                    "type": "string"
+                },
+                "timeout": {
+                    "type": "integer"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.OrganizationSpec": {
+            "type": "object",
+            "properties": {
+                "createdTime": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "组织ID",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "组织名称",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.PrecisionPoint": {
+            "type": "object",
+            "properties": {
+                "falsePositive": {
+                    "description": "标注为误报的消息数",
+                    "type": "integer"
+                },
+                "precision": {
+                    "description": "准确率，即 TruePositive / (TruePositive + FalsePositive)",
+                    "type": "number"
+                },
+                "time": {
+                    "description": "时间桶的开始时间",
+                    "type": "string"
+                },
+                "truePositive": {
+                    "description": "标注为检测正确的消息数",
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.PreviewTask": {
+            "type": "object",
+            "properties": {
+                "audio": {
+                    "type": "boolean"
+                },
+                "expireTime": {
+                    "type": "string"
+                },
+                "hlsAddr": {
+                    "type": "string"
+                },
+                "mode": {
+                    "$ref": "#/definitions/model.PreviewMode"
+                },
+                "previewAddr": {
+                    "type": "string"
+                },
+                "pullAddr": {
+                    "type": "string"
+                },
+                "pushAddr": {
+                    "type": "string"
+                },
+                "taskUuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.PtzGotoPresetRequest": {
+            "type": "object",
+            "required": [
+                "presetToken"
+            ],
+            "properties": {
+                "presetToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.PtzMoveRequest": {
+            "type": "object",
+            "properties": {
+                "pan": {
+                    "description": "水平、垂直和变焦速度，取值 -1 到 1",
+                    "type": "number",
+                    "maximum": 1,
+                    "minimum": -1
+                },
+                "tilt": {
+                    "type": "number",
+                    "maximum": 1,
+                    "minimum": -1
+                },
+                "timeout": {
+                    "description": "持续时间，单位毫秒，超时后摄像头自动停止，默认 1000",
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 100
+                },
+                "zoom": {
+                    "type": "number",
+                    "maximum": 1,
+                    "minimum": -1
+                }
+            }
+        },
+        "dao.PtzPreset": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.PushSubscriptionKeys": {
+            "type": "object",
+            "required": [
+                "auth",
+                "p256dh"
+            ],
+            "properties": {
+                "auth": {
+                    "type": "string"
+                },
+                "p256dh": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.PushSubscriptionRequest": {
+            "type": "object",
+            "required": [
+                "endpoint",
+                "keys"
+            ],
+            "properties": {
+                "endpoint": {
+                    "description": "浏览器推送服务地址",
+                    "type": "string"
+                },
+                "keys": {
+                    "description": "订阅密钥",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.PushSubscriptionKeys"
+                        }
+                    ]
                 }
             }
-        }
-    },
-    "definitions": {
-        "dao.AccessTokenSpec": {
+        },
+        "dao.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refreshToken": {
+                    "description": "刷新令牌，为空时使用 refresh_token cookie",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.RefreshResponse": {
+            "type": "object",
+            "properties": {
+                "expiresIn": {
+                    "description": "登录凭证的有效秒数",
+                    "type": "integer"
+                },
+                "refreshToken": {
+                    "description": "新的刷新令牌，原刷新令牌已作废",
+                    "type": "string"
+                },
+                "token": {
+                    "description": "新的登录凭证",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.RegisterRequest": {
             "type": "object",
             "required": [
-                "accessToken"
+                "accessToken",
+                "name"
             ],
             "properties": {
                 "accessToken": {
                     "type": "string"
                 },
+                "name": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.RegisterResponse": {
+            "type": "object",
+            "properties": {
+                "s3AccessKeyID": {
+                    "type": "string"
+                },
+                "s3SecretAccessKey": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.ReleaseSpec": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "$ref": "#/definitions/model.ReleaseChannel"
+                },
                 "createTime": {
                     "type": "string"
                 },
-                "deviceUuid": {
+                "id": {
+                    "type": "integer"
+                },
+                "notes": {
                     "type": "string"
                 },
-                "expireTime": {
+                "sha256": {
+                    "description": "程序的 sha256 校验值",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "程序下载地址",
                     "type": "string"
                 },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.ReportScheduleSpec": {
+            "type": "object",
+            "properties": {
+                "channelIds": {
+                    "description": "投递报表的通知通道ID",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "format": {
+                    "description": "报表格式：html 或 pdf",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReportFormat"
+                        }
+                    ]
+                },
+                "hour": {
+                    "description": "生成上一周期报表的时刻（0-23 点）",
+                    "type": "integer"
+                },
                 "id": {
                     "type": "integer"
+                },
+                "lastError": {
+                    "description": "最近一次生成或投递失败的原因，为空表示成功",
+                    "type": "string"
+                },
+                "lastPeriodEnd": {
+                    "description": "最近一次报表的周期结束时间",
+                    "type": "string"
+                },
+                "lastRunTime": {
+                    "description": "最近一次生成报表的时间",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "period": {
+                    "description": "报表周期：daily 每日，weekly 每周",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReportPeriod"
+                        }
+                    ]
+                },
+                "site": {
+                    "description": "报表覆盖的站点，即摄像头标签，为空表示全部摄像头",
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "报表使用的时区，为空表示服务器本地时区",
+                    "type": "string"
+                },
+                "updateTime": {
+                    "type": "string"
+                },
+                "weekday": {
+                    "description": "周报在星期几生成，0 表示星期日",
+                    "type": "integer"
                 }
             }
         },
-        "dao.AgentThoughtSpec": {
+        "dao.ReportSpec": {
             "type": "object",
             "properties": {
+                "createTime": {
+                    "type": "string"
+                },
+                "format": {
+                    "$ref": "#/definitions/model.ReportFormat"
+                },
                 "id": {
+                    "type": "integer"
+                },
+                "period": {
+                    "$ref": "#/definitions/model.ReportPeriod"
+                },
+                "periodEnd": {
                     "type": "string"
                 },
-                "observation": {
+                "periodStart": {
+                    "description": "报表统计的时间范围",
                     "type": "string"
                 },
-                "phase": {
+                "scheduleId": {
+                    "type": "integer"
+                },
+                "site": {
                     "type": "string"
                 },
-                "thought": {
+                "summary": {
+                    "description": "报表的概要",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReportSummary"
+                        }
+                    ]
+                }
+            }
+        },
+        "dao.ResetPasswordResponse": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "description": "临时密码，只返回这一次，用户登录后应尽快修改",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.ResolveAlertRequest": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "description": "解决说明",
+                    "type": "string",
+                    "maxLength": 4096
+                }
+            }
+        },
+        "dao.RolloutSpec": {
+            "type": "object",
+            "properties": {
+                "createTime": {
                     "type": "string"
                 },
-                "toolCall": {
-                    "$ref": "#/definitions/dao.ToolCallSpec"
+                "failed": {
+                    "description": "升级失败的设备数",
+                    "type": "integer"
+                },
+                "failureThreshold": {
+                    "description": "失败率阈值，升级过的设备中失败的比例超过该值时自动暂停",
+                    "type": "number"
+                },
+                "groupId": {
+                    "type": "integer"
+                },
+                "haltReason": {
+                    "description": "暂停原因",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "release": {
+                    "$ref": "#/definitions/dao.ReleaseSpec"
+                },
+                "status": {
+                    "description": "状态：running、halted、completed、cancelled",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.RolloutStatus"
+                        }
+                    ]
+                },
+                "succeeded": {
+                    "description": "升级成功的设备数",
+                    "type": "integer"
+                },
+                "updateTime": {
+                    "type": "string"
+                },
+                "upgrading": {
+                    "description": "升级中的设备数",
+                    "type": "integer"
                 }
             }
         },
-        "dao.CameraSpec": {
+        "dao.RotateDeviceTokenResponse": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "新令牌，设备首次使用后旧令牌失效",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.RunReportResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "投递失败的原因，为空表示全部通道投递成功",
+                    "type": "string"
+                },
+                "report": {
+                    "$ref": "#/definitions/dao.ReportSpec"
+                }
+            }
+        },
+        "dao.ScheduleWindow": {
             "type": "object",
             "required": [
-                "createTime",
-                "ip",
-                "name",
-                "protocol",
-                "updateTime",
-                "uuid"
+                "end",
+                "start"
             ],
             "properties": {
-                "bindDevice": {
-                    "$ref": "#/definitions/dao.DeviceSpec"
+                "days": {
+                    "description": "时段开始的星期，0 为周日，为空表示每天",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "end": {
+                    "description": "结束时间，HH:MM",
+                    "type": "string"
+                },
+                "start": {
+                    "description": "开始时间，HH:MM",
+                    "type": "string"
+                }
+            }
+        },
+        "dao.SemanticSearchHit": {
+            "type": "object",
+            "properties": {
+                "alerted": {
+                    "type": "boolean"
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "detectBoxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.DetectionBox"
+                    }
+                },
+                "frameHeight": {
+                    "type": "integer"
                 },
-                "createTime": {
-                    "type": "string"
+                "frameWidth": {
+                    "description": "检测框所在画面的宽高（像素），为 0 表示未知",
+                    "type": "integer"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "ip": {
+                "imagePath": {
                     "type": "string"
                 },
-                "name": {
-                    "type": "string"
+                "jobId": {
+                    "type": "integer"
                 },
-                "password": {
-                    "type": "string"
+                "metadata": {
+                    "description": "设备后处理钩子添加的字段",
+                    "type": "object",
+                    "additionalProperties": {}
                 },
-                "path": {
-                    "type": "string"
+                "normalizedBoxes": {
+                    "description": "按画面宽高归一化的检测框，画面宽高未知时为空",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.NormalizedBox"
+                    }
                 },
-                "port": {
-                    "type": "integer"
+                "score": {
+                    "description": "与搜索描述的相似度",
+                    "type": "number"
                 },
-                "protocol": {
-                    "$ref": "#/definitions/model.CameraProtocol"
+                "sensors": {
+                    "description": "设备传感器读数，按传感器名称索引",
+                    "type": "object",
+                    "additionalProperties": {}
                 },
-                "updateTime": {
-                    "type": "string"
+                "signed": {
+                    "description": "是否带有设备签名，可通过校验接口证明未被篡改",
+                    "type": "boolean"
                 },
-                "username": {
+                "timestamp": {
                     "type": "string"
                 },
-                "uuid": {
+                "videoPath": {
                     "type": "string"
+                },
+                "workflowResp": {
+                    "$ref": "#/definitions/dao.WorkflowResp"
                 }
             }
         },
-        "dao.ChatMessageSpec": {
+        "dao.SemanticSearchResponse": {
             "type": "object",
             "properties": {
-                "agentThoughts": {
+                "items": {
+                    "description": "按相似度从高到低排列的消息",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dao.AgentThoughtSpec"
+                        "$ref": "#/definitions/dao.SemanticSearchHit"
                     }
+                }
+            }
+        },
+        "dao.SessionSpec": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "description": "是否为当前请求的会话",
+                    "type": "boolean"
                 },
-                "answer": {
+                "expireTime": {
+                    "description": "未刷新时的过期时间",
                     "type": "string"
                 },
-                "conversationId": {
-                    "type": "integer"
+                "id": {
+                    "description": "会话ID",
+                    "type": "string"
                 },
-                "createTime": {
+                "ip": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
+                "loginTime": {
+                    "description": "登录时间",
+                    "type": "string"
                 },
-                "query": {
+                "refreshTime": {
+                    "description": "最近一次刷新的时间",
+                    "type": "string"
+                },
+                "userAgent": {
                     "type": "string"
                 }
             }
         },
-        "dao.ChatRequest": {
+        "dao.SetCameraCalibrationRequest": {
             "type": "object",
             "required": [
-                "query"
+                "points"
             ],
             "properties": {
-                "query": {
-                    "type": "string"
+                "points": {
+                    "type": "array",
+                    "maxItems": 64,
+                    "minItems": 4,
+                    "items": {
+                        "$ref": "#/definitions/dao.CalibrationPoint"
+                    }
+                },
+                "unit": {
+                    "description": "世界坐标单位，默认 m",
+                    "type": "string",
+                    "maxLength": 16
                 }
             }
         },
-        "dao.Condition": {
+        "dao.SeverityRule": {
             "type": "object",
+            "required": [
+                "severity"
+            ],
             "properties": {
-                "field": {
-                    "type": "string"
-                },
-                "op": {
-                    "type": "string"
+                "filter": {
+                    "$ref": "#/definitions/dao.FilterCondition"
                 },
-                "value": {
-                    "type": "string"
+                "severity": {
+                    "enum": [
+                        "low",
+                        "medium",
+                        "high",
+                        "critical"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.AlertSeverity"
+                        }
+                    ]
                 }
             }
         },
-        "dao.ConversationSpec": {
+        "dao.SnapshotResult": {
             "type": "object",
             "required": [
-                "createTime",
-                "uuid"
+                "taskUuid"
             ],
             "properties": {
-                "createTime": {
+                "error": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
-                },
-                "title": {
+                "imagePath": {
                     "type": "string"
                 },
-                "uuid": {
+                "taskUuid": {
                     "type": "string"
                 }
             }
         },
-        "dao.CreateAccessTokenRequest": {
+        "dao.SnapshotTask": {
             "type": "object",
             "properties": {
                 "expireTime": {
                     "type": "string"
-                }
-            }
-        },
-        "dao.CreateAccessTokenResponse": {
-            "type": "object",
-            "properties": {
-                "token": {
+                },
+                "pullAddr": {
+                    "type": "string"
+                },
+                "taskUuid": {
                     "type": "string"
                 }
             }
         },
-        "dao.CreateCameraRequest": {
+        "dao.TalkDownOptions": {
             "type": "object",
-            "required": [
-                "ip",
-                "name",
-                "protocol"
-            ],
             "properties": {
-                "bindDeviceId": {
-                    "type": "integer"
-                },
-                "ip": {
-                    "type": "string"
-                },
-                "name": {
-                    "type": "string"
-                },
-                "password": {
+                "audioUrl": {
+                    "description": "音频文件地址",
                     "type": "string"
                 },
-                "path": {
-                    "type": "string"
+                "cooldown": {
+                    "description": "告警触发喊话的冷却时间，单位秒",
+                    "type": "integer",
+                    "minimum": 0
                 },
-                "port": {
-                    "type": "integer"
+                "enabled": {
+                    "type": "boolean"
                 },
-                "protocol": {
-                    "$ref": "#/definitions/model.CameraProtocol"
+                "output": {
+                    "description": "播放方式，onvif 为摄像头音频回传通道，local 为设备本地音频输出",
+                    "type": "string",
+                    "enum": [
+                        "onvif",
+                        "local"
+                    ]
                 },
-                "username": {
+                "text": {
+                    "description": "喊话文本，由设备合成语音",
                     "type": "string"
                 }
             }
         },
-        "dao.CreateCameraResponse": {
+        "dao.TalkDownRequest": {
             "type": "object",
             "properties": {
-                "uuid": {
+                "audioUrl": {
+                    "description": "音频文件地址",
                     "type": "string"
-                }
-            }
-        },
-        "dao.CreateConversationRequest": {
-            "type": "object",
-            "properties": {
-                "title": {
+                },
+                "output": {
+                    "description": "播放方式，onvif 为摄像头音频回传通道，local 为设备本地音频输出",
+                    "type": "string",
+                    "enum": [
+                        "onvif",
+                        "local"
+                    ]
+                },
+                "text": {
+                    "description": "喊话文本，由设备合成语音",
                     "type": "string"
                 }
             }
         },
-        "dao.CreateConversationResponse": {
+        "dao.TalkDownResult": {
             "type": "object",
             "required": [
-                "uuid"
+                "taskUuid"
             ],
             "properties": {
-                "uuid": {
+                "createTime": {
                     "type": "string"
-                }
-            }
-        },
-        "dao.CreateJobRequest": {
-            "type": "object",
-            "required": [
-                "cameraId",
-                "kind"
-            ],
-            "properties": {
-                "cameraId": {
-                    "type": "integer"
-                },
-                "detect": {
-                    "$ref": "#/definitions/dao.DetectOptions"
                 },
-                "deviceId": {
+                "duration": {
+                    "description": "播放耗时，单位毫秒",
                     "type": "integer"
                 },
-                "kind": {
-                    "$ref": "#/definitions/model.JobKind"
-                },
-                "query": {
+                "error": {
+                    "description": "播放失败时的错误信息",
                     "type": "string"
                 },
-                "resultFilter": {
-                    "$ref": "#/definitions/dao.FilterCondition"
-                },
-                "videoSegment": {
-                    "$ref": "#/definitions/dao.VideoSegmentOptions"
-                },
-                "workflowId": {
-                    "type": "integer"
-                }
-            }
-        },
-        "dao.CreateJobResponse": {
-            "type": "object",
-            "properties": {
-                "uuid": {
+                "taskUuid": {
                     "type": "string"
                 }
             }
         },
-        "dao.CreateMessageRequest": {
+        "dao.TalkDownTask": {
             "type": "object",
-            "required": [
-                "jobId",
-                "timestamp"
-            ],
             "properties": {
-                "detectBoxes": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/dao.DetectionBox"
-                    }
+                "audioUrl": {
+                    "type": "string"
                 },
-                "imagePath": {
+                "expireTime": {
                     "type": "string"
                 },
-                "jobId": {
-                    "type": "integer"
+                "output": {
+                    "type": "string"
                 },
-                "timestamp": {
-                    "type": "integer"
+                "pullAddr": {
+                    "type": "string"
                 },
-                "videoPath": {
+                "taskUuid": {
                     "type": "string"
                 },
-                "workflowResp": {
-                    "$ref": "#/definitions/dao.WorkflowResp"
-                }
-            }
-        },
-        "dao.CreateMessageResponse": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "integer"
+                "text": {
+                    "type": "string"
                 }
             }
         },
-        "dao.CreateUserRequest": {
+        "dao.TestDetectRequest": {
             "type": "object",
             "required": [
-                "departmentId",
-                "nickname",
-                "password",
-                "username"
+                "modelName"
             ],
             "properties": {
-                "departmentId": {
-                    "description": "部门id",
-                    "type": "integer"
+                "confThreshold": {
+                    "type": "number",
+                    "maximum": 1,
+                    "minimum": 0
                 },
-                "isAdmin": {
-                    "description": "是否管理员",
-                    "type": "boolean"
+                "iouThreshold": {
+                    "type": "number",
+                    "maximum": 1,
+                    "minimum": 0
                 },
-                "nickname": {
-                    "description": "昵称",
+                "labels": {
                     "type": "string"
                 },
-                "password": {
-                    "description": "密码",
+                "modelName": {
                     "type": "string"
                 },
-                "username": {
-                    "description": "用户名",
-                    "type": "string"
-                }
-            }
-        },
-        "dao.CreateUserResponse": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "description": "用户ID",
-                    "type": "integer"
+                "noCache": {
+                    "type": "boolean"
                 }
             }
         },
-        "dao.CreateWorkflowRequest": {
+        "dao.TestDetectResponse": {
             "type": "object",
-            "required": [
-                "endpoint",
-                "key",
-                "modelName",
-                "name"
-            ],
             "properties": {
-                "endpoint": {
-                    "type": "string"
+                "boxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.DetectionBox"
+                    }
                 },
-                "key": {
-                    "type": "string"
+                "cached": {
+                    "type": "boolean"
                 },
-                "modelName": {
+                "createTime": {
                     "type": "string"
                 },
-                "name": {
+                "imagePath": {
                     "type": "string"
                 },
-                "query": {
+                "taskUuid": {
                     "type": "string"
-                },
-                "resultFilter": {
-                    "$ref": "#/definitions/dao.FilterCondition"
-                },
-                "timeout": {
-                    "type": "integer",
-                    "minimum": 1
                 }
             }
         },
-        "dao.CreateWorkflowResponse": {
+        "dao.TestDetectResult": {
             "type": "object",
+            "required": [
+                "taskUuid"
+            ],
             "properties": {
-                "id": {
-                    "type": "integer"
+                "boxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.DetectionBox"
+                    }
                 },
-                "uuid": {
+                "error": {
+                    "type": "string"
+                },
+                "imagePath": {
+                    "type": "string"
+                },
+                "taskUuid": {
                     "type": "string"
                 }
             }
         },
-        "dao.DetectOptions": {
+        "dao.TestDetectTask": {
             "type": "object",
-            "required": [
-                "modelName"
-            ],
             "properties": {
                 "confThreshold": {
                     "type": "number"
                 },
-                "interval": {
-                    "type": "integer"
+                "expireTime": {
+                    "type": "string"
                 },
                 "iouThreshold": {
                     "type": "number"
@@ -2881,533 +14324,689 @@ const docTemplate = `{
                 "modelName": {
                     "type": "string"
                 },
-                "triggerCount": {
-                    "type": "integer"
+                "pullAddr": {
+                    "type": "string"
                 },
-                "triggerInterval": {
-                    "type": "integer"
+                "taskUuid": {
+                    "type": "string"
                 }
             }
         },
-        "dao.DetectionBox": {
+        "dao.TestNotificationChannelResponse": {
             "type": "object",
             "properties": {
-                "classId": {
-                    "type": "integer"
-                },
-                "confidence": {
-                    "type": "number"
-                },
-                "label": {
+                "body": {
+                    "description": "发送的请求体或邮件正文",
                     "type": "string"
                 },
-                "x1": {
-                    "type": "integer"
-                },
-                "x2": {
-                    "type": "integer"
-                },
-                "y1": {
-                    "type": "integer"
+                "error": {
+                    "description": "投递失败的原因，为空表示成功",
+                    "type": "string"
                 },
-                "y2": {
+                "statusCode": {
+                    "description": "对端返回的状态码，请求未送达或 email 通道时为 0",
                     "type": "integer"
                 }
             }
         },
-        "dao.DeviceJobStatus": {
+        "dao.TimeCount": {
             "type": "object",
             "properties": {
-                "exectorStatus": {
-                    "$ref": "#/definitions/model.ExectorStatus"
+                "count": {
+                    "type": "integer"
+                },
+                "time": {
+                    "type": "string"
                 }
             }
         },
-        "dao.DeviceSpec": {
+        "dao.ToolCallSpec": {
             "type": "object",
             "properties": {
-                "id": {
-                    "type": "integer"
-                },
-                "lastPingTime": {
+                "args": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
-                },
-                "registerTime": {
-                    "type": "string"
-                },
-                "token": {
-                    "type": "string"
-                },
-                "uuid": {
-                    "type": "string"
                 }
             }
         },
-        "dao.DeviceStatus": {
+        "dao.TotpCodeRequest": {
             "type": "object",
+            "required": [
+                "code"
+            ],
             "properties": {
-                "jobStatus": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "$ref": "#/definitions/dao.DeviceJobStatus"
-                    }
+                "code": {
+                    "description": "验证器应用生成的 6 位验证码",
+                    "type": "string"
                 }
             }
         },
-        "dao.FilterCondition": {
+        "dao.TotpRecoveryCodesResponse": {
             "type": "object",
             "properties": {
-                "combineOp": {
-                    "type": "string"
-                },
-                "conditions": {
+                "recoveryCodes": {
+                    "description": "恢复码，每个只能使用一次，只返回这一次",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dao.Condition"
+                        "type": "string"
                     }
                 }
             }
         },
-        "dao.GenChatTitleResponse": {
+        "dao.TotpSetupResponse": {
             "type": "object",
-            "required": [
-                "title"
-            ],
             "properties": {
-                "title": {
+                "secret": {
+                    "description": "base32 编码的密钥，供无法扫码时手动输入",
+                    "type": "string"
+                },
+                "uri": {
+                    "description": "otpauth 地址，以二维码展示给验证器应用扫描",
                     "type": "string"
                 }
             }
         },
-        "dao.JobSpec": {
+        "dao.UpdateCameraRequest": {
             "type": "object",
-            "required": [
-                "camera",
-                "createTime",
-                "enabled",
-                "kind",
-                "status",
-                "updateTime",
-                "uuid"
-            ],
             "properties": {
-                "camera": {
-                    "$ref": "#/definitions/dao.CameraSpec"
-                },
-                "createTime": {
-                    "type": "string"
-                },
-                "detect": {
-                    "$ref": "#/definitions/dao.DetectOptions"
-                },
-                "device": {
-                    "$ref": "#/definitions/dao.DeviceSpec"
-                },
-                "enabled": {
-                    "type": "boolean"
-                },
-                "id": {
+                "bindDeviceId": {
                     "type": "integer"
                 },
-                "kind": {
-                    "$ref": "#/definitions/model.JobKind"
+                "ip": {
+                    "type": "string"
                 },
-                "query": {
+                "name": {
                     "type": "string"
                 },
-                "resultFilter": {
-                    "$ref": "#/definitions/dao.FilterCondition"
+                "onvifPort": {
+                    "description": "ONVIF 设备服务端口",
+                    "type": "integer",
+                    "maximum": 65535,
+                    "minimum": 0
                 },
-                "status": {
+                "password": {
                     "type": "string"
                 },
-                "updateTime": {
+                "path": {
                     "type": "string"
                 },
-                "uuid": {
-                    "type": "string"
+                "port": {
+                    "type": "integer"
                 },
-                "videoSegment": {
-                    "$ref": "#/definitions/dao.VideoSegmentOptions"
+                "previewAudio": {
+                    "description": "预览时是否包含音频",
+                    "type": "boolean"
                 },
-                "workflow": {
-                    "$ref": "#/definitions/dao.WorkflowSpec"
+                "protocol": {
+                    "$ref": "#/definitions/model.CameraProtocol"
+                },
+                "tags": {
+                    "description": "标签，传空数组表示清空",
+                    "type": "array",
+                    "maxItems": 32,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "username": {
+                    "type": "string"
                 }
             }
         },
-        "dao.JobStatsResponse": {
+        "dao.UpdateDeviceGroupRequest": {
             "type": "object",
             "properties": {
-                "labels": {
+                "channel": {
+                    "description": "发布通道，stable 或 beta",
+                    "enum": [
+                        "stable",
+                        "beta"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReleaseChannel"
+                        }
+                    ]
+                },
+                "description": {
+                    "description": "描述",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "deviceIds": {
+                    "description": "成员设备ID，传入时整体替换，传空数组表示清空",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dao.LabelTimeCount"
+                        "type": "integer"
                     }
                 },
-                "messages": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/dao.TimeCount"
-                    }
+                "name": {
+                    "description": "设备组名称",
+                    "type": "string",
+                    "maxLength": 96,
+                    "minLength": 1
+                },
+                "upgradeWindow": {
+                    "description": "升级时段，传空的时段表示任意时间",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.JobSchedule"
+                        }
+                    ]
                 }
             }
         },
-        "dao.LabelTimeCount": {
+        "dao.UpdateDeviceRequest": {
             "type": "object",
             "properties": {
-                "count": {
-                    "type": "integer"
-                },
-                "label": {
-                    "type": "string"
+                "maxExecutors": {
+                    "description": "最大并发执行任务数，0 表示使用设备本地配置",
+                    "type": "integer",
+                    "minimum": 0
                 },
-                "time": {
-                    "type": "string"
+                "name": {
+                    "description": "设备名称",
+                    "type": "string",
+                    "maxLength": 96,
+                    "minLength": 1
                 }
             }
         },
-        "dao.ListAccessTokenResponse": {
+        "dao.UpdateEscalationPolicyRequest": {
             "type": "object",
             "properties": {
-                "accessTokens": {
+                "cameraIds": {
+                    "description": "只升级这些摄像头的告警，传入时整体替换，传空数组表示全部",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dao.AccessTokenSpec"
+                        "type": "integer"
                     }
                 },
-                "total": {
-                    "type": "integer"
-                }
-            }
-        },
-        "dao.ListCamerasResponse": {
-            "type": "object",
-            "properties": {
-                "items": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "jobIds": {
+                    "description": "只升级这些任务的告警，传入时整体替换，传空数组表示全部",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dao.CameraSpec"
+                        "type": "integer"
                     }
                 },
-                "total": {
-                    "type": "integer"
-                }
-            }
-        },
-        "dao.ListChatMessagesResponse": {
-            "type": "object",
-            "properties": {
-                "items": {
+                "minSeverity": {
+                    "description": "只升级不低于该级别的告警，传空字符串表示全部",
+                    "enum": [
+                        "low",
+                        "medium",
+                        "high",
+                        "critical",
+                        ""
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.AlertSeverity"
+                        }
+                    ]
+                },
+                "name": {
+                    "description": "策略名称",
+                    "type": "string",
+                    "maxLength": 96,
+                    "minLength": 1
+                },
+                "steps": {
+                    "description": "升级步骤，传入时整体替换",
                     "type": "array",
+                    "maxItems": 10,
+                    "minItems": 1,
                     "items": {
-                        "$ref": "#/definitions/dao.ChatMessageSpec"
+                        "$ref": "#/definitions/dao.EscalationStep"
                     }
-                },
-                "total": {
-                    "type": "integer"
                 }
             }
         },
-        "dao.ListConversationsResponse": {
+        "dao.UpdateJobRequest": {
             "type": "object",
             "properties": {
-                "items": {
+                "alertDedupWindow": {
+                    "description": "告警去重窗口，单位秒，0 表示不去重",
+                    "type": "integer",
+                    "maximum": 86400,
+                    "minimum": 0
+                },
+                "cameraId": {
+                    "type": "integer"
+                },
+                "detect": {
+                    "$ref": "#/definitions/dao.DetectOptions"
+                },
+                "deviceGroupId": {
+                    "description": "目标设备组，设置非零的设备或设备组会清除另一个",
+                    "type": "integer"
+                },
+                "deviceId": {
+                    "type": "integer"
+                },
+                "hooks": {
+                    "description": "后处理钩子，传空数组表示清空",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dao.ConversationSpec"
+                        "$ref": "#/definitions/dao.JobHook"
                     }
                 },
-                "total": {
-                    "type": "integer"
-                }
-            }
-        },
-        "dao.ListDeviceResponse": {
-            "type": "object",
-            "properties": {
-                "devices": {
+                "maxFps": {
+                    "description": "每秒推理帧数上限，0 表示只受检测间隔限制",
+                    "type": "number",
+                    "minimum": 0
+                },
+                "plugin": {
+                    "description": "自定义任务类型的参数",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "resultFilter": {
+                    "description": "告警过滤条件，传空的条件表示使用工作流的过滤条件",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.FilterCondition"
+                        }
+                    ]
+                },
+                "schedule": {
+                    "description": "布防计划，传空的时段表示取消计划",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.JobSchedule"
+                        }
+                    ]
+                },
+                "severityRules": {
+                    "description": "告警级别规则，传空数组表示清空",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dao.DeviceSpec"
+                        "$ref": "#/definitions/dao.SeverityRule"
                     }
                 },
-                "total": {
+                "talkDown": {
+                    "description": "告警时的现场喊话",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dao.TalkDownOptions"
+                        }
+                    ]
+                },
+                "videoSegment": {
+                    "$ref": "#/definitions/dao.VideoSegmentOptions"
+                },
+                "workflowId": {
                     "type": "integer"
                 }
             }
         },
-        "dao.ListJobsResponse": {
+        "dao.UpdateNotificationChannelRequest": {
             "type": "object",
             "properties": {
-                "items": {
+                "cameraIds": {
+                    "description": "只投递这些摄像头的告警，传入时整体替换，传空数组表示全部",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dao.JobSpec"
+                        "type": "integer"
                     }
                 },
-                "total": {
-                    "type": "integer"
-                }
-            }
-        },
-        "dao.ListMessagesResponse": {
-            "type": "object",
-            "properties": {
-                "items": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "escalationOnly": {
+                    "description": "只接收告警升级策略发送的告警",
+                    "type": "boolean"
+                },
+                "headers": {
+                    "description": "请求头，传入时整体替换",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "jobIds": {
+                    "description": "只投递这些任务的告警，传入时整体替换，传空数组表示全部",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dao.MessageSpec"
+                        "type": "integer"
                     }
                 },
-                "total": {
-                    "type": "integer"
-                }
-            }
-        },
-        "dao.ListPreviewTasksResponse": {
-            "type": "object",
-            "properties": {
-                "items": {
+                "jobRecipients": {
+                    "description": "按任务ID额外接收该任务告警的邮箱，传入时整体替换",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "maxRetries": {
+                    "description": "投递失败后的重试次数",
+                    "type": "integer",
+                    "maximum": 10,
+                    "minimum": 0
+                },
+                "minSeverity": {
+                    "description": "只投递不低于该级别的告警，传空字符串表示全部",
+                    "enum": [
+                        "low",
+                        "medium",
+                        "high",
+                        "critical",
+                        ""
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.AlertSeverity"
+                        }
+                    ]
+                },
+                "name": {
+                    "description": "通道名称",
+                    "type": "string",
+                    "maxLength": 96,
+                    "minLength": 1
+                },
+                "recipients": {
+                    "description": "接收所有告警的邮箱，传入时整体替换",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dao.PreviewTask"
+                        "type": "string"
                     }
                 },
-                "total": {
-                    "type": "integer"
+                "secret": {
+                    "description": "钉钉机器人加签密钥，传空字符串表示不加签",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "template": {
+                    "description": "模板，传空字符串表示使用默认的请求体或消息内容",
+                    "type": "string",
+                    "maxLength": 65535
+                },
+                "url": {
+                    "description": "告警投递地址",
+                    "type": "string",
+                    "maxLength": 1024
                 }
             }
         },
-        "dao.ListUsersResponse": {
+        "dao.UpdateNotificationPreferenceRequest": {
             "type": "object",
             "properties": {
-                "items": {
-                    "description": "用户列表",
+                "cameraIds": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dao.UserSpec"
+                        "type": "integer"
                     }
                 },
-                "total": {
-                    "description": "用户总数",
-                    "type": "integer"
-                }
-            }
-        },
-        "dao.ListWorkflowResponse": {
-            "type": "object",
-            "properties": {
-                "total": {
-                    "type": "integer"
+                "enabled": {
+                    "type": "boolean"
                 },
-                "workflows": {
+                "jobIds": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dao.WorkflowSpec"
+                        "type": "integer"
                     }
-                }
-            }
-        },
-        "dao.LoginRequest": {
-            "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
-            "properties": {
-                "password": {
-                    "description": "密码",
-                    "type": "string"
                 },
-                "username": {
-                    "description": "用户名",
-                    "type": "string"
+                "minSeverity": {
+                    "description": "只推送不低于该级别的告警，为空表示全部",
+                    "enum": [
+                        "low",
+                        "medium",
+                        "high",
+                        "critical"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.AlertSeverity"
+                        }
+                    ]
                 }
             }
         },
-        "dao.LoginResponse": {
+        "dao.UpdateProfileRequest": {
             "type": "object",
             "properties": {
-                "token": {
-                    "description": "登录凭证",
+                "timezone": {
+                    "description": "显示时区，IANA 时区名，如 Asia/Shanghai，为空时使用浏览器时区",
                     "type": "string"
-                },
-                "user": {
-                    "$ref": "#/definitions/dao.UserSpec"
                 }
             }
         },
-        "dao.MessageSpec": {
+        "dao.UpdateReportScheduleRequest": {
             "type": "object",
             "properties": {
-                "alerted": {
-                    "type": "boolean"
-                },
-                "createTime": {
-                    "type": "string"
-                },
-                "detectBoxes": {
+                "channelIds": {
+                    "description": "投递报表的通知通道ID，传入时整体替换",
                     "type": "array",
+                    "maxItems": 20,
+                    "minItems": 1,
                     "items": {
-                        "$ref": "#/definitions/dao.DetectionBox"
+                        "type": "integer"
                     }
                 },
-                "id": {
-                    "type": "integer"
-                },
-                "imagePath": {
-                    "type": "string"
+                "enabled": {
+                    "type": "boolean"
                 },
-                "jobId": {
-                    "type": "integer"
+                "format": {
+                    "description": "报表格式：html 或 pdf",
+                    "enum": [
+                        "html",
+                        "pdf"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReportFormat"
+                        }
+                    ]
                 },
-                "timestamp": {
-                    "type": "string"
+                "hour": {
+                    "description": "生成上一周期报表的时刻（0-23 点）",
+                    "type": "integer",
+                    "maximum": 23,
+                    "minimum": 0
                 },
-                "videoPath": {
+                "name": {
+                    "description": "报表名称",
+                    "type": "string",
+                    "maxLength": 96,
+                    "minLength": 1
+                },
+                "period": {
+                    "description": "报表周期：daily 每日，weekly 每周",
+                    "enum": [
+                        "daily",
+                        "weekly"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ReportPeriod"
+                        }
+                    ]
+                },
+                "site": {
+                    "description": "报表覆盖的站点，传空字符串表示全部摄像头",
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "timezone": {
+                    "description": "报表使用的时区，传空字符串表示服务器本地时区",
                     "type": "string"
                 },
-                "workflowResp": {
-                    "$ref": "#/definitions/dao.WorkflowResp"
+                "weekday": {
+                    "description": "周报在星期几生成，0 表示星期日",
+                    "type": "integer",
+                    "maximum": 6,
+                    "minimum": 0
                 }
             }
         },
-        "dao.PreviewTask": {
+        "dao.UpdateWorkflowRequest": {
             "type": "object",
             "properties": {
-                "expireTime": {
+                "endpoint": {
                     "type": "string"
                 },
-                "previewAddr": {
+                "key": {
                     "type": "string"
                 },
-                "pullAddr": {
+                "modelName": {
                     "type": "string"
                 },
-                "pushAddr": {
+                "name": {
                     "type": "string"
                 },
-                "taskUuid": {
+                "query": {
                     "type": "string"
+                },
+                "resultFilter": {
+                    "$ref": "#/definitions/dao.FilterCondition"
+                },
+                "timeout": {
+                    "type": "integer"
                 }
             }
         },
-        "dao.RegisterRequest": {
+        "dao.UpgradeResult": {
             "type": "object",
             "required": [
-                "accessToken",
-                "name"
+                "error",
+                "rolloutId"
             ],
             "properties": {
-                "accessToken": {
+                "error": {
                     "type": "string"
                 },
-                "name": {
+                "rolloutId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.UpgradeTask": {
+            "type": "object",
+            "properties": {
+                "rolloutId": {
+                    "type": "integer"
+                },
+                "sha256": {
                     "type": "string"
                 },
-                "uuid": {
+                "url": {
+                    "type": "string"
+                },
+                "version": {
                     "type": "string"
                 }
             }
         },
-        "dao.RegisterResponse": {
+        "dao.UserSpec": {
             "type": "object",
+            "required": [
+                "createdTime",
+                "nickname",
+                "username"
+            ],
             "properties": {
-                "s3AccessKeyID": {
+                "createdTime": {
                     "type": "string"
                 },
-                "s3SecretAccessKey": {
+                "id": {
+                    "type": "integer"
+                },
+                "isAdmin": {
+                    "type": "boolean"
+                },
+                "nickname": {
                     "type": "string"
                 },
-                "token": {
+                "orgId": {
+                    "description": "所属组织ID",
+                    "type": "integer"
+                },
+                "timezone": {
+                    "description": "显示时区，IANA 时区名，如 Asia/Shanghai，为空时使用浏览器时区",
                     "type": "string"
                 },
-                "uuid": {
+                "totpEnabled": {
+                    "description": "是否已启用两步验证",
+                    "type": "boolean"
+                },
+                "username": {
                     "type": "string"
                 }
             }
         },
-        "dao.TimeCount": {
+        "dao.ValidateJobResponse": {
             "type": "object",
             "properties": {
-                "count": {
-                    "type": "integer"
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.JobCheck"
+                    }
                 },
-                "time": {
-                    "type": "string"
+                "ready": {
+                    "description": "没有未通过的检查项",
+                    "type": "boolean"
                 }
             }
         },
-        "dao.ToolCallSpec": {
+        "dao.VapidPublicKeyResponse": {
             "type": "object",
             "properties": {
-                "args": {
-                    "type": "string"
-                },
-                "name": {
+                "publicKey": {
+                    "description": "推送服务公钥，为空表示未启用推送",
                     "type": "string"
                 }
             }
         },
-        "dao.UpdateCameraRequest": {
+        "dao.VideoSegmentOptions": {
             "type": "object",
             "properties": {
-                "bindDeviceId": {
-                    "type": "integer"
-                },
-                "ip": {
-                    "type": "string"
-                },
-                "name": {
-                    "type": "string"
-                },
-                "password": {
-                    "type": "string"
-                },
-                "path": {
-                    "type": "string"
-                },
-                "port": {
+                "interval": {
                     "type": "integer"
-                },
-                "protocol": {
-                    "$ref": "#/definitions/model.CameraProtocol"
-                },
-                "username": {
-                    "type": "string"
                 }
             }
         },
-        "dao.UpdateJobRequest": {
+        "dao.WorkflowBundle": {
             "type": "object",
+            "required": [
+                "version"
+            ],
             "properties": {
-                "cameraId": {
-                    "type": "integer"
-                },
-                "detect": {
-                    "$ref": "#/definitions/dao.DetectOptions"
+                "exportTime": {
+                    "type": "string"
                 },
-                "deviceId": {
+                "version": {
                     "type": "integer"
                 },
-                "videoSegment": {
-                    "$ref": "#/definitions/dao.VideoSegmentOptions"
-                },
-                "workflowId": {
-                    "type": "integer"
+                "workflows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.WorkflowExportItem"
+                    }
                 }
             }
         },
-        "dao.UpdateWorkflowRequest": {
+        "dao.WorkflowExportItem": {
             "type": "object",
-            "properties": {
-                "endpoint": {
-                    "type": "string"
-                },
-                "key": {
+            "required": [
+                "endpoint",
+                "modelName",
+                "name",
+                "uuid"
+            ],
+            "properties": {
+                "endpoint": {
                     "type": "string"
                 },
                 "modelName": {
@@ -3424,50 +15023,33 @@ const docTemplate = `{
                 },
                 "timeout": {
                     "type": "integer"
+                },
+                "uuid": {
+                    "type": "string"
                 }
             }
         },
-        "dao.UserSpec": {
+        "dao.WorkflowResp": {
             "type": "object",
-            "required": [
-                "createdTime",
-                "nickname",
-                "username"
-            ],
             "properties": {
-                "createdTime": {
+                "answer": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
+                "confidence": {
+                    "description": "置信度和是否匹配总是序列化，0 和 false 也是分析结果",
+                    "type": "number"
                 },
-                "isAdmin": {
+                "match": {
                     "type": "boolean"
                 },
-                "nickname": {
+                "rawContent": {
                     "type": "string"
                 },
-                "username": {
-                    "type": "string"
-                }
-            }
-        },
-        "dao.VideoSegmentOptions": {
-            "type": "object",
-            "properties": {
-                "interval": {
+                "totalTokens": {
                     "type": "integer"
                 }
             }
         },
-        "dao.WorkflowResp": {
-            "type": "object",
-            "properties": {
-                "answer": {
-                    "type": "string"
-                }
-            }
-        },
         "dao.WorkflowSpec": {
             "type": "object",
             "required": [
@@ -3511,6 +15093,152 @@ const docTemplate = `{
                 }
             }
         },
+        "dao.ZoneOccupancyTrend": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "description": "最近一次上报的人数",
+                    "type": "integer"
+                },
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.OccupancyPoint"
+                    }
+                },
+                "updateTime": {
+                    "description": "最近一次上报的时间",
+                    "type": "string"
+                },
+                "zone": {
+                    "type": "string"
+                }
+            }
+        },
+        "graphql.Error": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "array",
+                    "items": {}
+                }
+            }
+        },
+        "graphql.Request": {
+            "type": "object",
+            "required": [
+                "query"
+            ],
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": {}
+                }
+            }
+        },
+        "graphql.Response": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/graphql.Error"
+                    }
+                }
+            }
+        },
+        "model.AgentProposalKind": {
+            "type": "string",
+            "enum": [
+                "create_job",
+                "update_job",
+                "create_workflow",
+                "update_workflow"
+            ],
+            "x-enum-varnames": [
+                "AgentProposalCreateJob",
+                "AgentProposalUpdateJob",
+                "AgentProposalCreateWorkflow",
+                "AgentProposalUpdateWorkflow"
+            ]
+        },
+        "model.AlertAction": {
+            "type": "string",
+            "enum": [
+                "ack",
+                "assign",
+                "comment",
+                "resolve"
+            ],
+            "x-enum-varnames": [
+                "AlertActionAck",
+                "AlertActionAssign",
+                "AlertActionComment",
+                "AlertActionResolve"
+            ]
+        },
+        "model.AlertSeverity": {
+            "type": "string",
+            "enum": [
+                "low",
+                "medium",
+                "high",
+                "critical"
+            ],
+            "x-enum-varnames": [
+                "SeverityLow",
+                "SeverityMedium",
+                "SeverityHigh",
+                "SeverityCritical"
+            ]
+        },
+        "model.AlertStatus": {
+            "type": "string",
+            "enum": [
+                "open",
+                "acknowledged",
+                "resolved"
+            ],
+            "x-enum-varnames": [
+                "AlertStatusOpen",
+                "AlertStatusAcknowledged",
+                "AlertStatusResolved"
+            ]
+        },
+        "model.ApiConsumerKind": {
+            "type": "string",
+            "enum": [
+                "user",
+                "device",
+                "anonymous"
+            ],
+            "x-enum-varnames": [
+                "ApiConsumerUser",
+                "ApiConsumerDevice",
+                "ApiConsumerAnonymous"
+            ]
+        },
+        "model.ArtifactKind": {
+            "type": "string",
+            "enum": [
+                "trigger_thumbnail",
+                "error_screenshot"
+            ],
+            "x-enum-varnames": [
+                "ArtifactTriggerThumbnail",
+                "ArtifactErrorScreenshot"
+            ]
+        },
         "model.CameraProtocol": {
             "type": "string",
             "enum": [
@@ -3522,38 +15250,293 @@ const docTemplate = `{
                 "CameraProtocolRtsp"
             ]
         },
+        "model.DeviceInventory": {
+            "type": "object",
+            "properties": {
+                "arch": {
+                    "type": "string"
+                },
+                "ffmpegVersion": {
+                    "type": "string"
+                },
+                "kernel": {
+                    "type": "string"
+                },
+                "luminaCommit": {
+                    "type": "string"
+                },
+                "luminaVersion": {
+                    "type": "string"
+                },
+                "opencvVersion": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "tritonVersion": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.DeviceState": {
+            "type": "string",
+            "enum": [
+                "online",
+                "degraded",
+                "offline"
+            ],
+            "x-enum-varnames": [
+                "DeviceStateOnline",
+                "DeviceStateDegraded",
+                "DeviceStateOffline"
+            ]
+        },
+        "model.DeviceUpgradeStatus": {
+            "type": "string",
+            "enum": [
+                "upgrading",
+                "succeeded",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "DeviceUpgradeStatusUpgrading",
+                "DeviceUpgradeStatusSucceeded",
+                "DeviceUpgradeStatusFailed"
+            ]
+        },
+        "model.DiskUsage": {
+            "type": "object",
+            "properties": {
+                "freeBytes": {
+                    "type": "integer"
+                },
+                "jobDirBytes": {
+                    "type": "integer"
+                },
+                "jobDirFiles": {
+                    "type": "integer"
+                },
+                "totalBytes": {
+                    "type": "integer"
+                }
+            }
+        },
         "model.ExectorStatus": {
             "type": "integer",
             "enum": [
                 0,
                 1,
                 2,
-                3
+                3,
+                4,
+                5,
+                6
             ],
             "x-enum-varnames": [
                 "ExectorStatusStopped",
                 "ExectorStatusRunning",
                 "ExectorStatusFinished",
-                "ExectorStatusFailed"
+                "ExectorStatusFailed",
+                "ExectorStatusPending",
+                "ExectorStatusPaused",
+                "ExectorStatusDisarmed"
+            ]
+        },
+        "model.FeedbackVerdict": {
+            "type": "string",
+            "enum": [
+                "true_positive",
+                "false_positive"
+            ],
+            "x-enum-varnames": [
+                "FeedbackTruePositive",
+                "FeedbackFalsePositive"
+            ]
+        },
+        "model.HealthEventKind": {
+            "type": "string",
+            "enum": [
+                "message_volume",
+                "alert_volume",
+                "canary",
+                "device_offline"
+            ],
+            "x-enum-varnames": [
+                "HealthEventMessageVolume",
+                "HealthEventAlertVolume",
+                "HealthEventCanary",
+                "HealthEventDeviceOffline"
             ]
         },
         "model.JobKind": {
             "type": "string",
             "enum": [
                 "detect",
-                "video_segment"
+                "video_segment",
+                "canary"
             ],
             "x-enum-varnames": [
                 "JobKindDetect",
-                "JobKindVideoSegment"
+                "JobKindVideoSegment",
+                "JobKindCanary"
+            ]
+        },
+        "model.JobStatusSource": {
+            "type": "string",
+            "enum": [
+                "device",
+                "server"
+            ],
+            "x-enum-varnames": [
+                "JobStatusSourceDevice",
+                "JobStatusSourceServer"
+            ]
+        },
+        "model.NotificationChannelKind": {
+            "type": "string",
+            "enum": [
+                "webhook",
+                "email",
+                "dingtalk",
+                "wecom",
+                "slack"
+            ],
+            "x-enum-varnames": [
+                "NotificationChannelWebhook",
+                "NotificationChannelEmail",
+                "NotificationChannelDingTalk",
+                "NotificationChannelWeCom",
+                "NotificationChannelSlack"
+            ]
+        },
+        "model.PreviewMode": {
+            "type": "string",
+            "enum": [
+                "flv",
+                "webrtc",
+                "hls"
+            ],
+            "x-enum-varnames": [
+                "PreviewModeFlv",
+                "PreviewModeWebRTC",
+                "PreviewModeHls"
+            ]
+        },
+        "model.PrivacyMode": {
+            "type": "string",
+            "enum": [
+                "blur",
+                "pixelate"
+            ],
+            "x-enum-varnames": [
+                "PrivacyModeBlur",
+                "PrivacyModePixelate"
+            ]
+        },
+        "model.ReleaseChannel": {
+            "type": "string",
+            "enum": [
+                "stable",
+                "beta"
+            ],
+            "x-enum-varnames": [
+                "ReleaseChannelStable",
+                "ReleaseChannelBeta"
+            ]
+        },
+        "model.ReportFormat": {
+            "type": "string",
+            "enum": [
+                "html",
+                "pdf"
+            ],
+            "x-enum-varnames": [
+                "ReportFormatHTML",
+                "ReportFormatPDF"
+            ]
+        },
+        "model.ReportPeriod": {
+            "type": "string",
+            "enum": [
+                "daily",
+                "weekly"
+            ],
+            "x-enum-varnames": [
+                "ReportPeriodDaily",
+                "ReportPeriodWeekly"
+            ]
+        },
+        "model.ReportSummary": {
+            "type": "object",
+            "properties": {
+                "alerts": {
+                    "type": "integer"
+                },
+                "cameras": {
+                    "type": "integer"
+                },
+                "devices": {
+                    "type": "integer"
+                },
+                "messages": {
+                    "type": "integer"
+                },
+                "offlineDevices": {
+                    "description": "OfflineDevices went offline at least once in the period",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.RolloutStatus": {
+            "type": "string",
+            "enum": [
+                "running",
+                "halted",
+                "completed",
+                "cancelled"
+            ],
+            "x-enum-varnames": [
+                "RolloutStatusRunning",
+                "RolloutStatusHalted",
+                "RolloutStatusCompleted",
+                "RolloutStatusCancelled"
             ]
         },
         "server.ErrorResponse": {
             "type": "object",
             "properties": {
+                "code": {
+                    "description": "错误码，如 validation_failed、not_found",
+                    "type": "string"
+                },
                 "error": {
                     "description": "错误信息",
                     "type": "string"
+                },
+                "fields": {
+                    "description": "校验失败的字段，仅 validation_failed 错误返回",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.FieldError"
+                    }
+                }
+            }
+        },
+        "server.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "失败的校验规则，如 required、max、oneof，类型错误为 type",
+                    "type": "string"
+                },
+                "field": {
+                    "description": "字段路径，与请求 JSON 或查询参数的名称一致，如 channelIds[0]",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "错误说明",
+                    "type": "string"
                 }
             }
         }
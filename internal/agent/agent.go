@@ -88,6 +88,7 @@ type Agent struct {
 	maxIterations int
 	instruction   string
 	logger        *logrus.Entry
+	filter        *OutputFilter
 }
 
 func NewAgent(name string, llmConf LLMConfig, maxIterations int, instruction string) *Agent {
@@ -108,6 +109,12 @@ func (a *Agent) AddTool(tool *Tool) {
 	a.tools[tool.Name] = tool
 }
 
+// SetOutputFilter sets the filter RunStream applies to the thoughts before
+// streaming and returning them, nil disables filtering.
+func (a *Agent) SetOutputFilter(f *OutputFilter) {
+	a.filter = f
+}
+
 func (a *Agent) Run(ctx context.Context, query string) (*LLMMessage, error) {
 	messages := make([]*LLMMessage, 0)
 
@@ -170,7 +177,7 @@ func (a *Agent) Run(ctx context.Context, query string) (*LLMMessage, error) {
 			})
 			continue
 		}
-		toolRes, err := tool.Func(toolCall.Id, toolCall.Args)
+		toolRes, err := tool.Call(ctx, toolCall.Id, toolCall.Args)
 		if err != nil {
 			messages = append(messages, &LLMMessage{
 				Role:    RoleAssistant,
@@ -192,6 +199,9 @@ func (a *Agent) Run(ctx context.Context, query string) (*LLMMessage, error) {
 func (a *Agent) RunStream(ctx context.Context, query string, history []*LLMMessage, w io.Writer) ([]*AgentThought, error) {
 	sseWriter := NewSSEMessageWriter(w)
 	defer sseWriter.Close()
+	// thoughts go to the client through the filter, and are returned for
+	// persistence redacted as well
+	writer := newFilterWriter(sseWriter, a.filter, a.logger)
 
 	messages := make([]*LLMMessage, 0)
 
@@ -226,10 +236,14 @@ func (a *Agent) RunStream(ctx context.Context, query string, history []*LLMMessa
 	agentThoughts := make([]*AgentThought, 0)
 
 	for i := 0; i < a.maxIterations; i++ {
-		response, err := a.llm.ChatCompletionStream(ctx, messages, tools, sseWriter)
+		response, err := a.llm.ChatCompletionStream(ctx, messages, tools, writer)
 		if err != nil {
 			return nil, fmt.Errorf("streaming chat completion failed: %w", err)
 		}
+		answer, err := writer.finish(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write answer: %w", err)
+		}
 
 		messages = append(messages, response)
 
@@ -237,7 +251,7 @@ func (a *Agent) RunStream(ctx context.Context, query string, history []*LLMMessa
 			agentThoughts = append(agentThoughts, &AgentThought{
 				Phase:   ThoughtPhaseThought,
 				ID:      response.ID,
-				Thought: response.Content,
+				Thought: answer,
 			})
 			break
 		}
@@ -247,11 +261,11 @@ func (a *Agent) RunStream(ctx context.Context, query string, history []*LLMMessa
 		thought := &AgentThought{
 			Phase:    ThoughtPhaseTool,
 			ID:       response.ID,
-			Thought:  response.Content,
+			Thought:  answer,
 			ToolCall: &toolCall,
 		}
 
-		err = sseWriter.Write(thought)
+		err = writer.Write(thought)
 		if err != nil {
 			return nil, fmt.Errorf("failed to write tool call: %w", err)
 		}
@@ -265,16 +279,16 @@ func (a *Agent) RunStream(ctx context.Context, query string, history []*LLMMessa
 				ToolCallId: toolCall.Id,
 			})
 			thought.Observation = content
-			err = sseWriter.Write(thought)
+			err = writer.Write(thought)
 			if err != nil {
 				return nil, fmt.Errorf("failed to write tool call: %w", err)
 			}
-			agentThoughts = append(agentThoughts, thought)
+			agentThoughts = append(agentThoughts, a.filter.redactThought(thought))
 			continue
 		}
 
 		a.logger.Debugf("executing tool %s with args: %s", toolCall.ToolName, toolCall.Args)
-		result, err := tool.Func(toolCall.Id, toolCall.Args)
+		result, err := tool.Call(ctx, toolCall.Id, toolCall.Args)
 		thought.Phase = ThoughtPhaseObservation
 		if err != nil {
 			a.logger.Errorf("tool %s execution failed: %v", toolCall.ToolName, err)
@@ -295,11 +309,11 @@ func (a *Agent) RunStream(ctx context.Context, query string, history []*LLMMessa
 			})
 			thought.Observation = content
 		}
-		err = sseWriter.Write(thought)
+		err = writer.Write(thought)
 		if err != nil {
 			return nil, fmt.Errorf("failed to write tool call: %w", err)
 		}
-		agentThoughts = append(agentThoughts, thought)
+		agentThoughts = append(agentThoughts, a.filter.redactThought(thought))
 	}
 
 	return agentThoughts, nil
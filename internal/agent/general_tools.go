@@ -62,7 +62,7 @@ func convertToGoLayout(format string) string {
 	return format
 }
 
-func GetCurrentTime(id string, req *GetCurrentTimeRequest) (*GetCurrentTimeResponse, error) {
+func GetCurrentTime(ctx context.Context, id string, req *GetCurrentTimeRequest) (*GetCurrentTimeResponse, error) {
 	format := req.Format
 	if format == "" {
 		format = "2006-01-02 15:04:05"
@@ -84,8 +84,8 @@ type HttpToolResult struct {
 	Headers    map[string]string `json:"headers"     jsonschema:"description=Response headers"`
 }
 
-func handleHttpRequest(callID string, params HttpToolParams) (*HttpToolResult, error) {
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, params.URL, nil)
+func handleHttpRequest(ctx context.Context, callID string, params HttpToolParams) (*HttpToolResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, params.URL, nil)
 	if err != nil {
 		return nil, err
 	}
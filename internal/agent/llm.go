@@ -20,6 +20,8 @@ type LLMConfig struct {
 	Model       string        `yaml:"model"`
 	Temperature float64       `yaml:"temperature"`
 	Timeout     time.Duration `yaml:"timeout"`
+	// OutputFilter redacts and blocks the answers of agents
+	OutputFilter OutputFilterConfig `yaml:"outputFilter"`
 }
 
 type LLMMessageRole string
@@ -255,7 +257,7 @@ func (w *SSEMessageWriter) Close() error {
 }
 
 // ChatCompletionStream performs streaming chat completion and returns the complete response
-func (llm *LLM) ChatCompletionStream(ctx context.Context, messages []*LLMMessage, tools []*Tool, writer *SSEMessageWriter) (*LLMMessage, error) {
+func (llm *LLM) ChatCompletionStream(ctx context.Context, messages []*LLMMessage, tools []*Tool, writer ThoughtWriter) (*LLMMessage, error) {
 	// Generate unique ID for the stream
 	id := uuid.New().String()
 
@@ -0,0 +1,292 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	redacted = "[REDACTED]"
+	// BlockedAnswer replaces an answer withheld by the output filter
+	BlockedAnswer = "The answer was withheld by the content filter."
+	// minSecretLen avoids redacting common short words given as secrets
+	minSecretLen = 4
+)
+
+// OutputFilterConfig configures the filter applied to the output of the agent
+// before it is streamed to the client and persisted.
+type OutputFilterConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Redact are regular expressions redacted in addition to the builtin
+	// secret patterns, the first capture group of a match is kept if any
+	Redact []string `yaml:"redact"`
+	// Block are regular expressions, an answer matching any is withheld
+	Block []string `yaml:"block"`
+	// ModerationUrl is an OpenAI compatible moderation endpoint, answers it
+	// flags are withheld
+	ModerationUrl     string        `yaml:"moderationUrl"`
+	ModerationApiKey  string        `yaml:"moderationApiKey"`
+	ModerationModel   string        `yaml:"moderationModel"`
+	ModerationTimeout time.Duration `yaml:"moderationTimeout"`
+}
+
+type redactRule struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// builtinRedactRules match secrets lumina hands out or stores: access tokens,
+// JWTs, bearer tokens, credentials in stream urls and key-value passwords.
+// Every rule only replaces text without whitespace, which keeps redaction
+// stable while the answer is streamed word by word.
+var builtinRedactRules = []redactRule{
+	{regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{8,}`), redacted},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), redacted},
+	{regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/=-]{8,}`), "${1}" + redacted},
+	{regexp.MustCompile(`(?i)(\b[a-z][a-z0-9+.-]*://[^\s:/@]+:)[^\s@/]+(@)`), "${1}" + redacted + "${2}"},
+	{regexp.MustCompile(`(?i)(\b(?:password|passwd|pwd|secret|api_?key)\s*[:=]\s*)[^\s,;"'` + "`" + `]+`), "${1}" + redacted},
+}
+
+// OutputFilter redacts secrets from the output of the agent and withholds
+// unsafe answers.
+type OutputFilter struct {
+	redact  []redactRule
+	secrets []string
+	block   []*regexp.Regexp
+	conf    OutputFilterConfig
+	httpCli *http.Client
+}
+
+// NewOutputFilter returns nil if the filter is disabled.
+func NewOutputFilter(conf OutputFilterConfig) (*OutputFilter, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+	f := &OutputFilter{
+		redact: append([]redactRule(nil), builtinRedactRules...),
+		conf:   conf,
+	}
+	for _, p := range conf.Redact {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %s: %w", p, err)
+		}
+		repl := redacted
+		if re.NumSubexp() > 0 {
+			repl = "${1}" + redacted
+		}
+		f.redact = append(f.redact, redactRule{re, repl})
+	}
+	for _, p := range conf.Block {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block pattern %s: %w", p, err)
+		}
+		f.block = append(f.block, re)
+	}
+	if conf.ModerationUrl != "" {
+		timeout := conf.ModerationTimeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		f.httpCli = &http.Client{Timeout: timeout}
+	}
+	return f, nil
+}
+
+// AddSecrets adds literal values to redact, e.g. camera passwords.
+func (f *OutputFilter) AddSecrets(secrets ...string) {
+	for _, s := range secrets {
+		if len(s) >= minSecretLen {
+			f.secrets = append(f.secrets, s)
+		}
+	}
+}
+
+// Redact replaces the secrets in s.
+func (f *OutputFilter) Redact(s string) string {
+	if f == nil || s == "" {
+		return s
+	}
+	for _, secret := range f.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	for _, r := range f.redact {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	return s
+}
+
+// buffered reports whether answers must be held back until they are
+// complete, since they can only be checked as a whole.
+func (f *OutputFilter) buffered() bool {
+	return f != nil && (len(f.block) > 0 || f.conf.ModerationUrl != "")
+}
+
+// Blocked reports whether the answer must be withheld. An answer that cannot
+// be moderated is withheld too.
+func (f *OutputFilter) Blocked(ctx context.Context, answer string) (bool, error) {
+	if f == nil || strings.TrimSpace(answer) == "" {
+		return false, nil
+	}
+	for _, re := range f.block {
+		if re.MatchString(answer) {
+			return true, nil
+		}
+	}
+	if f.conf.ModerationUrl == "" {
+		return false, nil
+	}
+	flagged, err := f.moderate(ctx, answer)
+	if err != nil {
+		return true, err
+	}
+	return flagged, nil
+}
+
+type moderationRequest struct {
+	Model string `json:"model,omitempty"`
+	Input string `json:"input"`
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged bool `json:"flagged"`
+	} `json:"results"`
+}
+
+func (f *OutputFilter) moderate(ctx context.Context, text string) (bool, error) {
+	body, _ := json.Marshal(moderationRequest{Model: f.conf.ModerationModel, Input: text})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.conf.ModerationUrl, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if f.conf.ModerationApiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+f.conf.ModerationApiKey)
+	}
+	resp, err := f.httpCli.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("moderation request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("moderation request failed with status: %d", resp.StatusCode)
+	}
+	var res moderationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return false, fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+	for _, r := range res.Results {
+		if r.Flagged {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// redactThought returns a copy of t with its texts redacted, t is left
+// untouched since its tool call may still be executed.
+func (f *OutputFilter) redactThought(t *AgentThought) *AgentThought {
+	if f == nil {
+		return t
+	}
+	res := *t
+	res.Thought = f.Redact(t.Thought)
+	res.Observation = f.Redact(t.Observation)
+	if t.ToolCall != nil {
+		call := *t.ToolCall
+		call.Args = f.Redact(call.Args)
+		res.ToolCall = &call
+	}
+	return &res
+}
+
+// ThoughtWriter streams the thoughts of the agent to the client.
+type ThoughtWriter interface {
+	Write(m *AgentThought) error
+}
+
+// filterWriter redacts the thoughts before streaming them. The deltas of an
+// answer are held back up to their last whitespace so that no secret is
+// split across two writes, or entirely if the answer must be checked as a
+// whole.
+type filterWriter struct {
+	w      ThoughtWriter
+	f      *OutputFilter
+	logger *logrus.Entry
+	id     string
+	answer strings.Builder
+	// sent is the length of the redacted answer already written
+	sent int
+}
+
+func newFilterWriter(w ThoughtWriter, f *OutputFilter, logger *logrus.Entry) *filterWriter {
+	return &filterWriter{w: w, f: f, logger: logger}
+}
+
+func (w *filterWriter) Write(t *AgentThought) error {
+	if w.f == nil {
+		return w.w.Write(t)
+	}
+	if t.Phase != ThoughtPhaseThought {
+		return w.w.Write(w.f.redactThought(t))
+	}
+
+	w.id = t.ID
+	w.answer.WriteString(t.Thought)
+	if w.f.buffered() {
+		return nil
+	}
+	answer := w.answer.String()
+	cut := strings.LastIndexAny(answer, " \t\r\n")
+	if cut < 0 {
+		return nil
+	}
+	return w.send(w.f.Redact(answer[:cut+1]))
+}
+
+func (w *filterWriter) send(redacted string) error {
+	if len(redacted) <= w.sent {
+		return nil
+	}
+	delta := redacted[w.sent:]
+	w.sent = len(redacted)
+	return w.w.Write(&AgentThought{
+		Phase:   ThoughtPhaseThought,
+		ID:      w.id,
+		Thought: delta,
+	})
+}
+
+// finish writes the rest of the answer streamed since the last call and
+// returns it as it must be persisted: redacted, or replaced if blocked.
+func (w *filterWriter) finish(ctx context.Context) (string, error) {
+	answer := w.answer.String()
+	defer func() {
+		w.answer.Reset()
+		w.sent = 0
+	}()
+	if w.f == nil {
+		return answer, nil
+	}
+
+	blocked, err := w.f.Blocked(ctx, answer)
+	if err != nil {
+		w.logger.WithError(err).Warn("check agent answer failed, withhold it")
+	}
+	if blocked {
+		// in buffered mode nothing of the answer has been written yet
+		return BlockedAnswer, w.send(BlockedAnswer)
+	}
+	redacted := w.f.Redact(answer)
+	return redacted, w.send(redacted)
+}
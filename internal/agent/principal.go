@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrUnauthenticated  = errors.New("tool requires an authenticated user")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Principal is the user on whose behalf the agent runs. Tools reading lumina
+// data must only return what this user could list through the API.
+type Principal struct {
+	UserId  int
+	IsAdmin bool
+	// OrgId is the organization the data of the user is scoped to, 0 for
+	// all of them
+	OrgId int
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal of ctx, nil for anonymous
+// requests.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// ToolAccess is the permission a principal needs to call a tool.
+type ToolAccess int
+
+const (
+	// ToolAccessPublic is for tools not touching lumina data
+	ToolAccessPublic ToolAccess = iota
+	// ToolAccessUser is for tools reading data any user can list
+	ToolAccessUser
+	// ToolAccessAdmin is for tools reading data only admins can access
+	ToolAccessAdmin
+)
+
+func (a ToolAccess) check(p *Principal) error {
+	switch {
+	case a == ToolAccessPublic:
+		return nil
+	case p == nil:
+		return ErrUnauthenticated
+	case a == ToolAccessAdmin && !p.IsAdmin:
+		return ErrPermissionDenied
+	}
+	return nil
+}
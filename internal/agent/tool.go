@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -28,10 +29,12 @@ func (b *BaseToolResult) GetId() string {
 }
 
 type Tool struct {
-	Name             string                                           `json:"name" jsonschema:"description=Tool name"`
-	Description      string                                           `json:"description" jsonschema:"description=Tool description"`
-	ParametersSchema any                                              `json:"parameters_schema" jsonschema:"description=Tool parameters schema"`
-	Func             func(id string, args string) (ToolResult, error) `json:"-"`
+	Name             string                                                                `json:"name" jsonschema:"description=Tool name"`
+	Description      string                                                                `json:"description" jsonschema:"description=Tool description"`
+	ParametersSchema any                                                                   `json:"parameters_schema" jsonschema:"description=Tool parameters schema"`
+	Func             func(ctx context.Context, id string, args string) (ToolResult, error) `json:"-"`
+	// Access is checked against the principal of the context on each call
+	Access ToolAccess `json:"-"`
 }
 
 // MarshalJSON implements custom JSON marshaling for Tool
@@ -44,7 +47,7 @@ func (t *Tool) MarshalJSON() ([]byte, error) {
 	if schema["properties"] != nil {
 		schema = schema["properties"].(map[string]any)
 	}
-	
+
 	return json.Marshal(struct {
 		Name             string         `json:"name"`
 		Description      string         `json:"description"`
@@ -78,24 +81,41 @@ func WithToolDescription(description string) ToolOption {
 	}
 }
 
+func WithToolAccess(access ToolAccess) ToolOption {
+	return func(t *Tool) {
+		t.Access = access
+	}
+}
+
 func WithToolParamsSchema[T any]() ToolOption {
 	return func(t *Tool) {
 		t.ParametersSchema = new(T)
 	}
 }
 
-func WithToolFunc[T any, P ToolResult](f func(id string, args T) (P, error)) ToolOption {
+// WithToolFunc sets the function of the tool. ctx carries the principal the
+// agent runs for, see PrincipalFromContext.
+func WithToolFunc[T any, P ToolResult](f func(ctx context.Context, id string, args T) (P, error)) ToolOption {
 	return func(t *Tool) {
-		t.Func = func(id string, args string) (ToolResult, error) {
+		t.Func = func(ctx context.Context, id string, args string) (ToolResult, error) {
 			var params T
 			if err := json.Unmarshal([]byte(args), &params); err != nil {
 				return nil, err
 			}
-			return f(id, params)
+			return f(ctx, id, params)
 		}
 	}
 }
 
+// Call checks the principal of ctx against the access of the tool and runs
+// it.
+func (t *Tool) Call(ctx context.Context, id string, args string) (ToolResult, error) {
+	if err := t.Access.check(PrincipalFromContext(ctx)); err != nil {
+		return nil, err
+	}
+	return t.Func(ctx, id, args)
+}
+
 func (t *Tool) GetParametersSchema() (map[string]any, error) {
 	schema, err := json.Marshal(reflector.Reflect(t.ParametersSchema))
 	if err != nil {
@@ -0,0 +1,486 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"lumina/internal/model"
+	"lumina/internal/version"
+)
+
+const (
+	// formatVersion 2 keys the rows by column, 1 dumped the JSON of the
+	// models which leaves out their json:"-" columns
+	formatVersion = 2
+	batchSize     = 500
+	manifestName  = "manifest.json"
+	tablesDir     = "tables/"
+	objectsDir    = "objects/"
+)
+
+type TableInfo struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+	// Columns are the columns of the rows in the backup
+	Columns []string `json:"columns,omitempty"`
+}
+
+type PrefixInfo struct {
+	Prefix  string `json:"prefix"`
+	Objects int64  `json:"objects"`
+	Bytes   int64  `json:"bytes"`
+}
+
+type Manifest struct {
+	FormatVersion int          `json:"formatVersion"`
+	AppVersion    string       `json:"appVersion"`
+	CreateTime    time.Time    `json:"createTime"`
+	Tables        []TableInfo  `json:"tables"`
+	Bucket        string       `json:"bucket,omitempty"`
+	Prefixes      []PrefixInfo `json:"prefixes,omitempty"`
+}
+
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+type Options struct {
+	// Passphrase encrypts the archive when not empty.
+	Passphrase string
+	// MinioCli, Bucket and Prefixes describe the objects to list in the
+	// archive, object data itself is not copied.
+	MinioCli *minio.Client
+	Bucket   string
+	Prefixes []string
+}
+
+func tableName(db *gorm.DB, m any) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(m); err != nil {
+		return "", err
+	}
+	return stmt.Schema.Table, nil
+}
+
+// Dump writes a backup of all tables, read from a single repeatable read
+// transaction so that rows referencing each other stay consistent.
+func Dump(ctx context.Context, db *gorm.DB, w io.Writer, opts Options) (*Manifest, error) {
+	out := w
+	var enc io.WriteCloser
+	if opts.Passphrase != "" {
+		var err error
+		if enc, err = NewEncryptWriter(w, opts.Passphrase); err != nil {
+			return nil, err
+		}
+		out = enc
+	}
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	tx := db.WithContext(ctx).Begin(&sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.Rollback()
+
+	manifest := &Manifest{
+		FormatVersion: formatVersion,
+		AppVersion:    version.VERSION,
+		CreateTime:    time.Now(),
+		Bucket:        opts.Bucket,
+	}
+	for _, m := range model.Tables() {
+		name, err := tableName(db, m)
+		if err != nil {
+			return nil, err
+		}
+		var rows int64
+		if err := tx.Model(m).Count(&rows).Error; err != nil {
+			return nil, fmt.Errorf("count %s: %w", name, err)
+		}
+		manifest.Tables = append(manifest.Tables, TableInfo{Name: name, Rows: rows, Columns: columns(tx, m)})
+	}
+
+	var objects [][]ObjectInfo
+	for _, prefix := range opts.Prefixes {
+		info, list, err := listObjects(ctx, opts.MinioCli, opts.Bucket, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("list objects of %s: %w", prefix, err)
+		}
+		manifest.Prefixes = append(manifest.Prefixes, *info)
+		objects = append(objects, list)
+	}
+
+	data, _ := json.MarshalIndent(manifest, "", "  ")
+	if err := writeTarFile(tw, manifestName, data); err != nil {
+		return nil, err
+	}
+
+	for i, m := range model.Tables() {
+		data, err := dumpTable(tx, m)
+		if err != nil {
+			return nil, fmt.Errorf("dump %s: %w", manifest.Tables[i].Name, err)
+		}
+		if err := writeTarFile(tw, tablesDir+manifest.Tables[i].Name+".jsonl", data); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, list := range objects {
+		var sb strings.Builder
+		for _, o := range list {
+			line, _ := json.Marshal(o)
+			sb.Write(line)
+			sb.WriteByte('\n')
+		}
+		name := fmt.Sprintf("%s%d.jsonl", objectsDir, i)
+		if err := writeTarFile(tw, name, []byte(sb.String())); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	if enc != nil {
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// columns returns the columns of the table of the model.
+func columns(db *gorm.DB, m any) []string {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(m); err != nil {
+		return nil
+	}
+	return stmt.Schema.DBNames
+}
+
+// dumpTable writes each row as an object of its column values, including the
+// fields the models keep out of their JSON such as the TOTP secrets.
+func dumpTable(tx *gorm.DB, m any) ([]byte, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(m); err != nil {
+		return nil, err
+	}
+	sch := stmt.Schema
+
+	var sb strings.Builder
+	rows := reflect.New(reflect.SliceOf(reflect.TypeOf(m)))
+	err := tx.Model(m).FindInBatches(rows.Interface(), batchSize, func(batch *gorm.DB, _ int) error {
+		items := rows.Elem()
+		for i := 0; i < items.Len(); i++ {
+			values := make(map[string]any, len(sch.DBNames))
+			for _, name := range sch.DBNames {
+				values[name], _ = sch.FieldsByDBName[name].ValueOf(tx.Statement.Context, items.Index(i).Elem())
+			}
+			line, err := json.Marshal(values)
+			if err != nil {
+				return err
+			}
+			sb.Write(line)
+			sb.WriteByte('\n')
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return []byte(sb.String()), nil
+}
+
+func listObjects(ctx context.Context, cli *minio.Client, bucket, prefix string) (*PrefixInfo, []ObjectInfo, error) {
+	if cli == nil {
+		return nil, nil, errors.New("minio client is not configured")
+	}
+	info := &PrefixInfo{Prefix: prefix}
+	var list []ObjectInfo
+	for obj := range cli.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, nil, obj.Err
+		}
+		list = append(list, ObjectInfo{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			ETag:         obj.ETag,
+			LastModified: obj.LastModified,
+		})
+		info.Objects++
+		info.Bytes += obj.Size
+	}
+	return info, list, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Restore replaces the content of all tables with the rows of the backup.
+// Tables must be empty unless force is set.
+func Restore(ctx context.Context, db *gorm.DB, r io.Reader, passphrase string, force bool) (*Manifest, error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(len(encryptedMagic))
+	in := io.Reader(br)
+	if IsEncrypted(header) {
+		if passphrase == "" {
+			return nil, errors.New("backup is encrypted, passphrase required")
+		}
+		var err error
+		if in, err = NewDecryptReader(br, passphrase); err != nil {
+			return nil, err
+		}
+	}
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	} else if hdr.Name != manifestName {
+		return nil, fmt.Errorf("unexpected first entry %s, not a lumina backup", hdr.Name)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > formatVersion {
+		return nil, fmt.Errorf("unsupported backup format version %d", manifest.FormatVersion)
+	}
+
+	if err := model.Migrate(db, ""); err != nil {
+		return nil, fmt.Errorf("migrate database: %w", err)
+	}
+
+	models := make(map[string]any)
+	for _, m := range model.Tables() {
+		name, err := tableName(db, m)
+		if err != nil {
+			return nil, err
+		}
+		models[name] = m
+	}
+	// refuse the backups of a newer schema rather than drop their columns
+	for _, t := range manifest.Tables {
+		m, ok := models[t.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown table %s in backup", t.Name)
+		}
+		known := columns(db, m)
+		for _, c := range t.Columns {
+			if !slices.Contains(known, c) {
+				return nil, fmt.Errorf("unknown column %s of table %s in backup", c, t.Name)
+			}
+		}
+	}
+
+	dumped := make(map[string][]string, len(manifest.Tables))
+	for _, t := range manifest.Tables {
+		dumped[t.Name] = t.Columns
+	}
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for name, m := range models {
+			var rows int64
+			if err := tx.Model(m).Count(&rows).Error; err != nil {
+				return err
+			}
+			if rows > 0 && !force {
+				return fmt.Errorf("table %s is not empty, use force to overwrite", name)
+			}
+			if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(m).Error; err != nil {
+				return fmt.Errorf("clear %s: %w", name, err)
+			}
+		}
+
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if !strings.HasPrefix(hdr.Name, tablesDir) {
+				continue
+			}
+			name := strings.TrimSuffix(strings.TrimPrefix(hdr.Name, tablesDir), ".jsonl")
+			m, ok := models[name]
+			if !ok {
+				return fmt.Errorf("unknown table %s in backup", name)
+			}
+			if err := restoreTable(tx, m, tr, manifest.FormatVersion, dumped[name]); err != nil {
+				return fmt.Errorf("restore %s: %w", name, err)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// restoreTable inserts rows as column maps, so that zero values are written
+// as they are instead of being replaced by the column defaults. The rows of a
+// version 2 backup must have the columns of their table in the manifest, the
+// columns of the model missing from it get their defaults.
+func restoreTable(tx *gorm.DB, m any, r io.Reader, version int, cols []string) error {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(m); err != nil {
+		return err
+	}
+	sch := stmt.Schema
+
+	batch := make([]map[string]any, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := tx.Table(sch.Table).Create(&batch).Error; err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	typ := reflect.TypeOf(m).Elem()
+	dec := json.NewDecoder(r)
+	for dec.More() {
+		var values map[string]any
+		var err error
+		if version == 1 {
+			values, err = decodeModelRow(tx, sch, typ, dec)
+		} else {
+			values, err = decodeColumnRow(sch, dec, cols)
+		}
+		if err != nil {
+			return err
+		}
+		batch = append(batch, values)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// decodeColumnRow decodes a row dumped as its column values.
+func decodeColumnRow(sch *schema.Schema, dec *json.Decoder, cols []string) (map[string]any, error) {
+	var raw map[string]json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	for _, name := range cols {
+		if _, ok := raw[name]; !ok {
+			return nil, fmt.Errorf("row without column %s", name)
+		}
+	}
+	if len(raw) != len(cols) {
+		return nil, fmt.Errorf("row with %d columns, %d dumped", len(raw), len(cols))
+	}
+	values := make(map[string]any, len(raw))
+	for name, data := range raw {
+		field, ok := sch.FieldsByDBName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %s", name)
+		}
+		v := reflect.New(field.FieldType)
+		if err := json.Unmarshal(data, v.Interface()); err != nil {
+			return nil, fmt.Errorf("decode column %s: %w", name, err)
+		}
+		values[name] = v.Elem().Interface()
+	}
+	return values, nil
+}
+
+// decodeModelRow decodes a row of a version 1 backup, dumped as the JSON of
+// its model.
+func decodeModelRow(tx *gorm.DB, sch *schema.Schema, typ reflect.Type, dec *json.Decoder) (map[string]any, error) {
+	row := reflect.New(typ)
+	if err := dec.Decode(row.Interface()); err != nil {
+		return nil, err
+	}
+	values := make(map[string]any, len(sch.DBNames))
+	for _, name := range sch.DBNames {
+		values[name], _ = sch.FieldsByDBName[name].ValueOf(tx.Statement.Context, row.Elem())
+	}
+	return values, nil
+}
+
+const filePrefix = "lumina-backup-"
+
+// FileName returns the name of a backup created at t.
+func FileName(t time.Time, encrypted bool) string {
+	name := filePrefix + t.UTC().Format("20060102T150405Z") + ".tar.gz"
+	if encrypted {
+		name += ".enc"
+	}
+	return name
+}
+
+// Prune removes the backups in dir beyond the newest keep ones and those older
+// than maxAge, a zero value disables the corresponding rule.
+func Prune(dir string, keep int, maxAge time.Duration) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, filePrefix+"*.tar.gz*"))
+	if err != nil {
+		return nil, err
+	}
+	// names embed the UTC creation time, so lexical order is creation order
+	var removed []string
+	for i := range matches {
+		path := matches[len(matches)-1-i]
+		expired := keep > 0 && i >= keep
+		if maxAge > 0 {
+			if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > maxAge {
+				expired = true
+			}
+		}
+		if !expired {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
@@ -0,0 +1,191 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted archives are a sequence of AES-256-GCM sealed chunks so that large
+// dumps never have to be held in memory:
+//
+//	magic | salt | nonce prefix | (chunk length | sealed chunk)* | 0
+//
+// The nonce of each chunk is the prefix followed by the chunk counter, the
+// zero length trailer protects against truncation.
+var encryptedMagic = []byte("LUMBAK1\n")
+
+const (
+	saltSize        = 16
+	noncePrefixSize = 4
+	chunkSize       = 64 * 1024
+)
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint64
+	buf     bytes.Buffer
+}
+
+// NewEncryptWriter returns a writer encrypting everything written to it with a
+// key derived from passphrase. Close must be called to flush the last chunk.
+func NewEncryptWriter(w io.Writer, passphrase string) (io.WriteCloser, error) {
+	header := make([]byte, len(encryptedMagic)+saltSize+noncePrefixSize)
+	copy(header, encryptedMagic)
+	if _, err := rand.Read(header[len(encryptedMagic):]); err != nil {
+		return nil, err
+	}
+	salt := header[len(encryptedMagic) : len(encryptedMagic)+saltSize]
+	prefix := header[len(encryptedMagic)+saltSize:]
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, prefix: prefix}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	n, _ := e.buf.Write(p)
+	for e.buf.Len() >= chunkSize {
+		if err := e.seal(e.buf.Next(chunkSize)); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func (e *encryptWriter) Close() error {
+	if e.buf.Len() > 0 {
+		if err := e.seal(e.buf.Next(e.buf.Len())); err != nil {
+			return err
+		}
+	}
+	return binary.Write(e.w, binary.BigEndian, uint32(0))
+}
+
+func (e *encryptWriter) seal(chunk []byte) error {
+	sealed := e.aead.Seal(nil, e.nonce(), chunk, nil)
+	e.counter++
+	if err := binary.Write(e.w, binary.BigEndian, uint32(len(sealed))); err != nil {
+		return err
+	}
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+func (e *encryptWriter) nonce() []byte {
+	nonce := make([]byte, e.aead.NonceSize())
+	copy(nonce, e.prefix)
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], e.counter)
+	return nonce
+}
+
+type decryptReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint64
+	buf     bytes.Buffer
+	done    bool
+}
+
+// NewDecryptReader returns a reader decrypting an archive written by
+// NewEncryptWriter.
+func NewDecryptReader(r io.Reader, passphrase string) (io.Reader, error) {
+	header := make([]byte, len(encryptedMagic)+saltSize+noncePrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	if !bytes.Equal(header[:len(encryptedMagic)], encryptedMagic) {
+		return nil, errors.New("not an encrypted backup")
+	}
+	salt := header[len(encryptedMagic) : len(encryptedMagic)+saltSize]
+	prefix := header[len(encryptedMagic)+saltSize:]
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: r, aead: aead, prefix: prefix}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for d.buf.Len() == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	return d.buf.Read(p)
+}
+
+func (d *decryptReader) open() error {
+	var size uint32
+	if err := binary.Read(d.r, binary.BigEndian, &size); err != nil {
+		return fmt.Errorf("read chunk: %w", io.ErrUnexpectedEOF)
+	}
+	if size == 0 {
+		d.done = true
+		return nil
+	}
+	if size > chunkSize+uint32(d.aead.Overhead()) {
+		return errors.New("corrupted backup: chunk too large")
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return fmt.Errorf("read chunk: %w", err)
+	}
+
+	nonce := make([]byte, d.aead.NonceSize())
+	copy(nonce, d.prefix)
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], d.counter)
+	d.counter++
+
+	chunk, err := d.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return errors.New("decrypt backup failed, wrong passphrase or corrupted file")
+	}
+	d.buf.Write(chunk)
+	return nil
+}
+
+// IsEncrypted reports whether the header read from an archive belongs to an
+// encrypted backup.
+func IsEncrypted(header []byte) bool {
+	return bytes.HasPrefix(header, encryptedMagic)
+}
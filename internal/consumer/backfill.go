@@ -0,0 +1,162 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+
+	"lumina/internal/model"
+)
+
+// influxMessagePoints returns the points a processed message adds to the
+// message, alert and detection measurements, severity being the one of its
+// alert if it alerted.
+func influxMessagePoints(job *model.Job, m *model.Message, severity model.AlertSeverity) []*write.Point {
+	// alerted is summed to get the alert trends
+	alerted := 0
+	if m.Alerted {
+		alerted = 1
+	}
+	points := []*write.Point{
+		influxdb2.NewPoint(influxMeasurementMessage,
+			map[string]string{
+				"job_uuid": job.Uuid,
+				"job_kind": string(job.Kind),
+			},
+			map[string]any{"count": 1, "alerted": alerted},
+			m.Timestamp),
+	}
+	if m.Alerted {
+		points = append(points, influxdb2.NewPoint(influxMeasurementAlert,
+			map[string]string{
+				"job_uuid": job.Uuid,
+				"job_kind": string(job.Kind),
+				"severity": string(severity),
+			},
+			map[string]any{"count": 1},
+			m.Timestamp))
+	}
+
+	if job.Kind != model.JobKindDetect {
+		return points
+	}
+	for _, box := range m.DetectBoxes {
+		if box == nil {
+			continue
+		}
+		tags := map[string]string{
+			"job_uuid": job.Uuid,
+			"label":    box.Label,
+		}
+		fields := map[string]any{
+			"confidence": box.Confidence,
+			"x1":         box.X1,
+			"y1":         box.Y1,
+			"x2":         box.X2,
+			"y2":         box.Y2,
+		}
+		points = append(points, influxdb2.NewPoint(influxMeasurementDetection, tags, fields, m.Timestamp))
+	}
+	return points
+}
+
+type BackfillOptions struct {
+	// From and To bound the time the messages were taken
+	From time.Time
+	To   time.Time
+	// BatchSize is the number of messages read and written at once
+	BatchSize int
+	// Progress is called after each batch with the messages replayed so far
+	// and the messages in the range
+	Progress func(done, total int64)
+}
+
+// BackfillInflux replays the messages of the database into the message,
+// alert and detection measurements, so that the trends cover the messages processed
+// before InfluxDB was enabled. Points identical to the ones written by the
+// consumer are overwritten, replaying a range twice is harmless. The
+// occupancy reports are not stored and cannot be replayed.
+func BackfillInflux(ctx context.Context, conf InfluxDBConfig, opts BackfillOptions) (int64, error) {
+	if opts.BatchSize <= 0 {
+		return 0, fmt.Errorf("invalid batch size %d", opts.BatchSize)
+	}
+	total, err := model.CountMessagesBetween(opts.From, opts.To)
+	if err != nil {
+		return 0, err
+	}
+
+	client := influxdb2.NewClient(conf.URL, conf.Token)
+	defer client.Close()
+	writeAPI := client.WriteAPIBlocking(conf.Org, conf.Bucket)
+
+	// nil for the deleted jobs, their messages have no tags to replay
+	jobs := make(map[int]*model.Job)
+	var done int64
+	afterId := 0
+	for {
+		ms, err := model.ListMessagesBetween(opts.From, opts.To, afterId, opts.BatchSize)
+		if err != nil {
+			return done, err
+		} else if len(ms) == 0 {
+			return done, nil
+		}
+
+		severities, err := alertSeverities(ms)
+		if err != nil {
+			return done, err
+		}
+		var points []*write.Point
+		for _, m := range ms {
+			job, ok := jobs[m.JobId]
+			if !ok {
+				if job, err = model.GetJobById(m.JobId); err != nil {
+					return done, err
+				}
+				jobs[m.JobId] = job
+			}
+			if job != nil {
+				points = append(points, influxMessagePoints(job, m, severities[m.Id])...)
+			}
+		}
+		if len(points) > 0 {
+			if err := writeAPI.WritePoint(ctx, points...); err != nil {
+				return done, fmt.Errorf("write messages after %d failed: %w", afterId, err)
+			}
+		}
+
+		afterId = ms[len(ms)-1].Id
+		done += int64(len(ms))
+		if opts.Progress != nil {
+			opts.Progress(done, total)
+		}
+	}
+}
+
+// alertSeverities returns the severities of the alerted messages: the one of
+// their alert, or the one of their confidence for the occurrences an alert
+// does not keep.
+func alertSeverities(ms []*model.Message) (map[int]model.AlertSeverity, error) {
+	var ids []int
+	for _, m := range ms {
+		if m.Alerted {
+			ids = append(ids, m.Id)
+		}
+	}
+	severities, err := model.GetAlertSeveritiesByMessageIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range ms {
+		if _, ok := severities[m.Id]; !ok && m.Alerted {
+			var confidence float32
+			if m.WorkflowResp != nil {
+				confidence = m.WorkflowResp.Confidence
+			}
+			severities[m.Id] = model.SeverityFromConfidence(confidence)
+		}
+	}
+	return severities, nil
+}
@@ -6,6 +6,10 @@ import (
 	"os"
 
 	"gopkg.in/yaml.v2"
+
+	"lumina/internal/embedding"
+	"lumina/pkg/profile"
+	"lumina/pkg/tracing"
 )
 
 type NSQConfig struct {
@@ -35,11 +39,31 @@ type InfluxDBConfig struct {
 	Enabled bool   `yaml:"enabled"`
 }
 
+// MetricsConfig configures the HTTP listener exposing the processing
+// metrics in the Prometheus text format on /metrics.
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"`
+}
+
+// SinksConfig enables the optional stores the processed messages are copied
+// to.
+type SinksConfig struct {
+	Elasticsearch ElasticsearchSinkConfig `yaml:"elasticsearch"`
+	ClickHouse    ClickHouseSinkConfig    `yaml:"clickhouse"`
+	// Embedding stores the vectors of the messages for the semantic search,
+	// the server must be configured with the same model and store
+	Embedding EmbeddingSinkConfig `yaml:"embedding"`
+}
+
 type Config struct {
 	NSQ      NSQConfig      `yaml:"nsq"`
 	S3       S3Config       `yaml:"s3"`
 	DB       model.DBConfig `yaml:"db"`
 	InfluxDB InfluxDBConfig `yaml:"influxdb"`
+	Sinks    SinksConfig    `yaml:"sinks"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Tracing  tracing.Config `yaml:"tracing"`
 }
 
 func DefaultConfig() *Config {
@@ -62,10 +86,38 @@ func DefaultConfig() *Config {
 			Token:   "",
 			Enabled: false,
 		},
+		Sinks: SinksConfig{
+			Elasticsearch: ElasticsearchSinkConfig{
+				Enabled: false,
+				URL:     "http://127.0.0.1:9200",
+				Index:   "lumina-messages-{2006.01}",
+				Queue:   DefaultSinkQueueConfig(),
+			},
+			ClickHouse: ClickHouseSinkConfig{
+				Enabled:     false,
+				URL:         "http://127.0.0.1:8123",
+				Database:    "default",
+				Table:       "lumina_messages",
+				CreateTable: true,
+				Queue:       DefaultSinkQueueConfig(),
+			},
+			Embedding: EmbeddingSinkConfig{
+				Enabled: false,
+				Config:  embedding.DefaultConfig(),
+				Queue:   DefaultSinkQueueConfig(),
+			},
+		},
+		Metrics: MetricsConfig{
+			Enabled: false,
+			Addr:    ":18492",
+		},
+		Tracing: tracing.DefaultConfig(),
 	}
 }
 
-func LoadConfig(configPath string) (*Config, error) {
+// LoadConfig loads the config at configPath and merges the overlay of
+// profileName over it, if any.
+func LoadConfig(configPath, profileName string) (*Config, error) {
 	conf := DefaultConfig()
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -75,6 +127,9 @@ func LoadConfig(configPath string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal config file: %v", err)
 	}
+	if err := profile.Apply(configPath, profileName, conf); err != nil {
+		return nil, err
+	}
 
 	return conf, nil
 }
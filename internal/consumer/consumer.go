@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -11,10 +13,12 @@ import (
 	"github.com/influxdata/influxdb-client-go/v2/api"
 	"github.com/nsqio/go-nsq"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 
 	"lumina/internal/dao"
 	"lumina/internal/model"
 	"lumina/pkg/log"
+	"lumina/pkg/tracing"
 )
 
 type Consumer struct {
@@ -28,6 +32,10 @@ type Consumer struct {
 	// influx
 	influxClient influxdb2.Client
 	writeAPI     api.WriteAPIBlocking
+	sinks        []*sinkQueue
+	// metrics is nil if the metrics listener is disabled
+	metrics    *Metrics
+	metricsSrv *http.Server
 }
 
 func NewConsumer(conf *Config) (*Consumer, error) {
@@ -54,6 +62,9 @@ func NewConsumer(conf *Config) (*Consumer, error) {
 		logger:          logger,
 		workflowManager: NewWorkflowManager(ctx),
 	}
+	if conf.Metrics.Enabled {
+		c.metrics = NewMetrics()
+	}
 
 	// init influxdb client if enabled
 	if conf.InfluxDB.Enabled {
@@ -62,20 +73,39 @@ func NewConsumer(conf *Config) (*Consumer, error) {
 		c.writeAPI = client.WriteAPIBlocking(conf.InfluxDB.Org, conf.InfluxDB.Bucket)
 	}
 
+	if err := c.initSinks(); err != nil {
+		cancel()
+		return nil, err
+	}
+
 	consumer.AddHandler(c)
 
 	return c, nil
 }
 
-func (c *Consumer) HandleMessage(message *nsq.Message) error {
+func (c *Consumer) HandleMessage(message *nsq.Message) (err error) {
 	c.logger.Debugf("Received NSQ message: %s", string(message.Body))
 	message.DisableAutoResponse()
+	if message.Attempts > 1 {
+		c.metrics.countRequeue()
+	}
+	result := messageProcessed
+	defer func() {
+		if err != nil {
+			result = messageFailed
+		}
+		c.metrics.countMessage(result)
+	}()
 
 	var msg dao.DeviceMessage
 	if err := json.Unmarshal(message.Body, &msg); err != nil {
 		c.logger.WithError(err).Error("Failed to unmarshal NSQ message")
 		return err
 	}
+	// continue the trace of the upload on the device
+	ctx, span := tracing.Start(tracing.Extract(c.ctx, msg.TraceContext), "consumer.handle",
+		attribute.String("lumina.job_uuid", msg.JobUuid), attribute.Int("nsq.attempts", int(message.Attempts)))
+	defer func() { tracing.End(span, err) }()
 
 	c.logger.WithFields(logrus.Fields{
 		"jobUuid":   msg.JobUuid,
@@ -89,25 +119,46 @@ func (c *Consumer) HandleMessage(message *nsq.Message) error {
 		c.logger.WithError(err).Errorf("Failed to get job by uuid %s", msg.JobUuid)
 		return err
 	} else if job == nil {
+		result = messageSkipped
 		message.Finish()
 		return nil
 	}
 
+	// occupancy reports carry no media, they only feed the analytics
+	if len(msg.Occupancy) > 0 {
+		c.writeInfluxOccupancy(job, &msg)
+		if msg.ImagePath == "" && msg.VideoPath == "" {
+			result = messageSkipped
+			message.Finish()
+			return nil
+		}
+	}
+
 	wf, err := job.Workflow()
 	if err != nil {
 		c.logger.WithError(err).Errorf("Failed to get workflow for job %s", msg.JobUuid)
 		return err
 	} else if wf == nil {
+		result = messageSkipped
 		message.Finish()
 		return nil
 	}
 
 	var resp *OpenAIResponse
-	if job.Kind == model.JobKindVideoSegment {
+	start := time.Now()
+	_, wfSpan := tracing.Start(ctx, "consumer.workflow", attribute.String("lumina.model", wf.ModelName))
+	// custom job kinds may publish either images or videos
+	if job.Kind == model.JobKindVideoSegment || msg.VideoPath != "" {
 		resp, err = c.workflowManager.VideoCompletion(wf, c.conf.S3.UrlPrefix()+msg.VideoPath)
+		c.metrics.observeWorkflow(wf.ModelName, "video", time.Since(start), resp)
 	} else {
 		resp, err = c.workflowManager.ImageCompletion(wf, c.conf.S3.UrlPrefix()+msg.ImagePath, msg.DetectBoxes)
+		c.metrics.observeWorkflow(wf.ModelName, "image", time.Since(start), resp)
+	}
+	if resp != nil {
+		wfSpan.SetAttributes(attribute.Int("lumina.total_tokens", resp.Usage.TotalTokens))
 	}
+	tracing.End(wfSpan, err)
 	if err != nil {
 		c.logger.WithError(err).Errorf("Failed to call WorkflowManager API for job %s", msg.JobUuid)
 		return err
@@ -123,71 +174,114 @@ func (c *Consumer) HandleMessage(message *nsq.Message) error {
 		Match:       answer.Match,
 		RawContent:  resp.Choices[0].Message.Content,
 	}
-	// if wf.ResultFilter != nil && wf.ResultFilter.Match(answer) {
-	// 	m.Alerted = true
-	// }
-	if answer.Match {
+	// the result filter replaces the plain match of the answer, so that
+	// alerts can also depend on the labels, the time or the sensor readings
+	fields := filterFields(&msg, answer)
+	if filter := job.AlertFilter(wf); filter != nil {
+		m.Alerted = filter.MatchFields(fields, answer.Reason)
+	} else if answer.Match {
 		m.Alerted = true
 	}
-	if err := model.AddMessage(m); err != nil {
+	severity := job.AlertSeverity(fields, answer.Reason, answer.Confidence)
+	_, dbSpan := tracing.Start(ctx, "consumer.add_message", attribute.Bool("lumina.alerted", m.Alerted))
+	err = model.AddMessage(m, severity, job.AlertDedupDuration())
+	tracing.End(dbSpan, err)
+	if err != nil {
 		c.logger.WithError(err).Errorf("Failed to add message to DB for job %s", msg.JobUuid)
 		return err
 	}
+	span.SetAttributes(attribute.Int("lumina.message_id", m.Id))
 
 	// write event to influxdb
-	c.writeInfluxEvents(job, &msg)
+	c.writeInfluxEvents(job, m, severity)
+	c.writeSinks(job, m)
 
 	message.Finish()
 	c.logger.Debugf("Successfully processed message for job %s", msg.JobUuid)
 	return nil
 }
 
-func (c *Consumer) writeInfluxEvents(job *model.Job, msg *dao.DeviceMessage) {
+// LogFailedMessage is called by nsq for the messages dropped after their
+// last attempt.
+func (c *Consumer) LogFailedMessage(message *nsq.Message) {
+	c.logger.Errorf("Give up NSQ message %s after %d attempts", message.ID, message.Attempts)
+	c.metrics.countGiveUp()
+}
+
+func (c *Consumer) initSinks() error {
+	var sinks []Sink
+	var confs []SinkQueueConfig
+	if conf := c.conf.Sinks.Elasticsearch; conf.Enabled {
+		sinks = append(sinks, newElasticsearchSink(conf))
+		confs = append(confs, conf.Queue)
+	}
+	if conf := c.conf.Sinks.ClickHouse; conf.Enabled {
+		sinks = append(sinks, newClickHouseSink(conf))
+		confs = append(confs, conf.Queue)
+	}
+	if conf := c.conf.Sinks.Embedding; conf.Enabled {
+		sink, err := newEmbeddingSink(conf, c.conf.S3.UrlPrefix())
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+		confs = append(confs, conf.Queue)
+	}
+	for i, sink := range sinks {
+		q, err := newSinkQueue(c.logger, sink, confs[i])
+		if err != nil {
+			return err
+		}
+		c.sinks = append(c.sinks, q)
+	}
+	return nil
+}
+
+// writeSinks queues the message for the enabled sinks, it blocks while a
+// sink with the block overflow policy is full.
+func (c *Consumer) writeSinks(job *model.Job, m *model.Message) {
+	if len(c.sinks) == 0 {
+		return
+	}
+	r := newSinkRecord(job, m)
+	for _, q := range c.sinks {
+		q.enqueue(c.ctx, r)
+	}
+}
+
+func (c *Consumer) writeInfluxEvents(job *model.Job, m *model.Message, severity model.AlertSeverity) {
+	if c.writeAPI == nil || !c.conf.InfluxDB.Enabled {
+		return
+	}
+	if err := c.writeAPI.WritePoint(c.ctx, influxMessagePoints(job, m, severity)...); err != nil {
+		c.logger.WithError(err).Warn("Failed to write message events to InfluxDB")
+	}
+}
+
+func (c *Consumer) writeInfluxOccupancy(job *model.Job, msg *dao.DeviceMessage) {
 	if c.writeAPI == nil || !c.conf.InfluxDB.Enabled {
 		return
 	}
 
-	// Event time derived from message timestamp (see dao conversion logic)
-	// Note: original timestamp unit may be microseconds; follow existing model conversion
 	evtTime := time.Unix(msg.Timestamp/1000000000, msg.Timestamp%1000000000)
-	if evtTime.IsZero() {
-		evtTime = time.Now()
-	}
-
-	// Base message event
-	baseTags := map[string]string{
-		"job_uuid": string(job.Uuid),
-		"job_kind": string(job.Kind),
-	}
-	baseFields := map[string]any{
-		"count": 1,
-	}
-	p := influxdb2.NewPoint(influxMeasurementMessage, baseTags, baseFields, evtTime)
-	if err := c.writeAPI.WritePoint(c.ctx, p); err != nil {
-		c.logger.WithError(err).Warn("Failed to write message event to InfluxDB")
-	}
-
-	// Detection boxes events if detection job
-	if job.Kind == model.JobKindDetect && len(msg.DetectBoxes) > 0 {
-		for _, box := range msg.DetectBoxes {
-			if box == nil {
-				continue
-			}
-			tags := map[string]string{
-				"job_uuid": job.Uuid,
-				"label":    box.Label,
-			}
-			fields := map[string]any{
-				"confidence": box.Confidence,
-				"x1":         box.X1,
-				"y1":         box.Y1,
-				"x2":         box.X2,
-				"y2":         box.Y2,
-			}
-			pbox := influxdb2.NewPoint(influxMeasurementDetection, tags, fields, evtTime)
-			if err := c.writeAPI.WritePoint(c.ctx, pbox); err != nil {
-				c.logger.WithError(err).Warn("Failed to write detection event to InfluxDB")
-			}
+	for _, o := range msg.Occupancy {
+		if o == nil {
+			continue
+		}
+		tags := map[string]string{
+			"job_uuid":  job.Uuid,
+			"camera_id": strconv.Itoa(job.CameraId),
+			"zone":      o.Zone,
+		}
+		fields := map[string]any{
+			"count": o.Count,
+			"max":   o.Max,
+			"in":    o.In,
+			"out":   o.Out,
+		}
+		p := influxdb2.NewPoint(influxMeasurementOccupancy, tags, fields, evtTime)
+		if err := c.writeAPI.WritePoint(c.ctx, p); err != nil {
+			c.logger.WithError(err).Warn("Failed to write occupancy event to InfluxDB")
 		}
 	}
 }
@@ -195,6 +289,16 @@ func (c *Consumer) writeInfluxEvents(job *model.Job, msg *dao.DeviceMessage) {
 func (c *Consumer) Start() error {
 	c.logger.Info("Starting NSQ consumer...")
 
+	for _, q := range c.sinks {
+		q.start(c.ctx)
+	}
+
+	if c.metrics != nil {
+		if err := c.startMetricsServer(); err != nil {
+			return err
+		}
+	}
+
 	err := c.consumer.ConnectToNSQDs(c.conf.NSQ.NSQDAddrs)
 	if err != nil {
 		return fmt.Errorf("failed to connect to NSQs: %w", err)
@@ -205,6 +309,8 @@ func (c *Consumer) Start() error {
 		defer c.wg.Done()
 		<-c.ctx.Done()
 		c.consumer.Stop()
+		// the handlers are done once stopped, none writes to the sinks
+		<-c.consumer.StopChan
 	}()
 
 	return nil
@@ -213,10 +319,19 @@ func (c *Consumer) Start() error {
 func (c *Consumer) Stop() {
 	c.cancel()
 	c.wg.Wait()
+	// the consumer has stopped, flush what the sinks still hold
+	for _, q := range c.sinks {
+		q.stop()
+	}
 	if c.influxClient != nil {
 		c.influxClient.Close()
 	}
+	if c.metricsSrv != nil {
+		c.stopMetricsServer()
+	}
 }
 
 const influxMeasurementMessage = "lumina_message"
 const influxMeasurementDetection = "lumina_detection"
+const influxMeasurementOccupancy = "lumina_occupancy"
+const influxMeasurementAlert = "lumina_alert"
@@ -0,0 +1,65 @@
+package consumer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"lumina/internal/dao"
+)
+
+// filterFields returns the fields of a processed message that the result
+// filter of a workflow can match:
+//
+//   - match, confidence and answer (or reason): the workflow answer
+//   - labels: the comma separated labels of the detection boxes
+//   - hour and time: the local hour and "15:04" time of the message
+//   - sensors.<name>: the device sensor readings
+//   - metadata.<key>: the fields added by the device hooks
+func filterFields(msg *dao.DeviceMessage, answer Answer) map[string]string {
+	ts := time.Unix(0, msg.Timestamp).Local()
+	fields := map[string]string{
+		"match":      strconv.FormatBool(answer.Match),
+		"confidence": strconv.FormatFloat(float64(answer.Confidence), 'f', -1, 32),
+		"answer":     answer.Reason,
+		"reason":     answer.Reason,
+		"hour":       strconv.Itoa(ts.Hour()),
+		"time":       ts.Format("15:04"),
+	}
+
+	labels := make([]string, 0, len(msg.DetectBoxes))
+	for _, box := range msg.DetectBoxes {
+		if box != nil && box.Label != "" {
+			labels = append(labels, box.Label)
+		}
+	}
+	fields["labels"] = strings.Join(labels, ",")
+
+	for name, value := range msg.Sensors {
+		fields["sensors."+name] = fieldValue(value)
+	}
+	for key, value := range msg.Metadata {
+		fields["metadata."+key] = fieldValue(value)
+	}
+	return fields
+}
+
+func fieldValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]any, []any:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
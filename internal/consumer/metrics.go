@@ -0,0 +1,234 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"lumina/pkg/metrics"
+)
+
+// metricsWorkflowBuckets are the upper bounds of the buckets of the workflow
+// latency histogram, in seconds, VLM calls taking seconds rather than
+// milliseconds.
+var metricsWorkflowBuckets = []float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60}
+
+const (
+	messageProcessed = "processed"
+	// messageSkipped messages are finished without a workflow call, e.g.
+	// those of deleted jobs or the occupancy reports
+	messageSkipped = "skipped"
+	messageFailed  = "failed"
+)
+
+type histogram struct {
+	// counts holds the observations of each bucket, not cumulated
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, le := range metricsWorkflowBuckets {
+		if v <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+type workflowKey struct {
+	model  string
+	media  string
+	failed bool
+}
+
+type tokensKey struct {
+	model string
+	kind  string
+}
+
+// Metrics collects the processing metrics of the consumer that the metrics
+// listener exposes in the Prometheus text format. A nil Metrics records
+// nothing.
+type Metrics struct {
+	mu        sync.Mutex
+	messages  map[string]uint64
+	workflows map[workflowKey]*histogram
+	tokens    map[tokensKey]uint64
+	requeues  uint64
+	giveUps   uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		messages:  make(map[string]uint64),
+		workflows: make(map[workflowKey]*histogram),
+		tokens:    make(map[tokensKey]uint64),
+	}
+}
+
+func (m *Metrics) countMessage(result string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.messages[result]++
+	m.mu.Unlock()
+}
+
+// countRequeue records a message delivered again, after its previous attempt
+// failed or timed out.
+func (m *Metrics) countRequeue() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.requeues++
+	m.mu.Unlock()
+}
+
+// countGiveUp records a message dropped after its last attempt.
+func (m *Metrics) countGiveUp() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.giveUps++
+	m.mu.Unlock()
+}
+
+// observeWorkflow records a workflow call and the tokens it used, resp is
+// nil if it failed.
+func (m *Metrics) observeWorkflow(model, media string, latency time.Duration, resp *OpenAIResponse) {
+	if m == nil {
+		return
+	}
+	key := workflowKey{model: model, media: media, failed: resp == nil}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.workflows[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(metricsWorkflowBuckets))}
+		m.workflows[key] = h
+	}
+	h.observe(latency.Seconds())
+	if resp != nil {
+		m.tokens[tokensKey{model: model, kind: "prompt"}] += uint64(resp.Usage.PromptTokens)
+		m.tokens[tokensKey{model: model, kind: "completion"}] += uint64(resp.Usage.CompletionTokens)
+	}
+}
+
+func (m *Metrics) write(w *metrics.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Family("lumina_consumer_messages_total", "counter", "NSQ messages handled by result.")
+	for _, result := range []string{messageProcessed, messageSkipped, messageFailed} {
+		w.Sample("lumina_consumer_messages_total", float64(m.messages[result]), "result", result)
+	}
+	w.Family("lumina_consumer_requeues_total", "counter", "NSQ messages delivered again after a failed or timed out attempt.")
+	w.Sample("lumina_consumer_requeues_total", float64(m.requeues))
+	w.Family("lumina_consumer_give_ups_total", "counter", "NSQ messages dropped after their last attempt.")
+	w.Sample("lumina_consumer_give_ups_total", float64(m.giveUps))
+
+	w.Family("lumina_consumer_workflow_duration_seconds", "histogram", "Latency of the workflow calls by model, media and result.")
+	keys := make([]workflowKey, 0, len(m.workflows))
+	for k := range m.workflows {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.model != b.model {
+			return a.model < b.model
+		}
+		if a.media != b.media {
+			return a.media < b.media
+		}
+		return !a.failed && b.failed
+	})
+	for _, k := range keys {
+		h := m.workflows[k]
+		result := "ok"
+		if k.failed {
+			result = "error"
+		}
+		labels := []string{"model", k.model, "media", k.media, "result", result}
+		var cumulated uint64
+		for i, le := range metricsWorkflowBuckets {
+			cumulated += h.counts[i]
+			w.Sample("lumina_consumer_workflow_duration_seconds_bucket", float64(cumulated),
+				append(labels, "le", strconv.FormatFloat(le, 'g', -1, 64))...)
+		}
+		w.Sample("lumina_consumer_workflow_duration_seconds_bucket", float64(h.count), append(labels, "le", "+Inf")...)
+		w.Sample("lumina_consumer_workflow_duration_seconds_sum", h.sum, labels...)
+		w.Sample("lumina_consumer_workflow_duration_seconds_count", float64(h.count), labels...)
+	}
+
+	w.Family("lumina_consumer_llm_tokens_total", "counter", "Tokens used by the workflow calls by model and kind.")
+	tokenKeys := make([]tokensKey, 0, len(m.tokens))
+	for k := range m.tokens {
+		tokenKeys = append(tokenKeys, k)
+	}
+	sort.Slice(tokenKeys, func(i, j int) bool {
+		if tokenKeys[i].model != tokenKeys[j].model {
+			return tokenKeys[i].model < tokenKeys[j].model
+		}
+		return tokenKeys[i].kind < tokenKeys[j].kind
+	})
+	for _, k := range tokenKeys {
+		w.Sample("lumina_consumer_llm_tokens_total", float64(m.tokens[k]), "model", k.model, "kind", k.kind)
+	}
+}
+
+// writeQueueGauges writes the records waiting in the sink queues, so that
+// a sink holding the consumer back shows.
+func (c *Consumer) writeQueueGauges(w *metrics.Writer) {
+	w.Family("lumina_consumer_sink_queue", "gauge", "Records waiting in the queue of each sink.")
+	for _, q := range c.sinks {
+		w.Sample("lumina_consumer_sink_queue", float64(len(q.ch)), "sink", q.sink.Name())
+	}
+}
+
+// startMetricsServer serves the metrics to Prometheus on /metrics.
+func (c *Consumer) startMetricsServer() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		var mw metrics.Writer
+		c.metrics.write(&mw)
+		c.writeQueueGauges(&mw)
+		w.Header().Set("Content-Type", metrics.ContentType)
+		_, _ = w.Write(mw.Bytes())
+	})
+
+	listener, err := net.Listen("tcp", c.conf.Metrics.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen metrics addr: %w", err)
+	}
+	c.metricsSrv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := c.metricsSrv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			c.logger.WithError(err).Error("metrics server stopped")
+		}
+	}()
+	c.logger.Infof("metrics server listening on http://%s/metrics", listener.Addr())
+	return nil
+}
+
+func (c *Consumer) stopMetricsServer() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.metricsSrv.Shutdown(ctx); err != nil {
+		c.logger.WithError(err).Warn("shutdown metrics server")
+	}
+}
@@ -0,0 +1,235 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/model"
+)
+
+// SinkRecord is a processed message as written to the sinks.
+type SinkRecord struct {
+	MessageId   int                     `json:"messageId"`
+	JobId       int                     `json:"jobId"`
+	JobUuid     string                  `json:"jobUuid"`
+	JobKind     model.JobKind           `json:"jobKind"`
+	CameraId    int                     `json:"cameraId"`
+	Timestamp   time.Time               `json:"timestamp"`
+	ImagePath   string                  `json:"imagePath,omitempty"`
+	VideoPath   string                  `json:"videoPath,omitempty"`
+	Labels      []string                `json:"labels"`
+	DetectBoxes model.DetectionBoxSlice `json:"detectBoxes,omitempty"`
+	Answer      string                  `json:"answer,omitempty"`
+	Confidence  float32                 `json:"confidence"`
+	Match       bool                    `json:"match"`
+	Alerted     bool                    `json:"alerted"`
+	TotalTokens int                     `json:"totalTokens"`
+	Metadata    model.MessageMetadata   `json:"metadata,omitempty"`
+	Sensors     model.MessageMetadata   `json:"sensors,omitempty"`
+	CreateTime  time.Time               `json:"createTime"`
+}
+
+func newSinkRecord(job *model.Job, m *model.Message) *SinkRecord {
+	r := &SinkRecord{
+		MessageId:   m.Id,
+		JobId:       job.Id,
+		JobUuid:     job.Uuid,
+		JobKind:     job.Kind,
+		CameraId:    job.CameraId,
+		Timestamp:   m.Timestamp,
+		ImagePath:   m.ImagePath,
+		VideoPath:   m.VideoPath,
+		Labels:      []string{},
+		DetectBoxes: m.DetectBoxes,
+		Alerted:     m.Alerted,
+		Metadata:    m.Metadata,
+		Sensors:     m.Sensors,
+		CreateTime:  m.CreateTime,
+	}
+	for _, box := range m.DetectBoxes {
+		if box != nil && box.Label != "" {
+			r.Labels = append(r.Labels, box.Label)
+		}
+	}
+	if resp := m.WorkflowResp; resp != nil {
+		r.Answer = resp.Answer
+		r.Confidence = resp.Confidence
+		r.Match = resp.Match
+		r.TotalTokens = resp.TotalTokens
+	}
+	return r
+}
+
+// Sink is an optional store the processed messages are copied to, in
+// addition to the database and InfluxDB.
+type Sink interface {
+	Name() string
+	// Write stores a batch of records, the whole batch is retried if it
+	// fails so that it must be idempotent
+	Write(ctx context.Context, records []*SinkRecord) error
+}
+
+const (
+	// SinkOverflowBlock makes the consumer wait for room in the queue of a
+	// sink, up to BlockTimeout, slowing down the consumption
+	SinkOverflowBlock = "block"
+	// SinkOverflowDrop drops the records a full sink cannot take
+	SinkOverflowDrop = "drop"
+)
+
+// SinkQueueConfig sets how the records are buffered and batched for a sink.
+type SinkQueueConfig struct {
+	// QueueSize is the number of records waiting to be written
+	QueueSize int `yaml:"queueSize"`
+	// BatchSize is the maximum number of records written at once
+	BatchSize int `yaml:"batchSize"`
+	// FlushInterval is the longest a record waits for its batch to fill
+	FlushInterval time.Duration `yaml:"flushInterval"`
+	// Overflow is what happens when the queue is full: block or drop
+	Overflow string `yaml:"overflow"`
+	// BlockTimeout bounds the wait for room in the queue, the record is
+	// dropped after it
+	BlockTimeout time.Duration `yaml:"blockTimeout"`
+	// MaxRetries is the number of retries of a failed batch before it is
+	// dropped
+	MaxRetries int `yaml:"maxRetries"`
+}
+
+func DefaultSinkQueueConfig() SinkQueueConfig {
+	return SinkQueueConfig{
+		QueueSize:     1000,
+		BatchSize:     100,
+		FlushInterval: 5 * time.Second,
+		Overflow:      SinkOverflowBlock,
+		BlockTimeout:  10 * time.Second,
+		MaxRetries:    3,
+	}
+}
+
+const (
+	sinkRetryBackoff = time.Second
+	sinkMaxBackoff   = 30 * time.Second
+)
+
+// sinkQueue buffers the records of a sink and writes them in batches from
+// its own goroutine, so that a slow sink does not hold the consumer back
+// more than its overflow policy allows.
+type sinkQueue struct {
+	sink   Sink
+	conf   SinkQueueConfig
+	ch     chan *SinkRecord
+	wg     sync.WaitGroup
+	logger *logrus.Entry
+}
+
+func newSinkQueue(logger *logrus.Entry, sink Sink, conf SinkQueueConfig) (*sinkQueue, error) {
+	if conf.QueueSize <= 0 || conf.BatchSize <= 0 || conf.FlushInterval <= 0 {
+		return nil, fmt.Errorf("sink %s: queueSize, batchSize and flushInterval must be positive", sink.Name())
+	}
+	if conf.Overflow != SinkOverflowBlock && conf.Overflow != SinkOverflowDrop {
+		return nil, fmt.Errorf("sink %s: unknown overflow %q", sink.Name(), conf.Overflow)
+	}
+	return &sinkQueue{
+		sink:   sink,
+		conf:   conf,
+		ch:     make(chan *SinkRecord, conf.QueueSize),
+		logger: logger.WithField("sink", sink.Name()),
+	}, nil
+}
+
+// enqueue adds the record to the queue following the overflow policy and
+// reports whether it was accepted.
+func (q *sinkQueue) enqueue(ctx context.Context, r *SinkRecord) bool {
+	select {
+	case q.ch <- r:
+		return true
+	default:
+	}
+	if q.conf.Overflow == SinkOverflowDrop {
+		q.logger.Warnf("queue is full, drop message %d", r.MessageId)
+		return false
+	}
+
+	timer := time.NewTimer(q.conf.BlockTimeout)
+	defer timer.Stop()
+	select {
+	case q.ch <- r:
+		return true
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	q.logger.Warnf("queue is still full after %s, drop message %d", q.conf.BlockTimeout, r.MessageId)
+	return false
+}
+
+func (q *sinkQueue) start(ctx context.Context) {
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		q.run(ctx)
+	}()
+}
+
+// stop writes the queued records and waits for the last batch, the queue
+// must not be used after.
+func (q *sinkQueue) stop() {
+	close(q.ch)
+	q.wg.Wait()
+}
+
+func (q *sinkQueue) run(ctx context.Context) {
+	ticker := time.NewTicker(q.conf.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*SinkRecord, 0, q.conf.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			q.write(ctx, batch)
+			batch = make([]*SinkRecord, 0, q.conf.BatchSize)
+		}
+	}
+	for {
+		select {
+		case r, ok := <-q.ch:
+			if !ok {
+				// the consumer is stopping, the context may be done already
+				ctx = context.WithoutCancel(ctx)
+				flush()
+				return
+			}
+			batch = append(batch, r)
+			if len(batch) >= q.conf.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// write writes the batch, retrying with an exponential backoff.
+func (q *sinkQueue) write(ctx context.Context, batch []*SinkRecord) {
+	backoff := sinkRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := q.sink.Write(ctx, batch)
+		if err == nil {
+			return
+		}
+		if attempt >= q.conf.MaxRetries {
+			q.logger.WithError(err).Errorf("write %d records failed, drop them", len(batch))
+			return
+		}
+		q.logger.WithError(err).Warnf("write %d records failed, retry in %s", len(batch), backoff)
+		select {
+		case <-ctx.Done():
+			q.logger.Errorf("consumer stopped, drop %d records", len(batch))
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, sinkMaxBackoff)
+	}
+}
@@ -0,0 +1,193 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ClickHouseSinkConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// URL is the HTTP interface of the server, e.g. http://127.0.0.1:8123
+	URL      string `yaml:"url"`
+	Database string `yaml:"database"`
+	Table    string `yaml:"table"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// CreateTable creates the table if it does not exist
+	CreateTable bool            `yaml:"createTable"`
+	Queue       SinkQueueConfig `yaml:"queue"`
+}
+
+// clickHouseTimeLayout is parsed by the default JSONEachRow settings.
+const clickHouseTimeLayout = "2006-01-02 15:04:05.000"
+
+// clickHouseTableSchema dedups the rows of retried batches by message id
+// when merging.
+const clickHouseTableSchema = `CREATE TABLE IF NOT EXISTS %s (
+	message_id UInt64,
+	job_id UInt32,
+	job_uuid String,
+	job_kind LowCardinality(String),
+	camera_id UInt32,
+	timestamp DateTime64(3, 'UTC'),
+	image_path String,
+	video_path String,
+	labels Array(String),
+	answer String,
+	confidence Float32,
+	match Bool,
+	alerted Bool,
+	total_tokens UInt32,
+	metadata String,
+	sensors String,
+	create_time DateTime64(3, 'UTC')
+) ENGINE = ReplacingMergeTree
+PARTITION BY toYYYYMM(timestamp)
+ORDER BY (job_id, timestamp, message_id)`
+
+// clickHouseRow is a record as a JSONEachRow row, the free-form fields are
+// kept as JSON strings.
+type clickHouseRow struct {
+	MessageId   int      `json:"message_id"`
+	JobId       int      `json:"job_id"`
+	JobUuid     string   `json:"job_uuid"`
+	JobKind     string   `json:"job_kind"`
+	CameraId    int      `json:"camera_id"`
+	Timestamp   string   `json:"timestamp"`
+	ImagePath   string   `json:"image_path"`
+	VideoPath   string   `json:"video_path"`
+	Labels      []string `json:"labels"`
+	Answer      string   `json:"answer"`
+	Confidence  float32  `json:"confidence"`
+	Match       bool     `json:"match"`
+	Alerted     bool     `json:"alerted"`
+	TotalTokens int      `json:"total_tokens"`
+	Metadata    string   `json:"metadata"`
+	Sensors     string   `json:"sensors"`
+	CreateTime  string   `json:"create_time"`
+}
+
+func newClickHouseRow(r *SinkRecord) *clickHouseRow {
+	row := &clickHouseRow{
+		MessageId:   r.MessageId,
+		JobId:       r.JobId,
+		JobUuid:     r.JobUuid,
+		JobKind:     string(r.JobKind),
+		CameraId:    r.CameraId,
+		Timestamp:   r.Timestamp.UTC().Format(clickHouseTimeLayout),
+		ImagePath:   r.ImagePath,
+		VideoPath:   r.VideoPath,
+		Labels:      r.Labels,
+		Answer:      r.Answer,
+		Confidence:  r.Confidence,
+		Match:       r.Match,
+		Alerted:     r.Alerted,
+		TotalTokens: r.TotalTokens,
+		Metadata:    "{}",
+		Sensors:     "{}",
+		CreateTime:  r.CreateTime.UTC().Format(clickHouseTimeLayout),
+	}
+	if len(r.Metadata) > 0 {
+		data, _ := json.Marshal(r.Metadata)
+		row.Metadata = string(data)
+	}
+	if len(r.Sensors) > 0 {
+		data, _ := json.Marshal(r.Sensors)
+		row.Sensors = string(data)
+	}
+	return row
+}
+
+// clickHouseSink inserts the messages for analytics through the HTTP
+// interface.
+type clickHouseSink struct {
+	conf    ClickHouseSinkConfig
+	client  *http.Client
+	mu      sync.Mutex
+	created bool
+}
+
+func newClickHouseSink(conf ClickHouseSinkConfig) *clickHouseSink {
+	return &clickHouseSink{
+		conf:   conf,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *clickHouseSink) Name() string {
+	return "clickhouse"
+}
+
+func (s *clickHouseSink) table() string {
+	if s.conf.Database == "" {
+		return s.conf.Table
+	}
+	return s.conf.Database + "." + s.conf.Table
+}
+
+func (s *clickHouseSink) Write(ctx context.Context, records []*SinkRecord) error {
+	if err := s.ensureTable(ctx); err != nil {
+		return fmt.Errorf("create table failed: %w", err)
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, r := range records {
+		if err := enc.Encode(newClickHouseRow(r)); err != nil {
+			return err
+		}
+	}
+	return s.exec(ctx, fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", s.table()), &body)
+}
+
+func (s *clickHouseSink) ensureTable(ctx context.Context) error {
+	if !s.conf.CreateTable {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.created {
+		return nil
+	}
+	if err := s.exec(ctx, "", strings.NewReader(fmt.Sprintf(clickHouseTableSchema, s.table()))); err != nil {
+		return err
+	}
+	s.created = true
+	return nil
+}
+
+// exec runs the query, the body is the data of an insert or the query
+// itself if query is empty.
+func (s *clickHouseSink) exec(ctx context.Context, query string, body io.Reader) error {
+	u := strings.TrimSuffix(s.conf.URL, "/") + "/"
+	if query != "" {
+		u += "?" + url.Values{"query": {query}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return err
+	}
+	if s.conf.Username != "" {
+		req.Header.Set("X-ClickHouse-User", s.conf.Username)
+		req.Header.Set("X-ClickHouse-Key", s.conf.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("query returned status %d: %s", resp.StatusCode, truncate(string(data), 512))
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ElasticsearchSinkConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	URL     string `yaml:"url"`
+	// Index is the index the messages are written to, a time layout between
+	// braces is replaced by the message time, e.g. lumina-{2006.01.02}
+	Index    string `yaml:"index"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// APIKey is used instead of the username and password if set
+	APIKey string          `yaml:"apiKey,omitempty"`
+	Queue  SinkQueueConfig `yaml:"queue"`
+}
+
+// elasticsearchSink indexes the messages for full-text search through the
+// bulk API, the message id is the document id so that retries overwrite.
+type elasticsearchSink struct {
+	conf   ElasticsearchSinkConfig
+	client *http.Client
+}
+
+func newElasticsearchSink(conf ElasticsearchSinkConfig) *elasticsearchSink {
+	return &elasticsearchSink{
+		conf:   conf,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *elasticsearchSink) Name() string {
+	return "elasticsearch"
+}
+
+// index returns the index of the record.
+func (s *elasticsearchSink) index(r *SinkRecord) string {
+	start := strings.IndexByte(s.conf.Index, '{')
+	end := strings.LastIndexByte(s.conf.Index, '}')
+	if start < 0 || end < start {
+		return s.conf.Index
+	}
+	layout := s.conf.Index[start+1 : end]
+	return s.conf.Index[:start] + r.Timestamp.UTC().Format(layout) + s.conf.Index[end+1:]
+}
+
+type elasticsearchDocument struct {
+	*SinkRecord
+	Time time.Time `json:"@timestamp"`
+}
+
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+func (s *elasticsearchSink) Write(ctx context.Context, records []*SinkRecord) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, r := range records {
+		action := map[string]any{
+			"index": map[string]string{
+				"_index": s.index(r),
+				"_id":    strconv.Itoa(r.MessageId),
+			},
+		}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(elasticsearchDocument{SinkRecord: r, Time: r.Timestamp}); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.conf.URL, "/")+"/_bulk", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.conf.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+s.conf.APIKey)
+	} else if s.conf.Username != "" {
+		req.SetBasicAuth(s.conf.Username, s.conf.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("bulk request returned status %d: %s", resp.StatusCode, truncate(string(data), 512))
+	}
+
+	var result elasticsearchBulkResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	if !result.Errors {
+		return nil
+	}
+	// the failed items are retried with the whole batch, indexing the
+	// others again by their id is harmless
+	for _, item := range result.Items {
+		for _, r := range item {
+			if r.Error != nil {
+				return fmt.Errorf("index document failed with status %d: %s: %s", r.Status, r.Error.Type, r.Error.Reason)
+			}
+		}
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
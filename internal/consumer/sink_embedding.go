@@ -0,0 +1,96 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"lumina/internal/embedding"
+)
+
+type EmbeddingSinkConfig struct {
+	Enabled          bool `yaml:"enabled"`
+	embedding.Config `yaml:",inline"`
+	Queue            SinkQueueConfig `yaml:"queue"`
+}
+
+// embeddingSink embeds the messages and stores the vectors for the semantic
+// search of the server, the collection is created on the first write with
+// the size of the vectors of the model.
+type embeddingSink struct {
+	conf      EmbeddingSinkConfig
+	embedder  *embedding.Embedder
+	store     *embedding.Store
+	loc       *time.Location
+	urlPrefix string
+
+	ensureMu sync.Mutex
+	ensured  bool
+}
+
+func newEmbeddingSink(conf EmbeddingSinkConfig, urlPrefix string) (*embeddingSink, error) {
+	if conf.Input != embedding.InputText && conf.Input != embedding.InputImage {
+		return nil, fmt.Errorf("sink embedding: unknown input %q", conf.Input)
+	}
+	loc, err := conf.Location()
+	if err != nil {
+		return nil, fmt.Errorf("sink embedding: %w", err)
+	}
+	return &embeddingSink{
+		conf:      conf,
+		embedder:  embedding.NewEmbedder(conf.Config),
+		store:     embedding.NewStore(conf.Qdrant),
+		loc:       loc,
+		urlPrefix: urlPrefix,
+	}, nil
+}
+
+func (s *embeddingSink) Name() string {
+	return "embedding"
+}
+
+func (s *embeddingSink) Write(ctx context.Context, records []*SinkRecord) error {
+	inputs := make([]embedding.Input, len(records))
+	for i, r := range records {
+		if s.conf.Input == embedding.InputImage && r.ImagePath != "" {
+			inputs[i].Image = s.urlPrefix + r.ImagePath
+		} else {
+			inputs[i].Text = embedding.Describe(r.Answer, r.Labels, r.Timestamp.In(s.loc))
+		}
+	}
+	vectors, err := s.embedder.Embed(ctx, inputs)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureCollection(ctx, len(vectors[0])); err != nil {
+		return err
+	}
+
+	points := make([]embedding.Point, len(records))
+	for i, r := range records {
+		points[i] = embedding.Point{
+			MessageId: r.MessageId,
+			Vector:    vectors[i],
+			JobId:     r.JobId,
+			CameraId:  r.CameraId,
+			Timestamp: r.Timestamp,
+			Labels:    r.Labels,
+			Alerted:   r.Alerted,
+		}
+	}
+	return s.store.Upsert(ctx, points)
+}
+
+func (s *embeddingSink) ensureCollection(ctx context.Context, dim int) error {
+	s.ensureMu.Lock()
+	defer s.ensureMu.Unlock()
+	if s.ensured {
+		return nil
+	}
+	if err := s.store.EnsureCollection(ctx, dim); err != nil {
+		return err
+	}
+	s.ensured = true
+	return nil
+}
@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"encoding/json"
+	"time"
+
+	"lumina/internal/model"
+)
+
+type AgentProposalSpec struct {
+	Uuid string                  `json:"uuid"`
+	Kind model.AgentProposalKind `json:"kind"`
+	// 更新的任务或工作流ID，创建时为0
+	TargetId int    `json:"targetId,omitempty"`
+	Summary  string `json:"summary"`
+	// 批准后执行的请求，工作流的key已隐藏
+	Request    json.RawMessage `json:"request" swaggertype:"object"`
+	CreateTime string          `json:"createTime"`
+	ExpireTime string          `json:"expireTime"`
+}
+
+func FromAgentProposalModel(p *model.AgentProposal) *AgentProposalSpec {
+	return &AgentProposalSpec{
+		Uuid:       p.Uuid,
+		Kind:       p.Kind,
+		TargetId:   p.TargetId,
+		Summary:    p.Summary,
+		Request:    redactProposalRequest(p.Request),
+		CreateTime: p.CreateTime.UTC().Format(time.RFC3339),
+		ExpireTime: p.CreateTime.Add(model.AgentProposalExpire).UTC().Format(time.RFC3339),
+	}
+}
+
+// redactProposalRequest hides the key a workflow request carries.
+func redactProposalRequest(data json.RawMessage) json.RawMessage {
+	var req map[string]any
+	if err := json.Unmarshal(data, &req); err != nil {
+		return data
+	}
+	if v, ok := req["key"]; !ok || v == nil {
+		return data
+	}
+	req["key"] = "******"
+	redacted, err := json.Marshal(req)
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+type ApproveAgentProposalResponse struct {
+	Kind model.AgentProposalKind `json:"kind"`
+	// 创建或更新的任务或工作流ID
+	Id   int    `json:"id"`
+	Uuid string `json:"uuid,omitempty"`
+}
@@ -0,0 +1,66 @@
+package dao
+
+import (
+	"time"
+
+	"lumina/internal/model"
+)
+
+type ApiTokenSpec struct {
+	// 令牌ID
+	Id int `json:"id"`
+	// 令牌名称
+	Name string `json:"name"`
+	// 令牌开头几位，用于区分令牌
+	Hint string `json:"hint"`
+	// 授权范围，all 为全部权限，read 为只读，jobs、cameras、devices、workflows、messages 为对应资源的全部权限
+	Scopes []string `json:"scopes"`
+	// 过期时间，为空时永不过期
+	ExpireTime string `json:"expireTime,omitempty"`
+	// 最后使用时间，为空时从未使用
+	LastUsedTime string `json:"lastUsedTime,omitempty"`
+	// 最后使用的客户端IP
+	LastUsedIp  string `json:"lastUsedIp,omitempty"`
+	CreatedTime string `json:"createdTime"`
+}
+
+type CreateApiTokenRequest struct {
+	// 令牌名称
+	Name string `json:"name" binding:"required,max=64"`
+	// 授权范围，可选 all、read、jobs、cameras、devices、workflows、messages
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=all read jobs cameras devices workflows messages"`
+	// 过期时间，RFC3339 格式或 Unix 时间戳（秒或毫秒），为空时永不过期
+	ExpireTime string `json:"expireTime" binding:"omitempty,timestamp"`
+}
+
+type CreateApiTokenResponse struct {
+	ApiTokenSpec
+	// 令牌，只返回这一次
+	Token string `json:"token"`
+}
+
+type ListApiTokensResponse struct {
+	// 令牌列表
+	Items []ApiTokenSpec `json:"items"`
+}
+
+func ToApiTokenSpec(t *model.ApiToken) ApiTokenSpec {
+	spec := ApiTokenSpec{
+		Id:          t.Id,
+		Name:        t.Name,
+		Hint:        t.Hint,
+		Scopes:      t.Scopes,
+		LastUsedIp:  t.LastUsedIp,
+		CreatedTime: t.CreatedTime.UTC().Format(time.RFC3339),
+	}
+	if spec.Scopes == nil {
+		spec.Scopes = []string{}
+	}
+	if t.ExpireTime != nil {
+		spec.ExpireTime = t.ExpireTime.UTC().Format(time.RFC3339)
+	}
+	if t.LastUsedTime != nil {
+		spec.LastUsedTime = t.LastUsedTime.UTC().Format(time.RFC3339)
+	}
+	return spec
+}
@@ -0,0 +1,60 @@
+package dao
+
+import "lumina/internal/model"
+
+// ApiUsageRequest 接口调用统计查询参数，时间格式与默认值同 JobStatsRequest，按小时统计
+type ApiUsageRequest struct {
+	Start string `form:"start" json:"start"`
+	End   string `form:"end" json:"end"`
+	// 返回的条数，默认 20
+	Limit int `form:"limit" json:"limit" binding:"min=0,max=100"`
+}
+
+// ApiEndpointUsageRequest 接口调用按接口统计的查询参数，不指定调用方时统计所有调用方
+type ApiEndpointUsageRequest struct {
+	ApiUsageRequest
+	// 调用方类型：user、device 或 anonymous
+	ConsumerKind model.ApiConsumerKind `form:"consumerKind" json:"consumerKind" binding:"omitempty,oneof=user device anonymous"`
+	// 调用方：用户名、设备 uuid 或匿名调用方的 IP
+	Consumer string `form:"consumer" json:"consumer" binding:"required_with=ConsumerKind"`
+}
+
+// ApiUsageSpec 一个调用方或一个接口的调用统计
+type ApiUsageSpec struct {
+	ConsumerKind model.ApiConsumerKind `json:"consumerKind,omitempty"`
+	Consumer     string                `json:"consumer,omitempty"`
+	Method       string                `json:"method,omitempty"`
+	// 接口路由，如 /api/v1/job/:job_id
+	Endpoint string `json:"endpoint,omitempty"`
+	Requests int64  `json:"requests"`
+	// 4xx 和 5xx 响应的数量
+	ClientErrors int64 `json:"clientErrors"`
+	ServerErrors int64 `json:"serverErrors"`
+	// 4xx 和 5xx 响应的比例
+	ErrorRate float64 `json:"errorRate"`
+	// 平均和最大耗时，单位毫秒
+	AvgLatency float64 `json:"avgLatency"`
+	MaxLatency float64 `json:"maxLatency"`
+}
+
+func FromApiUsageSummaryModel(m *model.ApiUsageSummary) *ApiUsageSpec {
+	spec := &ApiUsageSpec{
+		ConsumerKind: m.ConsumerKind,
+		Consumer:     m.Consumer,
+		Method:       m.Method,
+		Endpoint:     m.Endpoint,
+		Requests:     m.Requests,
+		ClientErrors: m.ClientErrors,
+		ServerErrors: m.ServerErrors,
+		MaxLatency:   float64(m.MaxLatency) / 1000,
+	}
+	if m.Requests > 0 {
+		spec.ErrorRate = float64(m.ClientErrors+m.ServerErrors) / float64(m.Requests)
+		spec.AvgLatency = float64(m.TotalLatency) / float64(m.Requests) / 1000
+	}
+	return spec
+}
+
+type ListApiUsageResponse struct {
+	Items []ApiUsageSpec `json:"items"`
+}
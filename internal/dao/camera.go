@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"lumina/internal/model"
+	"lumina/pkg/homography"
 	"lumina/pkg/str"
 )
 
@@ -22,6 +23,14 @@ type CameraSpec struct {
 	CreateTime string               `json:"createTime" binding:"required,datetime=2006-01-02T15:04:05Z07:00"`
 	UpdateTime string               `json:"updateTime" binding:"required,datetime=2006-01-02T15:04:05Z07:00"`
 	BindDevice *DeviceSpec          `json:"bindDevice,omitempty"`
+	// 预览时是否包含音频
+	PreviewAudio bool `json:"previewAudio"`
+	// ONVIF 设备服务端口，0 表示不支持 ONVIF
+	OnvifPort int `json:"onvifPort"`
+	// 标签，如所在楼栋、楼层
+	Tags []string `json:"tags"`
+	// 地面标定，未标定时为空
+	Calibration *CameraCalibration `json:"calibration,omitempty"`
 }
 
 func (c CameraSpec) Url() string {
@@ -60,8 +69,15 @@ func FromCameraModel(m *model.Camera) (*CameraSpec, error) {
 	c.Path = m.Path
 	c.Username = m.Username
 	c.Password = m.Password
-	c.CreateTime = m.CreateTime.Format(time.RFC3339)
-	c.UpdateTime = m.UpdateTime.Format(time.RFC3339)
+	c.CreateTime = m.CreateTime.UTC().Format(time.RFC3339)
+	c.UpdateTime = m.UpdateTime.UTC().Format(time.RFC3339)
+	c.PreviewAudio = m.PreviewAudio
+	c.OnvifPort = m.OnvifPort
+	c.Tags = m.Tags
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	c.Calibration = fromCalibrationModel(m.Calibration)
 	if m.BindDeviceId != 0 {
 		dev, err := m.BindDevice()
 		if err != nil {
@@ -73,8 +89,50 @@ func FromCameraModel(m *model.Camera) (*CameraSpec, error) {
 }
 
 type ListCamerasRequest struct {
-	Start int `json:"start"`
-	Limit int `json:"limit"`
+	Start int `json:"start" form:"start" binding:"min=0"`
+	Limit int `json:"limit" form:"limit" binding:"min=0,max=200"`
+	// 按标签过滤
+	Tag string `json:"tag" form:"tag"`
+	// 按名称模糊匹配
+	Name string `json:"name" form:"name"`
+	// 按协议过滤
+	Protocol model.CameraProtocol `json:"protocol" form:"protocol" binding:"omitempty,oneof=rtmp rtsp"`
+	// 按绑定设备过滤
+	BindDeviceId int `json:"bindDeviceId" form:"bindDeviceId"`
+}
+
+func (req *ListCamerasRequest) Filter() model.CameraFilter {
+	return model.CameraFilter{
+		Tag:          strings.TrimSpace(req.Tag),
+		Name:         strings.TrimSpace(req.Name),
+		Protocol:     req.Protocol,
+		BindDeviceId: req.BindDeviceId,
+	}
+}
+
+type ListCameraTagsResponse struct {
+	Tags []string `json:"tags"`
+}
+
+// normalizeTags trims the tags and drops the empty and duplicate ones.
+func normalizeTags(tags []string) model.CameraTags {
+	if tags == nil {
+		return nil
+	}
+	res := make(model.CameraTags, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		res = append(res, tag)
+	}
+	return res
 }
 
 type ListCamerasResponse struct {
@@ -91,6 +149,12 @@ type CreateCameraRequest struct {
 	Username     string               `json:"username"`
 	Password     string               `json:"password"`
 	BindDeviceId int                  `json:"bindDeviceId"`
+	// 预览时是否包含音频
+	PreviewAudio bool `json:"previewAudio"`
+	// ONVIF 设备服务端口，0 表示不支持 ONVIF
+	OnvifPort int `json:"onvifPort" binding:"min=0,max=65535"`
+	// 标签
+	Tags []string `json:"tags" binding:"omitempty,max=32,dive,max=64"`
 }
 
 func (c *CreateCameraRequest) ToModel() *model.Camera {
@@ -104,6 +168,9 @@ func (c *CreateCameraRequest) ToModel() *model.Camera {
 		Username:     c.Username,
 		Password:     c.Password,
 		BindDeviceId: c.BindDeviceId,
+		PreviewAudio: c.PreviewAudio,
+		OnvifPort:    c.OnvifPort,
+		Tags:         normalizeTags(c.Tags),
 	}
 }
 
@@ -120,6 +187,12 @@ type UpdateCameraRequest struct {
 	Username     *string               `json:"username"`
 	Password     *string               `json:"password"`
 	BindDeviceId *int                  `json:"bindDeviceId"`
+	// 预览时是否包含音频
+	PreviewAudio *bool `json:"previewAudio"`
+	// ONVIF 设备服务端口
+	OnvifPort *int `json:"onvifPort" binding:"omitempty,min=0,max=65535"`
+	// 标签，传空数组表示清空
+	Tags []string `json:"tags" binding:"omitempty,max=32,dive,max=64"`
 }
 
 func (req *UpdateCameraRequest) UpdateModel(c *model.Camera) {
@@ -147,14 +220,33 @@ func (req *UpdateCameraRequest) UpdateModel(c *model.Camera) {
 	if req.BindDeviceId != nil {
 		c.BindDeviceId = *req.BindDeviceId
 	}
+	if req.PreviewAudio != nil {
+		c.PreviewAudio = *req.PreviewAudio
+	}
+	if req.OnvifPort != nil {
+		c.OnvifPort = *req.OnvifPort
+	}
+	if req.Tags != nil {
+		c.Tags = normalizeTags(req.Tags)
+	}
+}
+
+type StartPreviewRequest struct {
+	// 预览方式，flv、webrtc 或 hls，默认 flv
+	Mode model.PreviewMode `json:"mode" form:"mode" binding:"omitempty,oneof=flv webrtc hls"`
+	// 是否包含音频，不填时使用摄像头的预览音频设置
+	Audio *bool `json:"audio" form:"audio"`
 }
 
 type PreviewTask struct {
-	TaskUuid    string `json:"taskUuid"`
-	PullAddr    string `json:"pullAddr"`
-	PushAddr    string `json:"pushAddr"`
-	PreviewAddr string `json:"previewAddr"`
-	ExpireTime  string `json:"expireTime"`
+	TaskUuid    string            `json:"taskUuid"`
+	Mode        model.PreviewMode `json:"mode"`
+	Audio       bool              `json:"audio"`
+	PullAddr    string            `json:"pullAddr"`
+	PushAddr    string            `json:"pushAddr"`
+	PreviewAddr string            `json:"previewAddr"`
+	HlsAddr     string            `json:"hlsAddr,omitempty"`
+	ExpireTime  string            `json:"expireTime"`
 }
 
 func (t PreviewTask) Expired() bool {
@@ -174,9 +266,14 @@ func FromPreviewTaskModel(m *model.PreviewTask) *PreviewTask {
 	}
 	t := &PreviewTask{}
 	t.TaskUuid = m.TaskUuid
+	t.Mode = m.Mode
+	if t.Mode == "" {
+		t.Mode = model.PreviewModeFlv
+	}
+	t.Audio = m.Audio
 	t.PullAddr = m.PullAddr
 	t.PushAddr = m.PushAddr
-	t.ExpireTime = m.ExpireTime.Format(time.RFC3339)
+	t.ExpireTime = m.ExpireTime.UTC().Format(time.RFC3339)
 	return t
 }
 
@@ -184,3 +281,388 @@ type ListPreviewTasksResponse struct {
 	Items []PreviewTask `json:"items"`
 	Total int64         `json:"total"`
 }
+
+type TestDetectRequest struct {
+	ModelName     string  `json:"modelName" binding:"required"`
+	Labels        string  `json:"labels,omitempty"`
+	ConfThreshold float32 `json:"confThreshold,omitempty" binding:"min=0,max=1"`
+	IoUThreshold  float32 `json:"iouThreshold,omitempty" binding:"min=0,max=1"`
+	NoCache       bool    `json:"noCache,omitempty"`
+}
+
+type TestDetectTask struct {
+	TaskUuid      string  `json:"taskUuid"`
+	PullAddr      string  `json:"pullAddr"`
+	ModelName     string  `json:"modelName"`
+	Labels        string  `json:"labels"`
+	ConfThreshold float32 `json:"confThreshold"`
+	IoUThreshold  float32 `json:"iouThreshold"`
+	ExpireTime    string  `json:"expireTime"`
+}
+
+func (t TestDetectTask) Expired() bool {
+	if t.ExpireTime == "" {
+		return false
+	}
+	expireTime, err := time.Parse(time.RFC3339, t.ExpireTime)
+	if err != nil {
+		return false
+	}
+	return expireTime.Before(time.Now())
+}
+
+func (t *TestDetectTask) GetLabelMap() map[int]string {
+	opts := DetectOptions{Labels: t.Labels}
+	return opts.GetLabelMap()
+}
+
+func FromTestDetectTaskModel(m *model.TestDetectTask) *TestDetectTask {
+	if m == nil {
+		return nil
+	}
+	return &TestDetectTask{
+		TaskUuid:      m.TaskUuid,
+		PullAddr:      m.PullAddr,
+		ModelName:     m.ModelName,
+		Labels:        m.Labels,
+		ConfThreshold: m.ConfThreshold,
+		IoUThreshold:  m.IoUThreshold,
+		ExpireTime:    m.ExpireTime.UTC().Format(time.RFC3339),
+	}
+}
+
+type ListTestDetectTasksResponse struct {
+	Items []TestDetectTask `json:"items"`
+	Total int64            `json:"total"`
+}
+
+type TestDetectResult struct {
+	TaskUuid  string          `json:"taskUuid" binding:"required"`
+	ImagePath string          `json:"imagePath,omitempty"`
+	Boxes     []*DetectionBox `json:"boxes,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+func (r *TestDetectResult) ToModel() *model.TestDetectResult {
+	m := &model.TestDetectResult{
+		TaskUuid:   r.TaskUuid,
+		ImagePath:  r.ImagePath,
+		Error:      r.Error,
+		CreateTime: time.Now(),
+	}
+	if r.Boxes != nil {
+		m.Boxes = make(model.DetectionBoxSlice, len(r.Boxes))
+		for i, box := range r.Boxes {
+			m.Boxes[i] = box.ToModel()
+		}
+	}
+	return m
+}
+
+type TestDetectResponse struct {
+	TaskUuid   string          `json:"taskUuid"`
+	ImagePath  string          `json:"imagePath,omitempty"`
+	Boxes      []*DetectionBox `json:"boxes"`
+	Cached     bool            `json:"cached"`
+	CreateTime string          `json:"createTime"`
+}
+
+func FromTestDetectResultModel(m *model.TestDetectResult) *TestDetectResponse {
+	if m == nil {
+		return nil
+	}
+	resp := &TestDetectResponse{
+		TaskUuid:   m.TaskUuid,
+		ImagePath:  m.ImagePath,
+		Boxes:      make([]*DetectionBox, 0, len(m.Boxes)),
+		CreateTime: m.CreateTime.UTC().Format(time.RFC3339),
+	}
+	for _, box := range m.Boxes {
+		resp.Boxes = append(resp.Boxes, &DetectionBox{
+			X1:         box.X1,
+			Y1:         box.Y1,
+			X2:         box.X2,
+			Y2:         box.Y2,
+			Confidence: box.Confidence,
+			ClassId:    box.ClassId,
+			Label:      box.Label,
+		})
+	}
+	return resp
+}
+
+type SnapshotTask struct {
+	TaskUuid   string `json:"taskUuid"`
+	PullAddr   string `json:"pullAddr"`
+	ExpireTime string `json:"expireTime"`
+}
+
+func (t SnapshotTask) Expired() bool {
+	if t.ExpireTime == "" {
+		return false
+	}
+	expireTime, err := time.Parse(time.RFC3339, t.ExpireTime)
+	if err != nil {
+		return false
+	}
+	return expireTime.Before(time.Now())
+}
+
+func FromSnapshotTaskModel(m *model.SnapshotTask) *SnapshotTask {
+	if m == nil {
+		return nil
+	}
+	return &SnapshotTask{
+		TaskUuid:   m.TaskUuid,
+		PullAddr:   m.PullAddr,
+		ExpireTime: m.ExpireTime.UTC().Format(time.RFC3339),
+	}
+}
+
+type ListSnapshotTasksResponse struct {
+	Items []SnapshotTask `json:"items"`
+	Total int64          `json:"total"`
+}
+
+type SnapshotResult struct {
+	TaskUuid  string `json:"taskUuid" binding:"required"`
+	ImagePath string `json:"imagePath,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (r *SnapshotResult) ToModel() *model.SnapshotResult {
+	return &model.SnapshotResult{
+		TaskUuid:   r.TaskUuid,
+		ImagePath:  r.ImagePath,
+		Error:      r.Error,
+		CreateTime: time.Now(),
+	}
+}
+
+type CameraSnapshotResponse struct {
+	// 快照的预签名下载地址
+	Url string `json:"url"`
+	// 预签名地址的过期时间
+	ExpireTime string `json:"expireTime"`
+	CreateTime string `json:"createTime"`
+}
+
+type CameraProbeTask struct {
+	TaskUuid string `json:"taskUuid"`
+	PullAddr string `json:"pullAddr"`
+	// 不为空时同时检查设备的 Triton 是否已加载该模型
+	ModelName  string `json:"modelName,omitempty"`
+	ExpireTime string `json:"expireTime"`
+}
+
+func (t CameraProbeTask) Expired() bool {
+	if t.ExpireTime == "" {
+		return false
+	}
+	expireTime, err := time.Parse(time.RFC3339, t.ExpireTime)
+	if err != nil {
+		return false
+	}
+	return expireTime.Before(time.Now())
+}
+
+func FromCameraProbeTaskModel(m *model.CameraProbeTask) *CameraProbeTask {
+	if m == nil {
+		return nil
+	}
+	return &CameraProbeTask{
+		TaskUuid:   m.TaskUuid,
+		PullAddr:   m.PullAddr,
+		ModelName:  m.ModelName,
+		ExpireTime: m.ExpireTime.UTC().Format(time.RFC3339),
+	}
+}
+
+type ListCameraProbeTasksResponse struct {
+	Items []CameraProbeTask `json:"items"`
+	Total int64             `json:"total"`
+}
+
+type CameraProbeVideo struct {
+	// 视频编码，如 h264、hevc
+	Codec   string `json:"codec"`
+	Profile string `json:"profile,omitempty"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	// 帧率
+	Fps    float64 `json:"fps"`
+	PixFmt string  `json:"pixFmt,omitempty"`
+}
+
+type CameraProbeAudio struct {
+	Codec      string `json:"codec"`
+	SampleRate int    `json:"sampleRate"`
+	Channels   int    `json:"channels"`
+}
+
+// CameraProbeResult 设备上报的探测结果，也作为探测接口的响应
+type CameraProbeResult struct {
+	TaskUuid string `json:"taskUuid" binding:"required"`
+	// 摄像头是否可以连通并读取到码流
+	Reachable bool `json:"reachable"`
+	// 无法连通时的错误信息
+	Error string `json:"error,omitempty"`
+	// 封装格式，如 rtsp
+	Format string `json:"format,omitempty"`
+	// 码率，单位 bit/s
+	Bitrate int64             `json:"bitrate,omitempty"`
+	Video   *CameraProbeVideo `json:"video,omitempty"`
+	Audio   *CameraProbeAudio `json:"audio,omitempty"`
+	// 任务指定了模型时，模型是否已就绪
+	ModelReady bool `json:"modelReady"`
+	// 模型未就绪的原因
+	ModelError string `json:"modelError,omitempty"`
+	// 探测耗时，单位毫秒
+	Duration   int64  `json:"duration"`
+	CreateTime string `json:"createTime,omitempty"`
+}
+
+func (r *CameraProbeResult) ToModel() *model.CameraProbeResult {
+	m := &model.CameraProbeResult{
+		TaskUuid:   r.TaskUuid,
+		Reachable:  r.Reachable,
+		Error:      r.Error,
+		Format:     r.Format,
+		Bitrate:    r.Bitrate,
+		ModelReady: r.ModelReady,
+		ModelError: r.ModelError,
+		Duration:   r.Duration,
+		CreateTime: time.Now(),
+	}
+	if r.Video != nil {
+		m.Video = &model.CameraProbeVideo{
+			Codec:   r.Video.Codec,
+			Profile: r.Video.Profile,
+			Width:   r.Video.Width,
+			Height:  r.Video.Height,
+			Fps:     r.Video.Fps,
+			PixFmt:  r.Video.PixFmt,
+		}
+	}
+	if r.Audio != nil {
+		m.Audio = &model.CameraProbeAudio{
+			Codec:      r.Audio.Codec,
+			SampleRate: r.Audio.SampleRate,
+			Channels:   r.Audio.Channels,
+		}
+	}
+	return m
+}
+
+func FromCameraProbeResultModel(m *model.CameraProbeResult) *CameraProbeResult {
+	if m == nil {
+		return nil
+	}
+	r := &CameraProbeResult{
+		TaskUuid:   m.TaskUuid,
+		Reachable:  m.Reachable,
+		Error:      m.Error,
+		Format:     m.Format,
+		Bitrate:    m.Bitrate,
+		ModelReady: m.ModelReady,
+		ModelError: m.ModelError,
+		Duration:   m.Duration,
+		CreateTime: m.CreateTime.UTC().Format(time.RFC3339),
+	}
+	if m.Video != nil {
+		r.Video = &CameraProbeVideo{
+			Codec:   m.Video.Codec,
+			Profile: m.Video.Profile,
+			Width:   m.Video.Width,
+			Height:  m.Video.Height,
+			Fps:     m.Video.Fps,
+			PixFmt:  m.Video.PixFmt,
+		}
+	}
+	if m.Audio != nil {
+		r.Audio = &CameraProbeAudio{
+			Codec:      m.Audio.Codec,
+			SampleRate: m.Audio.SampleRate,
+			Channels:   m.Audio.Channels,
+		}
+	}
+	return r
+}
+
+// CalibrationPoint 标定点，图像像素坐标与其在地面上的世界坐标
+type CalibrationPoint struct {
+	// 像素坐标 [x, y]
+	Pixel [2]float64 `json:"pixel"`
+	// 世界坐标 [x, y]
+	World [2]float64 `json:"world"`
+}
+
+// CameraCalibration 摄像头地面标定
+type CameraCalibration struct {
+	Points []CalibrationPoint `json:"points"`
+	// 像素到世界坐标的单应矩阵，3x3 行优先
+	Matrix []float64 `json:"matrix"`
+	// 世界坐标单位，如 m
+	Unit string `json:"unit"`
+	// 标定点的平均重投影误差，单位同 Unit
+	Error float64 `json:"error"`
+}
+
+func fromCalibrationModel(m *model.CameraCalibration) *CameraCalibration {
+	if m == nil {
+		return nil
+	}
+	c := &CameraCalibration{
+		Points: make([]CalibrationPoint, 0, len(m.Points)),
+		Matrix: m.Matrix[:],
+		Unit:   m.Unit,
+		Error:  m.Error,
+	}
+	for _, p := range m.Points {
+		c.Points = append(c.Points, CalibrationPoint{Pixel: p.Pixel, World: p.World})
+	}
+	return c
+}
+
+// Homography returns the matrix of the calibration, ok is false if it is
+// not a 3x3 matrix.
+func (c *CameraCalibration) Homography() (homography.Matrix, bool) {
+	var m homography.Matrix
+	if c == nil || len(c.Matrix) != len(m) {
+		return m, false
+	}
+	copy(m[:], c.Matrix)
+	return m, true
+}
+
+// SetCameraCalibrationRequest 设置摄像头地面标定，至少 4 个点且不能共线
+type SetCameraCalibrationRequest struct {
+	Points []CalibrationPoint `json:"points" binding:"required,min=4,max=64"`
+	// 世界坐标单位，默认 m
+	Unit string `json:"unit" binding:"max=16"`
+}
+
+// ToModel solves the homography of the points.
+func (req *SetCameraCalibrationRequest) ToModel() (*model.CameraCalibration, error) {
+	src := make([][2]float64, len(req.Points))
+	dst := make([][2]float64, len(req.Points))
+	points := make([]model.CalibrationPoint, len(req.Points))
+	for i, p := range req.Points {
+		src[i], dst[i] = p.Pixel, p.World
+		points[i] = model.CalibrationPoint{Pixel: p.Pixel, World: p.World}
+	}
+	m, err := homography.Solve(src, dst)
+	if err != nil {
+		return nil, err
+	}
+	unit := req.Unit
+	if unit == "" {
+		unit = "m"
+	}
+	return &model.CameraCalibration{
+		Points: points,
+		Matrix: m,
+		Unit:   unit,
+		Error:  homography.ReprojectionError(m, src, dst),
+	}, nil
+}
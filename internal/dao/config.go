@@ -0,0 +1,30 @@
+package dao
+
+// 配置重新加载的触发方式
+const (
+	// 配置文件变化
+	ConfigReloadTriggerWatch = "watch"
+	// 管理员调用接口
+	ConfigReloadTriggerManual = "manual"
+)
+
+// ConfigReloadSpec 一次配置重新加载的结果
+type ConfigReloadSpec struct {
+	// 触发方式：watch 为配置文件变化，manual 为管理员触发
+	Trigger string `json:"trigger"`
+	// 重新加载的时间
+	Time string `json:"time"`
+	// 已生效的配置项，如 llm、smtp、messageRetention、rateLimit
+	Applied []string `json:"applied"`
+	// 有变化但重启后才生效的配置项
+	RestartRequired []string `json:"restartRequired"`
+	// 加载失败的原因，失败时保留原配置
+	Error string `json:"error,omitempty"`
+}
+
+type ConfigStatusResponse struct {
+	// 无需重启即可生效的配置项
+	Reloadable []string `json:"reloadable"`
+	// 最近一次重新加载的结果，未重新加载过时为空
+	LastReload *ConfigReloadSpec `json:"lastReload,omitempty"`
+}
@@ -33,7 +33,7 @@ func FromConversationModel(c *model.Conversation) (*ConversationSpec, error) {
 		Id:         c.Id,
 		Uuid:       c.Uuid,
 		Title:      c.Title,
-		CreateTime: c.CreateTime.Format(time.RFC3339),
+		CreateTime: c.CreateTime.UTC().Format(time.RFC3339),
 	}, nil
 }
 
@@ -98,7 +98,7 @@ func FromChatMessageModel(m *model.ChatMessage) *ChatMessageSpec {
 		ConversationId: m.ConversationId,
 		Query:          m.Query,
 		Answer:         m.Answer,
-		CreateTime:     m.CreateTime.Format(time.RFC3339),
+		CreateTime:     m.CreateTime.UTC().Format(time.RFC3339),
 	}
 	if len(m.AgentThoughts) > 0 {
 		spec.AgentThoughts = make([]*AgentThoughtSpec, 0, len(m.AgentThoughts))
@@ -136,6 +136,12 @@ type ChatRequest struct {
 	Query string `json:"query" binding:"required"`
 }
 
+// ResumeChatRequest 续传聊天回复
+type ResumeChatRequest struct {
+	// 已收到的事件数，从下一个事件开始续传
+	Offset int `json:"offset" form:"offset" binding:"min=0"`
+}
+
 type GenChatTitleResponse struct {
 	Title string `json:"title" binding:"required"`
 }
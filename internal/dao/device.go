@@ -29,7 +29,14 @@ type AccessTokenSpec struct {
 	AccessToken string `json:"accessToken" binding:"required"`
 	CreateTime  string `json:"createTime" binding:"datetime=2006-01-02T15:04:05Z07:00"`
 	ExpireTime  string `json:"expireTime" binding:"datetime=2006-01-02T15:04:05Z07:00"`
-	DeviceUuid  string `json:"deviceUuid"`
+	// 最近一次使用该令牌注册的设备
+	DeviceUuid string `json:"deviceUuid"`
+	// 可注册的设备数量上限
+	MaxBinds int `json:"maxBinds"`
+	// 已注册的设备数量
+	BindCount int `json:"bindCount"`
+	// 最近一次注册设备的时间，未使用过时为空
+	LastBindTime string `json:"lastBindTime,omitempty"`
 }
 
 func FromAccessTokenModel(m *model.AccessToken) *AccessTokenSpec {
@@ -39,16 +46,24 @@ func FromAccessTokenModel(m *model.AccessToken) *AccessTokenSpec {
 	t := &AccessTokenSpec{}
 	t.Id = int(m.Id)
 	t.AccessToken = m.AccessToken
-	t.CreateTime = m.CreateTime.Format(time.RFC3339)
-	t.ExpireTime = m.ExpireTime.Format(time.RFC3339)
+	t.CreateTime = m.CreateTime.UTC().Format(time.RFC3339)
+	t.ExpireTime = m.ExpireTime.UTC().Format(time.RFC3339)
 	if m.DeviceUuid != "" {
 		t.DeviceUuid = m.DeviceUuid
 	}
+	t.MaxBinds = m.MaxBinds
+	t.BindCount = m.BindCount
+	if m.LastBindTime.Valid {
+		t.LastBindTime = m.LastBindTime.Time.UTC().Format(time.RFC3339)
+	}
 	return t
 }
 
 type CreateAccessTokenRequest struct {
-	ExpireTime string `json:"expireTime" binding:"datetime=2006-01-02T15:04:05Z07:00"`
+	// 过期时间，RFC3339 格式或 Unix 时间戳（秒或毫秒），默认 24 小时后
+	ExpireTime string `json:"expireTime" binding:"omitempty,timestamp"`
+	// 可注册的设备数量上限，默认 1，用于批量部署时一个令牌注册多台设备
+	MaxBinds int `json:"maxBinds" binding:"omitempty,min=1,max=10000"`
 }
 
 type CreateAccessTokenResponse struct {
@@ -66,12 +81,22 @@ type ListAccessTokenResponse struct {
 }
 
 type DeviceSpec struct {
-	Id           int    `json:"id"`
-	Name         string `json:"name"`
-	Token        string `json:"token"`
-	Uuid         string `json:"uuid"`
-	RegisterTime string `json:"registerTime"`
-	LastPingTime string `json:"lastPingTime"`
+	Id           int              `json:"id"`
+	Name         string           `json:"name"`
+	Token        string           `json:"token"`
+	Uuid         string           `json:"uuid"`
+	RegisterTime string           `json:"registerTime"`
+	LastPingTime string           `json:"lastPingTime"`
+	DiskUsage    *model.DiskUsage `json:"diskUsage,omitempty"`
+	MaxExecutors int              `json:"maxExecutors"`
+	// 设备软件版本，设备未上报时为空
+	Inventory *model.DeviceInventory `json:"inventory,omitempty"`
+	// 根据最近上报时间得出的状态：online 在线，degraded 上报延迟，offline 离线或未注册
+	State model.DeviceState `json:"state"`
+	// 是否已要求设备轮换令牌且尚未完成
+	RotateToken bool `json:"rotateToken"`
+	// 最近一次完成令牌轮换的时间，未轮换过时为空
+	TokenRotateTime string `json:"tokenRotateTime,omitempty"`
 }
 
 func FromDeviceModel(m *model.Device) *DeviceSpec {
@@ -83,16 +108,46 @@ func FromDeviceModel(m *model.Device) *DeviceSpec {
 	t.Token = m.Token
 	t.Uuid = m.Uuid
 	t.Name = m.Name
-	t.RegisterTime = m.RegisterTime.Time.Format(time.RFC3339)
+	t.RegisterTime = m.RegisterTime.Time.UTC().Format(time.RFC3339)
 	if !m.LastPingTime.Time.IsZero() {
-		t.LastPingTime = m.LastPingTime.Time.Format(time.RFC3339)
+		t.LastPingTime = m.LastPingTime.Time.UTC().Format(time.RFC3339)
+	}
+	t.DiskUsage = m.DiskUsage
+	t.MaxExecutors = m.MaxExecutors
+	t.Inventory = m.Inventory
+	t.State = m.State(time.Now())
+	t.RotateToken = m.RotateToken
+	if m.TokenRotateTime.Valid {
+		t.TokenRotateTime = m.TokenRotateTime.Time.UTC().Format(time.RFC3339)
 	}
 	return t
 }
 
+type UpdateDeviceRequest struct {
+	// 设备名称
+	Name *string `json:"name,omitempty" binding:"omitempty,min=1,max=96"`
+	// 最大并发执行任务数，0 表示使用设备本地配置
+	MaxExecutors *int `json:"maxExecutors,omitempty" binding:"omitempty,min=0"`
+}
+
+func (req *UpdateDeviceRequest) UpdateModel(d *model.Device) {
+	if req.Name != nil {
+		d.Name = *req.Name
+	}
+	if req.MaxExecutors != nil {
+		d.MaxExecutors = *req.MaxExecutors
+	}
+}
+
 type ListDeviceRequest struct {
 	Start int `json:"start" form:"start" binding:"min=0"`
 	Limit int `json:"limit" form:"limit" binding:"min=0,max=100"`
+	// 按状态过滤
+	State model.DeviceState `json:"state" form:"state" binding:"omitempty,oneof=online degraded offline"`
+}
+
+func (req *ListDeviceRequest) Filter() model.DeviceFilter {
+	return model.DeviceFilter{State: req.State}
 }
 
 type ListDeviceResponse struct {
@@ -100,10 +155,110 @@ type ListDeviceResponse struct {
 	Total   int64        `json:"total"`
 }
 
+// ListDeviceInventoryRequest 设备软件版本查询参数，版本按完整的版本段前缀匹配，
+// 如 v0.3 匹配 v0.3 和 v0.3.1，不匹配 v0.30
+type ListDeviceInventoryRequest struct {
+	Start int `json:"start" form:"start" binding:"min=0"`
+	Limit int `json:"limit" form:"limit" binding:"min=0,max=100"`
+	// 操作系统，模糊匹配
+	Os            string `json:"os" form:"os"`
+	Kernel        string `json:"kernel" form:"kernel"`
+	LuminaVersion string `json:"luminaVersion" form:"luminaVersion"`
+	FFmpegVersion string `json:"ffmpegVersion" form:"ffmpegVersion"`
+	OpenCVVersion string `json:"opencvVersion" form:"opencvVersion"`
+	TritonVersion string `json:"tritonVersion" form:"tritonVersion"`
+}
+
+func (req *ListDeviceInventoryRequest) Filter() model.InventoryFilter {
+	return model.InventoryFilter{
+		Os:            req.Os,
+		Kernel:        req.Kernel,
+		LuminaVersion: req.LuminaVersion,
+		FFmpegVersion: req.FFmpegVersion,
+		OpenCVVersion: req.OpenCVVersion,
+		TritonVersion: req.TritonVersion,
+	}
+}
+
+type DeviceInventorySpec struct {
+	Id           int                    `json:"id"`
+	Uuid         string                 `json:"uuid"`
+	Name         string                 `json:"name"`
+	LastPingTime string                 `json:"lastPingTime"`
+	Inventory    *model.DeviceInventory `json:"inventory,omitempty"`
+}
+
+type ListDeviceInventoryResponse struct {
+	Items []DeviceInventorySpec `json:"items"`
+	Total int64                 `json:"total"`
+	// 符合条件的设备按 lumina 版本计数，未上报版本的设备计入空版本
+	LuminaVersions map[string]int64 `json:"luminaVersions"`
+}
+
+func FromDeviceInventoryModel(m *model.Device) DeviceInventorySpec {
+	t := DeviceInventorySpec{
+		Id:        m.Id,
+		Uuid:      m.Uuid,
+		Name:      m.Name,
+		Inventory: m.Inventory,
+	}
+	if !m.LastPingTime.Time.IsZero() {
+		t.LastPingTime = m.LastPingTime.Time.UTC().Format(time.RFC3339)
+	}
+	return t
+}
+
 type DeviceJobStatus struct {
 	ExectorStatus model.ExectorStatus `json:"exectorStatus"`
+	RestartCount  int                 `json:"restartCount"`
+	LastError     string              `json:"lastError,omitempty"`
+	// 执行器健康检查失败的原因，为空表示健康
+	HealthReason string `json:"healthReason,omitempty"`
+	// 实际每秒推理帧数
+	FrameRate float64 `json:"frameRate"`
+	// 设备限流后的每秒推理帧数上限，0 表示未限流
+	FrameRateLimit float64 `json:"frameRateLimit"`
 }
 
 type DeviceStatus struct {
 	JobStatus map[string]DeviceJobStatus `josn:"jobStatus,omitempty"`
+	DiskUsage *model.DiskUsage           `json:"diskUsage,omitempty"`
+	// 设备软件版本，设备启动后定期采集
+	Inventory *model.DeviceInventory `json:"inventory,omitempty"`
+	// 对象存储不可用时随状态上报的小文件，服务端临时保存
+	Artifacts []DeviceArtifact `json:"artifacts,omitempty" binding:"max=4,dive"`
+	// 设备签名公钥（ed25519），启用证据签名时上报，首次上报后固定，设备注销后才可更换
+	SigningKey []byte `json:"signingKey,omitempty" binding:"omitempty,len=32"`
+}
+
+// DeviceArtifact 设备上传对象存储失败时随状态上报的小文件，如最新触发图片的缩略图、最近一次错误时的画面
+type DeviceArtifact struct {
+	JobUuid string `json:"jobUuid" binding:"required"`
+	// 类型：trigger_thumbnail 最新触发图片的缩略图，error_screenshot 最近一次错误时的画面
+	Kind        model.ArtifactKind `json:"kind" binding:"oneof=trigger_thumbnail error_screenshot"`
+	ContentType string             `json:"contentType" binding:"oneof=image/jpeg image/png"`
+	// 文件内容，base64 编码，最大 64KB
+	Data []byte `json:"data" binding:"required,max=65536"`
+	// 采集时间，Unix 纳秒时间戳
+	Timestamp int64 `json:"timestamp"`
+}
+
+type DeviceStatusResponse struct {
+	// 服务端为该设备设置的最大并发执行任务数，0 表示未设置
+	MaxExecutors int `json:"maxExecutors"`
+	// 为 true 时设备应调用轮换令牌接口更换令牌
+	RotateToken bool `json:"rotateToken"`
+}
+
+type RotateDeviceTokenResponse struct {
+	// 新令牌，设备首次使用后旧令牌失效
+	Token string `json:"token"`
+}
+
+// WatchRequest 设备拉取任务的长轮询参数
+type WatchRequest struct {
+	// 为 true 且 If-None-Match 与当前 ETag 相同时，等待变更或超时后再返回，超时返回 304
+	Watch bool `json:"watch" form:"watch"`
+	// 等待的最长秒数，默认 30，最大 60
+	Timeout int `json:"timeout" form:"timeout" binding:"min=0,max=60"`
 }
@@ -0,0 +1,146 @@
+package dao
+
+import (
+	"time"
+
+	"lumina/internal/model"
+)
+
+type DeviceGroupSpec struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CreateTime  string `json:"createTime"`
+	UpdateTime  string `json:"updateTime"`
+	// 发布通道，stable 或 beta
+	Channel model.ReleaseChannel `json:"channel"`
+	// 升级时段，为空表示任意时间
+	UpgradeWindow *JobSchedule `json:"upgradeWindow,omitempty"`
+	// 成员设备，列表接口不返回
+	Devices []DeviceSpec `json:"devices,omitempty"`
+}
+
+func FromDeviceGroupModel(m *model.DeviceGroup, devices []model.Device) *DeviceGroupSpec {
+	if m == nil {
+		return nil
+	}
+	g := &DeviceGroupSpec{
+		Id:            m.Id,
+		Name:          m.Name,
+		Description:   m.Description,
+		CreateTime:    m.CreateTime.UTC().Format(time.RFC3339),
+		UpdateTime:    m.UpdateTime.UTC().Format(time.RFC3339),
+		Channel:       m.Channel,
+		UpgradeWindow: fromJobScheduleModel(m.UpgradeWindow),
+	}
+	if g.Channel == "" {
+		g.Channel = model.ReleaseChannelStable
+	}
+	if devices != nil {
+		g.Devices = make([]DeviceSpec, 0, len(devices))
+		for _, d := range devices {
+			g.Devices = append(g.Devices, *FromDeviceModel(&d))
+		}
+	}
+	return g
+}
+
+type CreateDeviceGroupRequest struct {
+	// 设备组名称
+	Name string `json:"name" binding:"required,max=96"`
+	// 描述
+	Description string `json:"description" binding:"max=255"`
+	// 成员设备ID
+	DeviceIds []int `json:"deviceIds"`
+	// 发布通道，stable 或 beta，默认 stable
+	Channel model.ReleaseChannel `json:"channel,omitempty" binding:"omitempty,oneof=stable beta"`
+	// 升级时段，为空表示任意时间
+	UpgradeWindow *JobSchedule `json:"upgradeWindow,omitempty"`
+}
+
+func (req *CreateDeviceGroupRequest) ToModel() *model.DeviceGroup {
+	g := &model.DeviceGroup{
+		Name:          req.Name,
+		Description:   req.Description,
+		Channel:       req.Channel,
+		UpgradeWindow: req.UpgradeWindow.ToModel(),
+	}
+	if g.Channel == "" {
+		g.Channel = model.ReleaseChannelStable
+	}
+	return g
+}
+
+type CreateDeviceGroupResponse struct {
+	Id int `json:"id"`
+}
+
+type UpdateDeviceGroupRequest struct {
+	// 设备组名称
+	Name *string `json:"name,omitempty" binding:"omitempty,min=1,max=96"`
+	// 描述
+	Description *string `json:"description,omitempty" binding:"omitempty,max=255"`
+	// 成员设备ID，传入时整体替换，传空数组表示清空
+	DeviceIds []int `json:"deviceIds,omitempty"`
+	// 发布通道，stable 或 beta
+	Channel *model.ReleaseChannel `json:"channel,omitempty" binding:"omitempty,oneof=stable beta"`
+	// 升级时段，传空的时段表示任意时间
+	UpgradeWindow *JobSchedule `json:"upgradeWindow,omitempty"`
+}
+
+func (req *UpdateDeviceGroupRequest) UpdateModel(g *model.DeviceGroup) {
+	if req.Name != nil {
+		g.Name = *req.Name
+	}
+	if req.Description != nil {
+		g.Description = *req.Description
+	}
+	if req.Channel != nil {
+		g.Channel = *req.Channel
+	}
+	if req.UpgradeWindow != nil {
+		g.UpgradeWindow = req.UpgradeWindow.ToModel()
+	}
+}
+
+type ListDeviceGroupsRequest struct {
+	Start int `json:"start" form:"start" binding:"min=0"`
+	Limit int `json:"limit" form:"limit" binding:"min=0,max=200"`
+}
+
+type ListDeviceGroupsResponse struct {
+	Items []DeviceGroupSpec `json:"items"`
+	Total int64             `json:"total"`
+}
+
+// JobDeviceStatus 设备组任务在单个设备上的运行状态
+type JobDeviceStatus struct {
+	Device       *DeviceSpec `json:"device"`
+	Status       string      `json:"status"`
+	RestartCount int         `json:"restartCount"`
+	LastError    string      `json:"lastError,omitempty"`
+	HealthReason string      `json:"healthReason,omitempty"`
+	// 实际每秒推理帧数
+	FrameRate float64 `json:"frameRate"`
+	// 设备限流后的每秒推理帧数上限，0 表示未限流
+	FrameRateLimit float64 `json:"frameRateLimit"`
+	// 最近一次上报时间
+	UpdateTime string `json:"updateTime"`
+}
+
+func FromJobDeviceStatusModel(m *model.JobDeviceStatus, device *model.Device) *JobDeviceStatus {
+	return &JobDeviceStatus{
+		Device:         FromDeviceModel(device),
+		Status:         m.Status.String(),
+		RestartCount:   m.RestartCount,
+		LastError:      m.LastError,
+		HealthReason:   m.HealthReason,
+		FrameRate:      m.FrameRate,
+		FrameRateLimit: m.FrameRateLimit,
+		UpdateTime:     m.UpdateTime.UTC().Format(time.RFC3339),
+	}
+}
+
+type ListJobDeviceStatusResponse struct {
+	Items []JobDeviceStatus `json:"items"`
+}
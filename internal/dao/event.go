@@ -0,0 +1,65 @@
+package dao
+
+import (
+	"strings"
+
+	"lumina/internal/model"
+)
+
+type EventKind string
+
+const (
+	EventKindDeviceState EventKind = "device_state"
+	EventKindJobStatus   EventKind = "job_status"
+	EventKindAlert       EventKind = "alert"
+)
+
+// Event 推送给仪表盘的实时事件，按类型只有 device、job、alert 之一
+type Event struct {
+	// 类型：device_state 设备在线状态变化，job_status 任务状态变更，alert 新告警
+	Kind   EventKind         `json:"kind"`
+	Time   string            `json:"time"`
+	Device *DeviceStateEvent `json:"device,omitempty"`
+	Job    *JobStatusEvent   `json:"job,omitempty"`
+	Alert  *AlertEvent       `json:"alert,omitempty"`
+	// 所属组织，只推送给同组织的订阅
+	OrgId int `json:"-"`
+}
+
+// DeviceStateEvent 设备在线状态变化
+type DeviceStateEvent struct {
+	DeviceId   int               `json:"deviceId"`
+	DeviceUuid string            `json:"deviceUuid"`
+	DeviceName string            `json:"deviceName"`
+	State      model.DeviceState `json:"state"`
+	PrevState  model.DeviceState `json:"prevState"`
+}
+
+// JobStatusEvent 任务状态变更
+type JobStatusEvent struct {
+	JobId      int                  `json:"jobId"`
+	JobUuid    string               `json:"jobUuid"`
+	Transition JobStatusHistorySpec `json:"transition"`
+}
+
+type EventFilter struct {
+	// 订阅的事件类型，逗号分隔，为空时订阅全部
+	Kinds string `form:"kinds"`
+	// 订阅者的组织，由服务端设置
+	OrgId int `form:"-"`
+}
+
+func (f EventFilter) Match(e *Event) bool {
+	if f.OrgId != model.AllOrgs && f.OrgId != e.OrgId {
+		return false
+	}
+	if f.Kinds == "" {
+		return true
+	}
+	for _, k := range strings.Split(f.Kinds, ",") {
+		if EventKind(strings.TrimSpace(k)) == e.Kind {
+			return true
+		}
+	}
+	return false
+}
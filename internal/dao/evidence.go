@@ -0,0 +1,133 @@
+package dao
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"lumina/internal/model"
+)
+
+// MessageEvidence 设备对消息内容和上传文件的签名，用于证明画面和检测结果在采集后未被篡改
+type MessageEvidence struct {
+	// 签名设备的uuid
+	DeviceUuid string `json:"deviceUuid"`
+	// 签名公钥的指纹
+	KeyFingerprint string `json:"keyFingerprint"`
+	// 图片文件的 SHA-256，十六进制
+	ImageSha256 string `json:"imageSha256,omitempty"`
+	// 视频文件的 SHA-256，十六进制
+	VideoSha256 string `json:"videoSha256,omitempty"`
+	// 签名的内容，即 EvidencePayload 的 json
+	Payload []byte `json:"payload"`
+	// Payload 的 ed25519 签名
+	Signature []byte `json:"signature"`
+}
+
+// EvidencePayload is the content a device signs for a message. It is kept
+// as signed with the message, so the timestamp keeps its nanoseconds the
+// datetime column drops.
+type EvidencePayload struct {
+	DeviceUuid  string          `json:"deviceUuid"`
+	JobUuid     string          `json:"jobUuid"`
+	Timestamp   int64           `json:"timestamp"`
+	ImagePath   string          `json:"imagePath,omitempty"`
+	ImageSha256 string          `json:"imageSha256,omitempty"`
+	VideoPath   string          `json:"videoPath,omitempty"`
+	VideoSha256 string          `json:"videoSha256,omitempty"`
+	DetectBoxes []*DetectionBox `json:"detectBoxes,omitempty"`
+	FrameWidth  int             `json:"frameWidth"`
+	FrameHeight int             `json:"frameHeight"`
+}
+
+// SignMessage signs the message and the hashes of the media it references
+// with the device key, and attaches the evidence to the message.
+func SignMessage(m *DeviceMessage, deviceUuid string, key ed25519.PrivateKey, imageSha256, videoSha256 string) error {
+	payload, err := json.Marshal(EvidencePayload{
+		DeviceUuid:  deviceUuid,
+		JobUuid:     m.JobUuid,
+		Timestamp:   m.Timestamp,
+		ImagePath:   m.ImagePath,
+		ImageSha256: imageSha256,
+		VideoPath:   m.VideoPath,
+		VideoSha256: videoSha256,
+		DetectBoxes: m.DetectBoxes,
+		FrameWidth:  m.FrameWidth,
+		FrameHeight: m.FrameHeight,
+	})
+	if err != nil {
+		return err
+	}
+	m.Evidence = &MessageEvidence{
+		DeviceUuid:     deviceUuid,
+		KeyFingerprint: KeyFingerprint(key.Public().(ed25519.PublicKey)),
+		ImageSha256:    imageSha256,
+		VideoSha256:    videoSha256,
+		Payload:        payload,
+		Signature:      ed25519.Sign(key, payload),
+	}
+	return nil
+}
+
+// KeyFingerprint identifies a signing key, it is the hex SHA-256 of the
+// public key.
+func KeyFingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:])
+}
+
+func (e MessageEvidence) ToModel() *model.MessageEvidence {
+	return &model.MessageEvidence{
+		DeviceUuid:     e.DeviceUuid,
+		KeyFingerprint: e.KeyFingerprint,
+		ImageSha256:    e.ImageSha256,
+		VideoSha256:    e.VideoSha256,
+		Payload:        e.Payload,
+		Signature:      e.Signature,
+	}
+}
+
+type VerifyMessageRequest struct {
+	// 是否下载图片和视频校验其哈希，默认 true
+	CheckMedia *bool `form:"checkMedia"`
+}
+
+// MessageVerification 消息的签名校验报告
+type MessageVerification struct {
+	MessageId int `json:"messageId"`
+	// 消息是否带有设备签名
+	Signed bool `json:"signed"`
+	// 签名、内容和文件均校验通过
+	Verified bool `json:"verified"`
+	// 签名设备的uuid
+	DeviceUuid string `json:"deviceUuid,omitempty"`
+	// 签名公钥的指纹
+	KeyFingerprint string `json:"keyFingerprint,omitempty"`
+	// 签名公钥登记的时间
+	KeyRegisterTime string `json:"keyRegisterTime,omitempty"`
+	// 签名是否由登记的设备公钥签出
+	SignatureValid bool `json:"signatureValid"`
+	// 消息内容是否与签名的内容一致
+	ContentMatches bool `json:"contentMatches"`
+	// 与签名内容不一致的字段
+	Mismatches []string `json:"mismatches,omitempty"`
+	// 图片和视频文件的校验结果，未校验文件时为空
+	Media []MediaVerification `json:"media,omitempty"`
+	// 无法校验的原因，如公钥未登记
+	Error string `json:"error,omitempty"`
+	// 校验时间
+	VerifyTime string `json:"verifyTime"`
+}
+
+// MediaVerification 图片或视频文件的哈希校验结果
+type MediaVerification struct {
+	Path string `json:"path"`
+	// 签名时的 SHA-256
+	ExpectedSha256 string `json:"expectedSha256"`
+	// 当前文件的 SHA-256，文件无法读取时为空
+	ActualSha256 string `json:"actualSha256,omitempty"`
+	Matches      bool   `json:"matches"`
+	// 文件无法读取的原因
+	Error string `json:"error,omitempty"`
+}
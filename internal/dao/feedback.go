@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"time"
+
+	"lumina/internal/model"
+)
+
+// MessageFeedbackRequest 标注消息的检测结果是否正确
+type MessageFeedbackRequest struct {
+	// 标注结果：true_positive 检测正确，false_positive 误报
+	Verdict model.FeedbackVerdict `json:"verdict" binding:"required,oneof=true_positive false_positive"`
+	// 修正后的标签，即消息实际应有的标签，检测正确时可为空
+	CorrectedLabels []string `json:"correctedLabels" binding:"max=64,dive,required,max=64"`
+	// 备注
+	Comment string `json:"comment" binding:"max=4096"`
+}
+
+// MessageFeedbackSpec 消息的人工标注
+type MessageFeedbackSpec struct {
+	MessageId       int                   `json:"messageId"`
+	JobId           int                   `json:"jobId"`
+	Verdict         model.FeedbackVerdict `json:"verdict"`
+	CorrectedLabels []string              `json:"correctedLabels,omitempty"`
+	Comment         string                `json:"comment,omitempty"`
+	UserId          int                   `json:"userId"`
+	Username        string                `json:"username"`
+	CreateTime      string                `json:"createTime"`
+	UpdateTime      string                `json:"updateTime"`
+}
+
+func FromMessageFeedbackModel(f *model.MessageFeedback) *MessageFeedbackSpec {
+	return &MessageFeedbackSpec{
+		MessageId:       f.MessageId,
+		JobId:           f.JobId,
+		Verdict:         f.Verdict,
+		CorrectedLabels: f.CorrectedLabels,
+		Comment:         f.Comment,
+		UserId:          f.UserId,
+		Username:        f.Username,
+		CreateTime:      f.CreateTime.UTC().Format(time.RFC3339),
+		UpdateTime:      f.UpdateTime.UTC().Format(time.RFC3339),
+	}
+}
+
+// JobPrecisionRequest 任务检测准确率趋势查询参数，时间格式同 JobStatsRequest
+type JobPrecisionRequest struct {
+	// 开始时间，默认 30 天前
+	Start string `json:"start" form:"start"`
+	// 结束时间，默认当前时间
+	End string `json:"end" form:"end"`
+	// 时间桶大小，如 6h、24h，默认 24h，最小 1h，最大 720h
+	Bucket string `json:"bucket" form:"bucket"`
+}
+
+// PrecisionPoint 一个时间桶内被标注消息的准确率
+type PrecisionPoint struct {
+	// 时间桶的开始时间
+	Time string `json:"time"`
+	// 标注为检测正确的消息数
+	TruePositive int64 `json:"truePositive"`
+	// 标注为误报的消息数
+	FalsePositive int64 `json:"falsePositive"`
+	// 准确率，即 TruePositive / (TruePositive + FalsePositive)
+	Precision float64 `json:"precision"`
+}
+
+// JobPrecisionResponse 任务检测准确率趋势，只统计有人工标注的消息，
+// 按消息的采集时间分桶，没有标注的时间桶不返回
+type JobPrecisionResponse struct {
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	Bucket string `json:"bucket"`
+	// 整个时间范围的准确率
+	Total  PrecisionPoint   `json:"total"`
+	Points []PrecisionPoint `json:"points"`
+}
+
+// NewPrecisionPoint computes the precision of the counts, 0 if there are
+// none.
+func NewPrecisionPoint(t string, tp, fp int64) PrecisionPoint {
+	p := PrecisionPoint{Time: t, TruePositive: tp, FalsePositive: fp}
+	if tp+fp > 0 {
+		p.Precision = float64(tp) / float64(tp+fp)
+	}
+	return p
+}
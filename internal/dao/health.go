@@ -0,0 +1,98 @@
+package dao
+
+import (
+	"time"
+
+	"lumina/internal/model"
+)
+
+// HealthEventSpec 系统健康事件
+type HealthEventSpec struct {
+	Id int `json:"id"`
+	// 类型：message_volume 消息量异常，alert_volume 告警量异常，canary 消息处理链路自检失败，device_offline 设备离线
+	Kind  model.HealthEventKind `json:"kind"`
+	JobId int                   `json:"jobId,omitempty"`
+	// 设备相关事件的设备ID
+	DeviceId int    `json:"deviceId,omitempty"`
+	Summary  string `json:"summary"`
+	// 最近一个异常时段的实际值
+	Observed float64 `json:"observed"`
+	// 最近一个异常时段的通常值
+	Expected float64 `json:"expected"`
+	// 偏离程度，实际值与通常值相差的标准差倍数
+	Score float64 `json:"score"`
+	// 异常开始时间
+	StartTime string `json:"startTime"`
+	// 最近一个异常时段的结束时间
+	EndTime     string `json:"endTime"`
+	Resolved    bool   `json:"resolved"`
+	ResolveTime string `json:"resolveTime,omitempty"`
+	CreateTime  string `json:"createTime"`
+}
+
+func FromHealthEventModel(m *model.HealthEvent) *HealthEventSpec {
+	e := &HealthEventSpec{
+		Id:         m.Id,
+		Kind:       m.Kind,
+		JobId:      m.JobId,
+		DeviceId:   m.DeviceId,
+		Summary:    m.Summary,
+		Observed:   m.Observed,
+		Expected:   m.Expected,
+		Score:      m.Score,
+		StartTime:  m.StartTime.UTC().Format(time.RFC3339),
+		EndTime:    m.EndTime.UTC().Format(time.RFC3339),
+		Resolved:   m.Resolved,
+		CreateTime: m.CreateTime.UTC().Format(time.RFC3339),
+	}
+	if m.ResolveTime != nil {
+		e.ResolveTime = m.ResolveTime.UTC().Format(time.RFC3339)
+	}
+	return e
+}
+
+type ListHealthEventsRequest struct {
+	Start int `json:"start" form:"start" binding:"min=0"`
+	Limit int `json:"limit" form:"limit" binding:"min=0,max=100"`
+	// 事件类型
+	Kind model.HealthEventKind `json:"kind" form:"kind" binding:"omitempty,oneof=message_volume alert_volume canary device_offline"`
+	// 只列出指定任务的事件
+	JobId int `json:"jobId" form:"jobId" binding:"min=0"`
+	// 只列出指定设备的事件
+	DeviceId int `json:"deviceId" form:"deviceId" binding:"min=0"`
+	// 为 true 只列出已解决的事件，为 false 只列出未解决的事件
+	Resolved *bool `json:"resolved" form:"resolved"`
+}
+
+func (req *ListHealthEventsRequest) Filter() model.HealthEventFilter {
+	return model.HealthEventFilter{
+		Kind:     req.Kind,
+		JobId:    req.JobId,
+		DeviceId: req.DeviceId,
+		Resolved: req.Resolved,
+	}
+}
+
+type ListHealthEventsResponse struct {
+	Items []HealthEventSpec `json:"items"`
+	Total int64             `json:"total"`
+}
+
+// CanaryRun 一次消息处理链路自检
+type CanaryRun struct {
+	Time string `json:"time"`
+	// 测试消息是否在时限内经过消费者和工作流处理完成
+	Success bool `json:"success"`
+	// 端到端耗时，单位毫秒，未完成时为 0
+	Latency int64  `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// CanaryStatusResponse 消息处理链路自检状态
+type CanaryStatusResponse struct {
+	Enabled bool `json:"enabled"`
+	// 最近各次自检的成功率
+	SuccessRate float64 `json:"successRate"`
+	// 最近各次自检，按时间倒序
+	Runs []CanaryRun `json:"runs"`
+}
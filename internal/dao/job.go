@@ -2,6 +2,7 @@ package dao
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -12,11 +13,72 @@ import (
 type DetectOptions struct {
 	ModelName       string  `json:"modelName" binding:"required"`
 	Labels          string  `json:"labels,omitempty"`
-	ConfThreshold   float32 `json:"confThreshold,omitempty"`
-	IoUThreshold    float32 `json:"iouThreshold,omitempty"`
-	Interval        int     `json:"interval,omitempty"`
-	TriggerCount    int     `json:"triggerCount,omitempty"`
-	TriggerInterval int     `json:"triggerInterval,omitempty"`
+	ConfThreshold   float32 `json:"confThreshold"`
+	IoUThreshold    float32 `json:"iouThreshold"`
+	Interval        int     `json:"interval"`
+	TriggerCount    int     `json:"triggerCount"`
+	TriggerInterval int     `json:"triggerInterval"`
+	// 需要打码的类别，逗号分隔，如 face,license_plate
+	PrivacyLabels string `json:"privacyLabels,omitempty"`
+	// 打码方式，blur 或 pixelate，默认 blur
+	PrivacyMode model.PrivacyMode `json:"privacyMode,omitempty" binding:"omitempty,oneof=blur pixelate"`
+	// 区域人数统计，基于跟踪的检测结果统计各区域的人数和进出人次
+	Occupancy *OccupancyOptions `json:"occupancy,omitempty"`
+}
+
+type OccupancyZone struct {
+	// 区域名称
+	Name string `json:"name" binding:"required"`
+	// 区域多边形顶点，坐标按画面宽高归一化到 0~1
+	Polygon [][2]float64 `json:"polygon" binding:"min=3"`
+}
+
+type OccupancyOptions struct {
+	// 统计的类别，逗号分隔，默认 person
+	Labels string `json:"labels,omitempty"`
+	// 统计区域，为空时统计整个画面
+	Zones []OccupancyZone `json:"zones,omitempty" binding:"omitempty,dive"`
+	// 上报间隔，单位秒，默认 60
+	Interval int `json:"interval,omitempty" binding:"min=0"`
+}
+
+// GetLabelSet returns the labels to count.
+func (o *OccupancyOptions) GetLabelSet() map[string]struct{} {
+	labelSet := make(map[string]struct{})
+	for _, label := range strings.Split(o.Labels, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labelSet[label] = struct{}{}
+		}
+	}
+	return labelSet
+}
+
+func fromOccupancyModel(o *model.OccupancyOptions) *OccupancyOptions {
+	if o == nil {
+		return nil
+	}
+	res := &OccupancyOptions{Labels: o.Labels, Interval: o.Interval}
+	for _, z := range o.Zones {
+		res.Zones = append(res.Zones, OccupancyZone(z))
+	}
+	return res
+}
+
+func (o *OccupancyOptions) ToModel() *model.OccupancyOptions {
+	if o == nil {
+		return nil
+	}
+	m := &model.OccupancyOptions{Labels: o.Labels, Interval: o.Interval}
+	for _, z := range o.Zones {
+		m.Zones = append(m.Zones, model.OccupancyZone(z))
+	}
+	if m.Labels == "" {
+		m.Labels = "person"
+	}
+	if m.Interval == 0 {
+		m.Interval = 60
+	}
+	return m
 }
 
 func (d *DetectOptions) GetLabelMap() map[int]string {
@@ -28,25 +90,167 @@ func (d *DetectOptions) GetLabelMap() map[int]string {
 	return labelMap
 }
 
+// GetPrivacyLabelSet returns the labels whose boxes must be masked in the
+// uploaded image, nil if privacy masking is disabled.
+func (d *DetectOptions) GetPrivacyLabelSet() map[string]struct{} {
+	var labelSet map[string]struct{}
+	for _, label := range strings.Split(d.PrivacyLabels, ",") {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			continue
+		}
+		if labelSet == nil {
+			labelSet = make(map[string]struct{})
+		}
+		labelSet[label] = struct{}{}
+	}
+	return labelSet
+}
+
 type VideoSegmentOptions struct {
 	Interval int `json:"interval,omitempty"`
 }
 
+type JobHook struct {
+	// 钩子名称，如 static_metadata、file_metadata、homography
+	Name string `json:"name" binding:"required"`
+	// 钩子参数
+	Params map[string]any `json:"params,omitempty"`
+}
+
+// TalkDownOptions 告警时通过现场扬声器播放的喊话，音频地址优先于文本
+type TalkDownOptions struct {
+	Enabled bool `json:"enabled"`
+	// 喊话文本，由设备合成语音
+	Text string `json:"text,omitempty"`
+	// 音频文件地址
+	AudioUrl string `json:"audioUrl,omitempty"`
+	// 播放方式，onvif 为摄像头音频回传通道，local 为设备本地音频输出
+	Output string `json:"output,omitempty" binding:"omitempty,oneof=onvif local"`
+	// 告警触发喊话的冷却时间，单位秒
+	Cooldown int `json:"cooldown" binding:"min=0"`
+}
+
+func fromTalkDownModel(t *model.TalkDownOptions) *TalkDownOptions {
+	if t == nil {
+		return nil
+	}
+	return &TalkDownOptions{
+		Enabled:  t.Enabled,
+		Text:     t.Text,
+		AudioUrl: t.AudioUrl,
+		Output:   t.Output,
+		Cooldown: t.Cooldown,
+	}
+}
+
+func (t *TalkDownOptions) ToModel() *model.TalkDownOptions {
+	m := &model.TalkDownOptions{
+		Enabled:  t.Enabled,
+		Text:     t.Text,
+		AudioUrl: t.AudioUrl,
+		Output:   t.Output,
+		Cooldown: t.Cooldown,
+	}
+	if m.Output == "" {
+		m.Output = model.TalkDownOutputOnvif
+	}
+	return m
+}
+
+func fromJobHooksModel(hooks model.JobHooks) []JobHook {
+	if hooks == nil {
+		return nil
+	}
+	res := make([]JobHook, len(hooks))
+	for i, h := range hooks {
+		res[i] = JobHook(h)
+	}
+	return res
+}
+
+func toJobHooksModel(hooks []JobHook) model.JobHooks {
+	if hooks == nil {
+		return nil
+	}
+	res := make(model.JobHooks, len(hooks))
+	for i, h := range hooks {
+		res[i] = model.JobHook(h)
+	}
+	return res
+}
+
+// SeverityRule 告警级别规则，告警字段满足过滤条件时使用该级别
+type SeverityRule struct {
+	Severity model.AlertSeverity `json:"severity" binding:"required,oneof=low medium high critical"`
+	Filter   FilterCondition     `json:"filter"`
+}
+
+func fromSeverityRulesModel(rules model.SeverityRules) []SeverityRule {
+	if rules == nil {
+		return nil
+	}
+	res := make([]SeverityRule, len(rules))
+	for i, r := range rules {
+		res[i] = SeverityRule{
+			Severity: r.Severity,
+			Filter:   *FromFilterConditionModel(&r.Filter),
+		}
+	}
+	return res
+}
+
+func toSeverityRulesModel(rules []SeverityRule) model.SeverityRules {
+	if rules == nil {
+		return nil
+	}
+	res := make(model.SeverityRules, len(rules))
+	for i, r := range rules {
+		res[i] = model.SeverityRule{
+			Severity: r.Severity,
+			Filter:   *r.Filter.ToModel(),
+		}
+	}
+	return res
+}
+
 type JobSpec struct {
-	Id           int                  `json:"id"`
-	Uuid         string               `json:"uuid" binding:"required"`
-	Kind         model.JobKind        `json:"kind" binding:"required"`
-	Status       string               `json:"status" binding:"required"`
-	Enabled      bool                 `json:"enabled" binding:"required"`
+	Id       int           `json:"id"`
+	Uuid     string        `json:"uuid" binding:"required"`
+	Kind     model.JobKind `json:"kind" binding:"required"`
+	Status   string        `json:"status" binding:"required"`
+	Enabled  bool          `json:"enabled" binding:"required"`
+	Paused   bool          `json:"paused"`
+	Priority int           `json:"priority"`
+	// 每秒推理帧数上限，0 表示只受检测间隔限制
+	MaxFps       float64              `json:"maxFps"`
 	Camera       CameraSpec           `json:"camera" binding:"required"`
 	CreateTime   string               `json:"createTime" binding:"required,datetime=2006-01-02T15:04:05Z07:00"`
 	UpdateTime   string               `json:"updateTime" binding:"required,datetime=2006-01-02T15:04:05Z07:00"`
 	Detect       *DetectOptions       `json:"detect,omitempty"`
 	VideoSegment *VideoSegmentOptions `json:"videoSegment,omitempty"`
+	Plugin       map[string]any       `json:"plugin,omitempty"`
+	Hooks        []JobHook            `json:"hooks,omitempty"`
+	TalkDown     *TalkDownOptions     `json:"talkDown,omitempty"`
 	Workflow     *WorkflowSpec        `json:"workflow,omitempty"`
 	Query        string               `json:"query,omitempty"`
 	Device       *DeviceSpec          `json:"device,omitempty"`
 	ResultFilter *FilterCondition     `json:"resultFilter,omitempty"`
+	RestartCount int                  `json:"restartCount"`
+	LastError    string               `json:"lastError,omitempty"`
+	HealthReason string               `json:"healthReason,omitempty"`
+	// 实际每秒推理帧数，设备组任务见各设备的状态
+	FrameRate float64 `json:"frameRate"`
+	// 设备限流后的每秒推理帧数上限，0 表示未限流
+	FrameRateLimit float64 `json:"frameRateLimit"`
+	// 目标设备组，任务在组内所有设备上运行
+	DeviceGroup *DeviceGroupSpec `json:"deviceGroup,omitempty"`
+	// 布防计划，为空表示始终布防
+	Schedule *JobSchedule `json:"schedule,omitempty"`
+	// 告警级别规则，按顺序匹配，都不匹配时由分析结果的置信度决定
+	SeverityRules []SeverityRule `json:"severityRules,omitempty"`
+	// 告警去重窗口，单位秒，窗口内相同标签的重复告警合并为一条并累计次数，0 表示不去重
+	AlertDedupWindow int `json:"alertDedupWindow"`
 }
 
 func (j JobSpec) Input() string {
@@ -66,14 +270,29 @@ func FromJobModel(job *model.Job) (*JobSpec, error) {
 		return nil, err
 	}
 	j := &JobSpec{
-		Id:         job.Id,
-		Uuid:       job.Uuid,
-		Kind:       job.Kind,
-		Status:     job.Status.String(),
-		Enabled:    job.Enabled,
-		Camera:     *cameraSpec,
-		CreateTime: job.CreateTime.Format(time.RFC3339),
-		UpdateTime: job.UpdateTime.Format(time.RFC3339),
+		Id:               job.Id,
+		Uuid:             job.Uuid,
+		Kind:             job.Kind,
+		Status:           job.Status.String(),
+		Enabled:          job.Enabled,
+		Paused:           job.Paused,
+		Priority:         job.Priority,
+		MaxFps:           job.MaxFps,
+		Camera:           *cameraSpec,
+		CreateTime:       job.CreateTime.UTC().Format(time.RFC3339),
+		UpdateTime:       job.UpdateTime.UTC().Format(time.RFC3339),
+		RestartCount:     job.RestartCount,
+		LastError:        job.LastError,
+		HealthReason:     job.HealthReason,
+		FrameRate:        job.FrameRate,
+		FrameRateLimit:   job.FrameRateLimit,
+		Plugin:           job.Plugin,
+		Hooks:            fromJobHooksModel(job.Hooks),
+		TalkDown:         fromTalkDownModel(job.TalkDown),
+		Schedule:         fromJobScheduleModel(job.Schedule),
+		ResultFilter:     FromFilterConditionModel(job.ResultFilter),
+		SeverityRules:    fromSeverityRulesModel(job.SeverityRules),
+		AlertDedupWindow: job.AlertDedupWindow,
 	}
 
 	if job.WorkflowId != 0 {
@@ -93,6 +312,9 @@ func FromJobModel(job *model.Job) (*JobSpec, error) {
 			Interval:        job.Detect.Interval,
 			TriggerCount:    job.Detect.TriggerCount,
 			TriggerInterval: job.Detect.TriggerInterval,
+			PrivacyLabels:   job.Detect.PrivacyLabels,
+			PrivacyMode:     job.Detect.PrivacyMode,
+			Occupancy:       fromOccupancyModel(job.Detect.Occupancy),
 		}
 	}
 
@@ -110,6 +332,14 @@ func FromJobModel(job *model.Job) (*JobSpec, error) {
 			j.Device = FromDeviceModel(device)
 		}
 	}
+
+	if job.DeviceGroupId != 0 {
+		group, err := model.GetDeviceGroupById(job.DeviceGroupId)
+		if err != nil {
+			return nil, err
+		}
+		j.DeviceGroup = FromDeviceGroupModel(group, nil)
+	}
 	return j, nil
 }
 
@@ -122,6 +352,24 @@ type CreateJobRequest struct {
 	WorkflowId   int                  `json:"workflowId,omitempty"`
 	Query        string               `json:"query,omitempty"`
 	ResultFilter *FilterCondition     `json:"resultFilter,omitempty"`
+	// 目标设备组，与 DeviceId 二选一
+	DeviceGroupId int `json:"deviceGroupId,omitempty"`
+	// 调度优先级，设备达到并发上限时优先级高的任务先启动，推理算力不足时优先级低的任务先降帧
+	Priority int `json:"priority,omitempty"`
+	// 每秒推理帧数上限，0 表示只受检测间隔限制
+	MaxFps float64 `json:"maxFps,omitempty" binding:"min=0"`
+	// 自定义任务类型的参数，原样传给设备上注册的执行器
+	Plugin map[string]any `json:"plugin,omitempty"`
+	// 设备发布消息前依次执行的后处理钩子
+	Hooks []JobHook `json:"hooks,omitempty" binding:"omitempty,dive"`
+	// 告警时的现场喊话
+	TalkDown *TalkDownOptions `json:"talkDown,omitempty"`
+	// 布防计划，为空表示始终布防
+	Schedule *JobSchedule `json:"schedule,omitempty"`
+	// 告警级别规则，按顺序匹配，都不匹配时由分析结果的置信度决定
+	SeverityRules []SeverityRule `json:"severityRules,omitempty" binding:"omitempty,dive"`
+	// 告警去重窗口，单位秒，窗口内相同标签的重复告警合并为一条并累计次数，0 表示不去重
+	AlertDedupWindow int `json:"alertDedupWindow,omitempty" binding:"min=0,max=86400"`
 }
 
 func (req *CreateJobRequest) ToModel() *model.Job {
@@ -133,6 +381,18 @@ func (req *CreateJobRequest) ToModel() *model.Job {
 		WorkflowId: req.WorkflowId,
 		DeviceId:   req.DeviceId,
 		Enabled:    true,
+		Priority:   req.Priority,
+		MaxFps:     req.MaxFps,
+		Plugin:     req.Plugin,
+		Hooks:      toJobHooksModel(req.Hooks),
+	}
+	job.DeviceGroupId = req.DeviceGroupId
+	job.Schedule = req.Schedule.ToModel()
+	job.ResultFilter = req.ResultFilter.ToModel()
+	job.SeverityRules = toSeverityRulesModel(req.SeverityRules)
+	job.AlertDedupWindow = req.AlertDedupWindow
+	if req.TalkDown != nil {
+		job.TalkDown = req.TalkDown.ToModel()
 	}
 
 	// 设置检测选项
@@ -145,6 +405,9 @@ func (req *CreateJobRequest) ToModel() *model.Job {
 			Interval:        req.Detect.Interval,
 			TriggerCount:    req.Detect.TriggerCount,
 			TriggerInterval: req.Detect.TriggerInterval,
+			PrivacyLabels:   req.Detect.PrivacyLabels,
+			PrivacyMode:     req.Detect.PrivacyMode,
+			Occupancy:       req.Detect.Occupancy.ToModel(),
 		}
 		// 设置默认值
 		if job.Detect.Interval == 0 {
@@ -162,6 +425,9 @@ func (req *CreateJobRequest) ToModel() *model.Job {
 		if job.Detect.TriggerInterval == 0 {
 			job.Detect.TriggerInterval = 30
 		}
+		if job.Detect.PrivacyLabels != "" && job.Detect.PrivacyMode == "" {
+			job.Detect.PrivacyMode = model.PrivacyModeBlur
+		}
 	}
 
 	// 设置视频分割选项
@@ -181,17 +447,126 @@ type CreateJobResponse struct {
 	Uuid string `json:"uuid"`
 }
 
+type JobCheckStatus string
+
+const (
+	JobCheckOk      JobCheckStatus = "ok"
+	JobCheckFailed  JobCheckStatus = "failed"
+	JobCheckSkipped JobCheckStatus = "skipped"
+)
+
+// JobCheck 任务就绪检查项
+type JobCheck struct {
+	// 检查项：target 运行设备，camera 摄像头连通性，model 模型加载，workflow 工作流接口
+	Name string `json:"name"`
+	// 结果：ok 通过，failed 未通过，skipped 不适用或无法检查
+	Status JobCheckStatus `json:"status"`
+	// 未通过或未检查的原因
+	Message string `json:"message,omitempty"`
+	// 检查耗时，单位毫秒
+	Duration int64 `json:"duration"`
+}
+
+// ValidateJobResponse 任务就绪报告
+type ValidateJobResponse struct {
+	// 没有未通过的检查项
+	Ready  bool       `json:"ready"`
+	Checks []JobCheck `json:"checks"`
+}
+
+// CloneJobRequest 复制任务请求，未设置的字段沿用原任务
+type CloneJobRequest struct {
+	// 目标摄像头
+	CameraId *int `json:"cameraId,omitempty"`
+	// 目标设备，设置非零的设备或设备组会清除另一个
+	DeviceId *int `json:"deviceId,omitempty"`
+	// 目标设备组
+	DeviceGroupId *int `json:"deviceGroupId,omitempty"`
+}
+
+// Clone returns a copy of job with a new uuid, retargeted as requested. The
+// copy keeps the options and the enabled state of job but none of its
+// runtime state.
+func (req *CloneJobRequest) Clone(job *model.Job) *model.Job {
+	clone := &model.Job{
+		Uuid:             str.GenDeviceId(16),
+		Kind:             job.Kind,
+		CameraId:         job.CameraId,
+		DeviceId:         job.DeviceId,
+		DeviceGroupId:    job.DeviceGroupId,
+		Status:           model.ExectorStatusStopped,
+		Enabled:          job.Enabled,
+		Priority:         job.Priority,
+		MaxFps:           job.MaxFps,
+		Detect:           job.Detect,
+		VideoSegment:     job.VideoSegment,
+		Plugin:           job.Plugin,
+		Hooks:            job.Hooks,
+		TalkDown:         job.TalkDown,
+		WorkflowId:       job.WorkflowId,
+		Schedule:         job.Schedule,
+		ResultFilter:     job.ResultFilter,
+		SeverityRules:    job.SeverityRules,
+		AlertDedupWindow: job.AlertDedupWindow,
+		OrgId:            job.OrgId,
+	}
+	if req.CameraId != nil {
+		clone.CameraId = *req.CameraId
+	}
+	if req.DeviceId != nil {
+		clone.DeviceId = *req.DeviceId
+		if clone.DeviceId != 0 {
+			clone.DeviceGroupId = 0
+		}
+	}
+	if req.DeviceGroupId != nil {
+		clone.DeviceGroupId = *req.DeviceGroupId
+		if clone.DeviceGroupId != 0 {
+			clone.DeviceId = 0
+		}
+	}
+	return clone
+}
+
 type UpdateJobRequest struct {
 	CameraId     *int                 `json:"cameraId,omitempty"`
 	Detect       *DetectOptions       `json:"detect,omitempty"`
 	VideoSegment *VideoSegmentOptions `json:"videoSegment,omitempty"`
 	WorkflowId   *int                 `json:"workflowId,omitempty"`
 	DeviceId     *int                 `json:"deviceId,omitempty"`
+	Priority     *int                 `json:"priority,omitempty"`
+	// 每秒推理帧数上限，0 表示只受检测间隔限制
+	MaxFps *float64 `json:"maxFps,omitempty" binding:"omitempty,min=0"`
+	// 目标设备组，设置非零的设备或设备组会清除另一个
+	DeviceGroupId *int `json:"deviceGroupId,omitempty"`
+	// 自定义任务类型的参数
+	Plugin map[string]any `json:"plugin,omitempty"`
+	// 后处理钩子，传空数组表示清空
+	Hooks []JobHook `json:"hooks,omitempty" binding:"omitempty,dive"`
+	// 告警时的现场喊话
+	TalkDown *TalkDownOptions `json:"talkDown,omitempty"`
+	// 布防计划，传空的时段表示取消计划
+	Schedule *JobSchedule `json:"schedule,omitempty"`
+	// 告警过滤条件，传空的条件表示使用工作流的过滤条件
+	ResultFilter *FilterCondition `json:"resultFilter,omitempty"`
+	// 告警级别规则，传空数组表示清空
+	SeverityRules []SeverityRule `json:"severityRules,omitempty" binding:"omitempty,dive"`
+	// 告警去重窗口，单位秒，0 表示不去重
+	AlertDedupWindow *int `json:"alertDedupWindow,omitempty" binding:"omitempty,min=0,max=86400"`
 }
 
 func (req *UpdateJobRequest) UpdateModel(job *model.Job) {
 	if req.DeviceId != nil {
 		job.DeviceId = *req.DeviceId
+		if job.DeviceId != 0 {
+			job.DeviceGroupId = 0
+		}
+	}
+	if req.DeviceGroupId != nil {
+		job.DeviceGroupId = *req.DeviceGroupId
+		if job.DeviceGroupId != 0 {
+			job.DeviceId = 0
+		}
 	}
 	if req.CameraId != nil {
 		job.CameraId = *req.CameraId
@@ -199,6 +574,33 @@ func (req *UpdateJobRequest) UpdateModel(job *model.Job) {
 	if req.WorkflowId != nil {
 		job.WorkflowId = *req.WorkflowId
 	}
+	if req.Priority != nil {
+		job.Priority = *req.Priority
+	}
+	if req.MaxFps != nil {
+		job.MaxFps = *req.MaxFps
+	}
+	if req.Plugin != nil {
+		job.Plugin = req.Plugin
+	}
+	if req.Hooks != nil {
+		job.Hooks = toJobHooksModel(req.Hooks)
+	}
+	if req.TalkDown != nil {
+		job.TalkDown = req.TalkDown.ToModel()
+	}
+	if req.ResultFilter != nil {
+		job.ResultFilter = req.ResultFilter.ToModel()
+	}
+	if req.SeverityRules != nil {
+		job.SeverityRules = toSeverityRulesModel(req.SeverityRules)
+	}
+	if req.AlertDedupWindow != nil {
+		job.AlertDedupWindow = *req.AlertDedupWindow
+	}
+	if req.Schedule != nil {
+		job.Schedule = req.Schedule.ToModel()
+	}
 	if req.Detect != nil {
 		job.Detect = &model.DetectOptions{
 			ModelName:       req.Detect.ModelName,
@@ -208,6 +610,12 @@ func (req *UpdateJobRequest) UpdateModel(job *model.Job) {
 			Interval:        req.Detect.Interval,
 			TriggerCount:    req.Detect.TriggerCount,
 			TriggerInterval: req.Detect.TriggerInterval,
+			PrivacyLabels:   req.Detect.PrivacyLabels,
+			PrivacyMode:     req.Detect.PrivacyMode,
+			Occupancy:       req.Detect.Occupancy.ToModel(),
+		}
+		if job.Detect.PrivacyLabels != "" && job.Detect.PrivacyMode == "" {
+			job.Detect.PrivacyMode = model.PrivacyModeBlur
 		}
 	}
 	if req.VideoSegment != nil {
@@ -226,3 +634,73 @@ type ListJobsResponse struct {
 	Items []JobSpec `json:"items"`
 	Total int64     `json:"total"`
 }
+
+// JobStatusHistorySpec 任务状态变更记录
+type JobStatusHistorySpec struct {
+	Id int `json:"id"`
+	// 来源：device 设备上报的执行器状态变化，server 通过接口进行的操作
+	Source model.JobStatusSource `json:"source"`
+	// 设备上报时为变更后的执行器状态，如 running、failed；接口操作时为操作，如 create、start、stop、pause、resume
+	Status string `json:"status"`
+	// 设备上报时变更前的执行器状态
+	PrevStatus string `json:"prevStatus,omitempty"`
+	// 上报的设备
+	DeviceId int `json:"deviceId,omitempty"`
+	// 进行操作的用户
+	Username string `json:"username,omitempty"`
+	// 变更时上报的错误或健康原因
+	Reason     string `json:"reason,omitempty"`
+	CreateTime string `json:"createTime"`
+}
+
+func FromJobStatusHistoryModel(m *model.JobStatusHistory) *JobStatusHistorySpec {
+	return &JobStatusHistorySpec{
+		Id:         m.Id,
+		Source:     m.Source,
+		Status:     m.Status,
+		PrevStatus: m.PrevStatus,
+		DeviceId:   m.DeviceId,
+		Username:   m.Username,
+		Reason:     m.Reason,
+		CreateTime: m.CreateTime.UTC().Format(time.RFC3339),
+	}
+}
+
+type ListJobStatusHistoryRequest struct {
+	Start int `json:"start" form:"start" binding:"min=0"`
+	Limit int `json:"limit" form:"limit" binding:"min=0,max=100"`
+}
+
+type ListJobStatusHistoryResponse struct {
+	Items []JobStatusHistorySpec `json:"items"`
+	Total int64                  `json:"total"`
+}
+
+// JobArtifactSpec 设备在对象存储不可用时随状态上报的小文件，服务端临时保存 24 小时
+type JobArtifactSpec struct {
+	// 类型：trigger_thumbnail 最新触发图片的缩略图，error_screenshot 最近一次错误时的画面
+	Kind        model.ArtifactKind `json:"kind"`
+	ContentType string             `json:"contentType"`
+	// 文件大小，字节
+	Size     int `json:"size"`
+	DeviceId int `json:"deviceId"`
+	// 设备采集的时间
+	Time string `json:"time"`
+	// 文件内容的地址
+	Url string `json:"url"`
+}
+
+func FromJobArtifactModel(jobId int, a *model.JobArtifact) *JobArtifactSpec {
+	return &JobArtifactSpec{
+		Kind:        a.Kind,
+		ContentType: a.ContentType,
+		Size:        len(a.Data),
+		DeviceId:    a.DeviceId,
+		Time:        a.Time.UTC().Format(time.RFC3339),
+		Url:         fmt.Sprintf("/api/v1/job/%d/artifacts/%s", jobId, a.Kind),
+	}
+}
+
+type ListJobArtifactsResponse struct {
+	Items []JobArtifactSpec `json:"items"`
+}
@@ -0,0 +1,122 @@
+package dao
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"lumina/internal/model"
+)
+
+// ScheduleWindow 每日布防时段，结束时间不晚于开始时间时跨越午夜，
+// 如 22:00-06:00 为夜间，00:00-00:00 为全天
+type ScheduleWindow struct {
+	// 时段开始的星期，0 为周日，为空表示每天
+	Days []int `json:"days,omitempty" binding:"omitempty,dive,min=0,max=6"`
+	// 开始时间，HH:MM
+	Start string `json:"start" binding:"required"`
+	// 结束时间，HH:MM
+	End string `json:"end" binding:"required"`
+}
+
+// JobSchedule 任务布防计划，设备在本地按计划布防和撤防，撤防时暂停执行器
+type JobSchedule struct {
+	// 布防时段，为空表示始终布防
+	Windows []ScheduleWindow `json:"windows" binding:"omitempty,dive"`
+	// 时区，如 Asia/Shanghai，为空时使用设备本地时区
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Validate checks the times and the timezone of the schedule.
+func (s *JobSchedule) Validate() error {
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("invalid schedule timezone %s: %w", s.Timezone, err)
+		}
+	}
+	for _, w := range s.Windows {
+		if _, err := parseClock(w.Start); err != nil {
+			return err
+		}
+		if _, err := parseClock(w.End); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Armed reports whether t is within a window of the schedule, a nil or empty
+// schedule is always armed.
+func (s *JobSchedule) Armed(t time.Time) bool {
+	if s == nil || len(s.Windows) == 0 {
+		return true
+	}
+	if s.Timezone != "" {
+		if loc, err := time.LoadLocation(s.Timezone); err == nil {
+			t = t.In(loc)
+		}
+	}
+	now := t.Hour()*60 + t.Minute()
+	today := int(t.Weekday())
+	yesterday := (today + 6) % 7
+	for _, w := range s.Windows {
+		start, err1 := parseClock(w.Start)
+		end, err2 := parseClock(w.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if start < end {
+			if w.onDay(today) && now >= start && now < end {
+				return true
+			}
+			continue
+		}
+		// the window wraps past midnight into the next day
+		if (w.onDay(today) && now >= start) || (w.onDay(yesterday) && now < end) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *ScheduleWindow) onDay(day int) bool {
+	return len(w.Days) == 0 || slices.Contains(w.Days, day)
+}
+
+// parseClock parses HH:MM into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule time %s, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ScheduleArmed reports whether a job with schedule s is armed at t.
+func ScheduleArmed(s *model.JobSchedule, t time.Time) bool {
+	return fromJobScheduleModel(s).Armed(t)
+}
+
+func fromJobScheduleModel(s *model.JobSchedule) *JobSchedule {
+	if s == nil {
+		return nil
+	}
+	res := &JobSchedule{Timezone: s.Timezone}
+	for _, w := range s.Windows {
+		res.Windows = append(res.Windows, ScheduleWindow(w))
+	}
+	return res
+}
+
+// ToModel returns nil for a schedule without windows, so that clearing the
+// windows disables the schedule.
+func (s *JobSchedule) ToModel() *model.JobSchedule {
+	if s == nil || len(s.Windows) == 0 {
+		return nil
+	}
+	m := &model.JobSchedule{Timezone: s.Timezone}
+	for _, w := range s.Windows {
+		m.Windows = append(m.Windows, model.ScheduleWindow(w))
+	}
+	return m
+}
@@ -1,18 +1,21 @@
 package dao
 
 import (
+	"math"
 	"time"
 
 	"lumina/internal/model"
 )
 
+// DetectionBox 检测框，坐标为像素，0 是画面边缘的有效坐标，所以除 label 外的
+// 字段总是序列化
 type DetectionBox struct {
-	X1         int     `json:"x1,omitempty"`
-	Y1         int     `json:"y1,omitempty"`
-	X2         int     `json:"x2,omitempty"`
-	Y2         int     `json:"y2,omitempty"`
-	Confidence float32 `json:"confidence,omitempty"`
-	ClassId    int     `json:"classId,omitempty"`
+	X1         int     `json:"x1"`
+	Y1         int     `json:"y1"`
+	X2         int     `json:"x2"`
+	Y2         int     `json:"y2"`
+	Confidence float32 `json:"confidence"`
+	ClassId    int     `json:"classId"`
 	Label      string  `json:"label,omitempty"`
 }
 
@@ -28,12 +31,87 @@ func (b DetectionBox) ToModel() *model.DetectionBox {
 	}
 }
 
+// Normalize returns the box with coordinates relative to the frame size,
+// from 0 to 1, nil if the frame size is unknown.
+func (b DetectionBox) Normalize(width, height int) *NormalizedBox {
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+	return &NormalizedBox{
+		X1:         float64(b.X1) / float64(width),
+		Y1:         float64(b.Y1) / float64(height),
+		X2:         float64(b.X2) / float64(width),
+		Y2:         float64(b.Y2) / float64(height),
+		Confidence: b.Confidence,
+		ClassId:    b.ClassId,
+		Label:      b.Label,
+	}
+}
+
+// NormalizedBox 检测框，坐标为相对画面宽高的比例，取值 0～1，与 DetectionBox
+// 一样除 label 外的字段总是序列化
+type NormalizedBox struct {
+	X1         float64 `json:"x1"`
+	Y1         float64 `json:"y1"`
+	X2         float64 `json:"x2"`
+	Y2         float64 `json:"y2"`
+	Confidence float32 `json:"confidence"`
+	ClassId    int     `json:"classId"`
+	Label      string  `json:"label,omitempty"`
+}
+
+// Denormalize returns the box in pixels of a frame of the given size, e.g.
+// of a resized image.
+func (b NormalizedBox) Denormalize(width, height int) *DetectionBox {
+	return &DetectionBox{
+		X1:         int(math.Round(b.X1 * float64(width))),
+		Y1:         int(math.Round(b.Y1 * float64(height))),
+		X2:         int(math.Round(b.X2 * float64(width))),
+		Y2:         int(math.Round(b.Y2 * float64(height))),
+		Confidence: b.Confidence,
+		ClassId:    b.ClassId,
+		Label:      b.Label,
+	}
+}
+
+// NormalizeBoxes normalizes the boxes of a frame, nil if the frame size is
+// unknown.
+func NormalizeBoxes(boxes []*DetectionBox, width, height int) []*NormalizedBox {
+	if width <= 0 || height <= 0 || boxes == nil {
+		return nil
+	}
+	nboxes := make([]*NormalizedBox, 0, len(boxes))
+	for _, b := range boxes {
+		if b != nil {
+			nboxes = append(nboxes, b.Normalize(width, height))
+		}
+	}
+	return nboxes
+}
+
+// ScaleBoxes returns the boxes of a frame of size fromWidth x fromHeight in
+// pixels of a frame of size toWidth x toHeight.
+func ScaleBoxes(boxes []*DetectionBox, fromWidth, fromHeight, toWidth, toHeight int) []*DetectionBox {
+	nboxes := NormalizeBoxes(boxes, fromWidth, fromHeight)
+	if nboxes == nil {
+		return boxes
+	}
+	scaled := make([]*DetectionBox, len(nboxes))
+	for i, b := range nboxes {
+		scaled[i] = b.Denormalize(toWidth, toHeight)
+	}
+	return scaled
+}
+
 type DetectionResult struct {
 	JobId     string          `json:"jobId"`
 	Timestamp int64           `json:"timestamp"`
 	ImagePath string          `json:"imagePath"`
 	JsonPath  string          `json:"jsonPath"`
 	Boxes     []*DetectionBox `json:"boxes,omitempty"`
+	// size in pixels of the frame the boxes are in
+	FrameWidth  int `json:"frameWidth"`
+	FrameHeight int `json:"frameHeight"`
 }
 
 type DeviceMessage struct {
@@ -42,14 +120,44 @@ type DeviceMessage struct {
 	ImagePath   string          `json:"imagePath,omitempty"`
 	DetectBoxes []*DetectionBox `json:"detectBoxes,omitempty"`
 	VideoPath   string          `json:"videoPath,omitempty"`
+	// size in pixels of the frame the boxes are in, zero if unknown
+	FrameWidth  int `json:"frameWidth"`
+	FrameHeight int `json:"frameHeight"`
+	// fields added by the post-processing hooks of the job
+	Metadata map[string]any `json:"metadata,omitempty"`
+	// latest readings of the device sensors by sensor name
+	Sensors map[string]any `json:"sensors,omitempty"`
+	// occupancy of the zones of the job, set on periodic occupancy reports
+	Occupancy []*ZoneOccupancy `json:"occupancy,omitempty"`
+	// signature of the device, set when evidence signing is enabled
+	Evidence *MessageEvidence `json:"evidence,omitempty"`
+	// W3C trace context of the upload, so that the consumer continues its
+	// trace
+	TraceContext map[string]string `json:"traceContext,omitempty"`
+}
+
+// ZoneOccupancy is the occupancy of a zone over a report interval.
+type ZoneOccupancy struct {
+	Zone string `json:"zone"`
+	// Count of objects in the zone at the end of the interval
+	Count int `json:"count"`
+	// Max count during the interval
+	Max int `json:"max"`
+	In  int `json:"in"`
+	Out int `json:"out"`
 }
 
 func (m DeviceMessage) ToModel(job *model.Job) *model.Message {
 	mdl := &model.Message{
-		JobId:     job.Id,
-		Timestamp: time.Unix(m.Timestamp/1000000000, m.Timestamp%1000000000),
-		ImagePath: m.ImagePath,
-		VideoPath: m.VideoPath,
+		JobId:       job.Id,
+		OrgId:       job.OrgId,
+		Timestamp:   time.Unix(m.Timestamp/1000000000, m.Timestamp%1000000000),
+		ImagePath:   m.ImagePath,
+		VideoPath:   m.VideoPath,
+		FrameWidth:  m.FrameWidth,
+		FrameHeight: m.FrameHeight,
+		Metadata:    m.Metadata,
+		Sensors:     m.Sensors,
 	}
 	if m.DetectBoxes != nil {
 		mdl.DetectBoxes = make(model.DetectionBoxSlice, len(m.DetectBoxes))
@@ -57,15 +165,19 @@ func (m DeviceMessage) ToModel(job *model.Job) *model.Message {
 			mdl.DetectBoxes[i] = box.ToModel()
 		}
 	}
+	if m.Evidence != nil {
+		mdl.Evidence = m.Evidence.ToModel()
+	}
 	return mdl
 }
 
 type WorkflowResp struct {
-	Answer      string  `json:"answer,omitempty"`
-	RawContent  string  `json:"rawContent,omitempty"`
-	Confidence  float32 `json:"confidence,omitempty"`
-	Match       bool    `json:"match,omitempty"`
-	TotalTokens int     `json:"totalTokens,omitempty"`
+	Answer     string `json:"answer,omitempty"`
+	RawContent string `json:"rawContent,omitempty"`
+	// 置信度和是否匹配总是序列化，0 和 false 也是分析结果
+	Confidence  float32 `json:"confidence"`
+	Match       bool    `json:"match"`
+	TotalTokens int     `json:"totalTokens"`
 }
 
 func (w WorkflowResp) ToModel() *model.WorkflowResp {
@@ -87,7 +199,18 @@ type MessageSpec struct {
 	VideoPath    string          `json:"videoPath,omitempty"`
 	CreateTime   string          `json:"createTime"`
 	WorkflowResp *WorkflowResp   `json:"workflowResp,omitempty"`
-	Alerted      bool            `json:"alerted,omitempty"`
+	Alerted      bool            `json:"alerted"`
+	// 检测框所在画面的宽高（像素），为 0 表示未知
+	FrameWidth  int `json:"frameWidth"`
+	FrameHeight int `json:"frameHeight"`
+	// 按画面宽高归一化的检测框，画面宽高未知时为空
+	NormalizedBoxes []*NormalizedBox `json:"normalizedBoxes,omitempty"`
+	// 设备后处理钩子添加的字段
+	Metadata map[string]any `json:"metadata,omitempty"`
+	// 设备传感器读数，按传感器名称索引
+	Sensors map[string]any `json:"sensors,omitempty"`
+	// 是否带有设备签名，可通过校验接口证明未被篡改
+	Signed bool `json:"signed"`
 }
 
 func FromMessageModel(msg *model.Message) *MessageSpec {
@@ -97,11 +220,16 @@ func FromMessageModel(msg *model.Message) *MessageSpec {
 	m := &MessageSpec{}
 	m.Id = msg.Id
 	m.JobId = msg.JobId
-	m.Timestamp = msg.Timestamp.Format(time.RFC3339)
+	m.Timestamp = msg.Timestamp.UTC().Format(time.RFC3339)
 	m.ImagePath = msg.ImagePath
 	m.VideoPath = msg.VideoPath
-	m.CreateTime = msg.CreateTime.Format(time.RFC3339)
+	m.CreateTime = msg.CreateTime.UTC().Format(time.RFC3339)
 	m.Alerted = msg.Alerted
+	m.FrameWidth = msg.FrameWidth
+	m.FrameHeight = msg.FrameHeight
+	m.Metadata = msg.Metadata
+	m.Sensors = msg.Sensors
+	m.Signed = msg.Evidence != nil
 
 	if msg.DetectBoxes != nil {
 		m.DetectBoxes = make([]*DetectionBox, len(msg.DetectBoxes))
@@ -116,6 +244,7 @@ func FromMessageModel(msg *model.Message) *MessageSpec {
 				Label:      box.Label,
 			}
 		}
+		m.NormalizedBoxes = NormalizeBoxes(m.DetectBoxes, m.FrameWidth, m.FrameHeight)
 	}
 
 	if msg.WorkflowResp != nil {
@@ -167,13 +296,332 @@ type CreateMessageResponse struct {
 }
 
 type ListMessagesRequest struct {
+	Start int `json:"start" form:"start" binding:"min=0"`
+	Limit int `json:"limit" form:"limit" binding:"min=0,max=50"`
+	MessageFilterParams
+}
+
+// MessageFilterParams 消息过滤条件，为空的条件不过滤
+type MessageFilterParams struct {
 	JobId   int  `json:"jobId" form:"jobId"`
-	Start   int  `json:"start" form:"start" binding:"min=0"`
-	Limit   int  `json:"limit" form:"limit" binding:"min=0,max=50"`
 	Alerted bool `json:"alerted" form:"alerted"`
+	// 告警处理状态，仅 alerted 为 true 时生效，为空时不过滤
+	AlertStatus model.AlertStatus `json:"alertStatus" form:"alertStatus" binding:"omitempty,oneof=open acknowledged resolved"`
+	// 摄像头ID，为空时不过滤
+	CameraId int `json:"cameraId" form:"cameraId" binding:"min=0"`
+	// 设备ID，匹配直接或通过设备组分配给该设备的任务的消息
+	DeviceId int `json:"deviceId" form:"deviceId" binding:"min=0"`
+	// 拍摄时间范围 [since, until)，RFC3339 格式或 Unix 时间戳（秒或毫秒），为空时不限
+	Since string `json:"since" form:"since" binding:"omitempty,timestamp"`
+	Until string `json:"until" form:"until" binding:"omitempty,timestamp"`
+	// 检测标签，匹配含有该标签检测框的消息
+	Label string `json:"label" form:"label"`
+	// 检测框最低置信度，与 label 同时指定时需为同一检测框
+	MinConfidence float32 `json:"minConfidence" form:"minConfidence" binding:"min=0,max=1"`
+}
+
+// ToFilter converts the params, the times must have been validated.
+func (p *MessageFilterParams) ToFilter() model.MessageFilter {
+	f := model.MessageFilter{
+		JobId:         p.JobId,
+		CameraId:      p.CameraId,
+		DeviceId:      p.DeviceId,
+		Label:         p.Label,
+		MinConfidence: p.MinConfidence,
+		Alerted:       p.Alerted,
+		AlertStatus:   p.AlertStatus,
+	}
+	if p.Since != "" {
+		f.Start, _ = ParseTime(p.Since)
+	}
+	if p.Until != "" {
+		f.End, _ = ParseTime(p.Until)
+	}
+	return f
+}
+
+// SearchMessagesRequest 全文搜索工作流回答，其余过滤条件同 ListMessagesRequest
+type SearchMessagesRequest struct {
+	// 搜索的文本，以空格分隔的多个词需全部出现在回答中，每个词至少 2 个字符
+	Q string `json:"q" form:"q" binding:"required,max=256"`
+	ListMessagesRequest
+}
+
+func (req *SearchMessagesRequest) ToFilter() model.MessageFilter {
+	f := req.MessageFilterParams.ToFilter()
+	f.Text = req.Q
+	return f
+}
+
+// SemanticSearchRequest 按语义搜索消息，如“夜间有人翻越围栏”
+type SemanticSearchRequest struct {
+	// 搜索的描述
+	Q string `form:"q" binding:"required,max=256"`
+	// 返回的数量，默认 20
+	Limit int `form:"limit" binding:"min=0,max=100"`
+	// 最低相似度，0-1，为 0 时不限
+	MinScore float32 `form:"minScore" binding:"min=0,max=1"`
+	JobId    int     `form:"jobId" binding:"min=0"`
+	CameraId int     `form:"cameraId" binding:"min=0"`
+	Alerted  bool    `form:"alerted"`
+	// 拍摄时间范围 [since, until)，RFC3339 格式或 Unix 时间戳（秒或毫秒），为空时不限
+	Since string `form:"since" binding:"omitempty,timestamp"`
+	Until string `form:"until" binding:"omitempty,timestamp"`
+}
+
+type SemanticSearchResponse struct {
+	// 按相似度从高到低排列的消息
+	Items []SemanticSearchHit `json:"items"`
+}
+
+type SemanticSearchHit struct {
+	// 与搜索描述的相似度
+	Score float32 `json:"score"`
+	MessageSpec
+}
+
+type MessageExportFormat string
+
+const (
+	MessageExportCsv   MessageExportFormat = "csv"
+	MessageExportJsonl MessageExportFormat = "jsonl"
+)
+
+// ExportMessagesRequest 导出消息，过滤条件同 ListMessagesRequest
+type ExportMessagesRequest struct {
+	// 导出格式：csv、jsonl，默认 csv
+	Format MessageExportFormat `json:"format" form:"format" binding:"omitempty,oneof=csv jsonl"`
+	// 全文搜索工作流回答，同 SearchMessagesRequest.q
+	Q string `json:"q" form:"q" binding:"max=256"`
+	// 最多导出的消息数量，默认 100000
+	Limit int `json:"limit" form:"limit" binding:"min=0,max=1000000"`
+	MessageFilterParams
+}
+
+func (req *ExportMessagesRequest) ToFilter() model.MessageFilter {
+	f := req.MessageFilterParams.ToFilter()
+	f.Text = req.Q
+	return f
+}
+
+// MessageMediaRequest 获取消息媒体的临时链接
+type MessageMediaRequest struct {
+	// 有效期，单位秒，为空时使用配置的有效期
+	Expire int `json:"expire" form:"expire" binding:"min=0,max=604800"`
+}
+
+// MessageMediaResponse 消息媒体的预签名链接，没有的媒体为空
+type MessageMediaResponse struct {
+	ImageUrl   string `json:"imageUrl,omitempty"`
+	VideoUrl   string `json:"videoUrl,omitempty"`
+	ExpireTime string `json:"expireTime"`
 }
 
 type ListMessagesResponse struct {
 	Items []MessageSpec `json:"items"`
 	Total int64         `json:"total"`
 }
+
+type AlertEvent struct {
+	AlertId    int           `json:"alertId"`
+	Message    MessageSpec   `json:"message"`
+	JobUuid    string        `json:"jobUuid"`
+	JobKind    model.JobKind `json:"jobKind"`
+	CameraId   int           `json:"cameraId"`
+	CameraName string        `json:"cameraName"`
+	// 告警级别：low、medium、high、critical
+	Severity   model.AlertSeverity `json:"severity"`
+	CreateTime string              `json:"createTime"`
+	// 所属组织，只推送给同组织的订阅
+	OrgId int `json:"-"`
+}
+
+// AlertSpec 告警的级别和处理状态
+type AlertSpec struct {
+	AlertId  int                 `json:"alertId"`
+	Severity model.AlertSeverity `json:"severity"`
+	// 处理状态：open、acknowledged、resolved
+	Status model.AlertStatus `json:"status"`
+	// 确认时间，为空表示未确认
+	AckTime string `json:"ackTime,omitempty"`
+	// 确认告警的用户ID
+	AckUserId int `json:"ackUserId,omitempty"`
+	// 负责处理告警的用户ID，0 表示未指派
+	AssigneeId int `json:"assigneeId,omitempty"`
+	// 解决时间，为空表示未解决
+	ResolveTime string `json:"resolveTime,omitempty"`
+	// 解决告警的用户ID
+	ResolveUserId int    `json:"resolveUserId,omitempty"`
+	CreateTime    string `json:"createTime"`
+	// 去重窗口内合并的告警次数，含第一次
+	Occurrences int `json:"occurrences"`
+	// 最近一次告警的时间和消息ID
+	LastOccurTime string `json:"lastOccurTime,omitempty"`
+	LastMessageId int    `json:"lastMessageId,omitempty"`
+	// 处理记录，按时间先后排列
+	Activities []AlertActivitySpec `json:"activities,omitempty"`
+}
+
+func FromAlertModel(m *model.AlertMessage) *AlertSpec {
+	spec := &AlertSpec{
+		AlertId:       m.Id,
+		Severity:      m.Severity,
+		Status:        m.Status,
+		AckUserId:     m.AckUserId,
+		AssigneeId:    m.AssigneeId,
+		ResolveUserId: m.ResolveUserId,
+		CreateTime:    m.CreateTime.UTC().Format(time.RFC3339),
+		Occurrences:   max(m.Occurrences, 1),
+		LastMessageId: m.LastMessageId,
+	}
+	if m.LastOccurTime != nil {
+		spec.LastOccurTime = m.LastOccurTime.UTC().Format(time.RFC3339)
+	}
+	if m.AckTime != nil {
+		spec.AckTime = m.AckTime.UTC().Format(time.RFC3339)
+	}
+	if m.ResolveTime != nil {
+		spec.ResolveTime = m.ResolveTime.UTC().Format(time.RFC3339)
+	}
+	return spec
+}
+
+// AlertActivitySpec 告警处理记录
+type AlertActivitySpec struct {
+	Id int `json:"id"`
+	// 操作：ack、assign、comment、resolve
+	Action   model.AlertAction `json:"action"`
+	UserId   int               `json:"userId"`
+	Username string            `json:"username"`
+	// 指派的用户ID，仅 assign 操作，0 表示取消指派
+	AssigneeId int `json:"assigneeId,omitempty"`
+	// 评论内容或解决说明
+	Content    string `json:"content,omitempty"`
+	CreateTime string `json:"createTime"`
+}
+
+func FromAlertActivityModel(m *model.AlertActivity) AlertActivitySpec {
+	return AlertActivitySpec{
+		Id:         m.Id,
+		Action:     m.Action,
+		UserId:     m.UserId,
+		Username:   m.Username,
+		AssigneeId: m.AssigneeId,
+		Content:    m.Content,
+		CreateTime: m.CreateTime.UTC().Format(time.RFC3339),
+	}
+}
+
+// AssignAlertRequest 指派告警
+type AssignAlertRequest struct {
+	// 负责处理告警的用户ID，0 表示取消指派
+	AssigneeId int `json:"assigneeId" binding:"min=0"`
+}
+
+// CommentAlertRequest 评论告警
+type CommentAlertRequest struct {
+	Content string `json:"content" binding:"required,max=4096"`
+}
+
+// ResolveAlertRequest 解决告警
+type ResolveAlertRequest struct {
+	// 解决说明
+	Comment string `json:"comment" binding:"max=4096"`
+}
+
+// AlertStatsRequest 告警处理统计查询参数，时间格式与默认值同 JobStatsRequest
+type AlertStatsRequest struct {
+	Start string `json:"start" form:"start"`
+	End   string `json:"end" form:"end"`
+	// 任务ID，为空时统计所有任务
+	JobId int `json:"jobId" form:"jobId"`
+	// 确认告警的 SLA，单位秒
+	SlaSeconds int `json:"slaSeconds" form:"slaSeconds" binding:"min=0"`
+}
+
+// AlertStatsResponse 时间范围内产生的告警的处理统计
+type AlertStatsResponse struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// 未处理（未确认且未解决）的告警数
+	Open int64 `json:"open"`
+	// 已确认未解决的告警数
+	Acknowledged int64 `json:"acknowledged"`
+	// 已解决的告警数
+	Resolved int64 `json:"resolved"`
+	// 平均确认耗时，单位秒
+	AvgAckSeconds float64 `json:"avgAckSeconds"`
+	// 平均解决耗时，单位秒
+	AvgResolveSeconds float64 `json:"avgResolveSeconds"`
+	SlaSeconds        int     `json:"slaSeconds"`
+	// 在 SLA 内确认的告警数
+	AckedWithinSla int64 `json:"ackedWithinSla"`
+}
+
+type AlertEventFilter struct {
+	JobId    int `form:"jobId"`
+	CameraId int `form:"cameraId"`
+	// 最低告警级别，为空时不过滤
+	MinSeverity model.AlertSeverity `form:"minSeverity" binding:"omitempty,oneof=low medium high critical"`
+	// 订阅者的组织，由服务端设置
+	OrgId int `form:"-"`
+}
+
+func (f AlertEventFilter) Match(e *AlertEvent) bool {
+	if f.OrgId != model.AllOrgs && f.OrgId != e.OrgId {
+		return false
+	}
+	if f.JobId != 0 && f.JobId != e.Message.JobId {
+		return false
+	}
+	if f.CameraId != 0 && f.CameraId != e.CameraId {
+		return false
+	}
+	if !e.Severity.AtLeast(f.MinSeverity) {
+		return false
+	}
+	return true
+}
+
+// AlertGalleryRequest 告警图库查询参数，时间格式与默认值同 JobStatsRequest
+type AlertGalleryRequest struct {
+	Start string `json:"start" form:"start"`
+	End   string `json:"end" form:"end"`
+	// 时间桶大小，如 10m、1h，默认 1h，最小 1m，最大 24h
+	Bucket   string            `json:"bucket" form:"bucket"`
+	JobId    int               `json:"jobId" form:"jobId" binding:"min=0"`
+	CameraId int               `json:"cameraId" form:"cameraId" binding:"min=0"`
+	Status   model.AlertStatus `json:"status" form:"status" binding:"omitempty,oneof=open acknowledged resolved"`
+	// 每个时间桶每个摄像头返回的最新缩略图数量，默认 4，最大 10
+	Thumbnails int `json:"thumbnails" form:"thumbnails" binding:"min=0,max=10"`
+}
+
+type AlertThumbnailSpec struct {
+	MessageId int    `json:"messageId"`
+	ImageUrl  string `json:"imageUrl"`
+}
+
+// AlertGalleryCamera 摄像头在时间桶内产生的告警
+type AlertGalleryCamera struct {
+	CameraId   int    `json:"cameraId"`
+	CameraName string `json:"cameraName"`
+	// 告警数量
+	Count int64 `json:"count"`
+	// 告警的发生次数，包含被去重合并的重复告警
+	Occurrences int64                `json:"occurrences"`
+	Thumbnails  []AlertThumbnailSpec `json:"thumbnails"`
+}
+
+type AlertGalleryBucket struct {
+	Start   string               `json:"start"`
+	Count   int64                `json:"count"`
+	Cameras []AlertGalleryCamera `json:"cameras"`
+}
+
+// AlertGalleryResponse 按时间桶分组的告警，没有告警的时间桶不返回
+type AlertGalleryResponse struct {
+	Start   string               `json:"start"`
+	End     string               `json:"end"`
+	Bucket  string               `json:"bucket"`
+	Buckets []AlertGalleryBucket `json:"buckets"`
+}
@@ -0,0 +1,445 @@
+package dao
+
+import (
+	"time"
+
+	"lumina/internal/model"
+)
+
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh" binding:"required"`
+	Auth   string `json:"auth" binding:"required"`
+}
+
+type PushSubscriptionRequest struct {
+	// 浏览器推送服务地址
+	Endpoint string `json:"endpoint" binding:"required,url"`
+	// 订阅密钥
+	Keys PushSubscriptionKeys `json:"keys" binding:"required"`
+}
+
+func (r PushSubscriptionRequest) ToModel(userId int, userAgent string) *model.PushSubscription {
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	return &model.PushSubscription{
+		UserId:    userId,
+		Endpoint:  r.Endpoint,
+		P256dh:    r.Keys.P256dh,
+		Auth:      r.Keys.Auth,
+		UserAgent: userAgent,
+	}
+}
+
+type DeletePushSubscriptionRequest struct {
+	// 浏览器推送服务地址
+	Endpoint string `json:"endpoint" binding:"required"`
+}
+
+type VapidPublicKeyResponse struct {
+	// 推送服务公钥，为空表示未启用推送
+	PublicKey string `json:"publicKey"`
+}
+
+type NotificationPreferenceSpec struct {
+	// 是否启用推送
+	Enabled bool `json:"enabled"`
+	// 只推送这些任务的告警，为空表示全部
+	JobIds []int `json:"jobIds"`
+	// 只推送这些摄像头的告警，为空表示全部
+	CameraIds []int `json:"cameraIds"`
+	// 只推送不低于该级别的告警，为空表示全部
+	MinSeverity model.AlertSeverity `json:"minSeverity"`
+	UpdateTime  string              `json:"updateTime,omitempty"`
+}
+
+func FromNotificationPreferenceModel(m *model.NotificationPreference) *NotificationPreferenceSpec {
+	spec := &NotificationPreferenceSpec{
+		Enabled:     m.Enabled,
+		JobIds:      m.JobIds,
+		CameraIds:   m.CameraIds,
+		MinSeverity: m.MinSeverity,
+	}
+	if spec.JobIds == nil {
+		spec.JobIds = []int{}
+	}
+	if spec.CameraIds == nil {
+		spec.CameraIds = []int{}
+	}
+	if !m.UpdateTime.IsZero() {
+		spec.UpdateTime = m.UpdateTime.UTC().Format(time.RFC3339)
+	}
+	return spec
+}
+
+type UpdateNotificationPreferenceRequest struct {
+	Enabled   bool  `json:"enabled"`
+	JobIds    []int `json:"jobIds"`
+	CameraIds []int `json:"cameraIds"`
+	// 只推送不低于该级别的告警，为空表示全部
+	MinSeverity model.AlertSeverity `json:"minSeverity,omitempty" binding:"omitempty,oneof=low medium high critical"`
+}
+
+func (r UpdateNotificationPreferenceRequest) ToModel(userId int) *model.NotificationPreference {
+	return &model.NotificationPreference{
+		UserId:      userId,
+		Enabled:     r.Enabled,
+		JobIds:      r.JobIds,
+		CameraIds:   r.CameraIds,
+		MinSeverity: r.MinSeverity,
+	}
+}
+
+// WebPushPayload is the JSON document delivered to the service worker.
+type WebPushPayload struct {
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	Icon    string `json:"icon,omitempty"`
+	Url     string `json:"url"`
+	AlertId int    `json:"alertId"`
+}
+
+type NotificationChannelSpec struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	// 通道类型：webhook、email、dingtalk（钉钉机器人）、wecom（企业微信机器人）或 slack
+	Kind    model.NotificationChannelKind `json:"kind"`
+	Enabled bool                          `json:"enabled"`
+	// 告警投递地址，webhook 通道为接收地址，钉钉、企业微信和 slack 通道为机器人 webhook 地址
+	Url string `json:"url"`
+	// 请求头，webhook 通道使用
+	Headers map[string]string `json:"headers"`
+	// 钉钉机器人加签密钥
+	Secret string `json:"secret,omitempty"`
+	// 接收所有告警的邮箱，email 通道使用
+	Recipients []string `json:"recipients"`
+	// 按任务ID额外接收该任务告警的邮箱，email 通道使用
+	JobRecipients map[int][]string `json:"jobRecipients"`
+	// 模板，Go text/template 语法，数据为告警事件，.ImageUrl 为告警图片的预签名地址；webhook 通道为请求体，为空时发送告警事件的 JSON；email 通道为邮件正文，钉钉、企业微信和 slack 通道为 markdown 消息内容，为空时使用默认内容
+	Template string `json:"template"`
+	// 投递失败后的重试次数
+	MaxRetries int `json:"maxRetries"`
+	// 只投递这些任务的告警，为空表示全部
+	JobIds []int `json:"jobIds"`
+	// 只投递这些摄像头的告警，为空表示全部
+	CameraIds []int `json:"cameraIds"`
+	// 只投递不低于该级别的告警，为空表示全部
+	MinSeverity model.AlertSeverity `json:"minSeverity"`
+	// 只接收告警升级策略发送的告警
+	EscalationOnly bool `json:"escalationOnly"`
+	// 最近一次投递的错误，为空表示成功
+	LastError string `json:"lastError,omitempty"`
+	// 最近一次投递时间
+	LastDeliveryTime string `json:"lastDeliveryTime,omitempty"`
+	CreateTime       string `json:"createTime"`
+	UpdateTime       string `json:"updateTime"`
+}
+
+func FromNotificationChannelModel(m *model.NotificationChannel) *NotificationChannelSpec {
+	spec := &NotificationChannelSpec{
+		Id:             m.Id,
+		Name:           m.Name,
+		Kind:           m.Kind,
+		Enabled:        m.Enabled,
+		Url:            m.Url,
+		Headers:        m.Headers,
+		Secret:         m.Secret,
+		Recipients:     m.Recipients,
+		JobRecipients:  m.JobRecipients,
+		Template:       m.Template,
+		MaxRetries:     m.MaxRetries,
+		JobIds:         m.JobIds,
+		CameraIds:      m.CameraIds,
+		MinSeverity:    m.MinSeverity,
+		EscalationOnly: m.EscalationOnly,
+		LastError:      m.LastError,
+		CreateTime:     m.CreateTime.UTC().Format(time.RFC3339),
+		UpdateTime:     m.UpdateTime.UTC().Format(time.RFC3339),
+	}
+	if spec.Headers == nil {
+		spec.Headers = map[string]string{}
+	}
+	if spec.Recipients == nil {
+		spec.Recipients = []string{}
+	}
+	if spec.JobRecipients == nil {
+		spec.JobRecipients = map[int][]string{}
+	}
+	if spec.JobIds == nil {
+		spec.JobIds = []int{}
+	}
+	if spec.CameraIds == nil {
+		spec.CameraIds = []int{}
+	}
+	if m.LastDeliveryTime != nil {
+		spec.LastDeliveryTime = m.LastDeliveryTime.UTC().Format(time.RFC3339)
+	}
+	return spec
+}
+
+type CreateNotificationChannelRequest struct {
+	// 通道名称
+	Name string `json:"name" binding:"required,max=96"`
+	// 通道类型：webhook、email、dingtalk、wecom 或 slack，默认 webhook
+	Kind    model.NotificationChannelKind `json:"kind,omitempty" binding:"omitempty,oneof=webhook email dingtalk wecom slack"`
+	Enabled bool                          `json:"enabled"`
+	// 告警投递地址，email 以外的通道必填
+	Url string `json:"url" binding:"omitempty,url,max=1024"`
+	// 请求头，webhook 通道使用
+	Headers map[string]string `json:"headers"`
+	// 钉钉机器人加签密钥
+	Secret string `json:"secret" binding:"max=255"`
+	// 接收所有告警的邮箱，email 通道使用
+	Recipients []string `json:"recipients" binding:"dive,email"`
+	// 按任务ID额外接收该任务告警的邮箱，email 通道使用
+	JobRecipients map[int][]string `json:"jobRecipients" binding:"dive,dive,email"`
+	// 模板，Go text/template 语法，数据为告警事件，.ImageUrl 为告警图片的预签名地址；webhook 通道为请求体，为空时发送告警事件的 JSON；email 通道为邮件正文，钉钉、企业微信和 slack 通道为 markdown 消息内容，为空时使用默认内容
+	Template string `json:"template" binding:"max=65535"`
+	// 投递失败后的重试次数，默认 3
+	MaxRetries *int `json:"maxRetries,omitempty" binding:"omitempty,min=0,max=10"`
+	// 只投递这些任务的告警，为空表示全部
+	JobIds []int `json:"jobIds"`
+	// 只投递这些摄像头的告警，为空表示全部
+	CameraIds []int `json:"cameraIds"`
+	// 只投递不低于该级别的告警，为空表示全部
+	MinSeverity model.AlertSeverity `json:"minSeverity,omitempty" binding:"omitempty,oneof=low medium high critical"`
+	// 只接收告警升级策略发送的告警
+	EscalationOnly bool `json:"escalationOnly"`
+}
+
+func (r *CreateNotificationChannelRequest) ToModel() *model.NotificationChannel {
+	ch := &model.NotificationChannel{
+		Name:           r.Name,
+		Kind:           r.Kind,
+		Enabled:        r.Enabled,
+		Url:            r.Url,
+		Headers:        r.Headers,
+		Secret:         r.Secret,
+		Recipients:     r.Recipients,
+		JobRecipients:  r.JobRecipients,
+		Template:       r.Template,
+		MaxRetries:     3,
+		JobIds:         r.JobIds,
+		CameraIds:      r.CameraIds,
+		MinSeverity:    r.MinSeverity,
+		EscalationOnly: r.EscalationOnly,
+	}
+	if ch.Kind == "" {
+		ch.Kind = model.NotificationChannelWebhook
+	}
+	if r.MaxRetries != nil {
+		ch.MaxRetries = *r.MaxRetries
+	}
+	return ch
+}
+
+type CreateNotificationChannelResponse struct {
+	Id int `json:"id"`
+}
+
+type UpdateNotificationChannelRequest struct {
+	// 通道名称
+	Name    *string `json:"name,omitempty" binding:"omitempty,min=1,max=96"`
+	Enabled *bool   `json:"enabled,omitempty"`
+	// 告警投递地址
+	Url *string `json:"url,omitempty" binding:"omitempty,url,max=1024"`
+	// 请求头，传入时整体替换
+	Headers map[string]string `json:"headers,omitempty"`
+	// 钉钉机器人加签密钥，传空字符串表示不加签
+	Secret *string `json:"secret,omitempty" binding:"omitempty,max=255"`
+	// 接收所有告警的邮箱，传入时整体替换
+	Recipients []string `json:"recipients,omitempty" binding:"omitempty,dive,email"`
+	// 按任务ID额外接收该任务告警的邮箱，传入时整体替换
+	JobRecipients map[int][]string `json:"jobRecipients,omitempty" binding:"omitempty,dive,dive,email"`
+	// 模板，传空字符串表示使用默认的请求体或消息内容
+	Template *string `json:"template,omitempty" binding:"omitempty,max=65535"`
+	// 投递失败后的重试次数
+	MaxRetries *int `json:"maxRetries,omitempty" binding:"omitempty,min=0,max=10"`
+	// 只投递这些任务的告警，传入时整体替换，传空数组表示全部
+	JobIds []int `json:"jobIds,omitempty"`
+	// 只投递这些摄像头的告警，传入时整体替换，传空数组表示全部
+	CameraIds []int `json:"cameraIds,omitempty"`
+	// 只投递不低于该级别的告警，传空字符串表示全部
+	MinSeverity *model.AlertSeverity `json:"minSeverity,omitempty" binding:"omitempty,oneof=low medium high critical ''"`
+	// 只接收告警升级策略发送的告警
+	EscalationOnly *bool `json:"escalationOnly,omitempty"`
+}
+
+func (r *UpdateNotificationChannelRequest) UpdateModel(ch *model.NotificationChannel) {
+	if r.Name != nil {
+		ch.Name = *r.Name
+	}
+	if r.Enabled != nil {
+		ch.Enabled = *r.Enabled
+	}
+	if r.Url != nil {
+		ch.Url = *r.Url
+	}
+	if r.Headers != nil {
+		ch.Headers = r.Headers
+	}
+	if r.Secret != nil {
+		ch.Secret = *r.Secret
+	}
+	if r.Recipients != nil {
+		ch.Recipients = r.Recipients
+	}
+	if r.JobRecipients != nil {
+		ch.JobRecipients = r.JobRecipients
+	}
+	if r.Template != nil {
+		ch.Template = *r.Template
+	}
+	if r.MaxRetries != nil {
+		ch.MaxRetries = *r.MaxRetries
+	}
+	if r.JobIds != nil {
+		ch.JobIds = r.JobIds
+	}
+	if r.CameraIds != nil {
+		ch.CameraIds = r.CameraIds
+	}
+	if r.MinSeverity != nil {
+		ch.MinSeverity = *r.MinSeverity
+	}
+	if r.EscalationOnly != nil {
+		ch.EscalationOnly = *r.EscalationOnly
+	}
+}
+
+type ListNotificationChannelsResponse struct {
+	Items []NotificationChannelSpec `json:"items"`
+}
+
+// TestNotificationChannelResponse 测试投递的结果
+type TestNotificationChannelResponse struct {
+	// 对端返回的状态码，请求未送达或 email 通道时为 0
+	StatusCode int `json:"statusCode"`
+	// 投递失败的原因，为空表示成功
+	Error string `json:"error,omitempty"`
+	// 发送的请求体或邮件正文
+	Body string `json:"body"`
+}
+
+type EscalationStep struct {
+	// 通知的通道ID
+	ChannelId int `json:"channelId" binding:"required,min=1"`
+	// 告警产生后仍未确认多少分钟时通知，0 表示立即通知
+	Delay int `json:"delay" binding:"min=0,max=1440"`
+}
+
+type EscalationPolicySpec struct {
+	Id      int    `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// 只升级不低于该级别的告警，为空表示全部
+	MinSeverity model.AlertSeverity `json:"minSeverity"`
+	// 只升级这些任务的告警，为空表示全部
+	JobIds []int `json:"jobIds"`
+	// 只升级这些摄像头的告警，为空表示全部
+	CameraIds []int `json:"cameraIds"`
+	// 升级步骤，告警确认后不再通知后续步骤
+	Steps      []EscalationStep `json:"steps"`
+	CreateTime string           `json:"createTime"`
+	UpdateTime string           `json:"updateTime"`
+}
+
+func FromEscalationPolicyModel(m *model.EscalationPolicy) *EscalationPolicySpec {
+	spec := &EscalationPolicySpec{
+		Id:          m.Id,
+		Name:        m.Name,
+		Enabled:     m.Enabled,
+		MinSeverity: m.MinSeverity,
+		JobIds:      m.JobIds,
+		CameraIds:   m.CameraIds,
+		Steps:       make([]EscalationStep, len(m.Steps)),
+		CreateTime:  m.CreateTime.UTC().Format(time.RFC3339),
+		UpdateTime:  m.UpdateTime.UTC().Format(time.RFC3339),
+	}
+	for i, step := range m.Steps {
+		spec.Steps[i] = EscalationStep(step)
+	}
+	if spec.JobIds == nil {
+		spec.JobIds = []int{}
+	}
+	if spec.CameraIds == nil {
+		spec.CameraIds = []int{}
+	}
+	return spec
+}
+
+func toEscalationStepsModel(steps []EscalationStep) model.EscalationSteps {
+	res := make(model.EscalationSteps, len(steps))
+	for i, step := range steps {
+		res[i] = model.EscalationStep(step)
+	}
+	return res
+}
+
+type CreateEscalationPolicyRequest struct {
+	// 策略名称
+	Name    string `json:"name" binding:"required,max=96"`
+	Enabled bool   `json:"enabled"`
+	// 只升级不低于该级别的告警，为空表示全部
+	MinSeverity model.AlertSeverity `json:"minSeverity,omitempty" binding:"omitempty,oneof=low medium high critical"`
+	// 只升级这些任务的告警，为空表示全部
+	JobIds []int `json:"jobIds"`
+	// 只升级这些摄像头的告警，为空表示全部
+	CameraIds []int `json:"cameraIds"`
+	// 升级步骤
+	Steps []EscalationStep `json:"steps" binding:"required,min=1,max=10,dive"`
+}
+
+func (r *CreateEscalationPolicyRequest) ToModel() *model.EscalationPolicy {
+	return &model.EscalationPolicy{
+		Name:        r.Name,
+		Enabled:     r.Enabled,
+		MinSeverity: r.MinSeverity,
+		JobIds:      r.JobIds,
+		CameraIds:   r.CameraIds,
+		Steps:       toEscalationStepsModel(r.Steps),
+	}
+}
+
+type CreateEscalationPolicyResponse struct {
+	Id int `json:"id"`
+}
+
+type UpdateEscalationPolicyRequest struct {
+	// 策略名称
+	Name    *string `json:"name,omitempty" binding:"omitempty,min=1,max=96"`
+	Enabled *bool   `json:"enabled,omitempty"`
+	// 只升级不低于该级别的告警，传空字符串表示全部
+	MinSeverity *model.AlertSeverity `json:"minSeverity,omitempty" binding:"omitempty,oneof=low medium high critical ''"`
+	// 只升级这些任务的告警，传入时整体替换，传空数组表示全部
+	JobIds []int `json:"jobIds,omitempty"`
+	// 只升级这些摄像头的告警，传入时整体替换，传空数组表示全部
+	CameraIds []int `json:"cameraIds,omitempty"`
+	// 升级步骤，传入时整体替换
+	Steps []EscalationStep `json:"steps,omitempty" binding:"omitempty,min=1,max=10,dive"`
+}
+
+func (r *UpdateEscalationPolicyRequest) UpdateModel(p *model.EscalationPolicy) {
+	if r.Name != nil {
+		p.Name = *r.Name
+	}
+	if r.Enabled != nil {
+		p.Enabled = *r.Enabled
+	}
+	if r.MinSeverity != nil {
+		p.MinSeverity = *r.MinSeverity
+	}
+	if r.JobIds != nil {
+		p.JobIds = r.JobIds
+	}
+	if r.CameraIds != nil {
+		p.CameraIds = r.CameraIds
+	}
+	if r.Steps != nil {
+		p.Steps = toEscalationStepsModel(r.Steps)
+	}
+}
+
+type ListEscalationPoliciesResponse struct {
+	Items []EscalationPolicySpec `json:"items"`
+}
@@ -0,0 +1,166 @@
+package dao
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"lumina/internal/model"
+)
+
+type DiscoverCamerasRequest struct {
+	// 执行扫描的设备ID
+	DeviceId int `json:"deviceId" binding:"required"`
+	// 扫描的网段，如 192.168.1.0/24，为空时只在设备所在局域网组播探测
+	Subnet string `json:"subnet,omitempty" binding:"omitempty,cidrv4"`
+	// 摄像头用户名和密码，用于读取设备信息和码流地址
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// 扫描时长，单位秒，默认 5
+	Timeout int `json:"timeout,omitempty" binding:"omitempty,min=1,max=20"`
+}
+
+type OnvifDiscoverTask struct {
+	TaskUuid   string `json:"taskUuid"`
+	Subnet     string `json:"subnet,omitempty"`
+	Username   string `json:"username,omitempty"`
+	Password   string `json:"password,omitempty"`
+	Timeout    int    `json:"timeout"`
+	ExpireTime string `json:"expireTime"`
+}
+
+func (t OnvifDiscoverTask) Expired() bool {
+	if t.ExpireTime == "" {
+		return false
+	}
+	expireTime, err := time.Parse(time.RFC3339, t.ExpireTime)
+	if err != nil {
+		return false
+	}
+	return expireTime.Before(time.Now())
+}
+
+func FromOnvifDiscoverTaskModel(m *model.OnvifDiscoverTask) *OnvifDiscoverTask {
+	if m == nil {
+		return nil
+	}
+	return &OnvifDiscoverTask{
+		TaskUuid:   m.TaskUuid,
+		Subnet:     m.Subnet,
+		Username:   m.Username,
+		Password:   m.Password,
+		Timeout:    m.Timeout,
+		ExpireTime: m.ExpireTime.UTC().Format(time.RFC3339),
+	}
+}
+
+type ListOnvifDiscoverTasksResponse struct {
+	Items []OnvifDiscoverTask `json:"items"`
+	Total int64               `json:"total"`
+}
+
+type OnvifCamera struct {
+	XAddr        string `json:"xaddr"`
+	Ip           string `json:"ip"`
+	OnvifPort    int    `json:"onvifPort"`
+	Name         string `json:"name,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Model        string `json:"model,omitempty"`
+	StreamUri    string `json:"streamUri,omitempty"`
+	Ptz          bool   `json:"ptz"`
+	Error        string `json:"error,omitempty"`
+}
+
+type OnvifDiscoverResult struct {
+	TaskUuid string        `json:"taskUuid" binding:"required"`
+	Cameras  []OnvifCamera `json:"cameras,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func (r *OnvifDiscoverResult) ToModel() *model.OnvifDiscoverResult {
+	m := &model.OnvifDiscoverResult{
+		TaskUuid:   r.TaskUuid,
+		Error:      r.Error,
+		CreateTime: time.Now(),
+	}
+	for _, c := range r.Cameras {
+		m.Cameras = append(m.Cameras, model.OnvifCamera(c))
+	}
+	return m
+}
+
+type DiscoveredCamera struct {
+	OnvifCamera
+	// 用于创建摄像头的预填请求，无法获取码流地址时为空
+	Camera *CreateCameraRequest `json:"camera,omitempty"`
+}
+
+type DiscoverCamerasResponse struct {
+	Cameras []DiscoveredCamera `json:"cameras"`
+}
+
+// FromOnvifDiscoverResultModel converts a discover result and prefills the
+// create request of every camera whose stream uri is known.
+func FromOnvifDiscoverResultModel(m *model.OnvifDiscoverResult, req *DiscoverCamerasRequest) *DiscoverCamerasResponse {
+	resp := &DiscoverCamerasResponse{Cameras: make([]DiscoveredCamera, 0, len(m.Cameras))}
+	for _, c := range m.Cameras {
+		d := DiscoveredCamera{OnvifCamera: OnvifCamera(c)}
+		d.Camera = prefillCamera(&c, req)
+		resp.Cameras = append(resp.Cameras, d)
+	}
+	return resp
+}
+
+func prefillCamera(c *model.OnvifCamera, req *DiscoverCamerasRequest) *CreateCameraRequest {
+	u, err := url.Parse(c.StreamUri)
+	if err != nil || c.StreamUri == "" {
+		return nil
+	}
+	name := c.Name
+	if name == "" {
+		name = c.Manufacturer + " " + c.Model
+	}
+	if name == " " {
+		name = c.Ip
+	}
+	port, _ := strconv.Atoi(u.Port())
+	path := u.Path
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	// cameras behind NAT report their internal address in the uri, the
+	// address that answered the probe is reachable from the device
+	return &CreateCameraRequest{
+		Name:         name,
+		Protocol:     model.CameraProtocol(u.Scheme),
+		Ip:           c.Ip,
+		Port:         port,
+		Path:         path,
+		Username:     req.Username,
+		Password:     req.Password,
+		BindDeviceId: req.DeviceId,
+		OnvifPort:    c.OnvifPort,
+	}
+}
+
+type PtzMoveRequest struct {
+	// 水平、垂直和变焦速度，取值 -1 到 1
+	Pan  float64 `json:"pan" binding:"min=-1,max=1"`
+	Tilt float64 `json:"tilt" binding:"min=-1,max=1"`
+	Zoom float64 `json:"zoom" binding:"min=-1,max=1"`
+	// 持续时间，单位毫秒，超时后摄像头自动停止，默认 1000
+	Timeout int `json:"timeout,omitempty" binding:"omitempty,min=100,max=10000"`
+}
+
+type PtzPreset struct {
+	Token string `json:"token"`
+	Name  string `json:"name"`
+}
+
+type ListPtzPresetsResponse struct {
+	Presets []PtzPreset `json:"presets"`
+}
+
+type PtzGotoPresetRequest struct {
+	PresetToken string `json:"presetToken" binding:"required"`
+}
@@ -0,0 +1,33 @@
+package dao
+
+import (
+	"time"
+
+	"lumina/internal/model"
+)
+
+type OrganizationSpec struct {
+	// 组织ID
+	Id int `json:"id"`
+	// 组织名称
+	Name        string `json:"name"`
+	CreatedTime string `json:"createdTime"`
+}
+
+type CreateOrganizationRequest struct {
+	// 组织名称
+	Name string `json:"name" binding:"required,max=96"`
+}
+
+type ListOrganizationsResponse struct {
+	// 组织列表
+	Items []OrganizationSpec `json:"items"`
+}
+
+func ToOrganizationSpec(o *model.Organization) OrganizationSpec {
+	return OrganizationSpec{
+		Id:          o.Id,
+		Name:        o.Name,
+		CreatedTime: o.CreatedTime.UTC().Format(time.RFC3339),
+	}
+}
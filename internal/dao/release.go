@@ -0,0 +1,170 @@
+package dao
+
+import (
+	"time"
+
+	"lumina/internal/model"
+)
+
+// ReleaseSpec 设备程序版本
+type ReleaseSpec struct {
+	Id      int                  `json:"id"`
+	Version string               `json:"version"`
+	Channel model.ReleaseChannel `json:"channel"`
+	// 程序下载地址
+	Url string `json:"url"`
+	// 程序的 sha256 校验值
+	Sha256     string `json:"sha256"`
+	Notes      string `json:"notes,omitempty"`
+	CreateTime string `json:"createTime"`
+}
+
+func FromReleaseModel(m *model.Release) *ReleaseSpec {
+	if m == nil {
+		return nil
+	}
+	return &ReleaseSpec{
+		Id:         m.Id,
+		Version:    m.Version,
+		Channel:    m.Channel,
+		Url:        m.Url,
+		Sha256:     m.Sha256,
+		Notes:      m.Notes,
+		CreateTime: m.CreateTime.UTC().Format(time.RFC3339),
+	}
+}
+
+type CreateReleaseRequest struct {
+	// 版本号，与设备上报的 lumina 版本一致，如 v0.4.0
+	Version string `json:"version" binding:"required,max=64"`
+	// 发布通道，stable 或 beta
+	Channel model.ReleaseChannel `json:"channel" binding:"required,oneof=stable beta"`
+	// 程序下载地址
+	Url string `json:"url" binding:"required,url,max=1024"`
+	// 程序的 sha256 校验值
+	Sha256 string `json:"sha256" binding:"required,len=64,hexadecimal"`
+	// 版本说明
+	Notes string `json:"notes" binding:"max=1024"`
+}
+
+func (req *CreateReleaseRequest) ToModel() *model.Release {
+	return &model.Release{
+		Version: req.Version,
+		Channel: req.Channel,
+		Url:     req.Url,
+		Sha256:  req.Sha256,
+		Notes:   req.Notes,
+	}
+}
+
+type CreateReleaseResponse struct {
+	Id int `json:"id"`
+}
+
+type ListReleasesRequest struct {
+	Start int `json:"start" form:"start" binding:"min=0"`
+	Limit int `json:"limit" form:"limit" binding:"min=0,max=100"`
+	// 只列出指定通道的版本
+	Channel model.ReleaseChannel `json:"channel" form:"channel" binding:"omitempty,oneof=stable beta"`
+}
+
+type ListReleasesResponse struct {
+	Items []ReleaseSpec `json:"items"`
+	Total int64         `json:"total"`
+}
+
+// RolloutSpec 设备组升级
+type RolloutSpec struct {
+	Id      int          `json:"id"`
+	GroupId int          `json:"groupId"`
+	Release *ReleaseSpec `json:"release,omitempty"`
+	// 状态：running、halted、completed、cancelled
+	Status model.RolloutStatus `json:"status"`
+	// 失败率阈值，升级过的设备中失败的比例超过该值时自动暂停
+	FailureThreshold float64 `json:"failureThreshold"`
+	// 暂停原因
+	HaltReason string `json:"haltReason,omitempty"`
+	// 升级中的设备数
+	Upgrading int64 `json:"upgrading"`
+	// 升级成功的设备数
+	Succeeded int64 `json:"succeeded"`
+	// 升级失败的设备数
+	Failed     int64  `json:"failed"`
+	CreateTime string `json:"createTime"`
+	UpdateTime string `json:"updateTime"`
+}
+
+func FromRolloutModel(m *model.Rollout, release *model.Release, counts map[model.DeviceUpgradeStatus]int64) *RolloutSpec {
+	return &RolloutSpec{
+		Id:               m.Id,
+		GroupId:          m.GroupId,
+		Release:          FromReleaseModel(release),
+		Status:           m.Status,
+		FailureThreshold: m.FailureThreshold,
+		HaltReason:       m.HaltReason,
+		Upgrading:        counts[model.DeviceUpgradeStatusUpgrading],
+		Succeeded:        counts[model.DeviceUpgradeStatusSucceeded],
+		Failed:           counts[model.DeviceUpgradeStatusFailed],
+		CreateTime:       m.CreateTime.UTC().Format(time.RFC3339),
+		UpdateTime:       m.UpdateTime.UTC().Format(time.RFC3339),
+	}
+}
+
+type CreateRolloutRequest struct {
+	// 目标版本，为空时使用设备组通道的最新版本
+	ReleaseId int `json:"releaseId,omitempty"`
+	// 失败率阈值，0 到 1，默认 0.2
+	FailureThreshold *float64 `json:"failureThreshold,omitempty" binding:"omitempty,min=0,max=1"`
+}
+
+type CreateRolloutResponse struct {
+	Id int `json:"id"`
+}
+
+type ListRolloutsRequest struct {
+	Start int `json:"start" form:"start" binding:"min=0"`
+	Limit int `json:"limit" form:"limit" binding:"min=0,max=100"`
+}
+
+type ListRolloutsResponse struct {
+	Items []RolloutSpec `json:"items"`
+	Total int64         `json:"total"`
+}
+
+// DeviceUpgradeSpec 单个设备的升级状态
+type DeviceUpgradeSpec struct {
+	Device *DeviceSpec `json:"device"`
+	// 状态：upgrading、succeeded、failed
+	Status     model.DeviceUpgradeStatus `json:"status"`
+	Error      string                    `json:"error,omitempty"`
+	UpdateTime string                    `json:"updateTime"`
+}
+
+type ListDeviceUpgradesResponse struct {
+	Items []DeviceUpgradeSpec `json:"items"`
+}
+
+// UpgradeTask 设备升级任务
+type UpgradeTask struct {
+	RolloutId int    `json:"rolloutId"`
+	Version   string `json:"version"`
+	Url       string `json:"url"`
+	Sha256    string `json:"sha256"`
+}
+
+type GetUpgradeTaskResponse struct {
+	// 无需升级时为空
+	Task *UpgradeTask `json:"task,omitempty"`
+}
+
+// UpgradeResult 设备升级失败时上报，升级成功以设备重启后上报的版本为准
+type UpgradeResult struct {
+	RolloutId int    `json:"rolloutId" binding:"required"`
+	Error     string `json:"error" binding:"required"`
+}
+
+// UpgradeWindowOpen reports whether the devices of the group may upgrade at
+// t, a group without upgrade window may upgrade any time.
+func UpgradeWindowOpen(g *model.DeviceGroup, t time.Time) bool {
+	return ScheduleArmed(g.UpgradeWindow, t)
+}
@@ -0,0 +1,206 @@
+package dao
+
+import (
+	"time"
+
+	"lumina/internal/model"
+)
+
+// ReportScheduleSpec 定时报表
+type ReportScheduleSpec struct {
+	Id      int    `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// 报表覆盖的站点，即摄像头标签，为空表示全部摄像头
+	Site string `json:"site"`
+	// 报表周期：daily 每日，weekly 每周
+	Period model.ReportPeriod `json:"period"`
+	// 报表格式：html 或 pdf
+	Format model.ReportFormat `json:"format"`
+	// 生成上一周期报表的时刻（0-23 点）
+	Hour int `json:"hour"`
+	// 周报在星期几生成，0 表示星期日
+	Weekday int `json:"weekday"`
+	// 报表使用的时区，为空表示服务器本地时区
+	Timezone string `json:"timezone"`
+	// 投递报表的通知通道ID
+	ChannelIds []int `json:"channelIds"`
+	// 最近一次报表的周期结束时间
+	LastPeriodEnd string `json:"lastPeriodEnd,omitempty"`
+	// 最近一次生成报表的时间
+	LastRunTime string `json:"lastRunTime,omitempty"`
+	// 最近一次生成或投递失败的原因，为空表示成功
+	LastError  string `json:"lastError,omitempty"`
+	CreateTime string `json:"createTime"`
+	UpdateTime string `json:"updateTime"`
+}
+
+func FromReportScheduleModel(m *model.ReportSchedule) *ReportScheduleSpec {
+	spec := &ReportScheduleSpec{
+		Id:         m.Id,
+		Name:       m.Name,
+		Enabled:    m.Enabled,
+		Site:       m.Site,
+		Period:     m.Period,
+		Format:     m.Format,
+		Hour:       m.Hour,
+		Weekday:    m.Weekday,
+		Timezone:   m.Timezone,
+		ChannelIds: m.ChannelIds,
+		LastError:  m.LastError,
+		CreateTime: m.CreateTime.UTC().Format(time.RFC3339),
+		UpdateTime: m.UpdateTime.UTC().Format(time.RFC3339),
+	}
+	if m.LastPeriodEnd != nil {
+		spec.LastPeriodEnd = m.LastPeriodEnd.UTC().Format(time.RFC3339)
+	}
+	if m.LastRunTime != nil {
+		spec.LastRunTime = m.LastRunTime.UTC().Format(time.RFC3339)
+	}
+	if spec.ChannelIds == nil {
+		spec.ChannelIds = []int{}
+	}
+	return spec
+}
+
+type CreateReportScheduleRequest struct {
+	// 报表名称
+	Name    string `json:"name" binding:"required,max=96"`
+	Enabled bool   `json:"enabled"`
+	// 报表覆盖的站点，即摄像头标签，为空表示全部摄像头
+	Site string `json:"site" binding:"max=64"`
+	// 报表周期：daily 每日，weekly 每周
+	Period model.ReportPeriod `json:"period" binding:"required,oneof=daily weekly"`
+	// 报表格式：html 或 pdf，默认 html
+	Format model.ReportFormat `json:"format,omitempty" binding:"omitempty,oneof=html pdf"`
+	// 生成上一周期报表的时刻（0-23 点）
+	Hour int `json:"hour" binding:"min=0,max=23"`
+	// 周报在星期几生成，0 表示星期日
+	Weekday int `json:"weekday" binding:"min=0,max=6"`
+	// 报表使用的时区，如 Asia/Shanghai，为空表示服务器本地时区
+	Timezone string `json:"timezone" binding:"omitempty,timezone"`
+	// 投递报表的通知通道ID
+	ChannelIds []int `json:"channelIds" binding:"required,min=1,max=20,dive,min=1"`
+}
+
+func (r *CreateReportScheduleRequest) ToModel() *model.ReportSchedule {
+	format := r.Format
+	if format == "" {
+		format = model.ReportFormatHTML
+	}
+	return &model.ReportSchedule{
+		Name:       r.Name,
+		Enabled:    r.Enabled,
+		Site:       r.Site,
+		Period:     r.Period,
+		Format:     format,
+		Hour:       r.Hour,
+		Weekday:    r.Weekday,
+		Timezone:   r.Timezone,
+		ChannelIds: r.ChannelIds,
+	}
+}
+
+type CreateReportScheduleResponse struct {
+	Id int `json:"id"`
+}
+
+type UpdateReportScheduleRequest struct {
+	// 报表名称
+	Name    *string `json:"name,omitempty" binding:"omitempty,min=1,max=96"`
+	Enabled *bool   `json:"enabled,omitempty"`
+	// 报表覆盖的站点，传空字符串表示全部摄像头
+	Site *string `json:"site,omitempty" binding:"omitempty,max=64"`
+	// 报表周期：daily 每日，weekly 每周
+	Period *model.ReportPeriod `json:"period,omitempty" binding:"omitempty,oneof=daily weekly"`
+	// 报表格式：html 或 pdf
+	Format *model.ReportFormat `json:"format,omitempty" binding:"omitempty,oneof=html pdf"`
+	// 生成上一周期报表的时刻（0-23 点）
+	Hour *int `json:"hour,omitempty" binding:"omitempty,min=0,max=23"`
+	// 周报在星期几生成，0 表示星期日
+	Weekday *int `json:"weekday,omitempty" binding:"omitempty,min=0,max=6"`
+	// 报表使用的时区，传空字符串表示服务器本地时区
+	Timezone *string `json:"timezone,omitempty" binding:"omitempty,timezone"`
+	// 投递报表的通知通道ID，传入时整体替换
+	ChannelIds []int `json:"channelIds,omitempty" binding:"omitempty,min=1,max=20,dive,min=1"`
+}
+
+func (r *UpdateReportScheduleRequest) UpdateModel(m *model.ReportSchedule) {
+	if r.Name != nil {
+		m.Name = *r.Name
+	}
+	if r.Enabled != nil {
+		m.Enabled = *r.Enabled
+	}
+	if r.Site != nil {
+		m.Site = *r.Site
+	}
+	if r.Period != nil {
+		m.Period = *r.Period
+	}
+	if r.Format != nil {
+		m.Format = *r.Format
+	}
+	if r.Hour != nil {
+		m.Hour = *r.Hour
+	}
+	if r.Weekday != nil {
+		m.Weekday = *r.Weekday
+	}
+	if r.Timezone != nil {
+		m.Timezone = *r.Timezone
+	}
+	if r.ChannelIds != nil {
+		m.ChannelIds = r.ChannelIds
+	}
+}
+
+type ListReportSchedulesResponse struct {
+	Items []ReportScheduleSpec `json:"items"`
+}
+
+// ReportSpec 已生成的报表
+type ReportSpec struct {
+	Id         int                `json:"id"`
+	ScheduleId int                `json:"scheduleId"`
+	Site       string             `json:"site"`
+	Period     model.ReportPeriod `json:"period"`
+	Format     model.ReportFormat `json:"format"`
+	// 报表统计的时间范围
+	PeriodStart string `json:"periodStart"`
+	PeriodEnd   string `json:"periodEnd"`
+	// 报表的概要
+	Summary    model.ReportSummary `json:"summary"`
+	CreateTime string              `json:"createTime"`
+}
+
+func FromReportModel(m *model.Report) *ReportSpec {
+	return &ReportSpec{
+		Id:          m.Id,
+		ScheduleId:  m.ScheduleId,
+		Site:        m.Site,
+		Period:      m.Period,
+		Format:      m.Format,
+		PeriodStart: m.PeriodStart.UTC().Format(time.RFC3339),
+		PeriodEnd:   m.PeriodEnd.UTC().Format(time.RFC3339),
+		Summary:     m.Summary,
+		CreateTime:  m.CreateTime.UTC().Format(time.RFC3339),
+	}
+}
+
+type ListReportsRequest struct {
+	Start int `json:"start" form:"start" binding:"min=0"`
+	Limit int `json:"limit" form:"limit" binding:"min=0,max=100"`
+}
+
+type ListReportsResponse struct {
+	Items []ReportSpec `json:"items"`
+	Total int64        `json:"total"`
+}
+
+// RunReportResponse 立即生成报表的结果
+type RunReportResponse struct {
+	Report *ReportSpec `json:"report"`
+	// 投递失败的原因，为空表示全部通道投递成功
+	Error string `json:"error,omitempty"`
+}
@@ -1,7 +1,13 @@
 package dao
 
+import (
+	"database/sql"
+
+	"lumina/internal/model"
+)
+
 // JobStatsRequest 查询参数
-// 采用 RFC3339 时间字符串和窗口字符串（如 1m、5m、15m）
+// 时间为 RFC3339 字符串或 Unix 时间戳（秒或毫秒），窗口为字符串（如 1m、5m、15m）
 // 若未提供则使用默认值：start=过去24小时, end=当前时间, window=5m
 type JobStatsRequest struct {
 	Start  string `form:"start" json:"start"`
@@ -22,9 +28,155 @@ type LabelTimeCount struct {
 	Count int64  `json:"count"`
 }
 
+// AlertTimeCount 用于各级别告警数量趋势，统计产生告警的消息，去重合并的告警按每次出现计数
+type AlertTimeCount struct {
+	// 任务ID，任务已删除时为 0；合计趋势中为空
+	JobId    int                 `json:"jobId,omitempty"`
+	JobUuid  string              `json:"jobUuid,omitempty"`
+	Severity model.AlertSeverity `json:"severity"`
+	Time     string              `json:"time"`
+	Count    int64               `json:"count"`
+}
+
 // JobStatsResponse 服务端返回结构
-// detect 任务返回 messages + labels；video_segment 仅返回 messages
+// detect 任务返回 messages + labels；video_segment 仅返回 messages；两者都返回 alerts
 type JobStatsResponse struct {
 	Messages []TimeCount      `json:"messages"`
 	Labels   []LabelTimeCount `json:"labels,omitempty"`
+	Alerts   []AlertTimeCount `json:"alerts"`
+}
+
+// AlertTrendRequest 告警趋势查询参数，时间和窗口的格式与默认值同 JobStatsRequest
+type AlertTrendRequest struct {
+	Start  string `form:"start" json:"start"`
+	End    string `form:"end" json:"end"`
+	Window string `form:"window" json:"window"`
+	// 任务ID，为空时统计所有任务
+	JobId int `form:"jobId" json:"jobId"`
+	// 告警级别：low、medium、high、critical，为空时统计所有级别
+	Severity model.AlertSeverity `form:"severity" json:"severity" binding:"omitempty,oneof=low medium high critical"`
+}
+
+// AlertTrendResponse 告警趋势
+type AlertTrendResponse struct {
+	// 所有任务合计的各级别告警数量趋势
+	Totals []AlertTimeCount `json:"totals"`
+	// 各任务各级别的告警数量趋势
+	Items []AlertTimeCount `json:"items"`
+}
+
+// FrameRatePoint 一个聚合窗口内执行器的平均帧率
+type FrameRatePoint struct {
+	Time string `json:"time"`
+	// 实际每秒推理帧数
+	FrameRate float64 `json:"frameRate"`
+	// 设备限流后的每秒推理帧数上限，0 表示未限流
+	FrameRateLimit float64 `json:"frameRateLimit"`
+}
+
+// DeviceJobStats 设备上一个任务的统计
+type DeviceJobStats struct {
+	JobId   int           `json:"jobId"`
+	JobUuid string        `json:"jobUuid"`
+	Kind    model.JobKind `json:"kind"`
+	// 设备组任务的消息和告警包含组内所有设备
+	DeviceGroupId int         `json:"deviceGroupId,omitempty"`
+	Messages      []TimeCount `json:"messages"`
+	Alerts        []TimeCount `json:"alerts"`
+	// 该任务在本设备上的执行器帧率
+	FrameRates []FrameRatePoint `json:"frameRates"`
+}
+
+// DeviceStatsResponse 设备上所有任务的统计
+type DeviceStatsResponse struct {
+	// 所有任务合计的消息数量趋势
+	Messages []TimeCount `json:"messages"`
+	// 所有任务合计的告警数量趋势
+	Alerts []TimeCount      `json:"alerts"`
+	Jobs   []DeviceJobStats `json:"jobs"`
+}
+
+// OccupancyStatsRequest 区域人数统计查询参数，时间和窗口的格式与默认值同 JobStatsRequest
+type OccupancyStatsRequest struct {
+	Start  string `form:"start" json:"start"`
+	End    string `form:"end" json:"end"`
+	Window string `form:"window" json:"window"`
+	// 只查询指定区域
+	Zone string `form:"zone" json:"zone"`
+}
+
+// HeatmapRequest 检测热力图查询参数
+type HeatmapRequest struct {
+	// 统计最近一段时间，如 24h、7d，默认 7d，最长 90d
+	Range string `form:"range" json:"range"`
+	// 只统计指定标签，如 person
+	Label string `form:"label" json:"label"`
+}
+
+// OccupancyPoint 一个聚合窗口内的区域人数
+type OccupancyPoint struct {
+	Time string `json:"time"`
+	// 窗口内的最大人数
+	Max int64 `json:"max"`
+	// 窗口内进入人次
+	In int64 `json:"in"`
+	// 窗口内离开人次
+	Out int64 `json:"out"`
+	// 净流入人次，即人数的变化量
+	Net int64 `json:"net"`
+}
+
+type ZoneOccupancyTrend struct {
+	Zone string `json:"zone"`
+	// 最近一次上报的人数
+	Current int64 `json:"current"`
+	// 最近一次上报的时间
+	UpdateTime string           `json:"updateTime,omitempty"`
+	Points     []OccupancyPoint `json:"points"`
+}
+
+type CameraOccupancyResponse struct {
+	Zones []ZoneOccupancyTrend `json:"zones"`
+}
+
+// DBPoolStats 数据库连接池状态
+type DBPoolStats struct {
+	// 连接池允许的最大连接数，0 表示不限制
+	MaxOpenConns int `json:"maxOpenConns"`
+	// 当前打开的连接数
+	OpenConns int `json:"openConns"`
+	// 使用中的连接数
+	InUse int `json:"inUse"`
+	// 空闲连接数
+	Idle int `json:"idle"`
+	// 累计等待连接的次数
+	WaitCount int64 `json:"waitCount"`
+	// 累计等待连接的时长，毫秒
+	WaitDuration int64 `json:"waitDuration"`
+	// 因超出最大空闲连接数而关闭的连接数
+	MaxIdleClosed int64 `json:"maxIdleClosed"`
+	// 因超出最大空闲时间而关闭的连接数
+	MaxIdleTimeClosed int64 `json:"maxIdleTimeClosed"`
+	// 因超出最大存活时间而关闭的连接数
+	MaxLifetimeClosed int64 `json:"maxLifetimeClosed"`
+	// 连接池饱和度，使用中的连接数占最大连接数的比例，不限制时为 0
+	Saturation float64 `json:"saturation"`
+}
+
+func FromDBStats(s sql.DBStats) DBPoolStats {
+	res := DBPoolStats{
+		MaxOpenConns:      s.MaxOpenConnections,
+		OpenConns:         s.OpenConnections,
+		InUse:             s.InUse,
+		Idle:              s.Idle,
+		WaitCount:         s.WaitCount,
+		WaitDuration:      s.WaitDuration.Milliseconds(),
+		MaxIdleClosed:     s.MaxIdleClosed,
+		MaxIdleTimeClosed: s.MaxIdleTimeClosed,
+		MaxLifetimeClosed: s.MaxLifetimeClosed,
+	}
+	if s.MaxOpenConnections > 0 {
+		res.Saturation = float64(s.InUse) / float64(s.MaxOpenConnections)
+	}
+	return res
 }
@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"time"
+
+	"lumina/internal/model"
+)
+
+// TalkDownRequest 手动喊话请求，未填写的字段使用任务的喊话配置
+type TalkDownRequest struct {
+	// 喊话文本，由设备合成语音
+	Text string `json:"text,omitempty"`
+	// 音频文件地址
+	AudioUrl string `json:"audioUrl,omitempty"`
+	// 播放方式，onvif 为摄像头音频回传通道，local 为设备本地音频输出
+	Output string `json:"output,omitempty" binding:"omitempty,oneof=onvif local"`
+}
+
+type TalkDownTask struct {
+	TaskUuid   string `json:"taskUuid"`
+	PullAddr   string `json:"pullAddr"`
+	Text       string `json:"text,omitempty"`
+	AudioUrl   string `json:"audioUrl,omitempty"`
+	Output     string `json:"output"`
+	ExpireTime string `json:"expireTime"`
+}
+
+func (t TalkDownTask) Expired() bool {
+	if t.ExpireTime == "" {
+		return false
+	}
+	expireTime, err := time.Parse(time.RFC3339, t.ExpireTime)
+	if err != nil {
+		return false
+	}
+	return expireTime.Before(time.Now())
+}
+
+func FromTalkDownTaskModel(m *model.TalkDownTask) *TalkDownTask {
+	if m == nil {
+		return nil
+	}
+	return &TalkDownTask{
+		TaskUuid:   m.TaskUuid,
+		PullAddr:   m.PullAddr,
+		Text:       m.Text,
+		AudioUrl:   m.AudioUrl,
+		Output:     m.Output,
+		ExpireTime: m.ExpireTime.UTC().Format(time.RFC3339),
+	}
+}
+
+type ListTalkDownTasksResponse struct {
+	Items []TalkDownTask `json:"items"`
+	Total int64          `json:"total"`
+}
+
+// TalkDownResult 设备上报的喊话结果，也作为手动喊话接口的响应
+type TalkDownResult struct {
+	TaskUuid string `json:"taskUuid" binding:"required"`
+	// 播放失败时的错误信息
+	Error string `json:"error,omitempty"`
+	// 播放耗时，单位毫秒
+	Duration   int64  `json:"duration"`
+	CreateTime string `json:"createTime,omitempty"`
+}
+
+func (r *TalkDownResult) ToModel() *model.TalkDownResult {
+	return &model.TalkDownResult{
+		TaskUuid:   r.TaskUuid,
+		Error:      r.Error,
+		Duration:   r.Duration,
+		CreateTime: time.Now(),
+	}
+}
+
+func FromTalkDownResultModel(m *model.TalkDownResult) *TalkDownResult {
+	if m == nil {
+		return nil
+	}
+	return &TalkDownResult{
+		TaskUuid:   m.TaskUuid,
+		Error:      m.Error,
+		Duration:   m.Duration,
+		CreateTime: m.CreateTime.UTC().Format(time.RFC3339),
+	}
+}
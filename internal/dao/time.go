@@ -0,0 +1,43 @@
+package dao
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	// the display timezones are validated without relying on the system
+	// zoneinfo, which minimal images lack
+	_ "time/tzdata"
+)
+
+// epochMillisThreshold tells the epochs in milliseconds from those in
+// seconds, it is in 1973 as milliseconds and in 5138 as seconds.
+const epochMillisThreshold = 1e11
+
+// ParseTime parses a time of an API request, given either as RFC3339 or as a
+// Unix epoch in seconds or milliseconds. The time is returned in UTC.
+func ParseTime(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n < 0 {
+			return time.Time{}, fmt.Errorf("invalid epoch %s", s)
+		}
+		if n >= epochMillisThreshold {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expect RFC3339 or a Unix epoch", s)
+	}
+	return t.UTC(), nil
+}
+
+// ValidTimezone reports whether tz is an IANA timezone, e.g. Asia/Shanghai.
+func ValidTimezone(tz string) bool {
+	if tz == "" || tz == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(tz)
+	return err == nil
+}
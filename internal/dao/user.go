@@ -11,6 +11,29 @@ type UserSpec struct {
 	Nickname    string `json:"nickname" binding:"required"`
 	IsAdmin     bool   `json:"isAdmin"`
 	CreatedTime string `json:"createdTime" binding:"required,datetime=2006-01-02T15:04:05Z07:00"`
+	// 显示时区，IANA 时区名，如 Asia/Shanghai，为空时使用浏览器时区
+	Timezone string `json:"timezone"`
+	// 是否已启用两步验证
+	TotpEnabled bool `json:"totpEnabled"`
+	// 所属组织ID
+	OrgId int `json:"orgId"`
+}
+
+type UpdateProfileRequest struct {
+	// 显示时区，IANA 时区名，如 Asia/Shanghai，为空时使用浏览器时区
+	Timezone string `json:"timezone" binding:"omitempty,timezone"`
+}
+
+type ChangePasswordRequest struct {
+	// 当前密码
+	OldPassword string `json:"oldPassword" binding:"required"`
+	// 新密码，至少 8 位
+	NewPassword string `json:"newPassword" binding:"required,min=8,max=72"`
+}
+
+type ResetPasswordResponse struct {
+	// 临时密码，只返回这一次，用户登录后应尽快修改
+	Password string `json:"password"`
 }
 
 type LoginRequest struct {
@@ -18,12 +41,94 @@ type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	// 密码
 	Password string `json:"password" binding:"required"`
+	// 两步验证码或恢复码，启用了两步验证的用户必填
+	TotpCode string `json:"totpCode"`
 }
 
 type LoginResponse struct {
-	// 登录凭证
-	Token string   `json:"token"`
-	User  UserSpec `json:"user"`
+	// 登录凭证，短期有效，过期后用刷新令牌换取新的
+	Token string `json:"token"`
+	// 刷新令牌，每次刷新后作废并返回新的
+	RefreshToken string `json:"refreshToken"`
+	// 登录凭证的有效秒数
+	ExpiresIn int      `json:"expiresIn"`
+	User      UserSpec `json:"user"`
+	// 管理员须先启用两步验证才能访问管理接口
+	TotpEnrollmentRequired bool `json:"totpEnrollmentRequired"`
+}
+
+type RefreshRequest struct {
+	// 刷新令牌，为空时使用 refresh_token cookie
+	RefreshToken string `json:"refreshToken"`
+}
+
+type RefreshResponse struct {
+	// 新的登录凭证
+	Token string `json:"token"`
+	// 新的刷新令牌，原刷新令牌已作废
+	RefreshToken string `json:"refreshToken"`
+	// 登录凭证的有效秒数
+	ExpiresIn int `json:"expiresIn"`
+}
+
+type SessionSpec struct {
+	// 会话ID
+	Id string `json:"id"`
+	// 登录时间
+	LoginTime string `json:"loginTime"`
+	// 最近一次刷新的时间
+	RefreshTime string `json:"refreshTime"`
+	// 未刷新时的过期时间
+	ExpireTime string `json:"expireTime"`
+	UserAgent  string `json:"userAgent"`
+	Ip         string `json:"ip"`
+	// 是否为当前请求的会话
+	Current bool `json:"current"`
+}
+
+type ListSessionsResponse struct {
+	// 会话列表
+	Items []SessionSpec `json:"items"`
+}
+
+func ToSessionSpec(t *model.RefreshToken, currentSessionId string) SessionSpec {
+	expireTime := t.ExpireTime
+	if t.SessionExpireTime != nil && t.SessionExpireTime.Before(expireTime) {
+		expireTime = *t.SessionExpireTime
+	}
+	return SessionSpec{
+		Id:          t.SessionId,
+		LoginTime:   t.LoginTime.UTC().Format(time.RFC3339),
+		RefreshTime: t.CreatedTime.UTC().Format(time.RFC3339),
+		ExpireTime:  expireTime.UTC().Format(time.RFC3339),
+		UserAgent:   t.UserAgent,
+		Ip:          t.Ip,
+		Current:     t.SessionId == currentSessionId,
+	}
+}
+
+type TotpSetupResponse struct {
+	// base32 编码的密钥，供无法扫码时手动输入
+	Secret string `json:"secret"`
+	// otpauth 地址，以二维码展示给验证器应用扫描
+	Uri string `json:"uri"`
+}
+
+type TotpCodeRequest struct {
+	// 验证器应用生成的 6 位验证码
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type DisableTotpRequest struct {
+	// 当前密码
+	Password string `json:"password" binding:"required"`
+	// 两步验证码或恢复码
+	Code string `json:"code" binding:"required"`
+}
+
+type TotpRecoveryCodesResponse struct {
+	// 恢复码，每个只能使用一次，只返回这一次
+	RecoveryCodes []string `json:"recoveryCodes"`
 }
 
 type ListUsersRequest struct {
@@ -51,6 +156,8 @@ type CreateUserRequest struct {
 	IsAdmin bool `json:"isAdmin"`
 	// 部门id
 	DepartmentId int `json:"departmentId" binding:"required"`
+	// 所属组织ID，默认为当前管理员的组织，仅默认组织的管理员可以指定其他组织
+	OrgId int `json:"orgId" binding:"omitempty,min=1"`
 }
 
 type CreateUserResponse struct {
@@ -64,14 +171,21 @@ func ToUserSpec(u *model.User) (*UserSpec, error) {
 		Username:    u.Username,
 		Nickname:    u.Nickname,
 		IsAdmin:     u.IsAdmin,
-		CreatedTime: u.CreatedTime.Format(time.RFC3339),
+		OrgId:       u.OrgId,
+		CreatedTime: u.CreatedTime.UTC().Format(time.RFC3339),
+		Timezone:    u.Timezone,
+		TotpEnabled: u.TotpEnabled,
 	}, nil
 }
 
 func (r CreateUserRequest) ToUserModel() (*model.User, error) {
+	hash, err := model.HashPassword(r.Password)
+	if err != nil {
+		return nil, err
+	}
 	return &model.User{
 		Username: r.Username,
-		Password: r.Password,
+		Password: hash,
 		Nickname: r.Nickname,
 		IsAdmin:  r.IsAdmin,
 	}, nil
@@ -1,6 +1,9 @@
 package dao
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"lumina/internal/model"
@@ -32,7 +35,7 @@ func FromWorkflowModel(m *model.Workflow) *WorkflowSpec {
 	w.ModelName = m.ModelName
 	w.Name = m.Name
 	w.Timeout = m.Timeout
-	w.CreateTime = m.CreateTime.Format(time.RFC3339)
+	w.CreateTime = m.CreateTime.UTC().Format(time.RFC3339)
 	w.Query = m.Query
 	w.ResultFilter = FromFilterConditionModel(m.ResultFilter)
 	return w
@@ -116,6 +119,89 @@ type ListWorkflowResponse struct {
 	Total     int64          `json:"total"`
 }
 
+// WorkflowBundleVersion is the format version written into exported bundles.
+const WorkflowBundleVersion = 1
+
+// WorkflowExportItem is a workflow as it appears in an export bundle. The
+// API key is never exported and must be re-entered on import.
+type WorkflowExportItem struct {
+	Uuid         string           `json:"uuid" binding:"required"`
+	Endpoint     string           `json:"endpoint" binding:"required"`
+	ModelName    string           `json:"modelName" binding:"required"`
+	Name         string           `json:"name" binding:"required"`
+	Timeout      int              `json:"timeout"`
+	Query        string           `json:"query"`
+	ResultFilter *FilterCondition `json:"resultFilter,omitempty"`
+}
+
+func FromWorkflowModelForExport(m *model.Workflow) WorkflowExportItem {
+	return WorkflowExportItem{
+		Uuid:         m.Uuid,
+		Endpoint:     m.Endpoint,
+		ModelName:    m.ModelName,
+		Name:         m.Name,
+		Timeout:      m.Timeout,
+		Query:        m.Query,
+		ResultFilter: FromFilterConditionModel(m.ResultFilter),
+	}
+}
+
+// UpdateModel copies the exported configuration onto w, leaving its key alone.
+func (item *WorkflowExportItem) UpdateModel(w *model.Workflow) {
+	w.Uuid = item.Uuid
+	w.Endpoint = item.Endpoint
+	w.ModelName = item.ModelName
+	w.Name = item.Name
+	w.Timeout = item.Timeout
+	if w.Timeout <= 0 {
+		w.Timeout = 30
+	}
+	w.Query = item.Query
+	w.ResultFilter = item.ResultFilter.ToModel()
+}
+
+type WorkflowBundle struct {
+	Version    int                  `json:"version" binding:"required"`
+	ExportTime string               `json:"exportTime"`
+	Workflows  []WorkflowExportItem `json:"workflows" binding:"dive"`
+}
+
+type ExportWorkflowRequest struct {
+	// 需要导出的工作流ID，逗号分隔，为空时导出全部
+	Ids string `json:"ids" form:"ids"`
+}
+
+func (req *ExportWorkflowRequest) GetIds() ([]int, error) {
+	var ids []int
+	for _, s := range strings.Split(req.Ids, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid workflow id %q", s)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+type ImportWorkflowRequest struct {
+	// 导出的工作流数据
+	Bundle WorkflowBundle `json:"bundle" binding:"required"`
+	// 工作流UUID到API Key的映射，新建的工作流必须提供
+	Keys map[string]string `json:"keys,omitempty"`
+	// 是否覆盖已存在的同UUID工作流，未提供新Key时保留原Key
+	Overwrite bool `json:"overwrite,omitempty"`
+}
+
+type ImportWorkflowResponse struct {
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+	Skipped []string `json:"skipped"`
+}
+
 type Condition struct {
 	Field string         `json:"field,omitempty"`
 	Op    model.Operator `json:"op,omitempty"`
@@ -0,0 +1,102 @@
+package device
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gocv.io/x/gocv"
+
+	"lumina/internal/dao"
+	"lumina/internal/device/exector"
+	"lumina/internal/model"
+)
+
+const (
+	// maxArtifactSize is below the 64KB accepted by the server
+	maxArtifactSize       = 48 << 10
+	maxArtifactsPerReport = 4
+)
+
+// collectArtifacts returns the artifacts to send along the status report:
+// for the jobs whose last upload failed, a thumbnail of the newest image
+// waiting for upload and of the frame of the last error, if not sent yet.
+// They let operators see something while the object storage is unreachable.
+func (a *Device) collectArtifacts() []dao.DeviceArtifact {
+	var artifacts []dao.DeviceArtifact
+	add := func(jobUuid string, kind model.ArtifactKind, data []byte, t time.Time) {
+		if len(artifacts) >= maxArtifactsPerReport || len(data) == 0 || len(data) > maxArtifactSize {
+			return
+		}
+		artifacts = append(artifacts, dao.DeviceArtifact{
+			JobUuid:     jobUuid,
+			Kind:        kind,
+			ContentType: "image/jpeg",
+			Data:        data,
+			Timestamp:   t.UnixNano(),
+		})
+	}
+
+	for jobUuid, e := range a.executors {
+		uploads := e.Metrics().RecentUploads
+		if len(uploads) == 0 || uploads[len(uploads)-1].Error == "" {
+			continue
+		}
+		key := artifactKey(jobUuid, model.ArtifactTriggerThumbnail)
+		if imgPath, t := newestImage(path.Join(a.conf.JobDir(), jobUuid)); imgPath != "" && t.After(a.sentArtifacts[key]) {
+			img := gocv.IMRead(imgPath, gocv.IMReadColor)
+			thumb, err := exector.Thumbnail(img)
+			img.Close()
+			if err != nil {
+				a.logger.WithError(err).Warnf("thumbnail %s failed", imgPath)
+			} else {
+				add(jobUuid, model.ArtifactTriggerThumbnail, thumb, t)
+			}
+		}
+		if framer, ok := e.(exector.ErrorFramer); ok {
+			key := artifactKey(jobUuid, model.ArtifactErrorScreenshot)
+			if thumb, t := framer.ErrorFrame(); thumb != nil && t.After(a.sentArtifacts[key]) {
+				add(jobUuid, model.ArtifactErrorScreenshot, thumb, t)
+			}
+		}
+	}
+	return artifacts
+}
+
+// artifactsSent remembers the artifacts the server accepted so that they are
+// not sent again.
+func (a *Device) artifactsSent(artifacts []dao.DeviceArtifact) {
+	for _, art := range artifacts {
+		a.sentArtifacts[artifactKey(art.JobUuid, art.Kind)] = time.Unix(0, art.Timestamp)
+	}
+}
+
+func artifactKey(jobUuid string, kind model.ArtifactKind) string {
+	return jobUuid + "/" + string(kind)
+}
+
+// newestImage returns the newest jpg in dir and its modification time.
+func newestImage(dir string) (string, time.Time) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", time.Time{}
+	}
+	var newest string
+	var newestTime time.Time
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(strings.ToLower(entry.Name()), ".jpg") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(newestTime) {
+			newest = filepath.Join(dir, entry.Name())
+			newestTime = info.ModTime()
+		}
+	}
+	return newest, newestTime
+}
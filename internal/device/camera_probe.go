@@ -0,0 +1,238 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"lumina/internal/dao"
+	"lumina/internal/device/metadata"
+)
+
+const (
+	fetchCameraProbeTasksPath   = "/api/v1/device/camera-probe-tasks"
+	reportCameraProbeResultPath = "/api/v1/device/camera-probe-result"
+
+	cameraProbeTimeout = 15 * time.Second
+	modelCheckTimeout  = 5 * time.Second
+)
+
+func (a *Device) fetchCameraProbeTasksFromServer(info *metadata.DeviceInfo) (*dao.ListCameraProbeTasksResponse, error) {
+	a.logger.Debugf("fetch camera probe tasks")
+
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodGet, a.conf.LuminaServerAddr+fetchCameraProbeTasksPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+
+	var respBody dao.ListCameraProbeTasksResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, err
+	}
+	return &respBody, nil
+}
+
+func (a *Device) syncCameraProbeTasksFromServer() error {
+	info, err := a.db.GetDeviceInfo()
+	if err != nil {
+		return err
+	} else if info == nil || info.Uuid == nil {
+		return errors.New("device Id is nil, please register device")
+	}
+
+	resp, err := a.fetchCameraProbeTasksFromServer(info)
+	if err != nil {
+		return err
+	}
+
+	a.cameraProbeMu.Lock()
+	defer a.cameraProbeMu.Unlock()
+	for _, task := range resp.Items {
+		if task.Expired() {
+			continue
+		}
+		if _, running := a.cameraProbeTasks[task.TaskUuid]; running {
+			continue
+		}
+		a.logger.Infof("start camera probe task, task uuid: %s", task.TaskUuid)
+		a.cameraProbeTasks[task.TaskUuid] = struct{}{}
+		go a.runCameraProbeTask(info, task)
+	}
+
+	return nil
+}
+
+func (a *Device) runCameraProbeTask(info *metadata.DeviceInfo, task dao.CameraProbeTask) {
+	defer func() {
+		a.cameraProbeMu.Lock()
+		delete(a.cameraProbeTasks, task.TaskUuid)
+		a.cameraProbeMu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(a.ctx, cameraProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := probeCamera(ctx, task.PullAddr)
+	if err != nil {
+		a.logger.WithField("taskUuid", task.TaskUuid).WithError(err).Warn("probe camera failed")
+		result = &dao.CameraProbeResult{Error: err.Error()}
+	}
+	if task.ModelName != "" {
+		mctx, mcancel := context.WithTimeout(a.ctx, modelCheckTimeout)
+		if err := tritonModelReady(mctx, a.conf.Triton.ServerAddr, task.ModelName); err != nil {
+			result.ModelError = err.Error()
+		} else {
+			result.ModelReady = true
+		}
+		mcancel()
+	}
+	result.TaskUuid = task.TaskUuid
+	result.Duration = time.Since(start).Milliseconds()
+
+	if err := a.reportCameraProbeResult(info, result); err != nil {
+		a.logger.WithField("taskUuid", task.TaskUuid).WithError(err).Errorf("report camera probe result failed")
+	}
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType    string `json:"codec_type"`
+		CodecName    string `json:"codec_name"`
+		Profile      string `json:"profile"`
+		Width        int    `json:"width"`
+		Height       int    `json:"height"`
+		PixFmt       string `json:"pix_fmt"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+		RFrameRate   string `json:"r_frame_rate"`
+		SampleRate   string `json:"sample_rate"`
+		Channels     int    `json:"channels"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string `json:"format_name"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// probeCamera 使用 ffprobe 读取输入流的封装和首个音视频流信息
+func probeCamera(ctx context.Context, input string) (*dao.CameraProbeResult, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_streams",
+		"-show_format",
+		"-of", "json",
+		input,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("probe timeout after %s", cameraProbeTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			lines := strings.Split(msg, "\n")
+			return nil, errors.New(lines[len(lines)-1])
+		}
+		return nil, err
+	}
+
+	var out ffprobeOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("parse ffprobe output: %w", err)
+	}
+
+	result := &dao.CameraProbeResult{Format: out.Format.FormatName}
+	result.Bitrate, _ = strconv.ParseInt(out.Format.BitRate, 10, 64)
+	for _, s := range out.Streams {
+		switch s.CodecType {
+		case "video":
+			if result.Video != nil {
+				continue
+			}
+			fps := parseFrameRate(s.AvgFrameRate)
+			if fps == 0 {
+				fps = parseFrameRate(s.RFrameRate)
+			}
+			result.Video = &dao.CameraProbeVideo{
+				Codec:   s.CodecName,
+				Profile: s.Profile,
+				Width:   s.Width,
+				Height:  s.Height,
+				Fps:     fps,
+				PixFmt:  s.PixFmt,
+			}
+		case "audio":
+			if result.Audio != nil {
+				continue
+			}
+			sampleRate, _ := strconv.Atoi(s.SampleRate)
+			result.Audio = &dao.CameraProbeAudio{
+				Codec:      s.CodecName,
+				SampleRate: sampleRate,
+				Channels:   s.Channels,
+			}
+		}
+	}
+	if result.Video == nil {
+		result.Error = "no video stream found"
+		return result, nil
+	}
+	result.Reachable = true
+	return result, nil
+}
+
+// parseFrameRate parses an ffprobe rate such as "25/1", it returns 0 if the
+// rate is unknown.
+func parseFrameRate(rate string) float64 {
+	num, den, ok := strings.Cut(rate, "/")
+	if !ok {
+		v, _ := strconv.ParseFloat(rate, 64)
+		return v
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || d == 0 {
+		return 0
+	}
+	return float64(int(n/d*100+0.5)) / 100
+}
+
+func (a *Device) reportCameraProbeResult(info *metadata.DeviceInfo, result *dao.CameraProbeResult) error {
+	body, _ := json.Marshal(result)
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.conf.LuminaServerAddr+reportCameraProbeResultPath, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+	return nil
+}
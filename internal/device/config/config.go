@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"time"
 
 	"gopkg.in/yaml.v2"
+
+	"lumina/pkg/profile"
+	"lumina/pkg/tracing"
 )
 
 type TritonConfig struct {
@@ -18,11 +22,133 @@ type NSQConfig struct {
 	Topic    string `yaml:"topic"`
 }
 
+// ConcurrencyConfig limits how many jobs of each kind run at the same time,
+// zero means unlimited.
+type ConcurrencyConfig struct {
+	MaxExecutors        int `yaml:"maxExecutors"`
+	MaxDetectJobs       int `yaml:"maxDetectJobs"`
+	MaxVideoSegmentJobs int `yaml:"maxVideoSegmentJobs"`
+	// InferenceFps is the frames per second the inference server sustains,
+	// shared among the detect jobs by priority. Zero estimates it from the
+	// frames dropped when inference falls behind.
+	InferenceFps float64 `yaml:"inferenceFps"`
+}
+
+// JanitorConfig bounds the files kept under the job dir, zero disables a limit.
+type JanitorConfig struct {
+	Interval  time.Duration `yaml:"interval"`
+	MaxAge    time.Duration `yaml:"maxAge"`
+	MaxSizeMB int64         `yaml:"maxSizeMB"`
+}
+
+// DebugConfig configures the local debug HTTP server, it only listens on
+// the loopback interface.
+type DebugConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"`
+}
+
+// MetricsConfig configures the HTTP listener exposing the executor metrics
+// in the Prometheus text format on /metrics.
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"`
+}
+
+// PluginConfig declares an executable that runs the jobs of a custom kind,
+// see exector.ExecPlugin for the protocol it speaks.
+type PluginConfig struct {
+	Kind    string   `yaml:"kind"`
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	Env     []string `yaml:"env"`
+	// MaxJobs limits the concurrent jobs of this kind, zero means unlimited.
+	MaxJobs int `yaml:"maxJobs"`
+}
+
+// SensorConfig declares an external sensor whose latest reading is attached
+// to the messages published by the device, Kind is one of modbus, mqtt and
+// gpio and selects the section configuring it.
+type SensorConfig struct {
+	Name string `yaml:"name"`
+	Kind string `yaml:"kind"`
+	// Interval between two polls of modbus and gpio sensors, 5s if zero
+	Interval time.Duration `yaml:"interval"`
+	// MaxAge after which a reading is no longer attached, zero keeps it
+	MaxAge time.Duration      `yaml:"maxAge"`
+	Modbus ModbusSensorConfig `yaml:"modbus"`
+	MQTT   MQTTSensorConfig   `yaml:"mqtt"`
+	GPIO   GPIOSensorConfig   `yaml:"gpio"`
+}
+
+// ModbusSensorConfig reads one value from registers of a Modbus TCP slave,
+// the reading is raw * Scale + Offset.
+type ModbusSensorConfig struct {
+	Addr     string `yaml:"addr"`
+	UnitId   int    `yaml:"unitId"`
+	Register int    `yaml:"register"`
+	// Input reads input registers instead of holding registers
+	Input bool `yaml:"input"`
+	// Words is 1 for a 16 bits value or 2 for a big endian 32 bits value
+	Words  int     `yaml:"words"`
+	Signed bool    `yaml:"signed"`
+	Scale  float64 `yaml:"scale"`
+	Offset float64 `yaml:"offset"`
+}
+
+// MQTTSensorConfig follows a topic, the reading is the payload parsed as
+// json, or the string payload if it is not json.
+type MQTTSensorConfig struct {
+	Broker   string `yaml:"broker"`
+	Topic    string `yaml:"topic"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Field of a json object payload to use as the reading
+	Field string `yaml:"field"`
+}
+
+// GPIOSensorConfig reads a digital input from its sysfs value file, e.g.
+// /sys/class/gpio/gpio17/value, the reading is a bool.
+type GPIOSensorConfig struct {
+	Path      string `yaml:"path"`
+	ActiveLow bool   `yaml:"activeLow"`
+}
+
+// TalkDownConfig sets the commands used to play talk-downs, {text},
+// {output} and {input} in their arguments are replaced by the message, the
+// wav file to write and the audio file or url to play.
+type TalkDownConfig struct {
+	TTSCommand  []string `yaml:"ttsCommand"`
+	PlayCommand []string `yaml:"playCommand"`
+}
+
+// UpgradeConfig lets the device replace its own binary with the releases
+// rolled out to its device groups and restart.
+type UpgradeConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// WatchConfig lets the device long-poll the jobs and preview tasks, the
+// server answers as soon as they change instead of at the next fetch. The
+// device falls back to the periodic fetch if the server cannot watch.
+type WatchConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Timeout the server waits for a change, at most 60s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// EvidenceConfig makes the device sign every message and the hashes of the
+// media it uploads with its own key, so that the server can prove they were
+// not altered after capture.
+type EvidenceConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
 type S3Config struct {
-	Bucket   string `json:"bucket"`
-	Endpoint string `json:"endpoint"`
-	UseSSL   bool   `json:"useSSL,omitempty"`
-	Region   string `json:"region,omitempty"`
+	Bucket   string `json:"bucket" yaml:"bucket"`
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	UseSSL   bool   `json:"useSSL,omitempty" yaml:"useSSL,omitempty"`
+	Region   string `json:"region,omitempty" yaml:"region,omitempty"`
 }
 
 func (s3 *S3Config) UrlPrefix() string {
@@ -33,11 +159,22 @@ func (s3 *S3Config) UrlPrefix() string {
 }
 
 type Config struct {
-	LuminaServerAddr string       `yaml:"luminaServerAddr"`
-	WorkDir          string       `yaml:"workDir"`
-	Triton           TritonConfig `yaml:"triton"`
-	NSQ              NSQConfig    `yaml:"nsq"`
-	S3               S3Config     `yaml:"s3"`
+	LuminaServerAddr string            `yaml:"luminaServerAddr"`
+	WorkDir          string            `yaml:"workDir"`
+	Triton           TritonConfig      `yaml:"triton"`
+	NSQ              NSQConfig         `yaml:"nsq"`
+	S3               S3Config          `yaml:"s3"`
+	Concurrency      ConcurrencyConfig `yaml:"concurrency"`
+	Janitor          JanitorConfig     `yaml:"janitor"`
+	Debug            DebugConfig       `yaml:"debug"`
+	Metrics          MetricsConfig     `yaml:"metrics"`
+	Tracing          tracing.Config    `yaml:"tracing"`
+	Plugins          []PluginConfig    `yaml:"plugins"`
+	Sensors          []SensorConfig    `yaml:"sensors"`
+	TalkDown         TalkDownConfig    `yaml:"talkDown"`
+	Upgrade          UpgradeConfig     `yaml:"upgrade"`
+	Watch            WatchConfig       `yaml:"watch"`
+	Evidence         EvidenceConfig    `yaml:"evidence"`
 }
 
 func (c Config) ModelDir() string {
@@ -52,6 +189,14 @@ func (c Config) JobDir() string {
 	return path.Join(c.WorkDir, "job")
 }
 
+func (c Config) TestDir() string {
+	return path.Join(c.WorkDir, "test")
+}
+
+func (c Config) StatusFile() string {
+	return path.Join(c.WorkDir, "status.json")
+}
+
 func DefaultConfig() *Config {
 	cfg := &Config{
 		LuminaServerAddr: "http://localhost:8080",
@@ -68,6 +213,28 @@ func DefaultConfig() *Config {
 			UseSSL:   false,
 			Region:   "us-east-1",
 		},
+		Debug: DebugConfig{
+			Enabled: false,
+			Addr:    "127.0.0.1:18490",
+		},
+		Metrics: MetricsConfig{
+			Enabled: false,
+			Addr:    ":18491",
+		},
+		Tracing: tracing.DefaultConfig(),
+		Janitor: JanitorConfig{
+			Interval:  5 * time.Minute,
+			MaxAge:    72 * time.Hour,
+			MaxSizeMB: 2048,
+		},
+		TalkDown: TalkDownConfig{
+			TTSCommand:  []string{"espeak-ng", "-v", "cmn", "-w", "{output}", "{text}"},
+			PlayCommand: []string{"ffplay", "-nodisp", "-autoexit", "-loglevel", "error", "{input}"},
+		},
+		Watch: WatchConfig{
+			Enabled: true,
+			Timeout: 30 * time.Second,
+		},
 	}
 
 	dataDir := os.Getenv("LUMINA_DATA")
@@ -82,7 +249,9 @@ func DefaultConfig() *Config {
 	return cfg
 }
 
-func LoadConfig(configPath string) (*Config, error) {
+// LoadConfig loads the config at configPath and merges the overlay of
+// profileName over it, if any.
+func LoadConfig(configPath, profileName string) (*Config, error) {
 	conf := DefaultConfig()
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -92,6 +261,9 @@ func LoadConfig(configPath string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal config file: %v", err)
 	}
+	if err := profile.Apply(configPath, profileName, conf); err != nil {
+		return nil, err
+	}
 
 	return conf, nil
 }
@@ -0,0 +1,126 @@
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"time"
+
+	"lumina/internal/device/exector"
+	"lumina/internal/model"
+)
+
+type DebugExecutor struct {
+	ExecutorState
+	Health  exector.Health  `json:"health"`
+	Metrics exector.Metrics `json:"metrics"`
+}
+
+// DebugState is served by the debug and metrics servers, it is refreshed by
+// the device loop so that handlers never touch the executors map.
+type DebugState struct {
+	Runtime   RuntimeStatus   `json:"runtime"`
+	Executors []DebugExecutor `json:"executors"`
+}
+
+func (a *Device) publishDebugState() {
+	if !a.conf.Debug.Enabled && !a.conf.Metrics.Enabled {
+		return
+	}
+	state := &DebugState{
+		Runtime:   a.status,
+		Executors: make([]DebugExecutor, 0, len(a.executors)+len(a.pending)),
+	}
+	for uuid, e := range a.executors {
+		restarts, lastError := a.supervisor.restartCount(uuid)
+		metrics := e.Metrics()
+		if metrics.LastError == "" {
+			metrics.LastError = lastError
+		}
+		health := e.Health()
+		state.Executors = append(state.Executors, DebugExecutor{
+			ExecutorState: ExecutorState{
+				JobUuid:  uuid,
+				Kind:     e.Job().Kind,
+				Status:   a.executorStatus(uuid, e).String(),
+				Restarts: restarts,
+				Health:   health.Reason,
+			},
+			Health:  health,
+			Metrics: metrics,
+		})
+	}
+	for uuid, kind := range a.pending {
+		restarts, lastError := a.supervisor.restartCount(uuid)
+		state.Executors = append(state.Executors, DebugExecutor{
+			ExecutorState: ExecutorState{
+				JobUuid:  uuid,
+				Kind:     kind,
+				Status:   model.ExectorStatusPending.String(),
+				Restarts: restarts,
+			},
+			Health:  exector.Health{Reason: "waiting for a free executor slot"},
+			Metrics: exector.Metrics{LastError: lastError},
+		})
+	}
+	sort.Slice(state.Executors, func(i, j int) bool {
+		return state.Executors[i].JobUuid < state.Executors[j].JobUuid
+	})
+	a.debugState.Store(state)
+}
+
+// startDebugServer serves executor states and pprof on a loopback address.
+func (a *Device) startDebugServer() (*http.Server, error) {
+	host, _, err := net.SplitHostPort(a.conf.Debug.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid debug addr %s: %w", a.conf.Debug.Addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("debug addr %s is not a loopback address", a.conf.Debug.Addr)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/status", func(w http.ResponseWriter, r *http.Request) {
+		state := a.debugState.Load()
+		if state == nil {
+			state = &DebugState{Executors: []DebugExecutor{}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(state)
+	})
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	listener, err := net.Listen("tcp", a.conf.Debug.Addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			a.logger.WithError(err).Error("debug server stopped")
+		}
+	}()
+	a.logger.Infof("debug server listening on http://%s/debug/status", listener.Addr())
+	return srv, nil
+}
+
+func (a *Device) stopDebugServer(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		a.logger.WithError(err).Warn("shutdown debug server")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/minio/minio-go/v7"
@@ -15,6 +17,8 @@ import (
 	"lumina/internal/device/config"
 	"lumina/internal/device/exector"
 	"lumina/internal/device/metadata"
+	"lumina/internal/device/sensor"
+	"lumina/internal/model"
 	"lumina/pkg/log"
 )
 
@@ -30,6 +34,44 @@ type Device struct {
 	nsqProducer *nsq.Producer
 	minioCli    *minio.Client
 	previewJobs map[string]*PreviewJob
+	sensors     *sensor.Hub
+	signer      *exector.Signer
+	status      RuntimeStatus
+	supervisor  *supervisor
+	pending     map[string]model.JobKind
+	diskUsage   *model.DiskUsage
+	inventory   atomic.Pointer[model.DeviceInventory]
+	// uuids of the paused executors, by their job or its arming schedule
+	paused map[string]bool
+	// uuids of the executors disarmed by the arming schedule of their job
+	disarmed map[string]bool
+	// per-device executor limit set on the server, zero if unset
+	serverMaxExecutors int
+	// inference budget estimated by throttleExecutors, zero if unlimited
+	inferenceBudget float64
+	// frames dropped by the executors as of the last throttling
+	framesDropped map[string]int64
+	// frame rate limits of the throttled executors
+	frameRateLimits map[string]float64
+	debugState      atomic.Pointer[DebugState]
+	// uuids of deleted jobs whose work dir is being flushed
+	teardowns sync.Map
+	// time of the last artifacts sent along the status, by job and kind
+	sentArtifacts map[string]time.Time
+
+	testDetectMu     sync.Mutex
+	testDetectTasks  map[string]struct{}
+	onvifMu          sync.Mutex
+	onvifTasks       map[string]struct{}
+	snapshotMu       sync.Mutex
+	snapshotTasks    map[string]struct{}
+	cameraProbeMu    sync.Mutex
+	cameraProbeTasks map[string]struct{}
+	talkDownMu       sync.Mutex
+	talkDownTasks    map[string]struct{}
+	talkDownPlayMu   sync.Mutex
+	upgrading        atomic.Bool
+	restartCh        chan struct{}
 }
 
 func NewDevice(conf *config.Config) (*Device, error) {
@@ -76,6 +118,31 @@ func NewDevice(conf *config.Config) (*Device, error) {
 		Timeout: 15 * time.Second,
 	}
 
+	for _, p := range conf.Plugins {
+		if err := exector.RegisterExecPlugin(p); err != nil {
+			logger.WithError(err).Errorf("register plugin %s failed", p.Kind)
+		}
+	}
+
+	var sensors *sensor.Hub
+	if len(conf.Sensors) > 0 {
+		sensors, err = sensor.NewHub(ctx, conf.Sensors)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("create sensors failed: %w", err)
+		}
+	}
+
+	var signer *exector.Signer
+	if conf.Evidence.Enabled {
+		key, err := db.GetOrCreateSigningKey()
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("get signing key failed: %w", err)
+		}
+		signer = exector.NewSigner(*info.Uuid, key)
+	}
+
 	producer, err := nsq.NewProducer(conf.NSQ.NSQDAddr, nsq.NewConfig())
 	if err != nil {
 		cancel()
@@ -93,39 +160,149 @@ func NewDevice(conf *config.Config) (*Device, error) {
 		deviceInfo:  info,
 		nsqProducer: producer,
 		minioCli:    minioCli,
+		sensors:     sensors,
+		signer:      signer,
 		previewJobs: make(map[string]*PreviewJob),
+		supervisor:  newSupervisor(),
+		pending:     make(map[string]model.JobKind),
+		paused:      make(map[string]bool),
+		disarmed:    make(map[string]bool),
+		status: RuntimeStatus{
+			StartTime: time.Now(),
+		},
+
+		testDetectTasks:  make(map[string]struct{}),
+		onvifTasks:       make(map[string]struct{}),
+		snapshotTasks:    make(map[string]struct{}),
+		cameraProbeTasks: make(map[string]struct{}),
+		talkDownTasks:    make(map[string]struct{}),
+		sentArtifacts:    make(map[string]time.Time),
+		restartCh:        make(chan struct{}),
 	}, nil
 }
 
 func (a *Device) Start() {
 	fetchTicker := time.NewTicker(5 * time.Second)
 	syncTicker := time.NewTicker(1 * time.Second)
+	janitorInterval := a.conf.Janitor.Interval
+	if janitorInterval <= 0 {
+		janitorInterval = 5 * time.Minute
+	}
+	janitorTicker := time.NewTicker(janitorInterval)
 	defer func() {
 		fetchTicker.Stop()
 		syncTicker.Stop()
+		janitorTicker.Stop()
 		a.logger.Info("device stopped")
 	}()
 
+	a.runJanitor()
+	go a.sensors.Run(a.ctx)
+	go a.runInventory()
+
+	// with the watches running the jobs and preview tasks are fetched as soon
+	// as they change, the ticker only fetches them while a watch is down or
+	// the fetch after a change failed
+	jobsWatch := newServerWatch(fetchJobsPath)
+	previewWatch := newServerWatch(fetchPreviewTasksPath)
+	if a.conf.Watch.Enabled {
+		go a.runServerWatch(jobsWatch)
+		go a.runServerWatch(previewWatch)
+	}
+	jobsDirty, previewDirty := false, false
+	syncJobs := func() {
+		err := a.syncJobsFromServer()
+		if err != nil {
+			a.logger.WithError(err).Errorf("sync jobs from server failed")
+		}
+		a.recordSync(err)
+		jobsDirty = err != nil
+	}
+	syncPreviewTasks := func() {
+		err := a.syncPreviewTasksFromServer()
+		if err != nil {
+			a.logger.WithError(err).Errorf("sync preview tasks from server failed")
+		}
+		previewDirty = err != nil
+	}
+
+	if a.conf.Debug.Enabled {
+		debugSrv, err := a.startDebugServer()
+		if err != nil {
+			a.logger.WithError(err).Error("start debug server failed")
+		} else {
+			defer a.stopDebugServer(debugSrv)
+		}
+	}
+	if a.conf.Metrics.Enabled {
+		metricsSrv, err := a.startMetricsServer()
+		if err != nil {
+			a.logger.WithError(err).Error("start metrics server failed")
+		} else {
+			defer a.stopMetricsServer(metricsSrv)
+		}
+	}
+
 	for {
 		select {
 		case <-a.ctx.Done():
 			return
 		case <-fetchTicker.C:
 			a.logger.Debug("fetch tick")
-			if err := a.syncJobsFromServer(); err != nil {
-				a.logger.WithError(err).Errorf("sync jobs from server failed")
+			a.throttleExecutors()
+			if jobsDirty || !jobsWatch.watching.Load() {
+				syncJobs()
+			} else {
+				a.recordSync(nil)
 			}
-			if err := a.reportDeviceStatus(); err != nil {
+			err := a.reportDeviceStatus()
+			if err != nil {
 				a.logger.WithError(err).Errorf("report device status failed")
 			}
-			if err := a.syncPreviewTasksFromServer(); err != nil {
-				a.logger.WithError(err).Errorf("sync preview tasks from server failed")
+			a.recordReport(err)
+			if previewDirty || !previewWatch.watching.Load() {
+				syncPreviewTasks()
+			} else {
+				a.expirePreviewJobs()
+			}
+			if err := a.syncTestDetectTasksFromServer(); err != nil {
+				a.logger.WithError(err).Errorf("sync test detect tasks from server failed")
+			}
+			if err := a.syncOnvifDiscoverTasksFromServer(); err != nil {
+				a.logger.WithError(err).Errorf("sync onvif discover tasks from server failed")
+			}
+			if err := a.syncSnapshotTasksFromServer(); err != nil {
+				a.logger.WithError(err).Errorf("sync snapshot tasks from server failed")
+			}
+			if err := a.syncCameraProbeTasksFromServer(); err != nil {
+				a.logger.WithError(err).Errorf("sync camera probe tasks from server failed")
+			}
+			if err := a.syncTalkDownTasksFromServer(); err != nil {
+				a.logger.WithError(err).Errorf("sync talk-down tasks from server failed")
+			}
+			if a.conf.Upgrade.Enabled {
+				if err := a.syncUpgradeTaskFromServer(); err != nil {
+					a.logger.WithError(err).Errorf("sync upgrade task from server failed")
+				}
+			}
+			if err := a.writeRuntimeStatus(); err != nil {
+				a.logger.WithError(err).Errorf("write runtime status failed")
 			}
+		case <-jobsWatch.changed:
+			a.logger.Debug("jobs changed")
+			syncJobs()
+		case <-previewWatch.changed:
+			a.logger.Debug("preview tasks changed")
+			syncPreviewTasks()
 		case <-syncTicker.C:
 			a.logger.Debug("sync tick")
 			if err := a.syncJobsFromMedadata(); err != nil {
 				a.logger.WithError(err).Errorf("sync jobs from metadata failed")
 			}
+			a.publishDebugState()
+		case <-janitorTicker.C:
+			a.logger.Debug("janitor tick")
+			a.runJanitor()
 		}
 	}
 }
@@ -20,24 +20,31 @@ import (
 	"github.com/minio/minio-go/v7"
 	"github.com/nsqio/go-nsq"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	"gocv.io/x/gocv"
 
 	"lumina/internal/dao"
 	"lumina/internal/device/config"
 	"lumina/internal/device/metadata"
+	"lumina/internal/device/occupancy"
+	"lumina/internal/device/sensor"
 	"lumina/internal/model"
 	"lumina/internal/utils"
 	"lumina/pkg/log"
+	"lumina/pkg/tracing"
 )
 
 type Detector struct {
+	pauseState
+	executorStatus
+	metricsRecorder
+	frameRateLimit
 	tritonCli       base.Client
 	ctx             context.Context
 	cancel          context.CancelFunc
 	wg              *sync.WaitGroup
 	job             *dao.JobSpec
 	logger          *logrus.Entry
-	status          model.ExectorStatus
 	workDir         string
 	conf            *config.Config
 	nsqProducer     *nsq.Producer
@@ -45,13 +52,23 @@ type Detector struct {
 	deviceInfo      *metadata.DeviceInfo
 	triggerCount    int
 	lastTriggerTime time.Time
+	hooks           hookChain
+	signer          *Signer
+	// occupancy counts the tracked objects in the zones of the job, nil if
+	// the job has no occupancy analytics
+	occupancy     *occupancy.Counter
+	lastOccupancy time.Time
 }
 
 func NewDetector(conf *config.Config, deviceInfo *metadata.DeviceInfo, parentCtx context.Context,
-	minioCli *minio.Client, nsqProducer *nsq.Producer, job *dao.JobSpec) (*Detector, error) {
+	minioCli *minio.Client, nsqProducer *nsq.Producer, sensors *sensor.Hub, signer *Signer, job *dao.JobSpec) (*Detector, error) {
 	if job.Detect == nil {
 		return nil, fmt.Errorf("job %s detect is nil", job.Uuid)
 	}
+	hooks, err := newHookChain(job, sensors)
+	if err != nil {
+		return nil, err
+	}
 
 	tritonCli, err := tritonGrpc.NewClient(
 		conf.Triton.ServerAddr,
@@ -71,6 +88,11 @@ func NewDetector(conf *config.Config, deviceInfo *metadata.DeviceInfo, parentCtx
 	if err := os.MkdirAll(workDir, 0755); err != nil {
 		return nil, err
 	}
+	var counter *occupancy.Counter
+	if job.Detect.Occupancy != nil {
+		counter = occupancy.NewCounter(job.Detect.Occupancy)
+	}
+
 	ctx, cancel := context.WithCancel(parentCtx)
 	return &Detector{
 		tritonCli:       tritonCli,
@@ -78,7 +100,6 @@ func NewDetector(conf *config.Config, deviceInfo *metadata.DeviceInfo, parentCtx
 		cancel:          cancel,
 		wg:              &sync.WaitGroup{},
 		job:             job,
-		status:          model.ExectorStatusStopped,
 		logger:          log.GetLogger(ctx).WithField("job", job.Uuid),
 		workDir:         workDir,
 		conf:            conf,
@@ -86,6 +107,10 @@ func NewDetector(conf *config.Config, deviceInfo *metadata.DeviceInfo, parentCtx
 		minioCli:        minioCli,
 		deviceInfo:      deviceInfo,
 		lastTriggerTime: time.Now(),
+		hooks:           hooks,
+		signer:          signer,
+		occupancy:       counter,
+		lastOccupancy:   time.Now(),
 	}, nil
 }
 
@@ -93,8 +118,45 @@ func (e *Detector) Job() *dao.JobSpec {
 	return e.job
 }
 
+// DemandFrameRate is the frame rate of the detect interval capped by the max
+// fps of the job.
+func (e *Detector) DemandFrameRate() float64 {
+	fps := float64(time.Second) / float64(e.interval())
+	if e.job.MaxFps > 0 {
+		fps = min(fps, e.job.MaxFps)
+	}
+	return fps
+}
+
+func (e *Detector) interval() time.Duration {
+	if e.job.Detect.Interval <= 0 {
+		return 3 * time.Second
+	}
+	return time.Duration(e.job.Detect.Interval) * time.Millisecond
+}
+
 func (e *Detector) Status() model.ExectorStatus {
-	return e.status
+	return e.pausedStatus(e.loadStatus())
+}
+
+// detectStallTimeout is how long a running detector may go without reading
+// a frame before it is reported unhealthy.
+const detectStallTimeout = 30 * time.Second
+
+func (e *Detector) Health() Health {
+	status := e.Status()
+	switch status {
+	case model.ExectorStatusPaused:
+		return healthy()
+	case model.ExectorStatusRunning:
+	default:
+		return unhealthy("executor %s", status)
+	}
+	m := e.Metrics()
+	if idle := sinceActivity(m, m.LastFrameTime, time.Now()); idle > detectStallTimeout {
+		return unhealthy("no frame read for %s", idle.Truncate(time.Second))
+	}
+	return healthy()
 }
 
 func (e *Detector) Start() error {
@@ -124,14 +186,17 @@ func (e *Detector) Start() error {
 	e.wg.Add(1)
 	go func() {
 		defer e.wg.Done()
+		defer guard(e.logger, &e.executorStatus)
 		e.uploadRoutine()
 	}()
 
 	e.wg.Add(1)
 	go func() {
 		defer e.wg.Done()
+		defer guard(e.logger, &e.executorStatus)
 		e.logger.Info("detect job started")
-		e.status = model.ExectorStatusRunning
+		e.started(time.Now())
+		e.setStatus(model.ExectorStatusRunning)
 		e.runJob(video)
 		e.logger.Info("detect job stopped")
 	}()
@@ -139,10 +204,17 @@ func (e *Detector) Start() error {
 	return nil
 }
 
+// Cleanup uploads the results left in the work dir and removes it, it must
+// only be called after Stop.
+func (e *Detector) Cleanup(ctx context.Context) error {
+	uploadErr := e.listAndUpload(ctx)
+	return errors.Join(uploadErr, os.RemoveAll(e.workDir))
+}
+
 func (e *Detector) Stop() {
 	e.cancel()
 	e.wg.Wait()
-	e.status = model.ExectorStatusStopped
+	e.setStatus(model.ExectorStatusStopped)
 }
 
 func (e *Detector) inferRoutine(frameCh <-chan gocv.Mat) {
@@ -158,10 +230,17 @@ func (e *Detector) inferRoutine(frameCh <-chan gocv.Mat) {
 		processedFrame, boxes, err := performInference(e.tritonCli, &frame, e.job.Detect.ModelName, labelMap)
 		if err != nil {
 			e.logger.WithError(err).Errorf("inference error")
+			e.recordErrorFrame(err, frame)
 			processedFrame = frame.Clone()
 		}
+		e.frameProcessed(time.Now())
 		inferenceTime := time.Since(start)
 		totalInferenceTime += inferenceTime
+		e.inferenceDone(inferenceTime)
+
+		if err == nil && e.occupancy != nil {
+			e.updateOccupancy(boxes, frame.Cols(), frame.Rows())
+		}
 
 		needSave := false
 		if len(boxes) > 0 {
@@ -178,6 +257,7 @@ func (e *Detector) inferRoutine(frameCh <-chan gocv.Mat) {
 		if needSave {
 			if err := e.saveResult(&frame, boxes); err != nil {
 				e.logger.WithError(err).Errorf("save result error")
+				e.recordErrorFrame(err, frame)
 			}
 		}
 
@@ -194,6 +274,32 @@ func (e *Detector) inferRoutine(frameCh <-chan gocv.Mat) {
 	}
 }
 
+// updateOccupancy feeds the boxes of a frame to the occupancy counter and
+// publishes the occupancy of the zones once per report interval.
+func (e *Detector) updateOccupancy(boxes []*dao.DetectionBox, width, height int) {
+	e.occupancy.Update(boxes, width, height)
+
+	interval := time.Duration(e.job.Detect.Occupancy.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if time.Since(e.lastOccupancy) < interval {
+		return
+	}
+	e.lastOccupancy = time.Now()
+
+	msg := &dao.DeviceMessage{
+		JobUuid:   e.job.Uuid,
+		Timestamp: e.lastOccupancy.UnixNano(),
+		Occupancy: e.occupancy.Report(),
+	}
+	msgData, _ := json.Marshal(msg)
+	if err := e.nsqProducer.Publish(e.conf.NSQ.Topic, msgData); err != nil {
+		e.logger.WithError(err).Errorf("publish occupancy to NSQ failed")
+		e.recordError(err)
+	}
+}
+
 func (e *Detector) runJob(input *gocv.VideoCapture) {
 	fps := input.Get(gocv.VideoCaptureFPS)
 	width := int(input.Get(gocv.VideoCaptureFrameWidth))
@@ -210,16 +316,12 @@ func (e *Detector) runJob(input *gocv.VideoCapture) {
 	e.wg.Add(1)
 	go func() {
 		defer e.wg.Done()
+		defer guard(e.logger, &e.executorStatus)
 		e.inferRoutine(frameChan)
 	}()
 
 	lastFrameTime := time.Now()
-	var interval time.Duration
-	if e.job.Detect.Interval <= 0 {
-		interval = 3 * time.Second
-	} else {
-		interval = time.Duration(e.job.Detect.Interval) * time.Millisecond
-	}
+	interval := e.interval()
 
 	for {
 		select {
@@ -231,7 +333,7 @@ func (e *Detector) runJob(input *gocv.VideoCapture) {
 		frame := gocv.NewMat()
 		if ok := input.Read(&frame); !ok {
 			frame.Close()
-			e.status = model.ExectorStatusFinished
+			e.setStatus(model.ExectorStatusFinished)
 			break
 		}
 
@@ -239,8 +341,15 @@ func (e *Detector) runJob(input *gocv.VideoCapture) {
 			frame.Close()
 			continue
 		}
+		e.frameRead()
 
-		if time.Since(lastFrameTime) < interval {
+		// keep reading while paused so the stream does not stall or time out
+		if e.isPaused() {
+			frame.Close()
+			continue
+		}
+
+		if time.Since(lastFrameTime) < e.limitInterval(interval) {
 			frame.Close()
 			continue
 		}
@@ -250,6 +359,7 @@ func (e *Detector) runJob(input *gocv.VideoCapture) {
 		case frameChan <- frame:
 		default:
 			e.logger.Warnf("frame dropped, frame pool is full")
+			e.frameDropped()
 			frame.Close()
 		}
 	}
@@ -261,17 +371,25 @@ func (e *Detector) saveResult(frame *gocv.Mat, boxes []*dao.DetectionBox) error
 	jsonPath := path.Join(e.workDir, fmt.Sprintf("%d.json", ts))
 
 	result := &dao.DetectionResult{
-		JobId:     e.job.Uuid,
-		Timestamp: ts,
-		ImagePath: imagePath,
-		JsonPath:  jsonPath,
-		Boxes:     boxes,
+		JobId:       e.job.Uuid,
+		Timestamp:   ts,
+		ImagePath:   imagePath,
+		JsonPath:    jsonPath,
+		Boxes:       boxes,
+		FrameWidth:  frame.Cols(),
+		FrameHeight: frame.Rows(),
 	}
 	jsonData, err := json.Marshal(result)
 	if err != nil {
 		return fmt.Errorf("marshal detection result error: %w", err)
 	}
 
+	if labels := e.job.Detect.GetPrivacyLabelSet(); labels != nil {
+		masked := maskPrivacyRegions(frame, boxes, labels, e.job.Detect.PrivacyMode)
+		defer masked.Close()
+		frame = &masked
+	}
+
 	if !gocv.IMWrite(imagePath, *frame) {
 		return fmt.Errorf("write image file error")
 	}
@@ -381,8 +499,11 @@ func (e *Detector) uploadRoutine() {
 	defer ticker.Stop()
 
 	for {
-		if err := e.listAndUpload(); err != nil {
-			e.logger.WithError(err).Errorf("list and upload failed")
+		// results saved before a pause are kept until the job is resumed
+		if !e.isPaused() {
+			if err := e.listAndUpload(e.ctx); err != nil {
+				e.logger.WithError(err).Errorf("list and upload failed")
+			}
 		}
 
 		select {
@@ -393,7 +514,9 @@ func (e *Detector) uploadRoutine() {
 	}
 }
 
-func (e *Detector) listAndUpload() error {
+func (e *Detector) listAndUpload(parentCtx context.Context) error {
+	queued := 0
+	defer func() { e.uploadQueued(queued) }()
 	return filepath.WalkDir(e.workDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -402,6 +525,7 @@ func (e *Detector) listAndUpload() error {
 		if d.IsDir() || !strings.HasSuffix(strings.ToLower(d.Name()), ".json") {
 			return nil
 		}
+		queued++
 
 		jsonData, err := os.ReadFile(path)
 		if err != nil {
@@ -428,27 +552,53 @@ func (e *Detector) listAndUpload() error {
 				ts = time.Now()
 			}
 		}
+		msg := &dao.DeviceMessage{
+			JobUuid:     result.JobId,
+			Timestamp:   ts.UnixNano(),
+			DetectBoxes: result.Boxes,
+			FrameWidth:  result.FrameWidth,
+			FrameHeight: result.FrameHeight,
+		}
+		if err := e.hooks.run(parentCtx, msg); errors.Is(err, ErrDropMessage) {
+			os.Remove(path)
+			os.Remove(imgPath)
+			return nil
+		} else if err != nil {
+			e.logger.WithError(err).Warnf("hooks failed for %s", path)
+			e.recordError(err)
+		}
+
 		minioPath := fmt.Sprintf("/%s/%04d/%02d/%02d/%s/%s.jpg",
 			*e.deviceInfo.Uuid, ts.Year(), ts.Month(), ts.Day(), result.JobId, fileName)
 
-		ctx, cancel := context.WithTimeout(e.ctx, 30*time.Second)
+		ctx, cancel := context.WithTimeout(parentCtx, 30*time.Second)
 		defer cancel()
+		ctx, span := tracing.Start(ctx, "device.upload",
+			attribute.String("lumina.job_uuid", result.JobId), attribute.String("lumina.path", minioPath))
 		if err := utils.UploadFileToMinio(ctx, e.minioCli, e.conf.S3.Bucket, imgPath, minioPath); err != nil {
 			e.logger.WithError(err).Errorf("upload image %s to minio failed", imgPath)
+			e.recordUpload(minioPath, err)
+			tracing.End(span, err)
 			return nil
 		}
 
-		msg := &dao.DeviceMessage{
-			JobUuid:     result.JobId,
-			Timestamp:   ts.UnixNano(),
-			ImagePath:   minioPath,
-			DetectBoxes: result.Boxes,
+		msg.ImagePath = minioPath
+		if err := e.signer.Sign(msg, imgPath); err != nil {
+			e.logger.WithError(err).Errorf("sign %s failed", path)
+			e.recordUpload(minioPath, err)
+			tracing.End(span, err)
+			return nil
 		}
+		msg.TraceContext = tracing.Inject(ctx)
 		msgData, _ := json.Marshal(msg)
 		if err := e.nsqProducer.Publish(e.conf.NSQ.Topic, msgData); err != nil {
 			e.logger.WithError(err).Errorf("publish to NSQ failed for %s", path)
+			e.recordUpload(minioPath, err)
+			tracing.End(span, err)
 			return nil
 		}
+		e.recordUpload(minioPath, nil)
+		span.End()
 
 		os.Remove(path)
 		os.Remove(imgPath)
@@ -0,0 +1,64 @@
+package exector
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+
+	"lumina/internal/dao"
+)
+
+// Signer signs the messages of the executors with the device key for chain
+// of custody, a nil Signer leaves them unsigned.
+type Signer struct {
+	deviceUuid string
+	key        ed25519.PrivateKey
+}
+
+func NewSigner(deviceUuid string, key ed25519.PrivateKey) *Signer {
+	return &Signer{deviceUuid: deviceUuid, key: key}
+}
+
+// PublicKey returns the key the server verifies the signatures with, nil for
+// a nil Signer.
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	if s == nil {
+		return nil
+	}
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Sign hashes the media file uploaded for the message and signs the message
+// along with the hash. It is called once the media path is set, before the
+// message is published.
+func (s *Signer) Sign(msg *dao.DeviceMessage, mediaFile string) error {
+	if s == nil {
+		return nil
+	}
+	sum, err := fileSha256(mediaFile)
+	if err != nil {
+		return err
+	}
+	var imageSha256, videoSha256 string
+	if msg.VideoPath != "" {
+		videoSha256 = sum
+	} else {
+		imageSha256 = sum
+	}
+	return dao.SignMessage(msg, s.deviceUuid, s.key, imageSha256, videoSha256)
+}
+
+func fileSha256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
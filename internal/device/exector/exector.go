@@ -1,6 +1,14 @@
 package exector
 
 import (
+	"context"
+	"math"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
 	"lumina/internal/dao"
 	"lumina/internal/model"
 )
@@ -10,4 +18,93 @@ type Executor interface {
 	Stop()
 	Job() *dao.JobSpec
 	Status() model.ExectorStatus
+	// Pause suspends frame ingestion and result publishing while keeping the
+	// input stream and inference connections open, Resume undoes it.
+	Pause()
+	Resume()
+	// Health probes whether the executor is making progress.
+	Health() Health
+	// Metrics returns a snapshot of the executor's runtime counters.
+	Metrics() Metrics
+	// Cleanup flushes the results left in the work dir and removes it, it is
+	// called after Stop when the job is deleted.
+	Cleanup(ctx context.Context) error
+}
+
+// Throttler is implemented by the executors that infer sampled frames, the
+// device limits their frame rate when inference cannot keep up.
+type Throttler interface {
+	// DemandFrameRate is the frames per second the executor samples when
+	// unthrottled.
+	DemandFrameRate() float64
+	// SetFrameRateLimit limits the frames per second the executor samples,
+	// zero removes the limit.
+	SetFrameRateLimit(fps float64)
+}
+
+// frameRateLimit is embedded by executors to implement SetFrameRateLimit.
+type frameRateLimit struct {
+	bits atomic.Uint64
+}
+
+func (l *frameRateLimit) SetFrameRateLimit(fps float64) {
+	l.bits.Store(math.Float64bits(fps))
+}
+
+// limitInterval returns the interval between two sampled frames, the larger
+// of interval and the one the frame rate limit allows.
+func (l *frameRateLimit) limitInterval(interval time.Duration) time.Duration {
+	fps := math.Float64frombits(l.bits.Load())
+	if fps <= 0 {
+		return interval
+	}
+	return max(interval, time.Duration(float64(time.Second)/fps))
+}
+
+// pauseState is embedded by executors to implement Pause and Resume.
+type pauseState struct {
+	paused atomic.Bool
+}
+
+func (p *pauseState) Pause() {
+	p.paused.Store(true)
+}
+
+func (p *pauseState) Resume() {
+	p.paused.Store(false)
+}
+
+func (p *pauseState) isPaused() bool {
+	return p.paused.Load()
+}
+
+// pausedStatus reports a running executor as paused while it is paused.
+func (p *pauseState) pausedStatus(status model.ExectorStatus) model.ExectorStatus {
+	if status == model.ExectorStatusRunning && p.isPaused() {
+		return model.ExectorStatusPaused
+	}
+	return status
+}
+
+// executorStatus is embedded by executors to hold their status, set by their
+// goroutines while the device reads it. The zero value is stopped.
+type executorStatus struct {
+	v atomic.Int32
+}
+
+func (s *executorStatus) loadStatus() model.ExectorStatus {
+	return model.ExectorStatus(s.v.Load())
+}
+
+func (s *executorStatus) setStatus(status model.ExectorStatus) {
+	s.v.Store(int32(status))
+}
+
+// guard recovers a panic of an executor goroutine and marks the executor as
+// failed, so that the device restarts the job instead of crashing.
+func guard(logger *logrus.Entry, status *executorStatus) {
+	if r := recover(); r != nil {
+		logger.Errorf("executor panic: %v\n%s", r, debug.Stack())
+		status.setStatus(model.ExectorStatusFailed)
+	}
 }
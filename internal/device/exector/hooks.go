@@ -0,0 +1,117 @@
+package exector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"lumina/internal/dao"
+	"lumina/internal/device/sensor"
+)
+
+// ErrDropMessage is returned by a hook to discard a message, the result is
+// removed without being uploaded or published.
+var ErrDropMessage = errors.New("drop message")
+
+// Hook post-processes the messages of a job after detection and before they
+// are published, e.g. to add metadata or transform coordinates. A hook is
+// only called from the upload goroutine of its executor.
+type Hook interface {
+	Process(ctx context.Context, msg *dao.DeviceMessage) error
+}
+
+// HookFactory creates a hook from the params configured on the job.
+type HookFactory func(params map[string]any) (Hook, error)
+
+var (
+	hookRegistryMu sync.RWMutex
+	hookRegistry   = make(map[string]HookFactory)
+)
+
+// RegisterHook makes a hook available to jobs by name, it is meant to be
+// called from an init function and panics if the name is already taken.
+func RegisterHook(name string, factory HookFactory) {
+	hookRegistryMu.Lock()
+	defer hookRegistryMu.Unlock()
+	if _, ok := hookRegistry[name]; ok {
+		panic(fmt.Sprintf("hook %s already registered", name))
+	}
+	hookRegistry[name] = factory
+}
+
+// hookChain runs the hooks of a job in order.
+type hookChain []Hook
+
+// newHookChain creates the hooks configured on a job, an unknown hook or
+// invalid params fail the executor creation so that the error shows up on
+// the job. The sensor readings and the world positions of a calibrated
+// camera are attached first so that the job hooks can use them.
+func newHookChain(job *dao.JobSpec, sensors *sensor.Hub) (hookChain, error) {
+	var chain hookChain
+	if sensors != nil {
+		chain = append(chain, &sensorsHook{hub: sensors})
+	}
+	if m, ok := job.Camera.Calibration.Homography(); ok {
+		chain = append(chain, &homographyHook{Matrix: m[:], Key: "world"})
+	}
+	for _, spec := range job.Hooks {
+		hookRegistryMu.RLock()
+		factory, ok := hookRegistry[spec.Name]
+		hookRegistryMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown hook %s", spec.Name)
+		}
+		hook, err := factory(spec.Params)
+		if err != nil {
+			return nil, fmt.Errorf("create hook %s: %w", spec.Name, err)
+		}
+		chain = append(chain, hook)
+	}
+	return chain, nil
+}
+
+// run applies the hooks to msg. It stops at ErrDropMessage, which it
+// returns as is. Other errors are joined and returned after all hooks ran,
+// the message is still meant to be published so that a broken sensor does
+// not lose detections.
+func (c hookChain) run(ctx context.Context, msg *dao.DeviceMessage) error {
+	var errs []error
+	for _, hook := range c {
+		err := hook.Process(ctx, msg)
+		if errors.Is(err, ErrDropMessage) {
+			return err
+		} else if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sensorsHook attaches the latest readings of the device sensors.
+type sensorsHook struct {
+	hub *sensor.Hub
+}
+
+func (h *sensorsHook) Process(ctx context.Context, msg *dao.DeviceMessage) error {
+	msg.Sensors = h.hub.Readings()
+	return nil
+}
+
+// decodeParams decodes hook params into the struct pointed to by v.
+func decodeParams(params map[string]any, v any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// setMetadata sets a metadata field of msg.
+func setMetadata(msg *dao.DeviceMessage, key string, value any) {
+	if msg.Metadata == nil {
+		msg.Metadata = make(map[string]any)
+	}
+	msg.Metadata[key] = value
+}
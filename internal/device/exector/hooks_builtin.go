@@ -0,0 +1,208 @@
+package exector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"lumina/internal/dao"
+)
+
+func init() {
+	RegisterHook("static_metadata", newStaticMetadataHook)
+	RegisterHook("file_metadata", newFileMetadataHook)
+	RegisterHook("homography", newHomographyHook)
+	RegisterHook("require_labels", newRequireLabelsHook)
+}
+
+// staticMetadataHook adds fixed fields to every message, e.g. the site or
+// the mounting position of the camera.
+type staticMetadataHook struct {
+	Fields map[string]any `json:"fields"`
+}
+
+func newStaticMetadataHook(params map[string]any) (Hook, error) {
+	h := &staticMetadataHook{}
+	if err := decodeParams(params, h); err != nil {
+		return nil, err
+	}
+	if len(h.Fields) == 0 {
+		return nil, errors.New("fields is empty")
+	}
+	return h, nil
+}
+
+func (h *staticMetadataHook) Process(ctx context.Context, msg *dao.DeviceMessage) error {
+	for k, v := range h.Fields {
+		setMetadata(msg, k, v)
+	}
+	return nil
+}
+
+// fileMetadataHook adds the json content of a file under a metadata key. It
+// lets a separate process, e.g. a GPIO sensor reader, enrich the messages by
+// keeping the file up to date.
+type fileMetadataHook struct {
+	Path string `json:"path"`
+	Key  string `json:"key"`
+	// MaxAge in seconds, an older file is ignored, zero disables the check
+	MaxAge int `json:"maxAge"`
+}
+
+func newFileMetadataHook(params map[string]any) (Hook, error) {
+	h := &fileMetadataHook{Key: "sensor"}
+	if err := decodeParams(params, h); err != nil {
+		return nil, err
+	}
+	if h.Path == "" {
+		return nil, errors.New("path is empty")
+	}
+	return h, nil
+}
+
+func (h *fileMetadataHook) Process(ctx context.Context, msg *dao.DeviceMessage) error {
+	info, err := os.Stat(h.Path)
+	if err != nil {
+		return err
+	}
+	if h.MaxAge > 0 {
+		if age := time.Since(info.ModTime()); age > time.Duration(h.MaxAge)*time.Second {
+			return fmt.Errorf("%s is stale, updated %s ago", h.Path, age.Truncate(time.Second))
+		}
+	}
+	data, err := os.ReadFile(h.Path)
+	if err != nil {
+		return err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("parse %s: %w", h.Path, err)
+	}
+	setMetadata(msg, h.Key, value)
+	return nil
+}
+
+// homographyHook maps the ground point of every detection box, the middle
+// of its bottom edge, to world coordinates with a 3x3 homography matrix
+// given in row-major order, e.g. computed from four reference points on
+// the floor. The speed of a point is estimated from the nearest point of the
+// same label in the previous message, in world units per second, e.g. for
+// vehicle speed rules on metadata.max_speed.
+type homographyHook struct {
+	Matrix []float64 `json:"matrix"`
+	Key    string    `json:"key"`
+
+	prev   []worldPoint
+	prevTs int64
+}
+
+type worldPoint struct {
+	Label string   `json:"label"`
+	X     float64  `json:"x"`
+	Y     float64  `json:"y"`
+	Speed *float64 `json:"speed,omitempty"`
+}
+
+// maxSpeedInterval is the longest gap between two messages for which the
+// speed of their points is estimated
+const maxSpeedInterval = 5 * time.Second
+
+func newHomographyHook(params map[string]any) (Hook, error) {
+	h := &homographyHook{Key: "world"}
+	if err := decodeParams(params, h); err != nil {
+		return nil, err
+	}
+	if len(h.Matrix) != 9 {
+		return nil, fmt.Errorf("matrix must have 9 elements, got %d", len(h.Matrix))
+	}
+	return h, nil
+}
+
+func (h *homographyHook) Process(ctx context.Context, msg *dao.DeviceMessage) error {
+	if len(msg.DetectBoxes) == 0 {
+		return nil
+	}
+	m := h.Matrix
+	points := make([]worldPoint, 0, len(msg.DetectBoxes))
+	for _, box := range msg.DetectBoxes {
+		x := float64(box.X1+box.X2) / 2
+		y := float64(box.Y2)
+		w := m[6]*x + m[7]*y + m[8]
+		if w == 0 {
+			continue
+		}
+		points = append(points, worldPoint{
+			Label: box.Label,
+			X:     (m[0]*x + m[1]*y + m[2]) / w,
+			Y:     (m[3]*x + m[4]*y + m[5]) / w,
+		})
+	}
+
+	dt := time.Duration(msg.Timestamp - h.prevTs)
+	if h.prev != nil && dt > 0 && dt <= maxSpeedInterval {
+		if maxSpeed, ok := estimateSpeeds(points, h.prev, dt.Seconds()); ok {
+			setMetadata(msg, "max_speed", maxSpeed)
+		}
+	}
+	h.prev, h.prevTs = points, msg.Timestamp
+
+	setMetadata(msg, h.Key, points)
+	return nil
+}
+
+// estimateSpeeds matches every point to the nearest unmatched point of the
+// same label in prev and sets its speed, it returns the highest speed.
+func estimateSpeeds(points, prev []worldPoint, seconds float64) (float64, bool) {
+	used := make([]bool, len(prev))
+	maxSpeed, found := 0.0, false
+	for i := range points {
+		best, bestDist := -1, math.Inf(1)
+		for j, p := range prev {
+			if used[j] || p.Label != points[i].Label {
+				continue
+			}
+			if d := math.Hypot(points[i].X-p.X, points[i].Y-p.Y); d < bestDist {
+				best, bestDist = j, d
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		used[best] = true
+		speed := bestDist / seconds
+		points[i].Speed = &speed
+		maxSpeed, found = max(maxSpeed, speed), true
+	}
+	return maxSpeed, found
+}
+
+// requireLabelsHook drops the messages without a box of the given labels.
+type requireLabelsHook struct {
+	Labels []string `json:"labels"`
+}
+
+func newRequireLabelsHook(params map[string]any) (Hook, error) {
+	h := &requireLabelsHook{}
+	if err := decodeParams(params, h); err != nil {
+		return nil, err
+	}
+	if len(h.Labels) == 0 {
+		return nil, errors.New("labels is empty")
+	}
+	return h, nil
+}
+
+func (h *requireLabelsHook) Process(ctx context.Context, msg *dao.DeviceMessage) error {
+	for _, box := range msg.DetectBoxes {
+		for _, label := range h.Labels {
+			if box.Label == label {
+				return nil
+			}
+		}
+	}
+	return ErrDropMessage
+}
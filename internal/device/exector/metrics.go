@@ -0,0 +1,184 @@
+package exector
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+const (
+	maxRecentUploads = 20
+	frameRateWindow  = 5 * time.Second
+)
+
+type UploadResult struct {
+	Time  time.Time `json:"time"`
+	Path  string    `json:"path"`
+	Error string    `json:"error,omitempty"`
+}
+
+// Metrics is a snapshot of the runtime counters of an executor.
+type Metrics struct {
+	StartTime       time.Time `json:"startTime,omitempty"`
+	FramesRead      int64     `json:"framesRead"`
+	FramesProcessed int64     `json:"framesProcessed"`
+	FramesDropped   int64     `json:"framesDropped"`
+	FrameRate       float64   `json:"frameRate"`
+	// Inferences and InferenceSeconds are the number and the total duration
+	// of the model inferences
+	Inferences       int64   `json:"inferences"`
+	InferenceSeconds float64 `json:"inferenceSeconds"`
+	// UploadQueue is the number of results waiting for upload found by the
+	// last upload pass
+	UploadQueue    int            `json:"uploadQueue"`
+	LastFrameTime  time.Time      `json:"lastFrameTime,omitempty"`
+	LastUploadTime time.Time      `json:"lastUploadTime,omitempty"`
+	LastError      string         `json:"lastError,omitempty"`
+	LastErrorTime  time.Time      `json:"lastErrorTime,omitempty"`
+	RecentUploads  []UploadResult `json:"recentUploads"`
+}
+
+// Health is the result of an executor health probe, an executor may be
+// running but unhealthy, e.g. when its input stream stalls.
+type Health struct {
+	Healthy bool   `json:"healthy"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+func healthy() Health {
+	return Health{Healthy: true}
+}
+
+func unhealthy(format string, args ...any) Health {
+	return Health{Reason: fmt.Sprintf(format, args...)}
+}
+
+// metricsRecorder is embedded by executors to collect Metrics, it is safe
+// for concurrent use by the executor goroutines and readers.
+type metricsRecorder struct {
+	metricsMu    sync.Mutex
+	metrics      Metrics
+	windowStart  time.Time
+	windowFrames int64
+	// thumbnail of the frame of the last error and when it was taken
+	errorFrame     []byte
+	errorFrameTime time.Time
+}
+
+func (r *metricsRecorder) Metrics() Metrics {
+	r.metricsMu.Lock()
+	defer r.metricsMu.Unlock()
+	m := r.metrics
+	m.RecentUploads = append([]UploadResult(nil), r.metrics.RecentUploads...)
+	return m
+}
+
+func (r *metricsRecorder) started(now time.Time) {
+	r.metricsMu.Lock()
+	r.metrics.StartTime = now
+	r.metricsMu.Unlock()
+}
+
+// sinceActivity returns how long ago the executor last made progress, as
+// given by last, counting from its start if it has not made any yet.
+func sinceActivity(m Metrics, last time.Time, now time.Time) time.Duration {
+	if last.Before(m.StartTime) {
+		last = m.StartTime
+	}
+	return now.Sub(last)
+}
+
+func (r *metricsRecorder) frameRead() {
+	r.metricsMu.Lock()
+	r.metrics.FramesRead++
+	r.metrics.LastFrameTime = time.Now()
+	r.metricsMu.Unlock()
+}
+
+func (r *metricsRecorder) frameProcessed(now time.Time) {
+	r.metricsMu.Lock()
+	defer r.metricsMu.Unlock()
+	r.metrics.FramesProcessed++
+	r.windowFrames++
+	if r.windowStart.IsZero() {
+		r.windowStart = now
+	} else if elapsed := now.Sub(r.windowStart); elapsed >= frameRateWindow {
+		r.metrics.FrameRate = float64(r.windowFrames) / elapsed.Seconds()
+		r.windowStart = now
+		r.windowFrames = 0
+	}
+}
+
+func (r *metricsRecorder) inferenceDone(d time.Duration) {
+	r.metricsMu.Lock()
+	r.metrics.Inferences++
+	r.metrics.InferenceSeconds += d.Seconds()
+	r.metricsMu.Unlock()
+}
+
+func (r *metricsRecorder) uploadQueued(n int) {
+	r.metricsMu.Lock()
+	r.metrics.UploadQueue = n
+	r.metricsMu.Unlock()
+}
+
+func (r *metricsRecorder) frameDropped() {
+	r.metricsMu.Lock()
+	r.metrics.FramesDropped++
+	r.metricsMu.Unlock()
+}
+
+func (r *metricsRecorder) ErrorFrame() ([]byte, time.Time) {
+	r.metricsMu.Lock()
+	defer r.metricsMu.Unlock()
+	return r.errorFrame, r.errorFrameTime
+}
+
+// recordErrorFrame records err along with a thumbnail of the frame it
+// occurred on, thumbnails are taken at most once per errorFrameInterval.
+func (r *metricsRecorder) recordErrorFrame(err error, frame gocv.Mat) {
+	r.recordError(err)
+	r.metricsMu.Lock()
+	due := time.Since(r.errorFrameTime) >= errorFrameInterval
+	r.metricsMu.Unlock()
+	if !due {
+		return
+	}
+	thumb, terr := Thumbnail(frame)
+	if terr != nil {
+		return
+	}
+	r.metricsMu.Lock()
+	r.errorFrame = thumb
+	r.errorFrameTime = time.Now()
+	r.metricsMu.Unlock()
+}
+
+func (r *metricsRecorder) recordError(err error) {
+	if err == nil {
+		return
+	}
+	r.metricsMu.Lock()
+	r.metrics.LastError = err.Error()
+	r.metrics.LastErrorTime = time.Now()
+	r.metricsMu.Unlock()
+}
+
+func (r *metricsRecorder) recordUpload(path string, err error) {
+	result := UploadResult{Time: time.Now(), Path: path}
+	if err != nil {
+		result.Error = err.Error()
+		r.recordError(err)
+	}
+	r.metricsMu.Lock()
+	defer r.metricsMu.Unlock()
+	if err == nil {
+		r.metrics.LastUploadTime = result.Time
+	}
+	r.metrics.RecentUploads = append(r.metrics.RecentUploads, result)
+	if n := len(r.metrics.RecentUploads); n > maxRecentUploads {
+		r.metrics.RecentUploads = r.metrics.RecentUploads[n-maxRecentUploads:]
+	}
+}
@@ -0,0 +1,306 @@
+package exector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+
+	"lumina/internal/dao"
+	"lumina/internal/device/config"
+	"lumina/internal/model"
+	"lumina/internal/utils"
+	"lumina/pkg/log"
+	"lumina/pkg/tracing"
+)
+
+// PluginResult is a result written by an exec plugin to its work dir as a
+// json file, Image and Video are file names relative to the work dir.
+type PluginResult struct {
+	Timestamp   int64               `json:"timestamp"` // ns
+	Image       string              `json:"image,omitempty"`
+	Video       string              `json:"video,omitempty"`
+	DetectBoxes []*dao.DetectionBox `json:"detectBoxes,omitempty"`
+	// size in pixels of the frame the boxes are in, optional
+	FrameWidth  int `json:"frameWidth,omitempty"`
+	FrameHeight int `json:"frameHeight,omitempty"`
+}
+
+// ExecPlugin runs the jobs of a custom kind with an external executable.
+//
+// The executable is started in the job work dir with the job described by
+// the environment variables LUMINA_JOB (the job spec as json),
+// LUMINA_JOB_UUID, LUMINA_JOB_KIND, LUMINA_INPUT and LUMINA_WORK_DIR. It
+// reports results by writing PluginResult json files next to the image or
+// video they refer to, renaming them into place once complete. The device
+// uploads the referenced file, publishes the result like the built-in
+// executors and removes both files. The job finishes when the executable
+// exits, a non-zero exit status fails it.
+type ExecPlugin struct {
+	pauseState
+	executorStatus
+	metricsRecorder
+	env     *Env
+	plugin  config.PluginConfig
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      *sync.WaitGroup
+	job     *dao.JobSpec
+	logger  *logrus.Entry
+	workDir string
+	hooks   hookChain
+}
+
+func NewExecPlugin(env *Env, plugin config.PluginConfig, job *dao.JobSpec) (*ExecPlugin, error) {
+	hooks, err := newHookChain(job, env.Sensors)
+	if err != nil {
+		return nil, err
+	}
+	workDir := path.Join(env.Conf.JobDir(), job.Uuid)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(env.Ctx)
+	return &ExecPlugin{
+		env:     env,
+		plugin:  plugin,
+		ctx:     ctx,
+		cancel:  cancel,
+		wg:      &sync.WaitGroup{},
+		job:     job,
+		logger:  log.GetLogger(ctx).WithFields(logrus.Fields{"job": job.Uuid, "plugin": plugin.Kind}),
+		workDir: workDir,
+		hooks:   hooks,
+	}, nil
+}
+
+func (e *ExecPlugin) Job() *dao.JobSpec {
+	return e.job
+}
+
+func (e *ExecPlugin) Status() model.ExectorStatus {
+	return e.pausedStatus(e.loadStatus())
+}
+
+// Health reports a plugin as healthy while its process runs, a plugin may
+// legitimately produce no result for a long time.
+func (e *ExecPlugin) Health() Health {
+	switch status := e.Status(); status {
+	case model.ExectorStatusRunning, model.ExectorStatusPaused:
+		return healthy()
+	default:
+		return unhealthy("executor %s", status)
+	}
+}
+
+func (e *ExecPlugin) Start() error {
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer guard(e.logger, &e.executorStatus)
+		e.logger.Info("plugin job started")
+		e.started(time.Now())
+		e.setStatus(model.ExectorStatusRunning)
+		e.runJob()
+		e.logger.Info("plugin job finished")
+	}()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer guard(e.logger, &e.executorStatus)
+		e.uploadRoutine()
+	}()
+
+	return nil
+}
+
+// Cleanup uploads the results left in the work dir and removes it, it must
+// only be called after Stop.
+func (e *ExecPlugin) Cleanup(ctx context.Context) error {
+	uploadErr := e.listAndUpload(ctx)
+	return errors.Join(uploadErr, os.RemoveAll(e.workDir))
+}
+
+func (e *ExecPlugin) Stop() {
+	e.cancel()
+	e.wg.Wait()
+	e.setStatus(model.ExectorStatusStopped)
+}
+
+func (e *ExecPlugin) runJob() {
+	jobData, err := json.Marshal(e.job)
+	if err != nil {
+		e.recordError(err)
+		e.setStatus(model.ExectorStatusFailed)
+		return
+	}
+
+	cmd := exec.CommandContext(e.ctx, e.plugin.Command, e.plugin.Args...)
+	cmd.Dir = e.workDir
+	cmd.Env = append(os.Environ(), e.plugin.Env...)
+	cmd.Env = append(cmd.Env,
+		"LUMINA_JOB="+string(jobData),
+		"LUMINA_JOB_UUID="+e.job.Uuid,
+		"LUMINA_JOB_KIND="+string(e.job.Kind),
+		"LUMINA_INPUT="+e.job.Input(),
+		"LUMINA_WORK_DIR="+e.workDir,
+	)
+	output := newTailBuffer(64 * 1024)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	e.logger.WithField("command", e.plugin.Command).Info("starting plugin process")
+	err = cmd.Run()
+	if s := strings.TrimSpace(output.String()); s != "" {
+		e.logger.Infof("plugin output: %s", s)
+	}
+	switch {
+	case e.ctx.Err() != nil:
+		e.setStatus(model.ExectorStatusStopped)
+	case err != nil:
+		e.logger.WithError(err).Error("plugin process exited with error")
+		e.recordError(fmt.Errorf("plugin exited: %v", err))
+		e.setStatus(model.ExectorStatusFailed)
+	default:
+		e.setStatus(model.ExectorStatusFinished)
+	}
+}
+
+func (e *ExecPlugin) uploadRoutine() {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if err := e.listAndUpload(e.ctx); err != nil {
+			e.logger.WithError(err).Errorf("list and upload failed")
+		}
+
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *ExecPlugin) listAndUpload(parentCtx context.Context) error {
+	entries, err := os.ReadDir(e.workDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	queued := 0
+	defer func() { e.uploadQueued(queued) }()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(strings.ToLower(entry.Name()), ".json") {
+			continue
+		}
+		queued++
+		jsonPath := filepath.Join(e.workDir, entry.Name())
+		if err := e.upload(parentCtx, jsonPath); err != nil {
+			e.logger.WithError(err).Errorf("process plugin result %s failed", jsonPath)
+		}
+	}
+	return nil
+}
+
+func (e *ExecPlugin) upload(parentCtx context.Context, jsonPath string) error {
+	jsonData, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return err
+	}
+	var result PluginResult
+	if err := json.Unmarshal(jsonData, &result); err != nil {
+		e.recordError(fmt.Errorf("invalid plugin result %s: %w", filepath.Base(jsonPath), err))
+		return os.Remove(jsonPath)
+	}
+
+	file := result.Image
+	if file == "" {
+		file = result.Video
+	}
+	// only plain file names are accepted so a plugin cannot upload files
+	// outside its work dir
+	if file == "" || file != filepath.Base(file) {
+		e.recordError(fmt.Errorf("plugin result %s has no valid image or video", filepath.Base(jsonPath)))
+		return os.Remove(jsonPath)
+	}
+	filePath := filepath.Join(e.workDir, file)
+
+	// results produced while paused are dropped instead of published
+	if e.isPaused() {
+		return errors.Join(os.Remove(jsonPath), os.Remove(filePath))
+	}
+
+	ts := time.Now()
+	if result.Timestamp != 0 {
+		ts = time.Unix(0, result.Timestamp)
+	}
+	msg := &dao.DeviceMessage{
+		JobUuid:     e.job.Uuid,
+		Timestamp:   ts.UnixNano(),
+		DetectBoxes: result.DetectBoxes,
+		FrameWidth:  result.FrameWidth,
+		FrameHeight: result.FrameHeight,
+	}
+	if err := e.hooks.run(parentCtx, msg); errors.Is(err, ErrDropMessage) {
+		return errors.Join(os.Remove(jsonPath), os.Remove(filePath))
+	} else if err != nil {
+		e.logger.WithError(err).Warnf("hooks failed for %s", jsonPath)
+		e.recordError(err)
+	}
+
+	minioPath := fmt.Sprintf("/%s/%04d/%02d/%02d/%s/%s",
+		*e.env.DeviceInfo.Uuid, ts.Year(), ts.Month(), ts.Day(), e.job.Uuid, file)
+
+	ctx, cancel := context.WithTimeout(parentCtx, 30*time.Second)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "device.upload",
+		attribute.String("lumina.job_uuid", e.job.Uuid), attribute.String("lumina.path", minioPath))
+	if err := utils.UploadFileToMinio(ctx, e.env.MinioCli, e.env.Conf.S3.Bucket, filePath, minioPath); err != nil {
+		e.recordUpload(minioPath, err)
+		tracing.End(span, err)
+		return err
+	}
+
+	if result.Image != "" {
+		msg.ImagePath = minioPath
+	} else {
+		msg.VideoPath = minioPath
+	}
+	if err := e.env.Signer.Sign(msg, filePath); err != nil {
+		e.recordUpload(minioPath, err)
+		tracing.End(span, err)
+		return err
+	}
+	msg.TraceContext = tracing.Inject(ctx)
+	msgData, _ := json.Marshal(msg)
+	if err := e.env.NsqProducer.Publish(e.env.Conf.NSQ.Topic, msgData); err != nil {
+		e.recordUpload(minioPath, err)
+		tracing.End(span, err)
+		return err
+	}
+	e.recordUpload(minioPath, nil)
+	span.End()
+
+	os.Remove(jsonPath)
+	os.Remove(filePath)
+
+	e.logger.Infof("successfully processed %s: uploaded to %s and sent to NSQ", jsonPath, minioPath)
+	return nil
+}
@@ -0,0 +1,57 @@
+package exector
+
+import (
+	"image"
+
+	"gocv.io/x/gocv"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+// pixelateBlockSize is the size in pixels of a mosaic block when pixelating.
+const pixelateBlockSize = 16
+
+// maskPrivacyRegions returns a copy of the frame with the boxes of the given
+// labels blurred or pixelated, the boxes themselves are left untouched.
+func maskPrivacyRegions(frame *gocv.Mat, boxes []*dao.DetectionBox, labels map[string]struct{}, mode model.PrivacyMode) gocv.Mat {
+	masked := frame.Clone()
+	bounds := image.Rect(0, 0, masked.Cols(), masked.Rows())
+
+	for _, box := range boxes {
+		if _, ok := labels[box.Label]; !ok {
+			continue
+		}
+		rect := image.Rect(box.X1, box.Y1, box.X2, box.Y2).Intersect(bounds)
+		if rect.Empty() {
+			continue
+		}
+
+		region := masked.Region(rect)
+		switch mode {
+		case model.PrivacyModePixelate:
+			pixelate(&region)
+		default:
+			blur(&region)
+		}
+		region.Close()
+	}
+
+	return masked
+}
+
+func blur(region *gocv.Mat) {
+	// kernel size must be odd, scale it with the region so that large
+	// faces are as unrecognizable as small ones
+	k := max(region.Cols(), region.Rows())/3 | 1
+	gocv.GaussianBlur(*region, region, image.Pt(k, k), 0, 0, gocv.BorderDefault)
+}
+
+func pixelate(region *gocv.Mat) {
+	w, h := region.Cols(), region.Rows()
+	small := gocv.NewMat()
+	defer small.Close()
+
+	gocv.Resize(*region, &small, image.Pt(max(w/pixelateBlockSize, 1), max(h/pixelateBlockSize, 1)), 0, 0, gocv.InterpolationLinear)
+	gocv.Resize(small, region, image.Pt(w, h), 0, 0, gocv.InterpolationNearestNeighbor)
+}
@@ -0,0 +1,102 @@
+package exector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/nsqio/go-nsq"
+
+	"lumina/internal/dao"
+	"lumina/internal/device/config"
+	"lumina/internal/device/metadata"
+	"lumina/internal/device/sensor"
+	"lumina/internal/model"
+)
+
+// Env carries the device resources an executor may use to run a job and
+// publish its results.
+type Env struct {
+	Conf        *config.Config
+	DeviceInfo  *metadata.DeviceInfo
+	Ctx         context.Context
+	MinioCli    *minio.Client
+	NsqProducer *nsq.Producer
+	Sensors     *sensor.Hub
+	// Signer signs the published messages, nil unless evidence is enabled
+	Signer *Signer
+}
+
+// Factory creates the executor of a job.
+type Factory func(env *Env, job *dao.JobSpec) (Executor, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[model.JobKind]Factory)
+)
+
+func init() {
+	Register(model.JobKindDetect, func(env *Env, job *dao.JobSpec) (Executor, error) {
+		return NewDetector(env.Conf, env.DeviceInfo, env.Ctx, env.MinioCli, env.NsqProducer, env.Sensors, env.Signer, job)
+	})
+	Register(model.JobKindVideoSegment, func(env *Env, job *dao.JobSpec) (Executor, error) {
+		return NewVideoSegmentor(env.Conf, env.DeviceInfo, env.Ctx, env.MinioCli, env.NsqProducer, env.Sensors, env.Signer, job)
+	})
+}
+
+// Register makes the executor of a job kind available to the device, it is
+// meant to be called from an init function of the package implementing the
+// kind and panics if the kind is already registered.
+func Register(kind model.JobKind, factory Factory) {
+	if err := register(kind, factory); err != nil {
+		panic(err)
+	}
+}
+
+func register(kind model.JobKind, factory Factory) error {
+	if kind == "" || factory == nil {
+		return fmt.Errorf("invalid executor registration for kind %q", kind)
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[kind]; ok {
+		return fmt.Errorf("executor for kind %s already registered", kind)
+	}
+	registry[kind] = factory
+	return nil
+}
+
+// RegisterExecPlugin registers the job kind served by an executable plugin.
+func RegisterExecPlugin(conf config.PluginConfig) error {
+	if conf.Command == "" {
+		return fmt.Errorf("plugin %s has no command", conf.Kind)
+	}
+	return register(model.JobKind(conf.Kind), func(env *Env, job *dao.JobSpec) (Executor, error) {
+		return NewExecPlugin(env, conf, job)
+	})
+}
+
+// New creates the executor of a job with the factory registered for its kind.
+func New(env *Env, job *dao.JobSpec) (Executor, error) {
+	registryMu.RLock()
+	factory, ok := registry[job.Kind]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown job kind %s", job.Kind)
+	}
+	return factory(env, job)
+}
+
+// Kinds returns the registered job kinds in sorted order.
+func Kinds() []model.JobKind {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	kinds := make([]model.JobKind, 0, len(registry))
+	for kind := range registry {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
@@ -0,0 +1,119 @@
+package exector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	tritonGrpc "github.com/Trendyol/go-triton-client/client/grpc"
+	"gocv.io/x/gocv"
+
+	"lumina/internal/dao"
+	"lumina/internal/device/config"
+)
+
+const testDetectMaxReadFrames = 50
+
+// TestDetect grabs a single frame from the task input, runs it through the
+// detection model and writes the annotated frame to imagePath.
+func TestDetect(ctx context.Context, conf *config.Config, task *dao.TestDetectTask, imagePath string) ([]*dao.DetectionBox, error) {
+	tritonCli, err := tritonGrpc.NewClient(
+		conf.Triton.ServerAddr,
+		false, // verbose logging
+		30,    // connection timeout in seconds
+		30,    // network timeout in seconds
+		false, // use ssl
+		true,  // insecure connection
+		nil,   // existing grpc connection
+		nil,   // logger
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if isReady, err := tritonCli.IsModelReady(ctx, task.ModelName, "1", nil); err != nil {
+		return nil, err
+	} else if !isReady {
+		return nil, errors.New("triton model is not ready")
+	}
+
+	video, err := gocv.VideoCaptureFile(task.PullAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input video: %v", err)
+	}
+	defer video.Close()
+
+	frame := gocv.NewMat()
+	defer frame.Close()
+	for i := 0; ; i++ {
+		if i >= testDetectMaxReadFrames {
+			return nil, errors.New("no frame read from input")
+		}
+		if ok := video.Read(&frame); !ok {
+			return nil, errors.New("read frame from input failed")
+		}
+		if !frame.Empty() {
+			break
+		}
+	}
+
+	processedFrame, boxes, err := performInference(tritonCli, &frame, task.ModelName, task.GetLabelMap())
+	if err != nil {
+		return nil, err
+	}
+	processedFrame.Close()
+
+	filtered := make([]*dao.DetectionBox, 0, len(boxes))
+	for _, box := range boxes {
+		if box.Confidence >= task.ConfThreshold {
+			filtered = append(filtered, box)
+		}
+	}
+	filtered = suppressOverlaps(filtered, task.IoUThreshold)
+
+	annotated := drawDetections(&frame, filtered)
+	defer annotated.Close()
+	if !gocv.IMWrite(imagePath, annotated) {
+		return nil, fmt.Errorf("write image file error")
+	}
+
+	return filtered, nil
+}
+
+// suppressOverlaps keeps, among the boxes of a class overlapping by more
+// than iouThreshold, the most confident one. A zero threshold, sent by the
+// servers not setting it, keeps every box.
+func suppressOverlaps(boxes []*dao.DetectionBox, iouThreshold float32) []*dao.DetectionBox {
+	if iouThreshold <= 0 {
+		return boxes
+	}
+	sort.SliceStable(boxes, func(i, j int) bool { return boxes[i].Confidence > boxes[j].Confidence })
+
+	kept := make([]*dao.DetectionBox, 0, len(boxes))
+	for _, box := range boxes {
+		suppressed := false
+		for _, k := range kept {
+			if k.ClassId == box.ClassId && boxIoU(k, box) > float64(iouThreshold) {
+				suppressed = true
+				break
+			}
+		}
+		if !suppressed {
+			kept = append(kept, box)
+		}
+	}
+	return kept
+}
+
+func boxIoU(a, b *dao.DetectionBox) float64 {
+	x1, y1 := max(a.X1, b.X1), max(a.Y1, b.Y1)
+	x2, y2 := min(a.X2, b.X2), min(a.Y2, b.Y2)
+	if x2 <= x1 || y2 <= y1 {
+		return 0
+	}
+	inter := float64((x2 - x1) * (y2 - y1))
+	areaA := float64((a.X2 - a.X1) * (a.Y2 - a.Y1))
+	areaB := float64((b.X2 - b.X1) * (b.Y2 - b.Y1))
+	return inter / (areaA + areaB - inter)
+}
@@ -0,0 +1,46 @@
+package exector
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+const (
+	// thumbnailSide is the longest side of the thumbnails
+	thumbnailSide    = 320
+	thumbnailQuality = 60
+	// errorFrameInterval limits the thumbnails taken on repeated errors
+	errorFrameInterval = 10 * time.Second
+)
+
+// ErrorFramer is implemented by the executors keeping a thumbnail of the
+// frame of their last error.
+type ErrorFramer interface {
+	// ErrorFrame returns the JPEG thumbnail and the time it was taken, nil if
+	// there was no error on a frame.
+	ErrorFrame() ([]byte, time.Time)
+}
+
+// Thumbnail encodes img scaled down to fit thumbnailSide as a JPEG.
+func Thumbnail(img gocv.Mat) ([]byte, error) {
+	w, h := img.Cols(), img.Rows()
+	if w == 0 || h == 0 {
+		return nil, errors.New("empty image")
+	}
+	if side := max(w, h); side > thumbnailSide {
+		small := gocv.NewMat()
+		defer small.Close()
+		gocv.Resize(img, &small, image.Pt(max(w*thumbnailSide/side, 1), max(h*thumbnailSide/side, 1)), 0, 0, gocv.InterpolationArea)
+		img = small
+	}
+	buf, err := gocv.IMEncodeWithParams(gocv.JPEGFileExt, img, []int{gocv.IMWriteJpegQuality, thumbnailQuality})
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Close()
+	return bytes.Clone(buf.GetBytes()), nil
+}
@@ -3,6 +3,7 @@ package exector
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -17,27 +19,34 @@ import (
 	"github.com/minio/minio-go/v7"
 	"github.com/nsqio/go-nsq"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 
 	"lumina/internal/dao"
 	"lumina/internal/device/config"
 	"lumina/internal/device/metadata"
+	"lumina/internal/device/sensor"
 	"lumina/internal/model"
 	"lumina/internal/utils"
 	"lumina/pkg/log"
+	"lumina/pkg/tracing"
 )
 
 type VideoSegmentor struct {
+	pauseState
+	executorStatus
+	metricsRecorder
 	ctx         context.Context
 	cancel      context.CancelFunc
 	wg          *sync.WaitGroup
 	job         *dao.JobSpec
 	logger      *logrus.Entry
-	status      model.ExectorStatus
 	workDir     string
 	conf        *config.Config
 	nsqProducer *nsq.Producer
 	minioCli    *minio.Client
 	deviceInfo  *metadata.DeviceInfo
+	hooks       hookChain
+	signer      *Signer
 }
 
 // tailBuffer stores only the last N bytes written to it to avoid unbounded memory growth.
@@ -71,10 +80,14 @@ func (t *tailBuffer) Write(p []byte) (int, error) {
 func (t *tailBuffer) String() string { return string(t.buf) }
 
 func NewVideoSegmentor(conf *config.Config, deviceInfo *metadata.DeviceInfo, parentCtx context.Context,
-	minioCli *minio.Client, nsqProducer *nsq.Producer, job *dao.JobSpec) (*VideoSegmentor, error) {
+	minioCli *minio.Client, nsqProducer *nsq.Producer, sensors *sensor.Hub, signer *Signer, job *dao.JobSpec) (*VideoSegmentor, error) {
 	if job.VideoSegment == nil {
 		return nil, fmt.Errorf("job %s video segment is nil", job.Uuid)
 	}
+	hooks, err := newHookChain(job, sensors)
+	if err != nil {
+		return nil, err
+	}
 	workDir := path.Join(conf.JobDir(), job.Uuid)
 	if err := os.MkdirAll(workDir, 0755); err != nil {
 		return nil, err
@@ -87,11 +100,12 @@ func NewVideoSegmentor(conf *config.Config, deviceInfo *metadata.DeviceInfo, par
 		wg:          &sync.WaitGroup{},
 		job:         job,
 		logger:      log.GetLogger(ctx).WithField("job", job.Uuid),
-		status:      model.ExectorStatusStopped,
 		workDir:     workDir,
 		conf:        conf,
 		nsqProducer: nsqProducer,
 		minioCli:    minioCli,
+		hooks:       hooks,
+		signer:      signer,
 	}, nil
 }
 
@@ -100,15 +114,40 @@ func (e *VideoSegmentor) Job() *dao.JobSpec {
 }
 
 func (e *VideoSegmentor) Status() model.ExectorStatus {
-	return e.status
+	return e.pausedStatus(e.loadStatus())
+}
+
+func (e *VideoSegmentor) Health() Health {
+	status := e.Status()
+	switch status {
+	case model.ExectorStatusPaused:
+		return healthy()
+	case model.ExectorStatusRunning:
+	default:
+		return unhealthy("executor %s", status)
+	}
+	// a segment is uploaded once the next one starts, allow some slack for
+	// slow uploads
+	interval := 30
+	if e.job.VideoSegment.Interval > 0 {
+		interval = e.job.VideoSegment.Interval
+	}
+	timeout := 3*time.Duration(interval)*time.Second + 30*time.Second
+	m := e.Metrics()
+	if idle := sinceActivity(m, m.LastUploadTime, time.Now()); idle > timeout {
+		return unhealthy("no segment uploaded for %s", idle.Truncate(time.Second))
+	}
+	return healthy()
 }
 
 func (e *VideoSegmentor) Start() error {
 	e.wg.Add(1)
 	go func() {
 		defer e.wg.Done()
+		defer guard(e.logger, &e.executorStatus)
 		e.logger.Info("video segmentation job started")
-		e.status = model.ExectorStatusRunning
+		e.started(time.Now())
+		e.setStatus(model.ExectorStatusRunning)
 		e.runJob()
 		e.logger.Info("video segmentation job finished")
 	}()
@@ -116,16 +155,24 @@ func (e *VideoSegmentor) Start() error {
 	e.wg.Add(1)
 	go func() {
 		defer e.wg.Done()
+		defer guard(e.logger, &e.executorStatus)
 		e.uploadRoutine()
 	}()
 
 	return nil
 }
 
+// Cleanup uploads the results left in the work dir and removes it, it must
+// only be called after Stop.
+func (e *VideoSegmentor) Cleanup(ctx context.Context) error {
+	uploadErr := e.listAndUpload(ctx)
+	return errors.Join(uploadErr, os.RemoveAll(e.workDir))
+}
+
 func (e *VideoSegmentor) Stop() {
 	e.cancel()
 	e.wg.Wait()
-	e.status = model.ExectorStatusStopped
+	e.setStatus(model.ExectorStatusStopped)
 }
 
 func (e *VideoSegmentor) runJob() {
@@ -160,7 +207,8 @@ func (e *VideoSegmentor) runJob() {
 
 	if err := cmd.Start(); err != nil {
 		e.logger.WithError(err).Error("failed to start ffmpeg process")
-		e.status = model.ExectorStatusFailed
+		e.recordError(err)
+		e.setStatus(model.ExectorStatusFailed)
 		return
 	}
 
@@ -185,18 +233,19 @@ func (e *VideoSegmentor) runJob() {
 				e.logger.Info("ffmpeg process terminated")
 			}
 		}
-		e.status = model.ExectorStatusStopped
+		e.setStatus(model.ExectorStatusStopped)
 	case err := <-done:
 		if err != nil {
 			// Read captured stderr after process exit
 			e.logger.WithError(err).Errorf("ffmpeg process exited with error: %s", strings.TrimSpace(stderr.String()))
-			e.status = model.ExectorStatusFailed
+			e.recordError(fmt.Errorf("ffmpeg exited: %v", err))
+			e.setStatus(model.ExectorStatusFailed)
 		} else {
 			if s := strings.TrimSpace(stderr.String()); s != "" {
 				e.logger.Infof("ffmpeg stderr output: %s", s)
 			}
 			e.logger.Info("ffmpeg process completed successfully")
-			e.status = model.ExectorStatusFinished
+			e.setStatus(model.ExectorStatusFinished)
 		}
 	}
 }
@@ -206,7 +255,7 @@ func (e *VideoSegmentor) uploadRoutine() {
 	defer ticker.Stop()
 
 	for {
-		if err := e.listAndUpload(); err != nil {
+		if err := e.listAndUpload(e.ctx); err != nil {
 			e.logger.WithError(err).Errorf("list and upload failed")
 		}
 
@@ -218,13 +267,17 @@ func (e *VideoSegmentor) uploadRoutine() {
 	}
 }
 
-func (e *VideoSegmentor) listAndUpload() error {
+func (e *VideoSegmentor) listAndUpload(parentCtx context.Context) error {
 	var files []string
+	quarantineDir := filepath.Join(e.workDir, quarantineDirName)
 	err := filepath.WalkDir(e.workDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
+		if d.IsDir() && path == quarantineDir {
+			return fs.SkipDir
+		}
 		if d.IsDir() || !strings.HasSuffix(strings.ToLower(d.Name()), ".mp4") {
 			return nil
 		}
@@ -238,6 +291,8 @@ func (e *VideoSegmentor) listAndUpload() error {
 
 	sort.Strings(files)
 
+	// the last segment is still being recorded
+	e.uploadQueued(max(len(files)-1, 0))
 	if len(files) <= 1 {
 		return nil
 	}
@@ -245,35 +300,83 @@ func (e *VideoSegmentor) listAndUpload() error {
 	// 处理除最后一个文件外的所有文件
 	for _, path := range files[:len(files)-1] {
 		filename := filepath.Base(path)
+		// ffmpeg keeps recording while paused, drop the segments instead of publishing them
+		if e.isPaused() {
+			if err := os.Remove(path); err != nil {
+				e.logger.WithError(err).Warnf("failed to remove paused segment %s", path)
+			}
+			continue
+		}
 		info, err := os.Stat(path)
 		if err != nil {
 			e.logger.WithError(err).Warnf("failed to get file info %s, skip", filename)
 			continue
 		}
 		ts := info.ModTime()
+
+		if duration, err := probeSegment(parentCtx, path); err != nil {
+			e.logger.WithError(err).Warnf("segment %s is corrupt, quarantine it", filename)
+			e.recordError(fmt.Errorf("corrupt segment %s: %w", filename, err))
+			if err := e.quarantine(path, info, err); err != nil {
+				e.logger.WithError(err).Errorf("quarantine segment %s failed", filename)
+			}
+			continue
+		} else if duration < minSegmentDuration {
+			err := fmt.Errorf("duration %s shorter than %s", duration, minSegmentDuration)
+			e.logger.WithError(err).Warnf("segment %s is partial, quarantine it", filename)
+			e.recordError(fmt.Errorf("partial segment %s: %w", filename, err))
+			if err := e.quarantine(path, info, err); err != nil {
+				e.logger.WithError(err).Errorf("quarantine segment %s failed", filename)
+			}
+			continue
+		}
+
+		msg := &dao.DeviceMessage{
+			JobUuid:   e.job.Uuid,
+			Timestamp: ts.UnixNano(),
+		}
+		if err := e.hooks.run(parentCtx, msg); errors.Is(err, ErrDropMessage) {
+			os.Remove(path)
+			continue
+		} else if err != nil {
+			e.logger.WithError(err).Warnf("hooks failed for %s", filename)
+			e.recordError(err)
+		}
+
 		minioPath := fmt.Sprintf("/%s/%04d/%02d/%02d/%s/%s",
 			*e.deviceInfo.Uuid, ts.Year(), ts.Month(), ts.Day(), e.job.Uuid, filename)
 
 		// 上传到 MinIO
-		ctx, cancel := context.WithTimeout(e.ctx, 30*time.Second)
+		spanCtx, span := tracing.Start(parentCtx, "device.upload",
+			attribute.String("lumina.job_uuid", e.job.Uuid), attribute.String("lumina.path", minioPath))
+		ctx, cancel := context.WithTimeout(spanCtx, 30*time.Second)
 		if err := utils.UploadFileToMinio(ctx, e.minioCli, e.conf.S3.Bucket, path, minioPath); err != nil {
 			e.logger.WithError(err).Errorf("upload video segment %s to minio failed", path)
+			e.recordUpload(minioPath, err)
+			tracing.End(span, err)
 			cancel()
 			continue
 		}
 		cancel()
 
-		// 创建消息并发送到 NSQ
-		msg := &dao.DeviceMessage{
-			JobUuid:   e.job.Uuid,
-			Timestamp: ts.UnixNano(),
-			VideoPath: minioPath,
+		// 发送消息到 NSQ
+		msg.VideoPath = minioPath
+		if err := e.signer.Sign(msg, path); err != nil {
+			e.logger.WithError(err).Errorf("sign %s failed", path)
+			e.recordUpload(minioPath, err)
+			tracing.End(span, err)
+			continue
 		}
+		msg.TraceContext = tracing.Inject(spanCtx)
 		msgData, _ := json.Marshal(msg)
 		if err := e.nsqProducer.Publish(e.conf.NSQ.Topic, msgData); err != nil {
 			e.logger.WithError(err).Errorf("publish to NSQ failed for %s", path)
+			e.recordUpload(minioPath, err)
+			tracing.End(span, err)
 			continue
 		}
+		e.recordUpload(minioPath, nil)
+		span.End()
 
 		// 删除本地文件
 		if err := os.Remove(path); err != nil {
@@ -285,3 +388,64 @@ func (e *VideoSegmentor) listAndUpload() error {
 
 	return nil
 }
+
+const (
+	quarantineDirName  = "quarantine"
+	minSegmentDuration = time.Second
+)
+
+// segmentReport describes why a segment was quarantined, it is written next
+// to the quarantined file.
+type segmentReport struct {
+	File       string    `json:"file"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"modTime"`
+	Reason     string    `json:"reason"`
+	ReportTime time.Time `json:"reportTime"`
+}
+
+// probeSegment checks that a segment is a complete mp4, ffprobe fails on a
+// file without moov atom, and returns its duration.
+func probeSegment(ctx context.Context, path string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path,
+	)
+	stderr := newTailBuffer(4 * 1024)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse duration %q: %v", strings.TrimSpace(string(out)), err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// quarantine moves a bad segment out of the upload queue together with a
+// report, the janitor removes both once they expire.
+func (e *VideoSegmentor) quarantine(path string, info fs.FileInfo, reason error) error {
+	dir := filepath.Join(e.workDir, quarantineDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	target := filepath.Join(dir, filepath.Base(path))
+	if err := os.Rename(path, target); err != nil {
+		return err
+	}
+	report := segmentReport{
+		File:       filepath.Base(path),
+		Size:       info.Size(),
+		ModTime:    info.ModTime(),
+		Reason:     reason.Error(),
+		ReportTime: time.Now(),
+	}
+	data, _ := json.MarshalIndent(report, "", "  ")
+	return os.WriteFile(target+".report", data, 0644)
+}
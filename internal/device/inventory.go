@@ -0,0 +1,143 @@
+package device
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tritonGrpc "github.com/Trendyol/go-triton-client/client/grpc"
+	"gocv.io/x/gocv"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"lumina/internal/model"
+	"lumina/internal/version"
+)
+
+const (
+	inventoryInterval = time.Hour
+	inventoryTimeout  = 10 * time.Second
+)
+
+// runInventory collects the software versions of the device at start and
+// then periodically, since ffmpeg or triton may be upgraded under a running
+// device.
+func (a *Device) runInventory() {
+	ticker := time.NewTicker(inventoryInterval)
+	defer ticker.Stop()
+	for {
+		a.inventory.Store(a.collectInventory())
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Device) collectInventory() *model.DeviceInventory {
+	ctx, cancel := context.WithTimeout(a.ctx, inventoryTimeout)
+	defer cancel()
+
+	inv := &model.DeviceInventory{
+		Os:            osRelease(),
+		Arch:          runtime.GOARCH,
+		LuminaVersion: version.VERSION,
+		LuminaCommit:  version.COMMIT,
+		OpenCVVersion: gocv.OpenCVVersion(),
+	}
+	if b, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		inv.Kernel = strings.TrimSpace(string(b))
+	}
+	if v, err := ffmpegVersion(ctx); err != nil {
+		a.logger.WithError(err).Warn("get ffmpeg version failed")
+	} else {
+		inv.FFmpegVersion = v
+	}
+	if v, err := tritonVersion(ctx, a.conf.Triton.ServerAddr); err != nil {
+		a.logger.WithError(err).Warn("get triton version failed")
+	} else {
+		inv.TritonVersion = v
+	}
+	return inv
+}
+
+// osRelease returns the pretty name of the distribution, or the os of the
+// build if the distribution is unknown.
+func osRelease() string {
+	f, err := os.Open("/etc/os-release")
+	if err != nil {
+		return runtime.GOOS
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(scanner.Text(), "PRETTY_NAME="); ok {
+			return strings.Trim(v, `"'`)
+		}
+	}
+	return runtime.GOOS
+}
+
+// ffmpegVersion parses the version from the first line of `ffmpeg -version`,
+// e.g. "ffmpeg version 4.4.2-0ubuntu0.22.04.1 Copyright ...".
+func ffmpegVersion(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "ffmpeg", "-version").Output()
+	if err != nil {
+		return "", err
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	fields := strings.Fields(line)
+	if len(fields) >= 3 && fields[1] == "version" {
+		return fields[2], nil
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func tritonVersion(ctx context.Context, addr string) (string, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	cli, err := tritonGrpc.NewClientWithGrpcConnection(conn, false, nil)
+	if err != nil {
+		return "", err
+	}
+	meta, err := cli.GetServerMetadata(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	return meta.Version, nil
+}
+
+// tritonModelReady returns why the model is not ready on the Triton server at
+// addr, nil if it is.
+func tritonModelReady(ctx context.Context, addr, name string) error {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	cli, err := tritonGrpc.NewClientWithGrpcConnection(conn, false, nil)
+	if err != nil {
+		return err
+	}
+	if ready, err := cli.IsServerReady(ctx, nil); err != nil {
+		return fmt.Errorf("triton server is not reachable: %w", err)
+	} else if !ready {
+		return errors.New("triton server is not ready")
+	}
+	if ready, err := cli.IsModelReady(ctx, name, "1", nil); err != nil {
+		return fmt.Errorf("check model %s failed: %w", name, err)
+	} else if !ready {
+		return fmt.Errorf("model %s is not loaded", name)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package device
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"lumina/internal/model"
+)
+
+// files modified more recently than this are never removed, they may still
+// be written by an executor, e.g. the segment ffmpeg is recording
+const janitorMinAge = time.Minute
+
+type janitorFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (a *Device) runJanitor() {
+	usage, err := a.cleanJobDir(time.Now())
+	if err != nil {
+		a.logger.WithError(err).Errorf("clean job dir failed")
+		return
+	}
+	a.diskUsage = usage
+}
+
+// cleanJobDir removes job directories of deleted jobs and enforces the
+// configured max age and size of the files under the job dir. It returns the
+// disk usage after cleaning.
+func (a *Device) cleanJobDir(now time.Time) (*model.DiskUsage, error) {
+	jobDir := a.conf.JobDir()
+	if err := os.MkdirAll(jobDir, 0755); err != nil {
+		return nil, err
+	}
+
+	jobs, err := a.db.GetJobs()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]struct{}, len(jobs))
+	for _, job := range jobs {
+		known[job.Uuid] = struct{}{}
+	}
+	for uuid := range a.executors {
+		known[uuid] = struct{}{}
+	}
+	a.teardowns.Range(func(key, _ any) bool {
+		known[key.(string)] = struct{}{}
+		return true
+	})
+
+	entries, err := os.ReadDir(jobDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if _, ok := known[entry.Name()]; ok || !entry.IsDir() {
+			continue
+		}
+		a.logger.Infof("remove orphaned job dir %s", entry.Name())
+		if err := os.RemoveAll(filepath.Join(jobDir, entry.Name())); err != nil {
+			a.logger.WithError(err).Warnf("remove orphaned job dir %s failed", entry.Name())
+		}
+	}
+
+	var files []janitorFile
+	var total int64
+	err = filepath.WalkDir(jobDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// files may be uploaded and removed while walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, janitorFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	maxAge := a.conf.Janitor.MaxAge
+	maxSize := a.conf.Janitor.MaxSizeMB * 1024 * 1024
+	kept := files[:0]
+	var removed int
+	for _, f := range files {
+		age := now.Sub(f.modTime)
+		expired := maxAge > 0 && age > maxAge
+		oversize := maxSize > 0 && total > maxSize
+		if age < janitorMinAge || (!expired && !oversize) {
+			kept = append(kept, f)
+			continue
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			a.logger.WithError(err).Warnf("remove %s failed", f.path)
+			kept = append(kept, f)
+			continue
+		}
+		total -= f.size
+		removed++
+	}
+	if removed > 0 {
+		a.logger.Warnf("janitor removed %d files from %s, %d bytes left", removed, jobDir, total)
+	}
+
+	usage := &model.DiskUsage{
+		JobDirBytes: total,
+		JobDirFiles: len(kept),
+	}
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(jobDir, &stat); err != nil {
+		return nil, err
+	}
+	usage.FreeBytes = int64(stat.Bavail) * int64(stat.Bsize)
+	usage.TotalBytes = int64(stat.Blocks) * int64(stat.Bsize)
+	return usage, nil
+}
@@ -2,12 +2,14 @@ package device
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
 	"time"
 
 	"lumina/internal/dao"
@@ -19,6 +21,8 @@ import (
 const (
 	fetchJobsPath    = "/api/v1/device/jobs"
 	reportStatusPath = "/api/v1/device/report-status"
+
+	jobTeardownTimeout = 2 * time.Minute
 )
 
 func (a *Device) reportDeviceStatus() error {
@@ -31,21 +35,35 @@ func (a *Device) reportDeviceStatus() error {
 
 	deviceStatus := dao.DeviceStatus{
 		JobStatus: make(map[string]dao.DeviceJobStatus),
+		DiskUsage: a.diskUsage,
+		Inventory: a.inventory.Load(),
+		// reported every time, the server pins it on the first report
+		SigningKey: a.signer.PublicKey(),
 	}
 
 	for _, job := range jobs {
 		jobUuid := job.Uuid
-		executor, exists := a.executors[jobUuid]
-		if !exists {
-			deviceStatus.JobStatus[jobUuid] = dao.DeviceJobStatus{
-				ExectorStatus: model.ExectorStatusStopped,
-			}
-		} else {
-			deviceStatus.JobStatus[jobUuid] = dao.DeviceJobStatus{
-				ExectorStatus: executor.Status(),
+		restarts, lastError := a.supervisor.restartCount(jobUuid)
+		jobStatus := dao.DeviceJobStatus{
+			ExectorStatus: model.ExectorStatusStopped,
+			RestartCount:  restarts,
+			LastError:     lastError,
+		}
+		if executor, exists := a.executors[jobUuid]; exists {
+			jobStatus.ExectorStatus = a.executorStatus(jobUuid, executor)
+			if health := executor.Health(); !health.Healthy {
+				jobStatus.HealthReason = health.Reason
 			}
+			jobStatus.FrameRate = executor.Metrics().FrameRate
+			jobStatus.FrameRateLimit = a.frameRateLimits[jobUuid]
+		} else if _, queued := a.pending[jobUuid]; queued {
+			jobStatus.ExectorStatus = model.ExectorStatusPending
+		} else if a.supervisor.backingOff(jobUuid, time.Now()) {
+			jobStatus.ExectorStatus = model.ExectorStatusFailed
 		}
+		deviceStatus.JobStatus[jobUuid] = jobStatus
 	}
+	deviceStatus.Artifacts = a.collectArtifacts()
 
 	info, err := a.db.GetDeviceInfo()
 	if err != nil {
@@ -79,6 +97,22 @@ func (a *Device) reportDeviceStatus() error {
 		return fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
 	}
 
+	var respBody dao.DeviceStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return err
+	}
+	if respBody.MaxExecutors != a.serverMaxExecutors {
+		a.logger.Infof("max executors set to %d by server", respBody.MaxExecutors)
+		a.serverMaxExecutors = respBody.MaxExecutors
+	}
+	a.artifactsSent(deviceStatus.Artifacts)
+	if respBody.RotateToken {
+		a.logger.Info("token rotation requested by server")
+		if err := a.rotateToken(info); err != nil {
+			a.logger.WithError(err).Error("rotate token failed")
+		}
+	}
+
 	return nil
 }
 
@@ -209,48 +243,247 @@ func (a *Device) syncJobsFromMedadata() error {
 		metaJobs[job.Uuid] = job
 	}
 
+	now := time.Now()
 	for _, e := range a.executors {
 		job := e.Job()
 		if metaJob, ok := metaJobs[job.Uuid]; !ok {
 			a.logger.Infof("job %s deleted, stop the executor", job.Uuid)
 			e.Stop()
-			delete(a.executors, job.Uuid)
+			a.removeExecutor(job.Uuid)
+			a.supervisor.forget(job.Uuid)
+			a.teardown(e)
+		} else if metaJob.UpdateTime != job.UpdateTime && onlyControlChanged(job, metaJob) {
+			if metaJob.Paused && !job.Paused {
+				a.logger.Infof("job %s paused", job.Uuid)
+			} else if !metaJob.Paused && job.Paused {
+				a.logger.Infof("job %s resumed", job.Uuid)
+			}
+			// executors never read these fields, so updating them in place is
+			// safe, syncPause applies the pause flag and the schedule
+			job.UpdateTime = metaJob.UpdateTime
+			job.Paused = metaJob.Paused
+			job.Priority = metaJob.Priority
+			job.MaxFps = metaJob.MaxFps
+			job.Schedule = metaJob.Schedule
+			job.ResultFilter = metaJob.ResultFilter
 		} else if metaJob.UpdateTime != job.UpdateTime {
 			a.logger.Infof("job %s updated, stop the executor", job.Uuid)
 			e.Stop()
-			delete(a.executors, job.Uuid)
+			a.removeExecutor(job.Uuid)
+			a.supervisor.forget(job.Uuid)
+		} else if status := e.Status(); status == model.ExectorStatusFailed || status == model.ExectorStatusFinished {
+			e.Stop()
+			a.removeExecutor(job.Uuid)
+			backoff := a.supervisor.failed(job.Uuid, "executor "+status.String(), now)
+			a.logger.Warnf("job %s executor %s, restart in %s", job.Uuid, status, backoff)
+		} else {
+			a.supervisor.healthy(job.Uuid, now)
+		}
+	}
+
+	// jobs holding a slot: running executors and admitted jobs waiting out
+	// their restart backoff
+	active := make(map[model.JobKind]int)
+	for _, e := range a.executors {
+		active[e.Job().Kind]++
+	}
+	for _, job := range jobs {
+		if _, ok := a.executors[job.Uuid]; !ok && job.Enabled && !a.supervisor.canStart(job.Uuid, now) {
+			active[job.Kind]++
 		}
 	}
+	totalActive := 0
+	for _, n := range active {
+		totalActive += n
+	}
+	maxExecutors := a.maxExecutors()
 
-	for _, job := range metaJobs {
+	// admit by priority, then the oldest jobs first, so the queue order is
+	// stable. Running executors are never preempted to avoid thrashing.
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Priority != jobs[j].Priority {
+			return jobs[i].Priority > jobs[j].Priority
+		}
+		if jobs[i].Id != jobs[j].Id {
+			return jobs[i].Id < jobs[j].Id
+		}
+		return jobs[i].Uuid < jobs[j].Uuid
+	})
+
+	pending := make(map[string]model.JobKind)
+	for _, job := range jobs {
 		if !job.Enabled {
 			continue
 		}
-		if _, ok := a.executors[job.Uuid]; !ok {
-			a.logger.Infof("job %s created, start the executor", job.Uuid)
-			newExector, err := a.newExector(job)
-			if err != nil {
-				a.logger.WithError(err).Errorf("create job %s executor failed", job.Uuid)
-				continue
+		if _, ok := a.executors[job.Uuid]; ok || !a.supervisor.canStart(job.Uuid, now) {
+			continue
+		}
+		if limit := a.jobLimit(job.Kind); limit > 0 && active[job.Kind] >= limit {
+			if _, ok := a.pending[job.Uuid]; !ok {
+				a.logger.Infof("job %s pending, %d %s jobs already running", job.Uuid, active[job.Kind], job.Kind)
 			}
-			if err := newExector.Start(); err != nil {
-				a.logger.WithError(err).Errorf("start job %s executor failed", job.Uuid)
-			} else {
-				a.executors[job.Uuid] = newExector
+			pending[job.Uuid] = job.Kind
+			continue
+		}
+		if maxExecutors > 0 && totalActive >= maxExecutors {
+			if _, ok := a.pending[job.Uuid]; !ok {
+				a.logger.Infof("job %s pending, %d executors already running", job.Uuid, totalActive)
 			}
+			pending[job.Uuid] = job.Kind
+			continue
+		}
+		// a job that fails to start keeps its slot while backing off
+		active[job.Kind]++
+		totalActive++
+
+		a.logger.Infof("job %s created, start the executor", job.Uuid)
+		newExector, err := a.newExector(job)
+		if err != nil {
+			backoff := a.supervisor.failed(job.Uuid, err.Error(), now)
+			a.logger.WithError(err).Errorf("create job %s executor failed, retry in %s", job.Uuid, backoff)
+			continue
+		}
+		if err := newExector.Start(); err != nil {
+			backoff := a.supervisor.failed(job.Uuid, err.Error(), now)
+			a.logger.WithError(err).Errorf("start job %s executor failed, retry in %s", job.Uuid, backoff)
+		} else {
+			a.executors[job.Uuid] = newExector
+			a.supervisor.started(job.Uuid, now)
 		}
 	}
+	a.pending = pending
+	a.syncPause(now)
 
 	return nil
 }
 
-func (a *Device) newExector(job *dao.JobSpec) (exector.Executor, error) {
-	switch job.Kind {
+// syncPause pauses the executors of paused jobs and of jobs disarmed by their
+// schedule, and resumes the others. Schedules are evaluated locally so that
+// executors are armed and disarmed on time without the server.
+func (a *Device) syncPause(now time.Time) {
+	for uuid, e := range a.executors {
+		job := e.Job()
+		disarmed := !job.Schedule.Armed(now)
+		if disarmed != a.disarmed[uuid] {
+			if disarmed {
+				a.logger.Infof("job %s disarmed by schedule", uuid)
+			} else {
+				a.logger.Infof("job %s armed by schedule", uuid)
+			}
+			a.disarmed[uuid] = disarmed
+		}
+
+		paused := job.Paused || disarmed
+		if paused == a.paused[uuid] {
+			continue
+		}
+		if paused {
+			e.Pause()
+		} else {
+			e.Resume()
+		}
+		a.paused[uuid] = paused
+	}
+}
+
+// removeExecutor forgets a stopped executor, a new executor of the job
+// starts resumed.
+func (a *Device) removeExecutor(jobUuid string) {
+	delete(a.executors, jobUuid)
+	delete(a.paused, jobUuid)
+	delete(a.disarmed, jobUuid)
+}
+
+// executorStatus returns the status of an executor, reporting a paused
+// executor of a disarmed job as disarmed.
+func (a *Device) executorStatus(jobUuid string, e exector.Executor) model.ExectorStatus {
+	status := e.Status()
+	if status == model.ExectorStatusPaused && a.disarmed[jobUuid] {
+		return model.ExectorStatusDisarmed
+	}
+	return status
+}
+
+// teardown flushes and removes the work dir of a deleted job in the
+// background, the janitor leaves the dir alone until it is done.
+func (a *Device) teardown(e exector.Executor) {
+	jobUuid := e.Job().Uuid
+	a.teardowns.Store(jobUuid, struct{}{})
+	go func() {
+		defer a.teardowns.Delete(jobUuid)
+		ctx, cancel := context.WithTimeout(a.ctx, jobTeardownTimeout)
+		defer cancel()
+		if err := e.Cleanup(ctx); err != nil {
+			a.logger.WithError(err).Warnf("clean up job %s work dir failed", jobUuid)
+			return
+		}
+		a.logger.Infof("job %s work dir removed", jobUuid)
+	}()
+}
+
+// onlyControlChanged reports whether newJob differs from oldJob only in the
+// fields that do not affect a running executor, i.e. its pause flag,
+// priority, max fps and arming schedule, so the executor can be kept instead of
+// restarted.
+func onlyControlChanged(oldJob, newJob *dao.JobSpec) bool {
+	normalize := func(job dao.JobSpec) []byte {
+		job.Paused = false
+		job.Priority = 0
+		job.MaxFps = 0
+		job.Schedule = nil
+		job.UpdateTime = ""
+		job.Status = ""
+		job.RestartCount = 0
+		job.LastError = ""
+		job.HealthReason = ""
+		// talk-downs are dispatched by the server as separate tasks
+		job.TalkDown = nil
+		// result filters are evaluated by the server consumer
+		job.ResultFilter = nil
+		// device specs carry the ever-changing ping time
+		job.Device = nil
+		job.Camera.BindDevice = nil
+		data, _ := json.Marshal(job)
+		return data
+	}
+	return bytes.Equal(normalize(*oldJob), normalize(*newJob))
+}
+
+// maxExecutors returns the maximum number of concurrent executors, the limit
+// set for this device on the server takes precedence over the local config.
+// Zero means unlimited.
+func (a *Device) maxExecutors() int {
+	if a.serverMaxExecutors > 0 {
+		return a.serverMaxExecutors
+	}
+	return a.conf.Concurrency.MaxExecutors
+}
+
+// jobLimit returns the maximum number of concurrent jobs of the given kind,
+// zero if unlimited.
+func (a *Device) jobLimit(kind model.JobKind) int {
+	switch kind {
 	case model.JobKindDetect:
-		return exector.NewDetector(a.conf, a.deviceInfo, a.ctx, a.minioCli, a.nsqProducer, job)
+		return a.conf.Concurrency.MaxDetectJobs
 	case model.JobKindVideoSegment:
-		return exector.NewVideoSegmentor(a.conf, a.deviceInfo, a.ctx, a.minioCli, a.nsqProducer, job)
-	default:
-		return nil, fmt.Errorf("unknown job kind %s", job.Kind)
+		return a.conf.Concurrency.MaxVideoSegmentJobs
+	}
+	for _, p := range a.conf.Plugins {
+		if model.JobKind(p.Kind) == kind {
+			return p.MaxJobs
+		}
 	}
+	return 0
+}
+
+func (a *Device) newExector(job *dao.JobSpec) (exector.Executor, error) {
+	return exector.New(&exector.Env{
+		Conf:        a.conf,
+		DeviceInfo:  a.deviceInfo,
+		Ctx:         a.ctx,
+		MinioCli:    a.minioCli,
+		NsqProducer: a.nsqProducer,
+		Sensors:     a.sensors,
+		Signer:      a.signer,
+	}, job)
 }
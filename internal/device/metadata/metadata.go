@@ -1,8 +1,11 @@
 package metadata
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strconv"
 
 	badger "github.com/dgraph-io/badger/v4"
@@ -14,6 +17,7 @@ import (
 const (
 	deviceInfoKey    = "device_info"
 	lastFetchTimeKey = "last_fetch_time"
+	signingKeyKey    = "signing_key"
 	jobKeyPrefix     = "job:"
 )
 
@@ -162,6 +166,36 @@ func (m *MetadataDB) UpdateDeviceInfo(new *DeviceInfo) error {
 	})
 }
 
+// GetOrCreateSigningKey returns the key the device signs its messages with,
+// generating it on first use. The key survives re-registrations.
+func (m *MetadataDB) GetOrCreateSigningKey() (ed25519.PrivateKey, error) {
+	var key ed25519.PrivateKey
+	err := m.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(signingKeyKey))
+		if err == nil {
+			seed, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			} else if len(seed) != ed25519.SeedSize {
+				return fmt.Errorf("invalid signing key of %d bytes", len(seed))
+			}
+			key = ed25519.NewKeyFromSeed(seed)
+			return nil
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		return txn.Set([]byte(signingKeyKey), key.Seed())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
 func (m *MetadataDB) GetLastFetchTime() (int64, error) {
 	t, err := m.Get([]byte(lastFetchTimeKey))
 	if err != nil {
@@ -0,0 +1,88 @@
+package device
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"lumina/pkg/metrics"
+)
+
+// writeDeviceMetrics writes the metrics of the executors of the debug state.
+func writeDeviceMetrics(w *metrics.Writer, state *DebugState) {
+	type metric struct {
+		name, kind, help string
+		value            func(e *DebugExecutor) float64
+	}
+	w.Family("lumina_device_inference_seconds", "summary", "Latency of the model inferences by job.")
+	for i := range state.Executors {
+		e := &state.Executors[i]
+		labels := []string{"job_uuid", e.JobUuid, "kind", string(e.Kind)}
+		w.Sample("lumina_device_inference_seconds_sum", e.Metrics.InferenceSeconds, labels...)
+		w.Sample("lumina_device_inference_seconds_count", float64(e.Metrics.Inferences), labels...)
+	}
+
+	perJob := []metric{
+		{"lumina_device_frames_read_total", "counter", "Frames read from the stream by job.",
+			func(e *DebugExecutor) float64 { return float64(e.Metrics.FramesRead) }},
+		{"lumina_device_frames_processed_total", "counter", "Frames processed by job.",
+			func(e *DebugExecutor) float64 { return float64(e.Metrics.FramesProcessed) }},
+		{"lumina_device_frames_dropped_total", "counter", "Frames dropped by job, e.g. while inference lags behind the stream.",
+			func(e *DebugExecutor) float64 { return float64(e.Metrics.FramesDropped) }},
+		{"lumina_device_frame_rate", "gauge", "Frames processed per second by job.",
+			func(e *DebugExecutor) float64 { return e.Metrics.FrameRate }},
+		{"lumina_device_upload_queue", "gauge", "Results waiting for upload by job, as found by the last upload pass.",
+			func(e *DebugExecutor) float64 { return float64(e.Metrics.UploadQueue) }},
+		{"lumina_device_executor_restarts_total", "counter", "Restarts of the executor of the job after it failed, e.g. when ffmpeg exited.",
+			func(e *DebugExecutor) float64 { return float64(e.Restarts) }},
+	}
+	for _, m := range perJob {
+		w.Family(m.name, m.kind, m.help)
+		for i := range state.Executors {
+			e := &state.Executors[i]
+			w.Sample(m.name, m.value(e), "job_uuid", e.JobUuid, "kind", string(e.Kind))
+		}
+	}
+}
+
+// startMetricsServer serves the executor metrics to Prometheus on /metrics.
+// Unlike the debug server it may listen on any address, it exposes no
+// profiling or job details besides the job uuids.
+func (a *Device) startMetricsServer() (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		state := a.debugState.Load()
+		if state == nil {
+			state = &DebugState{}
+		}
+		var mw metrics.Writer
+		writeDeviceMetrics(&mw, state)
+		w.Header().Set("Content-Type", metrics.ContentType)
+		_, _ = w.Write(mw.Bytes())
+	})
+
+	listener, err := net.Listen("tcp", a.conf.Metrics.Addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			a.logger.WithError(err).Error("metrics server stopped")
+		}
+	}()
+	a.logger.Infof("metrics server listening on http://%s/metrics", listener.Addr())
+	return srv, nil
+}
+
+func (a *Device) stopMetricsServer(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		a.logger.WithError(err).Warn("shutdown metrics server")
+	}
+}
@@ -0,0 +1,218 @@
+// Package occupancy tracks the detected objects across frames and counts
+// them in the zones of the frame, e.g. the people in a shop or a hall.
+package occupancy
+
+import (
+	"sort"
+
+	"lumina/internal/dao"
+)
+
+const (
+	// minIoU is the overlap a box needs with a track to continue it
+	minIoU = 0.3
+	// maxMissed is the number of frames a track survives without a box
+	maxMissed = 5
+	// wholeFrame names the zone counted when the job has no zone
+	wholeFrame = "all"
+)
+
+type point struct {
+	x, y float64
+}
+
+type zone struct {
+	name    string
+	polygon []point
+
+	count int
+	max   int
+	in    int
+	out   int
+}
+
+// contains reports whether p is inside the polygon of z, using ray casting.
+func (z *zone) contains(p point) bool {
+	inside := false
+	n := len(z.polygon)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := z.polygon[i], z.polygon[j]
+		if (a.y > p.y) != (b.y > p.y) && p.x < (b.x-a.x)*(p.y-a.y)/(b.y-a.y)+a.x {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+type track struct {
+	id     int
+	box    *dao.DetectionBox
+	missed int
+	// inside[i] tells whether the track is in the i-th zone
+	inside []bool
+}
+
+// Counter counts the objects of some labels in zones, an object entering a
+// zone counts as in and leaving it, or being lost while in it, as out.
+type Counter struct {
+	labels map[string]struct{}
+	zones  []*zone
+	tracks []*track
+	nextId int
+}
+
+func NewCounter(opts *dao.OccupancyOptions) *Counter {
+	c := &Counter{labels: opts.GetLabelSet()}
+	for _, z := range opts.Zones {
+		polygon := make([]point, len(z.Polygon))
+		for i, p := range z.Polygon {
+			polygon[i] = point{x: p[0], y: p[1]}
+		}
+		c.zones = append(c.zones, &zone{name: z.Name, polygon: polygon})
+	}
+	if len(c.zones) == 0 {
+		c.zones = append(c.zones, &zone{
+			name:    wholeFrame,
+			polygon: []point{{0, 0}, {1, 0}, {1, 1}, {0, 1}},
+		})
+	}
+	return c
+}
+
+// Update continues the tracks with the boxes detected in a frame of the
+// given size and updates the zone counters.
+func (c *Counter) Update(boxes []*dao.DetectionBox, width, height int) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	var dets []*dao.DetectionBox
+	for _, b := range boxes {
+		if b == nil {
+			continue
+		}
+		if _, ok := c.labels[b.Label]; len(c.labels) > 0 && !ok {
+			continue
+		}
+		dets = append(dets, b)
+	}
+
+	matched := c.match(dets)
+
+	alive := c.tracks[:0]
+	for _, t := range c.tracks {
+		if t.missed > maxMissed {
+			// lost objects leave the zones they were in
+			for i, in := range t.inside {
+				if in {
+					c.zones[i].out++
+				}
+			}
+			continue
+		}
+		alive = append(alive, t)
+	}
+	c.tracks = alive
+
+	for i, b := range dets {
+		if matched[i] {
+			continue
+		}
+		c.nextId++
+		c.tracks = append(c.tracks, &track{id: c.nextId, box: b, inside: make([]bool, len(c.zones))})
+	}
+
+	for _, z := range c.zones {
+		z.count = 0
+	}
+	for _, t := range c.tracks {
+		p := point{
+			x: float64(t.box.X1+t.box.X2) / 2 / float64(width),
+			y: float64(t.box.Y2) / float64(height),
+		}
+		for i, z := range c.zones {
+			in := z.contains(p)
+			switch {
+			case in && !t.inside[i]:
+				z.in++
+			case !in && t.inside[i]:
+				z.out++
+			}
+			t.inside[i] = in
+			if in {
+				z.count++
+			}
+		}
+	}
+	for _, z := range c.zones {
+		if z.count > z.max {
+			z.max = z.count
+		}
+	}
+}
+
+// match assigns the boxes to the tracks greedily by overlap, it returns
+// which boxes continue a track.
+func (c *Counter) match(dets []*dao.DetectionBox) []bool {
+	type pair struct {
+		track, det int
+		iou        float64
+	}
+	var pairs []pair
+	for i, t := range c.tracks {
+		for j, d := range dets {
+			if d.Label != t.box.Label {
+				continue
+			}
+			if v := iou(t.box, d); v >= minIoU {
+				pairs = append(pairs, pair{track: i, det: j, iou: v})
+			}
+		}
+	}
+	sort.Slice(pairs, func(a, b int) bool { return pairs[a].iou > pairs[b].iou })
+
+	trackMatched := make([]bool, len(c.tracks))
+	detMatched := make([]bool, len(dets))
+	for _, p := range pairs {
+		if trackMatched[p.track] || detMatched[p.det] {
+			continue
+		}
+		trackMatched[p.track], detMatched[p.det] = true, true
+		c.tracks[p.track].box = dets[p.det]
+		c.tracks[p.track].missed = 0
+	}
+	for i, t := range c.tracks {
+		if !trackMatched[i] {
+			t.missed++
+		}
+	}
+	return detMatched
+}
+
+func iou(a, b *dao.DetectionBox) float64 {
+	x1, y1 := max(a.X1, b.X1), max(a.Y1, b.Y1)
+	x2, y2 := min(a.X2, b.X2), min(a.Y2, b.Y2)
+	if x2 <= x1 || y2 <= y1 {
+		return 0
+	}
+	inter := float64((x2 - x1) * (y2 - y1))
+	areaA := float64((a.X2 - a.X1) * (a.Y2 - a.Y1))
+	areaB := float64((b.X2 - b.X1) * (b.Y2 - b.Y1))
+	return inter / (areaA + areaB - inter)
+}
+
+// Report returns the occupancy of every zone since the previous report and
+// starts a new interval.
+func (c *Counter) Report() []*dao.ZoneOccupancy {
+	res := make([]*dao.ZoneOccupancy, 0, len(c.zones))
+	for _, z := range c.zones {
+		res = append(res, &dao.ZoneOccupancy{
+			Zone:  z.name,
+			Count: z.count,
+			Max:   z.max,
+			In:    z.in,
+			Out:   z.out,
+		})
+		z.max, z.in, z.out = z.count, 0, 0
+	}
+	return res
+}
@@ -0,0 +1,201 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"lumina/internal/dao"
+	"lumina/internal/device/metadata"
+	"lumina/pkg/onvif"
+)
+
+const (
+	fetchOnvifDiscoverTasksPath   = "/api/v1/device/onvif-discover-tasks"
+	reportOnvifDiscoverResultPath = "/api/v1/device/onvif-discover-result"
+	// cameras found by a scan are queried concurrently, bounded by this
+	onvifQueryConcurrency = 8
+	onvifQueryTimeout     = 8 * time.Second
+)
+
+func (a *Device) fetchOnvifDiscoverTasksFromServer(info *metadata.DeviceInfo) (*dao.ListOnvifDiscoverTasksResponse, error) {
+	a.logger.Debugf("fetch onvif discover tasks")
+
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodGet, a.conf.LuminaServerAddr+fetchOnvifDiscoverTasksPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+
+	var respBody dao.ListOnvifDiscoverTasksResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, err
+	}
+
+	return &respBody, nil
+}
+
+func (a *Device) syncOnvifDiscoverTasksFromServer() error {
+	info, err := a.db.GetDeviceInfo()
+	if err != nil {
+		return err
+	} else if info == nil || info.Uuid == nil {
+		return errors.New("device Id is nil, please register device")
+	}
+
+	resp, err := a.fetchOnvifDiscoverTasksFromServer(info)
+	if err != nil {
+		return err
+	}
+
+	a.onvifMu.Lock()
+	defer a.onvifMu.Unlock()
+	for _, task := range resp.Items {
+		if task.Expired() {
+			continue
+		}
+		if _, running := a.onvifTasks[task.TaskUuid]; running {
+			continue
+		}
+		a.logger.Infof("start onvif discover task, task uuid: %s, subnet: %s", task.TaskUuid, task.Subnet)
+		a.onvifTasks[task.TaskUuid] = struct{}{}
+		go a.runOnvifDiscoverTask(info, task)
+	}
+
+	return nil
+}
+
+func (a *Device) runOnvifDiscoverTask(info *metadata.DeviceInfo, task dao.OnvifDiscoverTask) {
+	defer func() {
+		a.onvifMu.Lock()
+		delete(a.onvifTasks, task.TaskUuid)
+		a.onvifMu.Unlock()
+	}()
+
+	logger := a.logger.WithField("taskUuid", task.TaskUuid)
+	result := &dao.OnvifDiscoverResult{TaskUuid: task.TaskUuid}
+
+	var subnet *net.IPNet
+	if task.Subnet != "" {
+		var err error
+		if _, subnet, err = net.ParseCIDR(task.Subnet); err != nil {
+			result.Error = err.Error()
+		}
+	}
+
+	if result.Error == "" {
+		found, err := onvif.Discover(a.ctx, subnet, time.Duration(task.Timeout)*time.Second)
+		if err != nil {
+			logger.WithError(err).Errorf("onvif discover failed")
+			result.Error = err.Error()
+		} else {
+			logger.Infof("onvif discover found %d cameras", len(found))
+			result.Cameras = a.queryOnvifCameras(found, task.Username, task.Password)
+		}
+	}
+
+	if err := a.reportOnvifDiscoverResult(info, result); err != nil {
+		logger.WithError(err).Errorf("report onvif discover result failed")
+	}
+}
+
+// queryOnvifCameras reads the device information and main stream uri of the
+// discovered cameras, a camera that fails keeps its error in the result.
+func (a *Device) queryOnvifCameras(found []onvif.Discovered, username, password string) []dao.OnvifCamera {
+	cameras := make([]dao.OnvifCamera, len(found))
+	sem := make(chan struct{}, onvifQueryConcurrency)
+	var wg sync.WaitGroup
+	for i, d := range found {
+		cameras[i] = dao.OnvifCamera{
+			XAddr:     d.XAddr,
+			Ip:        d.Host,
+			OnvifPort: d.Port,
+			Name:      d.Name,
+			Model:     d.Hardware,
+		}
+		wg.Add(1)
+		go func(cam *dao.OnvifCamera) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			ctx, cancel := context.WithTimeout(a.ctx, onvifQueryTimeout)
+			defer cancel()
+			if err := queryOnvifCamera(ctx, cam, username, password); err != nil {
+				cam.Error = err.Error()
+			}
+		}(&cameras[i])
+	}
+	wg.Wait()
+	return cameras
+}
+
+func queryOnvifCamera(ctx context.Context, cam *dao.OnvifCamera, username, password string) error {
+	client := onvif.NewClient(cam.XAddr, username, password)
+	devInfo, err := client.GetDeviceInformation(ctx)
+	if err != nil {
+		return fmt.Errorf("get device information: %w", err)
+	}
+	cam.Manufacturer = devInfo.Manufacturer
+	cam.Model = devInfo.Model
+
+	caps, err := client.GetCapabilities(ctx)
+	if err != nil {
+		return fmt.Errorf("get capabilities: %w", err)
+	}
+	profiles, err := client.GetProfiles(ctx, caps.MediaXAddr)
+	if err != nil {
+		return fmt.Errorf("get profiles: %w", err)
+	} else if len(profiles) == 0 {
+		return errors.New("camera has no media profile")
+	}
+	for _, p := range profiles {
+		if p.PTZConfiguration != nil && caps.PTZXAddr != "" {
+			cam.Ptz = true
+		}
+	}
+	// the first profile is the main stream by convention
+	uri, err := client.GetStreamUri(ctx, caps.MediaXAddr, profiles[0].Token)
+	if err != nil {
+		return fmt.Errorf("get stream uri: %w", err)
+	}
+	cam.StreamUri = uri
+	return nil
+}
+
+func (a *Device) reportOnvifDiscoverResult(info *metadata.DeviceInfo, result *dao.OnvifDiscoverResult) error {
+	body, _ := json.Marshal(result)
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.conf.LuminaServerAddr+reportOnvifDiscoverResultPath, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -14,6 +14,7 @@ import (
 
 	"lumina/internal/dao"
 	"lumina/internal/device/metadata"
+	"lumina/internal/model"
 )
 
 const fetchPreviewTasksPath = "/api/v1/device/preview-tasks"
@@ -63,7 +64,7 @@ func (a *Device) fetchPreviewTasksFromServer(info *metadata.DeviceInfo) (*dao.Li
 	return &respBody, nil
 }
 
-func (a *Device) syncPreviewTasksFromServer() error {
+func (a *Device) expirePreviewJobs() {
 	for _, job := range a.previewJobs {
 		if job.Task.Expired() {
 			a.logger.Infof("preview task expired, task uuid: %s", job.Task.TaskUuid)
@@ -71,6 +72,10 @@ func (a *Device) syncPreviewTasksFromServer() error {
 			delete(a.previewJobs, job.Task.TaskUuid)
 		}
 	}
+}
+
+func (a *Device) syncPreviewTasksFromServer() error {
+	a.expirePreviewJobs()
 
 	info, err := a.db.GetDeviceInfo()
 	if err != nil {
@@ -131,7 +136,7 @@ func (a *Device) startPreviewJob(ctx context.Context, task *dao.PreviewTask) *Pr
 			}
 
 			// 根据编码选择是否转码
-			args := []string{"-i", task.PullAddr, "-an"}
+			args := []string{"-i", task.PullAddr}
 			switch strings.ToLower(codec) {
 			case "h264":
 				args = append(args, "-c:v", "copy")
@@ -141,7 +146,17 @@ func (a *Device) startPreviewJob(ctx context.Context, task *dao.PreviewTask) *Pr
 				// 未知编码时默认尝试直接复制
 				args = append(args, "-c:v", "copy")
 			}
-			args = append(args, "-f", "flv", task.PushAddr)
+			args = append(args, a.previewAudioArgs(job.ctx, task)...)
+			if task.Mode == model.PreviewModeWebRTC {
+				// WebRTC 推流使用 WHIP 协议，需要 ffmpeg 8.0 及以上版本
+				if args[len(args)-1] != "copy" {
+					// 浏览器不支持 B 帧
+					args = append(args, "-profile:v", "baseline", "-bf", "0")
+				}
+				args = append(args, "-f", "whip", task.PushAddr)
+			} else {
+				args = append(args, "-f", "flv", task.PushAddr)
+			}
 
 			cmd := exec.CommandContext(job.ctx, "ffmpeg", args...)
 			err = cmd.Run()
@@ -156,11 +171,49 @@ func (a *Device) startPreviewJob(ctx context.Context, task *dao.PreviewTask) *Pr
 	return job
 }
 
+// previewAudioArgs 返回音频相关的 ffmpeg 参数，未开启音频或输入没有音轨时丢弃音频
+func (a *Device) previewAudioArgs(ctx context.Context, task *dao.PreviewTask) []string {
+	if !task.Audio {
+		return []string{"-an"}
+	}
+	codec, err := probeAudioCodec(ctx, task.PullAddr)
+	if err != nil {
+		a.logger.WithField("taskUuid", task.TaskUuid).Warnf("ffprobe audio failed, drop audio, err: %v", err)
+		return []string{"-an"}
+	} else if codec == "" {
+		a.logger.WithField("taskUuid", task.TaskUuid).Info("input has no audio stream, drop audio")
+		return []string{"-an"}
+	}
+	a.logger.WithField("taskUuid", task.TaskUuid).Infof("detected input audio codec: %s", codec)
+
+	if task.Mode == model.PreviewModeWebRTC {
+		// WebRTC 只支持 Opus
+		if codec == "opus" {
+			return []string{"-c:a", "copy"}
+		}
+		return []string{"-c:a", "libopus", "-ar", "48000", "-ac", "2"}
+	}
+	// FLV 推流使用 AAC
+	if codec == "aac" {
+		return []string{"-c:a", "copy"}
+	}
+	return []string{"-c:a", "aac", "-ar", "44100"}
+}
+
 // 使用 ffprobe 探测视频编码
 func probeVideoCodec(ctx context.Context, input string) (string, error) {
+	return probeCodec(ctx, input, "v:0")
+}
+
+// 使用 ffprobe 探测音频编码，没有音轨时返回空字符串
+func probeAudioCodec(ctx context.Context, input string) (string, error) {
+	return probeCodec(ctx, input, "a:0")
+}
+
+func probeCodec(ctx context.Context, input, stream string) (string, error) {
 	cmd := exec.CommandContext(ctx, "ffprobe",
 		"-v", "error",
-		"-select_streams", "v:0",
+		"-select_streams", stream,
 		"-show_entries", "stream=codec_name",
 		"-of", "default=noprint_wrappers=1:nokey=1",
 		input,
@@ -0,0 +1,39 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"lumina/internal/device/config"
+)
+
+type gpioSensor struct {
+	conf config.GPIOSensorConfig
+}
+
+func newGPIOSensor(conf config.GPIOSensorConfig) (*gpioSensor, error) {
+	if conf.Path == "" {
+		return nil, errors.New("gpio path is empty")
+	}
+	return &gpioSensor{conf: conf}, nil
+}
+
+func (s *gpioSensor) poll(ctx context.Context) (any, error) {
+	data, err := os.ReadFile(s.conf.Path)
+	if err != nil {
+		return nil, err
+	}
+	var high bool
+	switch v := strings.TrimSpace(string(data)); v {
+	case "1":
+		high = true
+	case "0":
+		high = false
+	default:
+		return nil, fmt.Errorf("unexpected gpio value %q", v)
+	}
+	return high != s.conf.ActiveLow, nil
+}
@@ -0,0 +1,63 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"lumina/internal/device/config"
+	"lumina/pkg/modbus"
+)
+
+type modbusSensor struct {
+	conf   config.ModbusSensorConfig
+	client *modbus.Client
+}
+
+func newModbusSensor(conf config.ModbusSensorConfig) (*modbusSensor, error) {
+	if conf.Addr == "" {
+		return nil, errors.New("modbus addr is empty")
+	}
+	if conf.Words == 0 {
+		conf.Words = 1
+	} else if conf.Words != 1 && conf.Words != 2 {
+		return nil, fmt.Errorf("modbus words must be 1 or 2, got %d", conf.Words)
+	}
+	if conf.Register < 0 || conf.Register > 0xffff {
+		return nil, fmt.Errorf("invalid modbus register %d", conf.Register)
+	}
+	if conf.Scale == 0 {
+		conf.Scale = 1
+	}
+	return &modbusSensor{conf: conf, client: modbus.NewClient(conf.Addr, 0)}, nil
+}
+
+func (s *modbusSensor) poll(ctx context.Context) (any, error) {
+	function := byte(modbus.FuncReadHoldingRegisters)
+	if s.conf.Input {
+		function = modbus.FuncReadInputRegisters
+	}
+	regs, err := s.client.ReadRegisters(ctx, byte(s.conf.UnitId), function, uint16(s.conf.Register), uint16(s.conf.Words))
+	if err != nil {
+		return nil, err
+	}
+
+	var raw float64
+	if s.conf.Words == 2 {
+		v := uint32(regs[0])<<16 | uint32(regs[1])
+		if s.conf.Signed {
+			raw = float64(int32(v))
+		} else {
+			raw = float64(v)
+		}
+	} else if s.conf.Signed {
+		raw = float64(int16(regs[0]))
+	} else {
+		raw = float64(regs[0])
+	}
+	return raw*s.conf.Scale + s.conf.Offset, nil
+}
+
+func (s *modbusSensor) Close() error {
+	return s.client.Close()
+}
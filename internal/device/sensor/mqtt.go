@@ -0,0 +1,77 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/device/config"
+	"lumina/pkg/mqtt"
+)
+
+type mqttSensor struct {
+	name string
+	conf config.MQTTSensorConfig
+}
+
+func newMQTTSensor(name string, conf config.MQTTSensorConfig) (*mqttSensor, error) {
+	if conf.Broker == "" || conf.Topic == "" {
+		return nil, errors.New("mqtt broker or topic is empty")
+	}
+	return &mqttSensor{name: name, conf: conf}, nil
+}
+
+func (s *mqttSensor) run(ctx context.Context, logger *logrus.Entry, set func(value any)) {
+	opts := mqtt.Options{
+		ClientId: "lumina-" + s.name,
+		Username: s.conf.Username,
+		Password: s.conf.Password,
+	}
+	for {
+		err := mqtt.Subscribe(ctx, s.conf.Broker, opts, []string{s.conf.Topic}, func(topic string, payload []byte) {
+			value, err := s.parse(payload)
+			if err != nil {
+				logger.WithError(err).Warnf("invalid payload on %s", topic)
+				return
+			}
+			set(value)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		logger.WithError(err).Warn("mqtt subscription lost, retrying")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// parse returns the payload as json, or as a string if it is not json.
+func (s *mqttSensor) parse(payload []byte) (any, error) {
+	var value any
+	if err := json.Unmarshal(payload, &value); err != nil {
+		if s.conf.Field != "" {
+			return nil, err
+		}
+		return strings.TrimSpace(string(payload)), nil
+	}
+	if s.conf.Field == "" {
+		return value, nil
+	}
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("payload is not a json object")
+	}
+	field, ok := obj[s.conf.Field]
+	if !ok {
+		return nil, errors.New("field " + s.conf.Field + " not found")
+	}
+	return field, nil
+}
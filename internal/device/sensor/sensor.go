@@ -0,0 +1,190 @@
+// Package sensor keeps the latest readings of the external sensors of a
+// device, e.g. a thermometer or a door contact, so that they can be attached
+// to the messages published by the executors.
+package sensor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/device/config"
+	"lumina/pkg/log"
+)
+
+const (
+	KindModbus = "modbus"
+	KindMQTT   = "mqtt"
+	KindGPIO   = "gpio"
+
+	defaultInterval = 5 * time.Second
+	retryInterval   = 5 * time.Second
+)
+
+type Reading struct {
+	Value any
+	Time  time.Time
+}
+
+// source produces the readings of one sensor until ctx is done.
+type source interface {
+	run(ctx context.Context, logger *logrus.Entry, set func(value any))
+}
+
+// poller is a source read at a fixed interval.
+type poller interface {
+	poll(ctx context.Context) (any, error)
+}
+
+type sensor struct {
+	conf   config.SensorConfig
+	source source
+}
+
+// Hub runs the configured sensors and keeps their latest readings, a nil Hub
+// has no reading.
+type Hub struct {
+	sensors []sensor
+	logger  *logrus.Entry
+
+	mu       sync.RWMutex
+	readings map[string]Reading
+}
+
+func NewHub(ctx context.Context, confs []config.SensorConfig) (*Hub, error) {
+	h := &Hub{
+		logger:   log.GetLogger(ctx).WithField("component", "sensor"),
+		readings: make(map[string]Reading),
+	}
+	names := make(map[string]struct{})
+	for _, conf := range confs {
+		if conf.Name == "" {
+			return nil, errors.New("sensor name is empty")
+		}
+		if _, ok := names[conf.Name]; ok {
+			return nil, fmt.Errorf("duplicate sensor %s", conf.Name)
+		}
+		names[conf.Name] = struct{}{}
+
+		src, err := newSource(conf)
+		if err != nil {
+			return nil, fmt.Errorf("sensor %s: %w", conf.Name, err)
+		}
+		h.sensors = append(h.sensors, sensor{conf: conf, source: src})
+	}
+	return h, nil
+}
+
+func newSource(conf config.SensorConfig) (source, error) {
+	interval := conf.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	switch conf.Kind {
+	case KindModbus:
+		p, err := newModbusSensor(conf.Modbus)
+		if err != nil {
+			return nil, err
+		}
+		return &polling{poller: p, interval: interval}, nil
+	case KindGPIO:
+		p, err := newGPIOSensor(conf.GPIO)
+		if err != nil {
+			return nil, err
+		}
+		return &polling{poller: p, interval: interval}, nil
+	case KindMQTT:
+		return newMQTTSensor(conf.Name, conf.MQTT)
+	default:
+		return nil, fmt.Errorf("unknown sensor kind %q", conf.Kind)
+	}
+}
+
+// Run reads the sensors until ctx is done.
+func (h *Hub) Run(ctx context.Context) {
+	if h == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, s := range h.sensors {
+		wg.Add(1)
+		go func(s sensor) {
+			defer wg.Done()
+			logger := h.logger.WithField("sensor", s.conf.Name)
+			logger.Infof("start %s sensor", s.conf.Kind)
+			s.source.run(ctx, logger, func(value any) {
+				h.set(s.conf.Name, value)
+			})
+		}(s)
+	}
+	wg.Wait()
+}
+
+func (h *Hub) set(name string, value any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readings[name] = Reading{Value: value, Time: time.Now()}
+}
+
+// Readings returns the latest value of every sensor, readings older than
+// the MaxAge of their sensor are left out. It returns nil if there is none.
+func (h *Hub) Readings() map[string]any {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var values map[string]any
+	for _, s := range h.sensors {
+		r, ok := h.readings[s.conf.Name]
+		if !ok {
+			continue
+		}
+		if s.conf.MaxAge > 0 && time.Since(r.Time) > s.conf.MaxAge {
+			continue
+		}
+		if values == nil {
+			values = make(map[string]any, len(h.sensors))
+		}
+		values[s.conf.Name] = r.Value
+	}
+	return values
+}
+
+// polling runs a poller at a fixed interval.
+type polling struct {
+	poller   poller
+	interval time.Duration
+}
+
+func (p *polling) run(ctx context.Context, logger *logrus.Entry, set func(value any)) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, p.interval)
+		value, err := p.poller.poll(pollCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.WithError(err).Warn("read sensor failed")
+			}
+		} else {
+			set(value)
+		}
+
+		select {
+		case <-ctx.Done():
+			if c, ok := p.poller.(interface{ Close() error }); ok {
+				c.Close()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
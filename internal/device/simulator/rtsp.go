@@ -0,0 +1,244 @@
+package simulator
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rtpWriteTimeout = 2 * time.Second
+
+var rtspStatusText = map[int]string{
+	200: "OK",
+	400: "Bad Request",
+	404: "Not Found",
+	454: "Session Not Found",
+	455: "Method Not Valid in This State",
+	461: "Unsupported Transport",
+	501: "Not Implemented",
+	503: "Service Unavailable",
+}
+
+type rtspRequest struct {
+	method  string
+	url     string
+	headers map[string]string
+}
+
+// session is an RTSP connection playing a stream, only the TCP interleaved
+// transport is supported so that no UDP port is needed per client.
+type session struct {
+	id      string
+	conn    net.Conn
+	wmu     sync.Mutex
+	stream  *stream
+	channel byte
+	closed  bool
+}
+
+func (s *Simulator) serveConn(conn net.Conn) {
+	sess := &session{conn: conn}
+	defer func() {
+		if sess.stream != nil {
+			sess.stream.removeClient(sess)
+		}
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return
+		}
+		// the RTCP reports of the client are interleaved with its requests
+		if b[0] == '$' {
+			var header [4]byte
+			if _, err := io.ReadFull(r, header[:]); err != nil {
+				return
+			}
+			if _, err := r.Discard(int(binary.BigEndian.Uint16(header[2:]))); err != nil {
+				return
+			}
+			continue
+		}
+
+		req, err := readRequest(r)
+		if err != nil {
+			return
+		}
+		if !s.handleRequest(sess, req) {
+			return
+		}
+	}
+}
+
+func readRequest(r *bufio.Reader) (*rtspRequest, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Fields(line)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid request line %q", line)
+	}
+	req := &rtspRequest{method: parts[0], url: parts[1], headers: make(map[string]string)}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			req.headers[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+		}
+	}
+	if n, _ := strconv.Atoi(req.headers["content-length"]); n > 0 {
+		if _, err := r.Discard(n); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// handleRequest replies to the request, it returns false if the connection
+// should be closed.
+func (s *Simulator) handleRequest(sess *session, req *rtspRequest) bool {
+	cseq := req.headers["cseq"]
+	switch req.method {
+	case "OPTIONS":
+		sess.reply(cseq, 200, []string{"Public: OPTIONS, DESCRIBE, SETUP, PLAY, TEARDOWN, GET_PARAMETER"}, "")
+	case "DESCRIBE":
+		st := s.lookupStream(req.url)
+		if st == nil {
+			sess.reply(cseq, 404, nil, "")
+			return true
+		}
+		sdp := st.getSdp()
+		if sdp == "" {
+			sess.reply(cseq, 503, nil, "")
+			return true
+		}
+		sess.reply(cseq, 200, []string{
+			"Content-Base: " + strings.TrimSuffix(req.url, "/") + "/",
+			"Content-Type: application/sdp",
+		}, sdp)
+	case "SETUP":
+		st := s.lookupStream(req.url)
+		if st == nil {
+			sess.reply(cseq, 404, nil, "")
+			return true
+		}
+		transport := req.headers["transport"]
+		if !strings.Contains(transport, "RTP/AVP/TCP") {
+			sess.reply(cseq, 461, nil, "")
+			return true
+		}
+		channel := 0
+		for _, p := range strings.Split(transport, ";") {
+			if v, ok := strings.CutPrefix(p, "interleaved="); ok {
+				first, _, _ := strings.Cut(v, "-")
+				channel, _ = strconv.Atoi(first)
+			}
+		}
+		if sess.stream != nil && sess.stream != st {
+			sess.stream.removeClient(sess)
+		}
+		sess.stream = st
+		sess.channel = byte(channel)
+		if sess.id == "" {
+			sess.id = newSessionId()
+		}
+		sess.reply(cseq, 200, []string{
+			fmt.Sprintf("Transport: RTP/AVP/TCP;unicast;interleaved=%d-%d", channel, channel+1),
+			"Session: " + sess.id + ";timeout=60",
+		}, "")
+	case "PLAY":
+		if sess.stream == nil {
+			sess.reply(cseq, 455, nil, "")
+			return true
+		}
+		sess.reply(cseq, 200, []string{"Session: " + sess.id, "Range: npt=0.000-"}, "")
+		sess.stream.addClient(sess)
+	case "TEARDOWN":
+		sess.reply(cseq, 200, []string{"Session: " + sess.id}, "")
+		return false
+	case "GET_PARAMETER", "SET_PARAMETER":
+		sess.reply(cseq, 200, []string{"Session: " + sess.id}, "")
+	default:
+		sess.reply(cseq, 501, nil, "")
+	}
+	return true
+}
+
+// lookupStream returns the stream named by the first path segment of the
+// url, nil if there is none.
+func (s *Simulator) lookupStream(rawUrl string) *stream {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil
+	}
+	name, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "/")
+	return s.streams[name]
+}
+
+func (sess *session) reply(cseq string, code int, headers []string, body string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "RTSP/1.0 %d %s\r\nCSeq: %s\r\n", code, rtspStatusText[code], cseq)
+	for _, h := range headers {
+		b.WriteString(h)
+		b.WriteString("\r\n")
+	}
+	if body != "" {
+		fmt.Fprintf(&b, "Content-Length: %d\r\n", len(body))
+	}
+	b.WriteString("\r\n")
+	b.WriteString(body)
+
+	sess.wmu.Lock()
+	defer sess.wmu.Unlock()
+	sess.conn.SetWriteDeadline(time.Now().Add(rtpWriteTimeout))
+	sess.conn.Write([]byte(b.String()))
+}
+
+// writeRtp sends the packet interleaved, a client too slow to take it is
+// disconnected.
+func (sess *session) writeRtp(pkt []byte) {
+	sess.wmu.Lock()
+	defer sess.wmu.Unlock()
+	if sess.closed {
+		return
+	}
+	frame := make([]byte, 4+len(pkt))
+	frame[0] = '$'
+	frame[1] = sess.channel
+	binary.BigEndian.PutUint16(frame[2:], uint16(len(pkt)))
+	copy(frame[4:], pkt)
+
+	sess.conn.SetWriteDeadline(time.Now().Add(rtpWriteTimeout))
+	if _, err := sess.conn.Write(frame); err != nil {
+		sess.closed = true
+		sess.conn.Close()
+	}
+}
+
+func newSessionId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,362 @@
+// Package simulator serves local video files as virtual RTSP cameras, so
+// that a single machine can exercise many jobs, the uploads and the consumer
+// without physical cameras.
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	restartDelay  = 5 * time.Second
+	sdpTimeout    = 10 * time.Second
+	rtpPacketSize = 1200
+)
+
+// videoExts are the extensions of the files a source directory is expanded
+// to.
+var videoExts = map[string]bool{
+	".mp4": true, ".mkv": true, ".mov": true, ".avi": true, ".ts": true, ".flv": true,
+}
+
+type Options struct {
+	// Sources are the video files, the cameras loop them in turn
+	Sources []string
+	Cameras int
+	// Listen is the address of the RTSP server, e.g. :8554
+	Listen string
+	// Transcode re-encodes the sources to H.264 even if they already are,
+	// otherwise only the sources in other codecs are
+	Transcode bool
+}
+
+// Simulator runs an ffmpeg process per camera looping its source in real
+// time to RTP, and restreams the packets to the RTSP clients of the camera.
+type Simulator struct {
+	opts    Options
+	logger  *logrus.Entry
+	streams map[string]*stream
+	names   []string
+	tmpDir  string
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// ExpandSources returns the video files of the paths, a directory stands for
+// the video files in it.
+func ExpandSources(paths []string) ([]string, error) {
+	var sources []string
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			sources = append(sources, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, e := range entries {
+			if !e.IsDir() && videoExts[strings.ToLower(filepath.Ext(e.Name()))] {
+				files = append(files, filepath.Join(p, e.Name()))
+			}
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no video file in %s", p)
+		}
+		sort.Strings(files)
+		sources = append(sources, files...)
+	}
+	return sources, nil
+}
+
+func New(logger *logrus.Entry, opts Options) (*Simulator, error) {
+	if len(opts.Sources) == 0 {
+		return nil, errors.New("no source")
+	}
+	if opts.Cameras <= 0 {
+		return nil, errors.New("cameras must be positive")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "lumina-simulator-")
+	if err != nil {
+		return nil, err
+	}
+	s := &Simulator{
+		opts:    opts,
+		logger:  logger.WithField("component", "simulator"),
+		streams: make(map[string]*stream),
+		tmpDir:  tmpDir,
+		conns:   make(map[net.Conn]struct{}),
+	}
+	for i := 0; i < opts.Cameras; i++ {
+		name := fmt.Sprintf("cam%d", i+1)
+		rtp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+		s.streams[name] = &stream{
+			name:    name,
+			source:  opts.Sources[i%len(opts.Sources)],
+			rtp:     rtp,
+			clients: make(map[*session]struct{}),
+		}
+		s.names = append(s.names, name)
+	}
+	return s, nil
+}
+
+// Urls returns the RTSP urls of the cameras, with host as the address the
+// cameras are reached at.
+func (s *Simulator) Urls(host string) []string {
+	_, port, err := net.SplitHostPort(s.opts.Listen)
+	if err != nil {
+		port = "554"
+	}
+	urls := make([]string, len(s.names))
+	for i, name := range s.names {
+		urls[i] = fmt.Sprintf("rtsp://%s/%s", net.JoinHostPort(host, port), name)
+	}
+	return urls
+}
+
+// Sources returns the source of each camera, in the order of Urls.
+func (s *Simulator) Sources() []string {
+	sources := make([]string, len(s.names))
+	for i, name := range s.names {
+		sources[i] = s.streams[name].source
+	}
+	return sources
+}
+
+// Run serves the cameras until ctx is done.
+func (s *Simulator) Run(ctx context.Context) error {
+	defer s.close()
+
+	ln, err := net.Listen("tcp", s.opts.Listen)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range s.names {
+		st := s.streams[name]
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s.runFfmpeg(ctx, st)
+		}()
+		go func() {
+			defer wg.Done()
+			st.forward(ctx)
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+		for _, st := range s.streams {
+			st.rtp.Close()
+		}
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			s.logger.WithError(err).Warn("accept rtsp connection failed")
+			continue
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (s *Simulator) close() {
+	for _, st := range s.streams {
+		st.rtp.Close()
+	}
+	os.RemoveAll(s.tmpDir)
+}
+
+// runFfmpeg loops the source of the stream to its RTP port, restarting
+// ffmpeg whenever it exits.
+func (s *Simulator) runFfmpeg(ctx context.Context, st *stream) {
+	logger := s.logger.WithFields(logrus.Fields{"camera": st.name, "source": st.source})
+	sdpFile := filepath.Join(s.tmpDir, st.name+".sdp")
+	for {
+		os.Remove(sdpFile)
+		args := []string{"-hide_banner", "-loglevel", "error", "-re", "-stream_loop", "-1", "-i", st.source, "-an"}
+		if s.opts.Transcode || !isH264(ctx, st.source) {
+			args = append(args, "-c:v", "libx264", "-preset", "ultrafast", "-tune", "zerolatency", "-pix_fmt", "yuv420p", "-bf", "0")
+		} else {
+			args = append(args, "-c:v", "copy")
+		}
+		args = append(args, "-f", "rtp", "-sdp_file", sdpFile,
+			fmt.Sprintf("rtp://%s?pkt_size=%d", st.rtp.LocalAddr(), rtpPacketSize))
+
+		cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Start(); err != nil {
+			logger.WithError(err).Error("start ffmpeg failed")
+		} else {
+			if sdp, err := waitSdp(ctx, sdpFile); err != nil {
+				logger.WithError(err).Warn("read sdp failed")
+			} else {
+				st.setSdp(rewriteSdp(sdp, st.name))
+				logger.Info("camera is streaming")
+			}
+			if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+				logger.WithError(err).Errorf("ffmpeg exited: %s", strings.TrimSpace(stderr.String()))
+			}
+		}
+		st.setSdp("")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(restartDelay):
+		}
+	}
+}
+
+// waitSdp waits for ffmpeg to write the sdp of its output.
+func waitSdp(ctx context.Context, path string) (string, error) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(sdpTimeout)
+	for {
+		if data, err := os.ReadFile(path); err == nil && bytes.Contains(data, []byte("m=video")) {
+			return string(data), nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-timeout:
+			return "", errors.New("timeout")
+		case <-ticker.C:
+		}
+	}
+}
+
+// rewriteSdp turns the sdp ffmpeg writes for its RTP output into the sdp of
+// the RTSP stream, whose single track is sent interleaved.
+func rewriteSdp(sdp, name string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.ReplaceAll(sdp, "\r\n", "\n"), "\n") {
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "s="):
+			line = "s=" + name
+		case strings.HasPrefix(line, "c="):
+			line = "c=IN IP4 0.0.0.0"
+		case strings.HasPrefix(line, "m="):
+			if fields := strings.Fields(line); len(fields) >= 2 {
+				fields[1] = "0"
+				line = strings.Join(fields, " ")
+			}
+		}
+		b.WriteString(line)
+		b.WriteString("\r\n")
+	}
+	b.WriteString("a=control:trackID=0\r\n")
+	return b.String()
+}
+
+func isH264(ctx context.Context, source string) bool {
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=codec_name",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		source,
+	)
+	out, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(out)) == "h264"
+}
+
+// stream is a virtual camera, the RTP packets ffmpeg sends to its port are
+// fanned out to the playing sessions.
+type stream struct {
+	name   string
+	source string
+	rtp    *net.UDPConn
+
+	mu      sync.RWMutex
+	sdp     string
+	clients map[*session]struct{}
+}
+
+func (st *stream) setSdp(sdp string) {
+	st.mu.Lock()
+	st.sdp = sdp
+	st.mu.Unlock()
+}
+
+func (st *stream) getSdp() string {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.sdp
+}
+
+func (st *stream) addClient(sess *session) {
+	st.mu.Lock()
+	st.clients[sess] = struct{}{}
+	st.mu.Unlock()
+}
+
+func (st *stream) removeClient(sess *session) {
+	st.mu.Lock()
+	delete(st.clients, sess)
+	st.mu.Unlock()
+}
+
+func (st *stream) forward(ctx context.Context) {
+	buf := make([]byte, 65536)
+	for {
+		n, err := st.rtp.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		st.mu.RLock()
+		for sess := range st.clients {
+			sess.writeRtp(buf[:n])
+		}
+		st.mu.RUnlock()
+	}
+}
@@ -0,0 +1,154 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"lumina/internal/dao"
+	"lumina/internal/device/metadata"
+	"lumina/internal/utils"
+)
+
+const (
+	fetchSnapshotTasksPath   = "/api/v1/device/snapshot-tasks"
+	reportSnapshotResultPath = "/api/v1/device/snapshot-result"
+)
+
+func (a *Device) fetchSnapshotTasksFromServer(info *metadata.DeviceInfo) (*dao.ListSnapshotTasksResponse, error) {
+	a.logger.Debugf("fetch snapshot tasks")
+
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodGet, a.conf.LuminaServerAddr+fetchSnapshotTasksPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+
+	var respBody dao.ListSnapshotTasksResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, err
+	}
+	return &respBody, nil
+}
+
+func (a *Device) syncSnapshotTasksFromServer() error {
+	info, err := a.db.GetDeviceInfo()
+	if err != nil {
+		return err
+	} else if info == nil || info.Uuid == nil {
+		return errors.New("device Id is nil, please register device")
+	}
+
+	resp, err := a.fetchSnapshotTasksFromServer(info)
+	if err != nil {
+		return err
+	}
+
+	a.snapshotMu.Lock()
+	defer a.snapshotMu.Unlock()
+	for _, task := range resp.Items {
+		if task.Expired() {
+			continue
+		}
+		if _, running := a.snapshotTasks[task.TaskUuid]; running {
+			continue
+		}
+		a.logger.Infof("start snapshot task, task uuid: %s", task.TaskUuid)
+		a.snapshotTasks[task.TaskUuid] = struct{}{}
+		go a.runSnapshotTask(info, task)
+	}
+
+	return nil
+}
+
+func (a *Device) runSnapshotTask(info *metadata.DeviceInfo, task dao.SnapshotTask) {
+	defer func() {
+		a.snapshotMu.Lock()
+		delete(a.snapshotTasks, task.TaskUuid)
+		a.snapshotMu.Unlock()
+	}()
+
+	logger := a.logger.WithField("taskUuid", task.TaskUuid)
+	result := &dao.SnapshotResult{TaskUuid: task.TaskUuid}
+
+	ctx, cancel := context.WithTimeout(a.ctx, 15*time.Second)
+	defer cancel()
+
+	if err := os.MkdirAll(a.conf.TestDir(), 0755); err != nil {
+		logger.WithError(err).Errorf("create test dir failed")
+		result.Error = err.Error()
+	} else {
+		imgPath := path.Join(a.conf.TestDir(), "snapshot-"+task.TaskUuid+".jpg")
+		defer os.Remove(imgPath)
+
+		if err := grabFrame(ctx, task.PullAddr, imgPath); err != nil {
+			logger.WithError(err).Errorf("grab frame failed")
+			result.Error = err.Error()
+		} else {
+			minioPath := fmt.Sprintf("/%s/snapshot/%s.jpg", *info.Uuid, task.TaskUuid)
+			if err := utils.UploadFileToMinio(ctx, a.minioCli, a.conf.S3.Bucket, imgPath, minioPath); err != nil {
+				logger.WithError(err).Errorf("upload snapshot failed")
+				result.Error = err.Error()
+			} else {
+				result.ImagePath = minioPath
+			}
+		}
+	}
+
+	if err := a.reportSnapshotResult(info, result); err != nil {
+		logger.WithError(err).Errorf("report snapshot result failed")
+	}
+}
+
+// grabFrame 使用 ffmpeg 从输入流截取一帧保存为 jpg
+func grabFrame(ctx context.Context, input, imgPath string) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-i", input, "-frames:v", "1", "-q:v", "2", imgPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("grab frame timeout: %w", ctx.Err())
+		}
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		return fmt.Errorf("ffmpeg failed: %v, %s", err, lines[len(lines)-1])
+	}
+	return nil
+}
+
+func (a *Device) reportSnapshotResult(info *metadata.DeviceInfo, result *dao.SnapshotResult) error {
+	body, _ := json.Marshal(result)
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.conf.LuminaServerAddr+reportSnapshotResultPath, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package device
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"time"
+
+	"lumina/internal/device/config"
+	"lumina/internal/model"
+)
+
+type ExecutorState struct {
+	JobUuid  string        `json:"jobUuid"`
+	Kind     model.JobKind `json:"kind"`
+	Status   string        `json:"status"`
+	Restarts int           `json:"restarts,omitempty"`
+	// reason of a failed health probe, empty if healthy
+	Health string `json:"health,omitempty"`
+}
+
+// RuntimeStatus is a snapshot of the running device written to the work dir,
+// so that CLI tools can inspect the daemon without opening its metadata DB.
+type RuntimeStatus struct {
+	Pid             int              `json:"pid"`
+	DeviceUuid      string           `json:"deviceUuid,omitempty"`
+	RegisterTime    string           `json:"registerTime,omitempty"`
+	StartTime       time.Time        `json:"startTime"`
+	UpdateTime      time.Time        `json:"updateTime"`
+	LastSyncTime    time.Time        `json:"lastSyncTime,omitempty"`
+	LastSyncError   string           `json:"lastSyncError,omitempty"`
+	LastReportTime  time.Time        `json:"lastReportTime,omitempty"`
+	LastReportError string           `json:"lastReportError,omitempty"`
+	Executors       []ExecutorState  `json:"executors"`
+	DiskUsage       *model.DiskUsage `json:"diskUsage,omitempty"`
+}
+
+func (a *Device) writeRuntimeStatus() error {
+	a.status.Pid = os.Getpid()
+	a.status.UpdateTime = time.Now()
+	if a.deviceInfo.Uuid != nil {
+		a.status.DeviceUuid = *a.deviceInfo.Uuid
+	}
+	if a.deviceInfo.RegisterTime != nil {
+		a.status.RegisterTime = *a.deviceInfo.RegisterTime
+	}
+	a.status.DiskUsage = a.diskUsage
+	a.status.Executors = make([]ExecutorState, 0, len(a.executors))
+	for uuid, e := range a.executors {
+		restarts, _ := a.supervisor.restartCount(uuid)
+		a.status.Executors = append(a.status.Executors, ExecutorState{
+			JobUuid:  uuid,
+			Kind:     e.Job().Kind,
+			Status:   a.executorStatus(uuid, e).String(),
+			Restarts: restarts,
+			Health:   e.Health().Reason,
+		})
+	}
+	for uuid, kind := range a.pending {
+		restarts, _ := a.supervisor.restartCount(uuid)
+		a.status.Executors = append(a.status.Executors, ExecutorState{
+			JobUuid:  uuid,
+			Kind:     kind,
+			Status:   model.ExectorStatusPending.String(),
+			Restarts: restarts,
+		})
+	}
+	sort.Slice(a.status.Executors, func(i, j int) bool {
+		return a.status.Executors[i].JobUuid < a.status.Executors[j].JobUuid
+	})
+
+	data, err := json.MarshalIndent(a.status, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := a.conf.StatusFile() + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, a.conf.StatusFile())
+}
+
+func (a *Device) recordSync(err error) {
+	a.status.LastSyncTime = time.Now()
+	a.status.LastSyncError = ""
+	if err != nil {
+		a.status.LastSyncError = err.Error()
+	}
+}
+
+func (a *Device) recordReport(err error) {
+	a.status.LastReportTime = time.Now()
+	a.status.LastReportError = ""
+	if err != nil {
+		a.status.LastReportError = err.Error()
+	}
+}
+
+// ReadRuntimeStatus loads the snapshot written by a running device, it returns
+// nil if the device has never been started in the work dir.
+func ReadRuntimeStatus(conf *config.Config) (*RuntimeStatus, error) {
+	data, err := os.ReadFile(conf.StatusFile())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var status RuntimeStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
@@ -0,0 +1,96 @@
+package device
+
+import (
+	"time"
+)
+
+const (
+	restartBackoffBase = 5 * time.Second
+	restartBackoffMax  = 5 * time.Minute
+	// an executor running this long is considered healthy again and its
+	// backoff is reset
+	restartResetAfter = 10 * time.Minute
+)
+
+type restartState struct {
+	restarts  int
+	failures  int
+	lastError string
+	startTime time.Time
+	nextStart time.Time
+}
+
+// supervisor tracks executor failures and decides when a failed or finished
+// job may be started again, backing off exponentially on repeated failures.
+type supervisor struct {
+	states map[string]*restartState
+}
+
+func newSupervisor() *supervisor {
+	return &supervisor{
+		states: make(map[string]*restartState),
+	}
+}
+
+func (s *supervisor) state(jobUuid string) *restartState {
+	st, ok := s.states[jobUuid]
+	if !ok {
+		st = &restartState{}
+		s.states[jobUuid] = st
+	}
+	return st
+}
+
+func (s *supervisor) canStart(jobUuid string, now time.Time) bool {
+	st, ok := s.states[jobUuid]
+	return !ok || !now.Before(st.nextStart)
+}
+
+func (s *supervisor) backingOff(jobUuid string, now time.Time) bool {
+	st, ok := s.states[jobUuid]
+	return ok && st.failures > 0 && now.Before(st.nextStart)
+}
+
+func (s *supervisor) started(jobUuid string, now time.Time) {
+	st := s.state(jobUuid)
+	if st.failures > 0 {
+		st.restarts++
+	}
+	st.startTime = now
+}
+
+// failed records a failure of the job and returns the delay before it may be
+// started again.
+func (s *supervisor) failed(jobUuid string, reason string, now time.Time) time.Duration {
+	st := s.state(jobUuid)
+	st.failures++
+	st.lastError = reason
+
+	backoff := restartBackoffBase << (st.failures - 1)
+	if backoff > restartBackoffMax || backoff <= 0 {
+		backoff = restartBackoffMax
+	}
+	st.nextStart = now.Add(backoff)
+	return backoff
+}
+
+// healthy resets the backoff of a job whose executor kept running long enough.
+func (s *supervisor) healthy(jobUuid string, now time.Time) {
+	st, ok := s.states[jobUuid]
+	if ok && st.failures > 0 && now.Sub(st.startTime) > restartResetAfter {
+		st.failures = 0
+	}
+}
+
+// forget drops the history of a deleted or updated job.
+func (s *supervisor) forget(jobUuid string) {
+	delete(s.states, jobUuid)
+}
+
+func (s *supervisor) restartCount(jobUuid string) (int, string) {
+	st, ok := s.states[jobUuid]
+	if !ok {
+		return 0, ""
+	}
+	return st.restarts, st.lastError
+}
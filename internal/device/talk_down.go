@@ -0,0 +1,242 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"lumina/internal/dao"
+	"lumina/internal/device/metadata"
+	"lumina/internal/model"
+	"lumina/pkg/onvif"
+)
+
+const (
+	fetchTalkDownTasksPath   = "/api/v1/device/talk-down-tasks"
+	reportTalkDownResultPath = "/api/v1/device/talk-down-result"
+
+	talkDownTimeout = 50 * time.Second
+)
+
+func (a *Device) fetchTalkDownTasksFromServer(info *metadata.DeviceInfo) (*dao.ListTalkDownTasksResponse, error) {
+	a.logger.Debugf("fetch talk-down tasks")
+
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodGet, a.conf.LuminaServerAddr+fetchTalkDownTasksPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+
+	var respBody dao.ListTalkDownTasksResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, err
+	}
+	return &respBody, nil
+}
+
+func (a *Device) syncTalkDownTasksFromServer() error {
+	info, err := a.db.GetDeviceInfo()
+	if err != nil {
+		return err
+	} else if info == nil || info.Uuid == nil {
+		return errors.New("device Id is nil, please register device")
+	}
+
+	resp, err := a.fetchTalkDownTasksFromServer(info)
+	if err != nil {
+		return err
+	}
+
+	a.talkDownMu.Lock()
+	defer a.talkDownMu.Unlock()
+	for _, task := range resp.Items {
+		if task.Expired() {
+			continue
+		}
+		if _, running := a.talkDownTasks[task.TaskUuid]; running {
+			continue
+		}
+		a.logger.Infof("start talk-down task, task uuid: %s", task.TaskUuid)
+		a.talkDownTasks[task.TaskUuid] = struct{}{}
+		go a.runTalkDownTask(info, task)
+	}
+
+	return nil
+}
+
+func (a *Device) runTalkDownTask(info *metadata.DeviceInfo, task dao.TalkDownTask) {
+	defer func() {
+		a.talkDownMu.Lock()
+		delete(a.talkDownTasks, task.TaskUuid)
+		a.talkDownMu.Unlock()
+	}()
+
+	// the speaker plays one talk-down at a time
+	a.talkDownPlayMu.Lock()
+	defer a.talkDownPlayMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(a.ctx, talkDownTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result := &dao.TalkDownResult{TaskUuid: task.TaskUuid}
+	if err := a.talkDown(ctx, task); err != nil {
+		a.logger.WithField("taskUuid", task.TaskUuid).WithError(err).Warn("talk-down failed")
+		result.Error = err.Error()
+	}
+	result.Duration = time.Since(start).Milliseconds()
+
+	if err := a.reportTalkDownResult(info, result); err != nil {
+		a.logger.WithField("taskUuid", task.TaskUuid).WithError(err).Errorf("report talk-down result failed")
+	}
+}
+
+func (a *Device) talkDown(ctx context.Context, task dao.TalkDownTask) error {
+	input := task.AudioUrl
+	if input == "" {
+		if task.Text == "" {
+			return errors.New("talk-down has neither text nor audio")
+		}
+		if err := os.MkdirAll(a.conf.TestDir(), 0755); err != nil {
+			return err
+		}
+		input = path.Join(a.conf.TestDir(), "talk-down-"+task.TaskUuid+".wav")
+		defer os.Remove(input)
+		if err := runCommand(ctx, a.conf.TalkDown.TTSCommand, map[string]string{
+			"{text}":   task.Text,
+			"{output}": input,
+		}); err != nil {
+			return fmt.Errorf("synthesize speech: %w", err)
+		}
+	}
+
+	switch task.Output {
+	case model.TalkDownOutputLocal:
+		return runCommand(ctx, a.conf.TalkDown.PlayCommand, map[string]string{"{input}": input})
+	case model.TalkDownOutputOnvif, "":
+		return playBackchannel(ctx, task.PullAddr, input)
+	default:
+		return fmt.Errorf("unknown talk-down output %q", task.Output)
+	}
+}
+
+// playBackchannel transcodes input to the G.711 codec of the camera with
+// ffmpeg and streams it through the ONVIF audio backchannel.
+func playBackchannel(ctx context.Context, pullAddr, input string) error {
+	bc, err := onvif.DialBackchannel(ctx, pullAddr)
+	if errors.Is(err, onvif.ErrNotSupported) {
+		return fmt.Errorf("camera has no audio backchannel: %w", err)
+	} else if err != nil {
+		return fmt.Errorf("open audio backchannel: %w", err)
+	}
+	defer bc.Close()
+
+	format := "mulaw"
+	if bc.Codec() == onvif.CodecPCMA {
+		format = "alaw"
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-v", "error",
+		"-i", input,
+		"-f", format,
+		"-ar", "8000",
+		"-ac", "1",
+		"pipe:1",
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	if err := bc.Send(ctx, stdout); err != nil {
+		cancel()
+		cmd.Wait()
+		return err
+	}
+	if err := cmd.Wait(); err != nil {
+		return commandError(err, &stderr)
+	}
+	return nil
+}
+
+// runCommand runs args after replacing the placeholders of vars in them.
+func runCommand(ctx context.Context, args []string, vars map[string]string) error {
+	if len(args) == 0 {
+		return errors.New("command is not configured")
+	}
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		for k, v := range vars {
+			arg = strings.ReplaceAll(arg, k, v)
+		}
+		expanded[i] = arg
+	}
+
+	cmd := exec.CommandContext(ctx, expanded[0], expanded[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s timeout after %s", expanded[0], talkDownTimeout)
+		}
+		return commandError(err, &stderr)
+	}
+	return nil
+}
+
+// commandError returns the last line the command wrote to stderr, or err if
+// there is none.
+func commandError(err error, stderr *bytes.Buffer) error {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		lines := strings.Split(msg, "\n")
+		return errors.New(lines[len(lines)-1])
+	}
+	return err
+}
+
+func (a *Device) reportTalkDownResult(info *metadata.DeviceInfo, result *dao.TalkDownResult) error {
+	body, _ := json.Marshal(result)
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.conf.LuminaServerAddr+reportTalkDownResultPath, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"time"
+
+	"lumina/internal/dao"
+	"lumina/internal/device/exector"
+	"lumina/internal/device/metadata"
+	"lumina/internal/utils"
+)
+
+const (
+	fetchTestDetectTasksPath   = "/api/v1/device/test-detect-tasks"
+	reportTestDetectResultPath = "/api/v1/device/test-detect-result"
+)
+
+func (a *Device) fetchTestDetectTasksFromServer(info *metadata.DeviceInfo) (*dao.ListTestDetectTasksResponse, error) {
+	a.logger.Debugf("fetch test detect tasks")
+
+	url, err := url.Parse(fmt.Sprintf(a.conf.LuminaServerAddr + fetchTestDetectTasksPath))
+	if err != nil {
+		return nil, err
+	}
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    url,
+		Header: http.Header{
+			"Authorization": []string{fmt.Sprintf("Bearer %s", *info.Token)},
+		},
+	}
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+
+	var respBody dao.ListTestDetectTasksResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, err
+	}
+
+	return &respBody, nil
+}
+
+func (a *Device) syncTestDetectTasksFromServer() error {
+	info, err := a.db.GetDeviceInfo()
+	if err != nil {
+		return err
+	} else if info == nil || info.Uuid == nil {
+		return errors.New("device Id is nil, please register device")
+	}
+
+	resp, err := a.fetchTestDetectTasksFromServer(info)
+	if err != nil {
+		return err
+	}
+
+	a.testDetectMu.Lock()
+	defer a.testDetectMu.Unlock()
+	for _, task := range resp.Items {
+		if task.Expired() {
+			continue
+		}
+		if _, running := a.testDetectTasks[task.TaskUuid]; running {
+			continue
+		}
+		a.logger.Infof("start test detect task, task: %+v", task)
+		a.testDetectTasks[task.TaskUuid] = struct{}{}
+		go a.runTestDetectTask(info, task)
+	}
+
+	return nil
+}
+
+func (a *Device) runTestDetectTask(info *metadata.DeviceInfo, task dao.TestDetectTask) {
+	defer func() {
+		a.testDetectMu.Lock()
+		delete(a.testDetectTasks, task.TaskUuid)
+		a.testDetectMu.Unlock()
+	}()
+
+	logger := a.logger.WithField("taskUuid", task.TaskUuid)
+	result := &dao.TestDetectResult{TaskUuid: task.TaskUuid}
+
+	ctx, cancel := context.WithTimeout(a.ctx, 30*time.Second)
+	defer cancel()
+
+	if err := os.MkdirAll(a.conf.TestDir(), 0755); err != nil {
+		logger.WithError(err).Errorf("create test dir failed")
+		result.Error = err.Error()
+	} else {
+		imgPath := path.Join(a.conf.TestDir(), task.TaskUuid+".jpg")
+		defer os.Remove(imgPath)
+
+		boxes, err := exector.TestDetect(ctx, a.conf, &task, imgPath)
+		if err != nil {
+			logger.WithError(err).Errorf("test detect failed")
+			result.Error = err.Error()
+		} else {
+			minioPath := fmt.Sprintf("/%s/test/%s.jpg", *info.Uuid, task.TaskUuid)
+			if err := utils.UploadFileToMinio(ctx, a.minioCli, a.conf.S3.Bucket, imgPath, minioPath); err != nil {
+				logger.WithError(err).Errorf("upload test detect image failed")
+				result.Error = err.Error()
+			} else {
+				result.ImagePath = minioPath
+				result.Boxes = boxes
+			}
+		}
+	}
+
+	if err := a.reportTestDetectResult(info, result); err != nil {
+		logger.WithError(err).Errorf("report test detect result failed")
+	}
+}
+
+func (a *Device) reportTestDetectResult(info *metadata.DeviceInfo, result *dao.TestDetectResult) error {
+	body, _ := json.Marshal(result)
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.conf.LuminaServerAddr+reportTestDetectResultPath, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package device
+
+import (
+	"math"
+	"sort"
+
+	"lumina/internal/device/exector"
+)
+
+const (
+	// minFrameRate keeps a throttled job sampling a frame every 5 seconds
+	minFrameRate = 0.2
+	// budgetBackoff and budgetGrowth adjust the estimated inference budget
+	// when inference falls behind and when it keeps up
+	budgetBackoff = 0.9
+	budgetGrowth  = 1.1
+)
+
+type throttled struct {
+	uuid     string
+	priority int
+	demand   float64
+	executor exector.Throttler
+}
+
+// throttleExecutors shares the inference budget of the device among the
+// executors by priority: higher-priority jobs keep their sampling rate and
+// lower-priority ones are throttled with what is left. The budget is the
+// configured one, or else estimated from the frames the executors drop when
+// inference cannot keep up, so that an unconstrained device throttles none.
+func (a *Device) throttleExecutors() {
+	var jobs []throttled
+	var demand, achieved float64
+	congested := false
+	dropped := make(map[string]int64, len(a.executors))
+	for uuid, e := range a.executors {
+		t, ok := e.(exector.Throttler)
+		if !ok {
+			continue
+		}
+		m := e.Metrics()
+		dropped[uuid] = m.FramesDropped
+		if m.FramesDropped > a.framesDropped[uuid] {
+			congested = true
+		}
+		if !a.paused[uuid] {
+			jobs = append(jobs, throttled{uuid: uuid, priority: e.Job().Priority, demand: t.DemandFrameRate(), executor: t})
+			demand += t.DemandFrameRate()
+			achieved += m.FrameRate
+		}
+	}
+	a.framesDropped = dropped
+
+	budget := a.conf.Concurrency.InferenceFps
+	if budget <= 0 {
+		switch {
+		case congested && achieved > 0:
+			budget = achieved * budgetBackoff
+			if a.inferenceBudget > 0 {
+				budget = min(budget, a.inferenceBudget*budgetBackoff)
+			}
+		case a.inferenceBudget > 0:
+			budget = a.inferenceBudget * budgetGrowth
+			if budget >= demand {
+				budget = 0
+			}
+		}
+		if budget != a.inferenceBudget {
+			a.logger.Infof("inference budget estimated to %.1f fps, demand %.1f fps", budget, demand)
+		}
+		a.inferenceBudget = budget
+	}
+
+	limits := allocateFrameRates(jobs, budget)
+	a.frameRateLimits = make(map[string]float64, len(jobs))
+	for _, j := range jobs {
+		limit := limits[j.uuid]
+		j.executor.SetFrameRateLimit(limit)
+		if limit > 0 && limit < j.demand {
+			a.frameRateLimits[j.uuid] = limit
+		}
+	}
+}
+
+// allocateFrameRates gives the jobs their demand by decreasing priority
+// while the budget lasts, the jobs of the priority it runs out at share the
+// rest in proportion to their demand and the lower ones get minFrameRate. A
+// zero budget is unlimited, the jobs are then only limited to their demand.
+func allocateFrameRates(jobs []throttled, budget float64) map[string]float64 {
+	res := make(map[string]float64, len(jobs))
+	if budget <= 0 {
+		for _, j := range jobs {
+			res[j.uuid] = j.demand
+		}
+		return res
+	}
+
+	sort.Slice(jobs, func(i, k int) bool {
+		if jobs[i].priority != jobs[k].priority {
+			return jobs[i].priority > jobs[k].priority
+		}
+		return jobs[i].uuid < jobs[k].uuid
+	})
+	remaining := budget
+	for i := 0; i < len(jobs); {
+		k := i
+		var levelDemand float64
+		for ; k < len(jobs) && jobs[k].priority == jobs[i].priority; k++ {
+			levelDemand += jobs[k].demand
+		}
+		scale := 1.0
+		if levelDemand > remaining {
+			scale = remaining / levelDemand
+		}
+		for _, j := range jobs[i:k] {
+			res[j.uuid] = math.Min(j.demand, math.Max(j.demand*scale, minFrameRate))
+		}
+		remaining = math.Max(remaining-levelDemand, 0)
+		i = k
+	}
+	return res
+}
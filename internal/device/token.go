@@ -0,0 +1,42 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"lumina/internal/dao"
+	"lumina/internal/device/metadata"
+)
+
+const rotateTokenPath = "/api/v1/device/rotate-token"
+
+// rotateToken replaces the token of the device with a new one issued by the
+// server. The server keeps the old token valid until the new one is first
+// used, so the requests in flight with the old token still succeed and a
+// rotation failing midway is retried with the old token.
+func (a *Device) rotateToken(info *metadata.DeviceInfo) error {
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.conf.LuminaServerAddr+rotateTokenPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+
+	var respBody dao.RotateDeviceTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return err
+	} else if respBody.Token == "" {
+		return fmt.Errorf("empty token rotated")
+	}
+	return a.db.UpdateDeviceInfo(&metadata.DeviceInfo{Token: &respBody.Token})
+}
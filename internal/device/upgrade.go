@@ -0,0 +1,182 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lumina/internal/dao"
+	"lumina/internal/device/metadata"
+	"lumina/internal/version"
+)
+
+const (
+	fetchUpgradeTaskPath    = "/api/v1/device/upgrade-task"
+	reportUpgradeResultPath = "/api/v1/device/upgrade-result"
+	downloadTimeout         = 10 * time.Minute
+)
+
+func (a *Device) fetchUpgradeTaskFromServer(info *metadata.DeviceInfo) (*dao.GetUpgradeTaskResponse, error) {
+	a.logger.Debugf("fetch upgrade task")
+
+	u := a.conf.LuminaServerAddr + fetchUpgradeTaskPath + "?version=" + url.QueryEscape(version.VERSION)
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+
+	var respBody dao.GetUpgradeTaskResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, err
+	}
+	return &respBody, nil
+}
+
+func (a *Device) syncUpgradeTaskFromServer() error {
+	if a.upgrading.Load() {
+		return nil
+	}
+	info, err := a.db.GetDeviceInfo()
+	if err != nil {
+		return err
+	} else if info == nil || info.Uuid == nil {
+		return errors.New("device Id is nil, please register device")
+	}
+
+	resp, err := a.fetchUpgradeTaskFromServer(info)
+	if err != nil {
+		return err
+	} else if resp.Task == nil {
+		return nil
+	}
+
+	if !a.upgrading.CompareAndSwap(false, true) {
+		return nil
+	}
+	a.logger.Infof("upgrade from %s to %s, rollout id: %d", version.VERSION, resp.Task.Version, resp.Task.RolloutId)
+	go a.runUpgradeTask(info, *resp.Task)
+	return nil
+}
+
+// runUpgradeTask replaces the running binary by the release and requests a
+// restart. A failure is reported to the server, a success is reported by the
+// restarted device fetching its next upgrade task with the new version.
+func (a *Device) runUpgradeTask(info *metadata.DeviceInfo, task dao.UpgradeTask) {
+	logger := a.logger.WithField("rolloutId", task.RolloutId)
+	if err := a.installRelease(task); err != nil {
+		a.upgrading.Store(false)
+		logger.WithError(err).Errorf("upgrade to %s failed", task.Version)
+		result := &dao.UpgradeResult{RolloutId: task.RolloutId, Error: err.Error()}
+		if err := a.reportUpgradeResult(info, result); err != nil {
+			logger.WithError(err).Errorf("report upgrade result failed")
+		}
+		return
+	}
+	logger.Infof("installed %s, restarting", task.Version)
+	close(a.restartCh)
+}
+
+func (a *Device) installRelease(task dao.UpgradeTask) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	// download next to the binary so that the rename stays on one filesystem
+	tmp := exe + ".download"
+	defer os.Remove(tmp)
+
+	ctx, cancel := context.WithTimeout(a.ctx, downloadTimeout)
+	defer cancel()
+	if err := downloadRelease(ctx, task.Url, tmp, task.Sha256); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp, exe)
+}
+
+// downloadRelease writes the file at u to dst and checks its sha256.
+func downloadRelease(ctx context.Context, u, dst, sum string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("download release failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download release failed, status code: %d", resp.StatusCode)
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("download release failed: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, sum) {
+		return fmt.Errorf("sha256 mismatch, want %s, got %s", sum, got)
+	}
+	return nil
+}
+
+func (a *Device) reportUpgradeResult(info *metadata.DeviceInfo, result *dao.UpgradeResult) error {
+	body, _ := json.Marshal(result)
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.conf.LuminaServerAddr+reportUpgradeResultPath, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// RestartRequested is closed once a new binary is installed, the device
+// must then be stopped and the binary executed again.
+func (a *Device) RestartRequested() <-chan struct{} {
+	return a.restartCh
+}
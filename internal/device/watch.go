@@ -0,0 +1,122 @@
+package device
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	watchRetryInterval = 5 * time.Second
+	// maxWatchTimeout is the longest wait accepted by the server
+	maxWatchTimeout = 60 * time.Second
+)
+
+// serverWatch long-polls a device endpoint of the server. While it is
+// watching, the main loop fetches the endpoint only when it changed.
+type serverWatch struct {
+	path string
+	// watching is set while the last watch request succeeded
+	watching atomic.Bool
+	// changed receives a value when the response changed
+	changed chan struct{}
+}
+
+func newServerWatch(path string) *serverWatch {
+	return &serverWatch{
+		path:    path,
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// runServerWatch long-polls the endpoint until the device stops. It gives up
+// if the server does not answer with an ETag, the endpoint is then only
+// fetched periodically.
+func (a *Device) runServerWatch(w *serverWatch) {
+	etag := ""
+	for {
+		newEtag, err := a.pollServer(w.path, etag)
+		if a.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			w.watching.Store(false)
+			a.logger.WithError(err).Warnf("watch %s failed", w.path)
+			select {
+			case <-a.ctx.Done():
+				return
+			case <-time.After(watchRetryInterval):
+			}
+			continue
+		}
+		if newEtag == "" {
+			w.watching.Store(false)
+			a.logger.Infof("server cannot watch %s, fetching it periodically", w.path)
+			return
+		}
+		w.watching.Store(true)
+		if newEtag != etag {
+			etag = newEtag
+			select {
+			case w.changed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// pollServer waits for the response of path to differ from etag and returns
+// its new ETag, or etag if it did not change before the timeout.
+func (a *Device) pollServer(path, etag string) (string, error) {
+	info, err := a.db.GetDeviceInfo()
+	if err != nil {
+		return "", err
+	} else if info == nil || info.Token == nil {
+		return "", fmt.Errorf("device is not registered")
+	}
+
+	timeout := a.conf.Watch.Timeout
+	if timeout < time.Second {
+		timeout = 30 * time.Second
+	}
+	timeout = min(timeout, maxWatchTimeout)
+	u, err := url.Parse(a.conf.LuminaServerAddr + path)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("watch", "true")
+	q.Set("timeout", strconv.Itoa(int(timeout.Seconds())))
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *info.Token))
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	// the request waits up to timeout on the server
+	cli := *a.httpCli
+	cli.Timeout += timeout
+	resp, err := cli.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Header.Get("ETag"), nil
+	case http.StatusNotModified:
+		return etag, nil
+	default:
+		return "", fmt.Errorf("http request failed, status code: %d", resp.StatusCode)
+	}
+}
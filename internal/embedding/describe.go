@@ -0,0 +1,40 @@
+package embedding
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Describe returns the text a message is embedded as: the workflow answer,
+// the detected labels and the time of day in the location of t, so that a
+// query such as "person climbing fence at night" matches on all three.
+func Describe(answer string, labels []string, t time.Time) string {
+	var b strings.Builder
+	b.WriteString(answer)
+	if len(labels) > 0 {
+		uniq := slices.Compact(slices.Sorted(slices.Values(labels)))
+		b.WriteString("\n检测到 detected: ")
+		b.WriteString(strings.Join(uniq, ", "))
+	}
+	b.WriteString("\n时间 time: ")
+	b.WriteString(timeOfDay(t.Hour()))
+	return b.String()
+}
+
+func timeOfDay(hour int) string {
+	switch {
+	case hour < 5:
+		return "夜间 night"
+	case hour < 8:
+		return "清晨 dawn"
+	case hour < 12:
+		return "上午 morning"
+	case hour < 18:
+		return "下午 afternoon"
+	case hour < 21:
+		return "傍晚 evening"
+	default:
+		return "夜间 night"
+	}
+}
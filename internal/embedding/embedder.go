@@ -0,0 +1,145 @@
+// Package embedding embeds the messages into vectors and stores them in a
+// vector store, so that they can be searched by meaning, e.g. "person
+// climbing fence at night".
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// InputText embeds the description of the messages with a text model
+	InputText = "text"
+	// InputImage embeds the images of the messages with a multimodal model
+	// sharing its space with the text, e.g. a CLIP model
+	InputImage = "image"
+)
+
+// Config is the embedding model and the vector store, the consumer and the
+// server must use the same.
+type Config struct {
+	// BaseUrl of an OpenAI compatible embeddings API
+	BaseUrl string        `yaml:"baseUrl"`
+	ApiKey  string        `yaml:"apiKey"`
+	Model   string        `yaml:"model"`
+	Timeout time.Duration `yaml:"timeout"`
+	// Input is text or image. Images are sent as {"image": url} inputs, as
+	// the multimodal embedding APIs take them, and the messages without an
+	// image fall back to their description.
+	Input string `yaml:"input"`
+	// Timezone the time of day of the messages is described in, the local
+	// one if empty
+	Timezone string       `yaml:"timezone"`
+	Qdrant   QdrantConfig `yaml:"qdrant"`
+}
+
+// Location returns the location of Timezone.
+func (c Config) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(c.Timezone)
+}
+
+func DefaultConfig() Config {
+	return Config{
+		BaseUrl: "https://api.openai.com/v1",
+		Model:   "text-embedding-3-small",
+		Timeout: 30 * time.Second,
+		Input:   InputText,
+		Qdrant: QdrantConfig{
+			URL:        "http://127.0.0.1:6333",
+			Collection: "lumina_messages",
+		},
+	}
+}
+
+// Input is a text or an image url to embed.
+type Input struct {
+	Text  string `json:"text,omitempty"`
+	Image string `json:"image,omitempty"`
+}
+
+// Embedder calls the embeddings API.
+type Embedder struct {
+	conf   Config
+	client *http.Client
+}
+
+func NewEmbedder(conf Config) *Embedder {
+	return &Embedder{
+		conf:   conf,
+		client: &http.Client{Timeout: conf.Timeout},
+	}
+}
+
+type embeddingRequest struct {
+	Model string `json:"model"`
+	// the plain strings of text inputs are understood by every API, the
+	// objects only by the multimodal ones
+	Input []any `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed returns the vectors of the inputs, in the same order.
+func (e *Embedder) Embed(ctx context.Context, inputs []Input) ([][]float32, error) {
+	req := embeddingRequest{Model: e.conf.Model, Input: make([]any, len(inputs))}
+	for i, in := range inputs {
+		if in.Image != "" {
+			req.Input[i] = in
+		} else {
+			req.Input[i] = in.Text
+		}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.conf.BaseUrl+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if e.conf.ApiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+e.conf.ApiKey)
+	}
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("embeddings api returned %d: %s", resp.StatusCode, msg)
+	}
+
+	var r embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(inputs))
+	for _, d := range r.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embeddings api returned index %d for %d inputs", d.Index, len(inputs))
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("embeddings api returned no vector for input %d", i)
+		}
+	}
+	return vectors, nil
+}
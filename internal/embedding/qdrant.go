@@ -0,0 +1,216 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type QdrantConfig struct {
+	URL        string `yaml:"url"`
+	ApiKey     string `yaml:"apiKey"`
+	Collection string `yaml:"collection"`
+}
+
+// Point is the vector of a message with the fields it is filtered by.
+type Point struct {
+	MessageId int
+	Vector    []float32
+	JobId     int
+	CameraId  int
+	Timestamp time.Time
+	Labels    []string
+	Alerted   bool
+}
+
+// SearchFilter restricts a search, zero fields are not filtered.
+type SearchFilter struct {
+	JobId    int
+	CameraId int
+	Start    time.Time
+	End      time.Time
+	Alerted  bool
+}
+
+// Hit is a message found by a search with its cosine similarity to the
+// query.
+type Hit struct {
+	MessageId int
+	Score     float32
+}
+
+// Store keeps the vectors of the messages in a Qdrant collection, the
+// message id is the point id so that upserts are idempotent.
+type Store struct {
+	conf   QdrantConfig
+	client *http.Client
+}
+
+func NewStore(conf QdrantConfig) *Store {
+	return &Store{
+		conf:   conf,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type qdrantPayload struct {
+	JobId     int      `json:"jobId"`
+	CameraId  int      `json:"cameraId"`
+	Timestamp int64    `json:"timestamp"`
+	Labels    []string `json:"labels"`
+	Alerted   bool     `json:"alerted"`
+}
+
+type qdrantPoint struct {
+	Id      int           `json:"id"`
+	Vector  []float32     `json:"vector"`
+	Payload qdrantPayload `json:"payload"`
+}
+
+// EnsureCollection creates the collection for vectors of size dim if it
+// does not exist, with indexes on the filtered fields.
+func (s *Store) EnsureCollection(ctx context.Context, dim int) error {
+	path := "/collections/" + url.PathEscape(s.conf.Collection)
+	status, err := s.do(ctx, http.MethodGet, path, nil, nil)
+	if err != nil && status != http.StatusNotFound {
+		return err
+	} else if status == http.StatusOK {
+		return nil
+	}
+
+	body := map[string]any{
+		"vectors": map[string]any{"size": dim, "distance": "Cosine"},
+	}
+	if _, err := s.do(ctx, http.MethodPut, path, body, nil); err != nil {
+		return err
+	}
+	for field, schema := range map[string]string{
+		"jobId": "integer", "cameraId": "integer", "timestamp": "integer", "alerted": "bool",
+	} {
+		index := map[string]string{"field_name": field, "field_schema": schema}
+		if _, err := s.do(ctx, http.MethodPut, path+"/index", index, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Upsert writes the points, replacing those of the same messages.
+func (s *Store) Upsert(ctx context.Context, points []Point) error {
+	body := struct {
+		Points []qdrantPoint `json:"points"`
+	}{Points: make([]qdrantPoint, len(points))}
+	for i, p := range points {
+		labels := p.Labels
+		if labels == nil {
+			labels = []string{}
+		}
+		body.Points[i] = qdrantPoint{
+			Id:     p.MessageId,
+			Vector: p.Vector,
+			Payload: qdrantPayload{
+				JobId:     p.JobId,
+				CameraId:  p.CameraId,
+				Timestamp: p.Timestamp.Unix(),
+				Labels:    labels,
+				Alerted:   p.Alerted,
+			},
+		}
+	}
+	path := "/collections/" + url.PathEscape(s.conf.Collection) + "/points?wait=true"
+	_, err := s.do(ctx, http.MethodPut, path, body, nil)
+	return err
+}
+
+// Search returns the messages most similar to the vector, best first, with
+// a similarity of at least minScore.
+func (s *Store) Search(ctx context.Context, vector []float32, filter SearchFilter, limit int, minScore float32) ([]Hit, error) {
+	var must []map[string]any
+	match := func(key string, value any) {
+		must = append(must, map[string]any{"key": key, "match": map[string]any{"value": value}})
+	}
+	if filter.JobId != 0 {
+		match("jobId", filter.JobId)
+	}
+	if filter.CameraId != 0 {
+		match("cameraId", filter.CameraId)
+	}
+	if filter.Alerted {
+		match("alerted", true)
+	}
+	if !filter.Start.IsZero() || !filter.End.IsZero() {
+		r := map[string]any{}
+		if !filter.Start.IsZero() {
+			r["gte"] = filter.Start.Unix()
+		}
+		if !filter.End.IsZero() {
+			r["lt"] = filter.End.Unix()
+		}
+		must = append(must, map[string]any{"key": "timestamp", "range": r})
+	}
+	body := map[string]any{
+		"vector": vector,
+		"limit":  limit,
+	}
+	if minScore > 0 {
+		body["score_threshold"] = minScore
+	}
+	if len(must) > 0 {
+		body["filter"] = map[string]any{"must": must}
+	}
+
+	var resp struct {
+		Result []struct {
+			Id    int     `json:"id"`
+			Score float32 `json:"score"`
+		} `json:"result"`
+	}
+	path := "/collections/" + url.PathEscape(s.conf.Collection) + "/points/search"
+	if _, err := s.do(ctx, http.MethodPost, path, body, &resp); err != nil {
+		return nil, err
+	}
+	hits := make([]Hit, len(resp.Result))
+	for i, r := range resp.Result {
+		hits[i] = Hit{MessageId: r.Id, Score: r.Score}
+	}
+	return hits, nil
+}
+
+// do sends the request and decodes the response into out if not nil, it
+// returns the status code along with the error.
+func (s *Store) do(ctx context.Context, method, path string, body, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.conf.URL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.conf.ApiKey != "" {
+		req.Header.Set("api-key", s.conf.ApiKey)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("qdrant %s %s returned %d: %s", method, path, resp.StatusCode, msg)
+	}
+	if out != nil {
+		return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode, nil
+}
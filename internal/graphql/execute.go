@@ -0,0 +1,355 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// Resolver returns the value of a field of source.
+type Resolver func(ctx context.Context, source any, args Args) (any, error)
+
+// FieldDef is a field declared on an object type. A field of an object type
+// resolves to a value of the type or a slice of them, a field without type to
+// a value served as JSON whose objects can still be narrowed by a selection.
+type FieldDef struct {
+	Type    *Object
+	Resolve Resolver
+}
+
+// Object is an object type. Fields not declared are looked up in the JSON
+// encoding of the source, so that a type only declares the fields that need
+// to be resolved.
+type Object struct {
+	Name   string
+	Fields map[string]*FieldDef
+}
+
+type Schema struct {
+	Query *Object
+	// MaxDepth limits the nesting of selections, 0 for no limit
+	MaxDepth int
+}
+
+type Request struct {
+	Query         string         `json:"query" binding:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+type Response struct {
+	Data   any      `json:"data"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Prepare parses the query of the request and returns the operation to
+// execute.
+func (s *Schema) Prepare(req *Request) (*Operation, error) {
+	ops, err := Parse(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	var op *Operation
+	if req.OperationName == "" {
+		if len(ops) > 1 {
+			return nil, fmt.Errorf("operationName is required for a document with several operations")
+		}
+		op = ops[0]
+	} else {
+		for _, o := range ops {
+			if o.Name == req.OperationName {
+				op = o
+			}
+		}
+		if op == nil {
+			return nil, fmt.Errorf("operation %s not found", req.OperationName)
+		}
+	}
+	if s.MaxDepth > 0 && depth(op.Selections) > s.MaxDepth {
+		return nil, fmt.Errorf("query is nested deeper than %d", s.MaxDepth)
+	}
+	return op, nil
+}
+
+func depth(fields []*Field) int {
+	d := 0
+	for _, f := range fields {
+		d = max(d, depth(f.Selections))
+	}
+	if len(fields) == 0 {
+		return 0
+	}
+	return d + 1
+}
+
+// Execute executes the operation. Errors of fields are reported in the
+// response along with the other fields.
+func (s *Schema) Execute(ctx context.Context, op *Operation, variables map[string]any) *Response {
+	vars := make(map[string]any, len(op.Defaults)+len(variables))
+	for k, v := range op.Defaults {
+		vars[k] = v.resolve(nil)
+	}
+	for k, v := range variables {
+		vars[k] = v
+	}
+	e := &executor{vars: vars}
+	data := e.object(ctx, s.Query, nil, op.Selections, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+type executor struct {
+	vars   map[string]any
+	errors []*Error
+}
+
+func (e *executor) errorf(path []any, format string, args ...any) {
+	e.errors = append(e.errors, &Error{
+		Message: fmt.Sprintf(format, args...),
+		Path:    append([]any(nil), path...),
+	})
+}
+
+func (e *executor) object(ctx context.Context, typ *Object, source any, fields []*Field, path []any) *orderedMap {
+	out := &orderedMap{}
+	var plain map[string]any
+	for _, f := range fields {
+		key := f.Key()
+		fieldPath := append(path[:len(path):len(path)], key)
+		if f.Name == "__typename" {
+			out.set(key, typ.Name)
+			continue
+		}
+
+		def, ok := typ.Fields[f.Name]
+		if !ok {
+			if plain == nil {
+				v, err := toPlain(source)
+				if err != nil {
+					e.errorf(fieldPath, "%s", err)
+					out.set(key, nil)
+					continue
+				}
+				plain, _ = v.(map[string]any)
+			}
+			v, ok := plain[f.Name]
+			if !ok && !knownField(reflect.TypeOf(source), f.Name) {
+				e.errorf(fieldPath, "cannot query field %q on type %s", f.Name, typ.Name)
+			}
+			out.set(key, e.project(v, f.Selections, fieldPath))
+			continue
+		}
+
+		args := make(Args, len(f.Args))
+		for name, v := range f.Args {
+			args[name] = v.resolve(e.vars)
+		}
+		v, err := def.Resolve(ctx, source, args)
+		if err != nil {
+			e.errorf(fieldPath, "%s", err)
+			out.set(key, nil)
+			continue
+		}
+		out.set(key, e.complete(ctx, def.Type, v, f, fieldPath))
+	}
+	return out
+}
+
+// knownField tells if a field left out of the JSON encoding of the source by
+// omitempty is one of its fields.
+func knownField(t reflect.Type, name string) bool {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		n, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if n == name || sf.Anonymous && n == "" && knownField(sf.Type, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *executor) complete(ctx context.Context, typ *Object, v any, f *Field, path []any) any {
+	if isNil(v) {
+		return nil
+	}
+	if typ == nil {
+		plain, err := toPlain(v)
+		if err != nil {
+			e.errorf(path, "%s", err)
+			return nil
+		}
+		return e.project(plain, f.Selections, path)
+	}
+	if len(f.Selections) == 0 {
+		e.errorf(path, "field %q of type %s must have a selection of subfields", f.Name, typ.Name)
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return e.object(ctx, typ, v, f.Selections, path)
+	}
+	items := make([]any, rv.Len())
+	for i := range items {
+		item := rv.Index(i).Interface()
+		if rv.Index(i).Kind() == reflect.Struct {
+			// undeclared fields of the item are served from its JSON encoding
+			// either way, a pointer lets resolvers use a single type
+			item = rv.Index(i).Addr().Interface()
+		}
+		items[i] = e.object(ctx, typ, item, f.Selections, append(path[:len(path):len(path)], i))
+	}
+	return items
+}
+
+// project narrows a JSON value to the selection, objects of lists included.
+func (e *executor) project(v any, fields []*Field, path []any) any {
+	if len(fields) == 0 || v == nil {
+		return v
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		out := &orderedMap{}
+		for _, f := range fields {
+			out.set(f.Key(), e.project(v[f.Name], f.Selections, append(path[:len(path):len(path)], f.Key())))
+		}
+		return out
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = e.project(item, fields, append(path[:len(path):len(path)], i))
+		}
+		return items
+	default:
+		e.errorf(path, "field has no subfields to select")
+		return nil
+	}
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func toPlain(v any) (any, error) {
+	if m, ok := v.(map[string]any); ok {
+		return m, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var plain any
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
+
+// orderedMap is an object of the response, its fields keep the order of the
+// selection.
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) set(key string, v any) {
+	if m.values == nil {
+		m.values = map[string]any{}
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = v
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		kb, _ := json.Marshal(k)
+		buf.Write(kb)
+		buf.WriteByte(':')
+		vb, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Args are the arguments of a field, nil for those left out.
+type Args map[string]any
+
+// Int returns the argument as an int, or def if it is left out.
+func (a Args) Int(name string, def int) (int, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return def, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("argument %s must be an int", name)
+		}
+		return int(v), nil
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("argument %s must be an int", name)
+		}
+		return int(n), nil
+	default:
+		return 0, fmt.Errorf("argument %s must be an int", name)
+	}
+}
+
+// String returns the argument as a string, empty if it is left out.
+func (a Args) String(name string) (string, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("argument %s must be a string", name)
+	}
+}
+
+// Bool returns the argument as a bool, false if it is left out.
+func (a Args) Bool(name string) (bool, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("argument %s must be a bool", name)
+	}
+}
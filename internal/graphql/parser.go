@@ -0,0 +1,505 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Field is a field of a selection set.
+type Field struct {
+	Alias      string
+	Name       string
+	Args       map[string]Value
+	Selections []*Field
+}
+
+// Key is the name of the field in the response.
+func (f *Field) Key() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// Operation is a query of a document.
+type Operation struct {
+	Name string
+	// Defaults are the default values of the variables
+	Defaults   map[string]Value
+	Selections []*Field
+}
+
+type Value interface {
+	resolve(vars map[string]any) any
+}
+
+type constValue struct{ v any }
+
+func (c constValue) resolve(map[string]any) any { return c.v }
+
+type variable string
+
+// resolve returns nil for a variable not given, as for an argument left out.
+func (v variable) resolve(vars map[string]any) any { return vars[string(v)] }
+
+type listValue []Value
+
+func (l listValue) resolve(vars map[string]any) any {
+	out := make([]any, len(l))
+	for i, v := range l {
+		out[i] = v.resolve(vars)
+	}
+	return out
+}
+
+type objectValue map[string]Value
+
+func (o objectValue) resolve(vars map[string]any) any {
+	out := make(map[string]any, len(o))
+	for k, v := range o {
+		out[k] = v.resolve(vars)
+	}
+	return out
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+// Parse parses a document and returns its operations. Fragments, directives
+// and mutations are not supported.
+func Parse(src string) ([]*Operation, error) {
+	p := &parser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var ops []*Operation
+	for p.tok.kind != tokenEOF {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("document has no operation")
+	}
+	return ops, nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("syntax error at %d: %s", p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) next() error {
+	// commas are insignificant like whitespace
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+		} else if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		} else {
+			break
+		}
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokenEOF, pos: start}
+		return nil
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{kind: tokenPunct, value: "...", pos: start}
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		p.pos++
+		p.tok = token{kind: tokenPunct, value: string(c), pos: start}
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: tokenName, value: p.src[start:p.pos], pos: start}
+	case c == '-' || isDigit(c):
+		return p.lexNumber()
+	case c == '"':
+		return p.lexString()
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		p.tok = token{pos: start}
+		return p.errorf("unexpected character %q", r)
+	}
+	return nil
+}
+
+func (p *parser) lexNumber() error {
+	start := p.pos
+	kind := tokenInt
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() {
+		for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+			p.pos++
+		}
+	}
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		kind = tokenFloat
+		p.pos++
+		digits()
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		kind = tokenFloat
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+	}
+	p.tok = token{kind: kind, value: p.src[start:p.pos], pos: start}
+	return nil
+}
+
+func (p *parser) lexString() error {
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			p.tok = token{pos: start}
+			return p.errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		if c == '"' {
+			p.pos++
+			break
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			p.pos++
+			continue
+		}
+		if p.pos+1 >= len(p.src) {
+			p.tok = token{pos: start}
+			return p.errorf("unterminated string")
+		}
+		esc := p.src[p.pos+1]
+		p.pos += 2
+		switch esc {
+		case '"', '\\', '/':
+			b.WriteByte(esc)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			if p.pos+4 > len(p.src) {
+				p.tok = token{pos: start}
+				return p.errorf("invalid unicode escape")
+			}
+			r, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+			if err != nil {
+				p.tok = token{pos: start}
+				return p.errorf("invalid unicode escape")
+			}
+			b.WriteRune(rune(r))
+			p.pos += 4
+		default:
+			p.tok = token{pos: start}
+			return p.errorf("invalid escape \\%c", esc)
+		}
+	}
+	p.tok = token{kind: tokenString, value: b.String(), pos: start}
+	return nil
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == punct
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return p.errorf("expected %q", punct)
+	}
+	return p.next()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.errorf("expected name")
+	}
+	name := p.tok.value
+	return name, p.next()
+}
+
+func (p *parser) parseOperation() (*Operation, error) {
+	op := &Operation{}
+	if p.tok.kind == tokenName {
+		switch p.tok.value {
+		case "query":
+		case "mutation", "subscription":
+			return nil, p.errorf("%s is not supported", p.tok.value)
+		case "fragment":
+			return nil, p.errorf("fragments are not supported")
+		default:
+			return nil, p.errorf("unexpected %q", p.tok.value)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenName {
+			op.Name = p.tok.value
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek("(") {
+			defaults, err := p.parseVariableDefinitions()
+			if err != nil {
+				return nil, err
+			}
+			op.Defaults = defaults
+		}
+	}
+	sels, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.Selections = sels
+	return op, nil
+}
+
+// parseVariableDefinitions keeps the default values, types are not checked.
+func (p *parser) parseVariableDefinitions() (map[string]Value, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	defaults := map[string]Value{}
+	for !p.peek(")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if err := p.skipType(); err != nil {
+			return nil, err
+		}
+		if p.peek("=") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			v, err := p.parseValue(true)
+			if err != nil {
+				return nil, err
+			}
+			defaults[name] = v
+		}
+	}
+	return defaults, p.next()
+}
+
+func (p *parser) skipType() error {
+	if p.peek("[") {
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.peek("!") {
+		return p.next()
+	}
+	return nil
+}
+
+func (p *parser) parseSelectionSet() ([]*Field, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var fields []*Field
+	for !p.peek("}") {
+		if p.peek("...") {
+			return nil, p.errorf("fragments are not supported")
+		}
+		f, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	return fields, p.next()
+}
+
+func (p *parser) parseField() (*Field, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	f := &Field{Name: name}
+	if p.peek(":") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if f.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		f.Alias = name
+	}
+	if p.peek("(") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		f.Args = map[string]Value{}
+		for !p.peek(")") {
+			arg, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if f.Args[arg], err = p.parseValue(false); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("@") {
+		return nil, p.errorf("directives are not supported")
+	}
+	if p.peek("{") {
+		if f.Selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) parseValue(isConst bool) (Value, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenPunct:
+		switch tok.value {
+		case "$":
+			if isConst {
+				return nil, p.errorf("variable not allowed in default value")
+			}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			return variable(name), err
+		case "[":
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			list := listValue{}
+			for !p.peek("]") {
+				v, err := p.parseValue(isConst)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			return list, p.next()
+		case "{":
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			obj := objectValue{}
+			for !p.peek("}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				if obj[name], err = p.parseValue(isConst); err != nil {
+					return nil, err
+				}
+			}
+			return obj, p.next()
+		}
+	case tokenInt:
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid int %s", tok.value)
+		}
+		return constValue{n}, p.next()
+	case tokenFloat:
+		n, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, p.errorf("invalid float %s", tok.value)
+		}
+		return constValue{n}, p.next()
+	case tokenString:
+		return constValue{tok.value}, p.next()
+	case tokenName:
+		var v any
+		switch tok.value {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			// enum values are passed as strings
+			v = tok.value
+		}
+		return constValue{v}, p.next()
+	}
+	return nil, p.errorf("expected value")
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type AgentProposalKind string
+
+const (
+	AgentProposalCreateJob      AgentProposalKind = "create_job"
+	AgentProposalUpdateJob      AgentProposalKind = "update_job"
+	AgentProposalCreateWorkflow AgentProposalKind = "create_workflow"
+	AgentProposalUpdateWorkflow AgentProposalKind = "update_workflow"
+)
+
+const (
+	agentProposalKeyTemplate = "agent-proposal:%s"
+	// AgentProposalExpire is how long a proposal waits for the approval of
+	// the user
+	AgentProposalExpire = 30 * time.Minute
+)
+
+// AgentProposal is a change the assistant proposes, it is only applied once
+// the user who asked for it approves it.
+type AgentProposal struct {
+	Uuid string            `json:"uuid"`
+	Kind AgentProposalKind `json:"kind"`
+	// TargetId is the job or workflow updated, 0 for creations
+	TargetId int `json:"targetId,omitempty"`
+	// Request is the API request applied on approval
+	Request    json.RawMessage `json:"request"`
+	Summary    string          `json:"summary"`
+	UserId     int             `json:"userId"`
+	CreateTime time.Time       `json:"createTime"`
+}
+
+func agentProposalKey(uuid string) string {
+	return fmt.Sprintf(agentProposalKeyTemplate, uuid)
+}
+
+func SaveAgentProposal(ctx context.Context, p *AgentProposal) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return Redis.Set(ctx, agentProposalKey(p.Uuid), data, AgentProposalExpire).Err()
+}
+
+// GetAgentProposal returns the proposal, nil if it does not exist or expired.
+func GetAgentProposal(ctx context.Context, uuid string) (*AgentProposal, error) {
+	data, err := Redis.Get(ctx, agentProposalKey(uuid)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var p AgentProposal
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// TakeAgentProposal removes the proposal and reports whether it was still
+// pending, so that a proposal is applied or rejected only once.
+func TakeAgentProposal(ctx context.Context, uuid string) (bool, error) {
+	n, err := Redis.Del(ctx, agentProposalKey(uuid)).Result()
+	return n > 0, err
+}
@@ -0,0 +1,296 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AlertStatus string
+
+const (
+	AlertStatusOpen         AlertStatus = "open"
+	AlertStatusAcknowledged AlertStatus = "acknowledged"
+	AlertStatusResolved     AlertStatus = "resolved"
+)
+
+type AlertAction string
+
+const (
+	AlertActionAck     AlertAction = "ack"
+	AlertActionAssign  AlertAction = "assign"
+	AlertActionComment AlertAction = "comment"
+	AlertActionResolve AlertAction = "resolve"
+)
+
+// alertDedupKey returns the key of the alert of the message: its job and the
+// distinct labels of its boxes, so that the same scene detected again
+// collapses into the same alert.
+func alertDedupKey(m *Message) string {
+	seen := make(map[string]struct{})
+	var labels []string
+	for _, box := range m.DetectBoxes {
+		if box == nil {
+			continue
+		}
+		if _, ok := seen[box.Label]; ok {
+			continue
+		}
+		seen[box.Label] = struct{}{}
+		labels = append(labels, box.Label)
+	}
+	sort.Strings(labels)
+	key := fmt.Sprintf("%d:%s", m.JobId, strings.Join(labels, ","))
+	if len(key) > 255 {
+		key = key[:255]
+	}
+	return key
+}
+
+// AlertActivity records who handled an alert, how and when.
+type AlertActivity struct {
+	Id       int         `gorm:"primaryKey"`
+	AlertId  int         `gorm:"type:int;index"`
+	Action   AlertAction `gorm:"type:char(16)"`
+	UserId   int         `gorm:"type:int"`
+	Username string      `gorm:"type:char(96)"`
+	// AssigneeId is the user the alert was assigned to by an assign action
+	AssigneeId int `gorm:"type:int;default:0"`
+	// Content is the text of a comment, or of the note of a resolve action
+	Content    string    `gorm:"type:text"`
+	CreateTime time.Time `gorm:"type:datetime;autoCreateTime"`
+}
+
+// AckAlertMessage acknowledges the alert, the first acknowledgement is kept.
+func AckAlertMessage(alert *AlertMessage, user *User, t time.Time) error {
+	if alert.AckTime != nil {
+		return nil
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(alert).Where("ack_time IS NULL").UpdateColumns(map[string]any{
+			"ack_time":    t,
+			"ack_user_id": user.Id,
+			"status":      AlertStatusAcknowledged,
+		})
+		if res.Error != nil {
+			return res.Error
+		} else if res.RowsAffected == 0 {
+			// acknowledged meanwhile
+			return tx.First(alert, alert.Id).Error
+		}
+		alert.AckTime = &t
+		alert.AckUserId = user.Id
+		alert.Status = AlertStatusAcknowledged
+		return addAlertActivity(tx, alert, user, AlertActionAck, 0, "")
+	})
+}
+
+// AssignAlertMessage assigns the alert to the user assigneeId, 0 unassigns
+// it.
+func AssignAlertMessage(alert *AlertMessage, user *User, assigneeId int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(alert).UpdateColumn("assignee_id", assigneeId).Error; err != nil {
+			return err
+		}
+		alert.AssigneeId = assigneeId
+		return addAlertActivity(tx, alert, user, AlertActionAssign, assigneeId, "")
+	})
+}
+
+// ResolveAlertMessage resolves the alert, which acknowledges it if nobody did.
+func ResolveAlertMessage(alert *AlertMessage, user *User, t time.Time, note string) error {
+	if alert.Status == AlertStatusResolved {
+		return nil
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]any{
+			"status":          AlertStatusResolved,
+			"resolve_time":    t,
+			"resolve_user_id": user.Id,
+		}
+		if alert.AckTime == nil {
+			updates["ack_time"] = t
+			updates["ack_user_id"] = user.Id
+		}
+		if err := tx.Model(alert).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+		if alert.AckTime == nil {
+			alert.AckTime = &t
+			alert.AckUserId = user.Id
+		}
+		alert.Status = AlertStatusResolved
+		alert.ResolveTime = &t
+		alert.ResolveUserId = user.Id
+		return addAlertActivity(tx, alert, user, AlertActionResolve, 0, note)
+	})
+}
+
+// CommentAlertMessage adds a comment to the alert.
+func CommentAlertMessage(alert *AlertMessage, user *User, content string) error {
+	return addAlertActivity(DB, alert, user, AlertActionComment, 0, content)
+}
+
+func addAlertActivity(tx *gorm.DB, alert *AlertMessage, user *User, action AlertAction, assigneeId int, content string) error {
+	return tx.Create(&AlertActivity{
+		AlertId:    alert.Id,
+		Action:     action,
+		UserId:     user.Id,
+		Username:   user.Username,
+		AssigneeId: assigneeId,
+		Content:    content,
+	}).Error
+}
+
+// ListAlertActivities returns the activities of the alert, the oldest first.
+func ListAlertActivities(alertId int) ([]AlertActivity, error) {
+	var as []AlertActivity
+	if err := DB.Where("alert_id = ?", alertId).Order("id").Find(&as).Error; err != nil {
+		return nil, err
+	}
+	return as, nil
+}
+
+// AlertStats counts the alerts created in a time range by status, with the
+// time they took to be acknowledged and resolved, in seconds.
+type AlertStats struct {
+	Open         int64
+	Acknowledged int64
+	Resolved     int64
+	AvgAck       float64
+	AvgResolve   float64
+	// AckedInSla counts the alerts acknowledged within the SLA
+	AckedInSla int64
+}
+
+// GetAlertStats returns the stats of the alerts of the organization created
+// in [start, end), of the job if jobId is not 0. The SLA is in seconds.
+func GetAlertStats(orgId int, start, end time.Time, jobId, sla int) (*AlertStats, error) {
+	var stats AlertStats
+	tx := DB.Model(&AlertMessage{}).
+		Select("COALESCE(SUM(CASE WHEN alert_messages.status = ? THEN 1 ELSE 0 END), 0) AS open, "+
+			"COALESCE(SUM(CASE WHEN alert_messages.status = ? THEN 1 ELSE 0 END), 0) AS acknowledged, "+
+			"COALESCE(SUM(CASE WHEN alert_messages.status = ? THEN 1 ELSE 0 END), 0) AS resolved, "+
+			"COALESCE(AVG("+secondsBetween("alert_messages.create_time", "alert_messages.ack_time")+"), 0) AS avg_ack, "+
+			"COALESCE(AVG("+secondsBetween("alert_messages.create_time", "alert_messages.resolve_time")+"), 0) AS avg_resolve, "+
+			"COALESCE(SUM(CASE WHEN "+secondsBetween("alert_messages.create_time", "alert_messages.ack_time")+" <= ? THEN 1 ELSE 0 END), 0) AS acked_in_sla",
+			AlertStatusOpen, AlertStatusAcknowledged, AlertStatusResolved, sla).
+		Where("alert_messages.create_time >= ? AND alert_messages.create_time < ?", start, end)
+	if jobId != 0 || orgId != AllOrgs {
+		tx = tx.Joins("JOIN messages ON messages.id = alert_messages.message_id").
+			Scopes(InOrgOf("messages", orgId))
+	}
+	if jobId != 0 {
+		tx = tx.Where("messages.job_id = ?", jobId)
+	}
+	if err := tx.Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// AlertGalleryQuery selects the alerts of the gallery, a zero JobId,
+// CameraId or Status matches any alert.
+type AlertGalleryQuery struct {
+	OrgId    int
+	Start    time.Time
+	End      time.Time
+	Bucket   time.Duration
+	JobId    int
+	CameraId int
+	Status   AlertStatus
+	// Thumbnails is the number of the latest images kept per bucket and
+	// camera
+	Thumbnails int
+}
+
+// AlertThumbnail is the image of the message that raised an alert.
+type AlertThumbnail struct {
+	MessageId int
+	ImagePath string
+}
+
+// AlertGalleryGroup counts the alerts of a camera created in a bucket of the
+// gallery.
+type AlertGalleryGroup struct {
+	// Bucket is the index of the bucket from the start of the range
+	Bucket      int
+	CameraId    int
+	CameraName  string
+	Count       int64
+	Occurrences int64
+	Thumbnails  []AlertThumbnail
+}
+
+// GetAlertGallery groups the alerts created in [Start, End) by bucket and
+// camera in a single query, the buckets with no alert are left out. The
+// groups are ordered by bucket then camera.
+func GetAlertGallery(q AlertGalleryQuery) ([]AlertGalleryGroup, error) {
+	if err := requireCapability(CapGroupConcat); err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Bucket      int
+		CameraId    int
+		CameraName  string
+		Count       int64
+		Occurrences int64
+		// the latest "messageId:imagePath" of the group separated by
+		// newlines, GROUP_CONCAT truncating the oldest first
+		Images string
+	}
+	tx := DB.Model(&AlertMessage{}).
+		Select("TIMESTAMPDIFF(SECOND, ?, alert_messages.create_time) DIV ? AS bucket, "+
+			"jobs.camera_id AS camera_id, COALESCE(cameras.name, '') AS camera_name, "+
+			"COUNT(*) AS count, COALESCE(SUM(alert_messages.occurrences), 0) AS occurrences, "+
+			"COALESCE(SUBSTRING_INDEX(GROUP_CONCAT("+
+			"IF(messages.image_path <> '', CONCAT(messages.id, ':', messages.image_path), NULL) "+
+			"ORDER BY alert_messages.id DESC SEPARATOR '\\n'), '\\n', ?), '') AS images",
+			q.Start, int64(q.Bucket/time.Second), q.Thumbnails).
+		Joins("JOIN messages ON messages.id = alert_messages.message_id").
+		Joins("JOIN jobs ON jobs.id = messages.job_id").
+		Joins("LEFT JOIN cameras ON cameras.id = jobs.camera_id").
+		Where("alert_messages.create_time >= ? AND alert_messages.create_time < ?", q.Start, q.End).
+		Scopes(InOrgOf("messages", q.OrgId))
+	if q.JobId != 0 {
+		tx = tx.Where("messages.job_id = ?", q.JobId)
+	}
+	if q.CameraId != 0 {
+		tx = tx.Where("jobs.camera_id = ?", q.CameraId)
+	}
+	if q.Status != "" {
+		tx = tx.Where("alert_messages.status = ?", q.Status)
+	}
+	if err := tx.Group("bucket, jobs.camera_id, cameras.name").
+		Order("bucket, jobs.camera_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	groups := make([]AlertGalleryGroup, len(rows))
+	for i, r := range rows {
+		groups[i] = AlertGalleryGroup{
+			Bucket:      r.Bucket,
+			CameraId:    r.CameraId,
+			CameraName:  r.CameraName,
+			Count:       r.Count,
+			Occurrences: r.Occurrences,
+		}
+		for _, image := range strings.Split(r.Images, "\n") {
+			id, path, ok := strings.Cut(image, ":")
+			if !ok {
+				continue
+			}
+			messageId, err := strconv.Atoi(id)
+			if err != nil {
+				continue
+			}
+			groups[i].Thumbnails = append(groups[i].Thumbnails, AlertThumbnail{MessageId: messageId, ImagePath: path})
+		}
+	}
+	return groups, nil
+}
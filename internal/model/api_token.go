@@ -0,0 +1,100 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The scopes an API token may be limited to.
+const (
+	ApiTokenScopeAll       = "all"
+	ApiTokenScopeRead      = "read"
+	ApiTokenScopeJobs      = "jobs"
+	ApiTokenScopeCameras   = "cameras"
+	ApiTokenScopeDevices   = "devices"
+	ApiTokenScopeWorkflows = "workflows"
+	ApiTokenScopeMessages  = "messages"
+)
+
+// apiTokenTouchInterval is how stale the last use of a token gets before
+// it is written again, to spare a write per request.
+const apiTokenTouchInterval = time.Minute
+
+// ApiToken is a named token a user created to call the API, only the hash
+// of the token is kept.
+type ApiToken struct {
+	Id        int    `gorm:"primarykey"`
+	UserId    int    `gorm:"index;not null"`
+	Name      string `gorm:"type:varchar(64);not null"`
+	TokenHash string `gorm:"type:char(64);uniqueIndex;not null"`
+	// Hint is the start of the token, for the users to tell them apart
+	Hint   string      `gorm:"type:varchar(16)"`
+	Scopes StringSlice `gorm:"type:json"`
+	// ExpireTime is nil for the tokens that never expire
+	ExpireTime   *time.Time
+	LastUsedTime *time.Time
+	LastUsedIp   string    `gorm:"type:varchar(64)"`
+	CreatedTime  time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+// HashApiToken returns the hash an API token is stored as.
+func HashApiToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Expired reports whether the token has expired by now.
+func (t *ApiToken) Expired(now time.Time) bool {
+	return t.ExpireTime != nil && !now.Before(*t.ExpireTime)
+}
+
+func CreateApiToken(token *ApiToken) error {
+	return DB.Create(token).Error
+}
+
+// GetApiToken returns nil if the token doesn't exist.
+func GetApiToken(id int) (*ApiToken, error) {
+	var token ApiToken
+	err := DB.First(&token, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &token, err
+}
+
+// GetApiTokenByToken returns the API token of a token as presented by a
+// request, nil if there is none.
+func GetApiTokenByToken(token string) (*ApiToken, error) {
+	var apiToken ApiToken
+	err := DB.Where("token_hash = ?", HashApiToken(token)).First(&apiToken).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &apiToken, err
+}
+
+func ListApiTokens(userId int) ([]*ApiToken, error) {
+	var tokens []*ApiToken
+	err := DB.Where("user_id = ?", userId).Order("id desc").Find(&tokens).Error
+	return tokens, err
+}
+
+func DeleteApiToken(id int) error {
+	return DB.Delete(&ApiToken{}, id).Error
+}
+
+// TouchApiToken records a use of the token, unless its last use was
+// recorded less than a minute ago.
+func TouchApiToken(token *ApiToken, ip string, now time.Time) error {
+	if token.LastUsedTime != nil && now.Sub(*token.LastUsedTime) < apiTokenTouchInterval {
+		return nil
+	}
+	return DB.Model(&ApiToken{}).Where("id = ?", token.Id).Updates(map[string]any{
+		"last_used_time": now,
+		"last_used_ip":   ip,
+	}).Error
+}
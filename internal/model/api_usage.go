@@ -0,0 +1,107 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ApiConsumerKind string
+
+const (
+	ApiConsumerUser   ApiConsumerKind = "user"
+	ApiConsumerDevice ApiConsumerKind = "device"
+	// ApiConsumerAnonymous is a client without credentials, named by its IP
+	ApiConsumerAnonymous ApiConsumerKind = "anonymous"
+)
+
+// ApiUsage counts the requests a consumer made to an endpoint within an
+// hour. The servers add their counts to the same rows.
+type ApiUsage struct {
+	Id           int             `gorm:"primaryKey"`
+	Hour         time.Time       `gorm:"type:datetime;uniqueIndex:idx_api_usage,priority:1"`
+	ConsumerKind ApiConsumerKind `gorm:"type:char(16);uniqueIndex:idx_api_usage,priority:2"`
+	// Consumer is the username, the device uuid or the IP of the consumer
+	Consumer string `gorm:"type:varchar(128);uniqueIndex:idx_api_usage,priority:3"`
+	Method   string `gorm:"type:char(8);uniqueIndex:idx_api_usage,priority:4"`
+	// Endpoint is the route of the request, e.g. /api/v1/job/:job_id
+	Endpoint string `gorm:"type:varchar(255);uniqueIndex:idx_api_usage,priority:5"`
+	Requests int64
+	// ClientErrors and ServerErrors count the 4xx and the 5xx responses
+	ClientErrors int64
+	ServerErrors int64
+	// TotalLatency and MaxLatency are in microseconds
+	TotalLatency int64
+	MaxLatency   int64
+}
+
+// AddApiUsage adds the counts to the ones already recorded.
+func AddApiUsage(us []*ApiUsage) error {
+	if len(us) == 0 {
+		return nil
+	}
+	return DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "hour"}, {Name: "consumer_kind"}, {Name: "consumer"},
+			{Name: "method"}, {Name: "endpoint"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"requests":      gorm.Expr(upsertAdd("requests")),
+			"client_errors": gorm.Expr(upsertAdd("client_errors")),
+			"server_errors": gorm.Expr(upsertAdd("server_errors")),
+			"total_latency": gorm.Expr(upsertAdd("total_latency")),
+			"max_latency":   gorm.Expr(upsertMax("max_latency")),
+		}),
+	}).Create(us).Error
+}
+
+// ApiUsageSummary sums the usage of a consumer, or of an endpoint.
+type ApiUsageSummary struct {
+	ConsumerKind ApiConsumerKind
+	Consumer     string
+	Method       string
+	Endpoint     string
+	Requests     int64
+	ClientErrors int64
+	ServerErrors int64
+	TotalLatency int64
+	MaxLatency   int64
+}
+
+const apiUsageSums = "SUM(requests) AS requests, SUM(client_errors) AS client_errors, " +
+	"SUM(server_errors) AS server_errors, SUM(total_latency) AS total_latency, MAX(max_latency) AS max_latency"
+
+// ListTopApiConsumers returns the consumers with the most requests in the
+// hours in [start, end).
+func ListTopApiConsumers(start, end time.Time, limit int) ([]*ApiUsageSummary, error) {
+	var res []*ApiUsageSummary
+	err := DB.Model(&ApiUsage{}).
+		Select("consumer_kind, consumer, "+apiUsageSums).
+		Where("hour >= ? AND hour < ?", start, end).
+		Group("consumer_kind, consumer").
+		Order("requests desc").
+		Limit(limit).
+		Scan(&res).Error
+	return res, err
+}
+
+// ListApiEndpointUsage returns the endpoints with the most requests in the
+// hours in [start, end), of a consumer if kind is not empty.
+func ListApiEndpointUsage(start, end time.Time, kind ApiConsumerKind, consumer string, limit int) ([]*ApiUsageSummary, error) {
+	var res []*ApiUsageSummary
+	tx := DB.Model(&ApiUsage{}).
+		Select("method, endpoint, "+apiUsageSums).
+		Where("hour >= ? AND hour < ?", start, end)
+	if kind != "" {
+		tx = tx.Where("consumer_kind = ? AND consumer = ?", kind, consumer)
+	}
+	err := tx.Group("method, endpoint").
+		Order("requests desc").
+		Limit(limit).
+		Scan(&res).Error
+	return res, err
+}
+
+// DeleteApiUsageBefore deletes the usage of the hours before t.
+func DeleteApiUsageBefore(t time.Time) error {
+	return DB.Where("hour < ?", t).Delete(&ApiUsage{}).Error
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ArtifactKind is the kind of a small file a device sends along its status
+// report while it cannot upload to the object storage.
+type ArtifactKind string
+
+const (
+	// ArtifactTriggerThumbnail is a thumbnail of the latest trigger image
+	// waiting for upload
+	ArtifactTriggerThumbnail ArtifactKind = "trigger_thumbnail"
+	// ArtifactErrorScreenshot is a thumbnail of the frame of the last error
+	ArtifactErrorScreenshot ArtifactKind = "error_screenshot"
+)
+
+var ArtifactKinds = []ArtifactKind{ArtifactTriggerThumbnail, ArtifactErrorScreenshot}
+
+const artifactKeyTemplate = "artifact:%s:%s"
+
+// artifactExpire keeps the artifacts through a storage outage, they are only
+// a stand-in for the images the device uploads once the storage is back.
+const artifactExpire = 24 * time.Hour
+
+// JobArtifact is the last artifact of a kind sent for a job, only kept in
+// redis.
+type JobArtifact struct {
+	Kind        ArtifactKind `json:"kind"`
+	ContentType string       `json:"contentType"`
+	Data        []byte       `json:"data"`
+	DeviceId    int          `json:"deviceId"`
+	// Time the device took the artifact
+	Time time.Time `json:"time"`
+}
+
+func artifactKey(jobUuid string, kind ArtifactKind) string {
+	return fmt.Sprintf(artifactKeyTemplate, jobUuid, kind)
+}
+
+func SaveJobArtifact(ctx context.Context, jobUuid string, a *JobArtifact) error {
+	data, _ := json.Marshal(a)
+	return Redis.Set(ctx, artifactKey(jobUuid, a.Kind), data, artifactExpire).Err()
+}
+
+func GetJobArtifact(ctx context.Context, jobUuid string, kind ArtifactKind) (*JobArtifact, error) {
+	var data []byte
+	if err := Redis.Get(ctx, artifactKey(jobUuid, kind)).Scan(&data); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var a JobArtifact
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ListJobArtifacts returns the artifacts of the job, one per kind at most.
+func ListJobArtifacts(ctx context.Context, jobUuid string) ([]*JobArtifact, error) {
+	var as []*JobArtifact
+	for _, kind := range ArtifactKinds {
+		a, err := GetJobArtifact(ctx, jobUuid, kind)
+		if err != nil {
+			return nil, err
+		} else if a != nil {
+			as = append(as, a)
+		}
+	}
+	return as, nil
+}
@@ -2,9 +2,12 @@ package model
 
 import (
 	"context"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -18,9 +21,74 @@ const (
 	CameraProtocolRtsp CameraProtocol = "rtsp"
 )
 
+// CameraTags are free-form labels of a camera, e.g. its building or floor.
+type CameraTags []string
+
+// Value implements driver.Valuer interface for JSON serialization
+func (t CameraTags) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (t *CameraTags) Scan(value any) error {
+	if value == nil {
+		*t = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, t)
+}
+
+// CalibrationPoint is a pixel of the camera image and the position of the
+// same point on the ground plane.
+type CalibrationPoint struct {
+	Pixel [2]float64 `json:"pixel"`
+	World [2]float64 `json:"world"`
+}
+
+// CameraCalibration maps the pixels of the camera image to the ground plane
+// with a homography solved from the point correspondences.
+type CameraCalibration struct {
+	Points []CalibrationPoint `json:"points"`
+	// Matrix is the 3x3 homography in row-major order
+	Matrix [9]float64 `json:"matrix"`
+	// Unit of the world coordinates, e.g. m
+	Unit string `json:"unit"`
+	// Error is the mean reprojection error of the points in Unit
+	Error float64 `json:"error"`
+}
+
+// Value implements driver.Valuer interface for JSON serialization
+func (c CameraCalibration) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (c *CameraCalibration) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, c)
+}
+
 type Camera struct {
 	Id           int            `gorm:"primaryKey"`
 	Uuid         string         `gorm:"type:char(96);unique"`
+	OrgId        int            `gorm:"index;not null;default:1"`
 	Name         string         `gorm:"type:char(96)"`
 	Protocol     CameraProtocol `gorm:"type:char(96)"`
 	Ip           string         `gorm:"type:char(96)"`
@@ -31,6 +99,12 @@ type Camera struct {
 	CreateTime   time.Time      `gorm:"datetime;autoCreateTime"`
 	UpdateTime   time.Time      `gorm:"datetime;autoCreateTime;autoUpdateTime"`
 	BindDeviceId int            `gorm:"type:int"`
+	PreviewAudio bool
+	// OnvifPort is the port of the ONVIF device service, zero if the camera
+	// does not support ONVIF
+	OnvifPort   int                `gorm:"type:int"`
+	Tags        CameraTags         `gorm:"type:json"`
+	Calibration *CameraCalibration `gorm:"type:json"`
 }
 
 func (c *Camera) BindDevice() (*Device, error) {
@@ -80,23 +154,96 @@ func UpdateCamera(camera *Camera) error {
 	return DB.Save(camera).Error
 }
 
-func ListCameras(start, limit int) ([]Camera, int64, error) {
+// CameraFilter selects the cameras to list, a zero field matches any camera.
+type CameraFilter struct {
+	OrgId int
+	Tag   string
+	// Name matches the cameras whose name contains it
+	Name         string
+	Protocol     CameraProtocol
+	BindDeviceId int
+}
+
+func (f CameraFilter) apply(db *gorm.DB) *gorm.DB {
+	db = db.Scopes(InOrg(f.OrgId))
+	if f.Tag != "" {
+		db = db.Where(jsonArrayHas("tags"), f.Tag)
+	}
+	if f.Name != "" {
+		db = db.Where(like("name"), "%"+escapeLike(f.Name)+"%")
+	}
+	if f.Protocol != "" {
+		db = db.Where("protocol = ?", f.Protocol)
+	}
+	if f.BindDeviceId != 0 {
+		db = db.Where("bind_device_id = ?", f.BindDeviceId)
+	}
+	return db
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func ListCameras(filter CameraFilter, start, limit int) ([]Camera, int64, error) {
 	var cameras []Camera
 	var total int64
-	if err := DB.Model(&Camera{}).Count(&total).Error; err != nil {
+	if err := filter.apply(DB.Model(&Camera{})).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
-	if err := DB.Model(&Camera{}).Offset(start).Limit(limit).Find(&cameras).Error; err != nil {
+	if err := filter.apply(DB.Model(&Camera{})).Offset(start).Limit(limit).Find(&cameras).Error; err != nil {
 		return nil, 0, err
 	}
 	return cameras, total, nil
 }
 
+// ListCameraPasswords returns the distinct non-empty passwords of all cameras.
+func ListCameraPasswords() ([]string, error) {
+	var passwords []string
+	err := DB.Model(&Camera{}).Where("password <> ''").Distinct().Pluck("password", &passwords).Error
+	return passwords, err
+}
+
+// ListCameraTags returns the distinct tags of the cameras of the
+// organization, sorted.
+func ListCameraTags(orgId int) ([]string, error) {
+	var tagsList []CameraTags
+	if err := DB.Model(&Camera{}).Scopes(InOrg(orgId)).Where("tags IS NOT NULL").Pluck("tags", &tagsList).Error; err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{})
+	res := make([]string, 0)
+	for _, tags := range tagsList {
+		for _, tag := range tags {
+			if _, ok := seen[tag]; ok {
+				continue
+			}
+			seen[tag] = struct{}{}
+			res = append(res, tag)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+type PreviewMode string
+
+const (
+	// PreviewModeFlv pushes RTMP from the device and plays HTTP-FLV
+	PreviewModeFlv PreviewMode = "flv"
+	// PreviewModeWebRTC publishes via WHIP from the device and plays WHEP
+	PreviewModeWebRTC PreviewMode = "webrtc"
+	// PreviewModeHls pushes RTMP from the device and plays the HLS playlist
+	PreviewModeHls PreviewMode = "hls"
+)
+
 type PreviewTask struct {
-	TaskUuid   string    `json:"taskUuid"`
-	PullAddr   string    `json:"pullAddr"`
-	PushAddr   string    `json:"pushAddr"`
-	ExpireTime time.Time `json:"expireTime,omitempty"`
+	TaskUuid   string      `json:"taskUuid"`
+	Mode       PreviewMode `json:"mode,omitempty"`
+	Audio      bool        `json:"audio,omitempty"`
+	PullAddr   string      `json:"pullAddr"`
+	PushAddr   string      `json:"pushAddr"`
+	ExpireTime time.Time   `json:"expireTime,omitempty"`
 }
 
 const previewKeyTemplate = "preview:%s:%s"
@@ -108,7 +255,11 @@ func previewKey(deviceUuid, cameraUuid string) string {
 
 func AddPreviewTask(ctx context.Context, deviceUuid, cameraUuid string, args *PreviewTask) error {
 	data, _ := json.Marshal(args)
-	return Redis.Set(ctx, previewKey(deviceUuid, cameraUuid), data, previewExpire).Err()
+	if err := Redis.Set(ctx, previewKey(deviceUuid, cameraUuid), data, previewExpire).Err(); err != nil {
+		return err
+	}
+	NotifyWatch(ctx, WatchKeyPreviewTasks(deviceUuid))
+	return nil
 }
 
 func GetPreviewTask(ctx context.Context, deviceUuid, cameraUuid string) (*PreviewTask, error) {
@@ -132,6 +283,14 @@ func TouchPreviewTask(ctx context.Context, deviceUuid, cameraUuid string) error
 	return Redis.Expire(ctx, previewKey(deviceUuid, cameraUuid), previewExpire).Err()
 }
 
+func DeletePreviewTask(ctx context.Context, deviceUuid, cameraUuid string) error {
+	if err := Redis.Del(ctx, previewKey(deviceUuid, cameraUuid)).Err(); err != nil {
+		return err
+	}
+	NotifyWatch(ctx, WatchKeyPreviewTasks(deviceUuid))
+	return nil
+}
+
 func GetPreviewTasksByDeviceUuid(ctx context.Context, deviceUuid string) ([]*PreviewTask, error) {
 	keys, err := Redis.Keys(ctx, fmt.Sprintf(previewKeyTemplate, deviceUuid, "*")).Result()
 	if err != nil {
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CameraProbeTask asks a device to run ffprobe against a camera, and to check
+// that the model is ready on its Triton server if ModelName is set.
+type CameraProbeTask struct {
+	TaskUuid   string    `json:"taskUuid"`
+	CameraUuid string    `json:"cameraUuid"`
+	PullAddr   string    `json:"pullAddr"`
+	ModelName  string    `json:"modelName,omitempty"`
+	ExpireTime time.Time `json:"expireTime,omitempty"`
+}
+
+type CameraProbeVideo struct {
+	Codec   string  `json:"codec"`
+	Profile string  `json:"profile,omitempty"`
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	Fps     float64 `json:"fps"`
+	PixFmt  string  `json:"pixFmt,omitempty"`
+}
+
+type CameraProbeAudio struct {
+	Codec      string `json:"codec"`
+	SampleRate int    `json:"sampleRate"`
+	Channels   int    `json:"channels"`
+}
+
+type CameraProbeResult struct {
+	TaskUuid  string            `json:"taskUuid"`
+	Reachable bool              `json:"reachable"`
+	Error     string            `json:"error,omitempty"`
+	Format    string            `json:"format,omitempty"`
+	Bitrate   int64             `json:"bitrate,omitempty"`
+	Video     *CameraProbeVideo `json:"video,omitempty"`
+	Audio     *CameraProbeAudio `json:"audio,omitempty"`
+	// ModelReady and ModelError are the model check of the task
+	ModelReady bool   `json:"modelReady,omitempty"`
+	ModelError string `json:"modelError,omitempty"`
+	// Duration of the probe in milliseconds
+	Duration   int64     `json:"duration"`
+	CreateTime time.Time `json:"createTime"`
+}
+
+const (
+	cameraProbeTaskKeyTemplate   = "camera-probe:%s:%s"
+	cameraProbeResultKeyTemplate = "camera-probe-result:%s"
+	cameraProbeTaskExpire        = time.Minute
+	cameraProbeResultExpire      = 5 * time.Minute
+)
+
+func cameraProbeTaskKey(deviceUuid, taskUuid string) string {
+	return fmt.Sprintf(cameraProbeTaskKeyTemplate, deviceUuid, taskUuid)
+}
+
+func AddCameraProbeTask(ctx context.Context, deviceUuid string, task *CameraProbeTask) error {
+	data, _ := json.Marshal(task)
+	return Redis.Set(ctx, cameraProbeTaskKey(deviceUuid, task.TaskUuid), data, cameraProbeTaskExpire).Err()
+}
+
+func DeleteCameraProbeTask(ctx context.Context, deviceUuid, taskUuid string) error {
+	return Redis.Del(ctx, cameraProbeTaskKey(deviceUuid, taskUuid)).Err()
+}
+
+func GetCameraProbeTasksByDeviceUuid(ctx context.Context, deviceUuid string) ([]*CameraProbeTask, error) {
+	keys, err := Redis.Keys(ctx, fmt.Sprintf(cameraProbeTaskKeyTemplate, deviceUuid, "*")).Result()
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*CameraProbeTask
+	for _, key := range keys {
+		var data []byte
+		if err := Redis.Get(ctx, key).Scan(&data); err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return nil, err
+		}
+		var task CameraProbeTask
+		if err := json.Unmarshal(data, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, &task)
+	}
+	return tasks, nil
+}
+
+func SetCameraProbeResult(ctx context.Context, result *CameraProbeResult) error {
+	data, _ := json.Marshal(result)
+	return Redis.Set(ctx, fmt.Sprintf(cameraProbeResultKeyTemplate, result.TaskUuid), data, cameraProbeResultExpire).Err()
+}
+
+func GetCameraProbeResult(ctx context.Context, taskUuid string) (*CameraProbeResult, error) {
+	var data []byte
+	if err := Redis.Get(ctx, fmt.Sprintf(cameraProbeResultKeyTemplate, taskUuid)).Scan(&data); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result CameraProbeResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	chatStreamKeyTemplate     = "chat-stream:%s:%s"
+	chatStreamDoneKeyTemplate = "chat-stream:%s:%s:done"
+	// ChatStreamExpire is how long the chunks of a chat stream can be resumed
+	// after the last one was written
+	ChatStreamExpire = 10 * time.Minute
+)
+
+func chatStreamKey(conversationUuid, streamId string) string {
+	return fmt.Sprintf(chatStreamKeyTemplate, conversationUuid, streamId)
+}
+
+func chatStreamDoneKey(conversationUuid, streamId string) string {
+	return fmt.Sprintf(chatStreamDoneKeyTemplate, conversationUuid, streamId)
+}
+
+// AppendChatStreamChunk buffers a chunk of the stream of a chat answer, so
+// that a client losing the connection can resume reading it.
+func AppendChatStreamChunk(ctx context.Context, conversationUuid, streamId string, chunk []byte) error {
+	key := chatStreamKey(conversationUuid, streamId)
+	pipe := Redis.TxPipeline()
+	pipe.RPush(ctx, key, chunk)
+	pipe.Expire(ctx, key, ChatStreamExpire)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// FinishChatStream marks the stream complete, no chunk follows.
+func FinishChatStream(ctx context.Context, conversationUuid, streamId string) error {
+	return Redis.Set(ctx, chatStreamDoneKey(conversationUuid, streamId), 1, ChatStreamExpire).Err()
+}
+
+// ChatStreamExists reports whether the stream has chunks to resume.
+func ChatStreamExists(ctx context.Context, conversationUuid, streamId string) (bool, error) {
+	n, err := Redis.Exists(ctx, chatStreamKey(conversationUuid, streamId)).Result()
+	return n > 0, err
+}
+
+// GetChatStreamChunks returns the chunks of the stream from offset, and
+// whether the stream was complete before they were read.
+func GetChatStreamChunks(ctx context.Context, conversationUuid, streamId string, offset int) ([]string, bool, error) {
+	// the flag is read first, so that a complete stream is read to its end
+	n, err := Redis.Exists(ctx, chatStreamDoneKey(conversationUuid, streamId)).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	chunks, err := Redis.LRange(ctx, chatStreamKey(conversationUuid, streamId), int64(offset), -1).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	return chunks, n > 0, nil
+}
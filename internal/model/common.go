@@ -2,31 +2,53 @@ package model
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/mattn/go-sqlite3"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-const defaultSqlDsn = "root:123456@tcp(127.0.0.1:3306)/lumina?charset=utf8mb4&parseTime=True&loc=Local"
+// times are stored in UTC, databases written with loc=Local must convert
+// their datetime columns, e.g. with CONVERT_TZ, before switching
+const defaultSqlDsn = "root:123456@tcp(127.0.0.1:3306)/lumina?charset=utf8mb4&parseTime=True&loc=UTC"
 
 var DB *gorm.DB
 var Redis *redis.Client
 
 type DBConfig struct {
+	// Driver is mysql, or sqlite for the single box installs, the DSN being
+	// the path of the database file. SQLite lacks some capabilities, see
+	// Capability. The times are stored in UTC like on MySQL.
+	Driver       string `yaml:"driver"`
 	DSN          string `yaml:"dsn"`
 	MaxIdleConns int    `yaml:"maxIdleConns"`
-	MaxOpenConns int    `yaml:"maxOpenConns"`
-	MaxLifetime  int    `yaml:"maxLifetime"`
+	// MaxOpenConns must stay below max_connections of MySQL shared by all
+	// lumina processes
+	MaxOpenConns int `yaml:"maxOpenConns"`
+	// MaxLifetime and MaxIdleTime of a connection in seconds
+	MaxLifetime int `yaml:"maxLifetime"`
+	MaxIdleTime int `yaml:"maxIdleTime"`
+	// SlowThreshold in milliseconds above which queries are logged as slow,
+	// zero disables it. Slow queries are explained in debug mode.
+	SlowThreshold int `yaml:"slowThreshold"`
 }
 
 func DefaultDBConfig() *DBConfig {
 	return &DBConfig{
-		DSN:          defaultSqlDsn,
-		MaxIdleConns: 100,
-		MaxOpenConns: 1000,
-		MaxLifetime:  60,
+		Driver:        string(DialectMySQL),
+		DSN:           defaultSqlDsn,
+		MaxIdleConns:  50,
+		MaxOpenConns:  100,
+		MaxLifetime:   300,
+		MaxIdleTime:   60,
+		SlowThreshold: 200,
 	}
 }
 
@@ -62,12 +84,25 @@ func InitRedis(cfg RedisConfig) (*redis.Client, error) {
 }
 
 func InitDB(dbConfig DBConfig) (*gorm.DB, error) {
-	db, err := gorm.Open(mysql.Open(dbConfig.DSN), &gorm.Config{
+	var dialector gorm.Dialector
+	switch Dialect(dbConfig.Driver) {
+	case DialectMySQL, "":
+		dialector = mysql.Open(dbConfig.DSN)
+	case DialectSQLite:
+		dialector = sqlite.New(sqlite.Config{DriverName: sqliteUTCDriverName, DSN: sqliteDSN(dbConfig.DSN)})
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", dbConfig.Driver)
+	}
+
+	dbLogger := newDBLogger(time.Duration(dbConfig.SlowThreshold) * time.Millisecond)
+	db, err := gorm.Open(dialector, &gorm.Config{
 		PrepareStmt: true,
+		Logger:      dbLogger,
 	})
 	if err != nil {
 		return nil, err
 	}
+	dbLogger.db = db
 
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -76,14 +111,84 @@ func InitDB(dbConfig DBConfig) (*gorm.DB, error) {
 	sqlDB.SetMaxIdleConns(dbConfig.MaxIdleConns)
 	sqlDB.SetMaxOpenConns(dbConfig.MaxOpenConns)
 	sqlDB.SetConnMaxLifetime(time.Second * time.Duration(dbConfig.MaxLifetime))
+	sqlDB.SetConnMaxIdleTime(time.Second * time.Duration(dbConfig.MaxIdleTime))
 
 	DB = db
 
 	return db, nil
 }
 
-func AutoMigrate(db *gorm.DB) error {
-	for _, model := range []any{
+// sqliteDSN adds to the DSN of a SQLite database the options letting the
+// server and the consumer share it: the readers don't block the writer in
+// WAL mode, and a writer waits for the other instead of failing at once. The
+// times are read in UTC, as they are written by sqliteUTCConn.
+func sqliteDSN(dsn string) string {
+	options := []string{"_journal_mode=WAL", "_busy_timeout=5000", "_txlock=immediate", "_loc=UTC"}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	for _, o := range options {
+		name, _, _ := strings.Cut(o, "=")
+		if !strings.Contains(dsn, name+"=") {
+			dsn += sep + o
+			sep = "&"
+		}
+	}
+	return dsn
+}
+
+// sqliteUTCDriverName is the SQLite driver binding the times in UTC.
+const sqliteUTCDriverName = "sqlite3_utc"
+
+func init() {
+	sql.Register(sqliteUTCDriverName, &sqliteUTCDriver{})
+}
+
+type sqliteUTCDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *sqliteUTCDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteUTCConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+// sqliteUTCConn converts the times bound to the queries to UTC. SQLite
+// compares the times as text in the offset they were written with, which
+// must be the same for every time whatever the local time zone.
+type sqliteUTCConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c *sqliteUTCConn) CheckNamedValue(nv *driver.NamedValue) error {
+	v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := v.(time.Time); ok {
+		v = t.UTC()
+	}
+	nv.Value = v
+	return nil
+}
+
+// DBStats returns the statistics of the connection pool.
+func DBStats() (sql.DBStats, error) {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return sql.DBStats{}, err
+	}
+	return sqlDB.Stats(), nil
+}
+
+// Tables returns the models of all tables managed by lumina.
+func Tables() []any {
+	return []any{
+		&Organization{},
 		&User{},
 		&Job{},
 		&Device{},
@@ -94,19 +199,28 @@ func AutoMigrate(db *gorm.DB) error {
 		&ChatMessage{},
 		&AlertMessage{},
 		&Camera{},
-	} {
-		err := db.AutoMigrate(model)
-		if err != nil {
-			return err
-		}
+		&PushSubscription{},
+		&NotificationPreference{},
+		&DeviceGroup{},
+		&DeviceGroupMember{},
+		&JobDeviceStatus{},
+		&Release{},
+		&Rollout{},
+		&DeviceUpgrade{},
+		&HealthEvent{},
+		&VolumeBaseline{},
+		&JobStatusHistory{},
+		&NotificationChannel{},
+		&EscalationPolicy{},
+		&AlertActivity{},
+		&ApiUsage{},
+		&ApiToken{},
+		&RefreshToken{},
+		&DeviceSigningKey{},
+		&MessageFeedback{},
+		&ReportSchedule{},
+		&Report{},
 	}
-
-	// Ensure ChatMessage.answer uses a large text type to avoid overflow errors
-	// MySQL TEXT/LONGTEXT columns cannot have default values; tag has been updated.
-	// AutoMigrate does not always change existing column types, so we enforce it here.
-	_ = db.Exec("ALTER TABLE chat_messages MODIFY COLUMN answer LONGTEXT").Error
-
-	return nil
 }
 
 func InsertTestData(db *gorm.DB) error {
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// dbLogger logs the messages of gorm with logrus. Queries slower than
+// slowThreshold are logged as warnings, with their plan in debug mode.
+type dbLogger struct {
+	slowThreshold time.Duration
+	level         logger.LogLevel
+	// db runs the EXPLAIN of slow queries, it is set once opened
+	db *gorm.DB
+}
+
+func newDBLogger(slowThreshold time.Duration) *dbLogger {
+	return &dbLogger{
+		slowThreshold: slowThreshold,
+		level:         logger.Warn,
+	}
+}
+
+func (l *dbLogger) LogMode(level logger.LogLevel) logger.Interface {
+	res := *l
+	res.level = level
+	return &res
+}
+
+func (l *dbLogger) Info(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Info {
+		logrus.Infof(msg, args...)
+	}
+}
+
+func (l *dbLogger) Warn(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Warn {
+		logrus.Warnf(msg, args...)
+	}
+}
+
+func (l *dbLogger) Error(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Error {
+		logrus.Errorf(msg, args...)
+	}
+}
+
+func (l *dbLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		query, rows := fc()
+		logrus.WithError(err).Errorf("query failed in %s, rows: %d, sql: %s", elapsed, rows, query)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		query, rows := fc()
+		entry := logrus.WithFields(logrus.Fields{"elapsed": elapsed, "rows": rows})
+		if logrus.IsLevelEnabled(logrus.DebugLevel) {
+			if plan, err := l.explain(ctx, query); err != nil {
+				entry = entry.WithField("explainError", err.Error())
+			} else if plan != "" {
+				entry = entry.WithField("plan", plan)
+			}
+		}
+		entry.Warnf("slow query over %s: %s", l.slowThreshold, query)
+	}
+}
+
+// explain returns the plan of a SELECT query, one line per row of the
+// EXPLAIN output. Other statements are not explained since EXPLAIN would not
+// tell much about them.
+func (l *dbLogger) explain(ctx context.Context, query string) (string, error) {
+	if l.db == nil || !Supports(CapExplain) || !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query)), "SELECT") {
+		return "", nil
+	}
+	// the pool runs it as a plain query, bypassing gorm and its prepared
+	// statements
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return "", err
+	}
+	rows, err := sqlDB.QueryContext(ctx, "EXPLAIN "+query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	values := make([]sql.RawBytes, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	var lines []string
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		fields := make([]string, 0, len(cols))
+		for i, col := range cols {
+			switch strings.ToLower(col) {
+			case "table", "type", "key", "rows", "extra":
+				fields = append(fields, fmt.Sprintf("%s=%s", strings.ToLower(col), values[i]))
+			}
+		}
+		lines = append(lines, strings.Join(fields, " "))
+	}
+	return strings.Join(lines, "; "), rows.Err()
+}
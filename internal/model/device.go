@@ -2,6 +2,8 @@ package model
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -11,10 +13,116 @@ import (
 type Device struct {
 	Id           int          `gorm:"primaryKey"`
 	Uuid         string       `gorm:"type:char(96);unique"`
+	OrgId        int          `gorm:"index;not null;default:1"`
 	Name         string       `gorm:"type:char(96)"`
 	Token        string       `gorm:"type:char(96);unique"`
 	RegisterTime sql.NullTime `gorm:"datetime;autoCreateTime"`
 	LastPingTime sql.NullTime `gorm:"datetime;autoCreateTime"`
+	DiskUsage    *DiskUsage   `gorm:"type:json"`
+	// MaxExecutors overrides the device's own executor limit when positive
+	MaxExecutors int
+	// Inventory is the software of the device as last reported
+	Inventory *DeviceInventory `gorm:"type:json"`
+	// SigningKey is the fingerprint of the key the device signs its
+	// messages with, pinned on its first report until it unregisters
+	SigningKey string `gorm:"type:char(64)"`
+	// NextToken is the token issued by a rotation, Token stays valid until
+	// the device first uses it so that a lost response doesn't lock the
+	// device out
+	NextToken string `gorm:"type:char(96);index"`
+	// RotateToken asks the device to rotate its token on its next report
+	RotateToken     bool `gorm:"default:false"`
+	TokenRotateTime sql.NullTime
+}
+
+// DiskUsage is the disk usage of a device's work directory as last reported.
+type DiskUsage struct {
+	JobDirBytes int64 `json:"jobDirBytes"`
+	JobDirFiles int   `json:"jobDirFiles"`
+	FreeBytes   int64 `json:"freeBytes"`
+	TotalBytes  int64 `json:"totalBytes"`
+}
+
+func (u *DiskUsage) Value() (driver.Value, error) {
+	return json.Marshal(u)
+}
+
+func (u *DiskUsage) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, u)
+}
+
+// DeviceInventory is the software a device runs, an empty field means the
+// device could not tell the version.
+type DeviceInventory struct {
+	Os            string `json:"os"`
+	Kernel        string `json:"kernel"`
+	Arch          string `json:"arch"`
+	LuminaVersion string `json:"luminaVersion"`
+	LuminaCommit  string `json:"luminaCommit"`
+	FFmpegVersion string `json:"ffmpegVersion"`
+	OpenCVVersion string `json:"opencvVersion"`
+	TritonVersion string `json:"tritonVersion"`
+}
+
+func (i *DeviceInventory) Value() (driver.Value, error) {
+	return json.Marshal(i)
+}
+
+func (i *DeviceInventory) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, i)
+}
+
+type DeviceState string
+
+const (
+	DeviceStateOnline   DeviceState = "online"
+	DeviceStateDegraded DeviceState = "degraded"
+	DeviceStateOffline  DeviceState = "offline"
+)
+
+// DeviceStateThresholds are the times since the last report after which a
+// device is degraded or offline, set from the server config.
+var DeviceStateThresholds = struct {
+	Degraded time.Duration
+	Offline  time.Duration
+}{
+	Degraded: 30 * time.Second,
+	Offline:  2 * time.Minute,
+}
+
+// State derives the state of the device from its last report, an
+// unregistered device is offline.
+func (d *Device) State(now time.Time) DeviceState {
+	if !d.IsRegistered() || !d.LastPingTime.Valid || d.LastPingTime.Time.IsZero() {
+		return DeviceStateOffline
+	}
+	since := now.Sub(d.LastPingTime.Time)
+	switch {
+	case since >= DeviceStateThresholds.Offline:
+		return DeviceStateOffline
+	case since >= DeviceStateThresholds.Degraded:
+		return DeviceStateDegraded
+	default:
+		return DeviceStateOnline
+	}
 }
 
 func (d *Device) IsRegistered() bool {
@@ -24,9 +132,41 @@ func (d *Device) IsRegistered() bool {
 func (d *Device) Unregister() error {
 	d.RegisterTime = sql.NullTime{Time: time.Time{}, Valid: false}
 	d.LastPingTime = sql.NullTime{Time: time.Time{}, Valid: false}
+	d.SigningKey = ""
+	d.NextToken = ""
+	d.RotateToken = false
 	return DB.Save(d).Error
 }
 
+// SetNextToken issues the token a rotation replaces the device's one with,
+// replacing the one of an unfinished rotation.
+func (d *Device) SetNextToken(token string) error {
+	d.NextToken = token
+	return DB.Model(&Device{}).Where("id = ?", d.Id).Update("next_token", token).Error
+}
+
+// PromoteNextToken finishes the rotation of the token on the first use of
+// the next one, the previous token stops being valid.
+func (d *Device) PromoteNextToken() error {
+	now := sql.NullTime{Time: time.Now(), Valid: true}
+	err := DB.Model(&Device{}).Where("id = ? AND next_token = ?", d.Id, d.NextToken).Updates(map[string]any{
+		"token":             d.NextToken,
+		"next_token":        "",
+		"rotate_token":      false,
+		"token_rotate_time": now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	d.Token, d.NextToken, d.RotateToken, d.TokenRotateTime = d.NextToken, "", false, now
+	return nil
+}
+
+// ForceTokenRotation asks the device to rotate its token on its next report.
+func ForceTokenRotation(id int) error {
+	return DB.Model(&Device{}).Where("id = ?", id).Update("rotate_token", true).Error
+}
+
 func CreateDevice(d *Device) error {
 	return DB.Create(d).Error
 }
@@ -35,8 +175,48 @@ func UpdateDevice(d *Device) error {
 	return DB.Save(d).Error
 }
 
+// DevicePing is the last report of a device.
+type DevicePing struct {
+	Time      time.Time
+	DiskUsage *DiskUsage
+	Inventory *DeviceInventory
+}
+
+// UpdateDevicePings records the status reports of many devices, keyed by
+// device id, without touching the fields managed through the API.
+func UpdateDevicePings(pings map[int]DevicePing) error {
+	if len(pings) == 0 {
+		return nil
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for id, p := range pings {
+			updates := map[string]any{
+				"last_ping_time": sql.NullTime{Time: p.Time, Valid: true},
+			}
+			if p.DiskUsage != nil {
+				updates["disk_usage"] = p.DiskUsage
+			}
+			if p.Inventory != nil {
+				updates["inventory"] = p.Inventory
+			}
+			if err := tx.Model(&Device{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func DeleteDevice(id uint) error {
-	return DB.Delete(&Device{}, id).Error
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("device_id = ?", id).Delete(&DeviceGroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("device_id = ?", id).Delete(&JobDeviceStatus{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Device{}, id).Error
+	})
 }
 
 func GetDeviceById(id int) (*Device, error) {
@@ -57,70 +237,189 @@ func GetDeviceByUuid(uuid string) (*Device, error) {
 	return &d, err
 }
 
+// GetDeviceByToken returns the device of a token, or of the next token of
+// an unfinished rotation.
 func GetDeviceByToken(token string) (*Device, error) {
+	if token == "" {
+		return nil, nil
+	}
 	var d Device
-	err := DB.Where("token = ?", token).First(&d).Error
+	err := DB.Where("token = ? OR next_token = ?", token, token).First(&d).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
 	return &d, err
 }
 
-func ListDevices(start, limit int) ([]Device, int64, error) {
+// DeviceFilter selects devices by their state as derived by Device.State.
+type DeviceFilter struct {
+	OrgId int
+	State DeviceState
+}
+
+func (f DeviceFilter) apply(db *gorm.DB) *gorm.DB {
+	db = db.Scopes(InOrg(f.OrgId))
+	now := time.Now()
+	degraded := now.Add(-DeviceStateThresholds.Degraded)
+	offline := now.Add(-DeviceStateThresholds.Offline)
+	// unregistering clears both times
+	switch f.State {
+	case DeviceStateOnline:
+		db = db.Where("register_time IS NOT NULL AND last_ping_time > ?", degraded)
+	case DeviceStateDegraded:
+		db = db.Where("register_time IS NOT NULL AND last_ping_time <= ? AND last_ping_time > ?", degraded, offline)
+	case DeviceStateOffline:
+		db = db.Where("(register_time IS NULL OR last_ping_time IS NULL OR last_ping_time <= ?)", offline)
+	}
+	return db
+}
+
+func ListDevices(filter DeviceFilter, start, limit int) ([]Device, int64, error) {
 	var devices []Device
 	var total int64
-	if err := DB.Model(&Device{}).Count(&total).Error; err != nil {
+	if err := filter.apply(DB.Model(&Device{})).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
-	if err := DB.Model(&Device{}).Offset(start).Limit(limit).Find(&devices).Error; err != nil {
+	if err := filter.apply(DB.Model(&Device{})).Offset(start).Limit(limit).Find(&devices).Error; err != nil {
 		return nil, 0, err
 	}
 	return devices, total, nil
 }
 
+// CountDevices returns the number of devices in the state of the filter.
+func CountDevices(filter DeviceFilter) (int64, error) {
+	var total int64
+	err := filter.apply(DB.Model(&Device{})).Count(&total).Error
+	return total, err
+}
+
+// ListRegisteredDevices returns every registered device.
+func ListRegisteredDevices() ([]Device, error) {
+	var devices []Device
+	err := DB.Where("register_time IS NOT NULL").Find(&devices).Error
+	return devices, err
+}
+
+// InventoryFilter selects devices by their inventory. Versions match as a
+// prefix of whole components, e.g. v0.3 matches v0.3 and v0.3.1 but not
+// v0.30, and Os matches any part of the os name.
+type InventoryFilter struct {
+	OrgId         int
+	Os            string
+	Kernel        string
+	LuminaVersion string
+	FFmpegVersion string
+	OpenCVVersion string
+	TritonVersion string
+}
+
+func (f InventoryFilter) apply(db *gorm.DB) *gorm.DB {
+	db = db.Scopes(InOrg(f.OrgId))
+	if f.Os != "" {
+		db = db.Where(like(jsonText("inventory", "$.os")), "%"+escapeLike(f.Os)+"%")
+	}
+	for _, v := range []struct{ field, version string }{
+		{"kernel", f.Kernel},
+		{"luminaVersion", f.LuminaVersion},
+		{"ffmpegVersion", f.FFmpegVersion},
+		{"opencvVersion", f.OpenCVVersion},
+		{"tritonVersion", f.TritonVersion},
+	} {
+		if v.version == "" {
+			continue
+		}
+		col := jsonText("inventory", "$."+v.field)
+		prefix := escapeLike(v.version)
+		db = db.Where(col+" = ? OR "+like(col)+" OR "+like(col), v.version, prefix+".%", prefix+"-%")
+	}
+	return db
+}
+
+// ListDeviceInventory lists the devices matching the filter and counts them
+// by lumina version, devices which never reported an inventory are counted
+// under an empty version.
+func ListDeviceInventory(filter InventoryFilter, start, limit int) ([]Device, int64, map[string]int64, error) {
+	var devices []Device
+	var total int64
+	if err := filter.apply(DB.Model(&Device{})).Count(&total).Error; err != nil {
+		return nil, 0, nil, err
+	}
+	if err := filter.apply(DB.Model(&Device{})).Order("id").Offset(start).Limit(limit).Find(&devices).Error; err != nil {
+		return nil, 0, nil, err
+	}
+
+	var rows []struct {
+		Version string
+		Count   int64
+	}
+	if err := filter.apply(DB.Model(&Device{})).
+		Select("COALESCE(" + jsonText("inventory", "$.luminaVersion") + ", '') AS version, COUNT(*) AS count").
+		Group("version").Scan(&rows).Error; err != nil {
+		return nil, 0, nil, err
+	}
+	versions := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		versions[r.Version] = r.Count
+	}
+	return devices, total, versions, nil
+}
+
+// AccessToken registers devices, in the organization of the token, up to
+// MaxBinds of them.
 type AccessToken struct {
 	Id          int       `gorm:"primaryKey"`
 	AccessToken string    `gorm:"type:char(96);unique"`
+	OrgId       int       `gorm:"index;not null;default:1"`
 	CreateTime  time.Time `gorm:"datetime;autoCreateTime"`
 	ExpireTime  time.Time `gorm:"datetime;autoCreateTime"`
-	DeviceUuid  string    `gorm:"type:char(96);index"`
+	// DeviceUuid is the last device registered with the token
+	DeviceUuid string `gorm:"type:char(96);index"`
+	// MaxBinds is the number of devices the token can register
+	MaxBinds     int `gorm:"not null;default:1"`
+	BindCount    int `gorm:"not null;default:0"`
+	LastBindTime sql.NullTime
 }
 
+// ErrAccessTokenUsedUp is returned when binding a device to a token which
+// registered as many devices as it can.
+var ErrAccessTokenUsedUp = errors.New("access token used up")
+
 func (t *AccessToken) IsExpired() bool {
 	return t.ExpireTime.Before(time.Now())
 }
 
-func (t *AccessToken) IsBound() bool {
-	return t.DeviceUuid != ""
+// IsUsedUp reports whether the token registered as many devices as it can.
+func (t *AccessToken) IsUsedUp() bool {
+	return t.BindCount >= t.MaxBinds
 }
 
+// BindDevice registers the device with the token, the count of the token is
+// checked and taken in the same statement for the devices of a batch
+// registering at once.
 func (t *AccessToken) BindDevice(d *Device) error {
-	t.DeviceUuid = d.Uuid
-	d.RegisterTime = sql.NullTime{Time: time.Now(), Valid: true}
+	now := sql.NullTime{Time: time.Now(), Valid: true}
+	d.OrgId = t.OrgId
+	d.RegisterTime = now
 
-	// 使用事务确保数据一致性
-	tx := DB.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	return DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&AccessToken{}).Where("id = ? AND bind_count < max_binds", t.Id).Updates(map[string]any{
+			"bind_count":     gorm.Expr("bind_count + 1"),
+			"device_uuid":    d.Uuid,
+			"last_bind_time": now,
+		})
+		if res.Error != nil {
+			return res.Error
+		} else if res.RowsAffected == 0 {
+			return ErrAccessTokenUsedUp
 		}
-	}()
-
-	if err := tx.Save(d).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	if err := tx.Save(t).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	return tx.Commit().Error
+		if err := tx.Save(d).Error; err != nil {
+			return err
+		}
+		t.BindCount++
+		t.DeviceUuid = d.Uuid
+		t.LastBindTime = now
+		return nil
+	})
 }
 
 func CreateAccessToken(t *AccessToken) error {
@@ -149,13 +448,13 @@ func GetAccessTokenByToken(token string) (*AccessToken, error) {
 	return &t, err
 }
 
-func ListAccessToken(start, limit int) ([]AccessToken, int64, error) {
+func ListAccessToken(orgId, start, limit int) ([]AccessToken, int64, error) {
 	var accessTokens []AccessToken
 	var total int64
-	if err := DB.Model(&AccessToken{}).Count(&total).Error; err != nil {
+	if err := DB.Model(&AccessToken{}).Scopes(InOrg(orgId)).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
-	if err := DB.Model(&AccessToken{}).Offset(start).Limit(limit).Find(&accessTokens).Error; err != nil {
+	if err := DB.Model(&AccessToken{}).Scopes(InOrg(orgId)).Offset(start).Limit(limit).Find(&accessTokens).Error; err != nil {
 		return nil, 0, err
 	}
 	return accessTokens, total, nil
@@ -0,0 +1,223 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeviceGroup is a set of devices a job can target as a whole, the job then
+// runs on every member device.
+type DeviceGroup struct {
+	Id          int       `gorm:"primaryKey"`
+	Name        string    `gorm:"type:char(96);unique"`
+	OrgId       int       `gorm:"index;not null;default:1"`
+	Description string    `gorm:"type:varchar(255)"`
+	CreateTime  time.Time `gorm:"datetime;autoCreateTime"`
+	UpdateTime  time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+	// Channel is the release channel the devices of the group are pinned to
+	Channel ReleaseChannel `gorm:"type:char(16);default:stable"`
+	// UpgradeWindow restricts when devices of the group upgrade, nil means
+	// any time
+	UpgradeWindow *JobSchedule `gorm:"type:json"`
+}
+
+type DeviceGroupMember struct {
+	Id       int `gorm:"primaryKey"`
+	GroupId  int `gorm:"uniqueIndex:idx_group_device"`
+	DeviceId int `gorm:"uniqueIndex:idx_group_device;index"`
+}
+
+func CreateDeviceGroup(g *DeviceGroup, deviceIds []int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(g).Error; err != nil {
+			return err
+		}
+		return setDeviceGroupMembers(tx, g.Id, deviceIds)
+	})
+}
+
+// UpdateDeviceGroup saves g and, if deviceIds is not nil, replaces its
+// members. The jobs targeting the group are touched so that the devices
+// leaving the group stop them and the joining ones start them.
+func UpdateDeviceGroup(g *DeviceGroup, deviceIds []int) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(g).Error; err != nil {
+			return err
+		}
+		if deviceIds == nil {
+			return nil
+		}
+		if err := setDeviceGroupMembers(tx, g.Id, deviceIds); err != nil {
+			return err
+		}
+		// the per-device status of the devices that left is stale
+		if err := tx.Where("job_id IN (?) AND device_id NOT IN (?)",
+			tx.Model(&Job{}).Select("id").Where("device_group_id = ?", g.Id),
+			tx.Model(&DeviceGroupMember{}).Select("device_id").Where("group_id = ?", g.Id),
+		).Delete(&JobDeviceStatus{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Job{}).Where("device_group_id = ?", g.Id).Update("update_time", time.Now()).Error
+	})
+	if err != nil || deviceIds == nil {
+		return err
+	}
+	NotifyWatch(context.Background(), WatchKeyJobs)
+	return nil
+}
+
+func setDeviceGroupMembers(tx *gorm.DB, groupId int, deviceIds []int) error {
+	if err := tx.Where("group_id = ?", groupId).Delete(&DeviceGroupMember{}).Error; err != nil {
+		return err
+	}
+	if len(deviceIds) == 0 {
+		return nil
+	}
+	members := make([]DeviceGroupMember, 0, len(deviceIds))
+	for _, id := range deviceIds {
+		members = append(members, DeviceGroupMember{GroupId: groupId, DeviceId: id})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error
+}
+
+var ErrDeviceGroupInUse = errors.New("device group is targeted by jobs")
+
+// DeleteDeviceGroup deletes the group and its members, it fails with
+// ErrDeviceGroupInUse while jobs target the group.
+func DeleteDeviceGroup(id int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Job{}).Where("device_group_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrDeviceGroupInUse
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&DeviceGroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("rollout_id IN (?)",
+			tx.Model(&Rollout{}).Select("id").Where("group_id = ?", id),
+		).Delete(&DeviceUpgrade{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&Rollout{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&DeviceGroup{}, id).Error
+	})
+}
+
+func GetDeviceGroupById(id int) (*DeviceGroup, error) {
+	var g DeviceGroup
+	err := DB.First(&g, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &g, err
+}
+
+func ListDeviceGroups(orgId, start, limit int) ([]DeviceGroup, int64, error) {
+	var groups []DeviceGroup
+	var total int64
+	if err := DB.Model(&DeviceGroup{}).Scopes(InOrg(orgId)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := DB.Model(&DeviceGroup{}).Scopes(InOrg(orgId)).Order("id").Offset(start).Limit(limit).Find(&groups).Error; err != nil {
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
+
+// ListDeviceGroupMembers returns the member devices of a group.
+func ListDeviceGroupMembers(groupId int) ([]Device, error) {
+	var devices []Device
+	err := DB.Model(&Device{}).
+		Joins("JOIN device_group_members ON device_group_members.device_id = devices.id").
+		Where("device_group_members.group_id = ?", groupId).
+		Order("devices.id").
+		Find(&devices).Error
+	return devices, err
+}
+
+// JobDeviceStatus is the runtime state a member device reports for a job
+// targeting its group.
+type JobDeviceStatus struct {
+	Id           int           `gorm:"primaryKey"`
+	JobId        int           `gorm:"uniqueIndex:idx_job_device"`
+	DeviceId     int           `gorm:"uniqueIndex:idx_job_device"`
+	Status       ExectorStatus `gorm:"default:0"`
+	RestartCount int
+	LastError    string `gorm:"type:varchar(1024)"`
+	HealthReason string `gorm:"type:varchar(255)"`
+	// frames per second inferred on the device and the limit it was
+	// throttled to, zero if none
+	FrameRate      float64
+	FrameRateLimit float64
+	UpdateTime     time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+// SetJobDeviceStatuses records the states of group jobs on their devices and
+// returns the ids of the jobs among them running on any device.
+func SetJobDeviceStatuses(sts []*JobDeviceStatus) (map[int]bool, error) {
+	if len(sts) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	jobIds := make([]int, 0, len(sts))
+	for _, st := range sts {
+		if len(st.LastError) > 1024 {
+			st.LastError = st.LastError[:1024]
+		}
+		if len(st.HealthReason) > 255 {
+			st.HealthReason = st.HealthReason[:255]
+		}
+		st.UpdateTime = now
+		jobIds = append(jobIds, st.JobId)
+	}
+	if err := DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "job_id"}, {Name: "device_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "restart_count", "last_error", "health_reason",
+			"frame_rate", "frame_rate_limit", "update_time"}),
+	}).Create(sts).Error; err != nil {
+		return nil, err
+	}
+
+	var running []int
+	if err := DB.Model(&JobDeviceStatus{}).
+		Where("job_id IN ? AND status = ?", jobIds, ExectorStatusRunning).
+		Distinct().Pluck("job_id", &running).Error; err != nil {
+		return nil, err
+	}
+	res := make(map[int]bool, len(running))
+	for _, id := range running {
+		res[id] = true
+	}
+	return res, nil
+}
+
+// GetJobDeviceStatuses returns the states of the jobs on their devices.
+func GetJobDeviceStatuses(jobIds []int) ([]JobDeviceStatus, error) {
+	var sts []JobDeviceStatus
+	if len(jobIds) == 0 {
+		return sts, nil
+	}
+	err := DB.Where("job_id IN ?", jobIds).Find(&sts).Error
+	return sts, err
+}
+
+func ListJobDeviceStatus(jobId int) ([]JobDeviceStatus, error) {
+	var sts []JobDeviceStatus
+	err := DB.Where("job_id = ?", jobId).Order("device_id").Find(&sts).Error
+	return sts, err
+}
+
+// DeleteJobDeviceStatus forgets the per-device status of a job, e.g. when
+// it is moved to another device or group.
+func DeleteJobDeviceStatus(jobId int) error {
+	return DB.Where("job_id = ?", jobId).Delete(&JobDeviceStatus{}).Error
+}
@@ -0,0 +1,156 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// Dialect is the SQL dialect of a database lumina runs on.
+type Dialect string
+
+const (
+	DialectMySQL Dialect = "mysql"
+	// DialectSQLite is for the single box installs, the server and the
+	// consumer sharing the database file. It lacks the capabilities of
+	// sqliteMissing.
+	DialectSQLite Dialect = "sqlite"
+)
+
+// Capability is a feature of MySQL some queries rely on. The queries
+// needing one the database lacks fail with an UnsupportedError, or degrade
+// as documented by the capability.
+type Capability string
+
+const (
+	// CapFullTextSearch searches the answers of the messages in their
+	// FULLTEXT ngram index. Without it the search matches the words as
+	// substrings, scanning the messages.
+	CapFullTextSearch Capability = "fullTextSearch"
+	// CapGroupConcat keeps the latest images of every group of the alert
+	// gallery with GROUP_CONCAT and SUBSTRING_INDEX. Without it the gallery
+	// is refused.
+	CapGroupConcat Capability = "groupConcat"
+	// CapExplain logs the plan of the slow queries in debug mode
+	CapExplain Capability = "explain"
+)
+
+var sqliteMissing = []Capability{CapFullTextSearch, CapGroupConcat, CapExplain}
+
+// UnsupportedError is returned by the queries needing a capability the
+// database lacks.
+type UnsupportedError struct {
+	Dialect    Dialect
+	Capability Capability
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s is not supported on %s", e.Capability, e.Dialect)
+}
+
+// CurrentDialect returns the dialect of DB, MySQL until it is opened.
+func CurrentDialect() Dialect {
+	if DB == nil {
+		return DialectMySQL
+	}
+	return dialectOf(DB)
+}
+
+// dialectOf returns the dialect of a database, the migrations running on the
+// one they are given rather than on DB.
+func dialectOf(db *gorm.DB) Dialect {
+	return Dialect(db.Dialector.Name())
+}
+
+// Supports reports whether DB has the capability.
+func Supports(c Capability) bool {
+	return CurrentDialect() != DialectSQLite || !slices.Contains(sqliteMissing, c)
+}
+
+// requireCapability returns an UnsupportedError if DB lacks the capability.
+func requireCapability(c Capability) error {
+	if !Supports(c) {
+		return &UnsupportedError{Dialect: CurrentDialect(), Capability: c}
+	}
+	return nil
+}
+
+// jsonText returns the expression of the text at the path of a JSON column,
+// NULL if there is none.
+func jsonText(column, path string) string {
+	return dialectJsonText(CurrentDialect(), column, path)
+}
+
+func dialectJsonText(d Dialect, column, path string) string {
+	if d == DialectSQLite {
+		return fmt.Sprintf("json_extract(%s, '%s')", column, path)
+	}
+	return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '%s'))", column, path)
+}
+
+// jsonArrayHas returns the condition on a JSON array column containing the
+// string given as its argument.
+func jsonArrayHas(column string) string {
+	if CurrentDialect() == DialectSQLite {
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) WHERE json_each.value = ?)", column)
+	}
+	return fmt.Sprintf("JSON_CONTAINS(%s, JSON_QUOTE(?))", column)
+}
+
+// detectBoxes returns the table of the detection boxes of the messages, to
+// join as boxes, and the expressions of their label and confidence.
+func detectBoxes() (table, label, confidence string) {
+	if CurrentDialect() == DialectSQLite {
+		return "json_each(messages.detect_boxes) AS boxes",
+			"json_extract(boxes.value, '$.label')", "json_extract(boxes.value, '$.confidence')"
+	}
+	return "JSON_TABLE(messages.detect_boxes, '$[*]' COLUMNS(" +
+			"label VARCHAR(64) PATH '$.label', confidence FLOAT PATH '$.confidence')) AS boxes",
+		"boxes.label", "boxes.confidence"
+}
+
+// secondsBetween returns the expression of the whole seconds from the time
+// from to the time to.
+func secondsBetween(from, to string) string {
+	if CurrentDialect() == DialectSQLite {
+		return fmt.Sprintf("(unixepoch(%s) - unixepoch(%s))", to, from)
+	}
+	return fmt.Sprintf("TIMESTAMPDIFF(SECOND, %s, %s)", from, to)
+}
+
+// intDiv returns the expression of the integer division of a by b.
+func intDiv(a, b string) string {
+	if CurrentDialect() == DialectSQLite {
+		return fmt.Sprintf("CAST(%s / %s AS INTEGER)", a, b)
+	}
+	return a + " DIV " + b
+}
+
+// like returns the condition on the expression matching the pattern given as
+// its argument, escaped with escapeLike. SQLite has no default escape
+// character.
+func like(expr string) string {
+	if CurrentDialect() == DialectSQLite {
+		return expr + ` LIKE ? ESCAPE '\'`
+	}
+	return expr + " LIKE ?"
+}
+
+// upsertMax returns the value of a column keeping the larger of its value
+// and the one inserted, on an insert conflict.
+func upsertMax(column string) string {
+	if CurrentDialect() == DialectSQLite {
+		return fmt.Sprintf("MAX(%s, excluded.%s)", column, column)
+	}
+	return fmt.Sprintf("GREATEST(%s, VALUES(%s))", column, column)
+}
+
+// upsertAdd returns the value of a column adding the one inserted to its
+// value, on an insert conflict.
+func upsertAdd(column string) string {
+	if CurrentDialect() == DialectSQLite {
+		return fmt.Sprintf("%s + excluded.%s", column, column)
+	}
+	return fmt.Sprintf("%s + VALUES(%s)", column, column)
+}
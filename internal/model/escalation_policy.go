@@ -0,0 +1,111 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EscalationStep notifies a channel of the alerts still unacknowledged
+// Delay minutes after they were raised, zero notifies at once.
+type EscalationStep struct {
+	ChannelId int `json:"channel_id"`
+	Delay     int `json:"delay"`
+}
+
+// EscalationSteps is a custom type for handling []EscalationStep serialization
+type EscalationSteps []EscalationStep
+
+// Value implements driver.Valuer interface for JSON serialization
+func (s EscalationSteps) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (s *EscalationSteps) Scan(value any) error {
+	if value == nil {
+		*s = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, s)
+}
+
+// EscalationPolicy notifies its channels one step after the other until the
+// alerts are acknowledged.
+type EscalationPolicy struct {
+	Id      int    `gorm:"primaryKey"`
+	Name    string `gorm:"type:char(96);unique"`
+	Enabled bool   `gorm:"type:bool"`
+	// MinSeverity is the lowest severity of the alerts escalated, empty
+	// escalates every alert
+	MinSeverity AlertSeverity   `gorm:"type:char(16)"`
+	JobIds      IntSlice        `gorm:"type:json"`
+	CameraIds   IntSlice        `gorm:"type:json"`
+	Steps       EscalationSteps `gorm:"type:json"`
+	CreateTime  time.Time       `gorm:"datetime;autoCreateTime"`
+	UpdateTime  time.Time       `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+// Accept reports whether an alert of the given job, camera and severity is
+// escalated by the policy. Empty filters accept everything.
+func (p *EscalationPolicy) Accept(jobId, cameraId int, severity AlertSeverity) bool {
+	if !p.Enabled || !severity.AtLeast(p.MinSeverity) {
+		return false
+	}
+	if len(p.JobIds) > 0 && !p.JobIds.Contains(jobId) {
+		return false
+	}
+	if len(p.CameraIds) > 0 && !p.CameraIds.Contains(cameraId) {
+		return false
+	}
+	return true
+}
+
+func CreateEscalationPolicy(p *EscalationPolicy) error {
+	return DB.Create(p).Error
+}
+
+func UpdateEscalationPolicy(p *EscalationPolicy) error {
+	return DB.Save(p).Error
+}
+
+func DeleteEscalationPolicy(id int) error {
+	return DB.Delete(&EscalationPolicy{}, id).Error
+}
+
+func GetEscalationPolicyById(id int) (*EscalationPolicy, error) {
+	var p EscalationPolicy
+	err := DB.First(&p, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &p, err
+}
+
+func ListEscalationPolicies() ([]EscalationPolicy, error) {
+	var ps []EscalationPolicy
+	if err := DB.Model(&EscalationPolicy{}).Order("id").Find(&ps).Error; err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+func ListEnabledEscalationPolicies() ([]EscalationPolicy, error) {
+	var ps []EscalationPolicy
+	if err := DB.Where("enabled = ?", true).Order("id").Find(&ps).Error; err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
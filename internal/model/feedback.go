@@ -0,0 +1,99 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FeedbackVerdict string
+
+const (
+	FeedbackTruePositive  FeedbackVerdict = "true_positive"
+	FeedbackFalsePositive FeedbackVerdict = "false_positive"
+)
+
+// MessageFeedback is the verdict of a user on a message, one per message, a
+// new one replacing the previous. The job and the time of the message are
+// copied so that the precision trends outlive the retention of the messages.
+type MessageFeedback struct {
+	Id          int             `gorm:"primaryKey"`
+	MessageId   int             `gorm:"type:int;uniqueIndex"`
+	JobId       int             `gorm:"type:int;index:idx_feedback_job_time,priority:1"`
+	MessageTime time.Time       `gorm:"type:datetime;index:idx_feedback_job_time,priority:2"`
+	Verdict     FeedbackVerdict `gorm:"type:char(16)"`
+	// CorrectedLabels are the labels the message should have had, empty if
+	// they were right or not given
+	CorrectedLabels StringSlice `gorm:"type:json"`
+	Comment         string      `gorm:"type:text"`
+	UserId          int         `gorm:"type:int"`
+	Username        string      `gorm:"type:char(96)"`
+	CreateTime      time.Time   `gorm:"type:datetime"`
+	UpdateTime      time.Time   `gorm:"type:datetime"`
+}
+
+// SaveMessageFeedback creates the feedback of the message, or replaces the
+// verdict, labels and comment of the existing one.
+func SaveMessageFeedback(f *MessageFeedback) error {
+	now := time.Now()
+	f.CreateTime = now
+	f.UpdateTime = now
+	if err := DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "message_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"verdict", "corrected_labels", "comment",
+			"user_id", "username", "update_time"}),
+	}).Create(f).Error; err != nil {
+		return err
+	}
+	// the id and the create time of a replaced feedback are not returned
+	saved, err := GetMessageFeedback(f.MessageId)
+	if err != nil {
+		return err
+	} else if saved != nil {
+		*f = *saved
+	}
+	return nil
+}
+
+// GetMessageFeedback returns the feedback of the message, nil if there is
+// none.
+func GetMessageFeedback(messageId int) (*MessageFeedback, error) {
+	var f MessageFeedback
+	if err := DB.Where("message_id = ?", messageId).First(&f).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &f, nil
+}
+
+func DeleteMessageFeedback(messageId int) error {
+	return DB.Where("message_id = ?", messageId).Delete(&MessageFeedback{}).Error
+}
+
+// FeedbackCount counts the verdicts on the messages of a bucket, the bucket
+// is the index of the interval from the start of the query.
+type FeedbackCount struct {
+	Bucket        int
+	TruePositive  int64
+	FalsePositive int64
+}
+
+// GetJobFeedbackTrend counts the verdicts on the messages of the job taken in
+// [start, end), by bucket of the given size, ordered by bucket.
+func GetJobFeedbackTrend(jobId int, start, end time.Time, bucket time.Duration) ([]*FeedbackCount, error) {
+	var res []*FeedbackCount
+	err := DB.Model(&MessageFeedback{}).
+		Select(intDiv(secondsBetween("?", "message_time"), "?")+" AS bucket, "+
+			"SUM(CASE WHEN verdict = ? THEN 1 ELSE 0 END) AS true_positive, "+
+			"SUM(CASE WHEN verdict = ? THEN 1 ELSE 0 END) AS false_positive",
+			start, int64(bucket/time.Second), FeedbackTruePositive, FeedbackFalsePositive).
+		Where("job_id = ? AND message_time >= ? AND message_time < ?", jobId, start, end).
+		Group("bucket").
+		Order("bucket").
+		Scan(&res).Error
+	return res, err
+}
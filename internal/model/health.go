@@ -0,0 +1,203 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HealthEventKind string
+
+const (
+	// HealthEventMessageVolume is a job producing far fewer or far more
+	// messages than usual at that hour of the week, e.g. because its camera
+	// moved, its model degraded or its detector is stuck
+	HealthEventMessageVolume HealthEventKind = "message_volume"
+	// HealthEventAlertVolume is the same for the alerts of a job
+	HealthEventAlertVolume HealthEventKind = "alert_volume"
+	// HealthEventCanary is the test messages injected by the server failing
+	// or being slow to go through the pipeline
+	HealthEventCanary HealthEventKind = "canary"
+	// HealthEventDeviceOffline is a device that stopped reporting for longer
+	// than the offline threshold
+	HealthEventDeviceOffline HealthEventKind = "device_offline"
+)
+
+// HealthEvent is an anomaly of the system detected by the server. An event
+// stays open while the anomaly lasts and is resolved once it is over or by
+// an operator.
+type HealthEvent struct {
+	Id    int             `gorm:"primaryKey"`
+	Kind  HealthEventKind `gorm:"type:char(32);index"`
+	JobId int             `gorm:"index"`
+	// DeviceId is set for the events of a device instead of a job
+	DeviceId int    `gorm:"index"`
+	Summary  string `gorm:"type:varchar(255)"`
+	// Observed and Expected are the measured and the usual value of the
+	// last anomalous period, Score how many deviations they are apart
+	Observed float64
+	Expected float64
+	Score    float64
+	// StartTime and EndTime bound the anomalous periods
+	StartTime   time.Time `gorm:"datetime"`
+	EndTime     time.Time `gorm:"datetime"`
+	Resolved    bool      `gorm:"index"`
+	ResolveTime *time.Time
+	CreateTime  time.Time `gorm:"datetime;autoCreateTime"`
+	UpdateTime  time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+func SaveHealthEvent(e *HealthEvent) error {
+	if len(e.Summary) > 255 {
+		e.Summary = e.Summary[:255]
+	}
+	return DB.Save(e).Error
+}
+
+func GetHealthEventById(id int) (*HealthEvent, error) {
+	var e HealthEvent
+	err := DB.First(&e, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &e, err
+}
+
+// GetOpenHealthEvent returns the unresolved event of the kind for the job,
+// nil if there is none.
+func GetOpenHealthEvent(kind HealthEventKind, jobId int) (*HealthEvent, error) {
+	var e HealthEvent
+	err := DB.Where("kind = ? AND job_id = ? AND resolved = ?", kind, jobId, false).
+		Order("id DESC").First(&e).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &e, err
+}
+
+// GetOpenDeviceHealthEvent returns the unresolved event of the kind for the
+// device, nil if there is none.
+func GetOpenDeviceHealthEvent(kind HealthEventKind, deviceId int) (*HealthEvent, error) {
+	var e HealthEvent
+	err := DB.Where("kind = ? AND device_id = ? AND resolved = ?", kind, deviceId, false).
+		Order("id DESC").First(&e).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &e, err
+}
+
+func ResolveHealthEvent(id int) error {
+	now := time.Now()
+	return DB.Model(&HealthEvent{}).Where("id = ?", id).Updates(map[string]any{
+		"resolved":     true,
+		"resolve_time": &now,
+	}).Error
+}
+
+type HealthEventFilter struct {
+	Kind     HealthEventKind
+	JobId    int
+	DeviceId int
+	Resolved *bool
+}
+
+func (f HealthEventFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.Kind != "" {
+		db = db.Where("kind = ?", f.Kind)
+	}
+	if f.JobId != 0 {
+		db = db.Where("job_id = ?", f.JobId)
+	}
+	if f.DeviceId != 0 {
+		db = db.Where("device_id = ?", f.DeviceId)
+	}
+	if f.Resolved != nil {
+		db = db.Where("resolved = ?", *f.Resolved)
+	}
+	return db
+}
+
+func ListHealthEvents(filter HealthEventFilter, start, limit int) ([]HealthEvent, int64, error) {
+	var events []HealthEvent
+	var total int64
+	db := filter.apply(DB.Model(&HealthEvent{}))
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("id DESC").Offset(start).Limit(limit).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// VolumeBaseline is the learnt volume of messages and alerts of a job in an
+// hour of the week, as moving averages and variances of the hourly counts.
+type VolumeBaseline struct {
+	Id    int `gorm:"primaryKey"`
+	JobId int `gorm:"uniqueIndex:idx_job_hour"`
+	// HourOfWeek is the hour since Sunday 00:00, in [0, 168)
+	HourOfWeek  int `gorm:"uniqueIndex:idx_job_hour"`
+	Messages    float64
+	MessagesVar float64
+	Alerts      float64
+	AlertsVar   float64
+	Samples     int
+	// LastHour is the start of the last hour learnt, so that an hour is not
+	// learnt twice
+	LastHour time.Time `gorm:"datetime"`
+}
+
+// HourOfWeek returns the hour of t since Sunday 00:00 in its location.
+func HourOfWeek(t time.Time) int {
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// GetVolumeBaselines returns the baselines of the hour of the week by job.
+func GetVolumeBaselines(hourOfWeek int) (map[int]*VolumeBaseline, error) {
+	var bs []*VolumeBaseline
+	if err := DB.Where("hour_of_week = ?", hourOfWeek).Find(&bs).Error; err != nil {
+		return nil, err
+	}
+	res := make(map[int]*VolumeBaseline, len(bs))
+	for _, b := range bs {
+		res[b.JobId] = b
+	}
+	return res, nil
+}
+
+func SaveVolumeBaselines(bs []*VolumeBaseline) error {
+	if len(bs) == 0 {
+		return nil
+	}
+	return DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "job_id"}, {Name: "hour_of_week"}},
+		DoUpdates: clause.AssignmentColumns([]string{"messages", "messages_var", "alerts", "alerts_var",
+			"samples", "last_hour"}),
+	}).Create(bs).Error
+}
+
+// CountMessagesByJob counts the messages and the alerts created in
+// [start, end) by job.
+func CountMessagesByJob(start, end time.Time) (map[int]int64, map[int]int64, error) {
+	var rows []struct {
+		JobId    int
+		Messages int64
+		Alerts   int64
+	}
+	if err := DB.Model(&Message{}).
+		Select("job_id, COUNT(*) AS messages, SUM(CASE WHEN alerted THEN 1 ELSE 0 END) AS alerts").
+		Where("create_time >= ? AND create_time < ?", start, end).
+		Group("job_id").Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	messages := make(map[int]int64, len(rows))
+	alerts := make(map[int]int64, len(rows))
+	for _, r := range rows {
+		messages[r.JobId] = r.Messages
+		alerts[r.JobId] = r.Alerts
+	}
+	return messages, alerts, nil
+}
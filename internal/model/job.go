@@ -1,10 +1,12 @@
 package model
 
 import (
+	"context"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"time"
+	"unicode/utf8"
 
 	"gorm.io/gorm"
 )
@@ -16,6 +18,15 @@ const (
 	ExectorStatusRunning
 	ExectorStatusFinished
 	ExectorStatusFailed
+	// ExectorStatusPending means the device has the job but has not admitted
+	// it yet because its concurrency limit is reached.
+	ExectorStatusPending
+	// ExectorStatusPaused means the executor is allocated but neither
+	// ingests frames nor publishes results.
+	ExectorStatusPaused
+	// ExectorStatusDisarmed means the executor is paused because the time is
+	// outside the arming schedule of the job.
+	ExectorStatusDisarmed
 )
 
 func (s ExectorStatus) String() string {
@@ -28,6 +39,12 @@ func (s ExectorStatus) String() string {
 		return "finished"
 	case ExectorStatusFailed:
 		return "failed"
+	case ExectorStatusPending:
+		return "pending"
+	case ExectorStatusPaused:
+		return "paused"
+	case ExectorStatusDisarmed:
+		return "disarmed"
 	default:
 		return "unknown"
 	}
@@ -38,6 +55,16 @@ type JobKind string
 const (
 	JobKindDetect       JobKind = "detect"
 	JobKindVideoSegment JobKind = "video_segment"
+	// JobKindCanary is the job the server injects test messages through, it
+	// never runs on a device
+	JobKindCanary JobKind = "canary"
+)
+
+type PrivacyMode string
+
+const (
+	PrivacyModeBlur     PrivacyMode = "blur"
+	PrivacyModePixelate PrivacyMode = "pixelate"
 )
 
 type DetectOptions struct {
@@ -49,6 +76,28 @@ type DetectOptions struct {
 
 	TriggerCount    int `json:"trigger_count" gorm:"default:1"`
 	TriggerInterval int `json:"trigger_interval" gorm:"default:30"`
+
+	PrivacyLabels string      `json:"privacy_labels,omitempty"`
+	PrivacyMode   PrivacyMode `json:"privacy_mode,omitempty"`
+
+	Occupancy *OccupancyOptions `json:"occupancy,omitempty"`
+}
+
+// OccupancyZone is a polygon of the frame, its points are normalized by the
+// frame size to [0, 1].
+type OccupancyZone struct {
+	Name    string       `json:"name"`
+	Polygon [][2]float64 `json:"polygon"`
+}
+
+// OccupancyOptions counts the tracked objects in zones of the frame and
+// reports the occupancy of each zone periodically.
+type OccupancyOptions struct {
+	// Labels to count, comma separated
+	Labels string          `json:"labels"`
+	Zones  []OccupancyZone `json:"zones,omitempty"`
+	// Interval in seconds between two reports
+	Interval int `json:"interval"`
 }
 
 // Value implements driver.Valuer interface for JSON serialization
@@ -93,19 +142,243 @@ func (v *VideoSegmentOptions) Scan(value any) error {
 	return json.Unmarshal(bytes, v)
 }
 
+// PluginOptions holds the parameters of a job of a custom kind, they are
+// passed to the executor as is.
+type PluginOptions map[string]any
+
+// Value implements driver.Valuer interface for JSON serialization
+func (p PluginOptions) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (p *PluginOptions) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, p)
+}
+
+// JobHook configures a post-processing hook the device runs on the
+// messages of a job before publishing them.
+type JobHook struct {
+	Name   string         `json:"name"`
+	Params map[string]any `json:"params,omitempty"`
+}
+
+type JobHooks []JobHook
+
+// Value implements driver.Valuer interface for JSON serialization
+func (h JobHooks) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	return json.Marshal(h)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (h *JobHooks) Scan(value any) error {
+	if value == nil {
+		*h = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, h)
+}
+
+// ScheduleWindow is a daily time range, Start and End are HH:MM. A window
+// ending before or when it starts wraps past midnight, so 22:00-06:00 is
+// overnight and 00:00-00:00 is the whole day.
+type ScheduleWindow struct {
+	// Days the window starts on, 0 is Sunday, empty means every day
+	Days  []int  `json:"days,omitempty"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// JobSchedule arms a job only within its windows, the device evaluates it
+// locally and pauses the executor while disarmed.
+type JobSchedule struct {
+	Windows []ScheduleWindow `json:"windows"`
+	// Timezone is an IANA name, the local time of the device if empty
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Value implements driver.Valuer interface for JSON serialization
+func (s JobSchedule) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (s *JobSchedule) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, s)
+}
+
+const (
+	TalkDownOutputOnvif = "onvif"
+	TalkDownOutputLocal = "local"
+)
+
+// TalkDownOptions configures the audio the device plays through the site
+// speaker when a job raises an alert, AudioUrl takes precedence over Text.
+type TalkDownOptions struct {
+	Enabled  bool   `json:"enabled"`
+	Text     string `json:"text,omitempty"`
+	AudioUrl string `json:"audio_url,omitempty"`
+	// Output is onvif for the audio backchannel of the camera or local for
+	// the speaker of the device
+	Output string `json:"output,omitempty"`
+	// Cooldown in seconds between two talk-downs triggered by alerts
+	Cooldown int `json:"cooldown,omitempty"`
+}
+
+// Value implements driver.Valuer interface for JSON serialization
+func (t TalkDownOptions) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (t *TalkDownOptions) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, t)
+}
+
 type Job struct {
-	Id           int                  `json:"id" gorm:"primaryKey"`
-	DeviceId     int                  `json:"device_id" gorm:"index"`
-	Uuid         string               `json:"uuid" gorm:"unique"`
-	Kind         JobKind              `json:"kind" gorm:"default:0"`
-	CameraId     int                  `json:"camera_id" gorm:"NOT NULL"`
-	Status       ExectorStatus        `json:"status" gorm:"default:0"`
-	Enabled      bool                 `json:"enabled" gorm:"default:true"`
+	Id       int           `json:"id" gorm:"primaryKey"`
+	DeviceId int           `json:"device_id" gorm:"index"`
+	Uuid     string        `json:"uuid" gorm:"unique"`
+	OrgId    int           `json:"org_id" gorm:"index;not null;default:1"`
+	Kind     JobKind       `json:"kind" gorm:"default:0"`
+	CameraId int           `json:"camera_id" gorm:"NOT NULL"`
+	Status   ExectorStatus `json:"status" gorm:"default:0"`
+	Enabled  bool          `json:"enabled" gorm:"default:true"`
+	Paused   bool          `json:"paused"`
+	Priority int           `json:"priority"`
+	// MaxFps caps the frames per second a detect job infers, zero means no
+	// cap besides its interval
+	MaxFps       float64              `json:"max_fps" gorm:"default:0"`
 	CreateTime   time.Time            `json:"create_time" gorm:"datetime;autoCreateTime"`
 	UpdateTime   time.Time            `json:"update_time" gorm:"datetime;autoCreateTime;autoUpdateTime"`
 	Detect       *DetectOptions       `json:"detect" gorm:"type:json"`
 	VideoSegment *VideoSegmentOptions `json:"video_segment" gorm:"type:json"`
+	Plugin       PluginOptions        `json:"plugin" gorm:"type:json"`
+	Hooks        JobHooks             `json:"hooks" gorm:"type:json"`
+	TalkDown     *TalkDownOptions     `json:"talk_down" gorm:"type:json"`
 	WorkflowId   int                  `json:"workflow_id" gorm:"default:0"`
+	RestartCount int                  `json:"restart_count" gorm:"default:0"`
+	LastError    string               `json:"last_error" gorm:"type:varchar(1024)"`
+	HealthReason string               `json:"health_reason" gorm:"type:varchar(255)"`
+	// FrameRate is the frames per second the job inferred as last reported,
+	// FrameRateLimit the limit the device throttled it to, zero if none
+	FrameRate      float64 `json:"frame_rate" gorm:"default:0"`
+	FrameRateLimit float64 `json:"frame_rate_limit" gorm:"default:0"`
+	// DeviceGroupId targets every device of the group instead of DeviceId
+	DeviceGroupId int `json:"device_group_id" gorm:"index;default:0"`
+	// Schedule arms the job only within its windows, nil means always armed
+	Schedule *JobSchedule `json:"schedule" gorm:"type:json"`
+	// ResultFilter decides which messages of the job alert, it overrides the
+	// result filter of the workflow
+	ResultFilter *FilterCondition `json:"result_filter" gorm:"type:json"`
+	// SeverityRules decide the severity of the alerts of the job, the
+	// confidence of the answer decides it if none matches
+	SeverityRules SeverityRules `json:"severity_rules" gorm:"type:json"`
+	// AlertDedupWindow in seconds collapses the repeated alerts of the same
+	// labels into one, zero raises an alert for every match
+	AlertDedupWindow int `json:"alert_dedup_window" gorm:"default:0"`
+}
+
+// SeverityRule gives its severity to the alerts whose fields match the
+// filter.
+type SeverityRule struct {
+	Severity AlertSeverity   `json:"severity"`
+	Filter   FilterCondition `json:"filter"`
+}
+
+// SeverityRules is a custom type for handling []SeverityRule serialization
+type SeverityRules []SeverityRule
+
+// Value implements driver.Valuer interface for JSON serialization
+func (r SeverityRules) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return json.Marshal(r)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (r *SeverityRules) Scan(value any) error {
+	if value == nil {
+		*r = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, r)
+}
+
+// AlertSeverity returns the severity of an alert of the job: the one of the
+// first rule matching the fields, otherwise the one the confidence gives.
+func (j *Job) AlertSeverity(fields map[string]string, def string, confidence float32) AlertSeverity {
+	for _, rule := range j.SeverityRules {
+		if len(rule.Filter.Conditions) > 0 && rule.Filter.MatchFields(fields, def) {
+			return rule.Severity
+		}
+	}
+	return SeverityFromConfidence(confidence)
+}
+
+// AlertDedupDuration returns the window the repeated alerts of the job are
+// collapsed within.
+func (j *Job) AlertDedupDuration() time.Duration {
+	return time.Duration(j.AlertDedupWindow) * time.Second
+}
+
+// AlertFilter returns the result filter deciding which messages of the job
+// alert: the one of the job if set, otherwise the one of its workflow. It
+// returns nil if neither has conditions.
+func (j *Job) AlertFilter(wf *Workflow) *FilterCondition {
+	if j.ResultFilter != nil && len(j.ResultFilter.Conditions) > 0 {
+		return j.ResultFilter
+	}
+	if wf != nil && wf.ResultFilter != nil && len(wf.ResultFilter.Conditions) > 0 {
+		return wf.ResultFilter
+	}
+	return nil
 }
 
 func (j *Job) Device() (*Device, error) {
@@ -131,11 +404,31 @@ func (j *Job) Workflow() (*Workflow, error) {
 }
 
 func AddJob(job *Job) error {
-	return DB.Create(job).Error
+	if err := DB.Create(job).Error; err != nil {
+		return err
+	}
+	NotifyWatch(context.Background(), WatchKeyJobs)
+	return nil
 }
 
 func DeleteJob(job *Job) error {
-	return DB.Delete(job).Error
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("job_id = ?", job.Id).Delete(&JobDeviceStatus{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("job_id = ?", job.Id).Delete(&VolumeBaseline{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("job_id = ?", job.Id).Delete(&JobStatusHistory{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(job).Error
+	})
+	if err != nil {
+		return err
+	}
+	NotifyWatch(context.Background(), WatchKeyJobs)
+	return nil
 }
 
 func GetJobByUuid(uuid string) (*Job, error) {
@@ -149,6 +442,15 @@ func GetJobByUuid(uuid string) (*Job, error) {
 	return &job, nil
 }
 
+func GetJobsByUuids(uuids []string) ([]Job, error) {
+	var jobs []Job
+	if len(uuids) == 0 {
+		return jobs, nil
+	}
+	err := DB.Where("uuid IN ?", uuids).Find(&jobs).Error
+	return jobs, err
+}
+
 func GetJobById(id int) (*Job, error) {
 	var job Job
 	if err := DB.Where("id = ?", id).First(&job).Error; err != nil {
@@ -160,34 +462,134 @@ func GetJobById(id int) (*Job, error) {
 	return &job, nil
 }
 
-func ListJobs(start, limit int) ([]Job, int64, error) {
+// ListJobs lists the jobs of the organization but the canary one.
+func ListJobs(orgId, start, limit int) ([]Job, int64, error) {
 	var jobs []Job
 	var total int64
-	if err := DB.Model(&Job{}).Count(&total).Error; err != nil {
+	query := func() *gorm.DB {
+		return DB.Model(&Job{}).Scopes(InOrg(orgId)).Where("kind <> ?", JobKindCanary)
+	}
+	if err := query().Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
-	if err := DB.Model(&Job{}).Offset(start).Limit(limit).Find(&jobs).Error; err != nil {
+	if err := query().Offset(start).Limit(limit).Find(&jobs).Error; err != nil {
 		return nil, 0, err
 	}
 	return jobs, total, nil
 }
 
+// ListRunningJobs returns the enabled jobs reported running.
+func ListRunningJobs() ([]Job, error) {
+	var jobs []Job
+	err := DB.Where("enabled = ? AND status = ?", true, ExectorStatusRunning).Find(&jobs).Error
+	return jobs, err
+}
+
+// CountRunningJobs returns the number of enabled jobs that are running.
+func CountRunningJobs() (int64, error) {
+	var total int64
+	err := DB.Model(&Job{}).Where("enabled = ? AND status = ?", true, ExectorStatusRunning).Count(&total).Error
+	return total, err
+}
+
+// ListJobsByDeviceId lists the jobs assigned to the device, directly or
+// through one of its groups.
 func ListJobsByDeviceId(deviceId int, start, limit int) ([]Job, int64, error) {
 	var jobs []Job
 	var total int64
-	if err := DB.Model(&Job{}).Where("device_id = ?", deviceId).Count(&total).Error; err != nil {
+	groups := DB.Model(&DeviceGroupMember{}).Select("group_id").Where("device_id = ?", deviceId)
+	query := func() *gorm.DB {
+		return DB.Model(&Job{}).Where("device_id = ? OR device_group_id IN (?)", deviceId, groups)
+	}
+	if err := query().Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
-	if err := DB.Model(&Job{}).Where("device_id = ?", deviceId).Offset(start).Limit(limit).Find(&jobs).Error; err != nil {
+	if err := query().Offset(start).Limit(limit).Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// ListJobsByCameraId lists the jobs on the camera.
+func ListJobsByCameraId(cameraId int, start, limit int) ([]Job, int64, error) {
+	var jobs []Job
+	var total int64
+	query := func() *gorm.DB {
+		return DB.Model(&Job{}).Where("camera_id = ? AND kind <> ?", cameraId, JobKindCanary)
+	}
+	if err := query().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query().Offset(start).Limit(limit).Find(&jobs).Error; err != nil {
 		return nil, 0, err
 	}
 	return jobs, total, nil
 }
 
 func UpdateJob(job *Job) error {
-	return DB.Save(job).Error
+	if err := DB.Save(job).Error; err != nil {
+		return err
+	}
+	NotifyWatch(context.Background(), WatchKeyJobs)
+	return nil
+}
+
+// TouchJobsByCameraId bumps the UpdateTime of the jobs on a camera so that
+// devices restart them with the new camera settings.
+func TouchJobsByCameraId(cameraId int) error {
+	if err := DB.Model(&Job{}).Where("camera_id = ?", cameraId).Update("update_time", time.Now()).Error; err != nil {
+		return err
+	}
+	NotifyWatch(context.Background(), WatchKeyJobs)
+	return nil
 }
 
 func UpdateJobStatus(id int, status ExectorStatus) error {
 	return DB.Model(&Job{}).Omit("UpdateTime").Where("id = ?", id).Update("status", status).Error
 }
+
+// JobRuntime is the state of a job reported by the device.
+type JobRuntime struct {
+	Status         ExectorStatus
+	RestartCount   int
+	LastError      string
+	HealthReason   string
+	FrameRate      float64
+	FrameRateLimit float64
+}
+
+// Runtime returns the state of the job last reported by the device.
+func (j *Job) Runtime() JobRuntime {
+	return JobRuntime{
+		Status:         j.Status,
+		RestartCount:   j.RestartCount,
+		LastError:      j.LastError,
+		HealthReason:   j.HealthReason,
+		FrameRate:      j.FrameRate,
+		FrameRateLimit: j.FrameRateLimit,
+	}
+}
+
+// UpdateJobRuntime updates the state reported by the device, it leaves
+// UpdateTime untouched so that devices do not see the job as modified.
+func UpdateJobRuntime(id int, rt JobRuntime) error {
+	rt.LastError = truncateRunes(rt.LastError, 1024)
+	rt.HealthReason = truncateRunes(rt.HealthReason, 255)
+	return DB.Model(&Job{}).Omit("UpdateTime").Where("id = ?", id).Updates(map[string]any{
+		"status":           rt.Status,
+		"restart_count":    rt.RestartCount,
+		"last_error":       rt.LastError,
+		"health_reason":    rt.HealthReason,
+		"frame_rate":       rt.FrameRate,
+		"frame_rate_limit": rt.FrameRateLimit,
+	}).Error
+}
+
+// truncateRunes cuts s to the n characters a varchar(n) column holds,
+// without splitting a multi-byte character.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
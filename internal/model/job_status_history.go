@@ -0,0 +1,89 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type JobStatusSource string
+
+const (
+	// JobStatusSourceDevice is a change of the executor status reported by a
+	// device
+	JobStatusSourceDevice JobStatusSource = "device"
+	// JobStatusSourceServer is a change made through the API
+	JobStatusSourceServer JobStatusSource = "server"
+)
+
+// Actions recorded as the status of the server-set changes.
+const (
+	JobActionCreate = "create"
+	JobActionStart  = "start"
+	JobActionStop   = "stop"
+	JobActionPause  = "pause"
+	JobActionResume = "resume"
+)
+
+// JobStatusHistory is a status transition of a job. For a device-reported
+// transition Status and PrevStatus are executor statuses and DeviceId the
+// reporting device, for a server-set one Status is the action and Username
+// who made it, empty if the request was not authenticated.
+type JobStatusHistory struct {
+	Id         int             `gorm:"primaryKey"`
+	JobId      int             `gorm:"index"`
+	Source     JobStatusSource `gorm:"type:char(16)"`
+	Status     string          `gorm:"type:char(32)"`
+	PrevStatus string          `gorm:"type:char(32)"`
+	DeviceId   int
+	Username   string `gorm:"type:varchar(96)"`
+	// Reason is the error or health reason reported along the transition
+	Reason     string    `gorm:"type:varchar(1024)"`
+	CreateTime time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+func AddJobStatusHistory(hs []*JobStatusHistory) error {
+	if len(hs) == 0 {
+		return nil
+	}
+	for _, h := range hs {
+		if len(h.Reason) > 1024 {
+			h.Reason = h.Reason[:1024]
+		}
+	}
+	return DB.Create(hs).Error
+}
+
+// ListJobStatusHistory lists the transitions of the job, newest first.
+func ListJobStatusHistory(jobId, start, limit int) ([]JobStatusHistory, int64, error) {
+	var hs []JobStatusHistory
+	var total int64
+	db := DB.Model(&JobStatusHistory{}).Where("job_id = ?", jobId)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("id DESC").Offset(start).Limit(limit).Find(&hs).Error; err != nil {
+		return nil, 0, err
+	}
+	return hs, total, nil
+}
+
+func GetLatestJobStatusHistoryId() (int, error) {
+	var h JobStatusHistory
+	if err := DB.Model(&JobStatusHistory{}).Order("id desc").First(&h).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return h.Id, nil
+}
+
+func GetJobStatusHistoryAfterId(id, limit int) ([]JobStatusHistory, error) {
+	var hs []JobStatusHistory
+	if err := DB.Model(&JobStatusHistory{}).Where("id > ?", id).Order("id asc").Limit(limit).Find(&hs).Error; err != nil {
+		return nil, err
+	}
+	return hs, nil
+}
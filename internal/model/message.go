@@ -4,18 +4,22 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// DetectionBox is a box in pixels of the frame, zero coordinates are on the
+// edge of the frame so they are always encoded.
 type DetectionBox struct {
-	X1         int     `json:"x1,omitempty"`
-	Y1         int     `json:"y1,omitempty"`
-	X2         int     `json:"x2,omitempty"`
-	Y2         int     `json:"y2,omitempty"`
-	Confidence float32 `json:"confidence,omitempty"`
-	ClassId    int     `json:"classId,omitempty"`
+	X1         int     `json:"x1"`
+	Y1         int     `json:"y1"`
+	X2         int     `json:"x2"`
+	Y2         int     `json:"y2"`
+	Confidence float32 `json:"confidence"`
+	ClassId    int     `json:"classId"`
 	Label      string  `json:"label,omitempty"`
 }
 
@@ -67,8 +71,8 @@ func (d *DetectionBoxSlice) Scan(value any) error {
 type WorkflowResp struct {
 	Answer      string  `json:"answer,omitempty" gorm:"type:text"`
 	RawContent  string  `json:"raw_content,omitempty" gorm:"type:text"`
-	Confidence  float32 `json:"confidence,omitempty" gorm:"type:float"`
-	Match       bool    `json:"match,omitempty" gorm:"type:bool"`
+	Confidence  float32 `json:"confidence" gorm:"type:float"`
+	Match       bool    `json:"match" gorm:"type:bool"`
 	TotalTokens int     `json:"total_tokens" gorm:"type:int"`
 }
 
@@ -89,30 +93,132 @@ func (w *WorkflowResp) Scan(value any) error {
 	return json.Unmarshal(bytes, w)
 }
 
+// MessageEvidence is the signature of the device over the message and the
+// hashes of its media, Payload is the signed content as it was signed.
+type MessageEvidence struct {
+	DeviceUuid     string `json:"deviceUuid"`
+	KeyFingerprint string `json:"keyFingerprint"`
+	ImageSha256    string `json:"imageSha256,omitempty"`
+	VideoSha256    string `json:"videoSha256,omitempty"`
+	Payload        []byte `json:"payload"`
+	Signature      []byte `json:"signature"`
+}
+
+func (e MessageEvidence) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+func (e *MessageEvidence) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, e)
+}
+
+// MessageMetadata holds free-form fields of a message, e.g. the fields added
+// by device hooks or the device sensor readings.
+type MessageMetadata map[string]any
+
+// Value implements driver.Valuer interface for JSON serialization
+func (m MessageMetadata) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(m)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (m *MessageMetadata) Scan(value any) error {
+	if value == nil {
+		*m = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, m)
+}
+
 type Message struct {
 	Id           int               `json:"id" gorm:"primaryKey"`
-	JobId        int               `json:"jobId" gorm:"type:int;index"`
-	Timestamp    time.Time         `json:"timestamp" gorm:"type:datetime;index"`
+	JobId        int               `json:"jobId" gorm:"type:int;index;index:idx_message_job_time,priority:1"`
+	OrgId        int               `json:"orgId" gorm:"index;not null;default:1"`
+	Timestamp    time.Time         `json:"timestamp" gorm:"type:datetime;index;index:idx_message_job_time,priority:2"`
 	ImagePath    string            `json:"imagePath,omitempty" gorm:"type:varchar(255)"`
 	DetectBoxes  DetectionBoxSlice `json:"detectBoxes,omitempty" gorm:"type:json"`
 	VideoPath    string            `json:"videoPath,omitempty" gorm:"type:varchar(255)"`
 	CreateTime   time.Time         `json:"createTime" gorm:"type:datetime;autoCreateTime"`
 	WorkflowResp *WorkflowResp     `json:"workflowResp,omitempty" gorm:"type:json"`
 	Alerted      bool              `json:"alerted,omitempty" gorm:"type:bool;default:false"`
+	Metadata     MessageMetadata   `json:"metadata,omitempty" gorm:"type:json"`
+	Sensors      MessageMetadata   `json:"sensors,omitempty" gorm:"type:json"`
+	// size in pixels of the frame the boxes are in, zero if unknown
+	FrameWidth  int `json:"frameWidth,omitempty" gorm:"type:int;default:0"`
+	FrameHeight int `json:"frameHeight,omitempty" gorm:"type:int;default:0"`
+	// AnswerText copies the answer of the workflow out of the JSON column for
+	// the full-text index, the ngram parser splitting the Chinese text. The
+	// index is created by the migrations on MySQL only.
+	AnswerText string `json:"-" gorm:"type:text"`
+	// Evidence is set for the messages signed by their device
+	Evidence *MessageEvidence `json:"evidence,omitempty" gorm:"type:json"`
 }
 
-func AddMessage(m *Message) error {
+// AddMessage saves the message, and its alert with the given severity if the
+// message alerted. With a positive dedupWindow, an alert of the same job and
+// labels that is not resolved and last occurred within the window counts the
+// message as one more occurrence instead of a new alert being raised.
+func AddMessage(m *Message, severity AlertSeverity, dedupWindow time.Duration) error {
+	if m.WorkflowResp != nil {
+		m.AnswerText = m.WorkflowResp.Answer
+	}
 	return DB.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(m).Error; err != nil {
 			return err
 		}
-		if m.Alerted {
-			alert := &AlertMessage{MessageId: m.Id}
-			if err := tx.Create(alert).Error; err != nil {
+		if !m.Alerted {
+			return nil
+		}
+
+		now := time.Now()
+		key := alertDedupKey(m)
+		if dedupWindow > 0 {
+			var alert AlertMessage
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("dedup_key = ? AND last_occur_time >= ? AND status <> ?", key, now.Add(-dedupWindow), AlertStatusResolved).
+				Order("id desc").Limit(1).Find(&alert).Error; err != nil {
 				return err
 			}
+			if alert.Id != 0 {
+				updates := map[string]any{
+					"occurrences":     gorm.Expr("occurrences + 1"),
+					"last_occur_time": now,
+					"last_message_id": m.Id,
+				}
+				if severity.Rank() > alert.Severity.Rank() {
+					updates["severity"] = severity
+				}
+				return tx.Model(&alert).UpdateColumns(updates).Error
+			}
 		}
-		return nil
+
+		alert := &AlertMessage{
+			MessageId:     m.Id,
+			Severity:      severity,
+			DedupKey:      key,
+			Occurrences:   1,
+			LastOccurTime: &now,
+			LastMessageId: m.Id,
+		}
+		return tx.Create(alert).Error
 	})
 }
 
@@ -131,78 +237,328 @@ func GetMessage(id int) (*Message, error) {
 	return m, nil
 }
 
-func GetMessagesByJobId(jobId, start, limit int) ([]*Message, int64, error) {
+// GetMessageByJobIdAndTimestamp returns the message of the job taken at ts,
+// nil if there is none.
+// GetMessagesByIds returns the messages of the ids that exist, in no
+// particular order.
+func GetMessagesByIds(ids []int) ([]*Message, error) {
 	var ms []*Message
-	if err := DB.Where("job_id = ?", jobId).Order("id desc").Offset(start).Limit(limit).Find(&ms).Error; err != nil {
-		return nil, 0, err
+	if len(ids) == 0 {
+		return ms, nil
 	}
-	var count int64
-	if err := DB.Model(&Message{}).Where("job_id = ?", jobId).Count(&count).Error; err != nil {
-		return nil, 0, err
+	err := DB.Where("id IN ?", ids).Find(&ms).Error
+	return ms, err
+}
+
+func GetMessageByJobIdAndTimestamp(jobId int, ts time.Time) (*Message, error) {
+	var m Message
+	err := DB.Where("job_id = ? AND timestamp = ?", jobId, ts).First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
 	}
-	return ms, count, nil
+	return &m, err
 }
 
-func GetMessages(start, limit int) ([]*Message, int64, error) {
-	var total int64
-	var ms []*Message
-	if err := DB.Order("id desc").Offset(start).Limit(limit).Find(&ms).Error; err != nil {
-		return nil, 0, err
+func DeleteMessagesByJobId(jobId int) error {
+	return DB.Where("job_id = ?", jobId).Delete(&Message{}).Error
+}
+
+// MessageFilter selects the messages to list, a zero field matches any
+// message.
+type MessageFilter struct {
+	OrgId    int
+	JobId    int
+	CameraId int
+	// DeviceId matches the messages of the jobs assigned to the device,
+	// directly or through one of its groups
+	DeviceId int
+	// Start and End bound the time the messages were taken, [Start, End)
+	Start time.Time
+	End   time.Time
+	// Label matches the messages with a detection box of the label, of a
+	// confidence not lower than MinConfidence
+	Label         string
+	MinConfidence float32
+	// Alerted matches the messages that raised an alert, of the given
+	// AlertStatus if it is not empty
+	Alerted     bool
+	AlertStatus AlertStatus
+	// Text matches the messages whose workflow answer contains every word of
+	// it, see MatchAnswerQuery
+	Text string
+}
+
+func (f MessageFilter) apply(db *gorm.DB) *gorm.DB {
+	db = db.Scopes(InOrgOf("messages", f.OrgId))
+	if f.JobId != 0 {
+		db = db.Where("messages.job_id = ?", f.JobId)
 	}
-	if err := DB.Model(&Message{}).Count(&total).Error; err != nil {
-		return nil, 0, err
+	if f.CameraId != 0 {
+		db = db.Where("messages.job_id IN (?)", DB.Model(&Job{}).Select("id").Where("camera_id = ?", f.CameraId))
 	}
-	return ms, total, nil
+	if f.DeviceId != 0 {
+		groups := DB.Model(&DeviceGroupMember{}).Select("group_id").Where("device_id = ?", f.DeviceId)
+		jobs := DB.Model(&Job{}).Select("id").Where("device_id = ? OR device_group_id IN (?)", f.DeviceId, groups)
+		db = db.Where("messages.job_id IN (?)", jobs)
+	}
+	if !f.Start.IsZero() {
+		db = db.Where("messages.timestamp >= ?", f.Start)
+	}
+	if !f.End.IsZero() {
+		db = db.Where("messages.timestamp < ?", f.End)
+	}
+	if f.Label != "" || f.MinConfidence > 0 {
+		// the boxes are only scanned for the rows the indexed conditions select
+		table, label, confidence := detectBoxes()
+		boxes := "SELECT 1 FROM " + table + " WHERE " + confidence + " >= ?"
+		args := []any{f.MinConfidence}
+		if f.Label != "" {
+			boxes += " AND " + label + " = ?"
+			args = append(args, f.Label)
+		}
+		db = db.Where("EXISTS ("+boxes+")", args...)
+	}
+	if q := MatchAnswerQuery(f.Text); q != "" {
+		if Supports(CapFullTextSearch) {
+			db = db.Where("MATCH(messages.answer_text) AGAINST (? IN BOOLEAN MODE)", q)
+		} else {
+			for _, word := range strings.Fields(f.Text) {
+				db = db.Where(like("messages.answer_text"), "%"+escapeLike(word)+"%")
+			}
+		}
+	}
+	if f.Alerted {
+		// a deduplicated alert lists the message that raised it
+		db = db.Joins("JOIN alert_messages ON alert_messages.message_id = messages.id")
+		if f.AlertStatus != "" {
+			db = db.Where("alert_messages.status = ?", f.AlertStatus)
+		}
+	}
+	return db
 }
 
-type AlertMessage struct {
-	Id         int       `gorm:"primaryKey"`
-	MessageId  int       `gorm:"type:int;index"`
-	Message    Message   `gorm:"foreignKey:MessageId;references:Id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	CreateTime time.Time `gorm:"type:datetime;autoCreateTime"`
+// ListMessagesBefore returns the messages matching the filter with an id
+// lower than beforeId, the newest first, so that long lists are read in
+// batches. A zero beforeId starts from the newest message.
+func ListMessagesBefore(filter MessageFilter, beforeId, limit int) ([]*Message, error) {
+	var ms []*Message
+	db := filter.apply(DB.Model(&Message{}))
+	if beforeId > 0 {
+		db = db.Where("messages.id < ?", beforeId)
+	}
+	err := db.Select("messages.*").Order("messages.id desc").Limit(limit).Find(&ms).Error
+	return ms, err
 }
 
-func GetAlertMessagesByJobId(jobId, start, limit int) ([]*Message, int64, error) {
-	var alerts []*AlertMessage
-	base := DB.Model(&AlertMessage{}).
-		Joins("JOIN messages ON messages.id = alert_messages.message_id").
-		Where("messages.job_id = ?", jobId)
+// MatchAnswerQuery turns the words of text into a boolean mode full-text
+// query requiring each of them as a phrase, so that a Chinese sentence
+// matches the answers containing it rather than any of its ngrams. It is
+// empty if text has no word.
+func MatchAnswerQuery(text string) string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		// the operators of the boolean mode are not searchable anyway
+		word = strings.Trim(strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`"+-<>()~*@`, r) {
+				return ' '
+			}
+			return r
+		}, word), " ")
+		if word != "" {
+			terms = append(terms, `+"`+word+`"`)
+		}
+	}
+	return strings.Join(terms, " ")
+}
 
-	if err := base.Preload("Message").
-		Order("alert_messages.id desc").
+// ListMessages returns the messages matching the filter, the newest first.
+func ListMessages(filter MessageFilter, start, limit int) ([]*Message, int64, error) {
+	var ms []*Message
+	var total int64
+	if err := filter.apply(DB.Model(&Message{})).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := filter.apply(DB.Model(&Message{})).
+		Select("messages.*").
+		Order("messages.id desc").
 		Offset(start).
 		Limit(limit).
-		Find(&alerts).Error; err != nil {
+		Find(&ms).Error; err != nil {
 		return nil, 0, err
 	}
+	return ms, total, nil
+}
+
+// ListMessagesBetween returns the messages taken in [start, end) with an id
+// greater than afterId, by id, so that long ranges are read in batches. Only
+// the job, the time and the detection boxes are loaded.
+func ListMessagesBetween(start, end time.Time, afterId, limit int) ([]*Message, error) {
+	var ms []*Message
+	err := DB.Model(&Message{}).
+		Select("id, job_id, timestamp, detect_boxes").
+		Where("timestamp >= ? AND timestamp < ?", start, end).
+		Where("id > ?", afterId).
+		Order("id asc").
+		Limit(limit).
+		Find(&ms).Error
+	return ms, err
+}
 
+func CountMessagesBetween(start, end time.Time) (int64, error) {
 	var count int64
-	if err := base.Count(&count).Error; err != nil {
-		return nil, 0, err
+	err := DB.Model(&Message{}).Where("timestamp >= ? AND timestamp < ?", start, end).Count(&count).Error
+	return count, err
+}
+
+type AlertSeverity string
+
+const (
+	SeverityLow      AlertSeverity = "low"
+	SeverityMedium   AlertSeverity = "medium"
+	SeverityHigh     AlertSeverity = "high"
+	SeverityCritical AlertSeverity = "critical"
+)
+
+// AlertSeverities are the severities from the lowest to the highest.
+var AlertSeverities = []AlertSeverity{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// Rank orders the severities, unknown severities rank as medium.
+func (s AlertSeverity) Rank() int {
+	for i, severity := range AlertSeverities {
+		if s == severity {
+			return i
+		}
 	}
+	return 1
+}
 
-	var ms []*Message
+// AtLeast reports whether the severity is not lower than min, an empty min
+// accepts every severity.
+func (s AlertSeverity) AtLeast(min AlertSeverity) bool {
+	return min == "" || s.Rank() >= min.Rank()
+}
+
+// SeverityFromConfidence derives the severity of an alert from the
+// confidence of the workflow answer, medium if the workflow reports none.
+func SeverityFromConfidence(confidence float32) AlertSeverity {
+	switch {
+	case confidence <= 0:
+		return SeverityMedium
+	case confidence >= 0.9:
+		return SeverityHigh
+	case confidence >= 0.6:
+		return SeverityMedium
+	default:
+		return SeverityLow
+	}
+}
+
+type AlertMessage struct {
+	Id        int           `gorm:"primaryKey"`
+	MessageId int           `gorm:"type:int;index"`
+	Message   Message       `gorm:"foreignKey:MessageId;references:Id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Severity  AlertSeverity `gorm:"type:char(16);default:medium"`
+	// AckTime is when a user acknowledged the alert, nil if nobody did, the
+	// escalation of acknowledged alerts stops
+	AckTime   *time.Time  `gorm:"type:datetime;index"`
+	AckUserId int         `gorm:"type:int;default:0"`
+	Status    AlertStatus `gorm:"type:char(16);default:open;index"`
+	// AssigneeId is the user handling the alert, 0 if nobody is assigned
+	AssigneeId    int        `gorm:"type:int;default:0;index"`
+	ResolveTime   *time.Time `gorm:"type:datetime"`
+	ResolveUserId int        `gorm:"type:int;default:0"`
+	CreateTime    time.Time  `gorm:"type:datetime;autoCreateTime;index"`
+	// DedupKey identifies the job and the labels of the alert, the later
+	// matches of the same key within the dedup window of the job are counted
+	// as occurrences of the alert
+	DedupKey      string     `gorm:"type:varchar(255);index:idx_alert_dedup"`
+	Occurrences   int        `gorm:"type:int;default:1"`
+	LastOccurTime *time.Time `gorm:"type:datetime;index:idx_alert_dedup"`
+	// LastMessageId is the message of the last occurrence
+	LastMessageId int `gorm:"type:int;default:0"`
+}
+
+// GetAlertSeveritiesByMessageIds returns the severities of the alerts raised
+// or last occurred on the messages, keyed by message id. The messages counted
+// as middle occurrences of an alert have none.
+func GetAlertSeveritiesByMessageIds(ids []int) (map[int]AlertSeverity, error) {
+	res := make(map[int]AlertSeverity)
+	if len(ids) == 0 {
+		return res, nil
+	}
+	var alerts []AlertMessage
+	if err := DB.Select("message_id", "last_message_id", "severity").
+		Where("message_id IN ? OR last_message_id IN ?", ids, ids).
+		Find(&alerts).Error; err != nil {
+		return nil, err
+	}
 	for _, a := range alerts {
-		ms = append(ms, &a.Message)
+		res[a.MessageId] = a.Severity
+		if a.LastMessageId != 0 {
+			res[a.LastMessageId] = a.Severity
+		}
 	}
+	return res, nil
+}
 
-	return ms, count, nil
+// GetAlertMessageByMessageId returns the alert of the message, nil if the
+// message did not alert.
+func GetAlertMessageByMessageId(messageId int) (*AlertMessage, error) {
+	var alert AlertMessage
+	err := DB.Where("message_id = ?", messageId).First(&alert).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &alert, err
 }
 
-func GetAlertMessages(start, limit int) ([]*Message, int64, error) {
-	var total int64
+// ListUnackedAlertMessages returns the alerts created in [start, end) that
+// nobody acknowledged, with their messages.
+func ListUnackedAlertMessages(start, end time.Time) ([]*AlertMessage, error) {
 	var alerts []*AlertMessage
-	if err := DB.Preload("Message").Model(&AlertMessage{}).Order("id desc").Offset(start).Limit(limit).Find(&alerts).Error; err != nil {
-		return nil, 0, err
+	if err := DB.Preload("Message").Model(&AlertMessage{}).
+		Where("ack_time IS NULL").
+		Where("create_time >= ? AND create_time < ?", start, end).
+		Order("id asc").Find(&alerts).Error; err != nil {
+		return nil, err
 	}
-	if err := DB.Model(&AlertMessage{}).Count(&total).Error; err != nil {
-		return nil, 0, err
+	return alerts, nil
+}
+
+func GetLatestAlertMessageId() (int, error) {
+	var alert AlertMessage
+	if err := DB.Model(&AlertMessage{}).Order("id desc").First(&alert).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
 	}
+	return alert.Id, nil
+}
 
-	var ms []*Message
-	for _, a := range alerts {
-		ms = append(ms, &a.Message)
+func GetAlertMessagesAfterId(id, limit int) ([]*AlertMessage, error) {
+	var alerts []*AlertMessage
+	if err := DB.Preload("Message").Model(&AlertMessage{}).Where("id > ?", id).Order("id asc").Limit(limit).Find(&alerts).Error; err != nil {
+		return nil, err
 	}
+	return alerts, nil
+}
 
-	return ms, total, nil
+// ListCameraDetectionMessages returns the messages with detection boxes of the
+// jobs on a camera in [start, end), the newest first.
+func ListCameraDetectionMessages(cameraId int, start, end time.Time, limit int) ([]*Message, error) {
+	var ms []*Message
+	err := DB.Model(&Message{}).
+		Select("messages.id, messages.job_id, messages.timestamp, messages.image_path, messages.detect_boxes").
+		Joins("JOIN jobs ON jobs.id = messages.job_id").
+		Where("jobs.camera_id = ?", cameraId).
+		Where("messages.timestamp >= ? AND messages.timestamp < ?", start, end).
+		Where("messages.detect_boxes IS NOT NULL").
+		Order("messages.id desc").
+		Limit(limit).
+		Find(&ms).Error
+	if err != nil {
+		return nil, err
+	}
+	return ms, nil
 }
@@ -0,0 +1,170 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned change of the schema. The migrations are applied
+// once each, in the order of their ids, and reverted in the reverse order.
+type Migration struct {
+	// Id orders the migrations, the date it was written followed by a
+	// sequence number, e.g. 202610170001
+	Id          string
+	Description string
+	Up          func(tx *gorm.DB) error
+	// Down reverts Up, nil if the migration cannot be reverted
+	Down func(tx *gorm.DB) error
+}
+
+// SchemaMigration records a migration applied to the database.
+type SchemaMigration struct {
+	Id          string    `gorm:"type:varchar(64);primaryKey"`
+	AppliedTime time.Time `gorm:"type:datetime"`
+}
+
+// MigrationStatus tells whether a migration was applied to the database.
+type MigrationStatus struct {
+	Id          string
+	Description string
+	// AppliedTime is nil for the pending migrations
+	AppliedTime *time.Time
+	Reversible  bool
+	// Unknown is set for the migrations applied by a newer lumina
+	Unknown bool
+}
+
+var ErrMigrationIrreversible = errors.New("migration cannot be reverted")
+
+func appliedMigrations(db *gorm.DB) (map[string]SchemaMigration, error) {
+	var records []SchemaMigration
+	if err := db.Order("id").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[string]SchemaMigration, len(records))
+	for _, r := range records {
+		applied[r.Id] = r
+	}
+	return applied, nil
+}
+
+// Migrate applies the pending migrations up to the one of the id, all of
+// them if it is empty. A new database runs them all, as does a database
+// created by AutoMigrate, the baseline adding only what it lacks. Each
+// migration runs in a transaction, though MySQL commits the schema changes
+// at once.
+func Migrate(db *gorm.DB, id string) error {
+	if id != "" && !slices.ContainsFunc(migrations, func(m Migration) bool { return m.Id == id }) {
+		return fmt.Errorf("unknown migration %s", id)
+	}
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if id != "" && m.Id > id {
+			break
+		}
+		if _, ok := applied[m.Id]; ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Id: m.Id, AppliedTime: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("apply migration %s: %w", m.Id, err)
+		}
+	}
+	return nil
+}
+
+// Rollback reverts the last steps applied migrations, stopping at the first
+// one that cannot be reverted.
+func Rollback(db *gorm.DB, steps int) error {
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return nil
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Id]; !ok {
+			continue
+		}
+		if m.Down == nil {
+			return fmt.Errorf("revert migration %s: %w", m.Id, ErrMigrationIrreversible)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{Id: m.Id}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("revert migration %s: %w", m.Id, err)
+		}
+		steps--
+	}
+	return nil
+}
+
+// ListMigrationStatus returns the status of every migration, by id, along
+// with the ones applied by a newer lumina.
+func ListMigrationStatus(db *gorm.DB) ([]MigrationStatus, error) {
+	applied := map[string]SchemaMigration{}
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		var err error
+		if applied, err = appliedMigrations(db); err != nil {
+			return nil, err
+		}
+	}
+	res := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Id: m.Id, Description: m.Description, Reversible: m.Down != nil}
+		if r, ok := applied[m.Id]; ok {
+			status.AppliedTime = &r.AppliedTime
+			delete(applied, m.Id)
+		}
+		res = append(res, status)
+	}
+	for _, r := range applied {
+		res = append(res, MigrationStatus{Id: r.Id, AppliedTime: &r.AppliedTime, Unknown: true})
+	}
+	slices.SortFunc(res, func(a, b MigrationStatus) int {
+		switch {
+		case a.Id < b.Id:
+			return -1
+		case a.Id > b.Id:
+			return 1
+		}
+		return 0
+	})
+	return res, nil
+}
+
+// PendingMigrations returns the ids of the migrations not applied yet.
+func PendingMigrations(db *gorm.DB) ([]string, error) {
+	statuses, err := ListMigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []string
+	for _, s := range statuses {
+		if s.AppliedTime == nil {
+			pending = append(pending, s.Id)
+		}
+	}
+	return pending, nil
+}
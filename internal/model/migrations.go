@@ -0,0 +1,95 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// migrations are the changes of the schema, ordered by id. A change of a
+// model is released with a migration appending the change to the
+// databases, never by editing the applied ones, and the migrations only use
+// the tables they freeze, such as the baseline ones, not the models.
+var migrations = []Migration{
+	{
+		Id:          "202610170001",
+		Description: "create the baseline tables",
+		Up:          createBaselineTables,
+		// the rows of every table are lost
+		Down: dropBaselineTables,
+	},
+	{
+		Id:          "202610170002",
+		Description: "store the chat answers as LONGTEXT",
+		Up: func(tx *gorm.DB) error {
+			if dialectOf(tx) != DialectMySQL {
+				return nil
+			}
+			return tx.Exec("ALTER TABLE chat_messages MODIFY COLUMN answer LONGTEXT").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if dialectOf(tx) != DialectMySQL {
+				return nil
+			}
+			// fails rather than truncating the answers too long for TEXT
+			return tx.Exec("ALTER TABLE chat_messages MODIFY COLUMN answer TEXT").Error
+		},
+	},
+	{
+		Id:          "202610170003",
+		Description: "count the device bound to the access tokens",
+		Up: func(tx *gorm.DB) error {
+			// the tokens created before they could register several devices
+			return tx.Exec("UPDATE access_tokens SET bind_count = 1 WHERE bind_count = 0 AND device_uuid != ''").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			// the counts stay, they are right whether the backfill ran or
+			// not, and the counts of 1 it set cannot be told from the others
+			return nil
+		},
+	},
+	{
+		Id:          "202610170004",
+		Description: "index the answers of the messages for the full-text search",
+		Up: func(tx *gorm.DB) error {
+			// the messages saved before the column was added
+			err := tx.Exec("UPDATE messages SET answer_text = COALESCE(" + dialectJsonText(dialectOf(tx), "workflow_resp", "$.answer") + ", '') " +
+				"WHERE answer_text IS NULL AND workflow_resp IS NOT NULL").Error
+			if err != nil {
+				return err
+			}
+			// SQLite has no full-text index, see CapFullTextSearch
+			if dialectOf(tx) != DialectMySQL || tx.Migrator().HasIndex(&baselineMessage{}, "idx_message_answer") {
+				return nil
+			}
+			// the ngram parser splits the Chinese text
+			return tx.Exec("CREATE FULLTEXT INDEX idx_message_answer ON messages (answer_text) WITH PARSER ngram").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			// the answers copied to answer_text stay, the column being a
+			// baseline one
+			if dialectOf(tx) != DialectMySQL || !tx.Migrator().HasIndex(&baselineMessage{}, "idx_message_answer") {
+				return nil
+			}
+			return tx.Migrator().DropIndex(&baselineMessage{}, "idx_message_answer")
+		},
+	},
+	{
+		Id:          "202610170005",
+		Description: "filter the web pushes by the severity of the alerts",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&notificationPreferenceSeverity{}, "MinSeverity") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&notificationPreferenceSeverity{}, "MinSeverity")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&notificationPreferenceSeverity{}, "MinSeverity")
+		},
+	},
+}
+
+// notificationPreferenceSeverity freezes the column added by 202610170005.
+type notificationPreferenceSeverity struct {
+	MinSeverity string `gorm:"type:char(16)"`
+}
+
+func (notificationPreferenceSeverity) TableName() string { return "notification_preferences" }
@@ -0,0 +1,570 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The tables of the baseline migration, frozen as the models were when the
+// migrations replaced AutoMigrate. The models change with the migrations
+// written after it, never these.
+
+type baselineOrganization struct {
+	Id          int       `gorm:"primaryKey"`
+	Name        string    `gorm:"type:varchar(96);uniqueIndex"`
+	CreatedTime time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+func (baselineOrganization) TableName() string { return "organizations" }
+
+type baselineUser struct {
+	Id                int       `gorm:"primarykey"`
+	Username          string    `gorm:"type:char(96);uniqueIndex"`
+	Nickname          string    `gorm:"type:char(96)"`
+	Password          string    `gorm:"type:char(96)"`
+	AccessToken       string    `gorm:"type:char(96);uniqueIndex"`
+	IsAdmin           bool      `gorm:"default:false"`
+	OrgId             int       `gorm:"index;not null;default:1"`
+	Timezone          string    `gorm:"type:varchar(64)"`
+	TotpSecret        string    `gorm:"type:varchar(64)"`
+	TotpEnabled       bool      `gorm:"default:false"`
+	TotpCounter       int64     `gorm:"default:0"`
+	TotpRecoveryCodes string    `gorm:"type:json"`
+	OidcSubject       string    `gorm:"type:varchar(512);index"`
+	CreatedTime       time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineJob struct {
+	Id               int    `gorm:"primaryKey"`
+	DeviceId         int    `gorm:"index"`
+	Uuid             string `gorm:"unique"`
+	OrgId            int    `gorm:"index;not null;default:1"`
+	Kind             string `gorm:"default:0"`
+	CameraId         int    `gorm:"NOT NULL"`
+	Status           int    `gorm:"default:0"`
+	Enabled          bool   `gorm:"default:true"`
+	Paused           bool
+	Priority         int
+	MaxFps           float64   `gorm:"default:0"`
+	CreateTime       time.Time `gorm:"datetime;autoCreateTime"`
+	UpdateTime       time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+	Detect           string    `gorm:"type:json"`
+	VideoSegment     string    `gorm:"type:json"`
+	Plugin           string    `gorm:"type:json"`
+	Hooks            string    `gorm:"type:json"`
+	TalkDown         string    `gorm:"type:json"`
+	WorkflowId       int       `gorm:"default:0"`
+	RestartCount     int       `gorm:"default:0"`
+	LastError        string    `gorm:"type:varchar(1024)"`
+	HealthReason     string    `gorm:"type:varchar(255)"`
+	FrameRate        float64   `gorm:"default:0"`
+	FrameRateLimit   float64   `gorm:"default:0"`
+	DeviceGroupId    int       `gorm:"index;default:0"`
+	Schedule         string    `gorm:"type:json"`
+	ResultFilter     string    `gorm:"type:json"`
+	SeverityRules    string    `gorm:"type:json"`
+	AlertDedupWindow int       `gorm:"default:0"`
+}
+
+func (baselineJob) TableName() string { return "jobs" }
+
+type baselineDevice struct {
+	Id              int          `gorm:"primaryKey"`
+	Uuid            string       `gorm:"type:char(96);unique"`
+	OrgId           int          `gorm:"index;not null;default:1"`
+	Name            string       `gorm:"type:char(96)"`
+	Token           string       `gorm:"type:char(96);unique"`
+	RegisterTime    sql.NullTime `gorm:"datetime;autoCreateTime"`
+	LastPingTime    sql.NullTime `gorm:"datetime;autoCreateTime"`
+	DiskUsage       string       `gorm:"type:json"`
+	MaxExecutors    int
+	Inventory       string `gorm:"type:json"`
+	SigningKey      string `gorm:"type:char(64)"`
+	NextToken       string `gorm:"type:char(96);index"`
+	RotateToken     bool   `gorm:"default:false"`
+	TokenRotateTime sql.NullTime
+}
+
+func (baselineDevice) TableName() string { return "devices" }
+
+type baselineWorkflow struct {
+	Id           int       `gorm:"primaryKey"`
+	Uuid         string    `gorm:"type:char(96);unique"`
+	OrgId        int       `gorm:"index;not null;default:1"`
+	Key          string    `gorm:"type:varchar(255)"`
+	ModelName    string    `gorm:"type:varchar(255)"`
+	Endpoint     string    `gorm:"type:varchar(255)"`
+	Name         string    `gorm:"type:varchar(255)"`
+	Timeout      int       `gorm:"type:integer;default:30"`
+	CreateTime   time.Time `gorm:"type:timestamp;autoCreateTime"`
+	Query        string    `gorm:"type:text"`
+	ResultFilter string    `gorm:"type:json"`
+}
+
+func (baselineWorkflow) TableName() string { return "workflows" }
+
+type baselineMessage struct {
+	Id           int       `gorm:"primaryKey"`
+	JobId        int       `gorm:"type:int;index;index:idx_message_job_time,priority:1"`
+	OrgId        int       `gorm:"index;not null;default:1"`
+	Timestamp    time.Time `gorm:"type:datetime;index;index:idx_message_job_time,priority:2"`
+	ImagePath    string    `gorm:"type:varchar(255)"`
+	DetectBoxes  string    `gorm:"type:json"`
+	VideoPath    string    `gorm:"type:varchar(255)"`
+	CreateTime   time.Time `gorm:"type:datetime;autoCreateTime"`
+	WorkflowResp string    `gorm:"type:json"`
+	Alerted      bool      `gorm:"type:bool;default:false"`
+	Metadata     string    `gorm:"type:json"`
+	Sensors      string    `gorm:"type:json"`
+	FrameWidth   int       `gorm:"type:int;default:0"`
+	FrameHeight  int       `gorm:"type:int;default:0"`
+	AnswerText   string    `gorm:"type:text"`
+	Evidence     string    `gorm:"type:json"`
+}
+
+func (baselineMessage) TableName() string { return "messages" }
+
+type baselineAccessToken struct {
+	Id           int       `gorm:"primaryKey"`
+	AccessToken  string    `gorm:"type:char(96);unique"`
+	OrgId        int       `gorm:"index;not null;default:1"`
+	CreateTime   time.Time `gorm:"datetime;autoCreateTime"`
+	ExpireTime   time.Time `gorm:"datetime;autoCreateTime"`
+	DeviceUuid   string    `gorm:"type:char(96);index"`
+	MaxBinds     int       `gorm:"not null;default:1"`
+	BindCount    int       `gorm:"not null;default:0"`
+	LastBindTime sql.NullTime
+}
+
+func (baselineAccessToken) TableName() string { return "access_tokens" }
+
+type baselineConversation struct {
+	Id         int       `gorm:"primaryKey"`
+	Uuid       string    `gorm:"unique"`
+	Title      string    `gorm:"default:''"`
+	CreateTime time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+func (baselineConversation) TableName() string { return "conversations" }
+
+type baselineChatMessage struct {
+	Id             int       `gorm:"primaryKey"`
+	ConversationId int       `gorm:"index"`
+	Query          string    `gorm:"default:''"`
+	Answer         string    `gorm:"type:longtext"`
+	AgentThoughts  string    `gorm:"type:json"`
+	CreateTime     time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+func (baselineChatMessage) TableName() string { return "chat_messages" }
+
+type baselineAlertMessage struct {
+	Id            int             `gorm:"primaryKey"`
+	MessageId     int             `gorm:"type:int;index"`
+	Severity      string          `gorm:"type:char(16);default:medium"`
+	AckTime       time.Time       `gorm:"type:datetime;index"`
+	AckUserId     int             `gorm:"type:int;default:0"`
+	Status        string          `gorm:"type:char(16);default:open;index"`
+	AssigneeId    int             `gorm:"type:int;default:0;index"`
+	ResolveTime   time.Time       `gorm:"type:datetime"`
+	ResolveUserId int             `gorm:"type:int;default:0"`
+	CreateTime    time.Time       `gorm:"type:datetime;autoCreateTime;index"`
+	DedupKey      string          `gorm:"type:varchar(255);index:idx_alert_dedup"`
+	Occurrences   int             `gorm:"type:int;default:1"`
+	LastOccurTime time.Time       `gorm:"type:datetime;index:idx_alert_dedup"`
+	LastMessageId int             `gorm:"type:int;default:0"`
+	Message       baselineMessage `gorm:"foreignKey:MessageId;references:Id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (baselineAlertMessage) TableName() string { return "alert_messages" }
+
+type baselineCamera struct {
+	Id           int       `gorm:"primaryKey"`
+	Uuid         string    `gorm:"type:char(96);unique"`
+	OrgId        int       `gorm:"index;not null;default:1"`
+	Name         string    `gorm:"type:char(96)"`
+	Protocol     string    `gorm:"type:char(96)"`
+	Ip           string    `gorm:"type:char(96)"`
+	Port         int       `gorm:"type:int"`
+	Path         string    `gorm:"type:char(96)"`
+	Username     string    `gorm:"type:char(96)"`
+	Password     string    `gorm:"type:char(96)"`
+	CreateTime   time.Time `gorm:"datetime;autoCreateTime"`
+	UpdateTime   time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+	BindDeviceId int       `gorm:"type:int"`
+	PreviewAudio bool
+	OnvifPort    int    `gorm:"type:int"`
+	Tags         string `gorm:"type:json"`
+	Calibration  string `gorm:"type:json"`
+}
+
+func (baselineCamera) TableName() string { return "cameras" }
+
+type baselinePushSubscription struct {
+	Id         int       `gorm:"primaryKey"`
+	UserId     int       `gorm:"type:int;index"`
+	Endpoint   string    `gorm:"type:varchar(512);uniqueIndex"`
+	P256dh     string    `gorm:"type:varchar(255)"`
+	Auth       string    `gorm:"type:varchar(255)"`
+	UserAgent  string    `gorm:"type:varchar(255)"`
+	CreateTime time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+func (baselinePushSubscription) TableName() string { return "push_subscriptions" }
+
+type baselineNotificationPreference struct {
+	Id         int       `gorm:"primaryKey"`
+	UserId     int       `gorm:"type:int;uniqueIndex"`
+	Enabled    bool      `gorm:"type:bool"`
+	JobIds     string    `gorm:"type:json"`
+	CameraIds  string    `gorm:"type:json"`
+	UpdateTime time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+func (baselineNotificationPreference) TableName() string { return "notification_preferences" }
+
+type baselineDeviceGroup struct {
+	Id            int       `gorm:"primaryKey"`
+	Name          string    `gorm:"type:char(96);unique"`
+	OrgId         int       `gorm:"index;not null;default:1"`
+	Description   string    `gorm:"type:varchar(255)"`
+	CreateTime    time.Time `gorm:"datetime;autoCreateTime"`
+	UpdateTime    time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+	Channel       string    `gorm:"type:char(16);default:stable"`
+	UpgradeWindow string    `gorm:"type:json"`
+}
+
+func (baselineDeviceGroup) TableName() string { return "device_groups" }
+
+type baselineDeviceGroupMember struct {
+	Id       int `gorm:"primaryKey"`
+	GroupId  int `gorm:"uniqueIndex:idx_group_device"`
+	DeviceId int `gorm:"uniqueIndex:idx_group_device;index"`
+}
+
+func (baselineDeviceGroupMember) TableName() string { return "device_group_members" }
+
+type baselineJobDeviceStatus struct {
+	Id             int `gorm:"primaryKey"`
+	JobId          int `gorm:"uniqueIndex:idx_job_device"`
+	DeviceId       int `gorm:"uniqueIndex:idx_job_device"`
+	Status         int `gorm:"default:0"`
+	RestartCount   int
+	LastError      string `gorm:"type:varchar(1024)"`
+	HealthReason   string `gorm:"type:varchar(255)"`
+	FrameRate      float64
+	FrameRateLimit float64
+	UpdateTime     time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+func (baselineJobDeviceStatus) TableName() string { return "job_device_statuses" }
+
+type baselineRelease struct {
+	Id         int       `gorm:"primaryKey"`
+	Version    string    `gorm:"type:char(64);unique"`
+	Channel    string    `gorm:"type:char(16);index"`
+	Url        string    `gorm:"type:varchar(1024)"`
+	Sha256     string    `gorm:"type:char(64)"`
+	Notes      string    `gorm:"type:varchar(1024)"`
+	CreateTime time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+func (baselineRelease) TableName() string { return "releases" }
+
+type baselineRollout struct {
+	Id               int    `gorm:"primaryKey"`
+	GroupId          int    `gorm:"index"`
+	ReleaseId        int    `gorm:"index"`
+	Status           string `gorm:"type:char(16);index"`
+	FailureThreshold float64
+	HaltReason       string    `gorm:"type:varchar(255)"`
+	CreateTime       time.Time `gorm:"datetime;autoCreateTime"`
+	UpdateTime       time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+func (baselineRollout) TableName() string { return "rollouts" }
+
+type baselineDeviceUpgrade struct {
+	Id         int       `gorm:"primaryKey"`
+	RolloutId  int       `gorm:"uniqueIndex:idx_rollout_device"`
+	DeviceId   int       `gorm:"uniqueIndex:idx_rollout_device"`
+	Status     string    `gorm:"type:char(16)"`
+	Error      string    `gorm:"type:varchar(1024)"`
+	UpdateTime time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+func (baselineDeviceUpgrade) TableName() string { return "device_upgrades" }
+
+type baselineHealthEvent struct {
+	Id          int    `gorm:"primaryKey"`
+	Kind        string `gorm:"type:char(32);index"`
+	JobId       int    `gorm:"index"`
+	DeviceId    int    `gorm:"index"`
+	Summary     string `gorm:"type:varchar(255)"`
+	Observed    float64
+	Expected    float64
+	Score       float64
+	StartTime   time.Time `gorm:"datetime"`
+	EndTime     time.Time `gorm:"datetime"`
+	Resolved    bool      `gorm:"index"`
+	ResolveTime time.Time
+	CreateTime  time.Time `gorm:"datetime;autoCreateTime"`
+	UpdateTime  time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+func (baselineHealthEvent) TableName() string { return "health_events" }
+
+type baselineVolumeBaseline struct {
+	Id          int `gorm:"primaryKey"`
+	JobId       int `gorm:"uniqueIndex:idx_job_hour"`
+	HourOfWeek  int `gorm:"uniqueIndex:idx_job_hour"`
+	Messages    float64
+	MessagesVar float64
+	Alerts      float64
+	AlertsVar   float64
+	Samples     int
+	LastHour    time.Time `gorm:"datetime"`
+}
+
+func (baselineVolumeBaseline) TableName() string { return "volume_baselines" }
+
+type baselineJobStatusHistory struct {
+	Id         int    `gorm:"primaryKey"`
+	JobId      int    `gorm:"index"`
+	Source     string `gorm:"type:char(16)"`
+	Status     string `gorm:"type:char(32)"`
+	PrevStatus string `gorm:"type:char(32)"`
+	DeviceId   int
+	Username   string    `gorm:"type:varchar(96)"`
+	Reason     string    `gorm:"type:varchar(1024)"`
+	CreateTime time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+func (baselineJobStatusHistory) TableName() string { return "job_status_histories" }
+
+type baselineNotificationChannel struct {
+	Id               int    `gorm:"primaryKey"`
+	Name             string `gorm:"type:char(96);unique"`
+	Kind             string `gorm:"type:char(16);default:webhook"`
+	Enabled          bool   `gorm:"type:bool"`
+	Url              string `gorm:"type:varchar(1024)"`
+	Headers          string `gorm:"type:json"`
+	Secret           string `gorm:"type:varchar(255)"`
+	Recipients       string `gorm:"type:json"`
+	JobRecipients    string `gorm:"type:json"`
+	Template         string `gorm:"type:text"`
+	MaxRetries       int
+	JobIds           string `gorm:"type:json"`
+	CameraIds        string `gorm:"type:json"`
+	MinSeverity      string `gorm:"type:char(16)"`
+	EscalationOnly   bool   `gorm:"type:bool"`
+	LastError        string `gorm:"type:varchar(1024)"`
+	LastDeliveryTime time.Time
+	CreateTime       time.Time `gorm:"datetime;autoCreateTime"`
+	UpdateTime       time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+func (baselineNotificationChannel) TableName() string { return "notification_channels" }
+
+type baselineEscalationPolicy struct {
+	Id          int       `gorm:"primaryKey"`
+	Name        string    `gorm:"type:char(96);unique"`
+	Enabled     bool      `gorm:"type:bool"`
+	MinSeverity string    `gorm:"type:char(16)"`
+	JobIds      string    `gorm:"type:json"`
+	CameraIds   string    `gorm:"type:json"`
+	Steps       string    `gorm:"type:json"`
+	CreateTime  time.Time `gorm:"datetime;autoCreateTime"`
+	UpdateTime  time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+func (baselineEscalationPolicy) TableName() string { return "escalation_policies" }
+
+type baselineAlertActivity struct {
+	Id         int       `gorm:"primaryKey"`
+	AlertId    int       `gorm:"type:int;index"`
+	Action     string    `gorm:"type:char(16)"`
+	UserId     int       `gorm:"type:int"`
+	Username   string    `gorm:"type:char(96)"`
+	AssigneeId int       `gorm:"type:int;default:0"`
+	Content    string    `gorm:"type:text"`
+	CreateTime time.Time `gorm:"type:datetime;autoCreateTime"`
+}
+
+func (baselineAlertActivity) TableName() string { return "alert_activities" }
+
+type baselineApiUsage struct {
+	Id           int       `gorm:"primaryKey"`
+	Hour         time.Time `gorm:"type:datetime;uniqueIndex:idx_api_usage,priority:1"`
+	ConsumerKind string    `gorm:"type:char(16);uniqueIndex:idx_api_usage,priority:2"`
+	Consumer     string    `gorm:"type:varchar(128);uniqueIndex:idx_api_usage,priority:3"`
+	Method       string    `gorm:"type:char(8);uniqueIndex:idx_api_usage,priority:4"`
+	Endpoint     string    `gorm:"type:varchar(255);uniqueIndex:idx_api_usage,priority:5"`
+	Requests     int64
+	ClientErrors int64
+	ServerErrors int64
+	TotalLatency int64
+	MaxLatency   int64
+}
+
+func (baselineApiUsage) TableName() string { return "api_usages" }
+
+type baselineApiToken struct {
+	Id           int    `gorm:"primarykey"`
+	UserId       int    `gorm:"index;not null"`
+	Name         string `gorm:"type:varchar(64);not null"`
+	TokenHash    string `gorm:"type:char(64);uniqueIndex;not null"`
+	Hint         string `gorm:"type:varchar(16)"`
+	Scopes       string `gorm:"type:json"`
+	ExpireTime   time.Time
+	LastUsedTime time.Time
+	LastUsedIp   string    `gorm:"type:varchar(64)"`
+	CreatedTime  time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+func (baselineApiToken) TableName() string { return "api_tokens" }
+
+type baselineRefreshToken struct {
+	Id                int    `gorm:"primarykey"`
+	UserId            int    `gorm:"index;not null"`
+	SessionId         string `gorm:"type:char(32);index;not null"`
+	TokenHash         string `gorm:"type:char(64);uniqueIndex;not null"`
+	ExpireTime        time.Time
+	SessionExpireTime time.Time
+	UsedTime          time.Time
+	RevokedTime       time.Time
+	LoginTime         time.Time
+	UserAgent         string    `gorm:"type:varchar(255)"`
+	Ip                string    `gorm:"type:varchar(64)"`
+	CreatedTime       time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+func (baselineRefreshToken) TableName() string { return "refresh_tokens" }
+
+type baselineDeviceSigningKey struct {
+	Id          int       `gorm:"primaryKey"`
+	DeviceUuid  string    `gorm:"type:char(96);index"`
+	Fingerprint string    `gorm:"type:char(64);uniqueIndex"`
+	PublicKey   []byte    `gorm:"type:varbinary(64)"`
+	CreateTime  time.Time `gorm:"type:datetime;autoCreateTime"`
+}
+
+func (baselineDeviceSigningKey) TableName() string { return "device_signing_keys" }
+
+type baselineMessageFeedback struct {
+	Id              int       `gorm:"primaryKey"`
+	MessageId       int       `gorm:"type:int;uniqueIndex"`
+	JobId           int       `gorm:"type:int;index:idx_feedback_job_time,priority:1"`
+	MessageTime     time.Time `gorm:"type:datetime;index:idx_feedback_job_time,priority:2"`
+	Verdict         string    `gorm:"type:char(16)"`
+	CorrectedLabels string    `gorm:"type:json"`
+	Comment         string    `gorm:"type:text"`
+	UserId          int       `gorm:"type:int"`
+	Username        string    `gorm:"type:char(96)"`
+	CreateTime      time.Time `gorm:"type:datetime"`
+	UpdateTime      time.Time `gorm:"type:datetime"`
+}
+
+func (baselineMessageFeedback) TableName() string { return "message_feedbacks" }
+
+type baselineReportSchedule struct {
+	Id            int       `gorm:"primaryKey"`
+	Name          string    `gorm:"type:char(96);unique"`
+	Enabled       bool      `gorm:"type:bool"`
+	Site          string    `gorm:"type:varchar(64)"`
+	Period        string    `gorm:"type:char(16);default:daily"`
+	Format        string    `gorm:"type:char(16);default:html"`
+	Hour          int       `gorm:"type:int"`
+	Weekday       int       `gorm:"type:int"`
+	Timezone      string    `gorm:"type:varchar(64)"`
+	ChannelIds    string    `gorm:"type:json"`
+	LastPeriodEnd time.Time `gorm:"type:datetime"`
+	LastError     string    `gorm:"type:varchar(1024)"`
+	LastRunTime   time.Time `gorm:"type:datetime"`
+	CreateTime    time.Time `gorm:"datetime;autoCreateTime"`
+	UpdateTime    time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+func (baselineReportSchedule) TableName() string { return "report_schedules" }
+
+type baselineReport struct {
+	Id          int       `gorm:"primaryKey"`
+	ScheduleId  int       `gorm:"type:int;index"`
+	Site        string    `gorm:"type:varchar(64)"`
+	Period      string    `gorm:"type:char(16)"`
+	Format      string    `gorm:"type:char(16)"`
+	PeriodStart time.Time `gorm:"type:datetime"`
+	PeriodEnd   time.Time `gorm:"type:datetime"`
+	Path        string    `gorm:"type:varchar(255)"`
+	Summary     string    `gorm:"type:json"`
+	CreateTime  time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+func (baselineReport) TableName() string { return "reports" }
+
+// baselineTables are the tables of the baseline migration, in the order
+// they are created.
+func baselineTables() []any {
+	return []any{
+		&baselineOrganization{},
+		&baselineUser{},
+		&baselineJob{},
+		&baselineDevice{},
+		&baselineWorkflow{},
+		&baselineMessage{},
+		&baselineAccessToken{},
+		&baselineConversation{},
+		&baselineChatMessage{},
+		&baselineAlertMessage{},
+		&baselineCamera{},
+		&baselinePushSubscription{},
+		&baselineNotificationPreference{},
+		&baselineDeviceGroup{},
+		&baselineDeviceGroupMember{},
+		&baselineJobDeviceStatus{},
+		&baselineRelease{},
+		&baselineRollout{},
+		&baselineDeviceUpgrade{},
+		&baselineHealthEvent{},
+		&baselineVolumeBaseline{},
+		&baselineJobStatusHistory{},
+		&baselineNotificationChannel{},
+		&baselineEscalationPolicy{},
+		&baselineAlertActivity{},
+		&baselineApiUsage{},
+		&baselineApiToken{},
+		&baselineRefreshToken{},
+		&baselineDeviceSigningKey{},
+		&baselineMessageFeedback{},
+		&baselineReportSchedule{},
+		&baselineReport{},
+	}
+}
+
+// createBaselineTables creates the baseline tables, and adds their missing
+// columns and indexes to the databases created by AutoMigrate.
+func createBaselineTables(tx *gorm.DB) error {
+	for _, table := range baselineTables() {
+		if err := tx.AutoMigrate(table); err != nil {
+			return err
+		}
+	}
+	// the organization the rows created before the organizations belong to
+	org := baselineOrganization{Id: DefaultOrgId, Name: "default"}
+	return tx.Where(baselineOrganization{Id: DefaultOrgId}).FirstOrCreate(&org).Error
+}
+
+// dropBaselineTables drops the baseline tables and all their rows.
+func dropBaselineTables(tx *gorm.DB) error {
+	tables := baselineTables()
+	for i := len(tables) - 1; i >= 0; i-- {
+		if err := tx.Migrator().DropTable(tables[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
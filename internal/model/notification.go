@@ -0,0 +1,166 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PushSubscription struct {
+	Id         int       `gorm:"primaryKey"`
+	UserId     int       `gorm:"type:int;index"`
+	Endpoint   string    `gorm:"type:varchar(512);uniqueIndex"`
+	P256dh     string    `gorm:"type:varchar(255)"`
+	Auth       string    `gorm:"type:varchar(255)"`
+	UserAgent  string    `gorm:"type:varchar(255)"`
+	CreateTime time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+// IntSlice is a custom type for handling []int serialization
+type IntSlice []int
+
+// Value implements driver.Valuer interface for JSON serialization
+func (s IntSlice) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (s *IntSlice) Scan(value any) error {
+	if value == nil {
+		*s = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, s)
+}
+
+func (s IntSlice) Contains(v int) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+type NotificationPreference struct {
+	Id        int      `gorm:"primaryKey"`
+	UserId    int      `gorm:"type:int;uniqueIndex"`
+	Enabled   bool     `gorm:"type:bool"`
+	JobIds    IntSlice `gorm:"type:json"`
+	CameraIds IntSlice `gorm:"type:json"`
+	// MinSeverity is the lowest severity of the alerts pushed, empty pushes
+	// every alert
+	MinSeverity AlertSeverity `gorm:"type:char(16)"`
+	UpdateTime  time.Time     `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+// Accept reports whether an alert of the given job, camera and severity
+// should be pushed to the owner of the preference. Empty filters accept
+// everything.
+func (p *NotificationPreference) Accept(jobId, cameraId int, severity AlertSeverity) bool {
+	if !p.Enabled || !severity.AtLeast(p.MinSeverity) {
+		return false
+	}
+	if len(p.JobIds) > 0 && !p.JobIds.Contains(jobId) {
+		return false
+	}
+	if len(p.CameraIds) > 0 && !p.CameraIds.Contains(cameraId) {
+		return false
+	}
+	return true
+}
+
+func DefaultNotificationPreference(userId int) *NotificationPreference {
+	return &NotificationPreference{
+		UserId:  userId,
+		Enabled: true,
+	}
+}
+
+// SavePushSubscription creates the subscription or rebinds an existing
+// endpoint to the given user with the latest keys.
+func SavePushSubscription(sub *PushSubscription) error {
+	var old PushSubscription
+	err := DB.Where("endpoint = ?", sub.Endpoint).First(&old).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return DB.Create(sub).Error
+		}
+		return err
+	}
+	sub.Id = old.Id
+	sub.CreateTime = old.CreateTime
+	return DB.Save(sub).Error
+}
+
+func DeletePushSubscription(userId int, endpoint string) error {
+	return DB.Where("user_id = ? AND endpoint = ?", userId, endpoint).Delete(&PushSubscription{}).Error
+}
+
+func DeletePushSubscriptionById(id int) error {
+	return DB.Delete(&PushSubscription{}, id).Error
+}
+
+// ListPushSubscriptions returns the subscriptions of the users of the
+// organization.
+func ListPushSubscriptions(orgId int) ([]*PushSubscription, error) {
+	var subs []*PushSubscription
+	err := DB.Model(&PushSubscription{}).
+		Select("push_subscriptions.*").
+		Joins("JOIN users ON users.id = push_subscriptions.user_id").
+		Scopes(InOrgOf("users", orgId)).
+		Find(&subs).Error
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func GetNotificationPreference(userId int) (*NotificationPreference, error) {
+	var pref NotificationPreference
+	if err := DB.Where("user_id = ?", userId).First(&pref).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return DefaultNotificationPreference(userId), nil
+		}
+		return nil, err
+	}
+	return &pref, nil
+}
+
+func SaveNotificationPreference(pref *NotificationPreference) error {
+	var old NotificationPreference
+	err := DB.Where("user_id = ?", pref.UserId).First(&old).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return DB.Create(pref).Error
+		}
+		return err
+	}
+	pref.Id = old.Id
+	return DB.Save(pref).Error
+}
+
+// ListNotificationPreferencesByUserIds returns the preferences saved by the
+// users, the others having the default one.
+func ListNotificationPreferencesByUserIds(userIds []int) ([]*NotificationPreference, error) {
+	var prefs []*NotificationPreference
+	if len(userIds) == 0 {
+		return prefs, nil
+	}
+	if err := DB.Model(&NotificationPreference{}).Where("user_id IN ?", userIds).Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
@@ -0,0 +1,239 @@
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type NotificationChannelKind string
+
+const (
+	// NotificationChannelWebhook posts the alerts to an HTTP endpoint
+	NotificationChannelWebhook NotificationChannelKind = "webhook"
+	// NotificationChannelEmail mails the alerts through the SMTP server
+	NotificationChannelEmail NotificationChannelKind = "email"
+	// NotificationChannelDingTalk, NotificationChannelWeCom and
+	// NotificationChannelSlack post the alerts to the robot webhook of a chat
+	// group
+	NotificationChannelDingTalk NotificationChannelKind = "dingtalk"
+	NotificationChannelWeCom    NotificationChannelKind = "wecom"
+	NotificationChannelSlack    NotificationChannelKind = "slack"
+)
+
+// StringMap is a custom type for handling map[string]string serialization
+type StringMap map[string]string
+
+// Value implements driver.Valuer interface for JSON serialization
+func (m StringMap) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(m)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (m *StringMap) Scan(value any) error {
+	if value == nil {
+		*m = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, m)
+}
+
+// StringSlice is a custom type for handling []string serialization
+type StringSlice []string
+
+// Value implements driver.Valuer interface for JSON serialization
+func (s StringSlice) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (s *StringSlice) Scan(value any) error {
+	if value == nil {
+		*s = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, s)
+}
+
+// JobRecipients maps a job id to the email addresses receiving its alerts.
+type JobRecipients map[int][]string
+
+// Value implements driver.Valuer interface for JSON serialization
+func (r JobRecipients) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return json.Marshal(r)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (r *JobRecipients) Scan(value any) error {
+	if value == nil {
+		*r = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, r)
+}
+
+// NotificationChannel delivers the alerts to an external system such as a
+// security console or a ticketing system.
+type NotificationChannel struct {
+	Id      int                     `gorm:"primaryKey"`
+	Name    string                  `gorm:"type:char(96);unique"`
+	Kind    NotificationChannelKind `gorm:"type:char(16);default:webhook"`
+	Enabled bool                    `gorm:"type:bool"`
+	Url     string                  `gorm:"type:varchar(1024)"`
+	Headers StringMap               `gorm:"type:json"`
+	// Secret signs the messages of DingTalk robots with the "加签" setting
+	Secret string `gorm:"type:varchar(255)"`
+	// Recipients receive every alert of an email channel, JobRecipients
+	// receive the alerts of their job in addition
+	Recipients    StringSlice   `gorm:"type:json"`
+	JobRecipients JobRecipients `gorm:"type:json"`
+	// Template is a text/template rendering the body from the alert event,
+	// the event is sent as JSON by webhooks and a summary by the others if
+	// empty. It renders the message text of the chat tools.
+	Template string `gorm:"type:text"`
+	// MaxRetries is the number of retries after a failed delivery
+	MaxRetries int
+	JobIds     IntSlice `gorm:"type:json"`
+	CameraIds  IntSlice `gorm:"type:json"`
+	// MinSeverity is the lowest severity of the alerts delivered, empty
+	// delivers every alert
+	MinSeverity AlertSeverity `gorm:"type:char(16)"`
+	// EscalationOnly channels only receive the alerts the escalation
+	// policies send them
+	EscalationOnly bool `gorm:"type:bool"`
+	// LastError is the error of the last delivery, empty if it succeeded
+	LastError        string `gorm:"type:varchar(1024)"`
+	LastDeliveryTime *time.Time
+	CreateTime       time.Time `gorm:"datetime;autoCreateTime"`
+	UpdateTime       time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+// Accept reports whether an alert of the given job, camera and severity
+// should be delivered through the channel. Empty filters accept everything.
+func (ch *NotificationChannel) Accept(jobId, cameraId int, severity AlertSeverity) bool {
+	if !ch.Enabled || ch.EscalationOnly || !severity.AtLeast(ch.MinSeverity) {
+		return false
+	}
+	if len(ch.JobIds) > 0 && !ch.JobIds.Contains(jobId) {
+		return false
+	}
+	if len(ch.CameraIds) > 0 && !ch.CameraIds.Contains(cameraId) {
+		return false
+	}
+	return true
+}
+
+// EmailRecipients returns the addresses to mail an alert of the job to.
+func (ch *NotificationChannel) EmailRecipients(jobId int) []string {
+	seen := make(map[string]bool)
+	var to []string
+	for _, addrs := range [][]string{ch.Recipients, ch.JobRecipients[jobId]} {
+		for _, addr := range addrs {
+			if !seen[addr] {
+				seen[addr] = true
+				to = append(to, addr)
+			}
+		}
+	}
+	return to
+}
+
+func CreateNotificationChannel(ch *NotificationChannel) error {
+	return DB.Create(ch).Error
+}
+
+func UpdateNotificationChannel(ch *NotificationChannel) error {
+	return DB.Save(ch).Error
+}
+
+func DeleteNotificationChannel(id int) error {
+	return DB.Delete(&NotificationChannel{}, id).Error
+}
+
+func GetNotificationChannelById(id int) (*NotificationChannel, error) {
+	var ch NotificationChannel
+	err := DB.First(&ch, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &ch, err
+}
+
+func ListNotificationChannels() ([]NotificationChannel, error) {
+	var chs []NotificationChannel
+	if err := DB.Model(&NotificationChannel{}).Order("id").Find(&chs).Error; err != nil {
+		return nil, err
+	}
+	return chs, nil
+}
+
+func ListEnabledNotificationChannels() ([]NotificationChannel, error) {
+	var chs []NotificationChannel
+	if err := DB.Where("enabled = ?", true).Order("id").Find(&chs).Error; err != nil {
+		return nil, err
+	}
+	return chs, nil
+}
+
+// SetNotificationChannelDelivery records the result of the last delivery
+// without touching the update time of the channel.
+func SetNotificationChannelDelivery(id int, t time.Time, deliveryErr error) error {
+	lastError := ""
+	if deliveryErr != nil {
+		lastError = deliveryErr.Error()
+		if len(lastError) > 1024 {
+			lastError = lastError[:1024]
+		}
+	}
+	return DB.Model(&NotificationChannel{}).Where("id = ?", id).UpdateColumns(map[string]any{
+		"last_error":         lastError,
+		"last_delivery_time": t,
+	}).Error
+}
+
+const notificationDeliveryKeyTemplate = "notification:%d:%d"
+
+// notificationDeliveryExpire outlives the retries of a delivery and the
+// longest escalation delay, so that an escalation does not notify a channel
+// the alert was delivered to again.
+const notificationDeliveryExpire = 25 * time.Hour
+
+// ClaimNotificationDelivery reports whether this server should deliver the
+// alert through the channel, every server sees every alert but only the
+// first one claiming it delivers it.
+func ClaimNotificationDelivery(ctx context.Context, channelId, alertId int) (bool, error) {
+	key := fmt.Sprintf(notificationDeliveryKeyTemplate, channelId, alertId)
+	return Redis.SetNX(ctx, key, 1, notificationDeliveryExpire).Result()
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const oidcStateKeyTemplate = "oidc-state:%s"
+
+// OidcState is what the login remembers of an OIDC authorization request
+// until the provider redirects back to the callback.
+type OidcState struct {
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+	// Redirect is the path of the dashboard to go back to after the login
+	Redirect string `json:"redirect"`
+}
+
+func SaveOidcState(ctx context.Context, state string, s *OidcState, ttl time.Duration) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return Redis.Set(ctx, fmt.Sprintf(oidcStateKeyTemplate, state), data, ttl).Err()
+}
+
+// TakeOidcState returns the state and deletes it so that it cannot be used
+// twice, nil if it does not exist or expired.
+func TakeOidcState(ctx context.Context, state string) (*OidcState, error) {
+	data, err := Redis.GetDel(ctx, fmt.Sprintf(oidcStateKeyTemplate, state)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var s OidcState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
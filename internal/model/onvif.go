@@ -0,0 +1,104 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OnvifDiscoverTask asks a device to scan its network for ONVIF cameras.
+type OnvifDiscoverTask struct {
+	TaskUuid   string    `json:"taskUuid"`
+	Subnet     string    `json:"subnet,omitempty"`
+	Username   string    `json:"username,omitempty"`
+	Password   string    `json:"password,omitempty"`
+	Timeout    int       `json:"timeout"` // seconds
+	ExpireTime time.Time `json:"expireTime,omitempty"`
+}
+
+type OnvifCamera struct {
+	XAddr        string `json:"xaddr"`
+	Ip           string `json:"ip"`
+	OnvifPort    int    `json:"onvifPort"`
+	Name         string `json:"name,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Model        string `json:"model,omitempty"`
+	StreamUri    string `json:"streamUri,omitempty"`
+	Ptz          bool   `json:"ptz"`
+	Error        string `json:"error,omitempty"`
+}
+
+type OnvifDiscoverResult struct {
+	TaskUuid   string        `json:"taskUuid"`
+	Cameras    []OnvifCamera `json:"cameras,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	CreateTime time.Time     `json:"createTime"`
+}
+
+const (
+	onvifDiscoverTaskKeyTemplate   = "onvif-discover:%s:%s"
+	onvifDiscoverResultKeyTemplate = "onvif-discover-result:%s"
+	onvifDiscoverTaskExpire        = time.Minute
+	onvifDiscoverResultExpire      = 5 * time.Minute
+)
+
+func onvifDiscoverTaskKey(deviceUuid, taskUuid string) string {
+	return fmt.Sprintf(onvifDiscoverTaskKeyTemplate, deviceUuid, taskUuid)
+}
+
+func AddOnvifDiscoverTask(ctx context.Context, deviceUuid string, task *OnvifDiscoverTask) error {
+	data, _ := json.Marshal(task)
+	return Redis.Set(ctx, onvifDiscoverTaskKey(deviceUuid, task.TaskUuid), data, onvifDiscoverTaskExpire).Err()
+}
+
+func DeleteOnvifDiscoverTask(ctx context.Context, deviceUuid, taskUuid string) error {
+	return Redis.Del(ctx, onvifDiscoverTaskKey(deviceUuid, taskUuid)).Err()
+}
+
+func GetOnvifDiscoverTasksByDeviceUuid(ctx context.Context, deviceUuid string) ([]*OnvifDiscoverTask, error) {
+	keys, err := Redis.Keys(ctx, fmt.Sprintf(onvifDiscoverTaskKeyTemplate, deviceUuid, "*")).Result()
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*OnvifDiscoverTask
+	for _, key := range keys {
+		var data []byte
+		if err := Redis.Get(ctx, key).Scan(&data); err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return nil, err
+		}
+		var task OnvifDiscoverTask
+		if err := json.Unmarshal(data, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, &task)
+	}
+	return tasks, nil
+}
+
+func SetOnvifDiscoverResult(ctx context.Context, result *OnvifDiscoverResult) error {
+	data, _ := json.Marshal(result)
+	return Redis.Set(ctx, fmt.Sprintf(onvifDiscoverResultKeyTemplate, result.TaskUuid), data, onvifDiscoverResultExpire).Err()
+}
+
+func GetOnvifDiscoverResult(ctx context.Context, taskUuid string) (*OnvifDiscoverResult, error) {
+	var data []byte
+	if err := Redis.Get(ctx, fmt.Sprintf(onvifDiscoverResultKeyTemplate, taskUuid)).Scan(&data); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result OnvifDiscoverResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
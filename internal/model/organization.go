@@ -0,0 +1,94 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultOrgId is the organization of the rows created before the
+	// organizations, and of every row on the single tenant servers
+	DefaultOrgId = 1
+	// AllOrgs disables the scoping of the queries, for the single tenant
+	// servers and the background tasks
+	AllOrgs = 0
+	// NoOrg scopes the queries of the anonymous requests of the multi
+	// tenant servers, it matches no row
+	NoOrg = -1
+)
+
+// Organization is a tenant of the server, its users only see its devices,
+// cameras, jobs, workflows, device groups and messages.
+type Organization struct {
+	Id          int       `gorm:"primaryKey"`
+	Name        string    `gorm:"type:varchar(96);uniqueIndex"`
+	CreatedTime time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+// InOrg scopes a query on a table having an org_id column to the
+// organization orgId.
+func InOrg(orgId int) func(db *gorm.DB) *gorm.DB {
+	return InOrgOf("", orgId)
+}
+
+// InOrgOf is InOrg for the queries joining several tables having an org_id
+// column, table is the one holding the organization.
+func InOrgOf(table string, orgId int) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if orgId == AllOrgs {
+			return db
+		}
+		column := "org_id"
+		if table != "" {
+			column = table + ".org_id"
+		}
+		return db.Where(column+" = ?", orgId)
+	}
+}
+
+func CreateOrganization(org *Organization) error {
+	return DB.Create(org).Error
+}
+
+func GetOrganizationById(id int) (*Organization, error) {
+	var org Organization
+	err := DB.First(&org, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &org, err
+}
+
+func ListOrganizations() ([]Organization, error) {
+	var orgs []Organization
+	err := DB.Order("id").Find(&orgs).Error
+	return orgs, err
+}
+
+// CountOrganizationUsers returns the number of users of the organization,
+// which cannot be deleted while it has some.
+func CountOrganizationUsers(id int) (int64, error) {
+	var count int64
+	err := DB.Model(&User{}).Where("org_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// DeleteOrganization deletes the organization with its devices, cameras,
+// jobs, workflows and device groups, its messages are left to the
+// retention.
+func DeleteOrganization(id int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		groups := tx.Model(&DeviceGroup{}).Select("id").Where("org_id = ?", id)
+		if err := tx.Where("group_id IN (?)", groups).Delete(&DeviceGroupMember{}).Error; err != nil {
+			return err
+		}
+		for _, table := range []any{&Job{}, &Camera{}, &Device{}, &Workflow{}, &AccessToken{}, &DeviceGroup{}} {
+			if err := tx.Where("org_id = ?", id).Delete(table).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&Organization{}, id).Error
+	})
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const rateLimitKeyTemplate = "rate-limit:%s:%d"
+
+// IncrRateLimit counts a request of key in the current fixed window and
+// returns the requests counted so far and the end of the window. The
+// counters live on redis so that every server instance shares them.
+func IncrRateLimit(ctx context.Context, key string, window time.Duration, now time.Time) (int64, time.Time, error) {
+	start := now.Truncate(window)
+	redisKey := fmt.Sprintf(rateLimitKeyTemplate, key, start.Unix())
+	pipe := Redis.TxPipeline()
+	incr := pipe.Incr(ctx, redisKey)
+	pipe.Expire(ctx, redisKey, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, time.Time{}, err
+	}
+	return incr.Val(), start.Add(window), nil
+}
@@ -0,0 +1,294 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReleaseChannel string
+
+const (
+	ReleaseChannelStable ReleaseChannel = "stable"
+	ReleaseChannelBeta   ReleaseChannel = "beta"
+)
+
+// Channels returns the release channels a device pinned to c accepts, a beta
+// device also takes stable releases.
+func (c ReleaseChannel) Channels() []ReleaseChannel {
+	if c == ReleaseChannelBeta {
+		return []ReleaseChannel{ReleaseChannelStable, ReleaseChannelBeta}
+	}
+	return []ReleaseChannel{ReleaseChannelStable}
+}
+
+// Release is a build of the device binary that devices can upgrade to.
+type Release struct {
+	Id      int            `gorm:"primaryKey"`
+	Version string         `gorm:"type:char(64);unique"`
+	Channel ReleaseChannel `gorm:"type:char(16);index"`
+	// Url is where devices download the binary from
+	Url        string    `gorm:"type:varchar(1024)"`
+	Sha256     string    `gorm:"type:char(64)"`
+	Notes      string    `gorm:"type:varchar(1024)"`
+	CreateTime time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+var ErrReleaseInUse = errors.New("release is used by rollouts")
+
+func CreateRelease(r *Release) error {
+	return DB.Create(r).Error
+}
+
+func GetReleaseById(id int) (*Release, error) {
+	var r Release
+	err := DB.First(&r, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &r, err
+}
+
+// LatestRelease returns the last created release of the channels, nil if
+// there is none.
+func LatestRelease(channels []ReleaseChannel) (*Release, error) {
+	var r Release
+	err := DB.Where("channel IN ?", channels).Order("id DESC").First(&r).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &r, err
+}
+
+func ListReleases(channel ReleaseChannel, start, limit int) ([]Release, int64, error) {
+	var releases []Release
+	var total int64
+	db := DB.Model(&Release{})
+	if channel != "" {
+		db = db.Where("channel = ?", channel)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("id DESC").Offset(start).Limit(limit).Find(&releases).Error; err != nil {
+		return nil, 0, err
+	}
+	return releases, total, nil
+}
+
+// DeleteRelease fails with ErrReleaseInUse while rollouts refer to it.
+func DeleteRelease(id int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Rollout{}).Where("release_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrReleaseInUse
+		}
+		return tx.Delete(&Release{}, id).Error
+	})
+}
+
+type RolloutStatus string
+
+const (
+	RolloutStatusRunning   RolloutStatus = "running"
+	RolloutStatusHalted    RolloutStatus = "halted"
+	RolloutStatusCompleted RolloutStatus = "completed"
+	RolloutStatusCancelled RolloutStatus = "cancelled"
+)
+
+// Rollout upgrades the devices of a group to a release. Devices upgrade one
+// by one as they poll within the upgrade window of the group, the rollout
+// halts when too many of them fail.
+type Rollout struct {
+	Id        int           `gorm:"primaryKey"`
+	GroupId   int           `gorm:"index"`
+	ReleaseId int           `gorm:"index"`
+	Status    RolloutStatus `gorm:"type:char(16);index"`
+	// FailureThreshold is the failure rate among the upgraded devices, in
+	// [0, 1], above which the rollout halts
+	FailureThreshold float64
+	HaltReason       string    `gorm:"type:varchar(255)"`
+	CreateTime       time.Time `gorm:"datetime;autoCreateTime"`
+	UpdateTime       time.Time `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+var ErrRolloutRunning = errors.New("a rollout of the group is already running")
+
+// CreateRollout fails with ErrRolloutRunning while another rollout of the
+// group is running.
+func CreateRollout(r *Rollout) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Rollout{}).
+			Where("group_id = ? AND status = ?", r.GroupId, RolloutStatusRunning).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrRolloutRunning
+		}
+		return tx.Create(r).Error
+	})
+}
+
+func GetRolloutById(id int) (*Rollout, error) {
+	var r Rollout
+	err := DB.First(&r, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &r, err
+}
+
+func ListRollouts(groupId, start, limit int) ([]Rollout, int64, error) {
+	var rollouts []Rollout
+	var total int64
+	db := DB.Model(&Rollout{}).Where("group_id = ?", groupId)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("id DESC").Offset(start).Limit(limit).Find(&rollouts).Error; err != nil {
+		return nil, 0, err
+	}
+	return rollouts, total, nil
+}
+
+func ListRunningRollouts() ([]Rollout, error) {
+	var rollouts []Rollout
+	err := DB.Where("status = ?", RolloutStatusRunning).Find(&rollouts).Error
+	return rollouts, err
+}
+
+func UpdateRolloutStatus(id int, status RolloutStatus, reason string) error {
+	if len(reason) > 255 {
+		reason = reason[:255]
+	}
+	return DB.Model(&Rollout{}).Where("id = ?", id).Updates(map[string]any{
+		"status":      status,
+		"halt_reason": reason,
+	}).Error
+}
+
+// GetRunningRolloutOfDevice returns the running rollout of a group of the
+// device and the group, or nils if there is none.
+func GetRunningRolloutOfDevice(deviceId int) (*Rollout, *DeviceGroup, error) {
+	var r Rollout
+	err := DB.Model(&Rollout{}).
+		Joins("JOIN device_group_members ON device_group_members.group_id = rollouts.group_id").
+		Where("device_group_members.device_id = ? AND rollouts.status = ?", deviceId, RolloutStatusRunning).
+		Order("rollouts.id").
+		First(&r).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	g, err := GetDeviceGroupById(r.GroupId)
+	if err != nil || g == nil {
+		return nil, nil, err
+	}
+	return &r, g, nil
+}
+
+type DeviceUpgradeStatus string
+
+const (
+	DeviceUpgradeStatusUpgrading DeviceUpgradeStatus = "upgrading"
+	DeviceUpgradeStatusSucceeded DeviceUpgradeStatus = "succeeded"
+	DeviceUpgradeStatusFailed    DeviceUpgradeStatus = "failed"
+)
+
+// DeviceUpgrade is the upgrade of a device within a rollout, a device is
+// upgraded at most once per rollout.
+type DeviceUpgrade struct {
+	Id         int                 `gorm:"primaryKey"`
+	RolloutId  int                 `gorm:"uniqueIndex:idx_rollout_device"`
+	DeviceId   int                 `gorm:"uniqueIndex:idx_rollout_device"`
+	Status     DeviceUpgradeStatus `gorm:"type:char(16)"`
+	Error      string              `gorm:"type:varchar(1024)"`
+	UpdateTime time.Time           `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+func GetDeviceUpgrade(rolloutId, deviceId int) (*DeviceUpgrade, error) {
+	var u DeviceUpgrade
+	err := DB.Where("rollout_id = ? AND device_id = ?", rolloutId, deviceId).First(&u).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &u, err
+}
+
+func SetDeviceUpgrade(u *DeviceUpgrade) error {
+	if len(u.Error) > 1024 {
+		u.Error = u.Error[:1024]
+	}
+	u.UpdateTime = time.Now()
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "rollout_id"}, {Name: "device_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "error", "update_time"}),
+	}).Create(u).Error
+}
+
+func ListDeviceUpgrades(rolloutId int) ([]DeviceUpgrade, error) {
+	var ups []DeviceUpgrade
+	err := DB.Where("rollout_id = ?", rolloutId).Order("device_id").Find(&ups).Error
+	return ups, err
+}
+
+// CountDeviceUpgrades counts the upgrades of a rollout by status.
+func CountDeviceUpgrades(rolloutId int) (map[DeviceUpgradeStatus]int64, error) {
+	var rows []struct {
+		Status DeviceUpgradeStatus
+		Count  int64
+	}
+	if err := DB.Model(&DeviceUpgrade{}).
+		Select("status, COUNT(*) AS count").
+		Where("rollout_id = ?", rolloutId).
+		Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	res := make(map[DeviceUpgradeStatus]int64, len(rows))
+	for _, r := range rows {
+		res[r.Status] = r.Count
+	}
+	return res, nil
+}
+
+// FailStaleUpgrades fails the upgrades of running rollouts still upgrading
+// since before, e.g. because the new binary does not start. It returns the
+// ids of the rollouts concerned.
+func FailStaleUpgrades(before time.Time) ([]int, error) {
+	var stale []DeviceUpgrade
+	if err := DB.Where("status = ? AND update_time < ? AND rollout_id IN (?)",
+		DeviceUpgradeStatusUpgrading, before,
+		DB.Model(&Rollout{}).Select("id").Where("status = ?", RolloutStatusRunning),
+	).Find(&stale).Error; err != nil {
+		return nil, err
+	}
+	if len(stale) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int, 0, len(stale))
+	rollouts := make(map[int]struct{})
+	for _, u := range stale {
+		ids = append(ids, u.Id)
+		rollouts[u.RolloutId] = struct{}{}
+	}
+	if err := DB.Model(&DeviceUpgrade{}).Where("id IN ?", ids).Updates(map[string]any{
+		"status": DeviceUpgradeStatusFailed,
+		"error":  "upgrade timed out",
+	}).Error; err != nil {
+		return nil, err
+	}
+	res := make([]int, 0, len(rollouts))
+	for id := range rollouts {
+		res = append(res, id)
+	}
+	return res, nil
+}
@@ -0,0 +1,327 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReportPeriod string
+
+const (
+	ReportPeriodDaily  ReportPeriod = "daily"
+	ReportPeriodWeekly ReportPeriod = "weekly"
+)
+
+type ReportFormat string
+
+const (
+	ReportFormatHTML ReportFormat = "html"
+	// ReportFormatPDF converts the HTML report with the PDF command of the
+	// server
+	ReportFormatPDF ReportFormat = "pdf"
+)
+
+// ReportSchedule generates a summary of the messages, alerts and devices of
+// a site every day or week and delivers it through notification channels.
+// A site is the cameras of a tag.
+type ReportSchedule struct {
+	Id      int    `gorm:"primaryKey"`
+	Name    string `gorm:"type:char(96);unique"`
+	Enabled bool   `gorm:"type:bool"`
+	// Site is the camera tag the report covers, empty covers every camera
+	Site   string       `gorm:"type:varchar(64)"`
+	Period ReportPeriod `gorm:"type:char(16);default:daily"`
+	Format ReportFormat `gorm:"type:char(16);default:html"`
+	// Hour is the local hour the report of the previous day or week is
+	// generated at, Weekday the day weekly reports are, 0 being Sunday
+	Hour    int `gorm:"type:int"`
+	Weekday int `gorm:"type:int"`
+	// Timezone is an IANA name, the local time of the server if empty
+	Timezone   string   `gorm:"type:varchar(64)"`
+	ChannelIds IntSlice `gorm:"type:json"`
+	// LastPeriodEnd is the end of the last period reported, so that a period
+	// is reported once whatever the number of servers
+	LastPeriodEnd *time.Time `gorm:"type:datetime"`
+	// LastError is the error of the last report, empty if it was delivered
+	LastError   string     `gorm:"type:varchar(1024)"`
+	LastRunTime *time.Time `gorm:"type:datetime"`
+	CreateTime  time.Time  `gorm:"datetime;autoCreateTime"`
+	UpdateTime  time.Time  `gorm:"datetime;autoCreateTime;autoUpdateTime"`
+}
+
+// Location returns the location of Timezone.
+func (r *ReportSchedule) Location() (*time.Location, error) {
+	if r.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(r.Timezone)
+}
+
+// LastPeriod returns the last period due to be reported at now: the day or
+// the week ending at the midnight before the last generation time.
+func (r *ReportSchedule) LastPeriod(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	now = now.In(loc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	days := 1
+	if r.Period == ReportPeriodWeekly {
+		days = 7
+		day = day.AddDate(0, 0, -((int(day.Weekday()) - r.Weekday + 7) % 7))
+	}
+	if now.Before(time.Date(day.Year(), day.Month(), day.Day(), r.Hour, 0, 0, 0, loc)) {
+		day = day.AddDate(0, 0, -days)
+	}
+	return day.AddDate(0, 0, -days), day
+}
+
+func CreateReportSchedule(r *ReportSchedule) error {
+	return DB.Create(r).Error
+}
+
+func UpdateReportSchedule(r *ReportSchedule) error {
+	return DB.Save(r).Error
+}
+
+func DeleteReportSchedule(id int) error {
+	return DB.Delete(&ReportSchedule{}, id).Error
+}
+
+func GetReportScheduleById(id int) (*ReportSchedule, error) {
+	var r ReportSchedule
+	err := DB.First(&r, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &r, err
+}
+
+func ListReportSchedules() ([]ReportSchedule, error) {
+	var rs []ReportSchedule
+	if err := DB.Model(&ReportSchedule{}).Order("id").Find(&rs).Error; err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+func ListEnabledReportSchedules() ([]ReportSchedule, error) {
+	var rs []ReportSchedule
+	if err := DB.Where("enabled = ?", true).Order("id").Find(&rs).Error; err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// ClaimReportPeriod marks the period ending at end as reported, it returns
+// false if it or a later one already was.
+func ClaimReportPeriod(id int, end time.Time) (bool, error) {
+	res := DB.Model(&ReportSchedule{}).
+		Where("id = ? AND (last_period_end IS NULL OR last_period_end < ?)", id, end).
+		Update("last_period_end", end)
+	return res.RowsAffected > 0, res.Error
+}
+
+// SetReportScheduleResult records the result of the last report.
+func SetReportScheduleResult(id int, t time.Time, err error) error {
+	var lastError string
+	if err != nil {
+		lastError = err.Error()
+		if len(lastError) > 1024 {
+			lastError = lastError[:1024]
+		}
+	}
+	return DB.Model(&ReportSchedule{}).Where("id = ?", id).
+		Updates(map[string]any{"last_run_time": t, "last_error": lastError}).Error
+}
+
+// ReportSummary is the headline of a report, kept with it for the lists.
+type ReportSummary struct {
+	Messages int64 `json:"messages"`
+	Alerts   int64 `json:"alerts"`
+	Cameras  int   `json:"cameras"`
+	Devices  int   `json:"devices"`
+	// OfflineDevices went offline at least once in the period
+	OfflineDevices int `json:"offlineDevices"`
+}
+
+// Value implements driver.Valuer interface for JSON serialization
+func (s ReportSummary) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner interface for JSON deserialization
+func (s *ReportSummary) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, s)
+}
+
+// Report is a report generated by a schedule, its file is kept in the
+// bucket.
+type Report struct {
+	Id          int          `gorm:"primaryKey"`
+	ScheduleId  int          `gorm:"type:int;index"`
+	Site        string       `gorm:"type:varchar(64)"`
+	Period      ReportPeriod `gorm:"type:char(16)"`
+	Format      ReportFormat `gorm:"type:char(16)"`
+	PeriodStart time.Time    `gorm:"type:datetime"`
+	PeriodEnd   time.Time    `gorm:"type:datetime"`
+	// Path is the object of the file in the bucket
+	Path       string        `gorm:"type:varchar(255)"`
+	Summary    ReportSummary `gorm:"type:json"`
+	CreateTime time.Time     `gorm:"datetime;autoCreateTime"`
+}
+
+func CreateReport(r *Report) error {
+	return DB.Create(r).Error
+}
+
+func GetReportById(id int) (*Report, error) {
+	var r Report
+	err := DB.First(&r, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &r, err
+}
+
+// ListReports returns the reports of the schedule, the latest first.
+func ListReports(scheduleId, start, limit int) ([]Report, int64, error) {
+	var rs []Report
+	var total int64
+	db := DB.Model(&Report{}).Where("schedule_id = ?", scheduleId)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("id DESC").Offset(start).Limit(limit).Find(&rs).Error; err != nil {
+		return nil, 0, err
+	}
+	return rs, total, nil
+}
+
+// ListSiteCameras returns the cameras of the tag, every camera if it is
+// empty.
+func ListSiteCameras(site string) ([]Camera, error) {
+	var cameras []Camera
+	err := CameraFilter{Tag: site}.apply(DB.Model(&Camera{})).Order("id").Find(&cameras).Error
+	return cameras, err
+}
+
+// ListSiteDevices returns the devices bound to the cameras or running their
+// jobs, directly or through a device group.
+func ListSiteDevices(cameraIds []int) ([]Device, error) {
+	var devices []Device
+	if len(cameraIds) == 0 {
+		return devices, nil
+	}
+	jobs := func(column string) *gorm.DB {
+		return DB.Model(&Job{}).Select(column).Where("camera_id IN ?", cameraIds)
+	}
+	err := DB.Model(&Device{}).
+		Where("id IN (?) OR id IN (?) OR id IN (?)",
+			DB.Model(&Camera{}).Select("bind_device_id").Where("id IN ?", cameraIds),
+			jobs("device_id"),
+			DB.Model(&DeviceGroupMember{}).Select("device_id").Where("group_id IN (?)", jobs("device_group_id"))).
+		Order("id").Find(&devices).Error
+	return devices, err
+}
+
+// CameraMessageCount is the number of messages of the jobs of a camera.
+type CameraMessageCount struct {
+	CameraId int
+	Messages int64
+}
+
+// CountMessagesByCamera counts the messages taken in [start, end) by the
+// jobs of the cameras.
+func CountMessagesByCamera(cameraIds []int, start, end time.Time) ([]CameraMessageCount, error) {
+	var res []CameraMessageCount
+	if len(cameraIds) == 0 {
+		return res, nil
+	}
+	err := DB.Model(&Message{}).
+		Select("jobs.camera_id AS camera_id, COUNT(*) AS messages").
+		Joins("JOIN jobs ON jobs.id = messages.job_id").
+		Where("jobs.camera_id IN ? AND messages.timestamp >= ? AND messages.timestamp < ?", cameraIds, start, end).
+		Group("jobs.camera_id").
+		Scan(&res).Error
+	return res, err
+}
+
+// CameraAlertCount is the number of alerts of a camera of a severity and a
+// status.
+type CameraAlertCount struct {
+	CameraId int
+	Severity AlertSeverity
+	Status   AlertStatus
+	Count    int64
+}
+
+// CountAlertsByCamera counts the alerts created in [start, end) by the jobs
+// of the cameras, by severity and status.
+func CountAlertsByCamera(cameraIds []int, start, end time.Time) ([]CameraAlertCount, error) {
+	var res []CameraAlertCount
+	if len(cameraIds) == 0 {
+		return res, nil
+	}
+	err := DB.Model(&AlertMessage{}).
+		Select("jobs.camera_id AS camera_id, alert_messages.severity AS severity, "+
+			"alert_messages.status AS status, COUNT(*) AS count").
+		Joins("JOIN messages ON messages.id = alert_messages.message_id").
+		Joins("JOIN jobs ON jobs.id = messages.job_id").
+		Where("jobs.camera_id IN ? AND alert_messages.create_time >= ? AND alert_messages.create_time < ?",
+			cameraIds, start, end).
+		Group("jobs.camera_id, alert_messages.severity, alert_messages.status").
+		Scan(&res).Error
+	return res, err
+}
+
+// LabelCount is the number of detection boxes of a label.
+type LabelCount struct {
+	Label string
+	Count int64
+}
+
+// CountTopLabels returns the labels detected the most in the messages taken
+// in [start, end) by the jobs of the cameras.
+func CountTopLabels(cameraIds []int, start, end time.Time, limit int) ([]LabelCount, error) {
+	var res []LabelCount
+	if len(cameraIds) == 0 {
+		return res, nil
+	}
+	table, label, _ := detectBoxes()
+	err := DB.Model(&Message{}).
+		Select(label+" AS label, COUNT(*) AS count").
+		Joins("JOIN jobs ON jobs.id = messages.job_id").
+		Joins("JOIN "+table).
+		Where("jobs.camera_id IN ? AND messages.timestamp >= ? AND messages.timestamp < ?", cameraIds, start, end).
+		Where(label + " IS NOT NULL").
+		Group(label).
+		Order("count DESC").
+		Limit(limit).
+		Scan(&res).Error
+	return res, err
+}
+
+// ListDeviceOfflineEvents returns the offline events of the devices that
+// overlap [start, end), the open ones lasting until now.
+func ListDeviceOfflineEvents(deviceIds []int, start, end time.Time) ([]HealthEvent, error) {
+	var events []HealthEvent
+	if len(deviceIds) == 0 {
+		return events, nil
+	}
+	err := DB.Where("kind = ? AND device_id IN ? AND start_time < ?", HealthEventDeviceOffline, deviceIds, end).
+		Where("resolved = ? OR resolve_time > ?", false, start).
+		Order("start_time").
+		Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const messageCleanupLockKey = "message-cleanup-lock"
+
+// ExpiredMessagesQuery selects the messages a retention policy deletes.
+type ExpiredMessagesQuery struct {
+	// Before expires the messages created before it, the alerted ones only
+	// before AlertBefore. A zero Before expires none by age.
+	Before      time.Time
+	AlertBefore time.Time
+	// MaxId expires the messages up to it whatever their age, 0 for none
+	MaxId int
+}
+
+// ListExpiredMessages returns the oldest expired messages, only their id and
+// the paths of their media are loaded.
+func ListExpiredMessages(q ExpiredMessagesQuery, limit int) ([]*Message, error) {
+	if q.Before.IsZero() && q.MaxId == 0 {
+		return nil, nil
+	}
+
+	cond := DB.Where("id <= ?", q.MaxId)
+	if !q.Before.IsZero() {
+		cond = cond.Or("create_time < ? AND (alerted = ? OR create_time < ?)", q.Before, false, q.AlertBefore)
+	}
+	var ms []*Message
+	err := DB.Model(&Message{}).
+		Select("id, image_path, video_path").
+		Where(cond).
+		Order("id asc").
+		Limit(limit).
+		Find(&ms).Error
+	return ms, err
+}
+
+// GetMessageIdBeyondNewest returns the id of the newest message after the n
+// newest ones, 0 if there are no more than n messages.
+func GetMessageIdBeyondNewest(n int64) (int, error) {
+	var ids []int
+	err := DB.Model(&Message{}).Order("id desc").Offset(int(n)).Limit(1).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// DeleteMessagesByIds deletes the messages with their alerts and the
+// activities of the alerts.
+func DeleteMessagesByIds(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		alertIds := tx.Model(&AlertMessage{}).Select("id").Where("message_id IN ?", ids)
+		if err := tx.Where("alert_id IN (?)", alertIds).Delete(&AlertActivity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id IN ?", ids).Delete(&AlertMessage{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Message{}, ids).Error
+	})
+}
+
+// AcquireMessageCleanupLock reports whether this server should clean the
+// expired messages, so that the servers do not clean them at once.
+func AcquireMessageCleanupLock(ctx context.Context, ttl time.Duration) (bool, error) {
+	return Redis.SetNX(ctx, messageCleanupLockKey, 1, ttl).Result()
+}
+
+// ReleaseMessageCleanupLock releases the lock once the cleanup is done.
+func ReleaseMessageCleanupLock(ctx context.Context) error {
+	return Redis.Del(ctx, messageCleanupLockKey).Err()
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken renews the access JWTs of a login session. Every refresh
+// uses the token up and issues the next one of the session, only the last
+// one of a session is live.
+type RefreshToken struct {
+	Id        int    `gorm:"primarykey"`
+	UserId    int    `gorm:"index;not null"`
+	SessionId string `gorm:"type:char(32);index;not null"`
+	// TokenHash is the SHA-256 hash of the token, as for the API tokens
+	TokenHash string `gorm:"type:char(64);uniqueIndex;not null"`
+	// ExpireTime ends the session if it is not refreshed before
+	ExpireTime time.Time
+	// SessionExpireTime ends the session whatever its refreshes, nil for no
+	// limit
+	SessionExpireTime *time.Time
+	// UsedTime is set once the token is refreshed, a token used twice was
+	// stolen
+	UsedTime    *time.Time
+	RevokedTime *time.Time
+	// LoginTime is the time of the login starting the session
+	LoginTime   time.Time
+	UserAgent   string    `gorm:"type:varchar(255)"`
+	Ip          string    `gorm:"type:varchar(64)"`
+	CreatedTime time.Time `gorm:"datetime;autoCreateTime"`
+}
+
+// Expired reports whether the session of the token has ended by now.
+func (t *RefreshToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpireTime) || (t.SessionExpireTime != nil && !now.Before(*t.SessionExpireTime))
+}
+
+func CreateRefreshToken(t *RefreshToken) error {
+	return DB.Create(t).Error
+}
+
+// GetRefreshTokenByToken returns the refresh token of a token as presented
+// by a client, nil if there is none.
+func GetRefreshTokenByToken(token string) (*RefreshToken, error) {
+	var t RefreshToken
+	err := DB.Where("token_hash = ?", HashApiToken(token)).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &t, err
+}
+
+// UseRefreshToken marks the token used, it returns false if it already was,
+// by a concurrent refresh among others.
+func UseRefreshToken(id int, now time.Time) (bool, error) {
+	res := DB.Model(&RefreshToken{}).Where("id = ? AND used_time IS NULL", id).Update("used_time", now)
+	return res.RowsAffected == 1, res.Error
+}
+
+// RevokeSession ends the session of the user, it returns false if the
+// session doesn't exist or already ended.
+func RevokeSession(userId int, sessionId string) (bool, error) {
+	res := DB.Model(&RefreshToken{}).Where("user_id = ? AND session_id = ? AND revoked_time IS NULL", userId, sessionId).
+		Update("revoked_time", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+// RevokeUserSessions ends every session of the user but the one kept, if
+// any.
+func RevokeUserSessions(userId int, keptSessionId string) error {
+	return DB.Model(&RefreshToken{}).Where("user_id = ? AND session_id != ? AND revoked_time IS NULL", userId, keptSessionId).
+		Update("revoked_time", time.Now()).Error
+}
+
+// ListUserSessions returns the live token of every session of the user.
+func ListUserSessions(userId int, now time.Time) ([]*RefreshToken, error) {
+	var tokens []*RefreshToken
+	err := DB.Where("user_id = ? AND used_time IS NULL AND revoked_time IS NULL AND expire_time > ?", userId, now).
+		Where("session_expire_time IS NULL OR session_expire_time > ?", now).
+		Order("id desc").Find(&tokens).Error
+	return tokens, err
+}
+
+// DeleteExpiredRefreshTokens deletes the tokens of the sessions which ended
+// before the time.
+func DeleteExpiredRefreshTokens(before time.Time) (int64, error) {
+	res := DB.Where("expire_time < ? OR session_expire_time < ?", before, before).Delete(&RefreshToken{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeviceSigningKey is a public key a device signed messages with. The keys
+// are never deleted, so the messages stay verifiable after the device
+// unregisters or changes its key.
+type DeviceSigningKey struct {
+	Id          int       `gorm:"primaryKey"`
+	DeviceUuid  string    `gorm:"type:char(96);index"`
+	Fingerprint string    `gorm:"type:char(64);uniqueIndex"`
+	PublicKey   []byte    `gorm:"type:varbinary(64)"`
+	CreateTime  time.Time `gorm:"type:datetime;autoCreateTime"`
+}
+
+// PinDeviceSigningKey records the key and makes it the signing key of the
+// device if it has none. It returns false if the device has another key
+// pinned, which is kept.
+func PinDeviceSigningKey(d *Device, key *DeviceSigningKey) (bool, error) {
+	if d.SigningKey == key.Fingerprint {
+		return true, nil
+	} else if d.SigningKey != "" {
+		return false, nil
+	}
+	var pinned bool
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Device{}).Where("id = ? AND (signing_key = '' OR signing_key IS NULL)", d.Id).
+			Update("signing_key", key.Fingerprint)
+		if res.Error != nil {
+			return res.Error
+		} else if res.RowsAffected == 0 {
+			// another report may have pinned it since the device was loaded
+			var current string
+			if err := tx.Model(&Device{}).Select("signing_key").Where("id = ?", d.Id).Scan(&current).Error; err != nil {
+				return err
+			}
+			pinned = current == key.Fingerprint
+			return nil
+		}
+		pinned = true
+		// a key pinned again after the device re-registered is kept as is
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(key).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	if pinned {
+		d.SigningKey = key.Fingerprint
+	}
+	return pinned, nil
+}
+
+func GetDeviceSigningKey(fingerprint string) (*DeviceSigningKey, error) {
+	var k DeviceSigningKey
+	err := DB.Where("fingerprint = ?", fingerprint).First(&k).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &k, err
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SnapshotTask asks a device to grab one frame of a camera.
+type SnapshotTask struct {
+	TaskUuid   string    `json:"taskUuid"`
+	CameraUuid string    `json:"cameraUuid"`
+	PullAddr   string    `json:"pullAddr"`
+	ExpireTime time.Time `json:"expireTime,omitempty"`
+}
+
+type SnapshotResult struct {
+	TaskUuid   string    `json:"taskUuid"`
+	ImagePath  string    `json:"imagePath,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CreateTime time.Time `json:"createTime"`
+}
+
+const (
+	snapshotTaskKeyTemplate   = "snapshot:%s:%s"
+	snapshotResultKeyTemplate = "snapshot-result:%s"
+	snapshotTaskExpire        = time.Minute
+	snapshotResultExpire      = 5 * time.Minute
+)
+
+func snapshotTaskKey(deviceUuid, taskUuid string) string {
+	return fmt.Sprintf(snapshotTaskKeyTemplate, deviceUuid, taskUuid)
+}
+
+func AddSnapshotTask(ctx context.Context, deviceUuid string, task *SnapshotTask) error {
+	data, _ := json.Marshal(task)
+	return Redis.Set(ctx, snapshotTaskKey(deviceUuid, task.TaskUuid), data, snapshotTaskExpire).Err()
+}
+
+func DeleteSnapshotTask(ctx context.Context, deviceUuid, taskUuid string) error {
+	return Redis.Del(ctx, snapshotTaskKey(deviceUuid, taskUuid)).Err()
+}
+
+func GetSnapshotTasksByDeviceUuid(ctx context.Context, deviceUuid string) ([]*SnapshotTask, error) {
+	keys, err := Redis.Keys(ctx, fmt.Sprintf(snapshotTaskKeyTemplate, deviceUuid, "*")).Result()
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*SnapshotTask
+	for _, key := range keys {
+		var data []byte
+		if err := Redis.Get(ctx, key).Scan(&data); err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return nil, err
+		}
+		var task SnapshotTask
+		if err := json.Unmarshal(data, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, &task)
+	}
+	return tasks, nil
+}
+
+func SetSnapshotResult(ctx context.Context, result *SnapshotResult) error {
+	data, _ := json.Marshal(result)
+	return Redis.Set(ctx, fmt.Sprintf(snapshotResultKeyTemplate, result.TaskUuid), data, snapshotResultExpire).Err()
+}
+
+func GetSnapshotResult(ctx context.Context, taskUuid string) (*SnapshotResult, error) {
+	var data []byte
+	if err := Redis.Get(ctx, fmt.Sprintf(snapshotResultKeyTemplate, taskUuid)).Scan(&data); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result SnapshotResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
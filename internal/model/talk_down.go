@@ -0,0 +1,107 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TalkDownTask asks a device to play an audio clip or a TTS message through
+// the speaker of a site.
+type TalkDownTask struct {
+	TaskUuid  string `json:"taskUuid"`
+	MessageId int    `json:"messageId,omitempty"`
+	JobUuid   string `json:"jobUuid,omitempty"`
+	// PullAddr of the camera, used to open its audio backchannel
+	PullAddr   string    `json:"pullAddr"`
+	Text       string    `json:"text,omitempty"`
+	AudioUrl   string    `json:"audioUrl,omitempty"`
+	Output     string    `json:"output"`
+	ExpireTime time.Time `json:"expireTime,omitempty"`
+}
+
+type TalkDownResult struct {
+	TaskUuid string `json:"taskUuid"`
+	Error    string `json:"error,omitempty"`
+	// Duration of the playback in milliseconds
+	Duration   int64     `json:"duration"`
+	CreateTime time.Time `json:"createTime"`
+}
+
+const (
+	talkDownTaskKeyTemplate     = "talk-down:%s:%s"
+	talkDownResultKeyTemplate   = "talk-down-result:%s"
+	talkDownCooldownKeyTemplate = "talk-down-cooldown:%s"
+	talkDownTaskExpire          = time.Minute
+	talkDownResultExpire        = 5 * time.Minute
+)
+
+func talkDownTaskKey(deviceUuid, taskUuid string) string {
+	return fmt.Sprintf(talkDownTaskKeyTemplate, deviceUuid, taskUuid)
+}
+
+func AddTalkDownTask(ctx context.Context, deviceUuid string, task *TalkDownTask) error {
+	data, _ := json.Marshal(task)
+	return Redis.Set(ctx, talkDownTaskKey(deviceUuid, task.TaskUuid), data, talkDownTaskExpire).Err()
+}
+
+func DeleteTalkDownTask(ctx context.Context, deviceUuid, taskUuid string) error {
+	return Redis.Del(ctx, talkDownTaskKey(deviceUuid, taskUuid)).Err()
+}
+
+func GetTalkDownTasksByDeviceUuid(ctx context.Context, deviceUuid string) ([]*TalkDownTask, error) {
+	keys, err := Redis.Keys(ctx, fmt.Sprintf(talkDownTaskKeyTemplate, deviceUuid, "*")).Result()
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*TalkDownTask
+	for _, key := range keys {
+		var data []byte
+		if err := Redis.Get(ctx, key).Scan(&data); err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return nil, err
+		}
+		var task TalkDownTask
+		if err := json.Unmarshal(data, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, &task)
+	}
+	return tasks, nil
+}
+
+func SetTalkDownResult(ctx context.Context, result *TalkDownResult) error {
+	data, _ := json.Marshal(result)
+	return Redis.Set(ctx, fmt.Sprintf(talkDownResultKeyTemplate, result.TaskUuid), data, talkDownResultExpire).Err()
+}
+
+func GetTalkDownResult(ctx context.Context, taskUuid string) (*TalkDownResult, error) {
+	var data []byte
+	if err := Redis.Get(ctx, fmt.Sprintf(talkDownResultKeyTemplate, taskUuid)).Scan(&data); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result TalkDownResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AcquireTalkDownCooldown returns true if no talk-down was triggered by an
+// alert of the job within cooldown, and starts a new cooldown period.
+func AcquireTalkDownCooldown(ctx context.Context, jobUuid string, cooldown time.Duration) (bool, error) {
+	if cooldown <= 0 {
+		return true, nil
+	}
+	return Redis.SetNX(ctx, fmt.Sprintf(talkDownCooldownKeyTemplate, jobUuid), 1, cooldown).Result()
+}
@@ -0,0 +1,134 @@
+package model
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type TestDetectTask struct {
+	TaskUuid      string    `json:"taskUuid"`
+	CameraUuid    string    `json:"cameraUuid"`
+	PullAddr      string    `json:"pullAddr"`
+	ModelName     string    `json:"modelName"`
+	Labels        string    `json:"labels"`
+	ConfThreshold float32   `json:"confThreshold"`
+	IoUThreshold  float32   `json:"iouThreshold"`
+	ExpireTime    time.Time `json:"expireTime,omitempty"`
+}
+
+// CacheKey identifies tasks that would produce the same result, so that repeated
+// tests with the same camera and model options can be answered from cache.
+func (t *TestDetectTask) CacheKey() string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s|%s|%s|%.4f|%.4f", t.CameraUuid, t.ModelName, t.Labels, t.ConfThreshold, t.IoUThreshold)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type TestDetectResult struct {
+	TaskUuid   string            `json:"taskUuid"`
+	ImagePath  string            `json:"imagePath,omitempty"`
+	Boxes      DetectionBoxSlice `json:"boxes,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	CreateTime time.Time         `json:"createTime"`
+}
+
+const (
+	testDetectTaskKeyTemplate   = "test-detect:%s:%s"
+	testDetectResultKeyTemplate = "test-detect-result:%s"
+	testDetectCacheKeyTemplate  = "test-detect-cache:%s"
+	testDetectTaskExpire        = time.Minute
+	testDetectResultExpire      = 5 * time.Minute
+	testDetectCacheExpire       = 30 * time.Second
+)
+
+func testDetectTaskKey(deviceUuid, taskUuid string) string {
+	return fmt.Sprintf(testDetectTaskKeyTemplate, deviceUuid, taskUuid)
+}
+
+func AddTestDetectTask(ctx context.Context, deviceUuid string, task *TestDetectTask) error {
+	data, _ := json.Marshal(task)
+	return Redis.Set(ctx, testDetectTaskKey(deviceUuid, task.TaskUuid), data, testDetectTaskExpire).Err()
+}
+
+func DeleteTestDetectTask(ctx context.Context, deviceUuid, taskUuid string) error {
+	return Redis.Del(ctx, testDetectTaskKey(deviceUuid, taskUuid)).Err()
+}
+
+// TakeTestDetectTask deletes the task of the device, returning false when
+// the device has no such task, the task being another device's or expired.
+func TakeTestDetectTask(ctx context.Context, deviceUuid, taskUuid string) (bool, error) {
+	n, err := Redis.Del(ctx, testDetectTaskKey(deviceUuid, taskUuid)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func GetTestDetectTasksByDeviceUuid(ctx context.Context, deviceUuid string) ([]*TestDetectTask, error) {
+	// SCAN rather than KEYS, which blocks redis while walking every key
+	var keys []string
+	iter := Redis.Scan(ctx, 0, fmt.Sprintf(testDetectTaskKeyTemplate, deviceUuid, "*"), 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	var tasks []*TestDetectTask
+	for _, key := range keys {
+		var data []byte
+		if err := Redis.Get(ctx, key).Scan(&data); err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return nil, err
+		}
+		var task TestDetectTask
+		if err := json.Unmarshal(data, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, &task)
+	}
+	return tasks, nil
+}
+
+func SetTestDetectResult(ctx context.Context, result *TestDetectResult) error {
+	data, _ := json.Marshal(result)
+	return Redis.Set(ctx, fmt.Sprintf(testDetectResultKeyTemplate, result.TaskUuid), data, testDetectResultExpire).Err()
+}
+
+func GetTestDetectResult(ctx context.Context, taskUuid string) (*TestDetectResult, error) {
+	return getTestDetectResult(ctx, fmt.Sprintf(testDetectResultKeyTemplate, taskUuid))
+}
+
+func SetCachedTestDetectResult(ctx context.Context, cacheKey string, result *TestDetectResult) error {
+	data, _ := json.Marshal(result)
+	return Redis.Set(ctx, fmt.Sprintf(testDetectCacheKeyTemplate, cacheKey), data, testDetectCacheExpire).Err()
+}
+
+func GetCachedTestDetectResult(ctx context.Context, cacheKey string) (*TestDetectResult, error) {
+	return getTestDetectResult(ctx, fmt.Sprintf(testDetectCacheKeyTemplate, cacheKey))
+}
+
+func getTestDetectResult(ctx context.Context, key string) (*TestDetectResult, error) {
+	var data []byte
+	if err := Redis.Get(ctx, key).Scan(&data); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result TestDetectResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
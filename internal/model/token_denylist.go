@@ -0,0 +1,52 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	deniedTokenKeyTemplate   = "token-denylist:jti:%s"
+	deniedUserTokensTemplate = "token-denylist:user:%d"
+)
+
+// DenyToken revokes the JWT of the jti until it expires.
+func DenyToken(ctx context.Context, jti string, expireTime time.Time) error {
+	ttl := time.Until(expireTime)
+	if ttl <= 0 {
+		return nil
+	}
+	return Redis.Set(ctx, fmt.Sprintf(deniedTokenKeyTemplate, jti), 1, ttl).Err()
+}
+
+// DenyUserTokens revokes the JWTs of the user issued up to now, ttl must be
+// as long as the lifetime of the JWTs.
+func DenyUserTokens(ctx context.Context, userId int, ttl time.Duration) error {
+	return Redis.Set(ctx, fmt.Sprintf(deniedUserTokensTemplate, userId), time.Now().Unix(), ttl).Err()
+}
+
+// IsTokenDenied reports whether the JWT of the jti, issued to the user at
+// the time, was revoked.
+func IsTokenDenied(ctx context.Context, jti string, userId int, issuedAt time.Time) (bool, error) {
+	pipe := Redis.Pipeline()
+	var jtiCmd *redis.IntCmd
+	if jti != "" {
+		jtiCmd = pipe.Exists(ctx, fmt.Sprintf(deniedTokenKeyTemplate, jti))
+	}
+	userCmd := pipe.Get(ctx, fmt.Sprintf(deniedUserTokensTemplate, userId))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return false, err
+	}
+	if jtiCmd != nil && jtiCmd.Val() > 0 {
+		return true, nil
+	}
+	if before, err := strconv.ParseInt(userCmd.Val(), 10, 64); err == nil && issuedAt.Unix() <= before {
+		return true, nil
+	}
+	return false, nil
+}
@@ -1,19 +1,153 @@
 package model
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type User struct {
-	Id          int       `gorm:"primarykey"`
-	Username    string    `json:"username" gorm:"type:char(96);uniqueIndex"`
-	Nickname    string    `json:"nickname" gorm:"type:char(96)"`
-	Password    string    `json:"password" gorm:"type:char(96)"`
-	AccessToken string    `json:"access_token" gorm:"type:char(96);uniqueIndex"`
-	IsAdmin     bool      `json:"is_admin" gorm:"default:false"`
+	Id          int    `gorm:"primarykey"`
+	Username    string `json:"username" gorm:"type:char(96);uniqueIndex"`
+	Nickname    string `json:"nickname" gorm:"type:char(96)"`
+	Password    string `json:"password" gorm:"type:char(96)"` // bcrypt hash, or plain text for the users not logged in since
+	AccessToken string `json:"access_token" gorm:"type:char(96);uniqueIndex"`
+	IsAdmin     bool   `json:"is_admin" gorm:"default:false"`
+	// OrgId is the organization the user belongs to and administers if
+	// IsAdmin, the admins of the default one administer every organization
+	OrgId int `json:"org_id" gorm:"index;not null;default:1"`
+	// Timezone is the IANA name the user views times in, empty for the
+	// browser's own
+	Timezone string `json:"timezone" gorm:"type:varchar(64)"`
+	// TotpSecret is the base32 secret of the two-factor authentication, set
+	// at the enrollment and only used once TotpEnabled is
+	TotpSecret  string `json:"-" gorm:"type:varchar(64)"`
+	TotpEnabled bool   `json:"totp_enabled" gorm:"default:false"`
+	// TotpCounter is the last time step used, the codes of this step and the
+	// ones before are refused to prevent their replay
+	TotpCounter int64 `json:"-" gorm:"default:0"`
+	// TotpRecoveryCodes are the SHA-256 hashes of the unused recovery codes
+	TotpRecoveryCodes StringSlice `json:"-" gorm:"type:json"`
+	// OidcSubject is the issuer and the subject, separated by a space, of
+	// the users logging in with OIDC, empty for the local users
+	OidcSubject string    `json:"-" gorm:"type:varchar(512);index"`
 	CreatedTime time.Time `json:"created_time" gorm:"datetime;autoCreateTime"`
 }
 
+// HashPassword returns the bcrypt hash of password.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func isPasswordHash(password string) bool {
+	return len(password) == 60 &&
+		(strings.HasPrefix(password, "$2a$") || strings.HasPrefix(password, "$2b$") || strings.HasPrefix(password, "$2y$"))
+}
+
+// CheckPassword reports whether password is the password of the user, and
+// whether the stored one is still in plain text and must be hashed.
+func (u *User) CheckPassword(password string) (ok bool, legacy bool) {
+	if isPasswordHash(u.Password) {
+		return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil, false
+	}
+	return subtle.ConstantTimeCompare([]byte(u.Password), []byte(password)) == 1, true
+}
+
+// UpdateUserPassword replaces the password of the user by hash.
+func UpdateUserPassword(id int, hash string) error {
+	return DB.Model(&User{}).Where("id = ?", id).Update("password", hash).Error
+}
+
+// HashRecoveryCode returns the hash a recovery code is stored as, the codes
+// are compared case insensitively and without their dashes.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetUserTotpSecret starts the two-factor enrollment of the user with a new
+// secret, it is enabled by EnableUserTotp.
+func SetUserTotpSecret(id int, secret string) error {
+	return DB.Model(&User{}).Where("id = ?", id).Updates(map[string]any{
+		"totp_secret":  secret,
+		"totp_enabled": false,
+		"totp_counter": 0,
+	}).Error
+}
+
+// EnableUserTotp enables the two-factor authentication of the user, counter
+// is the time step of the code which confirmed the enrollment.
+func EnableUserTotp(id int, counter int64, recoveryCodes []string) error {
+	return DB.Model(&User{}).Where("id = ?", id).Updates(map[string]any{
+		"totp_enabled":        true,
+		"totp_counter":        counter,
+		"totp_recovery_codes": StringSlice(recoveryCodes),
+	}).Error
+}
+
+// SetUserTotpRecoveryCodes replaces the recovery codes of the user.
+func SetUserTotpRecoveryCodes(id int, recoveryCodes []string) error {
+	return DB.Model(&User{}).Where("id = ?", id).
+		Update("totp_recovery_codes", StringSlice(recoveryCodes)).Error
+}
+
+// DisableUserTotp disables the two-factor authentication of the user and
+// forgets the secret and the recovery codes.
+func DisableUserTotp(id int) error {
+	return DB.Model(&User{}).Where("id = ?", id).Updates(map[string]any{
+		"totp_secret":         "",
+		"totp_enabled":        false,
+		"totp_counter":        0,
+		"totp_recovery_codes": nil,
+	}).Error
+}
+
+// UseUserTotpCounter records the time step of a valid code and reports
+// whether it was not used yet.
+func UseUserTotpCounter(id int, counter int64) (bool, error) {
+	res := DB.Model(&User{}).Where("id = ? AND totp_counter < ?", id, counter).
+		Update("totp_counter", counter)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// UseUserTotpRecoveryCode removes the recovery code from the ones of the
+// user and reports whether it was there.
+func UseUserTotpRecoveryCode(id int, code string) (bool, error) {
+	hash := HashRecoveryCode(code)
+	used := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			return err
+		}
+		i := slices.Index(user.TotpRecoveryCodes, hash)
+		if i < 0 {
+			return nil
+		}
+		codes := slices.Delete(slices.Clone(user.TotpRecoveryCodes), i, i+1)
+		used = true
+		return tx.Model(&User{}).Where("id = ?", id).
+			Update("totp_recovery_codes", StringSlice(codes)).Error
+	})
+	return used, err
+}
+
 func CreateUser(user *User) error {
 	return DB.Create(user).Error
 }
@@ -24,9 +158,21 @@ func GetUserById(id int) (*User, error) {
 	return &user, err
 }
 
+// GetUserByToken returns the user of an API token, or else of a legacy
+// access token, the expired API tokens have no user.
 func GetUserByToken(token string) (*User, error) {
+	apiToken, err := GetApiTokenByToken(token)
+	if err != nil {
+		return nil, err
+	} else if apiToken != nil {
+		if apiToken.Expired(time.Now()) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return GetUserById(apiToken.UserId)
+	}
+
 	var user User
-	err := DB.Where("access_token = ?", token).First(&user).Error
+	err = DB.Where("access_token = ?", token).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -46,22 +192,57 @@ func UpdateUser(user *User) error {
 	return DB.Save(user).Error
 }
 
+// GetUserByOidcSubject returns the user provisioned for the OIDC subject,
+// nil if there is none.
+func GetUserByOidcSubject(subject string) (*User, error) {
+	var user User
+	err := DB.Where("oidc_subject = ?", subject).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateUserOidcProfile updates the fields of the user the OIDC provider
+// manages on every login.
+func UpdateUserOidcProfile(id int, nickname string, isAdmin bool) error {
+	return DB.Model(&User{}).Where("id = ?", id).Updates(map[string]any{
+		"nickname": nickname,
+		"is_admin": isAdmin,
+	}).Error
+}
+
+func UpdateUserTimezone(id int, timezone string) error {
+	return DB.Model(&User{}).Where("id = ?", id).Update("timezone", timezone).Error
+}
+
 func DeleteUser(id int) error {
-	return DB.Delete(&User{}, id).Error
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&ApiToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&RefreshToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&User{}, id).Error
+	})
 }
 
-func CountUsers() (int, error) {
+func CountUsers(orgId int) (int, error) {
 	var count int64
-	err := DB.Model(&User{}).Count(&count).Error
+	err := DB.Model(&User{}).Scopes(InOrg(orgId)).Count(&count).Error
 	if err != nil {
 		return 0, err
 	}
 	return int(count), nil
 }
 
-func GetUsers(start, limit int) ([]*User, error) {
+func GetUsers(orgId, start, limit int) ([]*User, error) {
 	var users []*User
-	err := DB.Offset(start).Limit(limit).Find(&users).Order("id desc").Error
+	err := DB.Scopes(InOrg(orgId)).Offset(start).Limit(limit).Find(&users).Order("id desc").Error
 	if err != nil {
 		return nil, err
 	}
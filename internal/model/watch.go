@@ -0,0 +1,41 @@
+package model
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Changes of what the devices fetch are published on redis so that the
+// watch requests waiting on any server instance are woken up. A missed
+// notification only delays a device until its watch request times out.
+const watchChannelPrefix = "watch:"
+
+// WatchKeyJobs is notified when any job changes, the jobs of a device are
+// checked again by its watch requests.
+const WatchKeyJobs = "jobs"
+
+// WatchKeyPreviewTasks is notified when the preview tasks of the device change.
+func WatchKeyPreviewTasks(deviceUuid string) string {
+	return "preview:" + deviceUuid
+}
+
+// NotifyWatch wakes up the watch requests on key. Errors are ignored, the
+// watch requests time out eventually.
+func NotifyWatch(ctx context.Context, key string) {
+	if Redis == nil {
+		return
+	}
+	Redis.Publish(ctx, watchChannelPrefix+key, "")
+}
+
+// SubscribeWatch subscribes to the notifications of all keys.
+func SubscribeWatch(ctx context.Context) *redis.PubSub {
+	return Redis.PSubscribe(ctx, watchChannelPrefix+"*")
+}
+
+// WatchKey returns the key notified on channel.
+func WatchKey(channel string) string {
+	return strings.TrimPrefix(channel, watchChannelPrefix)
+}
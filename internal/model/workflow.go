@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 type Workflow struct {
 	Id           int              `gorm:"primaryKey"`
 	Uuid         string           `gorm:"type:char(96);unique"`
+	OrgId        int              `gorm:"index;not null;default:1"`
 	Key          string           `gorm:"type:varchar(255)"`
 	ModelName    string           `gorm:"type:varchar(255)"`
 	Endpoint     string           `gorm:"type:varchar(255)"`
@@ -49,13 +51,13 @@ func DeleteWorkflow(id int) error {
 	return DB.Delete(&Workflow{}, id).Error
 }
 
-func ListWorkflows(start, limit int) ([]Workflow, int64, error) {
+func ListWorkflows(orgId, start, limit int) ([]Workflow, int64, error) {
 	var workflows []Workflow
 	var total int64
-	if err := DB.Model(&Workflow{}).Count(&total).Error; err != nil {
+	if err := DB.Model(&Workflow{}).Scopes(InOrg(orgId)).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
-	if err := DB.Model(&Workflow{}).Offset(start).Limit(limit).Find(&workflows).Error; err != nil {
+	if err := DB.Model(&Workflow{}).Scopes(InOrg(orgId)).Offset(start).Limit(limit).Find(&workflows).Error; err != nil {
 		return nil, 0, err
 	}
 	return workflows, total, nil
@@ -65,6 +67,37 @@ func UpdateWorkflow(wf *Workflow) error {
 	return DB.Save(wf).Error
 }
 
+// GetWorkflowsByIds returns the given workflows, or every workflow if ids is empty.
+func GetWorkflowsByIds(ids []int) ([]Workflow, error) {
+	var workflows []Workflow
+	query := DB.Model(&Workflow{}).Order("id")
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	if err := query.Find(&workflows).Error; err != nil {
+		return nil, err
+	}
+	return workflows, nil
+}
+
+// ImportWorkflows creates and updates workflows in a single transaction so a
+// bundle is either applied entirely or not at all.
+func ImportWorkflows(creates, updates []*Workflow) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, wf := range creates {
+			if err := tx.Create(wf).Error; err != nil {
+				return err
+			}
+		}
+		for _, wf := range updates {
+			if err := tx.Save(wf).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 type Operator string
 
 const (
@@ -78,6 +111,15 @@ const (
 	OperatorEndsWith    = "ends_with"
 	OperatorEmpty       = "empty"
 	OperatorNotEmpty    = "not_empty"
+	// numeric comparisons, a value that is not a number never matches
+	OperatorGreater      = "gt"
+	OperatorGreaterEqual = "ge"
+	OperatorLess         = "lt"
+	OperatorLessEqual    = "le"
+	// Value is "min,max", both inclusive, compared as numbers when both
+	// bounds are numbers and as strings otherwise, e.g. "08:00,18:00"
+	OperatorBetween    = "between"
+	OperatorNotBetween = "not_between"
 )
 
 type CombineOperator string
@@ -115,11 +157,46 @@ func (c Condition) Match(s string) bool {
 		return s == ""
 	case OperatorNotEmpty:
 		return s != ""
+	case OperatorGreater, OperatorGreaterEqual, OperatorLess, OperatorLessEqual:
+		v, err1 := strconv.ParseFloat(s, 64)
+		ref, err2 := strconv.ParseFloat(c.Value, 64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		switch c.Op {
+		case OperatorGreater:
+			return v > ref
+		case OperatorGreaterEqual:
+			return v >= ref
+		case OperatorLess:
+			return v < ref
+		default:
+			return v <= ref
+		}
+	case OperatorBetween:
+		return between(s, c.Value)
+	case OperatorNotBetween:
+		return s != "" && !between(s, c.Value)
 	default:
 		return false
 	}
 }
 
+func between(s, bounds string) bool {
+	min, max, ok := strings.Cut(bounds, ",")
+	if !ok {
+		return false
+	}
+	min, max = strings.TrimSpace(min), strings.TrimSpace(max)
+	lo, err1 := strconv.ParseFloat(min, 64)
+	hi, err2 := strconv.ParseFloat(max, 64)
+	if err1 == nil && err2 == nil {
+		v, err := strconv.ParseFloat(s, 64)
+		return err == nil && v >= lo && v <= hi
+	}
+	return s >= min && s <= max
+}
+
 type FilterCondition struct {
 	CombineOperator CombineOperator `json:"combine_op,omitempty"`
 	Conditions      []*Condition    `json:"conditions,omitempty"`
@@ -161,3 +238,32 @@ func (f FilterCondition) Match(s string) bool {
 	}
 	return false
 }
+
+// MatchFields matches each condition against the value of its field, a
+// condition without field is matched against def. A missing field has an
+// empty value.
+func (f FilterCondition) MatchFields(fields map[string]string, def string) bool {
+	value := func(c *Condition) string {
+		if c.Field == "" {
+			return def
+		}
+		return fields[c.Field]
+	}
+	switch f.CombineOperator {
+	case CombineOperatorAnd:
+		for _, cond := range f.Conditions {
+			if !cond.Match(value(cond)) {
+				return false
+			}
+		}
+		return true
+	case CombineOperatorOr:
+		for _, cond := range f.Conditions {
+			if cond.Match(value(cond)) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
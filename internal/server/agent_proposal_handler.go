@@ -0,0 +1,219 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const agentProposalKey = "agentProposal"
+
+// errProposalTarget is returned when the job or workflow a proposal updates
+// no longer exists.
+var errProposalTarget = errors.New("target of the proposal not found")
+
+// SetAgentProposalToContext sets the pending proposal in the context, a
+// proposal is only visible to the user it was made for and the admins.
+func SetAgentProposalToContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		proposal, err := model.GetAgentProposal(c.Request.Context(), c.Param("proposal_uuid"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+		user := c.MustGet(userKey).(*model.User)
+		if proposal == nil || (proposal.UserId != user.Id && !user.IsAdmin) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "proposal not found",
+			})
+			return
+		}
+		c.Set(agentProposalKey, proposal)
+		c.Next()
+	}
+}
+
+// handleGetAgentProposal 获取助手提议
+// @Summary 获取助手提议
+// @Description 获取助手提议的任务或工作流变更，提议在批准前不会生效，30分钟后过期
+// @Tags 对话
+// @Produce json
+// @Param proposal_uuid path string true "提议uuid"
+// @Success 200 {object} dao.AgentProposalSpec "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "提议不存在或已过期"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/agent/proposal/{proposal_uuid} [get]
+func (s *Server) handleGetAgentProposal(c *gin.Context) {
+	proposal := c.MustGet(agentProposalKey).(*model.AgentProposal)
+	c.JSON(http.StatusOK, dao.FromAgentProposalModel(proposal))
+}
+
+// handleApproveAgentProposal 批准助手提议
+// @Summary 批准助手提议
+// @Description 批准并执行助手提议的任务或工作流变更，与直接调用对应的创建、更新接口效果相同
+// @Tags 对话
+// @Produce json
+// @Param proposal_uuid path string true "提议uuid"
+// @Success 200 {object} dao.ApproveAgentProposalResponse "执行成功"
+// @Failure 400 {object} ErrorResponse "提议的变更不合法"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "提议不存在或已过期"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/agent/proposal/{proposal_uuid}/approve [post]
+func (s *Server) handleApproveAgentProposal(c *gin.Context) {
+	proposal := c.MustGet(agentProposalKey).(*model.AgentProposal)
+
+	// taking the proposal first makes sure it is applied only once
+	ok, err := model.TakeAgentProposal(c.Request.Context(), proposal.Uuid)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if !ok {
+		s.writeError(c, http.StatusNotFound, errors.New("proposal not found"))
+		return
+	}
+
+	resp := dao.ApproveAgentProposalResponse{Kind: proposal.Kind}
+	switch proposal.Kind {
+	case model.AgentProposalCreateJob:
+		resp.Id, resp.Uuid, err = s.applyCreateJobProposal(c, proposal)
+	case model.AgentProposalUpdateJob:
+		resp.Id, resp.Uuid, err = s.applyUpdateJobProposal(c, proposal)
+	case model.AgentProposalCreateWorkflow:
+		resp.Id, resp.Uuid, err = s.applyCreateWorkflowProposal(c, proposal)
+	case model.AgentProposalUpdateWorkflow:
+		resp.Id, resp.Uuid, err = s.applyUpdateWorkflowProposal(c, proposal)
+	default:
+		err = fmt.Errorf("unknown proposal kind %s", proposal.Kind)
+	}
+	if err != nil {
+		var invalid *invalidProposalError
+		if errors.As(err, &invalid) {
+			s.writeError(c, http.StatusBadRequest, invalid.err)
+		} else if errors.Is(err, errProposalTarget) {
+			s.writeError(c, http.StatusNotFound, err)
+		} else {
+			s.writeError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleRejectAgentProposal 拒绝助手提议
+// @Summary 拒绝助手提议
+// @Description 拒绝并删除助手提议，不做任何变更
+// @Tags 对话
+// @Produce json
+// @Param proposal_uuid path string true "提议uuid"
+// @Success 200 "拒绝成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "提议不存在或已过期"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/agent/proposal/{proposal_uuid} [delete]
+func (s *Server) handleRejectAgentProposal(c *gin.Context) {
+	proposal := c.MustGet(agentProposalKey).(*model.AgentProposal)
+	if _, err := model.TakeAgentProposal(c.Request.Context(), proposal.Uuid); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// invalidProposalError is a proposal that no longer passes the validation,
+// e.g. its camera was deleted after it was made.
+type invalidProposalError struct {
+	err error
+}
+
+func (e *invalidProposalError) Error() string {
+	return e.err.Error()
+}
+
+func (s *Server) applyCreateJobProposal(c *gin.Context, proposal *model.AgentProposal) (int, string, error) {
+	var req dao.CreateJobRequest
+	if err := json.Unmarshal(proposal.Request, &req); err != nil {
+		return 0, "", err
+	}
+	// the targets may have changed since the proposal was made
+	if _, err := validateCreateJob(&req, requestOrgId(c)); err != nil {
+		return 0, "", &invalidProposalError{err}
+	}
+
+	job := req.ToModel()
+	job.OrgId = ownerOf(requestOrgId(c))
+	if err := model.AddJob(job); err != nil {
+		return 0, "", err
+	}
+	s.recordJobAction(c, job, model.JobActionCreate)
+	return job.Id, job.Uuid, nil
+}
+
+func (s *Server) applyUpdateJobProposal(c *gin.Context, proposal *model.AgentProposal) (int, string, error) {
+	var req dao.UpdateJobRequest
+	if err := json.Unmarshal(proposal.Request, &req); err != nil {
+		return 0, "", err
+	}
+	job, err := model.GetJobById(proposal.TargetId)
+	if err != nil {
+		return 0, "", err
+	} else if job == nil || !inRequestOrg(c, job.OrgId) {
+		return 0, "", errProposalTarget
+	}
+	if err := validateUpdateJob(job, &req); err != nil {
+		return 0, "", &invalidProposalError{err}
+	}
+
+	oldGroupId := job.DeviceGroupId
+	req.UpdateModel(job)
+	if err := model.UpdateJob(job); err != nil {
+		return 0, "", err
+	}
+	if job.DeviceGroupId != oldGroupId {
+		if err := model.DeleteJobDeviceStatus(job.Id); err != nil {
+			return 0, "", err
+		}
+	}
+	return job.Id, job.Uuid, nil
+}
+
+func (s *Server) applyCreateWorkflowProposal(c *gin.Context, proposal *model.AgentProposal) (int, string, error) {
+	var req dao.CreateWorkflowRequest
+	if err := json.Unmarshal(proposal.Request, &req); err != nil {
+		return 0, "", err
+	}
+	workflow := req.ToModel()
+	workflow.OrgId = ownerOf(requestOrgId(c))
+	if err := model.CreateWorkflow(workflow); err != nil {
+		return 0, "", err
+	}
+	return workflow.Id, workflow.Uuid, nil
+}
+
+func (s *Server) applyUpdateWorkflowProposal(c *gin.Context, proposal *model.AgentProposal) (int, string, error) {
+	var req dao.UpdateWorkflowRequest
+	if err := json.Unmarshal(proposal.Request, &req); err != nil {
+		return 0, "", err
+	}
+	workflow, err := model.GetWorkflowById(proposal.TargetId)
+	if err != nil {
+		return 0, "", err
+	} else if workflow == nil || !inRequestOrg(c, workflow.OrgId) {
+		return 0, "", errProposalTarget
+	}
+	req.UpdateModel(workflow)
+	if err := model.UpdateWorkflow(workflow); err != nil {
+		return 0, "", err
+	}
+	return workflow.Id, workflow.Uuid, nil
+}
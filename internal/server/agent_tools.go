@@ -0,0 +1,336 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+
+	"lumina/internal/agent"
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const agentListLimit = 50
+
+// agentProposalHint tells the assistant what to do with a proposal.
+const agentProposalHint = "Nothing is changed yet. Show the summary to the user and ask them to approve " +
+	"or reject the proposal in the dashboard, do not propose it again unless they ask for changes."
+
+// luminaAgentTools let the assistant look up the cameras, devices and
+// workflows, and propose jobs and workflows that are only applied once the
+// user approves them.
+var luminaAgentTools = []*agent.Tool{
+	agent.NewTool(
+		agent.WithToolName("list_cameras"),
+		agent.WithToolDescription("List the cameras with their ids, optionally those whose name contains the given text or having the given tag"),
+		agent.WithToolAccess(agent.ToolAccessUser),
+		agent.WithToolParamsSchema[listCamerasParams](),
+		agent.WithToolFunc(listCamerasTool),
+	),
+	agent.NewTool(
+		agent.WithToolName("list_devices"),
+		agent.WithToolDescription("List the edge devices running the jobs, with their ids and states"),
+		agent.WithToolAccess(agent.ToolAccessUser),
+		agent.WithToolParamsSchema[listDevicesParams](),
+		agent.WithToolFunc(listDevicesTool),
+	),
+	agent.NewTool(
+		agent.WithToolName("list_workflows"),
+		agent.WithToolDescription("List the workflows analysing the job messages with a vision language model, with their ids and queries"),
+		agent.WithToolAccess(agent.ToolAccessUser),
+		agent.WithToolParamsSchema[listWorkflowsParams](),
+		agent.WithToolFunc(listWorkflowsTool),
+	),
+	agent.NewTool(
+		agent.WithToolName("propose_create_job"),
+		agent.WithToolDescription("Propose to create a job on a camera, e.g. a detect job alerting on trucks. "+
+			"Look up the ids of the camera, the device and the workflow first. The job is only created once the user approves the proposal"),
+		agent.WithToolAccess(agent.ToolAccessUser),
+		agent.WithToolParamsSchema[dao.CreateJobRequest](),
+		agent.WithToolFunc(proposeCreateJobTool),
+	),
+	agent.NewTool(
+		agent.WithToolName("propose_update_job"),
+		agent.WithToolDescription("Propose to update a job, only the given fields change. The job is only updated once the user approves the proposal"),
+		agent.WithToolAccess(agent.ToolAccessUser),
+		agent.WithToolParamsSchema[proposeUpdateJobParams](),
+		agent.WithToolFunc(proposeUpdateJobTool),
+	),
+	agent.NewTool(
+		agent.WithToolName("propose_create_workflow"),
+		agent.WithToolDescription("Propose to create a workflow asking a vision language model the query about each message. "+
+			"The workflow is only created once the user approves the proposal"),
+		agent.WithToolAccess(agent.ToolAccessUser),
+		agent.WithToolParamsSchema[dao.CreateWorkflowRequest](),
+		agent.WithToolFunc(proposeCreateWorkflowTool),
+	),
+	agent.NewTool(
+		agent.WithToolName("propose_update_workflow"),
+		agent.WithToolDescription("Propose to update a workflow, only the given fields change. The workflow is only updated once the user approves the proposal"),
+		agent.WithToolAccess(agent.ToolAccessUser),
+		agent.WithToolParamsSchema[proposeUpdateWorkflowParams](),
+		agent.WithToolFunc(proposeUpdateWorkflowTool),
+	),
+}
+
+type listCamerasParams struct {
+	Name string `json:"name,omitempty" jsonschema:"description=Text the camera names contain"`
+	Tag  string `json:"tag,omitempty" jsonschema:"description=Tag of the cameras, e.g. a building"`
+}
+
+type cameraBrief struct {
+	Id           int      `json:"id"`
+	Name         string   `json:"name"`
+	Tags         []string `json:"tags,omitempty"`
+	BindDeviceId int      `json:"bindDeviceId,omitempty"`
+}
+
+type listCamerasResult struct {
+	agent.BaseToolResult
+	Cameras []cameraBrief `json:"cameras"`
+	Total   int64         `json:"total"`
+}
+
+// principalOrgId returns the organization the tools of the user are scoped
+// to.
+func principalOrgId(ctx context.Context) int {
+	if p := agent.PrincipalFromContext(ctx); p != nil {
+		return p.OrgId
+	}
+	return model.NoOrg
+}
+
+func listCamerasTool(ctx context.Context, id string, params *listCamerasParams) (*listCamerasResult, error) {
+	filter := model.CameraFilter{OrgId: principalOrgId(ctx), Name: params.Name, Tag: params.Tag}
+	cameras, total, err := model.ListCameras(filter, 0, agentListLimit)
+	if err != nil {
+		return nil, err
+	}
+	res := &listCamerasResult{BaseToolResult: agent.BaseToolResult{Id: id}, Total: total}
+	for _, c := range cameras {
+		res.Cameras = append(res.Cameras, cameraBrief{Id: c.Id, Name: c.Name, Tags: c.Tags, BindDeviceId: c.BindDeviceId})
+	}
+	return res, nil
+}
+
+type listDevicesParams struct{}
+
+type deviceBrief struct {
+	Id    int               `json:"id"`
+	Name  string            `json:"name"`
+	State model.DeviceState `json:"state"`
+}
+
+type listDevicesResult struct {
+	agent.BaseToolResult
+	Devices []deviceBrief `json:"devices"`
+	Total   int64         `json:"total"`
+}
+
+func listDevicesTool(ctx context.Context, id string, params *listDevicesParams) (*listDevicesResult, error) {
+	devices, total, err := model.ListDevices(model.DeviceFilter{OrgId: principalOrgId(ctx)}, 0, agentListLimit)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	res := &listDevicesResult{BaseToolResult: agent.BaseToolResult{Id: id}, Total: total}
+	for _, d := range devices {
+		res.Devices = append(res.Devices, deviceBrief{Id: d.Id, Name: d.Name, State: d.State(now)})
+	}
+	return res, nil
+}
+
+type listWorkflowsParams struct{}
+
+type workflowBrief struct {
+	Id        int    `json:"id"`
+	Name      string `json:"name"`
+	ModelName string `json:"modelName"`
+	Query     string `json:"query,omitempty"`
+}
+
+type listWorkflowsResult struct {
+	agent.BaseToolResult
+	Workflows []workflowBrief `json:"workflows"`
+	Total     int64           `json:"total"`
+}
+
+func listWorkflowsTool(ctx context.Context, id string, params *listWorkflowsParams) (*listWorkflowsResult, error) {
+	workflows, total, err := model.ListWorkflows(principalOrgId(ctx), 0, agentListLimit)
+	if err != nil {
+		return nil, err
+	}
+	res := &listWorkflowsResult{BaseToolResult: agent.BaseToolResult{Id: id}, Total: total}
+	for _, w := range workflows {
+		res.Workflows = append(res.Workflows, workflowBrief{Id: w.Id, Name: w.Name, ModelName: w.ModelName, Query: w.Query})
+	}
+	return res, nil
+}
+
+type proposeUpdateJobParams struct {
+	JobId  int                  `json:"jobId" jsonschema:"description=Id of the job"`
+	Update dao.UpdateJobRequest `json:"update" jsonschema:"description=Fields of the job to change"`
+}
+
+type proposeUpdateWorkflowParams struct {
+	WorkflowId int                       `json:"workflowId" jsonschema:"description=Id of the workflow"`
+	Update     dao.UpdateWorkflowRequest `json:"update" jsonschema:"description=Fields of the workflow to change"`
+}
+
+type proposalResult struct {
+	agent.BaseToolResult
+	ProposalUuid string `json:"proposalUuid"`
+	Summary      string `json:"summary"`
+	Hint         string `json:"hint"`
+}
+
+func proposeCreateJobTool(ctx context.Context, id string, req *dao.CreateJobRequest) (*proposalResult, error) {
+	summary, err := validateCreateJob(req, principalOrgId(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return saveAgentProposal(ctx, id, model.AgentProposalCreateJob, 0, req, summary)
+}
+
+func proposeUpdateJobTool(ctx context.Context, id string, params *proposeUpdateJobParams) (*proposalResult, error) {
+	job, err := model.GetJobById(params.JobId)
+	if err != nil {
+		return nil, err
+	} else if job == nil || !inOrg(principalOrgId(ctx), job.OrgId) {
+		return nil, fmt.Errorf("job %d not found", params.JobId)
+	}
+	if err := validateUpdateJob(job, &params.Update); err != nil {
+		return nil, err
+	}
+	return saveAgentProposal(ctx, id, model.AgentProposalUpdateJob, job.Id, &params.Update,
+		fmt.Sprintf("Update job %d (%s) on camera %d", job.Id, job.Uuid, job.CameraId))
+}
+
+func proposeCreateWorkflowTool(ctx context.Context, id string, req *dao.CreateWorkflowRequest) (*proposalResult, error) {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+	return saveAgentProposal(ctx, id, model.AgentProposalCreateWorkflow, 0, req,
+		fmt.Sprintf("Create workflow %q asking model %s: %s", req.Name, req.ModelName, req.Query))
+}
+
+func proposeUpdateWorkflowTool(ctx context.Context, id string, params *proposeUpdateWorkflowParams) (*proposalResult, error) {
+	wf, err := model.GetWorkflowById(params.WorkflowId)
+	if err != nil {
+		return nil, err
+	} else if wf == nil || !inOrg(principalOrgId(ctx), wf.OrgId) {
+		return nil, fmt.Errorf("workflow %d not found", params.WorkflowId)
+	}
+	return saveAgentProposal(ctx, id, model.AgentProposalUpdateWorkflow, wf.Id, &params.Update,
+		fmt.Sprintf("Update workflow %d (%s)", wf.Id, wf.Name))
+}
+
+// saveAgentProposal saves the request for the approval of the user the
+// agent runs for.
+func saveAgentProposal(ctx context.Context, id string, kind model.AgentProposalKind, targetId int, req any, summary string) (*proposalResult, error) {
+	p := agent.PrincipalFromContext(ctx)
+	if p == nil {
+		return nil, agent.ErrUnauthenticated
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	proposal := &model.AgentProposal{
+		Uuid:       strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Kind:       kind,
+		TargetId:   targetId,
+		Request:    data,
+		Summary:    summary,
+		UserId:     p.UserId,
+		CreateTime: time.Now(),
+	}
+	if err := model.SaveAgentProposal(ctx, proposal); err != nil {
+		return nil, err
+	}
+	return &proposalResult{
+		BaseToolResult: agent.BaseToolResult{Id: id},
+		ProposalUuid:   proposal.Uuid,
+		Summary:        summary,
+		Hint:           agentProposalHint,
+	}, nil
+}
+
+// validateCreateJob checks the request, made in the scope of orgId, as the
+// job API does, and returns a summary of the job.
+func validateCreateJob(req *dao.CreateJobRequest, orgId int) (string, error) {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return "", err
+	}
+	if req.Schedule != nil {
+		if err := req.Schedule.Validate(); err != nil {
+			return "", err
+		}
+	}
+	job := req.ToModel()
+	job.OrgId = ownerOf(orgId)
+	if err := checkJobTarget(job); err != nil {
+		return "", err
+	}
+
+	camera, err := model.GetCameraById(req.CameraId)
+	if err != nil {
+		return "", err
+	} else if camera == nil {
+		return "", fmt.Errorf("camera %d not found", req.CameraId)
+	}
+	summary := fmt.Sprintf("Create a %s job on camera %d (%s)", req.Kind, camera.Id, camera.Name)
+	if req.Detect != nil && req.Detect.Labels != "" {
+		summary += fmt.Sprintf(" detecting %s", req.Detect.Labels)
+	}
+	if req.WorkflowId != 0 {
+		wf, err := model.GetWorkflowById(req.WorkflowId)
+		if err != nil {
+			return "", err
+		} else if wf == nil {
+			return "", fmt.Errorf("workflow %d not found", req.WorkflowId)
+		}
+		summary += fmt.Sprintf(", analysed by workflow %d (%s)", wf.Id, wf.Name)
+	}
+	return summary, nil
+}
+
+// validateUpdateJob checks the request against a copy of the job.
+func validateUpdateJob(job *model.Job, req *dao.UpdateJobRequest) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return err
+	}
+	if req.Schedule != nil {
+		if err := req.Schedule.Validate(); err != nil {
+			return err
+		}
+	}
+	updated := *job
+	req.UpdateModel(&updated)
+	if err := checkJobTarget(&updated); err != nil {
+		return err
+	}
+	if req.CameraId != nil {
+		camera, err := model.GetCameraById(*req.CameraId)
+		if err != nil {
+			return err
+		} else if camera == nil {
+			return fmt.Errorf("camera %d not found", *req.CameraId)
+		}
+	}
+	if req.WorkflowId != nil && *req.WorkflowId != 0 {
+		wf, err := model.GetWorkflowById(*req.WorkflowId)
+		if err != nil {
+			return err
+		} else if wf == nil {
+			return errors.New("workflow not found")
+		}
+	}
+	return nil
+}
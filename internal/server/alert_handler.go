@@ -0,0 +1,357 @@
+package server
+
+import (
+	goerrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const alertKey = "alert"
+
+// SetAlertToContext sets the alert of the message in the context, it must
+// follow SetMessageToContext.
+func SetAlertToContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		message := c.MustGet(messageKey).(*model.Message)
+
+		alert, err := model.GetAlertMessageByMessageId(message.Id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		} else if alert == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "message did not alert",
+			})
+			return
+		}
+		c.Set(alertKey, alert)
+		c.Next()
+	}
+}
+
+// handleGetAlert 获取告警处理状态
+// @Summary 获取告警处理状态
+// @Description 获取消息产生的告警的级别、处理状态和处理记录
+// @Tags 消息
+// @Produce json
+// @Param message_id path string true "消息message_id"
+// @Success 200 {object} dao.AlertSpec "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "消息不存在或未产生告警"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/{message_id}/alert [get]
+func (s *Server) handleGetAlert(c *gin.Context) {
+	alert := c.MustGet(alertKey).(*model.AlertMessage)
+	s.writeAlert(c, alert)
+}
+
+// writeAlert writes the state of the alert with its activities.
+func (s *Server) writeAlert(c *gin.Context, alert *model.AlertMessage) {
+	activities, err := model.ListAlertActivities(alert.Id)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	spec := dao.FromAlertModel(alert)
+	spec.Activities = make([]dao.AlertActivitySpec, len(activities))
+	for i := range activities {
+		spec.Activities[i] = dao.FromAlertActivityModel(&activities[i])
+	}
+	c.JSON(http.StatusOK, spec)
+}
+
+// handleAckAlert 确认告警
+// @Summary 确认告警
+// @Description 确认消息产生的告警，告警升级策略不再通知后续的通道，重复确认时保留第一次确认
+// @Tags 消息
+// @Produce json
+// @Param message_id path string true "消息message_id"
+// @Success 200 {object} dao.AlertSpec "确认成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "消息不存在或未产生告警"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/{message_id}/ack [put]
+func (s *Server) handleAckAlert(c *gin.Context) {
+	alert := c.MustGet(alertKey).(*model.AlertMessage)
+	user := c.MustGet(userKey).(*model.User)
+
+	if err := model.AckAlertMessage(alert, user, time.Now()); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeAlert(c, alert)
+}
+
+// handleAssignAlert 指派告警
+// @Summary 指派告警
+// @Description 指派负责处理告警的用户，assigneeId 为 0 时取消指派
+// @Tags 消息
+// @Accept json
+// @Produce json
+// @Param message_id path string true "消息message_id"
+// @Param req body dao.AssignAlertRequest true "指派的用户"
+// @Success 200 {object} dao.AlertSpec "指派成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误或用户不存在"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "消息不存在或未产生告警"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/{message_id}/assign [put]
+func (s *Server) handleAssignAlert(c *gin.Context) {
+	alert := c.MustGet(alertKey).(*model.AlertMessage)
+	user := c.MustGet(userKey).(*model.User)
+
+	var req dao.AssignAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.AssigneeId != 0 {
+		assignee, err := model.GetUserById(req.AssigneeId)
+		if err != nil {
+			if goerrors.Is(err, gorm.ErrRecordNotFound) {
+				s.writeError(c, http.StatusBadRequest, fmt.Errorf("user %d not found", req.AssigneeId))
+				return
+			}
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		}
+		if !inRequestOrg(c, assignee.OrgId) {
+			s.writeError(c, http.StatusBadRequest, fmt.Errorf("user %d not found", req.AssigneeId))
+			return
+		}
+	}
+
+	if err := model.AssignAlertMessage(alert, user, req.AssigneeId); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeAlert(c, alert)
+}
+
+// handleCommentAlert 评论告警
+// @Summary 评论告警
+// @Description 在告警的处理记录中添加评论
+// @Tags 消息
+// @Accept json
+// @Produce json
+// @Param message_id path string true "消息message_id"
+// @Param req body dao.CommentAlertRequest true "评论内容"
+// @Success 200 {object} dao.AlertSpec "评论成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "消息不存在或未产生告警"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/{message_id}/comment [post]
+func (s *Server) handleCommentAlert(c *gin.Context) {
+	alert := c.MustGet(alertKey).(*model.AlertMessage)
+	user := c.MustGet(userKey).(*model.User)
+
+	var req dao.CommentAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := model.CommentAlertMessage(alert, user, req.Content); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeAlert(c, alert)
+}
+
+// handleResolveAlert 解决告警
+// @Summary 解决告警
+// @Description 将告警标记为已解决，未确认的告警同时被确认，重复解决时保留第一次解决
+// @Tags 消息
+// @Accept json
+// @Produce json
+// @Param message_id path string true "消息message_id"
+// @Param req body dao.ResolveAlertRequest false "解决说明"
+// @Success 200 {object} dao.AlertSpec "解决成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "消息不存在或未产生告警"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/{message_id}/resolve [put]
+func (s *Server) handleResolveAlert(c *gin.Context) {
+	alert := c.MustGet(alertKey).(*model.AlertMessage)
+	user := c.MustGet(userKey).(*model.User)
+
+	var req dao.ResolveAlertRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			s.writeError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := model.ResolveAlertMessage(alert, user, time.Now(), req.Comment); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeAlert(c, alert)
+}
+
+// handleAlertStats 告警处理统计
+// @Summary 获取告警处理统计
+// @Description 统计时间范围内产生的告警的处理状态、平均确认和解决耗时，以及在 SLA 内确认的告警数
+// @Tags 消息
+// @Produce json
+// @Param start query string false "开始时间(RFC3339 或 Unix 时间戳)，默认 24 小时前"
+// @Param end query string false "结束时间(RFC3339 或 Unix 时间戳)，默认当前时间"
+// @Param jobId query int false "任务ID"
+// @Param slaSeconds query int false "确认告警的 SLA，单位秒" default(300)
+// @Success 200 {object} dao.AlertStatsResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/stats/alerts [get]
+func (s *Server) handleAlertStats(c *gin.Context) {
+	var req dao.AlertStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.SlaSeconds == 0 {
+		req.SlaSeconds = 300
+	}
+
+	start, end, _, err := parseStatsRange(req.Start, req.End, "")
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	stats, err := model.GetAlertStats(requestOrgId(c), start, end, req.JobId, req.SlaSeconds)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.AlertStatsResponse{
+		Start:             start.UTC().Format(time.RFC3339),
+		End:               end.UTC().Format(time.RFC3339),
+		Open:              stats.Open,
+		Acknowledged:      stats.Acknowledged,
+		Resolved:          stats.Resolved,
+		AvgAckSeconds:     stats.AvgAck,
+		AvgResolveSeconds: stats.AvgResolve,
+		SlaSeconds:        req.SlaSeconds,
+		AckedWithinSla:    stats.AckedInSla,
+	})
+}
+
+const (
+	minGalleryBucket = time.Minute
+	maxGalleryBucket = 24 * time.Hour
+	// maxGalleryBuckets bounds the buckets a range is split into
+	maxGalleryBuckets = 2000
+)
+
+// handleAlertGallery 告警图库
+// @Summary 获取告警图库
+// @Description 将时间范围内产生的告警按时间桶和摄像头分组，返回每组的告警数量和最新告警图片，用于快速浏览排查
+// @Tags 消息
+// @Produce json
+// @Param start query string false "开始时间(RFC3339 或 Unix 时间戳)，默认 24 小时前"
+// @Param end query string false "结束时间(RFC3339 或 Unix 时间戳)，默认当前时间"
+// @Param bucket query string false "时间桶大小，如 10m、1h，最小 1m，最大 24h" default(1h)
+// @Param jobId query int false "任务ID"
+// @Param cameraId query int false "摄像头ID"
+// @Param status query string false "告警处理状态：open、acknowledged、resolved"
+// @Param thumbnails query int false "每组返回的最新图片数量，最大 10" default(4)
+// @Success 200 {object} dao.AlertGalleryResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Failure 501 {object} ErrorResponse "数据库不支持，如 SQLite"
+// @Router /api/v1/alerts/gallery [get]
+func (s *Server) handleAlertGallery(c *gin.Context) {
+	var req dao.AlertGalleryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Bucket == "" {
+		req.Bucket = "1h"
+	}
+	if req.Thumbnails == 0 {
+		req.Thumbnails = 4
+	}
+
+	bucket, err := time.ParseDuration(req.Bucket)
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("invalid bucket: %w", err))
+		return
+	} else if bucket < minGalleryBucket || bucket > maxGalleryBucket || bucket%time.Second != 0 {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("bucket must be whole seconds between %s and %s", minGalleryBucket, maxGalleryBucket))
+		return
+	}
+	start, end, _, err := parseStatsRange(req.Start, req.End, "")
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if end.Sub(start)/bucket > maxGalleryBuckets {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("too many buckets, at most %d", maxGalleryBuckets))
+		return
+	}
+
+	groups, err := model.GetAlertGallery(model.AlertGalleryQuery{
+		OrgId:      requestOrgId(c),
+		Start:      start,
+		End:        end,
+		Bucket:     bucket,
+		JobId:      req.JobId,
+		CameraId:   req.CameraId,
+		Status:     req.Status,
+		Thumbnails: req.Thumbnails,
+	})
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := dao.AlertGalleryResponse{
+		Start:   start.UTC().Format(time.RFC3339),
+		End:     end.UTC().Format(time.RFC3339),
+		Bucket:  req.Bucket,
+		Buckets: []dao.AlertGalleryBucket{},
+	}
+	// the groups are ordered by bucket
+	for i, g := range groups {
+		if i == 0 || g.Bucket != groups[i-1].Bucket {
+			resp.Buckets = append(resp.Buckets, dao.AlertGalleryBucket{
+				Start: start.Add(time.Duration(g.Bucket) * bucket).UTC().Format(time.RFC3339),
+			})
+		}
+		b := &resp.Buckets[len(resp.Buckets)-1]
+		b.Count += g.Count
+
+		camera := dao.AlertGalleryCamera{
+			CameraId:    g.CameraId,
+			CameraName:  g.CameraName,
+			Count:       g.Count,
+			Occurrences: g.Occurrences,
+			Thumbnails:  make([]dao.AlertThumbnailSpec, len(g.Thumbnails)),
+		}
+		for j, t := range g.Thumbnails {
+			camera.Thumbnails[j] = dao.AlertThumbnailSpec{
+				MessageId: t.MessageId,
+				ImageUrl:  s.mediaUrls.Url(t.ImagePath),
+			}
+		}
+		b.Cameras = append(b.Cameras, camera)
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const (
+	alertHubPollInterval = 2 * time.Second
+	alertHubBatchSize    = 100
+	alertSubscriberQueue = 64
+)
+
+type alertSubscriber struct {
+	filter dao.AlertEventFilter
+	ch     chan *dao.AlertEvent
+}
+
+// AlertHub polls newly created alert messages and fans them out to the
+// subscribed dashboard sessions.
+type AlertHub struct {
+	mu          sync.RWMutex
+	subscribers map[*alertSubscriber]struct{}
+	logger      *logrus.Entry
+	visitPrefix string
+}
+
+func NewAlertHub(logger *logrus.Entry, visitPrefix string) *AlertHub {
+	return &AlertHub{
+		subscribers: make(map[*alertSubscriber]struct{}),
+		logger:      logger.WithField("component", "alertHub"),
+		visitPrefix: visitPrefix,
+	}
+}
+
+func (h *AlertHub) subscribe(filter dao.AlertEventFilter) *alertSubscriber {
+	sub := &alertSubscriber{
+		filter: filter,
+		ch:     make(chan *dao.AlertEvent, alertSubscriberQueue),
+	}
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *AlertHub) unsubscribe(sub *alertSubscriber) {
+	h.mu.Lock()
+	delete(h.subscribers, sub)
+	h.mu.Unlock()
+}
+
+func (h *AlertHub) Run(ctx context.Context) {
+	// the alerts are pushed from the latest one at startup, the cursor is
+	// read again on the next polls until it can be, rather than pushing the
+	// whole history
+	lastId, err := model.GetLatestAlertMessageId()
+	started := err == nil
+	if err != nil {
+		h.logger.WithError(err).Error("get latest alert id failed, retrying")
+	}
+
+	ticker := time.NewTicker(alertHubPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !started {
+			if lastId, err = model.GetLatestAlertMessageId(); err != nil {
+				h.logger.WithError(err).Error("get latest alert id failed, retrying")
+				continue
+			}
+			started = true
+		}
+
+		alerts, err := model.GetAlertMessagesAfterId(lastId, alertHubBatchSize)
+		if err != nil {
+			h.logger.WithError(err).Error("poll alert messages failed")
+			continue
+		}
+		for _, alert := range alerts {
+			lastId = alert.Id
+			event, err := h.toAlertEvent(alert)
+			if err != nil {
+				h.logger.WithError(err).Errorf("build alert event %d failed", alert.Id)
+				continue
+			}
+			h.broadcast(event)
+		}
+	}
+}
+
+func (h *AlertHub) broadcast(event *dao.AlertEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subscribers {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			h.logger.Warnf("alert subscriber queue is full, drop alert %d", event.AlertId)
+		}
+	}
+}
+
+func (h *AlertHub) toAlertEvent(alert *model.AlertMessage) (*dao.AlertEvent, error) {
+	msg := dao.FromMessageModel(&alert.Message)
+	if msg.ImagePath != "" {
+		msg.ImagePath = h.visitPrefix + msg.ImagePath
+	}
+	if msg.VideoPath != "" {
+		msg.VideoPath = h.visitPrefix + msg.VideoPath
+	}
+	event := &dao.AlertEvent{
+		AlertId:    alert.Id,
+		Message:    *msg,
+		Severity:   alert.Severity,
+		CreateTime: alert.CreateTime.UTC().Format(time.RFC3339),
+		OrgId:      alert.Message.OrgId,
+	}
+
+	job, err := model.GetJobById(alert.Message.JobId)
+	if err != nil {
+		return nil, err
+	} else if job == nil {
+		return event, nil
+	}
+	event.JobUuid = job.Uuid
+	event.JobKind = job.Kind
+	event.CameraId = job.CameraId
+
+	camera, err := model.GetCameraById(job.CameraId)
+	if err != nil {
+		return nil, err
+	} else if camera != nil {
+		event.CameraName = camera.Name
+	}
+	return event, nil
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	goerrors "errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+	"lumina/pkg/str"
+)
+
+// apiTokenLength is the number of random characters of an API token.
+const apiTokenLength = 40
+
+// apiTokenScopePaths are the routes each resource scope of the API tokens
+// grants every method of.
+var apiTokenScopePaths = map[string][]string{
+	model.ApiTokenScopeJobs:      {"/api/v1/job"},
+	model.ApiTokenScopeCameras:   {"/api/v1/camera"},
+	model.ApiTokenScopeDevices:   {"/api/v1/device", "/api/v1/access-token"},
+	model.ApiTokenScopeWorkflows: {"/api/v1/workflow"},
+	model.ApiTokenScopeMessages: {
+		"/api/v1/message", "/api/v1/stats/alerts", "/api/v1/alerts", "/api/v1/ws/alerts", "/api/v1/events",
+	},
+}
+
+// apiTokenAllows reports whether the scopes of the token grant a request
+// of the method to the route.
+func apiTokenAllows(token *model.ApiToken, method, route string) bool {
+	if slices.Contains(token.Scopes, model.ApiTokenScopeAll) {
+		return true
+	}
+	if slices.Contains(token.Scopes, model.ApiTokenScopeRead) && (method == http.MethodGet || method == http.MethodHead) {
+		return true
+	}
+	for _, scope := range token.Scopes {
+		for _, prefix := range apiTokenScopePaths[scope] {
+			if strings.HasPrefix(route, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkApiToken refuses the requests of the expired API tokens and those
+// out of the scopes of their token, and records the uses of the tokens.
+// The legacy access tokens of the users keep every permission.
+func (s *Server) checkApiToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenStr := requestToken(c)
+		if !strings.HasPrefix(tokenStr, "sk-") {
+			c.Next()
+			return
+		}
+		token, err := model.GetApiTokenByToken(tokenStr)
+		if err != nil {
+			s.logger.WithError(err).Error("get api token failed")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		} else if token == nil {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		if token.Expired(now) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "token expired",
+				"code":  errorCodeTokenExpired,
+			})
+			return
+		}
+		if route := c.FullPath(); route != "" && !apiTokenAllows(token, c.Request.Method, route) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "token scope does not allow this request",
+				"code":  errorCodeInsufficientScope,
+			})
+			return
+		}
+		if err := model.TouchApiToken(token, c.ClientIP(), now); err != nil {
+			s.logger.WithError(err).Warnf("record use of api token %d failed", token.Id)
+		}
+		c.Next()
+	}
+}
+
+// @Summary 列出 API 令牌
+// @Description 列出当前用户创建的 API 令牌，不含令牌本身
+// @Tags 用户管理
+// @Produce json
+// @Success 200 {object} dao.ListApiTokensResponse
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/settings/tokens [get]
+func (s *Server) handleListApiTokens(c *gin.Context) {
+	user := c.MustGet(userKey).(*model.User)
+	tokens, err := model.ListApiTokens(user.Id)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListApiTokensResponse{Items: make([]dao.ApiTokenSpec, 0, len(tokens))}
+	for _, t := range tokens {
+		resp.Items = append(resp.Items, dao.ToApiTokenSpec(t))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary 创建 API 令牌
+// @Description 为当前用户创建限定授权范围和过期时间的 API 令牌，令牌只在创建时返回一次
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body dao.CreateApiTokenRequest true "令牌名称、授权范围和过期时间"
+// @Success 200 {object} dao.CreateApiTokenResponse
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/settings/tokens [post]
+func (s *Server) handleCreateApiToken(c *gin.Context) {
+	var req dao.CreateApiTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	user := c.MustGet(userKey).(*model.User)
+
+	tokenStr := "sk-" + str.RandStr(apiTokenLength, str.Alphanumeric)
+	token := &model.ApiToken{
+		UserId:    user.Id,
+		Name:      req.Name,
+		TokenHash: model.HashApiToken(tokenStr),
+		Hint:      tokenStr[:7],
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+	}
+	if req.ExpireTime != "" {
+		expireTime, err := dao.ParseTime(req.ExpireTime)
+		if err != nil {
+			s.writeError(c, http.StatusBadRequest, err)
+			return
+		} else if !expireTime.After(time.Now()) {
+			s.writeError(c, http.StatusBadRequest, goerrors.New("expire time must be in the future"))
+			return
+		}
+		token.ExpireTime = &expireTime
+	}
+	if err := model.CreateApiToken(token); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.CreateApiTokenResponse{
+		ApiTokenSpec: dao.ToApiTokenSpec(token),
+		Token:        tokenStr,
+	})
+}
+
+// @Summary 吊销 API 令牌
+// @Description 吊销当前用户的 API 令牌，吊销后立即失效
+// @Tags 用户管理
+// @Produce json
+// @Param token_id path int true "令牌ID"
+// @Success 200
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "令牌不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/settings/token/{token_id} [delete]
+func (s *Server) handleDeleteApiToken(c *gin.Context) {
+	tokenId, err := strconv.Atoi(c.Param("token_id"))
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	user := c.MustGet(userKey).(*model.User)
+	token, err := model.GetApiToken(tokenId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if token == nil || token.UserId != user.Id {
+		s.writeError(c, http.StatusNotFound, goerrors.New("api token not found"))
+		return
+	}
+	if err := model.DeleteApiToken(token.Id); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/model"
+)
+
+const (
+	// apiConsumerCacheExpire is how long the consumer of a token is cached,
+	// so that the requests of routes without authentication are attributed
+	// without querying the database every time
+	apiConsumerCacheExpire = 5 * time.Minute
+	apiConsumerCacheSize   = 10000
+	apiUsageCleanInterval  = time.Hour
+)
+
+// apiConsumer is who made a request.
+type apiConsumer struct {
+	kind model.ApiConsumerKind
+	name string
+}
+
+type cachedApiConsumer struct {
+	consumer apiConsumer
+	// ok is false for the tokens of nobody
+	ok     bool
+	expire time.Time
+}
+
+type apiUsageKey struct {
+	hour     time.Time
+	consumer apiConsumer
+	method   string
+	endpoint string
+}
+
+// UsageRecorder counts the requests by consumer and endpoint in memory and
+// adds the counts to the database periodically.
+type UsageRecorder struct {
+	conf      ApiUsageConfig
+	jwtSecret string
+	mu        sync.Mutex
+	pending   map[apiUsageKey]*model.ApiUsage
+	consumers map[string]cachedApiConsumer
+	logger    *logrus.Entry
+}
+
+func NewUsageRecorder(logger *logrus.Entry, conf ApiUsageConfig, jwtSecret string) *UsageRecorder {
+	return &UsageRecorder{
+		conf:      conf,
+		jwtSecret: jwtSecret,
+		pending:   make(map[apiUsageKey]*model.ApiUsage),
+		consumers: make(map[string]cachedApiConsumer),
+		logger:    logger.WithField("component", "usageRecorder"),
+	}
+}
+
+// consumer returns who made the request: the user or the device the
+// handlers authenticated, the owner of the token of the request otherwise,
+// and the client IP if there is none.
+func (r *UsageRecorder) consumer(c *gin.Context) apiConsumer {
+	if u, ok := c.Get(userKey); ok {
+		return apiConsumer{kind: model.ApiConsumerUser, name: u.(*model.User).Username}
+	}
+	if d, ok := c.Get(deviceKey); ok {
+		return apiConsumer{kind: model.ApiConsumerDevice, name: d.(*model.Device).Uuid}
+	}
+	if token := requestToken(c); token != "" {
+		if consumer, ok := r.tokenConsumer(token); ok {
+			return consumer
+		}
+	}
+	return apiConsumer{kind: model.ApiConsumerAnonymous, name: c.ClientIP()}
+}
+
+func (r *UsageRecorder) tokenConsumer(token string) (apiConsumer, bool) {
+	now := time.Now()
+	r.mu.Lock()
+	cached, ok := r.consumers[token]
+	r.mu.Unlock()
+	if ok && now.Before(cached.expire) {
+		return cached.consumer, cached.ok
+	}
+
+	cached = cachedApiConsumer{expire: now.Add(apiConsumerCacheExpire)}
+	switch {
+	case strings.HasPrefix(token, "sk-"):
+		if user, err := model.GetUserByToken(token); err == nil {
+			cached.consumer = apiConsumer{kind: model.ApiConsumerUser, name: user.Username}
+			cached.ok = true
+		}
+	case strings.HasPrefix(token, "device-"):
+		if device, err := model.GetDeviceByToken(token); err == nil && device != nil {
+			cached.consumer = apiConsumer{kind: model.ApiConsumerDevice, name: device.Uuid}
+			cached.ok = true
+		}
+	default:
+		var claims TokenClaims
+		parsed, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(r.jwtSecret), nil
+		})
+		if err == nil && parsed.Valid {
+			if user, err := model.GetUserById(claims.UserId); err == nil {
+				cached.consumer = apiConsumer{kind: model.ApiConsumerUser, name: user.Username}
+				cached.ok = true
+			}
+		}
+	}
+
+	r.mu.Lock()
+	if len(r.consumers) >= apiConsumerCacheSize {
+		r.consumers = make(map[string]cachedApiConsumer)
+	}
+	r.consumers[token] = cached
+	r.mu.Unlock()
+	return cached.consumer, cached.ok
+}
+
+// Add counts a request of the consumer to the endpoint.
+func (r *UsageRecorder) Add(consumer apiConsumer, method, endpoint string, status int, latency time.Duration) {
+	if !r.conf.Enabled {
+		return
+	}
+	now := time.Now()
+	key := apiUsageKey{
+		hour:     now.Truncate(time.Hour),
+		consumer: consumer,
+		method:   method,
+		endpoint: endpoint,
+	}
+	micros := latency.Microseconds()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.pending[key]
+	if !ok {
+		u = &model.ApiUsage{
+			Hour:         key.hour,
+			ConsumerKind: consumer.kind,
+			Consumer:     truncate(consumer.name, 128),
+			Method:       method,
+			Endpoint:     truncate(endpoint, 255),
+		}
+		r.pending[key] = u
+	}
+	u.Requests++
+	if status >= 500 {
+		u.ServerErrors++
+	} else if status >= 400 {
+		u.ClientErrors++
+	}
+	u.TotalLatency += micros
+	u.MaxLatency = max(u.MaxLatency, micros)
+}
+
+func (r *UsageRecorder) Run(ctx context.Context) {
+	if !r.conf.Enabled {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(r.conf.FlushInterval) * time.Second)
+	defer ticker.Stop()
+	clean := time.NewTicker(apiUsageCleanInterval)
+	defer clean.Stop()
+	r.clean()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Flush()
+		case <-clean.C:
+			r.clean()
+		}
+	}
+}
+
+// Flush adds the requests counted since the last flush to the database.
+func (r *UsageRecorder) Flush() {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = make(map[apiUsageKey]*model.ApiUsage)
+	r.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	us := make([]*model.ApiUsage, 0, len(pending))
+	for _, u := range pending {
+		us = append(us, u)
+	}
+	if err := model.AddApiUsage(us); err != nil {
+		r.logger.WithError(err).Errorf("add usage of %d endpoints failed", len(us))
+	}
+}
+
+func (r *UsageRecorder) clean() {
+	if r.conf.RetentionDays <= 0 {
+		return
+	}
+	before := time.Now().AddDate(0, 0, -r.conf.RetentionDays)
+	if err := model.DeleteApiUsageBefore(before); err != nil {
+		r.logger.WithError(err).Error("delete expired usage failed")
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -1,9 +1,12 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -37,7 +40,7 @@ func SetCameraToContext() gin.HandlerFunc {
 				"error": "internal server error",
 			})
 			return
-		} else if camera == nil {
+		} else if camera == nil || !inRequestOrg(c, camera.OrgId) {
 			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 				"error": "camera not found",
 			})
@@ -67,7 +70,16 @@ func (s *Server) handleCreateCamera(c *gin.Context) {
 		return
 	}
 
+	orgId, ok := s.ownerOrgId(c)
+	if !ok {
+		return
+	}
 	cam := req.ToModel()
+	cam.OrgId = orgId
+	if err := checkCameraDevice(cam); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
 	if err := model.CreateCamera(cam); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -125,6 +137,10 @@ func (s *Server) handleUpdateCamera(c *gin.Context) {
 	cam := c.MustGet(cameraKey).(*model.Camera)
 
 	req.UpdateModel(cam)
+	if err := checkCameraDevice(cam); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
 	if err := model.UpdateCamera(cam); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -132,6 +148,93 @@ func (s *Server) handleUpdateCamera(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{})
 }
 
+// checkCameraDevice checks that the camera is not bound to a device of
+// another organization.
+func checkCameraDevice(cam *model.Camera) error {
+	if cam.BindDeviceId == 0 {
+		return nil
+	}
+	device, err := model.GetDeviceById(cam.BindDeviceId)
+	if err != nil {
+		return err
+	} else if device != nil && device.OrgId != cam.OrgId {
+		return fmt.Errorf("device %d not found", cam.BindDeviceId)
+	}
+	return nil
+}
+
+// handleSetCameraCalibration 设置摄像头地面标定
+// @Summary 设置摄像头地面标定
+// @Description 根据至少4组图像像素与地面世界坐标的对应点求解单应矩阵并保存，摄像头上的检测任务会据此把检测框换算为世界坐标并估算速度
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Param camera_id path int true "摄像头ID"
+// @Param req body dao.SetCameraCalibrationRequest true "标定点"
+// @Success 200 {object} dao.CameraCalibration "设置成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误或标定点退化"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "摄像头不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/camera/{camera_id}/calibration [put]
+func (s *Server) handleSetCameraCalibration(c *gin.Context) {
+	var req dao.SetCameraCalibrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	calibration, err := req.ToModel()
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	cam := c.MustGet(cameraKey).(*model.Camera)
+	cam.Calibration = calibration
+	if err := s.updateCameraCalibration(cam); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	spec, err := dao.FromCameraModel(cam)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, spec.Calibration)
+}
+
+// handleDeleteCameraCalibration 删除摄像头地面标定
+// @Summary 删除摄像头地面标定
+// @Description 删除摄像头地面标定
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Param camera_id path int true "摄像头ID"
+// @Success 200 "删除成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "摄像头不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/camera/{camera_id}/calibration [delete]
+func (s *Server) handleDeleteCameraCalibration(c *gin.Context) {
+	cam := c.MustGet(cameraKey).(*model.Camera)
+	cam.Calibration = nil
+	if err := s.updateCameraCalibration(cam); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// updateCameraCalibration saves the calibration and restarts the jobs on the
+// camera so that they use it.
+func (s *Server) updateCameraCalibration(cam *model.Camera) error {
+	if err := model.UpdateCamera(cam); err != nil {
+		return err
+	}
+	return model.TouchJobsByCameraId(cam.Id)
+}
+
 // handleDeleteCamera 删除摄像头
 // @Summary 删除摄像头
 // @Description 删除摄像头
@@ -154,14 +257,37 @@ func (s *Server) handleDeleteCamera(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{})
 }
 
+// handleListCameraTags 列出摄像头标签
+// @Summary 列出摄像头标签
+// @Description 列出所有摄像头使用的标签，用于按标签过滤摄像头
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Success 200 {object} dao.ListCameraTagsResponse "列出成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/camera/tags [get]
+func (s *Server) handleListCameraTags(c *gin.Context) {
+	tags, err := model.ListCameraTags(requestOrgId(c))
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.ListCameraTagsResponse{Tags: tags})
+}
+
 // handleListCameras 列出摄像头
 // @Summary 列出摄像头
-// @Description 列出摄像头
+// @Description 列出摄像头，支持按标签、名称、协议和绑定设备过滤
 // @Tags 摄像头
 // @Accept json
 // @Produce json
 // @Param start query int true "分页起始位置"
 // @Param limit query int true "分页每页数量"
+// @Param tag query string false "标签"
+// @Param name query string false "名称，模糊匹配"
+// @Param protocol query string false "协议，rtsp 或 rtmp"
+// @Param bindDeviceId query int false "绑定设备ID"
 // @Success 200 {object} dao.ListCamerasResponse "列出成功"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权"
@@ -177,7 +303,9 @@ func (s *Server) handleListCameras(c *gin.Context) {
 		req.Limit = 10
 	}
 
-	items, total, err := model.ListCameras(req.Start, req.Limit)
+	filter := req.Filter()
+	filter.OrgId = requestOrgId(c)
+	items, total, err := model.ListCameras(filter, req.Start, req.Limit)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -208,6 +336,51 @@ func genPushAddr(serverIp string, serverPort int, taskUuid string) string {
 	return fmt.Sprintf("rtmp://%s:%d/preview/%s", serverIp, serverPort, taskUuid)
 }
 
+// genHlsAddr 浏览器拉取 HLS 播放列表的地址
+func genHlsAddr(serverIp string, serverPort int, taskUuid string) string {
+	return fmt.Sprintf("http://%s:%d/preview/%s/hls.m3u8", serverIp, serverPort, taskUuid)
+}
+
+// genWhepAddr 浏览器通过 WHEP 拉取 WebRTC 流的地址
+func genWhepAddr(serverIp string, serverPort int, taskUuid string) string {
+	return fmt.Sprintf("http://%s:%d/index/api/whep?app=preview&stream=%s", serverIp, serverPort, taskUuid)
+}
+
+// genWhipAddr 设备通过 WHIP 推送 WebRTC 流的地址
+func genWhipAddr(serverIp string, serverPort int, taskUuid string) string {
+	return fmt.Sprintf("http://%s:%d/index/api/whip?app=preview&stream=%s", serverIp, serverPort, taskUuid)
+}
+
+func (s *Server) previewAddrs(mode model.PreviewMode, taskUuid string) (pushAddr, previewAddr string) {
+	ms := s.conf.MediaServer
+	switch mode {
+	case model.PreviewModeWebRTC:
+		return genWhipAddr(ms.Ip, ms.HttpPort, taskUuid), genWhepAddr(ms.Ip, ms.HttpPort, taskUuid)
+	case model.PreviewModeHls:
+		return genPushAddr(ms.Ip, ms.RtmpPort, taskUuid), s.hlsAddr(taskUuid)
+	default:
+		return genPushAddr(ms.Ip, ms.RtmpPort, taskUuid), genPreviewAddr(ms.Ip, ms.HttpPort, taskUuid)
+	}
+}
+
+func (s *Server) hlsAddr(taskUuid string) string {
+	port := s.conf.MediaServer.HlsPort
+	if port == 0 {
+		port = s.conf.MediaServer.HttpPort
+	}
+	return genHlsAddr(s.conf.MediaServer.Ip, port, taskUuid)
+}
+
+// newPreviewTaskResponse 填充播放地址，RTMP 推流的任务同时返回 HLS 地址
+func (s *Server) newPreviewTaskResponse(task *model.PreviewTask, previewAddr string) *dao.PreviewTask {
+	resp := dao.FromPreviewTaskModel(task)
+	resp.PreviewAddr = previewAddr
+	if task.Mode != model.PreviewModeWebRTC {
+		resp.HlsAddr = s.hlsAddr(task.TaskUuid)
+	}
+	return resp
+}
+
 // handleStartCameraPreview 开始摄像头预览
 // @Summary 开始摄像头预览
 // @Description 开始摄像头预览
@@ -215,6 +388,8 @@ func genPushAddr(serverIp string, serverPort int, taskUuid string) string {
 // @Accept json
 // @Produce json
 // @Param camera_id path int true "摄像头ID"
+// @Param mode query string false "预览方式，flv、webrtc 或 hls，默认 flv"
+// @Param audio query bool false "是否包含音频，默认使用摄像头设置"
 // @Success 200 {object} dao.PreviewTask "预览任务"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权"
@@ -223,7 +398,20 @@ func genPushAddr(serverIp string, serverPort int, taskUuid string) string {
 // @Failure 500 {object} ErrorResponse "内部服务器错误"
 // @Router /api/v1/camera/{camera_id}/preview [post]
 func (s *Server) handleStartCameraPreview(c *gin.Context) {
+	var req dao.StartPreviewRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = model.PreviewModeFlv
+	}
+
 	cam := c.MustGet(cameraKey).(*model.Camera)
+	audio := cam.PreviewAudio
+	if req.Audio != nil {
+		audio = *req.Audio
+	}
 	camSpec, err := dao.FromCameraModel(cam)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
@@ -240,23 +428,28 @@ func (s *Server) handleStartCameraPreview(c *gin.Context) {
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
-	} else if task != nil {
-		previewAddr := genPreviewAddr(s.conf.MediaServer.Ip, s.conf.MediaServer.HttpPort, task.TaskUuid)
+	} else if task != nil && task.Mode == "" {
+		task.Mode = model.PreviewModeFlv
+	}
+	// a running task in another mode or with another audio setting is
+	// replaced, the device restarts the push
+	if task != nil && task.Mode == req.Mode && task.Audio == audio {
+		_, previewAddr := s.previewAddrs(task.Mode, task.TaskUuid)
 		task.ExpireTime = time.Now().Add(15 * time.Minute)
 		if err := model.AddPreviewTask(c, device.Uuid, cam.Uuid, task); err != nil {
 			s.writeError(c, http.StatusInternalServerError, err)
 			return
 		}
-		resp := dao.FromPreviewTaskModel(task)
-		resp.PreviewAddr = previewAddr
-		c.JSON(http.StatusOK, resp)
+		c.JSON(http.StatusOK, s.newPreviewTaskResponse(task, previewAddr))
 		return
 	}
 
 	taskUuid := uuid.New().String()
-	pushAddr := genPushAddr(s.conf.MediaServer.Ip, s.conf.MediaServer.RtmpPort, taskUuid)
+	pushAddr, previewAddr := s.previewAddrs(req.Mode, taskUuid)
 	task = &model.PreviewTask{
 		TaskUuid:   taskUuid,
+		Mode:       req.Mode,
+		Audio:      audio,
 		ExpireTime: time.Now().Add(15 * time.Minute),
 		PullAddr:   camSpec.Url(),
 		PushAddr:   pushAddr,
@@ -267,10 +460,7 @@ func (s *Server) handleStartCameraPreview(c *gin.Context) {
 		return
 	}
 
-	previewAddr := genPreviewAddr(s.conf.MediaServer.Ip, s.conf.MediaServer.HttpPort, taskUuid)
-	resp := dao.FromPreviewTaskModel(task)
-	resp.PreviewAddr = previewAddr
-	c.JSON(http.StatusOK, resp)
+	c.JSON(http.StatusOK, s.newPreviewTaskResponse(task, previewAddr))
 }
 
 // handleTouchCameraPreview 刷新摄像头预览任务过期时间
@@ -301,3 +491,334 @@ func (s *Server) handleTouchCameraPreview(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{})
 }
+
+// handleStopCameraPreview 停止摄像头预览
+// @Summary 停止摄像头预览
+// @Description 立即删除摄像头预览任务，设备在下次同步时停止推流
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Param camera_id path int true "摄像头ID"
+// @Success 200 "停止成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "摄像头不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/camera/{camera_id}/preview [delete]
+func (s *Server) handleStopCameraPreview(c *gin.Context) {
+	cam := c.MustGet(cameraKey).(*model.Camera)
+	device, err := cam.BindDevice()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if device == nil {
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+
+	if err := model.DeletePreviewTask(c, device.Uuid, cam.Uuid); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+const testDetectTimeout = 30 * time.Second
+
+// handleTestCameraDetect 测试摄像头检测效果
+// @Summary 测试摄像头检测效果
+// @Description 在摄像头绑定的设备上同步执行一次单帧检测，返回检测框和标注图片，相同参数的结果会短暂缓存
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Param camera_id path int true "摄像头ID"
+// @Param req body dao.TestDetectRequest true "测试检测请求"
+// @Success 200 {object} dao.TestDetectResponse "检测结果"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "摄像头不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Failure 504 {object} ErrorResponse "设备响应超时"
+// @Router /api/v1/camera/{camera_id}/test-detect [post]
+func (s *Server) handleTestCameraDetect(c *gin.Context) {
+	var req dao.TestDetectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	cam := c.MustGet(cameraKey).(*model.Camera)
+	camSpec, err := dao.FromCameraModel(cam)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	device, err := cam.BindDevice()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if device == nil {
+		s.writeError(c, http.StatusBadRequest, errors.New("camera is not bound to a device"))
+		return
+	}
+
+	if req.ConfThreshold == 0 {
+		req.ConfThreshold = 0.25
+	}
+	if req.IoUThreshold == 0 {
+		req.IoUThreshold = 0.45
+	}
+	task := &model.TestDetectTask{
+		TaskUuid:      uuid.New().String(),
+		CameraUuid:    cam.Uuid,
+		PullAddr:      camSpec.Url(),
+		ModelName:     req.ModelName,
+		Labels:        req.Labels,
+		ConfThreshold: req.ConfThreshold,
+		IoUThreshold:  req.IoUThreshold,
+		ExpireTime:    time.Now().Add(testDetectTimeout),
+	}
+
+	if !req.NoCache {
+		cached, err := model.GetCachedTestDetectResult(c, task.CacheKey())
+		if err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		} else if cached != nil {
+			resp := s.toTestDetectResponse(cached)
+			resp.Cached = true
+			c.JSON(http.StatusOK, resp)
+			return
+		}
+	}
+
+	if err := model.AddTestDetectTask(c, device.Uuid, task); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	defer model.DeleteTestDetectTask(context.Background(), device.Uuid, task.TaskUuid)
+
+	result, err := s.waitTestDetectResult(c, task.TaskUuid, testDetectTimeout)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if result == nil {
+		s.writeError(c, http.StatusGatewayTimeout, errors.New("wait for device result timeout"))
+		return
+	} else if result.Error != "" {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("device detect failed: %s", result.Error))
+		return
+	}
+
+	if err := model.SetCachedTestDetectResult(c, task.CacheKey(), result); err != nil {
+		s.logger.WithError(err).Warnf("cache test detect result %s failed", task.TaskUuid)
+	}
+	c.JSON(http.StatusOK, s.toTestDetectResponse(result))
+}
+
+func (s *Server) waitTestDetectResult(c *gin.Context, taskUuid string, timeout time.Duration) (*model.TestDetectResult, error) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+
+	for {
+		result, err := model.GetTestDetectResult(c, taskUuid)
+		if err != nil {
+			return nil, err
+		} else if result != nil {
+			return result, nil
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return nil, c.Request.Context().Err()
+		case <-deadline:
+			return nil, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+const (
+	snapshotTimeout   = 20 * time.Second
+	snapshotUrlExpire = time.Hour
+)
+
+// handleCameraSnapshot 获取摄像头快照
+// @Summary 获取摄像头快照
+// @Description 在摄像头绑定的设备上截取一帧画面并上传到对象存储，返回预签名下载地址，无需开启预览
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Param camera_id path int true "摄像头ID"
+// @Success 200 {object} dao.CameraSnapshotResponse "快照地址"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "摄像头不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Failure 504 {object} ErrorResponse "设备响应超时"
+// @Router /api/v1/camera/{camera_id}/snapshot [post]
+func (s *Server) handleCameraSnapshot(c *gin.Context) {
+	cam := c.MustGet(cameraKey).(*model.Camera)
+	camSpec, err := dao.FromCameraModel(cam)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	device, err := cam.BindDevice()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if device == nil {
+		s.writeError(c, http.StatusBadRequest, errors.New("camera is not bound to a device"))
+		return
+	}
+
+	task := &model.SnapshotTask{
+		TaskUuid:   uuid.New().String(),
+		CameraUuid: cam.Uuid,
+		PullAddr:   camSpec.Url(),
+		ExpireTime: time.Now().Add(snapshotTimeout),
+	}
+	if err := model.AddSnapshotTask(c, device.Uuid, task); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	defer model.DeleteSnapshotTask(context.Background(), device.Uuid, task.TaskUuid)
+
+	result, err := s.waitSnapshotResult(c, task.TaskUuid, snapshotTimeout)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if result == nil {
+		s.writeError(c, http.StatusGatewayTimeout, errors.New("wait for device result timeout"))
+		return
+	} else if result.Error != "" {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("device snapshot failed: %s", result.Error))
+		return
+	}
+
+	u, err := s.minioCli.PresignedGetObject(c, s.conf.S3.Bucket,
+		strings.TrimPrefix(result.ImagePath, "/"), snapshotUrlExpire, nil)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.CameraSnapshotResponse{
+		Url:        u.String(),
+		ExpireTime: time.Now().Add(snapshotUrlExpire).UTC().Format(time.RFC3339),
+		CreateTime: result.CreateTime.UTC().Format(time.RFC3339),
+	})
+}
+
+func (s *Server) waitSnapshotResult(c *gin.Context, taskUuid string, timeout time.Duration) (*model.SnapshotResult, error) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+
+	for {
+		result, err := model.GetSnapshotResult(c, taskUuid)
+		if err != nil {
+			return nil, err
+		} else if result != nil {
+			return result, nil
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return nil, c.Request.Context().Err()
+		case <-deadline:
+			return nil, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+const cameraProbeTimeout = 30 * time.Second
+
+// handleProbeCamera 探测摄像头连通性
+// @Summary 探测摄像头连通性
+// @Description 在摄像头绑定的设备上使用 ffprobe 探测摄像头地址，返回编码、分辨率、帧率等信息，无法连通时 reachable 为 false 并返回错误信息，用于创建任务前校验摄像头配置
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Param camera_id path int true "摄像头ID"
+// @Success 200 {object} dao.CameraProbeResult "探测结果"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "摄像头不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Failure 504 {object} ErrorResponse "设备响应超时"
+// @Router /api/v1/camera/{camera_id}/probe [post]
+func (s *Server) handleProbeCamera(c *gin.Context) {
+	cam := c.MustGet(cameraKey).(*model.Camera)
+	camSpec, err := dao.FromCameraModel(cam)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	device, err := cam.BindDevice()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if device == nil {
+		s.writeError(c, http.StatusBadRequest, errors.New("camera is not bound to a device"))
+		return
+	}
+
+	task := &model.CameraProbeTask{
+		TaskUuid:   uuid.New().String(),
+		CameraUuid: cam.Uuid,
+		PullAddr:   camSpec.Url(),
+		ExpireTime: time.Now().Add(cameraProbeTimeout),
+	}
+	if err := model.AddCameraProbeTask(c, device.Uuid, task); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	defer model.DeleteCameraProbeTask(context.Background(), device.Uuid, task.TaskUuid)
+
+	result, err := s.waitCameraProbeResult(c, task.TaskUuid, cameraProbeTimeout)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if result == nil {
+		s.writeError(c, http.StatusGatewayTimeout, errors.New("wait for device result timeout"))
+		return
+	}
+	c.JSON(http.StatusOK, dao.FromCameraProbeResultModel(result))
+}
+
+func (s *Server) waitCameraProbeResult(c *gin.Context, taskUuid string, timeout time.Duration) (*model.CameraProbeResult, error) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+
+	for {
+		result, err := model.GetCameraProbeResult(c, taskUuid)
+		if err != nil {
+			return nil, err
+		} else if result != nil {
+			return result, nil
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return nil, c.Request.Context().Err()
+		case <-deadline:
+			return nil, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) toTestDetectResponse(result *model.TestDetectResult) *dao.TestDetectResponse {
+	resp := dao.FromTestDetectResultModel(result)
+	if resp.ImagePath != "" {
+		resp.ImagePath = s.conf.S3.VisitPrefix() + resp.ImagePath
+	}
+	return resp
+}
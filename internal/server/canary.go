@@ -0,0 +1,384 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/nsqio/go-nsq"
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const (
+	canaryUuid      = "lumina-canary"
+	canaryImagePath = "/canary/canary.jpg"
+	// canaryReason is the answer of the stub workflow, a message carrying it
+	// went through the workflow
+	canaryReason = "canary"
+	// canaryMinRuns is the number of runs before the success rate is checked
+	canaryMinRuns = 3
+)
+
+type canaryRun struct {
+	time    time.Time
+	latency time.Duration
+	err     error
+}
+
+// Canary publishes a test message of the canary job to the topic of the
+// devices at every interval and waits for the consumer to store it with the
+// answer of the stub workflow. It raises a health event when too few of the
+// last runs succeed in time, catching a broken pipeline no job would report.
+type Canary struct {
+	conf     CanaryConfig
+	bucket   string
+	minioCli *minio.Client
+	producer *nsq.Producer
+	metrics  *Metrics
+	logger   *logrus.Entry
+
+	mu   sync.Mutex
+	runs []canaryRun
+}
+
+func NewCanary(logger *logrus.Entry, conf CanaryConfig, bucket string, minioCli *minio.Client, metrics *Metrics) (*Canary, error) {
+	producer, err := nsq.NewProducer(conf.NSQDAddr, nsq.NewConfig())
+	if err != nil {
+		return nil, fmt.Errorf("create nsq producer failed: %w", err)
+	}
+	return &Canary{
+		conf:     conf,
+		bucket:   bucket,
+		minioCli: minioCli,
+		producer: producer,
+		metrics:  metrics,
+		logger:   logger.WithField("component", "canary"),
+	}, nil
+}
+
+func (cn *Canary) Run(ctx context.Context) {
+	defer cn.producer.Stop()
+	ticker := time.NewTicker(time.Duration(cn.conf.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		run := canaryRun{time: time.Now()}
+		run.latency, run.err = cn.inject(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if run.err != nil {
+			cn.logger.WithError(run.err).Warn("canary run failed")
+		} else {
+			cn.logger.Debugf("canary run took %s", run.latency)
+		}
+		cn.record(run)
+	}
+}
+
+// inject publishes a test message and waits for it to be stored, it returns
+// the end to end latency.
+func (cn *Canary) inject(ctx context.Context) (time.Duration, error) {
+	job, err := cn.ensureJob()
+	if err != nil {
+		return 0, err
+	}
+	// messages of the previous runs that came in too late
+	if err := model.DeleteMessagesByJobId(job.Id); err != nil {
+		return 0, err
+	}
+
+	img, err := canaryImage()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := cn.minioCli.PutObject(ctx, cn.bucket, strings.TrimPrefix(canaryImagePath, "/"),
+		bytes.NewReader(img), int64(len(img)), minio.PutObjectOptions{ContentType: "image/jpeg"}); err != nil {
+		return 0, fmt.Errorf("upload image failed: %w", err)
+	}
+
+	// message timestamps are stored to the second
+	ts := time.Now().Truncate(time.Second)
+	body, _ := json.Marshal(dao.DeviceMessage{
+		JobUuid:   job.Uuid,
+		Timestamp: ts.UnixNano(),
+		ImagePath: canaryImagePath,
+	})
+	start := time.Now()
+	if err := cn.producer.Publish(cn.conf.Topic, body); err != nil {
+		cn.metrics.countWriteFailure(metricsTargetNSQ)
+		return 0, fmt.Errorf("publish message failed: %w", err)
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(time.Duration(cn.conf.MaxLatency) * time.Second)
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-deadline:
+			return 0, fmt.Errorf("message not processed in %ds", cn.conf.MaxLatency)
+		case <-ticker.C:
+		}
+
+		m, err := model.GetMessageByJobIdAndTimestamp(job.Id, ts)
+		if err != nil {
+			return 0, err
+		} else if m == nil {
+			continue
+		}
+		latency := time.Since(start)
+		if err := model.DeleteMessage(m.Id); err != nil {
+			cn.logger.WithError(err).Warn("delete canary message failed")
+		}
+		if m.WorkflowResp == nil || m.WorkflowResp.Answer != canaryReason {
+			return latency, errors.New("message stored without the answer of the stub workflow")
+		}
+		return latency, nil
+	}
+}
+
+// ensureJob returns the canary job, creating it and its workflow if needed.
+// The workflow endpoint follows the config.
+func (cn *Canary) ensureJob() (*model.Job, error) {
+	wf, err := model.GetWorkflowByUuid(canaryUuid)
+	if err != nil {
+		return nil, err
+	}
+	if wf == nil {
+		wf = &model.Workflow{
+			Uuid:      canaryUuid,
+			Name:      "lumina canary",
+			ModelName: "canary",
+			Timeout:   int((time.Duration(cn.conf.MaxLatency) * time.Second).Milliseconds()),
+			Query:     "pipeline health check",
+		}
+	}
+	if wf.Endpoint != cn.conf.StubEndpoint || wf.Id == 0 {
+		wf.Endpoint = cn.conf.StubEndpoint
+		if err := model.UpdateWorkflow(wf); err != nil {
+			return nil, err
+		}
+	}
+
+	job, err := model.GetJobByUuid(canaryUuid)
+	if err != nil {
+		return nil, err
+	} else if job != nil {
+		return job, nil
+	}
+	job = &model.Job{
+		Uuid:       canaryUuid,
+		Kind:       model.JobKindCanary,
+		Status:     model.ExectorStatusStopped,
+		WorkflowId: wf.Id,
+	}
+	if err := model.AddJob(job); err != nil {
+		return nil, err
+	}
+	// Enabled defaults to true in the database
+	if err := model.UpdateJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// canaryStubEndpoint returns the stub workflow endpoint on the local address
+// the server listens on.
+func canaryStubEndpoint(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://" + addr + "/api/v1/canary"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/api/v1/canary"
+}
+
+var canaryImageOnce = sync.OnceValues(func() ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.Gray{Y: 128}}, image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+})
+
+// canaryImage returns the known test image.
+func canaryImage() ([]byte, error) {
+	return canaryImageOnce()
+}
+
+// record keeps the run among the last ones and opens or resolves the canary
+// health event depending on their success rate.
+func (cn *Canary) record(run canaryRun) {
+	cn.mu.Lock()
+	cn.runs = append(cn.runs, run)
+	if len(cn.runs) > cn.conf.Window {
+		cn.runs = cn.runs[len(cn.runs)-cn.conf.Window:]
+	}
+	rate := cn.successRate()
+	count := len(cn.runs)
+	cn.mu.Unlock()
+	if count < min(canaryMinRuns, cn.conf.Window) {
+		return
+	}
+
+	event, err := model.GetOpenHealthEvent(model.HealthEventCanary, 0)
+	if err != nil {
+		cn.logger.WithError(err).Error("get open health event failed")
+		return
+	}
+	if rate >= cn.conf.MinSuccessRate {
+		if event != nil {
+			if err := model.ResolveHealthEvent(event.Id); err != nil {
+				cn.logger.WithError(err).Error("resolve health event failed")
+				return
+			}
+			cn.logger.Infof("canary back to normal, health event %d resolved", event.Id)
+		}
+		return
+	}
+
+	summary := fmt.Sprintf("%.0f%% of the last %d canary messages went through the pipeline in time", rate*100, count)
+	if run.err != nil {
+		summary += ", last: " + run.err.Error()
+	}
+	if event == nil {
+		event = &model.HealthEvent{Kind: model.HealthEventCanary, StartTime: run.time}
+		cn.logger.Warn(summary)
+	}
+	event.Summary = summary
+	event.Observed = rate
+	event.Expected = cn.conf.MinSuccessRate
+	event.EndTime = time.Now()
+	if err := model.SaveHealthEvent(event); err != nil {
+		cn.logger.WithError(err).Error("save health event failed")
+	}
+}
+
+// successRate must be called with mu held.
+func (cn *Canary) successRate() float64 {
+	if len(cn.runs) == 0 {
+		return 0
+	}
+	succeeded := 0
+	for _, r := range cn.runs {
+		if r.err == nil {
+			succeeded++
+		}
+	}
+	return float64(succeeded) / float64(len(cn.runs))
+}
+
+// Status returns the last runs, newest first.
+func (cn *Canary) Status() *dao.CanaryStatusResponse {
+	cn.mu.Lock()
+	defer cn.mu.Unlock()
+	resp := &dao.CanaryStatusResponse{
+		Enabled:     true,
+		SuccessRate: cn.successRate(),
+		Runs:        make([]dao.CanaryRun, 0, len(cn.runs)),
+	}
+	for i := len(cn.runs) - 1; i >= 0; i-- {
+		r := cn.runs[i]
+		run := dao.CanaryRun{
+			Time:    r.time.UTC().Format(time.RFC3339),
+			Success: r.err == nil,
+		}
+		if r.err != nil {
+			run.Error = r.err.Error()
+		} else {
+			run.Latency = r.latency.Milliseconds()
+		}
+		resp.Runs = append(resp.Runs, run)
+	}
+	return resp
+}
+
+// handleGetCanaryStatus 获取消息处理链路自检状态
+// @Summary 获取消息处理链路自检状态
+// @Description 服务端定期向设备使用的 NSQ 主题发布测试消息，经消费者和内置的模拟工作流处理后检查端到端耗时，成功率低于阈值时产生系统健康事件
+// @Tags 系统健康
+// @Accept json
+// @Produce json
+// @Success 200 {object} dao.CanaryStatusResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Router /api/v1/canary [get]
+func (s *Server) handleGetCanaryStatus(c *gin.Context) {
+	if s.canary == nil {
+		c.JSON(http.StatusOK, dao.CanaryStatusResponse{Runs: []dao.CanaryRun{}})
+		return
+	}
+	c.JSON(http.StatusOK, s.canary.Status())
+}
+
+// handleCanaryChatCompletions is the stub workflow of the canary job, an
+// OpenAI compatible chat completion answering every canary image with a
+// fixed answer. A request without the canary image is rejected so that a
+// message mangled on its way fails the run.
+func (s *Server) handleCanaryChatCompletions(c *gin.Context) {
+	var req struct {
+		Messages []struct {
+			Content []struct {
+				ImageUrl struct {
+					Url string `json:"url"`
+				} `json:"image_url"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	found := false
+	for _, m := range req.Messages {
+		for _, content := range m.Content {
+			if strings.HasSuffix(content.ImageUrl.Url, canaryImagePath) {
+				found = true
+			}
+		}
+	}
+	if !found {
+		s.writeError(c, http.StatusBadRequest, errors.New("canary image not found in request"))
+		return
+	}
+
+	answer, _ := json.Marshal(gin.H{"match": false, "confidence": 1, "reason": canaryReason})
+	c.JSON(http.StatusOK, gin.H{
+		"id":      "canary",
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   "canary",
+		"choices": []gin.H{{
+			"index":         0,
+			"message":       gin.H{"role": "assistant", "content": string(answer)},
+			"finish_reason": "stop",
+		}},
+		"usage": gin.H{"prompt_tokens": 0, "completion_tokens": 0, "total_tokens": 0},
+	})
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const (
+	// chatRunTimeout bounds an agent run, which goes on when the client
+	// disconnects so that the answer can be resumed
+	chatRunTimeout     = 10 * time.Minute
+	chatResumeInterval = 200 * time.Millisecond
+)
+
+// chatStreamWriter buffers the SSE chunks of a chat answer for resuming, and
+// passes them to the client while it is connected.
+type chatStreamWriter struct {
+	ctx              context.Context
+	conversationUuid string
+	streamId         string
+	client           gin.ResponseWriter
+	clientGone       bool
+	bufferFailed     bool
+	logger           *logrus.Entry
+}
+
+func newChatStreamWriter(ctx context.Context, client gin.ResponseWriter, conversationUuid, streamId string, logger *logrus.Entry) *chatStreamWriter {
+	return &chatStreamWriter{
+		ctx:              ctx,
+		conversationUuid: conversationUuid,
+		streamId:         streamId,
+		client:           client,
+		logger:           logger.WithField("stream", streamId),
+	}
+}
+
+// Write never fails, the agent run outlives both the client and the buffer.
+func (w *chatStreamWriter) Write(p []byte) (int, error) {
+	if !w.bufferFailed {
+		if err := model.AppendChatStreamChunk(w.ctx, w.conversationUuid, w.streamId, p); err != nil {
+			w.bufferFailed = true
+			w.logger.WithError(err).Warn("buffer chat stream failed, it cannot be resumed")
+		}
+	}
+	if !w.clientGone {
+		if _, err := w.client.Write(p); err != nil {
+			w.clientGone = true
+		} else {
+			w.client.Flush()
+		}
+	}
+	return len(p), nil
+}
+
+// finish marks the stream complete for the resuming clients.
+func (w *chatStreamWriter) finish() {
+	if err := model.FinishChatStream(w.ctx, w.conversationUuid, w.streamId); err != nil {
+		w.logger.WithError(err).Warn("finish chat stream failed")
+	}
+}
+
+// handleResumeChat 续传聊天回复
+// @Summary 续传聊天回复
+// @Description 连接中断后从第 offset 个事件起继续读取聊天回复，流ID由聊天接口的 X-Stream-Id 响应头返回，回复完成后 10 分钟内可续传
+// @Tags 对话
+// @Produce text/event-stream
+// @Param uuid path string true "对话uuid"
+// @Param stream_id path string true "流ID"
+// @Param offset query int false "已收到的事件数" default(0)
+// @Success 200 "聊天回复"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "流不存在或已过期"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /v1/conversation/{uuid}/chat/{stream_id}/resume [get]
+func (s *Server) handleResumeChat(c *gin.Context) {
+	var req dao.ResumeChatRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	conversation := c.MustGet(conversationKey).(*model.Conversation)
+	streamId := c.Param("stream_id")
+	ctx := c.Request.Context()
+
+	exists, err := model.ChatStreamExists(ctx, conversation.Uuid, streamId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if !exists {
+		s.writeError(c, http.StatusNotFound, errors.New("stream not found"))
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	offset := req.Offset
+	for {
+		chunks, done, err := model.GetChatStreamChunks(ctx, conversation.Uuid, streamId, offset)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.WithError(err).Warnf("resume chat stream %s failed", streamId)
+			}
+			return
+		}
+		for _, chunk := range chunks {
+			if _, err := io.WriteString(c.Writer, chunk); err != nil {
+				return
+			}
+		}
+		offset += len(chunks)
+		c.Writer.Flush()
+		if done && len(chunks) == 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.draining:
+			// the client resumes from another server, the chunks being
+			// buffered in redis
+			return
+		case <-time.After(chatResumeInterval):
+		}
+	}
+}
@@ -3,12 +3,16 @@ package server
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v2"
 
 	"lumina/internal/agent"
+	"lumina/internal/embedding"
 	"lumina/internal/model"
+	"lumina/pkg/profile"
+	"lumina/pkg/tracing"
 )
 
 type S3Config struct {
@@ -19,6 +23,11 @@ type S3Config struct {
 	UseSSL          bool   `yaml:"useSSL"`
 	Region          string `yaml:"region"`
 	VisitEndpoint   string `yaml:"visitEndpoint"`
+	// PresignMedia makes the message APIs return presigned URLs of the media
+	// instead of the visit URLs, for buckets that are not public
+	PresignMedia bool `yaml:"presignMedia"`
+	// PresignExpire is how long the presigned URLs are valid, in seconds
+	PresignExpire int `yaml:"presignExpire"`
 }
 
 func (c S3Config) VisitPrefix() string {
@@ -40,13 +49,135 @@ type InfluxDBConfig struct {
 }
 
 type MediaServerConfig struct {
-	Ip         string `yaml:"ip"`
-	RtmpPort   int    `yaml:"rtmpPort"`
-	RtspPort   int    `yaml:"rtspPort"`
-	HttpPort   int    `yaml:"httpPort"`
+	Ip       string `yaml:"ip"`
+	RtmpPort int    `yaml:"rtmpPort"`
+	RtspPort int    `yaml:"rtspPort"`
+	HttpPort int    `yaml:"httpPort"`
+	// HlsPort serves the HLS playlists, HttpPort is used if zero
+	HlsPort    int    `yaml:"hlsPort"`
 	PathPrefix string `yaml:"pathPrefix"`
 }
 
+type WebPushConfig struct {
+	Enabled         bool   `yaml:"enabled"`
+	Subject         string `yaml:"subject"`
+	VAPIDPublicKey  string `yaml:"vapidPublicKey"`
+	VAPIDPrivateKey string `yaml:"vapidPrivateKey"`
+	TTL             int    `yaml:"ttl"`
+	DashboardURL    string `yaml:"dashboardURL"`
+}
+
+const (
+	SMTPTLSNone     = "none"
+	SMTPTLSStartTLS = "starttls"
+	SMTPTLSImplicit = "tls"
+)
+
+// SMTPConfig is the server sending the alerts of email notification
+// channels.
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+	// TLS is none, starttls or tls
+	TLS string `yaml:"tls"`
+	// AttachImage attaches the detection image to the alert emails, it is
+	// only linked if larger than MaxAttachmentSize bytes
+	AttachImage       bool `yaml:"attachImage"`
+	MaxAttachmentSize int  `yaml:"maxAttachmentSize"`
+	// DashboardURL links the emails to the alert message in the dashboard
+	DashboardURL string `yaml:"dashboardURL"`
+}
+
+// VolumeAnomalyConfig configures the detection of jobs whose hourly message
+// or alert volume deviates from the one learnt for that hour of the week.
+type VolumeAnomalyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Threshold is the deviation, in standard deviations, above which an
+	// hour is anomalous
+	Threshold float64 `yaml:"threshold"`
+	// MinSamples is the number of weeks an hour must be learnt before it is
+	// checked
+	MinSamples int `yaml:"minSamples"`
+}
+
+// CanaryConfig configures the canary, a test message the server publishes
+// periodically to the topic of the devices so that it goes through the
+// consumer and a stub workflow served by the server.
+type CanaryConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// NSQDAddr and Topic are those the devices publish to
+	NSQDAddr string `yaml:"nsqdAddr"`
+	Topic    string `yaml:"topic"`
+	// StubEndpoint is the address of the stub workflow as seen by the
+	// consumer, by default /api/v1/canary on the local address of the server
+	StubEndpoint string `yaml:"stubEndpoint"`
+	// Interval between runs, in seconds
+	Interval int `yaml:"interval"`
+	// MaxLatency is the end to end latency, in seconds, a run fails after
+	MaxLatency int `yaml:"maxLatency"`
+	// A health event is raised when less than MinSuccessRate of the last
+	// Window runs succeed
+	Window         int     `yaml:"window"`
+	MinSuccessRate float64 `yaml:"minSuccessRate"`
+}
+
+// DeviceStateConfig configures how the state of a device is derived from
+// the time of its last report, in seconds.
+type DeviceStateConfig struct {
+	DegradedAfter int `yaml:"degradedAfter"`
+	OfflineAfter  int `yaml:"offlineAfter"`
+}
+
+// GraphQLConfig configures the GraphQL endpoint, which composes devices,
+// cameras, jobs, messages and stats into single queries for the dashboard.
+type GraphQLConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxDepth limits the nesting of the selections of a query
+	MaxDepth int `yaml:"maxDepth"`
+}
+
+// ApiUsageConfig configures the usage of the API recorded by consumer and
+// endpoint, so that the clients loading the server can be found.
+type ApiUsageConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// FlushInterval between writes of the counts to the database, in seconds
+	FlushInterval int `yaml:"flushInterval"`
+	// RetentionDays is how long the usage is kept, 0 keeps it forever
+	RetentionDays int `yaml:"retentionDays"`
+}
+
+// SemanticSearchConfig configures the search of the messages by meaning, the
+// embedding model and the vector store must be those the embedding sink of
+// the consumer writes to.
+type SemanticSearchConfig struct {
+	Enabled          bool `yaml:"enabled"`
+	embedding.Config `yaml:",inline"`
+}
+
+// MetricsConfig configures /metrics, scraped by Prometheus.
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Token is the bearer token the scrapes must send, empty if they need
+	// none
+	Token string `yaml:"token"`
+}
+
+// ReportConfig configures the generation of the scheduled reports.
+type ReportConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Interval between checks of the due reports, in seconds
+	Interval int `yaml:"interval"`
+	// PdfCommand converts the HTML report read from its stdin into the PDF
+	// written to its stdout, e.g. wkhtmltopdf. The PDF reports fail if it
+	// is not installed.
+	PdfCommand []string `yaml:"pdfCommand"`
+	// Timeout of the generation of a report, in seconds
+	Timeout int `yaml:"timeout"`
+}
+
 type Config struct {
 	Addr        string            `yaml:"addr"`
 	SSLCert     string            `yaml:"sslCert"`
@@ -58,6 +189,198 @@ type Config struct {
 	InfluxDB    InfluxDBConfig    `yaml:"influxdb"`
 	MediaServer MediaServerConfig `yaml:"mediaServer"`
 	Redis       model.RedisConfig `yaml:"redis"`
+	WebPush     WebPushConfig     `yaml:"webPush"`
+	// SMTP sends the alerts of email notification channels, they are
+	// disabled if Host is empty
+	SMTP SMTPConfig `yaml:"smtp"`
+	// VolumeAnomaly raises health events for jobs with unusual volumes
+	VolumeAnomaly VolumeAnomalyConfig `yaml:"volumeAnomaly"`
+	// Canary checks the message pipeline end to end
+	Canary CanaryConfig `yaml:"canary"`
+	// DeviceState sets when devices are shown degraded or offline, a device
+	// going offline raises a health event
+	DeviceState DeviceStateConfig `yaml:"deviceState"`
+	// GraphQL serves /api/v1/graphql
+	GraphQL GraphQLConfig `yaml:"graphql"`
+	// ApiUsage records the requests of every user, device and anonymous
+	// client by endpoint
+	ApiUsage ApiUsageConfig `yaml:"apiUsage"`
+	// MessageRetention deletes the old messages with their alerts and media
+	MessageRetention MessageRetentionConfig `yaml:"messageRetention"`
+	// SemanticSearch serves /api/v1/message/semantic-search
+	SemanticSearch SemanticSearchConfig `yaml:"semanticSearch"`
+	// Report generates the scheduled reports of the sites
+	Report ReportConfig `yaml:"report"`
+	// Metrics serves /metrics
+	Metrics MetricsConfig `yaml:"metrics"`
+	// CORS allows dashboards hosted on other origins to call the API
+	CORS CORSConfig `yaml:"cors"`
+	// RateLimit limits the requests of the clients and tokens
+	RateLimit RateLimitConfig `yaml:"rateLimit"`
+	// Tracing exports the spans of the requests and continues the traces of
+	// the callers
+	Tracing tracing.Config `yaml:"tracing"`
+	// TwoFactor configures the TOTP two-factor authentication of the logins
+	TwoFactor TwoFactorConfig `yaml:"twoFactor"`
+	// OIDC logs the users in with an OpenID Connect provider
+	OIDC OIDCConfig `yaml:"oidc"`
+	// MultiTenant scopes the data to the organizations of the users
+	MultiTenant MultiTenantConfig `yaml:"multiTenant"`
+	// Session sets the lifetimes of the logins
+	Session SessionConfig `yaml:"session"`
+	// ShutdownTimeout is how long a shutdown waits for the in-flight
+	// requests and chat answers before closing their connections, in seconds
+	ShutdownTimeout int `yaml:"shutdownTimeout"`
+
+	// path and profile are the files the config was loaded from, to reload
+	// it
+	path    string
+	profile string
+}
+
+// SessionConfig configures the login sessions of the users. A login is
+// issued a short lived access JWT and a refresh token renewing it, every
+// refresh replaces the refresh token and slides the session. A refresh
+// token used twice ends its session, as it was stolen.
+type SessionConfig struct {
+	// AccessTokenTtl is the lifetime of the access JWTs, in seconds
+	AccessTokenTtl int `yaml:"accessTokenTtl"`
+	// IdleTimeout ends the sessions not refreshed for as long, in seconds
+	IdleTimeout int `yaml:"idleTimeout"`
+	// MaxLifetime ends the sessions as long after their login whatever
+	// their refreshes, in seconds, 0 for no limit
+	MaxLifetime int `yaml:"maxLifetime"`
+}
+
+// MultiTenantConfig hosts several organizations on the server. The users
+// and the devices only see the devices, cameras, jobs, workflows, device
+// groups and messages of their organization, and the server wide endpoints
+// (health events, releases, rollouts, canary, notification channels,
+// reports, usage) are left to the default organization. Every row belongs
+// to the default organization if disabled.
+type MultiTenantConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// OIDCConfig configures the single sign-on with an OpenID Connect provider,
+// e.g. Keycloak or Azure AD, by the authorization code flow. The users
+// logged in are issued the same JWT as the local ones.
+type OIDCConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Issuer is the issuer URL, the endpoints are discovered from it, e.g.
+	// https://keycloak.example.com/realms/lumina or
+	// https://login.microsoftonline.com/<tenant>/v2.0
+	Issuer       string `yaml:"issuer"`
+	ClientId     string `yaml:"clientId"`
+	ClientSecret string `yaml:"clientSecret"`
+	// RedirectUrl is the callback registered at the provider, it ends with
+	// /api/v1/oidc/callback
+	RedirectUrl string   `yaml:"redirectUrl"`
+	Scopes      []string `yaml:"scopes"`
+	// UsernameClaim names the users provisioned
+	UsernameClaim string `yaml:"usernameClaim"`
+	// RolesClaim is the path of the claim listing the roles or groups of
+	// the user, e.g. realm_access.roles for Keycloak or roles for Azure AD
+	RolesClaim string `yaml:"rolesClaim"`
+	// AdminRoles make the users having any of them admins, the admin flag
+	// is then updated on every login
+	AdminRoles []string `yaml:"adminRoles"`
+	// AllowedRoles restrict the login to the users having any of them, all
+	// the users of the provider may log in if empty
+	AllowedRoles []string `yaml:"allowedRoles"`
+	// AutoProvision creates the unknown users on their first login, they
+	// must be created by an admin otherwise
+	AutoProvision bool `yaml:"autoProvision"`
+}
+
+// TwoFactorConfig configures the TOTP two-factor authentication, the users
+// enroll from their settings.
+type TwoFactorConfig struct {
+	// Issuer is the name the authenticator apps show the accounts under
+	Issuer string `yaml:"issuer"`
+	// RequireForAdmins refuses the admin endpoints to the admins without
+	// two-factor authentication, their access tokens included, until they
+	// enroll
+	RequireForAdmins bool `yaml:"requireForAdmins"`
+}
+
+// CORSConfig configures the cross-origin requests, they are refused if
+// disabled.
+type CORSConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// AllowOrigins are the origins allowed, e.g. https://dashboard.example.com,
+	// "*" allows any origin but not with AllowCredentials
+	AllowOrigins []string `yaml:"allowOrigins"`
+	// AllowCredentials lets the browsers send the cookies, the token cookie
+	// of the dashboard among them, from the listed origins only
+	AllowCredentials bool `yaml:"allowCredentials"`
+	// AllowHeaders are the request headers allowed besides Authorization,
+	// Content-Type and X-Request-Id
+	AllowHeaders []string `yaml:"allowHeaders"`
+	// ExposeHeaders are the response headers exposed besides X-Request-Id,
+	// Content-Disposition, Retry-After and X-Stream-Id
+	ExposeHeaders []string `yaml:"exposeHeaders"`
+	// MaxAge is how long in seconds the browsers cache a preflight response
+	MaxAge int `yaml:"maxAge"`
+}
+
+// RateLimitConfig limits the requests of every valid token, or of every
+// client IP for the requests without one, in fixed windows counted on redis.
+// Login and register are limited per client IP only.
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Login limits /api/v1/login against password brute force
+	Login RateLimitRule `yaml:"login"`
+	// Register limits /api/v1/device/register
+	Register RateLimitRule `yaml:"register"`
+	// Api limits every request under /api/v1, login and register included
+	Api RateLimitRule `yaml:"api"`
+}
+
+// rule returns the rule of a scope of the limiter, an unknown scope is not
+// limited.
+func (c *RateLimitConfig) rule(scope string) RateLimitRule {
+	switch scope {
+	case "login":
+		return c.Login
+	case "register":
+		return c.Register
+	case "api":
+		return c.Api
+	}
+	return RateLimitRule{}
+}
+
+type RateLimitRule struct {
+	// Window in seconds
+	Window int `yaml:"window"`
+	// PerIP is the requests allowed in a window to a client IP without a
+	// token, 0 for no limit
+	PerIP int `yaml:"perIP"`
+	// PerToken is the requests allowed in a window to a token, 0 for no
+	// limit
+	PerToken int `yaml:"perToken"`
+}
+
+// MessageRetentionConfig configures the cleanup of the messages by age or by
+// count, with their alerts and the images and videos they reference in the
+// bucket.
+type MessageRetentionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Days the messages are kept, 0 keeps them whatever their age
+	Days int `yaml:"days"`
+	// AlertDays the alerted messages are kept, when longer than Days
+	AlertDays int `yaml:"alertDays"`
+	// MaxRows is the number of messages kept, the oldest beyond it are
+	// deleted whatever their age, 0 keeps any number
+	MaxRows int64 `yaml:"maxRows"`
+	// Interval between cleanups, in seconds
+	Interval int `yaml:"interval"`
+	// BatchSize is the number of messages deleted at once
+	BatchSize int `yaml:"batchSize"`
+	// DeleteMedia deletes the images and videos of the messages from the
+	// bucket
+	DeleteMedia bool `yaml:"deleteMedia"`
 }
 
 func DefaultConfig() *Config {
@@ -65,15 +388,19 @@ func DefaultConfig() *Config {
 		Addr: "127.0.0.1:8081",
 		DB:   *model.DefaultDBConfig(),
 		S3: S3Config{
-			Bucket:   "lumina",
-			Endpoint: "127.0.0.1:9000",
-			UseSSL:   false,
-			Region:   "us-east-1",
+			Bucket:        "lumina",
+			Endpoint:      "127.0.0.1:9000",
+			UseSSL:        false,
+			Region:        "us-east-1",
+			PresignExpire: 3600,
 		},
 		LLM: agent.LLMConfig{
 			Model:   "gpt-3.5-turbo",
 			BaseUrl: "https://api.openai.com/v1",
 			Timeout: 300 * time.Second,
+			OutputFilter: agent.OutputFilterConfig{
+				Enabled: true,
+			},
 		},
 		InfluxDB: InfluxDBConfig{
 			URL:     "http://127.0.0.1:48086",
@@ -90,10 +417,97 @@ func DefaultConfig() *Config {
 			PathPrefix: "/preview",
 		},
 		Redis: *model.DefaultRedisConfig(),
+		WebPush: WebPushConfig{
+			Enabled: false,
+			Subject: "mailto:admin@example.com",
+			TTL:     3600,
+		},
+		SMTP: SMTPConfig{
+			Port:              587,
+			TLS:               SMTPTLSStartTLS,
+			AttachImage:       true,
+			MaxAttachmentSize: 5 << 20,
+		},
+		VolumeAnomaly: VolumeAnomalyConfig{
+			Enabled:    true,
+			Threshold:  4,
+			MinSamples: 3,
+		},
+		Canary: CanaryConfig{
+			Enabled:        false,
+			NSQDAddr:       "127.0.0.1:4150",
+			Topic:          "detection_results",
+			Interval:       60,
+			MaxLatency:     30,
+			Window:         10,
+			MinSuccessRate: 0.8,
+		},
+		DeviceState: DeviceStateConfig{
+			DegradedAfter: 30,
+			OfflineAfter:  120,
+		},
+		GraphQL: GraphQLConfig{
+			Enabled:  false,
+			MaxDepth: 6,
+		},
+		ApiUsage: ApiUsageConfig{
+			Enabled:       true,
+			FlushInterval: 60,
+			RetentionDays: 30,
+		},
+		MessageRetention: MessageRetentionConfig{
+			Enabled:     false,
+			Days:        90,
+			Interval:    3600,
+			BatchSize:   500,
+			DeleteMedia: true,
+		},
+		SemanticSearch: SemanticSearchConfig{
+			Enabled: false,
+			Config:  embedding.DefaultConfig(),
+		},
+		Report: ReportConfig{
+			Enabled:    true,
+			Interval:   60,
+			PdfCommand: []string{"wkhtmltopdf", "--quiet", "--encoding", "utf-8", "-", "-"},
+			Timeout:    300,
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+		},
+		CORS: CORSConfig{
+			Enabled: false,
+			MaxAge:  600,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:  true,
+			Login:    RateLimitRule{Window: 60, PerIP: 10},
+			Register: RateLimitRule{Window: 60, PerIP: 20},
+			Api:      RateLimitRule{Window: 60, PerIP: 300, PerToken: 1200},
+		},
+		Tracing: tracing.DefaultConfig(),
+		TwoFactor: TwoFactorConfig{
+			Issuer:           "Lumina",
+			RequireForAdmins: false,
+		},
+		OIDC: OIDCConfig{
+			Enabled:       false,
+			Scopes:        []string{"openid", "profile", "email"},
+			UsernameClaim: "preferred_username",
+			AutoProvision: true,
+		},
+		Session: SessionConfig{
+			AccessTokenTtl: 15 * 60,
+			IdleTimeout:    7 * 24 * 60 * 60,
+			MaxLifetime:    30 * 24 * 60 * 60,
+		},
+		ShutdownTimeout: 30,
 	}
 }
 
-func LoadConfig(configPath string) (*Config, error) {
+// LoadConfig loads the config at configPath and merges the overlay of
+// profileName over it, if any.
+func LoadConfig(configPath, profileName string) (*Config, error) {
 	conf := DefaultConfig()
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -103,6 +517,53 @@ func LoadConfig(configPath string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal config file: %v", err)
 	}
+	if err := profile.Apply(configPath, profileName, conf); err != nil {
+		return nil, err
+	}
+	conf.path, conf.profile = configPath, profileName
+	if err := conf.CORS.validate(); err != nil {
+		return nil, err
+	}
 
 	return conf, nil
 }
+
+// String prints the config with its secrets masked, for the startup log.
+func (c *Config) String() string {
+	r := *c
+	r.JwtSecret = redactSecret(r.JwtSecret)
+	r.DB.DSN = redactDSN(r.DB.DSN)
+	r.S3.SecretAccessKey = redactSecret(r.S3.SecretAccessKey)
+	r.LLM.ApiKey = redactSecret(r.LLM.ApiKey)
+	r.InfluxDB.Token = redactSecret(r.InfluxDB.Token)
+	r.Redis.Password = redactSecret(r.Redis.Password)
+	r.WebPush.VAPIDPrivateKey = redactSecret(r.WebPush.VAPIDPrivateKey)
+	r.SMTP.Password = redactSecret(r.SMTP.Password)
+	r.SemanticSearch.ApiKey = redactSecret(r.SemanticSearch.ApiKey)
+	r.SemanticSearch.Qdrant.ApiKey = redactSecret(r.SemanticSearch.Qdrant.ApiKey)
+	r.Metrics.Token = redactSecret(r.Metrics.Token)
+	r.OIDC.ClientSecret = redactSecret(r.OIDC.ClientSecret)
+	// a Config value, its String is only defined on the pointer
+	return fmt.Sprintf("%+v", r)
+}
+
+// redactSecret masks a secret, an empty one stays empty to show it is unset.
+func redactSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "******"
+}
+
+// redactDSN masks the password of a MySQL DSN, e.g. user:password@tcp(...).
+func redactDSN(dsn string) string {
+	at := strings.LastIndex(dsn, "@")
+	if at < 0 {
+		return dsn
+	}
+	colon := strings.Index(dsn[:at], ":")
+	if colon < 0 {
+		return dsn
+	}
+	return dsn[:colon+1] + redactSecret(dsn[colon+1:at]) + dsn[at:]
+}
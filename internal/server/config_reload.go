@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	goerrors "errors"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"lumina/internal/dao"
+	"lumina/pkg/profile"
+)
+
+// configWatchInterval is how often the config files are checked for
+// changes.
+const configWatchInterval = 5 * time.Second
+
+// reloadableConfigs are the sections of the config, by their yaml key,
+// applied without restarting the server. The others are read once at
+// startup.
+var reloadableConfigs = []string{"llm", "smtp", "messageRetention", "rateLimit"}
+
+// config returns the current config, with the reloadable sections as last
+// reloaded.
+func (s *Server) config() *Config {
+	return s.live.Load()
+}
+
+// reloadConfig reads the config files again and applies their reloadable
+// sections. The other sections that changed are reported as requiring a
+// restart. The server keeps its config if the files cannot be loaded.
+func (s *Server) reloadConfig(trigger string) (*dao.ConfigReloadSpec, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	result := &dao.ConfigReloadSpec{
+		Trigger:         trigger,
+		Time:            time.Now().UTC().Format(time.RFC3339),
+		Applied:         []string{},
+		RestartRequired: []string{},
+	}
+	defer func() { s.lastReload = result }()
+
+	if s.conf.path == "" {
+		err := goerrors.New("the config was not loaded from a file")
+		result.Error = err.Error()
+		return result, err
+	}
+	loaded, err := LoadConfig(s.conf.path, s.conf.profile)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	current := s.config()
+	next := *current
+	cur, load, nxt := reflect.ValueOf(current).Elem(), reflect.ValueOf(loaded).Elem(), reflect.ValueOf(&next).Elem()
+	for i := 0; i < cur.NumField(); i++ {
+		field := cur.Type().Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if !field.IsExported() || key == "" || reflect.DeepEqual(cur.Field(i).Interface(), load.Field(i).Interface()) {
+			continue
+		}
+		if !slices.Contains(reloadableConfigs, key) {
+			result.RestartRequired = append(result.RestartRequired, key)
+			continue
+		}
+		nxt.Field(i).Set(load.Field(i))
+		result.Applied = append(result.Applied, key)
+	}
+	if len(result.Applied) == 0 {
+		return result, nil
+	}
+
+	s.live.Store(&next)
+	s.notifier.SetSMTP(next.SMTP)
+	s.cleaner.SetConfig(next.MessageRetention)
+	return result, nil
+}
+
+// configModTime returns the last modification of the config files, zero if
+// none can be read.
+func configModTime(conf *Config) time.Time {
+	paths := []string{conf.path}
+	if conf.profile != "" {
+		paths = append(paths, profile.Path(conf.path, conf.profile))
+	}
+	var modTime time.Time
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime
+}
+
+// watchConfig reloads the config when its files change.
+func (s *Server) watchConfig(ctx context.Context) {
+	if s.conf.path == "" {
+		return
+	}
+	modTime := configModTime(s.conf)
+	ticker := time.NewTicker(configWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		t := configModTime(s.conf)
+		if t.Equal(modTime) {
+			continue
+		}
+		modTime = t
+		result, err := s.reloadConfig(dao.ConfigReloadTriggerWatch)
+		if err != nil {
+			s.logger.WithError(err).Error("reload config failed, keeping the current config")
+			continue
+		}
+		s.logReload(result)
+	}
+}
+
+func (s *Server) logReload(result *dao.ConfigReloadSpec) {
+	logger := s.logger.WithField("trigger", result.Trigger)
+	if len(result.Applied) > 0 {
+		logger.Infof("config reloaded, applied %s", strings.Join(result.Applied, ", "))
+	}
+	if len(result.RestartRequired) > 0 {
+		logger.Warnf("config of %s changed, it is applied by a restart", strings.Join(result.RestartRequired, ", "))
+	}
+}
+
+// handleAdminConfigStatus 配置加载状态
+// @Summary 获取配置加载状态
+// @Description 获取可热加载的配置项和最近一次重新加载的结果，配置文件变化时自动重新加载
+// @Tags 用户管理
+// @Produce json
+// @Success 200 {object} dao.ConfigStatusResponse
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Router /api/v1/admin/config/reload [get]
+func (s *Server) handleAdminConfigStatus(c *gin.Context) {
+	s.reloadMu.Lock()
+	lastReload := s.lastReload
+	s.reloadMu.Unlock()
+	c.JSON(http.StatusOK, dao.ConfigStatusResponse{
+		Reloadable: reloadableConfigs,
+		LastReload: lastReload,
+	})
+}
+
+// handleAdminReloadConfig 重新加载配置
+// @Summary 重新加载配置
+// @Description 重新读取配置文件，立即应用 LLM、邮件、消息保留和限流配置，返回已生效和需重启才生效的配置项
+// @Tags 用户管理
+// @Produce json
+// @Success 200 {object} dao.ConfigReloadSpec
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "配置文件加载失败，保留原配置"
+// @Router /api/v1/admin/config/reload [post]
+func (s *Server) handleAdminReloadConfig(c *gin.Context) {
+	result, err := s.reloadConfig(dao.ConfigReloadTriggerManual)
+	if err != nil {
+		s.writeErrorCode(c, http.StatusInternalServerError, errorCodeInvalidConfig, err)
+		return
+	}
+	s.logReload(result)
+	c.JSON(http.StatusOK, result)
+}
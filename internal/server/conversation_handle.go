@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -45,7 +46,7 @@ func SetConversationToContext() gin.HandlerFunc {
 func (s *Server) handleCreateConversation(c *gin.Context) {
 	var req dao.CreateConversationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		s.writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -79,7 +80,7 @@ func (s *Server) handleCreateConversation(c *gin.Context) {
 func (s *Server) handleListConversations(c *gin.Context) {
 	var req dao.ListConversationsRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		s.writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -230,6 +231,15 @@ OUTPUT REQUIREMENTS:
 - List 5-10 key insights that reveal important aspects of the site
 - Response in Markdown format`
 
+// operatorInstruction lets the assistant operate lumina on behalf of the user.
+const operatorInstruction = `
+
+OPERATING LUMINA:
+When the user asks to set up or change detection jobs or workflows, look up the ids ` +
+	`with the list_cameras, list_devices and list_workflows tools, then call the matching ` +
+	`propose_* tool. A proposal changes nothing until the user approves it in the dashboard, ` +
+	`so tell the user what was proposed and never claim the change is done.`
+
 // handleChat 聊天
 // @Summary 聊天
 // @Description 发送聊天消息并获取回复
@@ -239,6 +249,7 @@ OUTPUT REQUIREMENTS:
 // @Param conversationId query int true "对话ID"
 // @Param request body dao.ChatRequest true "聊天请求"
 // @Success 200 "聊天回复"
+// @Header 200 {string} X-Stream-Id "流ID，连接中断后用于续传回复"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 500 {object} ErrorResponse "内部服务器错误"
 // @Router /v1/conversation/{uuid}/chat [post]
@@ -257,8 +268,33 @@ func (s *Server) handleChat(c *gin.Context) {
 
 	llmMessages := toLLMMessages(messages)
 
-	a := agent.NewAgent("test", s.conf.LLM, 10, instruction)
-	agentThoughts, err := a.RunStream(c, req.Query, llmMessages, c.Writer)
+	// tools reading lumina data run with the permissions of the requesting user
+	ctx := c.Request.Context()
+	if u, ok := c.Get(userKey); ok {
+		user := u.(*model.User)
+		ctx = agent.WithPrincipal(ctx, &agent.Principal{UserId: user.Id, IsAdmin: user.IsAdmin, OrgId: requestOrgId(c)})
+	}
+
+	filter, err := s.newOutputFilter()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	a := agent.NewAgent("test", s.config().LLM, 10, instruction+operatorInstruction)
+	a.SetOutputFilter(filter)
+	for _, tool := range luminaAgentTools {
+		a.AddTool(tool)
+	}
+	// the run goes on if the client disconnects, so that it can resume
+	// reading the answer with the stream id
+	runCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), chatRunTimeout)
+	defer cancel()
+	streamId := strings.ReplaceAll(uuid.New().String(), "-", "")
+	c.Header("X-Stream-Id", streamId)
+	stream := newChatStreamWriter(runCtx, c.Writer, conversation.Uuid, streamId, s.logger)
+	agentThoughts, err := a.RunStream(runCtx, req.Query, llmMessages, stream)
+	stream.finish()
 	if err != nil {
 		s.logger.Errorf("run agent stream failed: %v", err)
 		c.AbortWithError(http.StatusInternalServerError, err)
@@ -282,6 +318,21 @@ func (s *Server) handleChat(c *gin.Context) {
 	}
 }
 
+// newOutputFilter returns the filter of agent outputs, with the camera
+// passwords as secrets to redact. It is nil if filtering is disabled.
+func (s *Server) newOutputFilter() (*agent.OutputFilter, error) {
+	filter, err := agent.NewOutputFilter(s.config().LLM.OutputFilter)
+	if err != nil || filter == nil {
+		return nil, err
+	}
+	passwords, err := model.ListCameraPasswords()
+	if err != nil {
+		return nil, err
+	}
+	filter.AddSecrets(passwords...)
+	return filter, nil
+}
+
 func toLLMMessages(messages []*model.ChatMessage) []*agent.LLMMessage {
 	llmMessages := make([]*agent.LLMMessage, 0, 2*len(messages))
 	for _, msg := range messages {
@@ -338,14 +389,19 @@ func (s *Server) handleGenChatTitle(c *gin.Context) {
 		Role:    agent.RoleUser,
 		Content: userPrompt,
 	}
-	llm := agent.NewLLM(s.conf.LLM)
+	llm := agent.NewLLM(s.config().LLM)
 	m, err := llm.ChatCompletion(c, []*agent.LLMMessage{systemMessage, userMessage}, nil)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
+	filter, err := s.newOutputFilter()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
 
-    title := strings.Trim(m.Content, "\n ")
+    title := strings.Trim(filter.Redact(m.Content), "\n ")
     // 以字符（rune）为单位进行截断，避免截断多字节字符导致无效 UTF-8 序列
     runes := []rune(title)
     if len(runes) > 32 {
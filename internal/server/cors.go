@@ -0,0 +1,75 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	corsAllowMethods  = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsAllowHeaders  = []string{"Authorization", "Content-Type", httpXRequestId}
+	corsExposeHeaders = []string{httpXRequestId, "Content-Disposition", "Retry-After", "X-Stream-Id"}
+)
+
+// validate refuses "*" with the credentials, any site could then call the
+// API with the token cookie of the dashboard.
+func (conf CORSConfig) validate() error {
+	if conf.Enabled && conf.AllowCredentials && slices.Contains(conf.AllowOrigins, "*") {
+		return errors.New(`cors: allowOrigins "*" cannot be used with allowCredentials, list the origins instead`)
+	}
+	return nil
+}
+
+// allows reports whether the origin is one of the allowed origins.
+func (conf CORSConfig) allows(origin string) bool {
+	return slices.Contains(conf.AllowOrigins, "*") || slices.Contains(conf.AllowOrigins, origin)
+}
+
+// CORS lets the browsers call the API from the allowed origins, for the
+// dashboards hosted apart from the server. The preflight requests are
+// answered here, they match no route.
+func CORS(conf CORSConfig) gin.HandlerFunc {
+	allowHeaders := strings.Join(append(slices.Clone(corsAllowHeaders), conf.AllowHeaders...), ", ")
+	exposeHeaders := strings.Join(append(slices.Clone(corsExposeHeaders), conf.ExposeHeaders...), ", ")
+	anyOrigin := slices.Contains(conf.AllowOrigins, "*")
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+		if !conf.allows(origin) {
+			c.Next()
+			return
+		}
+
+		// the wildcard comes without the credentials, see validate
+		if anyOrigin {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if conf.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", corsAllowMethods)
+			c.Header("Access-Control-Allow-Headers", allowHeaders)
+			if conf.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", strconv.Itoa(conf.MaxAge))
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Header("Access-Control-Expose-Headers", exposeHeaders)
+		c.Next()
+	}
+}
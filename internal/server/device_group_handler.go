@@ -0,0 +1,236 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const deviceGroupKey = "deviceGroup"
+
+func SetDeviceGroupToContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		groupIdStr := c.Param("group_id")
+		if groupIdStr == "" {
+			c.Next()
+			return
+		}
+
+		groupId, err := strconv.Atoi(groupIdStr)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "invalid group_id",
+			})
+			return
+		}
+
+		group, err := model.GetDeviceGroupById(groupId)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		} else if group == nil || !inRequestOrg(c, group.OrgId) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "device group not found",
+			})
+			return
+		}
+		c.Set(deviceGroupKey, group)
+		c.Next()
+	}
+}
+
+// checkDevicesExist returns an error naming the first device that does not
+// exist in the organization orgId.
+func checkDevicesExist(orgId int, ids []int) error {
+	for _, id := range ids {
+		device, err := model.GetDeviceById(id)
+		if err != nil {
+			return err
+		} else if device == nil || device.OrgId != orgId {
+			return fmt.Errorf("device %d not found", id)
+		}
+	}
+	return nil
+}
+
+// handleCreateDeviceGroup 创建设备组
+// @Summary 创建设备组
+// @Description 创建设备组，任务可以以设备组为目标，在组内所有设备上运行
+// @Tags 设备组
+// @Accept json
+// @Produce json
+// @Param req body dao.CreateDeviceGroupRequest true "创建设备组请求"
+// @Success 200 {object} dao.CreateDeviceGroupResponse "创建成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device-group [post]
+func (s *Server) handleCreateDeviceGroup(c *gin.Context) {
+	var req dao.CreateDeviceGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.UpgradeWindow != nil {
+		if err := req.UpgradeWindow.Validate(); err != nil {
+			s.writeError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	orgId, ok := s.ownerOrgId(c)
+	if !ok {
+		return
+	}
+	if err := checkDevicesExist(orgId, req.DeviceIds); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	group := req.ToModel()
+	group.OrgId = orgId
+	if err := model.CreateDeviceGroup(group, req.DeviceIds); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.CreateDeviceGroupResponse{Id: group.Id})
+}
+
+// handleGetDeviceGroup 获取设备组
+// @Summary 获取设备组
+// @Description 获取设备组及其成员设备
+// @Tags 设备组
+// @Accept json
+// @Produce json
+// @Param group_id path int true "设备组ID"
+// @Success 200 {object} dao.DeviceGroupSpec "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "设备组不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device-group/{group_id} [get]
+func (s *Server) handleGetDeviceGroup(c *gin.Context) {
+	group := c.MustGet(deviceGroupKey).(*model.DeviceGroup)
+	s.writeDeviceGroup(c, group)
+}
+
+func (s *Server) writeDeviceGroup(c *gin.Context, group *model.DeviceGroup) {
+	devices, err := model.ListDeviceGroupMembers(group.Id)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if devices == nil {
+		devices = []model.Device{}
+	}
+	c.JSON(http.StatusOK, dao.FromDeviceGroupModel(group, devices))
+}
+
+// handleUpdateDeviceGroup 更新设备组
+// @Summary 更新设备组
+// @Description 更新设备组名称、描述或成员，成员变化后以该组为目标的任务会在新成员上启动、在移出的设备上停止
+// @Tags 设备组
+// @Accept json
+// @Produce json
+// @Param group_id path int true "设备组ID"
+// @Param req body dao.UpdateDeviceGroupRequest true "更新设备组请求"
+// @Success 200 {object} dao.DeviceGroupSpec "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "设备组不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device-group/{group_id} [put]
+func (s *Server) handleUpdateDeviceGroup(c *gin.Context) {
+	var req dao.UpdateDeviceGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.UpgradeWindow != nil {
+		if err := req.UpgradeWindow.Validate(); err != nil {
+			s.writeError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	group := c.MustGet(deviceGroupKey).(*model.DeviceGroup)
+	if err := checkDevicesExist(group.OrgId, req.DeviceIds); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	req.UpdateModel(group)
+	if err := model.UpdateDeviceGroup(group, req.DeviceIds); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeDeviceGroup(c, group)
+}
+
+// handleDeleteDeviceGroup 删除设备组
+// @Summary 删除设备组
+// @Description 删除设备组，仍有任务以该组为目标时不能删除
+// @Tags 设备组
+// @Accept json
+// @Produce json
+// @Param group_id path int true "设备组ID"
+// @Success 200 "删除成功"
+// @Failure 400 {object} ErrorResponse "设备组仍被任务使用"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "设备组不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device-group/{group_id} [delete]
+func (s *Server) handleDeleteDeviceGroup(c *gin.Context) {
+	group := c.MustGet(deviceGroupKey).(*model.DeviceGroup)
+	if err := model.DeleteDeviceGroup(group.Id); errors.Is(err, model.ErrDeviceGroupInUse) {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handleListDeviceGroups 列出设备组
+// @Summary 列出设备组
+// @Description 列出设备组，不包含成员设备
+// @Tags 设备组
+// @Accept json
+// @Produce json
+// @Param start query int false "分页起始位置"
+// @Param limit query int false "分页每页数量"
+// @Success 200 {object} dao.ListDeviceGroupsResponse "列出成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device-group [get]
+func (s *Server) handleListDeviceGroups(c *gin.Context) {
+	var req dao.ListDeviceGroupsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	groups, total, err := model.ListDeviceGroups(requestOrgId(c), req.Start, req.Limit)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListDeviceGroupsResponse{
+		Items: make([]dao.DeviceGroupSpec, 0, len(groups)),
+		Total: total,
+	}
+	for _, g := range groups {
+		resp.Items = append(resp.Items, *dao.FromDeviceGroupModel(&g, nil))
+	}
+	c.JSON(http.StatusOK, resp)
+}
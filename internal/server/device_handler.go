@@ -1,9 +1,11 @@
 package server
 
 import (
-	"database/sql"
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -43,6 +45,14 @@ func DeviceAuth() gin.HandlerFunc {
 			})
 			return
 		}
+		if tokenStr == device.NextToken {
+			if err := device.PromoteNextToken(); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"error": "internal server error",
+				})
+				return
+			}
+		}
 		c.Set(deviceKey, device)
 		c.Next()
 	}
@@ -59,6 +69,7 @@ func DeviceAuth() gin.HandlerFunc {
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权"
 // @Failure 409 {object} ErrorResponse "冲突"
+// @Failure 429 {object} ErrorResponse "请求过于频繁"
 // @Failure 500 {object} ErrorResponse "内部服务器错误"
 // @Router /api/v1/device/register [post]
 func (s *Server) handleRegister(c *gin.Context) {
@@ -78,7 +89,7 @@ func (s *Server) handleRegister(c *gin.Context) {
 	} else if accessToken.IsExpired() {
 		s.writeError(c, http.StatusUnauthorized, errors.New("token expired"))
 		return
-	} else if accessToken.IsBound() {
+	} else if accessToken.IsUsedUp() {
 		s.writeError(c, http.StatusConflict, errors.New("token already bound"))
 		return
 	}
@@ -89,7 +100,7 @@ func (s *Server) handleRegister(c *gin.Context) {
 		if err != nil {
 			s.writeError(c, http.StatusInternalServerError, err)
 			return
-		} else if device == nil {
+		} else if device == nil || device.OrgId != accessToken.OrgId {
 			s.writeError(c, http.StatusNotFound, errors.New("device not found"))
 			return
 		} else if device.IsRegistered() {
@@ -103,7 +114,12 @@ func (s *Server) handleRegister(c *gin.Context) {
 	}
 	device.Name = req.Name
 	device.Token = genDeviceToken()
-	if err := accessToken.BindDevice(device); err != nil {
+	device.NextToken = ""
+	device.RotateToken = false
+	if err := accessToken.BindDevice(device); errors.Is(err, model.ErrAccessTokenUsedUp) {
+		s.writeError(c, http.StatusConflict, errors.New("token already bound"))
+		return
+	} else if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
@@ -157,25 +173,33 @@ func (s *Server) handleCreateAccessToken(c *gin.Context) {
 	}
 
 	if req.ExpireTime == "" {
-		req.ExpireTime = time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+		req.ExpireTime = time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
 	}
-	expireTime, err := time.Parse(time.RFC3339, req.ExpireTime)
+	expireTime, err := dao.ParseTime(req.ExpireTime)
 	if err != nil {
 		s.writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	token := str.GenDeviceId(16)
+	orgId, ok := s.ownerOrgId(c)
+	if !ok {
+		return
+	}
+	if req.MaxBinds == 0 {
+		req.MaxBinds = 1
+	}
 	accessToken := &model.AccessToken{
 		AccessToken: str.RandStr(16, str.UpperAlphabet+str.Numerals),
 		ExpireTime:  expireTime,
+		OrgId:       orgId,
+		MaxBinds:    req.MaxBinds,
 	}
 	if err := model.CreateAccessToken(accessToken); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
 	resp := dao.CreateAccessTokenResponse{
-		AccessToken: token,
+		AccessToken: accessToken.AccessToken,
 	}
 	c.JSON(http.StatusOK, resp)
 }
@@ -190,6 +214,7 @@ func (s *Server) handleCreateAccessToken(c *gin.Context) {
 // @Success 200 "删除成功"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "访问令牌不存在"
 // @Failure 500 {object} ErrorResponse "内部服务器错误"
 // @Router /api/v1/access-token/{token_id} [delete]
 func (s *Server) handleDeleteAccessToken(c *gin.Context) {
@@ -198,6 +223,14 @@ func (s *Server) handleDeleteAccessToken(c *gin.Context) {
 		s.writeError(c, http.StatusBadRequest, err)
 		return
 	}
+	accessToken, err := model.GetAccessToken(tokenId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if accessToken == nil || !inRequestOrg(c, accessToken.OrgId) {
+		s.writeError(c, http.StatusNotFound, errors.New("access token not found"))
+		return
+	}
 	if err := model.DeleteAccessToken(uint(tokenId)); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -230,7 +263,7 @@ func (s *Server) handleListAccessToken(c *gin.Context) {
 		req.Limit = 10
 	}
 
-	accessTokens, total, err := model.ListAccessToken(req.Start, req.Limit)
+	accessTokens, total, err := model.ListAccessToken(requestOrgId(c), req.Start, req.Limit)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -269,7 +302,7 @@ func (s *Server) handleGetAccessToken(c *gin.Context) {
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
-	} else if accessToken == nil {
+	} else if accessToken == nil || !inRequestOrg(c, accessToken.OrgId) {
 		s.writeError(c, http.StatusNotFound, errors.New("access token not found"))
 		return
 	}
@@ -299,7 +332,7 @@ func (s *Server) handleGetDevice(c *gin.Context) {
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
-	} else if device == nil {
+	} else if device == nil || !inRequestOrg(c, device.OrgId) {
 		s.writeError(c, http.StatusNotFound, errors.New("device not found"))
 		return
 	}
@@ -307,6 +340,100 @@ func (s *Server) handleGetDevice(c *gin.Context) {
 	c.JSON(http.StatusOK, spec)
 }
 
+// handleUpdateDevice 更新设备
+// @Summary 更新设备
+// @Description 更新设备名称和最大并发执行任务数
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Param device_id path int true "设备ID"
+// @Param req body dao.UpdateDeviceRequest true "更新设备请求"
+// @Success 200 {object} dao.DeviceSpec "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "设备不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/{device_id} [put]
+func (s *Server) handleUpdateDevice(c *gin.Context) {
+	deviceId, err := strconv.Atoi(c.Param("device_id"))
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var req dao.UpdateDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	device, err := model.GetDeviceById(deviceId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if device == nil || !inRequestOrg(c, device.OrgId) {
+		s.writeError(c, http.StatusNotFound, errors.New("device not found"))
+		return
+	}
+	req.UpdateModel(device)
+	if err := model.UpdateDevice(device); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.FromDeviceModel(device))
+}
+
+// handleRotateDeviceToken 轮换设备令牌
+// @Summary 轮换设备令牌
+// @Description 为当前设备签发新令牌，设备首次使用新令牌后旧令牌失效，此前旧令牌仍然有效，未使用的新令牌在再次轮换时作废
+// @Tags 设备
+// @Produce json
+// @Success 200 {object} dao.RotateDeviceTokenResponse "轮换成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/rotate-token [post]
+func (s *Server) handleRotateDeviceToken(c *gin.Context) {
+	device := c.MustGet(deviceKey).(*model.Device)
+	if err := device.SetNextToken(genDeviceToken()); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.RotateDeviceTokenResponse{Token: device.NextToken})
+}
+
+// handleForceRotateDeviceToken 强制轮换设备令牌
+// @Summary 强制轮换设备令牌
+// @Description 要求设备在下次上报状态时轮换令牌，用于令牌泄露后无需重新注册设备即可使旧令牌失效
+// @Tags 设备
+// @Produce json
+// @Param device_id path int true "设备ID"
+// @Success 200 "设置成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "设备不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/{device_id}/rotate-token [put]
+func (s *Server) handleForceRotateDeviceToken(c *gin.Context) {
+	deviceId, err := strconv.Atoi(c.Param("device_id"))
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	device, err := model.GetDeviceById(deviceId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if device == nil || !inRequestOrg(c, device.OrgId) {
+		s.writeError(c, http.StatusNotFound, errors.New("device not found"))
+		return
+	}
+	if err := model.ForceTokenRotation(device.Id); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
 // handleListDevices 列出设备
 // @Summary 列出设备
 // @Description 列出设备
@@ -315,6 +442,7 @@ func (s *Server) handleGetDevice(c *gin.Context) {
 // @Produce json
 // @Param start query int true "分页起始位置"
 // @Param limit query int true "分页每页数量"
+// @Param state query string false "设备状态，online、degraded 或 offline"
 // @Success 200 {object} dao.ListDeviceResponse "列出成功"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权"
@@ -332,7 +460,9 @@ func (s *Server) handleListDevices(c *gin.Context) {
 		req.Limit = 10
 	}
 
-	devices, total, err := model.ListDevices(req.Start, req.Limit)
+	filter := req.Filter()
+	filter.OrgId = requestOrgId(c)
+	devices, total, err := model.ListDevices(filter, req.Start, req.Limit)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -349,6 +479,53 @@ func (s *Server) handleListDevices(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// handleListDeviceInventory 列出设备软件版本
+// @Summary 列出设备软件版本
+// @Description 按操作系统和各组件版本筛选设备，并按 lumina 版本统计设备数，用于规划升级
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Param start query int false "分页起始位置"
+// @Param limit query int false "分页每页数量"
+// @Param os query string false "操作系统，模糊匹配"
+// @Param kernel query string false "内核版本前缀"
+// @Param luminaVersion query string false "lumina 版本前缀，如 v0.3"
+// @Param ffmpegVersion query string false "ffmpeg 版本前缀"
+// @Param opencvVersion query string false "OpenCV 版本前缀"
+// @Param tritonVersion query string false "Triton 版本前缀"
+// @Success 200 {object} dao.ListDeviceInventoryResponse "列出成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/inventory [get]
+func (s *Server) handleListDeviceInventory(c *gin.Context) {
+	var req dao.ListDeviceInventoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	filter := req.Filter()
+	filter.OrgId = requestOrgId(c)
+	devices, total, versions, err := model.ListDeviceInventory(filter, req.Start, req.Limit)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := dao.ListDeviceInventoryResponse{
+		Items:          make([]dao.DeviceInventorySpec, 0, len(devices)),
+		Total:          total,
+		LuminaVersions: versions,
+	}
+	for i := range devices {
+		resp.Items = append(resp.Items, dao.FromDeviceInventoryModel(&devices[i]))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
 // handleDeleteDevice 删除设备
 // @Summary 删除设备
 // @Description 删除设备
@@ -359,6 +536,7 @@ func (s *Server) handleListDevices(c *gin.Context) {
 // @Success 200 "删除成功"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "设备不存在"
 // @Failure 500 {object} ErrorResponse "内部服务器错误"
 // @Router /api/v1/device/{device_id} [delete]
 func (s *Server) handleDeleteDevice(c *gin.Context) {
@@ -367,6 +545,14 @@ func (s *Server) handleDeleteDevice(c *gin.Context) {
 		s.writeError(c, http.StatusBadRequest, err)
 		return
 	}
+	device, err := model.GetDeviceById(deviceId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if device == nil || !inRequestOrg(c, device.OrgId) {
+		s.writeError(c, http.StatusNotFound, errors.New("device not found"))
+		return
+	}
 	if err := model.DeleteDevice(uint(deviceId)); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -376,48 +562,59 @@ func (s *Server) handleDeleteDevice(c *gin.Context) {
 
 // handleGetDeviceJobs 获取设备的作业列表
 // @Summary 获取设备的作业列表
-// @Description 获取设备的作业列表
+// @Description 获取设备的作业列表，响应带有 ETag，仅在作业增删或更新时变化。
+// @Description 请求带 If-None-Match 且作业未变化时返回 304，watch=true 时等待作业变化或超时后再返回，用于设备及时获取作业变更
 // @Tags 设备
 // @Accept json
 // @Produce json
 // @Param device_id path int true "设备ID"
+// @Param watch query bool false "是否等待作业变化"
+// @Param timeout query int false "等待的最长秒数，默认 30，最大 60"
+// @Param If-None-Match header string false "上次响应的 ETag"
 // @Success 200 {object} dao.ListJobsResponse "获取成功"
+// @Success 304 "作业未变化"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权"
 // @Failure 500 {object} ErrorResponse "内部服务器错误"
 // @Router /api/v1/device/jobs [get]
 func (s *Server) handleGetDeviceJobs(c *gin.Context) {
 	device := c.MustGet(deviceKey).(*model.Device)
-	jobs, total, err := model.ListJobsByDeviceId(device.Id, 0, 1000)
-	if err != nil {
-		s.writeError(c, http.StatusInternalServerError, err)
-		return
-	}
-	resp := dao.ListJobsResponse{
-		Items: make([]dao.JobSpec, 0, len(jobs)),
-		Total: total,
-	}
-	for _, j := range jobs {
-		j.DeviceId = 0 // remove device info
-		spec, err := dao.FromJobModel(&j)
+	s.serveWatched(c, model.WatchKeyJobs, func(ctx context.Context) (any, string, error) {
+		jobs, total, err := model.ListJobsByDeviceId(device.Id, 0, 1000)
 		if err != nil {
-			s.writeError(c, http.StatusInternalServerError, err)
-			return
+			return nil, "", err
 		}
-		resp.Items = append(resp.Items, *spec)
-	}
-	c.JSON(http.StatusOK, resp)
+		resp := dao.ListJobsResponse{
+			Items: make([]dao.JobSpec, 0, len(jobs)),
+			Total: total,
+		}
+		// the runtime reported by the device leaves UpdateTime untouched and
+		// does not change the ETag
+		parts := make([]string, 0, len(jobs))
+		for _, j := range jobs {
+			j.DeviceId = 0 // remove device info
+			j.DeviceGroupId = 0
+			spec, err := dao.FromJobModel(&j)
+			if err != nil {
+				return nil, "", err
+			}
+			resp.Items = append(resp.Items, *spec)
+			parts = append(parts, j.Uuid+"@"+j.UpdateTime.Format(time.RFC3339Nano))
+		}
+		sort.Strings(parts)
+		return resp, watchETag(parts...), nil
+	})
 }
 
 // handleReportDeviceStatus 上报设备状态
 // @Summary 上报设备状态
-// @Description 上报设备状态
+// @Description 上报设备状态，对象存储不可用时可附带最多 4 个不超过 64KB 的小文件，服务端临时保存 24 小时
 // @Tags 设备
 // @Accept json
 // @Produce json
 // @Param device_id path int true "设备ID"
 // @Param req body dao.DeviceStatus true "设备状态"
-// @Success 200 "上报成功"
+// @Success 200 {object} dao.DeviceStatusResponse "上报成功"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权"
 // @Failure 500 {object} ErrorResponse "内部服务器错误"
@@ -429,57 +626,393 @@ func (s *Server) handleReportDeviceStatus(c *gin.Context) {
 		return
 	}
 	device := c.MustGet(deviceKey).(*model.Device)
-	for jobUuid, status := range req.JobStatus {
-		job, err := model.GetJobByUuid(jobUuid)
+	if len(req.Artifacts) > 0 {
+		s.saveArtifacts(c, device, req.Artifacts)
+		// the buffer only needs the status
+		req.Artifacts = nil
+	}
+	if len(req.SigningKey) > 0 {
+		s.pinSigningKey(device, req.SigningKey)
+		req.SigningKey = nil
+	}
+	s.statusBuffer.Add(device.Id, &req)
+	c.JSON(http.StatusOK, dao.DeviceStatusResponse{
+		MaxExecutors: device.MaxExecutors,
+		RotateToken:  device.RotateToken,
+	})
+}
+
+// saveArtifacts keeps the artifacts sent along a status report. They are
+// best effort and never fail the report.
+func (s *Server) saveArtifacts(ctx context.Context, device *model.Device, artifacts []dao.DeviceArtifact) {
+	for _, a := range artifacts {
+		job, err := model.GetJobByUuid(a.JobUuid)
 		if err != nil {
-			s.logger.WithError(err).Errorf("get job %s failed", jobUuid)
-			continue
-		} else if job == nil {
-			s.logger.Errorf("job %s not found", jobUuid)
+			s.logger.WithError(err).Errorf("get job %s failed", a.JobUuid)
 			continue
 		}
-		if job.Status == status.ExectorStatus {
+		// group jobs are trusted as their status is
+		if job == nil || job.DeviceId != device.Id && job.DeviceGroupId == 0 {
+			s.logger.Warnf("device %s sent an artifact of job %s not on it", device.Uuid, a.JobUuid)
 			continue
 		}
-
-		job.Status = status.ExectorStatus
-		if err := model.UpdateJobStatus(job.Id, job.Status); err != nil {
-			s.logger.WithError(err).Errorf("update job %s failed", jobUuid)
-			continue
+		t := time.Unix(0, a.Timestamp)
+		if a.Timestamp == 0 {
+			t = time.Now()
+		}
+		if err := model.SaveJobArtifact(ctx, job.Uuid, &model.JobArtifact{
+			Kind:        a.Kind,
+			ContentType: a.ContentType,
+			Data:        a.Data,
+			DeviceId:    device.Id,
+			Time:        t,
+		}); err != nil {
+			s.logger.WithError(err).Errorf("save %s of job %s failed", a.Kind, a.JobUuid)
 		}
 	}
-	device.LastPingTime = sql.NullTime{Time: time.Now(), Valid: true}
-	if err := model.UpdateDevice(device); err != nil {
-		s.logger.WithError(err).Errorf("update device %d failed", device.Id)
+}
+
+// pinSigningKey pins the first signing key a device reports, a different key
+// is refused so that a stolen device token cannot sign as the device. It is
+// best effort and never fails the report.
+func (s *Server) pinSigningKey(device *model.Device, pub []byte) {
+	fingerprint := dao.KeyFingerprint(pub)
+	pinned, err := model.PinDeviceSigningKey(device, &model.DeviceSigningKey{
+		DeviceUuid:  device.Uuid,
+		Fingerprint: fingerprint,
+		PublicKey:   pub,
+	})
+	if err != nil {
+		s.logger.WithError(err).Errorf("pin signing key of device %s failed", device.Uuid)
+	} else if !pinned {
+		s.logger.Warnf("device %s reported signing key %s but has %s pinned", device.Uuid, fingerprint, device.SigningKey)
 	}
-	c.JSON(http.StatusOK, gin.H{})
 }
 
 // handleGetDevicePreviewTasks 获取设备的预览任务列表
 // @Summary 获取设备的预览任务列表
-// @Description 获取设备的预览任务列表
+// @Description 获取设备的预览任务列表，响应带有 ETag，请求带 If-None-Match 且预览任务未变化时返回 304，
+// @Description watch=true 时等待预览任务变化或超时后再返回
 // @Tags 设备
 // @Accept json
 // @Produce json
 // @Param device_id path int true "设备ID"
+// @Param watch query bool false "是否等待预览任务变化"
+// @Param timeout query int false "等待的最长秒数，默认 30，最大 60"
+// @Param If-None-Match header string false "上次响应的 ETag"
 // @Success 200 {object} dao.ListPreviewTasksResponse "获取成功"
+// @Success 304 "预览任务未变化"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权"
 // @Failure 500 {object} ErrorResponse "内部服务器错误"
 // @Router /api/v1/device/preview-tasks [get]
 func (s *Server) handleGetDevicePreviewTasks(c *gin.Context) {
 	device := c.MustGet(deviceKey).(*model.Device)
-	previewTasks, err := model.GetPreviewTasksByDeviceUuid(c, device.Uuid)
+	s.serveWatched(c, model.WatchKeyPreviewTasks(device.Uuid), func(ctx context.Context) (any, string, error) {
+		previewTasks, err := model.GetPreviewTasksByDeviceUuid(ctx, device.Uuid)
+		if err != nil {
+			return nil, "", err
+		}
+		sort.Slice(previewTasks, func(i, j int) bool {
+			return previewTasks[i].TaskUuid < previewTasks[j].TaskUuid
+		})
+		resp := dao.ListPreviewTasksResponse{
+			Items: make([]dao.PreviewTask, 0, len(previewTasks)),
+			Total: int64(len(previewTasks)),
+		}
+		for _, t := range previewTasks {
+			resp.Items = append(resp.Items, *dao.FromPreviewTaskModel(t))
+		}
+		data, err := json.Marshal(resp.Items)
+		if err != nil {
+			return nil, "", err
+		}
+		return resp, watchETag(string(data)), nil
+	})
+}
+
+// handleGetDeviceTestDetectTasks 获取设备的测试检测任务列表
+// @Summary 获取设备的测试检测任务列表
+// @Description 获取设备的测试检测任务列表
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Success 200 {object} dao.ListTestDetectTasksResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/test-detect-tasks [get]
+func (s *Server) handleGetDeviceTestDetectTasks(c *gin.Context) {
+	device := c.MustGet(deviceKey).(*model.Device)
+	tasks, err := model.GetTestDetectTasksByDeviceUuid(c, device.Uuid)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
-	resp := dao.ListPreviewTasksResponse{
-		Items: make([]dao.PreviewTask, 0, len(previewTasks)),
-		Total: int64(len(previewTasks)),
+	resp := dao.ListTestDetectTasksResponse{
+		Items: make([]dao.TestDetectTask, 0, len(tasks)),
+		Total: int64(len(tasks)),
 	}
-	for _, t := range previewTasks {
-		resp.Items = append(resp.Items, *dao.FromPreviewTaskModel(t))
+	for _, t := range tasks {
+		resp.Items = append(resp.Items, *dao.FromTestDetectTaskModel(t))
 	}
 	c.JSON(http.StatusOK, resp)
 }
+
+// handleReportTestDetectResult 上报测试检测结果
+// @Summary 上报测试检测结果
+// @Description 上报测试检测结果
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Param req body dao.TestDetectResult true "测试检测结果"
+// @Success 200 "上报成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "测试检测任务不存在或未分配给该设备"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/test-detect-result [post]
+func (s *Server) handleReportTestDetectResult(c *gin.Context) {
+	var req dao.TestDetectResult
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	device := c.MustGet(deviceKey).(*model.Device)
+	// only the device the task was assigned to reports its result
+	if ok, err := model.TakeTestDetectTask(c, device.Uuid, req.TaskUuid); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if !ok {
+		s.writeError(c, http.StatusNotFound, errors.New("test detect task not found"))
+		return
+	}
+	if err := model.SetTestDetectResult(c, req.ToModel()); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handleGetDeviceSnapshotTasks 获取设备的快照任务列表
+// @Summary 获取设备的快照任务列表
+// @Description 获取设备的快照任务列表
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Success 200 {object} dao.ListSnapshotTasksResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/snapshot-tasks [get]
+func (s *Server) handleGetDeviceSnapshotTasks(c *gin.Context) {
+	device := c.MustGet(deviceKey).(*model.Device)
+	tasks, err := model.GetSnapshotTasksByDeviceUuid(c, device.Uuid)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListSnapshotTasksResponse{
+		Items: make([]dao.SnapshotTask, 0, len(tasks)),
+		Total: int64(len(tasks)),
+	}
+	for _, t := range tasks {
+		resp.Items = append(resp.Items, *dao.FromSnapshotTaskModel(t))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleReportSnapshotResult 上报快照结果
+// @Summary 上报快照结果
+// @Description 上报快照结果
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Param req body dao.SnapshotResult true "快照结果"
+// @Success 200 "上报成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/snapshot-result [post]
+func (s *Server) handleReportSnapshotResult(c *gin.Context) {
+	var req dao.SnapshotResult
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	device := c.MustGet(deviceKey).(*model.Device)
+	if err := model.DeleteSnapshotTask(c, device.Uuid, req.TaskUuid); err != nil {
+		s.logger.WithError(err).Warnf("delete snapshot task %s failed", req.TaskUuid)
+	}
+	if err := model.SetSnapshotResult(c, req.ToModel()); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handleGetDeviceCameraProbeTasks 获取设备的摄像头探测任务列表
+// @Summary 获取设备的摄像头探测任务列表
+// @Description 获取设备的摄像头探测任务列表
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Success 200 {object} dao.ListCameraProbeTasksResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/camera-probe-tasks [get]
+func (s *Server) handleGetDeviceCameraProbeTasks(c *gin.Context) {
+	device := c.MustGet(deviceKey).(*model.Device)
+	tasks, err := model.GetCameraProbeTasksByDeviceUuid(c, device.Uuid)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListCameraProbeTasksResponse{
+		Items: make([]dao.CameraProbeTask, 0, len(tasks)),
+		Total: int64(len(tasks)),
+	}
+	for _, t := range tasks {
+		resp.Items = append(resp.Items, *dao.FromCameraProbeTaskModel(t))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleReportCameraProbeResult 上报摄像头探测结果
+// @Summary 上报摄像头探测结果
+// @Description 上报摄像头探测结果
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Param req body dao.CameraProbeResult true "探测结果"
+// @Success 200 "上报成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/camera-probe-result [post]
+func (s *Server) handleReportCameraProbeResult(c *gin.Context) {
+	var req dao.CameraProbeResult
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	device := c.MustGet(deviceKey).(*model.Device)
+	if err := model.DeleteCameraProbeTask(c, device.Uuid, req.TaskUuid); err != nil {
+		s.logger.WithError(err).Warnf("delete camera probe task %s failed", req.TaskUuid)
+	}
+	if err := model.SetCameraProbeResult(c, req.ToModel()); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handleGetDeviceTalkDownTasks 获取设备的喊话任务列表
+// @Summary 获取设备的喊话任务列表
+// @Description 获取设备的喊话任务列表
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Success 200 {object} dao.ListTalkDownTasksResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/talk-down-tasks [get]
+func (s *Server) handleGetDeviceTalkDownTasks(c *gin.Context) {
+	device := c.MustGet(deviceKey).(*model.Device)
+	tasks, err := model.GetTalkDownTasksByDeviceUuid(c, device.Uuid)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListTalkDownTasksResponse{
+		Items: make([]dao.TalkDownTask, 0, len(tasks)),
+		Total: int64(len(tasks)),
+	}
+	for _, t := range tasks {
+		resp.Items = append(resp.Items, *dao.FromTalkDownTaskModel(t))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleReportTalkDownResult 上报喊话结果
+// @Summary 上报喊话结果
+// @Description 上报喊话结果
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Param req body dao.TalkDownResult true "喊话结果"
+// @Success 200 "上报成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/talk-down-result [post]
+func (s *Server) handleReportTalkDownResult(c *gin.Context) {
+	var req dao.TalkDownResult
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	device := c.MustGet(deviceKey).(*model.Device)
+	if err := model.DeleteTalkDownTask(c, device.Uuid, req.TaskUuid); err != nil {
+		s.logger.WithError(err).Warnf("delete talk-down task %s failed", req.TaskUuid)
+	}
+	if err := model.SetTalkDownResult(c, req.ToModel()); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handleGetDeviceOnvifDiscoverTasks 获取设备的 ONVIF 扫描任务列表
+// @Summary 获取设备的 ONVIF 扫描任务列表
+// @Description 获取设备的 ONVIF 扫描任务列表
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Success 200 {object} dao.ListOnvifDiscoverTasksResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/onvif-discover-tasks [get]
+func (s *Server) handleGetDeviceOnvifDiscoverTasks(c *gin.Context) {
+	device := c.MustGet(deviceKey).(*model.Device)
+	tasks, err := model.GetOnvifDiscoverTasksByDeviceUuid(c, device.Uuid)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListOnvifDiscoverTasksResponse{
+		Items: make([]dao.OnvifDiscoverTask, 0, len(tasks)),
+		Total: int64(len(tasks)),
+	}
+	for _, t := range tasks {
+		resp.Items = append(resp.Items, *dao.FromOnvifDiscoverTaskModel(t))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleReportOnvifDiscoverResult 上报 ONVIF 扫描结果
+// @Summary 上报 ONVIF 扫描结果
+// @Description 上报 ONVIF 扫描结果
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Param req body dao.OnvifDiscoverResult true "扫描结果"
+// @Success 200 "上报成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/onvif-discover-result [post]
+func (s *Server) handleReportOnvifDiscoverResult(c *gin.Context) {
+	var req dao.OnvifDiscoverResult
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	device := c.MustGet(deviceKey).(*model.Device)
+	if err := model.DeleteOnvifDiscoverTask(c, device.Uuid, req.TaskUuid); err != nil {
+		s.logger.WithError(err).Warnf("delete onvif discover task %s failed", req.TaskUuid)
+	}
+	if err := model.SetOnvifDiscoverResult(c, req.ToModel()); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
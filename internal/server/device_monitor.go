@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/model"
+)
+
+const deviceStateCheckInterval = 15 * time.Second
+
+// DeviceMonitor follows the state of the registered devices, it opens a
+// health event when a device goes offline and resolves it once the device
+// reports again or is unregistered. State changes are pushed to the
+// dashboard through the event hub.
+type DeviceMonitor struct {
+	logger *logrus.Entry
+	events *EventHub
+	// states are the states at the last check, nil before the first one
+	states map[int]model.DeviceState
+}
+
+func NewDeviceMonitor(logger *logrus.Entry, events *EventHub) *DeviceMonitor {
+	return &DeviceMonitor{
+		logger: logger.WithField("component", "deviceMonitor"),
+		events: events,
+	}
+}
+
+func (m *DeviceMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(deviceStateCheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := m.check(time.Now()); err != nil {
+			m.logger.WithError(err).Error("check device states failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *DeviceMonitor) check(now time.Time) error {
+	devices, err := model.ListRegisteredDevices()
+	if err != nil {
+		return err
+	}
+
+	// devices already offline when the server starts are not reported, their
+	// events are kept open if they went offline before
+	first := m.states == nil
+	states := make(map[int]model.DeviceState, len(devices))
+	for _, d := range devices {
+		state := d.State(now)
+		states[d.Id] = state
+		prev, seen := m.states[d.Id]
+		switch {
+		case state == model.DeviceStateOffline && seen && prev != model.DeviceStateOffline:
+			if err := m.openOfflineEvent(&d, now); err != nil {
+				m.logger.WithError(err).Errorf("open offline event of device %s failed", d.Uuid)
+			}
+		case state != model.DeviceStateOffline && (first || prev == model.DeviceStateOffline):
+			m.resolveOfflineEvent(d.Id)
+		}
+		if seen && prev != state {
+			m.logger.Infof("device %s is %s, was %s", d.Uuid, state, prev)
+			m.events.publishDeviceState(&d, state, prev, now)
+		}
+	}
+	for id, prev := range m.states {
+		if _, ok := states[id]; !ok && prev == model.DeviceStateOffline {
+			m.resolveOfflineEvent(id)
+		}
+	}
+	m.states = states
+	return nil
+}
+
+func (m *DeviceMonitor) openOfflineEvent(d *model.Device, now time.Time) error {
+	if e, err := model.GetOpenDeviceHealthEvent(model.HealthEventDeviceOffline, d.Id); err != nil || e != nil {
+		return err
+	}
+	name := d.Name
+	if name == "" {
+		name = d.Uuid
+	}
+	since := d.LastPingTime.Time
+	if !d.LastPingTime.Valid || since.IsZero() {
+		since = now
+	}
+	m.logger.Warnf("device %s went offline, last report at %s", d.Uuid, since.UTC().Format(time.RFC3339))
+	return model.SaveHealthEvent(&model.HealthEvent{
+		Kind:     model.HealthEventDeviceOffline,
+		DeviceId: d.Id,
+		Summary:  fmt.Sprintf("device %s has not reported since %s", name, since.UTC().Format(time.RFC3339)),
+		Observed: now.Sub(since).Seconds(),
+		Expected: model.DeviceStateThresholds.Offline.Seconds(),
+		// the device went offline at its last report
+		StartTime: since,
+		EndTime:   now,
+	})
+}
+
+func (m *DeviceMonitor) resolveOfflineEvent(deviceId int) {
+	e, err := model.GetOpenDeviceHealthEvent(model.HealthEventDeviceOffline, deviceId)
+	if err == nil && e != nil {
+		err = model.ResolveHealthEvent(e.Id)
+	}
+	if err != nil {
+		m.logger.WithError(err).Errorf("resolve offline event of device %d failed", deviceId)
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const (
+	escalationInterval = 30 * time.Second
+	// escalationWindow is how late a step still notifies, the alerts that
+	// went unacknowledged while the servers were down are not escalated
+	// long after
+	escalationWindow = 10 * time.Minute
+)
+
+// escalate notifies the channels of the steps without delay of the policies
+// accepting the new alert.
+func (n *Notifier) escalate(ctx context.Context, event *dao.AlertEvent) error {
+	ps, err := model.ListEnabledEscalationPolicies()
+	if err != nil {
+		return err
+	}
+	for i := range ps {
+		p := &ps[i]
+		if !p.Accept(event.Message.JobId, event.CameraId, event.Severity) {
+			continue
+		}
+		for _, step := range p.Steps {
+			if step.Delay != 0 {
+				continue
+			}
+			if err := n.dispatchStep(ctx, step, event); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// escalateUnacked notifies the channels of the delayed steps of the alerts
+// still unacknowledged when their delay has passed.
+func (n *Notifier) escalateUnacked(ctx context.Context, hub *AlertHub, now time.Time) error {
+	ps, err := model.ListEnabledEscalationPolicies()
+	if err != nil {
+		return err
+	}
+	alerts := make(map[int][]*model.AlertMessage)
+	events := make(map[int]*dao.AlertEvent)
+	for i := range ps {
+		p := &ps[i]
+		for _, step := range p.Steps {
+			if step.Delay == 0 {
+				continue
+			}
+			due, ok := alerts[step.Delay]
+			if !ok {
+				end := now.Add(-time.Duration(step.Delay) * time.Minute)
+				if due, err = model.ListUnackedAlertMessages(end.Add(-escalationWindow), end); err != nil {
+					return err
+				}
+				alerts[step.Delay] = due
+			}
+			for _, alert := range due {
+				event, ok := events[alert.Id]
+				if !ok {
+					if event, err = hub.toAlertEvent(alert); err != nil {
+						n.logger.WithError(err).Errorf("build alert event %d failed", alert.Id)
+						continue
+					}
+					events[alert.Id] = event
+				}
+				if !p.Accept(event.Message.JobId, event.CameraId, event.Severity) {
+					continue
+				}
+				if err := n.dispatchStep(ctx, step, event); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// dispatchStep delivers the alert through the channel of the step whatever
+// the filters of the channel, unless the channel is disabled.
+func (n *Notifier) dispatchStep(ctx context.Context, step model.EscalationStep, event *dao.AlertEvent) error {
+	ch, err := model.GetNotificationChannelById(step.ChannelId)
+	if err != nil {
+		return err
+	} else if ch == nil || !ch.Enabled {
+		return nil
+	}
+	return n.dispatch(ctx, ch, event)
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"lumina/internal/dao"
+)
+
+// sseKeepAlive is the interval of the comments keeping idle streams open
+// through proxies.
+const sseKeepAlive = 30 * time.Second
+
+// handleEvents 推送实时事件
+// @Summary 推送实时事件
+// @Description 通过 Server-Sent Events 推送设备在线状态变化、任务状态变更和新告警，事件名为事件类型，数据为 JSON，用于仪表盘替代定时刷新列表
+// @Tags 事件
+// @Produce text/event-stream
+// @Param kinds query string false "订阅的事件类型，逗号分隔：device_state、job_status、alert，为空时订阅全部"
+// @Success 200 {object} dao.Event "事件"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Router /api/v1/events [get]
+func (s *Server) handleEvents(c *gin.Context) {
+	var filter dao.EventFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	filter.OrgId = requestOrgId(c)
+
+	sub := s.eventHub.subscribe(filter)
+	defer s.eventHub.unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// disables the response buffering of nginx
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.draining:
+			// the client reconnects to another server
+			return
+		case event := <-sub.ch:
+			c.SSEvent(string(event.Kind), event)
+		case <-ticker.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const (
+	eventHubPollInterval = 2 * time.Second
+	eventHubBatchSize    = 100
+	eventSubscriberQueue = 64
+)
+
+type eventSubscriber struct {
+	filter dao.EventFilter
+	ch     chan *dao.Event
+}
+
+// EventHub fans the changes the dashboard follows out to the subscribed
+// sessions: device state changes seen by the device monitor, job status
+// transitions polled from their history and new alerts of the alert hub.
+type EventHub struct {
+	mu          sync.RWMutex
+	subscribers map[*eventSubscriber]struct{}
+	logger      *logrus.Entry
+}
+
+func NewEventHub(logger *logrus.Entry) *EventHub {
+	return &EventHub{
+		subscribers: make(map[*eventSubscriber]struct{}),
+		logger:      logger.WithField("component", "eventHub"),
+	}
+}
+
+func (h *EventHub) subscribe(filter dao.EventFilter) *eventSubscriber {
+	sub := &eventSubscriber{
+		filter: filter,
+		ch:     make(chan *dao.Event, eventSubscriberQueue),
+	}
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *EventHub) unsubscribe(sub *eventSubscriber) {
+	h.mu.Lock()
+	delete(h.subscribers, sub)
+	h.mu.Unlock()
+}
+
+func (h *EventHub) Run(ctx context.Context, alertHub *AlertHub) {
+	alerts := alertHub.subscribe(dao.AlertEventFilter{})
+	defer alertHub.unsubscribe(alerts)
+
+	lastId, err := model.GetLatestJobStatusHistoryId()
+	if err != nil {
+		h.logger.WithError(err).Error("get latest job status history id failed")
+	}
+
+	ticker := time.NewTicker(eventHubPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case alert := <-alerts.ch:
+			h.broadcast(&dao.Event{Kind: dao.EventKindAlert, Time: alert.CreateTime, Alert: alert, OrgId: alert.OrgId})
+		case <-ticker.C:
+			lastId = h.pollJobStatus(lastId)
+		}
+	}
+}
+
+// pollJobStatus broadcasts the job status transitions after lastId and
+// returns the id of the last one.
+func (h *EventHub) pollJobStatus(lastId int) int {
+	hs, err := model.GetJobStatusHistoryAfterId(lastId, eventHubBatchSize)
+	if err != nil {
+		h.logger.WithError(err).Error("poll job status history failed")
+		return lastId
+	}
+	jobs := make(map[int]*model.Job)
+	for _, m := range hs {
+		lastId = m.Id
+		job, ok := jobs[m.JobId]
+		if !ok {
+			var err error
+			job, err = model.GetJobById(m.JobId)
+			if err != nil {
+				h.logger.WithError(err).Errorf("get job %d failed", m.JobId)
+			}
+			jobs[m.JobId] = job
+		}
+		var uuid string
+		var orgId int
+		if job != nil {
+			uuid, orgId = job.Uuid, job.OrgId
+		}
+		transition := dao.FromJobStatusHistoryModel(&m)
+		h.broadcast(&dao.Event{
+			Kind: dao.EventKindJobStatus,
+			Time: transition.CreateTime,
+			Job: &dao.JobStatusEvent{
+				JobId:      m.JobId,
+				JobUuid:    uuid,
+				Transition: *transition,
+			},
+			OrgId: orgId,
+		})
+	}
+	return lastId
+}
+
+// publishDeviceState broadcasts a change of the state of the device.
+func (h *EventHub) publishDeviceState(d *model.Device, state, prev model.DeviceState, now time.Time) {
+	h.broadcast(&dao.Event{
+		Kind: dao.EventKindDeviceState,
+		Time: now.UTC().Format(time.RFC3339),
+		Device: &dao.DeviceStateEvent{
+			DeviceId:   d.Id,
+			DeviceUuid: d.Uuid,
+			DeviceName: d.Name,
+			State:      state,
+			PrevState:  prev,
+		},
+		OrgId: d.OrgId,
+	})
+}
+
+func (h *EventHub) broadcast(event *dao.Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subscribers {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			h.logger.Warnf("event subscriber queue is full, drop %s event", event.Kind)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+// handleVerifyMessage 校验消息的设备签名
+// @Summary 校验消息的设备签名
+// @Description 校验消息的设备签名，证明图片、视频和检测结果在设备采集后未被篡改：签名由设备登记的公钥签出，
+// @Description 消息内容与签名内容一致，存储桶中的文件哈希与签名时一致。只有启用证据签名的设备上报的消息带有签名
+// @Tags 消息
+// @Produce json
+// @Param message_id path string true "消息message_id"
+// @Param checkMedia query bool false "是否下载图片和视频校验其哈希" default(true)
+// @Success 200 {object} dao.MessageVerification "校验报告"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "消息不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/{message_id}/verify [get]
+func (s *Server) handleVerifyMessage(c *gin.Context) {
+	var req dao.VerifyMessageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	message := c.MustGet(messageKey).(*model.Message)
+
+	report := &dao.MessageVerification{
+		MessageId:  message.Id,
+		VerifyTime: time.Now().UTC().Format(time.RFC3339),
+	}
+	e := message.Evidence
+	if e == nil {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	report.Signed = true
+	report.DeviceUuid = e.DeviceUuid
+	report.KeyFingerprint = e.KeyFingerprint
+
+	key, err := model.GetDeviceSigningKey(e.KeyFingerprint)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if key == nil || key.DeviceUuid != e.DeviceUuid {
+		report.Error = "signing key is not registered for the device"
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	report.KeyRegisterTime = key.CreateTime.UTC().Format(time.RFC3339)
+	report.SignatureValid = len(key.PublicKey) == ed25519.PublicKeySize &&
+		ed25519.Verify(key.PublicKey, e.Payload, e.Signature)
+
+	var payload dao.EvidencePayload
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		report.Error = fmt.Sprintf("invalid signed payload: %v", err)
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	mismatches, err := evidenceMismatches(message, &payload)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	report.Mismatches = mismatches
+	report.ContentMatches = len(mismatches) == 0
+
+	mediaMatches := true
+	if req.CheckMedia == nil || *req.CheckMedia {
+		for _, m := range []struct{ path, sha256 string }{
+			{payload.ImagePath, payload.ImageSha256},
+			{payload.VideoPath, payload.VideoSha256},
+		} {
+			if m.path == "" {
+				continue
+			}
+			v := s.verifyMedia(c, m.path, m.sha256)
+			mediaMatches = mediaMatches && v.Matches
+			report.Media = append(report.Media, v)
+		}
+	}
+	report.Verified = report.SignatureValid && report.ContentMatches && mediaMatches
+
+	c.JSON(http.StatusOK, report)
+}
+
+// evidenceMismatches lists the fields of the message that differ from the
+// signed payload. The timestamp is compared to the second, the precision of
+// the datetime column.
+func evidenceMismatches(m *model.Message, p *dao.EvidencePayload) ([]string, error) {
+	var mismatches []string
+	e := m.Evidence
+	if p.DeviceUuid != e.DeviceUuid {
+		mismatches = append(mismatches, "deviceUuid")
+	}
+	job, err := model.GetJobById(m.JobId)
+	if err != nil {
+		return nil, err
+	} else if job == nil || job.Uuid != p.JobUuid {
+		mismatches = append(mismatches, "jobId")
+	}
+	if m.Timestamp.Unix() != time.Unix(0, p.Timestamp).Unix() {
+		mismatches = append(mismatches, "timestamp")
+	}
+	if m.ImagePath != p.ImagePath || e.ImageSha256 != p.ImageSha256 {
+		mismatches = append(mismatches, "imagePath")
+	}
+	if m.VideoPath != p.VideoPath || e.VideoSha256 != p.VideoSha256 {
+		mismatches = append(mismatches, "videoPath")
+	}
+	signed := make(model.DetectionBoxSlice, len(p.DetectBoxes))
+	for i, b := range p.DetectBoxes {
+		signed[i] = b.ToModel()
+	}
+	if len(signed) != len(m.DetectBoxes) || len(signed) > 0 && !reflect.DeepEqual(signed, m.DetectBoxes) {
+		mismatches = append(mismatches, "detectBoxes")
+	}
+	if m.FrameWidth != p.FrameWidth || m.FrameHeight != p.FrameHeight {
+		mismatches = append(mismatches, "frameSize")
+	}
+	return mismatches, nil
+}
+
+// verifyMedia hashes the object in the bucket and compares it to the hash
+// the device signed.
+func (s *Server) verifyMedia(ctx context.Context, path, expected string) dao.MediaVerification {
+	v := dao.MediaVerification{Path: path, ExpectedSha256: expected}
+	obj, err := s.minioCli.GetObject(ctx, s.conf.S3.Bucket, strings.TrimPrefix(path, "/"), minio.GetObjectOptions{})
+	if err != nil {
+		v.Error = err.Error()
+		return v
+	}
+	defer obj.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, obj); err != nil {
+		v.Error = err.Error()
+		return v
+	}
+	v.ActualSha256 = hex.EncodeToString(h.Sum(nil))
+	v.Matches = v.ActualSha256 == expected
+	return v
+}
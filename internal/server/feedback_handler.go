@@ -0,0 +1,179 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const (
+	minPrecisionBucket = time.Hour
+	maxPrecisionBucket = 30 * 24 * time.Hour
+	// maxPrecisionBuckets bounds the buckets a range is split into
+	maxPrecisionBuckets = 2000
+)
+
+// handleGetMessageFeedback 获取消息的人工标注
+// @Summary 获取消息的人工标注
+// @Description 获取消息检测结果是否正确的人工标注
+// @Tags 消息
+// @Produce json
+// @Param message_id path string true "消息message_id"
+// @Success 200 {object} dao.MessageFeedbackSpec "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "消息不存在或未标注"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/{message_id}/feedback [get]
+func (s *Server) handleGetMessageFeedback(c *gin.Context) {
+	message := c.MustGet(messageKey).(*model.Message)
+
+	feedback, err := model.GetMessageFeedback(message.Id)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if feedback == nil {
+		s.writeError(c, http.StatusNotFound, fmt.Errorf("message has no feedback"))
+		return
+	}
+	c.JSON(http.StatusOK, dao.FromMessageFeedbackModel(feedback))
+}
+
+// handlePutMessageFeedback 标注消息
+// @Summary 标注消息
+// @Description 标注消息的检测结果正确（true_positive）或误报（false_positive），可附带修正后的标签，
+// @Description 重复标注时覆盖之前的标注。标注用于统计任务的检测准确率趋势
+// @Tags 消息
+// @Accept json
+// @Produce json
+// @Param message_id path string true "消息message_id"
+// @Param req body dao.MessageFeedbackRequest true "标注内容"
+// @Success 200 {object} dao.MessageFeedbackSpec "标注成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "消息不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/{message_id}/feedback [put]
+func (s *Server) handlePutMessageFeedback(c *gin.Context) {
+	message := c.MustGet(messageKey).(*model.Message)
+	user := c.MustGet(userKey).(*model.User)
+
+	var req dao.MessageFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	feedback := &model.MessageFeedback{
+		MessageId:       message.Id,
+		JobId:           message.JobId,
+		MessageTime:     message.Timestamp,
+		Verdict:         req.Verdict,
+		CorrectedLabels: req.CorrectedLabels,
+		Comment:         req.Comment,
+		UserId:          user.Id,
+		Username:        user.Username,
+	}
+	if err := model.SaveMessageFeedback(feedback); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.FromMessageFeedbackModel(feedback))
+}
+
+// handleDeleteMessageFeedback 删除消息的人工标注
+// @Summary 删除消息的人工标注
+// @Description 删除消息的人工标注，该消息不再计入准确率统计
+// @Tags 消息
+// @Param message_id path string true "消息message_id"
+// @Success 204 "删除成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "消息不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/{message_id}/feedback [delete]
+func (s *Server) handleDeleteMessageFeedback(c *gin.Context) {
+	message := c.MustGet(messageKey).(*model.Message)
+
+	if err := model.DeleteMessageFeedback(message.Id); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// handleJobPrecision 任务检测准确率趋势
+// @Summary 获取任务检测准确率趋势
+// @Description 根据消息的人工标注统计任务检测准确率随时间的变化，用于发现模型效果下降。
+// @Description 只统计有标注的消息，按消息的采集时间分桶，标注保留到消息被清理之后
+// @Tags 任务
+// @Produce json
+// @Param job_id path string true "任务job_id"
+// @Param start query string false "开始时间(RFC3339 或 Unix 时间戳)，默认 30 天前"
+// @Param end query string false "结束时间(RFC3339 或 Unix 时间戳)，默认当前时间"
+// @Param bucket query string false "时间桶大小，如 6h、24h，最小 1h，最大 720h" default(24h)
+// @Success 200 {object} dao.JobPrecisionResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "任务不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/job/{job_id}/precision [get]
+func (s *Server) handleJobPrecision(c *gin.Context) {
+	job := c.MustGet(jobKey).(*model.Job)
+
+	var req dao.JobPrecisionRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Bucket == "" {
+		req.Bucket = "24h"
+	}
+
+	bucket, err := time.ParseDuration(req.Bucket)
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("invalid bucket: %w", err))
+		return
+	} else if bucket < minPrecisionBucket || bucket > maxPrecisionBucket || bucket%time.Second != 0 {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("bucket must be whole seconds between %s and %s", minPrecisionBucket, maxPrecisionBucket))
+		return
+	}
+	start, end, _, err := parseStatsRange(req.Start, req.End, "")
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Start == "" {
+		start = end.Add(-30 * 24 * time.Hour)
+	}
+	if end.Sub(start)/bucket > maxPrecisionBuckets {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("too many buckets, at most %d", maxPrecisionBuckets))
+		return
+	}
+
+	counts, err := model.GetJobFeedbackTrend(job.Id, start, end, bucket)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := dao.JobPrecisionResponse{
+		Start:  start.UTC().Format(time.RFC3339),
+		End:    end.UTC().Format(time.RFC3339),
+		Bucket: req.Bucket,
+		Points: make([]dao.PrecisionPoint, 0, len(counts)),
+	}
+	var tp, fp int64
+	for _, cnt := range counts {
+		t := start.Add(time.Duration(cnt.Bucket) * bucket).UTC().Format(time.RFC3339)
+		resp.Points = append(resp.Points, dao.NewPrecisionPoint(t, cnt.TruePositive, cnt.FalsePositive))
+		tp += cnt.TruePositive
+		fp += cnt.FalsePositive
+	}
+	resp.Total = dao.NewPrecisionPoint(resp.Start, tp, fp)
+	c.JSON(http.StatusOK, resp)
+}
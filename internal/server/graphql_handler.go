@@ -0,0 +1,330 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"lumina/internal/dao"
+	"lumina/internal/graphql"
+	"lumina/internal/model"
+)
+
+const graphqlMaxLimit = 100
+
+// graphqlList is the value of the list types, a page of items and the total.
+type graphqlList struct {
+	items any
+	total int64
+}
+
+// handleGraphQL 执行 GraphQL 查询
+// @Summary 执行 GraphQL 查询
+// @Description 在一次查询中组合设备、摄像头、任务、最近消息和任务统计，只返回选择的字段，用于减少仪表盘复杂页面的请求数，仅支持查询。
+// @Description 根查询字段：devices(state)、device(id)、cameras、camera(id)、jobs、job(id)、messages，列表字段返回 items 和 total，参数 start、limit 同 REST 接口，limit 最大 100。
+// @Description 设备可查询 cameras、jobs，摄像头可查询 jobs，任务可查询 latestMessages(limit, alerted) 和 stats(start, end, window)，其余字段同 REST 接口返回的 JSON 字段
+// @Tags GraphQL
+// @Accept json
+// @Produce json
+// @Param req body graphql.Request true "GraphQL 请求"
+// @Success 200 {object} graphql.Response "查询结果，字段错误在 errors 中返回"
+// @Failure 400 {object} graphql.Response "查询语法错误"
+// @Router /api/v1/graphql [post]
+func (s *Server) handleGraphQL(c *gin.Context) {
+	var req graphql.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	schema := s.graphqlSchema(requestOrgId(c))
+	op, err := schema.Prepare(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, graphql.Response{Errors: []*graphql.Error{{Message: err.Error()}}})
+		return
+	}
+	c.JSON(http.StatusOK, schema.Execute(c.Request.Context(), op, req.Variables))
+}
+
+// graphqlSchema returns the schema resolving the data of the organization
+// orgId.
+func (s *Server) graphqlSchema(orgId int) *graphql.Schema {
+	device := &graphql.Object{Name: "Device"}
+	camera := &graphql.Object{Name: "Camera"}
+	job := &graphql.Object{Name: "Job"}
+	message := &graphql.Object{Name: "Message"}
+	deviceList := graphqlListType("DeviceList", device)
+	cameraList := graphqlListType("CameraList", camera)
+	jobList := graphqlListType("JobList", job)
+	messageList := graphqlListType("MessageList", message)
+
+	device.Fields = map[string]*graphql.FieldDef{
+		"cameras": {Type: cameraList, Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			d := source.(*dao.DeviceSpec)
+			return s.graphqlCameras(args, model.CameraFilter{OrgId: orgId, BindDeviceId: d.Id})
+		}},
+		"jobs": {Type: jobList, Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			d := source.(*dao.DeviceSpec)
+			start, limit, err := graphqlPage(args)
+			if err != nil {
+				return nil, err
+			}
+			jobs, total, err := model.ListJobsByDeviceId(d.Id, start, limit)
+			if err != nil {
+				return nil, err
+			}
+			return graphqlJobs(jobs, total)
+		}},
+	}
+	camera.Fields = map[string]*graphql.FieldDef{
+		"jobs": {Type: jobList, Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			cam := source.(*dao.CameraSpec)
+			start, limit, err := graphqlPage(args)
+			if err != nil {
+				return nil, err
+			}
+			jobs, total, err := model.ListJobsByCameraId(cam.Id, start, limit)
+			if err != nil {
+				return nil, err
+			}
+			return graphqlJobs(jobs, total)
+		}},
+	}
+	job.Fields = map[string]*graphql.FieldDef{
+		"latestMessages": {Type: message, Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			j := source.(*dao.JobSpec)
+			limit, err := args.Int("limit", 5)
+			if err != nil {
+				return nil, err
+			} else if limit < 0 || limit > graphqlMaxLimit {
+				return nil, fmt.Errorf("limit must be between 0 and %d", graphqlMaxLimit)
+			}
+			alerted, err := args.Bool("alerted")
+			if err != nil {
+				return nil, err
+			}
+			list, err := s.graphqlMessages(orgId, j.Id, alerted, 0, limit)
+			if err != nil {
+				return nil, err
+			}
+			return list.items, nil
+		}},
+		"stats": {Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			if s.influxQuery == nil || !s.conf.InfluxDB.Enabled {
+				return nil, errors.New("influxdb not enabled")
+			}
+			j := source.(*dao.JobSpec)
+			var req dao.JobStatsRequest
+			var err error
+			if req.Start, err = args.String("start"); err != nil {
+				return nil, err
+			}
+			if req.End, err = args.String("end"); err != nil {
+				return nil, err
+			}
+			if req.Window, err = args.String("window"); err != nil {
+				return nil, err
+			}
+			start, end, window, err := parseStatsRange(req.Start, req.End, req.Window)
+			if err != nil {
+				return nil, err
+			}
+			return s.queryJobStats(ctx, &model.Job{Id: j.Id, Uuid: j.Uuid, Kind: j.Kind}, start, end, window)
+		}},
+	}
+
+	query := &graphql.Object{Name: "Query", Fields: map[string]*graphql.FieldDef{
+		"devices": {Type: deviceList, Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			start, limit, err := graphqlPage(args)
+			if err != nil {
+				return nil, err
+			}
+			state, err := args.String("state")
+			if err != nil {
+				return nil, err
+			}
+			switch model.DeviceState(state) {
+			case "", model.DeviceStateOnline, model.DeviceStateDegraded, model.DeviceStateOffline:
+			default:
+				return nil, fmt.Errorf("invalid state %s", state)
+			}
+			devices, total, err := model.ListDevices(model.DeviceFilter{OrgId: orgId, State: model.DeviceState(state)}, start, limit)
+			if err != nil {
+				return nil, err
+			}
+			items := make([]dao.DeviceSpec, 0, len(devices))
+			for _, d := range devices {
+				items = append(items, *dao.FromDeviceModel(&d))
+			}
+			return &graphqlList{items: items, total: total}, nil
+		}},
+		"device": {Type: device, Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			id, err := graphqlId(args)
+			if err != nil {
+				return nil, err
+			}
+			d, err := model.GetDeviceById(id)
+			if err != nil || d == nil || !inOrg(orgId, d.OrgId) {
+				return nil, err
+			}
+			return dao.FromDeviceModel(d), nil
+		}},
+		"cameras": {Type: cameraList, Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			var req dao.ListCamerasRequest
+			var err error
+			if req.Tag, err = args.String("tag"); err != nil {
+				return nil, err
+			}
+			if req.Name, err = args.String("name"); err != nil {
+				return nil, err
+			}
+			protocol, err := args.String("protocol")
+			if err != nil {
+				return nil, err
+			}
+			req.Protocol = model.CameraProtocol(protocol)
+			if req.BindDeviceId, err = args.Int("bindDeviceId", 0); err != nil {
+				return nil, err
+			}
+			filter := req.Filter()
+			filter.OrgId = orgId
+			return s.graphqlCameras(args, filter)
+		}},
+		"camera": {Type: camera, Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			id, err := graphqlId(args)
+			if err != nil {
+				return nil, err
+			}
+			cam, err := model.GetCameraById(id)
+			if err != nil || cam == nil || !inOrg(orgId, cam.OrgId) {
+				return nil, err
+			}
+			return dao.FromCameraModel(cam)
+		}},
+		"jobs": {Type: jobList, Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			start, limit, err := graphqlPage(args)
+			if err != nil {
+				return nil, err
+			}
+			jobs, total, err := model.ListJobs(orgId, start, limit)
+			if err != nil {
+				return nil, err
+			}
+			return graphqlJobs(jobs, total)
+		}},
+		"job": {Type: job, Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			id, err := graphqlId(args)
+			if err != nil {
+				return nil, err
+			}
+			j, err := model.GetJobById(id)
+			if err != nil || j == nil || j.Kind == model.JobKindCanary || !inOrg(orgId, j.OrgId) {
+				return nil, err
+			}
+			return dao.FromJobModel(j)
+		}},
+		"messages": {Type: messageList, Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			start, limit, err := graphqlPage(args)
+			if err != nil {
+				return nil, err
+			}
+			jobId, err := args.Int("jobId", 0)
+			if err != nil {
+				return nil, err
+			}
+			alerted, err := args.Bool("alerted")
+			if err != nil {
+				return nil, err
+			}
+			return s.graphqlMessages(orgId, jobId, alerted, start, limit)
+		}},
+	}}
+
+	return &graphql.Schema{Query: query, MaxDepth: s.conf.GraphQL.MaxDepth}
+}
+
+func graphqlListType(name string, item *graphql.Object) *graphql.Object {
+	return &graphql.Object{Name: name, Fields: map[string]*graphql.FieldDef{
+		"items": {Type: item, Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			return source.(*graphqlList).items, nil
+		}},
+		"total": {Resolve: func(ctx context.Context, source any, args graphql.Args) (any, error) {
+			return source.(*graphqlList).total, nil
+		}},
+	}}
+}
+
+// graphqlPage returns the page of a list field, 10 items by default as the
+// REST lists.
+func graphqlPage(args graphql.Args) (int, int, error) {
+	start, err := args.Int("start", 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	limit, err := args.Int("limit", 10)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start < 0 || limit < 0 || limit > graphqlMaxLimit {
+		return 0, 0, fmt.Errorf("start must be positive and limit between 0 and %d", graphqlMaxLimit)
+	}
+	return start, limit, nil
+}
+
+func graphqlId(args graphql.Args) (int, error) {
+	id, err := args.Int("id", 0)
+	if err != nil {
+		return 0, err
+	} else if id == 0 {
+		return 0, errors.New("argument id is required")
+	}
+	return id, nil
+}
+
+func (s *Server) graphqlCameras(args graphql.Args, filter model.CameraFilter) (*graphqlList, error) {
+	start, limit, err := graphqlPage(args)
+	if err != nil {
+		return nil, err
+	}
+	cams, total, err := model.ListCameras(filter, start, limit)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]dao.CameraSpec, 0, len(cams))
+	for _, cam := range cams {
+		spec, err := dao.FromCameraModel(&cam)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *spec)
+	}
+	return &graphqlList{items: items, total: total}, nil
+}
+
+func graphqlJobs(jobs []model.Job, total int64) (*graphqlList, error) {
+	items := make([]dao.JobSpec, 0, len(jobs))
+	for _, j := range jobs {
+		spec, err := dao.FromJobModel(&j)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *spec)
+	}
+	return &graphqlList{items: items, total: total}, nil
+}
+
+// graphqlMessages lists the messages as the message list API, newest first.
+func (s *Server) graphqlMessages(orgId, jobId int, alerted bool, start, limit int) (*graphqlList, error) {
+	filter := model.MessageFilter{OrgId: orgId, JobId: jobId, Alerted: alerted}
+	messages, total, err := model.ListMessages(filter, start, limit)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]dao.MessageSpec, len(messages))
+	for i, m := range messages {
+		items[i] = s.messageSpec(m)
+	}
+	return &graphqlList{items: items, total: total}, nil
+}
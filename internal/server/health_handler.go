@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+// handleListHealthEvents 列出系统健康事件
+// @Summary 列出系统健康事件
+// @Description 按创建时间倒序列出系统健康事件，如任务消息量或告警量与同一时段的通常值相差过大、设备离线
+// @Tags 系统健康
+// @Accept json
+// @Produce json
+// @Param start query int false "分页起始位置"
+// @Param limit query int false "分页每页数量"
+// @Param kind query string false "事件类型，message_volume、alert_volume、canary 或 device_offline"
+// @Param jobId query int false "任务ID"
+// @Param deviceId query int false "设备ID"
+// @Param resolved query bool false "是否已解决"
+// @Success 200 {object} dao.ListHealthEventsResponse "列出成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/health-event [get]
+func (s *Server) handleListHealthEvents(c *gin.Context) {
+	var req dao.ListHealthEventsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	events, total, err := model.ListHealthEvents(req.Filter(), req.Start, req.Limit)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListHealthEventsResponse{
+		Items: make([]dao.HealthEventSpec, 0, len(events)),
+		Total: total,
+	}
+	for _, e := range events {
+		resp.Items = append(resp.Items, *dao.FromHealthEventModel(&e))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleResolveHealthEvent 解决系统健康事件
+// @Summary 解决系统健康事件
+// @Description 手动将事件标记为已解决，异常再次出现时会产生新的事件
+// @Tags 系统健康
+// @Accept json
+// @Produce json
+// @Param event_id path int true "事件ID"
+// @Success 200 {object} dao.HealthEventSpec "解决成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "事件不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/health-event/{event_id}/resolve [put]
+func (s *Server) handleResolveHealthEvent(c *gin.Context) {
+	eventId, err := strconv.Atoi(c.Param("event_id"))
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("invalid event_id"))
+		return
+	}
+	event, err := model.GetHealthEventById(eventId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if event == nil {
+		s.writeError(c, http.StatusNotFound, fmt.Errorf("health event not found"))
+		return
+	}
+
+	if !event.Resolved {
+		if err := model.ResolveHealthEvent(eventId); err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		}
+		if event, err = model.GetHealthEventById(eventId); err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, dao.FromHealthEventModel(event))
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+	"lumina/pkg/heatmap"
+)
+
+const (
+	defaultHeatmapRange = 7 * 24 * time.Hour
+	maxHeatmapRange     = 90 * 24 * time.Hour
+	// maxHeatmapMessages bounds the messages aggregated by one heatmap
+	maxHeatmapMessages = 20000
+)
+
+var heatmapRangeRe = regexp.MustCompile(`^([0-9]+)(h|d)$`)
+
+// handleCameraHeatmap 摄像头检测热力图
+// @Summary 获取摄像头检测热力图
+// @Description 统计摄像头上检测任务最近一段时间内检测框中心点的分布，以最近一张检测图片为底图渲染热力图，返回JPEG图片，响应头X-Heatmap-Samples为统计的检测框数量
+// @Tags 摄像头
+// @Produce image/jpeg
+// @Param camera_id path int true "摄像头ID"
+// @Param range query string false "统计范围，如24h、7d，最长90d" default(7d)
+// @Param label query string false "只统计指定标签"
+// @Success 200 {file} binary "热力图"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "摄像头不存在或范围内没有检测结果"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/camera/{camera_id}/heatmap [get]
+func (s *Server) handleCameraHeatmap(c *gin.Context) {
+	cam := c.MustGet(cameraKey).(*model.Camera)
+
+	var req dao.HeatmapRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	period, err := parseHeatmapRange(req.Range)
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	end := time.Now()
+	msgs, err := model.ListCameraDetectionMessages(cam.Id, end.Add(-period), end, maxHeatmapMessages)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the centers of the boxes, relative to the frame size if the message
+	// has it and in pixels otherwise
+	type center struct {
+		x, y       float64
+		normalized bool
+	}
+	var boxes []center
+	width, height := 0, 0
+	for _, m := range msgs {
+		for _, b := range m.DetectBoxes {
+			if b == nil || (req.Label != "" && b.Label != req.Label) {
+				continue
+			}
+			x, y := float64(b.X1+b.X2)/2, float64(b.Y1+b.Y2)/2
+			if m.FrameWidth > 0 && m.FrameHeight > 0 {
+				boxes = append(boxes, center{x / float64(m.FrameWidth), y / float64(m.FrameHeight), true})
+				width, height = max(width, m.FrameWidth), max(height, m.FrameHeight)
+			} else {
+				boxes = append(boxes, center{x, y, false})
+				width, height = max(width, b.X2), max(height, b.Y2)
+			}
+		}
+	}
+	if len(boxes) == 0 {
+		s.writeError(c, http.StatusNotFound, fmt.Errorf("no detection of camera %d in the last %s", cam.Id, period))
+		return
+	}
+
+	// the boxes without frame size are in the coordinates of the frames,
+	// which have the size of the message images
+	bg := s.heatmapBackground(c.Request.Context(), msgs)
+	if bg != nil {
+		width, height = bg.Bounds().Dx(), bg.Bounds().Dy()
+	}
+
+	hm := heatmap.New(width, height)
+	for _, b := range boxes {
+		if b.normalized {
+			hm.Add(b.x*float64(width), b.y*float64(height))
+		} else {
+			hm.Add(b.x, b.y)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, hm.Render(bg), &jpeg.Options{Quality: 85}); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Header("X-Heatmap-Samples", strconv.Itoa(hm.Samples()))
+	c.Data(http.StatusOK, "image/jpeg", buf.Bytes())
+}
+
+// heatmapBackground returns the newest message image that can be decoded, or
+// nil if there is none.
+func (s *Server) heatmapBackground(ctx context.Context, msgs []*model.Message) image.Image {
+	tried := 0
+	for _, m := range msgs {
+		if m.ImagePath == "" {
+			continue
+		}
+		if tried++; tried > 3 {
+			break
+		}
+		obj, err := s.minioCli.GetObject(ctx, s.conf.S3.Bucket,
+			strings.TrimPrefix(m.ImagePath, "/"), minio.GetObjectOptions{})
+		if err != nil {
+			s.logger.WithError(err).Warnf("get heatmap background %s failed", m.ImagePath)
+			continue
+		}
+		img, _, err := image.Decode(obj)
+		obj.Close()
+		if err != nil {
+			s.logger.WithError(err).Warnf("decode heatmap background %s failed", m.ImagePath)
+			continue
+		}
+		return img
+	}
+	return nil
+}
+
+// parseHeatmapRange parses a range like 24h or 7d.
+func parseHeatmapRange(r string) (time.Duration, error) {
+	if r == "" {
+		return defaultHeatmapRange, nil
+	}
+	match := heatmapRangeRe.FindStringSubmatch(r)
+	if match == nil {
+		return 0, fmt.Errorf("invalid range: %s", r)
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid range: %s", r)
+	}
+	unit := time.Hour
+	if match[2] == "d" {
+		unit = 24 * time.Hour
+	}
+	d := time.Duration(n) * unit
+	if d <= 0 || d > maxHeatmapRange {
+		return 0, fmt.Errorf("range must be between 1h and 90d")
+	}
+	return d, nil
+}
@@ -1,6 +1,9 @@
 package server
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -34,7 +37,7 @@ func SetJobToContext() gin.HandlerFunc {
 				"error": "internal server error",
 			})
 			return
-		} else if job == nil {
+		} else if job == nil || !inRequestOrg(c, job.OrgId) {
 			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 				"error": "job not found",
 			})
@@ -64,12 +67,29 @@ func (s *Server) handleCreateJob(c *gin.Context) {
 		return
 	}
 
+	if req.Schedule != nil {
+		if err := req.Schedule.Validate(); err != nil {
+			s.writeError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	orgId, ok := s.ownerOrgId(c)
+	if !ok {
+		return
+	}
 	job := req.ToModel()
+	job.OrgId = orgId
+	if err := checkJobTarget(job); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
 
 	if err := model.AddJob(job); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
+	s.recordJobAction(c, job, model.JobActionCreate)
 
 	resp := dao.CreateJobResponse{
 		Uuid: job.Uuid,
@@ -121,18 +141,273 @@ func (s *Server) handleUpdateJob(c *gin.Context) {
 		return
 	}
 
+	if req.Schedule != nil {
+		if err := req.Schedule.Validate(); err != nil {
+			s.writeError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
 	job := c.MustGet(jobKey).(*model.Job)
+	oldGroupId := job.DeviceGroupId
 
 	req.UpdateModel(job)
+	if err := checkJobTarget(job); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
 
 	if err := model.UpdateJob(job); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
+	if job.DeviceGroupId != oldGroupId {
+		if err := model.DeleteJobDeviceStatus(job.Id); err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{})
 }
 
+// handleCloneJob 复制任务
+// @Summary 复制任务
+// @Description 以相同的配置复制任务，可指定新的摄像头、设备或设备组，便于将同一检测配置批量应用到多个摄像头
+// @Tags 任务
+// @Accept json
+// @Produce json
+// @Param job_id path string true "任务job_id"
+// @Param req body dao.CloneJobRequest false "复制任务请求"
+// @Success 200 {object} dao.CreateJobResponse "复制成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "任务不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/job/{job_id}/clone [post]
+func (s *Server) handleCloneJob(c *gin.Context) {
+	var req dao.CloneJobRequest
+	// the body is optional, an empty one clones the job as is
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	job := req.Clone(c.MustGet(jobKey).(*model.Job))
+	if err := checkJobTarget(job); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.CameraId != nil {
+		camera, err := model.GetCameraById(job.CameraId)
+		if err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		} else if camera == nil {
+			s.writeError(c, http.StatusBadRequest, fmt.Errorf("camera %d not found", job.CameraId))
+			return
+		}
+	}
+	if req.DeviceId != nil && job.DeviceId != 0 {
+		device, err := model.GetDeviceById(job.DeviceId)
+		if err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		} else if device == nil {
+			s.writeError(c, http.StatusBadRequest, fmt.Errorf("device %d not found", job.DeviceId))
+			return
+		}
+	}
+
+	if err := model.AddJob(job); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.recordJobAction(c, job, model.JobActionCreate)
+
+	c.JSON(http.StatusOK, dao.CreateJobResponse{Uuid: job.Uuid})
+}
+
+// recordJobAction records a change of the job made through the API. A
+// failure is only logged since the change itself is done.
+func (s *Server) recordJobAction(c *gin.Context, job *model.Job, action string) {
+	h := &model.JobStatusHistory{
+		JobId:  job.Id,
+		Source: model.JobStatusSourceServer,
+		Status: action,
+	}
+	if u, ok := c.Get(userKey); ok {
+		h.Username = u.(*model.User).Username
+	}
+	if err := model.AddJobStatusHistory([]*model.JobStatusHistory{h}); err != nil {
+		s.logger.WithError(err).Errorf("record %s of job %s failed", action, job.Uuid)
+	}
+}
+
+// handleListJobStatusHistory 获取任务状态变更记录
+// @Summary 获取任务状态变更记录
+// @Description 按时间倒序列出任务的状态变更，包括设备上报的执行器状态变化和通过接口进行的创建、启动、停止、暂停、恢复操作，设备组任务按设备分别记录
+// @Tags 任务
+// @Accept json
+// @Produce json
+// @Param job_id path string true "任务job_id"
+// @Param start query int false "分页起始位置"
+// @Param limit query int false "分页每页数量"
+// @Success 200 {object} dao.ListJobStatusHistoryResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "任务不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/job/{job_id}/history [get]
+func (s *Server) handleListJobStatusHistory(c *gin.Context) {
+	job := c.MustGet(jobKey).(*model.Job)
+	var req dao.ListJobStatusHistoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	hs, total, err := model.ListJobStatusHistory(job.Id, req.Start, req.Limit)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListJobStatusHistoryResponse{
+		Items: make([]dao.JobStatusHistorySpec, 0, len(hs)),
+		Total: total,
+	}
+	for _, h := range hs {
+		resp.Items = append(resp.Items, *dao.FromJobStatusHistoryModel(&h))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleListJobArtifacts 获取设备随状态上报的小文件
+// @Summary 获取设备随状态上报的小文件
+// @Description 设备上传对象存储失败时会随状态上报最新触发图片的缩略图和最近一次错误时的画面，服务端保存 24 小时，每种类型只保留最新一个
+// @Tags 任务
+// @Accept json
+// @Produce json
+// @Param job_id path string true "任务job_id"
+// @Success 200 {object} dao.ListJobArtifactsResponse "获取成功"
+// @Failure 404 {object} ErrorResponse "任务不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/job/{job_id}/artifacts [get]
+func (s *Server) handleListJobArtifacts(c *gin.Context) {
+	job := c.MustGet(jobKey).(*model.Job)
+	as, err := model.ListJobArtifacts(c, job.Uuid)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListJobArtifactsResponse{Items: make([]dao.JobArtifactSpec, 0, len(as))}
+	for _, a := range as {
+		resp.Items = append(resp.Items, *dao.FromJobArtifactModel(job.Id, a))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleGetJobArtifact 获取设备随状态上报的小文件内容
+// @Summary 获取设备随状态上报的小文件内容
+// @Description 返回文件内容，Content-Type 为设备上报的类型
+// @Tags 任务
+// @Produce image/jpeg,image/png
+// @Param job_id path string true "任务job_id"
+// @Param kind path string true "类型：trigger_thumbnail、error_screenshot"
+// @Success 200 {file} binary "文件内容"
+// @Failure 404 {object} ErrorResponse "任务或文件不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/job/{job_id}/artifacts/{kind} [get]
+func (s *Server) handleGetJobArtifact(c *gin.Context) {
+	job := c.MustGet(jobKey).(*model.Job)
+	a, err := model.GetJobArtifact(c, job.Uuid, model.ArtifactKind(c.Param("kind")))
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if a == nil {
+		s.writeError(c, http.StatusNotFound, errors.New("artifact not found"))
+		return
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, a.ContentType, a.Data)
+}
+
+// checkJobTarget checks that the job targets at most one of a device and a
+// device group, that the group exists, and that the camera, the device, the
+// group and the workflow are not of another organization.
+func checkJobTarget(job *model.Job) error {
+	if job.DeviceId != 0 && job.DeviceGroupId != 0 {
+		return errors.New("deviceId and deviceGroupId are exclusive")
+	}
+	if job.DeviceGroupId != 0 {
+		group, err := model.GetDeviceGroupById(job.DeviceGroupId)
+		if err != nil {
+			return err
+		} else if group == nil || group.OrgId != job.OrgId {
+			return fmt.Errorf("device group %d not found", job.DeviceGroupId)
+		}
+	}
+	if job.CameraId != 0 {
+		camera, err := model.GetCameraById(job.CameraId)
+		if err != nil {
+			return err
+		} else if camera != nil && camera.OrgId != job.OrgId {
+			return fmt.Errorf("camera %d not found", job.CameraId)
+		}
+	}
+	if job.DeviceId != 0 {
+		device, err := model.GetDeviceById(job.DeviceId)
+		if err != nil {
+			return err
+		} else if device != nil && device.OrgId != job.OrgId {
+			return fmt.Errorf("device %d not found", job.DeviceId)
+		}
+	}
+	if job.WorkflowId != 0 {
+		wf, err := model.GetWorkflowById(job.WorkflowId)
+		if err != nil {
+			return err
+		} else if wf != nil && wf.OrgId != job.OrgId {
+			return fmt.Errorf("workflow %d not found", job.WorkflowId)
+		}
+	}
+	return nil
+}
+
+// handleGetJobDeviceStatus 获取任务在各设备上的状态
+// @Summary 获取任务在各设备上的状态
+// @Description 获取以设备组为目标的任务在各成员设备上的运行状态
+// @Tags 任务
+// @Accept json
+// @Produce json
+// @Param job_id path string true "任务job_id"
+// @Success 200 {object} dao.ListJobDeviceStatusResponse "获取成功"
+// @Failure 404 {object} ErrorResponse "任务不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/job/{job_id}/device-status [get]
+func (s *Server) handleGetJobDeviceStatus(c *gin.Context) {
+	job := c.MustGet(jobKey).(*model.Job)
+
+	sts, err := model.ListJobDeviceStatus(job.Id)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListJobDeviceStatusResponse{
+		Items: make([]dao.JobDeviceStatus, 0, len(sts)),
+	}
+	for _, st := range sts {
+		device, err := model.GetDeviceById(st.DeviceId)
+		if err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		}
+		resp.Items = append(resp.Items, *dao.FromJobDeviceStatusModel(&st, device))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
 // handleDeleteJob 删除任务
 // @Summary 删除任务
 // @Description 根据job_id删除任务
@@ -180,7 +455,7 @@ func (s *Server) handleListJobs(c *gin.Context) {
 		req.Limit = 10
 	}
 
-	jobs, total, err := model.ListJobs(req.Start, req.Limit)
+	jobs, total, err := model.ListJobs(requestOrgId(c), req.Start, req.Limit)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -218,10 +493,12 @@ func (s *Server) handleListJobs(c *gin.Context) {
 func (s *Server) handleStartJob(c *gin.Context) {
 	job := c.MustGet(jobKey).(*model.Job)
 	job.Enabled = true
+	job.Paused = false
 	if err := model.UpdateJob(job); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
+	s.recordJobAction(c, job, model.JobActionStart)
 	c.JSON(http.StatusOK, gin.H{})
 }
 
@@ -244,5 +521,64 @@ func (s *Server) handleStopJob(c *gin.Context) {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
+	s.recordJobAction(c, job, model.JobActionStop)
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handlePauseJob 暂停任务
+// @Summary 暂停任务
+// @Description 根据job_id暂停任务，设备保留执行器和连接，但不再处理和上报数据
+// @Tags 任务
+// @Accept json
+// @Produce json
+// @Param job_id path string true "任务job_id"
+// @Success 200 "暂停成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "任务不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/job/{job_id}/pause [put]
+func (s *Server) handlePauseJob(c *gin.Context) {
+	job := c.MustGet(jobKey).(*model.Job)
+	if !job.Enabled {
+		s.writeError(c, http.StatusBadRequest, errors.New("job is stopped"))
+		return
+	}
+	if job.Paused {
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+	job.Paused = true
+	if err := model.UpdateJob(job); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.recordJobAction(c, job, model.JobActionPause)
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handleResumeJob 恢复任务
+// @Summary 恢复任务
+// @Description 根据job_id恢复已暂停的任务
+// @Tags 任务
+// @Accept json
+// @Produce json
+// @Param job_id path string true "任务job_id"
+// @Success 200 "恢复成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "任务不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/job/{job_id}/resume [put]
+func (s *Server) handleResumeJob(c *gin.Context) {
+	job := c.MustGet(jobKey).(*model.Job)
+	if !job.Paused {
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+	job.Paused = false
+	if err := model.UpdateJob(job); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.recordJobAction(c, job, model.JobActionResume)
 	c.JSON(http.StatusOK, gin.H{})
 }
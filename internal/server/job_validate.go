@@ -0,0 +1,270 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const workflowCheckTimeout = 10 * time.Second
+
+// handleValidateJob 检查任务是否就绪
+// @Summary 检查任务是否就绪
+// @Description 不保存任务，检查运行设备、摄像头连通性、设备 Triton 是否已加载模型以及工作流接口是否可用，返回各检查项的结果。
+// @Description 摄像头与模型在运行任务的设备上检查，设备未响应时两项均为未通过
+// @Tags 任务
+// @Accept json
+// @Produce json
+// @Param req body dao.CreateJobRequest true "创建任务请求"
+// @Success 200 {object} dao.ValidateJobResponse "就绪报告"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/job/validate [post]
+func (s *Server) handleValidateJob(c *gin.Context) {
+	var req dao.CreateJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Schedule != nil {
+		if err := req.Schedule.Validate(); err != nil {
+			s.writeError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	orgId, ok := s.ownerOrgId(c)
+	if !ok {
+		return
+	}
+	job := req.ToModel()
+	job.OrgId = orgId
+
+	workflowCh := make(chan dao.JobCheck, 1)
+	go func() {
+		workflowCh <- s.checkJobWorkflow(c.Request.Context(), job)
+	}()
+	checks, err := s.checkJobDevice(c, job)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := dao.ValidateJobResponse{
+		Ready:  true,
+		Checks: append(checks, <-workflowCh),
+	}
+	for _, check := range resp.Checks {
+		if check.Status == dao.JobCheckFailed {
+			resp.Ready = false
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// checkJobDevice checks the device the job would run on, then probes the
+// camera and the model from it. The error is only set for server failures.
+func (s *Server) checkJobDevice(c *gin.Context, job *model.Job) ([]dao.JobCheck, error) {
+	target := dao.JobCheck{Name: "target", Status: dao.JobCheckOk}
+	camera := dao.JobCheck{Name: "camera", Status: dao.JobCheckSkipped}
+	modelCheck := dao.JobCheck{Name: "model", Status: dao.JobCheckSkipped}
+	var modelName string
+	if job.Kind == model.JobKindDetect && job.Detect != nil {
+		modelName = job.Detect.ModelName
+	} else {
+		modelCheck.Message = "job uses no model"
+	}
+	checks := func() []dao.JobCheck {
+		return []dao.JobCheck{target, camera, modelCheck}
+	}
+
+	cam, err := model.GetCameraById(job.CameraId)
+	if err != nil {
+		return nil, err
+	} else if cam == nil || cam.OrgId != job.OrgId {
+		camera.Status = dao.JobCheckFailed
+		camera.Message = fmt.Sprintf("camera %d not found", job.CameraId)
+		target.Status = dao.JobCheckSkipped
+		return checks(), nil
+	}
+
+	start := time.Now()
+	device, reason, err := jobDevice(job, cam)
+	target.Duration = time.Since(start).Milliseconds()
+	if err != nil {
+		return nil, err
+	} else if device == nil {
+		target.Status = dao.JobCheckFailed
+		target.Message = reason
+		camera.Message = "no device to probe from"
+		if modelName != "" {
+			modelCheck.Message = camera.Message
+		}
+		return checks(), nil
+	}
+
+	camSpec, err := dao.FromCameraModel(cam)
+	if err != nil {
+		return nil, err
+	}
+	task := &model.CameraProbeTask{
+		TaskUuid:   uuid.New().String(),
+		CameraUuid: cam.Uuid,
+		PullAddr:   camSpec.Url(),
+		ModelName:  modelName,
+		ExpireTime: time.Now().Add(cameraProbeTimeout),
+	}
+	if err := model.AddCameraProbeTask(c, device.Uuid, task); err != nil {
+		return nil, err
+	}
+	defer model.DeleteCameraProbeTask(context.Background(), device.Uuid, task.TaskUuid)
+
+	result, err := s.waitCameraProbeResult(c, task.TaskUuid, cameraProbeTimeout)
+	if err != nil {
+		return nil, err
+	} else if result == nil {
+		camera.Status = dao.JobCheckFailed
+		camera.Message = fmt.Sprintf("device %s did not respond", device.Uuid)
+		if modelName != "" {
+			modelCheck.Status = dao.JobCheckFailed
+			modelCheck.Message = camera.Message
+		}
+		return checks(), nil
+	}
+
+	camera.Duration = result.Duration
+	if result.Reachable {
+		camera.Status = dao.JobCheckOk
+	} else {
+		camera.Status = dao.JobCheckFailed
+		camera.Message = result.Error
+	}
+	if modelName != "" {
+		modelCheck.Duration = result.Duration
+		if result.ModelReady {
+			modelCheck.Status = dao.JobCheckOk
+		} else {
+			modelCheck.Status = dao.JobCheckFailed
+			modelCheck.Message = result.ModelError
+		}
+	}
+	return checks(), nil
+}
+
+// jobDevice returns the device the job would run on: its device, the
+// camera's device if it belongs to the job's group or else the group's first
+// one, or the camera's device. It returns nil and why if there is none.
+func jobDevice(job *model.Job, cam *model.Camera) (*model.Device, string, error) {
+	if err := checkJobTarget(job); err != nil {
+		return nil, err.Error(), nil
+	}
+
+	var device *model.Device
+	switch {
+	case job.DeviceId != 0:
+		d, err := model.GetDeviceById(job.DeviceId)
+		if err != nil {
+			return nil, "", err
+		} else if d == nil {
+			return nil, fmt.Sprintf("device %d not found", job.DeviceId), nil
+		}
+		device = d
+	case job.DeviceGroupId != 0:
+		members, err := model.ListDeviceGroupMembers(job.DeviceGroupId)
+		if err != nil {
+			return nil, "", err
+		} else if len(members) == 0 {
+			return nil, fmt.Sprintf("device group %d has no device", job.DeviceGroupId), nil
+		}
+		i := slices.IndexFunc(members, func(d model.Device) bool { return d.Id == cam.BindDeviceId })
+		device = &members[max(i, 0)]
+	default:
+		d, err := cam.BindDevice()
+		if err != nil {
+			return nil, "", err
+		} else if d == nil {
+			return nil, "camera is not bound to a device", nil
+		}
+		device = d
+	}
+	if !device.IsRegistered() {
+		return nil, fmt.Sprintf("device %s is not registered", device.Uuid), nil
+	}
+	return device, "", nil
+}
+
+func (s *Server) checkJobWorkflow(ctx context.Context, job *model.Job) dao.JobCheck {
+	check := dao.JobCheck{Name: "workflow", Status: dao.JobCheckSkipped}
+	if job.WorkflowId == 0 {
+		check.Message = "job uses no workflow"
+		return check
+	}
+
+	start := time.Now()
+	wf, err := model.GetWorkflowById(job.WorkflowId)
+	if err == nil && (wf == nil || wf.OrgId != job.OrgId) {
+		err = fmt.Errorf("workflow %d not found", job.WorkflowId)
+	}
+	if err == nil {
+		err = s.pingWorkflow(ctx, wf)
+	}
+	check.Duration = time.Since(start).Milliseconds()
+	if err != nil {
+		check.Status = dao.JobCheckFailed
+		check.Message = err.Error()
+	} else {
+		check.Status = dao.JobCheckOk
+	}
+	return check
+}
+
+// pingWorkflow lists the models of the OpenAI compatible endpoint of the
+// workflow, and checks that its model is served if the endpoint tells.
+func (s *Server) pingWorkflow(ctx context.Context, wf *model.Workflow) error {
+	ctx, cancel := context.WithTimeout(ctx, workflowCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wf.Endpoint+"/models", nil)
+	if err != nil {
+		return err
+	}
+	if wf.Key != "" {
+		req.Header.Set("Authorization", "Bearer "+wf.Key)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("endpoint did not respond in %s", workflowCheckTimeout)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data []struct {
+			Id string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || len(body.Data) == 0 || wf.ModelName == "" {
+		return nil
+	}
+	for _, m := range body.Data {
+		if m.Id == wf.ModelName {
+			return nil
+		}
+	}
+	return fmt.Errorf("model %s is not served by the endpoint", wf.ModelName)
+}
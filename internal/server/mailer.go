@@ -0,0 +1,234 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"lumina/internal/model"
+)
+
+const smtpTimeout = 30 * time.Second
+
+// defaultEmailTemplate is the body of the alert emails of the channels
+// without template.
+const defaultEmailTemplate = `摄像头：{{.CameraName}}
+任务：{{.Message.JobId}}
+时间：{{.Message.Timestamp}}
+{{with .Message.WorkflowResp}}{{if .Answer}}分析结果：{{.Answer}}
+{{end}}{{end}}{{with .ImageUrl}}图片：{{.}}
+{{end}}{{with .Message.VideoPath}}视频：{{.}}
+{{end}}`
+
+var errNoSMTPServer = errors.New("smtp server is not configured")
+
+// alertEmail is an alert or a report rendered for an email channel.
+type alertEmail struct {
+	subject string
+	body    string
+	// html sends the body as text/html instead of text/plain
+	html bool
+	// attachment, the alert image or the report file, is attached if not
+	// nil
+	attachment     []byte
+	attachmentName string
+}
+
+func (n *Notifier) renderEmail(ctx context.Context, ch *model.NotificationChannel, data *notificationData) (*alertEmail, error) {
+	text := ch.Template
+	if text == "" {
+		text = defaultEmailTemplate
+	}
+	rendered, err := executeNotificationTemplate(text, data)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(rendered)
+	event := data.AlertEvent
+	conf := n.smtp.Load()
+	if conf.DashboardURL != "" && event.Message.Id != 0 {
+		fmt.Fprintf(body, "\n详情：%s/message/%d\n", strings.TrimSuffix(conf.DashboardURL, "/"), event.Message.Id)
+	}
+
+	e := &alertEmail{
+		subject: fmt.Sprintf("[Lumina] 任务 %d 告警", event.Message.JobId),
+		body:    body.String(),
+	}
+	if event.CameraName != "" {
+		e.subject = fmt.Sprintf("[Lumina] 告警: %s", event.CameraName)
+	}
+	if conf.AttachImage && event.Message.ImagePath != "" {
+		// the image stays linked in the body if it cannot be attached
+		image, err := n.fetchImage(ctx, data.ImageUrl)
+		if err != nil {
+			n.logger.WithError(err).Warnf("fetch image of alert %d failed", event.AlertId)
+		} else {
+			e.attachment = image
+			e.attachmentName = path.Base(event.Message.ImagePath)
+		}
+	}
+	return e, nil
+}
+
+// fetchImage downloads the image, it fails if the image is larger than the
+// attachments allowed.
+func (n *Notifier) fetchImage(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image returned status %d", resp.StatusCode)
+	}
+	maxSize := n.smtp.Load().MaxAttachmentSize
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	} else if len(data) > maxSize {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxSize)
+	}
+	return data, nil
+}
+
+// message returns the MIME message of the email.
+func (e *alertEmail) message(from string, to []string) ([]byte, error) {
+	if addr, err := mail.ParseAddress(from); err == nil {
+		// encodes a non-ASCII display name
+		from = addr.String()
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", e.subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", w.Boundary())
+
+	contentType := "text/plain; charset=utf-8"
+	if e.html {
+		contentType = "text/html; charset=utf-8"
+	}
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(part, []byte(e.body))
+
+	if e.attachment != nil {
+		part, err = w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(http.DetectContentType(e.attachment), map[string]string{"name": e.attachmentName})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": e.attachmentName})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(part, e.attachment)
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64 encoded in lines of 76 characters.
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+// sendMail sends the message to the recipients through the SMTP server.
+func sendMail(ctx context.Context, conf SMTPConfig, to []string, msg []byte) error {
+	if conf.Host == "" {
+		return errNoSMTPServer
+	}
+	from, err := mail.ParseAddress(conf.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+	addr := net.JoinHostPort(conf.Host, strconv.Itoa(conf.Port))
+	tlsConf := &tls.Config{ServerName: conf.Host}
+	var conn net.Conn
+	if conf.TLS == SMTPTLSImplicit {
+		dialer := &tls.Dialer{Config: tlsConf}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, conf.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if conf.TLS == SMTPTLSStartTLS {
+		if err := c.StartTLS(tlsConf); err != nil {
+			return err
+		}
+	}
+	if conf.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", conf.Username, conf.Password, conf.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
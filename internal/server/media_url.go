@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/sirupsen/logrus"
+)
+
+// MediaUrls turns the paths of the media in the bucket into URLs the
+// dashboard can open, presigned for private buckets.
+type MediaUrls struct {
+	conf   S3Config
+	logger *logrus.Entry
+	// signer presigns for the visit endpoint if it is an S3 endpoint, the
+	// host being part of the signature
+	signer *minio.Client
+}
+
+func NewMediaUrls(logger *logrus.Entry, conf S3Config, minioCli *minio.Client, region string) (*MediaUrls, error) {
+	m := &MediaUrls{
+		conf:   conf,
+		logger: logger.WithField("component", "mediaUrls"),
+		signer: minioCli,
+	}
+	if conf.VisitEndpoint == "" {
+		return m, nil
+	}
+	u, err := url.Parse(conf.VisitEndpoint)
+	if err != nil || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+		// a proxy path cannot be presigned for, the internal endpoint is
+		m.logger.Warnf("visit endpoint %s is not an S3 endpoint, presign for %s", conf.VisitEndpoint, conf.Endpoint)
+		return m, nil
+	}
+	signer, err := minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(conf.AccessKeyID, conf.SecretAccessKey, ""),
+		Secure: u.Scheme == "https",
+		Region: region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.signer = signer
+	return m, nil
+}
+
+// Url returns the URL of the media, presigned if the bucket is configured so,
+// empty for an empty path.
+func (m *MediaUrls) Url(path string) string {
+	if path == "" {
+		return ""
+	}
+	if !m.conf.PresignMedia {
+		return m.conf.VisitPrefix() + path
+	}
+	u, err := m.Presign(context.Background(), path, m.expire())
+	if err != nil {
+		m.logger.WithError(err).Warnf("presign %s failed", path)
+		return m.conf.VisitPrefix() + path
+	}
+	return u
+}
+
+// Presign returns a URL of the media valid for expire.
+func (m *MediaUrls) Presign(ctx context.Context, path string, expire time.Duration) (string, error) {
+	u, err := m.signer.PresignedGetObject(ctx, m.conf.Bucket, strings.TrimPrefix(path, "/"), expire, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (m *MediaUrls) expire() time.Duration {
+	if m.conf.PresignExpire <= 0 {
+		return time.Hour
+	}
+	return time.Duration(m.conf.PresignExpire) * time.Second
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const (
+	defaultExportLimit = 100000
+	exportBatchSize    = 500
+)
+
+var messageCsvHeader = []string{
+	"id", "jobId", "timestamp", "createTime", "alerted", "imageUrl", "videoUrl",
+	"frameWidth", "frameHeight", "labels", "detectBoxes",
+	"answer", "confidence", "match", "totalTokens",
+}
+
+// handleExportMessages 导出消息
+// @Summary 导出消息
+// @Description 按与消息列表相同的过滤条件流式导出消息，包括检测框和工作流回答，用于离线分析和合规报告，最新的在前
+// @Tags 消息
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "导出格式：csv、jsonl" default(csv)
+// @Param limit query int false "最多导出的消息数量，最大 1000000" default(100000)
+// @Param q query string false "全文搜索工作流回答"
+// @Param jobId query int false "任务ID"
+// @Param cameraId query int false "摄像头ID"
+// @Param deviceId query int false "设备ID，匹配直接或通过设备组分配给该设备的任务"
+// @Param since query string false "拍摄时间下限（含），RFC3339 格式或 Unix 时间戳"
+// @Param until query string false "拍摄时间上限（不含），RFC3339 格式或 Unix 时间戳"
+// @Param label query string false "检测标签"
+// @Param minConfidence query number false "检测框最低置信度，0-1"
+// @Param alerted query bool false "只导出产生告警的消息"
+// @Param alertStatus query string false "告警处理状态：open、acknowledged、resolved，仅 alerted 为 true 时生效"
+// @Success 200 {string} string "导出的文件"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/export [get]
+func (s *Server) handleExportMessages(c *gin.Context) {
+	req := &dao.ExportMessagesRequest{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Format == "" {
+		req.Format = dao.MessageExportCsv
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultExportLimit
+	}
+	filter := req.ToFilter()
+	filter.OrgId = requestOrgId(c)
+
+	// the first batch is read before the headers are written, so that a
+	// failing query is still reported as an error
+	batch, err := model.ListMessagesBefore(filter, 0, min(exportBatchSize, req.Limit))
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	var w messageWriter
+	contentType := "text/csv; charset=utf-8"
+	if req.Format == dao.MessageExportJsonl {
+		contentType = "application/x-ndjson"
+		w = &jsonlMessageWriter{enc: json.NewEncoder(c.Writer)}
+	} else {
+		w = &csvMessageWriter{w: csv.NewWriter(c.Writer)}
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=messages-%s.%s",
+		time.Now().UTC().Format("20060102T150405Z"), req.Format))
+	c.Status(http.StatusOK)
+	if err := w.begin(c.Writer); err != nil {
+		return
+	}
+
+	var exported int
+	for len(batch) > 0 {
+		for _, m := range batch {
+			if err := w.write(s.messageSpec(m)); err != nil {
+				s.logger.WithError(err).Warn("write exported message failed")
+				return
+			}
+		}
+		exported += len(batch)
+		if err := w.flush(); err != nil {
+			return
+		}
+		c.Writer.Flush()
+
+		if exported >= req.Limit || len(batch) < exportBatchSize || c.Request.Context().Err() != nil {
+			break
+		}
+		batch, err = model.ListMessagesBefore(filter, batch[len(batch)-1].Id, min(exportBatchSize, req.Limit-exported))
+		if err != nil {
+			// the status is sent already, the truncated file is all we can do
+			s.logger.WithError(err).Errorf("export messages failed after %d messages", exported)
+			return
+		}
+	}
+}
+
+type messageWriter interface {
+	begin(w io.Writer) error
+	write(m dao.MessageSpec) error
+	flush() error
+}
+
+type jsonlMessageWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlMessageWriter) begin(io.Writer) error { return nil }
+
+func (j *jsonlMessageWriter) write(m dao.MessageSpec) error { return j.enc.Encode(m) }
+
+func (j *jsonlMessageWriter) flush() error { return nil }
+
+type csvMessageWriter struct {
+	w *csv.Writer
+}
+
+// begin writes the UTF-8 BOM so that spreadsheets decode the Chinese answers,
+// then the header.
+func (cw *csvMessageWriter) begin(w io.Writer) error {
+	if _, err := w.Write([]byte("\xef\xbb\xbf")); err != nil {
+		return err
+	}
+	return cw.w.Write(messageCsvHeader)
+}
+
+func (cw *csvMessageWriter) write(m dao.MessageSpec) error {
+	var labels, boxes string
+	if len(m.DetectBoxes) > 0 {
+		seen := make(map[string]bool)
+		for _, b := range m.DetectBoxes {
+			if b.Label != "" && !seen[b.Label] {
+				seen[b.Label] = true
+				if labels != "" {
+					labels += ","
+				}
+				labels += b.Label
+			}
+		}
+		data, err := json.Marshal(m.DetectBoxes)
+		if err != nil {
+			return err
+		}
+		boxes = string(data)
+	}
+	var answer, confidence, match, tokens string
+	if r := m.WorkflowResp; r != nil {
+		answer = r.Answer
+		confidence = strconv.FormatFloat(float64(r.Confidence), 'f', -1, 32)
+		match = strconv.FormatBool(r.Match)
+		tokens = strconv.Itoa(r.TotalTokens)
+	}
+	return cw.w.Write([]string{
+		strconv.Itoa(m.Id), strconv.Itoa(m.JobId), m.Timestamp, m.CreateTime,
+		strconv.FormatBool(m.Alerted), m.ImagePath, m.VideoPath,
+		strconv.Itoa(m.FrameWidth), strconv.Itoa(m.FrameHeight), labels, boxes,
+		answer, confidence, match, tokens,
+	})
+}
+
+func (cw *csvMessageWriter) flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
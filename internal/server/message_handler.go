@@ -1,12 +1,16 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"lumina/internal/dao"
+	"lumina/internal/embedding"
 	"lumina/internal/model"
 )
 
@@ -28,7 +32,7 @@ func SetMessageToContext() gin.HandlerFunc {
 				"error": "internal server error",
 			})
 			return
-		} else if message == nil {
+		} else if message == nil || !inRequestOrg(c, message.OrgId) {
 			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 				"error": "message not found",
 			})
@@ -58,9 +62,27 @@ func (s *Server) handleCreateMessage(c *gin.Context) {
 		return
 	}
 
+	orgId, ok := s.ownerOrgId(c)
+	if !ok {
+		return
+	}
 	message := req.ToModel()
+	message.OrgId = orgId
+
+	var dedupWindow time.Duration
+	if job, err := model.GetJobById(message.JobId); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if job != nil {
+		if !inRequestOrg(c, job.OrgId) {
+			s.writeError(c, http.StatusBadRequest, errors.New("job not found"))
+			return
+		}
+		dedupWindow = job.AlertDedupDuration()
+		message.OrgId = job.OrgId
+	}
 
-	if err := model.AddMessage(message); err != nil {
+	if err := model.AddMessage(message, model.SeverityMedium, dedupWindow); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
@@ -86,15 +108,49 @@ func (s *Server) handleCreateMessage(c *gin.Context) {
 func (s *Server) handleGetMessage(c *gin.Context) {
 	message := c.MustGet(messageKey).(*model.Message)
 
-	spec := dao.FromMessageModel(message)
-	if spec.ImagePath != "" {
-		spec.ImagePath = s.conf.S3.VisitPrefix() + spec.ImagePath
+	c.JSON(http.StatusOK, s.messageSpec(message))
+}
+
+// handleGetMessageMedia 获取消息媒体的临时链接
+// @Summary 获取消息媒体的临时链接
+// @Description 返回消息图片和视频的预签名链接，用于访问私有存储桶中的对象，链接在过期时间前有效
+// @Tags 消息
+// @Produce json
+// @Param message_id path string true "消息message_id"
+// @Param expire query int false "有效期，单位秒，最大 7 天，默认为配置的 s3.presignExpire"
+// @Success 200 {object} dao.MessageMediaResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "消息不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/{message_id}/media [get]
+func (s *Server) handleGetMessageMedia(c *gin.Context) {
+	var req dao.MessageMediaRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
 	}
-	if spec.VideoPath != "" {
-		spec.VideoPath = s.conf.S3.VisitPrefix() + spec.VideoPath
+	expire := s.mediaUrls.expire()
+	if req.Expire > 0 {
+		expire = time.Duration(req.Expire) * time.Second
 	}
 
-	c.JSON(http.StatusOK, spec)
+	message := c.MustGet(messageKey).(*model.Message)
+	var resp dao.MessageMediaResponse
+	var err error
+	if message.ImagePath != "" {
+		if resp.ImageUrl, err = s.mediaUrls.Presign(c, message.ImagePath, expire); err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if message.VideoPath != "" {
+		if resp.VideoUrl, err = s.mediaUrls.Presign(c, message.VideoPath, expire); err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	resp.ExpireTime = time.Now().Add(expire).UTC().Format(time.RFC3339)
+	c.JSON(http.StatusOK, resp)
 }
 
 // handleDeleteMessage 删除消息
@@ -122,13 +178,21 @@ func (s *Server) handleDeleteMessage(c *gin.Context) {
 
 // handleListMessages 获取消息列表
 // @Summary 获取消息列表
-// @Description 根据jobId分页获取消息列表
+// @Description 按任务、摄像头、设备、拍摄时间、检测标签和置信度、告警状态过滤并分页获取消息列表，最新的在前
 // @Tags 消息
 // @Accept json
 // @Produce json
-// @Param jobId query int true "任务ID"
+// @Param jobId query int false "任务ID"
 // @Param start query int false "起始位置" default(0)
 // @Param limit query int false "每页数量" default(10)
+// @Param alerted query bool false "只返回产生告警的消息"
+// @Param alertStatus query string false "告警处理状态：open、acknowledged、resolved，仅 alerted 为 true 时生效"
+// @Param cameraId query int false "摄像头ID"
+// @Param deviceId query int false "设备ID，匹配直接或通过设备组分配给该设备的任务"
+// @Param since query string false "拍摄时间下限（含），RFC3339 格式或 Unix 时间戳"
+// @Param until query string false "拍摄时间上限（不含），RFC3339 格式或 Unix 时间戳"
+// @Param label query string false "检测标签"
+// @Param minConfidence query number false "检测框最低置信度，0-1"
 // @Success 200 {object} dao.ListMessagesResponse "获取成功"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 500 {object} ErrorResponse "内部服务器错误"
@@ -145,22 +209,9 @@ func (s *Server) handleListMessages(c *gin.Context) {
 		req.Limit = 10
 	}
 
-	var err error
-	var messages []*model.Message
-	var total int64
-	if req.Alerted {
-		if req.JobId == 0 {
-			messages, total, err = model.GetAlertMessages(req.Start, req.Limit)
-		} else {
-			messages, total, err = model.GetAlertMessagesByJobId(req.JobId, req.Start, req.Limit)
-		}
-	} else {
-		if req.JobId == 0 {
-			messages, total, err = model.GetMessages(req.Start, req.Limit)
-		} else {
-			messages, total, err = model.GetMessagesByJobId(req.JobId, req.Start, req.Limit)
-		}
-	}
+	filter := req.ToFilter()
+	filter.OrgId = requestOrgId(c)
+	messages, total, err := model.ListMessages(filter, req.Start, req.Limit)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -168,14 +219,7 @@ func (s *Server) handleListMessages(c *gin.Context) {
 
 	items := make([]dao.MessageSpec, len(messages))
 	for i, message := range messages {
-		m := *dao.FromMessageModel(message)
-		if m.ImagePath != "" {
-			m.ImagePath = s.conf.S3.VisitPrefix() + m.ImagePath
-		}
-		if m.VideoPath != "" {
-			m.VideoPath = s.conf.S3.VisitPrefix() + m.VideoPath
-		}
-		items[i] = m
+		items[i] = s.messageSpec(message)
 	}
 
 	resp := dao.ListMessagesResponse{
@@ -184,3 +228,236 @@ func (s *Server) handleListMessages(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, resp)
 }
+
+// handleSearchMessages 搜索消息
+// @Summary 搜索消息
+// @Description 在所有任务的工作流回答中全文搜索，如“把手伸进配电箱”，可同时按任务、摄像头、设备、时间等过滤，最新的在前
+// @Tags 消息
+// @Produce json
+// @Param q query string true "搜索的文本，以空格分隔的多个词需全部匹配"
+// @Param jobId query int false "任务ID"
+// @Param cameraId query int false "摄像头ID"
+// @Param deviceId query int false "设备ID，匹配直接或通过设备组分配给该设备的任务"
+// @Param since query string false "拍摄时间下限（含），RFC3339 格式或 Unix 时间戳"
+// @Param until query string false "拍摄时间上限（不含），RFC3339 格式或 Unix 时间戳"
+// @Param alerted query bool false "只返回产生告警的消息"
+// @Param start query int false "起始位置" default(0)
+// @Param limit query int false "每页数量" default(10)
+// @Success 200 {object} dao.ListMessagesResponse "搜索成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/search [get]
+func (s *Server) handleSearchMessages(c *gin.Context) {
+	req := &dao.SearchMessagesRequest{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if model.MatchAnswerQuery(req.Q) == "" {
+		s.writeError(c, http.StatusBadRequest, errors.New("q has no searchable word"))
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	filter := req.ToFilter()
+	filter.OrgId = requestOrgId(c)
+	messages, total, err := model.ListMessages(filter, req.Start, req.Limit)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	items := make([]dao.MessageSpec, len(messages))
+	for i, message := range messages {
+		items[i] = s.messageSpec(message)
+	}
+	c.JSON(http.StatusOK, dao.ListMessagesResponse{
+		Items: items,
+		Total: total,
+	})
+}
+
+// handleSemanticSearchMessages 按语义搜索消息
+// @Summary 按语义搜索消息
+// @Description 按描述的含义搜索消息，如“夜间有人翻越围栏”，按相似度从高到低返回。消息由 consumer 的 embedding sink 向量化，
+// @Description 未启用时返回 400；已删除的消息不会返回
+// @Tags 消息
+// @Produce json
+// @Param q query string true "搜索的描述"
+// @Param limit query int false "返回的数量，最大 100" default(20)
+// @Param minScore query number false "最低相似度，0-1"
+// @Param jobId query int false "任务ID"
+// @Param cameraId query int false "摄像头ID"
+// @Param alerted query bool false "只返回产生告警的消息"
+// @Param since query string false "拍摄时间下限（含），RFC3339 格式或 Unix 时间戳"
+// @Param until query string false "拍摄时间上限（不含），RFC3339 格式或 Unix 时间戳"
+// @Success 200 {object} dao.SemanticSearchResponse "搜索成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/message/semantic-search [get]
+func (s *Server) handleSemanticSearchMessages(c *gin.Context) {
+	if s.embedder == nil {
+		s.writeError(c, http.StatusBadRequest, errors.New("semantic search not enabled"))
+		return
+	}
+	req := &dao.SemanticSearchRequest{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+	filter := embedding.SearchFilter{
+		JobId:    req.JobId,
+		CameraId: req.CameraId,
+		Alerted:  req.Alerted,
+	}
+	if req.Since != "" {
+		filter.Start, _ = dao.ParseTime(req.Since)
+	}
+	if req.Until != "" {
+		filter.End, _ = dao.ParseTime(req.Until)
+	}
+
+	// the query is embedded as text whatever the input of the messages, a
+	// multimodal model puts both in the same space
+	vectors, err := s.embedder.Embed(c, []embedding.Input{{Text: req.Q}})
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	hits, err := s.vectors.Search(c, vectors[0], filter, req.Limit, req.MinScore)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	ids := make([]int, len(hits))
+	for i, h := range hits {
+		ids[i] = h.MessageId
+	}
+	messages, err := model.GetMessagesByIds(ids)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	byId := make(map[int]*model.Message, len(messages))
+	for _, m := range messages {
+		byId[m.Id] = m
+	}
+
+	// the vectors of the messages deleted since are skipped, as are those of
+	// the other organizations, the vectors do not know them
+	resp := dao.SemanticSearchResponse{Items: make([]dao.SemanticSearchHit, 0, len(hits))}
+	for _, h := range hits {
+		if m, ok := byId[h.MessageId]; ok && inRequestOrg(c, m.OrgId) {
+			resp.Items = append(resp.Items, dao.SemanticSearchHit{Score: h.Score, MessageSpec: s.messageSpec(m)})
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// messageSpec converts the message, with the media paths made visitable,
+// presigned if the bucket is private.
+func (s *Server) messageSpec(message *model.Message) dao.MessageSpec {
+	m := *dao.FromMessageModel(message)
+	m.ImagePath = s.mediaUrls.Url(m.ImagePath)
+	m.VideoPath = s.mediaUrls.Url(m.VideoPath)
+	return m
+}
+
+const talkDownTimeout = 60 * time.Second
+
+// handleTalkDown 对消息现场手动喊话
+// @Summary 对消息现场手动喊话
+// @Description 通过消息所属任务的设备在现场扬声器播放音频或合成语音，请求中未填写的字段使用任务的喊话配置，等待设备播放完成后返回
+// @Tags 消息
+// @Accept json
+// @Produce json
+// @Param message_id path string true "消息message_id"
+// @Param req body dao.TalkDownRequest false "喊话内容"
+// @Success 200 {object} dao.TalkDownResult "喊话结果"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "消息不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Failure 504 {object} ErrorResponse "设备响应超时"
+// @Router /api/v1/message/{message_id}/talk-down [post]
+func (s *Server) handleTalkDown(c *gin.Context) {
+	var req dao.TalkDownRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			s.writeError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	message := c.MustGet(messageKey).(*model.Message)
+
+	job, err := model.GetJobById(message.JobId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if job == nil {
+		s.writeError(c, http.StatusBadRequest, errors.New("job of message not found"))
+		return
+	}
+
+	opts := model.TalkDownOptions{}
+	if job.TalkDown != nil {
+		opts = *job.TalkDown
+	}
+	if req.Text != "" || req.AudioUrl != "" {
+		opts.Text, opts.AudioUrl = req.Text, req.AudioUrl
+	}
+	if req.Output != "" {
+		opts.Output = req.Output
+	}
+
+	task, deviceUuid, err := newTalkDownTask(job, &opts, message.Id, talkDownTimeout)
+	if errors.Is(err, errNoTalkDownDevice) || errors.Is(err, errNoTalkDownContent) {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := model.AddTalkDownTask(c, deviceUuid, task); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	defer model.DeleteTalkDownTask(context.Background(), deviceUuid, task.TaskUuid)
+
+	result, err := s.waitTalkDownResult(c, task.TaskUuid, talkDownTimeout)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if result == nil {
+		s.writeError(c, http.StatusGatewayTimeout, errors.New("wait for device result timeout"))
+		return
+	}
+	c.JSON(http.StatusOK, dao.FromTalkDownResultModel(result))
+}
+
+func (s *Server) waitTalkDownResult(c *gin.Context, taskUuid string, timeout time.Duration) (*model.TalkDownResult, error) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+
+	for {
+		result, err := model.GetTalkDownResult(c, taskUuid)
+		if err != nil {
+			return nil, err
+		} else if result != nil {
+			return result, nil
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return nil, c.Request.Context().Err()
+		case <-deadline:
+			return nil, nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"lumina/internal/model"
+	"lumina/pkg/metrics"
+)
+
+// metricsLatencyBuckets are the upper bounds of the buckets of the request
+// latency histograms, in seconds, the default ones of the Prometheus clients.
+var metricsLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+const (
+	metricsTargetNSQ    = "nsq"
+	metricsTargetInflux = "influxdb"
+)
+
+type histogram struct {
+	// counts holds the observations of each bucket, not cumulated
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, le := range metricsLatencyBuckets {
+		if v <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+type requestKey struct {
+	method string
+	route  string
+	code   string
+}
+
+type dbQueryKey struct {
+	operation string
+	failed    bool
+}
+
+// Metrics collects the metrics of the server that /metrics exposes in the
+// Prometheus text format. The business gauges are read from the database on
+// every scrape. A nil Metrics records nothing, so that the components do not
+// check whether the metrics are enabled.
+type Metrics struct {
+	mu            sync.Mutex
+	requests      map[requestKey]*histogram
+	dbQueries     map[dbQueryKey]uint64
+	writeFailures map[string]uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:      make(map[requestKey]*histogram),
+		dbQueries:     make(map[dbQueryKey]uint64),
+		writeFailures: make(map[string]uint64),
+	}
+}
+
+// observeRequest records a request of the route, the requests matching no
+// route being counted together.
+func (m *Metrics) observeRequest(method, route string, status int, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	key := requestKey{method: method, route: route, code: strconv.Itoa(status)}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.requests[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(metricsLatencyBuckets))}
+		m.requests[key] = h
+	}
+	h.observe(latency.Seconds())
+}
+
+func (m *Metrics) countDBQuery(operation string, err error) {
+	if m == nil {
+		return
+	}
+	key := dbQueryKey{
+		operation: operation,
+		failed:    err != nil && !errors.Is(err, gorm.ErrRecordNotFound),
+	}
+	m.mu.Lock()
+	m.dbQueries[key]++
+	m.mu.Unlock()
+}
+
+// countWriteFailure records a failed write to NSQ or InfluxDB.
+func (m *Metrics) countWriteFailure(target string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.writeFailures[target]++
+	m.mu.Unlock()
+}
+
+// registerDBCallbacks counts the statements gorm runs on db by operation.
+func (m *Metrics) registerDBCallbacks(db *gorm.DB) error {
+	count := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			m.countDBQuery(operation, tx.Error)
+		}
+	}
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().After("gorm:create").Register("metrics:create", count("create")),
+		cb.Query().After("gorm:query").Register("metrics:query", count("query")),
+		cb.Update().After("gorm:update").Register("metrics:update", count("update")),
+		cb.Delete().After("gorm:delete").Register("metrics:delete", count("delete")),
+		cb.Row().After("gorm:row").Register("metrics:row", count("row")),
+		cb.Raw().After("gorm:raw").Register("metrics:raw", count("raw")),
+	)
+}
+
+// writeCounters writes the metrics recorded since the server started.
+func (m *Metrics) writeCounters(w *metrics.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Family("lumina_http_request_duration_seconds", "histogram", "Latency of the HTTP requests by method, route and status code.")
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.code < b.code
+	})
+	for _, k := range keys {
+		h := m.requests[k]
+		labels := []string{"method", k.method, "route", k.route, "code", k.code}
+		var cumulated uint64
+		for i, le := range metricsLatencyBuckets {
+			cumulated += h.counts[i]
+			w.Sample("lumina_http_request_duration_seconds_bucket", float64(cumulated),
+				append(labels, "le", strconv.FormatFloat(le, 'g', -1, 64))...)
+		}
+		w.Sample("lumina_http_request_duration_seconds_bucket", float64(h.count), append(labels, "le", "+Inf")...)
+		w.Sample("lumina_http_request_duration_seconds_sum", h.sum, labels...)
+		w.Sample("lumina_http_request_duration_seconds_count", float64(h.count), labels...)
+	}
+
+	w.Family("lumina_db_queries_total", "counter", "Database statements run by operation and result.")
+	dbKeys := make([]dbQueryKey, 0, len(m.dbQueries))
+	for k := range m.dbQueries {
+		dbKeys = append(dbKeys, k)
+	}
+	sort.Slice(dbKeys, func(i, j int) bool {
+		if dbKeys[i].operation != dbKeys[j].operation {
+			return dbKeys[i].operation < dbKeys[j].operation
+		}
+		return !dbKeys[i].failed && dbKeys[j].failed
+	})
+	for _, k := range dbKeys {
+		result := "ok"
+		if k.failed {
+			result = "error"
+		}
+		w.Sample("lumina_db_queries_total", float64(m.dbQueries[k]), "operation", k.operation, "result", result)
+	}
+
+	w.Family("lumina_write_failures_total", "counter", "Failed writes to NSQ and InfluxDB.")
+	for _, target := range []string{metricsTargetInflux, metricsTargetNSQ} {
+		w.Sample("lumina_write_failures_total", float64(m.writeFailures[target]), "target", target)
+	}
+}
+
+// writeMetricsGauges writes the state of the devices, jobs and database pool.
+func writeMetricsGauges(w *metrics.Writer) error {
+	w.Family("lumina_devices", "gauge", "Devices by state.")
+	for _, state := range []model.DeviceState{model.DeviceStateOnline, model.DeviceStateDegraded, model.DeviceStateOffline} {
+		n, err := model.CountDevices(model.DeviceFilter{State: state})
+		if err != nil {
+			return err
+		}
+		w.Sample("lumina_devices", float64(n), "state", string(state))
+	}
+
+	running, err := model.CountRunningJobs()
+	if err != nil {
+		return err
+	}
+	w.Family("lumina_jobs_running", "gauge", "Enabled jobs running on their devices.")
+	w.Sample("lumina_jobs_running", float64(running))
+
+	stats, err := model.DBStats()
+	if err != nil {
+		return err
+	}
+	w.Family("lumina_db_open_connections", "gauge", "Open connections of the database pool by use.")
+	w.Sample("lumina_db_open_connections", float64(stats.InUse), "use", "in_use")
+	w.Sample("lumina_db_open_connections", float64(stats.Idle), "use", "idle")
+	w.Family("lumina_db_wait_count_total", "counter", "Requests that waited for a database connection.")
+	w.Sample("lumina_db_wait_count_total", float64(stats.WaitCount))
+	return nil
+}
+
+// handleMetrics serves the metrics to Prometheus, behind a bearer token if
+// one is configured.
+func (s *Server) handleMetrics(c *gin.Context) {
+	token := s.conf.Metrics.Token
+	if token != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+		s.writeError(c, http.StatusUnauthorized, errors.New("invalid metrics token"))
+		return
+	}
+
+	var w metrics.Writer
+	s.metrics.writeCounters(&w)
+	if err := writeMetricsGauges(&w); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(http.StatusOK, metrics.ContentType, w.Bytes())
+}
@@ -0,0 +1,533 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+// handleGetVapidPublicKey 获取推送公钥
+// @Summary 获取推送公钥
+// @Description 获取浏览器订阅推送所需的VAPID公钥
+// @Tags 通知
+// @Produce json
+// @Success 200 {object} dao.VapidPublicKeyResponse "获取成功"
+// @Router /api/v1/notification/vapid-public-key [get]
+func (s *Server) handleGetVapidPublicKey(c *gin.Context) {
+	resp := dao.VapidPublicKeyResponse{}
+	if s.conf.WebPush.Enabled {
+		resp.PublicKey = s.conf.WebPush.VAPIDPublicKey
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleCreatePushSubscription 订阅推送
+// @Summary 订阅推送
+// @Description 保存当前用户浏览器的推送订阅
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Param req body dao.PushSubscriptionRequest true "订阅信息"
+// @Success 200 "订阅成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/notification/subscription [post]
+func (s *Server) handleCreatePushSubscription(c *gin.Context) {
+	user := c.MustGet(userKey).(*model.User)
+
+	var req dao.PushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := model.SavePushSubscription(req.ToModel(user.Id, c.Request.UserAgent())); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handleDeletePushSubscription 取消订阅推送
+// @Summary 取消订阅推送
+// @Description 删除当前用户浏览器的推送订阅
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Param req body dao.DeletePushSubscriptionRequest true "订阅信息"
+// @Success 200 "取消成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/notification/subscription [delete]
+func (s *Server) handleDeletePushSubscription(c *gin.Context) {
+	user := c.MustGet(userKey).(*model.User)
+
+	var req dao.DeletePushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := model.DeletePushSubscription(user.Id, req.Endpoint); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handleGetNotificationPreference 获取通知偏好
+// @Summary 获取通知偏好
+// @Description 获取当前用户的告警推送偏好
+// @Tags 通知
+// @Produce json
+// @Success 200 {object} dao.NotificationPreferenceSpec "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/notification/preference [get]
+func (s *Server) handleGetNotificationPreference(c *gin.Context) {
+	user := c.MustGet(userKey).(*model.User)
+
+	pref, err := model.GetNotificationPreference(user.Id)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.FromNotificationPreferenceModel(pref))
+}
+
+// handleUpdateNotificationPreference 更新通知偏好
+// @Summary 更新通知偏好
+// @Description 更新当前用户的告警推送偏好
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Param req body dao.UpdateNotificationPreferenceRequest true "通知偏好"
+// @Success 200 {object} dao.NotificationPreferenceSpec "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/notification/preference [put]
+func (s *Server) handleUpdateNotificationPreference(c *gin.Context) {
+	user := c.MustGet(userKey).(*model.User)
+
+	var req dao.UpdateNotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	pref := req.ToModel(user.Id)
+	if err := model.SaveNotificationPreference(pref); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.FromNotificationPreferenceModel(pref))
+}
+
+const notificationChannelKey = "notificationChannel"
+
+// validateNotificationChannel checks the settings the kind of the channel
+// needs.
+func (s *Server) validateNotificationChannel(ch *model.NotificationChannel) error {
+	if _, err := parseNotificationTemplate(ch.Template); err != nil {
+		return err
+	}
+	switch ch.Kind {
+	case model.NotificationChannelWebhook, model.NotificationChannelDingTalk,
+		model.NotificationChannelWeCom, model.NotificationChannelSlack:
+		if ch.Url == "" {
+			return fmt.Errorf("%s channel needs a url", ch.Kind)
+		}
+	case model.NotificationChannelEmail:
+		if s.config().SMTP.Host == "" {
+			return errNoSMTPServer
+		}
+		if len(ch.Recipients) == 0 && len(ch.JobRecipients) == 0 {
+			return errors.New("email channel needs recipients")
+		}
+	}
+	return nil
+}
+
+// firstRecipientJob returns the smallest job having recipients, 0 if none.
+func firstRecipientJob(r model.JobRecipients) int {
+	first := 0
+	for jobId := range r {
+		if first == 0 || jobId < first {
+			first = jobId
+		}
+	}
+	return first
+}
+
+func SetNotificationChannelToContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		channelIdStr := c.Param("channel_id")
+		if channelIdStr == "" {
+			c.Next()
+			return
+		}
+
+		channelId, err := strconv.Atoi(channelIdStr)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "invalid channel_id",
+			})
+			return
+		}
+
+		ch, err := model.GetNotificationChannelById(channelId)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		} else if ch == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "notification channel not found",
+			})
+			return
+		}
+		c.Set(notificationChannelKey, ch)
+		c.Next()
+	}
+}
+
+// handleCreateNotificationChannel 创建通知通道
+// @Summary 创建通知通道
+// @Description 创建通知通道，告警产生后 webhook 通道按模板渲染请求体并 POST 到配置的地址，email 通道通过 SMTP 将告警图片、分析结果发送到通道和任务的收件人，钉钉、企业微信和 slack 通道向群机器人发送带图片预签名地址的 markdown 消息，失败时按指数退避重试，需要管理员权限
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Param req body dao.CreateNotificationChannelRequest true "通知通道"
+// @Success 200 {object} dao.CreateNotificationChannelResponse "创建成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/notification/channel [post]
+func (s *Server) handleCreateNotificationChannel(c *gin.Context) {
+	var req dao.CreateNotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	ch := req.ToModel()
+	if err := s.validateNotificationChannel(ch); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := model.CreateNotificationChannel(ch); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.CreateNotificationChannelResponse{Id: ch.Id})
+}
+
+// handleListNotificationChannels 列出通知通道
+// @Summary 列出通知通道
+// @Description 列出所有通知通道及其最近一次投递结果，需要管理员权限
+// @Tags 通知
+// @Produce json
+// @Success 200 {object} dao.ListNotificationChannelsResponse "列出成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/notification/channel [get]
+func (s *Server) handleListNotificationChannels(c *gin.Context) {
+	chs, err := model.ListNotificationChannels()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListNotificationChannelsResponse{
+		Items: make([]dao.NotificationChannelSpec, 0, len(chs)),
+	}
+	for i := range chs {
+		resp.Items = append(resp.Items, *dao.FromNotificationChannelModel(&chs[i]))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleGetNotificationChannel 获取通知通道
+// @Summary 获取通知通道
+// @Description 获取通知通道，需要管理员权限
+// @Tags 通知
+// @Produce json
+// @Param channel_id path int true "通知通道ID"
+// @Success 200 {object} dao.NotificationChannelSpec "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "通知通道不存在"
+// @Router /api/v1/notification/channel/{channel_id} [get]
+func (s *Server) handleGetNotificationChannel(c *gin.Context) {
+	ch := c.MustGet(notificationChannelKey).(*model.NotificationChannel)
+	c.JSON(http.StatusOK, dao.FromNotificationChannelModel(ch))
+}
+
+// handleUpdateNotificationChannel 更新通知通道
+// @Summary 更新通知通道
+// @Description 更新通知通道，只修改传入的字段，需要管理员权限
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Param channel_id path int true "通知通道ID"
+// @Param req body dao.UpdateNotificationChannelRequest true "通知通道"
+// @Success 200 {object} dao.NotificationChannelSpec "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "通知通道不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/notification/channel/{channel_id} [put]
+func (s *Server) handleUpdateNotificationChannel(c *gin.Context) {
+	var req dao.UpdateNotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	ch := c.MustGet(notificationChannelKey).(*model.NotificationChannel)
+	req.UpdateModel(ch)
+	if err := s.validateNotificationChannel(ch); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := model.UpdateNotificationChannel(ch); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.FromNotificationChannelModel(ch))
+}
+
+// handleDeleteNotificationChannel 删除通知通道
+// @Summary 删除通知通道
+// @Description 删除通知通道，需要管理员权限
+// @Tags 通知
+// @Produce json
+// @Param channel_id path int true "通知通道ID"
+// @Success 200 "删除成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "通知通道不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/notification/channel/{channel_id} [delete]
+func (s *Server) handleDeleteNotificationChannel(c *gin.Context) {
+	ch := c.MustGet(notificationChannelKey).(*model.NotificationChannel)
+	if err := model.DeleteNotificationChannel(ch.Id); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handleTestNotificationChannel 测试通知通道
+// @Summary 测试通知通道
+// @Description 向通知通道发送一条示例告警，不重试，返回对端的状态码和发送的请求体或邮件正文，需要管理员权限
+// @Tags 通知
+// @Produce json
+// @Param channel_id path int true "通知通道ID"
+// @Success 200 {object} dao.TestNotificationChannelResponse "测试结果"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "通知通道不存在"
+// @Router /api/v1/notification/channel/{channel_id}/test [post]
+func (s *Server) handleTestNotificationChannel(c *gin.Context) {
+	ch := c.MustGet(notificationChannelKey).(*model.NotificationChannel)
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	event := &dao.AlertEvent{
+		Message: dao.MessageSpec{
+			Timestamp:    now,
+			CreateTime:   now,
+			Alerted:      true,
+			WorkflowResp: &dao.WorkflowResp{Answer: "这是一条测试告警"},
+		},
+		JobKind:    model.JobKindDetect,
+		CameraName: "测试摄像头",
+		Severity:   model.SeverityMedium,
+		CreateTime: now,
+	}
+
+	if job := firstRecipientJob(ch.JobRecipients); len(ch.Recipients) == 0 && job != 0 {
+		// mails the recipients of a job if the channel has no others
+		event.Message.JobId = job
+	}
+	c.JSON(http.StatusOK, s.notifier.test(c.Request.Context(), ch, event))
+}
+
+const escalationPolicyKey = "escalationPolicy"
+
+// validateEscalationPolicy checks that the steps notify existing channels.
+func validateEscalationPolicy(p *model.EscalationPolicy) error {
+	for _, step := range p.Steps {
+		ch, err := model.GetNotificationChannelById(step.ChannelId)
+		if err != nil {
+			return err
+		} else if ch == nil {
+			return fmt.Errorf("notification channel %d not found", step.ChannelId)
+		}
+	}
+	return nil
+}
+
+func SetEscalationPolicyToContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policyIdStr := c.Param("policy_id")
+		if policyIdStr == "" {
+			c.Next()
+			return
+		}
+
+		policyId, err := strconv.Atoi(policyIdStr)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "invalid policy_id",
+			})
+			return
+		}
+
+		p, err := model.GetEscalationPolicyById(policyId)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		} else if p == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "escalation policy not found",
+			})
+			return
+		}
+		c.Set(escalationPolicyKey, p)
+		c.Next()
+	}
+}
+
+// handleCreateEscalationPolicy 创建告警升级策略
+// @Summary 创建告警升级策略
+// @Description 创建告警升级策略，策略接受的告警按步骤通知通道，延迟为 0 的步骤立即通知，其余步骤在告警产生后仍未确认达到延迟分钟数时通知，需要管理员权限
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Param req body dao.CreateEscalationPolicyRequest true "告警升级策略"
+// @Success 200 {object} dao.CreateEscalationPolicyResponse "创建成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/notification/escalation [post]
+func (s *Server) handleCreateEscalationPolicy(c *gin.Context) {
+	var req dao.CreateEscalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	p := req.ToModel()
+	if err := validateEscalationPolicy(p); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := model.CreateEscalationPolicy(p); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.CreateEscalationPolicyResponse{Id: p.Id})
+}
+
+// handleListEscalationPolicies 列出告警升级策略
+// @Summary 列出告警升级策略
+// @Description 列出所有告警升级策略，需要管理员权限
+// @Tags 通知
+// @Produce json
+// @Success 200 {object} dao.ListEscalationPoliciesResponse "列出成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/notification/escalation [get]
+func (s *Server) handleListEscalationPolicies(c *gin.Context) {
+	ps, err := model.ListEscalationPolicies()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListEscalationPoliciesResponse{
+		Items: make([]dao.EscalationPolicySpec, 0, len(ps)),
+	}
+	for i := range ps {
+		resp.Items = append(resp.Items, *dao.FromEscalationPolicyModel(&ps[i]))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleGetEscalationPolicy 获取告警升级策略
+// @Summary 获取告警升级策略
+// @Description 获取告警升级策略，需要管理员权限
+// @Tags 通知
+// @Produce json
+// @Param policy_id path int true "告警升级策略ID"
+// @Success 200 {object} dao.EscalationPolicySpec "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "告警升级策略不存在"
+// @Router /api/v1/notification/escalation/{policy_id} [get]
+func (s *Server) handleGetEscalationPolicy(c *gin.Context) {
+	p := c.MustGet(escalationPolicyKey).(*model.EscalationPolicy)
+	c.JSON(http.StatusOK, dao.FromEscalationPolicyModel(p))
+}
+
+// handleUpdateEscalationPolicy 更新告警升级策略
+// @Summary 更新告警升级策略
+// @Description 更新告警升级策略，只修改传入的字段，需要管理员权限
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Param policy_id path int true "告警升级策略ID"
+// @Param req body dao.UpdateEscalationPolicyRequest true "告警升级策略"
+// @Success 200 {object} dao.EscalationPolicySpec "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "告警升级策略不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/notification/escalation/{policy_id} [put]
+func (s *Server) handleUpdateEscalationPolicy(c *gin.Context) {
+	var req dao.UpdateEscalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	p := c.MustGet(escalationPolicyKey).(*model.EscalationPolicy)
+	req.UpdateModel(p)
+	if err := validateEscalationPolicy(p); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := model.UpdateEscalationPolicy(p); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.FromEscalationPolicyModel(p))
+}
+
+// handleDeleteEscalationPolicy 删除告警升级策略
+// @Summary 删除告警升级策略
+// @Description 删除告警升级策略，需要管理员权限
+// @Tags 通知
+// @Produce json
+// @Param policy_id path int true "告警升级策略ID"
+// @Success 200 "删除成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "告警升级策略不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/notification/escalation/{policy_id} [delete]
+func (s *Server) handleDeleteEscalationPolicy(c *gin.Context) {
+	p := c.MustGet(escalationPolicyKey).(*model.EscalationPolicy)
+	if err := model.DeleteEscalationPolicy(p.Id); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
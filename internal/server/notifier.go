@@ -0,0 +1,361 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const (
+	// notifierConcurrency bounds the deliveries in flight, including the
+	// ones waiting to retry
+	notifierConcurrency  = 16
+	notifierRetryBackoff = time.Second
+	notifierMaxBackoff   = time.Minute
+	// notificationImageUrlExpire is the longest presigned URLs are valid
+	notificationImageUrlExpire = 7 * 24 * time.Hour
+)
+
+var notificationTemplateFuncs = template.FuncMap{
+	// json renders a value as JSON, it keeps templated JSON payloads valid
+	// whatever the strings of the alert contain
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// parseNotificationTemplate parses the payload template of a channel.
+func parseNotificationTemplate(text string) (*template.Template, error) {
+	return template.New("payload").Funcs(notificationTemplateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// notificationData is what the templates of the channels render: the alert
+// event and the URLs the recipients can open.
+type notificationData struct {
+	*dao.AlertEvent
+	// ImageUrl is a presigned URL of the alert image, the image path if it
+	// cannot be presigned
+	ImageUrl string
+}
+
+func executeNotificationTemplate(text string, data *notificationData) ([]byte, error) {
+	tmpl, err := parseNotificationTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderNotification renders the payload of the alert for a webhook channel,
+// the alert event as JSON if the channel has no template.
+func renderNotification(ch *model.NotificationChannel, data *notificationData) ([]byte, error) {
+	if ch.Template == "" {
+		return json.Marshal(data.AlertEvent)
+	}
+	return executeNotificationTemplate(ch.Template, data)
+}
+
+// deliveryError is a failed delivery, retryable unless the endpoint
+// rejected the payload.
+type deliveryError struct {
+	statusCode int
+	err        error
+}
+
+func (e *deliveryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *deliveryError) retryable() bool {
+	return e.statusCode == 0 || e.statusCode == http.StatusTooManyRequests || e.statusCode >= 500
+}
+
+// retryable reports whether a failed delivery may succeed later: webhook
+// endpoints and SMTP servers rejecting the alert for good are not retried.
+func retryable(err error) bool {
+	var de *deliveryError
+	if errors.As(err, &de) {
+		return de.retryable()
+	}
+	var pe *textproto.Error
+	if errors.As(err, &pe) {
+		return pe.Code < 500
+	}
+	return !errors.Is(err, errNoSMTPServer)
+}
+
+// Notifier delivers alert events through the enabled notification channels
+// accepting them, so that they reach external systems, and through the
+// channels of the escalation policies until the alerts are acknowledged.
+type Notifier struct {
+	smtp     atomic.Pointer[SMTPConfig]
+	s3       S3Config
+	minioCli *minio.Client
+	client   *http.Client
+	sem      chan struct{}
+	logger   *logrus.Entry
+}
+
+func NewNotifier(logger *logrus.Entry, smtp SMTPConfig, s3 S3Config, minioCli *minio.Client, client *http.Client) *Notifier {
+	n := &Notifier{
+		s3:       s3,
+		minioCli: minioCli,
+		client:   client,
+		sem:      make(chan struct{}, notifierConcurrency),
+		logger:   logger.WithField("component", "notifier"),
+	}
+	n.SetSMTP(smtp)
+	return n
+}
+
+// SetSMTP replaces the SMTP server of the emails sent from now on.
+func (n *Notifier) SetSMTP(conf SMTPConfig) {
+	n.smtp.Store(&conf)
+}
+
+func (n *Notifier) Run(ctx context.Context, hub *AlertHub) {
+	sub := hub.subscribe(dao.AlertEventFilter{})
+	defer hub.unsubscribe(sub)
+	ticker := time.NewTicker(escalationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-sub.ch:
+			if err := n.notify(ctx, event); err != nil {
+				n.logger.WithError(err).Errorf("notify alert %d failed", event.AlertId)
+			}
+			if err := n.escalate(ctx, event); err != nil {
+				n.logger.WithError(err).Errorf("escalate alert %d failed", event.AlertId)
+			}
+		case now := <-ticker.C:
+			if err := n.escalateUnacked(ctx, hub, now); err != nil {
+				n.logger.WithError(err).Error("escalate unacknowledged alerts failed")
+			}
+		}
+	}
+}
+
+func (n *Notifier) notify(ctx context.Context, event *dao.AlertEvent) error {
+	chs, err := model.ListEnabledNotificationChannels()
+	if err != nil {
+		return err
+	}
+	for i := range chs {
+		ch := &chs[i]
+		if !ch.Accept(event.Message.JobId, event.CameraId, event.Severity) {
+			continue
+		}
+		if err := n.dispatch(ctx, ch, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dispatch delivers the alert through the channel in the background unless
+// it was already delivered through it.
+func (n *Notifier) dispatch(ctx context.Context, ch *model.NotificationChannel, event *dao.AlertEvent) error {
+	if ok, err := model.ClaimNotificationDelivery(ctx, ch.Id, event.AlertId); err != nil {
+		return err
+	} else if !ok {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case n.sem <- struct{}{}:
+	}
+	go func() {
+		defer func() { <-n.sem }()
+		n.deliver(ctx, ch, event)
+	}()
+	return nil
+}
+
+// deliver sends the alert through the channel, retrying with an
+// exponential backoff, and records the result on the channel.
+func (n *Notifier) deliver(ctx context.Context, ch *model.NotificationChannel, event *dao.AlertEvent) {
+	logger := n.logger.WithField("channel", ch.Name)
+	send, _, err := n.prepare(ctx, ch, event)
+	if err != nil {
+		logger.WithError(err).Errorf("render alert %d failed", event.AlertId)
+		n.record(ch, err)
+		return
+	} else if send == nil {
+		return
+	}
+
+	err = n.sendWithRetry(ctx, ch, send, fmt.Sprintf("alert %d", event.AlertId))
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		logger.WithError(err).Errorf("deliver alert %d failed", event.AlertId)
+	}
+	n.record(ch, err)
+}
+
+// sendWithRetry sends until it succeeds, fails for good or the retries of
+// the channel run out, with an exponential backoff. what names the payload
+// in the logs.
+func (n *Notifier) sendWithRetry(ctx context.Context, ch *model.NotificationChannel, send func(context.Context) (int, error), what string) error {
+	backoff := notifierRetryBackoff
+	for attempt := 0; ; attempt++ {
+		_, err := send(ctx)
+		if err == nil || attempt >= ch.MaxRetries || !retryable(err) {
+			return err
+		}
+		n.logger.WithField("channel", ch.Name).WithError(err).Warnf("deliver %s failed, retry in %s", what, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, notifierMaxBackoff)
+	}
+}
+
+// prepare renders the alert for the channel and returns the function
+// sending it once, which returns the status code of webhook endpoints, and
+// the rendered payload or email body. send is nil if the alert has no
+// recipient.
+func (n *Notifier) prepare(ctx context.Context, ch *model.NotificationChannel, event *dao.AlertEvent) (send func(context.Context) (int, error), body string, err error) {
+	data := &notificationData{
+		AlertEvent: event,
+		ImageUrl:   n.imageUrl(ctx, event.Message.ImagePath),
+	}
+	if ch.Kind == model.NotificationChannelEmail {
+		to := ch.EmailRecipients(event.Message.JobId)
+		if len(to) == 0 {
+			return nil, "", nil
+		}
+		e, err := n.renderEmail(ctx, ch, data)
+		if err != nil {
+			return nil, "", err
+		}
+		conf := n.smtp.Load()
+		msg, err := e.message(conf.From, to)
+		if err != nil {
+			return nil, "", err
+		}
+		return func(ctx context.Context) (int, error) {
+			return 0, sendMail(ctx, *conf, to, msg)
+		}, e.body, nil
+	}
+
+	var payload []byte
+	if isIMChannel(ch.Kind) {
+		payload, err = renderIMPayload(ch, data)
+	} else {
+		payload, err = renderNotification(ch, data)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return func(ctx context.Context) (int, error) {
+		return n.post(ctx, ch, payload)
+	}, string(payload), nil
+}
+
+// imageUrl presigns the alert image so that the recipients can open it
+// without access to the bucket, it returns the visit URL if it cannot.
+func (n *Notifier) imageUrl(ctx context.Context, imagePath string) string {
+	prefix := n.s3.VisitPrefix()
+	if imagePath == "" || n.minioCli == nil || !strings.HasPrefix(imagePath, prefix) {
+		return imagePath
+	}
+	u, err := n.minioCli.PresignedGetObject(ctx, n.s3.Bucket,
+		strings.TrimPrefix(strings.TrimPrefix(imagePath, prefix), "/"), notificationImageUrlExpire, nil)
+	if err != nil {
+		n.logger.WithError(err).Warnf("presign %s failed", imagePath)
+		return imagePath
+	}
+	return u.String()
+}
+
+// test sends the alert through the channel once.
+func (n *Notifier) test(ctx context.Context, ch *model.NotificationChannel, event *dao.AlertEvent) *dao.TestNotificationChannelResponse {
+	var resp dao.TestNotificationChannelResponse
+	send, body, err := n.prepare(ctx, ch, event)
+	if err != nil {
+		resp.Error = err.Error()
+		return &resp
+	} else if send == nil {
+		resp.Error = "the channel has no recipient for the alert"
+		return &resp
+	}
+	resp.Body = body
+	resp.StatusCode, err = send(ctx)
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return &resp
+}
+
+func (n *Notifier) record(ch *model.NotificationChannel, err error) {
+	if err := model.SetNotificationChannelDelivery(ch.Id, time.Now(), err); err != nil {
+		n.logger.WithError(err).Errorf("record delivery of channel %d failed", ch.Id)
+	}
+}
+
+// post sends the payload to the channel once and returns the status code of
+// the endpoint. The error is a *deliveryError.
+func (n *Notifier) post(ctx context.Context, ch *model.NotificationChannel, body []byte) (int, error) {
+	target := ch.Url
+	if ch.Kind == model.NotificationChannelDingTalk && ch.Secret != "" {
+		var err error
+		if target, err = dingTalkSignedUrl(ch.Url, ch.Secret, time.Now()); err != nil {
+			return 0, &deliveryError{err: err}
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, &deliveryError{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lumina-notifier")
+	for k, v := range ch.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, &deliveryError{err: err}
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, &deliveryError{
+			statusCode: resp.StatusCode,
+			err:        fmt.Errorf("endpoint returned status %d", resp.StatusCode),
+		}
+	}
+	if err := checkIMResponse(ch.Kind, respBody); err != nil {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, nil
+}
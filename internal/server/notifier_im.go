@@ -0,0 +1,154 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"lumina/internal/model"
+)
+
+// defaultIMTemplates render the text of the alerts for the chat tools
+// without template, in their markdown flavour.
+var defaultIMTemplates = map[model.NotificationChannelKind]string{
+	model.NotificationChannelDingTalk: `### 告警：{{.CameraName}}
+- 任务：{{.Message.JobId}}
+- 时间：{{.Message.Timestamp}}
+{{with .Message.WorkflowResp}}{{if .Answer}}- 分析结果：{{.Answer}}
+{{end}}{{end}}{{with .ImageUrl}}
+![告警图片]({{.}})
+{{end}}`,
+	model.NotificationChannelWeCom: `### 告警：{{.CameraName}}
+> 任务：{{.Message.JobId}}
+> 时间：{{.Message.Timestamp}}
+{{with .Message.WorkflowResp}}{{if .Answer}}> 分析结果：{{.Answer}}
+{{end}}{{end}}{{with .ImageUrl}}[查看图片]({{.}})
+{{end}}`,
+	model.NotificationChannelSlack: `*告警：{{.CameraName}}*
+任务：{{.Message.JobId}}
+时间：{{.Message.Timestamp}}
+{{with .Message.WorkflowResp}}{{if .Answer}}分析结果：{{.Answer}}
+{{end}}{{end}}`,
+}
+
+// isIMChannel reports whether the kind is one of the chat tools, whose
+// payload is built by the notifier around the rendered text.
+func isIMChannel(kind model.NotificationChannelKind) bool {
+	_, ok := defaultIMTemplates[kind]
+	return ok
+}
+
+// renderIMPayload renders the robot message of the alert for a chat tool
+// channel, the template renders its text.
+func renderIMPayload(ch *model.NotificationChannel, data *notificationData) ([]byte, error) {
+	text := ch.Template
+	if text == "" {
+		text = defaultIMTemplates[ch.Kind]
+	}
+	rendered, err := executeNotificationTemplate(text, data)
+	if err != nil {
+		return nil, err
+	}
+	title := "告警"
+	if data.CameraName != "" {
+		title = "告警：" + data.CameraName
+	}
+	return imPayload(ch.Kind, title, string(rendered), data.ImageUrl)
+}
+
+// imPayload wraps the markdown content into the robot message of the chat
+// tool, Slack shows the image if imageUrl is not empty.
+func imPayload(kind model.NotificationChannelKind, title, content, imageUrl string) ([]byte, error) {
+	var payload any
+	switch kind {
+	case model.NotificationChannelDingTalk:
+		payload = map[string]any{
+			"msgtype": "markdown",
+			"markdown": map[string]string{
+				"title": title,
+				"text":  content,
+			},
+		}
+	case model.NotificationChannelWeCom:
+		payload = map[string]any{
+			"msgtype": "markdown",
+			"markdown": map[string]string{
+				"content": content,
+			},
+		}
+	case model.NotificationChannelSlack:
+		blocks := []map[string]any{{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": content},
+		}}
+		if imageUrl != "" {
+			blocks = append(blocks, map[string]any{
+				"type":      "image",
+				"image_url": imageUrl,
+				"alt_text":  title,
+			})
+		}
+		payload = map[string]any{
+			// shown in the notifications of the clients
+			"text":   title,
+			"blocks": blocks,
+		}
+	default:
+		return nil, fmt.Errorf("unknown channel kind %s", kind)
+	}
+	return json.Marshal(payload)
+}
+
+// dingTalkSignedUrl appends the signature of the robots with the "加签"
+// security setting to the webhook URL.
+func dingTalkSignedUrl(webhook, secret string, now time.Time) (string, error) {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return "", err
+	}
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	q := u.Query()
+	q.Set("timestamp", timestamp)
+	q.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// imRateLimitCodes are the error codes of DingTalk and WeCom robots sending
+// too many messages, the delivery is retried.
+var imRateLimitCodes = map[int]bool{
+	130101: true, // DingTalk: send too fast
+	45009:  true, // WeCom: api freq out of limit
+}
+
+// checkIMResponse checks the body of the answer of DingTalk and WeCom
+// robots, which reply 200 with an error code when they reject a message.
+func checkIMResponse(kind model.NotificationChannelKind, body []byte) error {
+	if kind != model.NotificationChannelDingTalk && kind != model.NotificationChannelWeCom {
+		return nil
+	}
+	var resp struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.ErrCode == 0 {
+		return nil
+	}
+	// reuses the status codes telling retryable deliveries apart
+	statusCode := http.StatusBadRequest
+	if imRateLimitCodes[resp.ErrCode] {
+		statusCode = http.StatusTooManyRequests
+	}
+	return &deliveryError{
+		statusCode: statusCode,
+		err:        fmt.Errorf("robot returned error %d: %s", resp.ErrCode, resp.ErrMsg),
+	}
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+
+	"lumina/internal/model"
+	"lumina/pkg/oidc"
+	"lumina/pkg/str"
+)
+
+// oidcStateTTL bounds the time the user has to log in at the provider.
+const oidcStateTTL = 10 * time.Minute
+
+// OIDCLogin logs the users in with an OpenID Connect provider. The provider
+// is discovered on the first login so that the server starts even if the
+// provider is down.
+type OIDCLogin struct {
+	conf   OIDCConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func NewOIDCLogin(conf OIDCConfig, client *http.Client) (*OIDCLogin, error) {
+	if conf.Issuer == "" || conf.ClientId == "" || conf.RedirectUrl == "" {
+		return nil, fmt.Errorf("oidc: issuer, clientId and redirectUrl are required")
+	}
+	if conf.UsernameClaim == "" {
+		return nil, fmt.Errorf("oidc: usernameClaim is required")
+	}
+	if (len(conf.AdminRoles) > 0 || len(conf.AllowedRoles) > 0) && conf.RolesClaim == "" {
+		return nil, fmt.Errorf("oidc: rolesClaim is required by adminRoles and allowedRoles")
+	}
+	return &OIDCLogin{conf: conf, client: client}, nil
+}
+
+// oauth2Config returns the provider, discovering it if needed, and the
+// client config of the authorization code flow.
+func (o *OIDCLogin) oauth2Config(ctx context.Context) (*oidc.Provider, *oauth2.Config, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.provider == nil {
+		p, err := oidc.Discover(ctx, o.client, o.conf.Issuer)
+		if err != nil {
+			return nil, nil, err
+		}
+		o.provider = p
+	}
+	return o.provider, &oauth2.Config{
+		ClientID:     o.conf.ClientId,
+		ClientSecret: o.conf.ClientSecret,
+		RedirectURL:  o.conf.RedirectUrl,
+		Scopes:       o.conf.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  o.provider.AuthorizationEndpoint,
+			TokenURL: o.provider.TokenEndpoint,
+		},
+	}, nil
+}
+
+// safeRedirect keeps the paths of this site only, so that the login cannot
+// be used to send the users elsewhere.
+func safeRedirect(redirect string) string {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(redirect, "/\\") {
+		return "/"
+	}
+	return redirect
+}
+
+// @Summary OIDC 登录
+// @Description 跳转到 OIDC 身份提供方登录，登录成功后回调接口设置登录凭证并跳回 redirect
+// @Tags 用户
+// @Param redirect query string false "登录后跳转的页面路径，默认为 /"
+// @Success 302
+// @Failure 404 {object} ErrorResponse "未启用 OIDC 登录"
+// @Failure 502 {object} ErrorResponse "身份提供方不可用"
+// @Router /api/v1/oidc/login [get]
+func (s *Server) handleOidcLogin(c *gin.Context) {
+	if s.oidc == nil {
+		s.writeError(c, http.StatusNotFound, fmt.Errorf("oidc login is disabled"))
+		return
+	}
+	_, conf, err := s.oidc.oauth2Config(c.Request.Context())
+	if err != nil {
+		s.logger.WithError(err).Error("oidc discovery failed")
+		s.writeError(c, http.StatusBadGateway, fmt.Errorf("identity provider unavailable"))
+		return
+	}
+
+	state := str.GenToken(32)
+	st := &model.OidcState{
+		Nonce:        str.GenToken(32),
+		CodeVerifier: oauth2.GenerateVerifier(),
+		Redirect:     safeRedirect(c.Query("redirect")),
+	}
+	if err := model.SaveOidcState(c.Request.Context(), state, st, oidcStateTTL); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Redirect(http.StatusFound, conf.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(st.CodeVerifier),
+		oauth2.SetAuthURLParam("nonce", st.Nonce)))
+}
+
+// @Summary OIDC 登录回调
+// @Description 身份提供方登录后的回调，校验 ID Token，按需创建用户并同步角色，设置登录凭证后跳回登录前的页面
+// @Tags 用户
+// @Param code query string true "授权码"
+// @Param state query string true "登录请求的 state"
+// @Success 302
+// @Failure 400 {object} ErrorResponse "state 无效或已过期"
+// @Failure 401 {object} ErrorResponse "登录失败"
+// @Failure 403 {object} ErrorResponse "用户无权登录"
+// @Failure 409 {object} ErrorResponse "用户名已被本地用户占用"
+// @Router /api/v1/oidc/callback [get]
+func (s *Server) handleOidcCallback(c *gin.Context) {
+	if s.oidc == nil {
+		s.writeError(c, http.StatusNotFound, fmt.Errorf("oidc login is disabled"))
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		s.writeError(c, http.StatusUnauthorized, fmt.Errorf("oidc login failed: %s %s", e, c.Query("error_description")))
+		return
+	}
+
+	ctx := c.Request.Context()
+	st, err := model.TakeOidcState(ctx, c.Query("state"))
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if st == nil {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("invalid or expired oidc state"))
+		return
+	}
+	provider, conf, err := s.oidc.oauth2Config(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("oidc discovery failed")
+		s.writeError(c, http.StatusBadGateway, fmt.Errorf("identity provider unavailable"))
+		return
+	}
+
+	token, err := conf.Exchange(context.WithValue(ctx, oauth2.HTTPClient, s.client), c.Query("code"),
+		oauth2.VerifierOption(st.CodeVerifier))
+	if err != nil {
+		s.logger.WithError(err).Warn("oidc code exchange failed")
+		s.writeError(c, http.StatusUnauthorized, fmt.Errorf("oidc login failed"))
+		return
+	}
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		s.writeError(c, http.StatusUnauthorized, fmt.Errorf("oidc login failed: no id token"))
+		return
+	}
+	claims, err := provider.Verify(ctx, rawIdToken, s.oidc.conf.ClientId, st.Nonce)
+	if err != nil {
+		s.logger.WithError(err).Warn("oidc id token rejected")
+		s.writeError(c, http.StatusUnauthorized, fmt.Errorf("oidc login failed"))
+		return
+	}
+
+	user, status, err := s.oidcUser(claims)
+	if err != nil {
+		s.writeError(c, status, err)
+		return
+	}
+	if _, err := s.startSession(c, user); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Redirect(http.StatusFound, st.Redirect)
+}
+
+// oidcUser returns the user of the verified claims, provisioning it on its
+// first login and updating its admin flag from its roles on every login.
+// The second factor is left to the provider.
+func (s *Server) oidcUser(claims map[string]any) (*model.User, int, error) {
+	conf := s.oidc.conf
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, http.StatusUnauthorized, fmt.Errorf("oidc login failed: no subject")
+	}
+	subject := strings.TrimSuffix(conf.Issuer, "/") + " " + sub
+
+	var roles []string
+	if conf.RolesClaim != "" {
+		roles = oidc.StringsClaim(claims, conf.RolesClaim)
+	}
+	hasRole := func(wanted []string) bool {
+		return slices.ContainsFunc(roles, func(r string) bool { return slices.Contains(wanted, r) })
+	}
+	isAdmin := hasRole(conf.AdminRoles)
+	if len(conf.AllowedRoles) > 0 && !isAdmin && !hasRole(conf.AllowedRoles) {
+		return nil, http.StatusForbidden, fmt.Errorf("user is not allowed to log in")
+	}
+
+	username := ""
+	if values := oidc.StringsClaim(claims, conf.UsernameClaim); len(values) > 0 {
+		username = values[0]
+	}
+	if username == "" {
+		return nil, http.StatusUnauthorized, fmt.Errorf("oidc login failed: no %s claim", conf.UsernameClaim)
+	}
+	nickname, _ := claims["name"].(string)
+	if nickname == "" {
+		nickname = username
+	}
+
+	user, err := model.GetUserByOidcSubject(subject)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if user != nil {
+		if len(conf.AdminRoles) == 0 {
+			isAdmin = user.IsAdmin
+		}
+		if err := model.UpdateUserOidcProfile(user.Id, nickname, isAdmin); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		user.Nickname = nickname
+		user.IsAdmin = isAdmin
+		return user, http.StatusOK, nil
+	}
+
+	if !conf.AutoProvision {
+		return nil, http.StatusForbidden, fmt.Errorf("user %s is not provisioned", username)
+	}
+	// the local users are never taken over by a provider user of the same
+	// name
+	if _, err := model.GetUserByUsername(username); err == nil {
+		return nil, http.StatusConflict, fmt.Errorf("username %s is taken by a local user", username)
+	}
+	// the provisioned users log in with the provider only
+	password, err := model.HashPassword(str.GenToken(32))
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	user = &model.User{
+		Username:    username,
+		Nickname:    nickname,
+		Password:    password,
+		IsAdmin:     isAdmin,
+		AccessToken: "sk-" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+		OidcSubject: subject,
+		OrgId:       model.DefaultOrgId,
+	}
+	if err := model.CreateUser(user); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	s.logger.Infof("provisioned oidc user %s, admin %v", username, isAdmin)
+	return user, http.StatusOK, nil
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+	"lumina/pkg/onvif"
+)
+
+const (
+	defaultDiscoverTimeout = 5
+	// the device picks up the task on its next fetch tick and then queries
+	// every camera it found
+	discoverWaitSlack = 20 * time.Second
+	ptzRequestTimeout = 10 * time.Second
+)
+
+// handleDiscoverCameras 扫描 ONVIF 摄像头
+// @Summary 扫描 ONVIF 摄像头
+// @Description 由指定设备扫描所在网络的 ONVIF 摄像头，返回可用于创建摄像头的预填信息
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Param req body dao.DiscoverCamerasRequest true "扫描请求"
+// @Success 200 {object} dao.DiscoverCamerasResponse "扫描结果"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "设备不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Failure 504 {object} ErrorResponse "设备响应超时"
+// @Router /api/v1/camera/discover [post]
+func (s *Server) handleDiscoverCameras(c *gin.Context) {
+	var req dao.DiscoverCamerasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Timeout == 0 {
+		req.Timeout = defaultDiscoverTimeout
+	}
+
+	device, err := model.GetDeviceById(req.DeviceId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if device == nil || !inRequestOrg(c, device.OrgId) {
+		s.writeError(c, http.StatusNotFound, errors.New("device not found"))
+		return
+	}
+
+	wait := time.Duration(req.Timeout)*time.Second + discoverWaitSlack
+	task := &model.OnvifDiscoverTask{
+		TaskUuid:   uuid.New().String(),
+		Subnet:     req.Subnet,
+		Username:   req.Username,
+		Password:   req.Password,
+		Timeout:    req.Timeout,
+		ExpireTime: time.Now().Add(wait),
+	}
+	if err := model.AddOnvifDiscoverTask(c, device.Uuid, task); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	defer model.DeleteOnvifDiscoverTask(context.Background(), device.Uuid, task.TaskUuid)
+
+	result, err := s.waitOnvifDiscoverResult(c, task.TaskUuid, wait)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if result == nil {
+		s.writeError(c, http.StatusGatewayTimeout, errors.New("device did not respond in time"))
+		return
+	} else if result.Error != "" {
+		s.writeError(c, http.StatusInternalServerError, errors.New(result.Error))
+		return
+	}
+	c.JSON(http.StatusOK, dao.FromOnvifDiscoverResultModel(result, &req))
+}
+
+func (s *Server) waitOnvifDiscoverResult(c *gin.Context, taskUuid string, timeout time.Duration) (*model.OnvifDiscoverResult, error) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+
+	for {
+		result, err := model.GetOnvifDiscoverResult(c, taskUuid)
+		if err != nil {
+			return nil, err
+		} else if result != nil {
+			return result, nil
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return nil, c.Request.Context().Err()
+		case <-deadline:
+			return nil, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// cameraPTZ connects to the PTZ service of the camera in the context, it
+// writes the error response and returns nil on failure.
+func (s *Server) cameraPTZ(ctx context.Context, c *gin.Context) *onvif.PTZ {
+	cam := c.MustGet(cameraKey).(*model.Camera)
+	if cam.OnvifPort == 0 {
+		s.writeError(c, http.StatusBadRequest, errors.New("camera does not support ONVIF"))
+		return nil
+	}
+	client := onvif.NewClient(onvif.DeviceServiceURL(cam.Ip, cam.OnvifPort), cam.Username, cam.Password)
+	ptz, err := onvif.NewPTZ(ctx, client)
+	if errors.Is(err, onvif.ErrNotSupported) {
+		s.writeError(c, http.StatusBadRequest, errors.New("camera does not support PTZ"))
+		return nil
+	} else if err != nil {
+		s.writeError(c, http.StatusBadGateway, err)
+		return nil
+	}
+	return ptz
+}
+
+// handlePtzMove 摄像头云台移动
+// @Summary 摄像头云台移动
+// @Description 以指定速度持续移动摄像头云台，直到停止或超时
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Param camera_id path int true "摄像头ID"
+// @Param req body dao.PtzMoveRequest true "移动请求"
+// @Success 200 "操作成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误或摄像头不支持云台"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "摄像头不存在"
+// @Failure 502 {object} ErrorResponse "摄像头请求失败"
+// @Router /api/v1/camera/{camera_id}/ptz/move [post]
+func (s *Server) handlePtzMove(c *gin.Context) {
+	var req dao.PtzMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Timeout == 0 {
+		req.Timeout = 1000
+	}
+
+	ctx, cancel := context.WithTimeout(c, ptzRequestTimeout)
+	defer cancel()
+	ptz := s.cameraPTZ(ctx, c)
+	if ptz == nil {
+		return
+	}
+	if err := ptz.ContinuousMove(ctx, req.Pan, req.Tilt, req.Zoom, time.Duration(req.Timeout)*time.Millisecond); err != nil {
+		s.writeError(c, http.StatusBadGateway, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handlePtzStop 停止摄像头云台移动
+// @Summary 停止摄像头云台移动
+// @Description 停止摄像头云台移动
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Param camera_id path int true "摄像头ID"
+// @Success 200 "操作成功"
+// @Failure 400 {object} ErrorResponse "摄像头不支持云台"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "摄像头不存在"
+// @Failure 502 {object} ErrorResponse "摄像头请求失败"
+// @Router /api/v1/camera/{camera_id}/ptz/stop [post]
+func (s *Server) handlePtzStop(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, ptzRequestTimeout)
+	defer cancel()
+	ptz := s.cameraPTZ(ctx, c)
+	if ptz == nil {
+		return
+	}
+	if err := ptz.Stop(ctx); err != nil {
+		s.writeError(c, http.StatusBadGateway, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handleListPtzPresets 获取摄像头云台预置位
+// @Summary 获取摄像头云台预置位
+// @Description 获取摄像头云台预置位
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Param camera_id path int true "摄像头ID"
+// @Success 200 {object} dao.ListPtzPresetsResponse "预置位列表"
+// @Failure 400 {object} ErrorResponse "摄像头不支持云台"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "摄像头不存在"
+// @Failure 502 {object} ErrorResponse "摄像头请求失败"
+// @Router /api/v1/camera/{camera_id}/ptz/presets [get]
+func (s *Server) handleListPtzPresets(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, ptzRequestTimeout)
+	defer cancel()
+	ptz := s.cameraPTZ(ctx, c)
+	if ptz == nil {
+		return
+	}
+	presets, err := ptz.GetPresets(ctx)
+	if err != nil {
+		s.writeError(c, http.StatusBadGateway, err)
+		return
+	}
+	resp := dao.ListPtzPresetsResponse{Presets: make([]dao.PtzPreset, 0, len(presets))}
+	for _, p := range presets {
+		resp.Presets = append(resp.Presets, dao.PtzPreset{Token: p.Token, Name: p.Name})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handlePtzGotoPreset 摄像头云台转到预置位
+// @Summary 摄像头云台转到预置位
+// @Description 摄像头云台转到预置位
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Param camera_id path int true "摄像头ID"
+// @Param req body dao.PtzGotoPresetRequest true "预置位"
+// @Success 200 "操作成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误或摄像头不支持云台"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "摄像头不存在"
+// @Failure 502 {object} ErrorResponse "摄像头请求失败"
+// @Router /api/v1/camera/{camera_id}/ptz/goto-preset [post]
+func (s *Server) handlePtzGotoPreset(c *gin.Context) {
+	var req dao.PtzGotoPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, ptzRequestTimeout)
+	defer cancel()
+	ptz := s.cameraPTZ(ctx, c)
+	if ptz == nil {
+		return
+	}
+	if err := ptz.GotoPreset(ctx, req.PresetToken); err != nil {
+		s.writeError(c, http.StatusBadGateway, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
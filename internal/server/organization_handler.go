@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const orgKey = "orgId"
+
+// tokenUser returns the user of an access or JWT token, nil if the token is
+// not valid.
+func tokenUser(ctx context.Context, tokenStr, jwtSecret string) *model.User {
+	if strings.HasPrefix(tokenStr, "sk-") {
+		user, err := model.GetUserByToken(tokenStr)
+		if err != nil {
+			return nil
+		}
+		return user
+	}
+	claims := &TokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(jwtSecret), nil
+	})
+	if err != nil || !token.Valid || tokenDenied(ctx, claims) {
+		return nil
+	}
+	user, err := model.GetUserById(claims.UserId)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
+// SetOrgToContext scopes the requests of the multi tenant servers to the
+// organization of the user or the device of the token. The anonymous
+// requests are scoped to no organization and see no data, the routes
+// needing a user still refuse them.
+func SetOrgToContext(jwtSecret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgId := model.NoOrg
+		if tokenStr := requestToken(c); strings.HasPrefix(tokenStr, "device-") {
+			if device, err := model.GetDeviceByToken(tokenStr); err == nil && device != nil {
+				orgId = device.OrgId
+			}
+		} else if tokenStr != "" {
+			if user := tokenUser(c, tokenStr, jwtSecret); user != nil {
+				orgId = user.OrgId
+			}
+		}
+		c.Set(orgKey, orgId)
+		c.Next()
+	}
+}
+
+// RequireDefaultOrg leaves the server wide endpoints to the default
+// organization on the multi tenant servers.
+func RequireDefaultOrg() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if orgId := requestOrgId(c); orgId != model.AllOrgs && orgId != model.DefaultOrgId {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "only the default organization can access this endpoint"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestOrgId returns the organization the request is scoped to,
+// model.AllOrgs on the single tenant servers.
+func requestOrgId(c *gin.Context) int {
+	if orgId, ok := c.Get(orgKey); ok {
+		return orgId.(int)
+	}
+	return model.AllOrgs
+}
+
+// inOrg reports whether a row of the organization rowOrgId is visible to
+// the requests scoped to orgId.
+func inOrg(orgId, rowOrgId int) bool {
+	return orgId == model.AllOrgs || orgId == rowOrgId
+}
+
+// inRequestOrg reports whether a row of the organization orgId is visible
+// to the request.
+func inRequestOrg(c *gin.Context, orgId int) bool {
+	return inOrg(requestOrgId(c), orgId)
+}
+
+// ownerOf returns the organization of the rows created by the requests
+// scoped to orgId.
+func ownerOf(orgId int) int {
+	if orgId == model.AllOrgs {
+		return model.DefaultOrgId
+	}
+	return orgId
+}
+
+// ownerOrgId returns the organization of the rows the request creates, it
+// writes the error and returns false for the anonymous requests of the
+// multi tenant servers.
+func (s *Server) ownerOrgId(c *gin.Context) (int, bool) {
+	orgId := requestOrgId(c)
+	if orgId == model.NoOrg {
+		s.writeError(c, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+		return 0, false
+	}
+	return ownerOf(orgId), true
+}
+
+// isSystemAdmin reports whether the user administers every organization.
+func isSystemAdmin(user *model.User) bool {
+	return user.IsAdmin && user.OrgId == model.DefaultOrgId
+}
+
+// @Summary 获取组织列表
+// @Description 获取所有组织，仅默认组织的管理员可用
+// @Tags 组织
+// @Produce json
+// @Success 200 {object} dao.ListOrganizationsResponse
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 403 {object} ErrorResponse "无权限"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/admin/organizations [get]
+func (s *Server) handleListOrganizations(c *gin.Context) {
+	if !isSystemAdmin(c.MustGet(userKey).(*model.User)) {
+		s.writeError(c, http.StatusForbidden, fmt.Errorf("only the admins of the default organization manage the organizations"))
+		return
+	}
+	orgs, err := model.ListOrganizations()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListOrganizationsResponse{
+		Items: make([]dao.OrganizationSpec, len(orgs)),
+	}
+	for i := range orgs {
+		resp.Items[i] = dao.ToOrganizationSpec(&orgs[i])
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary 创建组织
+// @Description 创建组织，再在组织下创建其管理员，仅默认组织的管理员可用
+// @Tags 组织
+// @Accept json
+// @Produce json
+// @Param request body dao.CreateOrganizationRequest true "组织信息"
+// @Success 200 {object} dao.OrganizationSpec
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 403 {object} ErrorResponse "无权限"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/admin/organizations [post]
+func (s *Server) handleCreateOrganization(c *gin.Context) {
+	var req dao.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if !isSystemAdmin(c.MustGet(userKey).(*model.User)) {
+		s.writeError(c, http.StatusForbidden, fmt.Errorf("only the admins of the default organization manage the organizations"))
+		return
+	}
+	org := &model.Organization{Name: req.Name}
+	if err := model.CreateOrganization(org); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.ToOrganizationSpec(org))
+}
+
+// @Summary 删除组织
+// @Description 删除组织及其设备、摄像头、任务和工作流，组织下仍有用户时不能删除，默认组织不能删除
+// @Tags 组织
+// @Produce json
+// @Param org_id path int true "组织ID"
+// @Success 200
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 403 {object} ErrorResponse "无权限"
+// @Failure 404 {object} ErrorResponse "组织不存在"
+// @Failure 409 {object} ErrorResponse "组织下仍有用户"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/admin/organization/{org_id} [delete]
+func (s *Server) handleDeleteOrganization(c *gin.Context) {
+	orgId, err := strconv.Atoi(c.Param("org_id"))
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if !isSystemAdmin(c.MustGet(userKey).(*model.User)) {
+		s.writeError(c, http.StatusForbidden, fmt.Errorf("only the admins of the default organization manage the organizations"))
+		return
+	}
+	if orgId == model.DefaultOrgId {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("the default organization cannot be deleted"))
+		return
+	}
+	org, err := model.GetOrganizationById(orgId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if org == nil {
+		s.writeError(c, http.StatusNotFound, fmt.Errorf("organization not found"))
+		return
+	}
+	users, err := model.CountOrganizationUsers(orgId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if users > 0 {
+		s.writeError(c, http.StatusConflict, fmt.Errorf("organization still has %d users", users))
+		return
+	}
+	if err := model.DeleteOrganization(orgId); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
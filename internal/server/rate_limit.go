@@ -0,0 +1,76 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/model"
+)
+
+// ipScopes are the scopes of the requests made before logging in, limited
+// per client IP whatever token they carry.
+var ipScopes = []string{"login", "register"}
+
+// rateLimit limits the requests of the scope with its rule of the current
+// config, per token for the requests carrying a valid one and per client IP
+// for the others. A token that does not authenticate must not buy a fresh
+// limit. The requests are let through if redis fails, a limiter must not
+// take the API down.
+func (s *Server) rateLimit(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf := s.config().RateLimit
+		rule := conf.rule(scope)
+		if !conf.Enabled || model.Redis == nil || rule.Window <= 0 {
+			c.Next()
+			return
+		}
+
+		var key string
+		var limit int
+		if token := requestToken(c); token != "" && !slices.Contains(ipScopes, scope) && s.validToken(token) {
+			// the tokens are not kept on redis in clear
+			sum := sha256.Sum256([]byte(token))
+			key, limit = scope+":token:"+hex.EncodeToString(sum[:8]), rule.PerToken
+		} else {
+			key, limit = scope+":ip:"+c.ClientIP(), rule.PerIP
+		}
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		count, reset, err := model.IncrRateLimit(c, key, time.Duration(rule.Window)*time.Second, now)
+		if err != nil {
+			logrus.WithError(err).Warnf("rate limit %s", scope)
+			c.Next()
+			return
+		}
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.FormatInt(max(int64(limit)-count, 0), 10))
+		if count > int64(limit) {
+			retryAfter := int(math.Ceil(reset.Sub(now).Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "too many requests",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// validToken reports whether the token authenticates a user, a device or an
+// API token, as cached by the usage recorder.
+func (s *Server) validToken(token string) bool {
+	_, ok := s.usage.tokenConsumer(token)
+	return ok
+}
@@ -0,0 +1,622 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const (
+	rolloutKey = "rollout"
+	// defaultFailureThreshold halts a rollout once a fifth of the upgraded
+	// devices failed
+	defaultFailureThreshold = 0.2
+	// upgradeTimeout fails the upgrade of a device that did not come back
+	// with the new version in time
+	upgradeTimeout       = 30 * time.Minute
+	rolloutCheckInterval = time.Minute
+)
+
+func SetRolloutToContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rolloutIdStr := c.Param("rollout_id")
+		if rolloutIdStr == "" {
+			c.Next()
+			return
+		}
+
+		rolloutId, err := strconv.Atoi(rolloutIdStr)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "invalid rollout_id",
+			})
+			return
+		}
+
+		rollout, err := model.GetRolloutById(rolloutId)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		} else if rollout == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "rollout not found",
+			})
+			return
+		}
+		c.Set(rolloutKey, rollout)
+		c.Next()
+	}
+}
+
+// handleCreateRelease 创建版本
+// @Summary 创建版本
+// @Description 登记一个设备程序版本，可通过设备组升级下发到设备
+// @Tags 版本升级
+// @Accept json
+// @Produce json
+// @Param req body dao.CreateReleaseRequest true "创建版本请求"
+// @Success 200 {object} dao.CreateReleaseResponse "创建成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/release [post]
+func (s *Server) handleCreateRelease(c *gin.Context) {
+	var req dao.CreateReleaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	release := req.ToModel()
+	if err := model.CreateRelease(release); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.CreateReleaseResponse{Id: release.Id})
+}
+
+// handleListReleases 列出版本
+// @Summary 列出版本
+// @Description 按创建时间倒序列出设备程序版本
+// @Tags 版本升级
+// @Accept json
+// @Produce json
+// @Param start query int false "分页起始位置"
+// @Param limit query int false "分页每页数量"
+// @Param channel query string false "发布通道，stable 或 beta"
+// @Success 200 {object} dao.ListReleasesResponse "列出成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/release [get]
+func (s *Server) handleListReleases(c *gin.Context) {
+	var req dao.ListReleasesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	releases, total, err := model.ListReleases(req.Channel, req.Start, req.Limit)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListReleasesResponse{
+		Items: make([]dao.ReleaseSpec, 0, len(releases)),
+		Total: total,
+	}
+	for _, r := range releases {
+		resp.Items = append(resp.Items, *dao.FromReleaseModel(&r))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleDeleteRelease 删除版本
+// @Summary 删除版本
+// @Description 删除设备程序版本，已被升级使用的版本不能删除
+// @Tags 版本升级
+// @Accept json
+// @Produce json
+// @Param release_id path int true "版本ID"
+// @Success 200 "删除成功"
+// @Failure 400 {object} ErrorResponse "版本已被升级使用"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "版本不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/release/{release_id} [delete]
+func (s *Server) handleDeleteRelease(c *gin.Context) {
+	releaseId, err := strconv.Atoi(c.Param("release_id"))
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("invalid release_id"))
+		return
+	}
+	release, err := model.GetReleaseById(releaseId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if release == nil {
+		s.writeError(c, http.StatusNotFound, fmt.Errorf("release not found"))
+		return
+	}
+
+	if err := model.DeleteRelease(releaseId); errors.Is(err, model.ErrReleaseInUse) {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handleCreateRollout 升级设备组
+// @Summary 升级设备组
+// @Description 将设备组升级到指定版本，版本必须属于设备组的发布通道，未指定时使用通道的最新版本。
+// @Description 设备在设备组的升级时段内逐个升级，升级过的设备中失败的比例超过阈值时自动暂停
+// @Tags 版本升级
+// @Accept json
+// @Produce json
+// @Param group_id path int true "设备组ID"
+// @Param req body dao.CreateRolloutRequest true "升级请求"
+// @Success 200 {object} dao.CreateRolloutResponse "创建成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误或设备组已有进行中的升级"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "设备组或版本不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device-group/{group_id}/rollout [post]
+func (s *Server) handleCreateRollout(c *gin.Context) {
+	var req dao.CreateRolloutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	group := c.MustGet(deviceGroupKey).(*model.DeviceGroup)
+	channels := group.Channel.Channels()
+	var release *model.Release
+	var err error
+	if req.ReleaseId != 0 {
+		release, err = model.GetReleaseById(req.ReleaseId)
+	} else {
+		release, err = model.LatestRelease(channels)
+	}
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if release == nil {
+		s.writeError(c, http.StatusNotFound, fmt.Errorf("release not found"))
+		return
+	}
+	if !slices.Contains(channels, release.Channel) {
+		s.writeError(c, http.StatusBadRequest,
+			fmt.Errorf("release %s is on channel %s, group is pinned to %s", release.Version, release.Channel, group.Channel))
+		return
+	}
+
+	rollout := &model.Rollout{
+		GroupId:          group.Id,
+		ReleaseId:        release.Id,
+		Status:           model.RolloutStatusRunning,
+		FailureThreshold: defaultFailureThreshold,
+	}
+	if req.FailureThreshold != nil {
+		rollout.FailureThreshold = *req.FailureThreshold
+	}
+	if err := model.CreateRollout(rollout); errors.Is(err, model.ErrRolloutRunning) {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.logger.Infof("rollout %d of release %s to device group %d started", rollout.Id, release.Version, group.Id)
+	c.JSON(http.StatusOK, dao.CreateRolloutResponse{Id: rollout.Id})
+}
+
+// handleListRollouts 列出设备组的升级
+// @Summary 列出设备组的升级
+// @Description 按创建时间倒序列出设备组的升级及其进度
+// @Tags 版本升级
+// @Accept json
+// @Produce json
+// @Param group_id path int true "设备组ID"
+// @Param start query int false "分页起始位置"
+// @Param limit query int false "分页每页数量"
+// @Success 200 {object} dao.ListRolloutsResponse "列出成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "设备组不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device-group/{group_id}/rollout [get]
+func (s *Server) handleListRollouts(c *gin.Context) {
+	var req dao.ListRolloutsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	group := c.MustGet(deviceGroupKey).(*model.DeviceGroup)
+	rollouts, total, err := model.ListRollouts(group.Id, req.Start, req.Limit)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListRolloutsResponse{
+		Items: make([]dao.RolloutSpec, 0, len(rollouts)),
+		Total: total,
+	}
+	for _, r := range rollouts {
+		spec, err := rolloutSpec(&r)
+		if err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		}
+		resp.Items = append(resp.Items, *spec)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func rolloutSpec(r *model.Rollout) (*dao.RolloutSpec, error) {
+	release, err := model.GetReleaseById(r.ReleaseId)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := model.CountDeviceUpgrades(r.Id)
+	if err != nil {
+		return nil, err
+	}
+	return dao.FromRolloutModel(r, release, counts), nil
+}
+
+// handleGetRollout 获取升级
+// @Summary 获取升级
+// @Description 获取升级及其进度
+// @Tags 版本升级
+// @Accept json
+// @Produce json
+// @Param rollout_id path int true "升级ID"
+// @Success 200 {object} dao.RolloutSpec "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "升级不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/rollout/{rollout_id} [get]
+func (s *Server) handleGetRollout(c *gin.Context) {
+	rollout := c.MustGet(rolloutKey).(*model.Rollout)
+	s.writeRollout(c, rollout)
+}
+
+func (s *Server) writeRollout(c *gin.Context, rollout *model.Rollout) {
+	spec, err := rolloutSpec(rollout)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, spec)
+}
+
+// handleListDeviceUpgrades 列出升级中各设备的状态
+// @Summary 列出升级中各设备的状态
+// @Description 列出已开始升级的设备及其状态和失败原因
+// @Tags 版本升级
+// @Accept json
+// @Produce json
+// @Param rollout_id path int true "升级ID"
+// @Success 200 {object} dao.ListDeviceUpgradesResponse "列出成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "升级不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/rollout/{rollout_id}/devices [get]
+func (s *Server) handleListDeviceUpgrades(c *gin.Context) {
+	rollout := c.MustGet(rolloutKey).(*model.Rollout)
+	upgrades, err := model.ListDeviceUpgrades(rollout.Id)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListDeviceUpgradesResponse{
+		Items: make([]dao.DeviceUpgradeSpec, 0, len(upgrades)),
+	}
+	for _, u := range upgrades {
+		device, err := model.GetDeviceById(u.DeviceId)
+		if err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		} else if device == nil {
+			continue
+		}
+		resp.Items = append(resp.Items, dao.DeviceUpgradeSpec{
+			Device:     dao.FromDeviceModel(device),
+			Status:     u.Status,
+			Error:      u.Error,
+			UpdateTime: u.UpdateTime.UTC().Format(time.RFC3339),
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleHaltRollout 暂停升级
+// @Summary 暂停升级
+// @Description 暂停进行中的升级，已在升级的设备不受影响
+// @Tags 版本升级
+// @Accept json
+// @Produce json
+// @Param rollout_id path int true "升级ID"
+// @Success 200 {object} dao.RolloutSpec "暂停成功"
+// @Failure 400 {object} ErrorResponse "升级不在进行中"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "升级不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/rollout/{rollout_id}/halt [put]
+func (s *Server) handleHaltRollout(c *gin.Context) {
+	s.setRolloutStatus(c, []model.RolloutStatus{model.RolloutStatusRunning},
+		model.RolloutStatusHalted, "halted manually")
+}
+
+// handleResumeRollout 恢复升级
+// @Summary 恢复升级
+// @Description 恢复已暂停的升级，已失败的设备不会重试
+// @Tags 版本升级
+// @Accept json
+// @Produce json
+// @Param rollout_id path int true "升级ID"
+// @Success 200 {object} dao.RolloutSpec "恢复成功"
+// @Failure 400 {object} ErrorResponse "升级未暂停或设备组已有进行中的升级"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "升级不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/rollout/{rollout_id}/resume [put]
+func (s *Server) handleResumeRollout(c *gin.Context) {
+	rollout := c.MustGet(rolloutKey).(*model.Rollout)
+	others, err := model.ListRunningRollouts()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	for _, r := range others {
+		if r.GroupId == rollout.GroupId {
+			s.writeError(c, http.StatusBadRequest, model.ErrRolloutRunning)
+			return
+		}
+	}
+	s.setRolloutStatus(c, []model.RolloutStatus{model.RolloutStatusHalted},
+		model.RolloutStatusRunning, "")
+}
+
+// handleCancelRollout 取消升级
+// @Summary 取消升级
+// @Description 取消进行中或已暂停的升级
+// @Tags 版本升级
+// @Accept json
+// @Produce json
+// @Param rollout_id path int true "升级ID"
+// @Success 200 {object} dao.RolloutSpec "取消成功"
+// @Failure 400 {object} ErrorResponse "升级已结束"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "升级不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/rollout/{rollout_id}/cancel [put]
+func (s *Server) handleCancelRollout(c *gin.Context) {
+	rollout := c.MustGet(rolloutKey).(*model.Rollout)
+	s.setRolloutStatus(c, []model.RolloutStatus{model.RolloutStatusRunning, model.RolloutStatusHalted},
+		model.RolloutStatusCancelled, rollout.HaltReason)
+}
+
+func (s *Server) setRolloutStatus(c *gin.Context, from []model.RolloutStatus, to model.RolloutStatus, reason string) {
+	rollout := c.MustGet(rolloutKey).(*model.Rollout)
+	if !slices.Contains(from, rollout.Status) {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("rollout is %s", rollout.Status))
+		return
+	}
+	if err := model.UpdateRolloutStatus(rollout.Id, to, reason); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	rollout.Status = to
+	rollout.HaltReason = reason
+	s.writeRollout(c, rollout)
+}
+
+// handleGetDeviceUpgradeTask 获取设备的升级任务
+// @Summary 获取设备的升级任务
+// @Description 设备所在设备组有进行中的升级且处于升级时段时返回升级任务。设备已是目标版本时记为升级成功
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Param version query string true "设备当前版本"
+// @Success 200 {object} dao.GetUpgradeTaskResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/upgrade-task [get]
+func (s *Server) handleGetDeviceUpgradeTask(c *gin.Context) {
+	device := c.MustGet(deviceKey).(*model.Device)
+	rollout, group, err := model.GetRunningRolloutOfDevice(device.Id)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if rollout == nil {
+		c.JSON(http.StatusOK, dao.GetUpgradeTaskResponse{})
+		return
+	}
+	release, err := model.GetReleaseById(rollout.ReleaseId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if release == nil {
+		c.JSON(http.StatusOK, dao.GetUpgradeTaskResponse{})
+		return
+	}
+	upgrade, err := model.GetDeviceUpgrade(rollout.Id, device.Id)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if c.Query("version") == release.Version {
+		if upgrade == nil || upgrade.Status != model.DeviceUpgradeStatusSucceeded {
+			if err := model.SetDeviceUpgrade(&model.DeviceUpgrade{
+				RolloutId: rollout.Id,
+				DeviceId:  device.Id,
+				Status:    model.DeviceUpgradeStatusSucceeded,
+			}); err != nil {
+				s.writeError(c, http.StatusInternalServerError, err)
+				return
+			}
+			s.checkRollout(rollout)
+		}
+		c.JSON(http.StatusOK, dao.GetUpgradeTaskResponse{})
+		return
+	}
+	// a device gets a single attempt per rollout, an upgrade in progress is
+	// not handed out again until it times out
+	if upgrade != nil || !dao.UpgradeWindowOpen(group, time.Now()) {
+		c.JSON(http.StatusOK, dao.GetUpgradeTaskResponse{})
+		return
+	}
+
+	if err := model.SetDeviceUpgrade(&model.DeviceUpgrade{
+		RolloutId: rollout.Id,
+		DeviceId:  device.Id,
+		Status:    model.DeviceUpgradeStatusUpgrading,
+	}); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.GetUpgradeTaskResponse{Task: &dao.UpgradeTask{
+		RolloutId: rollout.Id,
+		Version:   release.Version,
+		Url:       release.Url,
+		Sha256:    release.Sha256,
+	}})
+}
+
+// handleReportDeviceUpgradeResult 上报升级失败
+// @Summary 上报升级失败
+// @Description 设备下载或安装新版本失败时上报，升级成功以设备重启后获取升级任务时上报的版本为准
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Param req body dao.UpgradeResult true "升级结果"
+// @Success 200 "上报成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/upgrade-result [post]
+func (s *Server) handleReportDeviceUpgradeResult(c *gin.Context) {
+	var req dao.UpgradeResult
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	device := c.MustGet(deviceKey).(*model.Device)
+	upgrade, err := model.GetDeviceUpgrade(req.RolloutId, device.Id)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if upgrade == nil {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("device is not upgrading in rollout %d", req.RolloutId))
+		return
+	}
+	upgrade.Status = model.DeviceUpgradeStatusFailed
+	upgrade.Error = req.Error
+	if err := model.SetDeviceUpgrade(upgrade); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.logger.Warnf("device %s failed to upgrade in rollout %d: %s", device.Uuid, req.RolloutId, req.Error)
+
+	rollout, err := model.GetRolloutById(req.RolloutId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if rollout != nil {
+		s.checkRollout(rollout)
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// checkRollout halts a running rollout whose failure rate among the
+// upgraded devices exceeds its threshold, and completes it once every
+// member of the group runs the release.
+func (s *Server) checkRollout(rollout *model.Rollout) {
+	if rollout.Status != model.RolloutStatusRunning {
+		return
+	}
+	logger := s.logger.WithField("rollout", rollout.Id)
+	counts, err := model.CountDeviceUpgrades(rollout.Id)
+	if err != nil {
+		logger.WithError(err).Error("count device upgrades failed")
+		return
+	}
+
+	failed := counts[model.DeviceUpgradeStatusFailed]
+	succeeded := counts[model.DeviceUpgradeStatusSucceeded]
+	if failed > 0 && float64(failed)/float64(failed+succeeded) > rollout.FailureThreshold {
+		reason := fmt.Sprintf("%d of %d upgraded devices failed, above threshold %.0f%%",
+			failed, failed+succeeded, rollout.FailureThreshold*100)
+		if err := model.UpdateRolloutStatus(rollout.Id, model.RolloutStatusHalted, reason); err != nil {
+			logger.WithError(err).Error("halt rollout failed")
+			return
+		}
+		logger.Warnf("rollout halted: %s", reason)
+		return
+	}
+
+	members, err := model.ListDeviceGroupMembers(rollout.GroupId)
+	if err != nil {
+		logger.WithError(err).Error("list device group members failed")
+		return
+	}
+	if len(members) > 0 && succeeded >= int64(len(members)) {
+		if err := model.UpdateRolloutStatus(rollout.Id, model.RolloutStatusCompleted, ""); err != nil {
+			logger.WithError(err).Error("complete rollout failed")
+			return
+		}
+		logger.Info("rollout completed")
+	}
+}
+
+// monitorRollouts fails the device upgrades that timed out and re-checks
+// the running rollouts.
+func (s *Server) monitorRollouts(ctx context.Context) {
+	ticker := time.NewTicker(rolloutCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if ids, err := model.FailStaleUpgrades(time.Now().Add(-upgradeTimeout)); err != nil {
+			s.logger.WithError(err).Error("fail stale device upgrades failed")
+		} else if len(ids) > 0 {
+			s.logger.Warnf("device upgrades of rollouts %v timed out", ids)
+		}
+		rollouts, err := model.ListRunningRollouts()
+		if err != nil {
+			s.logger.WithError(err).Error("list running rollouts failed")
+			continue
+		}
+		for i := range rollouts {
+			s.checkRollout(&rollouts[i])
+		}
+	}
+}
@@ -0,0 +1,535 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+// reportTopLabels is the number of labels listed in the reports
+const reportTopLabels = 10
+
+var reportSeverities = []model.AlertSeverity{
+	model.SeverityCritical, model.SeverityHigh, model.SeverityMedium, model.SeverityLow,
+}
+
+var reportPeriodNames = map[model.ReportPeriod]string{
+	model.ReportPeriodDaily:  "日报",
+	model.ReportPeriodWeekly: "周报",
+}
+
+// reportTemplate renders the HTML report, with inline styles so that it
+// shows the same in the mail clients.
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body style="font-family: sans-serif; color: #222; max-width: 880px; margin: 0 auto;">
+<h2>{{.Title}}</h2>
+<p>统计时间：{{.Start}} 至 {{.End}}（{{.Timezone}}）<br>站点：{{if .Site}}{{.Site}}{{else}}全部摄像头{{end}}<br>生成时间：{{.GenerateTime}}</p>
+
+<h3>概要</h3>
+<table cellpadding="6" style="border-collapse: collapse;">
+<tr><td>消息数</td><td><b>{{.Summary.Messages}}</b></td></tr>
+<tr><td>告警数</td><td><b>{{.Summary.Alerts}}</b>{{if .Summary.Alerts}}（已解决 {{.ResolvedAlerts}}）{{end}}</td></tr>
+<tr><td>摄像头</td><td>{{.Summary.Cameras}}</td></tr>
+<tr><td>设备</td><td>{{.Summary.Devices}}{{if .Summary.OfflineDevices}}，其中 {{.Summary.OfflineDevices}} 台曾离线{{end}}</td></tr>
+</table>
+
+{{if .Severities}}<h3>告警级别</h3>
+<table border="1" cellpadding="6" style="border-collapse: collapse;">
+<tr><th>级别</th><th>告警数</th></tr>
+{{range .Severities}}<tr><td>{{.Severity}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Labels}}<h3>检测最多的标签</h3>
+<table border="1" cellpadding="6" style="border-collapse: collapse;">
+<tr><th>标签</th><th>检测次数</th></tr>
+{{range .Labels}}<tr><td>{{.Label}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Cameras}}<h3>摄像头</h3>
+<table border="1" cellpadding="6" style="border-collapse: collapse;">
+<tr><th>摄像头</th><th>消息数</th><th>告警数</th></tr>
+{{range .Cameras}}<tr><td>{{.Name}}</td><td>{{.Messages}}</td><td>{{.Alerts}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Devices}}<h3>设备在线率</h3>
+<table border="1" cellpadding="6" style="border-collapse: collapse;">
+<tr><th>设备</th><th>在线率</th><th>离线次数</th><th>离线时长</th><th>当前状态</th></tr>
+{{range .Devices}}<tr><td>{{.Name}}</td><td>{{printf "%.2f" .Uptime}}%</td><td>{{.Offline}}</td><td>{{.Downtime}}</td><td>{{.State}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+type reportSeverity struct {
+	Severity model.AlertSeverity
+	Count    int64
+}
+
+type reportCamera struct {
+	Name     string
+	Messages int64
+	Alerts   int64
+}
+
+type reportDevice struct {
+	Name string
+	// Uptime is the percentage of the period the device was online
+	Uptime   float64
+	Offline  int
+	Downtime time.Duration
+	State    model.DeviceState
+}
+
+// siteReport is what the report template renders.
+type siteReport struct {
+	Title          string
+	Site           string
+	Start          string
+	End            string
+	Timezone       string
+	GenerateTime   string
+	Summary        model.ReportSummary
+	ResolvedAlerts int64
+	Severities     []reportSeverity
+	Labels         []model.LabelCount
+	Cameras        []reportCamera
+	Devices        []reportDevice
+}
+
+// renderedReport is a generated report with what it is delivered from.
+type renderedReport struct {
+	*model.Report
+	data *siteReport
+	html []byte
+	// file is the content kept in the bucket, the HTML or the PDF
+	file []byte
+}
+
+// Reporter generates the reports of the schedules once their period is
+// over, keeps them in the bucket and delivers them through the notification
+// channels of the schedules.
+type Reporter struct {
+	logger    *logrus.Entry
+	conf      ReportConfig
+	bucket    string
+	minioCli  *minio.Client
+	mediaUrls *MediaUrls
+	notifier  *Notifier
+}
+
+func NewReporter(logger *logrus.Entry, conf ReportConfig, bucket string, minioCli *minio.Client, mediaUrls *MediaUrls, notifier *Notifier) *Reporter {
+	if conf.Interval <= 0 {
+		conf.Interval = 60
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = 300
+	}
+	return &Reporter{
+		logger:    logger.WithField("component", "reporter"),
+		conf:      conf,
+		bucket:    bucket,
+		minioCli:  minioCli,
+		mediaUrls: mediaUrls,
+		notifier:  notifier,
+	}
+}
+
+func (r *Reporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(r.conf.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		r.runDue(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue reports the periods of the schedules that are over and not
+// reported yet, the servers claiming each period so that it is reported
+// once.
+func (r *Reporter) runDue(ctx context.Context, now time.Time) {
+	schedules, err := model.ListEnabledReportSchedules()
+	if err != nil {
+		r.logger.WithError(err).Error("list report schedules failed")
+		return
+	}
+	for i := range schedules {
+		sch := &schedules[i]
+		loc, err := sch.Location()
+		if err != nil {
+			r.logger.WithError(err).Errorf("invalid timezone of report schedule %d", sch.Id)
+			continue
+		}
+		start, end := sch.LastPeriod(now, loc)
+		if sch.LastPeriodEnd != nil && !sch.LastPeriodEnd.Before(end) {
+			continue
+		}
+		if ok, err := model.ClaimReportPeriod(sch.Id, end); err != nil {
+			r.logger.WithError(err).Errorf("claim report of schedule %d failed", sch.Id)
+			continue
+		} else if !ok {
+			continue
+		}
+		r.run(ctx, sch, start, end)
+	}
+}
+
+// run generates the report of the period and delivers it, and records the
+// result on the schedule. The report is nil if it could not be generated.
+func (r *Reporter) run(ctx context.Context, sch *model.ReportSchedule, start, end time.Time) (*model.Report, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.conf.Timeout)*time.Second)
+	defer cancel()
+
+	var report *model.Report
+	rendered, err := r.generate(ctx, sch, start, end)
+	if err == nil {
+		report = rendered.Report
+		err = r.deliver(ctx, sch, rendered)
+	}
+	if err != nil {
+		r.logger.WithError(err).Errorf("report of schedule %d for %s failed", sch.Id, start.Format(time.DateOnly))
+	}
+	if err := model.SetReportScheduleResult(sch.Id, time.Now(), err); err != nil {
+		r.logger.WithError(err).Errorf("record report of schedule %d failed", sch.Id)
+	}
+	return report, err
+}
+
+// generate collects the stats of the site in [start, end), renders the
+// report in the format of the schedule and keeps it in the bucket.
+func (r *Reporter) generate(ctx context.Context, sch *model.ReportSchedule, start, end time.Time) (*renderedReport, error) {
+	data, err := collectSiteReport(sch, start, end, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	content, contentType := buf.Bytes(), "text/html; charset=utf-8"
+	if sch.Format == model.ReportFormatPDF {
+		if content, err = r.toPdf(ctx, content); err != nil {
+			return nil, err
+		}
+		contentType = "application/pdf"
+	}
+
+	report := &model.Report{
+		ScheduleId:  sch.Id,
+		Site:        sch.Site,
+		Period:      sch.Period,
+		Format:      sch.Format,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Path: fmt.Sprintf("reports/%d/%s-%s.%s", sch.Id,
+			start.Format("20060102"), end.Format("20060102"), sch.Format),
+		Summary: data.Summary,
+	}
+	if _, err := r.minioCli.PutObject(ctx, r.bucket, report.Path, bytes.NewReader(content), int64(len(content)),
+		minio.PutObjectOptions{ContentType: contentType}); err != nil {
+		return nil, fmt.Errorf("upload report failed: %w", err)
+	}
+	if err := model.CreateReport(report); err != nil {
+		return nil, err
+	}
+	return &renderedReport{Report: report, data: data, html: buf.Bytes(), file: content}, nil
+}
+
+// collectSiteReport queries the stats of the cameras of the site and of the
+// devices running them in [start, end).
+func collectSiteReport(sch *model.ReportSchedule, start, end, now time.Time) (*siteReport, error) {
+	loc := start.Location()
+	data := &siteReport{
+		Title:        fmt.Sprintf("%s %s", sch.Name, reportPeriodNames[sch.Period]),
+		Site:         sch.Site,
+		Start:        start.Format("2006-01-02 15:04"),
+		End:          end.Format("2006-01-02 15:04"),
+		Timezone:     loc.String(),
+		GenerateTime: now.In(loc).Format("2006-01-02 15:04"),
+	}
+
+	cameras, err := model.ListSiteCameras(sch.Site)
+	if err != nil {
+		return nil, err
+	}
+	cameraIds := make([]int, len(cameras))
+	rows := make(map[int]*reportCamera, len(cameras))
+	for i, c := range cameras {
+		cameraIds[i] = c.Id
+		data.Cameras = append(data.Cameras, reportCamera{Name: c.Name})
+	}
+	for i, c := range cameras {
+		rows[c.Id] = &data.Cameras[i]
+	}
+	data.Summary.Cameras = len(cameras)
+
+	messages, err := model.CountMessagesByCamera(cameraIds, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range messages {
+		rows[m.CameraId].Messages = m.Messages
+		data.Summary.Messages += m.Messages
+	}
+
+	alerts, err := model.CountAlertsByCamera(cameraIds, start, end)
+	if err != nil {
+		return nil, err
+	}
+	severities := make(map[model.AlertSeverity]int64)
+	for _, a := range alerts {
+		rows[a.CameraId].Alerts += a.Count
+		severities[a.Severity] += a.Count
+		data.Summary.Alerts += a.Count
+		if a.Status == model.AlertStatusResolved {
+			data.ResolvedAlerts += a.Count
+		}
+	}
+	for _, sev := range reportSeverities {
+		if severities[sev] > 0 {
+			data.Severities = append(data.Severities, reportSeverity{Severity: sev, Count: severities[sev]})
+		}
+	}
+	// the busiest cameras first
+	slices.SortStableFunc(data.Cameras, func(a, b reportCamera) int {
+		if c := cmp.Compare(b.Alerts, a.Alerts); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Messages, a.Messages)
+	})
+
+	if data.Labels, err = model.CountTopLabels(cameraIds, start, end, reportTopLabels); err != nil {
+		return nil, err
+	}
+
+	devices, err := model.ListSiteDevices(cameraIds)
+	if err != nil {
+		return nil, err
+	}
+	deviceIds := make([]int, len(devices))
+	for i, d := range devices {
+		deviceIds[i] = d.Id
+	}
+	events, err := model.ListDeviceOfflineEvents(deviceIds, start, end)
+	if err != nil {
+		return nil, err
+	}
+	data.Summary.Devices = len(devices)
+	data.Devices = deviceUptimes(devices, events, start, end, now)
+	for _, d := range data.Devices {
+		if d.Offline > 0 {
+			data.Summary.OfflineDevices++
+		}
+	}
+	return data, nil
+}
+
+// deviceUptimes computes the share of [start, end) each device was not
+// offline, the open offline events lasting until now.
+func deviceUptimes(devices []model.Device, events []model.HealthEvent, start, end, now time.Time) []reportDevice {
+	until := end
+	if now.Before(until) {
+		until = now
+	}
+	res := make([]reportDevice, len(devices))
+	index := make(map[int]int, len(devices))
+	for i, d := range devices {
+		name := d.Name
+		if name == "" {
+			name = d.Uuid
+		}
+		res[i] = reportDevice{Name: name, State: d.State(now)}
+		index[d.Id] = i
+	}
+	for _, e := range events {
+		i, ok := index[e.DeviceId]
+		if !ok {
+			continue
+		}
+		eventEnd := now
+		if e.ResolveTime != nil {
+			eventEnd = *e.ResolveTime
+		}
+		from, to := e.StartTime, eventEnd
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(until) {
+			to = until
+		}
+		if to.After(from) {
+			res[i].Downtime += to.Sub(from)
+		}
+		res[i].Offline++
+	}
+	total := until.Sub(start)
+	for i := range res {
+		res[i].Uptime = 100
+		if total > 0 {
+			res[i].Uptime = 100 * (1 - float64(res[i].Downtime)/float64(total))
+		}
+		res[i].Downtime = res[i].Downtime.Round(time.Minute)
+	}
+	// the least available devices first
+	slices.SortStableFunc(res, func(a, b reportDevice) int {
+		return cmp.Compare(a.Uptime, b.Uptime)
+	})
+	return res
+}
+
+// toPdf converts the HTML report with the PDF command.
+func (r *Reporter) toPdf(ctx context.Context, html []byte) ([]byte, error) {
+	if len(r.conf.PdfCommand) == 0 {
+		return nil, errors.New("pdf command is not configured")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.conf.PdfCommand[0], r.conf.PdfCommand[1:]...)
+	cmd.Stdin = bytes.NewReader(html)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("convert report to pdf failed: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("convert report to pdf failed: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// reportWebhookPayload is what webhook channels receive for a report, their
+// template only renders the alerts.
+type reportWebhookPayload struct {
+	Event  string          `json:"event"`
+	Name   string          `json:"name"`
+	Report *dao.ReportSpec `json:"report"`
+	// Url is a presigned URL of the report file
+	Url string `json:"url,omitempty"`
+}
+
+// deliver sends the report through the enabled channels of the schedule,
+// it returns the errors of the channels it failed to reach.
+func (r *Reporter) deliver(ctx context.Context, sch *model.ReportSchedule, report *renderedReport) error {
+	url, err := r.mediaUrls.Presign(ctx, report.Path, notificationImageUrlExpire)
+	if err != nil {
+		r.logger.WithError(err).Warnf("presign report %d failed", report.Id)
+	}
+
+	var errs []error
+	for _, id := range sch.ChannelIds {
+		ch, err := model.GetNotificationChannelById(id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		} else if ch == nil {
+			errs = append(errs, fmt.Errorf("notification channel %d not found", id))
+			continue
+		} else if !ch.Enabled {
+			continue
+		}
+
+		send, err := r.prepare(ch, report, url)
+		if err == nil {
+			err = r.notifier.sendWithRetry(ctx, ch, send, fmt.Sprintf("report %d", report.Id))
+		}
+		r.notifier.record(ch, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", ch.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// prepare renders the report for the channel: the HTML report, with the
+// PDF attached if it is not too large, for the emails, a markdown summary
+// linking the file for the chat tools and a JSON summary for the webhooks.
+func (r *Reporter) prepare(ch *model.NotificationChannel, report *renderedReport, url string) (func(context.Context) (int, error), error) {
+	n := r.notifier
+	data := report.data
+	if ch.Kind == model.NotificationChannelEmail {
+		to := ch.EmailRecipients(0)
+		if len(to) == 0 {
+			return nil, errors.New("the channel has no recipient")
+		}
+		e := &alertEmail{
+			subject: "[Lumina] " + data.Title,
+			body:    string(report.html),
+			html:    true,
+		}
+		conf := n.smtp.Load()
+		if report.Format == model.ReportFormatPDF && len(report.file) <= conf.MaxAttachmentSize {
+			e.attachment = report.file
+			e.attachmentName = fmt.Sprintf("report-%s.pdf", report.PeriodStart.Format("20060102"))
+		}
+		msg, err := e.message(conf.From, to)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (int, error) {
+			return 0, sendMail(ctx, *conf, to, msg)
+		}, nil
+	}
+
+	var payload []byte
+	var err error
+	if isIMChannel(ch.Kind) {
+		payload, err = imPayload(ch.Kind, data.Title, reportMarkdown(data, url), "")
+	} else {
+		payload, err = json.Marshal(reportWebhookPayload{
+			Event:  "report",
+			Name:   data.Title,
+			Report: dao.FromReportModel(report.Report),
+			Url:    url,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) (int, error) {
+		return n.post(ctx, ch, payload)
+	}, nil
+}
+
+// reportMarkdown summarizes the report for the chat tools.
+func reportMarkdown(data *siteReport, url string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n", data.Title)
+	fmt.Fprintf(&b, "- 统计时间：%s 至 %s\n", data.Start, data.End)
+	fmt.Fprintf(&b, "- 消息数：%d\n", data.Summary.Messages)
+	fmt.Fprintf(&b, "- 告警数：%d\n", data.Summary.Alerts)
+	fmt.Fprintf(&b, "- 设备：%d，离线过 %d\n", data.Summary.Devices, data.Summary.OfflineDevices)
+	if len(data.Labels) > 0 {
+		labels := make([]string, 0, len(data.Labels))
+		for _, l := range data.Labels {
+			labels = append(labels, fmt.Sprintf("%s(%d)", l.Label, l.Count))
+		}
+		fmt.Fprintf(&b, "- 检测最多：%s\n", strings.Join(labels, "、"))
+	}
+	if url != "" {
+		fmt.Fprintf(&b, "\n[查看完整报表](%s)\n", url)
+	}
+	return b.String()
+}
@@ -0,0 +1,325 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const (
+	reportScheduleKey = "reportSchedule"
+	reportKey         = "report"
+)
+
+// validateReportSchedule checks that the report is delivered through
+// existing channels.
+func validateReportSchedule(r *model.ReportSchedule) error {
+	for _, id := range r.ChannelIds {
+		ch, err := model.GetNotificationChannelById(id)
+		if err != nil {
+			return err
+		} else if ch == nil {
+			return fmt.Errorf("notification channel %d not found", id)
+		}
+	}
+	return nil
+}
+
+func SetReportScheduleToContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheduleId, err := strconv.Atoi(c.Param("schedule_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "invalid schedule_id",
+			})
+			return
+		}
+
+		r, err := model.GetReportScheduleById(scheduleId)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		} else if r == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "report schedule not found",
+			})
+			return
+		}
+		c.Set(reportScheduleKey, r)
+		c.Next()
+	}
+}
+
+func SetReportToContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reportId, err := strconv.Atoi(c.Param("report_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "invalid report_id",
+			})
+			return
+		}
+
+		r, err := model.GetReportById(reportId)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		} else if r == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "report not found",
+			})
+			return
+		}
+		c.Set(reportKey, r)
+		c.Next()
+	}
+}
+
+// handleCreateReportSchedule 创建定时报表
+// @Summary 创建定时报表
+// @Description 创建定时报表，每日或每周在指定时刻生成上一周期站点（摄像头标签）的消息数、检测最多的标签、告警和设备在线率汇总，
+// @Description 保存为 HTML 或 PDF 并通过通知通道投递：邮件通道发送完整报表，即时通讯通道发送摘要和报表链接，webhook 通道发送 JSON 摘要。需要管理员权限
+// @Tags 报表
+// @Accept json
+// @Produce json
+// @Param req body dao.CreateReportScheduleRequest true "定时报表"
+// @Success 200 {object} dao.CreateReportScheduleResponse "创建成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/report-schedule [post]
+func (s *Server) handleCreateReportSchedule(c *gin.Context) {
+	var req dao.CreateReportScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	r := req.ToModel()
+	if err := validateReportSchedule(r); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := model.CreateReportSchedule(r); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.CreateReportScheduleResponse{Id: r.Id})
+}
+
+// handleListReportSchedules 列出定时报表
+// @Summary 列出定时报表
+// @Description 列出所有定时报表，需要管理员权限
+// @Tags 报表
+// @Produce json
+// @Success 200 {object} dao.ListReportSchedulesResponse "列出成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/report-schedule [get]
+func (s *Server) handleListReportSchedules(c *gin.Context) {
+	rs, err := model.ListReportSchedules()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListReportSchedulesResponse{
+		Items: make([]dao.ReportScheduleSpec, 0, len(rs)),
+	}
+	for i := range rs {
+		resp.Items = append(resp.Items, *dao.FromReportScheduleModel(&rs[i]))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleGetReportSchedule 获取定时报表
+// @Summary 获取定时报表
+// @Description 获取定时报表及最近一次生成的结果，需要管理员权限
+// @Tags 报表
+// @Produce json
+// @Param schedule_id path int true "定时报表ID"
+// @Success 200 {object} dao.ReportScheduleSpec "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "定时报表不存在"
+// @Router /api/v1/report-schedule/{schedule_id} [get]
+func (s *Server) handleGetReportSchedule(c *gin.Context) {
+	r := c.MustGet(reportScheduleKey).(*model.ReportSchedule)
+	c.JSON(http.StatusOK, dao.FromReportScheduleModel(r))
+}
+
+// handleUpdateReportSchedule 更新定时报表
+// @Summary 更新定时报表
+// @Description 更新定时报表，只修改传入的字段，需要管理员权限
+// @Tags 报表
+// @Accept json
+// @Produce json
+// @Param schedule_id path int true "定时报表ID"
+// @Param req body dao.UpdateReportScheduleRequest true "定时报表"
+// @Success 200 {object} dao.ReportScheduleSpec "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "定时报表不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/report-schedule/{schedule_id} [put]
+func (s *Server) handleUpdateReportSchedule(c *gin.Context) {
+	var req dao.UpdateReportScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	r := c.MustGet(reportScheduleKey).(*model.ReportSchedule)
+	req.UpdateModel(r)
+	if err := validateReportSchedule(r); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := model.UpdateReportSchedule(r); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.FromReportScheduleModel(r))
+}
+
+// handleDeleteReportSchedule 删除定时报表
+// @Summary 删除定时报表
+// @Description 删除定时报表，已生成的报表保留，需要管理员权限
+// @Tags 报表
+// @Produce json
+// @Param schedule_id path int true "定时报表ID"
+// @Success 200 "删除成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "定时报表不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/report-schedule/{schedule_id} [delete]
+func (s *Server) handleDeleteReportSchedule(c *gin.Context) {
+	r := c.MustGet(reportScheduleKey).(*model.ReportSchedule)
+	if err := model.DeleteReportSchedule(r.Id); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// handleRunReportSchedule 立即生成报表
+// @Summary 立即生成报表
+// @Description 立即生成最近一个已结束周期的报表并投递，用于检查报表和通道配置，不影响定时生成。需要管理员权限
+// @Tags 报表
+// @Produce json
+// @Param schedule_id path int true "定时报表ID"
+// @Success 200 {object} dao.RunReportResponse "生成结果"
+// @Failure 400 {object} ErrorResponse "报表未启用"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "定时报表不存在"
+// @Failure 500 {object} ErrorResponse "报表生成失败"
+// @Router /api/v1/report-schedule/{schedule_id}/run [post]
+func (s *Server) handleRunReportSchedule(c *gin.Context) {
+	if s.reporter == nil {
+		s.writeError(c, http.StatusBadRequest, errors.New("report not enabled"))
+		return
+	}
+	r := c.MustGet(reportScheduleKey).(*model.ReportSchedule)
+	loc, err := r.Location()
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	start, end := r.LastPeriod(time.Now(), loc)
+	report, err := s.reporter.run(c.Request.Context(), r, start, end)
+	if report == nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.RunReportResponse{Report: dao.FromReportModel(report)}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleListReports 列出已生成的报表
+// @Summary 列出已生成的报表
+// @Description 列出定时报表已生成的报表，最新的在前，需要管理员权限
+// @Tags 报表
+// @Produce json
+// @Param schedule_id path int true "定时报表ID"
+// @Param start query int false "起始位置"
+// @Param limit query int false "返回数量，最大 100" default(10)
+// @Success 200 {object} dao.ListReportsResponse "列出成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "定时报表不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/report-schedule/{schedule_id}/reports [get]
+func (s *Server) handleListReports(c *gin.Context) {
+	var req dao.ListReportsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	r := c.MustGet(reportScheduleKey).(*model.ReportSchedule)
+	reports, total, err := model.ListReports(r.Id, req.Start, req.Limit)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListReportsResponse{
+		Items: make([]dao.ReportSpec, 0, len(reports)),
+		Total: total,
+	}
+	for i := range reports {
+		resp.Items = append(resp.Items, *dao.FromReportModel(&reports[i]))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleDownloadReport 下载报表
+// @Summary 下载报表
+// @Description 下载已生成报表的 HTML 或 PDF 文件，需要管理员权限
+// @Tags 报表
+// @Produce html
+// @Produce application/pdf
+// @Param report_id path int true "报表ID"
+// @Success 200 {file} file "报表文件"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "报表不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/report/{report_id}/download [get]
+func (s *Server) handleDownloadReport(c *gin.Context) {
+	r := c.MustGet(reportKey).(*model.Report)
+	obj, err := s.minioCli.GetObject(c, s.conf.S3.Bucket, r.Path, minio.GetObjectOptions{})
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	defer obj.Close()
+	info, err := obj.Stat()
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			s.writeError(c, http.StatusNotFound, errors.New("report file not found"))
+			return
+		}
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, obj, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, path.Base(r.Path)),
+	})
+}
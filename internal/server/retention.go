@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/model"
+)
+
+// MessageCleaner deletes the messages expired by the retention policy, with
+// their alerts and the media they reference in the bucket. It runs whether
+// the retention is enabled or not, for a reload of the config to enable it.
+type MessageCleaner struct {
+	logger   *logrus.Entry
+	conf     atomic.Pointer[MessageRetentionConfig]
+	bucket   string
+	minioCli *minio.Client
+}
+
+func NewMessageCleaner(logger *logrus.Entry, conf MessageRetentionConfig, bucket string, minioCli *minio.Client) *MessageCleaner {
+	m := &MessageCleaner{
+		logger:   logger.WithField("component", "messageCleaner"),
+		bucket:   bucket,
+		minioCli: minioCli,
+	}
+	m.SetConfig(conf)
+	return m
+}
+
+// SetConfig replaces the retention policy from the next cleanup on.
+func (m *MessageCleaner) SetConfig(conf MessageRetentionConfig) {
+	if conf.Interval <= 0 {
+		conf.Interval = 3600
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 500
+	}
+	m.conf.Store(&conf)
+}
+
+func (m *MessageCleaner) Run(ctx context.Context) {
+	interval := time.Duration(m.conf.Load().Interval) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		conf := m.conf.Load()
+		if conf.Enabled {
+			m.clean(ctx, conf, interval)
+		}
+		if d := time.Duration(conf.Interval) * time.Second; d != interval {
+			interval = d
+			ticker.Reset(interval)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// clean deletes the expired messages in batches, one server at a time.
+func (m *MessageCleaner) clean(ctx context.Context, conf *MessageRetentionConfig, interval time.Duration) {
+	ok, err := model.AcquireMessageCleanupLock(ctx, interval)
+	if err != nil {
+		m.logger.WithError(err).Error("acquire cleanup lock failed")
+		return
+	} else if !ok {
+		return
+	}
+	defer model.ReleaseMessageCleanupLock(context.WithoutCancel(ctx))
+
+	q, err := m.query(conf)
+	if err != nil {
+		m.logger.WithError(err).Error("get retained messages failed")
+		return
+	}
+
+	var total int
+	for ctx.Err() == nil {
+		ms, err := model.ListExpiredMessages(q, conf.BatchSize)
+		if err != nil {
+			m.logger.WithError(err).Error("list expired messages failed")
+			break
+		} else if len(ms) == 0 {
+			break
+		}
+
+		if conf.DeleteMedia {
+			m.deleteMedia(ctx, ms)
+		}
+		ids := make([]int, len(ms))
+		for i, msg := range ms {
+			ids[i] = msg.Id
+		}
+		if err := model.DeleteMessagesByIds(ids); err != nil {
+			m.logger.WithError(err).Errorf("delete %d expired messages failed", len(ids))
+			break
+		}
+		total += len(ids)
+		if len(ms) < conf.BatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		m.logger.Infof("deleted %d expired messages", total)
+	}
+}
+
+func (m *MessageCleaner) query(conf *MessageRetentionConfig) (model.ExpiredMessagesQuery, error) {
+	var q model.ExpiredMessagesQuery
+	now := time.Now()
+	if conf.Days > 0 {
+		q.Before = now.AddDate(0, 0, -conf.Days)
+		q.AlertBefore = now.AddDate(0, 0, -max(conf.Days, conf.AlertDays))
+	}
+	if conf.MaxRows > 0 {
+		id, err := model.GetMessageIdBeyondNewest(conf.MaxRows)
+		if err != nil {
+			return q, err
+		}
+		q.MaxId = id
+	}
+	return q, nil
+}
+
+// deleteMedia deletes the images and videos of the messages, the objects
+// that fail to be deleted are left in the bucket.
+func (m *MessageCleaner) deleteMedia(ctx context.Context, ms []*model.Message) {
+	objects := make(chan minio.ObjectInfo, len(ms)*2)
+	for _, msg := range ms {
+		for _, p := range []string{msg.ImagePath, msg.VideoPath} {
+			if p != "" {
+				objects <- minio.ObjectInfo{Key: strings.TrimPrefix(p, "/")}
+			}
+		}
+	}
+	close(objects)
+
+	for e := range m.minioCli.RemoveObjects(ctx, m.bucket, objects, minio.RemoveObjectsOptions{}) {
+		m.logger.WithError(e.Err).Warnf("delete object %s failed", e.ObjectName)
+	}
+}
@@ -4,12 +4,18 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+
+	"lumina/pkg/tracing"
 )
 
 func (s *Server) SetUpRouter() *gin.Engine {
 	router := gin.New()
+	if s.conf.CORS.Enabled {
+		router.Use(CORS(s.conf.CORS))
+	}
 	router.Use(RequestId())
-	router.Use(Logger())
+	router.Use(tracing.Middleware())
+	router.Use(Logger(s.usage, s.metrics))
 	router.Use(gin.Recovery())
 
 	router.GET("/healthz", func(c *gin.Context) {
@@ -17,6 +23,9 @@ func (s *Server) SetUpRouter() *gin.Engine {
 			"message": "ok",
 		})
 	})
+	if s.metrics != nil {
+		router.GET("/metrics", s.handleMetrics)
+	}
 	// Serve static files from dashboard/build
 	router.Static("/static", "./dashboard/build/static")
 	router.NoRoute(func(c *gin.Context) {
@@ -31,27 +40,54 @@ func (s *Server) SetUpRouter() *gin.Engine {
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
-	apiV1 := router.Group("/api/v1")
+	apiV1 := router.Group("/api/v1", s.rateLimit("api"))
 	s.SetUpApiV1Router(apiV1)
 
 	return router
 }
 
 func (s *Server) SetUpApiV1Router(apiV1 *gin.RouterGroup) {
-	apiV1.POST("/login", s.handleLogin)
+	apiV1.Use(s.checkApiToken())
+	if s.conf.TwoFactor.RequireForAdmins {
+		apiV1.Use(RequireAdminTwoFactor())
+	}
+	if s.conf.MultiTenant.Enabled {
+		apiV1.Use(SetOrgToContext(s.conf.JwtSecret))
+	}
+	apiV1.POST("/login", s.rateLimit("login"), s.handleLogin)
 	apiV1.POST("/logout", s.handleLogout)
+	apiV1.POST("/refresh", s.handleRefresh)
+	apiV1.GET("/oidc/login", s.rateLimit("login"), s.handleOidcLogin)
+	apiV1.GET("/oidc/callback", s.rateLimit("login"), s.handleOidcCallback)
 
 	device := apiV1.Group("/device")
-	device.POST("/register", s.handleRegister)
+	device.POST("/register", s.rateLimit("register"), s.handleRegister)
 	device.GET("", s.handleListDevices)
+	device.GET("/inventory", s.handleListDeviceInventory)
 	device.GET("/:device_id", s.handleGetDevice)
+	device.PUT("/:device_id", s.handleUpdateDevice)
 	device.DELETE("/:device_id", s.handleDeleteDevice)
+	device.GET("/:device_id/stats", s.handleDeviceStats)
+	device.PUT("/:device_id/rotate-token", s.handleForceRotateDeviceToken)
 
 	deviceAuthed := device.Group("").Use(DeviceAuth())
 	deviceAuthed.POST("/unregister", s.handleUnregister)
+	deviceAuthed.POST("/rotate-token", s.handleRotateDeviceToken)
 	deviceAuthed.GET("/jobs", s.handleGetDeviceJobs)
 	deviceAuthed.GET("/preview-tasks", s.handleGetDevicePreviewTasks)
 	deviceAuthed.POST("/report-status", s.handleReportDeviceStatus)
+	deviceAuthed.GET("/test-detect-tasks", s.handleGetDeviceTestDetectTasks)
+	deviceAuthed.POST("/test-detect-result", s.handleReportTestDetectResult)
+	deviceAuthed.GET("/snapshot-tasks", s.handleGetDeviceSnapshotTasks)
+	deviceAuthed.POST("/snapshot-result", s.handleReportSnapshotResult)
+	deviceAuthed.GET("/camera-probe-tasks", s.handleGetDeviceCameraProbeTasks)
+	deviceAuthed.POST("/camera-probe-result", s.handleReportCameraProbeResult)
+	deviceAuthed.GET("/talk-down-tasks", s.handleGetDeviceTalkDownTasks)
+	deviceAuthed.POST("/talk-down-result", s.handleReportTalkDownResult)
+	deviceAuthed.GET("/onvif-discover-tasks", s.handleGetDeviceOnvifDiscoverTasks)
+	deviceAuthed.POST("/onvif-discover-result", s.handleReportOnvifDiscoverResult)
+	deviceAuthed.GET("/upgrade-task", s.handleGetDeviceUpgradeTask)
+	deviceAuthed.POST("/upgrade-result", s.handleReportDeviceUpgradeResult)
 
 	accessToken := apiV1.Group("/access-token")
 	accessToken.GET("", s.handleListAccessToken)
@@ -62,6 +98,8 @@ func (s *Server) SetUpApiV1Router(apiV1 *gin.RouterGroup) {
 	workflow := apiV1.Group("/workflow")
 	workflow.GET("", s.handleListWorkflows)
 	workflow.POST("", s.handleCreateWorkflow)
+	workflow.GET("/export", s.handleExportWorkflows)
+	workflow.POST("/import", s.handleImportWorkflows)
 	workflow.GET("/:workflow_id", s.handleGetWorkflow)
 	workflow.PUT("/:workflow_id", s.handleUpdateWorkflow)
 	workflow.DELETE("/:workflow_id", s.handleDeleteWorkflow)
@@ -69,47 +107,182 @@ func (s *Server) SetUpApiV1Router(apiV1 *gin.RouterGroup) {
 	// Camera routes
 	apiV1.GET("/camera", s.handleListCameras)
 	apiV1.POST("/camera", s.handleCreateCamera)
+	apiV1.POST("/camera/discover", s.handleDiscoverCameras)
+	apiV1.GET("/camera/tags", s.handleListCameraTags)
 	camera := apiV1.Group("/camera/:camera_id")
 	camera.Use(SetCameraToContext())
 	camera.GET("", s.handleGetCamera)
 	camera.PUT("", s.handleUpdateCamera)
 	camera.DELETE("", s.handleDeleteCamera)
+	camera.PUT("/calibration", s.handleSetCameraCalibration)
+	camera.DELETE("/calibration", s.handleDeleteCameraCalibration)
 	camera.POST("/preview", s.handleStartCameraPreview)
 	camera.PUT("/preview", s.handleTouchCameraPreview)
+	camera.DELETE("/preview", s.handleStopCameraPreview)
+	camera.POST("/test-detect", s.handleTestCameraDetect)
+	camera.POST("/snapshot", s.handleCameraSnapshot)
+	camera.POST("/probe", s.handleProbeCamera)
+	camera.GET("/occupancy", s.handleCameraOccupancy)
+	camera.GET("/heatmap", s.handleCameraHeatmap)
+	camera.POST("/ptz/move", s.handlePtzMove)
+	camera.POST("/ptz/stop", s.handlePtzStop)
+	camera.GET("/ptz/presets", s.handleListPtzPresets)
+	camera.POST("/ptz/goto-preset", s.handlePtzGotoPreset)
 
 	job := apiV1.Group("/job")
 	job.Use(SetJobToContext())
 	job.GET("", s.handleListJobs)
 	job.POST("", s.handleCreateJob)
+	job.POST("/validate", s.handleValidateJob)
 	job.GET("/:job_id", s.handleGetJob)
 	job.PUT("/:job_id", s.handleUpdateJob)
 	job.DELETE("/:job_id", s.handleDeleteJob)
+	job.POST("/:job_id/clone", s.handleCloneJob)
 	job.PUT("/:job_id/start", s.handleStartJob)
 	job.PUT("/:job_id/stop", s.handleStopJob)
+	job.PUT("/:job_id/pause", s.handlePauseJob)
+	job.PUT("/:job_id/resume", s.handleResumeJob)
 	job.GET("/:job_id/stats", s.handleJobStats)
+	job.GET("/:job_id/precision", s.handleJobPrecision)
+	job.GET("/:job_id/device-status", s.handleGetJobDeviceStatus)
+	job.GET("/:job_id/history", s.handleListJobStatusHistory)
+	job.GET("/:job_id/artifacts", s.handleListJobArtifacts)
+	job.GET("/:job_id/artifacts/:kind", s.handleGetJobArtifact)
+
+	apiV1.GET("/device-group", s.handleListDeviceGroups)
+	apiV1.POST("/device-group", s.handleCreateDeviceGroup)
+	deviceGroup := apiV1.Group("/device-group/:group_id")
+	deviceGroup.Use(SetDeviceGroupToContext())
+	deviceGroup.GET("", s.handleGetDeviceGroup)
+	deviceGroup.PUT("", s.handleUpdateDeviceGroup)
+	deviceGroup.DELETE("", s.handleDeleteDeviceGroup)
+	deviceGroup.GET("/rollout", RequireDefaultOrg(), s.handleListRollouts)
+	deviceGroup.POST("/rollout", RequireDefaultOrg(), s.handleCreateRollout)
+
+	apiV1.GET("/release", RequireDefaultOrg(), s.handleListReleases)
+	apiV1.POST("/release", RequireDefaultOrg(), s.handleCreateRelease)
+	apiV1.DELETE("/release/:release_id", RequireDefaultOrg(), s.handleDeleteRelease)
+	rollout := apiV1.Group("/rollout/:rollout_id")
+	rollout.Use(RequireDefaultOrg(), SetRolloutToContext())
+	rollout.GET("", s.handleGetRollout)
+	rollout.GET("/devices", s.handleListDeviceUpgrades)
+	rollout.PUT("/halt", s.handleHaltRollout)
+	rollout.PUT("/resume", s.handleResumeRollout)
+	rollout.PUT("/cancel", s.handleCancelRollout)
+
+	apiV1.GET("/health-event", RequireDefaultOrg(), s.handleListHealthEvents)
+	apiV1.PUT("/health-event/:event_id/resolve", RequireDefaultOrg(), s.handleResolveHealthEvent)
+	apiV1.GET("/canary", RequireDefaultOrg(), s.handleGetCanaryStatus)
+	apiV1.POST("/canary/chat/completions", RequireDefaultOrg(), s.handleCanaryChatCompletions)
+
+	if s.conf.GraphQL.Enabled {
+		apiV1.POST("/graphql", s.handleGraphQL)
+	}
 
 	apiV1.GET("/message", s.handleListMessages)
+	apiV1.GET("/message/search", s.handleSearchMessages)
+	apiV1.GET("/message/export", s.handleExportMessages)
+	apiV1.GET("/message/semantic-search", s.handleSemanticSearchMessages)
 	apiV1.POST("/message", s.handleCreateMessage)
 	message := apiV1.Group("/message/:message_id")
 	message.Use(SetMessageToContext())
 	message.GET("", s.handleGetMessage)
 	message.DELETE("", s.handleDeleteMessage)
+	message.POST("/talk-down", s.handleTalkDown)
+	message.GET("/media", s.handleGetMessageMedia)
+	message.GET("/verify", s.handleVerifyMessage)
+	feedback := message.Group("/feedback")
+	feedback.Use(TrySetUserToContext(s.conf.JwtSecret), NeedAuth(false))
+	feedback.GET("", s.handleGetMessageFeedback)
+	feedback.PUT("", s.handlePutMessageFeedback)
+	feedback.DELETE("", s.handleDeleteMessageFeedback)
+	alert := message.Group("")
+	alert.Use(TrySetUserToContext(s.conf.JwtSecret), NeedAuth(false), SetAlertToContext())
+	alert.GET("/alert", s.handleGetAlert)
+	alert.PUT("/ack", s.handleAckAlert)
+	alert.PUT("/assign", s.handleAssignAlert)
+	alert.POST("/comment", s.handleCommentAlert)
+	alert.PUT("/resolve", s.handleResolveAlert)
+	apiV1.GET("/stats/alerts", s.handleAlertStats)
+	apiV1.GET("/stats/alerts/trend", s.handleAlertTrend)
+	apiV1.GET("/alerts/gallery", s.handleAlertGallery)
+
+	ws := apiV1.Group("/ws")
+	ws.GET("/alerts", s.handleAlertsWebSocket)
+	apiV1.GET("/events", s.handleEvents)
+
+	apiV1.GET("/notification/vapid-public-key", s.handleGetVapidPublicKey)
+	notification := apiV1.Group("/notification")
+	notification.Use(TrySetUserToContext(s.conf.JwtSecret), NeedAuth(false))
+	notification.POST("/subscription", s.handleCreatePushSubscription)
+	notification.DELETE("/subscription", s.handleDeletePushSubscription)
+	notification.GET("/preference", s.handleGetNotificationPreference)
+	notification.PUT("/preference", s.handleUpdateNotificationPreference)
+	notification.GET("/channel", NeedAuth(true), RequireDefaultOrg(), s.handleListNotificationChannels)
+	notification.POST("/channel", NeedAuth(true), RequireDefaultOrg(), s.handleCreateNotificationChannel)
+	channel := notification.Group("/channel/:channel_id")
+	channel.Use(NeedAuth(true), RequireDefaultOrg(), SetNotificationChannelToContext())
+	channel.GET("", s.handleGetNotificationChannel)
+	channel.PUT("", s.handleUpdateNotificationChannel)
+	channel.DELETE("", s.handleDeleteNotificationChannel)
+	channel.POST("/test", s.handleTestNotificationChannel)
+	notification.GET("/escalation", NeedAuth(true), RequireDefaultOrg(), s.handleListEscalationPolicies)
+	notification.POST("/escalation", NeedAuth(true), RequireDefaultOrg(), s.handleCreateEscalationPolicy)
+	escalation := notification.Group("/escalation/:policy_id")
+	escalation.Use(NeedAuth(true), RequireDefaultOrg(), SetEscalationPolicyToContext())
+	escalation.GET("", s.handleGetEscalationPolicy)
+	escalation.PUT("", s.handleUpdateEscalationPolicy)
+	escalation.DELETE("", s.handleDeleteEscalationPolicy)
+
+	reportSchedule := apiV1.Group("/report-schedule")
+	reportSchedule.Use(TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), RequireDefaultOrg())
+	reportSchedule.GET("", s.handleListReportSchedules)
+	reportSchedule.POST("", s.handleCreateReportSchedule)
+	schedule := reportSchedule.Group("/:schedule_id")
+	schedule.Use(SetReportScheduleToContext())
+	schedule.GET("", s.handleGetReportSchedule)
+	schedule.PUT("", s.handleUpdateReportSchedule)
+	schedule.DELETE("", s.handleDeleteReportSchedule)
+	schedule.POST("/run", s.handleRunReportSchedule)
+	schedule.GET("/reports", s.handleListReports)
+	report := apiV1.Group("/report/:report_id")
+	report.Use(TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), RequireDefaultOrg(), SetReportToContext())
+	report.GET("/download", s.handleDownloadReport)
 
 	apiV1.GET("/conversation", s.handleListConversations)
 	apiV1.POST("/conversation", s.handleCreateConversation)
 	conversation := apiV1.Group("/conversation/:uuid")
-	conversation.Use(SetConversationToContext())
+	conversation.Use(TrySetUserToContext(s.conf.JwtSecret), SetConversationToContext())
 	conversation.GET("", s.handleGetConversation)
 	conversation.DELETE("", s.handleDeleteConversation)
 	conversation.GET("/message", s.handleListChatMessages)
 	conversation.POST("/chat", s.handleChat)
+	conversation.GET("/chat/:stream_id/resume", s.handleResumeChat)
 	conversation.POST("/title", s.handleGenChatTitle)
 
+	proposal := apiV1.Group("/agent/proposal/:proposal_uuid")
+	proposal.Use(TrySetUserToContext(s.conf.JwtSecret), NeedAuth(false), SetAgentProposalToContext())
+	proposal.GET("", s.handleGetAgentProposal)
+	proposal.POST("/approve", s.handleApproveAgentProposal)
+	proposal.DELETE("", s.handleRejectAgentProposal)
+
 	v1Authed := apiV1.Group("")
 	// v1Authed.Use(NeedAuth(false))
 
 	v1UserSettings := v1Authed.Group("/settings")
+	v1UserSettings.Use(TrySetUserToContext(s.conf.JwtSecret), NeedAuth(false))
 	v1UserSettings.GET("/profile", s.handleGetUserProfile)
+	v1UserSettings.PUT("/profile", s.handleUpdateUserProfile)
+	v1UserSettings.PUT("/password", s.handleChangePassword)
+	v1UserSettings.POST("/totp", s.handleSetupTotp)
+	v1UserSettings.POST("/totp/enable", s.handleEnableTotp)
+	v1UserSettings.POST("/totp/disable", s.handleDisableTotp)
+	v1UserSettings.POST("/totp/recovery-codes", s.handleRegenerateRecoveryCodes)
+	v1UserSettings.GET("/tokens", s.handleListApiTokens)
+	v1UserSettings.POST("/tokens", s.handleCreateApiToken)
+	v1UserSettings.DELETE("/token/:token_id", s.handleDeleteApiToken)
+	v1UserSettings.GET("/sessions", s.handleListSessions)
+	v1UserSettings.DELETE("/session/:session_id", s.handleRevokeSession)
 
 	{
 		v1Admin := v1Authed.Group("/admin")
@@ -117,5 +290,16 @@ func (s *Server) SetUpApiV1Router(apiV1 *gin.RouterGroup) {
 		v1Admin.GET("/users", s.handleAdminListUsers)
 		v1Admin.POST("/users", s.handleAdminCreateUsers)
 		v1Admin.DELETE("/user/:user_id", s.handleAdminDeleteUser)
+		v1Admin.POST("/user/:user_id/password/reset", TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), s.handleAdminResetPassword)
+		v1Admin.POST("/user/:user_id/logout", TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), s.handleAdminLogoutUser)
+		v1Admin.DELETE("/user/:user_id/totp", TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), s.handleAdminResetTotp)
+		v1Admin.GET("/db-stats", TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), RequireDefaultOrg(), s.handleAdminDBStats)
+		v1Admin.GET("/config/reload", TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), RequireDefaultOrg(), s.handleAdminConfigStatus)
+		v1Admin.POST("/config/reload", TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), RequireDefaultOrg(), s.handleAdminReloadConfig)
+		v1Admin.GET("/usage", TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), RequireDefaultOrg(), s.handleAdminListApiConsumers)
+		v1Admin.GET("/usage/endpoint", TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), RequireDefaultOrg(), s.handleAdminListApiEndpointUsage)
+		v1Admin.GET("/organizations", TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), s.handleListOrganizations)
+		v1Admin.POST("/organizations", TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), s.handleCreateOrganization)
+		v1Admin.DELETE("/organization/:org_id", TrySetUserToContext(s.conf.JwtSecret), NeedAuth(true), s.handleDeleteOrganization)
 	}
 }
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-contrib/pprof"
@@ -16,9 +18,14 @@ import (
 	"github.com/google/uuid"
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	api "github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/sirupsen/logrus"
 
 	_ "lumina/docs"
+	"lumina/internal/dao"
+	"lumina/internal/embedding"
+	"lumina/internal/model"
 	"lumina/pkg/log"
 )
 
@@ -31,6 +38,35 @@ type Server struct {
 	logger       *logrus.Entry
 	influxClient influxdb2.Client
 	influxQuery  api.QueryAPI
+	influxWrite  api.WriteAPIBlocking
+	alertHub     *AlertHub
+	watchHub     *WatchHub
+	eventHub     *EventHub
+	statusBuffer *StatusBuffer
+	minioCli     *minio.Client
+	mediaUrls    *MediaUrls
+	canary       *Canary
+	notifier     *Notifier
+	usage        *UsageRecorder
+	embedder     *embedding.Embedder
+	vectors      *embedding.Store
+	reporter     *Reporter
+	// metrics is nil if /metrics is disabled
+	metrics *Metrics
+	// oidc is nil if the OIDC login is disabled
+	oidc *OIDCLogin
+	// draining is closed when the server shuts down, the event streams end
+	// on it for their clients to reconnect to another server
+	draining chan struct{}
+	// hijacked tracks the websocket connections, which the shutdown of the
+	// http server doesn't wait for
+	hijacked sync.WaitGroup
+	// live is the config with its reloadable sections as last reloaded, conf
+	// stays the config the server started with
+	live       atomic.Pointer[Config]
+	cleaner    *MessageCleaner
+	reloadMu   sync.Mutex
+	lastReload *dao.ConfigReloadSpec
 }
 
 func NewServer(ctx context.Context, conf *Config) (*Server, error) {
@@ -44,13 +80,98 @@ func NewServer(ctx context.Context, conf *Config) (*Server, error) {
 				IdleConnTimeout:     90 * time.Second,
 			},
 		},
-		logger: log.GetLogger(ctx),
+		logger:   log.GetLogger(ctx),
+		draining: make(chan struct{}),
+	}
+	s.live.Store(conf)
+
+	if conf.Metrics.Enabled {
+		s.metrics = NewMetrics()
+		if err := s.metrics.registerDBCallbacks(model.DB); err != nil {
+			return nil, fmt.Errorf("register db metrics failed: %w", err)
+		}
 	}
 
 	if conf.InfluxDB.Enabled {
 		client := influxdb2.NewClient(conf.InfluxDB.URL, conf.InfluxDB.Token)
 		s.influxClient = client
 		s.influxQuery = client.QueryAPI(conf.InfluxDB.Org)
+		s.influxWrite = client.WriteAPIBlocking(conf.InfluxDB.Org, conf.InfluxDB.Bucket)
+	}
+
+	region := conf.S3.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	minioCli, err := minio.New(conf.S3.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(conf.S3.AccessKeyID, conf.S3.SecretAccessKey, ""),
+		Secure: conf.S3.UseSSL,
+		Region: region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create minio client failed: %w", err)
+	}
+	s.minioCli = minioCli
+	s.mediaUrls, err = NewMediaUrls(s.logger, conf.S3, minioCli, region)
+	if err != nil {
+		return nil, fmt.Errorf("create media urls failed: %w", err)
+	}
+
+	if conf.OIDC.Enabled {
+		s.oidc, err = NewOIDCLogin(conf.OIDC, s.client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.alertHub = NewAlertHub(s.logger, conf.S3.VisitPrefix())
+	go s.alertHub.Run(ctx)
+
+	go NewTalkDowner(s.logger).Run(ctx, s.alertHub)
+	s.notifier = NewNotifier(s.logger, conf.SMTP, conf.S3, minioCli, s.client)
+	go s.notifier.Run(ctx, s.alertHub)
+	s.watchHub = NewWatchHub(s.logger)
+	go s.watchHub.Run(ctx)
+	model.DeviceStateThresholds.Degraded = time.Duration(conf.DeviceState.DegradedAfter) * time.Second
+	model.DeviceStateThresholds.Offline = time.Duration(conf.DeviceState.OfflineAfter) * time.Second
+	s.eventHub = NewEventHub(s.logger)
+	go s.eventHub.Run(ctx, s.alertHub)
+	go NewDeviceMonitor(s.logger, s.eventHub).Run(ctx)
+	go s.monitorDBPool(ctx)
+	go s.monitorRollouts(ctx)
+	go s.cleanSessions(ctx)
+	go s.watchConfig(ctx)
+	if conf.VolumeAnomaly.Enabled {
+		go NewVolumeAnalyzer(s.logger, conf.VolumeAnomaly).Run(ctx)
+	}
+	if conf.Canary.Enabled {
+		canaryConf := conf.Canary
+		if canaryConf.StubEndpoint == "" {
+			canaryConf.StubEndpoint = canaryStubEndpoint(conf.Addr)
+		}
+		s.canary, err = NewCanary(s.logger, canaryConf, conf.S3.Bucket, minioCli, s.metrics)
+		if err != nil {
+			return nil, err
+		}
+		go s.canary.Run(ctx)
+	}
+
+	s.statusBuffer = NewStatusBuffer(s.logger, s.influxWrite, s.metrics)
+	go s.statusBuffer.Run(ctx)
+	s.usage = NewUsageRecorder(s.logger, conf.ApiUsage, conf.JwtSecret)
+	go s.usage.Run(ctx)
+	s.cleaner = NewMessageCleaner(s.logger, conf.MessageRetention, conf.S3.Bucket, minioCli)
+	go s.cleaner.Run(ctx)
+	if conf.SemanticSearch.Enabled {
+		s.embedder = embedding.NewEmbedder(conf.SemanticSearch.Config)
+		s.vectors = embedding.NewStore(conf.SemanticSearch.Qdrant)
+	}
+	if conf.Report.Enabled {
+		s.reporter = NewReporter(s.logger, conf.Report, conf.S3.Bucket, minioCli, s.mediaUrls, s.notifier)
+		go s.reporter.Run(ctx)
+	}
+	if conf.WebPush.Enabled {
+		go NewWebPusher(s.logger, conf.WebPush, s.client).Run(ctx, s.alertHub)
 	}
 
 	return s, nil
@@ -67,7 +188,9 @@ func RequestId() gin.HandlerFunc {
 	}
 }
 
-func Logger() gin.HandlerFunc {
+// Logger logs every request with the consumer who made it, and counts it in
+// the usage of its endpoint.
+func Logger(usage *UsageRecorder, metrics *Metrics) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		t := time.Now()
 		c.Next()
@@ -79,8 +202,28 @@ func Logger() gin.HandlerFunc {
 			totalTokens = val.(int)
 		}
 
-		logrus.Info("ip: ", c.ClientIP(), " method: ", c.Request.Method, " path: ",
-			c.Request.URL.Path, " status: ", status, " latency: ", latency, " total_tokens: ", totalTokens)
+		// the route keeps the usage of endpoints with path parameters
+		// together, requests matching no route are counted together
+		endpoint := c.FullPath()
+		if endpoint == "" {
+			endpoint = "unmatched"
+		}
+		consumer := usage.consumer(c)
+		usage.Add(consumer, c.Request.Method, endpoint, status, latency)
+		metrics.observeRequest(c.Request.Method, endpoint, status, latency)
+
+		logrus.WithFields(logrus.Fields{
+			"request_id":    c.Writer.Header().Get(httpXRequestId),
+			"ip":            c.ClientIP(),
+			"method":        c.Request.Method,
+			"path":          c.Request.URL.Path,
+			"endpoint":      endpoint,
+			"status":        status,
+			"latency":       latency,
+			"consumer_kind": consumer.kind,
+			"consumer":      consumer.name,
+			"total_tokens":  totalTokens,
+		}).Info("access")
 	}
 }
 
@@ -106,33 +249,93 @@ func (s *Server) Start() {
 	}
 }
 
+// Shutdown stops accepting requests and waits, up to the shutdown timeout,
+// for the in-flight ones to complete, the chat answers being streamed among
+// them. The event streams and websockets end right away. It then writes the
+// data buffered for InfluxDB and the database, which must still be open.
 func (s *Server) Shutdown() {
-	err := s.httpServer.Shutdown(context.Background())
-	if err != nil {
-		logrus.Fatalf("server forced to shutdown: %v", err)
+	close(s.draining)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.conf.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	if s.httpServer != nil {
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			s.logger.WithError(err).Warn("in-flight requests not completed in time, closing their connections")
+			s.httpServer.Close()
+		}
+	}
+	hijackedDone := make(chan struct{})
+	go func() {
+		s.hijacked.Wait()
+		close(hijackedDone)
+	}()
+	select {
+	case <-hijackedDone:
+	case <-ctx.Done():
+		s.logger.Warn("websockets not closed in time")
+	}
+
+	// write the status reports and the usage received since the last flush
+	s.statusBuffer.Flush()
+	s.usage.Flush()
+	if s.influxClient != nil {
+		s.influxClient.Close()
 	}
 }
 
 type ErrorResponse struct {
 	// 错误信息
 	Error string `json:"error"`
+	// 错误码，如 validation_failed、not_found
+	Code string `json:"code,omitempty"`
+	// 校验失败的字段，仅 validation_failed 错误返回
+	Fields []FieldError `json:"fields,omitempty"`
 }
 
 func (s *Server) writeError(c *gin.Context, code int, err error) {
+	var unsupported *model.UnsupportedError
+	if goerrors.As(err, &unsupported) {
+		s.writeErrorCode(c, http.StatusNotImplemented, errorCodeUnsupportedByDatabase, err)
+		return
+	}
 	if code == http.StatusInternalServerError {
 		s.logger.Errorf("error: %v", err)
 		err = fmt.Errorf("internal server error")
 	}
+	if code == http.StatusBadRequest {
+		if resp := bindingErrorResponse(err); resp != nil {
+			c.JSON(code, resp)
+			return
+		}
+	}
 	c.JSON(code, ErrorResponse{
 		Error: err.Error(),
+		Code:  errorCode(code),
+	})
+}
+
+// writeErrorCode is writeError with a code more specific than the one of
+// the status.
+func (s *Server) writeErrorCode(c *gin.Context, status int, code string, err error) {
+	c.JSON(status, ErrorResponse{
+		Error: err.Error(),
+		Code:  code,
 	})
 }
 
 func init() {
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(requestFieldName)
 		v.RegisterValidation("password", func(fl validator.FieldLevel) bool {
 			matched, _ := regexp.MatchString(`^[a-zA-Z0-9!@#$%^*+()]+$`, fl.Field().String())
 			return matched
 		})
+		v.RegisterValidation("timestamp", func(fl validator.FieldLevel) bool {
+			_, err := dao.ParseTime(fl.Field().String())
+			return err == nil
+		})
+		v.RegisterValidation("timezone", func(fl validator.FieldLevel) bool {
+			return dao.ValidTimezone(fl.Field().String())
+		})
 	}
 }
@@ -0,0 +1,347 @@
+package server
+
+import (
+	"context"
+	goerrors "errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+	"lumina/internal/version"
+	"lumina/pkg/str"
+)
+
+const (
+	// sessionKey holds the session of the access JWT of the request
+	sessionKey = "session"
+	// refreshTokenCookie is sent to the API only, the refresh and the logout
+	// read it
+	refreshTokenCookie     = "refresh_token"
+	refreshTokenCookiePath = "/api/v1"
+	refreshTokenLength     = 48
+	// refreshReuseGrace is how long a refresh token used up can be sent
+	// again without ending its session, for the clients refreshing
+	// concurrently from several tabs
+	refreshReuseGrace = 30 * time.Second
+	// sessionCleanInterval between deletions of the ended sessions
+	sessionCleanInterval = time.Hour
+	// legacyJwtLifetime is the lifetime of the JWTs issued before the
+	// sessions, the forced logouts deny the JWTs of a user for as long
+	legacyJwtLifetime = 7 * 24 * time.Hour
+)
+
+var errInvalidRefreshToken = goerrors.New("invalid refresh token")
+
+func genJwtToken(user *model.User, sessionId string, ttl time.Duration, jwtSecret string) (string, error) {
+	now := time.Now()
+	claims := TokenClaims{
+		UserId:    user.Id,
+		SessionId: sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        strings.ReplaceAll(uuid.New().String(), "-", ""),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    version.APP,
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(jwtSecret))
+}
+
+// startSession starts a session for the user logging in, see issueTokens.
+func (s *Server) startSession(c *gin.Context, user *model.User) (*dao.RefreshResponse, error) {
+	now := time.Now()
+	var sessionExpireTime *time.Time
+	if s.conf.Session.MaxLifetime > 0 {
+		t := now.Add(time.Duration(s.conf.Session.MaxLifetime) * time.Second)
+		sessionExpireTime = &t
+	}
+	session := &model.RefreshToken{
+		UserId:            user.Id,
+		SessionId:         strings.ReplaceAll(uuid.New().String(), "-", ""),
+		SessionExpireTime: sessionExpireTime,
+		LoginTime:         now,
+	}
+	return s.issueTokens(c, user, session)
+}
+
+// issueTokens issues the access JWT and the next refresh token of the
+// session and sets them as cookies for the dashboard.
+func (s *Server) issueTokens(c *gin.Context, user *model.User, session *model.RefreshToken) (*dao.RefreshResponse, error) {
+	now := time.Now()
+	refreshToken := str.RandStr(refreshTokenLength, str.Alphanumeric)
+	next := &model.RefreshToken{
+		UserId:            user.Id,
+		SessionId:         session.SessionId,
+		TokenHash:         model.HashApiToken(refreshToken),
+		ExpireTime:        now.Add(time.Duration(s.conf.Session.IdleTimeout) * time.Second),
+		SessionExpireTime: session.SessionExpireTime,
+		LoginTime:         session.LoginTime,
+		UserAgent:         truncate(c.Request.UserAgent(), 255),
+		Ip:                c.ClientIP(),
+	}
+	if next.SessionExpireTime != nil && next.SessionExpireTime.Before(next.ExpireTime) {
+		next.ExpireTime = *next.SessionExpireTime
+	}
+	if err := model.CreateRefreshToken(next); err != nil {
+		return nil, err
+	}
+
+	ttl := s.conf.Session.AccessTokenTtl
+	token, err := genJwtToken(user, next.SessionId, time.Duration(ttl)*time.Second, s.conf.JwtSecret)
+	if err != nil {
+		return nil, err
+	}
+	c.SetCookie("token", token, ttl, "/", "", false, true)
+	c.SetCookie(refreshTokenCookie, refreshToken, int(next.ExpireTime.Sub(now).Seconds()), refreshTokenCookiePath, "", false, true)
+	return &dao.RefreshResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    ttl,
+	}, nil
+}
+
+// tokenDenied reports whether the JWT was revoked by a logout. The JWTs are
+// let through if redis fails, as the rate limits do.
+func tokenDenied(ctx context.Context, claims *TokenClaims) bool {
+	if model.Redis == nil {
+		return false
+	}
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	denied, err := model.IsTokenDenied(ctx, claims.ID, claims.UserId, issuedAt)
+	if err != nil {
+		logrus.WithError(err).Warn("check the token denylist")
+		return false
+	}
+	return denied
+}
+
+// denyRequestToken revokes the JWT the request carries, if any, until it
+// expires.
+func (s *Server) denyRequestToken(c *gin.Context) {
+	tokenStr := requestToken(c)
+	if tokenStr == "" || strings.HasPrefix(tokenStr, "sk-") || model.Redis == nil {
+		return
+	}
+	claims := &TokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(s.conf.JwtSecret), nil
+	})
+	if err != nil || !token.Valid || claims.ID == "" || claims.ExpiresAt == nil {
+		return
+	}
+	if err := model.DenyToken(c, claims.ID, claims.ExpiresAt.Time); err != nil {
+		s.logger.WithError(err).Warn("deny token on logout failed")
+	}
+}
+
+// logoutUser ends every session of the user and revokes the JWTs issued to
+// it so far.
+func (s *Server) logoutUser(ctx context.Context, userId int) error {
+	if err := model.RevokeUserSessions(userId, ""); err != nil {
+		return err
+	}
+	if model.Redis == nil {
+		return nil
+	}
+	ttl := max(time.Duration(s.conf.Session.AccessTokenTtl)*time.Second, legacyJwtLifetime)
+	return model.DenyUserTokens(ctx, userId, ttl)
+}
+
+// clearSessionCookies removes the cookies of the session of the dashboard.
+func clearSessionCookies(c *gin.Context) {
+	c.SetCookie("token", "", -1, "/", "", false, true)
+	c.SetCookie(refreshTokenCookie, "", -1, refreshTokenCookiePath, "", false, true)
+}
+
+// requestRefreshToken returns the refresh token of the request, from the
+// cookie or the body.
+func requestRefreshToken(c *gin.Context) string {
+	if token, _ := c.Cookie(refreshTokenCookie); token != "" {
+		return token
+	}
+	var req dao.RefreshRequest
+	if c.Request.ContentLength != 0 && c.ShouldBindJSON(&req) == nil {
+		return req.RefreshToken
+	}
+	return ""
+}
+
+// @Summary 刷新登录凭证
+// @Description 用刷新令牌换取新的登录凭证和刷新令牌，原刷新令牌随即作废，已作废的刷新令牌再次使用时视为被盗用，其会话随之失效
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Param request body dao.RefreshRequest false "刷新令牌，为空时使用 refresh_token cookie"
+// @Success 200 {object} dao.RefreshResponse
+// @Failure 401 {object} ErrorResponse "刷新令牌无效、已过期或已作废"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/refresh [post]
+func (s *Server) handleRefresh(c *gin.Context) {
+	tokenStr := requestRefreshToken(c)
+	if tokenStr == "" {
+		s.writeError(c, http.StatusUnauthorized, errInvalidRefreshToken)
+		return
+	}
+	session, err := model.GetRefreshTokenByToken(tokenStr)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	now := time.Now()
+	if session == nil || session.RevokedTime != nil || session.Expired(now) {
+		clearSessionCookies(c)
+		s.writeError(c, http.StatusUnauthorized, errInvalidRefreshToken)
+		return
+	}
+
+	used := session.UsedTime != nil
+	if !used {
+		ok, err := model.UseRefreshToken(session.Id, now)
+		if err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		}
+		used = !ok
+	}
+	if used {
+		// a concurrent refresh using the token first is in the grace
+		if session.UsedTime != nil && now.Sub(*session.UsedTime) > refreshReuseGrace {
+			s.logger.Warnf("refresh token of session %s of user %d reused, ending the session", session.SessionId, session.UserId)
+			if _, err := model.RevokeSession(session.UserId, session.SessionId); err != nil {
+				s.logger.WithError(err).Errorf("revoke session %s failed", session.SessionId)
+			}
+		}
+		s.writeError(c, http.StatusUnauthorized, errInvalidRefreshToken)
+		return
+	}
+
+	user, err := model.GetUserById(session.UserId)
+	if err != nil {
+		clearSessionCookies(c)
+		s.writeError(c, http.StatusUnauthorized, errInvalidRefreshToken)
+		return
+	}
+	resp, err := s.issueTokens(c, user, session)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary 列出登录会话
+// @Description 列出当前用户未过期的登录会话
+// @Tags 用户管理
+// @Produce json
+// @Success 200 {object} dao.ListSessionsResponse
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/settings/sessions [get]
+func (s *Server) handleListSessions(c *gin.Context) {
+	user := c.MustGet(userKey).(*model.User)
+	sessions, err := model.ListUserSessions(user.Id, time.Now())
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp := dao.ListSessionsResponse{Items: make([]dao.SessionSpec, 0, len(sessions))}
+	for _, t := range sessions {
+		resp.Items = append(resp.Items, dao.ToSessionSpec(t, c.GetString(sessionKey)))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary 注销登录会话
+// @Description 注销当前用户的登录会话，其刷新令牌立即失效，已签发的登录凭证在过期前仍然有效
+// @Tags 用户管理
+// @Produce json
+// @Param session_id path string true "会话ID"
+// @Success 200
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "会话不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/settings/session/{session_id} [delete]
+func (s *Server) handleRevokeSession(c *gin.Context) {
+	user := c.MustGet(userKey).(*model.User)
+	ok, err := model.RevokeSession(user.Id, c.Param("session_id"))
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if !ok {
+		s.writeError(c, http.StatusNotFound, goerrors.New("session not found"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary 强制用户登出
+// @Description 注销用户的全部登录会话，已签发的登录凭证立即失效，API 令牌不受影响
+// @Tags 用户管理
+// @Produce json
+// @Param user_id path int true "用户ID"
+// @Success 200
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "用户不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/admin/user/{user_id}/logout [post]
+func (s *Server) handleAdminLogoutUser(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	user, err := model.GetUserById(userId)
+	if err != nil {
+		if goerrors.Is(err, gorm.ErrRecordNotFound) {
+			s.writeError(c, http.StatusNotFound, err)
+			return
+		}
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !inRequestOrg(c, user.OrgId) {
+		s.writeError(c, http.StatusNotFound, goerrors.New("user not found"))
+		return
+	}
+	if err := s.logoutUser(c, user.Id); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	admin := c.MustGet(userKey).(*model.User)
+	s.logger.Infof("admin %s logged out user %s", admin.Username, user.Username)
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// cleanSessions deletes the refresh tokens of the ended sessions.
+func (s *Server) cleanSessions(ctx context.Context) {
+	ticker := time.NewTicker(sessionCleanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if n, err := model.DeleteExpiredRefreshTokens(time.Now()); err != nil {
+			s.logger.WithError(err).Error("delete expired refresh tokens failed")
+		} else if n > 0 {
+			s.logger.Debugf("deleted %d expired refresh tokens", n)
+		}
+	}
+}
@@ -2,9 +2,14 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,13 +20,13 @@ import (
 
 // handleJobStats 任务统计
 // @Summary 获取任务统计
-// @Description 根据job_id从InfluxDB查询消息数量趋势；检测任务还返回各Label数量趋势
+// @Description 根据job_id从InfluxDB查询消息数量和各级别告警数量趋势；检测任务还返回各Label数量趋势
 // @Tags 任务
 // @Accept json
 // @Produce json
 // @Param job_id path string true "任务job_id"
-// @Param start query string false "开始时间(RFC3339)"
-// @Param end query string false "结束时间(RFC3339)"
+// @Param start query string false "开始时间(RFC3339 或 Unix 时间戳)"
+// @Param end query string false "结束时间(RFC3339 或 Unix 时间戳)"
 // @Param window query string false "聚合窗口，如1m、5m、15m" default(5m)
 // @Success 200 {object} dao.JobStatsResponse "获取成功"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
@@ -42,59 +47,403 @@ func (s *Server) handleJobStats(c *gin.Context) {
 		return
 	}
 
-	end := time.Now().UTC()
-	if req.End != "" {
-		te, err := time.Parse(time.RFC3339, req.End)
+	start, end, window, err := parseStatsRange(req.Start, req.End, req.Window)
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := s.queryJobStats(c.Request.Context(), job, start, end, window)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// queryJobStats queries the message and alert trends of the job, and the
+// label trends of a detect job.
+func (s *Server) queryJobStats(ctx context.Context, job *model.Job, start, end time.Time, window string) (*dao.JobStatsResponse, error) {
+	messages, err := s.queryMessagesTrend(ctx, job.Uuid, start, end, window)
+	if err != nil {
+		return nil, err
+	}
+	alerts, err := s.queryAlertsTrend(ctx, job.Uuid, "", start, end, window)
+	if err != nil {
+		return nil, err
+	}
+	for i := range alerts {
+		alerts[i].JobId = job.Id
+	}
+	resp := &dao.JobStatsResponse{
+		Messages: messages,
+		Alerts:   alerts,
+	}
+	if job.Kind == model.JobKindDetect {
+		labels, err := s.queryLabelsTrend(ctx, job.Uuid, start, end, window)
 		if err != nil {
-			s.writeError(c, http.StatusBadRequest, fmt.Errorf("invalid end: %w", err))
-			return
+			return nil, err
 		}
-		end = te.UTC()
+		resp.Labels = labels
 	}
+	return resp, nil
+}
 
-	start := end.Add(-24 * time.Hour)
-	if req.Start != "" {
-		ts, err := time.Parse(time.RFC3339, req.Start)
+// handleAlertTrend 告警趋势
+// @Summary 获取告警趋势
+// @Description 从InfluxDB查询各任务各级别的告警数量趋势及所有任务的合计，统计产生告警的消息，去重合并的告警按每次出现计数
+// @Tags 消息
+// @Produce json
+// @Param start query string false "开始时间(RFC3339 或 Unix 时间戳)"
+// @Param end query string false "结束时间(RFC3339 或 Unix 时间戳)"
+// @Param window query string false "聚合窗口，如1m、5m、15m" default(5m)
+// @Param jobId query int false "任务ID"
+// @Param severity query string false "告警级别：low、medium、high、critical"
+// @Success 200 {object} dao.AlertTrendResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "任务不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/stats/alerts/trend [get]
+func (s *Server) handleAlertTrend(c *gin.Context) {
+	if s.influxQuery == nil || !s.conf.InfluxDB.Enabled {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("influxdb not enabled"))
+		return
+	}
+
+	var req dao.AlertTrendRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	start, end, window, err := parseStatsRange(req.Start, req.End, req.Window)
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var jobUuid string
+	if req.JobId != 0 {
+		job, err := model.GetJobById(req.JobId)
 		if err != nil {
-			s.writeError(c, http.StatusBadRequest, fmt.Errorf("invalid start: %w", err))
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		} else if job == nil || !inRequestOrg(c, job.OrgId) {
+			s.writeError(c, http.StatusNotFound, errors.New("job not found"))
 			return
 		}
-		start = ts.UTC()
+		jobUuid = job.Uuid
 	}
-	if !start.Before(end) {
-		s.writeError(c, http.StatusBadRequest, fmt.Errorf("start must be before end"))
+
+	items, err := s.queryAlertsTrend(c.Request.Context(), jobUuid, req.Severity, start, end, window)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	window := req.Window
-	if window == "" {
-		window = "5m"
+	uuids := make([]string, 0, len(items))
+	for _, item := range items {
+		uuids = append(uuids, item.JobUuid)
 	}
-	if !isValidWindow(window) {
-		s.writeError(c, http.StatusBadRequest, fmt.Errorf("invalid window: %s", window))
+	jobs, err := model.GetJobsByUuids(uuids)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	jobIds := make(map[string]int, len(jobs))
+	for _, job := range jobs {
+		if inRequestOrg(c, job.OrgId) {
+			jobIds[job.Uuid] = job.Id
+		}
+	}
+	// the points are not tagged with the organization, the jobs tell it
+	if requestOrgId(c) != model.AllOrgs {
+		items = slices.DeleteFunc(items, func(item dao.AlertTimeCount) bool {
+			_, ok := jobIds[item.JobUuid]
+			return !ok
+		})
+	}
+
+	type key struct {
+		severity model.AlertSeverity
+		time     string
+	}
+	totals := make(map[key]int64)
+	for i := range items {
+		items[i].JobId = jobIds[items[i].JobUuid]
+		totals[key{items[i].Severity, items[i].Time}] += items[i].Count
+	}
+	resp := dao.AlertTrendResponse{
+		Totals: make([]dao.AlertTimeCount, 0, len(totals)),
+		Items:  items,
+	}
+	for k, count := range totals {
+		resp.Totals = append(resp.Totals, dao.AlertTimeCount{Severity: k.severity, Time: k.time, Count: count})
+	}
+	sort.Slice(resp.Totals, func(i, j int) bool {
+		if resp.Totals[i].Time != resp.Totals[j].Time {
+			return resp.Totals[i].Time < resp.Totals[j].Time
+		}
+		return resp.Totals[i].Severity.Rank() > resp.Totals[j].Severity.Rank()
+	})
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleCameraOccupancy 摄像头区域人数统计
+// @Summary 获取摄像头区域人数统计
+// @Description 从InfluxDB查询摄像头上开启区域人数统计的检测任务上报的各区域人数趋势，包括窗口内最大人数、进出人次和净流入，以及最近一次上报的人数
+// @Tags 摄像头
+// @Accept json
+// @Produce json
+// @Param camera_id path int true "摄像头ID"
+// @Param start query string false "开始时间(RFC3339 或 Unix 时间戳)"
+// @Param end query string false "结束时间(RFC3339 或 Unix 时间戳)"
+// @Param window query string false "聚合窗口，如1m、5m、15m" default(5m)
+// @Param zone query string false "区域名称"
+// @Success 200 {object} dao.CameraOccupancyResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "摄像头不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/camera/{camera_id}/occupancy [get]
+func (s *Server) handleCameraOccupancy(c *gin.Context) {
+	if s.influxQuery == nil || !s.conf.InfluxDB.Enabled {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("influxdb not enabled"))
 		return
 	}
 
-	messages, err := s.queryMessagesTrend(c.Request.Context(), job.Uuid, start, end, window)
+	cam := c.MustGet(cameraKey).(*model.Camera)
+
+	var req dao.OccupancyStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	start, end, window, err := parseStatsRange(req.Start, req.End, req.Window)
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	zones, err := s.queryOccupancyTrend(c.Request.Context(), cam.Id, req.Zone, start, end, window)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
+	c.JSON(http.StatusOK, dao.CameraOccupancyResponse{Zones: zones})
+}
 
-	resp := dao.JobStatsResponse{
-		Messages: messages,
+// handleDeviceStats 设备统计
+// @Summary 获取设备统计
+// @Description 从InfluxDB查询设备上所有任务（包括所在设备组的任务）的消息数量、告警数量和执行器帧率趋势，用于按设备排查算力不足。
+// @Description 设备组任务的消息和告警无法区分设备，包含组内所有设备；帧率只包含本设备
+// @Tags 设备
+// @Accept json
+// @Produce json
+// @Param device_id path int true "设备ID"
+// @Param start query string false "开始时间(RFC3339 或 Unix 时间戳)"
+// @Param end query string false "结束时间(RFC3339 或 Unix 时间戳)"
+// @Param window query string false "聚合窗口，如1m、5m、15m" default(5m)
+// @Success 200 {object} dao.DeviceStatsResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "设备不存在"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/device/{device_id}/stats [get]
+func (s *Server) handleDeviceStats(c *gin.Context) {
+	if s.influxQuery == nil || !s.conf.InfluxDB.Enabled {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("influxdb not enabled"))
+		return
 	}
 
-	if job.Kind == model.JobKindDetect {
-		labels, err := s.queryLabelsTrend(c.Request.Context(), job.Uuid, start, end, window)
+	deviceId, err := strconv.Atoi(c.Param("device_id"))
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	device, err := model.GetDeviceById(deviceId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if device == nil || !inRequestOrg(c, device.OrgId) {
+		s.writeError(c, http.StatusNotFound, errors.New("device not found"))
+		return
+	}
+
+	var req dao.JobStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	start, end, window, err := parseStatsRange(req.Start, req.End, req.Window)
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	jobs, _, err := model.ListJobsByDeviceId(device.Id, 0, -1)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	resp, err := s.queryDeviceStats(c.Request.Context(), device.Id, jobs, start, end, window)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// queryDeviceStats queries the message and alert trends of the jobs and the
+// frame rates of their executors on the device, with a query per metric
+// over all the jobs.
+func (s *Server) queryDeviceStats(ctx context.Context, deviceId int, jobs []model.Job, start, end time.Time, window string) (*dao.DeviceStatsResponse, error) {
+	resp := &dao.DeviceStatsResponse{
+		Messages: []dao.TimeCount{},
+		Alerts:   []dao.TimeCount{},
+		Jobs:     make([]dao.DeviceJobStats, 0, len(jobs)),
+	}
+	if len(jobs) == 0 {
+		return resp, nil
+	}
+
+	byUuid := make(map[string]*dao.DeviceJobStats, len(jobs))
+	uuids := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		resp.Jobs = append(resp.Jobs, dao.DeviceJobStats{
+			JobId:         job.Id,
+			JobUuid:       job.Uuid,
+			Kind:          job.Kind,
+			DeviceGroupId: job.DeviceGroupId,
+			Messages:      []dao.TimeCount{},
+			Alerts:        []dao.TimeCount{},
+			FrameRates:    []dao.FrameRatePoint{},
+		})
+		uuids = append(uuids, fmt.Sprintf(`"%s"`, fluxEscape(job.Uuid)))
+	}
+	for i := range resp.Jobs {
+		byUuid[resp.Jobs[i].JobUuid] = &resp.Jobs[i]
+	}
+
+	base := func(measurement string) string {
+		return fmt.Sprintf(
+			`from(bucket: "%s")
+      |> range(start: time(v: "%s"), stop: time(v: "%s"))
+      |> filter(fn: (r) => r["_measurement"] == "%s")
+      |> filter(fn: (r) => contains(value: r["job_uuid"], set: [%s]))`,
+			s.conf.InfluxDB.Bucket,
+			start.UTC().Format(time.RFC3339),
+			end.UTC().Format(time.RFC3339),
+			measurement,
+			strings.Join(uuids, ", "),
+		)
+	}
+
+	// the messages are counted and the alerted flags summed per job
+	messages := make(map[string]int64)
+	alerts := make(map[string]int64)
+	res, err := s.influxQuery.Query(ctx, base(influxMeasurementMessage)+fmt.Sprintf(`
+      |> filter(fn: (r) => r["_field"] == "count" or r["_field"] == "alerted")
+      |> group(columns: ["job_uuid", "_field"])
+      |> aggregateWindow(every: %s, fn: sum, createEmpty: false)`, window))
+	if err != nil {
+		return nil, fmt.Errorf("query device messages trend: %w", err)
+	}
+	for res.Next() {
+		rec := res.Record()
+		jobUuid, _ := rec.ValueByKey("job_uuid").(string)
+		job, ok := byUuid[jobUuid]
+		count := toInt64(rec.Value())
+		if !ok || count == 0 {
+			continue
+		}
+		t := rec.Time().UTC().Format(time.RFC3339)
+		switch rec.Field() {
+		case "count":
+			job.Messages = append(job.Messages, dao.TimeCount{Time: t, Count: count})
+			messages[t] += count
+		case "alerted":
+			job.Alerts = append(job.Alerts, dao.TimeCount{Time: t, Count: count})
+			alerts[t] += count
+		}
+	}
+	err = res.Err()
+	res.Close()
+	if err != nil {
+		return nil, fmt.Errorf("query device messages trend result error: %v", err)
+	}
+
+	res, err = s.influxQuery.Query(ctx, base(influxMeasurementExecutor)+fmt.Sprintf(`
+      |> filter(fn: (r) => r["device_id"] == "%d")
+      |> filter(fn: (r) => r["_field"] == "frame_rate" or r["_field"] == "frame_rate_limit")
+      |> aggregateWindow(every: %s, fn: mean, createEmpty: false)
+      |> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")`, deviceId, window))
+	if err != nil {
+		return nil, fmt.Errorf("query device frame rates: %w", err)
+	}
+	defer res.Close()
+	for res.Next() {
+		rec := res.Record()
+		jobUuid, _ := rec.ValueByKey("job_uuid").(string)
+		job, ok := byUuid[jobUuid]
+		if !ok {
+			continue
+		}
+		frameRate, _ := rec.ValueByKey("frame_rate").(float64)
+		frameRateLimit, _ := rec.ValueByKey("frame_rate_limit").(float64)
+		job.FrameRates = append(job.FrameRates, dao.FrameRatePoint{
+			Time:           rec.Time().UTC().Format(time.RFC3339),
+			FrameRate:      roundFrameRate(frameRate),
+			FrameRateLimit: roundFrameRate(frameRateLimit),
+		})
+	}
+	if res.Err() != nil {
+		return nil, fmt.Errorf("query device frame rates result error: %v", res.Err())
+	}
+
+	resp.Messages = sortedTimeCounts(messages)
+	resp.Alerts = sortedTimeCounts(alerts)
+	return resp, nil
+}
+
+func sortedTimeCounts(counts map[string]int64) []dao.TimeCount {
+	items := make([]dao.TimeCount, 0, len(counts))
+	for t, count := range counts {
+		items = append(items, dao.TimeCount{Time: t, Count: count})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Time < items[j].Time })
+	return items
+}
+
+// parseStatsRange parses the time range and the aggregation window of a
+// stats query, the range defaults to the last 24 hours and the window to 5m.
+func parseStatsRange(startStr, endStr, window string) (time.Time, time.Time, string, error) {
+	end := time.Now().UTC()
+	if endStr != "" {
+		te, err := dao.ParseTime(endStr)
 		if err != nil {
-			s.writeError(c, http.StatusInternalServerError, err)
-			return
+			return time.Time{}, time.Time{}, "", fmt.Errorf("invalid end: %w", err)
 		}
-		resp.Labels = labels
+		end = te.UTC()
 	}
 
-	c.JSON(http.StatusOK, resp)
+	start := end.Add(-24 * time.Hour)
+	if startStr != "" {
+		ts, err := dao.ParseTime(startStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, "", fmt.Errorf("invalid start: %w", err)
+		}
+		start = ts.UTC()
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, "", fmt.Errorf("start must be before end")
+	}
+
+	if window == "" {
+		window = "5m"
+	}
+	if !isValidWindow(window) {
+		return time.Time{}, time.Time{}, "", fmt.Errorf("invalid window: %s", window)
+	}
+	return start, end, window, nil
 }
 
 func isValidWindow(w string) bool {
@@ -104,6 +453,9 @@ func isValidWindow(w string) bool {
 
 const influxMeasurementMessage = "lumina_message"
 const influxMeasurementDetection = "lumina_detection"
+const influxMeasurementOccupancy = "lumina_occupancy"
+const influxMeasurementExecutor = "lumina_executor"
+const influxMeasurementAlert = "lumina_alert"
 
 func (s *Server) queryMessagesTrend(ctx context.Context, jobUuid string, start, end time.Time, window string) ([]dao.TimeCount, error) {
 	flux := fmt.Sprintf(
@@ -114,8 +466,8 @@ func (s *Server) queryMessagesTrend(ctx context.Context, jobUuid string, start,
       |> filter(fn: (r) => r["_field"] == "count")
       |> aggregateWindow(every: %s, fn: count, createEmpty: false)`,
 		s.conf.InfluxDB.Bucket,
-		start.Format(time.RFC3339),
-		end.Format(time.RFC3339),
+		start.UTC().Format(time.RFC3339),
+		end.UTC().Format(time.RFC3339),
 		influxMeasurementMessage,
 		jobUuid,
 		window,
@@ -150,8 +502,8 @@ func (s *Server) queryLabelsTrend(ctx context.Context, jobUuid string, start, en
       |> aggregateWindow(every: %s, fn: count, createEmpty: false)
       |> group(columns: ["label"])`,
 		s.conf.InfluxDB.Bucket,
-		start.Format(time.RFC3339),
-		end.Format(time.RFC3339),
+		start.UTC().Format(time.RFC3339),
+		end.UTC().Format(time.RFC3339),
 		influxMeasurementDetection,
 		jobUuid,
 		window,
@@ -177,6 +529,219 @@ func (s *Server) queryLabelsTrend(ctx context.Context, jobUuid string, start, en
 	return items, nil
 }
 
+// queryAlertsTrend returns the alerted messages per window, job and
+// severity, of every job if jobUuid is empty and of every severity if
+// severity is.
+func (s *Server) queryAlertsTrend(ctx context.Context, jobUuid string, severity model.AlertSeverity, start, end time.Time, window string) ([]dao.AlertTimeCount, error) {
+	flux := fmt.Sprintf(
+		`from(bucket: "%s")
+      |> range(start: time(v: "%s"), stop: time(v: "%s"))
+      |> filter(fn: (r) => r["_measurement"] == "%s")
+      |> filter(fn: (r) => r["_field"] == "count")`,
+		s.conf.InfluxDB.Bucket,
+		start.UTC().Format(time.RFC3339),
+		end.UTC().Format(time.RFC3339),
+		influxMeasurementAlert,
+	)
+	if jobUuid != "" {
+		flux += fmt.Sprintf(`
+      |> filter(fn: (r) => r["job_uuid"] == "%s")`, fluxEscape(jobUuid))
+	}
+	if severity != "" {
+		flux += fmt.Sprintf(`
+      |> filter(fn: (r) => r["severity"] == "%s")`, fluxEscape(string(severity)))
+	}
+	flux += fmt.Sprintf(`
+      |> group(columns: ["job_uuid", "severity"])
+      |> aggregateWindow(every: %s, fn: sum, createEmpty: false)`, window)
+
+	res, err := s.influxQuery.Query(ctx, flux)
+	if err != nil {
+		return nil, fmt.Errorf("query alerts trend: %w", err)
+	}
+	defer res.Close()
+
+	items := make([]dao.AlertTimeCount, 0, 32)
+	for res.Next() {
+		rec := res.Record()
+		jobUuid, _ := rec.ValueByKey("job_uuid").(string)
+		severity, _ := rec.ValueByKey("severity").(string)
+		items = append(items, dao.AlertTimeCount{
+			JobUuid:  jobUuid,
+			Severity: model.AlertSeverity(severity),
+			Time:     rec.Time().UTC().Format(time.RFC3339),
+			Count:    toInt64(rec.Value()),
+		})
+	}
+	if res.Err() != nil {
+		return nil, fmt.Errorf("query alerts trend result error: %v", res.Err())
+	}
+	return items, nil
+}
+
+// queryOccupancyTrend returns the occupancy of the zones of a camera per
+// window: the max of the counts and the sums of the ins and outs.
+func (s *Server) queryOccupancyTrend(ctx context.Context, cameraId int, zone string, start, end time.Time, window string) ([]dao.ZoneOccupancyTrend, error) {
+	base := fmt.Sprintf(
+		`from(bucket: "%s")
+      |> range(start: time(v: "%s"), stop: time(v: "%s"))
+      |> filter(fn: (r) => r["_measurement"] == "%s")
+      |> filter(fn: (r) => r["camera_id"] == "%d")`,
+		s.conf.InfluxDB.Bucket,
+		start.UTC().Format(time.RFC3339),
+		end.UTC().Format(time.RFC3339),
+		influxMeasurementOccupancy,
+		cameraId,
+	)
+	if zone != "" {
+		base += fmt.Sprintf(`
+      |> filter(fn: (r) => r["zone"] == "%s")`, fluxEscape(zone))
+	}
+
+	trends := make(map[string]*dao.ZoneOccupancyTrend)
+	points := make(map[string]map[string]*dao.OccupancyPoint)
+	trend := func(zone string) *dao.ZoneOccupancyTrend {
+		if _, ok := trends[zone]; !ok {
+			trends[zone] = &dao.ZoneOccupancyTrend{Zone: zone}
+			points[zone] = make(map[string]*dao.OccupancyPoint)
+		}
+		return trends[zone]
+	}
+	point := func(zone, t string) *dao.OccupancyPoint {
+		trend(zone)
+		p, ok := points[zone][t]
+		if !ok {
+			p = &dao.OccupancyPoint{Time: t}
+			points[zone][t] = p
+		}
+		return p
+	}
+
+	queries := []string{
+		base + fmt.Sprintf(`
+      |> filter(fn: (r) => r["_field"] == "max")
+      |> group(columns: ["zone", "_field"])
+      |> aggregateWindow(every: %s, fn: max, createEmpty: false)`, window),
+		base + fmt.Sprintf(`
+      |> filter(fn: (r) => r["_field"] == "in" or r["_field"] == "out")
+      |> group(columns: ["zone", "_field"])
+      |> aggregateWindow(every: %s, fn: sum, createEmpty: false)`, window),
+	}
+	for _, flux := range queries {
+		res, err := s.influxQuery.Query(ctx, flux)
+		if err != nil {
+			return nil, fmt.Errorf("query occupancy trend: %w", err)
+		}
+		for res.Next() {
+			rec := res.Record()
+			zone, _ := rec.ValueByKey("zone").(string)
+			p := point(zone, rec.Time().UTC().Format(time.RFC3339))
+			switch rec.Field() {
+			case "max":
+				p.Max = toInt64(rec.Value())
+			case "in":
+				p.In = toInt64(rec.Value())
+			case "out":
+				p.Out = toInt64(rec.Value())
+			}
+		}
+		err = res.Err()
+		res.Close()
+		if err != nil {
+			return nil, fmt.Errorf("query occupancy trend result error: %v", err)
+		}
+	}
+
+	// the count of the last report is the current occupancy
+	res, err := s.influxQuery.Query(ctx, base+`
+      |> filter(fn: (r) => r["_field"] == "count")
+      |> group(columns: ["zone"])
+      |> last()`)
+	if err != nil {
+		return nil, fmt.Errorf("query current occupancy: %w", err)
+	}
+	defer res.Close()
+	for res.Next() {
+		rec := res.Record()
+		zone, _ := rec.ValueByKey("zone").(string)
+		t := trend(zone)
+		t.Current = toInt64(rec.Value())
+		t.UpdateTime = rec.Time().UTC().Format(time.RFC3339)
+	}
+	if res.Err() != nil {
+		return nil, fmt.Errorf("query current occupancy result error: %v", res.Err())
+	}
+
+	items := make([]dao.ZoneOccupancyTrend, 0, len(trends))
+	for zone, trend := range trends {
+		for _, p := range points[zone] {
+			p.Net = p.In - p.Out
+			trend.Points = append(trend.Points, *p)
+		}
+		sort.Slice(trend.Points, func(i, j int) bool { return trend.Points[i].Time < trend.Points[j].Time })
+		if trend.Points == nil {
+			trend.Points = []dao.OccupancyPoint{}
+		}
+		items = append(items, *trend)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Zone < items[j].Zone })
+	return items, nil
+}
+
+// handleAdminDBStats 数据库连接池状态
+// @Summary 获取数据库连接池状态
+// @Description 获取数据库连接池的连接数、等待次数和饱和度
+// @Tags 用户管理
+// @Produce json
+// @Success 200 {object} dao.DBPoolStats "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/admin/db-stats [get]
+func (s *Server) handleAdminDBStats(c *gin.Context) {
+	stats, err := model.DBStats()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.FromDBStats(stats))
+}
+
+const dbPoolCheckInterval = 30 * time.Second
+
+// monitorDBPool periodically warns when requests had to wait for a database
+// connection, which means the pool is saturated.
+func (s *Server) monitorDBPool(ctx context.Context) {
+	ticker := time.NewTicker(dbPoolCheckInterval)
+	defer ticker.Stop()
+
+	var lastWaitCount int64
+	var lastWaitDuration time.Duration
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		stats, err := model.DBStats()
+		if err != nil {
+			s.logger.WithError(err).Warn("get db stats failed")
+			continue
+		}
+		if stats.WaitCount > lastWaitCount {
+			s.logger.WithField("stats", dao.FromDBStats(stats)).Warnf(
+				"db pool saturated, %d requests waited %s for a connection in the last %s",
+				stats.WaitCount-lastWaitCount, stats.WaitDuration-lastWaitDuration, dbPoolCheckInterval)
+		}
+		lastWaitCount = stats.WaitCount
+		lastWaitDuration = stats.WaitDuration
+	}
+}
+
+// fluxEscape escapes s to be used in a flux string literal.
+func fluxEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(s)
+}
+
 func toInt64(v any) int64 {
 	switch t := v.(type) {
 	case int64:
@@ -199,3 +764,89 @@ func toInt64(v any) int64 {
 		return 0
 	}
 }
+
+const defaultApiUsageLimit = 20
+
+func (s *Server) writeApiUsage(c *gin.Context, summaries []*model.ApiUsageSummary) {
+	resp := dao.ListApiUsageResponse{
+		Items: make([]dao.ApiUsageSpec, 0, len(summaries)),
+	}
+	for _, sum := range summaries {
+		resp.Items = append(resp.Items, *dao.FromApiUsageSummaryModel(sum))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleAdminListApiConsumers 接口调用方排行
+// @Summary 接口调用方排行
+// @Description 按调用次数列出调用接口最多的用户、设备和匿名调用方，及其错误率和耗时，统计按小时汇总并周期写入，需要管理员权限
+// @Tags 用户管理
+// @Produce json
+// @Param start query string false "开始时间，RFC3339，默认 24 小时前"
+// @Param end query string false "结束时间，RFC3339，默认当前时间"
+// @Param limit query int false "返回条数，默认 20"
+// @Success 200 {object} dao.ListApiUsageResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/admin/usage [get]
+func (s *Server) handleAdminListApiConsumers(c *gin.Context) {
+	var req dao.ApiUsageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	start, end, _, err := parseStatsRange(req.Start, req.End, "")
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultApiUsageLimit
+	}
+
+	summaries, err := model.ListTopApiConsumers(start.Truncate(time.Hour), end, req.Limit)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeApiUsage(c, summaries)
+}
+
+// handleAdminListApiEndpointUsage 接口调用统计
+// @Summary 接口调用统计
+// @Description 按调用次数列出接口的调用次数、错误率和耗时，可只统计一个调用方，需要管理员权限
+// @Tags 用户管理
+// @Produce json
+// @Param start query string false "开始时间，RFC3339，默认 24 小时前"
+// @Param end query string false "结束时间，RFC3339，默认当前时间"
+// @Param limit query int false "返回条数，默认 20"
+// @Param consumerKind query string false "调用方类型" Enums(user, device, anonymous)
+// @Param consumer query string false "调用方：用户名、设备 uuid 或 IP"
+// @Success 200 {object} dao.ListApiUsageResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/admin/usage/endpoint [get]
+func (s *Server) handleAdminListApiEndpointUsage(c *gin.Context) {
+	var req dao.ApiEndpointUsageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	start, end, _, err := parseStatsRange(req.Start, req.End, "")
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultApiUsageLimit
+	}
+
+	summaries, err := model.ListApiEndpointUsage(start.Truncate(time.Hour), end, req.ConsumerKind, req.Consumer, req.Limit)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeApiUsage(c, summaries)
+}
@@ -0,0 +1,282 @@
+package server
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const statusFlushInterval = 5 * time.Second
+
+type pendingStatus struct {
+	ping model.DevicePing
+	jobs map[string]dao.DeviceJobStatus
+}
+
+// StatusBuffer keeps the latest status report of every device in memory and
+// writes them to the database in batches, so that a report costs no database
+// work. Devices report their full state periodically, a batch failing to be
+// written is therefore dropped and superseded by the next reports.
+type StatusBuffer struct {
+	mu      sync.Mutex
+	pending map[int]*pendingStatus
+	logger  *logrus.Entry
+	// influxWrite records the frame rates of the executors, nil if InfluxDB
+	// is not enabled
+	influxWrite api.WriteAPIBlocking
+	metrics     *Metrics
+}
+
+func NewStatusBuffer(logger *logrus.Entry, influxWrite api.WriteAPIBlocking, metrics *Metrics) *StatusBuffer {
+	return &StatusBuffer{
+		pending:     make(map[int]*pendingStatus),
+		logger:      logger.WithField("component", "statusBuffer"),
+		influxWrite: influxWrite,
+		metrics:     metrics,
+	}
+}
+
+// Add records a status report of the device, replacing the states of the
+// jobs it reported before the last flush.
+func (b *StatusBuffer) Add(deviceId int, st *dao.DeviceStatus) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p, ok := b.pending[deviceId]
+	if !ok {
+		p = &pendingStatus{jobs: make(map[string]dao.DeviceJobStatus)}
+		b.pending[deviceId] = p
+	}
+	p.ping.Time = time.Now()
+	if st.DiskUsage != nil {
+		p.ping.DiskUsage = st.DiskUsage
+	}
+	if st.Inventory != nil {
+		p.ping.Inventory = st.Inventory
+	}
+	for jobUuid, jobStatus := range st.JobStatus {
+		p.jobs[jobUuid] = jobStatus
+	}
+}
+
+func (b *StatusBuffer) Run(ctx context.Context) {
+	ticker := time.NewTicker(statusFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.Flush()
+		}
+	}
+}
+
+// Flush writes the reports received since the last flush.
+func (b *StatusBuffer) Flush() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[int]*pendingStatus)
+	b.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	pings := make(map[int]model.DevicePing, len(pending))
+	var uuids []string
+	for deviceId, p := range pending {
+		pings[deviceId] = p.ping
+		for jobUuid := range p.jobs {
+			uuids = append(uuids, jobUuid)
+		}
+	}
+	if err := model.UpdateDevicePings(pings); err != nil {
+		b.logger.WithError(err).Errorf("update pings of %d devices failed", len(pings))
+	}
+	if len(uuids) == 0 {
+		return
+	}
+
+	jobs, err := model.GetJobsByUuids(uuids)
+	if err != nil {
+		b.logger.WithError(err).Errorf("get %d reported jobs failed", len(uuids))
+		return
+	}
+	jobsByUuid := make(map[string]*model.Job, len(jobs))
+	for i := range jobs {
+		jobsByUuid[jobs[i].Uuid] = &jobs[i]
+	}
+
+	reported := make(map[int]dao.DeviceJobStatus)
+	transitions := make(map[int]*model.JobStatusHistory)
+	var groupStatus []*model.JobDeviceStatus
+	var points []*write.Point
+	for deviceId, p := range pending {
+		for jobUuid, st := range p.jobs {
+			job, ok := jobsByUuid[jobUuid]
+			if !ok {
+				b.logger.Errorf("job %s reported by device %d not found", jobUuid, deviceId)
+				continue
+			}
+			points = append(points, executorPoint(job, deviceId, st, p.ping.Time))
+			if job.DeviceGroupId != 0 {
+				groupStatus = append(groupStatus, &model.JobDeviceStatus{
+					JobId:          job.Id,
+					DeviceId:       deviceId,
+					Status:         st.ExectorStatus,
+					RestartCount:   st.RestartCount,
+					LastError:      st.LastError,
+					HealthReason:   st.HealthReason,
+					FrameRate:      st.FrameRate,
+					FrameRateLimit: st.FrameRateLimit,
+				})
+				// the frame rates of a group job are kept per device
+				st.FrameRate, st.FrameRateLimit = 0, 0
+			} else if st.ExectorStatus != job.Status {
+				transitions[job.Id] = deviceTransition(job.Id, deviceId, job.Status, st)
+			}
+			reported[job.Id] = st
+		}
+	}
+
+	b.writeExecutorPoints(points)
+
+	var history []*model.JobStatusHistory
+	if len(groupStatus) > 0 {
+		prev, err := b.groupJobStatuses(groupStatus)
+		if err != nil {
+			b.logger.WithError(err).Errorf("get status of %d group jobs failed", len(groupStatus))
+		}
+		// a group job runs on several devices, the job keeps the state over
+		// all of them: running if it runs on any device, otherwise the state
+		// last reported
+		running, err := model.SetJobDeviceStatuses(groupStatus)
+		if err != nil {
+			b.logger.WithError(err).Errorf("update status of %d group jobs failed", len(groupStatus))
+			for _, st := range groupStatus {
+				delete(reported, st.JobId)
+			}
+		} else if prev != nil {
+			// the transitions of a group job are recorded per device
+			for _, st := range groupStatus {
+				if p := prev[[2]int{st.JobId, st.DeviceId}]; p != st.Status {
+					history = append(history, deviceTransition(st.JobId, st.DeviceId, p, dao.DeviceJobStatus{
+						ExectorStatus: st.Status,
+						LastError:     st.LastError,
+						HealthReason:  st.HealthReason,
+					}))
+				}
+			}
+		}
+		for jobId := range running {
+			st := reported[jobId]
+			st.ExectorStatus = model.ExectorStatusRunning
+			reported[jobId] = st
+		}
+	}
+
+	for _, job := range jobsByUuid {
+		st, ok := reported[job.Id]
+		if !ok {
+			continue
+		}
+		rt := model.JobRuntime{
+			Status:         st.ExectorStatus,
+			RestartCount:   st.RestartCount,
+			LastError:      st.LastError,
+			HealthReason:   st.HealthReason,
+			FrameRate:      roundFrameRate(st.FrameRate),
+			FrameRateLimit: roundFrameRate(st.FrameRateLimit),
+		}
+		if job.Runtime() == rt {
+			continue
+		}
+		if err := model.UpdateJobRuntime(job.Id, rt); err != nil {
+			b.logger.WithError(err).Errorf("update job %s failed", job.Uuid)
+		} else if h, ok := transitions[job.Id]; ok {
+			history = append(history, h)
+		}
+	}
+
+	if err := model.AddJobStatusHistory(history); err != nil {
+		b.logger.WithError(err).Errorf("record %d job status transitions failed", len(history))
+	}
+}
+
+// groupJobStatuses returns the statuses recorded for the group jobs on their
+// devices before sts, keyed by job and device id. A device without any is
+// stopped.
+func (b *StatusBuffer) groupJobStatuses(sts []*model.JobDeviceStatus) (map[[2]int]model.ExectorStatus, error) {
+	jobIds := make([]int, 0, len(sts))
+	for _, st := range sts {
+		jobIds = append(jobIds, st.JobId)
+	}
+	prev, err := model.GetJobDeviceStatuses(jobIds)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[[2]int]model.ExectorStatus, len(prev))
+	for _, p := range prev {
+		res[[2]int{p.JobId, p.DeviceId}] = p.Status
+	}
+	return res, nil
+}
+
+// executorPoint records the frame rates of a job on a device, the jobs of a
+// group being run by several devices.
+func executorPoint(job *model.Job, deviceId int, st dao.DeviceJobStatus, t time.Time) *write.Point {
+	return influxdb2.NewPoint(influxMeasurementExecutor,
+		map[string]string{
+			"job_uuid":  job.Uuid,
+			"device_id": strconv.Itoa(deviceId),
+		},
+		map[string]any{
+			"frame_rate":       st.FrameRate,
+			"frame_rate_limit": st.FrameRateLimit,
+			"restart_count":    st.RestartCount,
+		},
+		t)
+}
+
+func (b *StatusBuffer) writeExecutorPoints(points []*write.Point) {
+	if b.influxWrite == nil || len(points) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), statusFlushInterval)
+	defer cancel()
+	if err := b.influxWrite.WritePoint(ctx, points...); err != nil {
+		b.logger.WithError(err).Warnf("write %d executor points to InfluxDB failed", len(points))
+		b.metrics.countWriteFailure(metricsTargetInflux)
+	}
+}
+
+func deviceTransition(jobId, deviceId int, prev model.ExectorStatus, st dao.DeviceJobStatus) *model.JobStatusHistory {
+	reason := st.HealthReason
+	if st.ExectorStatus == model.ExectorStatusFailed && st.LastError != "" {
+		reason = st.LastError
+	}
+	return &model.JobStatusHistory{
+		JobId:      jobId,
+		Source:     model.JobStatusSourceDevice,
+		Status:     st.ExectorStatus.String(),
+		PrevStatus: prev.String(),
+		DeviceId:   deviceId,
+		Reason:     reason,
+	}
+}
+
+// roundFrameRate keeps one decimal, so that the jitter of the measured rate
+// does not rewrite the job on every report.
+func roundFrameRate(fps float64) float64 {
+	return math.Round(fps*10) / 10
+}
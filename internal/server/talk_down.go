@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+var (
+	errNoTalkDownDevice  = errors.New("job has no device to play the talk-down")
+	errNoTalkDownContent = errors.New("talk-down has neither text nor audio")
+)
+
+// TalkDowner asks the device of a job to play the talk-down of the job
+// through the site speaker when the job raises an alert.
+type TalkDowner struct {
+	logger *logrus.Entry
+}
+
+func NewTalkDowner(logger *logrus.Entry) *TalkDowner {
+	return &TalkDowner{
+		logger: logger.WithField("component", "talkDowner"),
+	}
+}
+
+func (t *TalkDowner) Run(ctx context.Context, hub *AlertHub) {
+	sub := hub.subscribe(dao.AlertEventFilter{})
+	defer hub.unsubscribe(sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-sub.ch:
+			if err := t.talkDown(ctx, event); err != nil {
+				t.logger.WithError(err).Errorf("talk-down for alert %d failed", event.AlertId)
+			}
+		}
+	}
+}
+
+func (t *TalkDowner) talkDown(ctx context.Context, event *dao.AlertEvent) error {
+	job, err := model.GetJobById(event.Message.JobId)
+	if err != nil {
+		return err
+	} else if job == nil || job.TalkDown == nil || !job.TalkDown.Enabled {
+		return nil
+	}
+
+	ok, err := model.AcquireTalkDownCooldown(ctx, job.Uuid, time.Duration(job.TalkDown.Cooldown)*time.Second)
+	if err != nil {
+		return err
+	} else if !ok {
+		return nil
+	}
+
+	task, deviceUuid, err := newTalkDownTask(job, job.TalkDown, event.Message.Id, time.Minute)
+	if err != nil {
+		return err
+	}
+	if err := model.AddTalkDownTask(ctx, deviceUuid, task); err != nil {
+		return err
+	}
+	t.logger.Infof("talk-down task %s of job %s queued to device %s", task.TaskUuid, job.Uuid, deviceUuid)
+	return nil
+}
+
+// newTalkDownTask builds a talk-down task of opts for the device running
+// job, or the device the camera of job is bound to.
+func newTalkDownTask(job *model.Job, opts *model.TalkDownOptions, messageId int, expire time.Duration) (*model.TalkDownTask, string, error) {
+	if opts.Text == "" && opts.AudioUrl == "" {
+		return nil, "", errNoTalkDownContent
+	}
+
+	cam, err := model.GetCameraById(job.CameraId)
+	if err != nil {
+		return nil, "", err
+	} else if cam == nil {
+		return nil, "", errors.New("camera of job not found")
+	}
+	camSpec, err := dao.FromCameraModel(cam)
+	if err != nil {
+		return nil, "", err
+	}
+
+	device, err := job.Device()
+	if err != nil {
+		return nil, "", err
+	}
+	if device == nil {
+		if device, err = cam.BindDevice(); err != nil {
+			return nil, "", err
+		}
+	}
+	if device == nil {
+		return nil, "", errNoTalkDownDevice
+	}
+
+	output := opts.Output
+	if output == "" {
+		output = model.TalkDownOutputOnvif
+	}
+	task := &model.TalkDownTask{
+		TaskUuid:   uuid.New().String(),
+		MessageId:  messageId,
+		JobUuid:    job.Uuid,
+		PullAddr:   camSpec.Url(),
+		Text:       opts.Text,
+		AudioUrl:   opts.AudioUrl,
+		Output:     output,
+		ExpireTime: time.Now().Add(expire),
+	}
+	return task, device.Uuid, nil
+}
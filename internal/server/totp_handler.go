@@ -0,0 +1,268 @@
+package server
+
+import (
+	goerrors "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+	"lumina/pkg/str"
+	"lumina/pkg/totp"
+)
+
+const (
+	// totpSkew is the number of time steps accepted before and after the
+	// current one, for the clock drift of the phones
+	totpSkew = 1
+	// recoveryCodeCount is the number of recovery codes issued at once
+	recoveryCodeCount = 10
+	// requireTwoFactorKey marks the requests whose admins must have enabled
+	// the two-factor authentication
+	requireTwoFactorKey = "requireTwoFactor"
+)
+
+// RequireAdminTwoFactor makes NeedAuth refuse the admin endpoints to the
+// admins without two-factor authentication.
+func RequireAdminTwoFactor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(requireTwoFactorKey, true)
+		c.Next()
+	}
+}
+
+// genRecoveryCodes returns new recovery codes, as shown to the user, and
+// their hashes, as stored.
+func genRecoveryCodes() ([]string, []string) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code := str.RandStr(10, str.LowerAlphabet+str.Numerals)
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = model.HashRecoveryCode(codes[i])
+	}
+	return codes, hashes
+}
+
+// verifySecondFactor checks a TOTP code, or else a recovery code, of the
+// user, the codes are used up by the check.
+func (s *Server) verifySecondFactor(user *model.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		if _, err := strconv.Atoi(code); err == nil {
+			counter, ok := totp.Validate(user.TotpSecret, code, time.Now(), totpSkew)
+			if !ok {
+				return false, nil
+			}
+			return model.UseUserTotpCounter(user.Id, counter)
+		}
+	}
+	return model.UseUserTotpRecoveryCode(user.Id, code)
+}
+
+// @Summary 开始启用两步验证
+// @Description 为当前用户生成新的两步验证密钥，用验证器应用扫描二维码后调用启用接口确认
+// @Tags 用户管理
+// @Produce json
+// @Success 200 {object} dao.TotpSetupResponse
+// @Failure 409 {object} ErrorResponse "已启用两步验证"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/settings/totp [post]
+func (s *Server) handleSetupTotp(c *gin.Context) {
+	user := c.MustGet(userKey).(*model.User)
+	if user.TotpEnabled {
+		s.writeError(c, http.StatusConflict, fmt.Errorf("two-factor authentication is already enabled"))
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := model.SetUserTotpSecret(user.Id, secret); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.TotpSetupResponse{
+		Secret: secret,
+		Uri:    totp.URI(s.conf.TwoFactor.Issuer, user.Username, secret),
+	})
+}
+
+// @Summary 启用两步验证
+// @Description 用验证器应用生成的验证码确认密钥并启用两步验证，返回一次性的恢复码
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body dao.TotpCodeRequest true "验证码"
+// @Success 200 {object} dao.TotpRecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse "验证码错误或未开始启用"
+// @Failure 409 {object} ErrorResponse "已启用两步验证"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/settings/totp/enable [post]
+func (s *Server) handleEnableTotp(c *gin.Context) {
+	var req dao.TotpCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	user := c.MustGet(userKey).(*model.User)
+	if user.TotpEnabled {
+		s.writeError(c, http.StatusConflict, fmt.Errorf("two-factor authentication is already enabled"))
+		return
+	}
+	if user.TotpSecret == "" {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("two-factor authentication setup is not started"))
+		return
+	}
+	counter, ok := totp.Validate(user.TotpSecret, req.Code, time.Now(), totpSkew)
+	if !ok {
+		s.writeErrorCode(c, http.StatusBadRequest, errorCodeTotpInvalid, fmt.Errorf("invalid two-factor code"))
+		return
+	}
+
+	codes, hashes := genRecoveryCodes()
+	if err := model.EnableUserTotp(user.Id, counter, hashes); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.logger.Infof("user %s enabled two-factor authentication", user.Username)
+	c.JSON(http.StatusOK, dao.TotpRecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary 重新生成恢复码
+// @Description 生成新的恢复码，原有的恢复码全部失效
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body dao.TotpCodeRequest true "验证码"
+// @Success 200 {object} dao.TotpRecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse "验证码错误或未启用两步验证"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/settings/totp/recovery-codes [post]
+func (s *Server) handleRegenerateRecoveryCodes(c *gin.Context) {
+	var req dao.TotpCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	user := c.MustGet(userKey).(*model.User)
+	if !user.TotpEnabled {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("two-factor authentication is not enabled"))
+		return
+	}
+	ok, err := s.verifySecondFactor(user, req.Code)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		s.writeErrorCode(c, http.StatusBadRequest, errorCodeTotpInvalid, fmt.Errorf("invalid two-factor code"))
+		return
+	}
+
+	codes, hashes := genRecoveryCodes()
+	if err := model.SetUserTotpRecoveryCodes(user.Id, hashes); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, dao.TotpRecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary 停用两步验证
+// @Description 验证当前密码和两步验证码（或恢复码）后停用两步验证
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body dao.DisableTotpRequest true "密码和验证码"
+// @Success 200
+// @Failure 400 {object} ErrorResponse "密码或验证码错误"
+// @Failure 403 {object} ErrorResponse "管理员必须启用两步验证"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/settings/totp/disable [post]
+func (s *Server) handleDisableTotp(c *gin.Context) {
+	var req dao.DisableTotpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	user := c.MustGet(userKey).(*model.User)
+	if !user.TotpEnabled {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("two-factor authentication is not enabled"))
+		return
+	}
+	if user.IsAdmin && s.conf.TwoFactor.RequireForAdmins {
+		s.writeError(c, http.StatusForbidden, fmt.Errorf("two-factor authentication is required for admins"))
+		return
+	}
+	if ok, _ := user.CheckPassword(req.Password); !ok {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("password is incorrect"))
+		return
+	}
+	ok, err := s.verifySecondFactor(user, req.Code)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		s.writeErrorCode(c, http.StatusBadRequest, errorCodeTotpInvalid, fmt.Errorf("invalid two-factor code"))
+		return
+	}
+
+	if err := model.DisableUserTotp(user.Id); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.logger.Infof("user %s disabled two-factor authentication", user.Username)
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary 重置用户两步验证
+// @Description 停用指定用户的两步验证，用于用户丢失验证器和恢复码的情况
+// @Tags 用户管理
+// @Produce json
+// @Param user_id path int true "用户ID"
+// @Success 200
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/user/{user_id}/totp [delete]
+func (s *Server) handleAdminResetTotp(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	user, err := model.GetUserById(userId)
+	if err != nil {
+		if goerrors.Is(err, gorm.ErrRecordNotFound) {
+			s.writeError(c, http.StatusNotFound, err)
+			return
+		}
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !inRequestOrg(c, user.OrgId) {
+		s.writeError(c, http.StatusNotFound, fmt.Errorf("user not found"))
+		return
+	}
+
+	if err := model.DisableUserTotp(user.Id); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	admin := c.MustGet(userKey).(*model.User)
+	s.logger.Infof("admin %s reset the two-factor authentication of user %s", admin.Username, user.Username)
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -6,37 +6,55 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
 	"lumina/internal/dao"
 	"lumina/internal/model"
-	"lumina/internal/version"
+	"lumina/pkg/str"
 )
 
-const userKey = "user"
+const (
+	userKey = "user"
+	// temporaryPasswordLen is the length of the passwords issued by the
+	// admin resets
+	temporaryPasswordLen = 16
+)
+
+// dummyPasswordHash is compared on the logins of the unknown users.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("lumina"), bcrypt.DefaultCost)
 
 type TokenClaims struct {
 	jwt.RegisteredClaims
 	UserId int `json:"user_id"`
+	// SessionId is the login session the token was issued to, empty for
+	// the tokens issued before the sessions
+	SessionId string `json:"sid,omitempty"`
+}
+
+// requestToken returns the token of the request, from the query, the cookie
+// or the Authorization header.
+func requestToken(c *gin.Context) string {
+	tokenStr := c.Query("token")
+	if tokenStr == "" {
+		tokenStr, _ = c.Cookie("token")
+	}
+	if tokenStr == "" {
+		auth := c.GetHeader("Authorization")
+		if auth != "" && len(auth) > 7 && auth[:7] == "Bearer " {
+			tokenStr = auth[7:]
+		}
+	}
+	return tokenStr
 }
 
 func TrySetUserToContext(jwtSecret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenStr := c.Query("token")
-		if tokenStr == "" {
-			tokenStr, _ = c.Cookie("token")
-		}
-		if tokenStr == "" {
-			auth := c.GetHeader("Authorization")
-			if auth != "" && len(auth) > 7 && auth[:7] == "Bearer " {
-				tokenStr = auth[7:]
-			}
-		}
+		tokenStr := requestToken(c)
 		if tokenStr != "" {
 			if strings.HasPrefix(tokenStr, "sk-") {
 				user, userErr := model.GetUserByToken(tokenStr)
@@ -59,6 +77,12 @@ func TrySetUserToContext(jwtSecret string) gin.HandlerFunc {
 				}
 
 				if claims, ok := token.Claims.(*TokenClaims); ok {
+					if tokenDenied(c, claims) {
+						c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+							"error": "token revoked",
+						})
+						return
+					}
 					user, userErr := model.GetUserById(claims.UserId)
 					if userErr != nil {
 						c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
@@ -67,6 +91,7 @@ func TrySetUserToContext(jwtSecret string) gin.HandlerFunc {
 						return
 					}
 					c.Set(userKey, user)
+					c.Set(sessionKey, claims.SessionId)
 				} else {
 					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 						"error": "invalid token claims",
@@ -98,6 +123,13 @@ func NeedAuth(needAdmin bool) gin.HandlerFunc {
 			})
 			return
 		}
+		if needAdmin && c.GetBool(requireTwoFactorKey) && !user.TotpEnabled {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "two-factor authentication is required for admins",
+				"code":  errorCodeTotpEnrollmentRequired,
+			})
+			return
+		}
 		c.Next()
 	}
 }
@@ -109,6 +141,8 @@ func NeedAuth(needAdmin bool) gin.HandlerFunc {
 // @Produce json
 // @Param request body dao.LoginRequest true "请求参数"
 // @Success 200 {object} dao.LoginResponse
+// @Failure 401 {object} ErrorResponse "用户名、密码或两步验证码错误，需要两步验证码时错误码为 totp_required"
+// @Failure 429 {object} ErrorResponse "请求过于频繁"
 // @Router /api/v1/login [post]
 func (s *Server) handleLogin(c *gin.Context) {
 	var req dao.LoginRequest
@@ -120,6 +154,9 @@ func (s *Server) handleLogin(c *gin.Context) {
 	user, err := model.GetUserByUsername(req.Username)
 	if err != nil {
 		if goerrors.Is(err, gorm.ErrRecordNotFound) {
+			// hash anyway, so that the unknown usernames do not answer
+			// faster than the wrong passwords
+			_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(req.Password))
 			s.writeError(c, http.StatusUnauthorized, fmt.Errorf("invalid username or password"))
 			return
 		}
@@ -127,53 +164,77 @@ func (s *Server) handleLogin(c *gin.Context) {
 		return
 	}
 
-	if user.Password != req.Password {
+	ok, legacy := user.CheckPassword(req.Password)
+	if !ok {
 		s.writeError(c, http.StatusUnauthorized, fmt.Errorf("invalid username or password"))
 		return
 	}
+	if legacy {
+		// the plain text passwords are hashed on their first login
+		hash, err := model.HashPassword(req.Password)
+		if err == nil {
+			err = model.UpdateUserPassword(user.Id, hash)
+		}
+		if err != nil {
+			s.logger.WithError(err).Warnf("hash the password of user %s", user.Username)
+		}
+	}
+	if user.TotpEnabled {
+		if req.TotpCode == "" {
+			s.writeErrorCode(c, http.StatusUnauthorized, errorCodeTotpRequired, fmt.Errorf("two-factor code required"))
+			return
+		}
+		ok, err := s.verifySecondFactor(user, req.TotpCode)
+		if err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		}
+		if !ok {
+			s.writeErrorCode(c, http.StatusUnauthorized, errorCodeTotpInvalid, fmt.Errorf("invalid two-factor code"))
+			return
+		}
+	}
 
-	token, err := genJwtToken(user, s.conf.JwtSecret)
+	userSpec, err := dao.ToUserSpec(user)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
-
-	userSpec, err := dao.ToUserSpec(user)
+	tokens, err := s.startSession(c, user)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
 	resp := dao.LoginResponse{
-		Token: token,
-		User:  *userSpec,
+		Token:                  tokens.Token,
+		RefreshToken:           tokens.RefreshToken,
+		ExpiresIn:              tokens.ExpiresIn,
+		User:                   *userSpec,
+		TotpEnrollmentRequired: user.IsAdmin && !user.TotpEnabled && s.conf.TwoFactor.RequireForAdmins,
 	}
-	c.SetCookie("token", token, 7*24*60*60, "/", "", false, true)
 	c.JSON(http.StatusOK, resp)
 }
 
-func genJwtToken(user *model.User, jwtSecret string) (string, error) {
-	claims := TokenClaims{
-		UserId: user.Id,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    version.APP,
-		},
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(jwtSecret))
-}
-
 // @Summary 用户登出
-// @Description 用户登出
+// @Description 用户登出，注销刷新令牌所属的会话，请求携带的登录凭证立即失效
 // @Tags 用户
 // @Accept json
 // @Produce json
+// @Param request body dao.RefreshRequest false "刷新令牌，为空时使用 refresh_token cookie"
 // @Success 200
 // @Router /api/v1/logout [post]
 func (s *Server) handleLogout(c *gin.Context) {
-	c.SetCookie("token", "", -1, "/", "", false, true)
+	if tokenStr := requestRefreshToken(c); tokenStr != "" {
+		session, err := model.GetRefreshTokenByToken(tokenStr)
+		if err == nil && session != nil {
+			_, err = model.RevokeSession(session.UserId, session.SessionId)
+		}
+		if err != nil {
+			s.logger.WithError(err).Warn("revoke session on logout failed")
+		}
+	}
+	s.denyRequestToken(c)
+	clearSessionCookies(c)
 }
 
 // @Summary 获取用户信息
@@ -194,6 +255,82 @@ func (s *Server) handleGetUserProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// @Summary 更新用户信息
+// @Description 更新当前用户的偏好设置，接口返回的时间均为 UTC，显示时区只影响前端展示
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body dao.UpdateProfileRequest true "用户偏好"
+// @Success 200 {object} dao.UserSpec
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/settings/profile [put]
+func (s *Server) handleUpdateUserProfile(c *gin.Context) {
+	var req dao.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	user := c.MustGet(userKey).(*model.User)
+	if err := model.UpdateUserTimezone(user.Id, req.Timezone); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	user.Timezone = req.Timezone
+
+	resp, err := dao.ToUserSpec(user)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary 修改密码
+// @Description 修改当前用户的密码，需要验证当前密码
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body dao.ChangePasswordRequest true "新旧密码"
+// @Success 200
+// @Failure 400 {object} ErrorResponse "请求参数错误或当前密码错误"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/settings/password [put]
+func (s *Server) handleChangePassword(c *gin.Context) {
+	var req dao.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	user := c.MustGet(userKey).(*model.User)
+	if ok, _ := user.CheckPassword(req.OldPassword); !ok {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("old password is incorrect"))
+		return
+	}
+	if req.NewPassword == req.OldPassword {
+		s.writeError(c, http.StatusBadRequest, fmt.Errorf("new password must differ from the old one"))
+		return
+	}
+
+	hash, err := model.HashPassword(req.NewPassword)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := model.UpdateUserPassword(user.Id, hash); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	// the other sessions may be those of whoever knew the old password
+	if err := model.RevokeUserSessions(user.Id, c.GetString(sessionKey)); err != nil {
+		s.logger.WithError(err).Warnf("revoke the sessions of user %s", user.Username)
+	}
+	s.logger.Infof("user %s changed the password", user.Username)
+	c.JSON(http.StatusOK, gin.H{})
+}
+
 // @Summary 获取用户列表
 // @Description 获取用户列表
 // @Tags 用户管理
@@ -218,13 +355,13 @@ func (s *Server) handleAdminListUsers(c *gin.Context) {
 		req.Start = 0
 	}
 
-	total, err := model.CountUsers()
+	total, err := model.CountUsers(requestOrgId(c))
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	users, err := model.GetUsers(req.Start, req.Limit)
+	users, err := model.GetUsers(requestOrgId(c), req.Start, req.Limit)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -264,13 +401,38 @@ func (s *Server) handleAdminCreateUsers(c *gin.Context) {
 		s.writeError(c, http.StatusBadRequest, fmt.Errorf("departmentId is required"))
 		return
 	}
+	orgId, ok := s.ownerOrgId(c)
+	if !ok {
+		return
+	}
+	if req.OrgId != 0 && req.OrgId != orgId {
+		if orgId != model.DefaultOrgId {
+			s.writeError(c, http.StatusForbidden, fmt.Errorf("only the default organization creates the users of the other organizations"))
+			return
+		}
+		org, err := model.GetOrganizationById(req.OrgId)
+		if err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		} else if org == nil {
+			s.writeError(c, http.StatusBadRequest, fmt.Errorf("organization %d not found", req.OrgId))
+			return
+		}
+		orgId = req.OrgId
+	}
+	hash, err := model.HashPassword(req.Password)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
 	token := "sk-" + strings.ReplaceAll(uuid.New().String(), "-", "")
 	user := &model.User{
 		Username:    req.Username,
-		Password:    req.Password,
+		Password:    hash,
 		Nickname:    req.Nickname,
 		IsAdmin:     req.IsAdmin,
 		AccessToken: token,
+		OrgId:       orgId,
 	}
 	if err := model.CreateUser(user); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
@@ -282,6 +444,55 @@ func (s *Server) handleAdminCreateUsers(c *gin.Context) {
 	})
 }
 
+// @Summary 重置用户密码
+// @Description 为指定用户生成临时密码，临时密码只在响应中返回一次
+// @Tags 用户管理
+// @Produce json
+// @Param user_id path int true "用户ID"
+// @Success 200 {object} dao.ResetPasswordResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/user/{user_id}/password/reset [post]
+func (s *Server) handleAdminResetPassword(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	user, err := model.GetUserById(userId)
+	if err != nil {
+		if goerrors.Is(err, gorm.ErrRecordNotFound) {
+			s.writeError(c, http.StatusNotFound, err)
+			return
+		}
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !inRequestOrg(c, user.OrgId) {
+		s.writeError(c, http.StatusNotFound, fmt.Errorf("user not found"))
+		return
+	}
+
+	password := str.RandStr(temporaryPasswordLen, str.Alphanumeric)
+	hash, err := model.HashPassword(password)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := model.UpdateUserPassword(user.Id, hash); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := s.logoutUser(c, user.Id); err != nil {
+		s.logger.WithError(err).Warnf("log out user %s", user.Username)
+	}
+	admin := c.MustGet(userKey).(*model.User)
+	s.logger.Infof("admin %s reset the password of user %s", admin.Username, user.Username)
+	c.JSON(http.StatusOK, dao.ResetPasswordResponse{Password: password})
+}
+
 // @Summary 删除用户
 // @Description 删除指定的用户
 // @Tags 用户管理
@@ -307,6 +518,10 @@ func (s *Server) handleAdminDeleteUser(c *gin.Context) {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
 	}
+	if !inRequestOrg(c, user.OrgId) {
+		s.writeError(c, http.StatusNotFound, fmt.Errorf("user not found"))
+		return
+	}
 
 	if err := model.DeleteUser(user.Id); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Codes of the error responses, for the clients to tell errors apart
+// without parsing the messages.
+const (
+	errorCodeBadRequest      = "bad_request"
+	errorCodeValidation      = "validation_failed"
+	errorCodeInvalidJSON     = "invalid_json"
+	errorCodeUnauthorized    = "unauthorized"
+	errorCodeForbidden       = "forbidden"
+	errorCodeNotFound        = "not_found"
+	errorCodeConflict        = "conflict"
+	errorCodeTooManyRequests = "too_many_requests"
+	errorCodeInternal        = "internal_error"
+	errorCodeUnavailable     = "service_unavailable"
+	// the login needs the two-factor code, or the one given is wrong
+	errorCodeTotpRequired = "totp_required"
+	errorCodeTotpInvalid  = "totp_invalid"
+	// the admin must enroll in the two-factor authentication first
+	errorCodeTotpEnrollmentRequired = "totp_enrollment_required"
+	// the API token has expired, or its scopes don't allow the request
+	errorCodeTokenExpired      = "token_expired"
+	errorCodeInsufficientScope = "insufficient_scope"
+	// the config file cannot be reloaded, the server keeps its config
+	errorCodeInvalidConfig = "invalid_config"
+	// the request needs a capability the database lacks, e.g. on SQLite
+	errorCodeUnsupportedByDatabase = "unsupported_by_database"
+)
+
+// FieldError is a field of the request failing validation.
+type FieldError struct {
+	// 字段路径，与请求 JSON 或查询参数的名称一致，如 channelIds[0]
+	Field string `json:"field"`
+	// 失败的校验规则，如 required、max、oneof，类型错误为 type
+	Code string `json:"code"`
+	// 错误说明
+	Message string `json:"message"`
+}
+
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return errorCodeBadRequest
+	case http.StatusUnauthorized:
+		return errorCodeUnauthorized
+	case http.StatusForbidden:
+		return errorCodeForbidden
+	case http.StatusNotFound:
+		return errorCodeNotFound
+	case http.StatusConflict:
+		return errorCodeConflict
+	case http.StatusTooManyRequests:
+		return errorCodeTooManyRequests
+	case http.StatusServiceUnavailable:
+		return errorCodeUnavailable
+	}
+	if status >= http.StatusInternalServerError {
+		return errorCodeInternal
+	}
+	return errorCodeBadRequest
+}
+
+// bindingErrorResponse returns the response of a request failing to bind,
+// nil if err is not a binding error.
+func bindingErrorResponse(err error) *ErrorResponse {
+	var validationErrs validator.ValidationErrors
+	if goerrors.As(err, &validationErrs) {
+		resp := &ErrorResponse{Code: errorCodeValidation}
+		msgs := make([]string, 0, len(validationErrs))
+		for _, e := range validationErrs {
+			fe := FieldError{
+				Field:   fieldPath(e),
+				Code:    e.Tag(),
+				Message: validationMessage(e),
+			}
+			resp.Fields = append(resp.Fields, fe)
+			msgs = append(msgs, fe.Field+" "+fe.Message)
+		}
+		resp.Error = strings.Join(msgs, "; ")
+		return resp
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if goerrors.As(err, &typeErr) {
+		fe := FieldError{
+			Field:   typeErr.Field,
+			Code:    "type",
+			Message: "must be " + jsonTypeName(typeErr.Type),
+		}
+		return &ErrorResponse{
+			Error:  fe.Field + " " + fe.Message,
+			Code:   errorCodeValidation,
+			Fields: []FieldError{fe},
+		}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if goerrors.As(err, &syntaxErr) || goerrors.Is(err, io.EOF) || goerrors.Is(err, io.ErrUnexpectedEOF) {
+		return &ErrorResponse{
+			Error: "invalid JSON body",
+			Code:  errorCodeInvalidJSON,
+		}
+	}
+	return nil
+}
+
+// fieldPath returns the path of the field from the request root, the names
+// being those of the json or form tags.
+func fieldPath(e validator.FieldError) string {
+	ns := e.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+func validationMessage(e validator.FieldError) string {
+	param := e.Param()
+	isString := e.Kind() == reflect.String
+	switch e.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters", param)
+		} else if e.Kind() == reflect.Slice || e.Kind() == reflect.Map {
+			return fmt.Sprintf("must have at least %s items", param)
+		}
+		return "must be at least " + param
+	case "max", "lte":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters", param)
+		} else if e.Kind() == reflect.Slice || e.Kind() == reflect.Map {
+			return fmt.Sprintf("must have at most %s items", param)
+		}
+		return "must be at most " + param
+	case "gt":
+		return "must be greater than " + param
+	case "lt":
+		return "must be less than " + param
+	case "len":
+		return "must have a length of " + param
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "email":
+		return "must be an email address"
+	case "url", "http_url":
+		return "must be a URL"
+	case "uuid", "uuid4":
+		return "must be a UUID"
+	case "ip", "ipv4", "ipv6":
+		return "must be an IP address"
+	case "password":
+		return "may only contain letters, digits and !@#$%^*+()"
+	case "timestamp":
+		return "must be a RFC 3339 time"
+	case "timezone":
+		return "must be an IANA time zone, e.g. Asia/Shanghai"
+	}
+	if param != "" {
+		return fmt.Sprintf("fails the %s=%s rule", e.Tag(), param)
+	}
+	return fmt.Sprintf("fails the %s rule", e.Tag())
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "a " + t.String()
+}
+
+// requestFieldName names the fields after their json tag, or their form tag
+// for the query parameters, in the validation errors.
+func requestFieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name == "-" {
+			break
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
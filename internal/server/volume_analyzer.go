@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const (
+	volumeCheckInterval = time.Minute
+	// volumeGrace lets the consumer store the messages of an hour before it
+	// is analyzed
+	volumeGrace = 5 * time.Minute
+	// volumeAlpha is the weight of the latest week in the learnt volume of
+	// an hour
+	volumeAlpha = 0.3
+	// volumeMinCount keeps quiet jobs from raising events: a drop must be
+	// from at least that many messages, a surge to at least that many
+	volumeMinCount = 5
+	// volumeSurgeFactor is how many times its usual volume a job must
+	// produce for a surge
+	volumeSurgeFactor = 3
+)
+
+// VolumeAnalyzer learns the hourly message and alert volumes of the running
+// jobs for every hour of the week, and raises a health event when the volume
+// of a job deviates sharply from the one learnt: a drop hints at a moved
+// camera or a stuck detector, a surge at a degraded model. The event is
+// resolved after the first hour back to normal.
+type VolumeAnalyzer struct {
+	conf     VolumeAnomalyConfig
+	logger   *logrus.Entry
+	lastHour time.Time
+}
+
+func NewVolumeAnalyzer(logger *logrus.Entry, conf VolumeAnomalyConfig) *VolumeAnalyzer {
+	return &VolumeAnalyzer{
+		conf:   conf,
+		logger: logger.WithField("component", "volumeAnalyzer"),
+	}
+}
+
+func (a *VolumeAnalyzer) Run(ctx context.Context) {
+	ticker := time.NewTicker(volumeCheckInterval)
+	defer ticker.Stop()
+	for {
+		// the last hour over since the grace period
+		t := time.Now().Add(-volumeGrace)
+		hour := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(-time.Hour)
+		if hour.After(a.lastHour) {
+			if err := a.analyze(hour); err != nil {
+				a.logger.WithError(err).Errorf("analyze message volume of %s failed", hour.UTC().Format(time.RFC3339))
+			} else {
+				a.lastHour = hour
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// analyze checks the volumes of the hour starting at start against the ones
+// learnt, then learns them. An hour already learnt, e.g. before a restart, is
+// skipped.
+func (a *VolumeAnalyzer) analyze(start time.Time) error {
+	end := start.Add(time.Hour)
+	jobs, err := model.ListRunningJobs()
+	if err != nil {
+		return err
+	}
+	messages, alerts, err := model.CountMessagesByJob(start, end)
+	if err != nil {
+		return err
+	}
+	hourOfWeek := model.HourOfWeek(start)
+	baselines, err := model.GetVolumeBaselines(hourOfWeek)
+	if err != nil {
+		return err
+	}
+
+	var learnt []*model.VolumeBaseline
+	for i := range jobs {
+		job := &jobs[i]
+		// a job that did not run for the whole hour says nothing about it
+		if job.Paused || job.CreateTime.After(start) ||
+			!dao.ScheduleArmed(job.Schedule, start) || !dao.ScheduleArmed(job.Schedule, end.Add(-time.Minute)) {
+			continue
+		}
+		b, ok := baselines[job.Id]
+		if !ok {
+			b = &model.VolumeBaseline{JobId: job.Id, HourOfWeek: hourOfWeek}
+		} else if !b.LastHour.Before(start) {
+			continue
+		}
+
+		observedMessages := float64(messages[job.Id])
+		observedAlerts := float64(alerts[job.Id])
+		if b.Samples >= a.conf.MinSamples {
+			a.check(job, model.HealthEventMessageVolume, "messages", observedMessages, b.Messages, b.MessagesVar, start, end)
+			a.check(job, model.HealthEventAlertVolume, "alerts", observedAlerts, b.Alerts, b.AlertsVar, start, end)
+		}
+		learnVolume(&b.Messages, &b.MessagesVar, observedMessages, b.Samples)
+		learnVolume(&b.Alerts, &b.AlertsVar, observedAlerts, b.Samples)
+		b.Samples++
+		b.LastHour = start
+		learnt = append(learnt, b)
+	}
+	return model.SaveVolumeBaselines(learnt)
+}
+
+// learnVolume updates the exponentially weighted mean and variance with the
+// count of an hour.
+func learnVolume(mean, variance *float64, count float64, samples int) {
+	if samples == 0 {
+		*mean, *variance = count, 0
+		return
+	}
+	diff := count - *mean
+	*mean += volumeAlpha * diff
+	*variance = (1 - volumeAlpha) * (*variance + volumeAlpha*diff*diff)
+}
+
+// volumeScore returns how many standard deviations count is from the mean.
+// The deviation is floored so that a job whose volume barely varied over the
+// weeks learnt does not raise events for small changes.
+func volumeScore(count, mean, variance float64) float64 {
+	sd := max(math.Sqrt(variance), math.Sqrt(mean)/2, 1)
+	return (count - mean) / sd
+}
+
+// check opens or updates the event of the kind of the job if the volume of
+// the hour is anomalous, and resolves it otherwise.
+func (a *VolumeAnalyzer) check(job *model.Job, kind model.HealthEventKind, what string,
+	count, mean, variance float64, start, end time.Time) {
+	logger := a.logger.WithField("job", job.Uuid)
+	score := volumeScore(count, mean, variance)
+	var summary string
+	switch {
+	case score <= -a.conf.Threshold && mean >= volumeMinCount:
+		summary = fmt.Sprintf("job %d produced %.0f %s in an hour, %.0f expected: its camera may have moved or its detector may be stuck",
+			job.Id, count, what, mean)
+	case score >= a.conf.Threshold && count >= volumeMinCount && count >= volumeSurgeFactor*mean:
+		summary = fmt.Sprintf("job %d produced %.0f %s in an hour, %.0f expected: its scene or model may have changed",
+			job.Id, count, what, mean)
+	}
+
+	event, err := model.GetOpenHealthEvent(kind, job.Id)
+	if err != nil {
+		logger.WithError(err).Error("get open health event failed")
+		return
+	}
+	if summary == "" {
+		if event != nil {
+			if err := model.ResolveHealthEvent(event.Id); err != nil {
+				logger.WithError(err).Error("resolve health event failed")
+				return
+			}
+			logger.Infof("%s volume back to normal, health event %d resolved", what, event.Id)
+		}
+		return
+	}
+
+	if event == nil {
+		event = &model.HealthEvent{Kind: kind, JobId: job.Id, StartTime: start}
+		logger.Warn(summary)
+	}
+	event.Summary = summary
+	event.Observed = count
+	event.Expected = mean
+	event.Score = score
+	event.EndTime = end
+	if err := model.SaveHealthEvent(event); err != nil {
+		logger.WithError(err).Error("save health event failed")
+	}
+}
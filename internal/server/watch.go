@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+const (
+	defaultWatchTimeout = 30 * time.Second
+	// watchSettleDelay lets a burst of changes and the transaction that
+	// notified them settle before the watched response is computed again
+	watchSettleDelay = 200 * time.Millisecond
+)
+
+// WatchHub fans the change notifications published on redis out to the
+// watch requests waiting on this server, with a single subscription.
+type WatchHub struct {
+	mu      sync.Mutex
+	waiters map[string]map[chan struct{}]struct{}
+	logger  *logrus.Entry
+}
+
+func NewWatchHub(logger *logrus.Entry) *WatchHub {
+	return &WatchHub{
+		waiters: make(map[string]map[chan struct{}]struct{}),
+		logger:  logger.WithField("component", "watchHub"),
+	}
+}
+
+func (h *WatchHub) Run(ctx context.Context) {
+	pubsub := model.SubscribeWatch(ctx)
+	defer pubsub.Close()
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				h.logger.Warn("watch subscription closed")
+				return
+			}
+			h.notify(model.WatchKey(msg.Channel))
+		}
+	}
+}
+
+// wait returns a channel receiving a value when key is notified, it must be
+// released with the returned func.
+func (h *WatchHub) wait(key string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	if h.waiters[key] == nil {
+		h.waiters[key] = make(map[chan struct{}]struct{})
+	}
+	h.waiters[key][ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.waiters[key], ch)
+		if len(h.waiters[key]) == 0 {
+			delete(h.waiters, key)
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (h *WatchHub) notify(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.waiters[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// watchETag returns a strong ETag of the parts.
+func watchETag(parts ...string) string {
+	h := sha1.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:10]) + `"`
+}
+
+// serveWatched writes the response returned by load along with its ETag, or
+// 304 if it matches If-None-Match. A watch request whose ETag matches waits
+// until key is notified and the response changes, or until the timeout.
+func (s *Server) serveWatched(c *gin.Context, key string, load func(ctx context.Context) (any, string, error)) {
+	var req dao.WatchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	ctx := c.Request.Context()
+	var notified <-chan struct{}
+	if req.Watch && s.watchHub != nil {
+		// waiting before the first load so that no change is missed
+		ch, release := s.watchHub.wait(key)
+		defer release()
+		notified = ch
+	}
+
+	resp, etag, err := load(ctx)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+	match := c.GetHeader("If-None-Match")
+	if notified != nil && etag == match {
+		timeout := defaultWatchTimeout
+		if req.Timeout > 0 {
+			timeout = time.Duration(req.Timeout) * time.Second
+		}
+		deadline := time.NewTimer(timeout)
+		defer deadline.Stop()
+		for etag == match {
+			select {
+			case <-ctx.Done():
+				return
+			case <-deadline.C:
+				c.Header("ETag", etag)
+				c.Status(http.StatusNotModified)
+				return
+			case <-s.draining:
+				// answered as a timeout for the client to watch again
+				c.Header("ETag", etag)
+				c.Status(http.StatusNotModified)
+				return
+			case <-notified:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchSettleDelay):
+			}
+			if resp, etag, err = load(ctx); err != nil {
+				s.writeError(c, http.StatusInternalServerError, err)
+				return
+			}
+		}
+	}
+
+	c.Header("ETag", etag)
+	if etag == match {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/SherClockHolmes/webpush-go"
+	"github.com/sirupsen/logrus"
+
+	"lumina/internal/dao"
+	"lumina/internal/model"
+)
+
+// WebPusher delivers alert events to the browser push subscriptions of the
+// users whose notification preferences accept them.
+type WebPusher struct {
+	conf   WebPushConfig
+	client *http.Client
+	logger *logrus.Entry
+}
+
+func NewWebPusher(logger *logrus.Entry, conf WebPushConfig, client *http.Client) *WebPusher {
+	return &WebPusher{
+		conf:   conf,
+		client: client,
+		logger: logger.WithField("component", "webPusher"),
+	}
+}
+
+func (p *WebPusher) Run(ctx context.Context, hub *AlertHub) {
+	sub := hub.subscribe(dao.AlertEventFilter{})
+	defer hub.unsubscribe(sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-sub.ch:
+			if err := p.push(ctx, event); err != nil {
+				p.logger.WithError(err).Errorf("push alert %d failed", event.AlertId)
+			}
+		}
+	}
+}
+
+func (p *WebPusher) push(ctx context.Context, event *dao.AlertEvent) error {
+	subs, err := model.ListPushSubscriptions(event.OrgId)
+	if err != nil {
+		return err
+	} else if len(subs) == 0 {
+		return nil
+	}
+
+	// only the preferences of the subscribers, a user may have several
+	// subscriptions
+	userIds := make([]int, 0, len(subs))
+	for _, sub := range subs {
+		if !slices.Contains(userIds, sub.UserId) {
+			userIds = append(userIds, sub.UserId)
+		}
+	}
+	prefs, err := model.ListNotificationPreferencesByUserIds(userIds)
+	if err != nil {
+		return err
+	}
+	prefByUser := make(map[int]*model.NotificationPreference, len(prefs))
+	for _, pref := range prefs {
+		prefByUser[pref.UserId] = pref
+	}
+
+	payload, err := json.Marshal(p.payload(event))
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		pref, ok := prefByUser[sub.UserId]
+		if !ok {
+			pref = model.DefaultNotificationPreference(sub.UserId)
+		}
+		if !pref.Accept(event.Message.JobId, event.CameraId, event.Severity) {
+			continue
+		}
+		if err := p.send(ctx, sub, payload); err != nil {
+			p.logger.WithError(err).Warnf("send web push to subscription %d failed", sub.Id)
+		}
+	}
+	return nil
+}
+
+func (p *WebPusher) send(ctx context.Context, sub *model.PushSubscription, payload []byte) error {
+	resp, err := webpush.SendNotificationWithContext(ctx, payload, &webpush.Subscription{
+		Endpoint: sub.Endpoint,
+		Keys: webpush.Keys{
+			Auth:   sub.Auth,
+			P256dh: sub.P256dh,
+		},
+	}, &webpush.Options{
+		HTTPClient:      p.client,
+		Subscriber:      p.conf.Subject,
+		VAPIDPublicKey:  p.conf.VAPIDPublicKey,
+		VAPIDPrivateKey: p.conf.VAPIDPrivateKey,
+		TTL:             p.conf.TTL,
+		Urgency:         webpush.UrgencyHigh,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		// The browser has dropped the subscription, stop pushing to it.
+		return model.DeletePushSubscriptionById(sub.Id)
+	case resp.StatusCode >= 400:
+		return fmt.Errorf("push service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (p *WebPusher) payload(event *dao.AlertEvent) *dao.WebPushPayload {
+	title := "告警"
+	if event.CameraName != "" {
+		title = fmt.Sprintf("告警: %s", event.CameraName)
+	}
+	body := fmt.Sprintf("任务 %d 于 %s 产生告警", event.Message.JobId, event.Message.Timestamp)
+	if resp := event.Message.WorkflowResp; resp != nil && resp.Answer != "" {
+		body = resp.Answer
+	}
+	return &dao.WebPushPayload{
+		Title:   title,
+		Body:    body,
+		Icon:    event.Message.ImagePath,
+		Url:     fmt.Sprintf("%s/message/%d", p.conf.DashboardURL, event.Message.Id),
+		AlertId: event.AlertId,
+	}
+}
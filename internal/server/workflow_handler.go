@@ -2,8 +2,11 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -30,7 +33,12 @@ func (s *Server) handleCreateWorkflow(c *gin.Context) {
 		return
 	}
 
+	orgId, ok := s.ownerOrgId(c)
+	if !ok {
+		return
+	}
 	workflow := req.ToModel()
+	workflow.OrgId = orgId
 	if err := model.CreateWorkflow(workflow); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -68,7 +76,7 @@ func (s *Server) handleGetWorkflow(c *gin.Context) {
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
-	} else if workflow == nil {
+	} else if workflow == nil || !inRequestOrg(c, workflow.OrgId) {
 		s.writeError(c, http.StatusNotFound, errors.New("workflow not found"))
 		return
 	}
@@ -108,7 +116,7 @@ func (s *Server) handleUpdateWorkflow(c *gin.Context) {
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
-	} else if workflow == nil {
+	} else if workflow == nil || !inRequestOrg(c, workflow.OrgId) {
 		s.writeError(c, http.StatusNotFound, errors.New("workflow not found"))
 		return
 	}
@@ -132,6 +140,7 @@ func (s *Server) handleUpdateWorkflow(c *gin.Context) {
 // @Success 200 "删除成功"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 404 {object} ErrorResponse "工作流不存在"
 // @Failure 500 {object} ErrorResponse "内部服务器错误"
 // @Router /api/v1/workflow/{workflow_id} [delete]
 func (s *Server) handleDeleteWorkflow(c *gin.Context) {
@@ -141,6 +150,14 @@ func (s *Server) handleDeleteWorkflow(c *gin.Context) {
 		return
 	}
 
+	workflow, err := model.GetWorkflowById(workflowId)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	} else if workflow == nil || !inRequestOrg(c, workflow.OrgId) {
+		s.writeError(c, http.StatusNotFound, errors.New("workflow not found"))
+		return
+	}
 	if err := model.DeleteWorkflow(workflowId); err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -174,7 +191,7 @@ func (s *Server) handleListWorkflows(c *gin.Context) {
 		req.Limit = 10
 	}
 
-	workflows, total, err := model.ListWorkflows(req.Start, req.Limit)
+	workflows, total, err := model.ListWorkflows(requestOrgId(c), req.Start, req.Limit)
 	if err != nil {
 		s.writeError(c, http.StatusInternalServerError, err)
 		return
@@ -191,3 +208,146 @@ func (s *Server) handleListWorkflows(c *gin.Context) {
 
 	c.JSON(http.StatusOK, resp)
 }
+
+// handleExportWorkflows 导出工作流
+// @Summary 导出工作流
+// @Description 导出工作流配置，不包含API Key
+// @Tags 工作流
+// @Accept json
+// @Produce json
+// @Param ids query string false "工作流ID，逗号分隔，为空时导出全部"
+// @Success 200 {object} dao.WorkflowBundle "导出成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/workflow/export [get]
+func (s *Server) handleExportWorkflows(c *gin.Context) {
+	var req dao.ExportWorkflowRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	ids, err := req.GetIds()
+	if err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	workflows, err := model.GetWorkflowsByIds(ids)
+	if err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	now := time.Now()
+	bundle := dao.WorkflowBundle{
+		Version:    dao.WorkflowBundleVersion,
+		ExportTime: now.UTC().Format(time.RFC3339),
+		Workflows:  make([]dao.WorkflowExportItem, 0, len(workflows)),
+	}
+	for _, wf := range workflows {
+		if !inRequestOrg(c, wf.OrgId) {
+			continue
+		}
+		bundle.Workflows = append(bundle.Workflows, dao.FromWorkflowModelForExport(&wf))
+	}
+
+	c.Header("Content-Disposition",
+		fmt.Sprintf("attachment; filename=workflows-%s.json", now.UTC().Format("20060102T150405Z")))
+	c.JSON(http.StatusOK, bundle)
+}
+
+// handleImportWorkflows 导入工作流
+// @Summary 导入工作流
+// @Description 导入导出的工作流配置，新建的工作流需要重新填写API Key
+// @Tags 工作流
+// @Accept json
+// @Produce json
+// @Param req body dao.ImportWorkflowRequest true "导入工作流请求"
+// @Success 200 {object} dao.ImportWorkflowResponse "导入成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权"
+// @Failure 409 {object} ErrorResponse "工作流属于其他组织"
+// @Failure 500 {object} ErrorResponse "内部服务器错误"
+// @Router /api/v1/workflow/import [post]
+func (s *Server) handleImportWorkflows(c *gin.Context) {
+	var req dao.ImportWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Bundle.Version != dao.WorkflowBundleVersion {
+		s.writeError(c, http.StatusBadRequest,
+			fmt.Errorf("unsupported workflow bundle version %d", req.Bundle.Version))
+		return
+	}
+
+	orgId, ok := s.ownerOrgId(c)
+	if !ok {
+		return
+	}
+
+	resp := dao.ImportWorkflowResponse{
+		Created: []string{},
+		Updated: []string{},
+		Skipped: []string{},
+	}
+	var creates, updates []*model.Workflow
+	var missingKeys []string
+	seen := make(map[string]struct{}, len(req.Bundle.Workflows))
+	for _, item := range req.Bundle.Workflows {
+		if _, ok := seen[item.Uuid]; ok {
+			s.writeError(c, http.StatusBadRequest, fmt.Errorf("duplicate workflow %s in bundle", item.Uuid))
+			return
+		}
+		seen[item.Uuid] = struct{}{}
+
+		key := req.Keys[item.Uuid]
+		existing, err := model.GetWorkflowByUuid(item.Uuid)
+		if err != nil {
+			s.writeError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if existing != nil {
+			// the uuids are global, the workflows of the other organizations
+			// are neither shown nor overwritten
+			if !inRequestOrg(c, existing.OrgId) {
+				s.writeError(c, http.StatusConflict, fmt.Errorf("workflow %s belongs to another organization", item.Uuid))
+				return
+			}
+			if !req.Overwrite {
+				resp.Skipped = append(resp.Skipped, item.Uuid)
+				continue
+			}
+			item.UpdateModel(existing)
+			if key != "" {
+				existing.Key = key
+			}
+			updates = append(updates, existing)
+			resp.Updated = append(resp.Updated, item.Uuid)
+			continue
+		}
+
+		if key == "" {
+			missingKeys = append(missingKeys, item.Uuid)
+			continue
+		}
+		wf := &model.Workflow{Key: key, OrgId: orgId}
+		item.UpdateModel(wf)
+		creates = append(creates, wf)
+		resp.Created = append(resp.Created, item.Uuid)
+	}
+	if len(missingKeys) > 0 {
+		s.writeError(c, http.StatusBadRequest,
+			fmt.Errorf("missing api key for workflows: %s", strings.Join(missingKeys, ", ")))
+		return
+	}
+
+	if err := model.ImportWorkflows(creates, updates); err != nil {
+		s.writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"lumina/internal/dao"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// checkWebSocketOrigin lets the browsers open the sockets from the server
+// origin or the origins listed by CORS, the sockets being authenticated by
+// the token cookie any site would send, so "*" allows none. The clients that
+// are not browsers send no origin.
+func (s *Server) checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return s.conf.CORS.Enabled && slices.Contains(s.conf.CORS.AllowOrigins, origin)
+}
+
+// handleAlertsWebSocket 推送新告警
+// @Summary 推送新告警
+// @Description 通过WebSocket推送新产生的告警消息，可按任务、摄像头或最低告警级别过滤
+// @Tags 消息
+// @Param jobId query int false "任务ID"
+// @Param cameraId query int false "摄像头ID"
+// @Param minSeverity query string false "最低告警级别" Enums(low, medium, high, critical)
+// @Success 101 {object} dao.AlertEvent "告警事件"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Router /api/v1/ws/alerts [get]
+func (s *Server) handleAlertsWebSocket(c *gin.Context) {
+	var filter dao.AlertEventFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		s.writeError(c, http.StatusBadRequest, err)
+		return
+	}
+	filter.OrgId = requestOrgId(c)
+
+	// added before the upgrade, while the shutdown still waits for the request
+	s.hijacked.Add(1)
+	defer s.hijacked.Done()
+	upgrader := wsUpgrader
+	upgrader.CheckOrigin = s.checkWebSocketOrigin
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		s.logger.WithError(err).Warn("upgrade websocket failed")
+		return
+	}
+	defer conn.Close()
+
+	sub := s.alertHub.subscribe(filter)
+	defer s.alertHub.unsubscribe(sub)
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-s.draining:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+			return
+		case event := <-sub.ch:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteJSON(event); err != nil {
+				s.logger.WithError(err).Debug("write alert event failed")
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package heatmap renders the spatial density of points, e.g. the centers of
+// detection boxes, as a colored overlay on a reference image.
+package heatmap
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+const (
+	// gridSize is the number of cells along the longer side of the frame
+	gridSize = 64
+	// blurRadius is the radius of the gaussian blur in cells
+	blurRadius = 2
+	// maxAlpha is the opacity of the hottest cells
+	maxAlpha = 0.65
+	// minValue is the normalized density below which nothing is drawn
+	minValue = 0.02
+)
+
+// Heatmap accumulates points of a width x height frame into a grid.
+type Heatmap struct {
+	width, height int
+	cols, rows    int
+	cell          float64
+	grid          []float64
+	samples       int
+}
+
+func New(width, height int) *Heatmap {
+	cell := float64(max(width, height)) / gridSize
+	cols := max(1, int(math.Ceil(float64(width)/cell)))
+	rows := max(1, int(math.Ceil(float64(height)/cell)))
+	return &Heatmap{
+		width:  width,
+		height: height,
+		cols:   cols,
+		rows:   rows,
+		cell:   cell,
+		grid:   make([]float64, cols*rows),
+	}
+}
+
+// Add adds a point in frame coordinates, points outside the frame are
+// ignored.
+func (h *Heatmap) Add(x, y float64) {
+	if x < 0 || y < 0 || x >= float64(h.width) || y >= float64(h.height) {
+		return
+	}
+	col := min(int(x/h.cell), h.cols-1)
+	row := min(int(y/h.cell), h.rows-1)
+	h.grid[row*h.cols+col]++
+	h.samples++
+}
+
+// Samples returns the number of points added.
+func (h *Heatmap) Samples() int {
+	return h.samples
+}
+
+// Render draws the heatmap over bg, which is scaled to the frame size. A nil
+// bg renders over a dark background.
+func (h *Heatmap) Render(bg image.Image) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, h.width, h.height))
+	if bg == nil {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.RGBA{32, 32, 32, 255}), image.Point{}, draw.Src)
+	} else {
+		scaleTo(dst, bg)
+	}
+
+	density := h.blur()
+	peak := 0.0
+	for _, v := range density {
+		peak = max(peak, v)
+	}
+	if peak == 0 {
+		return dst
+	}
+
+	for y := 0; y < h.height; y++ {
+		for x := 0; x < h.width; x++ {
+			v := h.sample(density, float64(x), float64(y)) / peak
+			if v < minValue {
+				continue
+			}
+			heat := ramp(v)
+			alpha := maxAlpha * math.Min(1, v*1.5)
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = blend(dst.Pix[i], heat.R, alpha)
+			dst.Pix[i+1] = blend(dst.Pix[i+1], heat.G, alpha)
+			dst.Pix[i+2] = blend(dst.Pix[i+2], heat.B, alpha)
+		}
+	}
+	return dst
+}
+
+// blur smooths the grid with a separable gaussian kernel.
+func (h *Heatmap) blur() []float64 {
+	kernel := make([]float64, 2*blurRadius+1)
+	sigma := float64(blurRadius) / 2
+	for i := range kernel {
+		d := float64(i - blurRadius)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+	}
+
+	tmp := make([]float64, len(h.grid))
+	for r := 0; r < h.rows; r++ {
+		for c := 0; c < h.cols; c++ {
+			sum := 0.0
+			for k, w := range kernel {
+				if cc := c + k - blurRadius; cc >= 0 && cc < h.cols {
+					sum += h.grid[r*h.cols+cc] * w
+				}
+			}
+			tmp[r*h.cols+c] = sum
+		}
+	}
+	res := make([]float64, len(h.grid))
+	for r := 0; r < h.rows; r++ {
+		for c := 0; c < h.cols; c++ {
+			sum := 0.0
+			for k, w := range kernel {
+				if rr := r + k - blurRadius; rr >= 0 && rr < h.rows {
+					sum += tmp[rr*h.cols+c] * w
+				}
+			}
+			res[r*h.cols+c] = sum
+		}
+	}
+	return res
+}
+
+// sample interpolates the density at a pixel bilinearly between the cell
+// centers.
+func (h *Heatmap) sample(density []float64, x, y float64) float64 {
+	gx := math.Max(0, (x+0.5)/h.cell-0.5)
+	gy := math.Max(0, (y+0.5)/h.cell-0.5)
+	c0, r0 := min(int(gx), h.cols-1), min(int(gy), h.rows-1)
+	c1, r1 := min(c0+1, h.cols-1), min(r0+1, h.rows-1)
+	fx, fy := gx-float64(c0), gy-float64(r0)
+
+	top := density[r0*h.cols+c0]*(1-fx) + density[r0*h.cols+c1]*fx
+	bottom := density[r1*h.cols+c0]*(1-fx) + density[r1*h.cols+c1]*fx
+	return top*(1-fy) + bottom*fy
+}
+
+// ramp maps v in [0, 1] to blue, cyan, green, yellow and red.
+func ramp(v float64) color.RGBA {
+	stops := []color.RGBA{
+		{0, 0, 255, 255},
+		{0, 255, 255, 255},
+		{0, 255, 0, 255},
+		{255, 255, 0, 255},
+		{255, 0, 0, 255},
+	}
+	pos := math.Min(v, 1) * float64(len(stops)-1)
+	i := min(int(pos), len(stops)-2)
+	f := pos - float64(i)
+	a, b := stops[i], stops[i+1]
+	return color.RGBA{
+		R: uint8(float64(a.R)*(1-f) + float64(b.R)*f),
+		G: uint8(float64(a.G)*(1-f) + float64(b.G)*f),
+		B: uint8(float64(a.B)*(1-f) + float64(b.B)*f),
+		A: 255,
+	}
+}
+
+func blend(dst, src uint8, alpha float64) uint8 {
+	return uint8(float64(dst)*(1-alpha) + float64(src)*alpha)
+}
+
+// scaleTo draws src scaled to the bounds of dst with nearest neighbor
+// sampling.
+func scaleTo(dst *image.RGBA, src image.Image) {
+	sb := src.Bounds()
+	db := dst.Bounds()
+	for y := 0; y < db.Dy(); y++ {
+		sy := sb.Min.Y + y*sb.Dy()/db.Dy()
+		for x := 0; x < db.Dx(); x++ {
+			sx := sb.Min.X + x*sb.Dx()/db.Dx()
+			dst.Set(x, y, src.At(sx, sy))
+		}
+	}
+}
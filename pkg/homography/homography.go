@@ -0,0 +1,192 @@
+// Package homography estimates the planar projective mapping between image
+// pixels and world coordinates, e.g. from points of known position on the
+// ground, and applies it.
+package homography
+
+import (
+	"errors"
+	"math"
+)
+
+// MinPoints is the number of correspondences needed to solve a homography.
+const MinPoints = 4
+
+var ErrDegenerate = errors.New("points are degenerate, at least 4 of them must not be collinear")
+
+// Matrix is a 3x3 homography in row-major order.
+type Matrix [9]float64
+
+// Apply maps the point (x, y), ok is false if the point maps to infinity.
+func (m Matrix) Apply(x, y float64) (float64, float64, bool) {
+	w := m[6]*x + m[7]*y + m[8]
+	if math.Abs(w) < 1e-12 {
+		return 0, 0, false
+	}
+	return (m[0]*x + m[1]*y + m[2]) / w, (m[3]*x + m[4]*y + m[5]) / w, true
+}
+
+// Solve returns the homography mapping src[i] to dst[i], in the least squares
+// sense when there are more than 4 points. The points are normalized first
+// to keep the system well conditioned with pixel coordinates.
+func Solve(src, dst [][2]float64) (Matrix, error) {
+	if len(src) != len(dst) {
+		return Matrix{}, errors.New("src and dst have different lengths")
+	}
+	if len(src) < MinPoints {
+		return Matrix{}, errors.New("at least 4 points are required")
+	}
+
+	ts, ok1 := normalization(src)
+	td, ok2 := normalization(dst)
+	if !ok1 || !ok2 {
+		return Matrix{}, ErrDegenerate
+	}
+
+	// h33 is fixed to 1, each correspondence gives two rows of ata and atb
+	var ata [8][8]float64
+	var atb [8]float64
+	for i := range src {
+		x, y, _ := ts.Apply(src[i][0], src[i][1])
+		u, v, _ := td.Apply(dst[i][0], dst[i][1])
+		rows := [2][8]float64{
+			{x, y, 1, 0, 0, 0, -u * x, -u * y},
+			{0, 0, 0, x, y, 1, -v * x, -v * y},
+		}
+		rhs := [2]float64{u, v}
+		for r := range rows {
+			for j := 0; j < 8; j++ {
+				atb[j] += rows[r][j] * rhs[r]
+				for k := 0; k < 8; k++ {
+					ata[j][k] += rows[r][j] * rows[r][k]
+				}
+			}
+		}
+	}
+
+	h, ok := solveLinear(ata, atb)
+	if !ok {
+		return Matrix{}, ErrDegenerate
+	}
+	hn := Matrix{h[0], h[1], h[2], h[3], h[4], h[5], h[6], h[7], 1}
+
+	inv, ok := td.inverse()
+	if !ok {
+		return Matrix{}, ErrDegenerate
+	}
+	m := inv.mul(hn).mul(ts)
+	if math.Abs(m[8]) < 1e-12 {
+		return Matrix{}, ErrDegenerate
+	}
+	for i := range m {
+		m[i] /= m[8]
+	}
+	return m, nil
+}
+
+// ReprojectionError returns the mean distance between m applied to src and
+// dst, in the units of dst.
+func ReprojectionError(m Matrix, src, dst [][2]float64) float64 {
+	if len(src) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for i := range src {
+		x, y, ok := m.Apply(src[i][0], src[i][1])
+		if !ok {
+			return math.Inf(1)
+		}
+		sum += math.Hypot(x-dst[i][0], y-dst[i][1])
+	}
+	return sum / float64(len(src))
+}
+
+// normalization returns the similarity moving the centroid of the points to
+// the origin and their mean distance to it to sqrt(2).
+func normalization(points [][2]float64) (Matrix, bool) {
+	cx, cy := 0.0, 0.0
+	for _, p := range points {
+		cx += p[0]
+		cy += p[1]
+	}
+	n := float64(len(points))
+	cx, cy = cx/n, cy/n
+
+	dist := 0.0
+	for _, p := range points {
+		dist += math.Hypot(p[0]-cx, p[1]-cy)
+	}
+	dist /= n
+	if dist < 1e-12 {
+		return Matrix{}, false
+	}
+	s := math.Sqrt2 / dist
+	return Matrix{s, 0, -s * cx, 0, s, -s * cy, 0, 0, 1}, true
+}
+
+func (m Matrix) mul(o Matrix) Matrix {
+	var r Matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[i*3+j] += m[i*3+k] * o[k*3+j]
+			}
+		}
+	}
+	return r
+}
+
+func (m Matrix) inverse() (Matrix, bool) {
+	det := m[0]*(m[4]*m[8]-m[5]*m[7]) -
+		m[1]*(m[3]*m[8]-m[5]*m[6]) +
+		m[2]*(m[3]*m[7]-m[4]*m[6])
+	if math.Abs(det) < 1e-12 {
+		return Matrix{}, false
+	}
+	return Matrix{
+		(m[4]*m[8] - m[5]*m[7]) / det,
+		(m[2]*m[7] - m[1]*m[8]) / det,
+		(m[1]*m[5] - m[2]*m[4]) / det,
+		(m[5]*m[6] - m[3]*m[8]) / det,
+		(m[0]*m[8] - m[2]*m[6]) / det,
+		(m[2]*m[3] - m[0]*m[5]) / det,
+		(m[3]*m[7] - m[4]*m[6]) / det,
+		(m[1]*m[6] - m[0]*m[7]) / det,
+		(m[0]*m[4] - m[1]*m[3]) / det,
+	}, true
+}
+
+// solveLinear solves a x = b by gaussian elimination with partial pivoting.
+func solveLinear(a [8][8]float64, b [8]float64) ([8]float64, bool) {
+	const n = 8
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-10 {
+			return [8]float64{}, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+
+		for r := col + 1; r < n; r++ {
+			f := a[r][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[r][k] -= f * a[col][k]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+
+	var x [8]float64
+	for r := n - 1; r >= 0; r-- {
+		sum := b[r]
+		for k := r + 1; k < n; k++ {
+			sum -= a[r][k] * x[k]
+		}
+		x[r] = sum / a[r][r]
+	}
+	return x, true
+}
@@ -0,0 +1,45 @@
+// Package metrics writes metrics in the Prometheus text format, for the
+// /metrics endpoints of the server, the consumer and the devices.
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Writer writes metric families in the Prometheus text format.
+type Writer struct {
+	b strings.Builder
+}
+
+// Family writes the help and type of the samples following it.
+func (w *Writer) Family(name, kind, help string) {
+	fmt.Fprintf(&w.b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// Sample writes a sample, labels being name and value pairs.
+func (w *Writer) Sample(name string, value float64, labels ...string) {
+	w.b.WriteString(name)
+	if len(labels) > 0 {
+		w.b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.b.WriteByte(',')
+			}
+			fmt.Fprintf(&w.b, `%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1]))
+		}
+		w.b.WriteByte('}')
+	}
+	fmt.Fprintf(&w.b, " %s\n", strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// Bytes returns the metrics written.
+func (w *Writer) Bytes() []byte {
+	return []byte(w.b.String())
+}
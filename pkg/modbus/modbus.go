@@ -0,0 +1,154 @@
+// Package modbus implements reading registers over Modbus TCP, the subset
+// needed to poll sensors such as thermometers and contact inputs.
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	FuncReadHoldingRegisters = 0x03
+	FuncReadInputRegisters   = 0x04
+
+	// maxRegisters is the most registers a single request may read.
+	maxRegisters = 125
+)
+
+// ExceptionError is returned when the slave answers with an exception.
+type ExceptionError struct {
+	Function byte
+	Code     byte
+}
+
+func (e *ExceptionError) Error() string {
+	return fmt.Sprintf("modbus exception %d for function %d", e.Code, e.Function)
+}
+
+// Client reads the registers of a Modbus TCP slave. It keeps a single
+// connection which is reopened after an error, requests are serialized.
+type Client struct {
+	addr    string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	lastId uint16
+}
+
+func NewClient(addr string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &Client{addr: addr, timeout: timeout}
+}
+
+// ReadRegisters reads count registers starting at address with function
+// FuncReadHoldingRegisters or FuncReadInputRegisters.
+func (c *Client) ReadRegisters(ctx context.Context, unitId, function byte, address, count uint16) ([]uint16, error) {
+	if count == 0 || count > maxRegisters {
+		return nil, fmt.Errorf("invalid register count %d", count)
+	}
+	if function != FuncReadHoldingRegisters && function != FuncReadInputRegisters {
+		return nil, fmt.Errorf("unsupported function %d", function)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pdu := make([]byte, 5)
+	pdu[0] = function
+	binary.BigEndian.PutUint16(pdu[1:], address)
+	binary.BigEndian.PutUint16(pdu[3:], count)
+
+	resp, err := c.send(ctx, unitId, pdu)
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+	if resp[0] == function|0x80 {
+		if len(resp) < 2 {
+			return nil, fmt.Errorf("short exception response")
+		}
+		return nil, &ExceptionError{Function: function, Code: resp[1]}
+	}
+	if resp[0] != function {
+		return nil, fmt.Errorf("unexpected function %d in response", resp[0])
+	}
+	if len(resp) < 2 || int(resp[1]) != int(count)*2 || len(resp) < 2+int(count)*2 {
+		return nil, fmt.Errorf("invalid response length")
+	}
+	regs := make([]uint16, count)
+	for i := range regs {
+		regs[i] = binary.BigEndian.Uint16(resp[2+i*2:])
+	}
+	return regs, nil
+}
+
+// send writes an MBAP framed request and returns the PDU of the response.
+func (c *Client) send(ctx context.Context, unitId byte, pdu []byte) ([]byte, error) {
+	if c.conn == nil {
+		dialer := net.Dialer{Timeout: c.timeout}
+		conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = conn
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	c.lastId++
+	req := make([]byte, 7+len(pdu))
+	binary.BigEndian.PutUint16(req[0:], c.lastId)
+	binary.BigEndian.PutUint16(req[2:], 0) // protocol id
+	binary.BigEndian.PutUint16(req[4:], uint16(len(pdu)+1))
+	req[6] = unitId
+	copy(req[7:], pdu)
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return nil, err
+	}
+	if id := binary.BigEndian.Uint16(header[0:]); id != c.lastId {
+		return nil, fmt.Errorf("unexpected transaction id %d", id)
+	}
+	length := binary.BigEndian.Uint16(header[4:])
+	if length < 2 || length > 256 {
+		return nil, fmt.Errorf("invalid response length %d", length)
+	}
+	resp := make([]byte, length-1)
+	if _, err := io.ReadFull(c.conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// Close closes the connection to the slave.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.close()
+	return nil
+}
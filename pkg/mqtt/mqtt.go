@@ -0,0 +1,300 @@
+// Package mqtt implements a minimal MQTT 3.1.1 subscriber, enough to follow
+// the topics sensors publish their readings to.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	packetConnect     = 0x10
+	packetConnack     = 0x20
+	packetPublish     = 0x30
+	packetPuback      = 0x40
+	packetSubscribe   = 0x82
+	packetSuback      = 0x90
+	packetPingreq     = 0xc0
+	packetPingresp    = 0xd0
+	packetDisconnect  = 0xe0
+	maxRemainingBytes = 4
+)
+
+// Options of a connection to a broker.
+type Options struct {
+	ClientId string
+	Username string
+	Password string
+	// KeepAlive is the ping interval, 30 seconds if zero
+	KeepAlive time.Duration
+}
+
+// Handler is called with every message received on a subscribed topic.
+type Handler func(topic string, payload []byte)
+
+// Subscribe connects to the broker at addr, e.g. tcp://127.0.0.1:1883 or
+// tls://broker:8883, subscribes to topics with QoS 0 and calls handler for
+// every message. It blocks until ctx is done or the connection fails, the
+// caller is expected to retry.
+func Subscribe(ctx context.Context, addr string, opts Options, topics []string, handler Handler) error {
+	if len(topics) == 0 {
+		return errors.New("no topic to subscribe")
+	}
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = 30 * time.Second
+	}
+
+	conn, err := dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	s := &session{conn: conn, r: bufio.NewReader(conn), keepAlive: opts.KeepAlive}
+	if err := s.connect(opts); err != nil {
+		return s.wrapErr(ctx, err)
+	}
+	if err := s.subscribe(topics); err != nil {
+		return s.wrapErr(ctx, err)
+	}
+
+	go s.ping(stop)
+	err = s.readLoop(handler)
+	s.write([]byte{packetDisconnect, 0})
+	return s.wrapErr(ctx, err)
+}
+
+func dial(ctx context.Context, addr string) (net.Conn, error) {
+	u, err := url.Parse(addr)
+	if err != nil || u.Host == "" {
+		// plain host:port
+		u = &url.URL{Scheme: "tcp", Host: addr}
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		return dialer.DialContext(ctx, "tcp", u.Host)
+	case "tls", "ssl", "mqtts":
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname()}}
+		return tlsDialer.DialContext(ctx, "tcp", u.Host)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %s", u.Scheme)
+	}
+}
+
+type session struct {
+	conn      net.Conn
+	r         *bufio.Reader
+	keepAlive time.Duration
+	writeMu   sync.Mutex
+}
+
+func (s *session) wrapErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (s *session) connect(opts Options) error {
+	var flags byte = 0x02 // clean session
+	payload := appendString(nil, opts.ClientId)
+	if opts.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, opts.Username)
+		if opts.Password != "" {
+			flags |= 0x40
+			payload = appendString(payload, opts.Password)
+		}
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(s.keepAlive/time.Second))
+	body = append(body, payload...)
+	if err := s.write(packet(packetConnect, body)); err != nil {
+		return err
+	}
+
+	typ, resp, err := s.readPacket()
+	if err != nil {
+		return err
+	}
+	if typ&0xf0 != packetConnack || len(resp) < 2 {
+		return errors.New("unexpected response to connect")
+	}
+	if resp[1] != 0 {
+		return fmt.Errorf("connection refused, return code %d", resp[1])
+	}
+	return nil
+}
+
+func (s *session) subscribe(topics []string) error {
+	body := binary.BigEndian.AppendUint16(nil, 1) // packet id
+	for _, topic := range topics {
+		body = appendString(body, topic)
+		body = append(body, 0) // QoS 0
+	}
+	if err := s.write(packet(packetSubscribe, body)); err != nil {
+		return err
+	}
+
+	typ, resp, err := s.readPacket()
+	if err != nil {
+		return err
+	}
+	if typ&0xf0 != packetSuback || len(resp) < 2+len(topics) {
+		return errors.New("unexpected response to subscribe")
+	}
+	for i, code := range resp[2:] {
+		if code == 0x80 {
+			return fmt.Errorf("subscribe to %s refused", topics[i])
+		}
+	}
+	return nil
+}
+
+func (s *session) ping(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.keepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := s.write([]byte{packetPingreq, 0}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *session) readLoop(handler Handler) error {
+	for {
+		// the broker answers pings, so silence means a dead connection
+		if err := s.conn.SetReadDeadline(time.Now().Add(s.keepAlive * 3 / 2)); err != nil {
+			return err
+		}
+		typ, body, err := s.readPacket()
+		if err != nil {
+			return err
+		}
+		switch typ & 0xf0 {
+		case packetPublish:
+			if err := s.handlePublish(typ, body, handler); err != nil {
+				return err
+			}
+		case packetPingresp:
+		default:
+			// other packets are not expected by a QoS 0 subscriber
+		}
+	}
+}
+
+func (s *session) handlePublish(typ byte, body []byte, handler Handler) error {
+	topic, rest, err := readString(body)
+	if err != nil {
+		return err
+	}
+	qos := (typ >> 1) & 0x03
+	if qos > 0 {
+		if len(rest) < 2 {
+			return errors.New("malformed publish packet")
+		}
+		id := rest[:2]
+		rest = rest[2:]
+		if err := s.write(packet(packetPuback, id)); err != nil {
+			return err
+		}
+	}
+	handler(topic, rest)
+	return nil
+}
+
+func (s *session) write(data []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return err
+	}
+	_, err := s.conn.Write(data)
+	return err
+}
+
+func (s *session) readPacket() (byte, []byte, error) {
+	typ, err := s.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i >= maxRemainingBytes {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.r, body); err != nil {
+		return 0, nil, err
+	}
+	return typ, body, nil
+}
+
+func packet(typ byte, body []byte) []byte {
+	data := []byte{typ}
+	length := len(body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		data = append(data, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(data, body...)
+}
+
+func appendString(data []byte, s string) []byte {
+	data = binary.BigEndian.AppendUint16(data, uint16(len(s)))
+	return append(data, s...)
+}
+
+func readString(data []byte) (string, []byte, error) {
+	if len(data) < 2 {
+		return "", nil, errors.New("malformed string")
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+n {
+		return "", nil, errors.New("malformed string")
+	}
+	return string(data[2 : 2+n]), data[2+n:], nil
+}
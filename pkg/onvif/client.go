@@ -0,0 +1,225 @@
+// Package onvif implements the small subset of ONVIF needed to discover
+// cameras, resolve their RTSP stream, drive their PTZ service and play audio
+// through their backchannel.
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	nsDevice = "http://www.onvif.org/ver10/device/wsdl"
+	nsMedia  = "http://www.onvif.org/ver10/media/wsdl"
+	nsPTZ    = "http://www.onvif.org/ver20/ptz/wsdl"
+	nsSchema = "http://www.onvif.org/ver10/schema"
+)
+
+// ErrNotSupported is returned when the camera does not provide a service.
+var ErrNotSupported = errors.New("not supported by the camera")
+
+// DeviceServiceURL returns the conventional device service address of a
+// camera, used when the address was not discovered.
+func DeviceServiceURL(host string, port int) string {
+	if port == 0 {
+		port = 80
+	}
+	return fmt.Sprintf("http://%s/onvif/device_service", net.JoinHostPort(host, fmt.Sprint(port)))
+}
+
+// Client talks to the services of a single camera.
+type Client struct {
+	xaddr    string
+	username string
+	password string
+	httpCli  *http.Client
+}
+
+func NewClient(xaddr, username, password string) *Client {
+	return &Client{
+		xaddr:    xaddr,
+		username: username,
+		password: password,
+		httpCli:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type DeviceInformation struct {
+	Manufacturer    string `xml:"Manufacturer"`
+	Model           string `xml:"Model"`
+	FirmwareVersion string `xml:"FirmwareVersion"`
+	SerialNumber    string `xml:"SerialNumber"`
+	HardwareId      string `xml:"HardwareId"`
+}
+
+func (c *Client) GetDeviceInformation(ctx context.Context) (*DeviceInformation, error) {
+	var resp struct {
+		Info DeviceInformation `xml:"Body>GetDeviceInformationResponse"`
+	}
+	body := `<GetDeviceInformation xmlns="` + nsDevice + `"/>`
+	if err := c.call(ctx, c.xaddr, body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Info, nil
+}
+
+// Capabilities holds the addresses of the services a camera provides, an
+// empty address means the service is not supported.
+type Capabilities struct {
+	MediaXAddr string
+	PTZXAddr   string
+}
+
+func (c *Client) GetCapabilities(ctx context.Context) (*Capabilities, error) {
+	var resp struct {
+		Media string `xml:"Body>GetCapabilitiesResponse>Capabilities>Media>XAddr"`
+		PTZ   string `xml:"Body>GetCapabilitiesResponse>Capabilities>PTZ>XAddr"`
+	}
+	body := `<GetCapabilities xmlns="` + nsDevice + `"><Category>All</Category></GetCapabilities>`
+	if err := c.call(ctx, c.xaddr, body, &resp); err != nil {
+		return nil, err
+	}
+	return &Capabilities{
+		MediaXAddr: c.rebase(strings.TrimSpace(resp.Media)),
+		PTZXAddr:   c.rebase(strings.TrimSpace(resp.PTZ)),
+	}, nil
+}
+
+type Profile struct {
+	Token string `xml:"token,attr"`
+	Name  string `xml:"Name"`
+	// PTZConfiguration is set if the profile can be used for PTZ control
+	PTZConfiguration *struct {
+		Token string `xml:"token,attr"`
+	} `xml:"PTZConfiguration"`
+}
+
+func (c *Client) GetProfiles(ctx context.Context, mediaXAddr string) ([]Profile, error) {
+	var resp struct {
+		Profiles []Profile `xml:"Body>GetProfilesResponse>Profiles"`
+	}
+	body := `<GetProfiles xmlns="` + nsMedia + `"/>`
+	if err := c.call(ctx, mediaXAddr, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Profiles, nil
+}
+
+// GetStreamUri returns the RTSP uri of a media profile.
+func (c *Client) GetStreamUri(ctx context.Context, mediaXAddr, profileToken string) (string, error) {
+	var resp struct {
+		Uri string `xml:"Body>GetStreamUriResponse>MediaUri>Uri"`
+	}
+	body := `<GetStreamUri xmlns="` + nsMedia + `"><StreamSetup>` +
+		`<Stream xmlns="` + nsSchema + `">RTP-Unicast</Stream>` +
+		`<Transport xmlns="` + nsSchema + `"><Protocol>RTSP</Protocol></Transport>` +
+		`</StreamSetup><ProfileToken>` + escape(profileToken) + `</ProfileToken></GetStreamUri>`
+	if err := c.call(ctx, mediaXAddr, body, &resp); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Uri), nil
+}
+
+// rebase replaces the host of a service address with the host the client
+// talks to, cameras behind NAT often report their internal address.
+func (c *Client) rebase(xaddr string) string {
+	if xaddr == "" {
+		return ""
+	}
+	u, err := url.Parse(xaddr)
+	if err != nil {
+		return xaddr
+	}
+	base, err := url.Parse(c.xaddr)
+	if err != nil {
+		return xaddr
+	}
+	u.Host = base.Host
+	return u.String()
+}
+
+type soapFault struct {
+	Code   string `xml:"Body>Fault>Code>Subcode>Value"`
+	Reason string `xml:"Body>Fault>Reason>Text"`
+}
+
+func (c *Client) call(ctx context.Context, xaddr, body string, out any) error {
+	if xaddr == "" {
+		return ErrNotSupported
+	}
+	var envelope bytes.Buffer
+	envelope.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+	envelope.WriteString(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope">`)
+	if c.username != "" {
+		header, err := securityHeader(c.username, c.password, time.Now())
+		if err != nil {
+			return err
+		}
+		envelope.WriteString(`<s:Header>` + header + `</s:Header>`)
+	}
+	envelope.WriteString(`<s:Body>` + body + `</s:Body></s:Envelope>`)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, xaddr, &envelope)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/soap+xml; charset=utf-8")
+
+	resp, err := c.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var fault soapFault
+		if xml.Unmarshal(data, &fault) == nil && (fault.Reason != "" || fault.Code != "") {
+			return fmt.Errorf("onvif fault %s: %s", strings.TrimSpace(fault.Code), strings.TrimSpace(fault.Reason))
+		}
+		return fmt.Errorf("onvif request failed, status code: %d", resp.StatusCode)
+	}
+	return xml.Unmarshal(data, out)
+}
+
+// securityHeader builds a WS-Security UsernameToken with password digest.
+func securityHeader(username, password string, now time.Time) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	created := now.UTC().Format("2006-01-02T15:04:05.000Z")
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(created))
+	h.Write([]byte(password))
+	digest := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	return `<Security s:mustUnderstand="1" xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">` +
+		`<UsernameToken><Username>` + escape(username) + `</Username>` +
+		`<Password Type="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest">` + digest + `</Password>` +
+		`<Nonce EncodingType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary">` + base64.StdEncoding.EncodeToString(nonce) + `</Nonce>` +
+		`<Created xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">` + created + `</Created>` +
+		`</UsernameToken></Security>`, nil
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
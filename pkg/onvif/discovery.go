@@ -0,0 +1,172 @@
+package onvif
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	discoveryAddr = "239.255.255.250:3702"
+	discoveryPort = 3702
+	// maxProbeHosts bounds the unicast probes of a subnet scan, a /22
+	maxProbeHosts = 1024
+)
+
+const probeTemplate = `<?xml version="1.0" encoding="UTF-8"?>` +
+	`<e:Envelope xmlns:e="http://www.w3.org/2003/05/soap-envelope" xmlns:w="http://schemas.xmlsoap.org/ws/2004/08/addressing" ` +
+	`xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:dn="http://www.onvif.org/ver10/network/wsdl">` +
+	`<e:Header><w:MessageID>uuid:%s</w:MessageID>` +
+	`<w:To e:mustUnderstand="true">urn:schemas-xmlsoap-org:ws:2005:04:discovery</w:To>` +
+	`<w:Action e:mustUnderstand="true">http://schemas.xmlsoap.org/ws/2005/04/discovery/Probe</w:Action></e:Header>` +
+	`<e:Body><d:Probe><d:Types>dn:NetworkVideoTransmitter</d:Types></d:Probe></e:Body></e:Envelope>`
+
+// Discovered is a camera that answered a WS-Discovery probe.
+type Discovered struct {
+	// XAddr is the device service address of the camera
+	XAddr    string
+	Host     string
+	Port     int
+	Name     string
+	Hardware string
+}
+
+type probeMatches struct {
+	Matches []struct {
+		XAddrs string `xml:"XAddrs"`
+		Scopes string `xml:"Scopes"`
+	} `xml:"Body>ProbeMatches>ProbeMatch"`
+}
+
+// Discover probes for ONVIF cameras until timeout elapses. The probe is
+// multicast on the local network, if subnet is set it is also sent to every
+// host of the subnet, which reaches cameras across routers, and only the
+// cameras within the subnet are returned.
+func Discover(ctx context.Context, subnet *net.IPNet, timeout time.Duration) ([]Discovered, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	probe := []byte(fmt.Sprintf(probeTemplate, uuid.New().String()))
+	mcast, err := net.ResolveUDPAddr("udp4", discoveryAddr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteToUDP(probe, mcast); err != nil {
+		// multicast may be unavailable, the unicast probes still work
+		if subnet == nil {
+			return nil, err
+		}
+	}
+	if subnet != nil {
+		hosts, err := subnetHosts(subnet)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range hosts {
+			conn.WriteToUDP(probe, &net.UDPAddr{IP: ip, Port: discoveryPort})
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+
+	seen := make(map[string]bool)
+	var found []Discovered
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// the read deadline ends the scan
+			break
+		}
+		var matches probeMatches
+		if err := xml.Unmarshal(buf[:n], &matches); err != nil {
+			continue
+		}
+		for _, m := range matches.Matches {
+			d, ok := parseMatch(m.XAddrs, m.Scopes)
+			if !ok || seen[d.XAddr] {
+				continue
+			}
+			if subnet != nil {
+				if ip := net.ParseIP(d.Host); ip == nil || !subnet.Contains(ip) {
+					continue
+				}
+			}
+			seen[d.XAddr] = true
+			found = append(found, d)
+		}
+	}
+	return found, nil
+}
+
+// parseMatch picks the first IPv4 device service address of a probe match.
+func parseMatch(xaddrs, scopes string) (Discovered, bool) {
+	var d Discovered
+	for _, addr := range strings.Fields(xaddrs) {
+		u, err := url.Parse(addr)
+		if err != nil {
+			continue
+		}
+		ip := net.ParseIP(u.Hostname())
+		if ip == nil || ip.To4() == nil {
+			continue
+		}
+		d.XAddr = addr
+		d.Host = u.Hostname()
+		d.Port = 80
+		if p := u.Port(); p != "" {
+			fmt.Sscanf(p, "%d", &d.Port)
+		}
+		break
+	}
+	if d.XAddr == "" {
+		return d, false
+	}
+	for _, scope := range strings.Fields(scopes) {
+		if v, ok := strings.CutPrefix(scope, "onvif://www.onvif.org/name/"); ok {
+			d.Name, _ = url.PathUnescape(v)
+		} else if v, ok := strings.CutPrefix(scope, "onvif://www.onvif.org/hardware/"); ok {
+			d.Hardware, _ = url.PathUnescape(v)
+		}
+	}
+	return d, true
+}
+
+// subnetHosts lists the host addresses of an IPv4 subnet.
+func subnetHosts(subnet *net.IPNet) ([]net.IP, error) {
+	ip4 := subnet.IP.To4()
+	if ip4 == nil {
+		return nil, fmt.Errorf("subnet %s is not IPv4", subnet)
+	}
+	ones, bits := subnet.Mask.Size()
+	size := uint64(1) << uint(bits-ones)
+	if size > maxProbeHosts {
+		return nil, fmt.Errorf("subnet %s is larger than %d hosts", subnet, maxProbeHosts)
+	}
+	start := binary.BigEndian.Uint32(ip4.Mask(subnet.Mask))
+	var hosts []net.IP
+	for i := uint64(0); i < size; i++ {
+		// skip the network and broadcast addresses
+		if size > 2 && (i == 0 || i == size-1) {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, start+uint32(i))
+		hosts = append(hosts, ip)
+	}
+	return hosts, nil
+}
@@ -0,0 +1,82 @@
+package onvif
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PTZ drives the PTZ service of a camera through one of its media profiles.
+type PTZ struct {
+	client       *Client
+	xaddr        string
+	profileToken string
+}
+
+// NewPTZ resolves the PTZ service and the first media profile with a PTZ
+// configuration, it returns ErrNotSupported if the camera has neither.
+func NewPTZ(ctx context.Context, client *Client) (*PTZ, error) {
+	caps, err := client.GetCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if caps.PTZXAddr == "" || caps.MediaXAddr == "" {
+		return nil, ErrNotSupported
+	}
+	profiles, err := client.GetProfiles(ctx, caps.MediaXAddr)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		if p.PTZConfiguration != nil {
+			return &PTZ{client: client, xaddr: caps.PTZXAddr, profileToken: p.Token}, nil
+		}
+	}
+	return nil, ErrNotSupported
+}
+
+// ContinuousMove moves the camera at the given velocities, each in [-1, 1],
+// until Stop is called or timeout elapses.
+func (p *PTZ) ContinuousMove(ctx context.Context, pan, tilt, zoom float64, timeout time.Duration) error {
+	body := fmt.Sprintf(`<ContinuousMove xmlns="%s"><ProfileToken>%s</ProfileToken><Velocity>`+
+		`<PanTilt xmlns="%s" x="%.3f" y="%.3f"/><Zoom xmlns="%s" x="%.3f"/></Velocity>`,
+		nsPTZ, escape(p.profileToken), nsSchema, pan, tilt, nsSchema, zoom)
+	if timeout > 0 {
+		body += fmt.Sprintf(`<Timeout>PT%.3fS</Timeout>`, timeout.Seconds())
+	}
+	body += `</ContinuousMove>`
+	return p.client.call(ctx, p.xaddr, body, &struct{}{})
+}
+
+func (p *PTZ) Stop(ctx context.Context) error {
+	body := fmt.Sprintf(`<Stop xmlns="%s"><ProfileToken>%s</ProfileToken><PanTilt>true</PanTilt><Zoom>true</Zoom></Stop>`,
+		nsPTZ, escape(p.profileToken))
+	return p.client.call(ctx, p.xaddr, body, &struct{}{})
+}
+
+type Preset struct {
+	Token string `xml:"token,attr"`
+	Name  string `xml:"Name"`
+}
+
+func (p *PTZ) GetPresets(ctx context.Context) ([]Preset, error) {
+	var resp struct {
+		Presets []Preset `xml:"Body>GetPresetsResponse>Preset"`
+	}
+	body := fmt.Sprintf(`<GetPresets xmlns="%s"><ProfileToken>%s</ProfileToken></GetPresets>`,
+		nsPTZ, escape(p.profileToken))
+	if err := p.client.call(ctx, p.xaddr, body, &resp); err != nil {
+		return nil, err
+	}
+	for i := range resp.Presets {
+		resp.Presets[i].Name = strings.TrimSpace(resp.Presets[i].Name)
+	}
+	return resp.Presets, nil
+}
+
+func (p *PTZ) GotoPreset(ctx context.Context, presetToken string) error {
+	body := fmt.Sprintf(`<GotoPreset xmlns="%s"><ProfileToken>%s</ProfileToken><PresetToken>%s</PresetToken></GotoPreset>`,
+		nsPTZ, escape(p.profileToken), escape(presetToken))
+	return p.client.call(ctx, p.xaddr, body, &struct{}{})
+}
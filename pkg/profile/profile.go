@@ -0,0 +1,51 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// EnvName is the environment variable used as the default profile.
+const EnvName = "LUMINA_PROFILE"
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Default returns the profile named by LUMINA_PROFILE, empty if unset.
+func Default() string {
+	return os.Getenv(EnvName)
+}
+
+// Path returns the overlay file of profile, which lives next to the base
+// config, e.g. etc/server.yaml with profile prod is etc/server.prod.yaml.
+func Path(configPath, profile string) string {
+	ext := filepath.Ext(configPath)
+	return strings.TrimSuffix(configPath, ext) + "." + profile + ext
+}
+
+// Apply merges the overlay of profile over conf, which must already hold
+// the base config. Only the keys present in the overlay are replaced. The
+// overlay is decoded strictly so that misspelled or unknown keys fail at
+// startup instead of being silently ignored. An empty profile is a no-op.
+func Apply(configPath, profile string, conf any) error {
+	if profile == "" {
+		return nil
+	}
+	if !nameRegexp.MatchString(profile) {
+		return fmt.Errorf("invalid profile name %q", profile)
+	}
+
+	overlayPath := Path(configPath, profile)
+	data, err := os.ReadFile(overlayPath)
+	if err != nil {
+		return fmt.Errorf("read profile %s: %v", profile, err)
+	}
+	if err := yaml.UnmarshalStrict(data, conf); err != nil {
+		return fmt.Errorf("unmarshal profile %s (%s): %v", profile, overlayPath, err)
+	}
+	return nil
+}